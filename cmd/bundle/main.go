@@ -0,0 +1,50 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command bundle packs a converted model directory into a single-file
+// model bundle, which can be loaded by the server with the -model-bundle flag.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nlpodyssey/cybertron/pkg/bundle"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	modelsDir := fs.String("models-dir", "models", "models's base directory")
+	modelName := fs.String("model", "", "model name (and sub-path of models-dir)")
+	task := fs.String("task", "", "type of inference/computation that the model can fulfill (optional)")
+	output := fs.String("output", "", "bundle filename")
+
+	err := fs.Parse(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if *modelName == "" || *output == "" {
+		return errors.New("both -model and -output must be specified")
+	}
+
+	manifest, err := bundle.Create(filepath.Join(*modelsDir, *modelName), *output, *modelName, *task)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Bundled %d files to \"%s\".\n", len(manifest.Files), *output)
+	return nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command cache manages the local cache of models.
+//
+// Usage:
+//
+//	cache [-models-dir dir] list
+//	cache [-models-dir dir] verify [model...]
+//	cache [-models-dir dir] prune [-max-age duration] [-max-size bytes] [-dry-run]
+//	cache [-models-dir dir] gc [-dry-run]
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/cache"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("cache", flag.ContinueOnError)
+	modelsDir := fs.String("models-dir", "models", "models's base directory")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("missing command: list, verify, prune or gc")
+	}
+
+	cmd, args := fs.Arg(0), fs.Args()[1:]
+	switch cmd {
+	case "list":
+		return list(*modelsDir)
+	case "verify":
+		return verify(*modelsDir, args)
+	case "prune":
+		return prune(*modelsDir, args)
+	case "gc":
+		return gc(*modelsDir, args)
+	default:
+		return fmt.Errorf("unknown command %#v", cmd)
+	}
+}
+
+func list(modelsDir string) error {
+	entries, err := cache.List(modelsDir)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tSIZE\tLAST USED")
+	var total int64
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", e.Name, formatSize(e.Size), e.LastUsed.Format(time.RFC3339))
+		total += e.Size
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("%d models, %s\n", len(entries), formatSize(total))
+	return nil
+}
+
+func verify(modelsDir string, modelNames []string) error {
+	if len(modelNames) == 0 {
+		entries, err := cache.List(modelsDir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			modelNames = append(modelNames, e.Name)
+		}
+	}
+	failed := 0
+	for _, name := range modelNames {
+		problems, err := cache.Verify(modelsDir, name)
+		if err != nil {
+			return err
+		}
+		if len(problems) == 0 {
+			fmt.Printf("%s: ok\n", name)
+			continue
+		}
+		failed++
+		fmt.Printf("%s: %d problems\n", name, len(problems))
+		for _, p := range problems {
+			fmt.Printf("  %s\n", p)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d models failed verification", failed, len(modelNames))
+	}
+	return nil
+}
+
+func prune(modelsDir string, args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ContinueOnError)
+	maxAge := fs.Duration("max-age", 0, "remove the models not used for longer than this (e.g. \"720h\")")
+	maxSize := fs.String("max-size", "", "remove the least recently used models until the cache fits this size (e.g. \"20GB\")")
+	dryRun := fs.Bool("dry-run", false, "only print the models that would be removed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	opts := cache.PruneOptions{MaxAge: *maxAge, DryRun: *dryRun}
+	if *maxSize != "" {
+		size, err := parseSize(*maxSize)
+		if err != nil {
+			return err
+		}
+		opts.MaxSize = size
+	}
+	if opts.MaxAge == 0 && opts.MaxSize == 0 {
+		return errors.New("at least one of -max-age and -max-size must be specified")
+	}
+
+	pruned, err := cache.Prune(modelsDir, opts)
+	var freed int64
+	for _, e := range pruned {
+		fmt.Printf("removed %s (%s)\n", e.Name, formatSize(e.Size))
+		freed += e.Size
+	}
+	fmt.Printf("%d models, %s freed\n", len(pruned), formatSize(freed))
+	return err
+}
+
+func gc(modelsDir string, args []string) error {
+	fs := flag.NewFlagSet("gc", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only print the artifacts that would be removed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	removed, err := cache.GC(modelsDir, *dryRun)
+	if err != nil {
+		return err
+	}
+	for _, p := range removed {
+		fmt.Printf("removed %s\n", p)
+	}
+	fmt.Printf("%d orphaned artifacts\n", len(removed))
+	return nil
+}
+
+var sizeUnits = []string{"B", "KB", "MB", "GB", "TB"}
+
+// formatSize formats a size in bytes with decimal units.
+func formatSize(size int64) string {
+	v, i := float64(size), 0
+	for ; v >= 1000 && i < len(sizeUnits)-1; i++ {
+		v /= 1000
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d B", size)
+	}
+	return fmt.Sprintf("%.1f %s", v, sizeUnits[i])
+}
+
+// parseSize parses a size in bytes with an optional decimal unit (e.g. "20GB").
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for i := len(sizeUnits) - 1; i > 0; i-- {
+		if strings.HasSuffix(s, sizeUnits[i]) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, sizeUnits[i]))
+			for j := 0; j < i; j++ {
+				multiplier *= 1000
+			}
+			break
+		}
+	}
+	s = strings.TrimSuffix(s, "B")
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %#v", s)
+	}
+	return int64(v * float64(multiplier)), nil
+}
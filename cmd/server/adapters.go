@@ -0,0 +1,185 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/nlpodyssey/cybertron/pkg/lora"
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/rs/zerolog/log"
+)
+
+// adapterAdmin lists, adds and removes the LoRA adapters of the served
+// models on request of the admin API, carrying them over to the new
+// revisions of the models.
+type adapterAdmin struct {
+	models []*loadedModel
+	// mu is held while the adapters are changed, and while a model is
+	// swapped.
+	mu sync.Mutex
+}
+
+// modelAdapters are the adapters of a served model, as listed by the admin
+// API.
+type modelAdapters struct {
+	Model    string   `json:"model"`
+	Adapters []string `json:"adapters"`
+}
+
+// ServeHTTP lists the adapters of the served models on GET requests, adds
+// the adapter with the "name" of the query from its "dir" on PUT requests,
+// replacing the one with the same name, and removes it on DELETE requests.
+// The models are the ones supporting the adapters, or the one named by the
+// "model" of the query, if any.
+func (a *adapterAdmin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodPut, http.MethodDelete:
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	q := r.URL.Query()
+	model, name := q.Get("model"), q.Get("name")
+	if r.Method != http.MethodGet && name == "" {
+		http.Error(w, "adapter name not specified", http.StatusBadRequest)
+		return
+	}
+	var ids []string
+	var sets []*tasks.AdapterSet
+	for _, lm := range leafModels(a.models) {
+		if model != "" && model != lm.config.ModelName && model != lm.id() {
+			continue
+		}
+		if s, ok := tasks.Adapters(lm.model); ok {
+			ids = append(ids, lm.id())
+			sets = append(sets, s)
+		}
+	}
+	if model != "" && len(sets) == 0 {
+		http.Error(w, fmt.Sprintf("no model %#v supporting the adapters", model), http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		models := make([]modelAdapters, len(sets))
+		for i, s := range sets {
+			models[i] = modelAdapters{Model: ids[i], Adapters: s.Names()}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{"models": models}); err != nil {
+			log.Warn().Err(err).Msg("failed to write the adapters")
+		}
+	case http.MethodPut:
+		adapter, err := lora.Load(q.Get("dir"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to load adapter %#v: %v", name, err), http.StatusBadRequest)
+			return
+		}
+		if err := addAdapter(sets, name, adapter); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Info().Str("adapter", name).Strs("models", ids).Msg("adapter added")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		removed := false
+		for _, s := range sets {
+			removed = s.Remove(name) || removed
+		}
+		if !removed {
+			http.Error(w, fmt.Sprintf("unknown adapter %#v", name), http.StatusNotFound)
+			return
+		}
+		log.Info().Str("adapter", name).Strs("models", ids).Msg("adapter removed")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// addAdapter adds the adapter to all the sets, or to none of them, the
+// adapters it replaces being restored on error.
+func addAdapter(sets []*tasks.AdapterSet, name string, adapter *lora.Adapter) error {
+	prevs := make([]*lora.Adapter, len(sets))
+	for i, s := range sets {
+		prevs[i], _ = s.Adapter(name)
+	}
+	for i, s := range sets {
+		err := s.Add(name, adapter)
+		if err == nil {
+			continue
+		}
+		for j, s := range sets[:i] {
+			if prevs[j] == nil {
+				s.Remove(name)
+			} else if rerr := s.Add(name, prevs[j]); rerr != nil {
+				log.Warn().Err(rerr).Str("adapter", name).Msg("failed to restore adapter")
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// lockSwap locks the adapters for the swap of a model.
+func (a *adapterAdmin) lockSwap() {
+	if a != nil {
+		a.mu.Lock()
+	}
+}
+
+// unlockSwap unlocks the adapters once a model is swapped.
+func (a *adapterAdmin) unlockSwap() {
+	if a != nil {
+		a.mu.Unlock()
+	}
+}
+
+// copyAdapters makes the adapters of the candidate model the same as the
+// ones of the served model, including the ones added or removed by the
+// admin API, if both support them.
+func copyAdapters(served, candidate any) error {
+	from, ok := tasks.Adapters(served)
+	if !ok {
+		return nil
+	}
+	to, ok := tasks.Adapters(candidate)
+	if !ok {
+		return nil
+	}
+	for _, name := range to.Names() {
+		if _, ok := from.Adapter(name); !ok {
+			to.Remove(name)
+		}
+	}
+	for _, name := range from.Names() {
+		a, _ := from.Adapter(name)
+		if err := to.Add(name, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// leafModels returns the models, with the ones of the ensembles, and the
+// variants, in place of them.
+func leafModels(models []*loadedModel) []*loadedModel {
+	var leaves []*loadedModel
+	for _, lm := range models {
+		if len(lm.members) > 0 {
+			leaves = append(leaves, leafModels(lm.members)...)
+			continue
+		}
+		leaves = append(leaves, lm)
+	}
+	return leaves
+}
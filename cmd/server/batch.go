@@ -0,0 +1,70 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+
+	"github.com/nlpodyssey/cybertron/pkg/batch"
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/rs/zerolog/log"
+)
+
+// runBatch runs the model over a corpus, either a directory, a dataset, or a
+// JSON lines file, writing the results incrementally to the output file, and resuming
+// from the checkpoint of a previous interrupted run, if any.
+func runBatch(args []string) error {
+	var o inferenceOptions
+	var input, output string
+	var bo batch.Options
+	conf, _, err := parseConfig("batch", args, func(_ *config, fs *flag.FlagSet) {
+		o.bind(fs)
+		fs.Func("input", `corpus to process: a directory, where each file is an input, a CoNLL, SQuAD (.json), CSV or TSV dataset, or a JSON lines file, where each line has an "input" field`,
+			flagAssignFunc(&input))
+		fs.Func("output", "file to write the results to, with the checkpoint of the progress next to it", flagAssignFunc(&output))
+		fs.Func("output-format", `format of the output: "jsonl", "arrow" or "parquet", by the extension of the output by default`,
+			flagParseFunc(batch.ParseFormat, &bo.Format))
+		fs.IntVar(&bo.Parallelism, "parallelism", 1, "number of inputs processed concurrently")
+		fs.IntVar(&bo.CheckpointInterval, "checkpoint-interval", 100, "number of results written between two checkpoints")
+	})
+	if err != nil {
+		return err
+	}
+	if input == "" || output == "" {
+		return errors.New("both -input and -output must be specified")
+	}
+	if bo.Format == "" {
+		bo.Format = batch.FormatOf(output)
+	}
+	r, err := batch.Open(input)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	o.recordQuestions = true
+	m, infer, err := loadForInference(conf, o)
+	if err != nil {
+		return err
+	}
+	defer tasks.Finalize(m)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	stats, err := batch.Run(ctx, r, output, batch.Func(infer), bo)
+	log.Info().
+		Int("skipped", stats.Skipped).
+		Int("processed", stats.Processed).
+		Int("failed", stats.Failed).
+		Msg("batch processing stopped")
+	if errors.Is(err, context.Canceled) {
+		log.Info().Msg("interrupted: run the same command again to resume")
+		return nil
+	}
+	return err
+}
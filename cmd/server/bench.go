@@ -0,0 +1,298 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime/metrics"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/verification"
+)
+
+// benchOptions are the options of the bench subcommand.
+type benchOptions struct {
+	requests     int
+	concurrency  int
+	warmup       int
+	batchSizes   []int
+	inputLengths []int
+}
+
+// bind binds the options to the flag set.
+func (o *benchOptions) bind(fs *flag.FlagSet) {
+	o.batchSizes = []int{1}
+	fs.IntVar(&o.requests, "requests", 100, "number of requests")
+	fs.IntVar(&o.concurrency, "concurrency", 1, "number of concurrent requests")
+	fs.IntVar(&o.warmup, "warmup", 5, "number of requests run before measuring")
+	fs.Func("batch-sizes", "numbers of inputs per request to measure, run one after the other, as the batch APIs serve them (comma separated, default 1)",
+		flagParseFunc(parseIntList, &o.batchSizes))
+	fs.Func("input-lengths", "lengths in words of the synthetic inputs to measure (comma separated, default the given inputs)",
+		flagParseFunc(parseIntList, &o.inputLengths))
+}
+
+// benchResult is the result of a benchmark run.
+type benchResult struct {
+	inputLength int
+	batchSize   int
+	requests    int
+	failed      int
+	err         error
+	elapsed     time.Duration
+	latencies   []time.Duration
+	tokens      int
+	// peakHeap is the peak size of the heap objects during the run, zero
+	// if not sampled.
+	peakHeap uint64
+}
+
+// bench runs the model on the inputs, given as arguments or, if none, the
+// canonical verification inputs, for each combination of batch size and
+// input length, and prints the throughput and latencies.
+func bench(args []string) error {
+	var o inferenceOptions
+	var bo benchOptions
+	conf, fs, err := parseConfig("bench", args, func(_ *config, fs *flag.FlagSet) {
+		o.bind(fs)
+		bo.bind(fs)
+	})
+	if err != nil {
+		return err
+	}
+	if bo.requests < 1 || bo.concurrency < 1 {
+		return errors.New("both -requests and -concurrency must be positive")
+	}
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		inputs = verification.CanonicalInputs
+	}
+	m, infer, err := loadForInference(conf, o)
+	if err != nil {
+		return err
+	}
+	defer tasks.Finalize(m)
+
+	ctx := context.Background()
+	for i := 0; i < bo.warmup; i++ {
+		if _, err := infer(ctx, inputs[i%len(inputs)]); err != nil {
+			return err
+		}
+	}
+
+	tokenizer, _ := tasks.AsTokenizer(m)
+	lengths := bo.inputLengths
+	if len(lengths) == 0 {
+		lengths = []int{0}
+	}
+	var results []benchResult
+	for _, length := range lengths {
+		in := inputs
+		if length > 0 {
+			in = syntheticInputs(inputs, length)
+		}
+		for _, batchSize := range bo.batchSizes {
+			r := runBench(ctx, infer, in, batchSize, bo)
+			r.inputLength = length
+			if tokenizer != nil {
+				r.tokens = countTokens(tokenizer, in, r.requests*batchSize)
+			}
+			results = append(results, r)
+		}
+	}
+	printBenchResults(os.Stdout, results, bo.concurrency)
+
+	for _, r := range results {
+		if r.failed > 0 {
+			return fmt.Errorf("%d requests failed: %w", r.failed, r.err)
+		}
+	}
+	return nil
+}
+
+// runBench runs the requests, each running the model on a batch of inputs,
+// one after the other, as the batch APIs serve them, with the given
+// concurrency, sampling the size of the heap meanwhile.
+func runBench(ctx context.Context, infer inferenceFunc, inputs []string, batchSize int, o benchOptions) benchResult {
+	stopSampling := sampleHeap(10 * time.Millisecond)
+	latencies := make([]time.Duration, o.requests)
+	errs := make([]error, o.requests)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < o.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				t := time.Now()
+				for j := 0; j < batchSize && errs[i] == nil; j++ {
+					_, errs[i] = infer(ctx, inputs[(i*batchSize+j)%len(inputs)])
+				}
+				latencies[i] = time.Since(t)
+			}
+		}()
+	}
+	for i := 0; i < o.requests; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	r := benchResult{
+		batchSize: batchSize,
+		requests:  o.requests,
+		elapsed:   time.Since(start),
+		latencies: latencies,
+		peakHeap:  stopSampling(),
+	}
+	for _, err := range errs {
+		if err != nil {
+			if r.failed == 0 {
+				r.err = err
+			}
+			r.failed++
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return r
+}
+
+// percentile returns the latency at the given percentile.
+func (r benchResult) percentile(p float64) time.Duration {
+	return r.latencies[int(p*float64(len(r.latencies)-1))]
+}
+
+// heapObjectsMetric is the runtime metric of the size of the heap objects.
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// sampleHeap samples the size of the heap objects at the given interval,
+// until the returned function is called, which returns its peak.
+func sampleHeap(interval time.Duration) (stop func() uint64) {
+	sample := []metrics.Sample{{Name: heapObjectsMetric}}
+	read := func() uint64 {
+		metrics.Read(sample)
+		if sample[0].Value.Kind() != metrics.KindUint64 {
+			return 0
+		}
+		return sample[0].Value.Uint64()
+	}
+	peak := read()
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if v := read(); v > peak {
+					peak = v
+				}
+			}
+		}
+	}()
+	return func() uint64 {
+		close(done)
+		<-finished
+		if v := read(); v > peak {
+			peak = v
+		}
+		return peak
+	}
+}
+
+// printBenchResults prints a table of the results, with the peak heap of
+// each run, followed by the peak resident set size of the whole process,
+// across the runs and the loading of the model.
+func printBenchResults(out io.Writer, results []benchResult, concurrency int) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "input length\tbatch size\trequests\tfailed\treq/s\tinputs/s\ttokens/s\tp50\tp95\tp99\tmax\tpeak heap\t\n")
+	for _, r := range results {
+		length := "-"
+		if r.inputLength > 0 {
+			length = strconv.Itoa(r.inputLength)
+		}
+		tokens := "-"
+		if r.tokens > 0 {
+			tokens = fmt.Sprintf("%.1f", float64(r.tokens)/r.elapsed.Seconds())
+		}
+		heap := "-"
+		if r.peakHeap > 0 {
+			heap = fmt.Sprintf("%.1f MiB", float64(r.peakHeap)/(1<<20))
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.2f\t%.2f\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+			length, r.batchSize, r.requests, r.failed,
+			float64(r.requests)/r.elapsed.Seconds(),
+			float64(r.requests*r.batchSize)/r.elapsed.Seconds(),
+			tokens,
+			r.percentile(0.5), r.percentile(0.95), r.percentile(0.99), r.latencies[len(r.latencies)-1],
+			heap)
+	}
+	w.Flush()
+	fmt.Fprintf(out, "concurrency: %d\n", concurrency)
+	if rss, ok := peakRSS(); ok {
+		fmt.Fprintf(out, "peak RSS of the process: %.1f MiB\n", float64(rss)/(1<<20))
+	}
+}
+
+// syntheticInputs returns inputs of the given length in words, made by
+// repeating the words of the given inputs.
+func syntheticInputs(inputs []string, length int) []string {
+	var words []string
+	for _, input := range inputs {
+		words = append(words, strings.Fields(input)...)
+	}
+	result := make([]string, len(inputs))
+	for i := range result {
+		w := make([]string, length)
+		for j := range w {
+			w[j] = words[(i+j)%len(words)]
+		}
+		result[i] = strings.Join(w, " ")
+	}
+	return result
+}
+
+// countTokens returns the number of tokens of the first n inputs, cycling
+// through them.
+func countTokens(t tasks.Tokenizer, inputs []string, n int) int {
+	counts := make([]int, len(inputs))
+	for i, input := range inputs {
+		counts[i] = len(t.Tokenize(input))
+	}
+	total := 0
+	for i := 0; i < n; i++ {
+		total += counts[i%len(counts)]
+	}
+	return total
+}
+
+// parseIntList parses the given string as a comma-separated list of
+// positive integers.
+func parseIntList(s string) ([]int, error) {
+	var result []int
+	for _, v := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid positive integer value %#v", v)
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIntList(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []int
+		wantErr bool
+	}{
+		{"1", []int{1}, false},
+		{"1, 8,32", []int{1, 8, 32}, false},
+		{"0", nil, true},
+		{"-1", nil, true},
+		{"1,,2", nil, true},
+		{"a", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseIntList(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSyntheticInputs(t *testing.T) {
+	got := syntheticInputs([]string{"a b", "c"}, 4)
+	assert.Equal(t, []string{"a b c a", "b c a b"}, got)
+}
+
+type wordTokenizer struct{}
+
+func (wordTokenizer) Tokenize(text string) []string {
+	return strings.Fields(text)
+}
+
+func TestCountTokens(t *testing.T) {
+	assert.Equal(t, 0, countTokens(wordTokenizer{}, []string{"a b"}, 0))
+	assert.Equal(t, 2+1+2, countTokens(wordTokenizer{}, []string{"a b", "c"}, 3))
+}
+
+func TestRunBench(t *testing.T) {
+	var calls atomic.Int64
+	infer := func(ctx context.Context, input string) (any, error) {
+		if calls.Add(1) == 7 {
+			return nil, errors.New("failed")
+		}
+		return input, nil
+	}
+	r := runBench(context.Background(), infer, []string{"a", "b"}, 3, benchOptions{requests: 4, concurrency: 2})
+
+	assert.Equal(t, 3, r.batchSize)
+	assert.Equal(t, 4, r.requests)
+	assert.Equal(t, 1, r.failed)
+	assert.EqualError(t, r.err, "failed")
+	// The request failing stops at its failed input.
+	assert.LessOrEqual(t, calls.Load(), int64(4*3))
+	assert.Len(t, r.latencies, 4)
+	for i := 1; i < len(r.latencies); i++ {
+		assert.LessOrEqual(t, r.latencies[i-1], r.latencies[i], "sorted latencies")
+	}
+	assert.Positive(t, r.peakHeap)
+}
+
+func TestBenchResult_percentile(t *testing.T) {
+	r := benchResult{latencies: []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+	assert.Equal(t, time.Duration(5), r.percentile(0.5))
+	assert.Equal(t, time.Duration(9), r.percentile(0.95))
+	assert.Equal(t, time.Duration(1), r.percentile(0))
+}
+
+func TestSampleHeap(t *testing.T) {
+	stop := sampleHeap(time.Millisecond)
+	buf := make([]byte, 64<<20)
+	buf[len(buf)-1] = 1
+	time.Sleep(5 * time.Millisecond)
+	peak := stop()
+	assert.GreaterOrEqual(t, peak, uint64(len(buf)))
+	assert.Equal(t, byte(1), buf[len(buf)-1])
+}
+
+func TestPrintBenchResults(t *testing.T) {
+	results := []benchResult{
+		{inputLength: 16, batchSize: 4, requests: 2, elapsed: time.Second, latencies: []time.Duration{time.Millisecond, 2 * time.Millisecond}, tokens: 100, peakHeap: 3 << 20},
+		{batchSize: 1, requests: 1, elapsed: time.Second, latencies: []time.Duration{time.Millisecond}},
+	}
+	var out bytes.Buffer
+	printBenchResults(&out, results, 2)
+	lines := strings.Split(out.String(), "\n")
+	require.GreaterOrEqual(t, len(lines), 4)
+	assert.Contains(t, lines[0], "peak heap")
+	assert.Equal(t, []string{"16", "4", "2", "0", "2.00", "8.00", "100.0", "1ms", "1ms", "1ms", "2ms", "3.0", "MiB"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"-", "1", "1", "0", "1.00", "1.00", "-", "1ms", "1ms", "1ms", "1ms", "-"}, strings.Fields(lines[2]))
+	assert.Equal(t, "concurrency: 2", lines[3])
+}
@@ -0,0 +1,190 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/calibration"
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier"
+	"github.com/rs/zerolog/log"
+)
+
+// calibrationExample is an example of the validation set of the calibrate
+// subcommand.
+type calibrationExample struct {
+	Input string `json:"input"`
+	Label string `json:"label"`
+}
+
+// classifyFunc returns the labels and the probabilities predicted for an
+// input, in the same order.
+type classifyFunc func(ctx context.Context, input string) ([]string, []float64, error)
+
+// calibrate fits the temperature of the probabilities of the classifier on
+// a labeled validation set, a JSON lines file with the "input" and the
+// "label" of each example, and writes it to the calibration file of the
+// model, loaded by the other subcommands from then on.
+func calibrate(args []string) error {
+	var input, output string
+	var labels []string
+	conf, _, err := parseConfig("calibrate", args, func(_ *config, fs *flag.FlagSet) {
+		fs.Func("input", `JSON lines file of the validation set, each with the "input" text and its "label" ("-" for the standard input)`,
+			flagAssignFunc(&input))
+		fs.Func("labels", "candidate labels (comma separated), for the zero-shot-classification task",
+			flagParseFunc(parseCommaSplit, &labels))
+		fs.Func("output", "file to write the calibration to (default the calibration.json file in the model directory)",
+			flagAssignFunc(&output))
+	})
+	if err != nil {
+		return err
+	}
+	if len(conf.models) > 0 {
+		return errors.New("multiple models are only supported by the serve, download and convert subcommands")
+	}
+	if input == "" {
+		return errors.New("the validation set is not specified")
+	}
+	examples, err := readCalibrationExamples(input)
+	if err != nil {
+		return err
+	}
+
+	// The probabilities are fitted as predicted, without the calibration
+	// the model may already have.
+	conf.loaderConfig.Calibration = tasks.CalibrationNone
+	lm, err := loadModel(conf.task, conf.loaderConfig)
+	if err != nil {
+		return err
+	}
+	defer tasks.Finalize(lm.model)
+	classify, err := newClassifyFunc(lm.model, labels)
+	if err != nil {
+		return err
+	}
+
+	probs, targets, err := classifyExamples(context.Background(), classify, examples)
+	if err != nil {
+		return err
+	}
+	temperature, err := calibration.FitTemperature(probs, targets)
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		output = filepath.Join(conf.loaderConfig.FullModelPath(), calibration.Filename)
+	}
+	if err := (calibration.Calibration{Temperature: temperature}).Save(output); err != nil {
+		return err
+	}
+	log.Info().Float64("temperature", temperature).Int("examples", len(examples)).Str("path", output).Msg("calibration fitted")
+	return nil
+}
+
+// newClassifyFunc returns the function classifying the inputs with the
+// model, with the candidate labels of the zero-shot classification.
+func newClassifyFunc(m any, labels []string) (classifyFunc, error) {
+	switch m := m.(type) {
+	case textclassification.Interface:
+		return func(ctx context.Context, input string) ([]string, []float64, error) {
+			resp, err := m.Classify(ctx, input, textclassification.Parameters{})
+			return resp.Labels, resp.Scores, err
+		}, nil
+	case zeroshotclassifier.Interface:
+		if len(labels) < 2 {
+			return nil, errors.New("the calibration of the zero-shot classification needs at least two -labels")
+		}
+		params := zeroshotclassifier.Parameters{CandidateLabels: labels}
+		return func(ctx context.Context, input string) ([]string, []float64, error) {
+			resp, err := m.Classify(ctx, input, params)
+			return resp.Labels, resp.Scores, err
+		}, nil
+	default:
+		return nil, fmt.Errorf("calibration not supported for model type %T", m)
+	}
+}
+
+// classifyExamples classifies the examples, and returns the probabilities
+// of the labels, in the order of the first response, and the index of the
+// label of each example.
+func classifyExamples(ctx context.Context, classify classifyFunc, examples []calibrationExample) ([][]float64, []int, error) {
+	var index map[string]int
+	probs := make([][]float64, 0, len(examples))
+	targets := make([]int, 0, len(examples))
+	for i, ex := range examples {
+		labels, scores, err := classify(ctx, ex.Input)
+		if err != nil {
+			return nil, nil, fmt.Errorf("example %d: %w", i+1, err)
+		}
+		if index == nil {
+			index = make(map[string]int, len(labels))
+			for j, l := range labels {
+				index[l] = j
+			}
+		}
+		p := make([]float64, len(index))
+		for j, l := range labels {
+			if k, ok := index[l]; ok {
+				p[k] = scores[j]
+			}
+		}
+		target, ok := index[ex.Label]
+		if !ok {
+			return nil, nil, fmt.Errorf("example %d: unknown label %#v", i+1, ex.Label)
+		}
+		probs = append(probs, p)
+		targets = append(targets, target)
+	}
+	return probs, targets, nil
+}
+
+// readCalibrationExamples reads the examples of the JSON lines file, "-"
+// being the standard input.
+func readCalibrationExamples(filename string) ([]calibrationExample, error) {
+	var r io.Reader = os.Stdin
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var examples []calibrationExample
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var ex calibrationExample
+		if err := json.Unmarshal([]byte(text), &ex); err != nil {
+			return nil, fmt.Errorf("%s: line %d: %w", filename, line, err)
+		}
+		if ex.Input == "" || ex.Label == "" {
+			return nil, fmt.Errorf(`%s: line %d: missing "input" or "label"`, filename, line)
+		}
+		examples = append(examples, ex)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(examples) == 0 {
+		return nil, fmt.Errorf("%s: no examples", filename)
+	}
+	return examples, nil
+}
@@ -0,0 +1,438 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/batch"
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/coreference"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/languagemodeling"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/postagging"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/relationextraction"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier"
+	"github.com/nlpodyssey/cybertron/pkg/utils/nullable"
+	"github.com/rs/zerolog/log"
+)
+
+// download downloads and converts the models, without serving them.
+func download(args []string) error {
+	conf, _, err := parseConfig("download", args, nil)
+	if err != nil {
+		return err
+	}
+	return prepareModels(conf)
+}
+
+// convert converts the models already in the models directory, without
+// downloading them. Unlike the other subcommands, the models are converted
+// even if they already are, unless the conversion policy is set.
+func convert(args []string) error {
+	conf, _, err := parseConfig("convert", args, func(conf *config, _ *flag.FlagSet) {
+		conf.loaderConfig.ConversionPolicy = tasks.ConvertAlways
+	})
+	if err != nil {
+		return err
+	}
+	conf.loaderConfig.DownloadPolicy = tasks.DownloadNever
+	return prepareModels(conf)
+}
+
+// prepareModels downloads and converts the configured model, or all the
+// models of the models manifest.
+func prepareModels(conf *config) error {
+	configs, err := modelConfigs(conf)
+	if err != nil {
+		return err
+	}
+	for _, c := range configs {
+		dir, err := tasks.Prepare(c)
+		if err != nil {
+			return fmt.Errorf("failed to prepare model %#v: %w", c.ModelName, err)
+		}
+		log.Info().Str("model", c.ModelName).Str("path", dir).Msg("model ready")
+	}
+	return nil
+}
+
+// modelConfigs returns the loader configurations of the configured model,
+// or of all the models of the models manifest, including the members of the
+// ensembles and the variants.
+func modelConfigs(conf *config) ([]*tasks.Config, error) {
+	if len(conf.models) == 0 {
+		return []*tasks.Config{conf.loaderConfig}, nil
+	}
+	var configs []*tasks.Config
+	for _, mm := range conf.models {
+		c, err := mm.loaderConfig(conf.loaderConfig)
+		if err != nil {
+			return nil, err
+		}
+		if len(mm.Ensemble) == 0 && len(mm.Variants) == 0 {
+			configs = append(configs, c)
+			continue
+		}
+		members, _ := mm.members()
+		variants, _, _ := mm.variants()
+		for _, member := range append(members, variants...) {
+			mc, err := member.loaderConfig(c)
+			if err != nil {
+				return nil, err
+			}
+			configs = append(configs, mc)
+		}
+	}
+	return configs, nil
+}
+
+// inferenceOptions are the task-specific options of the run, bench and repl
+// subcommands.
+type inferenceOptions struct {
+	question        string
+	labels          []string
+	poolingStrategy int
+	k               int
+	layers          int
+	explain         bool
+	textPair        string
+	nested          bool
+	generation      text2text.Options
+	// recordQuestions is whether the questions of the question-answering
+	// task can be the "question" fields of the batch records, without the
+	// -question flag.
+	recordQuestions bool
+}
+
+// bind binds the options to the flag set.
+func (o *inferenceOptions) bind(fs *flag.FlagSet) {
+	o.generation = *text2text.DefaultOptions()
+	fs.Func("question", "question to answer, for the question-answering task (the input is the passage)",
+		flagAssignFunc(&o.question))
+	fs.Func("labels", "candidate labels (comma separated), for the zero-shot-classification task",
+		flagParseFunc(parseCommaSplit, &o.labels))
+	fs.IntVar(&o.poolingStrategy, "pooling-strategy", 0, "pooling strategy, for the text-encoding task")
+	fs.IntVar(&o.k, "k", 1, "number of predictions per token, for the language-modeling task")
+	fs.IntVar(&o.layers, "layers", 0, "number of encoder layers to run, trading accuracy for latency, for the text-classification task (default 0 for all)")
+	fs.BoolVar(&o.explain, "explain", false, "whether to explain the predictions with the attributions to the tokens of the input, for the text-classification and question-answering tasks")
+	fs.Func("text-pair", `second text classified with the input, e.g. the hypothesis of a premise, for the text-classification task (optional: the "text_pair" field of the batch records otherwise)`,
+		flagAssignFunc(&o.textPair))
+	fs.BoolVar(&o.nested, "nested", false, "whether the entities can be nested in or overlap with each other, for the token-classification task")
+	fs.Func("temperature", "temperature used for sampling, for the text2text task (default 1)",
+		flagParseFunc(parseNullable(parseFloat), &o.generation.Temperature))
+	fs.Func("sample", `whether to sample instead of generating greedily, for the text2text task ("true"|"false", default "false")`,
+		flagParseFunc(parseNullable(parseBool), &o.generation.Sample))
+	fs.Func("top-k", "number of top candidates considered when sampling, for the text2text task (optional)",
+		flagParseFunc(parseNullable(strconv.Atoi), &o.generation.TopK))
+	fs.Func("top-p", "cumulative probability of the top candidates considered when sampling, for the text2text task (optional)",
+		flagParseFunc(parseNullable(parseFloat), &o.generation.TopP))
+}
+
+// parseNullable returns a function that parses a value with the given
+// function, as a valid nullable value.
+func parseNullable[T any](parse func(string) (T, error)) func(string) (nullable.Type[T], error) {
+	return func(s string) (nullable.Type[T], error) {
+		v, err := parse(s)
+		if err != nil {
+			return nullable.Type[T]{}, err
+		}
+		return nullable.Type[T]{Value: v, Valid: true}, nil
+	}
+}
+
+// parseFloat parses the given string as a float64.
+func parseFloat(s string) (float64, error) {
+	return strconv.ParseFloat(s, 64)
+}
+
+// inferenceFunc runs the model on an input.
+type inferenceFunc func(ctx context.Context, input string) (any, error)
+
+// newInferenceFunc returns the function running the model on an input,
+// with the options.
+func newInferenceFunc(m any, o inferenceOptions) (inferenceFunc, error) {
+	switch m := m.(type) {
+	case text2text.Interface:
+		opts := o.generation
+		return func(ctx context.Context, input string) (any, error) {
+			return m.Generate(ctx, input, &opts)
+		}, nil
+	case zeroshotclassifier.Interface:
+		if len(o.labels) == 0 {
+			return nil, errors.New("the zero-shot-classification task requires the -labels flag")
+		}
+		return func(ctx context.Context, input string) (any, error) {
+			return m.Classify(ctx, input, zeroshotclassifier.Parameters{CandidateLabels: o.labels})
+		}, nil
+	case questionanswering.Interface:
+		if o.question == "" && !o.recordQuestions {
+			return nil, errors.New("the question-answering task requires the -question flag")
+		}
+		return func(ctx context.Context, input string) (any, error) {
+			question := o.question
+			if rec, ok := batch.RecordFromContext(ctx); ok && question == "" {
+				question, _ = rec.Fields["question"].(string)
+			}
+			if question == "" {
+				return nil, errors.New(`missing "question" field`)
+			}
+			return m.Answer(ctx, question, input, &questionanswering.Options{Explain: o.explain})
+		}, nil
+	case textclassification.Interface:
+		return func(ctx context.Context, input string) (any, error) {
+			pair := o.textPair
+			if rec, ok := batch.RecordFromContext(ctx); ok && pair == "" {
+				pair, _ = rec.Fields["text_pair"].(string)
+			}
+			return m.Classify(ctx, input, textclassification.Parameters{
+				TextPair: pair,
+				Layers:   o.layers,
+				Explain:  o.explain,
+			})
+		}, nil
+	case tokenclassification.Interface:
+		strategy := tokenclassification.AggregationStrategySimple
+		if o.nested {
+			strategy = tokenclassification.AggregationStrategyNested
+		}
+		return func(ctx context.Context, input string) (any, error) {
+			return m.Classify(ctx, input, tokenclassification.Parameters{AggregationStrategy: strategy})
+		}, nil
+	case textencoding.Interface:
+		return func(ctx context.Context, input string) (any, error) {
+			resp, err := m.Encode(ctx, input, o.poolingStrategy)
+			if err != nil {
+				return nil, err
+			}
+			return struct{ Vector []float32 }{resp.Vector.Data().F32()}, nil
+		}, nil
+	case languagemodeling.Interface:
+		return func(ctx context.Context, input string) (any, error) {
+			return m.Predict(ctx, input, languagemodeling.Parameters{K: o.k})
+		}, nil
+	case coreference.Interface:
+		return func(ctx context.Context, input string) (any, error) {
+			return m.Resolve(ctx, input)
+		}, nil
+	case relationextraction.Interface:
+		return func(ctx context.Context, input string) (any, error) {
+			return m.Extract(ctx, input, relationextraction.Parameters{})
+		}, nil
+	case sentencesegmentation.Interface:
+		return func(ctx context.Context, input string) (any, error) {
+			return m.Segment(ctx, input)
+		}, nil
+	case postagging.Interface:
+		return func(ctx context.Context, input string) (any, error) {
+			return m.Tag(ctx, input)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported model type %T", m)
+	}
+}
+
+// loadForInference loads the configured model, and returns it with the
+// function running it.
+func loadForInference(conf *config, o inferenceOptions) (any, inferenceFunc, error) {
+	if len(conf.models) > 0 {
+		return nil, nil, errors.New("multiple models are only supported by the serve, download and convert subcommands")
+	}
+	embeddings, err := openEmbeddingCache(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+	lm, err := loadModel(conf.task, conf.loaderConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	m := withEmbeddingCache(lm.model, embeddings, lm.id())
+	infer, err := newInferenceFunc(m, o)
+	if err != nil {
+		tasks.Finalize(m)
+		return nil, nil, err
+	}
+	return m, infer, nil
+}
+
+// Input formats of the run subcommand.
+const (
+	// textInputFormat is one input per line.
+	textInputFormat = "text"
+	// jsonlInputFormat is one JSON object per line, with the input in the
+	// "input" field.
+	jsonlInputFormat = "jsonl"
+)
+
+// runOptions are the options of the run subcommand.
+type runOptions struct {
+	inputs      []string
+	inputFormat string
+	output      string
+}
+
+// bind binds the options to the flag set.
+func (o *runOptions) bind(fs *flag.FlagSet) {
+	fs.Func("input", `files to read the inputs from (comma separated, "-" for the standard input)`,
+		flagParseFunc(parseCommaSplit, &o.inputs))
+	fs.Func("input-format", `format of the input files ("text"|"jsonl", default "jsonl" for the ".jsonl" files and "text" otherwise)`,
+		flagParseFunc(parseInputFormat, &o.inputFormat))
+	fs.Func("output", "file to write the results to (default the standard output)", flagAssignFunc(&o.output))
+}
+
+// parseInputFormat parses an input format of the run subcommand.
+func parseInputFormat(s string) (string, error) {
+	switch s {
+	case textInputFormat, jsonlInputFormat:
+		return s, nil
+	default:
+		return "", fmt.Errorf("invalid input format value %#v", s)
+	}
+}
+
+// runInference runs the model once on each input, and writes the results as
+// JSON lines. The inputs are given as arguments or, if none, read from the
+// input files or the standard input, either as lines of text or as JSON
+// lines. In the latter case, each result is written as the JSON object of
+// the input, with the result in the "output" field, so that any other field,
+// e.g. an ID, is carried over.
+func runInference(args []string) error {
+	var o inferenceOptions
+	var ro runOptions
+	conf, fs, err := parseConfig("run", args, func(_ *config, fs *flag.FlagSet) {
+		o.bind(fs)
+		ro.bind(fs)
+	})
+	if err != nil {
+		return err
+	}
+	m, infer, err := loadForInference(conf, o)
+	if err != nil {
+		return err
+	}
+	defer tasks.Finalize(m)
+
+	out := os.Stdout
+	if ro.output != "" {
+		f, err := os.Create(ro.output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+	r := &inferenceRunner{ctx: context.Background(), infer: infer, enc: json.NewEncoder(w)}
+
+	switch {
+	case fs.NArg() > 0:
+		for _, input := range fs.Args() {
+			if err := r.runText(input); err != nil {
+				return err
+			}
+		}
+	case len(ro.inputs) > 0:
+		for _, filename := range ro.inputs {
+			if err := r.runFile(filename, ro.inputFormat); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := r.runReader(os.Stdin, ro.inputFormat); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// inferenceRunner runs the model on the inputs of the run subcommand, and
+// writes the results.
+type inferenceRunner struct {
+	ctx   context.Context
+	infer inferenceFunc
+	enc   *json.Encoder
+}
+
+// runFile runs the model on the inputs of the file, "-" being the standard
+// input.
+func (r *inferenceRunner) runFile(filename, format string) error {
+	if filename == "-" {
+		return r.runReader(os.Stdin, format)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if format == "" && strings.HasSuffix(filename, ".jsonl") {
+		format = jsonlInputFormat
+	}
+	if err := r.runReader(f, format); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	return nil
+}
+
+// runReader runs the model on each non-empty line of the reader.
+func (r *inferenceRunner) runReader(rd io.Reader, format string) error {
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		input := strings.TrimSpace(scanner.Text())
+		if input == "" {
+			continue
+		}
+		run := r.runText
+		if format == jsonlInputFormat {
+			run = r.runJSON
+		}
+		if err := run(input); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// runText runs the model on the input text, and writes the result.
+func (r *inferenceRunner) runText(input string) error {
+	result, err := r.infer(r.ctx, input)
+	if err != nil {
+		return fmt.Errorf("%q: %w", input, err)
+	}
+	return r.enc.Encode(result)
+}
+
+// runJSON runs the model on the "input" field of the JSON object, and writes
+// the object with the result in the "output" field.
+func (r *inferenceRunner) runJSON(line string) error {
+	var record map[string]any
+	if err := json.Unmarshal([]byte(line), &record); err != nil {
+		return err
+	}
+	input, ok := record["input"].(string)
+	if !ok {
+		return errors.New(`missing string field "input"`)
+	}
+	result, err := r.infer(r.ctx, input)
+	if err != nil {
+		return fmt.Errorf("%q: %w", input, err)
+	}
+	record["output"] = result
+	return r.enc.Encode(record)
+}
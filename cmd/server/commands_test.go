@@ -0,0 +1,209 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unsetEnv unsets the environment variables for the duration of the test.
+func unsetEnv(t *testing.T, keys ...string) {
+	for _, key := range keys {
+		t.Setenv(key, "") // restores the value after the test
+		require.NoError(t, os.Unsetenv(key))
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name           string
+		env            map[string]string
+		file           string
+		args           []string
+		wantAddress    string
+		wantModel      string
+		wantConversion tasks.ConversionPolicy
+	}{
+		{
+			name:           "defaults",
+			wantAddress:    addrRandomPort,
+			wantConversion: tasks.ConvertAlways, // set by the setup function
+		},
+		{
+			name:           "file",
+			file:           "address: :1000\nmodel: file/model\nmodel-conversion: never",
+			wantAddress:    ":1000",
+			wantModel:      "file/model",
+			wantConversion: tasks.ConvertNever,
+		},
+		{
+			name:           "env over file",
+			env:            map[string]string{"CYBERTRON_ADDRESS": ":2000"},
+			file:           "address: :1000\nmodel: file/model",
+			wantAddress:    ":2000",
+			wantModel:      "file/model",
+			wantConversion: tasks.ConvertAlways,
+		},
+		{
+			name:           "flags over env",
+			env:            map[string]string{"CYBERTRON_ADDRESS": ":2000", "CYBERTRON_MODEL": "env/model"},
+			file:           "address: :1000",
+			args:           []string{"-address", ":3000", "-model-conversion", "missing"},
+			wantAddress:    ":3000",
+			wantModel:      "env/model",
+			wantConversion: tasks.ConvertMissing,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unsetEnv(t, "CYBERTRON_CONFIG", "CYBERTRON_ADDRESS", "CYBERTRON_MODEL", "CYBERTRON_MODEL_CONVERSION")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeConfigFile(t, "cybertron.yaml", tt.file)}, args...)
+			}
+			conf, _, err := parseConfig("test", args, func(conf *config, _ *flag.FlagSet) {
+				conf.loaderConfig.ConversionPolicy = tasks.ConvertAlways
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantAddress, conf.serverConfig.Address)
+			assert.Equal(t, tt.wantModel, conf.loaderConfig.ModelName)
+			assert.Equal(t, tt.wantConversion, conf.loaderConfig.ConversionPolicy)
+		})
+	}
+}
+
+func TestParseConfig_Lazy(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		file string
+		args []string
+		want bool
+	}{
+		{name: "default"},
+		{name: "file", file: "model-lazy: true", want: true},
+		{name: "env", env: map[string]string{"CYBERTRON_MODEL_LAZY": "true"}, want: true},
+		{name: "flag over env", env: map[string]string{"CYBERTRON_MODEL_LAZY": "true"}, args: []string{"-model-lazy", "false"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unsetEnv(t, "CYBERTRON_CONFIG", "CYBERTRON_MODEL_LAZY")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeConfigFile(t, "cybertron.yaml", tt.file)}, args...)
+			}
+			conf, _, err := parseConfig("test", args, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, conf.loaderConfig.Lazy)
+		})
+	}
+}
+
+func TestParseConfig_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+	}{
+		{name: "help", args: []string{"-h"}},
+		{name: "unknown flag", args: []string{"-unknown"}},
+		{name: "invalid flag", args: []string{"-model-conversion", "sometimes"}},
+		{name: "invalid env", env: map[string]string{"CYBERTRON_MODEL_CONVERSION": "sometimes"}},
+		{name: "missing config file", args: []string{"-config", "missing.yaml"}},
+		{name: "invalid lazy flag", args: []string{"-model-lazy", "maybe"}},
+		{name: "invalid lazy env", env: map[string]string{"CYBERTRON_MODEL_LAZY": "maybe"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unsetEnv(t, "CYBERTRON_CONFIG", "CYBERTRON_MODEL_CONVERSION", "CYBERTRON_MODEL_LAZY")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, _, err := parseConfig("test", tt.args, func(_ *config, f *flag.FlagSet) {
+				f.SetOutput(nopWriter{})
+			})
+			assert.Error(t, err)
+		})
+	}
+}
+
+// nopWriter discards the usage of the flag sets.
+type nopWriter struct{}
+
+func (nopWriter) Write(p []byte) (int, error) { return len(p), nil }
+
+func TestModelConfigs(t *testing.T) {
+	base := &tasks.Config{ModelName: "base", Revision: "main"}
+
+	configs, err := modelConfigs(&config{loaderConfig: base})
+	require.NoError(t, err)
+	assert.Equal(t, []*tasks.Config{base}, configs, "the configured model without a manifest")
+
+	v2 := "v2"
+	configs, err = modelConfigs(&config{loaderConfig: base, models: []manifestModel{
+		{Task: "text-encoding", Model: "org/encoder"},
+		{Task: "text-classification", Model: "ensemble", Ensemble: []manifestModel{
+			{Model: "org/a"},
+			{Model: "org/b", Revision: &v2},
+		}},
+	}})
+	require.NoError(t, err)
+	var names, revisions []string
+	for _, c := range configs {
+		names = append(names, c.ModelName)
+		revisions = append(revisions, c.Revision)
+	}
+	assert.Equal(t, []string{"org/encoder", "org/a", "org/b"}, names, "the members of the ensembles are prepared")
+	assert.Equal(t, []string{"main", "main", "v2"}, revisions)
+
+	invalid := "invalid"
+	_, err = modelConfigs(&config{loaderConfig: base, models: []manifestModel{
+		{Task: "text-encoding", Model: "org/encoder", Backend: &invalid},
+	}})
+	assert.Error(t, err)
+}
+
+func TestNewInferenceFunc(t *testing.T) {
+	tests := []struct {
+		name    string
+		model   any
+		opts    inferenceOptions
+		wantErr bool
+	}{
+		{name: "text encoding", model: struct{ textencoding.Interface }{}},
+		{name: "unsupported model", model: struct{}{}, wantErr: true},
+		{name: "zero-shot without labels", model: struct{ zeroshotclassifier.Interface }{}, wantErr: true},
+		{name: "zero-shot", model: struct{ zeroshotclassifier.Interface }{}, opts: inferenceOptions{labels: []string{"a", "b"}}},
+		{name: "question answering without question", model: struct{ questionanswering.Interface }{}, wantErr: true},
+		{name: "question answering", model: struct{ questionanswering.Interface }{}, opts: inferenceOptions{question: "why?"}},
+		{name: "question answering with record questions", model: struct{ questionanswering.Interface }{}, opts: inferenceOptions{recordQuestions: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newInferenceFunc(tt.model, tt.opts)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, f)
+		})
+	}
+}
@@ -8,12 +8,18 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/nlpodyssey/cybertron/pkg/logging"
+	"github.com/nlpodyssey/cybertron/pkg/onnx"
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
 	"github.com/nlpodyssey/cybertron/pkg/server"
 	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/textnorm"
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 )
 
 // TaskType is the task type.
@@ -27,6 +33,10 @@ const (
 	TokenClassificationTask    TaskType = "token-classification"
 	TextEncodingTask           TaskType = "text-encoding"
 	LanguageModelingTask       TaskType = "language-modeling"
+	CoreferenceResolutionTask  TaskType = "coreference-resolution"
+	RelationExtractionTask     TaskType = "relation-extraction"
+	SentenceSegmentationTask   TaskType = "sentence-segmentation"
+	POSTaggingTask             TaskType = "pos-tagging"
 )
 
 // TaskTypeValues is the list of supported task types.
@@ -38,6 +48,10 @@ var TaskTypeValues = []TaskType{
 	TokenClassificationTask,
 	TextEncodingTask,
 	LanguageModelingTask,
+	CoreferenceResolutionTask,
+	RelationExtractionTask,
+	SentenceSegmentationTask,
+	POSTaggingTask,
 }
 
 // ParseTaskType parses a task type.
@@ -52,25 +66,78 @@ func ParseTaskType(s string) (TaskType, error) {
 
 // config represents the configuration of the server.
 type config struct {
-	task         TaskType
-	loaderConfig *tasks.Config
-	serverConfig *server.Config
+	task           TaskType
+	modelsManifest string
+	models         []manifestModel
+	updateInterval time.Duration
+	// evaluationDatasets are the golden datasets the models of their tasks
+	// are evaluated on, before being swapped, and by the admin API.
+	evaluationDatasets  []string
+	evaluationTolerance float64
+	// responseCache is the URL of the response cache, if enabled.
+	responseCache     string
+	responseCacheSize int
+	responseCacheTTL  time.Duration
+	coalesceRequests  bool
+	// embeddingCacheDir is the directory of the embedding cache, if enabled.
+	embeddingCacheDir  string
+	embeddingCacheSize int
+	// tenants is the JSON file of the tenants, if any.
+	tenants string
+	// jobStore is the URL of the store of the async jobs, if any.
+	jobStore string
+	// workQueue is the URL of the work queue, if any.
+	workQueue string
+	// preflight is whether the preflight checks are run before serving.
+	preflight bool
+	// auditLog is the file of the audit log, if enabled.
+	auditLog       string
+	auditMaxLength int
+	auditRedact    []string
+	configFile     string
+	printConfig    bool
+	loaderConfig   *tasks.Config
+	serverConfig   *server.Config
+	// logging is the configuration of the logs, applied once parsed.
+	logging logging.Config
+	// slowRequestThreshold is the latency beyond which the requests are
+	// logged as slow, if set.
+	slowRequestThreshold time.Duration
+	// progress holds the progress of the loading of the models, for the
+	// admin API and the terminal.
+	progress *progressAdmin
 }
 
 // loadEnv loads config values from environment variables.
 func (conf *config) loadEnv() error {
-	if v, ok := os.LookupEnv("CYBERTRON_LOGLEVEL"); ok {
-		l, err := zerolog.ParseLevel(v)
-		if err != nil {
-			log.Warn().Err(err).Msg("failed to parse env var CYBERTRON_LOGLEVEL")
-		}
-		zerolog.SetGlobalLevel(l)
+	lc := &conf.logging
+	if err := lookupEnvAndParse("LOGLEVEL", zerolog.ParseLevel, &lc.Level); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("LOG_FORMAT", logging.ParseFormat, &lc.Format); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("LOG_SAMPLING", strconv.Atoi, &lc.Sampling); err != nil {
+		return err
 	}
+	lookupEnv("LOG_FILE", &lc.File)
+	if err := lookupEnvAndParse("LOG_FILE_MAX_SIZE", strconv.Atoi, &lc.FileMaxSize); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("LOG_FILE_MAX_BACKUPS", strconv.Atoi, &lc.FileMaxBackups); err != nil {
+		return err
+	}
+	lookupEnv("LOG_SYSLOG", &lc.Syslog)
 
 	mm := conf.loaderConfig
 	lookupEnv("MODELS_DIR", &mm.ModelsDir)
 	lookupEnv("MODEL", &mm.ModelName)
 	lookupEnv("HUB_ACCESS_TOKEN", &mm.HubAccessToken)
+	lookupEnv("HUB_ENDPOINT", &mm.HubEndpoint)
+	lookupEnv("HTTP_PROXY", &mm.HTTPProxy)
+	lookupEnv("CA_BUNDLE", &mm.CABundle)
+	lookupEnv("MODEL_REVISION", &mm.Revision)
+	lookupEnv("MODEL_BUNDLE", &mm.Bundle)
 	if err := lookupEnvAndParse("MODEL_DOWNLOAD", tasks.ParseDownloadPolicy, &mm.DownloadPolicy); err != nil {
 		return err
 	}
@@ -80,9 +147,110 @@ func (conf *config) loadEnv() error {
 	if err := lookupEnvAndParse("MODEL_CONVERSION_PRECISION", tasks.ParseFloatPrecision, &mm.ConversionPrecision); err != nil {
 		return err
 	}
+	if err := lookupEnvAndParse("MODEL_CONVERSION_QUANTIZATION", quantization.ParseScheme, &mm.ConversionQuantization); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("MODEL_CONVERSION_GGUF", parseBool, &mm.ConversionGGUF); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("MODEL_CONVERSION_VERIFICATION", parseBool, &mm.ConversionVerification); err != nil {
+		return err
+	}
+	lookupEnv("MODEL_STORE", &mm.ModelStore)
+	if err := lookupEnvAndParse("MODEL_STORE_PUSH", parseBool, &mm.ModelStorePush); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("OFFLINE", parseBool, &mm.Offline); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("MODEL_BACKEND", tasks.ParseBackend, &mm.Backend); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("MODEL_REPLICAS", strconv.Atoi, &mm.Replicas); err != nil {
+		return err
+	}
+	lookupEnv("MODEL_NUMA_NODES", &mm.NUMANodes)
+	lookupEnv("MODEL_DEVICE", &mm.Device)
+	if err := lookupEnvAndParse("MODEL_INTRA_OP_PARALLELISM", strconv.Atoi, &mm.IntraOpParallelism); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("MODEL_INTER_OP_PARALLELISM", strconv.Atoi, &mm.InterOpParallelism); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("MODEL_PRIORITY_WEIGHT", strconv.Atoi, &mm.PriorityWeight); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("MODEL_PREEMPTION", parseBool, &mm.Preemption); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("MODEL_LAZY", parseBool, &mm.Lazy); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("MODEL_PRECOMPUTE_POSITIONS", parseBool, &mm.PrecomputePositions); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("MODEL_MEMORY_LIMIT", strconv.Atoi, &mm.MemoryLimit); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("MODEL_ATTENTION_WINDOW", strconv.Atoi, &mm.AttentionWindow); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("MODEL_KERNEL_TUNING", parseBool, &mm.KernelTuning); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("MODEL_ROPE_SCALING", onnx.ParseRopeScaling, &mm.RopeScaling); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("MODEL_TIMEOUT", time.ParseDuration, &mm.Timeout); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("MODEL_NORMALIZATION", textnorm.ParseOptions, &mm.Normalization); err != nil {
+		return err
+	}
+	lookupEnv("MODEL_CALIBRATION", &mm.Calibration)
+	if err := lookupEnvAndParse("MODEL_ADAPTERS", parseAdapters, &mm.Adapters); err != nil {
+		return err
+	}
 	if err := lookupEnvAndParse("MODEL_TASK", ParseTaskType, &conf.task); err != nil {
 		return err
 	}
+	lookupEnv("MODELS_MANIFEST", &conf.modelsManifest)
+	if err := lookupEnvAndParse("MODEL_UPDATE_INTERVAL", time.ParseDuration, &conf.updateInterval); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("EVALUATION_DATASETS", parseCommaSplit, &conf.evaluationDatasets); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("EVALUATION_TOLERANCE", parseFloat, &conf.evaluationTolerance); err != nil {
+		return err
+	}
+	lookupEnv("RESPONSE_CACHE", &conf.responseCache)
+	if err := lookupEnvAndParse("RESPONSE_CACHE_SIZE", strconv.Atoi, &conf.responseCacheSize); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("RESPONSE_CACHE_TTL", time.ParseDuration, &conf.responseCacheTTL); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("COALESCE_REQUESTS", parseBool, &conf.coalesceRequests); err != nil {
+		return err
+	}
+	lookupEnv("EMBEDDING_CACHE_DIR", &conf.embeddingCacheDir)
+	if err := lookupEnvAndParse("EMBEDDING_CACHE_SIZE", strconv.Atoi, &conf.embeddingCacheSize); err != nil {
+		return err
+	}
+	lookupEnv("AUDIT_LOG", &conf.auditLog)
+	if err := lookupEnvAndParse("AUDIT_MAX_LENGTH", strconv.Atoi, &conf.auditMaxLength); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("AUDIT_REDACT", parseCommaSplit, &conf.auditRedact); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("SLOW_REQUEST_THRESHOLD", time.ParseDuration, &conf.slowRequestThreshold); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("PREFLIGHT", parseBool, &conf.preflight); err != nil {
+		return err
+	}
 
 	s := conf.serverConfig
 	lookupEnv("NETWORK", &s.Network)
@@ -95,6 +263,39 @@ func (conf *config) loadEnv() error {
 	}
 	lookupEnv("TLS_CERT", &s.TLSCert)
 	lookupEnv("TLS_KEY", &s.TLSKey)
+	lookupEnv("NATS_URL", &s.NATS.URL)
+	lookupEnv("NATS_SUBJECT_PREFIX", &s.NATS.SubjectPrefix)
+	lookupEnv("NATS_QUEUE", &s.NATS.Queue)
+	if err := lookupEnvAndParse("NATS_WORKERS", strconv.Atoi, &s.NATS.Workers); err != nil {
+		return err
+	}
+	lookupEnv("NATS_STREAM", &s.NATS.Stream)
+	lookupEnv("NATS_CONSUMER", &s.NATS.Consumer)
+	lookupEnv("NATS_RESULTS_PREFIX", &s.NATS.ResultsPrefix)
+	lookupEnv("WORK_QUEUE", &conf.workQueue)
+	if err := lookupEnvAndParse("WORK_QUEUE_FRONTEND", parseBool, &s.WorkQueue.Frontend); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("WORK_QUEUE_WORKERS", strconv.Atoi, &s.WorkQueue.Workers); err != nil {
+		return err
+	}
+	lookupEnv("TENANTS", &conf.tenants)
+	lookupEnv("TENANT_HEADER", &s.TenantHeader)
+	lookupEnv("ADMIN_KEY", &s.AdminKey)
+	lookupEnv("WEBHOOK_SECRET", &s.WebhookSecret)
+	if err := lookupEnvAndParse("WEBHOOK_ALLOW_PRIVATE", parseBool, &s.WebhookAllowPrivate); err != nil {
+		return err
+	}
+	lookupEnv("JOB_STORE", &conf.jobStore)
+	if err := lookupEnvAndParse("JOB_TTL", time.ParseDuration, &s.JobTTL); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("PLAYGROUND", parseBool, &s.Playground); err != nil {
+		return err
+	}
+	if err := lookupEnvAndParse("API_SUNSET", parseTime, &s.APISunset); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -105,27 +306,125 @@ func (conf *config) loadEnv() error {
 // The flags are defined using FlagSet.Func, so that if a command line flag is
 // not encountered, its related config value is not overridden with any default.
 func (conf *config) bindFlagSet(fs *flag.FlagSet) {
-	fs.Func("loglevel", "zerolog global level", func(v string) error {
-		l, err := zerolog.ParseLevel(v)
-		if err != nil {
-			log.Warn().Err(err).Msg("failed to parse -loglevel flag")
-		}
-		zerolog.SetGlobalLevel(l)
-		return nil
-	})
+	lc := &conf.logging
+	fs.Func("loglevel", `minimum level of the logs ("trace"|"debug"|"info"|"warn"|"error"|"fatal"|"panic"|"disabled")`,
+		flagParseFunc(zerolog.ParseLevel, &lc.Level))
+	fs.Func("log-format", `format of the logs of the standard error ("console"|"json")`,
+		flagParseFunc(logging.ParseFormat, &lc.Format))
+	fs.Func("log-sampling", `log one of every N debug and info messages, the warnings and the errors being all logged (default 1)`,
+		flagParseFunc(strconv.Atoi, &lc.Sampling))
+	fs.Func("log-file", `file the logs are also appended to, as JSON lines, rotated by size (optional)`,
+		flagAssignFunc(&lc.File))
+	fs.Func("log-file-max-size", `size in MiB of the log file beyond which it's rotated (default 100)`,
+		flagParseFunc(strconv.Atoi, &lc.FileMaxSize))
+	fs.Func("log-file-max-backups", `number of rotated log files kept (default 5)`,
+		flagParseFunc(strconv.Atoi, &lc.FileMaxBackups))
+	fs.Func("log-syslog", `syslog daemon the logs are also sent to: "local", or its address, e.g. "udp://logs.example.com:514" (optional)`,
+		flagAssignFunc(&lc.Syslog))
+	fs.Func("config", "path of a YAML (or TOML, with the .toml extension) file setting the values of these flags, and the models to load (optional, default $CYBERTRON_CONFIG)",
+		flagAssignFunc(&conf.configFile))
+	fs.BoolVar(&conf.printConfig, "print-config", false, "print the effective configuration, in the format of the configuration file, and exit")
 
 	mm := conf.loaderConfig
 	fs.Func("models-dir", "models's base directory", flagAssignFunc(&mm.ModelsDir))
-	fs.Func("model", "model name (and sub-path of models-dir)", flagAssignFunc(&mm.ModelName))
-	fs.Func("hub-access-token", `access token to download private models from the Hugging Face Hub (optional)`, flagAssignFunc(&mm.HubAccessToken))
+	fs.Func("model", "model name (and sub-path of models-dir), or rule-based for the rule-based sentence-segmentation and pos-tagging", flagAssignFunc(&mm.ModelName))
+	fs.Func("hub-access-token", `access token to download private and gated models from the Hugging Face Hub (optional, default $HF_TOKEN)`, flagAssignFunc(&mm.HubAccessToken))
 	fs.Func("model-download", `model downloading policy ("always"|"missing"|"never")`,
 		flagParseFunc(tasks.ParseDownloadPolicy, &mm.DownloadPolicy))
 	fs.Func("model-conversion", `model conversion policy ("always"|"missing"|"never")`,
 		flagParseFunc(tasks.ParseConversionPolicy, &mm.ConversionPolicy))
 	fs.Func("model-conversion-precision", `floating-point bits of precision to use if the model is converted ("32"|"64")`,
 		flagParseFunc(tasks.ParseFloatPrecision, &mm.ConversionPrecision))
-	fs.Func("task", `type of inference/computation that the model can fulfill ("text2text"|"zero-shot-classification"|"question-answering"|"text-classification"|"token-classification"|"text-encoding"|"language-modeling")`,
+	fs.Func("model-conversion-quantization", `quantization scheme to apply to the weights if the model is converted ("none"|"int8"|"float16"|"bfloat16")`,
+		flagParseFunc(quantization.ParseScheme, &mm.ConversionQuantization))
+	fs.Func("model-conversion-gguf", `whether to also convert the model to GGUF, for llama.cpp-ecosystem tools ("true"|"false")`,
+		flagParseFunc(parseBool, &mm.ConversionGGUF))
+	fs.Func("model-conversion-verification", `whether to verify the model against reference outputs after loading ("true"|"false")`,
+		flagParseFunc(parseBool, &mm.ConversionVerification))
+	fs.Func("hub-endpoint", `URL of the Hugging Face Hub or of a mirror (optional, default $HF_ENDPOINT)`,
+		flagAssignFunc(&mm.HubEndpoint))
+	fs.Func("http-proxy", `URL of the HTTP(S) or SOCKS5 proxy for downloads (optional, default $HTTPS_PROXY)`,
+		flagAssignFunc(&mm.HTTPProxy))
+	fs.Func("ca-bundle", `PEM file of additional CA certificates to trust for downloads (optional)`,
+		flagAssignFunc(&mm.CABundle))
+	fs.Func("model-revision", `branch, tag or commit SHA of the model to download (default "main")`,
+		flagAssignFunc(&mm.Revision))
+	fs.Func("model-bundle", "path of a model bundle to verify and load, instead of downloading and converting the model",
+		flagAssignFunc(&mm.Bundle))
+	fs.Func("model-store", `URL of the object store to pull converted models from ("file://..."|"s3://..."|"gs://..."|"az://...")`,
+		flagAssignFunc(&mm.ModelStore))
+	fs.Func("model-store-push", `whether to push the converted model to the object store ("true"|"false")`,
+		flagParseFunc(parseBool, &mm.ModelStorePush))
+	fs.Func("offline", `whether to load the model from the local cache only, without network access ("true"|"false")`,
+		flagParseFunc(parseBool, &mm.Offline))
+	fs.Func("model-backend", `engine used to run the model ("spago"|"onnx")`,
+		flagParseFunc(tasks.ParseBackend, &mm.Backend))
+	fs.Func("model-replicas", `number of copies of the model loaded to serve the requests in parallel, each running one forward pass at a time (default 1)`,
+		flagParseFunc(strconv.Atoi, &mm.Replicas))
+	fs.Func("model-numa-nodes", `NUMA nodes the replicas of the model are placed on, round-robin, each with its own copy of the weights, serving the requests on the CPUs of its node, on Linux ("all", or a list of node IDs, e.g. "0-1", default none)`,
+		flagAssignFunc(&mm.NUMANodes))
+	fs.Func("model-device", `device running the large matrix products of the model, with the onnx backend built with its tag ("cpu"|"cuda")`,
+		flagAssignFunc(&mm.Device))
+	fs.Func("model-intra-op-parallelism", `maximum number of goroutines used within a single request, e.g. by the matrix products of the onnx backend`,
+		flagParseFunc(strconv.Atoi, &mm.IntraOpParallelism))
+	fs.Func("model-inter-op-parallelism", `maximum number of requests served concurrently by each replica of the model (default unlimited, or 1 with more than one replica)`,
+		flagParseFunc(strconv.Atoi, &mm.InterOpParallelism))
+	fs.Func("model-priority-weight", `number of interactive requests served for each batch request while both are waiting for a replica (default 4)`,
+		flagParseFunc(strconv.Atoi, &mm.PriorityWeight))
+	fs.Func("model-preemption", `whether the batch text generations are preempted, failing with ABORTED, when an interactive request waits for a replica ("true"|"false", default "false")`,
+		flagParseFunc(parseBool, &mm.Preemption))
+	fs.Func("model-lazy", `whether the model is loaded, downloaded and converted on its first request, which waits for it, instead of at startup ("true"|"false", default "false")`,
+		flagParseFunc(parseBool, &mm.Lazy))
+	fs.Func("model-precompute-positions", `whether the embeddings of all the positions, up to the maximum length of the model, and of the token types are read at load time, instead of on their first use, with the spago backend ("true"|"false", default "false")`,
+		flagParseFunc(parseBool, &mm.PrecomputePositions))
+	fs.Func("model-memory-limit", `maximum memory in MiB of the intermediate tensors of a request of the onnx backend, beyond which it fails with RESOURCE_EXHAUSTED (default unlimited)`,
+		flagParseFunc(strconv.Atoi, &mm.MemoryLimit))
+	fs.Func("model-attention-window", `maximum distance between the tokens attending to each other with the onnx backend, approximating the full attention of long inputs in linear time (default 0, the full attention)`,
+		flagParseFunc(strconv.Atoi, &mm.AttentionWindow))
+	fs.Func("model-kernel-tuning", `whether the fastest kernel of the matrix products of the onnx backend (pure Go, SIMD or BLAS) is selected for each shape of the weights by benchmarking them at load time, cached in the model directory ("true"|"false", default "false")`,
+		flagParseFunc(parseBool, &mm.KernelTuning))
+	fs.Func("model-rope-scaling", `scaling of the rotary position embeddings of the onnx models having them, extending their maximum input length by the factor ("none"|"linear:<factor>"|"ntk:<factor>"|"yarn:<factor>")`,
+		flagParseFunc(onnx.ParseRopeScaling, &mm.RopeScaling))
+	fs.Func("model-timeout", `maximum time to serve a request of the model, beyond which it fails with DEADLINE_EXCEEDED (e.g. "30s", default "0" for no timeout)`,
+		flagParseFunc(time.ParseDuration, &mm.Timeout))
+	fs.Func("model-normalization", `comma-separated normalization of the input texts before their tokenization, the offsets of the responses referring to the original texts ("nfc"|"nfkc", "strip-control", "collapse-whitespace", "lowercase", default "none")`,
+		flagParseFunc(textnorm.ParseOptions, &mm.Normalization))
+	fs.Func("model-calibration", `JSON file with the temperature or the Platt scaling calibrating the probabilities of the classifiers, or "none" (default the calibration.json file in the model directory, if any)`,
+		flagAssignFunc(&mm.Calibration))
+	fs.Func("model-adapters", `comma-separated LoRA adapters of PEFT applied on top of the BERT model, sharing its weights, each as name=directory; the adapter serving a request is named by its Cybertron-Adapter header (optional)`,
+		flagParseFunc(parseAdapters, &mm.Adapters))
+	fs.Func("task", `type of inference/computation that the model can fulfill ("text2text"|"zero-shot-classification"|"question-answering"|"text-classification"|"token-classification"|"text-encoding"|"language-modeling"|"coreference-resolution"|"relation-extraction"|"sentence-segmentation"|"pos-tagging")`,
 		flagParseFunc(ParseTaskType, &conf.task))
+	fs.Func("models-manifest", "path of a JSON file listing the models to load at startup, each with its task and options (instead of -model and -task)",
+		flagAssignFunc(&conf.modelsManifest))
+	fs.Func("model-update-interval", `interval between checks for new revisions of the models on the Hub, which are hot-swapped once converted and checked (e.g. "1h", default "0" for never)`,
+		flagParseFunc(time.ParseDuration, &conf.updateInterval))
+	fs.Func("evaluation-datasets", `golden datasets (comma separated files) the models of their tasks are evaluated on before being updated, and at /admin/evaluation (optional)`,
+		flagParseFunc(parseCommaSplit, &conf.evaluationDatasets))
+	fs.Func("evaluation-tolerance", `maximum drop of the metrics of the evaluation allowed from the ones of the served model (default 0.01)`,
+		flagParseFunc(parseFloat, &conf.evaluationTolerance))
+	fs.Func("response-cache", `cache of the responses to repeated identical requests ("memory"|"redis://[:password@]host[:port][/db]", optional)`,
+		flagAssignFunc(&conf.responseCache))
+	fs.Func("response-cache-size", `maximum number of responses of the "memory" cache (default 10000)`,
+		flagParseFunc(strconv.Atoi, &conf.responseCacheSize))
+	fs.Func("response-cache-ttl", `time to live of the cached responses (e.g. "1h", default "0" for no expiration)`,
+		flagParseFunc(time.ParseDuration, &conf.responseCacheTTL))
+	fs.Func("coalesce-requests", `whether the concurrent identical requests share a single inference ("true"|"false", default "true")`,
+		flagParseFunc(parseBool, &conf.coalesceRequests))
+	fs.Func("embedding-cache-dir", `directory of a persistent cache of the embeddings of the text-encoding models, surviving restarts (optional)`,
+		flagAssignFunc(&conf.embeddingCacheDir))
+	fs.Func("embedding-cache-size", `maximum size of the embedding cache in MiB, beyond which the least recently used embeddings are evicted (default 1024)`,
+		flagParseFunc(strconv.Atoi, &conf.embeddingCacheSize))
+	fs.Func("audit-log", `file the audit log of the requests is appended to, as JSON lines, or "-" for the standard output (optional)`,
+		flagAssignFunc(&conf.auditLog))
+	fs.Func("audit-max-length", `maximum length, in characters, of the requests and responses in the audit log (default 1000, -1 for no limit)`,
+		flagParseFunc(strconv.Atoi, &conf.auditMaxLength))
+	fs.Func("audit-redact", `personal information removed from the audit log (comma separated "email"|"phone"|"card"|"ip", optional)`,
+		flagParseFunc(parseCommaSplit, &conf.auditRedact))
+	fs.Func("slow-request-threshold", `latency beyond which the requests are logged as slow, with their input length, parameters and the time spent in their stages (e.g. "500ms", optional)`,
+		flagParseFunc(time.ParseDuration, &conf.slowRequestThreshold))
+	fs.Func("preflight", `whether the configuration, the files of the models, the address and the memory are checked before loading the models, failing with all the problems found ("true"|"false", default "true")`,
+		flagParseFunc(parseBool, &conf.preflight))
 
 	s := conf.serverConfig
 	fs.Func("network", "network type for server listening", flagAssignFunc(&s.Network))
@@ -136,6 +435,44 @@ func (conf *config) bindFlagSet(fs *flag.FlagSet) {
 		flagParseFunc(parseBool, &s.TLSEnabled))
 	fs.Func("tls-cert", "TLS cert filename", flagAssignFunc(&s.TLSCert))
 	fs.Func("tls-key", "TLS key filename", flagAssignFunc(&s.TLSKey))
+	fs.Func("nats-url", `URL of the NATS server to also serve the requests over NATS (optional, e.g. "nats://127.0.0.1:4222")`,
+		flagAssignFunc(&s.NATS.URL))
+	fs.Func("nats-subject-prefix", `prefix of the NATS subjects "<prefix>.<service>.<method>" of the requests (default "cybertron")`,
+		flagAssignFunc(&s.NATS.SubjectPrefix))
+	fs.Func("nats-queue", `NATS queue group sharing the requests among the servers (default "cybertron")`,
+		flagAssignFunc(&s.NATS.Queue))
+	fs.Func("nats-workers", `number of NATS messages processed concurrently (default 1)`,
+		flagParseFunc(strconv.Atoi, &s.NATS.Workers))
+	fs.Func("nats-stream", `JetStream work queue stream to process the messages of (optional)`,
+		flagAssignFunc(&s.NATS.Stream))
+	fs.Func("nats-consumer", `durable pull consumer of the JetStream stream`,
+		flagAssignFunc(&s.NATS.Consumer))
+	fs.Func("nats-results-prefix", `prefix of the NATS subjects the results of the JetStream messages are published to (optional)`,
+		flagAssignFunc(&s.NATS.ResultsPrefix))
+	fs.Func("work-queue", `work queue shared by the servers, whose workers serve the rows of the bulk uploads and async jobs of the frontends ("redis://host[:port][/db]?stream=<key>&group=<name>"|"nats://host[:port]?stream=<name>&consumer=<name>&subject=<prefix>", optional)`,
+		flagAssignFunc(&conf.workQueue))
+	fs.Func("work-queue-frontend", `whether the server is a frontend of the work queue, pushing the rows of its bulk uploads and async jobs to it without loading any model, instead of a worker ("true"|"false", default "false")`,
+		flagParseFunc(parseBool, &s.WorkQueue.Frontend))
+	fs.Func("work-queue-workers", `number of rows of the work queue served concurrently by a worker (default 1)`,
+		flagParseFunc(strconv.Atoi, &s.WorkQueue.Workers))
+	fs.Func("tenants", `path of a JSON file listing the tenants sharing the server, with their API keys, rate limits and token quotas (optional: the server is open if empty)`,
+		flagAssignFunc(&conf.tenants))
+	fs.Func("tenant-header", `header identifying the tenant of a request by its name, set by a trusted proxy, instead of its API key (optional)`,
+		flagAssignFunc(&s.TenantHeader))
+	fs.Func("admin-key", `key authorizing the requests of the admin API, serving the usage of the tenants at /admin/tenants, the evaluation of the models at /admin/evaluation, their adapters at /admin/adapters, the progress of their loading at /admin/progress, and the faults injected at /admin/faults in the builds with the chaos tag, and tracing the inference of the requests carrying it in their Cybertron-Trace header (optional)`,
+		flagAssignFunc(&s.AdminKey))
+	fs.Func("webhook-secret", `key signing the webhooks of the async jobs, with HMAC-SHA256 (optional: the jobs can't have webhooks if empty)`,
+		flagAssignFunc(&s.WebhookSecret))
+	fs.Func("webhook-allow-private", `whether to allow the webhooks to the loopback, private and link-local addresses, e.g. of the services of a private network ("true"|"false", default "false")`,
+		flagParseFunc(parseBool, &s.WebhookAllowPrivate))
+	fs.Func("job-store", `store of the async jobs and of their results, shared by the servers behind a load balancer and surviving restarts ("file:///path/to/dir"|"redis://[:password@]host[:port][/db]"|"s3://bucket/prefix"|"gs://bucket/prefix"|"az://account/container/prefix", optional: kept in memory if empty)`,
+		flagAssignFunc(&conf.jobStore))
+	fs.Func("job-ttl", `time to live of the finished async jobs and of their results (e.g. "1h", default "24h")`,
+		flagParseFunc(time.ParseDuration, &s.JobTTL))
+	fs.Func("playground", `whether to serve the web playground at /playground/, with a form for each task served ("true"|"false")`,
+		flagParseFunc(parseBool, &s.Playground))
+	fs.Func("api-sunset", `time the deprecated versions of the APIs stop being served, reported in their responses until then ("2006-01-02"|RFC 3339, optional: served indefinitely if empty)`,
+		flagParseFunc(parseTime, &s.APISunset))
 }
 
 // lookupEnv looks up the value of the given environment variable and assign it to dest.
@@ -184,6 +521,46 @@ func parseCommaSplit(s string) ([]string, error) {
 	return strings.Split(s, ","), nil
 }
 
+// parseAdapters parses the given string as a comma-separated list of LoRA
+// adapters, each as name=directory.
+func parseAdapters(s string) (map[string]string, error) {
+	adapters := make(map[string]string)
+	for _, item := range strings.Split(s, ",") {
+		name, dir, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok || name == "" || dir == "" {
+			return nil, fmt.Errorf("invalid adapter %#v, expected name=directory", item)
+		}
+		if _, ok := adapters[name]; ok {
+			return nil, fmt.Errorf("duplicate adapter %#v", name)
+		}
+		adapters[name] = dir
+	}
+	return adapters, nil
+}
+
+// formatAdapters returns the adapters as name=directory, sorted by name.
+func formatAdapters(adapters map[string]string) []string {
+	items := make([]string, 0, len(adapters))
+	for name, dir := range adapters {
+		items = append(items, name+"="+dir)
+	}
+	sort.Strings(items)
+	return items
+}
+
+// parseTime parses the given string as a date, at midnight UTC, or as a
+// time in RFC 3339 format.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %#v, expected a date (2006-01-02) or an RFC 3339 time", s)
+	}
+	return t, nil
+}
+
 // parseBool parses the given string as a boolean.
 func parseBool(s string) (bool, error) {
 	switch s {
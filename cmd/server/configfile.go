@@ -0,0 +1,297 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// errConfigPrinted is returned by parseConfig when the -print-config flag is
+// set, once the effective configuration has been printed.
+var errConfigPrinted = errors.New("configuration printed")
+
+// modelsKey is the key of the configuration file listing the models to load,
+// as in the models manifest.
+const modelsKey = "models"
+
+// redacted replaces the secrets in the printed configuration.
+const redacted = "<redacted>"
+
+// applyConfigFile sets the configuration from the YAML configuration file,
+// or the TOML one if its extension is ".toml", whose keys are the names of
+// the command line flags, plus the "models" key, listing the models to load
+// as in the models manifest.
+//
+// Example:
+//
+//	address: 0.0.0.0:8080
+//	allowed-origins: [https://example.com]
+//	model-conversion-quantization: int8
+//	models:
+//	  - task: text-encoding
+//	    model: sentence-transformers/all-MiniLM-L6-v2
+func applyConfigFile(filename string, conf *config, fs *flag.FlagSet) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var values map[string]yaml.Node
+	if strings.EqualFold(filepath.Ext(filename), ".toml") {
+		values, err = decodeTOML(data)
+	} else {
+		err = yaml.Unmarshal(data, &values)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid configuration file %#v: %w", filename, err)
+	}
+	for key, node := range values {
+		if err := applyConfigValue(key, &node, conf, fs); err != nil {
+			return fmt.Errorf("invalid configuration file %#v: %s: %w", filename, key, err)
+		}
+	}
+	return nil
+}
+
+// decodeTOML decodes the TOML configuration file to the YAML nodes of its
+// values, so that they're applied as the ones of a YAML file.
+func decodeTOML(data []byte) (map[string]yaml.Node, error) {
+	var raw map[string]any
+	if err := toml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	values := make(map[string]yaml.Node, len(raw))
+	for key, value := range raw {
+		var node yaml.Node
+		if err := node.Encode(value); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		values[key] = node
+	}
+	return values, nil
+}
+
+// applyConfigValue sets the flag with the given name, or the models, from
+// the value of the configuration file.
+func applyConfigValue(key string, node *yaml.Node, conf *config, fs *flag.FlagSet) error {
+	if key == modelsKey {
+		var models []manifestModel
+		if err := node.Decode(&models); err != nil {
+			return err
+		}
+		if err := validateModels(models); err != nil {
+			return err
+		}
+		conf.models = models
+		return nil
+	}
+	if key == "config" || fs.Lookup(key) == nil {
+		return errors.New("unknown setting")
+	}
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return fs.Set(key, node.Value)
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			return nil
+		}
+		items := make([]string, len(node.Content))
+		for i, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return errors.New("expected a list of scalar values")
+			}
+			items[i] = item.Value
+		}
+		return fs.Set(key, strings.Join(items, ","))
+	default:
+		return errors.New("expected a scalar value or a list of scalar values")
+	}
+}
+
+// lookupConfigFile returns the path of the configuration file, given either
+// by the -config command line flag or by the CYBERTRON_CONFIG environment
+// variable. The flag is looked up before parsing the command line, since
+// the configuration file has a lower priority than the other flags.
+func lookupConfigFile(args []string) string {
+	var filename string
+	lookupEnv("CONFIG", &filename)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue // the value of a flag given as a separate argument
+		}
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-"), "=")
+		if name != "config" {
+			continue
+		}
+		if hasValue {
+			filename = value
+		} else if i+1 < len(args) {
+			filename = args[i+1]
+			i++
+		}
+	}
+	return filename
+}
+
+// writeSettings writes the effective configuration as YAML, in the format of
+// the configuration file. The secrets are redacted.
+func (conf *config) writeSettings(w io.Writer) error {
+	mm := conf.loaderConfig
+	s := conf.serverConfig
+	settings := map[string]any{
+		"loglevel":                      conf.logging.Level.String(),
+		"log-format":                    conf.logging.Format,
+		"log-sampling":                  conf.logging.Sampling,
+		"log-file":                      conf.logging.File,
+		"log-file-max-size":             conf.logging.FileMaxSize,
+		"log-file-max-backups":          conf.logging.FileMaxBackups,
+		"log-syslog":                    conf.logging.Syslog,
+		"models-dir":                    mm.ModelsDir,
+		"model":                         mm.ModelName,
+		"hub-access-token":              redact(mm.HubAccessToken),
+		"hub-endpoint":                  mm.HubEndpoint,
+		"http-proxy":                    mm.HTTPProxy,
+		"ca-bundle":                     mm.CABundle,
+		"model-revision":                mm.Revision,
+		"model-bundle":                  mm.Bundle,
+		"model-download":                mm.DownloadPolicy.String(),
+		"model-conversion":              mm.ConversionPolicy.String(),
+		"model-conversion-precision":    mm.ConversionPrecision.String(),
+		"model-conversion-quantization": mm.ConversionQuantization.String(),
+		"model-conversion-gguf":         mm.ConversionGGUF,
+		"model-conversion-verification": mm.ConversionVerification,
+		"model-store":                   mm.ModelStore,
+		"model-store-push":              mm.ModelStorePush,
+		"offline":                       mm.Offline,
+		"model-backend":                 mm.Backend.String(),
+		"model-replicas":                mm.Replicas,
+		"model-numa-nodes":              mm.NUMANodes,
+		"model-device":                  mm.Device,
+		"model-intra-op-parallelism":    mm.IntraOpParallelism,
+		"model-inter-op-parallelism":    mm.InterOpParallelism,
+		"model-priority-weight":         mm.PriorityWeight,
+		"model-preemption":              mm.Preemption,
+		"model-lazy":                    mm.Lazy,
+		"model-precompute-positions":    mm.PrecomputePositions,
+		"model-memory-limit":            mm.MemoryLimit,
+		"model-attention-window":        mm.AttentionWindow,
+		"model-kernel-tuning":           mm.KernelTuning,
+		"model-rope-scaling":            mm.RopeScaling.String(),
+		"model-timeout":                 mm.Timeout.String(),
+		"model-normalization":           mm.Normalization.String(),
+		"model-calibration":             mm.Calibration,
+		"model-adapters":                formatAdapters(mm.Adapters),
+		"task":                          string(conf.task),
+		"models-manifest":               conf.modelsManifest,
+		"model-update-interval":         conf.updateInterval.String(),
+		"evaluation-datasets":           conf.evaluationDatasets,
+		"evaluation-tolerance":          conf.evaluationTolerance,
+		"response-cache":                redactURL(conf.responseCache),
+		"response-cache-size":           conf.responseCacheSize,
+		"response-cache-ttl":            conf.responseCacheTTL.String(),
+		"coalesce-requests":             conf.coalesceRequests,
+		"embedding-cache-dir":           conf.embeddingCacheDir,
+		"embedding-cache-size":          conf.embeddingCacheSize,
+		"audit-log":                     conf.auditLog,
+		"audit-max-length":              conf.auditMaxLength,
+		"audit-redact":                  conf.auditRedact,
+		"slow-request-threshold":        conf.slowRequestThreshold.String(),
+		"preflight":                     conf.preflight,
+		"network":                       s.Network,
+		"address":                       s.Address,
+		"allowed-origins":               s.AllowedOrigins,
+		"tls":                           s.TLSEnabled,
+		"tls-cert":                      s.TLSCert,
+		"tls-key":                       s.TLSKey,
+		"nats-url":                      redactURL(s.NATS.URL),
+		"nats-subject-prefix":           s.NATS.SubjectPrefix,
+		"nats-queue":                    s.NATS.Queue,
+		"nats-workers":                  s.NATS.Workers,
+		"nats-stream":                   s.NATS.Stream,
+		"nats-consumer":                 s.NATS.Consumer,
+		"nats-results-prefix":           s.NATS.ResultsPrefix,
+		"work-queue":                    redactURL(conf.workQueue),
+		"work-queue-frontend":           s.WorkQueue.Frontend,
+		"work-queue-workers":            s.WorkQueue.Workers,
+		"tenants":                       conf.tenants,
+		"tenant-header":                 s.TenantHeader,
+		"admin-key":                     redact(s.AdminKey),
+		"webhook-secret":                redact(s.WebhookSecret),
+		"webhook-allow-private":         s.WebhookAllowPrivate,
+		"job-store":                     redactURL(conf.jobStore),
+		"job-ttl":                       s.JobTTL.String(),
+		"playground":                    s.Playground,
+		"api-sunset":                    formatTime(s.APISunset),
+	}
+	if len(conf.models) > 0 {
+		settings[modelsKey] = redactModels(conf.models)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(settings); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// redact returns the secret replaced by a placeholder, if it's set.
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
+
+// formatTime returns the time in RFC 3339 format, or empty if zero.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// redactURL returns the URL with the password, if any, redacted.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Redacted()
+}
+
+// redactModels returns a copy of the models, and of the models of their
+// ensembles and variants, with the access tokens redacted.
+func redactModels(models []manifestModel) []manifestModel {
+	if models == nil {
+		return nil
+	}
+	out := make([]manifestModel, len(models))
+	for i, m := range models {
+		if m.HubAccessToken != nil {
+			token := redact(*m.HubAccessToken)
+			m.HubAccessToken = &token
+		}
+		m.Ensemble = redactModels(m.Ensemble)
+		m.Variants = redactModels(m.Variants)
+		out[i] = m
+	}
+	return out
+}
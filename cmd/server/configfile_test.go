@@ -0,0 +1,126 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/server"
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestConfig returns a configuration with its flags bound.
+func newTestConfig() (*config, *flag.FlagSet) {
+	conf := &config{
+		loaderConfig: &tasks.Config{},
+		serverConfig: &server.Config{},
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	conf.bindFlagSet(fs)
+	return conf, fs
+}
+
+// writeConfigFile writes the configuration file in a temporary directory.
+func writeConfigFile(t *testing.T, filename, content string) string {
+	filename = filepath.Join(t.TempDir(), filename)
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0o644))
+	return filename
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	files := map[string]string{
+		"cybertron.yaml": `
+address: 0.0.0.0:8080
+allowed-origins: [https://a.example, https://b.example]
+model-replicas: 2
+models:
+  - task: text-encoding
+    model: org/encoder
+  - task: text-classification
+    model: org/classifier
+    revision: v1.0
+`,
+		"cybertron.toml": `
+address = "0.0.0.0:8080"
+allowed-origins = ["https://a.example", "https://b.example"]
+model-replicas = 2
+
+[[models]]
+task = "text-encoding"
+model = "org/encoder"
+
+[[models]]
+task = "text-classification"
+model = "org/classifier"
+revision = "v1.0"
+`,
+	}
+	for filename, content := range files {
+		t.Run(filename, func(t *testing.T) {
+			conf, fs := newTestConfig()
+			require.NoError(t, applyConfigFile(writeConfigFile(t, filename, content), conf, fs))
+			assert.Equal(t, "0.0.0.0:8080", conf.serverConfig.Address)
+			assert.Equal(t, []string{"https://a.example", "https://b.example"}, conf.serverConfig.AllowedOrigins)
+			assert.Equal(t, 2, conf.loaderConfig.Replicas)
+			require.Len(t, conf.models, 2)
+			assert.Equal(t, "org/encoder", conf.models[0].Model)
+			assert.Equal(t, "text-classification", conf.models[1].Task)
+			require.NotNil(t, conf.models[1].Revision)
+			assert.Equal(t, "v1.0", *conf.models[1].Revision)
+		})
+	}
+}
+
+func TestApplyConfigFile_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+	}{
+		{"invalid yaml", "c.yaml", "address: [0.0.0.0"},
+		{"invalid toml", "c.toml", "address = "},
+		{"unknown yaml setting", "c.yaml", "unknown: 1"},
+		{"unknown toml setting", "c.toml", "unknown = 1"},
+		{"nested config", "c.yaml", "config: other.yaml"},
+		{"yaml mapping", "c.yaml", "address:\n  host: localhost"},
+		{"toml table", "c.toml", "[address]\nhost = \"localhost\""},
+		{"toml list of tables", "c.toml", "[[allowed-origins]]\nhost = \"localhost\""},
+		{"invalid value", "c.yaml", "model-replicas: many"},
+		{"model without task", "c.toml", "[[models]]\nmodel = \"org/model\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, fs := newTestConfig()
+			assert.Error(t, applyConfigFile(writeConfigFile(t, tt.filename, tt.content), conf, fs))
+		})
+	}
+
+	conf, fs := newTestConfig()
+	assert.Error(t, applyConfigFile(filepath.Join(t.TempDir(), "missing.yaml"), conf, fs))
+}
+
+func TestLookupConfigFile(t *testing.T) {
+	t.Setenv("CYBERTRON_CONFIG", "")
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"-config", "a.yaml"}, "a.yaml"},
+		{[]string{"--config=a.toml"}, "a.toml"},
+		{[]string{"-address", ":8080", "-config", "a.yaml"}, "a.yaml"},
+		{[]string{"-config"}, ""},
+		{[]string{"--", "-config", "a.yaml"}, ""},
+		{[]string{"-config", "a.yaml", "-config", "b.yaml"}, "b.yaml"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, lookupConfigFile(tt.args), tt.args)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/nlpodyssey/cybertron/pkg/datasets"
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/training"
+	"github.com/rs/zerolog/log"
+)
+
+// distill trains a small student model on the soft labels of the model, the
+// teacher, for the texts of an unlabeled corpus, writing the distilled model
+// to the output directory, and resuming from the checkpoint of a previous
+// interrupted run, if any.
+func distill(args []string) error {
+	var corpus, student, output string
+	tc := training.Config{FullModel: true}
+	conf, _, err := parseConfig("distill", args, func(_ *config, fs *flag.FlagSet) {
+		fs.Func("corpus", `unlabeled corpus: a CSV, TSV or JSON lines file, with the "input" of each example`,
+			flagAssignFunc(&corpus))
+		fs.Func("student", "name of the student model: a small BERT model, e.g. a MiniLM one, downloaded and converted as the teacher",
+			flagAssignFunc(&student))
+		fs.Func("output", "directory to write the distilled model to, with the checkpoint of the progress next to it", flagAssignFunc(&output))
+		trainingFlags(fs, &tc)
+		fs.Float64Var(&tc.Temperature, "temperature", 2, "temperature softening the probabilities of the teacher and of the student")
+	})
+	if err != nil {
+		return err
+	}
+	if len(conf.models) > 0 {
+		return errors.New("multiple models are only supported by the serve, download and convert subcommands")
+	}
+	if corpus == "" || student == "" || output == "" {
+		return errors.New("-corpus, -student and -output must be specified")
+	}
+	if conf.task == "" {
+		conf.task = TextClassificationTask
+	}
+	if conf.task != TextClassificationTask {
+		return fmt.Errorf("distillation not supported for task %#v", conf.task)
+	}
+	examples, err := datasets.ReadFile(corpus, "")
+	if err != nil {
+		return err
+	}
+	studentDir, err := tasks.Prepare(studentConfig(conf.loaderConfig, student))
+	if err != nil {
+		return err
+	}
+	lm, err := loadModel(conf.task, conf.loaderConfig)
+	if err != nil {
+		return err
+	}
+	defer tasks.Finalize(lm.model)
+	tc.Task = string(conf.task)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	report, err := training.Distill(ctx, lm.model.(textclassification.Interface), studentDir, output, examples, tc)
+	if errors.Is(err, context.Canceled) {
+		log.Info().Msg("interrupted: run the same command again to resume from the last epoch")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	last := report.Epochs[len(report.Epochs)-1]
+	log.Info().
+		Strs("labels", report.Labels).
+		Int("examples", report.Examples).
+		Float64("loss", last.Loss).
+		Float64("agreement", last.Accuracy).
+		Str("teacher", lm.id()).
+		Str("path", output).
+		Msg("model distilled")
+	return nil
+}
+
+// studentConfig returns the configuration of the student model, sharing the
+// access to the Hub and the models directory of the teacher, and converted
+// to the spago backend, at full precision, so that it can be trained.
+func studentConfig(teacher *tasks.Config, name string) *tasks.Config {
+	return &tasks.Config{
+		ModelsDir:        teacher.ModelsDir,
+		ModelName:        name,
+		HubAccessToken:   teacher.HubAccessToken,
+		HubEndpoint:      teacher.HubEndpoint,
+		HTTPProxy:        teacher.HTTPProxy,
+		CABundle:         teacher.CABundle,
+		DownloadPolicy:   teacher.DownloadPolicy,
+		ConversionPolicy: teacher.ConversionPolicy,
+		// The quantized layers would stay frozen.
+		ConversionQuantization: quantization.None,
+		Offline:                teacher.Offline,
+		Resolver:               teacher.Resolver,
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/evaluation"
+	"github.com/rs/zerolog/log"
+)
+
+// evaluate runs a golden dataset through the model and prints the metrics
+// of its task, compared with the ones of a baseline report, if any: it
+// fails if any of them drops beyond the tolerance, e.g. to gate a release.
+func evaluate(args []string) error {
+	var dataset, baseline, output string
+	conf, _, err := parseConfig("evaluate", args, func(_ *config, fs *flag.FlagSet) {
+		fs.Func("dataset", `golden dataset: a JSON file with the "task" and its labeled "examples", or a CoNLL, SQuAD, CSV/TSV or JSON lines file`,
+			flagAssignFunc(&dataset))
+		fs.Func("baseline", "JSON file of the report the metrics are compared with (optional)",
+			flagAssignFunc(&baseline))
+		fs.Func("output", "file to write the report to, e.g. the baseline of the next evaluations (optional)",
+			flagAssignFunc(&output))
+	})
+	if err != nil {
+		return err
+	}
+	if len(conf.models) > 0 {
+		return errors.New("multiple models are only supported by the serve, download and convert subcommands")
+	}
+	if dataset == "" {
+		return errors.New("the dataset is not specified")
+	}
+	ds, err := evaluation.LoadDataset(dataset, string(conf.task))
+	if err != nil {
+		return err
+	}
+	if conf.task == "" {
+		conf.task = TaskType(ds.Task)
+	} else if string(conf.task) != ds.Task {
+		return fmt.Errorf("dataset of task %#v for a model of task %#v", ds.Task, conf.task)
+	}
+
+	lm, err := loadModel(conf.task, conf.loaderConfig)
+	if err != nil {
+		return err
+	}
+	defer tasks.Finalize(lm.model)
+	report, err := evaluation.Evaluate(context.Background(), lm.model, ds)
+	if err != nil {
+		return err
+	}
+	fmt.Print(report)
+	if output != "" {
+		if err := report.Save(output); err != nil {
+			return err
+		}
+	}
+	if baseline == "" {
+		return nil
+	}
+	base, err := evaluation.ReadReport(baseline)
+	if err != nil {
+		return err
+	}
+	regressions, err := evaluation.Compare(report, base, conf.evaluationTolerance)
+	if err != nil {
+		return err
+	}
+	for _, r := range regressions {
+		log.Error().Str("metric", r.Metric).Float64("baseline", r.Baseline).Float64("actual", r.Actual).Msg("metric regressed")
+	}
+	if len(regressions) > 0 {
+		return fmt.Errorf("%d metrics regressed from the baseline", len(regressions))
+	}
+	return nil
+}
+
+// evaluator evaluates the served models on the golden datasets of their
+// tasks, before they're updated, and on request of the admin API.
+type evaluator struct {
+	models    []*loadedModel
+	datasets  map[TaskType]*evaluation.Dataset
+	tolerance float64
+	// swapMu is held for writing while a model is swapped and finalized, and
+	// for reading while the served models are evaluated.
+	swapMu sync.RWMutex
+	mu     sync.Mutex
+	// baselines are the reports of the served models by task, once
+	// evaluated, which the next evaluations are compared with.
+	baselines map[TaskType]*evaluation.Report
+}
+
+// newEvaluator returns the evaluator of the models on the datasets of the
+// configuration, or nil if there are none.
+func newEvaluator(conf *config, models []*loadedModel) (*evaluator, error) {
+	if len(conf.evaluationDatasets) == 0 {
+		return nil, nil
+	}
+	e := &evaluator{
+		models:    models,
+		datasets:  make(map[TaskType]*evaluation.Dataset),
+		tolerance: conf.evaluationTolerance,
+		baselines: make(map[TaskType]*evaluation.Report),
+	}
+	// The datasets of the formats not implying a task, e.g. CSV, are of the
+	// task of the models, if only one.
+	var task string
+	for _, lm := range models {
+		if task != "" && task != string(lm.task) {
+			task = ""
+			break
+		}
+		task = string(lm.task)
+	}
+	for _, filename := range conf.evaluationDatasets {
+		ds, err := evaluation.LoadDataset(filename, task)
+		if err != nil {
+			return nil, err
+		}
+		task := TaskType(ds.Task)
+		if e.datasets[task] != nil {
+			return nil, fmt.Errorf("more than one evaluation dataset for task %#v", ds.Task)
+		}
+		if e.model(task) == nil {
+			log.Warn().Str("dataset", filename).Str("task", ds.Task).Msg("no model served for the task of the evaluation dataset")
+		}
+		e.datasets[task] = ds
+	}
+	return e, nil
+}
+
+// model returns the served model of the task, if any.
+func (e *evaluator) model(task TaskType) *loadedModel {
+	for _, lm := range e.models {
+		if lm.task == task {
+			return lm
+		}
+	}
+	return nil
+}
+
+// evaluate evaluates the served model on the dataset of its task, returning
+// its id and report.
+func (e *evaluator) evaluate(ctx context.Context, lm *loadedModel) (string, *evaluation.Report, error) {
+	e.swapMu.RLock()
+	defer e.swapMu.RUnlock()
+	report, err := evaluation.Evaluate(ctx, lm.model, e.datasets[lm.task])
+	return lm.id(), report, err
+}
+
+// baseline returns the report the evaluations of the model of the task are
+// compared with, which is the report given the first time.
+func (e *evaluator) baseline(task TaskType, report *evaluation.Report) *evaluation.Report {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if b := e.baselines[task]; b != nil {
+		return b
+	}
+	e.baselines[task] = report
+	return report
+}
+
+// check evaluates the candidate replacing the served model, failing if any
+// metric drops beyond the tolerance from the ones of the served model. It
+// returns the report of the candidate, the baseline once it's swapped in,
+// if the model has a dataset.
+func (e *evaluator) check(ctx context.Context, lm *loadedModel, candidate any) (*evaluation.Report, error) {
+	if e == nil || e.datasets[lm.task] == nil {
+		return nil, nil
+	}
+	e.mu.Lock()
+	baseline := e.baselines[lm.task]
+	e.mu.Unlock()
+	if baseline == nil {
+		_, report, err := e.evaluate(ctx, lm)
+		if err != nil {
+			return nil, fmt.Errorf("evaluation of the served model: %w", err)
+		}
+		baseline = e.baseline(lm.task, report)
+	}
+	report, err := evaluation.Evaluate(ctx, candidate, e.datasets[lm.task])
+	if err != nil {
+		return nil, err
+	}
+	regressions, err := evaluation.Compare(report, baseline, e.tolerance)
+	if err != nil {
+		return nil, err
+	}
+	if len(regressions) > 0 {
+		s := make([]string, len(regressions))
+		for i, r := range regressions {
+			s[i] = r.String()
+		}
+		return nil, fmt.Errorf("evaluation regressed: %s", strings.Join(s, ", "))
+	}
+	return report, nil
+}
+
+// lockSwap locks the models for a swap, waiting for the evaluations in
+// progress.
+func (e *evaluator) lockSwap() {
+	if e != nil {
+		e.swapMu.Lock()
+	}
+}
+
+// unlockSwap unlocks the models once swapped, the report of the swapped in
+// model of the task, if any, becoming its baseline.
+func (e *evaluator) unlockSwap(task TaskType, report *evaluation.Report) {
+	if e == nil {
+		return
+	}
+	if report != nil {
+		e.mu.Lock()
+		e.baselines[task] = report
+		e.mu.Unlock()
+	}
+	e.swapMu.Unlock()
+}
+
+// modelEvaluation is the evaluation of a served model by the admin API.
+type modelEvaluation struct {
+	Task        TaskType                `json:"task"`
+	Model       string                  `json:"model"`
+	Report      *evaluation.Report      `json:"report"`
+	Baseline    *evaluation.Report      `json:"baseline"`
+	Regressions []evaluation.Regression `json:"regressions"`
+}
+
+// ServeHTTP evaluates the served models with a dataset, or the one of the
+// "task" of the query, on POST requests, comparing their reports with the
+// baselines.
+func (e *evaluator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	task := TaskType(r.URL.Query().Get("task"))
+	if task != "" && (e.datasets[task] == nil || e.model(task) == nil) {
+		http.Error(w, fmt.Sprintf("no evaluation of task %#v", task), http.StatusNotFound)
+		return
+	}
+	evaluations := []modelEvaluation{}
+	for _, lm := range e.models {
+		if e.datasets[lm.task] == nil || (task != "" && lm.task != task) {
+			continue
+		}
+		id, report, err := e.evaluate(r.Context(), lm)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("evaluation of model %#v: %v", id, err), http.StatusInternalServerError)
+			return
+		}
+		baseline := e.baseline(lm.task, report)
+		regressions, err := evaluation.Compare(report, baseline, e.tolerance)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		evaluations = append(evaluations, modelEvaluation{
+			Task:        lm.task,
+			Model:       id,
+			Report:      report,
+			Baseline:    baseline,
+			Regressions: regressions,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"evaluations": evaluations}); err != nil {
+		log.Warn().Err(err).Msg("failed to write the evaluations")
+	}
+}
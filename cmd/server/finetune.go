@@ -0,0 +1,89 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+
+	"github.com/nlpodyssey/cybertron/pkg/datasets"
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/training"
+	"github.com/rs/zerolog/log"
+)
+
+// fineTune fine-tunes the classification head of the model, and optionally
+// its encoder, on a labeled dataset, writing the fine-tuned model to the
+// output directory, and resuming from the checkpoint of a previous
+// interrupted run, if any.
+func fineTune(args []string) error {
+	var dataset, output string
+	var tc training.Config
+	conf, _, err := parseConfig("finetune", args, func(_ *config, fs *flag.FlagSet) {
+		fs.Func("dataset", `labeled dataset: a CSV, TSV or JSON lines file, with the "input" and its "label" for the text-classification task, or a CoNLL file for the token-classification task`,
+			flagAssignFunc(&dataset))
+		fs.Func("output", "directory to write the fine-tuned model to, with the checkpoint of the progress next to it", flagAssignFunc(&output))
+		trainingFlags(fs, &tc)
+	})
+	if err != nil {
+		return err
+	}
+	if len(conf.models) > 0 {
+		return errors.New("multiple models are only supported by the serve, download and convert subcommands")
+	}
+	if dataset == "" || output == "" {
+		return errors.New("both -dataset and -output must be specified")
+	}
+	if format, ok := datasets.FormatOf(dataset); ok && conf.task == "" {
+		conf.task = TaskType(format.Task())
+	}
+	if conf.task == "" {
+		return errors.New("the task is not specified")
+	}
+	examples, err := datasets.ReadFile(dataset, "")
+	if err != nil {
+		return err
+	}
+	modelDir, err := tasks.Prepare(conf.loaderConfig)
+	if err != nil {
+		return err
+	}
+	tc.Task = string(conf.task)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	report, err := training.FineTune(ctx, modelDir, output, examples, tc)
+	if errors.Is(err, context.Canceled) {
+		log.Info().Msg("interrupted: run the same command again to resume from the last epoch")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	last := report.Epochs[len(report.Epochs)-1]
+	log.Info().
+		Strs("labels", report.Labels).
+		Int("examples", report.Examples).
+		Float64("loss", last.Loss).
+		Float64("accuracy", last.Accuracy).
+		Str("path", output).
+		Msg("model fine-tuned")
+	return nil
+}
+
+// trainingFlags defines the flags of the options of the training, whose
+// defaults are the ones of the configuration.
+func trainingFlags(fs *flag.FlagSet, tc *training.Config) {
+	fs.IntVar(&tc.Epochs, "epochs", 3, "number of passes over the dataset")
+	fs.IntVar(&tc.BatchSize, "batch-size", 16, "number of examples per optimization step")
+	fs.Float64Var(&tc.LearningRate, "learning-rate", 0, "learning rate of the Adam optimizer (default 1e-3, or 3e-5 with -full-model)")
+	fs.Float64Var(&tc.WeightDecay, "weight-decay", 0, "decoupled weight decay (AdamW)")
+	fs.BoolVar(&tc.FullModel, "full-model", tc.FullModel, "whether to fine-tune the encoder too, rather than the classification head alone")
+	fs.Float64Var(&tc.ValidationSplit, "validation-split", 0, "fraction of the examples held out to measure the accuracy after each epoch")
+	fs.Uint64Var(&tc.Seed, "seed", 0, "seed of the shuffling of the examples and of the initialization of a new classification head")
+}
@@ -0,0 +1,159 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nlpodyssey/cybertron/pkg/downloader"
+	"github.com/nlpodyssey/cybertron/pkg/inspect"
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+)
+
+// inspectModel prints the description of the model, given as argument or by
+// the model setting, reading it from the models directory or, if not there,
+// from the Hub, without downloading the weights.
+func inspectModel(args []string) error {
+	var asJSON, tensors bool
+	var batchSize, sequenceLength int
+	conf, fs, err := parseConfig("inspect", args, func(_ *config, fs *flag.FlagSet) {
+		fs.BoolVar(&asJSON, "json", false, "print the description as JSON")
+		fs.BoolVar(&tensors, "tensors", false, "also print the name, type and shape of each tensor")
+		fs.IntVar(&batchSize, "batch-size", 1, "number of sequences processed at once, for the estimate of the memory")
+		fs.IntVar(&sequenceLength, "sequence-length", 0, "length in tokens of the sequences, for the estimate of the memory (default the maximum one of the model)")
+	})
+	if err != nil {
+		return err
+	}
+	mc := conf.loaderConfig
+	if fs.NArg() > 0 {
+		mc.ModelName = fs.Arg(0)
+	}
+	if mc.ModelName == "" {
+		return errors.New("the model to inspect is not specified")
+	}
+
+	r, err := inspect.Inspect(mc.ModelsDir, mc.ModelName, downloader.Options{
+		AccessToken: mc.HubAccessToken,
+		Revision:    mc.Revision,
+		Offline:     mc.Offline,
+		Endpoint:    mc.HubEndpoint,
+		Proxy:       mc.HTTPProxy,
+		CABundle:    mc.CABundle,
+	})
+	if err != nil {
+		return err
+	}
+	if !tensors {
+		r.Tensors = nil
+	}
+	var estimate *inspect.Estimate
+	if e, err := r.EstimateMemory(weightsPrecision(mc), batchSize, sequenceLength); err == nil {
+		estimate = &e
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			*inspect.Report
+			Estimate *inspect.Estimate `json:"estimate,omitempty"`
+		}{r, estimate})
+	}
+	printReport(r, estimate)
+	return nil
+}
+
+// weightsPrecision returns the precision of the weights of the model
+// converted with the configuration (see inspect.Report.Memory).
+func weightsPrecision(c *tasks.Config) string {
+	switch c.ConversionQuantization {
+	case quantization.Int8:
+		return "int8"
+	case quantization.Float16, quantization.BFloat16:
+		return "float16"
+	}
+	if c.ConversionPrecision == tasks.F64 {
+		return "float64"
+	}
+	return "float32"
+}
+
+// printReport prints the description of the model, and the estimate of its
+// memory, if any, in a human-readable form.
+func printReport(r *inspect.Report, estimate *inspect.Estimate) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	source := "Hugging Face Hub"
+	if r.Local {
+		source = "models directory"
+	}
+	parameters := fmt.Sprintf("%d", r.Parameters)
+	if r.ParametersEstimated {
+		parameters = fmt.Sprintf("~%d (estimated from the weights size)", r.Parameters)
+	}
+	fmt.Fprintf(w, "model:\t%s (from the %s)\n", r.Model, source)
+	fmt.Fprintf(w, "model type:\t%s\n", r.ModelType)
+	fmt.Fprintf(w, "architectures:\t%s\n", strings.Join(r.Architectures, ", "))
+	fmt.Fprintf(w, "supported:\t%t\n", r.Supported)
+	fmt.Fprintf(w, "tasks:\t%s\n", strings.Join(r.Tasks, ", "))
+	fmt.Fprintf(w, "tokenizer:\t%s\n", r.Tokenizer)
+	fmt.Fprintf(w, "parameters:\t%s\n", parameters)
+	fmt.Fprintf(w, "weights size:\t%s\n", formatBytes(r.WeightsSize))
+
+	precisions := make([]string, 0, len(r.Memory))
+	for p := range r.Memory {
+		precisions = append(precisions, p)
+	}
+	sort.Slice(precisions, func(i, j int) bool { return r.Memory[precisions[i]] > r.Memory[precisions[j]] })
+	for _, p := range precisions {
+		fmt.Fprintf(w, "memory (%s):\t%s\n", p, formatBytes(r.Memory[p]))
+	}
+	if e := estimate; e != nil {
+		fmt.Fprintf(w, "memory estimate:\t%s (%s weights in %s, %s activations for %d sequences of %d tokens)\n",
+			formatBytes(e.Total), formatBytes(e.Weights), e.Precision, formatBytes(e.Activations), e.BatchSize, e.SequenceLength)
+	}
+
+	fmt.Fprintf(w, "downloaded:\t%t\n", r.Downloaded)
+	if r.Commit != "" {
+		fmt.Fprintf(w, "commit:\t%s\n", r.Commit)
+	}
+	converted := fmt.Sprintf("%t", r.Converted)
+	if r.Converted {
+		converted = fmt.Sprintf("true (%s)", formatBytes(r.ConvertedSize))
+	}
+	fmt.Fprintf(w, "converted:\t%s\n", converted)
+	fmt.Fprintf(w, "GGUF:\t%t\n", r.GGUF)
+	w.Flush()
+
+	if len(r.Tensors) > 0 {
+		fmt.Println()
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, t := range r.Tensors {
+			fmt.Fprintf(w, "%s\t%s\t%v\n", t.Name, t.DType, t.Shape)
+		}
+		w.Flush()
+	}
+}
+
+// formatBytes formats a size in bytes with a binary unit.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,90 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+
+	"github.com/nlpodyssey/cybertron/pkg/stream"
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+)
+
+// kafkaOptions are the options of the kafka subcommand.
+type kafkaOptions struct {
+	proxyURL    string
+	group       string
+	inputTopics []string
+	outputTopic string
+	consumers   int
+	parallelism int
+}
+
+// bind binds the options to the flag set.
+func (o *kafkaOptions) bind(fs *flag.FlagSet) {
+	o.group = "cybertron"
+	fs.Func("kafka-rest-url", "URL of the Kafka REST Proxy (API v2)", flagAssignFunc(&o.proxyURL))
+	fs.Func("kafka-group", `consumer group (default "cybertron")`, flagAssignFunc(&o.group))
+	fs.Func("kafka-input-topics", "topics to consume the inputs from (comma separated)",
+		flagParseFunc(parseCommaSplit, &o.inputTopics))
+	fs.Func("kafka-output-topic", "topic to produce the results to", flagAssignFunc(&o.outputTopic))
+	fs.IntVar(&o.consumers, "kafka-consumers", 1, "number of consumers of the group, sharing the partitions of the input topics")
+	fs.IntVar(&o.parallelism, "parallelism", 1, "number of messages processed concurrently by each consumer")
+}
+
+// runKafka consumes the inputs from Kafka topics, runs the model on them,
+// and produces the results to another topic, with at-least-once semantics,
+// until interrupted.
+func runKafka(args []string) error {
+	var o inferenceOptions
+	var ko kafkaOptions
+	conf, _, err := parseConfig("kafka", args, func(_ *config, fs *flag.FlagSet) {
+		o.bind(fs)
+		ko.bind(fs)
+	})
+	if err != nil {
+		return err
+	}
+	if ko.proxyURL == "" || len(ko.inputTopics) == 0 || ko.outputTopic == "" {
+		return errors.New("-kafka-rest-url, -kafka-input-topics and -kafka-output-topic must be specified")
+	}
+	m, infer, err := loadForInference(conf, o)
+	if err != nil {
+		return err
+	}
+	defer tasks.Finalize(m)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	producer := stream.NewKafkaProducer(ko.proxyURL, stream.KafkaOptions{})
+	g, ctx := errgroup.WithContext(ctx)
+	for i := 0; i < ko.consumers; i++ {
+		g.Go(func() error {
+			c, err := stream.NewKafkaConsumer(ctx, ko.proxyURL, ko.group, ko.inputTopics, stream.KafkaOptions{})
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := c.Close(); err != nil {
+					log.Warn().Err(err).Msg("failed to close the Kafka consumer")
+				}
+			}()
+			return stream.Run(ctx, c, producer, stream.Func(infer), stream.Options{
+				OutputTopic: ko.outputTopic,
+				Parallelism: ko.parallelism,
+			})
+		})
+	}
+	log.Info().Strs("topics", ko.inputTopics).Str("group", ko.group).Int("consumers", ko.consumers).Msg("consuming")
+	if err := g.Wait(); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
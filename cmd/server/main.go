@@ -9,21 +9,36 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"os/signal"
-	"time"
+	"path/filepath"
+	"strings"
 
 	"github.com/joho/godotenv"
+	"github.com/nlpodyssey/cybertron/pkg/audit"
+	"github.com/nlpodyssey/cybertron/pkg/downloader"
+	"github.com/nlpodyssey/cybertron/pkg/embeddingcache"
+	"github.com/nlpodyssey/cybertron/pkg/jobstore"
+	"github.com/nlpodyssey/cybertron/pkg/logging"
+	"github.com/nlpodyssey/cybertron/pkg/responsecache"
+	"github.com/nlpodyssey/cybertron/pkg/routing"
 	"github.com/nlpodyssey/cybertron/pkg/server"
 	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/coreference"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/languagemodeling"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/postagging"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/relationextraction"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier"
-	"github.com/rs/zerolog"
+	"github.com/nlpodyssey/cybertron/pkg/tenancy"
+	"github.com/nlpodyssey/cybertron/pkg/workqueue"
 	"github.com/rs/zerolog/log"
 )
 
@@ -32,86 +47,617 @@ const addrRandomPort = ":0"
 
 // main is the entry point of the application.
 func main() {
-	if err := run(); err != nil {
+	err := run()
+	if err != nil {
 		log.Error().Err(err).Send()
+	}
+	closeLogger()
+	if err != nil {
 		os.Exit(1)
 	}
 }
 
-// run set the configuration and starts the server.
+// commands are the subcommands, by name.
+var commands = map[string]func(args []string) error{
+	"serve":     serve,
+	"download":  download,
+	"convert":   convert,
+	"run":       runInference,
+	"bench":     bench,
+	"repl":      repl,
+	"inspect":   inspectModel,
+	"batch":     runBatch,
+	"kafka":     runKafka,
+	"calibrate": calibrate,
+	"evaluate":  evaluate,
+	"finetune":  fineTune,
+	"distill":   distill,
+	"preflight": runPreflight,
+}
+
+// run runs the subcommand given as first argument, serving the models by
+// default, when no subcommand is given.
 func run() error {
 	initLogger()
 	loadDotenv()
 
+	cmd, args := serve, os.Args[1:]
+	if len(args) > 0 {
+		if c, ok := commands[args[0]]; ok {
+			cmd, args = c, args[1:]
+		}
+	}
+	err := cmd(args)
+	if errors.Is(err, flag.ErrHelp) || errors.Is(err, errConfigPrinted) {
+		return nil
+	}
+	return err
+}
+
+// parseConfig sets the configuration from the configuration file, then from
+// the environment variables and then from the command line flags of the
+// subcommand, so that the flags have priority over the environment, which
+// has priority over the file. The setup function, if any, can set defaults
+// and bind additional flags.
+func parseConfig(name string, args []string, setup func(*config, *flag.FlagSet)) (*config, *flag.FlagSet, error) {
 	conf := &config{
-		loaderConfig: &tasks.Config{ModelsDir: defaultModelsDir},
-		serverConfig: &server.Config{Address: addrRandomPort},
+		loaderConfig:     &tasks.Config{ModelsDir: defaultModelsDir},
+		serverConfig:     &server.Config{Address: addrRandomPort},
+		coalesceRequests: true,
+		preflight:        true,
+	}
+	fs := flag.NewFlagSet(fmt.Sprintf("%s %s", filepath.Base(os.Args[0]), name), flag.ContinueOnError)
+	if setup != nil {
+		setup(conf, fs)
 	}
 
+	conf.bindFlagSet(fs)
+
+	if filename := lookupConfigFile(args); filename != "" {
+		if err := applyConfigFile(filename, conf, fs); err != nil {
+			return nil, nil, err
+		}
+	}
 	// load env vars values *before* parsing command line flags:
 	// this gives to the flag a priority over values from the environment.
 	if err := conf.loadEnv(); err != nil {
-		return err
+		return nil, nil, err
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if err := setupLogger(conf.logging); err != nil {
+		return nil, nil, err
+	}
+	if conf.modelsManifest != "" {
+		models, err := readModelsManifest(conf.modelsManifest)
+		if err != nil {
+			return nil, nil, err
+		}
+		conf.models = models
+	}
+	if conf.tenants != "" {
+		tenants, err := tenancy.Load(conf.tenants)
+		if err != nil {
+			return nil, nil, err
+		}
+		conf.serverConfig.Tenants = tenants
+	}
+	if conf.jobStore != "" {
+		store, err := jobstore.Open(conf.jobStore)
+		if err != nil {
+			return nil, nil, err
+		}
+		conf.serverConfig.JobStore = store
 	}
 
-	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
-	conf.bindFlagSet(fs)
+	conf.progress = newProgressAdmin(stderrTerminal())
+	conf.loaderConfig.Progress = conf.progress.report
 
-	err := fs.Parse(os.Args[1:])
-	if errors.Is(err, flag.ErrHelp) {
-		return nil
+	if conf.printConfig {
+		if err := conf.writeSettings(os.Stdout); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, errConfigPrinted
 	}
+	return conf, fs, nil
+}
+
+// serve loads the models and starts the server.
+func serve(args []string) error {
+	conf, _, err := parseConfig("serve", args, nil)
 	if err != nil {
 		return err
 	}
+	if conf.preflight && !(conf.workQueue != "" && conf.serverConfig.WorkQueue.Frontend) {
+		if err := preflightChecks(conf); err != nil {
+			return err
+		}
+	}
+	if conf.workQueue != "" {
+		if conf.serverConfig.WorkQueue.Queue, err = workqueue.Open(conf.workQueue); err != nil {
+			return err
+		}
+		if conf.serverConfig.WorkQueue.Frontend {
+			return serveFrontend(conf)
+		}
+	}
 
-	m, err := loadModelForTask(conf)
+	var models []*loadedModel
+	if len(conf.models) > 0 {
+		metrics := &routing.Metrics{}
+		models, err = loadModels(conf, metrics)
+		if hasVariants(conf.models) {
+			conf.serverConfig.Metrics = append(conf.serverConfig.Metrics, metrics)
+		}
+	} else {
+		var lm *loadedModel
+		lm, err = loadModel(conf.task, conf.loaderConfig)
+		models = []*loadedModel{lm}
+	}
 	if err != nil {
 		return err
 	}
-	defer tasks.Finalize(m)
+	defer finalizeModels(models)
+
+	opts := handlerOptions{
+		coalesce: conf.coalesceRequests,
+		latency:  &server.LatencyMetrics{SlowThreshold: conf.slowRequestThreshold},
+	}
+	conf.serverConfig.Metrics = append(conf.serverConfig.Metrics, opts.latency)
+	if opts.embeddings, err = openEmbeddingCache(conf); err != nil {
+		return err
+	}
+	if conf.responseCache != "" {
+		store, err := responsecache.Open(conf.responseCache, conf.responseCacheSize)
+		if err != nil {
+			return err
+		}
+		opts.cache = &server.ResponseCache{Store: store, TTL: conf.responseCacheTTL}
+	}
+	if conf.auditLog != "" {
+		var sink *audit.JSONSink
+		if opts.audit, sink, err = openAuditLog(conf); err != nil {
+			return err
+		}
+		defer sink.Close()
+	}
 
-	requestHandler, err := server.ResolveRequestHandler(m)
+	requestHandler, err := resolveRequestHandler(models, opts)
+	if err != nil {
+		return err
+	}
+	eval, err := newEvaluator(conf, models)
 	if err != nil {
 		return err
 	}
+	adapters := &adapterAdmin{models: models}
+	conf.serverConfig.AdminHandlers = map[string]http.Handler{"adapters": adapters, "progress": conf.progress}
+	conf.serverConfig.ModelHealth = modelHealth(models, adapters)
+	if eval != nil {
+		conf.serverConfig.AdminHandlers["evaluation"] = eval
+	}
 
 	s := server.New(conf.serverConfig, requestHandler)
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer stop()
 
+	if conf.updateInterval > 0 {
+		u := &updater{server: s, models: models, interval: conf.updateInterval, handlerOptions: opts, evaluator: eval, adapters: adapters}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			u.watch(ctx)
+		}()
+		// The models must not be finalized while they're being swapped.
+		defer func() {
+			stop()
+			<-done
+		}()
+	}
+
 	return s.Start(ctx)
 }
 
-func loadModelForTask(conf *config) (m any, err error) {
-	switch conf.task {
+// serveFrontend starts a frontend of the work queue, serving the bulk
+// uploads and the async jobs with the workers of the queue, without loading
+// any model.
+func serveFrontend(conf *config) error {
+	s := server.New(conf.serverConfig, server.RequestHandlers{})
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer stop()
+	return s.Start(ctx)
+}
+
+// loadedModel is a model loaded for a task.
+type loadedModel struct {
+	task TaskType
+	// baseConfig is the configuration the model was requested with.
+	baseConfig *tasks.Config
+	// config is the configuration the current model was loaded with, which
+	// differs from baseConfig once the model is updated.
+	config *tasks.Config
+	model  any
+	// commit is the commit SHA of the current model, if known.
+	commit string
+	// members are the models of the ensemble, or the variants, if any.
+	members []*loadedModel
+}
+
+// id identifies the current model, by name and revision, or an ensemble or
+// a model with variants, by name and the ids of its members.
+func (lm *loadedModel) id() string {
+	if len(lm.members) > 0 {
+		ids := make([]string, len(lm.members))
+		for i, m := range lm.members {
+			ids[i] = m.id()
+		}
+		return lm.config.ModelName + "(" + strings.Join(ids, ",") + ")"
+	}
+	revision := lm.commit
+	if revision == "" {
+		revision = lm.config.Revision
+	}
+	return lm.config.ModelName + "@" + revision
+}
+
+// loadModel loads the model for the task.
+func loadModel(task TaskType, loaderConfig *tasks.Config) (*loadedModel, error) {
+	m, err := loadModelForTask(task, loaderConfig)
+	if err != nil {
+		return nil, err
+	}
+	lm := &loadedModel{task: task, baseConfig: loaderConfig, config: loaderConfig, model: m}
+	md, err := downloader.ReadMetadata(loaderConfig.FullModelPath())
+	switch {
+	case err == nil:
+		lm.commit = md.Commit
+	case !os.IsNotExist(err):
+		log.Warn().Err(err).Str("model", loaderConfig.ModelName).Msg("failed to read the revision of the model")
+	}
+	return lm, nil
+}
+
+// loadModels loads all the models of the models manifest, or of the
+// configuration file. The requests of the variants of the models are
+// accounted in the metrics.
+func loadModels(conf *config, metrics *routing.Metrics) ([]*loadedModel, error) {
+	models := make([]*loadedModel, 0, len(conf.models))
+	for _, mm := range conf.models {
+		loaderConfig, err := mm.loaderConfig(conf.loaderConfig)
+		if err != nil {
+			finalizeModels(models)
+			return nil, err
+		}
+		var lm *loadedModel
+		switch {
+		case len(mm.Ensemble) > 0:
+			lm, err = loadEnsemble(mm, loaderConfig)
+		case len(mm.Variants) > 0:
+			lm, err = loadVariants(mm, loaderConfig, metrics)
+		default:
+			log.Info().Str("model", mm.Model).Str("task", mm.Task).Msg("loading model")
+			lm, err = loadModel(TaskType(mm.Task), loaderConfig)
+		}
+		if err != nil {
+			finalizeModels(models)
+			return nil, fmt.Errorf("failed to load model %#v: %w", mm.Model, err)
+		}
+		models = append(models, lm)
+	}
+	return models, nil
+}
+
+// loadMembers loads the members of an ensemble, or the variants of a model,
+// whose options default to the ones of the model, described by kind.
+func loadMembers(mm manifestModel, kind string, members []manifestModel, loaderConfig *tasks.Config) ([]*loadedModel, error) {
+	loaded := make([]*loadedModel, 0, len(members))
+	for _, member := range members {
+		c, err := member.loaderConfig(loaderConfig)
+		if err == nil {
+			log.Info().Str("model", member.Model).Str("task", member.Task).Str(kind, mm.Model).Msg("loading model")
+			var lm *loadedModel
+			if lm, err = loadModel(TaskType(member.Task), c); err == nil {
+				loaded = append(loaded, lm)
+				continue
+			}
+		}
+		finalizeModels(loaded)
+		return nil, fmt.Errorf("%s model %#v: %w", kind, member.Model, err)
+	}
+	return loaded, nil
+}
+
+// hasVariants returns whether any of the models has variants.
+func hasVariants(models []manifestModel) bool {
+	for _, mm := range models {
+		if len(mm.Variants) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// loadEnsemble loads the models of the ensemble and combines them.
+func loadEnsemble(mm manifestModel, loaderConfig *tasks.Config) (*loadedModel, error) {
+	members, weights := mm.members()
+	loaded, err := loadMembers(mm, "ensemble", members, loaderConfig)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ensembleForTask(TaskType(mm.Task), loaded, weights)
+	if err != nil {
+		finalizeModels(loaded)
+		return nil, err
+	}
+	return &loadedModel{task: TaskType(mm.Task), baseConfig: loaderConfig, config: loaderConfig, model: m, members: loaded}, nil
+}
+
+// ensembleForTask returns the ensemble of the models of the task.
+func ensembleForTask(task TaskType, models []*loadedModel, weights []float64) (any, error) {
+	switch task {
+	case ZeroShotClassificationTask:
+		return ensembleOf[zeroshotclassifier.Interface](models, weights)
+	case QuestionAnsweringTask:
+		return ensembleOf[questionanswering.Interface](models, weights)
+	case TextClassificationTask:
+		return ensembleOf[textclassification.Interface](models, weights)
+	default:
+		return nil, fmt.Errorf("ensemble not supported for task %s", task)
+	}
+}
+
+func ensembleOf[T any](models []*loadedModel, weights []float64) (T, error) {
+	ms := make([]T, len(models))
+	for i, lm := range models {
+		ms[i] = lm.model.(T)
+	}
+	return tasks.Ensemble(ms, weights)
+}
+
+// loadVariants loads the variants of the model and routes the requests
+// between them, accounting them in the metrics by variant id.
+func loadVariants(mm manifestModel, loaderConfig *tasks.Config, metrics *routing.Metrics) (*loadedModel, error) {
+	variants, traffic, shadows := mm.variants()
+	loaded, err := loadMembers(mm, "variant", variants, loaderConfig)
+	if err != nil {
+		return nil, err
+	}
+	r := variantRoute{name: mm.Model, variants: loaded, traffic: traffic, shadows: shadows, metrics: metrics}
+	m, err := r.forTask(TaskType(mm.Task))
+	if err != nil {
+		finalizeModels(loaded)
+		return nil, err
+	}
+	return &loadedModel{task: TaskType(mm.Task), baseConfig: loaderConfig, config: loaderConfig, model: m, members: loaded}, nil
+}
+
+// variantRoute is the routing of the requests of a model between its
+// loaded variants.
+type variantRoute struct {
+	name     string
+	variants []*loadedModel
+	traffic  []float64
+	shadows  []bool
+	metrics  *routing.Metrics
+}
+
+// forTask returns the model of the task routing the requests.
+func (r variantRoute) forTask(task TaskType) (any, error) {
+	switch task {
 	case ZeroShotClassificationTask:
-		return tasks.Load[zeroshotclassifier.Interface](conf.loaderConfig)
+		return routeOf[zeroshotclassifier.Interface](r)
 	case Text2TextTask:
-		return tasks.Load[text2text.Interface](conf.loaderConfig)
+		return routeOf[text2text.Interface](r)
 	case QuestionAnsweringTask:
-		return tasks.Load[questionanswering.Interface](conf.loaderConfig)
+		return routeOf[questionanswering.Interface](r)
 	case TextClassificationTask:
-		return tasks.Load[textclassification.Interface](conf.loaderConfig)
+		return routeOf[textclassification.Interface](r)
 	case TokenClassificationTask:
-		return tasks.Load[tokenclassification.Interface](conf.loaderConfig)
+		return routeOf[tokenclassification.Interface](r)
 	case TextEncodingTask:
-		return tasks.Load[textencoding.Interface](conf.loaderConfig)
+		return routeOf[textencoding.Interface](r)
 	case LanguageModelingTask:
-		return tasks.Load[languagemodeling.Interface](conf.loaderConfig)
+		return routeOf[languagemodeling.Interface](r)
+	case CoreferenceResolutionTask:
+		return routeOf[coreference.Interface](r)
+	case RelationExtractionTask:
+		return routeOf[relationextraction.Interface](r)
+	case SentenceSegmentationTask:
+		return routeOf[sentencesegmentation.Interface](r)
+	case POSTaggingTask:
+		return routeOf[postagging.Interface](r)
 	default:
-		return nil, fmt.Errorf("failed to load model/task type %s", conf.task)
+		return nil, fmt.Errorf("variants not supported for task %s", task)
 	}
 }
 
-// initLogger initializes the logger.
+func routeOf[T any](r variantRoute) (T, error) {
+	variants := make([]tasks.Variant[T], len(r.variants))
+	for i, lm := range r.variants {
+		variants[i] = tasks.Variant[T]{Model: lm.model.(T), Version: lm.id(), Share: r.traffic[i], Shadow: r.shadows[i]}
+	}
+	return tasks.Route(r.name, variants, r.metrics)
+}
+
+// handlerOptions are the options of the request handlers of the models.
+type handlerOptions struct {
+	// cache is the response cache, whose store is shared by all the models,
+	// if enabled.
+	cache *server.ResponseCache
+	// coalesce is whether the concurrent identical requests are coalesced.
+	coalesce bool
+	// embeddings is the cache of the embeddings of the text encoders, if enabled.
+	embeddings *embeddingcache.Cache
+	// audit is the audit log of the requests, shared by all the models, if
+	// enabled.
+	audit *audit.Logger
+	// latency records the latency of the requests of all the models, if set.
+	latency *server.LatencyMetrics
+}
+
+// resolveRequestHandler returns the request handler serving all the models.
+func resolveRequestHandler(models []*loadedModel, opts handlerOptions) (server.RequestHandler, error) {
+	handlers := make(server.RequestHandlers, len(models))
+	for i, lm := range models {
+		h, err := server.ResolveRequestHandler(withEmbeddingCache(lm.model, opts.embeddings, lm.id()))
+		if err != nil {
+			return nil, err
+		}
+		if opts.cache != nil {
+			h = server.WithResponseCache(h, &server.ResponseCache{
+				Store: opts.cache.Store,
+				Model: lm.id(),
+				TTL:   opts.cache.TTL,
+			})
+		}
+		if opts.coalesce {
+			h = server.WithRequestCoalescing(h)
+		}
+		if lm.config.Timeout > 0 {
+			h = server.WithTimeout(h, lm.config.Timeout)
+		}
+		h = server.WithNormalization(h, lm.config.Normalization)
+		if opts.audit != nil {
+			h = server.WithAuditLog(h, &server.AuditLog{Logger: opts.audit, Model: lm.id()})
+		}
+		if opts.latency != nil {
+			h = server.WithLatency(h, opts.latency, lm.id())
+		}
+		handlers[i] = h
+	}
+	if len(handlers) == 1 {
+		return handlers[0], nil
+	}
+	return handlers, nil
+}
+
+// openEmbeddingCache opens the embedding cache, if configured.
+func openEmbeddingCache(conf *config) (*embeddingcache.Cache, error) {
+	if conf.embeddingCacheDir == "" {
+		return nil, nil
+	}
+	return embeddingcache.Open(conf.embeddingCacheDir, int64(conf.embeddingCacheSize)<<20)
+}
+
+// openAuditLog opens the audit log, returning it with its sink to close.
+func openAuditLog(conf *config) (*audit.Logger, *audit.JSONSink, error) {
+	redactors := make([]audit.Redactor, len(conf.auditRedact))
+	for i, name := range conf.auditRedact {
+		r, err := audit.BuiltinRedactor(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		redactors[i] = r
+	}
+	var sink *audit.JSONSink
+	if conf.auditLog == "-" {
+		sink = audit.NewJSONSink(os.Stdout)
+	} else {
+		var err error
+		if sink, err = audit.OpenFile(conf.auditLog); err != nil {
+			return nil, nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+	}
+	return &audit.Logger{Sink: sink, Redactors: redactors, MaxLength: conf.auditMaxLength}, sink, nil
+}
+
+// withEmbeddingCache returns the model caching its embeddings, identified by
+// the model id, if it's a text encoder and the cache is not nil.
+func withEmbeddingCache(m any, cache *embeddingcache.Cache, id string) any {
+	if enc, ok := m.(textencoding.Interface); ok && cache != nil {
+		return embeddingcache.Wrap(enc, cache, id)
+	}
+	return m
+}
+
+// modelHealth returns the function reporting the load status of the models,
+// or of the members of the ensembles and of the variants, read while none
+// of them is swapped.
+func modelHealth(models []*loadedModel, adapters *adapterAdmin) func() []server.ModelHealth {
+	return func() []server.ModelHealth {
+		adapters.lockSwap()
+		defer adapters.unlockSwap()
+		leaves := leafModels(models)
+		health := make([]server.ModelHealth, len(leaves))
+		for i, lm := range leaves {
+			status, err := tasks.LoadStatus(lm.model)
+			health[i] = server.ModelHealth{Model: lm.id(), Status: status}
+			if err != nil {
+				health[i].Error = err.Error()
+			}
+		}
+		return health
+	}
+}
+
+// finalizeModels finalizes all the models.
+func finalizeModels(models []*loadedModel) {
+	for _, lm := range models {
+		tasks.Finalize(lm.model)
+	}
+}
+
+func loadModelForTask(task TaskType, loaderConfig *tasks.Config) (m any, err error) {
+	switch task {
+	case ZeroShotClassificationTask:
+		return tasks.Load[zeroshotclassifier.Interface](loaderConfig)
+	case Text2TextTask:
+		return tasks.Load[text2text.Interface](loaderConfig)
+	case QuestionAnsweringTask:
+		return tasks.Load[questionanswering.Interface](loaderConfig)
+	case TextClassificationTask:
+		return tasks.Load[textclassification.Interface](loaderConfig)
+	case TokenClassificationTask:
+		return tasks.Load[tokenclassification.Interface](loaderConfig)
+	case TextEncodingTask:
+		return tasks.Load[textencoding.Interface](loaderConfig)
+	case LanguageModelingTask:
+		return tasks.Load[languagemodeling.Interface](loaderConfig)
+	case CoreferenceResolutionTask:
+		return tasks.Load[coreference.Interface](loaderConfig)
+	case RelationExtractionTask:
+		return tasks.Load[relationextraction.Interface](loaderConfig)
+	case SentenceSegmentationTask:
+		return tasks.Load[sentencesegmentation.Interface](loaderConfig)
+	case POSTaggingTask:
+		return tasks.Load[postagging.Interface](loaderConfig)
+	default:
+		return nil, fmt.Errorf("failed to load model/task type %s", task)
+	}
+}
+
+// logCloser closes the sinks of the logger set up from the configuration.
+var logCloser io.Closer
+
+// initLogger initializes the logger with the defaults, for the logs before
+// the configuration is parsed.
 func initLogger() {
-	log.Logger = log.Output(zerolog.ConsoleWriter{
-		Out:        os.Stderr,
-		TimeFormat: time.RFC3339,
-	})
-	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	_ = setupLogger(logging.Config{})
+}
+
+// setupLogger sets up the logger from the configuration, closing the sinks
+// of the previous one, if any.
+func setupLogger(c logging.Config) error {
+	closer, err := logging.Setup(c)
+	if err != nil {
+		return err
+	}
+	closeLogger()
+	logCloser = closer
+	return nil
+}
+
+// closeLogger closes the sinks of the logger, e.g. its file.
+func closeLogger() {
+	if logCloser == nil {
+		return
+	}
+	if err := logCloser.Close(); err != nil {
+		log.Warn().Err(err).Msg("failed to close the logs")
+	}
+	logCloser = nil
 }
 
 // loadDotenv loads the .env file if it exists.
@@ -0,0 +1,56 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"github.com/nlpodyssey/cybertron/pkg/server"
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readyClassifier is a text classification model not loaded lazily.
+type readyClassifier struct{}
+
+func (readyClassifier) Classify(context.Context, string, textclassification.Parameters) (textclassification.Response, error) {
+	return textclassification.Response{}, nil
+}
+
+func TestModelHealth(t *testing.T) {
+	conf := &tasks.Config{
+		ModelsDir:      t.TempDir(),
+		ModelName:      "org/missing",
+		Revision:       "main",
+		DownloadPolicy: tasks.DownloadNever,
+		Lazy:           true,
+	}
+	lazy, err := tasks.Load[textclassification.Interface](conf)
+	require.NoError(t, err)
+	ready := &tasks.Config{ModelName: "org/ready", Revision: "main"}
+	models := []*loadedModel{
+		{config: ready, model: readyClassifier{}},
+		{config: &tasks.Config{ModelName: "ensemble"}, members: []*loadedModel{
+			{config: conf, model: lazy},
+		}},
+	}
+	health := modelHealth(models, &adapterAdmin{models: models})
+
+	assert.Equal(t, []server.ModelHealth{
+		{Model: "org/ready@main", Status: tasks.LoadReady},
+		{Model: "org/missing@main", Status: tasks.LoadPending},
+	}, health(), "the members of the ensembles are reported")
+
+	_, err = lazy.Classify(context.Background(), "text", textclassification.Parameters{})
+	require.ErrorIs(t, err, errdefs.ErrModelLoadFailed)
+	got := health()
+	require.Len(t, got, 2)
+	assert.Equal(t, tasks.LoadFailed, got[1].Status)
+	assert.NotEmpty(t, got[1].Error)
+}
@@ -0,0 +1,362 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/onnx"
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/textnorm"
+)
+
+// modelsManifest is the content of the models manifest file: the models to
+// download, convert and load at startup, each serving a different task.
+//
+// Example:
+//
+//	{
+//	  "models": [
+//	    {"task": "text-encoding", "model": "sentence-transformers/all-MiniLM-L6-v2"},
+//	    {"task": "text-classification", "model": "org/classifier", "revision": "v1.0", "conversion_quantization": "int8"},
+//	    {"task": "question-answering", "model": "qa-ensemble", "ensemble": [
+//	      {"model": "org/qa-large", "weight": 2},
+//	      {"model": "org/qa-base"}
+//	    ]},
+//	    {"task": "zero-shot-classification", "model": "zero-shot", "variants": [
+//	      {"model": "org/nli", "revision": "v1", "traffic": 90},
+//	      {"model": "org/nli", "revision": "v2", "traffic": 10},
+//	      {"model": "org/nli-large", "shadow": true}
+//	    ]}
+//	  ]
+//	}
+type modelsManifest struct {
+	Models []manifestModel `json:"models" yaml:"models"`
+}
+
+// manifestModel is a model of the manifest, or of the configuration file.
+// The options which are not set default to the server-wide ones.
+//
+// A model with an ensemble is named by Model, and combines the outputs of
+// the models of the ensemble, each weighted by its Weight (default 1). Their
+// options default to the ones of the ensemble, and their task is the one of
+// the ensemble.
+//
+// Likewise, a model with variants is served by its variants, e.g. versions
+// of the same model, each serving its Traffic percentage of the requests:
+// the variants not setting it share the rest equally. The Shadow variants
+// are sent a copy of all the requests instead, and their results discarded.
+type manifestModel struct {
+	Task                   string            `json:"task" yaml:"task"`
+	Model                  string            `json:"model" yaml:"model"`
+	HubAccessToken         *string           `json:"hub_access_token" yaml:"hub_access_token,omitempty"`
+	Revision               *string           `json:"revision" yaml:"revision,omitempty"`
+	Bundle                 *string           `json:"bundle" yaml:"bundle,omitempty"`
+	Download               *string           `json:"download" yaml:"download,omitempty"`
+	Conversion             *string           `json:"conversion" yaml:"conversion,omitempty"`
+	ConversionPrecision    *string           `json:"conversion_precision" yaml:"conversion_precision,omitempty"`
+	ConversionQuantization *string           `json:"conversion_quantization" yaml:"conversion_quantization,omitempty"`
+	ConversionGGUF         *bool             `json:"conversion_gguf" yaml:"conversion_gguf,omitempty"`
+	ConversionVerification *bool             `json:"conversion_verification" yaml:"conversion_verification,omitempty"`
+	Backend                *string           `json:"backend" yaml:"backend,omitempty"`
+	Replicas               *int              `json:"replicas" yaml:"replicas,omitempty"`
+	NUMANodes              *string           `json:"numa_nodes" yaml:"numa_nodes,omitempty"`
+	Device                 *string           `json:"device" yaml:"device,omitempty"`
+	IntraOpParallelism     *int              `json:"intra_op_parallelism" yaml:"intra_op_parallelism,omitempty"`
+	InterOpParallelism     *int              `json:"inter_op_parallelism" yaml:"inter_op_parallelism,omitempty"`
+	PriorityWeight         *int              `json:"priority_weight" yaml:"priority_weight,omitempty"`
+	Preemption             *bool             `json:"preemption" yaml:"preemption,omitempty"`
+	Lazy                   *bool             `json:"lazy" yaml:"lazy,omitempty"`
+	PrecomputePositions    *bool             `json:"precompute_positions" yaml:"precompute_positions,omitempty"`
+	MemoryLimit            *int              `json:"memory_limit" yaml:"memory_limit,omitempty"`
+	AttentionWindow        *int              `json:"attention_window" yaml:"attention_window,omitempty"`
+	KernelTuning           *bool             `json:"kernel_tuning" yaml:"kernel_tuning,omitempty"`
+	RopeScaling            *string           `json:"rope_scaling" yaml:"rope_scaling,omitempty"`
+	Timeout                *string           `json:"timeout" yaml:"timeout,omitempty"`
+	Normalization          *string           `json:"normalization" yaml:"normalization,omitempty"`
+	Calibration            *string           `json:"calibration" yaml:"calibration,omitempty"`
+	Adapters               map[string]string `json:"adapters" yaml:"adapters,omitempty"`
+	Ensemble               []manifestModel   `json:"ensemble" yaml:"ensemble,omitempty"`
+	Weight                 *float64          `json:"weight" yaml:"weight,omitempty"`
+	Variants               []manifestModel   `json:"variants" yaml:"variants,omitempty"`
+	Traffic                *float64          `json:"traffic" yaml:"traffic,omitempty"`
+	Shadow                 *bool             `json:"shadow" yaml:"shadow,omitempty"`
+}
+
+// readModelsManifest reads and validates the models of the models manifest file.
+func readModelsManifest(filename string) ([]manifestModel, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	m := &modelsManifest{}
+	if err := dec.Decode(m); err != nil {
+		return nil, fmt.Errorf("invalid models manifest %#v: %w", filename, err)
+	}
+	if len(m.Models) == 0 {
+		return nil, fmt.Errorf("invalid models manifest %#v: no models", filename)
+	}
+	if err := validateModels(m.Models); err != nil {
+		return nil, fmt.Errorf("invalid models manifest %#v: %w", filename, err)
+	}
+	return m.Models, nil
+}
+
+// validateModels checks that each model has a name and a task, and that
+// each task is served by a single model.
+func validateModels(models []manifestModel) error {
+	seen := make(map[string]bool, len(models))
+	for i, model := range models {
+		if model.Model == "" {
+			return fmt.Errorf("model #%d: model name not specified", i+1)
+		}
+		if _, err := ParseTaskType(model.Task); err != nil {
+			return fmt.Errorf("model %#v: %w", model.Model, err)
+		}
+		if model.Weight != nil {
+			return fmt.Errorf("model %#v: weight only valid for the models of an ensemble", model.Model)
+		}
+		if model.Traffic != nil || model.Shadow != nil {
+			return fmt.Errorf("model %#v: traffic and shadow only valid for the variants of a model", model.Model)
+		}
+		if len(model.Ensemble) > 0 && len(model.Variants) > 0 {
+			return fmt.Errorf("model %#v: both ensemble and variants specified", model.Model)
+		}
+		if err := validateEnsemble(model); err != nil {
+			return fmt.Errorf("model %#v: %w", model.Model, err)
+		}
+		if err := validateVariants(model); err != nil {
+			return fmt.Errorf("model %#v: %w", model.Model, err)
+		}
+		// Each task is served by a single gRPC/HTTP service.
+		if seen[model.Task] {
+			return fmt.Errorf("more than one model for task %#v", model.Task)
+		}
+		seen[model.Task] = true
+	}
+	return nil
+}
+
+// ensembleTasks are the tasks whose models can be combined in an ensemble.
+var ensembleTasks = []TaskType{TextClassificationTask, ZeroShotClassificationTask, QuestionAnsweringTask}
+
+// validateEnsemble checks the models of the ensemble, if any.
+func validateEnsemble(model manifestModel) error {
+	if len(model.Ensemble) == 0 {
+		return nil
+	}
+	supported := false
+	for _, t := range ensembleTasks {
+		supported = supported || t == TaskType(model.Task)
+	}
+	if !supported {
+		return fmt.Errorf("ensemble not supported for task %#v", model.Task)
+	}
+	if len(model.Ensemble) < 2 {
+		return errors.New("ensemble of less than two models")
+	}
+	for i, m := range model.Ensemble {
+		switch {
+		case m.Model == "":
+			return fmt.Errorf("ensemble model #%d: model name not specified", i+1)
+		case m.Task != "" && m.Task != model.Task:
+			return fmt.Errorf("ensemble model %#v: task %#v differs from the one of the ensemble", m.Model, m.Task)
+		case len(m.Ensemble) > 0 || len(m.Variants) > 0:
+			return fmt.Errorf("ensemble model %#v: nested ensembles or variants not supported", m.Model)
+		case m.Traffic != nil || m.Shadow != nil:
+			return fmt.Errorf("ensemble model %#v: traffic and shadow only valid for the variants of a model", m.Model)
+		case m.Weight != nil && !(*m.Weight > 0):
+			return fmt.Errorf("ensemble model %#v: weight must be positive", m.Model)
+		}
+	}
+	return nil
+}
+
+// validateVariants checks the variants of the model, if any.
+func validateVariants(model manifestModel) error {
+	if len(model.Variants) == 0 {
+		return nil
+	}
+	if len(model.Variants) < 2 {
+		return errors.New("less than two variants")
+	}
+	serving, unset, total := 0, 0, 0.0
+	for i, v := range model.Variants {
+		switch {
+		case v.Model == "":
+			return fmt.Errorf("variant #%d: model name not specified", i+1)
+		case v.Task != "" && v.Task != model.Task:
+			return fmt.Errorf("variant %#v: task %#v differs from the one of the model", v.Model, v.Task)
+		case len(v.Ensemble) > 0 || len(v.Variants) > 0:
+			return fmt.Errorf("variant %#v: nested ensembles or variants not supported", v.Model)
+		case v.Weight != nil:
+			return fmt.Errorf("variant %#v: weight only valid for the models of an ensemble", v.Model)
+		case v.Shadow != nil && *v.Shadow:
+			if v.Traffic != nil {
+				return fmt.Errorf("variant %#v: traffic of a shadow variant", v.Model)
+			}
+			continue
+		case v.Traffic == nil:
+			unset++
+		case !(*v.Traffic > 0 && *v.Traffic <= 100):
+			return fmt.Errorf("variant %#v: traffic must be a percentage greater than zero", v.Model)
+		default:
+			total += *v.Traffic
+		}
+		serving++
+	}
+	switch {
+	case serving == 0:
+		return errors.New("no variants serving the traffic")
+	case total > 100+1e-9:
+		return fmt.Errorf("traffic of the variants over 100%% (%v%%)", total)
+	case unset == 0 && total < 100-1e-9:
+		return fmt.Errorf("traffic of the variants under 100%% (%v%%)", total)
+	case unset > 0 && total >= 100-1e-9:
+		return errors.New("no traffic left for the variants not setting it")
+	}
+	return nil
+}
+
+// members returns the models of the ensemble, with the task of the
+// ensemble, and their weights.
+func (m manifestModel) members() ([]manifestModel, []float64) {
+	members := make([]manifestModel, len(m.Ensemble))
+	weights := make([]float64, len(m.Ensemble))
+	for i, member := range m.Ensemble {
+		member.Task = m.Task
+		members[i] = member
+		weights[i] = 1
+		if member.Weight != nil {
+			weights[i] = *member.Weight
+		}
+	}
+	return members, weights
+}
+
+// variants returns the variants of the model, with the task of the model,
+// their percentages of the traffic, and whether they're shadows, whose
+// percentages are zero.
+func (m manifestModel) variants() ([]manifestModel, []float64, []bool) {
+	variants := make([]manifestModel, len(m.Variants))
+	traffic := make([]float64, len(m.Variants))
+	shadows := make([]bool, len(m.Variants))
+	left, unset := 100.0, 0
+	for _, v := range m.Variants {
+		switch {
+		case v.Shadow != nil && *v.Shadow:
+		case v.Traffic != nil:
+			left -= *v.Traffic
+		default:
+			unset++
+		}
+	}
+	for i, v := range m.Variants {
+		v.Task = m.Task
+		variants[i] = v
+		switch {
+		case v.Shadow != nil && *v.Shadow:
+			shadows[i] = true
+		case v.Traffic != nil:
+			traffic[i] = *v.Traffic
+		default:
+			traffic[i] = left / float64(unset)
+		}
+	}
+	return variants, traffic, shadows
+}
+
+// loaderConfig returns the loader configuration of the model: a copy of the
+// server-wide configuration, overridden by the options of the model.
+func (m manifestModel) loaderConfig(base *tasks.Config) (*tasks.Config, error) {
+	c := *base
+	c.ModelName = m.Model
+	// The adapters are specific to each model, and not shared.
+	c.Adapters = m.Adapters
+	if m.HubAccessToken != nil {
+		c.HubAccessToken = *m.HubAccessToken
+	}
+	if m.Revision != nil {
+		c.Revision = *m.Revision
+	}
+	if m.Bundle != nil {
+		c.Bundle = *m.Bundle
+	}
+	if m.ConversionGGUF != nil {
+		c.ConversionGGUF = *m.ConversionGGUF
+	}
+	if m.ConversionVerification != nil {
+		c.ConversionVerification = *m.ConversionVerification
+	}
+	if m.Replicas != nil {
+		c.Replicas = *m.Replicas
+	}
+	if m.NUMANodes != nil {
+		c.NUMANodes = *m.NUMANodes
+	}
+	if m.Device != nil {
+		c.Device = *m.Device
+	}
+	if m.IntraOpParallelism != nil {
+		c.IntraOpParallelism = *m.IntraOpParallelism
+	}
+	if m.InterOpParallelism != nil {
+		c.InterOpParallelism = *m.InterOpParallelism
+	}
+	if m.PriorityWeight != nil {
+		c.PriorityWeight = *m.PriorityWeight
+	}
+	if m.Preemption != nil {
+		c.Preemption = *m.Preemption
+	}
+	if m.Lazy != nil {
+		c.Lazy = *m.Lazy
+	}
+	if m.PrecomputePositions != nil {
+		c.PrecomputePositions = *m.PrecomputePositions
+	}
+	if m.MemoryLimit != nil {
+		c.MemoryLimit = *m.MemoryLimit
+	}
+	if m.AttentionWindow != nil {
+		c.AttentionWindow = *m.AttentionWindow
+	}
+	if m.KernelTuning != nil {
+		c.KernelTuning = *m.KernelTuning
+	}
+	if m.Calibration != nil {
+		c.Calibration = *m.Calibration
+	}
+	err := errors.Join(
+		parseOption(m.Download, tasks.ParseDownloadPolicy, &c.DownloadPolicy),
+		parseOption(m.Conversion, tasks.ParseConversionPolicy, &c.ConversionPolicy),
+		parseOption(m.ConversionPrecision, tasks.ParseFloatPrecision, &c.ConversionPrecision),
+		parseOption(m.ConversionQuantization, quantization.ParseScheme, &c.ConversionQuantization),
+		parseOption(m.Backend, tasks.ParseBackend, &c.Backend),
+		parseOption(m.RopeScaling, onnx.ParseRopeScaling, &c.RopeScaling),
+		parseOption(m.Timeout, time.ParseDuration, &c.Timeout),
+		parseOption(m.Normalization, textnorm.ParseOptions, &c.Normalization),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("model %#v: %w", m.Model, err)
+	}
+	return &c, nil
+}
+
+// parseOption parses the option, if set, and assigns it to dest.
+func parseOption[T any](v *string, parse func(string) (T, error), dest *T) error {
+	if v == nil {
+		return nil
+	}
+	return flagParseFunc(parse, dest)(*v)
+}
@@ -0,0 +1,221 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadModelsManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string // the names of the models, if valid
+	}{
+		{
+			name: "valid",
+			content: `{"models": [
+				{"task": "text-encoding", "model": "org/encoder"},
+				{"task": "text-classification", "model": "org/classifier", "revision": "v1.0"}
+			]}`,
+			want: []string{"org/encoder", "org/classifier"},
+		},
+		{name: "invalid json", content: `{"models": [`},
+		{name: "unknown field", content: `{"models": [{"task": "text-encoding", "model": "m", "unknown": 1}]}`},
+		{name: "no models", content: `{"models": []}`},
+		{name: "no model name", content: `{"models": [{"task": "text-encoding"}]}`},
+		{name: "invalid task", content: `{"models": [{"task": "translation", "model": "m"}]}`},
+		{
+			name: "duplicate task",
+			content: `{"models": [
+				{"task": "text-encoding", "model": "a"},
+				{"task": "text-encoding", "model": "b"}
+			]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "models.json")
+			require.NoError(t, os.WriteFile(filename, []byte(tt.content), 0o644))
+			models, err := readModelsManifest(filename)
+			if tt.want == nil {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			var names []string
+			for _, m := range models {
+				names = append(names, m.Model)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+
+	_, err := readModelsManifest(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestManifestModel_LoaderConfig(t *testing.T) {
+	base := &tasks.Config{
+		ModelsDir:      "models",
+		ModelName:      "base",
+		Revision:       "main",
+		HubAccessToken: "token",
+		Replicas:       1,
+		Timeout:        time.Second,
+	}
+	revision, replicas, timeout, quant, lazy := "v1.0", 2, "5s", "int8", true
+	m := manifestModel{
+		Task:                   "text-encoding",
+		Model:                  "org/encoder",
+		Revision:               &revision,
+		Replicas:               &replicas,
+		Timeout:                &timeout,
+		ConversionQuantization: &quant,
+		Lazy:                   &lazy,
+	}
+	c, err := m.loaderConfig(base)
+	require.NoError(t, err)
+	assert.Equal(t, "models", c.ModelsDir)
+	assert.Equal(t, "org/encoder", c.ModelName)
+	assert.Equal(t, "v1.0", c.Revision)
+	assert.Equal(t, "token", c.HubAccessToken, "the options not set default to the server-wide ones")
+	assert.Equal(t, 2, c.Replicas)
+	assert.Equal(t, 5*time.Second, c.Timeout)
+	assert.Equal(t, quantization.Int8, c.ConversionQuantization)
+	assert.True(t, c.Lazy)
+	assert.False(t, base.Lazy)
+	assert.Equal(t, "base", base.ModelName, "the server-wide configuration is not modified")
+
+	invalid := []struct {
+		name string
+		set  func(m *manifestModel, v *string)
+	}{
+		{"download", func(m *manifestModel, v *string) { m.Download = v }},
+		{"conversion", func(m *manifestModel, v *string) { m.Conversion = v }},
+		{"conversion precision", func(m *manifestModel, v *string) { m.ConversionPrecision = v }},
+		{"conversion quantization", func(m *manifestModel, v *string) { m.ConversionQuantization = v }},
+		{"backend", func(m *manifestModel, v *string) { m.Backend = v }},
+		{"timeout", func(m *manifestModel, v *string) { m.Timeout = v }},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			m := manifestModel{Task: "text-encoding", Model: "org/encoder"}
+			v := "invalid"
+			tt.set(&m, &v)
+			_, err := m.loaderConfig(base)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestValidateModels_Ensemble(t *testing.T) {
+	weight := func(w float64) *float64 { return &w }
+	tests := []struct {
+		name    string
+		model   manifestModel
+		wantErr string
+	}{
+		{
+			name: "valid",
+			model: manifestModel{Task: "question-answering", Model: "qa", Ensemble: []manifestModel{
+				{Model: "org/qa-large", Weight: weight(2)},
+				{Model: "org/qa-base", Task: "question-answering"},
+			}},
+		},
+		{
+			name: "unsupported task",
+			model: manifestModel{Task: "text-encoding", Model: "e", Ensemble: []manifestModel{
+				{Model: "a"}, {Model: "b"},
+			}},
+			wantErr: "ensemble not supported",
+		},
+		{
+			name:    "single model",
+			model:   manifestModel{Task: "text-classification", Model: "e", Ensemble: []manifestModel{{Model: "a"}}},
+			wantErr: "less than two models",
+		},
+		{
+			name: "no model name",
+			model: manifestModel{Task: "text-classification", Model: "e", Ensemble: []manifestModel{
+				{Model: "a"}, {Weight: weight(1)},
+			}},
+			wantErr: "model name not specified",
+		},
+		{
+			name: "other task",
+			model: manifestModel{Task: "text-classification", Model: "e", Ensemble: []manifestModel{
+				{Model: "a"}, {Model: "b", Task: "question-answering"},
+			}},
+			wantErr: "differs from the one of the ensemble",
+		},
+		{
+			name: "nested ensemble",
+			model: manifestModel{Task: "text-classification", Model: "e", Ensemble: []manifestModel{
+				{Model: "a"}, {Model: "b", Ensemble: []manifestModel{{Model: "c"}, {Model: "d"}}},
+			}},
+			wantErr: "nested ensembles",
+		},
+		{
+			name: "zero weight",
+			model: manifestModel{Task: "text-classification", Model: "e", Ensemble: []manifestModel{
+				{Model: "a"}, {Model: "b", Weight: weight(0)},
+			}},
+			wantErr: "weight must be positive",
+		},
+		{
+			name:    "weight outside of an ensemble",
+			model:   manifestModel{Task: "text-classification", Model: "m", Weight: weight(1)},
+			wantErr: "weight only valid for the models of an ensemble",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateModels([]manifestModel{tt.model})
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestManifestModel_Members(t *testing.T) {
+	weight := 2.0
+	m := manifestModel{Task: "text-classification", Model: "e", Ensemble: []manifestModel{
+		{Model: "a", Weight: &weight},
+		{Model: "b"},
+	}}
+	members, weights := m.members()
+	require.Len(t, members, 2)
+	assert.Equal(t, "text-classification", members[0].Task, "the members have the task of the ensemble")
+	assert.Equal(t, "text-classification", members[1].Task)
+	assert.Equal(t, []float64{2, 1}, weights)
+	assert.Empty(t, m.Ensemble[1].Task, "the ensemble is not modified")
+}
+
+func TestRedactModels(t *testing.T) {
+	token, empty := "secret", ""
+	models := []manifestModel{{
+		Model:          "e",
+		HubAccessToken: &token,
+		Ensemble:       []manifestModel{{Model: "a", HubAccessToken: &token}, {Model: "b", HubAccessToken: &empty}},
+	}}
+	out := redactModels(models)
+	assert.Equal(t, redacted, *out[0].HubAccessToken)
+	assert.Equal(t, redacted, *out[0].Ensemble[0].HubAccessToken)
+	assert.Equal(t, "", *out[0].Ensemble[1].HubAccessToken)
+	assert.Equal(t, "secret", *models[0].Ensemble[0].HubAccessToken, "the models are not modified")
+	assert.Nil(t, redactModels(nil))
+}
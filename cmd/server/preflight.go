@@ -0,0 +1,95 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+
+	"github.com/nlpodyssey/cybertron/pkg/downloader"
+	"github.com/nlpodyssey/cybertron/pkg/preflight"
+	"github.com/nlpodyssey/cybertron/pkg/server"
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/rs/zerolog/log"
+)
+
+// runPreflight runs the preflight checks of the configuration, without
+// serving the models, e.g. in a deployment pipeline.
+func runPreflight(args []string) error {
+	conf, _, err := parseConfig("preflight", args, nil)
+	if err != nil {
+		return err
+	}
+	if err := preflightChecks(conf); err != nil {
+		return err
+	}
+	log.Info().Msg("preflight checks passed")
+	return nil
+}
+
+// preflightChecks checks the configuration, the models and the resources
+// they need, reporting all the problems found at once.
+func preflightChecks(conf *config) error {
+	configs, err := modelConfigs(conf)
+	if err != nil {
+		return err
+	}
+	opts := preflight.Options{
+		Network:  conf.serverConfig.Network,
+		Address:  conf.serverConfig.Address,
+		Problems: configProblems(conf),
+	}
+	if opts.Network == "" {
+		opts.Network = server.DefaultNetwork
+	}
+	for _, c := range configs {
+		if c.ModelName == "" {
+			continue
+		}
+		opts.Models = append(opts.Models, preflight.Model{
+			Name:      c.ModelName,
+			ModelsDir: c.ModelsDir,
+			Local:     isLocalModel(c),
+			External:  c.Backend == tasks.BackendONNX,
+		})
+	}
+	return preflight.Run(opts)
+}
+
+// isLocalModel returns whether the model must already be in the models
+// directory, since it can't be downloaded.
+func isLocalModel(c *tasks.Config) bool {
+	if c.Resolver != nil || c.Bundle != "" || c.ModelStore != "" {
+		return false
+	}
+	return c.Backend == tasks.BackendONNX || c.DownloadPolicy == tasks.DownloadNever || c.Offline || downloader.IsOfflineEnv()
+}
+
+// configProblems returns the problems of the configuration which the
+// parsing of the options doesn't find.
+func configProblems(conf *config) []preflight.Problem {
+	var problems []preflight.Problem
+	s := conf.serverConfig
+	if s.TLSEnabled {
+		if _, err := tls.LoadX509KeyPair(s.TLSCert, s.TLSKey); err != nil {
+			problems = append(problems, preflight.Problem{
+				Subject: "TLS",
+				Message: err.Error(),
+				Fix:     "set -tls-cert and -tls-key to the PEM files of the certificate and of its private key",
+			})
+		}
+	}
+	if dir := conf.loaderConfig.ModelsDir; dir != "" {
+		if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+			problems = append(problems, preflight.Problem{
+				Subject: "models directory",
+				Message: fmt.Sprintf("%s is not a directory", dir),
+				Fix:     "set -models-dir to a directory",
+			})
+		}
+	}
+	return problems
+}
@@ -0,0 +1,207 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/rs/zerolog/log"
+)
+
+// progressSubscriberBuffer is the number of progress events buffered for
+// each client of the admin API, beyond which the events are dropped for a
+// slow client, rather than slowing down the downloads.
+const progressSubscriberBuffer = 64
+
+// progressAdmin holds the progress of the loading of the models, e.g. of
+// their downloads, streaming it to the admin API as server-sent events, and
+// drawing it on the terminal, if any.
+type progressAdmin struct {
+	mu sync.Mutex
+	// last is the last progress of each model, with the one of each file
+	// it's downloading, by model and file.
+	last map[string]tasks.Progress
+	// subscribers are the channels of the clients of the admin API.
+	subscribers map[chan tasks.Progress]struct{}
+	// bar draws the downloads on the terminal, if any.
+	bar *progressBar
+}
+
+func newProgressAdmin(terminal io.Writer) *progressAdmin {
+	p := &progressAdmin{
+		last:        make(map[string]tasks.Progress),
+		subscribers: make(map[chan tasks.Progress]struct{}),
+	}
+	if terminal != nil {
+		p.bar = &progressBar{w: terminal}
+	}
+	return p
+}
+
+// report records the progress, sends it to the clients of the admin API,
+// and draws it on the terminal. It's the Progress of the loader
+// configuration.
+func (p *progressAdmin) report(tp tasks.Progress) {
+	p.mu.Lock()
+	if tp.Stage == tasks.StageReady {
+		for key, last := range p.last {
+			if last.Model == tp.Model {
+				delete(p.last, key)
+			}
+		}
+	}
+	p.last[progressKey(tp)] = tp
+	for ch := range p.subscribers {
+		select {
+		case ch <- tp:
+		default:
+		}
+	}
+	p.mu.Unlock()
+	if p.bar != nil {
+		p.bar.draw(tp)
+	}
+}
+
+// progressKey returns the key of the progress in progressAdmin.last.
+func progressKey(tp tasks.Progress) string {
+	if tp.Download != nil {
+		return tp.Model + "\x00" + tp.Download.File
+	}
+	return tp.Model
+}
+
+// snapshot returns the last progress of the models, sorted by model and
+// file.
+func (p *progressAdmin) snapshot() []tasks.Progress {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	keys := make([]string, 0, len(p.last))
+	for key := range p.last {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	list := make([]tasks.Progress, len(keys))
+	for i, key := range keys {
+		list[i] = p.last[key]
+	}
+	return list
+}
+
+func (p *progressAdmin) subscribe() chan tasks.Progress {
+	ch := make(chan tasks.Progress, progressSubscriberBuffer)
+	p.mu.Lock()
+	p.subscribers[ch] = struct{}{}
+	p.mu.Unlock()
+	return ch
+}
+
+func (p *progressAdmin) unsubscribe(ch chan tasks.Progress) {
+	p.mu.Lock()
+	delete(p.subscribers, ch)
+	p.mu.Unlock()
+}
+
+// ServeHTTP streams the progress of the loading of the models as
+// server-sent events, each a "progress" event with the JSON of a
+// tasks.Progress: the last progress of the models first, then each one
+// reported, until the client disconnects.
+func (p *progressAdmin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	ch := p.subscribe()
+	defer p.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	for _, tp := range p.snapshot() {
+		if err := writeProgressEvent(w, tp); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case tp := <-ch:
+			if err := writeProgressEvent(w, tp); err != nil {
+				log.Debug().Err(err).Msg("failed to write the progress event")
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeProgressEvent(w io.Writer, tp tasks.Progress) error {
+	data, err := json.Marshal(tp)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: progress\ndata: %s\n\n", data)
+	return err
+}
+
+// progressBarWidth is the number of characters of the progress bars.
+const progressBarWidth = 30
+
+// progressBar draws the progress of the downloads on the terminal, on a
+// line rewritten in place, ended once the download is done.
+type progressBar struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+func (b *progressBar) draw(tp tasks.Progress) {
+	d := tp.Download
+	if d == nil {
+		return
+	}
+	line := fmt.Sprintf("%s %s %s", tp.Model, path.Base(d.File), formatBytes(d.Downloaded))
+	if pct := d.Percent(); pct >= 0 {
+		filled := int(pct * progressBarWidth / 100)
+		line = fmt.Sprintf("%s [%s%s] %5.1f%% %s/%s", line,
+			strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled), pct,
+			formatBytes(d.Downloaded), formatBytes(d.Total))
+	}
+	if d.ETA > 0 {
+		line += " ETA " + d.ETA.String()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// Carriage return and erase the line, to draw it again in place.
+	fmt.Fprint(b.w, "\r\x1b[K"+line)
+	if d.Done {
+		fmt.Fprintln(b.w)
+	}
+}
+
+// stderrTerminal returns the standard error if it's a terminal, or nil.
+func stderrTerminal() io.Writer {
+	fi, err := os.Stderr.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	return os.Stderr
+}
@@ -0,0 +1,171 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+)
+
+const replHelp = `Type an input to run the model on it, or one of the commands:
+  :set <option> <value>  set an option (e.g. ":set temperature 0.7")
+  :options               show the options
+  :tokens <text>         show the tokens of the text, as seen by the model
+  :help                  show this help
+  :quit                  exit (or Ctrl-D)
+`
+
+// repl loads the model once, and runs it on each input typed by the user,
+// printing the results along with the time taken. The task-specific options
+// can be changed between the inputs.
+func repl(args []string) error {
+	var o inferenceOptions
+	conf, fs, err := parseConfig("repl", args, func(_ *config, fs *flag.FlagSet) { o.bind(fs) })
+	if err != nil {
+		return err
+	}
+	m, infer, err := loadForInference(conf, o)
+	if err != nil {
+		return err
+	}
+	defer tasks.Finalize(m)
+
+	r := &replSession{
+		model:   m,
+		infer:   infer,
+		options: &o,
+		fs:      fs,
+		out:     os.Stdout,
+	}
+	return r.run(os.Stdin)
+}
+
+// replSession is the state of the repl subcommand.
+type replSession struct {
+	model   any
+	infer   inferenceFunc
+	options *inferenceOptions
+	// fs is the flag set the options are bound to, used to set them.
+	fs  *flag.FlagSet
+	out io.Writer
+}
+
+// run reads and evaluates the lines of the reader until the end or the
+// :quit command.
+func (r *replSession) run(in io.Reader) error {
+	fmt.Fprint(r.out, replHelp)
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for {
+		fmt.Fprint(r.out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(r.out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, ":") {
+			r.evaluate(line)
+			continue
+		}
+		cmd, arg, _ := strings.Cut(line, " ")
+		arg = strings.TrimSpace(arg)
+		switch cmd {
+		case ":quit", ":q", ":exit":
+			return nil
+		case ":help", ":h":
+			fmt.Fprint(r.out, replHelp)
+		case ":options":
+			r.print(r.options.values())
+		case ":set":
+			if err := r.set(arg); err != nil {
+				fmt.Fprintf(r.out, "error: %v\n", err)
+			}
+		case ":tokens":
+			t, ok := tasks.AsTokenizer(r.model)
+			if !ok {
+				fmt.Fprintln(r.out, "error: the model doesn't expose its tokens")
+				continue
+			}
+			r.print(t.Tokenize(arg))
+		default:
+			fmt.Fprintf(r.out, "error: unknown command %s (type :help for the list)\n", cmd)
+		}
+	}
+}
+
+// evaluate runs the model on the input, and prints the result.
+func (r *replSession) evaluate(input string) {
+	start := time.Now()
+	result, err := r.infer(context.Background(), input)
+	if err != nil {
+		fmt.Fprintf(r.out, "error: %v\n", err)
+		return
+	}
+	r.print(result)
+	fmt.Fprintf(r.out, "(%s)\n", time.Since(start).Round(time.Microsecond))
+}
+
+// set sets the option given as "<option> <value>", and recreates the
+// inference function with the new options. The options are left unchanged
+// on error.
+func (r *replSession) set(arg string) error {
+	name, value, _ := strings.Cut(arg, " ")
+	if _, ok := r.options.values()[name]; !ok {
+		return fmt.Errorf("unknown option %#v", name)
+	}
+	// Some flag values, e.g. the integers, are zeroed by an invalid value.
+	prev := *r.options
+	if err := r.fs.Set(name, strings.TrimSpace(value)); err != nil {
+		*r.options = prev
+		return err
+	}
+	infer, err := newInferenceFunc(r.model, *r.options)
+	if err != nil {
+		*r.options = prev
+		return err
+	}
+	r.infer = infer
+	return nil
+}
+
+// print prints the value as indented JSON.
+func (r *replSession) print(v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintf(r.out, "error: %v\n", err)
+		return
+	}
+	fmt.Fprintln(r.out, string(data))
+}
+
+// values returns the options by flag name, the unset ones being nil.
+func (o *inferenceOptions) values() map[string]any {
+	return map[string]any{
+		"question":         o.question,
+		"labels":           o.labels,
+		"pooling-strategy": o.poolingStrategy,
+		"k":                o.k,
+		"layers":           o.layers,
+		"explain":          o.explain,
+		"text-pair":        o.textPair,
+		"nested":           o.nested,
+		"temperature":      o.generation.Temperature.ValuePtr(),
+		"sample":           o.generation.Sample.ValuePtr(),
+		"top-k":            o.generation.TopK.ValuePtr(),
+		"top-p":            o.generation.TopP.ValuePtr(),
+	}
+}
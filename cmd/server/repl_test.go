@@ -0,0 +1,107 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replModel is a text encoding model exposing its tokens.
+type replModel struct {
+	textencoding.Interface
+}
+
+func (replModel) Tokenize(text string) []string {
+	return strings.Fields(text)
+}
+
+func TestReplSession(t *testing.T) {
+	tests := []struct {
+		name  string
+		model any
+		input string
+		want  []string
+		// notWant are the outputs following the :quit command
+		notWant []string
+	}{
+		{
+			name:  "evaluate",
+			input: "hello\n\n",
+			want:  []string{`"HELLO"`},
+		},
+		{
+			name:    "quit",
+			input:   ":quit\nhello\n",
+			notWant: []string{`"HELLO"`},
+		},
+		{
+			name:  "set option",
+			input: ":set k 3\n:options\n",
+			want:  []string{`"k": 3`},
+		},
+		{
+			name:  "set invalid value",
+			input: ":set k many\n:options\n",
+			want:  []string{"error: parse error", `"k": 1`},
+		},
+		{
+			name:  "set unknown option",
+			input: ":set unknown 1\n",
+			want:  []string{`error: unknown option "unknown"`},
+		},
+		{
+			name:  "tokens",
+			input: ":tokens the cat\n",
+			want:  []string{"[\n  \"the\",\n  \"cat\"\n]"},
+		},
+		{
+			name:  "tokens not exposed",
+			model: struct{ textencoding.Interface }{},
+			input: ":tokens the cat\n",
+			want:  []string{"error: the model doesn't expose its tokens"},
+		},
+		{
+			name:  "unknown command",
+			input: ":unknown\n",
+			want:  []string{"error: unknown command :unknown"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var o inferenceOptions
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			o.bind(fs)
+			model := tt.model
+			if model == nil {
+				model = replModel{}
+			}
+			out := &bytes.Buffer{}
+			r := &replSession{
+				model: model,
+				infer: func(_ context.Context, input string) (any, error) {
+					return strings.ToUpper(input), nil
+				},
+				options: &o,
+				fs:      fs,
+				out:     out,
+			}
+			require.NoError(t, r.run(strings.NewReader(tt.input)))
+			for _, s := range tt.want {
+				assert.Contains(t, out.String(), s)
+			}
+			for _, s := range tt.notWant {
+				assert.NotContains(t, out.String(), s)
+			}
+		})
+	}
+}
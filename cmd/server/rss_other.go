@@ -0,0 +1,13 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !unix
+
+package main
+
+// peakRSS returns the peak resident set size of the process, in bytes. It's
+// not available on this platform.
+func peakRSS() (int64, bool) {
+	return 0, false
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRunner returns a runner upper-casing the inputs, failing on the
+// "fail" input, and the buffer of its results.
+func newTestRunner() (*inferenceRunner, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	infer := func(_ context.Context, input string) (any, error) {
+		if input == "fail" {
+			return nil, errors.New("inference failed")
+		}
+		return strings.ToUpper(input), nil
+	}
+	return &inferenceRunner{ctx: context.Background(), infer: infer, enc: json.NewEncoder(buf)}, buf
+}
+
+func TestParseInputFormat(t *testing.T) {
+	for _, s := range []string{"text", "jsonl"} {
+		got, err := parseInputFormat(s)
+		require.NoError(t, err)
+		assert.Equal(t, s, got)
+	}
+	for _, s := range []string{"", "json", "csv"} {
+		_, err := parseInputFormat(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestInferenceRunner_RunReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "text",
+			input: "a\n\n  b  \n",
+			want:  "\"A\"\n\"B\"\n",
+		},
+		{
+			name:   "jsonl",
+			format: jsonlInputFormat,
+			input:  "{\"id\": 1, \"input\": \"a\"}\n\n{\"input\": \"b\"}\n",
+			want:   "{\"id\":1,\"input\":\"a\",\"output\":\"A\"}\n{\"input\":\"b\",\"output\":\"B\"}\n",
+		},
+		{
+			name:    "inference error",
+			input:   "a\nfail\n",
+			wantErr: "line 2",
+		},
+		{
+			name:    "invalid json",
+			format:  jsonlInputFormat,
+			input:   "{\"input\": \"a\"}\n{\"input\":\n",
+			wantErr: "line 2",
+		},
+		{
+			name:    "missing input field",
+			format:  jsonlInputFormat,
+			input:   "{\"text\": \"a\"}\n",
+			wantErr: `missing string field "input"`,
+		},
+		{
+			name:    "input field not a string",
+			format:  jsonlInputFormat,
+			input:   "{\"input\": 1}\n",
+			wantErr: `missing string field "input"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, buf := newTestRunner()
+			err := r.runReader(strings.NewReader(tt.input), tt.format)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestInferenceRunner_RunFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		filename := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(filename, []byte(content), 0o644))
+		return filename
+	}
+	jsonl := write("inputs.jsonl", "{\"input\": \"a\"}\n")
+	text := write("inputs.txt", "{\"input\": \"a\"}\n")
+
+	tests := []struct {
+		name     string
+		filename string
+		format   string
+		want     string
+	}{
+		{"jsonl by extension", jsonl, "", "{\"input\":\"a\",\"output\":\"A\"}\n"},
+		{"text by extension", text, "", "\"{\\\"INPUT\\\": \\\"A\\\"}\"\n"},
+		{"text by format", jsonl, textInputFormat, "\"{\\\"INPUT\\\": \\\"A\\\"}\"\n"},
+		{"jsonl by format", text, jsonlInputFormat, "{\"input\":\"a\",\"output\":\"A\"}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, buf := newTestRunner()
+			require.NoError(t, r.runFile(tt.filename, tt.format))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+
+	r, _ := newTestRunner()
+	err := r.runFile(write("invalid.jsonl", "{\n"), "")
+	assert.ErrorContains(t, err, "invalid.jsonl: line 1")
+	assert.ErrorIs(t, r.runFile(filepath.Join(dir, "missing.txt"), ""), os.ErrNotExist)
+}
@@ -0,0 +1,156 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/downloader"
+	"github.com/nlpodyssey/cybertron/pkg/server"
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/evaluation"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/verification"
+	"github.com/rs/zerolog/log"
+)
+
+// updatesDirname is the directory, in the models directory, where the new
+// revisions of the models are downloaded and converted, by commit SHA, so
+// that the files of the models being served are never touched.
+const updatesDirname = ".updates"
+
+// updater periodically checks the Hub for new revisions of the served
+// models, and hot-swaps them in the server once they're downloaded,
+// converted and checked.
+type updater struct {
+	server   *server.Server
+	models   []*loadedModel
+	interval time.Duration
+	// handlerOptions are the options of the request handlers.
+	handlerOptions handlerOptions
+	// evaluator evaluates the new revisions before they're swapped in, if
+	// there are evaluation datasets.
+	evaluator *evaluator
+	// adapters are carried over to the new revisions.
+	adapters *adapterAdmin
+}
+
+// watch checks for updates every interval, until the context is done.
+func (u *updater) watch(ctx context.Context) {
+	t := time.NewTicker(u.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		for i, lm := range u.models {
+			if ctx.Err() != nil {
+				return
+			}
+			if err := u.update(ctx, i); err != nil {
+				log.Warn().Err(err).Str("model", lm.baseConfig.ModelName).Msg("failed to update model")
+			}
+		}
+	}
+}
+
+// update updates the i-th model, if there's a new revision on the Hub.
+func (u *updater) update(ctx context.Context, i int) error {
+	lm := u.models[i]
+	base := lm.baseConfig
+	// The models of the ensembles, and the variants, are not updated.
+	if len(lm.members) > 0 || base.Offline || downloader.IsOfflineEnv() || base.Bundle != "" || base.Backend == tasks.BackendONNX {
+		return nil
+	}
+
+	commit, err := downloader.ResolveRevision(base.ModelName, downloader.Options{
+		AccessToken: base.HubAccessToken,
+		Revision:    base.Revision,
+		Endpoint:    base.HubEndpoint,
+		Proxy:       base.HTTPProxy,
+		CABundle:    base.CABundle,
+	})
+	if err != nil {
+		return err
+	}
+	// If the commit of the model loaded at startup is unknown, e.g. it was
+	// downloaded before the revisions were recorded, it's replaced by the
+	// current one, rather than assuming they're the same.
+	if commit == lm.commit {
+		return nil
+	}
+	log.Info().Str("model", base.ModelName).Str("commit", commit).Msg("new model revision found, updating")
+
+	conf := *base
+	conf.ModelsDir = filepath.Join(base.ModelsDir, updatesDirname, commit)
+	conf.Revision = commit
+	candidate, err := loadModelForTask(lm.task, &conf)
+	if err != nil {
+		return err
+	}
+	if err := verification.CheckParity(ctx, lm.model, candidate); err != nil {
+		tasks.Finalize(candidate)
+		return fmt.Errorf("parity check failed: %w", err)
+	}
+	report, err := u.evaluator.check(ctx, lm, candidate)
+	if err != nil {
+		tasks.Finalize(candidate)
+		return fmt.Errorf("evaluation check failed: %w", err)
+	}
+
+	// The adapters can't change until the candidate is swapped in.
+	u.adapters.lockSwap()
+	defer u.adapters.unlockSwap()
+	if err := copyAdapters(lm.model, candidate); err != nil {
+		tasks.Finalize(candidate)
+		return fmt.Errorf("failed to carry over the adapters: %w", err)
+	}
+
+	// The report of the candidate becomes the baseline once it's swapped in.
+	var baseline *evaluation.Report
+	u.evaluator.lockSwap()
+	defer func() { u.evaluator.unlockSwap(lm.task, baseline) }()
+
+	old := *lm
+	lm.config, lm.model, lm.commit = &conf, candidate, commit
+	h, err := resolveRequestHandler(u.models, u.handlerOptions)
+	if err == nil {
+		err = u.server.SwapRequestHandler(h)
+	}
+	if err != nil {
+		*lm = old
+		tasks.Finalize(candidate)
+		return err
+	}
+	tasks.Finalize(old.model)
+	baseline = report
+	log.Info().Str("model", base.ModelName).Str("commit", commit).Msg("model updated")
+
+	if old.config != base {
+		removeUpdate(base.ModelsDir, old.config.FullModelPath())
+	}
+	return nil
+}
+
+// removeUpdate removes the directory of a replaced update, along with its
+// parent directories, up to the updates directory, if they're empty.
+func removeUpdate(modelsDir, modelPath string) {
+	if err := os.RemoveAll(modelPath); err != nil {
+		log.Warn().Err(err).Str("path", modelPath).Msg("failed to remove replaced model")
+		return
+	}
+	root := filepath.Join(modelsDir, updatesDirname)
+	for dir := filepath.Dir(modelPath); strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build js && wasm
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"syscall/js"
+	"testing/fstest"
+)
+
+// main exposes the global object:
+//
+//	cybertron.loadModel(task, files) -> Promise<model>
+//	model.infer(text[, poolingStrategy]) -> Promise<{vector: Float32Array} | {labels: string[], scores: number[]}>
+//	model.release()
+//
+// with the files an object mapping the file names to their Uint8Array
+// contents. The inference runs synchronously on the JavaScript thread,
+// so it's best to run it in a Web Worker.
+func main() {
+	js.Global().Set("cybertron", js.ValueOf(map[string]any{
+		"loadModel": js.FuncOf(jsLoadModel),
+	}))
+	select {} // the functions are called by the host
+}
+
+func jsLoadModel(_ js.Value, args []js.Value) any {
+	return promise(func() (any, error) {
+		if len(args) != 2 || args[0].Type() != js.TypeString || args[1].Type() != js.TypeObject {
+			return nil, errors.New("usage: loadModel(task, files)")
+		}
+		fsys := fstest.MapFS{}
+		names := js.Global().Get("Object").Call("keys", args[1])
+		for i := 0; i < names.Length(); i++ {
+			name := names.Index(i).String()
+			content := args[1].Get(name)
+			data := make([]byte, content.Get("length").Int())
+			js.CopyBytesToGo(data, content)
+			fsys[name] = &fstest.MapFile{Data: data}
+		}
+		m, err := loadModel(args[0].String(), fsys)
+		if err != nil {
+			return nil, err
+		}
+		return m.jsValue(), nil
+	})
+}
+
+// jsValue returns the JavaScript object of the model.
+func (m *model) jsValue() js.Value {
+	infer := js.FuncOf(func(_ js.Value, args []js.Value) any {
+		return promise(func() (any, error) {
+			if len(args) == 0 || args[0].Type() != js.TypeString {
+				return nil, errors.New("usage: infer(text[, poolingStrategy])")
+			}
+			req := request{Text: args[0].String()}
+			if len(args) > 1 && args[1].Type() == js.TypeNumber {
+				req.PoolingStrategy = args[1].Int()
+			}
+			resp, err := m.infer(context.Background(), req)
+			if err != nil {
+				return nil, err
+			}
+			return resp.jsValue(), nil
+		})
+	})
+	obj := js.ValueOf(map[string]any{"task": m.task, "infer": infer})
+	var release js.Func
+	release = js.FuncOf(func(js.Value, []js.Value) any {
+		infer.Release()
+		release.Release()
+		return nil
+	})
+	obj.Set("release", release)
+	return obj
+}
+
+// jsValue returns the JavaScript object of the response.
+func (r response) jsValue() js.Value {
+	if r.Vector != nil {
+		data := make([]byte, len(r.Vector)*4)
+		for i, v := range r.Vector {
+			binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(v))
+		}
+		buf := js.Global().Get("Uint8Array").New(len(data))
+		js.CopyBytesToJS(buf, data)
+		vector := js.Global().Get("Float32Array").New(buf.Get("buffer"))
+		return js.ValueOf(map[string]any{"vector": vector})
+	}
+	labels := make([]any, len(r.Labels))
+	scores := make([]any, len(r.Scores))
+	for i, l := range r.Labels {
+		labels[i] = l
+	}
+	for i, s := range r.Scores {
+		scores[i] = s
+	}
+	return js.ValueOf(map[string]any{"labels": labels, "scores": scores})
+}
+
+// promise returns a Promise resolved with the result of the function, run
+// in a goroutine, or rejected with its error.
+func promise(f func() (any, error)) js.Value {
+	var executor js.Func
+	executor = js.FuncOf(func(_ js.Value, args []js.Value) any {
+		resolve, reject := args[0], args[1]
+		go func() {
+			defer executor.Release()
+			v, err := f()
+			if err != nil {
+				reject.Invoke(js.Global().Get("Error").New(err.Error()))
+				return
+			}
+			resolve.Invoke(v)
+		}()
+		return nil
+	})
+	return js.Global().Get("Promise").New(executor)
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build wasip1
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// main serves the requests read from stdin, one JSON object per line, e.g.
+// {"text": "..."}, writing the responses to stdout in the same order:
+//
+//	wasmtime run --dir model cybertron.wasm -task text-encoding model < requests.jsonl
+//
+// A failed request is answered with {"error": "..."}.
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("wasm", flag.ContinueOnError)
+	task := fs.String("task", "text-encoding", `task of the model ("text-encoding"|"text-classification")`)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: wasm [-task task] model-dir")
+	}
+	m, err := loadModel(*task, os.DirFS(fs.Arg(0)))
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(nil, 1<<24)
+	w := bufio.NewWriter(os.Stdout)
+	enc := json.NewEncoder(w)
+	for scanner.Scan() {
+		var out any
+		var req request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			out = map[string]string{"error": fmt.Sprintf("invalid request: %v", err)}
+		} else if resp, err := m.infer(context.Background(), req); err != nil {
+			out = map[string]string{"error": err.Error()}
+		} else {
+			out = resp
+		}
+		if err := enc.Encode(out); err != nil {
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
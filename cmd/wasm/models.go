@@ -0,0 +1,88 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build (js && wasm) || wasip1
+
+// Command wasm runs the ONNX text encoding and text classification models
+// in WebAssembly hosts, without the server, so that small models can run in
+// browsers and edge runtimes.
+//
+// Built for JavaScript hosts (GOOS=js GOARCH=wasm), it exposes a global
+// "cybertron" object loading the models from the files passed by the host
+// (see main_js.go). Built for WASI (GOOS=wasip1 GOARCH=wasm), it loads the
+// model from a directory, and serves the JSON requests read from stdin, one
+// per line (see main_wasip1.go); this port needs Go 1.21 or later.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	onnx_for_text_classification "github.com/nlpodyssey/cybertron/pkg/tasks/textclassification/onnx"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	onnx_for_text_encoding "github.com/nlpodyssey/cybertron/pkg/tasks/textencoding/onnx"
+)
+
+// model is a model of a supported task.
+type model struct {
+	task string
+	impl any
+}
+
+// request is a request to a model. PoolingStrategy is used by the text
+// encoding only.
+type request struct {
+	Text            string `json:"text"`
+	PoolingStrategy int    `json:"pooling_strategy"`
+}
+
+// response is the response of a model, with the vector of the text
+// encoding, or the labels and scores of the text classification.
+type response struct {
+	Vector []float32 `json:"vector,omitempty"`
+	Labels []string  `json:"labels,omitempty"`
+	Scores []float64 `json:"scores,omitempty"`
+}
+
+// loadModel loads the model of the task from the root of the file system,
+// which must have the "model.onnx", "config.json", "tokenizer_config.json"
+// and "vocab.txt" files.
+func loadModel(task string, fsys fs.FS) (*model, error) {
+	var impl any
+	var err error
+	switch task {
+	case "text-encoding":
+		impl, err = onnx_for_text_encoding.LoadTextEncodingFS(fsys)
+	case "text-classification":
+		impl, err = onnx_for_text_classification.LoadTextClassificationFS(fsys)
+	default:
+		return nil, fmt.Errorf("unsupported task %#v (text-encoding|text-classification)", task)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &model{task: task, impl: impl}, nil
+}
+
+// infer serves the request.
+func (m *model) infer(ctx context.Context, req request) (response, error) {
+	switch impl := m.impl.(type) {
+	case textencoding.Interface:
+		resp, err := impl.Encode(ctx, req.Text, req.PoolingStrategy)
+		if err != nil {
+			return response{}, err
+		}
+		return response{Vector: resp.Vector.Data().F32()}, nil
+	case textclassification.Interface:
+		resp, err := impl.Classify(ctx, req.Text, textclassification.Parameters{})
+		if err != nil {
+			return response{}, err
+		}
+		return response{Labels: resp.Labels, Scores: resp.Scores}, nil
+	default:
+		return response{}, fmt.Errorf("unexpected model type %T", m.impl)
+	}
+}
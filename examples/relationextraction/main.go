@@ -13,41 +13,34 @@ import (
 	//lint:ignore ST1001 allow dot import just to make the example more readable
 	. "github.com/nlpodyssey/cybertron/examples"
 	"github.com/nlpodyssey/cybertron/pkg/tasks"
-	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/relationextraction"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
-// DefaultModel is REBEL, a text generation model that performs end-to-end relation extraction
-// for more than 200 different relation types.
-// Model card: https://huggingface.co/Babelscape/rebel-large
-const DefaultModel = "Babelscape/rebel-large"
-
 func main() {
 	zerolog.SetGlobalLevel(zerolog.DebugLevel)
 	LoadDotenv()
 
 	modelsDir := HasEnvVar("CYBERTRON_MODELS_DIR")
 
-	m, err := tasks.LoadModelForTextGeneration(&tasks.Config{
+	m, err := tasks.Load[relationextraction.Interface](&tasks.Config{
 		ModelsDir: modelsDir,
-		ModelName: DefaultModel,
+		ModelName: relationextraction.DefaultModel,
 	})
 	if err != nil {
 		log.Fatal().Err(err).Send()
 	}
 	defer tasks.Finalize(m)
 
-	opts := text2text.DefaultOptions()
-
 	fn := func(text string) error {
 		start := time.Now()
-		result, err := m.Generate(context.Background(), text, opts)
+		result, err := m.Extract(context.Background(), text, relationextraction.Parameters{})
 		if err != nil {
 			return err
 		}
 		fmt.Println(time.Since(start).Seconds())
-		fmt.Println(MarshalJSON(ExtractTriplets(result.Texts[0])))
+		fmt.Println(MarshalJSON(result.Relations))
 		return nil
 	}
 
@@ -33,7 +33,7 @@ func main() {
 
 	fn := func(text string) error {
 		start := time.Now()
-		result, err := m.Classify(context.Background(), text)
+		result, err := m.Classify(context.Background(), text, textclassification.Parameters{})
 		if err != nil {
 			return err
 		}
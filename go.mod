@@ -3,6 +3,7 @@ module github.com/nlpodyssey/cybertron
 go 1.20
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/bufbuild/buf v1.4.0
 	github.com/grpc-ecosystem/grpc-gateway/v2 v2.11.1
 	github.com/joho/godotenv v1.4.0
@@ -15,11 +16,13 @@ require (
 	github.com/stretchr/testify v1.8.1
 	golang.org/x/net v0.9.0
 	golang.org/x/sync v0.0.0-20220722155255-886fb9371eb4
+	golang.org/x/sys v0.7.0
 	golang.org/x/text v0.9.0
 	google.golang.org/genproto v0.0.0-20220728213248-dd149ef739b9
 	google.golang.org/grpc v1.48.0
 	google.golang.org/grpc/cmd/protoc-gen-go-grpc v1.2.0
 	google.golang.org/protobuf v1.30.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -59,9 +62,7 @@ require (
 	go.uber.org/atomic v1.9.0 // indirect
 	go.uber.org/multierr v1.8.0 // indirect
 	go.uber.org/zap v1.21.0 // indirect
-	golang.org/x/sys v0.7.0 // indirect
 	golang.org/x/term v0.7.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,145 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package audit records the requests served, with their responses, for the
+// deployments that must keep track of them, e.g. for compliance.
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultMaxLength is the default maximum length, in characters, of the
+// requests and responses recorded.
+const DefaultMaxLength = 1000
+
+// Record is the record of a request.
+type Record struct {
+	// Time is when the request was received.
+	Time time.Time `json:"time"`
+	// Method is the full name of the method, e.g.
+	// "/textencoding.v1.TextEncodingService/Encode".
+	Method string `json:"method"`
+	// Model identifies the model, by name and revision.
+	Model string `json:"model,omitempty"`
+	// Caller identifies the caller, by its tenant, if any, or by its address.
+	Caller string `json:"caller,omitempty"`
+	// Request and Response are the JSON encodings of the request and of the
+	// response, redacted and truncated.
+	Request  string `json:"request"`
+	Response string `json:"response,omitempty"`
+	// Error is the error of the request, if failed.
+	Error string `json:"error,omitempty"`
+	// Latency is the time taken to serve the request.
+	Latency time.Duration `json:"-"`
+}
+
+// MarshalJSON encodes the record, with its latency in milliseconds.
+func (r Record) MarshalJSON() ([]byte, error) {
+	type record Record
+	return json.Marshal(struct {
+		record
+		LatencyMS float64 `json:"latency_ms"`
+	}{record(r), float64(r.Latency) / float64(time.Millisecond)})
+}
+
+// Sink is where the records are written, e.g. a file or a log collector.
+// It must be safe for concurrent use.
+type Sink interface {
+	Write(Record) error
+}
+
+// Redactor returns the text with the personal information removed.
+type Redactor func(string) string
+
+// Logger writes the records of the requests to a sink.
+type Logger struct {
+	// Sink is where the records are written.
+	Sink Sink
+	// Redactors are applied, in order, to the requests, the responses and
+	// the errors, before truncating them (optional).
+	Redactors []Redactor
+	// MaxLength is the maximum length, in characters, of the requests and of
+	// the responses recorded (default DefaultMaxLength, negative for no limit).
+	MaxLength int
+}
+
+// Log redacts and truncates the record, and writes it to the sink.
+func (l *Logger) Log(r Record) error {
+	r.Request = l.truncate(l.redact(r.Request))
+	r.Response = l.truncate(l.redact(r.Response))
+	r.Error = l.redact(r.Error)
+	return l.Sink.Write(r)
+}
+
+func (l *Logger) redact(s string) string {
+	for _, redact := range l.Redactors {
+		s = redact(s)
+	}
+	return s
+}
+
+// truncate returns the string cut to the maximum length, marked by an
+// ellipsis.
+func (l *Logger) truncate(s string) string {
+	n := l.MaxLength
+	if n == 0 {
+		n = DefaultMaxLength
+	}
+	if n < 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	i := 0
+	for j := range s {
+		if i == n {
+			return s[:j] + "…"
+		}
+		i++
+	}
+	return s
+}
+
+// JSONSink writes the records as JSON lines.
+type JSONSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	c   io.Closer
+}
+
+// NewJSONSink returns a sink writing the records as JSON lines to w.
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{enc: json.NewEncoder(w)}
+}
+
+// OpenFile returns a sink appending the records as JSON lines to the file,
+// created if it doesn't exist.
+func OpenFile(filename string) (*JSONSink, error) {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	s := NewJSONSink(f)
+	s.c = f
+	return s, nil
+}
+
+// Write writes the record.
+func (s *JSONSink) Write(r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(r)
+}
+
+// Close closes the file of the sink, if opened by OpenFile.
+func (s *JSONSink) Close() error {
+	if s.c == nil {
+		return nil
+	}
+	return s.c.Close()
+}
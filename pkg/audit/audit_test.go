@@ -0,0 +1,105 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memorySink []Record
+
+func (s *memorySink) Write(r Record) error {
+	*s = append(*s, r)
+	return nil
+}
+
+func TestLoggerRedactsAndTruncates(t *testing.T) {
+	email, err := BuiltinRedactor("email")
+	require.NoError(t, err)
+	var sink memorySink
+	l := &Logger{Sink: &sink, Redactors: []Redactor{email}, MaxLength: 20}
+
+	require.NoError(t, l.Log(Record{
+		Method:   "/textencoding.v1.TextEncodingService/Encode",
+		Request:  `{"input":"à jane@example.com"}`,
+		Response: `{"vector":[0.1,0.2]}`,
+		Error:    "failed for jane@example.com",
+	}))
+	require.Len(t, sink, 1)
+	assert.Equal(t, `{"input":"à [REDACTE…`, sink[0].Request)
+	assert.Equal(t, `{"vector":[0.1,0.2]}`, sink[0].Response)
+	assert.Equal(t, "failed for [REDACTED]", sink[0].Error)
+}
+
+func TestLoggerDefaultMaxLength(t *testing.T) {
+	var sink memorySink
+	l := &Logger{Sink: &sink}
+	require.NoError(t, l.Log(Record{Request: strings.Repeat("a", DefaultMaxLength+1)}))
+	assert.Equal(t, strings.Repeat("a", DefaultMaxLength)+"…", sink[0].Request)
+
+	l.MaxLength = -1
+	require.NoError(t, l.Log(Record{Request: strings.Repeat("a", DefaultMaxLength+1)}))
+	assert.Len(t, sink[1].Request, DefaultMaxLength+1)
+}
+
+func TestBuiltinRedactors(t *testing.T) {
+	for name, text := range map[string]string{
+		"email": "write to john.doe@example.org today",
+		"phone": "call +39 333 123 4567 today",
+		"card":  "pay with 4111 1111 1111 1111 today",
+		"ip":    "from 192.168.1.10 today",
+	} {
+		redact, err := BuiltinRedactor(name)
+		require.NoError(t, err)
+		got := redact(text)
+		assert.NotContains(t, got, "1", name)
+		assert.True(t, strings.HasSuffix(got, " [REDACTED] today"), name)
+	}
+	_, err := BuiltinRedactor("ssn")
+	assert.Error(t, err)
+}
+
+func TestJSONSink(t *testing.T) {
+	var b bytes.Buffer
+	s := NewJSONSink(&b)
+	require.NoError(t, s.Write(Record{
+		Time:    time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC),
+		Method:  "/m",
+		Caller:  "search",
+		Request: "{}",
+		Latency: 1500 * time.Microsecond,
+	}))
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(b.Bytes(), &got))
+	assert.Equal(t, map[string]any{
+		"time":       "2023-05-01T10:00:00Z",
+		"method":     "/m",
+		"caller":     "search",
+		"request":    "{}",
+		"latency_ms": 1.5,
+	}, got)
+}
+
+func TestOpenFileAppends(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.jsonl")
+	for i := 0; i < 2; i++ {
+		s, err := OpenFile(filename)
+		require.NoError(t, err)
+		require.NoError(t, s.Write(Record{Method: "/m"}))
+		require.NoError(t, s.Close())
+	}
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, 2, bytes.Count(data, []byte("\n")))
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// redacted replaces the personal information removed.
+const redacted = "[REDACTED]"
+
+// patterns are the patterns of the built-in redactors, by name.
+var patterns = map[string]*regexp.Regexp{
+	"email": regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	"phone": regexp.MustCompile(`\+?\d[\d ().-]{7,}\d`),
+	"card":  regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+	"ip":    regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`),
+}
+
+// RegexpRedactor returns a redactor replacing the matches of the pattern.
+func RegexpRedactor(re *regexp.Regexp) Redactor {
+	return func(s string) string {
+		return re.ReplaceAllLiteralString(s, redacted)
+	}
+}
+
+// BuiltinRedactor returns the built-in redactor with the name
+// ("email"|"phone"|"card"|"ip").
+func BuiltinRedactor(name string) (Redactor, error) {
+	re, ok := patterns[name]
+	if !ok {
+		names := make([]string, 0, len(patterns))
+		for n := range patterns {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown redactor %#v (expected %s)", name, strings.Join(names, "|"))
+	}
+	return RegexpRedactor(re), nil
+}
@@ -0,0 +1,375 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package batch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// The Arrow IPC file format is the stream of the encapsulated messages, a
+// Schema followed by the RecordBatches, between the magic strings, with a
+// Footer indexing the messages at the end. The metadata of the messages
+// are flatbuffers, built by fbBuilder; the bodies are the buffers of the
+// arrays, one after the other.
+//
+// See https://arrow.apache.org/docs/format/Columnar.html.
+
+const arrowMagic = "ARROW1"
+
+const (
+	arrowMetadataV5 = 4
+
+	arrowHeaderSchema      = 1
+	arrowHeaderRecordBatch = 3
+
+	arrowTypeInt           = 2
+	arrowTypeFloatingPoint = 3
+	arrowTypeUtf8          = 5
+	arrowTypeBool          = 6
+	arrowTypeFixedSizeList = 16
+
+	arrowPrecisionSingle = 1
+	arrowPrecisionDouble = 2
+)
+
+// arrowField returns the Field table of the column: the vectors are
+// FixedSizeLists of float32.
+func arrowField(c *column) fbTable {
+	var typeID uint8
+	var typ fbTable
+	children := []fbTable{}
+	switch c.kind {
+	case kindBool:
+		typeID, typ = arrowTypeBool, fbTable{}
+	case kindInt:
+		typeID, typ = arrowTypeInt, fbTable{fbInt32(64), fbBool(true)}
+	case kindFloat:
+		typeID, typ = arrowTypeFloatingPoint, fbTable{fbInt16(arrowPrecisionDouble)}
+	case kindVector:
+		typeID, typ = arrowTypeFixedSizeList, fbTable{fbInt32(int32(c.dim))}
+		children = append(children, fbTable{
+			"item",
+			fbBool(false),
+			fbUint8(arrowTypeFloatingPoint),
+			fbTable{fbInt16(arrowPrecisionSingle)},
+			nil,
+			[]fbTable{},
+		})
+	default:
+		typeID, typ = arrowTypeUtf8, fbTable{}
+	}
+	return fbTable{c.name, fbBool(true), fbUint8(typeID), typ, nil, children}
+}
+
+// arrowWriter writes a table as an Arrow IPC file, a record batch per batch
+// of rows.
+type arrowWriter struct {
+	w      *countingWriter
+	schema fbTable
+	// blocks are the Block structs of the record batches, for the footer.
+	blocks []byte
+}
+
+func newArrowWriter(w *countingWriter, columns []*column) (*arrowWriter, error) {
+	fields := make([]fbTable, len(columns))
+	for i, c := range columns {
+		fields[i] = arrowField(c)
+	}
+	a := &arrowWriter{w: w, schema: fbTable{nil, fields}}
+	if _, err := w.Write([]byte(arrowMagic + "\x00\x00")); err != nil {
+		return nil, err
+	}
+	if _, _, err := a.writeMessage(arrowHeaderSchema, a.schema, nil); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// writeMessage writes an encapsulated message, returning its offset and
+// the length of its metadata, prefix included.
+func (a *arrowWriter) writeMessage(headerType uint8, header fbTable, body []byte) (int64, int, error) {
+	offset := a.w.n
+	meta := buildFlatbuffer(fbTable{
+		fbInt16(arrowMetadataV5),
+		fbUint8(headerType),
+		header,
+		fbInt64(int64(len(body))),
+	})
+	var prefix [8]byte
+	binary.LittleEndian.PutUint32(prefix[0:], 0xFFFFFFFF)
+	binary.LittleEndian.PutUint32(prefix[4:], uint32(len(meta)))
+	for _, p := range [][]byte{prefix[:], meta, body} {
+		if _, err := a.w.Write(p); err != nil {
+			return 0, 0, err
+		}
+	}
+	return offset, len(prefix) + len(meta), nil
+}
+
+// arrowBody is the body of a record batch, with the FieldNode and the
+// Buffer structs describing it.
+type arrowBody struct {
+	data    []byte
+	nodes   fbStructs
+	buffers fbStructs
+}
+
+func (b *arrowBody) node(length, nulls int) {
+	b.nodes.n++
+	b.nodes.data = binary.LittleEndian.AppendUint64(b.nodes.data, uint64(length))
+	b.nodes.data = binary.LittleEndian.AppendUint64(b.nodes.data, uint64(nulls))
+}
+
+// buffer appends the buffer to the body, padded to 8 bytes.
+func (b *arrowBody) buffer(p []byte) {
+	b.buffers.n++
+	b.buffers.data = binary.LittleEndian.AppendUint64(b.buffers.data, uint64(len(b.data)))
+	b.buffers.data = binary.LittleEndian.AppendUint64(b.buffers.data, uint64(len(p)))
+	b.data = append(b.data, p...)
+	for len(b.data)%8 != 0 {
+		b.data = append(b.data, 0)
+	}
+}
+
+// bitmap returns the bits, least significant first.
+func bitmap(bits []bool) []byte {
+	p := make([]byte, (len(bits)+7)/8)
+	for i, b := range bits {
+		if b {
+			p[i/8] |= 1 << (i % 8)
+		}
+	}
+	return p
+}
+
+func (a *arrowWriter) writeBatch(rows int, data []*columnData) error {
+	var body arrowBody
+	for i, c := range a.schema[1].([]fbTable) {
+		d := data[i]
+		body.node(rows, d.nulls)
+		if d.nulls > 0 {
+			body.buffer(bitmap(d.valid))
+		} else {
+			body.buffer(nil)
+		}
+		switch c[2].(fbScalar).v {
+		case arrowTypeBool:
+			body.buffer(bitmap(d.bools))
+		case arrowTypeInt:
+			p := make([]byte, 0, 8*len(d.ints))
+			for _, n := range d.ints {
+				p = binary.LittleEndian.AppendUint64(p, uint64(n))
+			}
+			body.buffer(p)
+		case arrowTypeFloatingPoint:
+			p := make([]byte, 0, 8*len(d.floats))
+			for _, x := range d.floats {
+				p = binary.LittleEndian.AppendUint64(p, math.Float64bits(x))
+			}
+			body.buffer(p)
+		case arrowTypeFixedSizeList:
+			body.node(len(d.vectors), 0)
+			body.buffer(nil)
+			p := make([]byte, 0, 4*len(d.vectors))
+			for _, x := range d.vectors {
+				p = binary.LittleEndian.AppendUint32(p, math.Float32bits(x))
+			}
+			body.buffer(p)
+		case arrowTypeUtf8:
+			offsets := make([]byte, 0, 4*(len(d.strings)+1))
+			var values []byte
+			offsets = binary.LittleEndian.AppendUint32(offsets, 0)
+			for _, s := range d.strings {
+				values = append(values, s...)
+				if len(values) > math.MaxInt32 {
+					return fmt.Errorf("batch: column %#v too large for an Arrow batch", c[0])
+				}
+				offsets = binary.LittleEndian.AppendUint32(offsets, uint32(len(values)))
+			}
+			body.buffer(offsets)
+			body.buffer(values)
+		}
+	}
+	offset, metaLen, err := a.writeMessage(arrowHeaderRecordBatch, fbTable{
+		fbInt64(int64(rows)),
+		body.nodes,
+		body.buffers,
+	}, body.data)
+	if err != nil {
+		return err
+	}
+	a.blocks = binary.LittleEndian.AppendUint64(a.blocks, uint64(offset))
+	a.blocks = binary.LittleEndian.AppendUint32(a.blocks, uint32(metaLen))
+	a.blocks = binary.LittleEndian.AppendUint32(a.blocks, 0) // padding
+	a.blocks = binary.LittleEndian.AppendUint64(a.blocks, uint64(len(body.data)))
+	return nil
+}
+
+func (a *arrowWriter) close() error {
+	footer := buildFlatbuffer(fbTable{
+		fbInt16(arrowMetadataV5),
+		a.schema,
+		fbStructs{},
+		fbStructs{n: len(a.blocks) / 24, data: a.blocks},
+	})
+	var eos, size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(len(footer)))
+	binary.LittleEndian.PutUint32(eos[:], 0xFFFFFFFF)
+	for _, p := range [][]byte{eos[:], {0, 0, 0, 0}, footer, size[:], []byte(arrowMagic)} {
+		if _, err := a.w.Write(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fbTable is a flatbuffers table to build, by field id: an fbScalar for an
+// inline scalar, or a string, fbTable, []fbTable or fbStructs for an
+// object referenced by offset; nil for an absent field. A union is the
+// fbUint8 of its type followed by its table.
+type fbTable []any
+
+// fbScalar is an inline scalar of the size, in bytes.
+type fbScalar struct {
+	v    uint64
+	size int
+}
+
+func fbBool(b bool) fbScalar {
+	if b {
+		return fbScalar{1, 1}
+	}
+	return fbScalar{0, 1}
+}
+
+func fbUint8(v uint8) fbScalar { return fbScalar{uint64(v), 1} }
+func fbInt16(v int16) fbScalar { return fbScalar{uint64(uint16(v)), 2} }
+func fbInt32(v int32) fbScalar { return fbScalar{uint64(uint32(v)), 4} }
+func fbInt64(v int64) fbScalar { return fbScalar{uint64(v), 8} }
+
+// fbStructs is a vector of structs, encoded, aligned to 8 bytes.
+type fbStructs struct {
+	n    int
+	data []byte
+}
+
+// fbBuilder builds a flatbuffer front to back: each object is written
+// before the objects it references, so that their offsets are positive,
+// and each vtable just before its table.
+type fbBuilder struct {
+	buf []byte
+}
+
+// buildFlatbuffer returns the flatbuffer of the root table, padded to 8
+// bytes.
+func buildFlatbuffer(root fbTable) []byte {
+	b := &fbBuilder{buf: make([]byte, 4)}
+	binary.LittleEndian.PutUint32(b.buf, uint32(b.table(root)))
+	b.align(8, 0)
+	return b.buf
+}
+
+// align pads the buffer to the offset rem modulo n.
+func (b *fbBuilder) align(n, rem int) {
+	for len(b.buf)%n != rem {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+// table writes the table and the objects it references, returning its
+// offset.
+func (b *fbBuilder) table(t fbTable) int {
+	type slot struct{ id, off, size int }
+	var slots []slot
+	for id, f := range t {
+		switch f := f.(type) {
+		case nil:
+		case fbScalar:
+			slots = append(slots, slot{id: id, size: f.size})
+		default:
+			slots = append(slots, slot{id: id, size: 4})
+		}
+	}
+	// The largest fields first, each aligned to its size, the table being
+	// aligned to 8 bytes.
+	sort.SliceStable(slots, func(i, j int) bool { return slots[i].size > slots[j].size })
+	size := 4
+	for i := range slots {
+		for size%slots[i].size != 0 {
+			size++
+		}
+		slots[i].off = size
+		size += slots[i].size
+	}
+
+	b.align(2, 0)
+	vtable := len(b.buf)
+	offsets := make([]uint16, len(t))
+	for _, s := range slots {
+		offsets[s.id] = uint16(s.off)
+	}
+	b.buf = binary.LittleEndian.AppendUint16(b.buf, uint16(4+2*len(t)))
+	b.buf = binary.LittleEndian.AppendUint16(b.buf, uint16(size))
+	for _, off := range offsets {
+		b.buf = binary.LittleEndian.AppendUint16(b.buf, off)
+	}
+
+	b.align(8, 0)
+	pos := len(b.buf)
+	b.buf = append(b.buf, make([]byte, size)...)
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(pos-vtable))
+	for _, s := range slots {
+		if v, ok := t[s.id].(fbScalar); ok {
+			for i := 0; i < s.size; i++ {
+				b.buf[pos+s.off+i] = byte(v.v >> (8 * i))
+			}
+		}
+	}
+	for _, s := range slots {
+		if _, ok := t[s.id].(fbScalar); !ok {
+			b.ref(pos+s.off, b.object(t[s.id]))
+		}
+	}
+	return pos
+}
+
+// ref sets the offset at the position to the object.
+func (b *fbBuilder) ref(pos, obj int) {
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(obj-pos))
+}
+
+// object writes the referenced object, returning its offset.
+func (b *fbBuilder) object(v any) int {
+	switch v := v.(type) {
+	case string:
+		b.align(4, 0)
+		pos := len(b.buf)
+		b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(v)))
+		b.buf = append(append(b.buf, v...), 0)
+		return pos
+	case fbTable:
+		return b.table(v)
+	case []fbTable:
+		b.align(4, 0)
+		pos := len(b.buf)
+		b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(v)))
+		b.buf = append(b.buf, make([]byte, 4*len(v))...)
+		for i, t := range v {
+			b.ref(pos+4+4*i, b.table(t))
+		}
+		return pos
+	case fbStructs:
+		b.align(8, 4)
+		pos := len(b.buf)
+		b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(v.n))
+		b.buf = append(b.buf, v.data...)
+		return pos
+	default:
+		panic(fmt.Sprintf("batch: unsupported flatbuffers value %T", v))
+	}
+}
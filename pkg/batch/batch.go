@@ -0,0 +1,314 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package batch runs a task over a corpus, e.g. to compute the embeddings or
+// the classes of a large set of documents offline. The results are written
+// incrementally, in the order of the corpus, and the progress is
+// checkpointed, so that an interrupted run can be resumed.
+package batch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// CheckpointSuffix is appended to the output filename to get the filename
+// of the checkpoint.
+const CheckpointSuffix = ".checkpoint"
+
+// SpoolSuffix is appended to the output filename to get the filename of the
+// JSON lines results of a run with a table format, converted to the output
+// once complete.
+const SpoolSuffix = ".spool.jsonl"
+
+// Func processes an input.
+type Func func(ctx context.Context, input string) (any, error)
+
+// Options are the options of Run.
+type Options struct {
+	// Parallelism is the number of inputs processed concurrently (default 1).
+	Parallelism int
+	// CheckpointInterval is the number of records written between two
+	// checkpoints (default 100).
+	CheckpointInterval int
+	// Format is the format of the output (default FormatJSONL).
+	Format Format
+}
+
+// Stats are the statistics of a run.
+type Stats struct {
+	// Skipped is the number of records already processed by a previous run.
+	Skipped int
+	// Processed is the number of records processed by this run.
+	Processed int
+	// Failed is the number of records which failed to process, included
+	// in Processed.
+	Failed int
+}
+
+// checkpoint is the progress of a run.
+type checkpoint struct {
+	// Records is the number of records written to the output.
+	Records int `json:"records"`
+	// OutputSize is the size of the output holding those records.
+	OutputSize int64 `json:"output_size"`
+}
+
+// job is a record to process, with its sequence number.
+type job struct {
+	seq    int
+	record Record
+}
+
+// result is the output line of a record, with its sequence number.
+type result struct {
+	seq    int
+	line   []byte
+	failed bool
+	// canceled is true if the processing was interrupted, in which case
+	// the record must be processed again on resume.
+	canceled bool
+}
+
+// Run processes the records of the reader with the function, and writes
+// the results to the output file as JSON lines: each is the fields of the
+// record, with the result in the "output" field or, if the processing
+// failed, the error in the "error" field.
+//
+// The progress is recorded in a checkpoint file, next to the output, every
+// CheckpointInterval records. If the checkpoint exists, the run resumes
+// from it: the records already processed are skipped, and the output is
+// truncated to the results of those records. Once the context is done, the
+// records being processed are completed and checkpointed before returning
+// the context error.
+//
+// With the Arrow or Parquet Format, the results are written as JSON lines
+// to a spool file next to the output, with the SpoolSuffix, and converted to
+// a table once all the records are processed: a column per field of the
+// records, with the fields of an object output flattened into
+// "output.<field>" columns, and the lists of numbers of the same length,
+// e.g. the embeddings, as float32 vector columns. The spool and the
+// checkpoint are then removed.
+func Run(parent context.Context, r Reader, output string, f Func, opts Options) (Stats, error) {
+	if opts.Parallelism < 1 {
+		opts.Parallelism = 1
+	}
+	if opts.CheckpointInterval < 1 {
+		opts.CheckpointInterval = 100
+	}
+	var stats Stats
+
+	spool := output
+	if opts.Format != "" && opts.Format != FormatJSONL {
+		spool = output + SpoolSuffix
+	}
+	cp, err := readCheckpoint(output + CheckpointSuffix)
+	if err != nil {
+		return stats, err
+	}
+	out, err := os.OpenFile(spool, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return stats, err
+	}
+	defer out.Close()
+	if err := out.Truncate(cp.OutputSize); err != nil {
+		return stats, err
+	}
+	if _, err := out.Seek(cp.OutputSize, io.SeekStart); err != nil {
+		return stats, err
+	}
+	for ; stats.Skipped < cp.Records; stats.Skipped++ {
+		if _, err := r.Next(); err != nil {
+			if err == io.EOF {
+				err = errors.New("batch: the corpus has fewer records than the checkpoint")
+			}
+			return stats, err
+		}
+	}
+	if cp.Records > 0 {
+		log.Info().Int("records", cp.Records).Msg("resuming from checkpoint")
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	jobs := make(chan job)
+	results := make(chan result)
+	var readErr error
+	go func() {
+		defer close(jobs)
+		for seq := 0; ctx.Err() == nil; seq++ {
+			rec, err := r.Next()
+			if err != nil {
+				if err != io.EOF {
+					readErr = err
+				}
+				return
+			}
+			select {
+			case jobs <- job{seq: seq, record: rec}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results <- process(ctx, f, j)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	w := bufio.NewWriter(out)
+	save := func() error {
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if err := out.Sync(); err != nil {
+			return err
+		}
+		size, err := out.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		return writeCheckpoint(output+CheckpointSuffix, checkpoint{Records: stats.Skipped + stats.Processed, OutputSize: size})
+	}
+
+	// The results are written in the order of the records, so that the
+	// checkpoint is always a prefix of the corpus.
+	pending := make(map[int]result)
+	next := 0
+	var writeErr error
+	stopped := false
+	for res := range results {
+		if stopped {
+			continue // drain
+		}
+		pending[res.seq] = res
+		for !stopped {
+			res, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if res.canceled {
+				stopped = true
+				break
+			}
+			if _, writeErr = w.Write(res.line); writeErr != nil {
+				stopped = true
+				cancel()
+				break
+			}
+			stats.Processed++
+			if res.failed {
+				stats.Failed++
+			}
+			if stats.Processed%opts.CheckpointInterval == 0 {
+				if writeErr = save(); writeErr != nil {
+					stopped = true
+					cancel()
+				}
+			}
+		}
+	}
+	if writeErr != nil {
+		return stats, writeErr
+	}
+	if err := save(); err != nil {
+		return stats, err
+	}
+	if readErr != nil {
+		return stats, readErr
+	}
+	if err := parent.Err(); err != nil || spool == output {
+		return stats, err
+	}
+	if err := out.Close(); err != nil {
+		return stats, err
+	}
+	if err := convertResults(spool, output, opts.Format); err != nil {
+		return stats, err
+	}
+	if err := os.Remove(spool); err != nil {
+		return stats, err
+	}
+	return stats, os.Remove(output + CheckpointSuffix)
+}
+
+type recordKey struct{}
+
+// RecordFromContext returns the record being processed, carried by the
+// context of the Func, e.g. to get the fields of the input other than its
+// text.
+func RecordFromContext(ctx context.Context) (Record, bool) {
+	r, ok := ctx.Value(recordKey{}).(Record)
+	return r, ok
+}
+
+// process processes the record of the job, returning its output line.
+func process(ctx context.Context, f Func, j job) result {
+	fields := make(map[string]any, len(j.record.Fields)+1)
+	for k, v := range j.record.Fields {
+		fields[k] = v
+	}
+	out, err := f(context.WithValue(ctx, recordKey{}, j.record), j.record.Input)
+	if err != nil && ctx.Err() != nil {
+		return result{seq: j.seq, canceled: true}
+	}
+	if err == nil {
+		fields["output"] = out
+	} else {
+		fields["error"] = err.Error()
+	}
+	line, merr := json.Marshal(fields)
+	if merr != nil {
+		delete(fields, "output")
+		fields["error"] = merr.Error()
+		line, _ = json.Marshal(fields)
+		err = merr
+	}
+	return result{seq: j.seq, line: append(line, '\n'), failed: err != nil}
+}
+
+func readCheckpoint(filename string) (checkpoint, error) {
+	var cp checkpoint
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, err
+	}
+	err = json.Unmarshal(data, &cp)
+	return cp, err
+}
+
+// writeCheckpoint writes the checkpoint atomically.
+func writeCheckpoint(filename string, cp checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
@@ -0,0 +1,159 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCorpus(t *testing.T, n int) string {
+	var lines []string
+	for i := 0; i < n; i++ {
+		lines = append(lines, fmt.Sprintf(`{"id": %d, "input": "text %d"}`, i, i))
+	}
+	filename := filepath.Join(t.TempDir(), "corpus.jsonl")
+	require.NoError(t, os.WriteFile(filename, []byte(strings.Join(lines, "\n")+"\n\n"), 0644))
+	return filename
+}
+
+func readOutput(t *testing.T, filename string) []map[string]any {
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var rec map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &rec))
+		records = append(records, rec)
+	}
+	return records
+}
+
+func upper(_ context.Context, input string) (any, error) {
+	if input == "text 3" {
+		return nil, errors.New("bad input")
+	}
+	return strings.ToUpper(input), nil
+}
+
+func TestRun(t *testing.T) {
+	corpus := writeCorpus(t, 10)
+	output := filepath.Join(t.TempDir(), "out.jsonl")
+	r, err := Open(corpus)
+	require.NoError(t, err)
+	defer r.Close()
+
+	stats, err := Run(context.Background(), r, output, upper, Options{Parallelism: 4, CheckpointInterval: 3})
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Processed: 10, Failed: 1}, stats)
+
+	records := readOutput(t, output)
+	require.Len(t, records, 10)
+	for i, rec := range records {
+		assert.Equal(t, float64(i), rec["id"])
+		if i == 3 {
+			assert.Equal(t, "bad input", rec["error"])
+			continue
+		}
+		assert.Equal(t, fmt.Sprintf("TEXT %d", i), rec["output"])
+	}
+}
+
+func TestRun_Resume(t *testing.T) {
+	corpus := writeCorpus(t, 20)
+	output := filepath.Join(t.TempDir(), "out.jsonl")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int32
+	interrupted := func(ctx context.Context, input string) (any, error) {
+		if atomic.AddInt32(&calls, 1) > 7 {
+			cancel()
+			return nil, ctx.Err()
+		}
+		return strings.ToUpper(input), nil
+	}
+	r, err := Open(corpus)
+	require.NoError(t, err)
+	stats, err := Run(ctx, r, output, interrupted, Options{CheckpointInterval: 5})
+	require.ErrorIs(t, err, context.Canceled)
+	require.NoError(t, r.Close())
+	assert.Equal(t, 7, stats.Processed)
+
+	// Simulate a crash after the checkpoint, with a partial line.
+	f, err := os.OpenFile(output, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"id": 7, "out`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	r, err = Open(corpus)
+	require.NoError(t, err)
+	defer r.Close()
+	stats, err = Run(context.Background(), r, output, upper, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, 7, stats.Skipped)
+	assert.Equal(t, 13, stats.Processed)
+
+	records := readOutput(t, output)
+	require.Len(t, records, 20)
+	for i, rec := range records {
+		assert.Equal(t, float64(i), rec["id"])
+	}
+}
+
+func TestOpenDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "b"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b", "2.txt"), []byte("two\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".hidden"), []byte("x"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("x"), 0644))
+
+	r, err := Open(dir)
+	require.NoError(t, err)
+	defer r.Close()
+	rec, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, Record{Input: "one", Fields: map[string]any{"path": "a.txt"}}, rec)
+	rec, err = r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, Record{Input: "two", Fields: map[string]any{"path": "b/2.txt"}}, rec)
+	_, err = r.Next()
+	assert.Error(t, err)
+}
+
+func TestOpenDataset(t *testing.T) {
+	corpus := filepath.Join(t.TempDir(), "corpus.tsv")
+	require.NoError(t, os.WriteFile(corpus, []byte("id\ttext\tquestion\n1\tRome is in Italy\tWhere is Rome?\n"), 0644))
+	output := filepath.Join(t.TempDir(), "out.jsonl")
+	r, err := Open(corpus)
+	require.NoError(t, err)
+	defer r.Close()
+
+	ask := func(ctx context.Context, input string) (any, error) {
+		rec, ok := RecordFromContext(ctx)
+		require.True(t, ok)
+		return fmt.Sprintf("%s %s", rec.Fields["question"], input), nil
+	}
+	stats, err := Run(context.Background(), r, output, ask, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Processed: 1}, stats)
+
+	records := readOutput(t, output)
+	require.Len(t, records, 1)
+	assert.Equal(t, "1", records[0]["id"])
+	assert.Equal(t, "Where is Rome? Rome is in Italy", records[0]["output"])
+}
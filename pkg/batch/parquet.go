@@ -0,0 +1,341 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package batch
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// The Parquet file is a row group per batch of rows, each with a column
+// chunk of a single uncompressed data page per column, between the magic
+// strings, with the FileMetaData at the end. The columns are optional, and
+// the vectors are LISTs of FLOAT, in the three-level structure. The
+// metadata are Thrift structs, in the compact protocol.
+//
+// See https://parquet.apache.org/docs/file-format/.
+
+const parquetMagic = "PAR1"
+
+// The values of the enums of parquet.thrift.
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetFloat     = 4
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetRequired = 0
+	parquetOptional = 1
+	parquetRepeated = 2
+
+	parquetConvertedUTF8 = 0
+	parquetConvertedList = 3
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetDataPage = 0
+)
+
+// parquetWriter writes a table as a Parquet file, a row group per batch of
+// rows.
+type parquetWriter struct {
+	w         *countingWriter
+	columns   []*column
+	rowGroups []thriftStruct
+	rows      int64
+}
+
+func newParquetWriter(w *countingWriter, columns []*column) (*parquetWriter, error) {
+	if _, err := w.Write([]byte(parquetMagic)); err != nil {
+		return nil, err
+	}
+	return &parquetWriter{w: w, columns: columns}, nil
+}
+
+// parquetType returns the physical type of the values of the column.
+func parquetType(c *column) int32 {
+	switch c.kind {
+	case kindBool:
+		return parquetBoolean
+	case kindInt:
+		return parquetInt64
+	case kindFloat:
+		return parquetDouble
+	case kindVector:
+		return parquetFloat
+	default:
+		return parquetByteArray
+	}
+}
+
+// schema returns the SchemaElements of the columns, flattened depth first.
+func (p *parquetWriter) schema() []thriftStruct {
+	elems := []thriftStruct{{
+		{4, "schema"},
+		{5, int32(len(p.columns))},
+	}}
+	for _, c := range p.columns {
+		switch c.kind {
+		case kindVector:
+			elems = append(elems,
+				thriftStruct{{3, int32(parquetOptional)}, {4, c.name}, {5, int32(1)}, {6, int32(parquetConvertedList)}},
+				thriftStruct{{3, int32(parquetRepeated)}, {4, "list"}, {5, int32(1)}},
+				thriftStruct{{1, int32(parquetFloat)}, {3, int32(parquetRequired)}, {4, "element"}},
+			)
+		case kindBool, kindInt, kindFloat:
+			elems = append(elems, thriftStruct{{1, parquetType(c)}, {3, int32(parquetOptional)}, {4, c.name}})
+		default:
+			elems = append(elems, thriftStruct{
+				{1, parquetType(c)},
+				{3, int32(parquetOptional)},
+				{4, c.name},
+				{6, int32(parquetConvertedUTF8)},
+			})
+		}
+	}
+	return elems
+}
+
+// encodeLevels returns the levels, of the bit width, in the RLE/bit-packed
+// hybrid encoding, as runs of repeated values, prefixed by their length.
+func encodeLevels(levels []byte, width int) []byte {
+	out := make([]byte, 4)
+	for i := 0; i < len(levels); {
+		j := i + 1
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		out = binary.AppendUvarint(out, uint64(j-i)<<1)
+		for b := 0; b < (width+7)/8; b++ {
+			out = append(out, levels[i]>>(8*b))
+		}
+		i = j
+	}
+	binary.LittleEndian.PutUint32(out, uint32(len(out)-4))
+	return out
+}
+
+// page returns the data page of the values of the column, with the number
+// of its levels: the nulls are only in the definition levels, with level 0,
+// and each value of a vector is a level, the repetition level being 1 for
+// all but the first one.
+func (p *parquetWriter) page(c *column, d *columnData) ([]byte, int) {
+	var page []byte
+	var n int
+	if c.kind == kindVector {
+		var rep, def []byte
+		for i, valid := range d.valid {
+			switch {
+			case !valid:
+				rep, def = append(rep, 0), append(def, 0)
+			case c.dim == 0:
+				rep, def = append(rep, 0), append(def, 1)
+			default:
+				for j := 0; j < c.dim; j++ {
+					rep, def = append(rep, 1), append(def, 2)
+				}
+				rep[len(rep)-c.dim] = 0
+				for _, x := range d.vectors[i*c.dim : (i+1)*c.dim] {
+					page = binary.LittleEndian.AppendUint32(page, math.Float32bits(x))
+				}
+			}
+		}
+		n = len(def)
+		page = append(append(encodeLevels(rep, 1), encodeLevels(def, 2)...), page...)
+		return page, n
+	}
+
+	def := make([]byte, len(d.valid))
+	var bools []bool
+	for i, valid := range d.valid {
+		if !valid {
+			continue
+		}
+		def[i] = 1
+		switch c.kind {
+		case kindBool:
+			bools = append(bools, d.bools[i])
+		case kindInt:
+			page = binary.LittleEndian.AppendUint64(page, uint64(d.ints[i]))
+		case kindFloat:
+			page = binary.LittleEndian.AppendUint64(page, math.Float64bits(d.floats[i]))
+		default:
+			page = binary.LittleEndian.AppendUint32(page, uint32(len(d.strings[i])))
+			page = append(page, d.strings[i]...)
+		}
+	}
+	if c.kind == kindBool {
+		page = bitmap(bools)
+	}
+	return append(encodeLevels(def, 1), page...), len(def)
+}
+
+func (p *parquetWriter) writeBatch(rows int, data []*columnData) error {
+	var chunks []thriftStruct
+	var size int64
+	for i, c := range p.columns {
+		page, n := p.page(c, data[i])
+		header := encodeThrift(thriftStruct{
+			{1, int32(parquetDataPage)},
+			{2, int32(len(page))},
+			{3, int32(len(page))},
+			{5, thriftStruct{
+				{1, int32(n)},
+				{2, int32(parquetPlain)},
+				{3, int32(parquetRLE)},
+				{4, int32(parquetRLE)},
+			}},
+		})
+		offset := p.w.n
+		if _, err := p.w.Write(header); err != nil {
+			return err
+		}
+		if _, err := p.w.Write(page); err != nil {
+			return err
+		}
+		path := []string{c.name}
+		if c.kind == kindVector {
+			path = append(path, "list", "element")
+		}
+		chunkSize := int64(len(header) + len(page))
+		size += chunkSize
+		chunks = append(chunks, thriftStruct{
+			{2, offset},
+			{3, thriftStruct{
+				{1, parquetType(c)},
+				{2, []int32{parquetPlain, parquetRLE}},
+				{3, path},
+				{4, int32(0)}, // uncompressed
+				{5, int64(n)},
+				{6, chunkSize},
+				{7, chunkSize},
+				{9, offset},
+			}},
+		})
+	}
+	p.rowGroups = append(p.rowGroups, thriftStruct{
+		{1, chunks},
+		{2, size},
+		{3, int64(rows)},
+	})
+	p.rows += int64(rows)
+	return nil
+}
+
+func (p *parquetWriter) close() error {
+	meta := encodeThrift(thriftStruct{
+		{1, int32(1)},
+		{2, p.schema()},
+		{3, p.rows},
+		{4, p.rowGroups},
+		{6, "cybertron"},
+	})
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(len(meta)))
+	for _, b := range [][]byte{meta, size[:], []byte(parquetMagic)} {
+		if _, err := p.w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// thriftStruct is a Thrift struct to encode, as its fields in increasing
+// order of id, each an int32, int64, string, thriftStruct, or a list of
+// int32, string or thriftStruct.
+type thriftStruct []thriftField
+
+type thriftField struct {
+	id    int16
+	value any
+}
+
+// The types of the Thrift compact protocol.
+const (
+	thriftTypeI32    = 5
+	thriftTypeI64    = 6
+	thriftTypeBinary = 8
+	thriftTypeList   = 9
+	thriftTypeStruct = 12
+)
+
+// encodeThrift returns the struct in the Thrift compact protocol.
+func encodeThrift(s thriftStruct) []byte {
+	return appendThriftStruct(nil, s)
+}
+
+func appendThriftStruct(b []byte, s thriftStruct) []byte {
+	var last int16
+	for _, f := range s {
+		typ := thriftTypeOf(f.value)
+		if delta := f.id - last; delta > 0 && delta <= 15 {
+			b = append(b, byte(delta)<<4|typ)
+		} else {
+			b = append(b, typ)
+			b = binary.AppendVarint(b, int64(f.id))
+		}
+		last = f.id
+		b = appendThriftValue(b, f.value)
+	}
+	return append(b, 0) // stop
+}
+
+func thriftTypeOf(v any) byte {
+	switch v.(type) {
+	case int32:
+		return thriftTypeI32
+	case int64:
+		return thriftTypeI64
+	case string:
+		return thriftTypeBinary
+	case thriftStruct:
+		return thriftTypeStruct
+	default:
+		return thriftTypeList
+	}
+}
+
+// appendThriftList appends the header of a list of n elements of the type.
+func appendThriftList(b []byte, n int, typ byte) []byte {
+	if n < 15 {
+		return append(b, byte(n)<<4|typ)
+	}
+	return binary.AppendUvarint(append(b, 0xF0|typ), uint64(n))
+}
+
+func appendThriftValue(b []byte, v any) []byte {
+	switch v := v.(type) {
+	case int32:
+		return binary.AppendVarint(b, int64(v))
+	case int64:
+		return binary.AppendVarint(b, v)
+	case string:
+		return append(binary.AppendUvarint(b, uint64(len(v))), v...)
+	case thriftStruct:
+		return appendThriftStruct(b, v)
+	case []int32:
+		b = appendThriftList(b, len(v), thriftTypeI32)
+		for _, x := range v {
+			b = binary.AppendVarint(b, int64(x))
+		}
+		return b
+	case []string:
+		b = appendThriftList(b, len(v), thriftTypeBinary)
+		for _, x := range v {
+			b = appendThriftValue(b, x)
+		}
+		return b
+	case []thriftStruct:
+		b = appendThriftList(b, len(v), thriftTypeStruct)
+		for _, x := range v {
+			b = appendThriftStruct(b, x)
+		}
+		return b
+	default:
+		panic("batch: unsupported thrift value")
+	}
+}
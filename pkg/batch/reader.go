@@ -0,0 +1,189 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package batch
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/datasets"
+)
+
+// Record is an input of the corpus.
+type Record struct {
+	// Input is the text to process.
+	Input string
+	// Fields are carried over to the output record: the "path" of the file
+	// for a directory, all the fields of the JSON line, or the fields of the
+	// example of a dataset, e.g. the "question" of SQuAD.
+	Fields map[string]any
+}
+
+// Reader reads the records of a corpus, always in the same order, so that
+// the processing can be resumed.
+type Reader interface {
+	// Next returns the next record, or io.EOF at the end of the corpus.
+	Next() (Record, error)
+	// Close closes the reader.
+	Close() error
+}
+
+// Open opens the corpus, either a directory, where each file is a record,
+// a dataset in a format of the datasets package other than JSON lines, by
+// extension, e.g. CoNLL or CSV, where each example is a record, or else a
+// JSON lines file, where each line is a record.
+func Open(path string) (Reader, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return OpenDir(path)
+	}
+	if format, ok := datasets.FormatOf(path); ok && format != datasets.FormatJSONL {
+		return OpenDataset(path, format)
+	}
+	return OpenJSONL(path)
+}
+
+// datasetReader reads the examples of a dataset.
+type datasetReader struct {
+	r datasets.Reader
+}
+
+// OpenDataset opens the dataset file in the format as a corpus, where each
+// example is a record, with its input, and its fields.
+func OpenDataset(filename string, format datasets.Format) (Reader, error) {
+	r, err := datasets.Open(filename, format)
+	if err != nil {
+		return nil, err
+	}
+	return datasetReader{r: r}, nil
+}
+
+func (r datasetReader) Next() (Record, error) {
+	ex, err := r.r.Next()
+	if err != nil {
+		return Record{}, err
+	}
+	return Record{Input: ex.Input, Fields: ex.Fields}, nil
+}
+
+func (r datasetReader) Close() error {
+	return r.r.Close()
+}
+
+// dirReader reads the files of a directory.
+type dirReader struct {
+	dir   string
+	files []string
+	next  int
+}
+
+// OpenDir opens the directory as a corpus, where each regular file, in
+// the sub-directories too, is a record whose input is the content of the
+// file. The files are read in lexical order of their paths, the hidden ones
+// being skipped.
+func OpenDir(dir string) (Reader, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return &dirReader{dir: dir, files: files}, nil
+}
+
+func (r *dirReader) Next() (Record, error) {
+	if r.next >= len(r.files) {
+		return Record{}, io.EOF
+	}
+	name := r.files[r.next]
+	r.next++
+	data, err := os.ReadFile(filepath.Join(r.dir, filepath.FromSlash(name)))
+	if err != nil {
+		return Record{}, err
+	}
+	return Record{
+		Input:  strings.TrimSpace(string(data)),
+		Fields: map[string]any{"path": name},
+	}, nil
+}
+
+func (r *dirReader) Close() error {
+	return nil
+}
+
+// jsonlReader reads the lines of a JSON lines file.
+type jsonlReader struct {
+	f       *os.File
+	scanner *bufio.Scanner
+	line    int
+}
+
+// OpenJSONL opens the JSON lines file as a corpus, where each non-empty line
+// is a JSON object with the input in its "input" field.
+func OpenJSONL(filename string) (Reader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	return &jsonlReader{f: f, scanner: scanner}, nil
+}
+
+func (r *jsonlReader) Next() (Record, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" {
+			continue
+		}
+		var fields map[string]any
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			return Record{}, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		input, ok := fields["input"].(string)
+		if !ok {
+			return Record{}, fmt.Errorf("line %d: %w", r.line, errors.New(`missing string field "input"`))
+		}
+		return Record{Input: input, Fields: fields}, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
+
+func (r *jsonlReader) Close() error {
+	return r.f.Close()
+}
@@ -0,0 +1,360 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Format is the format of the output file of a run.
+type Format string
+
+const (
+	// FormatJSONL is the JSON lines format, a result per line.
+	FormatJSONL Format = "jsonl"
+	// FormatArrow is the Apache Arrow IPC file format, also known as
+	// Feather v2.
+	FormatArrow Format = "arrow"
+	// FormatParquet is the Apache Parquet format.
+	FormatParquet Format = "parquet"
+)
+
+// ParseFormat parses the name of a format.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatJSONL, FormatArrow, FormatParquet:
+		return f, nil
+	default:
+		return "", fmt.Errorf("invalid output format %#v", s)
+	}
+}
+
+// FormatOf returns the format of the output file by its extension: ".arrow",
+// ".feather" and ".ipc" for Arrow, ".parquet" for Parquet, and JSON lines
+// otherwise.
+func FormatOf(filename string) Format {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".arrow", ".feather", ".ipc":
+		return FormatArrow
+	case ".parquet":
+		return FormatParquet
+	default:
+		return FormatJSONL
+	}
+}
+
+// tableBatchRows is the number of rows of a record batch of Arrow, or of a
+// row group of Parquet, so that the results are converted in bounded memory.
+// It's a variable for the tests.
+var tableBatchRows = 8192
+
+// columnKind is the type of the values of a column.
+type columnKind int
+
+const (
+	// kindNull is the kind of a column whose values are all null; it's
+	// written as a string column.
+	kindNull columnKind = iota
+	kindBool
+	kindInt
+	kindFloat
+	kindString
+	// kindVector is the kind of a column of lists of numbers, all of the
+	// same length, written as float32 vectors.
+	kindVector
+	// kindJSON is the kind of a column of any other values, or of values of
+	// different kinds, written as strings of their JSON encoding.
+	kindJSON
+)
+
+// column is a column of the table of the results.
+type column struct {
+	name string
+	kind columnKind
+	// dim is the length of the vectors of a kindVector column.
+	dim int
+}
+
+// kindOf returns the kind of the JSON value, with the length of a vector.
+func kindOf(v any) (columnKind, int) {
+	switch v := v.(type) {
+	case nil:
+		return kindNull, 0
+	case bool:
+		return kindBool, 0
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return kindInt, 0
+		}
+		return kindFloat, 0
+	case string:
+		return kindString, 0
+	case []any:
+		for _, x := range v {
+			if _, ok := x.(json.Number); !ok {
+				return kindJSON, 0
+			}
+		}
+		return kindVector, len(v)
+	default:
+		return kindJSON, 0
+	}
+}
+
+// observe widens the kind of the column to hold the value: integers are
+// widened to floats, and the values of different kinds, or vectors of
+// different lengths, to JSON.
+func (c *column) observe(v any) {
+	k, dim := kindOf(v)
+	switch {
+	case k == kindNull:
+	case c.kind == kindNull:
+		c.kind, c.dim = k, dim
+	case c.kind == k && (k != kindVector || c.dim == dim):
+	case (c.kind == kindInt || c.kind == kindFloat) && (k == kindInt || k == kindFloat):
+		c.kind = kindFloat
+	default:
+		c.kind = kindJSON
+	}
+}
+
+// flattenResult decodes a result line into a row, by column name: the
+// fields of an object output are flattened into "output.<field>" columns,
+// e.g. "output.Vector" for the embeddings.
+func flattenResult(line []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var row map[string]any
+	if err := dec.Decode(&row); err != nil {
+		return nil, err
+	}
+	if out, ok := row["output"].(map[string]any); ok {
+		delete(row, "output")
+		for k, v := range out {
+			row["output."+k] = v
+		}
+	}
+	return row, nil
+}
+
+// scanResults calls the function with the row of each result line of the
+// file.
+func scanResults(filename string, fn func(row map[string]any) error) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		row, err := flattenResult(scanner.Bytes())
+		if err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// inferColumns returns the columns of the results of the file, sorted by
+// name, with the kinds of their values.
+func inferColumns(filename string) ([]*column, error) {
+	byName := make(map[string]*column)
+	err := scanResults(filename, func(row map[string]any) error {
+		for name, v := range row {
+			c, ok := byName[name]
+			if !ok {
+				c = &column{name: name}
+				byName[name] = c
+			}
+			c.observe(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]*column, 0, len(byName))
+	for _, c := range byName {
+		columns = append(columns, c)
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].name < columns[j].name })
+	return columns, nil
+}
+
+// columnData are the values of a column in a batch of rows. Only the slice
+// of the kind of the column is set, with the zero value for the nulls; the
+// vectors are flattened.
+type columnData struct {
+	valid   []bool
+	nulls   int
+	bools   []bool
+	ints    []int64
+	floats  []float64
+	strings []string
+	vectors []float32
+}
+
+// appendValue appends the value of a row to the data of the column.
+func (d *columnData) appendValue(c *column, v any) error {
+	d.valid = append(d.valid, v != nil)
+	if v == nil {
+		d.nulls++
+	}
+	switch c.kind {
+	case kindBool:
+		b, _ := v.(bool)
+		d.bools = append(d.bools, b)
+	case kindInt:
+		var n int64
+		if v != nil {
+			var err error
+			if n, err = v.(json.Number).Int64(); err != nil {
+				return err
+			}
+		}
+		d.ints = append(d.ints, n)
+	case kindFloat:
+		var x float64
+		if v != nil {
+			var err error
+			if x, err = v.(json.Number).Float64(); err != nil {
+				return err
+			}
+		}
+		d.floats = append(d.floats, x)
+	case kindVector:
+		values, _ := v.([]any)
+		for i := 0; i < c.dim; i++ {
+			var x float64
+			if v != nil {
+				var err error
+				if x, err = values[i].(json.Number).Float64(); err != nil {
+					return err
+				}
+			}
+			d.vectors = append(d.vectors, float32(x))
+		}
+	case kindNull, kindString:
+		s, _ := v.(string)
+		d.strings = append(d.strings, s)
+	case kindJSON:
+		var s string
+		if v != nil {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			s = string(data)
+		}
+		d.strings = append(d.strings, s)
+	}
+	return nil
+}
+
+// tableWriter writes a table in a columnar format.
+type tableWriter interface {
+	// writeBatch writes a batch of rows, given the data of each column.
+	writeBatch(rows int, data []*columnData) error
+	// close writes the end of the file.
+	close() error
+}
+
+// countingWriter counts the bytes written, i.e. the offset in the file.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// convertResults converts the JSON lines results of the file to a table in
+// the format, written to the output file.
+func convertResults(results, output string, format Format) error {
+	columns, err := inferColumns(results)
+	if err != nil {
+		return err
+	}
+	tmp := output + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	bw := bufio.NewWriter(f)
+	cw := &countingWriter{w: bw}
+	var tw tableWriter
+	switch format {
+	case FormatArrow:
+		tw, err = newArrowWriter(cw, columns)
+	case FormatParquet:
+		tw, err = newParquetWriter(cw, columns)
+	default:
+		err = fmt.Errorf("batch: unsupported table format %#v", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	var rows int
+	data := make([]*columnData, len(columns))
+	reset := func() {
+		rows = 0
+		for i := range data {
+			data[i] = &columnData{}
+		}
+	}
+	reset()
+	err = scanResults(results, func(row map[string]any) error {
+		for i, c := range columns {
+			if err := data[i].appendValue(c, row[c.name]); err != nil {
+				return fmt.Errorf("batch: column %#v: %w", c.name, err)
+			}
+		}
+		if rows++; rows == tableBatchRows {
+			if err := tw.writeBatch(rows, data); err != nil {
+				return err
+			}
+			reset()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if rows > 0 {
+		if err := tw.writeBatch(rows, data); err != nil {
+			return err
+		}
+	}
+	if err := tw.close(); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, output)
+}
@@ -0,0 +1,327 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package batch
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// embed returns the embedding of an input as the text encoding does.
+func embed(_ context.Context, input string) (any, error) {
+	if input == "text 3" {
+		return nil, errors.New("bad input")
+	}
+	var n float32
+	fmt.Sscanf(input, "text %f", &n)
+	return struct{ Vector []float32 }{[]float32{n, n / 2, -n}}, nil
+}
+
+func TestFormatOf(t *testing.T) {
+	assert.Equal(t, FormatArrow, FormatOf("out.arrow"))
+	assert.Equal(t, FormatArrow, FormatOf("out.Feather"))
+	assert.Equal(t, FormatParquet, FormatOf("out.parquet"))
+	assert.Equal(t, FormatJSONL, FormatOf("out.jsonl"))
+	assert.Equal(t, FormatJSONL, FormatOf("out"))
+}
+
+func TestInferColumns(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "results.jsonl")
+	require.NoError(t, os.WriteFile(filename, []byte(strings.Join([]string{
+		`{"id": 1, "score": 1, "tags": ["a"], "output": {"Vector": [1, 2], "Label": "x"}}`,
+		`{"id": 2, "score": 0.5, "tags": null, "output": {"Vector": [3, 4.5], "Label": null}}`,
+		`{"id": 3, "score": 2, "tags": [], "error": "failed"}`,
+	}, "\n")), 0644))
+
+	columns, err := inferColumns(filename)
+	require.NoError(t, err)
+	assert.Equal(t, []*column{
+		{name: "error", kind: kindString},
+		{name: "id", kind: kindInt},
+		{name: "output.Label", kind: kindString},
+		{name: "output.Vector", kind: kindVector, dim: 2},
+		{name: "score", kind: kindFloat},
+		{name: "tags", kind: kindJSON},
+	}, columns)
+}
+
+func runTable(t *testing.T, format Format) string {
+	corpus := writeCorpus(t, 10)
+	output := filepath.Join(t.TempDir(), "out."+string(format))
+	r, err := Open(corpus)
+	require.NoError(t, err)
+	defer r.Close()
+
+	stats, err := Run(context.Background(), r, output, embed, Options{Parallelism: 2, Format: format})
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Processed: 10, Failed: 1}, stats)
+	assert.NoFileExists(t, output+SpoolSuffix)
+	assert.NoFileExists(t, output+CheckpointSuffix)
+	return output
+}
+
+func TestRun_Arrow(t *testing.T) {
+	data, err := os.ReadFile(runTable(t, FormatArrow))
+	require.NoError(t, err)
+	require.Equal(t, "ARROW1\x00\x00", string(data[:8]))
+	require.Equal(t, "ARROW1", string(data[len(data)-6:]))
+
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-10:]))
+	footer := fbRoot(data[len(data)-10-footerLen : len(data)-10])
+	var names []string
+	var types []uint64
+	for _, f := range footer.table(1).tables(1) {
+		names = append(names, f.str(0))
+		types = append(types, f.scalar(2, 1))
+	}
+	assert.Equal(t, []string{"error", "id", "input", "output.Vector"}, names)
+	assert.Equal(t, []uint64{arrowTypeUtf8, arrowTypeInt, arrowTypeUtf8, arrowTypeFixedSizeList}, types)
+	vector := footer.table(1).tables(1)[3]
+	assert.Equal(t, uint64(3), vector.table(3).scalar(0, 4))
+	assert.Equal(t, "item", vector.tables(5)[0].str(0))
+
+	blocks := footer.structs(3)
+	require.Len(t, blocks, 24)
+	offset := int(binary.LittleEndian.Uint64(blocks))
+	metaLen := int(binary.LittleEndian.Uint32(blocks[8:]))
+	require.Equal(t, uint32(0xFFFFFFFF), binary.LittleEndian.Uint32(data[offset:]))
+	msg := fbRoot(data[offset+8 : offset+metaLen])
+	assert.Equal(t, uint64(arrowHeaderRecordBatch), msg.scalar(1, 1))
+	batch := msg.table(2)
+	assert.Equal(t, uint64(10), batch.scalar(0, 8))
+	body := data[offset+metaLen:]
+	buffers := batch.structs(2)
+	buffer := func(i int) []byte {
+		start := binary.LittleEndian.Uint64(buffers[16*i:])
+		return body[start : start+binary.LittleEndian.Uint64(buffers[16*i+8:])]
+	}
+
+	ids := buffer(4)
+	vectorValidity := buffer(8)
+	vectors := buffer(10)
+	require.Len(t, vectors, 10*3*4)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, uint64(i), binary.LittleEndian.Uint64(ids[8*i:]))
+		assert.Equal(t, i != 3, vectorValidity[i/8]&(1<<(i%8)) != 0)
+		if i != 3 {
+			assert.Equal(t, float32(i)/2, math.Float32frombits(binary.LittleEndian.Uint32(vectors[12*i+4:])))
+		}
+	}
+}
+
+func TestRun_Parquet(t *testing.T) {
+	data, err := os.ReadFile(runTable(t, FormatParquet))
+	require.NoError(t, err)
+	require.Equal(t, "PAR1", string(data[:4]))
+	require.Equal(t, "PAR1", string(data[len(data)-4:]))
+
+	metaLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	meta, _ := decodeThrift(data[len(data)-8-metaLen:])
+	assert.Equal(t, int64(10), meta[3])
+	var names []any
+	for _, e := range meta[2].([]any) {
+		names = append(names, e.(map[int16]any)[4])
+	}
+	assert.Equal(t, []any{"schema", "error", "id", "input", "output.Vector", "list", "element"}, names)
+
+	columns := meta[4].([]any)[0].(map[int16]any)[1].([]any)
+	require.Len(t, columns, 4)
+	page := func(i int) (map[int16]any, []byte) {
+		md := columns[i].(map[int16]any)[3].(map[int16]any)
+		header, n := decodeThrift(data[md[9].(int64):])
+		start := int(md[9].(int64)) + n
+		return header, data[start : start+int(header[3].(int64))]
+	}
+
+	header, ids := page(1)
+	assert.Equal(t, int64(10), header[5].(map[int16]any)[1])
+	def, ids := decodeLevels(ids)
+	assert.Equal(t, []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}, def)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, uint64(i), binary.LittleEndian.Uint64(ids[8*i:]))
+	}
+
+	header, vectors := page(3)
+	assert.Equal(t, int64(9*3+1), header[5].(map[int16]any)[1])
+	rep, vectors := decodeLevels(vectors)
+	def, vectors = decodeLevels(vectors)
+	assert.Equal(t, []int{0, 1, 1, 0, 1, 1}, rep[:6])
+	assert.Equal(t, []int{2, 2, 2, 2, 2, 2, 2, 2, 2, 0, 2}, def[:11])
+	require.Len(t, vectors, 9*3*4)
+	assert.Equal(t, float32(-4), math.Float32frombits(binary.LittleEndian.Uint32(vectors[3*3*4+8:])))
+}
+
+// TestConvertResults_Golden compares the tables converted from the results
+// of testdata/results.jsonl, with all the kinds of columns, nulls, and two
+// batches, with the golden files, decoded by the readers of Apache Arrow as
+// in testdata/golden/results.txt (see testdata/verify). The golden files
+// are written instead when the env var TEST_GOLDEN_UPDATE is set, to be
+// verified again.
+func TestConvertResults_Golden(t *testing.T) {
+	defer func(rows int) { tableBatchRows = rows }(tableBatchRows)
+	tableBatchRows = 4
+
+	for _, format := range []Format{FormatArrow, FormatParquet} {
+		t.Run(string(format), func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "results."+string(format))
+			require.NoError(t, convertResults(filepath.Join("testdata", "results.jsonl"), output, format))
+			got, err := os.ReadFile(output)
+			require.NoError(t, err)
+
+			golden := filepath.Join("testdata", "golden", "results."+string(format))
+			if os.Getenv("TEST_GOLDEN_UPDATE") != "" {
+				require.NoError(t, os.WriteFile(golden, got, 0o644))
+				return
+			}
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err, "set env var TEST_GOLDEN_UPDATE to write it")
+			assert.Equal(t, want, got, "the table doesn't match %s - set env var TEST_GOLDEN_UPDATE if the change is intended", golden)
+		})
+	}
+}
+
+// fbRef is a table of a flatbuffer.
+type fbRef struct {
+	buf []byte
+	pos int
+}
+
+func fbRoot(buf []byte) fbRef {
+	return fbRef{buf, int(binary.LittleEndian.Uint32(buf))}
+}
+
+// field returns the position of the field, or 0 if absent.
+func (t fbRef) field(id int) int {
+	vtable := t.pos - int(int32(binary.LittleEndian.Uint32(t.buf[t.pos:])))
+	if 4+2*id >= int(binary.LittleEndian.Uint16(t.buf[vtable:])) {
+		return 0
+	}
+	if off := int(binary.LittleEndian.Uint16(t.buf[vtable+4+2*id:])); off != 0 {
+		return t.pos + off
+	}
+	return 0
+}
+
+func (t fbRef) scalar(id, size int) uint64 {
+	var v uint64
+	for i, pos := 0, t.field(id); i < size && pos != 0; i++ {
+		v |= uint64(t.buf[pos+i]) << (8 * i)
+	}
+	return v
+}
+
+func (t fbRef) deref(id int) int {
+	pos := t.field(id)
+	return pos + int(binary.LittleEndian.Uint32(t.buf[pos:]))
+}
+
+func (t fbRef) table(id int) fbRef {
+	return fbRef{t.buf, t.deref(id)}
+}
+
+func (t fbRef) str(id int) string {
+	pos := t.deref(id)
+	return string(t.buf[pos+4 : pos+4+int(binary.LittleEndian.Uint32(t.buf[pos:]))])
+}
+
+func (t fbRef) tables(id int) []fbRef {
+	pos := t.deref(id)
+	tables := make([]fbRef, binary.LittleEndian.Uint32(t.buf[pos:]))
+	for i := range tables {
+		elem := pos + 4 + 4*i
+		tables[i] = fbRef{t.buf, elem + int(binary.LittleEndian.Uint32(t.buf[elem:]))}
+	}
+	return tables
+}
+
+// structs returns the data of a vector of structs of 8-byte fields.
+func (t fbRef) structs(id int) []byte {
+	pos := t.deref(id)
+	if (pos+4)%8 != 0 {
+		panic("misaligned structs")
+	}
+	return t.buf[pos+4:]
+}
+
+// decodeThrift decodes a struct in the Thrift compact protocol, with the
+// integers as int64 and the lists as []any, returning its length.
+func decodeThrift(b []byte) (map[int16]any, int) {
+	s := make(map[int16]any)
+	pos := 0
+	var last int16
+	for {
+		h := b[pos]
+		pos++
+		if h == 0 {
+			return s, pos
+		}
+		if h>>4 != 0 {
+			last += int16(h >> 4)
+		} else {
+			id, n := binary.Varint(b[pos:])
+			pos += n
+			last = int16(id)
+		}
+		v, n := decodeThriftValue(b[pos:], h&0x0F)
+		s[last] = v
+		pos += n
+	}
+}
+
+func decodeThriftValue(b []byte, typ byte) (any, int) {
+	switch typ {
+	case thriftTypeI32, thriftTypeI64:
+		return binary.Varint(b)
+	case thriftTypeBinary:
+		n, m := binary.Uvarint(b)
+		return string(b[m : m+int(n)]), m + int(n)
+	case thriftTypeList:
+		n, elem, pos := int(b[0]>>4), b[0]&0x0F, 1
+		if n == 15 {
+			size, m := binary.Uvarint(b[1:])
+			n, pos = int(size), 1+m
+		}
+		list := make([]any, n)
+		for i := range list {
+			v, m := decodeThriftValue(b[pos:], elem)
+			list[i] = v
+			pos += m
+		}
+		return list, pos
+	case thriftTypeStruct:
+		return decodeThrift(b)
+	default:
+		panic(fmt.Sprintf("unexpected thrift type %d", typ))
+	}
+}
+
+// decodeLevels decodes the RLE runs of the levels at the start of the page,
+// returning the rest of it.
+func decodeLevels(page []byte) ([]int, []byte) {
+	n := int(binary.LittleEndian.Uint32(page))
+	runs := page[4 : 4+n]
+	var levels []int
+	for len(runs) > 0 {
+		h, m := binary.Uvarint(runs)
+		if h&1 != 0 {
+			panic("unexpected bit-packed run")
+		}
+		for i := 0; i < int(h>>1); i++ {
+			levels = append(levels, int(runs[m]))
+		}
+		runs = runs[m+1:]
+	}
+	return levels, page[4+n:]
+}
@@ -0,0 +1,156 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command verify decodes the golden Arrow and Parquet files of the batch
+// package with the readers of Apache Arrow, independent of the writers of
+// the package, and prints their schemas and rows, failing if the files
+// don't hold the same rows. The output is compared with results.txt:
+//
+//	go run . ../golden | diff ../golden/results.txt -
+//
+// It's a module of its own, so that the package doesn't depend on Arrow.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: verify <golden dir>")
+		os.Exit(2)
+	}
+	if err := run(os.Args[1]); err != nil {
+		fmt.Fprintln(os.Stderr, "verify:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string) error {
+	arrowRows, err := readArrow(filepath.Join(dir, "results.arrow"))
+	if err != nil {
+		return fmt.Errorf("arrow: %w", err)
+	}
+	parquetRows, err := readParquet(filepath.Join(dir, "results.parquet"))
+	if err != nil {
+		return fmt.Errorf("parquet: %w", err)
+	}
+	fmt.Println("rows:")
+	for _, r := range arrowRows {
+		fmt.Println("  " + r)
+	}
+	if strings.Join(arrowRows, "\n") != strings.Join(parquetRows, "\n") {
+		fmt.Println("parquet rows:")
+		for _, r := range parquetRows {
+			fmt.Println("  " + r)
+		}
+		return fmt.Errorf("the Arrow and Parquet files hold different rows")
+	}
+	return nil
+}
+
+// readArrow prints the schema and the batches of the Arrow IPC file, and
+// returns its rows.
+func readArrow(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := ipc.NewFileReader(f, ipc.WithAllocator(memory.DefaultAllocator))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	fmt.Printf("arrow schema:\n%s\n", indent(r.Schema().String()))
+	fmt.Printf("arrow record batches: %d\n", r.NumRecords())
+	var rows []string
+	for i := 0; i < r.NumRecords(); i++ {
+		rec, err := r.RecordAt(i)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, recordRows(rec)...)
+		rec.Release()
+	}
+	return rows, nil
+}
+
+// readParquet prints the schema and the row groups of the Parquet file, and
+// returns its rows.
+func readParquet(filename string) ([]string, error) {
+	pf, err := file.OpenParquetFile(filename, false)
+	if err != nil {
+		return nil, err
+	}
+	defer pf.Close()
+	fmt.Printf("parquet schema:\n%s", indent(pf.MetaData().Schema.String()))
+	fmt.Printf("parquet row groups: %d, created by %q\n", pf.NumRowGroups(), pf.MetaData().GetCreatedBy())
+	fr, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	if err != nil {
+		return nil, err
+	}
+	tbl, err := fr.ReadTable(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer tbl.Release()
+	var rows []string
+	tr := array.NewTableReader(tbl, tbl.NumRows())
+	defer tr.Release()
+	for tr.Next() {
+		rows = append(rows, recordRows(tr.Record())...)
+	}
+	return rows, nil
+}
+
+// recordRows returns the rows of the record, with the values of the
+// columns by name.
+func recordRows(rec arrow.Record) []string {
+	rows := make([]string, rec.NumRows())
+	for i := range rows {
+		var fields []string
+		for j, col := range rec.Columns() {
+			fields = append(fields, fmt.Sprintf("%s=%s", rec.ColumnName(j), value(col, i)))
+		}
+		rows[i] = strings.Join(fields, " | ")
+	}
+	return rows
+}
+
+// value returns the value of the array at the index, the lists with their
+// elements, whatever their type.
+func value(arr arrow.Array, i int) string {
+	if arr.IsNull(i) {
+		return "null"
+	}
+	switch a := arr.(type) {
+	case array.ListLike:
+		start, end := a.ValueOffsets(i)
+		var elems []string
+		for j := start; j < end; j++ {
+			elems = append(elems, value(a.ListValues(), int(j)))
+		}
+		return "[" + strings.Join(elems, " ") + "]"
+	case *array.String:
+		return fmt.Sprintf("%q", a.Value(i))
+	default:
+		return arr.ValueStr(i)
+	}
+}
+
+func indent(s string) string {
+	return "  " + strings.ReplaceAll(strings.TrimSuffix(s, "\n"), "\n", "\n  ") + "\n"
+}
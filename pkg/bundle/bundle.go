@@ -0,0 +1,332 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package bundle implements a single-file format for converted models.
+//
+// A bundle is a tar archive containing a JSON manifest, as first entry,
+// followed by all the files of the model directory needed at inference time
+// (weights, tokenizer, configuration, ...). The manifest records the task
+// metadata and the SHA-256 hash of each file, which is verified on extraction.
+package bundle
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/models"
+)
+
+// FormatVersion is the version of the bundle format.
+const FormatVersion = 1
+
+// ManifestFilename is the name of the manifest entry.
+const ManifestFilename = "manifest.json"
+
+// excludedFiles are the files of the model directory not needed at
+// inference time, that are never included in a bundle.
+var excludedFiles = map[string]bool{
+	"pytorch_model.bin": true,
+	"model.safetensors": true,
+	".last_used":        true,
+}
+
+// Manifest describes the content of a bundle.
+type Manifest struct {
+	// FormatVersion is the version of the bundle format.
+	FormatVersion int `json:"format_version"`
+	// ModelName is the name of the model (format: <org>/<model>).
+	ModelName string `json:"model_name"`
+	// ModelType is the model type, as in the model configuration (e.g. "bert").
+	ModelType string `json:"model_type"`
+	// Task is the task the model is meant for (e.g. "text-classification").
+	Task string `json:"task,omitempty"`
+	// CreatedAt is the creation time of the bundle.
+	CreatedAt time.Time `json:"created_at"`
+	// Files are the files of the bundle, in the same order as in the archive.
+	Files []File `json:"files"`
+}
+
+// File is a file of the bundle.
+type File struct {
+	// Path is the slash-separated path relative to the model directory.
+	Path string `json:"path"`
+	// Size is the size in bytes.
+	Size int64 `json:"size"`
+	// SHA256 is the hex-encoded SHA-256 hash of the content.
+	SHA256 string `json:"sha256"`
+}
+
+// Create creates a bundle with the files of the model directory.
+// The model name and the task of the manifest are taken from the arguments,
+// the model type from the model configuration.
+func Create(modelDir, filename, modelName, task string) (*Manifest, error) {
+	modelConfig, err := models.ReadCommonModelConfig(modelDir, "")
+	if err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		ModelName:     modelName,
+		ModelType:     modelConfig.ModelType,
+		Task:          task,
+		CreatedAt:     time.Now().UTC(),
+	}
+	if manifest.Files, err = hashFiles(modelDir); err != nil {
+		return nil, err
+	}
+
+	out, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	if err = write(out, modelDir, manifest); err != nil {
+		_ = out.Close()
+		_ = os.Remove(filename)
+		return nil, err
+	}
+	return manifest, out.Close()
+}
+
+// hashFiles returns the files of the model directory to bundle.
+func hashFiles(modelDir string) ([]File, error) {
+	var files []File
+	err := filepath.WalkDir(modelDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(modelDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if excludedFiles[rel] {
+			return nil
+		}
+		size, sum, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+		files = append(files, File{Path: rel, Size: size, SHA256: sum})
+		return nil
+	})
+	return files, err
+}
+
+func hashFile(filename string) (int64, string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func write(w io.Writer, modelDir string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(w)
+	hdr := &tar.Header{Name: ManifestFilename, Mode: 0o644, Size: int64(len(data)), ModTime: manifest.CreatedAt}
+	if err = tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if _, err = tw.Write(data); err != nil {
+		return err
+	}
+	for _, file := range manifest.Files {
+		if err = writeFile(tw, modelDir, file, manifest.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeFile(tw *tar.Writer, modelDir string, file File, modTime time.Time) error {
+	f, err := os.Open(filepath.Join(modelDir, filepath.FromSlash(file.Path)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	hdr := &tar.Header{Name: file.Path, Mode: 0o644, Size: file.Size, ModTime: modTime}
+	if err = tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	// A file modified after hashing results in a short write or in a
+	// corrupted bundle, which is detected on extraction.
+	_, err = io.CopyN(tw, f, file.Size)
+	return err
+}
+
+// ErrIntegrity is returned when the content of a bundle doesn't match its manifest.
+var ErrIntegrity = errors.New("bundle: integrity check failed")
+
+// Extract extracts a bundle to the given directory, verifying each file
+// against the manifest. The files are extracted to a temporary directory,
+// which replaces the destination directory as a whole, with a rename, only
+// once all of them are verified: a corrupted bundle never replaces a valid
+// model, and the model is never a mix of the files of two bundles. The
+// files of the destination directory not in the bundle are removed.
+func Extract(filename, destDir string) (manifest *Manifest, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	manifest, err = readManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+	expected := make(map[string]File, len(manifest.Files))
+	for _, file := range manifest.Files {
+		if !validPath(file.Path) {
+			return nil, fmt.Errorf("%w: invalid path %q", ErrIntegrity, file.Path)
+		}
+		expected[file.Path] = file
+	}
+
+	parent := filepath.Dir(filepath.Clean(destDir))
+	if err = os.MkdirAll(parent, 0o755); err != nil {
+		return nil, err
+	}
+	tmpDir, err := os.MkdirTemp(parent, ".bundle-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.RemoveAll(tmpDir)
+		}
+	}()
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		file, ok := expected[hdr.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unexpected file %q", ErrIntegrity, hdr.Name)
+		}
+		if err = extractFile(tr, tmpDir, file); err != nil {
+			return nil, err
+		}
+		delete(expected, hdr.Name)
+	}
+	for _, file := range manifest.Files {
+		if _, missing := expected[file.Path]; missing {
+			return nil, fmt.Errorf("%w: missing file %q", ErrIntegrity, file.Path)
+		}
+	}
+	if err = replaceDir(tmpDir, destDir); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// replaceDir renames the directory src to dest, replacing dest if it
+// exists. The replaced directory is moved aside first, and put back if the
+// rename fails.
+func replaceDir(src, dest string) error {
+	if _, err := os.Stat(dest); os.IsNotExist(err) {
+		return os.Rename(src, dest)
+	}
+	oldDir, err := os.MkdirTemp(filepath.Dir(src), ".bundle-*")
+	if err != nil {
+		return err
+	}
+	old := filepath.Join(oldDir, "old")
+	if err = os.Rename(dest, old); err != nil {
+		_ = os.Remove(oldDir)
+		return err
+	}
+	if err = os.Rename(src, dest); err != nil {
+		if e := os.Rename(old, dest); e == nil {
+			_ = os.Remove(oldDir)
+		}
+		return err
+	}
+	return os.RemoveAll(oldDir)
+}
+
+// ReadManifest reads the manifest of a bundle, without verifying the files.
+func ReadManifest(filename string) (*Manifest, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readManifest(tar.NewReader(f))
+}
+
+func readManifest(tr *tar.Reader) (*Manifest, error) {
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("bundle: failed to read manifest: %w", err)
+	}
+	if hdr.Name != ManifestFilename {
+		return nil, fmt.Errorf("bundle: missing manifest")
+	}
+	manifest := &Manifest{}
+	if err = json.NewDecoder(tr).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("bundle: invalid manifest: %w", err)
+	}
+	if manifest.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("bundle: unsupported format version %d", manifest.FormatVersion)
+	}
+	return manifest, nil
+}
+
+// validPath reports whether p is a relative path that doesn't escape the
+// destination directory.
+func validPath(p string) bool {
+	return p != "" && p == path.Clean(p) && !path.IsAbs(p) && p != ".." && !strings.HasPrefix(p, "../")
+}
+
+func extractFile(r io.Reader, destDir string, file File) (err error) {
+	dest := filepath.Join(destDir, filepath.FromSlash(file.Path))
+	if err = os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := out.Close(); err == nil {
+			err = e
+		}
+	}()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(out, h), r)
+	if err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); n != file.Size || sum != file.SHA256 {
+		return fmt.Errorf("%w: %q has size %d and hash %s, expected %d and %s", ErrIntegrity, file.Path, n, sum, file.Size, file.SHA256)
+	}
+	return nil
+}
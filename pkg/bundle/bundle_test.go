@@ -0,0 +1,119 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeModelDir(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.json":       `{"model_type": "bert"}`,
+		"vocab.txt":         "[PAD]\n[UNK]\nhello\n",
+		"spago_model.bin":   "weights",
+		"repo/tokens/000":   "embeddings",
+		"pytorch_model.bin": "original weights",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestCreateAndExtract(t *testing.T) {
+	modelDir := writeModelDir(t)
+	filename := filepath.Join(t.TempDir(), "model.tar")
+
+	manifest, err := Create(modelDir, filename, "org/model", "text-encoding")
+	require.NoError(t, err)
+	assert.Equal(t, "bert", manifest.ModelType)
+	assert.Len(t, manifest.Files, 4)
+
+	read, err := ReadManifest(filename)
+	require.NoError(t, err)
+	assert.Equal(t, "org/model", read.ModelName)
+	assert.Equal(t, "text-encoding", read.Task)
+
+	dest := t.TempDir()
+	_, err = Extract(filename, dest)
+	require.NoError(t, err)
+	for _, name := range []string{"config.json", "vocab.txt", "spago_model.bin", "repo/tokens/000"} {
+		expected, err := os.ReadFile(filepath.Join(modelDir, name))
+		require.NoError(t, err)
+		actual, err := os.ReadFile(filepath.Join(dest, name))
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual, name)
+	}
+	assert.NoFileExists(t, filepath.Join(dest, "pytorch_model.bin"))
+}
+
+func TestExtract_Corrupted(t *testing.T) {
+	modelDir := writeModelDir(t)
+	filename := filepath.Join(t.TempDir(), "model.tar")
+	_, err := Create(modelDir, filename, "org/model", "")
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	i := bytes.Index(data, []byte("weights"))
+	require.True(t, i > 0)
+	data[i] = 'W'
+	require.NoError(t, os.WriteFile(filename, data, 0o644))
+
+	dest := filepath.Join(t.TempDir(), "org", "model")
+	_, err = Extract(filename, dest)
+	assert.ErrorIs(t, err, ErrIntegrity)
+	assert.NoDirExists(t, dest)
+	entries, err := os.ReadDir(filepath.Dir(dest))
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the temporary directory is removed")
+}
+
+func TestExtract_Replace(t *testing.T) {
+	modelDir := writeModelDir(t)
+	filename := filepath.Join(t.TempDir(), "model.tar")
+	_, err := Create(modelDir, filename, "org/model", "")
+	require.NoError(t, err)
+
+	dest := filepath.Join(t.TempDir(), "model")
+	require.NoError(t, os.MkdirAll(dest, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dest, "spago_model.bin"), []byte("old weights"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dest, "stale.txt"), nil, 0o644))
+
+	// A corrupted bundle leaves the old model untouched.
+	corrupted := filepath.Join(t.TempDir(), "corrupted.tar")
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(corrupted, data[:len(data)-2048], 0o644))
+	_, err = Extract(corrupted, dest)
+	assert.Error(t, err)
+	assertFile(t, filepath.Join(dest, "spago_model.bin"), "old weights")
+	assert.NoFileExists(t, filepath.Join(dest, "config.json"))
+
+	_, err = Extract(filename, dest)
+	require.NoError(t, err)
+	assertFile(t, filepath.Join(dest, "spago_model.bin"), "weights")
+	assert.FileExists(t, filepath.Join(dest, "config.json"))
+	assert.NoFileExists(t, filepath.Join(dest, "stale.txt"))
+	entries, err := os.ReadDir(filepath.Dir(dest))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "the replaced directory is removed")
+}
+
+func assertFile(t *testing.T, filename, content string) {
+	t.Helper()
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
@@ -0,0 +1,336 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cache manages the local cache of models, i.e. the models
+// directory where models are downloaded and converted.
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/downloader"
+	"github.com/nlpodyssey/cybertron/pkg/filelock"
+	"github.com/nlpodyssey/cybertron/pkg/models"
+)
+
+// LastUsedFilename is the name of the file, in the model's directory,
+// whose modification time records when the model was last loaded.
+const LastUsedFilename = ".last_used"
+
+const (
+	// goModelFilename is the name of the converted model.
+	goModelFilename = "spago_model.bin"
+	// embeddingsRepoDirname is the directory of the converted embeddings.
+	embeddingsRepoDirname = "repo"
+	// lockSuffix is the suffix of the lease file of the lock of a model's
+	// directory, held while it's downloaded or converted.
+	lockSuffix = ".lock"
+)
+
+// Entry is a model in the cache.
+type Entry struct {
+	// Name is the model name (format: <org>/<model>).
+	Name string
+	// Path is the model's directory.
+	Path string
+	// Size is the total size of the model's files, in bytes.
+	Size int64
+	// LastUsed is when the model was last loaded or, if never loaded,
+	// last modified.
+	LastUsed time.Time
+}
+
+// Touch records that the model in the given directory has been used now.
+func Touch(modelPath string) error {
+	filename := filepath.Join(modelPath, LastUsedFilename)
+	now := time.Now()
+	err := os.Chtimes(filename, now, now)
+	if os.IsNotExist(err) {
+		return os.WriteFile(filename, nil, 0644)
+	}
+	return err
+}
+
+// List returns the models in the models directory, sorted by name.
+// A model is any directory with a configuration or a converted model.
+func List(modelsDir string) ([]Entry, error) {
+	var entries []Entry
+	err := filepath.WalkDir(modelsDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		if isPartialFile(d.Name()) {
+			return fs.SkipDir
+		}
+		if !isModelDir(p) {
+			return nil
+		}
+		rel, err := filepath.Rel(modelsDir, p)
+		if err != nil {
+			return err
+		}
+		e, err := newEntry(filepath.ToSlash(rel), p)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, e)
+		return fs.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+func isModelDir(p string) bool {
+	for _, name := range []string{models.DefaultModelConfigFilename, goModelFilename} {
+		if _, err := os.Stat(filepath.Join(p, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func newEntry(name, modelPath string) (Entry, error) {
+	e := Entry{Name: name, Path: modelPath}
+	var lastModified time.Time
+	err := filepath.WalkDir(modelPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		e.Size += info.Size()
+		if d.Name() == LastUsedFilename && p == filepath.Join(modelPath, LastUsedFilename) {
+			e.LastUsed = info.ModTime()
+		}
+		if info.ModTime().After(lastModified) {
+			lastModified = info.ModTime()
+		}
+		return nil
+	})
+	if e.LastUsed.IsZero() {
+		e.LastUsed = lastModified
+	}
+	return e, err
+}
+
+// Problem is an integrity problem of a cached model.
+type Problem struct {
+	// Path is the file or directory affected by the problem.
+	Path string
+	// Description describes the problem.
+	Description string
+}
+
+// String returns a human-readable description of the problem.
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Path, p.Description)
+}
+
+// Verify checks the integrity of the model in the models directory,
+// without any network access: it reports the missing and empty files, and
+// the leftovers of interrupted downloads and conversions.
+func Verify(modelsDir, modelName string) ([]Problem, error) {
+	modelPath := filepath.Join(modelsDir, modelName)
+	if _, err := os.Stat(modelPath); err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	missing, err := downloader.MissingFiles(modelsDir, modelName)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range missing {
+		problems = append(problems, Problem{Path: filepath.Join(modelPath, name), Description: "missing file"})
+	}
+
+	err = filepath.WalkDir(modelPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == embeddingsRepoDirname {
+				// The embeddings repository is managed by the key-value store.
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == LastUsedFilename {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() == 0 {
+			problems = append(problems, Problem{Path: p, Description: "empty file"})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	orphans, err := findOrphans(modelPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range orphans {
+		problems = append(problems, Problem{Path: p, Description: "orphaned artifact"})
+	}
+	return problems, nil
+}
+
+// PruneOptions are the criteria to select the models to prune.
+type PruneOptions struct {
+	// MaxAge removes the models not used for longer than MaxAge (zero means no limit).
+	MaxAge time.Duration
+	// MaxSize removes the least recently used models until the total size of
+	// the cache is within MaxSize bytes (zero means no limit).
+	MaxSize int64
+	// DryRun only reports the models that would be removed.
+	DryRun bool
+}
+
+// Prune removes the models selected by the options from the models
+// directory, and returns them. The models being downloaded or converted,
+// whose lock is held by another process (see filelock), are skipped.
+func Prune(modelsDir string, opts PruneOptions) ([]Entry, error) {
+	entries, err := List(modelsDir)
+	if err != nil {
+		return nil, err
+	}
+	// Least recently used first.
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].LastUsed.Before(entries[j].LastUsed) })
+
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+	now := time.Now()
+	var pruned []Entry
+	for _, e := range entries {
+		tooOld := opts.MaxAge > 0 && now.Sub(e.LastUsed) > opts.MaxAge
+		overBudget := opts.MaxSize > 0 && total > opts.MaxSize
+		if !tooOld && !overBudget {
+			continue
+		}
+		ok, err := remove(modelsDir, e.Path, opts.DryRun)
+		if err != nil {
+			return pruned, err
+		}
+		if !ok {
+			continue
+		}
+		total -= e.Size
+		pruned = append(pruned, e)
+	}
+	return pruned, nil
+}
+
+// remove removes the model's directory, holding its lock, reporting whether
+// it wasn't held by another process. In dry-run mode, the lock is only
+// checked.
+func remove(modelsDir, modelPath string, dryRun bool) (bool, error) {
+	lock, err := filelock.TryAcquire(modelPath+lockSuffix, filelock.Options{})
+	if errors.Is(err, filelock.ErrHeld) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !dryRun {
+		err = os.RemoveAll(modelPath)
+	}
+	if e := lock.Release(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return false, err
+	}
+	if !dryRun {
+		removeEmptyParents(modelsDir, modelPath)
+	}
+	return true, nil
+}
+
+// GC removes the orphaned artifacts from the models directory, and returns
+// their paths. Orphaned artifacts are the partial files of interrupted
+// downloads and extractions, and the embeddings of interrupted conversions.
+func GC(modelsDir string, dryRun bool) ([]string, error) {
+	orphans, err := findOrphans(modelsDir)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return orphans, nil
+	}
+	for _, p := range orphans {
+		if err := os.RemoveAll(p); err != nil {
+			return nil, err
+		}
+	}
+	return orphans, nil
+}
+
+// findOrphans returns the orphaned artifacts under the given directory.
+func findOrphans(root string) ([]string, error) {
+	var orphans []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if isPartialFile(d.Name()) {
+				orphans = append(orphans, p)
+				return fs.SkipDir
+			}
+			if d.Name() != embeddingsRepoDirname {
+				return nil
+			}
+			if _, err := os.Stat(filepath.Join(filepath.Dir(p), goModelFilename)); errors.Is(err, fs.ErrNotExist) {
+				orphans = append(orphans, p)
+			}
+			return fs.SkipDir
+		}
+		if isPartialFile(d.Name()) {
+			orphans = append(orphans, p)
+		}
+		return nil
+	})
+	return orphans, err
+}
+
+// isPartialFile reports whether the file is a leftover of an interrupted
+// download (".incomplete" files), bundle extraction (".bundle-" files and
+// directories) or lock breaking (".lock.stale." files).
+func isPartialFile(name string) bool {
+	return strings.HasSuffix(name, ".incomplete") ||
+		strings.Contains(name, ".incomplete.") ||
+		strings.HasPrefix(name, ".bundle-") ||
+		strings.Contains(name, ".lock.stale.")
+}
+
+// removeEmptyParents removes the empty parent directories of p, up to root,
+// e.g. the organization's directory of the last removed model.
+func removeEmptyParents(root, p string) {
+	root = filepath.Clean(root)
+	for dir := filepath.Dir(p); dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/filelock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	}
+}
+
+func TestListAndPrune(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"org/old/config.json":     `{"model_type": "bert"}`,
+		"org/old/spago_model.bin": "0123456789",
+		"org/new/config.json":     `{"model_type": "bert"}`,
+		"org/new/spago_model.bin": "01234",
+		"single/spago_model.bin":  "0123456789",
+	})
+	now := time.Now()
+	require.NoError(t, Touch(filepath.Join(dir, "org", "new")))
+	require.NoError(t, Touch(filepath.Join(dir, "single")))
+	require.NoError(t, Touch(filepath.Join(dir, "org", "old")))
+	old := now.Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "org", "old", LastUsedFilename), old, old))
+
+	entries, err := List(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "org/new", entries[0].Name)
+	assert.Equal(t, int64(27), entries[0].Size)
+	assert.Equal(t, "org/old", entries[1].Name)
+	assert.Equal(t, old.Unix(), entries[1].LastUsed.Unix())
+	assert.Equal(t, "single", entries[2].Name)
+
+	pruned, err := Prune(dir, PruneOptions{MaxAge: 24 * time.Hour, DryRun: true})
+	require.NoError(t, err)
+	require.Len(t, pruned, 1)
+	assert.DirExists(t, filepath.Join(dir, "org", "old"))
+
+	pruned, err = Prune(dir, PruneOptions{MaxAge: 24 * time.Hour})
+	require.NoError(t, err)
+	require.Len(t, pruned, 1)
+	assert.Equal(t, "org/old", pruned[0].Name)
+	assertNotExist(t, filepath.Join(dir, "org", "old"))
+
+	pruned, err = Prune(dir, PruneOptions{MaxSize: 30})
+	require.NoError(t, err)
+	require.Len(t, pruned, 1)
+	assert.Equal(t, "org/new", pruned[0].Name)
+	assertNotExist(t, filepath.Join(dir, "org"))
+	assert.DirExists(t, filepath.Join(dir, "single"))
+}
+
+func TestPrune_Locked(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"org/busy/spago_model.bin": "0123456789",
+		"org/idle/spago_model.bin": "0123456789",
+	})
+	lock, err := filelock.TryAcquire(filepath.Join(dir, "org", "busy")+lockSuffix, filelock.Options{})
+	require.NoError(t, err)
+
+	for _, dryRun := range []bool{true, false} {
+		pruned, err := Prune(dir, PruneOptions{MaxSize: 1, DryRun: dryRun})
+		require.NoError(t, err)
+		require.Len(t, pruned, 1)
+		assert.Equal(t, "org/idle", pruned[0].Name)
+	}
+	assert.DirExists(t, filepath.Join(dir, "org", "busy"))
+	assertNotExist(t, filepath.Join(dir, "org", "idle"))
+
+	require.NoError(t, lock.Release())
+	pruned, err := Prune(dir, PruneOptions{MaxSize: 1})
+	require.NoError(t, err)
+	require.Len(t, pruned, 1)
+	assertNotExist(t, filepath.Join(dir, "org"))
+}
+
+func TestVerifyAndGC(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"org/model/config.json":                  `{"model_type": "bert"}`,
+		"org/model/vocab.txt":                    "",
+		"org/model/tokenizer_config.json":        "{}",
+		"org/model/pytorch_model.bin.incomplete": "partial",
+		"org/broken/config.json":                 `{"model_type": "bert"}`,
+		"org/broken/repo/000001.vlog":            "embeddings",
+		"org/.bundle-123/config.json":            `{"model_type": "bert"}`,
+	})
+	entries, err := List(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "the partial extractions of the bundles aren't models")
+
+	problems, err := Verify(dir, "org/model")
+	require.NoError(t, err)
+	assert.Contains(t, problems, Problem{Path: filepath.Join(dir, "org", "model", "pytorch_model.bin"), Description: "missing file"})
+	assert.Contains(t, problems, Problem{Path: filepath.Join(dir, "org", "model", "vocab.txt"), Description: "empty file"})
+	assert.Contains(t, problems, Problem{Path: filepath.Join(dir, "org", "model", "pytorch_model.bin.incomplete"), Description: "orphaned artifact"})
+
+	removed, err := GC(dir, false)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		filepath.Join(dir, "org", "broken", "repo"),
+		filepath.Join(dir, "org", "model", "pytorch_model.bin.incomplete"),
+		filepath.Join(dir, "org", ".bundle-123"),
+	}, removed)
+	assertNotExist(t, filepath.Join(dir, "org", "broken", "repo"))
+	assert.NoFileExists(t, filepath.Join(dir, "org", "model", "pytorch_model.bin.incomplete"))
+	assert.FileExists(t, filepath.Join(dir, "org", "model", "config.json"))
+}
+
+func assertNotExist(t *testing.T, p string) {
+	t.Helper()
+	_, err := os.Stat(p)
+	assert.True(t, os.IsNotExist(err), p+" exists")
+}
@@ -0,0 +1,181 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package calibration calibrates the probabilities of the classifiers, so
+// that they match the observed accuracy, with the temperature scaling and
+// the Platt scaling, whose parameters are read from a sidecar file of the
+// model, and fits the temperature on a labeled validation set.
+package calibration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Filename is the name of the sidecar file of the calibration, in the
+// model directory.
+const Filename = "calibration.json"
+
+// minProbability bounds the probabilities away from zero, and one, where
+// their logarithms and logits are undefined.
+const minProbability = 1e-12
+
+// Calibration are the parameters of the calibration. The zero value leaves
+// the probabilities unchanged.
+type Calibration struct {
+	// Temperature divides the logits of the probabilities: greater than 1
+	// it softens them, less than 1 it sharpens them (0 means 1).
+	Temperature float64 `json:"temperature,omitempty"`
+	// Platt maps each probability, after the temperature scaling, if any.
+	Platt *Platt `json:"platt,omitempty"`
+}
+
+// Platt are the parameters of the Platt scaling, mapping a probability p to
+// 1 / (1 + exp(A*f + B)), where f is its logit, log(p / (1-p)). A = -1 and
+// B = 0 leave the probabilities unchanged.
+type Platt struct {
+	A float64 `json:"a"`
+	B float64 `json:"b"`
+}
+
+// IsZero reports whether the calibration leaves the probabilities unchanged.
+func (c Calibration) IsZero() bool {
+	return (c.Temperature == 0 || c.Temperature == 1) && c.Platt == nil
+}
+
+// Validate returns an error if the parameters are invalid.
+func (c Calibration) Validate() error {
+	if c.Temperature < 0 || math.IsNaN(c.Temperature) || math.IsInf(c.Temperature, 0) {
+		return fmt.Errorf("calibration: invalid temperature %v", c.Temperature)
+	}
+	if p := c.Platt; p != nil && (math.IsNaN(p.A) || math.IsNaN(p.B) || math.IsInf(p.A, 0) || math.IsInf(p.B, 0)) {
+		return fmt.Errorf("calibration: invalid Platt parameters %v, %v", p.A, p.B)
+	}
+	return nil
+}
+
+// Load reads the calibration from the JSON file, e.g.
+// {"temperature": 1.4} or {"platt": {"a": -1.2, "b": 0.1}}.
+func Load(filename string) (Calibration, error) {
+	var c Calibration
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("calibration: failed to parse %s: %w", filename, err)
+	}
+	return c, c.Validate()
+}
+
+// Save writes the calibration to the JSON file.
+func (c Calibration) Save(filename string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+// Distribution returns the calibrated probabilities of the classes of a
+// distribution, e.g. the softmax of the logits of a multi-class classifier.
+// The temperature scaling keeps them summing to one, unlike the Platt scaling.
+func (c Calibration) Distribution(probs []float64) []float64 {
+	out := make([]float64, len(probs))
+	copy(out, probs)
+	if t := c.Temperature; t != 0 && t != 1 {
+		scaleTemperature(out, t)
+	}
+	if c.Platt != nil {
+		for i, p := range out {
+			out[i] = c.Platt.apply(p)
+		}
+	}
+	return out
+}
+
+// Score returns the calibrated probability of a binary outcome, e.g. of a
+// label of a multi-label classifier, as the distribution {p, 1-p}.
+func (c Calibration) Score(p float64) float64 {
+	return c.Distribution([]float64{p, 1 - p})[0]
+}
+
+// scaleTemperature sets the probabilities to the softmax of their logits
+// divided by the temperature, in place.
+func scaleTemperature(probs []float64, t float64) {
+	logits := make([]float64, len(probs))
+	maxLogit := math.Inf(-1)
+	for i, p := range probs {
+		logits[i] = math.Log(math.Max(p, minProbability)) / t
+		maxLogit = math.Max(maxLogit, logits[i])
+	}
+	sum := 0.0
+	for i, l := range logits {
+		probs[i] = math.Exp(l - maxLogit)
+		sum += probs[i]
+	}
+	for i := range probs {
+		probs[i] /= sum
+	}
+}
+
+func (p *Platt) apply(prob float64) float64 {
+	prob = math.Min(math.Max(prob, minProbability), 1-minProbability)
+	f := math.Log(prob / (1 - prob))
+	return 1 / (1 + math.Exp(p.A*f+p.B))
+}
+
+// The range of the temperatures searched by FitTemperature.
+const (
+	minTemperature = 0.05
+	maxTemperature = 20
+)
+
+// FitTemperature returns the temperature minimizing the negative
+// log-likelihood of the labels, given the probabilities of the classes
+// predicted for each example of a validation set, and the index of the
+// correct class of each one.
+func FitTemperature(probs [][]float64, labels []int) (float64, error) {
+	if len(probs) == 0 {
+		return 0, errors.New("calibration: no examples")
+	}
+	if len(probs) != len(labels) {
+		return 0, fmt.Errorf("calibration: %d examples but %d labels", len(probs), len(labels))
+	}
+	for i, l := range labels {
+		if l < 0 || l >= len(probs[i]) {
+			return 0, fmt.Errorf("calibration: label %d of example %d out of range", l, i)
+		}
+	}
+	nll := func(logT float64) float64 {
+		c := Calibration{Temperature: math.Exp(logT)}
+		sum := 0.0
+		for i, p := range probs {
+			sum -= math.Log(math.Max(c.Distribution(p)[labels[i]], minProbability))
+		}
+		return sum
+	}
+	// The negative log-likelihood is unimodal in the temperature: the golden
+	// section search on its logarithm narrows the range around the minimum.
+	const tolerance = 1e-6
+	invPhi := (math.Sqrt(5) - 1) / 2
+	a, b := math.Log(minTemperature), math.Log(maxTemperature)
+	c, d := b-invPhi*(b-a), a+invPhi*(b-a)
+	fc, fd := nll(c), nll(d)
+	for b-a > tolerance {
+		if fc < fd {
+			b, d, fd = d, c, fc
+			c = b - invPhi*(b-a)
+			fc = nll(c)
+		} else {
+			a, c, fc = c, d, fd
+			d = a + invPhi*(b-a)
+			fd = nll(d)
+		}
+	}
+	return math.Exp((a + b) / 2), nil
+}
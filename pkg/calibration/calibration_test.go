@@ -0,0 +1,94 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package calibration
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDistribution(t *testing.T) {
+	probs := []float64{0.7, 0.2, 0.1}
+	assert.Equal(t, probs, Calibration{}.Distribution(probs))
+	assert.True(t, Calibration{Temperature: 1}.IsZero())
+
+	soft := Calibration{Temperature: 2}.Distribution(probs)
+	assert.InDelta(t, 1, soft[0]+soft[1]+soft[2], 1e-9)
+	assert.Less(t, soft[0], probs[0])
+	assert.Greater(t, soft[2], probs[2])
+	// The ratios of the probabilities are the ones of their square roots.
+	assert.InDelta(t, math.Sqrt(0.7/0.2), soft[0]/soft[1], 1e-9)
+
+	sharp := Calibration{Temperature: 0.5}.Distribution(probs)
+	assert.Greater(t, sharp[0], probs[0])
+
+	identity := Calibration{Platt: &Platt{A: -1, B: 0}}
+	assert.InDelta(t, 0.7, identity.Score(0.7), 1e-9)
+	shifted := Calibration{Platt: &Platt{A: -1, B: 1}}
+	assert.Less(t, shifted.Score(0.7), 0.7)
+}
+
+func TestLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), Filename)
+	c := Calibration{Temperature: 1.5, Platt: &Platt{A: -1.2, B: 0.1}}
+	require.NoError(t, c.Save(filename))
+	loaded, err := Load(filename)
+	require.NoError(t, err)
+	assert.Equal(t, c, loaded)
+
+	require.NoError(t, Calibration{Temperature: -1}.Save(filename))
+	_, err = Load(filename)
+	assert.Error(t, err)
+}
+
+func TestFitTemperature(t *testing.T) {
+	// The labels are drawn from the distributions at temperature 2, while
+	// the model is overconfident, predicting the ones at temperature 1.
+	const trueTemperature = 2
+	rng := rand.New(rand.NewSource(42))
+	var probs [][]float64
+	var labels []int
+	for i := 0; i < 5000; i++ {
+		logits := []float64{rng.NormFloat64() * 3, rng.NormFloat64() * 3, rng.NormFloat64() * 3}
+		probs = append(probs, softmax(logits, 1))
+		labels = append(labels, sample(rng, softmax(logits, trueTemperature)))
+	}
+	temperature, err := FitTemperature(probs, labels)
+	require.NoError(t, err)
+	assert.InDelta(t, trueTemperature, temperature, 0.2)
+
+	_, err = FitTemperature(nil, nil)
+	assert.Error(t, err)
+	_, err = FitTemperature([][]float64{{0.5, 0.5}}, []int{2})
+	assert.Error(t, err)
+}
+
+func softmax(logits []float64, t float64) []float64 {
+	out := make([]float64, len(logits))
+	sum := 0.0
+	for i, l := range logits {
+		out[i] = math.Exp(l / t)
+		sum += out[i]
+	}
+	for i := range out {
+		out[i] /= sum
+	}
+	return out
+}
+
+func sample(rng *rand.Rand, probs []float64) int {
+	r := rng.Float64()
+	for i, p := range probs {
+		if r -= p; r < 0 {
+			return i
+		}
+	}
+	return len(probs) - 1
+}
@@ -0,0 +1,74 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	coreferencev1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/coreference/v1"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/coreference"
+)
+
+var _ coreference.Interface = &clientForCoreference{}
+
+// clientForCoreference is a client for coreference resolution implementing coreference.Interface
+type clientForCoreference struct {
+	// target is the server endpoint.
+	target string
+	// opts is the gRPC options for the client.
+	opts Options
+}
+
+// NewClientForCoreference creates a new client for coreference resolution.
+func NewClientForCoreference(target string, opts Options) coreference.Interface {
+	return &clientForCoreference{
+		target: target,
+		opts:   opts,
+	}
+}
+
+// Resolve returns the clusters of the mentions of the same entities in the text.
+func (c *clientForCoreference) Resolve(ctx context.Context, text string) (coreference.Response, error) {
+	conn, err := Dial(ctx, c.target, c.opts)
+	if err != nil {
+		return coreference.Response{}, fmt.Errorf("failed to dial %q: %w", c.target, err)
+	}
+	cc := coreferencev1.NewCoreferenceServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	response, err := cc.Resolve(ctx, &coreferencev1.ResolveRequest{
+		Input: text,
+	})
+	if err != nil {
+		return coreference.Response{}, err
+	}
+	if response.GetClusters() == nil {
+		return coreference.Response{}, nil
+	}
+
+	clusters := make([]coreference.Cluster, len(response.Clusters))
+	for i, cluster := range response.Clusters {
+		mentions := make([]coreference.Mention, len(cluster.Mentions))
+		for j, m := range cluster.Mentions {
+			mentions[j] = coreference.Mention{
+				Text:  m.Text,
+				Start: int(m.Start),
+				End:   int(m.End),
+				Score: m.Score,
+
+				ByteStart: int(m.ByteStart),
+				ByteEnd:   int(m.ByteEnd),
+			}
+		}
+		clusters[i] = coreference.Cluster{Mentions: mentions}
+	}
+	return coreference.Response{
+		Clusters: clusters,
+	}, nil
+}
@@ -62,6 +62,9 @@ func (c *clientForLanguageModeling) Predict(ctx context.Context, text string, pa
 			End:    int(token.End),
 			Words:  token.Words,
 			Scores: token.Scores,
+
+			ByteStart: int(token.ByteStart),
+			ByteEnd:   int(token.ByteEnd),
 		}
 	}
 	return languagemodeling.Response{
@@ -0,0 +1,71 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	postaggingv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/postagging/v1"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/postagging"
+)
+
+var _ postagging.Interface = &clientForPOSTagging{}
+
+// clientForPOSTagging is a client for part-of-speech tagging implementing postagging.Interface
+type clientForPOSTagging struct {
+	// target is the server endpoint.
+	target string
+	// opts is the gRPC options for the client.
+	opts Options
+}
+
+// NewClientForPOSTagging creates a new client for part-of-speech tagging.
+func NewClientForPOSTagging(target string, opts Options) postagging.Interface {
+	return &clientForPOSTagging{
+		target: target,
+		opts:   opts,
+	}
+}
+
+// Tag returns the words of the text with their part-of-speech tags.
+func (c *clientForPOSTagging) Tag(ctx context.Context, text string) (postagging.Response, error) {
+	conn, err := Dial(ctx, c.target, c.opts)
+	if err != nil {
+		return postagging.Response{}, fmt.Errorf("failed to dial %q: %w", c.target, err)
+	}
+	cc := postaggingv1.NewPosTaggingServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	response, err := cc.Tag(ctx, &postaggingv1.TagRequest{
+		Input: text,
+	})
+	if err != nil {
+		return postagging.Response{}, err
+	}
+	if response.GetTokens() == nil {
+		return postagging.Response{}, nil
+	}
+
+	tokens := make([]postagging.Token, len(response.Tokens))
+	for i, t := range response.Tokens {
+		tokens[i] = postagging.Token{
+			Text:  t.Text,
+			Start: int(t.Start),
+			End:   int(t.End),
+			Tag:   t.Tag,
+			Score: t.Score,
+
+			ByteStart: int(t.ByteStart),
+			ByteEnd:   int(t.ByteEnd),
+		}
+	}
+	return postagging.Response{
+		Tokens: tokens,
+	}, nil
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	questionansweringnv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/questionanswering/v1"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/attribution"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering"
 	"github.com/nlpodyssey/cybertron/pkg/utils/ptr"
 )
@@ -55,6 +56,7 @@ func (c *clientForQuestionAnswering) Answer(ctx context.Context, question, passa
 			MaxAnswersLen: ptr.Of[int64](int64(opts.MaxAnswerLength)),
 			MaxCandidates: ptr.Of[int64](int64(opts.MaxCandidates)),
 			MinScore:      ptr.Of[float64](opts.MinScore),
+			Explain:       ptr.Of[bool](opts.Explain),
 		},
 	})
 	if err != nil {
@@ -68,6 +70,21 @@ func (c *clientForQuestionAnswering) Answer(ctx context.Context, question, passa
 			Start: int(answer.Start),
 			End:   int(answer.End),
 			Score: answer.Score,
+
+			ByteStart: int(answer.ByteStart),
+			ByteEnd:   int(answer.ByteEnd),
+
+			Attributions: make([]attribution.Token, len(answer.Attributions)),
+		}
+		for j, a := range answer.Attributions {
+			answers[i].Attributions[j] = attribution.Token{
+				Text:      a.Text,
+				Start:     int(a.Start),
+				End:       int(a.End),
+				ByteStart: int(a.ByteStart),
+				ByteEnd:   int(a.ByteEnd),
+				Score:     a.Score,
+			}
 		}
 	}
 	return questionanswering.Response{Answers: answers}, nil
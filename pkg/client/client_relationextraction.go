@@ -0,0 +1,87 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	relationextractionv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/relationextraction/v1"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/relationextraction"
+)
+
+var _ relationextraction.Interface = &clientForRelationExtraction{}
+
+// clientForRelationExtraction is a client for relation extraction implementing relationextraction.Interface
+type clientForRelationExtraction struct {
+	// target is the server endpoint.
+	target string
+	// opts is the gRPC options for the client.
+	opts Options
+}
+
+// NewClientForRelationExtraction creates a new client for relation extraction.
+func NewClientForRelationExtraction(target string, opts Options) relationextraction.Interface {
+	return &clientForRelationExtraction{
+		target: target,
+		opts:   opts,
+	}
+}
+
+// Extract returns the typed relations between the entities of the text.
+func (c *clientForRelationExtraction) Extract(ctx context.Context, text string, parameters relationextraction.Parameters) (relationextraction.Response, error) {
+	conn, err := Dial(ctx, c.target, c.opts)
+	if err != nil {
+		return relationextraction.Response{}, fmt.Errorf("failed to dial %q: %w", c.target, err)
+	}
+	cc := relationextractionv1.NewRelationExtractionServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	entities := make([]*relationextractionv1.Entity, len(parameters.Entities))
+	for i, e := range parameters.Entities {
+		entities[i] = &relationextractionv1.Entity{
+			Text:  e.Text,
+			Label: e.Label,
+			Start: int32(e.Start),
+			End:   int32(e.End),
+		}
+	}
+	response, err := cc.Extract(ctx, &relationextractionv1.ExtractRequest{
+		Input:    text,
+		Entities: entities,
+	})
+	if err != nil {
+		return relationextraction.Response{}, err
+	}
+	if response.GetRelations() == nil {
+		return relationextraction.Response{}, nil
+	}
+
+	entity := func(e *relationextractionv1.Entity) relationextraction.Entity {
+		return relationextraction.Entity{
+			Text:      e.GetText(),
+			Label:     e.GetLabel(),
+			Start:     int(e.GetStart()),
+			End:       int(e.GetEnd()),
+			ByteStart: int(e.GetByteStart()),
+			ByteEnd:   int(e.GetByteEnd()),
+		}
+	}
+	relations := make([]relationextraction.Relation, len(response.Relations))
+	for i, r := range response.Relations {
+		relations[i] = relationextraction.Relation{
+			Head:  entity(r.GetHead()),
+			Tail:  entity(r.GetTail()),
+			Type:  r.GetType(),
+			Score: r.GetScore(),
+		}
+	}
+	return relationextraction.Response{
+		Relations: relations,
+	}, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sentencesegmentationv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/sentencesegmentation/v1"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation"
+)
+
+var _ sentencesegmentation.Interface = &clientForSentenceSegmentation{}
+
+// clientForSentenceSegmentation is a client for sentence segmentation implementing sentencesegmentation.Interface
+type clientForSentenceSegmentation struct {
+	// target is the server endpoint.
+	target string
+	// opts is the gRPC options for the client.
+	opts Options
+}
+
+// NewClientForSentenceSegmentation creates a new client for sentence segmentation.
+func NewClientForSentenceSegmentation(target string, opts Options) sentencesegmentation.Interface {
+	return &clientForSentenceSegmentation{
+		target: target,
+		opts:   opts,
+	}
+}
+
+// Segment returns the sentences of the text.
+func (c *clientForSentenceSegmentation) Segment(ctx context.Context, text string) (sentencesegmentation.Response, error) {
+	conn, err := Dial(ctx, c.target, c.opts)
+	if err != nil {
+		return sentencesegmentation.Response{}, fmt.Errorf("failed to dial %q: %w", c.target, err)
+	}
+	cc := sentencesegmentationv1.NewSentenceSegmentationServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	response, err := cc.Segment(ctx, &sentencesegmentationv1.SegmentRequest{
+		Input: text,
+	})
+	if err != nil {
+		return sentencesegmentation.Response{}, err
+	}
+	if response.GetSentences() == nil {
+		return sentencesegmentation.Response{}, nil
+	}
+
+	sentences := make([]sentencesegmentation.Sentence, len(response.Sentences))
+	for i, s := range response.Sentences {
+		sentences[i] = sentencesegmentation.Sentence{
+			Text:  s.Text,
+			Start: int(s.Start),
+			End:   int(s.End),
+
+			ByteStart: int(s.ByteStart),
+			ByteEnd:   int(s.ByteEnd),
+		}
+	}
+	return sentencesegmentation.Response{
+		Sentences: sentences,
+	}, nil
+}
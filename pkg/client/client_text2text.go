@@ -9,9 +9,10 @@ import (
 	"fmt"
 	"time"
 
-	text2textv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/text2text/v1"
+	text2textv2 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/text2text/v2"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
 	"github.com/nlpodyssey/cybertron/pkg/utils/nullable"
+	"google.golang.org/grpc/status"
 )
 
 var _ text2text.Interface = &clientForTextGeneration{}
@@ -46,25 +47,45 @@ func (c *clientForTextGeneration) Generate(ctx context.Context, text string, opt
 	if err != nil {
 		return text2text.Response{}, fmt.Errorf("failed to dial %q: %w", c.target, err)
 	}
-	cc := text2textv1.NewText2TextServiceClient(conn)
+	cc := text2textv2.NewText2TextServiceClient(conn)
 
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	response, err := cc.Generate(ctx, &text2textv1.GenerateRequest{
-		Input: text,
-		Parameters: &text2textv1.Text2TextParameters{
+	response, err := cc.Generate(ctx, &text2textv2.GenerateRequest{
+		Inputs: []string{text},
+		Sampling: &text2textv2.SamplingParameters{
+			Enabled:     opts.Sample.ValuePtr(),
 			Temperature: opts.Temperature.ValuePtr(),
-			DoSample:    opts.Sample.ValuePtr(),
 			TopK:        topK64.ValuePtr(),
 			TopP:        opts.TopP.ValuePtr(),
 		},
+		PartialOutput: true,
 	})
 	if err != nil {
-		return text2text.Response{}, err
+		return partialResponse(err), err
 	}
-	return text2text.Response{
-		Texts:  response.Texts,
-		Scores: response.Scores,
-	}, nil
+	return firstGeneration(response), nil
+}
+
+// firstGeneration returns the generation of the single input of the
+// request, if any.
+func firstGeneration(resp *text2textv2.GenerateResponse) text2text.Response {
+	if len(resp.GetGenerations()) == 0 {
+		return text2text.Response{}
+	}
+	g := resp.GetGenerations()[0]
+	return text2text.Response{Texts: g.GetTexts(), Scores: g.GetScores()}
+}
+
+// partialResponse returns the texts generated so far by the request timed
+// out, if any, set in the details of its error.
+func partialResponse(err error) text2text.Response {
+	s, _ := status.FromError(err)
+	for _, d := range s.Details() {
+		if resp, ok := d.(*text2textv2.GenerateResponse); ok {
+			return firstGeneration(resp)
+		}
+	}
+	return text2text.Response{}
 }
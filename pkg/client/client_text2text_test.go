@@ -0,0 +1,51 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"errors"
+	"testing"
+
+	text2textv2 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/text2text/v2"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+)
+
+func TestPartialResponse(t *testing.T) {
+	withDetails := func(details ...protoiface.MessageV1) error {
+		s, err := status.New(codes.DeadlineExceeded, "deadline expired while decoding").WithDetails(details...)
+		require.NoError(t, err)
+		return s.Err()
+	}
+	partial := &text2textv2.GenerateResponse{Generations: []*text2textv2.Generation{
+		{Texts: []string{"partial"}, Scores: []float64{0.5}},
+	}}
+
+	tests := []struct {
+		name string
+		err  error
+		want text2text.Response
+	}{
+		{"not a status", errors.New("connection refused"), text2text.Response{}},
+		{"no details", status.Error(codes.DeadlineExceeded, "deadline exceeded"), text2text.Response{}},
+		{"other details", withDetails(&errdetails.ErrorInfo{Reason: "DECODING_TIMEOUT"}), text2text.Response{}},
+		{"no generations", withDetails(&text2textv2.GenerateResponse{}), text2text.Response{}},
+		{
+			name: "partial output",
+			err:  withDetails(&errdetails.ErrorInfo{Reason: "DECODING_TIMEOUT"}, partial),
+			want: text2text.Response{Texts: []string{"partial"}, Scores: []float64{0.5}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, partialResponse(tt.err))
+		})
+	}
+}
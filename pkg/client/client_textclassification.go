@@ -10,6 +10,7 @@ import (
 	"time"
 
 	textclassificationv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textclassification/v1"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/attribution"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
 )
 
@@ -32,7 +33,7 @@ func NewClientForTextClassification(target string, opts Options) textclassificat
 }
 
 // Classify classifies the given text.
-func (c *clientForTextClassification) Classify(ctx context.Context, text string) (textclassification.Response, error) {
+func (c *clientForTextClassification) Classify(ctx context.Context, text string, parameters textclassification.Parameters) (textclassification.Response, error) {
 	conn, err := Dial(ctx, c.target, c.opts)
 	if err != nil {
 		return textclassification.Response{}, fmt.Errorf("failed to dial %q: %w", c.target, err)
@@ -43,13 +44,28 @@ func (c *clientForTextClassification) Classify(ctx context.Context, text string)
 	defer cancel()
 
 	response, err := cc.Classify(ctx, &textclassificationv1.ClassifyRequest{
-		Input: text,
+		Input:    text,
+		Layers:   int32(parameters.Layers),
+		Explain:  parameters.Explain,
+		TextPair: parameters.TextPair,
 	})
 	if err != nil {
 		return textclassification.Response{}, err
 	}
+	attributions := make([]attribution.Token, len(response.Attributions))
+	for i, a := range response.Attributions {
+		attributions[i] = attribution.Token{
+			Text:      a.Text,
+			Start:     int(a.Start),
+			End:       int(a.End),
+			ByteStart: int(a.ByteStart),
+			ByteEnd:   int(a.ByteEnd),
+			Score:     a.Score,
+		}
+	}
 	return textclassification.Response{
-		Labels: response.Labels,
-		Scores: response.Scores,
+		Labels:       response.Labels,
+		Scores:       response.Scores,
+		Attributions: attributions,
 	}, nil
 }
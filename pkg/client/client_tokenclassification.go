@@ -61,6 +61,10 @@ func (c *clientForTokenClassification) Classify(ctx context.Context, text string
 			End:   int(token.End),
 			Label: token.Label,
 			Score: token.Score,
+
+			ByteStart: int(token.ByteStart),
+			ByteEnd:   int(token.ByteEnd),
+			Depth:     int(token.Depth),
 		}
 	}
 	return tokenclassification.Response{
@@ -74,6 +78,8 @@ func grpcAggregationStrategy(value tokenclassification.AggregationStrategy) toke
 		return tokenclassificationv1.ClassifyRequest_NONE
 	case tokenclassification.AggregationStrategySimple:
 		return tokenclassificationv1.ClassifyRequest_SIMPLE
+	case tokenclassification.AggregationStrategyNested:
+		return tokenclassificationv1.ClassifyRequest_NESTED
 	default:
 		panic(fmt.Sprintf("client: invalid aggreagation strategy %v", value))
 	}
@@ -0,0 +1,53 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+
+	"github.com/nlpodyssey/cybertron/pkg/routing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// conversationKey is the context key of the ID of a conversation.
+type conversationKey struct{}
+
+// WithConversation returns a copy of the context with the ID of the
+// conversation its requests are part of: they're sent to the same replica
+// of Options.Replicas, and carry the ID in the "cybertron-conversation"
+// metadata, e.g. for a proxy routing them by consistent hashing too.
+func WithConversation(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, conversationKey{}, id)
+}
+
+// conversation returns the ID of the conversation of the context, if any.
+func conversation(ctx context.Context) string {
+	id, _ := ctx.Value(conversationKey{}).(string)
+	return id
+}
+
+// conversationTarget returns the replica of the conversation of the
+// context, if any, or the target.
+func conversationTarget(ctx context.Context, target string, replicas []string) (string, error) {
+	id := conversation(ctx)
+	if id == "" || len(replicas) == 0 {
+		return target, nil
+	}
+	r, err := routing.NewRing(replicas, 0)
+	if err != nil {
+		return "", err
+	}
+	return r.Pick(id), nil
+}
+
+// conversationInterceptor forwards the ID of the conversation of the
+// request, if any, in the metadata.
+func conversationInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if id := conversation(ctx); id != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "cybertron-conversation", id)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
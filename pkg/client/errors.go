@@ -0,0 +1,55 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// statusError is the error of a call, with its gRPC status, matching the
+// error of the errdefs taxonomy set in the details of the status.
+type statusError struct {
+	s   *status.Status
+	err *errdefs.Error
+}
+
+func (e *statusError) Error() string {
+	return e.s.Err().Error()
+}
+
+// GRPCStatus returns the gRPC status of the error, for status.FromError.
+func (e *statusError) GRPCStatus() *status.Status {
+	return e.s
+}
+
+// Unwrap returns the error of the errdefs taxonomy.
+func (e *statusError) Unwrap() error {
+	return e.err
+}
+
+// fromStatus returns the error of a call, matching the errors of the
+// errdefs taxonomy with errors.Is, if the server set its code.
+func fromStatus(err error) error {
+	s, ok := status.FromError(err)
+	if !ok || err == nil {
+		return err
+	}
+	for _, d := range s.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.GetDomain() == errdefs.Domain {
+			return &statusError{s: s, err: errdefs.New(errdefs.Code(info.GetReason()), s.Message())}
+		}
+	}
+	return err
+}
+
+// errorInterceptor converts the errors of the calls with fromStatus.
+func errorInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return fromStatus(invoker(ctx, method, req, reply, cc, opts...))
+}
@@ -9,10 +9,12 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/nlpodyssey/cybertron/pkg/tasks/scheduling"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/balancer/roundrobin"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 )
 
 // loadBalancingConfig is the configuration for the round-robin load balancer.
@@ -23,16 +25,27 @@ type Options struct {
 	UseTLS        bool
 	CertFile      string
 	UseRoundRobin bool
+	// Replicas are the targets of the replicas of the server: the requests
+	// of a conversation, set with WithConversation, are sent to the same
+	// one, picked by consistent hashing, e.g. the one holding its state
+	// (optional: the requests are sent to the target of the client if
+	// empty, or if they're not part of a conversation).
+	Replicas []string
 }
 
 // Dial creates a client connection to the configured target, also respecting
-// the given configuration.
+// the given configuration. The errors of the calls match the errors of the
+// errdefs package with errors.Is.
 //
 // This function blocks until the underlying connection is up, within a
 // timeout of 30 seconds.
 func Dial(ctx context.Context, target string, opts Options) (_ *grpc.ClientConn, err error) {
+	if target, err = conversationTarget(ctx, target, opts.Replicas); err != nil {
+		return nil, err
+	}
 	grpcOpts := []grpc.DialOption{
 		grpc.WithBlock(),
+		grpc.WithChainUnaryInterceptor(priorityInterceptor, conversationInterceptor, errorInterceptor),
 	}
 
 	creds := insecure.NewCredentials()
@@ -57,3 +70,12 @@ func Dial(ctx context.Context, target string, opts Options) (_ *grpc.ClientConn,
 	}
 	return conn, nil
 }
+
+// priorityInterceptor forwards the priority of the request set with
+// scheduling.NewContext, if any, in the metadata read by the server.
+func priorityInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if p := scheduling.FromContext(ctx); p != scheduling.Interactive {
+		ctx = metadata.AppendToOutgoingContext(ctx, "cybertron-priority", p.String())
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
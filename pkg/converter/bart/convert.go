@@ -38,7 +38,7 @@ type mappingParam struct {
 // Convert converts a Bart PyTorch model to a Spago (Cybertron) model.
 func Convert[T float.DType](modelDir string, overwriteIfExist bool) error {
 	configFilename := filepath.Join(modelDir, defaultConfigFilename)
-	pyModelFilename := filepath.Join(modelDir, defaultPyModelFilename)
+	pyModelFilename := pytorch.ResolveModelFile(modelDir, defaultPyModelFilename)
 	goModelFilename := filepath.Join(modelDir, defaultGoModelFilename)
 
 	if info, err := os.Stat(goModelFilename); !overwriteIfExist && err == nil && !info.IsDir() {
@@ -73,6 +73,7 @@ func Convert[T float.DType](modelDir string, overwriteIfExist bool) error {
 	if err = pyParams.Load(pyModelFilename); err != nil {
 		return err
 	}
+	defer pyParams.Close()
 
 	repo, err := diskstore.NewRepository(filepath.Join(modelDir, "repo"), diskstore.ReadWriteMode)
 	if err != nil {
@@ -157,14 +158,10 @@ func Convert[T float.DType](modelDir string, overwriteIfExist bool) error {
 				log.Trace().Str("parameter", key).Msg("parameter not initialized")
 			}
 		}
-		err = pyParams.Iterate(func(name string, _ []T) error {
+		for _, name := range pyParams.Names() {
 			if _, ok := mapping[name]; !ok {
 				log.Trace().Str("parameter", name).Msg("parameter not mapped")
 			}
-			return nil
-		})
-		if err != nil {
-			return err
 		}
 	}
 
@@ -0,0 +1,115 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bart
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/converter/convertertest"
+	"github.com/nlpodyssey/cybertron/pkg/models/bart"
+	"github.com/nlpodyssey/spago/nn"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testHiddenSize   = 8
+	testFFNDim       = 16
+	testNumLayers    = 2
+	testMaxPositions = 6
+	testVocabSize    = 10
+	// testPositionalOffset is the offset of the positions of the BART models.
+	testPositionalOffset = 2
+)
+
+func TestConvert_Golden(t *testing.T) {
+	t.Run("BartForConditionalGeneration", func(t *testing.T) {
+		testConvertGolden[*bart.ModelForConditionalGeneration](t, "BartForConditionalGeneration", convertertest.Checkpoint{
+			"final_logits_bias": {1, testVocabSize},
+		})
+	})
+	t.Run("BartForSequenceClassification", func(t *testing.T) {
+		testConvertGolden[*bart.ModelForSequenceClassification](t, "BartForSequenceClassification", convertertest.Checkpoint{
+			"classification_head.dense.weight":    {testHiddenSize, testHiddenSize},
+			"classification_head.dense.bias":      {testHiddenSize},
+			"classification_head.out_proj.weight": {3, testHiddenSize},
+			"classification_head.out_proj.bias":   {3},
+		})
+	})
+}
+
+// testConvertGolden converts a synthetic checkpoint of the architecture,
+// with the weights of the base model and the ones of its head, comparing
+// the converted model with the golden file.
+func testConvertGolden[T nn.Model](t *testing.T, architecture string, head convertertest.Checkpoint) {
+	dir := t.TempDir()
+	config := map[string]any{
+		"architectures":           []string{architecture},
+		"model_type":              "bart",
+		"activation_function":     "gelu",
+		"d_model":                 testHiddenSize,
+		"encoder_attention_heads": 2,
+		"decoder_attention_heads": 2,
+		"encoder_ffn_dim":         testFFNDim,
+		"decoder_ffn_dim":         testFFNDim,
+		"encoder_layers":          testNumLayers,
+		"decoder_layers":          testNumLayers,
+		"max_position_embeddings": testMaxPositions,
+		"normalize_embedding":     true,
+		"vocab_size":              testVocabSize,
+		"id2label":                map[string]string{"0": "A", "1": "B", "2": "C"},
+		"_num_labels":             3,
+	}
+	require.NoError(t, convertertest.WriteJSON(dir, defaultConfigFilename, config))
+
+	checkpoint := baseCheckpoint()
+	for name, shape := range head {
+		checkpoint[name] = shape
+	}
+	require.NoError(t, convertertest.WriteSafetensors(dir, checkpoint))
+
+	require.NoError(t, Convert[float32](dir, true))
+	digest, err := convertertest.Digest[T](dir)
+	require.NoError(t, err)
+	convertertest.CheckGolden(t, filepath.Join("testdata", "golden", architecture+".txt"), digest)
+}
+
+// baseCheckpoint returns the weights of the base model, named as in the
+// checkpoints of the transformers library.
+func baseCheckpoint() convertertest.Checkpoint {
+	h, f := testHiddenSize, testFFNDim
+	c := convertertest.Checkpoint{
+		"model.shared.weight": {testVocabSize, h},
+	}
+	for _, stack := range []string{"encoder", "decoder"} {
+		prefix := "model." + stack
+		c[prefix+".embed_positions.weight"] = []int{testMaxPositions + testPositionalOffset, h}
+		c[prefix+".layernorm_embedding.weight"] = []int{h}
+		c[prefix+".layernorm_embedding.bias"] = []int{h}
+		attentions := []string{"self_attn"}
+		if stack == "decoder" {
+			attentions = append(attentions, "encoder_attn")
+		}
+		for l := 0; l < testNumLayers; l++ {
+			layer := fmt.Sprintf("%s.layers.%d", prefix, l)
+			for _, attn := range attentions {
+				for _, name := range []string{"q_proj", "k_proj", "v_proj", "out_proj"} {
+					c[layer+"."+attn+"."+name+".weight"] = []int{h, h}
+					c[layer+"."+attn+"."+name+".bias"] = []int{h}
+				}
+				c[layer+"."+attn+"_layer_norm.weight"] = []int{h}
+				c[layer+"."+attn+"_layer_norm.bias"] = []int{h}
+			}
+			c[layer+".fc1.weight"] = []int{f, h}
+			c[layer+".fc1.bias"] = []int{f}
+			c[layer+".fc2.weight"] = []int{h, f}
+			c[layer+".fc2.bias"] = []int{h}
+			c[layer+".final_layer_norm.weight"] = []int{h}
+			c[layer+".final_layer_norm.bias"] = []int{h}
+		}
+	}
+	return c
+}
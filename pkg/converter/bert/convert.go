@@ -12,11 +12,13 @@ import (
 
 	"github.com/nlpodyssey/cybertron/pkg/converter/pytorch"
 	"github.com/nlpodyssey/cybertron/pkg/models/bert"
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
 	"github.com/nlpodyssey/cybertron/pkg/vocabulary"
 	"github.com/nlpodyssey/spago/embeddings/store/diskstore"
 	"github.com/nlpodyssey/spago/mat"
 	"github.com/nlpodyssey/spago/mat/float"
 	"github.com/nlpodyssey/spago/nn"
+	"github.com/nlpodyssey/spago/nn/crf"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -39,10 +41,11 @@ type mappingParam struct {
 }
 
 // Convert converts a Bert PyTorch model to a Spago (Cybertron) model.
-func Convert[T float.DType](modelDir string, overwriteIfExist bool) error {
+// The weights of the converted model are quantized according to the given scheme.
+func Convert[T float.DType](modelDir string, overwriteIfExist bool, scheme quantization.Scheme) error {
 	var (
 		configFilename  = filepath.Join(modelDir, defaultConfigFilename)
-		pyModelFilename = filepath.Join(modelDir, defaultPyModelFilename)
+		pyModelFilename = pytorch.ResolveModelFile(modelDir, defaultPyModelFilename)
 		goModelFilename = filepath.Join(modelDir, defaultGoModelFilename)
 		vocabFilename   = filepath.Join(modelDir, defaultVocabularyFile)
 	)
@@ -96,10 +99,11 @@ func Convert[T float.DType](modelDir string, overwriteIfExist bool) error {
 	if err = pyParams.Load(pyModelFilename); err != nil {
 		return err
 	}
+	defer pyParams.Close()
 
 	params := make(paramsMap)
 	baseModel := mapBaseModel[T](config, repo, pyParams, params, vocab)
-	finalModel := mapSpecificArchitecture[T](baseModel, config.Architectures, params)
+	finalModel := mapSpecificArchitecture[T](baseModel, config.Architectures, pyParams, params)
 
 	mapping := make(map[string]*mappingParam)
 	for k, v := range params {
@@ -129,17 +133,21 @@ func Convert[T float.DType](modelDir string, overwriteIfExist bool) error {
 				log.Debug().Str("parameter", key).Msg("parameter not initialized")
 			}
 		}
-		err = pyParams.Iterate(func(name string, _ []T) error {
+		for _, name := range pyParams.Names() {
 			if _, ok := mapping[name]; !ok {
 				log.Debug().Str("parameter", name).Msg("parameter not mapped")
 			}
-			return nil
-		})
-		if err != nil {
-			return err
 		}
 	}
 
+	quantized, err := quantization.Quantize(finalModel, scheme)
+	if err != nil {
+		return err
+	}
+	if quantized > 0 {
+		log.Info().Int("layers", quantized).Stringer("scheme", scheme).Msg("quantized linear layers")
+	}
+
 	fmt.Printf("Serializing model to \"%s\"... ", goModelFilename)
 	err = nn.DumpToFile(finalModel, goModelFilename)
 	if err != nil {
@@ -194,7 +202,7 @@ func mapBaseModel[T float.DType](config bert.Config, repo *diskstore.Repository,
 	return baseModel
 }
 
-func mapSpecificArchitecture[T float.DType](baseModel *bert.Model, architectures []string, params paramsMap) nn.Model {
+func mapSpecificArchitecture[T float.DType](baseModel *bert.Model, architectures []string, pyParams *pytorch.ParamsProvider[T], params paramsMap) nn.Model {
 	if architectures == nil {
 		architectures = append(architectures, "BertBase")
 	}
@@ -216,15 +224,56 @@ func mapSpecificArchitecture[T float.DType](baseModel *bert.Model, architectures
 		m := bert.NewModelForSequenceClassification[T](baseModel)
 		mapSeqClassifier(m.Classifier, params)
 		return m
-	case "BertForTokenClassification":
+	case "BertForTokenClassification", "BertCRFForTokenClassification", "BertCrfForTokenClassification":
 		m := bert.NewModelForTokenClassification[T](baseModel)
 		mapTokenClassifier(m.Classifier, params)
+		m.CRF = mapCRF[T](pyParams, len(baseModel.Config.ID2Label))
+		return m
+	case "BertForCoreferenceResolution":
+		m := bert.NewModelForCoreferenceResolution[T](baseModel)
+		mapCoreference(m, params)
 		return m
 	default:
 		panic(fmt.Errorf("bert: unsupported architecture %s", architectures[0]))
 	}
 }
 
+// crfParamsNames are the names of the transition scores of the CRF layers,
+// from a label to the next one, from the start to the first label, and from
+// the last label to the end, in the checkpoints of pytorch-crf and TorchCRF.
+var crfParamsNames = [][3]string{
+	{"crf.transitions", "crf.start_transitions", "crf.end_transitions"},
+	{"crf.trans_matrix", "crf.start_trans", "crf.end_trans"},
+}
+
+// mapCRF returns the CRF layer of the labels with the transition scores of
+// the checkpoint, or nil if it has none.
+func mapCRF[T float.DType](pyParams *pytorch.ParamsProvider[T], labels int) *crf.Model {
+	for _, names := range crfParamsNames {
+		transitions := pyParams.Pop(names[0])
+		if transitions == nil {
+			continue
+		}
+		start, end := pyParams.Pop(names[1]), pyParams.Pop(names[2])
+		if len(transitions) != labels*labels || len(start) != labels || len(end) != labels {
+			panic(fmt.Errorf("bert: CRF transitions of %d labels mismatch", labels))
+		}
+		// The first row and column are the transitions from the start and
+		// to the end, the next ones the transitions between the labels.
+		m := crf.New[T](labels)
+		scores := m.TransitionScores.Value()
+		for i := 0; i < labels; i++ {
+			scores.SetScalar(0, i+1, float.Interface(start[i]))
+			scores.SetScalar(i+1, 0, float.Interface(end[i]))
+			for j := 0; j < labels; j++ {
+				scores.SetScalar(i+1, j+1, float.Interface(transitions[i*labels+j]))
+			}
+		}
+		return m
+	}
+	return nil
+}
+
 func fixParamsName(from string) (to string) {
 	to = from
 	to = strings.Replace(to, "electra.", "bert.", -1)
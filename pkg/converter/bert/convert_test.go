@@ -0,0 +1,210 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bert
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/converter/convertertest"
+	"github.com/nlpodyssey/cybertron/pkg/models/bert"
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
+	"github.com/nlpodyssey/spago/nn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testHiddenSize       = 8
+	testIntermediateSize = 16
+	testNumLayers        = 2
+	testMaxPositions     = 6
+)
+
+var testVocabulary = []string{"[PAD]", "[UNK]", "[CLS]", "[SEP]", "[MASK]", "the", "cat", "sat", "##s", "."}
+
+func TestConvert_Golden(t *testing.T) {
+	t.Run("BertModel", func(t *testing.T) {
+		testConvertGolden[*bert.ModelForSequenceEncoding](t, "BertModel", nil)
+	})
+	t.Run("BertForSequenceClassification", func(t *testing.T) {
+		testConvertGolden[*bert.ModelForSequenceClassification](t, "BertForSequenceClassification", convertertest.Checkpoint{
+			"classifier.weight": {3, testHiddenSize},
+			"classifier.bias":   {3},
+		})
+	})
+	t.Run("BertForTokenClassification", func(t *testing.T) {
+		testConvertGolden[*bert.ModelForTokenClassification](t, "BertForTokenClassification", convertertest.Checkpoint{
+			"classifier.weight": {3, testHiddenSize},
+			"classifier.bias":   {3},
+		})
+	})
+	t.Run("BertCRFForTokenClassification", func(t *testing.T) {
+		testConvertGolden[*bert.ModelForTokenClassification](t, "BertCRFForTokenClassification", convertertest.Checkpoint{
+			"classifier.weight":     {3, testHiddenSize},
+			"classifier.bias":       {3},
+			"crf.transitions":       {3, 3},
+			"crf.start_transitions": {3},
+			"crf.end_transitions":   {3},
+		})
+	})
+	t.Run("BertForQuestionAnswering", func(t *testing.T) {
+		testConvertGolden[*bert.ModelForQuestionAnswering](t, "BertForQuestionAnswering", convertertest.Checkpoint{
+			"qa_outputs.weight": {2, testHiddenSize},
+			"qa_outputs.bias":   {2},
+		})
+	})
+	t.Run("BertForMaskedLM", func(t *testing.T) {
+		testConvertGolden[*bert.ModelForMaskedLM](t, "BertForMaskedLM", convertertest.Checkpoint{
+			"cls.predictions.transform.dense.weight":     {testHiddenSize, testHiddenSize},
+			"cls.predictions.transform.dense.bias":       {testHiddenSize},
+			"cls.predictions.transform.LayerNorm.weight": {testHiddenSize},
+			"cls.predictions.transform.LayerNorm.bias":   {testHiddenSize},
+			"cls.predictions.decoder.weight":             {len(testVocabulary), testHiddenSize},
+			"cls.predictions.decoder.bias":               {len(testVocabulary)},
+		})
+	})
+	t.Run("BertForCoreferenceResolution", func(t *testing.T) {
+		head := convertertest.Checkpoint{}
+		for _, name := range []string{"start_mention_mlp", "end_mention_mlp", "start_coref_mlp", "end_coref_mlp"} {
+			head[name+".dense.weight"] = []int{testHiddenSize, testHiddenSize}
+			head[name+".dense.bias"] = []int{testHiddenSize}
+			head[name+".layer_norm.weight"] = []int{testHiddenSize}
+			head[name+".layer_norm.bias"] = []int{testHiddenSize}
+		}
+		head["mention_start_classifier.weight"] = []int{1, testHiddenSize}
+		head["mention_start_classifier.bias"] = []int{1}
+		head["mention_end_classifier.weight"] = []int{1, testHiddenSize}
+		head["mention_end_classifier.bias"] = []int{1}
+		for _, name := range []string{"mention_s2e", "antecedent_s2s", "antecedent_e2e", "antecedent_s2e", "antecedent_e2s"} {
+			head[name+"_classifier.weight"] = []int{testHiddenSize, testHiddenSize}
+			head[name+"_classifier.bias"] = []int{testHiddenSize}
+		}
+		testConvertGolden[*bert.ModelForCoreferenceResolution](t, "BertForCoreferenceResolution", head)
+	})
+}
+
+// testConvertGolden converts a synthetic checkpoint of the architecture,
+// with the weights of the base model and the ones of its head, comparing
+// the converted model with the golden file.
+func TestConvert_Quantization(t *testing.T) {
+	head := convertertest.Checkpoint{
+		"classifier.weight": {3, testHiddenSize},
+		"classifier.bias":   {3},
+	}
+	sizes := make(map[quantization.Scheme]int64)
+	for _, scheme := range []quantization.Scheme{quantization.None, quantization.Int8} {
+		dir := writeTestCheckpoint(t, "BertForSequenceClassification", head)
+		require.NoError(t, Convert[float32](dir, true, scheme))
+		filename := filepath.Join(dir, "spago_model.bin")
+		info, err := os.Stat(filename)
+		require.NoError(t, err)
+		sizes[scheme] = info.Size()
+
+		m, err := nn.LoadFromFile[*bert.ModelForSequenceClassification](filename)
+		require.NoError(t, err)
+		var quantized, total int
+		nn.Apply(m, func(model nn.Model) {
+			if l, ok := model.(*quantization.Linear); ok {
+				r, c := l.W.Dims()
+				quantized += r * c
+				total += r * c
+			}
+		})
+		nn.ForEachParam(m, func(p nn.Param) {
+			r, c := p.Value().Dims()
+			if _, ok := p.(*quantization.Param); ok {
+				quantized += r * c
+			}
+			total += r * c
+		})
+
+		// All the weights of the linear layers, of the feed-forward blocks,
+		// of the self-attention, of the pooler and of the head, out of the
+		// biases, normalizations and embeddings.
+		h, i := testHiddenSize, testIntermediateSize
+		linear := testNumLayers*(4*h*h+2*h*i) + h*h + 3*h
+		if scheme == quantization.None {
+			assert.Zero(t, quantized)
+			continue
+		}
+		assert.Equal(t, linear, quantized)
+		assert.Greater(t, float64(quantized)/float64(total), 0.8)
+	}
+	// The encoding overhead of such a tiny model dominates its file.
+	assert.Less(t, sizes[quantization.Int8], sizes[quantization.None])
+}
+
+func testConvertGolden[T nn.Model](t *testing.T, architecture string, head convertertest.Checkpoint) {
+	dir := writeTestCheckpoint(t, architecture, head)
+	require.NoError(t, Convert[float32](dir, true, quantization.None))
+	digest, err := convertertest.Digest[T](dir)
+	require.NoError(t, err)
+	convertertest.CheckGolden(t, filepath.Join("testdata", "golden", architecture+".txt"), digest)
+}
+
+// writeTestCheckpoint writes the configuration, the vocabulary and the
+// synthetic checkpoint of a tiny model of the architecture, with the
+// weights of the head, and returns its directory.
+func writeTestCheckpoint(t *testing.T, architecture string, head convertertest.Checkpoint) string {
+	dir := t.TempDir()
+	config := map[string]any{
+		"architectures":           []string{architecture},
+		"model_type":              "bert",
+		"hidden_act":              "gelu",
+		"hidden_size":             testHiddenSize,
+		"intermediate_size":       testIntermediateSize,
+		"layer_norm_eps":          1e-12,
+		"max_position_embeddings": testMaxPositions,
+		"num_attention_heads":     2,
+		"num_hidden_layers":       testNumLayers,
+		"type_vocab_size":         2,
+		"vocab_size":              len(testVocabulary),
+		"id2label":                map[string]string{"0": "A", "1": "B", "2": "C"},
+	}
+	require.NoError(t, convertertest.WriteJSON(dir, defaultConfigFilename, config))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, defaultVocabularyFile), []byte(strings.Join(testVocabulary, "\n")+"\n"), 0o644))
+
+	checkpoint := baseCheckpoint()
+	for name, shape := range head {
+		checkpoint[name] = shape
+	}
+	require.NoError(t, convertertest.WriteSafetensors(dir, checkpoint))
+	return dir
+}
+
+// baseCheckpoint returns the weights of the base model, named as in the
+// checkpoints of the transformers library.
+func baseCheckpoint() convertertest.Checkpoint {
+	h, i := testHiddenSize, testIntermediateSize
+	c := convertertest.Checkpoint{
+		"bert.embeddings.word_embeddings.weight":       {len(testVocabulary), h},
+		"bert.embeddings.position_embeddings.weight":   {testMaxPositions, h},
+		"bert.embeddings.token_type_embeddings.weight": {2, h},
+		"bert.embeddings.LayerNorm.weight":             {h},
+		"bert.embeddings.LayerNorm.bias":               {h},
+		"bert.pooler.dense.weight":                     {h, h},
+		"bert.pooler.dense.bias":                       {h},
+	}
+	for l := 0; l < testNumLayers; l++ {
+		prefix := fmt.Sprintf("bert.encoder.layer.%d", l)
+		for _, name := range []string{"attention.self.query", "attention.self.key", "attention.self.value", "attention.output.dense"} {
+			c[prefix+"."+name+".weight"] = []int{h, h}
+			c[prefix+"."+name+".bias"] = []int{h}
+		}
+		c[prefix+".attention.output.LayerNorm.weight"] = []int{h}
+		c[prefix+".attention.output.LayerNorm.bias"] = []int{h}
+		c[prefix+".intermediate.dense.weight"] = []int{i, h}
+		c[prefix+".intermediate.dense.bias"] = []int{i}
+		c[prefix+".output.dense.weight"] = []int{h, i}
+		c[prefix+".output.dense.bias"] = []int{h}
+		c[prefix+".output.LayerNorm.weight"] = []int{h}
+		c[prefix+".output.LayerNorm.bias"] = []int{h}
+	}
+	return c
+}
@@ -0,0 +1,236 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bert
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/converter/pytorch"
+	"github.com/nlpodyssey/cybertron/pkg/gguf"
+	"github.com/nlpodyssey/cybertron/pkg/models/bert"
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
+	"github.com/nlpodyssey/cybertron/pkg/tokenizers/wordpiecetokenizer"
+	"github.com/nlpodyssey/cybertron/pkg/vocabulary"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultGGUFModelFilename is the default GGUF model filename.
+const defaultGGUFModelFilename = "model.gguf"
+
+// ggufTensorNames maps the names of the embeddings parameters to the
+// names used by the BERT architecture of llama.cpp.
+var ggufTensorNames = map[string]string{
+	"bert.embeddings.word_embeddings.weight":       "token_embd.weight",
+	"bert.embeddings.position_embeddings.weight":   "position_embd.weight",
+	"bert.embeddings.token_type_embeddings.weight": "token_types.weight",
+	"bert.embeddings.LayerNorm.weight":             "token_embd_norm.weight",
+	"bert.embeddings.LayerNorm.bias":               "token_embd_norm.bias",
+}
+
+// ggufLayerTensorNames maps the names of the encoder layers modules to the
+// names used by the BERT architecture of llama.cpp.
+var ggufLayerTensorNames = map[string]string{
+	"attention.self.query":       "attn_q",
+	"attention.self.key":         "attn_k",
+	"attention.self.value":       "attn_v",
+	"attention.output.dense":     "attn_output",
+	"attention.output.LayerNorm": "attn_output_norm",
+	"intermediate.dense":         "ffn_up",
+	"output.dense":               "ffn_down",
+	"output.LayerNorm":           "layer_output_norm",
+}
+
+var ggufLayerParam = regexp.MustCompile(`^bert\.encoder\.layer\.(\d+)\.(.+)\.(weight|bias)$`)
+
+// ConvertToGGUF converts a Bert PyTorch model to a GGUF file, including the
+// tokenizer and the architecture hyperparameters, so that it can be used by
+// the tools of the llama.cpp ecosystem.
+//
+// Only the base encoder is exported: the task-specific heads (and the
+// pooler) are not part of the llama.cpp BERT architecture.
+// The 2D weights are stored according to the given quantization scheme,
+// while biases and normalization parameters are always stored as float32.
+func ConvertToGGUF(modelDir string, overwriteIfExist bool, scheme quantization.Scheme) error {
+	var (
+		configFilename    = filepath.Join(modelDir, defaultConfigFilename)
+		pyModelFilename   = pytorch.ResolveModelFile(modelDir, defaultPyModelFilename)
+		ggufModelFilename = filepath.Join(modelDir, defaultGGUFModelFilename)
+		vocabFilename     = filepath.Join(modelDir, defaultVocabularyFile)
+	)
+
+	if info, err := os.Stat(ggufModelFilename); !overwriteIfExist && err == nil && !info.IsDir() {
+		log.Info().Str("model", ggufModelFilename).Msg("model file already exists, skipping conversion")
+		return nil
+	}
+
+	tensorType, err := ggufTensorType(scheme)
+	if err != nil {
+		return err
+	}
+
+	config, err := bert.ConfigFromFile[bert.Config](configFilename)
+	if err != nil {
+		return err
+	}
+	if config.EmbeddingsSize != 0 && config.EmbeddingsSize != config.HiddenSize {
+		return fmt.Errorf("GGUF conversion doesn't support embeddings size different from hidden size")
+	}
+
+	vocab, err := vocabulary.NewFromFile(vocabFilename)
+	if err != nil {
+		return err
+	}
+
+	pyParams := pytorch.NewParamsProvider[float32]().WithNameMapping(fixParamsName)
+	if err = pyParams.Load(pyModelFilename); err != nil {
+		return err
+	}
+	defer pyParams.Close()
+
+	f := &gguf.File{}
+	setGGUFMetadata(f, config, filepath.Base(modelDir), tensorType)
+	setGGUFVocabulary(f, config, vocab)
+
+	for _, name := range pyParams.Names() {
+		ggufName, ok := ggufTensorName(name)
+		if !ok {
+			log.Debug().Str("parameter", name).Msg("parameter not exported to GGUF")
+			continue
+		}
+		shape := pyParams.Shape(name)
+		typ := gguf.TypeF32
+		if len(shape) == 2 && ggufName != "position_embd.weight" && ggufName != "token_types.weight" {
+			typ = tensorType
+			if typ == gguf.TypeQ8_0 && shape[1]%32 != 0 {
+				typ = gguf.TypeF16
+			}
+		}
+		// The values are read only while writing, one tensor at a time.
+		name := name
+		t, err := gguf.NewLazyTensor(ggufName, shape, typ, func() ([]float32, error) {
+			return pyParams.Get(name), pyParams.Err()
+		})
+		if err != nil {
+			return err
+		}
+		f.Tensors = append(f.Tensors, t)
+	}
+	if n := len(ggufTensorNames) + config.NumHiddenLayers*len(ggufLayerTensorNames)*2; len(f.Tensors) != n {
+		return fmt.Errorf("GGUF conversion: expected %d tensors, found %d", n, len(f.Tensors))
+	}
+	sort.Slice(f.Tensors, func(i, j int) bool {
+		return f.Tensors[i].Name < f.Tensors[j].Name
+	})
+
+	fmt.Printf("Serializing model to \"%s\"... ", ggufModelFilename)
+	if err = f.WriteFile(ggufModelFilename); err != nil {
+		return err
+	}
+	fmt.Println("Done.")
+
+	return nil
+}
+
+// ggufTensorType returns the GGUF type of the 2D weights for the given scheme.
+func ggufTensorType(scheme quantization.Scheme) (gguf.TensorType, error) {
+	switch scheme {
+	case quantization.None:
+		return gguf.TypeF32, nil
+	case quantization.Int8:
+		return gguf.TypeQ8_0, nil
+	case quantization.Float16:
+		return gguf.TypeF16, nil
+	case quantization.BFloat16:
+		return gguf.TypeBF16, nil
+	default:
+		return 0, fmt.Errorf("quantization %s is not supported by the GGUF conversion", scheme)
+	}
+}
+
+// ggufTensorName returns the GGUF name of a base model parameter.
+func ggufTensorName(name string) (string, bool) {
+	if n, ok := ggufTensorNames[name]; ok {
+		return n, true
+	}
+	m := ggufLayerParam.FindStringSubmatch(name)
+	if m == nil {
+		return "", false
+	}
+	n, ok := ggufLayerTensorNames[m[2]]
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("blk.%s.%s.%s", m[1], n, m[3]), true
+}
+
+func setGGUFMetadata(f *gguf.File, config bert.Config, name string, tensorType gguf.TensorType) {
+	f.Set("general.architecture", "bert")
+	f.Set("general.name", name)
+	f.Set("general.file_type", gguf.FileType(tensorType))
+	f.Set("bert.context_length", uint32(config.MaxPositionEmbeddings))
+	f.Set("bert.embedding_length", uint32(config.HiddenSize))
+	f.Set("bert.feed_forward_length", uint32(config.IntermediateSize))
+	f.Set("bert.block_count", uint32(config.NumHiddenLayers))
+	f.Set("bert.attention.head_count", uint32(config.NumAttentionHeads))
+	f.Set("bert.attention.layer_norm_epsilon", float32(config.LayerNormEps))
+	f.Set("bert.attention.causal", false)
+}
+
+// GGUF token types.
+const (
+	ggufTokenNormal  int32 = 1
+	ggufTokenUnknown int32 = 2
+	ggufTokenControl int32 = 3
+	ggufTokenUnused  int32 = 5
+)
+
+// setGGUFVocabulary sets the tokenizer metadata.
+//
+// Following llama.cpp, the WordPiece vocabulary is converted to the
+// "phantom space" representation: word-initial pieces are prefixed with
+// U+2581, and continuation pieces lose their "##" prefix.
+func setGGUFVocabulary(f *gguf.File, config bert.Config, vocab *vocabulary.Vocabulary) {
+	tokens := make([]string, config.VocabSize)
+	types := make([]int32, config.VocabSize)
+	for i := range tokens {
+		term, ok := vocab.Term(i)
+		switch {
+		case !ok:
+			tokens[i], types[i] = fmt.Sprintf("[PAD%d]", i), ggufTokenUnused
+		case term == wordpiecetokenizer.DefaultUnknownToken:
+			tokens[i], types[i] = term, ggufTokenUnknown
+		case strings.HasPrefix(term, "[") && strings.HasSuffix(term, "]"):
+			tokens[i], types[i] = term, ggufTokenControl
+		case strings.HasPrefix(term, "##"):
+			tokens[i], types[i] = term[2:], ggufTokenNormal
+		default:
+			tokens[i], types[i] = "▁"+term, ggufTokenNormal
+		}
+	}
+	f.Set("tokenizer.ggml.model", "bert")
+	f.Set("tokenizer.ggml.tokens", tokens)
+	f.Set("tokenizer.ggml.token_type", types)
+	f.Set("tokenizer.ggml.token_type_count", uint32(config.TypeVocabSize))
+
+	specials := []struct {
+		key, token string
+	}{
+		{"tokenizer.ggml.unknown_token_id", wordpiecetokenizer.DefaultUnknownToken},
+		{"tokenizer.ggml.seperator_token_id", wordpiecetokenizer.DefaultSequenceSeparator},
+		{"tokenizer.ggml.cls_token_id", wordpiecetokenizer.DefaultClassToken},
+		{"tokenizer.ggml.mask_token_id", wordpiecetokenizer.DefaultMaskToken},
+		{"tokenizer.ggml.padding_token_id", "[PAD]"},
+	}
+	for _, s := range specials {
+		if id, ok := vocab.ID(s.token); ok {
+			f.Set(s.key, uint32(id))
+		}
+	}
+}
@@ -82,3 +82,33 @@ func mapMaskedLM(layers []nn.StandardModel, params paramsMap) {
 	params["cls.predictions.decoder.weight"] = layers[3].(*linear.Model).W.Value()
 	params["cls.predictions.decoder.bias"] = layers[3].(*linear.Model).B.Value()
 }
+
+// mapCoreference maps the parameters of the heads of the span-based
+// coreference resolution models, named as in the s2e-coref checkpoints.
+func mapCoreference(m *bert.ModelForCoreferenceResolution, params paramsMap) {
+	mlps := map[string][]nn.StandardModel{
+		"start_mention_mlp": m.StartMentionMLP,
+		"end_mention_mlp":   m.EndMentionMLP,
+		"start_coref_mlp":   m.StartCorefMLP,
+		"end_coref_mlp":     m.EndCorefMLP,
+	}
+	for name, layers := range mlps {
+		params[name+".dense.weight"] = layers[0].(*linear.Model).W.Value()
+		params[name+".dense.bias"] = layers[0].(*linear.Model).B.Value()
+		params[name+".layer_norm.weight"] = layers[2].(*layernorm.Model).W.Value()
+		params[name+".layer_norm.bias"] = layers[2].(*layernorm.Model).B.Value()
+	}
+	classifiers := map[string]*linear.Model{
+		"mention_start_classifier":  m.MentionStartClassifier,
+		"mention_end_classifier":    m.MentionEndClassifier,
+		"mention_s2e_classifier":    m.MentionS2EClassifier,
+		"antecedent_s2s_classifier": m.AntecedentS2SClassifier,
+		"antecedent_e2e_classifier": m.AntecedentE2EClassifier,
+		"antecedent_s2e_classifier": m.AntecedentS2EClassifier,
+		"antecedent_e2s_classifier": m.AntecedentE2SClassifier,
+	}
+	for name, model := range classifiers {
+		params[name+".weight"] = model.W.Value()
+		params[name+".bias"] = model.B.Value()
+	}
+}
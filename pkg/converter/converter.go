@@ -13,25 +13,41 @@ import (
 	"github.com/nlpodyssey/cybertron/pkg/converter/distilbert"
 	"github.com/nlpodyssey/cybertron/pkg/converter/flair"
 	"github.com/nlpodyssey/cybertron/pkg/models"
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
 	"github.com/nlpodyssey/spago/mat/float"
 )
 
+// quantizableModelTypes is the set of model types whose converters support
+// weights quantization.
+var quantizableModelTypes = map[string]bool{
+	"bert":       true,
+	"electra":    true,
+	"distilbert": true,
+}
+
 // Convert automatically converts a supported pre-trained model, already
 // downloaded from huggingface.co repositories, to a format usable by Spago machine learning framework.
 //
 // It accepts the path to the model's directory and creates the converted
 // files in the same place.
-func Convert[T float.DType](modelPath string, overwriteIfExists bool) error {
+//
+// The weights of the converted model are quantized according to the given
+// scheme. Quantization is currently supported by encoder-only models.
+func Convert[T float.DType](modelPath string, overwriteIfExists bool, scheme quantization.Scheme) error {
 	modelType, err := resolveModelType(modelPath)
 	if err != nil {
 		return err
 	}
 
+	if scheme != quantization.None && !quantizableModelTypes[modelType] {
+		return fmt.Errorf("quantization %s is not supported for model type: %#v", scheme, modelType)
+	}
+
 	switch modelType {
 	case "bert", "electra":
-		return bert.Convert[T](modelPath, overwriteIfExists)
+		return bert.Convert[T](modelPath, overwriteIfExists, scheme)
 	case "distilbert":
-		return distilbert.Convert[T](modelPath, overwriteIfExists)
+		return distilbert.Convert[T](modelPath, overwriteIfExists, scheme)
 	case "bart", "marian", "pegasus":
 		return bart.Convert[T](modelPath, overwriteIfExists)
 	case "flair":
@@ -41,6 +57,26 @@ func Convert[T float.DType](modelPath string, overwriteIfExists bool) error {
 	}
 }
 
+// ConvertToGGUF converts a supported pre-trained model, already downloaded
+// from huggingface.co repositories, to a GGUF file ("model.gguf") that can
+// be consumed by llama.cpp-ecosystem tools.
+//
+// The weights are stored according to the given quantization scheme.
+// GGUF conversion is currently supported by BERT models only.
+func ConvertToGGUF(modelPath string, overwriteIfExists bool, scheme quantization.Scheme) error {
+	modelType, err := resolveModelType(modelPath)
+	if err != nil {
+		return err
+	}
+
+	switch modelType {
+	case "bert":
+		return bert.ConvertToGGUF(modelPath, overwriteIfExists, scheme)
+	default:
+		return fmt.Errorf("GGUF conversion is not supported for model type: %#v", modelType)
+	}
+}
+
 func resolveModelType(modelPath string) (string, error) {
 	if strings.Contains(modelPath, "flair") {
 		// Handling the case where there is no configuration file
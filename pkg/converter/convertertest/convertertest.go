@@ -0,0 +1,202 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package convertertest tests the converters without downloading real
+// models: it writes small synthetic checkpoints, whose weights are all
+// distinct, and compares a digest of the converted models with golden
+// files, so that any change of the mapping of the weights is caught.
+//
+// The golden files are written again, e.g. after an intended change of a
+// converter, with the env var TEST_GOLDEN_UPDATE set (see "make golden").
+package convertertest
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/converter/safetensors"
+	"github.com/nlpodyssey/spago/embeddings/store/diskstore"
+	"github.com/nlpodyssey/spago/nn"
+)
+
+// Checkpoint maps the names of the weights of a synthetic checkpoint to
+// their shapes.
+type Checkpoint map[string][]int
+
+// Values returns the values of the weight of a synthetic checkpoint, a
+// pseudo-random sequence seeded by its name, so that no two weights are
+// alike, and the digest of a converted model reveals where each one ended.
+func Values(name string, size int) []float32 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	r := rand.New(rand.NewSource(int64(h.Sum64())))
+	values := make([]float32, size)
+	for i := range values {
+		values[i] = r.Float32()*2 - 1
+	}
+	return values
+}
+
+// WriteSafetensors writes the checkpoint, with the values of its weights,
+// to the safetensors file in the model directory, which the converters
+// read instead of the PyTorch file.
+func WriteSafetensors(modelDir string, c Checkpoint) error {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	header := map[string]any{"__metadata__": map[string]string{"format": "pt"}}
+	var data []byte
+	for _, name := range names {
+		size := 1
+		for _, d := range c[name] {
+			size *= d
+		}
+		start := len(data)
+		for _, v := range Values(name, size) {
+			data = binary.LittleEndian.AppendUint32(data, math.Float32bits(v))
+		}
+		header[name] = safetensors.TensorInfo{DType: "F32", Shape: c[name], DataOffsets: [2]int64{int64(start), int64(len(data))}}
+	}
+	h, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	content := binary.LittleEndian.AppendUint64(nil, uint64(len(h)))
+	content = append(append(content, h...), data...)
+	return os.WriteFile(filepath.Join(modelDir, safetensors.DefaultFilename), content, 0o644)
+}
+
+// WriteJSON writes the value to the JSON file in the model directory, e.g.
+// the configuration of the model.
+func WriteJSON(modelDir, filename string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(modelDir, filename), data, 0o644)
+}
+
+// Digest returns a digest of the model converted in the model directory,
+// one line for each parameter, in the order of the model structure, with
+// its shape and the hash of its values, and one for each embeddings store
+// of the repository, with the number of its embeddings and the hash of
+// their keys and values.
+func Digest[T nn.Model](modelDir string) (string, error) {
+	m, err := nn.LoadFromFile[T](filepath.Join(modelDir, "spago_model.bin"))
+	if err != nil {
+		return "", err
+	}
+	repoDir := filepath.Join(modelDir, "repo")
+	entries, err := os.ReadDir(repoDir)
+	if err != nil {
+		return "", err
+	}
+	repo, err := diskstore.NewRepository(repoDir, diskstore.ReadOnlyMode)
+	if err != nil {
+		return "", err
+	}
+	defer repo.Close()
+
+	// The embeddings shared by the models, e.g. in BART, are only connected
+	// with the repository.
+	type embeddingsSetter interface {
+		SetEmbeddings(repo *diskstore.Repository) error
+	}
+	var setters []embeddingsSetter
+	nn.Apply(m, func(model nn.Model) {
+		if s, ok := model.(embeddingsSetter); ok {
+			setters = append(setters, s)
+		}
+	})
+	for _, s := range setters {
+		if err := s.SetEmbeddings(repo); err != nil {
+			return "", err
+		}
+	}
+
+	var sb strings.Builder
+	i := 0
+	nn.ForEachParam(m, func(param nn.Param) {
+		v := param.Value()
+		h := sha256.New()
+		for _, x := range v.Data().F64() {
+			binary.Write(h, binary.LittleEndian, math.Float64bits(x))
+		}
+		fmt.Fprintf(&sb, "param %d %dx%d %s\n", i, v.Rows(), v.Columns(), shortHash(h.Sum(nil)))
+		i++
+	})
+
+	for _, e := range entries {
+		// The directories of the stores are named after their encoded names.
+		encoded, ok := strings.CutPrefix(e.Name(), "store_")
+		if !e.IsDir() || !ok {
+			continue
+		}
+		name, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", err
+		}
+		s, err := repo.Store(string(name))
+		if err != nil {
+			return "", err
+		}
+		keys, err := s.Keys()
+		if err != nil {
+			return "", err
+		}
+		sort.Slice(keys, func(i, j int) bool { return string(keys[i]) < string(keys[j]) })
+		h := sha256.New()
+		for _, key := range keys {
+			var value []byte
+			if _, err := s.Get(key, &value); err != nil {
+				return "", err
+			}
+			h.Write(key)
+			h.Write(value)
+		}
+		fmt.Fprintf(&sb, "store %s %d %s\n", name, len(keys), shortHash(h.Sum(nil)))
+	}
+	return sb.String(), nil
+}
+
+func shortHash(sum []byte) string {
+	return hex.EncodeToString(sum[:8])
+}
+
+// CheckGolden compares the digest with the golden file, writing it instead
+// when the env var TEST_GOLDEN_UPDATE is set.
+func CheckGolden(t testing.TB, filename, digest string) {
+	t.Helper()
+	if os.Getenv("TEST_GOLDEN_UPDATE") != "" {
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(digest), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("%v - set env var TEST_GOLDEN_UPDATE to write it", err)
+	}
+	if string(expected) != digest {
+		t.Errorf("the converted model doesn't match %s - set env var TEST_GOLDEN_UPDATE if the change is intended\nexpected:\n%s\nactual:\n%s", filename, expected, digest)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/nlpodyssey/cybertron/pkg/converter/pytorch"
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
 	"github.com/nlpodyssey/cybertron/pkg/vocabulary"
 	"github.com/nlpodyssey/spago/embeddings/store/diskstore"
 	"github.com/nlpodyssey/spago/mat"
@@ -35,10 +36,11 @@ type mappingParam struct {
 }
 
 // Convert converts a DistilBert PyTorch model to a Spago (Cybertron) model.
-func Convert[T float.DType](modelDir string, overwriteIfExist bool) error {
+// The weights of the converted model are quantized according to the given scheme.
+func Convert[T float.DType](modelDir string, overwriteIfExist bool, scheme quantization.Scheme) error {
 	var (
 		configFilename  = filepath.Join(modelDir, defaultConfigFilename)
-		pyModelFilename = filepath.Join(modelDir, defaultPyModelFilename)
+		pyModelFilename = pytorch.ResolveModelFile(modelDir, defaultPyModelFilename)
 		goModelFilename = filepath.Join(modelDir, defaultGoModelFilename)
 		vocabFilename   = filepath.Join(modelDir, defaultVocabularyFile)
 	)
@@ -92,6 +94,7 @@ func Convert[T float.DType](modelDir string, overwriteIfExist bool) error {
 	if err = pyParams.Load(pyModelFilename); err != nil {
 		return err
 	}
+	defer pyParams.Close()
 
 	params := make(paramsMap)
 	baseModel := mapBaseModel[T](config, repo, pyParams, params, vocab)
@@ -125,17 +128,21 @@ func Convert[T float.DType](modelDir string, overwriteIfExist bool) error {
 				log.Debug().Str("parameter", key).Msg("parameter not initialized")
 			}
 		}
-		err = pyParams.Iterate(func(name string, _ []T) error {
+		for _, name := range pyParams.Names() {
 			if _, ok := mapping[name]; !ok {
 				log.Debug().Str("parameter", name).Msg("parameter not mapped")
 			}
-			return nil
-		})
-		if err != nil {
-			return err
 		}
 	}
 
+	quantized, err := quantization.Quantize(finalModel, scheme)
+	if err != nil {
+		return err
+	}
+	if quantized > 0 {
+		log.Info().Int("layers", quantized).Stringer("scheme", scheme).Msg("quantized linear layers")
+	}
+
 	fmt.Printf("Serializing model to \"%s\"... ", goModelFilename)
 	err = nn.DumpToFile(finalModel, goModelFilename)
 	if err != nil {
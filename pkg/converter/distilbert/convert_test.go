@@ -0,0 +1,103 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package distilbert
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/converter/convertertest"
+	"github.com/nlpodyssey/cybertron/pkg/models/distilbert"
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
+	"github.com/nlpodyssey/spago/nn"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testHiddenSize       = 8
+	testIntermediateSize = 16
+	testNumLayers        = 2
+	testMaxPositions     = 6
+)
+
+var testVocabulary = []string{"[PAD]", "[UNK]", "[CLS]", "[SEP]", "[MASK]", "the", "cat", "sat", "##s", "."}
+
+func TestConvert_Golden(t *testing.T) {
+	t.Run("DistilBertModel", func(t *testing.T) {
+		testConvertGolden[*distilbert.ModelForSequenceEncoding](t, "DistilBertModel", nil)
+	})
+	t.Run("DistilBertForMaskedLM", func(t *testing.T) {
+		testConvertGolden[*distilbert.ModelForMaskedLM](t, "DistilBertForMaskedLM", convertertest.Checkpoint{
+			"vocab_transform.weight":  {testHiddenSize, testHiddenSize},
+			"vocab_transform.bias":    {testHiddenSize},
+			"vocab_layer_norm.weight": {testHiddenSize},
+			"vocab_layer_norm.bias":   {testHiddenSize},
+			"vocab_projector.weight":  {len(testVocabulary), testHiddenSize},
+			"vocab_projector.bias":    {len(testVocabulary)},
+		})
+	})
+}
+
+// testConvertGolden converts a synthetic checkpoint of the architecture,
+// with the weights of the base model and the ones of its head, comparing
+// the converted model with the golden file.
+func testConvertGolden[T nn.Model](t *testing.T, architecture string, head convertertest.Checkpoint) {
+	dir := t.TempDir()
+	config := map[string]any{
+		"architectures":           []string{architecture},
+		"model_type":              "distilbert",
+		"activation":              "gelu",
+		"dim":                     testHiddenSize,
+		"hidden_dim":              testIntermediateSize,
+		"max_position_embeddings": testMaxPositions,
+		"n_heads":                 2,
+		"n_layers":                testNumLayers,
+		"vocab_size":              len(testVocabulary),
+	}
+	require.NoError(t, convertertest.WriteJSON(dir, defaultConfigFilename, config))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, defaultVocabularyFile), []byte(strings.Join(testVocabulary, "\n")+"\n"), 0o644))
+
+	checkpoint := baseCheckpoint()
+	for name, shape := range head {
+		checkpoint[name] = shape
+	}
+	require.NoError(t, convertertest.WriteSafetensors(dir, checkpoint))
+
+	require.NoError(t, Convert[float32](dir, true, quantization.None))
+	digest, err := convertertest.Digest[T](dir)
+	require.NoError(t, err)
+	convertertest.CheckGolden(t, filepath.Join("testdata", "golden", architecture+".txt"), digest)
+}
+
+// baseCheckpoint returns the weights of the base model, named as in the
+// checkpoints of the transformers library.
+func baseCheckpoint() convertertest.Checkpoint {
+	h, i := testHiddenSize, testIntermediateSize
+	c := convertertest.Checkpoint{
+		"distilbert.embeddings.word_embeddings.weight":     {len(testVocabulary), h},
+		"distilbert.embeddings.position_embeddings.weight": {testMaxPositions, h},
+		"distilbert.embeddings.LayerNorm.weight":           {h},
+		"distilbert.embeddings.LayerNorm.bias":             {h},
+	}
+	for l := 0; l < testNumLayers; l++ {
+		prefix := fmt.Sprintf("distilbert.transformer.layer.%d", l)
+		for _, name := range []string{"attention.q_lin", "attention.k_lin", "attention.v_lin", "attention.out_lin"} {
+			c[prefix+"."+name+".weight"] = []int{h, h}
+			c[prefix+"."+name+".bias"] = []int{h}
+		}
+		for _, name := range []string{"sa_layer_norm", "output_layer_norm"} {
+			c[prefix+"."+name+".weight"] = []int{h}
+			c[prefix+"."+name+".bias"] = []int{h}
+		}
+		c[prefix+".ffn.lin1.weight"] = []int{i, h}
+		c[prefix+".ffn.lin1.bias"] = []int{i}
+		c[prefix+".ffn.lin2.weight"] = []int{h, i}
+		c[prefix+".ffn.lin2.bias"] = []int{h}
+	}
+	return c
+}
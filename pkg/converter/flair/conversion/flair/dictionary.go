@@ -19,6 +19,8 @@ type Dictionary struct {
 	Idx2Item           []string
 	Item2IdxNotEncoded *collections.DefaultDict
 	MultiLabel         bool
+	AddUnk             bool
+	SpanLabels         bool
 }
 
 func (DictionaryClass) PyNew(args ...any) (any, error) {
@@ -46,6 +48,10 @@ func (d *Dictionary) PyDictSet(k, v any) (err error) {
 		err = conversion.AssignAssertedType(v, &d.Item2IdxNotEncoded)
 	case "multi_label":
 		err = conversion.AssignAssertedType(v, &d.MultiLabel)
+	case "add_unk":
+		err = conversion.AssignAssertedType(v, &d.AddUnk)
+	case "span_labels":
+		err = conversion.AssignAssertedType(v, &d.SpanLabels)
 	default:
 		err = fmt.Errorf("unexpected key with value %#v", v)
 	}
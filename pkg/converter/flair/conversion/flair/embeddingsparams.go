@@ -0,0 +1,93 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flair
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/converter/flair/conversion"
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+// paramsEmbeddings is implemented by the embeddings which can be loaded
+// from the dict of parameters saved by Flair >= 0.12 in place of the
+// pickled object.
+type paramsEmbeddings interface {
+	TokenEmbeddings
+	PyDictSet(k, v any) error
+	// initFromParams builds the torch modules, and the other attributes
+	// not saved among the parameters, once they're all set.
+	initFromParams() error
+}
+
+// loadEmbeddings returns the embeddings described by a dict of parameters,
+// with the name of their class as "__cls__" and, optionally, their weights
+// as "state_dict".
+func loadEmbeddings(params *types.Dict) (TokenEmbeddings, error) {
+	cls, err := dictGet[string](params, "__cls__")
+	if err != nil {
+		return nil, err
+	}
+	cls = cls[strings.LastIndex(cls, ".")+1:]
+
+	var e paramsEmbeddings
+	switch cls {
+	case "StackedEmbeddings":
+		e = &StackedEmbeddings{}
+	case "WordEmbeddings":
+		e = &WordEmbeddings{}
+	case "FlairEmbeddings":
+		e = &FlairEmbeddings{}
+	default:
+		return nil, fmt.Errorf("unsupported embeddings class %q", cls)
+	}
+
+	var stateDict *types.OrderedDict
+	for _, entry := range *params {
+		switch entry.Key {
+		case "__cls__":
+		case "state_dict":
+			err = conversion.AssignAssertedType(entry.Value, &stateDict)
+		default:
+			err = e.PyDictSet(entry.Key, entry.Value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", cls, err)
+		}
+	}
+
+	if err := e.initFromParams(); err != nil {
+		return nil, fmt.Errorf("%s: %w", cls, err)
+	}
+	if stateDict != nil {
+		if err := loadStateDict(stateDict, e.LoadStateDictEntry); err != nil {
+			return nil, fmt.Errorf("%s: %w", cls, err)
+		}
+	}
+	return e, nil
+}
+
+// toTokenEmbeddings returns the embeddings of a stack, either pickled or
+// saved as a dict of parameters.
+func toTokenEmbeddings(v any) (TokenEmbeddings, error) {
+	if params, ok := v.(*types.Dict); ok {
+		return loadEmbeddings(params)
+	}
+	return conversion.AssertType[TokenEmbeddings](v)
+}
+
+func loadStateDict(stateDict *types.OrderedDict, load func(k string, v any) error) error {
+	for _, e := range stateDict.Map {
+		k, ok := e.Key.(string)
+		if !ok {
+			return fmt.Errorf("want state_dict key type string, got %T: %#v", e.Key, e.Key)
+		}
+		if err := load(k, e.Value); err != nil {
+			return fmt.Errorf("failed to load state_dict[%q]: %w", k, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flair
+
+import (
+	"testing"
+
+	"github.com/nlpodyssey/gopickle/pytorch"
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+func TestLoadEmbeddings(t *testing.T) {
+	vocab := types.NewDict()
+	vocab.Set("hello", 0)
+	vocab.Set("world", 1)
+
+	word := types.NewDict()
+	word.Set("__cls__", "WordEmbeddings")
+	word.Set("vocab", vocab)
+	word.Set("embedding_length", 2)
+	word.Set("name", "glove")
+	word.Set("stable", false)
+	word.Set("fine_tune", false)
+	word.Set("field", nil)
+
+	stack := types.NewDict()
+	stack.Set("__cls__", "StackedEmbeddings")
+	stack.Set("embeddings", &types.List{word})
+
+	e, err := loadEmbeddings(stack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	se, ok := e.(*StackedEmbeddings)
+	if !ok {
+		t.Fatalf("want *StackedEmbeddings, got %T", e)
+	}
+	if got := se.EmbeddingLength(); got != 2 {
+		t.Errorf("want embedding length 2, got %d", got)
+	}
+
+	weight := &pytorch.Tensor{
+		Source: &pytorch.FloatStorage{Data: []float32{1, 2, 3, 4, 0, 0}},
+		Size:   []int{3, 2},
+		Stride: []int{2, 1},
+	}
+	if err := se.LoadStateDictEntry("list_embedding_0.embedding.weight", weight); err != nil {
+		t.Fatal(err)
+	}
+	we := se.Embeddings[0].(*WordEmbeddings)
+	if we.Vocab["world"] != 1 {
+		t.Errorf("want index 1 of %q, got %d", "world", we.Vocab["world"])
+	}
+	if got := we.Embedding.Weight[1].Data().F32(); got[0] != 3 || got[1] != 4 {
+		t.Errorf("want vector [3 4] of %q, got %v", "world", got)
+	}
+
+	unsupported := types.NewDict()
+	unsupported.Set("__cls__", "TransformerWordEmbeddings")
+	if _, err := loadEmbeddings(unsupported); err == nil {
+		t.Error("want error for unsupported embeddings class, got nil")
+	}
+}
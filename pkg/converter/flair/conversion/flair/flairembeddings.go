@@ -27,6 +27,7 @@ type FlairEmbeddings struct {
 	InstanceParameters        *types.Dict
 	WithWhitespace            bool // Default: true
 	TokenizedLM               bool // Default: true
+	IsLower                   bool
 	LM                        *LanguageModel
 }
 
@@ -75,6 +76,12 @@ func (f *FlairEmbeddings) PyDictSet(k, v any) (err error) {
 		err = conversion.AssignAssertedType(v, &f.WithWhitespace)
 	case "tokenized_lm":
 		err = conversion.AssignAssertedType(v, &f.TokenizedLM)
+	case "is_lower":
+		err = conversion.AssignAssertedType(v, &f.IsLower)
+	case "model":
+		f.LM, err = toLanguageModel(v)
+	case "has_decoder":
+		// the language model has it too
 	case "detach", "cache": // TODO
 	default:
 		err = fmt.Errorf("unexpected key with value %#v", v)
@@ -86,6 +93,20 @@ func (f *FlairEmbeddings) PyDictSet(k, v any) (err error) {
 	return err
 }
 
+func (f *FlairEmbeddings) initFromParams() error {
+	if f.LM == nil {
+		return fmt.Errorf("missing language model")
+	}
+	f.IsForwardLm = f.LM.IsForwardLm
+	f.embeddingLength = f.LM.HiddenSize
+	if f.LM.NOut > 0 {
+		f.embeddingLength = f.LM.NOut
+	}
+	f.Modules = types.NewOrderedDict()
+	f.Modules.Set("lm", f.LM)
+	return nil
+}
+
 func (f *FlairEmbeddings) LoadStateDictEntry(k string, v any) (err error) {
 	name, rest, _ := strings.Cut(k, ".")
 
@@ -10,6 +10,7 @@ import (
 
 	"github.com/nlpodyssey/cybertron/pkg/converter/flair/conversion"
 	"github.com/nlpodyssey/cybertron/pkg/converter/flair/conversion/torch"
+	"github.com/nlpodyssey/gopickle/types"
 )
 
 type LanguageModelClass struct{}
@@ -24,6 +25,8 @@ type LanguageModel struct {
 	NLayers           int
 	NOut              int
 	DocumentDelimiter string
+	RecurrentType     string
+	HasDecoder        bool
 
 	Encoder *torch.SparseEmbedding
 	Decoder *torch.Linear
@@ -72,6 +75,10 @@ func (l *LanguageModel) PyDictSet(k, v any) (err error) {
 		}
 	case "document_delimiter":
 		err = conversion.AssignAssertedType(v, &l.DocumentDelimiter)
+	case "recurrent_type":
+		err = conversion.AssignAssertedType(v, &l.RecurrentType)
+	case "has_decoder":
+		err = conversion.AssignAssertedType(v, &l.HasDecoder)
 	default:
 		err = fmt.Errorf("unexpected key with value %#v", v)
 	}
@@ -82,6 +89,88 @@ func (l *LanguageModel) PyDictSet(k, v any) (err error) {
 	return err
 }
 
+// toLanguageModel returns the language model of the FlairEmbeddings, either
+// pickled or, since Flair 0.12, saved as a dict of parameters.
+func toLanguageModel(v any) (*LanguageModel, error) {
+	params, ok := v.(*types.Dict)
+	if !ok {
+		return conversion.AssertType[*LanguageModel](v)
+	}
+
+	l := &LanguageModel{
+		RecurrentType: "LSTM",
+		HasDecoder:    true,
+	}
+	var stateDict *types.OrderedDict
+	for _, entry := range *params {
+		var err error
+		switch entry.Key {
+		case "state_dict":
+			err = conversion.AssignAssertedType(entry.Value, &stateDict)
+		default:
+			err = l.PyDictSet(entry.Key, entry.Value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := l.initModules(); err != nil {
+		return nil, err
+	}
+	if stateDict != nil {
+		if err := loadStateDict(stateDict, l.LoadStateDictEntry); err != nil {
+			return nil, fmt.Errorf("LanguageModel: %w", err)
+		}
+	}
+	return l, nil
+}
+
+// initModules builds the torch modules of a language model loaded from its
+// parameters, whose weights are then loaded from the state dict.
+func (l *LanguageModel) initModules() error {
+	switch {
+	case l.Dictionary == nil:
+		return fmt.Errorf("LanguageModel: missing dictionary")
+	case l.RecurrentType != "LSTM":
+		return fmt.Errorf("LanguageModel: invalid or unimplemented recurrent type: %q", l.RecurrentType)
+	}
+
+	rnn, err := torch.NewLSTM(torch.RNNBaseConfig{
+		InputSize:  l.EmbeddingSize,
+		HiddenSize: l.HiddenSize,
+		NumLayers:  l.NLayers,
+		Bias:       true,
+		Dropout:    l.Dropout,
+	})
+	if err != nil {
+		return fmt.Errorf("LanguageModel: %w", err)
+	}
+
+	l.Modules = types.NewOrderedDict()
+	l.Modules.Set("encoder", &torch.SparseEmbedding{
+		NumEmbeddings: l.Dictionary.Size(),
+		EmbeddingDim:  l.EmbeddingSize,
+	})
+	l.Modules.Set("rnn", rnn)
+
+	outputSize := l.HiddenSize
+	if l.NOut > 0 {
+		outputSize = l.NOut
+		l.Modules.Set("proj", &torch.Linear{
+			InFeatures:  l.HiddenSize,
+			OutFeatures: l.NOut,
+		})
+	}
+	if l.HasDecoder {
+		l.Modules.Set("decoder", &torch.Linear{
+			InFeatures:  outputSize,
+			OutFeatures: l.Dictionary.Size(),
+		})
+	}
+	return nil
+}
+
 func (l *LanguageModel) LoadStateDictEntry(k string, v any) (err error) {
 	name, rest, _ := strings.Cut(k, ".")
 
@@ -185,9 +185,9 @@ func NewSequenceTagger(conf SequenceTaggerConfig) (*SequenceTagger, error) {
 			TagsetSize:    st.TagsetSize,
 		}
 		st.ViterbiDecoder = &ViterbiDecoder{TagDictionary: st.LabelDictionary}
-	} else {
-		return nil, fmt.Errorf("SequenceTagger: CrossEntropyLoss not implemented (caused by UseCRF = false)")
 	}
+	// Without the CRF, the tags are predicted by the softmax of the scores:
+	// the CrossEntropyLoss is only needed for the training.
 
 	return st, nil
 }
@@ -245,7 +245,7 @@ func newSequenceTaggerFromStateDict(state *types.Dict) (*SequenceTagger, error)
 		AllowUnkPredictions:     false,
 	}
 
-	conf.Embeddings, err = dictGet[TokenEmbeddings](state, "embeddings")
+	conf.Embeddings, err = sequenceTaggerEmbeddings(state)
 	if err != nil {
 		return nil, err
 	}
@@ -257,6 +257,10 @@ func newSequenceTaggerFromStateDict(state *types.Dict) (*SequenceTagger, error)
 	if err != nil {
 		return nil, err
 	}
+	conf.TagFormat, err = dictGetDefault(state, "tag_format", conf.TagFormat)
+	if err != nil {
+		return nil, err
+	}
 	conf.UseRNN, err = dictGet[bool](state, "use_rnn")
 	if err != nil {
 		return nil, err
@@ -294,6 +298,11 @@ func newSequenceTaggerFromStateDict(state *types.Dict) (*SequenceTagger, error)
 		return nil, err
 	}
 
+	conf.TrainInitialHiddenState, err = dictGetDefault(state, "train_initial_hidden_state", false)
+	if err != nil {
+		return nil, err
+	}
+
 	if v, ok := state.Get("weight_dict"); ok && v != nil {
 		return nil, fmt.Errorf("'weight_dict' (loss weights dict) is not supported")
 	}
@@ -318,6 +327,20 @@ func newSequenceTaggerFromStateDict(state *types.Dict) (*SequenceTagger, error)
 	return st, nil
 }
 
+// sequenceTaggerEmbeddings returns the embeddings of the tagger, either
+// pickled or, since Flair 0.12, saved as a dict of parameters.
+func sequenceTaggerEmbeddings(state *types.Dict) (TokenEmbeddings, error) {
+	v, ok := state.Get("embeddings")
+	if !ok {
+		return nil, fmt.Errorf("missing key %q", "embeddings")
+	}
+	e, err := toTokenEmbeddings(v)
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", "embeddings", err)
+	}
+	return e, nil
+}
+
 func determineIfSpanPredictionProblem(d *Dictionary) bool {
 	for _, s := range d.Idx2Item {
 		if strings.HasPrefix(s, "B-") || strings.HasPrefix(s, "S-") || strings.HasPrefix(s, "I-") {
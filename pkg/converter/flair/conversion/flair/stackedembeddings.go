@@ -49,7 +49,7 @@ func (s *StackedEmbeddings) PyDictSet(k, v any) (err error) {
 		var l *types.List
 		err = conversion.AssignAssertedType(v, &l)
 		if err == nil {
-			err = conversion.AssignListToSlice(l, &s.Embeddings)
+			err = s.setEmbeddings(l)
 		}
 	case "name":
 		err = conversion.AssignAssertedType(v, &s.Name)
@@ -70,6 +70,28 @@ func (s *StackedEmbeddings) PyDictSet(k, v any) (err error) {
 	return err
 }
 
+func (s *StackedEmbeddings) setEmbeddings(l *types.List) error {
+	s.Embeddings = make([]TokenEmbeddings, l.Len())
+	for i, v := range *l {
+		e, err := toTokenEmbeddings(v)
+		if err != nil {
+			return fmt.Errorf("item at index %d: %w", i, err)
+		}
+		s.Embeddings[i] = e
+	}
+	return nil
+}
+
+func (s *StackedEmbeddings) initFromParams() error {
+	s.Modules = types.NewOrderedDict()
+	s.embeddingLength = 0
+	for i, e := range s.Embeddings {
+		s.Modules.Set(fmt.Sprintf("list_embedding_%d", i), e)
+		s.embeddingLength += e.EmbeddingLength()
+	}
+	return nil
+}
+
 func (s *StackedEmbeddings) LoadStateDictEntry(k string, v any) (err error) {
 	name, rest, _ := strings.Cut(k, ".")
 
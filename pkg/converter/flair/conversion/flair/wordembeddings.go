@@ -21,6 +21,7 @@ type WordEmbeddings struct {
 	Embeddings         string
 	Name               string
 	StaticEmbeddings   bool
+	Stable             bool
 	Embedding          *torch.Embedding
 	Vocab              map[string]int
 	InstanceParameters *types.Dict
@@ -64,8 +65,18 @@ func (w *WordEmbeddings) PyDictSet(k, v any) (err error) {
 		if err == nil {
 			err = w.setPrecomputedWordEmbeddings(kv)
 		}
-	case "_WordEmbeddings__embedding_length":
+	case "_WordEmbeddings__embedding_length", "embedding_length":
 		err = conversion.AssignAssertedType(v, &w.embeddingLength)
+	case "vocab":
+		var d *types.Dict
+		err = conversion.AssignAssertedType(v, &d)
+		if err == nil {
+			err = conversion.AssignDictToMap(d, &w.Vocab)
+		}
+	case "stable":
+		err = conversion.AssignAssertedType(v, &w.Stable)
+	case "fine_tune", "force_cpu", "no_header":
+		// training and loading options of recent models, can be ignored
 	case "field":
 		if v != nil {
 			err = fmt.Errorf("only nil is supported, got %T: %#v", v, v)
@@ -110,6 +121,42 @@ func (w *WordEmbeddings) PyGetAttribute(name string) (value any, exists bool, er
 	}
 }
 
-func (w *WordEmbeddings) LoadStateDictEntry(string, any) error {
-	return fmt.Errorf("WordEmbeddings: loading from state dict entry not implemented")
+func (w *WordEmbeddings) initFromParams() error {
+	if w.Vocab == nil {
+		return fmt.Errorf("missing vocab")
+	}
+	return nil
+}
+
+func (w *WordEmbeddings) LoadStateDictEntry(k string, v any) (err error) {
+	switch k {
+	case "embedding.weight":
+		err = w.setEmbeddingWeight(v)
+	default:
+		err = fmt.Errorf("unexpected key with value %#v", v)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("WordEmbeddings: state dict key %q: %w", k, err)
+	}
+	return err
+}
+
+// setEmbeddingWeight sets the vectors of the words of the recent models,
+// which keep them in a torch embedding indexed by the vocab, in place of
+// the precomputed gensim ones.
+func (w *WordEmbeddings) setEmbeddingWeight(v any) error {
+	t, err := torch.AnyToTensor(v, nil)
+	if err != nil {
+		return err
+	}
+	if len(t.Size) != 2 {
+		return fmt.Errorf("want 2 dimensions, got %d", len(t.Size))
+	}
+	vectors, err := conversion.Tensor2DToSliceOfVectors(t)
+	if err != nil {
+		return err
+	}
+	w.Embedding = torch.EmbeddingFromPretrained(vectors, t.Size[1])
+	return nil
 }
@@ -81,6 +81,10 @@ func (m *Module) PyDictSet(k, v any) (err error) {
 		if v != nil {
 			err = fmt.Errorf("only nil is supported, got %T: %#v", v, v)
 		}
+	case "_backward_pre_hooks", "_forward_hooks_with_kwargs", "_forward_hooks_always_called",
+		"_forward_pre_hooks_with_kwargs", "_state_dict_pre_hooks", "_load_state_dict_post_hooks",
+		"_is_full_backward_hook", "_compiled_call_impl":
+		// present on models saved by recent PyTorch releases, not needed for the conversion
 	default:
 		err = fmt.Errorf("%w with value %#v", ErrUnexpectedModuleDictKey, v)
 	}
@@ -161,97 +161,118 @@ func (conv *converter[T]) encoderEmbeddings() (e *flair.Embeddings, err error) {
 	return e, nil
 }
 
+// encoderEmbeddingsTokensEncoder converts the embeddings of the tagger, in
+// the order of the stack: each forward FlairEmbeddings is paired with the
+// backward one following it into the same ContextualStringEmbeddings.
 func (conv *converter[T]) encoderEmbeddingsTokensEncoder() ([]flair.TokensEncoder, error) {
-	se, err := conversion.AssertType[*convflair.StackedEmbeddings](conv.st.Embeddings)
-	if err != nil {
-		return nil, fmt.Errorf("unexpected SequenceTagger.Embeddings: %w", err)
+	items := []convflair.TokenEmbeddings{conv.st.Embeddings}
+	if se, ok := conv.st.Embeddings.(*convflair.StackedEmbeddings); ok {
+		items = se.Embeddings
 	}
 
-	var flairWe []*convflair.WordEmbeddings
-	var flairEmbForward *convflair.FlairEmbeddings
-	var flairEmbBackward *convflair.FlairEmbeddings
+	tEnc := make([]flair.TokensEncoder, 0)
+	var flairEmbForward, flairEmbBackward *convflair.FlairEmbeddings
+	var wordIndex, charLMIndex int
 
-	for _, item := range se.Embeddings {
+	for _, item := range items {
 		switch tt := item.(type) {
 		case *convflair.WordEmbeddings:
-			flairWe = append(flairWe, tt)
+			st, err := conv.repo.Store(fmt.Sprintf("word_%d", wordIndex))
+			if err != nil {
+				return nil, fmt.Errorf("failed to get embeddings store for word embeddings: %w", err)
+			}
+			we, err := conv.encoderEmbeddingsTokensEncoderWordEmbeddings(st, tt)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert WordEmbeddings: %w", err)
+			}
+			tEnc = append(tEnc, we)
+			wordIndex++
 		case *convflair.FlairEmbeddings:
 			if tt.IsForwardLm {
 				if flairEmbForward != nil {
-					return nil, fmt.Errorf("more than one FlairEmbeddings-forward found in StackedEmbeddings")
+					return nil, fmt.Errorf("FlairEmbeddings-forward %q without a backward one", flairEmbForward.Name)
 				}
 				flairEmbForward = tt
 			} else {
 				if flairEmbBackward != nil {
-					return nil, fmt.Errorf("more than one FlairEmbeddings-backward found in StackedEmbeddings")
+					return nil, fmt.Errorf("FlairEmbeddings-backward %q without a forward one", flairEmbBackward.Name)
 				}
 				flairEmbBackward = tt
 			}
+			if flairEmbForward == nil || flairEmbBackward == nil {
+				continue
+			}
+			cse, err := conv.encoderEmbeddingsTokensEncoderContextualStringEmbeddings(charLMIndex, flairEmbForward, flairEmbBackward)
+			if err != nil {
+				return nil, err
+			}
+			tEnc = append(tEnc, cse)
+			flairEmbForward, flairEmbBackward = nil, nil
+			charLMIndex++
 		default:
 			return nil, fmt.Errorf("unexpected TokenEmbeddings type: %T", item)
 		}
 	}
 
 	switch {
-	case flairEmbForward == nil:
-		return nil, fmt.Errorf("FlairEmbeddings-forward not found in StackedEmbeddings")
-	case flairEmbBackward == nil:
-		return nil, fmt.Errorf("FlairEmbeddings-backward not found in StackedEmbeddings")
+	case flairEmbForward != nil:
+		return nil, fmt.Errorf("FlairEmbeddings-forward %q without a backward one", flairEmbForward.Name)
+	case flairEmbBackward != nil:
+		return nil, fmt.Errorf("FlairEmbeddings-backward %q without a forward one", flairEmbBackward.Name)
+	case len(tEnc) == 0:
+		return nil, fmt.Errorf("no embeddings found")
 	}
 
-	tEnc := make([]flair.TokensEncoder, 0)
-
-	{ // --- Word embeddings ---
-		for i, item := range flairWe {
-			st, err := conv.repo.Store(fmt.Sprintf("word_%d", i))
-			if err != nil {
-				return nil, fmt.Errorf("failed to get embeddings store for word embeddings: %w", err)
-			}
+	return tEnc, nil
+}
 
-			we, err := conv.encoderEmbeddingsTokensEncoderWordEmbeddings(st, item)
-			if err != nil {
-				return nil, fmt.Errorf("failed to convert WordEmbeddings: %w", err)
-			}
-			tEnc = append(tEnc, we)
-		}
+// encoderEmbeddingsTokensEncoderContextualStringEmbeddings converts the
+// index-th pair of FlairEmbeddings, whose stores are suffixed by the index
+// past the first one.
+func (conv *converter[T]) encoderEmbeddingsTokensEncoderContextualStringEmbeddings(index int, forward, backward *convflair.FlairEmbeddings) (*flair.ContextualStringEmbeddings, error) {
+	voc, err := conv.encoderEmbeddingsTokensEncoderCharLMVocabulary(forward, backward)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert FlairEmbeddings CharLM vocabulary: %w", err)
 	}
 
-	{ // --- CharLM embeddings ---
-		voc, err := conv.encoderEmbeddingsTokensEncoderCharLMVocabulary(flairEmbForward, flairEmbBackward)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert FlairEmbeddings CharLM vocabulary: %w", err)
-		}
-
-		storeL2R, err := conv.repo.Store("charlm_l2r")
-		if err != nil {
-			return nil, fmt.Errorf("failed to get embeddings store for charlm left-to-right: %w", err)
-		}
-		storeR2L, err := conv.repo.Store("charlm_r2l")
-		if err != nil {
-			return nil, fmt.Errorf("failed to get embeddings store for charlm right-to-left: %w", err)
-		}
-
-		l2r, err := conv.encoderEmbeddingsTokensEncoderCharLM(storeL2R, voc, flairEmbForward)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert FlairEmbeddings-forward: %w", err)
-		}
-		r2l, err := conv.encoderEmbeddingsTokensEncoderCharLM(storeR2L, voc, flairEmbBackward)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert FlairEmbeddings-backward: %w", err)
-		}
-		tEnc = append(tEnc, &flair.ContextualStringEmbeddings{
-			LeftToRight: l2r,
-			RightToLeft: r2l,
-			MergeMode:   flair.Concat,
-			StartMarker: '\n',
-			EndMarker:   ' ',
-		})
+	suffix := ""
+	if index > 0 {
+		suffix = fmt.Sprintf("_%d", index)
+	}
+	storeL2R, err := conv.repo.Store("charlm_l2r" + suffix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get embeddings store for charlm left-to-right: %w", err)
+	}
+	storeR2L, err := conv.repo.Store("charlm_r2l" + suffix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get embeddings store for charlm right-to-left: %w", err)
 	}
 
-	return tEnc, nil
+	l2r, err := conv.encoderEmbeddingsTokensEncoderCharLM(storeL2R, voc, forward)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert FlairEmbeddings-forward: %w", err)
+	}
+	r2l, err := conv.encoderEmbeddingsTokensEncoderCharLM(storeR2L, voc, backward)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert FlairEmbeddings-backward: %w", err)
+	}
+	return &flair.ContextualStringEmbeddings{
+		LeftToRight: l2r,
+		RightToLeft: r2l,
+		MergeMode:   flair.Concat,
+		StartMarker: '\n',
+		EndMarker:   ' ',
+	}, nil
 }
 
 func (conv *converter[T]) encoderEmbeddingsTokensEncoderWordEmbeddings(st store.Store, we *convflair.WordEmbeddings) (*flair.WordEmbeddings, error) {
+	switch {
+	case we.Embedding == nil:
+		return nil, fmt.Errorf("missing word vectors of %q", we.Name)
+	case we.Stable:
+		return nil, fmt.Errorf("stable WordEmbeddings (with layer normalization) not supported")
+	}
+
 	conf := embeddings.Config{
 		Size:             we.Embedding.EmbeddingDim,
 		UseZeroEmbedding: true,
@@ -277,10 +298,13 @@ func (conv *converter[T]) encoderEmbeddingsTokensEncoderCharLMVocabulary(forward
 	return vocabulary.New(d1.Idx2Item), nil
 }
 
-func (conv *converter[T]) encoderEmbeddingsTokensEncoderCharLM(st store.Store, voc *vocabulary.Vocabulary, fe *convflair.FlairEmbeddings) (*charlm.Model, error) {
-	decoder, err := conv.convertLinear(fe.LM.Decoder)
-	if err != nil {
-		return nil, fmt.Errorf("failed to convert CharLM decoder: %w", err)
+func (conv *converter[T]) encoderEmbeddingsTokensEncoderCharLM(st store.Store, voc *vocabulary.Vocabulary, fe *convflair.FlairEmbeddings) (_ *charlm.Model, err error) {
+	var decoder *linear.Model
+	if fe.LM.Decoder != nil {
+		decoder, err = conv.convertLinear(fe.LM.Decoder)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert CharLM decoder: %w", err)
+		}
 	}
 
 	rnn, err := conv.convertLSTM(fe.LM.RNN, false)
@@ -495,6 +519,9 @@ func (conv *converter[T]) decoder() (_ *flair.Decoder, err error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert Scorer: %w", err)
 	}
+	if conv.st.CRF == nil {
+		return d, nil // the tags are predicted by the best scores
+	}
 	d.CRF, err = conv.decoderCRF()
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert CRF: %w", err)
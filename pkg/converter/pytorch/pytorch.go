@@ -5,14 +5,34 @@
 package pytorch
 
 import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/converter/safetensors"
 	"github.com/nlpodyssey/gopickle/pytorch"
 	"github.com/nlpodyssey/gopickle/types"
 	"github.com/nlpodyssey/spago/mat/float"
+	"github.com/rs/zerolog/log"
 )
 
 // ParamsProvider is a provider of parameters for a PyTorch model.
+//
+// Parameters loaded from a safetensors file are read lazily, one at a time,
+// when requested, so that the whole state dict is never materialized.
+// PyTorch pickle files, instead, are loaded in memory all at once, since the
+// pickle format can't be decoded one tensor at a time: their conversion
+// takes about the size of the checkpoint in memory (see ResolveModelFile,
+// preferring the safetensors checkpoint).
 type ParamsProvider[T float.DType] struct {
-	paramsData    map[string][]T
+	paramsData  map[string][]T
+	paramsShape map[string][]int
+	// lazyParams maps the names of the parameters not read yet to their
+	// names in the safetensors file.
+	lazyParams    map[string]string
+	safetensors   *safetensors.File
+	err           error
 	nameMapping   MappingFunc
 	preProcessing PreProcessingFunc[T]
 }
@@ -26,8 +46,20 @@ type PreProcessingFunc[T float.DType] func(params *ParamsProvider[T]) error
 // NewParamsProvider returns a new ParamsProvider.
 func NewParamsProvider[T float.DType]() *ParamsProvider[T] {
 	return &ParamsProvider[T]{
-		paramsData: make(map[string][]T),
+		paramsData:  make(map[string][]T),
+		paramsShape: make(map[string][]int),
+		lazyParams:  make(map[string]string),
+	}
+}
+
+// ResolveModelFile returns the path of the safetensors checkpoint in the
+// model directory, if it exists, or the path of the given PyTorch file.
+func ResolveModelFile(modelDir, pyModelFilename string) string {
+	st := filepath.Join(modelDir, safetensors.DefaultFilename)
+	if info, err := os.Stat(st); err == nil && !info.IsDir() {
+		return st
 	}
+	return filepath.Join(modelDir, pyModelFilename)
 }
 
 // WithNameMapping sets the name mapping function.
@@ -42,18 +74,37 @@ func (p *ParamsProvider[T]) WithPreProcessing(fn PreProcessingFunc[T]) *ParamsPr
 	return p
 }
 
-// Load loads parameters from a PyTorch model.
+// Load loads parameters from a PyTorch model, or from a safetensors file
+// if the filename has the ".safetensors" extension.
 func (p *ParamsProvider[T]) Load(filename string) error {
+	var err error
+	if strings.HasSuffix(filename, ".safetensors") {
+		err = p.loadSafetensors(filename)
+	} else {
+		err = p.loadPickle(filename)
+	}
+	if err != nil {
+		return err
+	}
+	if p.preProcessing != nil {
+		if err := p.preProcessing(p); err != nil {
+			return err
+		}
+	}
+	return p.err
+}
+
+func (p *ParamsProvider[T]) loadPickle(filename string) error {
+	log.Info().Str("file", filename).Msg("loading the whole PyTorch checkpoint in memory: a safetensors checkpoint would be streamed")
 	result, err := pytorch.Load(filename)
 	if err != nil {
 		return err
 	}
 	fn := func(name string, tensor *pytorch.Tensor) {
 		if _, ok := tensor.Source.(*pytorch.FloatStorage); ok {
-			if p.nameMapping != nil {
-				name = p.nameMapping(name)
-			}
+			name = p.mapName(name)
 			p.paramsData[name] = data[T](tensor)
+			p.paramsShape[name] = tensor.Size
 		}
 	}
 	switch r := result.(type) {
@@ -62,8 +113,45 @@ func (p *ParamsProvider[T]) Load(filename string) error {
 	case *types.Dict:
 		p.yieldDict(r, fn)
 	}
-	err = p.preProcessing(p)
-	return err
+	return nil
+}
+
+func (p *ParamsProvider[T]) loadSafetensors(filename string) error {
+	st, err := safetensors.Open(filename)
+	if err != nil {
+		return err
+	}
+	p.safetensors = st
+	for _, stName := range st.Names() {
+		info, _ := st.Info(stName)
+		if info.DType != "F32" && info.DType != "F16" && info.DType != "BF16" && info.DType != "F64" {
+			continue
+		}
+		name := p.mapName(stName)
+		p.lazyParams[name] = stName
+		p.paramsShape[name] = info.Shape
+	}
+	return nil
+}
+
+func (p *ParamsProvider[T]) mapName(name string) string {
+	if p.nameMapping != nil {
+		return p.nameMapping(name)
+	}
+	return name
+}
+
+// Close releases the resources of the provider.
+func (p *ParamsProvider[T]) Close() error {
+	if p.safetensors == nil {
+		return nil
+	}
+	return p.safetensors.Close()
+}
+
+// Err returns the first error occurred reading a lazily loaded parameter.
+func (p *ParamsProvider[T]) Err() error {
+	return p.err
 }
 
 func (p *ParamsProvider[T]) yieldOrderedDict(dict *types.OrderedDict, fn func(name string, tensor *pytorch.Tensor)) {
@@ -80,24 +168,60 @@ func (p *ParamsProvider[T]) yieldDict(dict *types.Dict, fn func(name string, ten
 
 // Pop returns a parameter with the given name and remove it from the params list.
 func (p *ParamsProvider[T]) Pop(name string) []T {
-	data := p.paramsData[name]
-	delete(p.paramsData, name)
+	data := p.Get(name)
+	p.Delete(name)
 	return data
 }
 
 // Get returns a parameter with the given name.
+// Lazily loaded parameters are read from file at each call.
 func (p *ParamsProvider[T]) Get(name string) []T {
-	return p.paramsData[name]
+	if data, ok := p.paramsData[name]; ok {
+		return data
+	}
+	if stName, ok := p.lazyParams[name]; ok {
+		return p.read(stName)
+	}
+	return nil
+}
+
+func (p *ParamsProvider[T]) read(stName string) []T {
+	data, err := safetensors.ReadFloats[T](p.safetensors, stName)
+	if err != nil && p.err == nil {
+		p.err = err
+	}
+	return data
 }
 
 // Delete deletes a parameter with the given name.
 func (p *ParamsProvider[T]) Delete(name string) {
 	delete(p.paramsData, name)
+	delete(p.lazyParams, name)
 }
 
 // Set sets a parameter with the given name.
 func (p *ParamsProvider[T]) Set(name string, data []T) {
 	p.paramsData[name] = data
+	delete(p.lazyParams, name)
+}
+
+// Shape returns the shape of the original tensor with the given name.
+// The shape of the parameters set by the pre-processing is not known.
+func (p *ParamsProvider[T]) Shape(name string) []int {
+	return p.paramsShape[name]
+}
+
+// Names returns the sorted names of all the parameters in the provider.
+func (p *ParamsProvider[T]) Names() []string {
+	names := make([]string, 0, len(p.paramsData)+len(p.lazyParams))
+	for name := range p.paramsData {
+		names = append(names, name)
+	}
+	for name := range p.lazyParams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // data returns the underlying values of a PyTorch tensor as a T slice.
@@ -130,11 +254,26 @@ func data[T float.DType](t *pytorch.Tensor) []T {
 }
 
 // Iterate iterates over all the parameters in the provider.
+// Lazily loaded parameters are read one at a time, so that only the
+// parameter being visited is kept in memory by the provider.
 func (p *ParamsProvider[T]) Iterate(fn func(name string, data []T) error) error {
 	for name, data := range p.paramsData {
 		if err := fn(name, data); err != nil {
 			return err
 		}
 	}
+	for _, name := range p.Names() {
+		stName, ok := p.lazyParams[name]
+		if !ok {
+			continue
+		}
+		data := p.read(stName)
+		if p.err != nil {
+			return p.err
+		}
+		if err := fn(name, data); err != nil {
+			return err
+		}
+	}
 	return nil
 }
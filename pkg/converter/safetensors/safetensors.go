@@ -0,0 +1,181 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package safetensors implements a lazy reader for the safetensors format,
+// which allows reading the tensors of a checkpoint one at a time, without
+// loading the whole file in memory.
+//
+// Specification: https://github.com/huggingface/safetensors
+package safetensors
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
+	"github.com/nlpodyssey/spago/mat/float"
+)
+
+// DefaultFilename is the default filename of a safetensors checkpoint
+// in Hugging Face model repositories.
+const DefaultFilename = "model.safetensors"
+
+// maxHeaderSize is a sanity limit on the size of the JSON header.
+const maxHeaderSize = 100 << 20
+
+// TensorInfo describes a tensor stored in the file.
+type TensorInfo struct {
+	// DType is the element type (e.g. "F32", "F16", "BF16").
+	DType string `json:"dtype"`
+	// Shape is the size of each dimension.
+	Shape []int `json:"shape"`
+	// DataOffsets are the begin and end offsets of the data, relative to the end of the header.
+	DataOffsets [2]int64 `json:"data_offsets"`
+}
+
+// File is an open safetensors file.
+type File struct {
+	f          *os.File
+	dataOffset int64
+	tensors    map[string]TensorInfo
+}
+
+// Open opens a safetensors file, reading only its header.
+func Open(filename string) (*File, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	st, err := readHeader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("safetensors: %s: %w", filename, err)
+	}
+	return st, nil
+}
+
+func readHeader(f *os.File) (*File, error) {
+	tensors, size, err := decodeHeader(f)
+	if err != nil {
+		return nil, err
+	}
+	return &File{f: f, dataOffset: 8 + size, tensors: tensors}, nil
+}
+
+// ReadHeader reads the descriptions of the tensors, by name, from the header
+// at the beginning of a safetensors file. Only the header is read from r,
+// so that, e.g., a remote file can be inspected without downloading it.
+func ReadHeader(r io.Reader) (map[string]TensorInfo, error) {
+	tensors, _, err := decodeHeader(r)
+	if err != nil {
+		return nil, fmt.Errorf("safetensors: %w", err)
+	}
+	return tensors, nil
+}
+
+// decodeHeader returns the tensors of the header, and the header size.
+func decodeHeader(r io.Reader) (map[string]TensorInfo, int64, error) {
+	var size uint64
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return nil, 0, err
+	}
+	if size > maxHeaderSize {
+		return nil, 0, fmt.Errorf("header too large (%d bytes)", size)
+	}
+	header := make([]byte, size)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, 0, err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(header, &raw); err != nil {
+		return nil, 0, err
+	}
+	tensors := make(map[string]TensorInfo, len(raw))
+	for name, value := range raw {
+		if name == "__metadata__" {
+			continue
+		}
+		var info TensorInfo
+		if err := json.Unmarshal(value, &info); err != nil {
+			return nil, 0, fmt.Errorf("tensor %q: %w", name, err)
+		}
+		tensors[name] = info
+	}
+	return tensors, int64(size), nil
+}
+
+// Close closes the file.
+func (st *File) Close() error {
+	return st.f.Close()
+}
+
+// Names returns the sorted names of the tensors.
+func (st *File) Names() []string {
+	names := make([]string, 0, len(st.tensors))
+	for name := range st.tensors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Info returns the description of the tensor with the given name.
+func (st *File) Info(name string) (TensorInfo, bool) {
+	info, ok := st.tensors[name]
+	return info, ok
+}
+
+// ReadFloats reads the values of a floating-point tensor, in row-major order.
+func ReadFloats[T float.DType](st *File, name string) ([]T, error) {
+	info, ok := st.tensors[name]
+	if !ok {
+		return nil, fmt.Errorf("safetensors: tensor %q not found", name)
+	}
+	n := 1
+	for _, d := range info.Shape {
+		n *= d
+	}
+	size := info.DataOffsets[1] - info.DataOffsets[0]
+
+	var elemSize int64
+	switch info.DType {
+	case "F64":
+		elemSize = 8
+	case "F32":
+		elemSize = 4
+	case "F16", "BF16":
+		elemSize = 2
+	default:
+		return nil, fmt.Errorf("safetensors: tensor %q: unsupported dtype %s", name, info.DType)
+	}
+	if size != int64(n)*elemSize {
+		return nil, fmt.Errorf("safetensors: tensor %q: expected %d bytes, got %d", name, int64(n)*elemSize, size)
+	}
+
+	buf := make([]byte, size)
+	if _, err := st.f.ReadAt(buf, st.dataOffset+info.DataOffsets[0]); err != nil {
+		return nil, fmt.Errorf("safetensors: tensor %q: %w", name, err)
+	}
+
+	out := make([]T, n)
+	for i := range out {
+		switch info.DType {
+		case "F64":
+			out[i] = T(math.Float64frombits(binary.LittleEndian.Uint64(buf[i*8:])))
+		case "F32":
+			out[i] = T(math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:])))
+		case "F16":
+			out[i] = T(quantization.Float16ToFloat32(binary.LittleEndian.Uint16(buf[i*2:])))
+		case "BF16":
+			out[i] = T(quantization.BFloat16ToFloat32(binary.LittleEndian.Uint16(buf[i*2:])))
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package safetensors
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_ReadFloats(t *testing.T) {
+	header := `{"__metadata__":{"format":"pt"},` +
+		`"a":{"dtype":"F32","shape":[2,2],"data_offsets":[0,16]},` +
+		`"b":{"dtype":"F16","shape":[3],"data_offsets":[16,22]},` +
+		`"c":{"dtype":"I64","shape":[1],"data_offsets":[22,30]}}`
+	data := make([]byte, 30)
+	for i, v := range []float32{1, -2, 3.5, 0.25} {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(v))
+	}
+	for i, v := range []float32{0.5, -1, 2} {
+		binary.LittleEndian.PutUint16(data[16+i*2:], quantization.Float32ToFloat16(v))
+	}
+
+	filename := filepath.Join(t.TempDir(), DefaultFilename)
+	content := binary.LittleEndian.AppendUint64(nil, uint64(len(header)))
+	content = append(append(content, header...), data...)
+	require.NoError(t, os.WriteFile(filename, content, 0o644))
+
+	st, err := Open(filename)
+	require.NoError(t, err)
+	defer st.Close()
+
+	assert.Equal(t, []string{"a", "b", "c"}, st.Names())
+	info, ok := st.Info("a")
+	require.True(t, ok)
+	assert.Equal(t, []int{2, 2}, info.Shape)
+
+	a, err := ReadFloats[float64](st, "a")
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1, -2, 3.5, 0.25}, a)
+
+	b, err := ReadFloats[float32](st, "b")
+	require.NoError(t, err)
+	assert.Equal(t, []float32{0.5, -1, 2}, b)
+
+	_, err = ReadFloats[float32](st, "c")
+	assert.Error(t, err)
+	_, err = ReadFloats[float32](st, "d")
+	assert.Error(t, err)
+}
@@ -0,0 +1,103 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package datasets
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// conllReader reads the sentences of a CoNLL file.
+type conllReader struct {
+	nopCloser
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newCoNLLReader(r io.Reader) *conllReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	return &conllReader{scanner: scanner}
+}
+
+// Next returns the next sentence, whose input is its tokens separated by
+// spaces, and whose entities are the spans of its tags, in the IOB1, IOB2
+// or IOBES schemes. Its fields are the "tokens" and their "tags". The
+// "-DOCSTART-" lines are skipped.
+func (r *conllReader) Next() (Example, error) {
+	var tokens, tags []string
+	for r.scanner.Scan() {
+		r.line++
+		fields := strings.Fields(r.scanner.Text())
+		if len(fields) == 0 {
+			if len(tokens) > 0 {
+				break
+			}
+			continue
+		}
+		if fields[0] == "-DOCSTART-" {
+			continue
+		}
+		if len(fields) < 2 {
+			return Example{}, fmt.Errorf("line %d: missing tag", r.line)
+		}
+		tokens = append(tokens, fields[0])
+		tags = append(tags, fields[len(fields)-1])
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Example{}, err
+	}
+	if len(tokens) == 0 {
+		return Example{}, io.EOF
+	}
+	input := strings.Join(tokens, " ")
+	return Example{
+		Input:    input,
+		Entities: iobEntities(tokens, tags),
+		Fields:   map[string]any{"input": input, "tokens": tokens, "tags": tags},
+	}, nil
+}
+
+// iobEntities returns the entities of the tokens tagged in the IOB1, IOB2
+// or IOBES schemes: an entity starts with a "B-" or "S-" tag, or with an
+// "I-" or "E-" tag of a label other than the one of the previous token.
+func iobEntities(tokens, tags []string) []Entity {
+	var entities []Entity
+	var span []string
+	label := ""
+	flush := func() {
+		if len(span) > 0 {
+			entities = append(entities, Entity{Text: strings.Join(span, " "), Label: label})
+		}
+		span, label = nil, ""
+	}
+	for i, tag := range tags {
+		prefix, l, ok := strings.Cut(tag, "-")
+		if !ok || len(prefix) != 1 {
+			flush()
+			continue
+		}
+		switch prefix {
+		case "B", "S":
+			flush()
+		case "I", "E":
+			if l != label {
+				flush()
+			}
+		default:
+			flush()
+			continue
+		}
+		span = append(span, tokens[i])
+		label = l
+		if prefix == "S" || prefix == "E" {
+			flush()
+		}
+	}
+	flush()
+	return entities
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package datasets
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvReader reads the rows of a CSV or TSV file.
+type csvReader struct {
+	nopCloser
+	r      *csv.Reader
+	header []string
+	// input, question, label and reference are the indices of the columns
+	// of the fields of the examples, -1 if missing.
+	input, question, label, reference int
+}
+
+// newCSVReader reads the rows of the file, with a header, separated by
+// comma: the input is the "input" or "text" column, and the "question",
+// "label" and "reference" columns are the other fields of the examples, if
+// any. The fields of the examples are all the columns, by name.
+func newCSVReader(r io.Reader, comma rune) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = comma
+	if comma == '\t' {
+		cr.LazyQuotes = true
+	}
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("missing header")
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &csvReader{r: cr, header: header, input: -1, question: -1, label: -1, reference: -1}
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "input", "text":
+			if c.input < 0 {
+				c.input = i
+			}
+		case "question":
+			c.question = i
+		case "label":
+			c.label = i
+		case "reference":
+			c.reference = i
+		}
+	}
+	if c.input < 0 {
+		return nil, errors.New(`missing "input" or "text" column`)
+	}
+	return c, nil
+}
+
+func (c *csvReader) Next() (Example, error) {
+	row, err := c.r.Read()
+	if err != nil {
+		return Example{}, err
+	}
+	line, _ := c.r.FieldPos(0)
+	if len(row) != len(c.header) {
+		return Example{}, fmt.Errorf("line %d: %d columns instead of %d", line, len(row), len(c.header))
+	}
+	fields := make(map[string]any, len(row))
+	for i, v := range row {
+		fields[c.header[i]] = v
+	}
+	column := func(i int) string {
+		if i < 0 {
+			return ""
+		}
+		return row[i]
+	}
+	return Example{
+		Input:     row[c.input],
+		Question:  column(c.question),
+		Label:     column(c.label),
+		Reference: column(c.reference),
+		Fields:    fields,
+	}, nil
+}
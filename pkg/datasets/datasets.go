@@ -0,0 +1,198 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package datasets reads the examples of the corpora in the standard
+// dataset formats, i.e. CoNLL, SQuAD, CSV/TSV and JSON lines, e.g. for the
+// evaluation of the models or their batch runs.
+package datasets
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Example is an example of a dataset, with its expected output, if any.
+type Example struct {
+	// Input is the input text, the passage of the question answering.
+	Input string `json:"input"`
+	// Question is the question of the question answering.
+	Question string `json:"question,omitempty"`
+	// Label is the expected label of the text and zero-shot classifications.
+	Label string `json:"label,omitempty"`
+	// Reference is the expected text of the text generation, the expected
+	// answer of the question answering, or a text similar to the input,
+	// for the text encoding.
+	Reference string `json:"reference,omitempty"`
+	// Entities are the expected entities of the token classification.
+	Entities []Entity `json:"entities,omitempty"`
+	// Fields are all the fields of the example in its format, e.g. the
+	// columns of a CSV row, including its input.
+	Fields map[string]any `json:"-"`
+}
+
+// Entity is an entity of the token classification.
+type Entity struct {
+	Text  string `json:"text"`
+	Label string `json:"label"`
+}
+
+// Format is the format of a dataset file.
+type Format string
+
+const (
+	// FormatCoNLL is the CoNLL format of the token classification: a token
+	// per line, with its IOB tag in the last column, and the sentences
+	// separated by blank lines.
+	FormatCoNLL Format = "conll"
+	// FormatSQuAD is the JSON format of the SQuAD question answering
+	// datasets.
+	FormatSQuAD Format = "squad"
+	// FormatCSV is the comma separated values format, with a header.
+	FormatCSV Format = "csv"
+	// FormatTSV is the tab separated values format, with a header.
+	FormatTSV Format = "tsv"
+	// FormatJSONL is the JSON lines format, an example per line.
+	FormatJSONL Format = "jsonl"
+)
+
+// Task returns the task of the examples of the format, if implied by it.
+func (f Format) Task() string {
+	switch f {
+	case FormatCoNLL:
+		return "token-classification"
+	case FormatSQuAD:
+		return "question-answering"
+	default:
+		return ""
+	}
+}
+
+// ParseFormat parses the name of a format.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatCoNLL, FormatSQuAD, FormatCSV, FormatTSV, FormatJSONL:
+		return f, nil
+	default:
+		return "", fmt.Errorf("invalid dataset format %#v", s)
+	}
+}
+
+// FormatOf returns the format of the file by its extension: ".conll",
+// ".iob" and ".bio" for CoNLL, ".json" for SQuAD, ".csv", ".tsv", and
+// ".jsonl" and ".ndjson" for JSON lines.
+func FormatOf(filename string) (Format, bool) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".conll", ".iob", ".bio":
+		return FormatCoNLL, true
+	case ".json":
+		return FormatSQuAD, true
+	case ".csv":
+		return FormatCSV, true
+	case ".tsv":
+		return FormatTSV, true
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, true
+	default:
+		return "", false
+	}
+}
+
+// Reader reads the examples of a dataset.
+type Reader interface {
+	// Next returns the next example, or io.EOF at the end of the dataset.
+	Next() (Example, error)
+	// Close closes the reader.
+	Close() error
+}
+
+// Open opens the dataset file in the format, or in the one of its extension
+// if empty.
+func Open(filename string, format Format) (Reader, error) {
+	if format == "" {
+		var ok bool
+		if format, ok = FormatOf(filename); !ok {
+			return nil, fmt.Errorf("datasets: unknown format of %#v", filename)
+		}
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(f, format)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("datasets: %s: %w", filename, err)
+	}
+	return fileReader{Reader: r, f: f}, nil
+}
+
+// fileReader reads the examples of a file, closing it.
+type fileReader struct {
+	Reader
+	f *os.File
+}
+
+func (r fileReader) Close() error {
+	return r.f.Close()
+}
+
+// NewReader returns the reader of the examples of the dataset in the
+// format. Closing it doesn't close r.
+func NewReader(r io.Reader, format Format) (Reader, error) {
+	switch format {
+	case FormatCoNLL:
+		return newCoNLLReader(r), nil
+	case FormatSQuAD:
+		return newSQuADReader(r)
+	case FormatCSV:
+		return newCSVReader(r, ',')
+	case FormatTSV:
+		return newCSVReader(r, '\t')
+	case FormatJSONL:
+		return newJSONLReader(r), nil
+	default:
+		return nil, fmt.Errorf("unsupported format %#v", format)
+	}
+}
+
+// ReadFile reads all the examples of the dataset file, in the format, or in
+// the one of its extension if empty.
+func ReadFile(filename string, format Format) ([]Example, error) {
+	r, err := Open(filename, format)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	examples, err := ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("datasets: %s: %w", filename, err)
+	}
+	return examples, nil
+}
+
+// ReadAll reads all the examples of the reader.
+func ReadAll(r Reader) ([]Example, error) {
+	var examples []Example
+	for {
+		ex, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return examples, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		examples = append(examples, ex)
+	}
+}
+
+// nopCloser is embedded by the readers with nothing to close.
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package datasets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func read(t *testing.T, format Format, data string) []Example {
+	r, err := NewReader(strings.NewReader(data), format)
+	require.NoError(t, err)
+	examples, err := ReadAll(r)
+	require.NoError(t, err)
+	return examples
+}
+
+func TestFormatOf(t *testing.T) {
+	for filename, want := range map[string]Format{
+		"train.conll": FormatCoNLL,
+		"dev.IOB":     FormatCoNLL,
+		"dev-v2.json": FormatSQuAD,
+		"data.csv":    FormatCSV,
+		"data.tsv":    FormatTSV,
+		"data.jsonl":  FormatJSONL,
+	} {
+		format, ok := FormatOf(filename)
+		assert.True(t, ok, filename)
+		assert.Equal(t, want, format, filename)
+	}
+	_, ok := FormatOf("data.txt")
+	assert.False(t, ok)
+
+	format, err := ParseFormat("SQuAD")
+	require.NoError(t, err)
+	assert.Equal(t, FormatSQuAD, format)
+	_, err = ParseFormat("xml")
+	assert.Error(t, err)
+}
+
+func TestReadCoNLL(t *testing.T) {
+	examples := read(t, FormatCoNLL, `-DOCSTART- -X- -X- O
+
+EU NNP B-NP B-ORG
+rejects VBZ B-VP O
+German JJ B-NP B-MISC
+call NN I-NP O
+to TO B-VP O
+boycott VB I-VP O
+British JJ B-NP B-MISC
+lamb NN I-NP O
+
+Peter NNP B-NP I-PER
+Blackburn NNP I-NP I-PER
+in IN B-PP O
+New S-LOC
+York S-LOC
+`)
+	require.Len(t, examples, 2)
+	assert.Equal(t, "EU rejects German call to boycott British lamb", examples[0].Input)
+	assert.Equal(t, []Entity{{"EU", "ORG"}, {"German", "MISC"}, {"British", "MISC"}}, examples[0].Entities)
+	assert.Equal(t, []Entity{{"Peter Blackburn", "PER"}, {"New", "LOC"}, {"York", "LOC"}}, examples[1].Entities)
+	assert.Equal(t, []string{"Peter", "Blackburn", "in", "New", "York"}, examples[1].Fields["tokens"])
+
+	_, err := ReadAll(newCoNLLReader(strings.NewReader("EU B-ORG\nrejects\n")))
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestReadSQuAD(t *testing.T) {
+	examples := read(t, FormatSQuAD, `{"version": "v2.0", "data": [{"title": "Normans", "paragraphs": [{
+		"context": "The Normans gave their name to Normandy, a region in France.",
+		"qas": [
+			{"id": "1", "question": "In what country is Normandy located?", "answers": [{"text": "France", "answer_start": 54}, {"text": "France.", "answer_start": 54}]},
+			{"id": "2", "question": "Who gave their name to Paris?", "answers": [], "is_impossible": true}
+		]
+	}]}]}`)
+	require.Len(t, examples, 1)
+	assert.Equal(t, "The Normans gave their name to Normandy, a region in France.", examples[0].Input)
+	assert.Equal(t, "In what country is Normandy located?", examples[0].Question)
+	assert.Equal(t, "France", examples[0].Reference)
+	assert.Equal(t, "1", examples[0].Fields["id"])
+	assert.Equal(t, []string{"France", "France."}, examples[0].Fields["answers"])
+
+	_, err := NewReader(strings.NewReader(`{"task": "question-answering"}`), FormatSQuAD)
+	assert.Error(t, err)
+}
+
+func TestReadCSV(t *testing.T) {
+	examples := read(t, FormatCSV, "id,text,label\n1,\"Great, really\",positive\n2,Awful,negative\n")
+	require.Len(t, examples, 2)
+	assert.Equal(t, Example{
+		Input:  "Great, really",
+		Label:  "positive",
+		Fields: map[string]any{"id": "1", "text": "Great, really", "label": "positive"},
+	}, examples[0])
+
+	examples = read(t, FormatTSV, "input\treference\nHello\tCiao\n")
+	require.Len(t, examples, 1)
+	assert.Equal(t, "Hello", examples[0].Input)
+	assert.Equal(t, "Ciao", examples[0].Reference)
+
+	_, err := NewReader(strings.NewReader("id,label\n1,positive\n"), FormatCSV)
+	assert.Error(t, err)
+	r, err := NewReader(strings.NewReader("text,label\nGreat\n"), FormatCSV)
+	require.NoError(t, err)
+	_, err = ReadAll(r)
+	assert.Error(t, err)
+}
+
+func TestReadJSONL(t *testing.T) {
+	examples := read(t, FormatJSONL, `{"id": 1, "input": "Rome is in Italy", "entities": [{"text": "Rome", "label": "LOC"}]}
+
+{"input": "Hi", "label": "greeting"}
+`)
+	require.Len(t, examples, 2)
+	assert.Equal(t, []Entity{{"Rome", "LOC"}}, examples[0].Entities)
+	assert.Equal(t, float64(1), examples[0].Fields["id"])
+	assert.Equal(t, "greeting", examples[1].Label)
+
+	_, err := ReadAll(newJSONLReader(strings.NewReader(`{"text": "Hi"}`)))
+	assert.ErrorContains(t, err, `line 1: missing string field "input"`)
+}
+
+func TestReadFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.tsv")
+	require.NoError(t, os.WriteFile(filename, []byte("text\tlabel\nHi\tgreeting\n"), 0644))
+
+	examples, err := ReadFile(filename, "")
+	require.NoError(t, err)
+	require.Len(t, examples, 1)
+	assert.Equal(t, "greeting", examples[0].Label)
+
+	_, err = ReadFile(filename, FormatJSONL)
+	assert.Error(t, err)
+	_, err = ReadFile(filepath.Join(t.TempDir(), "data.txt"), "")
+	assert.ErrorContains(t, err, "unknown format")
+}
@@ -0,0 +1,55 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package datasets
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// jsonlReader reads the lines of a JSON lines file.
+type jsonlReader struct {
+	nopCloser
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newJSONLReader(r io.Reader) *jsonlReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	return &jsonlReader{scanner: scanner}
+}
+
+// Next returns the example of the next non-empty line, a JSON object with
+// the fields of Example, e.g. "input" and "label", whose fields are all the
+// ones of the object.
+func (r *jsonlReader) Next() (Example, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" {
+			continue
+		}
+		var ex Example
+		if err := json.Unmarshal([]byte(line), &ex.Fields); err != nil {
+			return Example{}, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		if _, ok := ex.Fields["input"].(string); !ok {
+			return Example{}, fmt.Errorf("line %d: %w", r.line, errors.New(`missing string field "input"`))
+		}
+		if err := json.Unmarshal([]byte(line), &ex); err != nil {
+			return Example{}, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		return ex, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Example{}, err
+	}
+	return Example{}, io.EOF
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package datasets
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// squadFile is a SQuAD dataset file, v1.1 or v2.0.
+type squadFile struct {
+	Data []struct {
+		Title      string `json:"title"`
+		Paragraphs []struct {
+			Context string `json:"context"`
+			QAs     []struct {
+				ID           string `json:"id"`
+				Question     string `json:"question"`
+				IsImpossible bool   `json:"is_impossible"`
+				Answers      []struct {
+					Text        string `json:"text"`
+					AnswerStart int    `json:"answer_start"`
+				} `json:"answers"`
+			} `json:"qas"`
+		} `json:"paragraphs"`
+	} `json:"data"`
+}
+
+// squadReader reads the questions of a SQuAD file, loaded at once.
+type squadReader struct {
+	nopCloser
+	examples []Example
+}
+
+// newSQuADReader reads the questions of the SQuAD file, each an example
+// whose input is its context, and whose reference is its first answer. The
+// unanswerable questions of SQuAD v2.0 are skipped. Their fields are their
+// "id", "title", "question" and the texts of their "answers".
+func newSQuADReader(r io.Reader) (*squadReader, error) {
+	var f squadFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, err
+	}
+	if f.Data == nil {
+		return nil, errors.New(`missing "data"`)
+	}
+	var examples []Example
+	for _, article := range f.Data {
+		for _, p := range article.Paragraphs {
+			for _, qa := range p.QAs {
+				if qa.IsImpossible || len(qa.Answers) == 0 {
+					continue
+				}
+				answers := make([]string, len(qa.Answers))
+				for i, a := range qa.Answers {
+					answers[i] = a.Text
+				}
+				examples = append(examples, Example{
+					Input:     p.Context,
+					Question:  qa.Question,
+					Reference: answers[0],
+					Fields: map[string]any{
+						"id":       qa.ID,
+						"title":    article.Title,
+						"input":    p.Context,
+						"question": qa.Question,
+						"answers":  answers,
+					},
+				})
+			}
+		}
+	}
+	return &squadReader{examples: examples}, nil
+}
+
+func (r *squadReader) Next() (Example, error) {
+	if len(r.examples) == 0 {
+		return Example{}, io.EOF
+	}
+	ex := r.examples[0]
+	r.examples = r.examples[1:]
+	return ex, nil
+}
@@ -5,9 +5,10 @@
 package downloader
 
 import (
+	"context"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -18,8 +19,8 @@ import (
 
 const (
 	// Hugging Face repository URL, in the format:
-	// "https://huggingface.co/{model_id}/resolve/{revision}/{filename}"
-	huggingFaceCoPrefix = "https://huggingface.co/%s/resolve/%s/%s"
+	// "{endpoint}/{model_id}/resolve/{revision}/{filename}"
+	huggingFaceCoPrefix = "%s/%s/resolve/%s/%s"
 	// Default revision name for fetching model from Hugging Face repository
 	defaultRevision = "main"
 )
@@ -52,21 +53,118 @@ var supportedModelsFiles = map[string][]string{
 // exists is kept and considered as already successfully downloaded. If
 // the flag is otherwise set to true, existing files will be forcefully
 // downloaded and overwritten.
+//
+// Files are downloaded to temporary ".incomplete" files first, so that an
+// interrupted download is resumed on the next call. Large files are
+// downloaded in parallel chunks, failed requests are retried with
+// exponential backoff, and the content is verified against the hash
+// reported by the Hub, when available.
+//
+// The access token is needed to download private and gated models. If it is
+// empty, the token is resolved with ResolveAccessToken.
 func Download(modelsDir, modelName string, overwriteIfExists bool, useAccessToken string) error {
+	return DownloadWithOptions(modelsDir, modelName, Options{
+		OverwriteIfExist: overwriteIfExists,
+		AccessToken:      useAccessToken,
+	})
+}
+
+// Options are the options for downloading a model.
+type Options struct {
+	// OverwriteIfExist forces the download of the files that already exist.
+	OverwriteIfExist bool
+	// AccessToken is the access token for the Hugging Face Hub (see ResolveAccessToken).
+	AccessToken string
+	// Revision is the branch, tag or commit SHA to download (default "main").
+	Revision string
+	// Offline disables any network access: the files are only checked to be
+	// in the local cache, failing with ErrMissingFiles otherwise.
+	Offline bool
+	// Endpoint is the URL of the Hub or of a mirror (default HF_ENDPOINT, or DefaultEndpoint).
+	Endpoint string
+	// Proxy is the URL of an HTTP(S) or SOCKS5 proxy (default HTTPS_PROXY, HTTP_PROXY).
+	Proxy string
+	// CABundle is a PEM file of CA certificates to trust in addition to the
+	// system ones (default REQUESTS_CA_BUNDLE, CURL_CA_BUNDLE).
+	CABundle string
+	// Progress is called with the progress of the download of each file,
+	// periodically, and once the file is done (optional).
+	Progress func(Progress)
+}
+
+// DownloadWithOptions is like Download, with additional options.
+//
+// The revision is resolved to a commit SHA, which is recorded in the
+// model's directory and used for all the files, so that files of different
+// revisions are never mixed: resuming the download of a model fetches the
+// recorded commit, and requesting a different revision of a model which
+// already exists fails, unless OverwriteIfExist is set.
+//
+// In offline mode, also enabled by the HF_HUB_OFFLINE environment variable,
+// nothing is downloaded: the files are only checked to be in the local cache.
+func DownloadWithOptions(modelsDir, modelName string, opts Options) error {
+	return DownloadContext(context.Background(), modelsDir, modelName, opts)
+}
+
+// DownloadContext is like DownloadWithOptions, the context bounding the
+// download: once it's done, the requests are canceled and the download
+// fails with its error, to be resumed by the next call.
+func DownloadContext(ctx context.Context, modelsDir, modelName string, opts Options) error {
+	d, err := newDownloader(modelsDir, modelName, opts)
+	if err != nil {
+		return err
+	}
+	d.ctx = ctx
+	if opts.Offline || IsOfflineEnv() {
+		return d.checkLocalFiles()
+	}
+	return d.download()
+}
+
+func newDownloader(modelsDir, modelName string, opts Options) (downloader, error) {
+	revision := opts.Revision
+	if revision == "" {
+		revision = defaultRevision
+	}
+	client, noRedirectClient, err := newHTTPClients(opts.Proxy, opts.CABundle)
+	if err != nil {
+		return downloader{}, err
+	}
 	return downloader{
+		endpoint:         resolveEndpoint(opts.Endpoint),
+		client:           client,
+		noRedirectClient: noRedirectClient,
 		modelPath:        filepath.Join(modelsDir, modelName),
 		modelName:        modelName,
-		overwriteIfExist: overwriteIfExists,
-		accessToken:      useAccessToken,
-	}.download()
+		revision:         revision,
+		overwriteIfExist: opts.OverwriteIfExist,
+		accessToken:      ResolveAccessToken(opts.AccessToken),
+		parallelism:      defaultParallelism,
+		chunkSize:        defaultChunkSize,
+		progress:         opts.Progress,
+	}, nil
 }
 
 // downloader is a helper struct for downloading a model.
 type downloader struct {
+	// ctx bounds the requests of the download (see context).
+	ctx              context.Context
+	endpoint         string
+	client           *http.Client
+	noRedirectClient *http.Client
 	modelPath        string
 	modelName        string
+	revision         string
+	// commit is the commit SHA the revision resolves to.
+	commit           string
 	accessToken      string
 	overwriteIfExist bool
+	// parallelism is the maximum number of chunks downloaded in parallel.
+	parallelism int
+	// chunkSize is the minimum size of a chunk downloaded in parallel.
+	chunkSize int64
+	// progress is called with the progress of the downloads, if set.
+	progress func(Progress)
 }
 
 func (d downloader) download() error {
@@ -74,7 +172,13 @@ func (d downloader) download() error {
 		return err
 	}
 
-	if strings.Contains(d.modelPath, "flair") {
+	isFlair := strings.Contains(d.modelPath, "flair")
+	d, err := d.pinRevision(d.probeFile())
+	if err != nil {
+		return err
+	}
+
+	if isFlair {
 		// Handling the case where there is no configuration file
 		return d.downloadModelSpecificFiles("flair")
 	}
@@ -89,6 +193,14 @@ func (d downloader) download() error {
 	return d.downloadModelSpecificFiles(config.ModelType)
 }
 
+// probeFile returns the first file to download, used to resolve the revision.
+func (d downloader) probeFile() string {
+	if strings.Contains(d.modelPath, "flair") {
+		return "pytorch_model.bin"
+	}
+	return models.DefaultModelConfigFilename
+}
+
 func (d downloader) ensureModelPath() error {
 	if info, err := os.Stat(d.modelPath); err == nil && info.IsDir() {
 		return nil
@@ -113,7 +225,7 @@ func (d downloader) downloadModelSpecificFiles(modelType string) error {
 	return nil
 }
 
-func (d downloader) downloadFile(name string) (err error) {
+func (d downloader) downloadFile(name string) error {
 	fPath := filepath.Join(d.modelPath, name)
 	if info, err := os.Stat(fPath); !d.overwriteIfExist && err == nil && !info.IsDir() {
 		log.Debug().Str("file", fPath).Msg("model file already exists, skipping download")
@@ -123,52 +235,36 @@ func (d downloader) downloadFile(name string) (err error) {
 	url := d.bucketURL(name)
 	log.Debug().Str("url", url).Str("destination", fPath).Msg("downloading")
 
-	f, err := os.Create(fPath)
-	if err != nil {
-		return fmt.Errorf("error creating file %#v: %w", fPath, err)
-	}
-	defer func() {
-		if e := f.Close(); e != nil && err == nil {
-			err = fmt.Errorf("error closing file %#v: %w", fPath, e)
-		}
-	}()
-
-	resp, err := d.httpGet(url)
-	if err != nil {
-		return fmt.Errorf("error getting %#v: %w", url, err)
-	}
-	defer func() {
-		if e := resp.Body.Close(); e != nil && err == nil {
-			err = fmt.Errorf("error closing %#v response body: %w", url, e)
-		}
-	}()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("%#v responded with %s", url, resp.Status)
-	}
-
-	prog := newDownloadProgress(int(resp.ContentLength))
-	prog.Start()
-	defer prog.Stop()
-
-	_, err = io.Copy(f, io.TeeReader(resp.Body, prog))
-	if err != nil {
+	if err := d.fetch(url, fPath); err != nil {
 		return fmt.Errorf("error downloading %#v to %#v: %w", url, fPath, err)
 	}
 	return nil
 }
 
-func (d downloader) httpGet(url string) (*http.Response, error) {
-	req, err := http.NewRequest("GET", url, nil)
+// context returns the context bounding the download, the background one
+// if none.
+func (d downloader) context() context.Context {
+	if d.ctx == nil {
+		return context.Background()
+	}
+	return d.ctx
+}
+
+func (d downloader) newRequest(method, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(d.context(), method, url, nil)
 	if err != nil {
 		return nil, err
 	}
 	if d.accessToken != "" {
 		req.Header.Set("Authorization", "Bearer "+d.accessToken)
 	}
-	return http.DefaultClient.Do(req)
+	return req, nil
 }
 
 func (d downloader) bucketURL(fileName string) string {
-	return fmt.Sprintf(huggingFaceCoPrefix, d.modelName, defaultRevision, fileName)
+	revision := d.commit
+	if revision == "" {
+		revision = d.revision
+	}
+	return fmt.Sprintf(huggingFaceCoPrefix, d.endpoint, d.modelName, url.PathEscape(revision), fileName)
 }
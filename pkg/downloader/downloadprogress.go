@@ -5,22 +5,83 @@
 package downloader
 
 import (
+	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
 )
 
+// Progress is the progress of the download of a file of a model.
+type Progress struct {
+	// Model is the name of the model.
+	Model string `json:"model"`
+	// File is the path of the file, relative to the model directory.
+	File string `json:"file"`
+	// Downloaded is the number of bytes downloaded, the ones of a resumed
+	// download included.
+	Downloaded int64 `json:"downloaded"`
+	// Total is the size of the file, or -1 if unknown.
+	Total int64 `json:"total"`
+	// ETA is the estimated time left, from the average rate of the
+	// download so far, or zero if unknown.
+	ETA time.Duration `json:"eta,omitempty"`
+	// Done is whether the download of the file is over, completed or not.
+	Done bool `json:"done"`
+}
+
+// MarshalJSON encodes the progress with its ETA as a string, e.g. "1m30s",
+// and its percentage, if known.
+func (p Progress) MarshalJSON() ([]byte, error) {
+	type progress Progress
+	v := struct {
+		progress
+		ETA     string   `json:"eta,omitempty"`
+		Percent *float64 `json:"percent,omitempty"`
+	}{progress: progress(p)}
+	if p.ETA > 0 {
+		v.ETA = p.ETA.String()
+	}
+	if pct := p.Percent(); pct >= 0 {
+		v.Percent = &pct
+	}
+	return json.Marshal(v)
+}
+
+// Percent returns the percentage of the file downloaded, or -1 if its size
+// is unknown.
+func (p Progress) Percent() float64 {
+	if p.Total < 0 {
+		return -1
+	}
+	if p.Total == 0 {
+		return 100
+	}
+	return float64(p.Downloaded) * 100 / float64(p.Total)
+}
+
 // downloadProgress is a helper struct for reporting download progress.
 type downloadProgress struct {
 	contentLength     int
 	readContentLength int
 	stopCh            chan struct{}
 	wg                sync.WaitGroup
+	mu                sync.Mutex
+	// report is called with the progress, if set, every
+	// downloadProgressReportFrequency instead of logging it only.
+	report func(Progress)
+	// start and startLength are the time and the content length at the
+	// start of the download, to estimate its rate.
+	start       time.Time
+	startLength int
 }
 
-const downloadProgressUpdateFrequency = 3 * time.Second
+const (
+	downloadProgressUpdateFrequency = 3 * time.Second
+	downloadProgressReportFrequency = 500 * time.Millisecond
+)
 
 func newDownloadProgress(contentLength int) *downloadProgress {
 	return &downloadProgress{
@@ -32,6 +93,10 @@ func newDownloadProgress(contentLength int) *downloadProgress {
 
 // Start starts the progress reporting goroutine.
 func (dp *downloadProgress) Start() {
+	dp.mu.Lock()
+	dp.start = time.Now()
+	dp.startLength = dp.readContentLength
+	dp.mu.Unlock()
 	dp.stopCh = make(chan struct{}, 1)
 	dp.wg.Add(1)
 	go dp.goRoutine()
@@ -46,23 +111,56 @@ func (dp *downloadProgress) Stop() {
 
 func (dp *downloadProgress) goRoutine() {
 	stopCh := dp.stopCh
+	frequency := downloadProgressUpdateFrequency
+	if dp.report != nil {
+		frequency = downloadProgressReportFrequency
+	}
+	ticker := time.NewTicker(frequency)
+	defer ticker.Stop()
+	lastLog := time.Now()
 
 	for {
 		select {
 		case <-stopCh:
-			dp.reportProgress()
+			dp.logProgress()
+			dp.reportProgress(true)
 			close(stopCh)
 			dp.wg.Done()
 			return
-		case <-time.After(downloadProgressUpdateFrequency):
-			dp.reportProgress()
+		case <-ticker.C:
+			if time.Since(lastLog) >= downloadProgressUpdateFrequency {
+				dp.logProgress()
+				lastLog = time.Now()
+			}
+			dp.reportProgress(false)
 		}
 	}
 }
 
-func (dp *downloadProgress) reportProgress() {
+// reportProgress calls the report function with the progress, if set.
+func (dp *downloadProgress) reportProgress(done bool) {
+	if dp.report == nil {
+		return
+	}
+	dp.mu.Lock()
+	p := Progress{
+		Downloaded: int64(dp.readContentLength),
+		Total:      int64(dp.contentLength),
+		Done:       done,
+	}
+	elapsed, read := time.Since(dp.start), dp.readContentLength-dp.startLength
+	dp.mu.Unlock()
+	if left := p.Total - p.Downloaded; !done && p.Total >= 0 && read > 0 && left > 0 {
+		p.ETA = time.Duration(float64(elapsed) * float64(left) / float64(read)).Round(time.Second)
+	}
+	dp.report(p)
+}
+
+func (dp *downloadProgress) logProgress() {
+	dp.mu.Lock()
 	cl := dp.contentLength
 	rcl := dp.readContentLength
+	dp.mu.Unlock()
 	hrcl := humanizeBytesSize(rcl)
 
 	switch {
@@ -78,11 +176,20 @@ func (dp *downloadProgress) reportProgress() {
 }
 
 // Write satisfies io.Writer interface.
+// It is safe to use concurrently, for parallel downloads.
 func (dp *downloadProgress) Write(p []byte) (int, error) {
-	dp.readContentLength += len(p)
+	dp.Add(len(p))
 	return len(p), nil
 }
 
+// Add adds n bytes to the downloaded content length, e.g. when a download
+// is resumed. A negative n is subtracted, e.g. when a download restarts.
+func (dp *downloadProgress) Add(n int) {
+	dp.mu.Lock()
+	dp.readContentLength += n
+	dp.mu.Unlock()
+}
+
 func humanizeBytesSize(n int) string {
 	switch {
 	case n < 1024:
@@ -95,3 +202,20 @@ func humanizeBytesSize(n int) string {
 		return fmt.Sprintf("%.2f GiB", float64(n)/1_073_741_824)
 	}
 }
+
+// reportFunc returns the function reporting the progress of the download
+// of the file to the Progress option, if set.
+func (d downloader) reportFunc(dest string) func(Progress) {
+	if d.progress == nil {
+		return nil
+	}
+	file, err := filepath.Rel(d.modelPath, dest)
+	if err != nil {
+		file = filepath.Base(dest)
+	}
+	file = filepath.ToSlash(file)
+	return func(p Progress) {
+		p.Model, p.File = d.modelName, file
+		d.progress(p)
+	}
+}
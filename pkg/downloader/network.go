@@ -0,0 +1,110 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// DefaultEndpoint is the default endpoint of the Hugging Face Hub.
+const DefaultEndpoint = "https://huggingface.co"
+
+// caBundleEnvVars are the environment variables commonly used to provide
+// a custom CA bundle, in order of priority. SSL_CERT_FILE is already
+// honored by the Go standard library on Unix systems.
+var caBundleEnvVars = []string{"REQUESTS_CA_BUNDLE", "CURL_CA_BUNDLE"}
+
+// resolveEndpoint returns the endpoint to use: the given one, if any, or the
+// value of the HF_ENDPOINT environment variable, or DefaultEndpoint.
+func resolveEndpoint(endpoint string) string {
+	if endpoint == "" {
+		endpoint = os.Getenv("HF_ENDPOINT")
+	}
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	return strings.TrimSuffix(endpoint, "/")
+}
+
+// newHTTPClients returns the HTTP clients used to download files and to
+// read their metadata (without following redirections).
+//
+// If proxy is empty, the proxy is read from the HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY environment variables. Both HTTP(S) and SOCKS5 proxies are
+// supported (e.g. "http://proxy:3128", "socks5://proxy:1080").
+// If caBundle is empty, it's read from the REQUESTS_CA_BUNDLE and
+// CURL_CA_BUNDLE environment variables. The certificates of the CA bundle
+// are trusted in addition to the system ones.
+func newHTTPClients(proxy, caBundle string) (client, noRedirect *http.Client, err error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid proxy URL %#v: %w", proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	for _, key := range caBundleEnvVars {
+		if caBundle != "" {
+			break
+		}
+		caBundle = os.Getenv(key)
+	}
+	if caBundle != "" {
+		pool, err := loadCABundle(caBundle)
+		if err != nil {
+			return nil, nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	client = &http.Client{Transport: transport}
+	noRedirect = &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return client, noRedirect, nil
+}
+
+func loadCABundle(filename string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no valid certificates found in CA bundle %#v", filename)
+	}
+	return pool, nil
+}
+
+// httpClient returns the client to download files.
+func (d downloader) httpClient() *http.Client {
+	if d.client != nil {
+		return d.client
+	}
+	return http.DefaultClient
+}
+
+// headClient returns the client to read the metadata of the files.
+func (d downloader) headClient() *http.Client {
+	if d.noRedirectClient != nil {
+		return d.noRedirectClient
+	}
+	return defaultNoRedirectClient
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/models"
+)
+
+// ErrMissingFiles is returned in offline mode when some files of the model
+// are not in the local cache.
+var ErrMissingFiles = errors.New("model files missing from the local cache")
+
+// weightsAlternatives are the files that make the PyTorch weights
+// unnecessary: the safetensors checkpoint and the converted model.
+var weightsAlternatives = []string{"model.safetensors", "spago_model.bin"}
+
+// IsOfflineEnv reports whether the offline mode is enabled by the
+// HF_HUB_OFFLINE environment variable, as for the Hugging Face tools.
+func IsOfflineEnv() bool {
+	switch strings.ToLower(os.Getenv("HF_HUB_OFFLINE")) {
+	case "1", "true", "yes", "on":
+		return true
+	default:
+		return false
+	}
+}
+
+// MissingFiles returns the files of the model which are not in the
+// model's directory, without any network access.
+func MissingFiles(modelsDir, modelName string) ([]string, error) {
+	return downloader{modelPath: filepath.Join(modelsDir, modelName), modelName: modelName}.missingFiles()
+}
+
+func (d downloader) missingFiles() ([]string, error) {
+	modelType := "flair"
+	if !strings.Contains(d.modelPath, "flair") {
+		if !fileExists(filepath.Join(d.modelPath, models.DefaultModelConfigFilename)) {
+			// Without the configuration, the other files are unknown.
+			return []string{models.DefaultModelConfigFilename}, nil
+		}
+		config, err := models.ReadCommonModelConfig(d.modelPath, "")
+		if err != nil {
+			return nil, err
+		}
+		modelType = config.ModelType
+	}
+	filenames, isSupported := supportedModelsFiles[modelType]
+	if !isSupported {
+		return nil, fmt.Errorf("unsupported model type: %#v", modelType)
+	}
+
+	var missing []string
+	for _, name := range filenames {
+		if fileExists(filepath.Join(d.modelPath, name)) || (name == "pytorch_model.bin" && d.hasWeightsAlternative()) {
+			continue
+		}
+		missing = append(missing, name)
+	}
+	return missing, nil
+}
+
+func (d downloader) hasWeightsAlternative() bool {
+	for _, name := range weightsAlternatives {
+		if fileExists(filepath.Join(d.modelPath, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkLocalFiles fails if any file of the model is missing.
+func (d downloader) checkLocalFiles() error {
+	missing, err := d.missingFiles()
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s in %#v (offline mode)", ErrMissingFiles, strings.Join(missing, ", "), d.modelPath)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	modelPath := filepath.Join(dir, "org", "model")
+	require.NoError(t, os.MkdirAll(modelPath, 0755))
+
+	missing, err := MissingFiles(dir, "org/model")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"config.json"}, missing)
+
+	require.NoError(t, os.WriteFile(filepath.Join(modelPath, "config.json"), []byte(`{"model_type": "bert"}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(modelPath, "vocab.txt"), []byte("[PAD]"), 0644))
+	missing, err = MissingFiles(dir, "org/model")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pytorch_model.bin", "tokenizer_config.json"}, missing)
+
+	require.NoError(t, os.WriteFile(filepath.Join(modelPath, "spago_model.bin"), nil, 0644))
+	err = DownloadWithOptions(dir, "org/model", Options{Offline: true})
+	assert.ErrorIs(t, err, ErrMissingFiles)
+	assert.Contains(t, err.Error(), "tokenizer_config.json")
+	assert.NotContains(t, err.Error(), "pytorch_model.bin")
+
+	require.NoError(t, os.WriteFile(filepath.Join(modelPath, "tokenizer_config.json"), []byte("{}"), 0644))
+	assert.NoError(t, DownloadWithOptions(dir, "org/model", Options{Offline: true}))
+}
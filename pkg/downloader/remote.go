@@ -0,0 +1,133 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// treeURL is the URL of the Hub API listing the files of a repository, in
+// the format: "{endpoint}/api/models/{model_id}/tree/{revision}".
+const treeURL = "%s/api/models/%s/tree/%s?recursive=true"
+
+// RemoteFile is a file of a model repository on the Hub.
+type RemoteFile struct {
+	// Name is the path of the file in the repository.
+	Name string `json:"path"`
+	// Size is the size of the file in bytes.
+	Size int64 `json:"size"`
+	// Type is "file" or "directory".
+	Type string `json:"type"`
+}
+
+// SupportedModelTypes returns the model types which can be downloaded and
+// converted.
+func SupportedModelTypes() []string {
+	types := make([]string, 0, len(supportedModelsFiles))
+	for t := range supportedModelsFiles {
+		types = append(types, t)
+	}
+	return types
+}
+
+// ListFiles returns the files of the model at the revision (default
+// "main") on the Hub, without downloading them. The models directory is
+// ignored.
+func ListFiles(modelName string, opts Options) ([]RemoteFile, error) {
+	if opts.Offline || IsOfflineEnv() {
+		return nil, errors.New("cannot list the files in offline mode")
+	}
+	d, err := newDownloader("", modelName, opts)
+	if err != nil {
+		return nil, err
+	}
+	var files []RemoteFile
+	next := fmt.Sprintf(treeURL, d.endpoint, d.modelName, url.PathEscape(d.revision))
+	for next != "" {
+		var page []RemoteFile
+		next, err = d.getJSON(next, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range page {
+			if f.Type == "file" {
+				files = append(files, f)
+			}
+		}
+	}
+	return files, nil
+}
+
+// OpenFile opens a file of the model at the revision (default "main") on
+// the Hub, streaming its content, so that only the part which is read is
+// downloaded. The models directory is ignored.
+func OpenFile(modelName, filename string, opts Options) (io.ReadCloser, error) {
+	if opts.Offline || IsOfflineEnv() {
+		return nil, errors.New("cannot open the file in offline mode")
+	}
+	d, err := newDownloader("", modelName, opts)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.get(d.bucketURL(filename))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// getJSON decodes the JSON response of the URL into v, and returns the URL
+// of the next page, if any.
+func (d downloader) getJSON(url string, v any) (string, error) {
+	resp, err := d.get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", fmt.Errorf("error decoding %#v: %w", url, err)
+	}
+	return nextPageURL(resp.Header.Get("Link")), nil
+}
+
+// get sends a GET request to the URL, failing if the status is not
+// successful.
+func (d downloader) get(url string) (*http.Response, error) {
+	req, err := d.newRequest("GET", url)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s", ErrAccessDenied, d.accessDeniedReason(resp))
+	case resp.StatusCode >= 400:
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("%#v responded with %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+// nextPageURL returns the URL of the next page from the Link header of a
+// paginated response, if any.
+func nextPageURL(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(part, ";")
+		if ok && strings.Contains(params, `rel="next"`) {
+			return strings.Trim(strings.TrimSpace(target), "<>")
+		}
+	}
+	return ""
+}
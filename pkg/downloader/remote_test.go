@@ -0,0 +1,63 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListFiles(t *testing.T) {
+	var s *httptest.Server
+	s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/models/org/model/tree/v1.0", r.URL.Path)
+		if r.URL.Query().Get("cursor") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?recursive=true&cursor=2>; rel="next"`, s.URL, r.URL.Path))
+			fmt.Fprint(w, `[{"type":"file","path":"config.json","size":10},{"type":"directory","path":"onnx","size":0}]`)
+			return
+		}
+		fmt.Fprint(w, `[{"type":"file","path":"onnx/model.onnx","size":2048}]`)
+	}))
+	defer s.Close()
+
+	files, err := ListFiles("org/model", Options{Endpoint: s.URL, Revision: "v1.0"})
+	require.NoError(t, err)
+	assert.Equal(t, []RemoteFile{
+		{Name: "config.json", Size: 10, Type: "file"},
+		{Name: "onnx/model.onnx", Size: 2048, Type: "file"},
+	}, files)
+}
+
+func TestOpenFile(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/org/model/resolve/main/config.json":
+			fmt.Fprint(w, `{"model_type": "bert"}`)
+		case "/org/private/resolve/main/config.json":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+
+	f, err := OpenFile("org/model", "config.json", Options{Endpoint: s.URL})
+	require.NoError(t, err)
+	content, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, `{"model_type": "bert"}`, string(content))
+
+	_, err = OpenFile("org/private", "config.json", Options{Endpoint: s.URL})
+	assert.ErrorIs(t, err, ErrAccessDenied)
+	_, err = OpenFile("org/model", "missing.json", Options{Endpoint: s.URL})
+	assert.Error(t, err)
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+)
+
+// MetadataFilename is the name of the file, in the model's directory,
+// recording the revision of the downloaded files.
+const MetadataFilename = "download_metadata.json"
+
+// Metadata is the download metadata of a model.
+type Metadata struct {
+	// Revision is the requested branch, tag or commit SHA.
+	Revision string `json:"revision"`
+	// Commit is the commit SHA the revision resolved to.
+	Commit string `json:"commit"`
+}
+
+// ReadMetadata reads the download metadata from the model's directory.
+// If the file doesn't exist, the returned error satisfies os.IsNotExist.
+func ReadMetadata(modelPath string) (*Metadata, error) {
+	data, err := os.ReadFile(filepath.Join(modelPath, MetadataFilename))
+	if err != nil {
+		return nil, err
+	}
+	m := &Metadata{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("invalid download metadata: %w", err)
+	}
+	return m, nil
+}
+
+func writeMetadata(modelPath string, m *Metadata) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(modelPath, MetadataFilename), data, 0644)
+}
+
+// pinRevision returns a downloader pinned to the commit of the requested
+// revision: the one recorded in the model's directory, if any, or the
+// current one on the Hub, which is then recorded.
+func (d downloader) pinRevision(probeFile string) (downloader, error) {
+	m, err := ReadMetadata(d.modelPath)
+	if err != nil && !os.IsNotExist(err) {
+		return d, err
+	}
+
+	if !d.overwriteIfExist {
+		if m != nil {
+			if m.Revision != d.revision {
+				return d, fmt.Errorf("model %#v was downloaded at revision %#v, not %#v: download it again to replace it", d.modelName, m.Revision, d.revision)
+			}
+			d.commit = m.Commit
+			return d, nil
+		}
+		if d.revision != defaultRevision && fileExists(filepath.Join(d.modelPath, probeFile)) {
+			return d, fmt.Errorf("model %#v exists with an unknown revision, not %#v: download it again to replace it", d.modelName, d.revision)
+		}
+	}
+
+	commit, err := d.resolveCommit(probeFile)
+	if err != nil && d.revision == defaultRevision && !errors.Is(err, ErrAccessDenied) {
+		// Keep working with models downloaded before the revision was recorded,
+		// without network access.
+		log.Warn().Err(err).Str("model", d.modelName).Msg("failed to resolve revision, downloading it unpinned")
+		return d, nil
+	}
+	if err != nil {
+		return d, err
+	}
+	if err = writeMetadata(d.modelPath, &Metadata{Revision: d.revision, Commit: commit}); err != nil {
+		return d, err
+	}
+	d.commit = commit
+	return d, nil
+}
+
+// ResolveRevision returns the commit SHA the revision of the model (default
+// "main") currently resolves to on the Hub, without downloading anything.
+// The models directory is ignored.
+func ResolveRevision(modelName string, opts Options) (string, error) {
+	if opts.Offline || IsOfflineEnv() {
+		return "", errors.New("cannot resolve the revision in offline mode")
+	}
+	d, err := newDownloader("", modelName, opts)
+	if err != nil {
+		return "", err
+	}
+	return d.resolveCommit(d.probeFile())
+}
+
+// resolveCommit returns the commit SHA of the revision, as reported by the
+// Hub for the given file. If the Hub doesn't report it, the revision itself
+// is returned.
+func (d downloader) resolveCommit(filename string) (string, error) {
+	url := d.bucketURL(filename)
+	req, err := d.newRequest("HEAD", url)
+	if err != nil {
+		return "", err
+	}
+	resp, err := d.headClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error resolving revision %#v: %w", d.revision, err)
+	}
+	_ = resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return "", fmt.Errorf("%w: %s", ErrAccessDenied, d.accessDeniedReason(resp))
+	case resp.StatusCode >= 400:
+		return "", fmt.Errorf("error resolving revision %#v: %#v responded with %s", d.revision, url, resp.Status)
+	}
+	if commit := resp.Header.Get("X-Repo-Commit"); commit != "" {
+		return commit, nil
+	}
+	return d.revision, nil
+}
+
+func fileExists(filename string) bool {
+	info, err := os.Stat(filename)
+	return err == nil && !info.IsDir()
+}
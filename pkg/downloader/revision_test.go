@@ -0,0 +1,88 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinRevision(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, writeMetadata(dir, &Metadata{Revision: "v1.0", Commit: "0123abcd"}))
+
+	d, err := downloader{endpoint: DefaultEndpoint, modelPath: dir, modelName: "org/model", revision: "v1.0"}.pinRevision("config.json")
+	require.NoError(t, err)
+	assert.Equal(t, "0123abcd", d.commit)
+	assert.Equal(t, "https://huggingface.co/org/model/resolve/0123abcd/config.json", d.bucketURL("config.json"))
+
+	_, err = downloader{modelPath: dir, modelName: "org/model", revision: "main"}.pinRevision("config.json")
+	assert.Error(t, err)
+}
+
+func TestPinRevision_UnknownRevision(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte("{}"), 0644))
+
+	_, err := downloader{modelPath: dir, modelName: "org/model", revision: "v1.0"}.pinRevision("config.json")
+	assert.Error(t, err)
+}
+
+func TestDownloadWithOptions_Endpoint(t *testing.T) {
+	files := map[string]string{
+		"config.json":           `{"model_type": "bert"}`,
+		"pytorch_model.bin":     "weights",
+		"vocab.txt":             "[PAD]",
+		"tokenizer_config.json": "{}",
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/org/model/resolve/")
+		revision, name, _ := strings.Cut(name, "/")
+		content, ok := files[name]
+		if !ok || (revision != "v1.0" && revision != "0123abcd") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Repo-Commit", "0123abcd")
+		http.ServeContent(w, r, name, time.Time{}, strings.NewReader(content))
+	}))
+	defer s.Close()
+
+	dir := t.TempDir()
+	err := DownloadWithOptions(dir, "org/model", Options{Endpoint: s.URL + "/", Revision: "v1.0"})
+	require.NoError(t, err)
+	for name, content := range files {
+		actual, err := os.ReadFile(filepath.Join(dir, "org", "model", name))
+		require.NoError(t, err)
+		assert.Equal(t, content, string(actual))
+	}
+	m, err := ReadMetadata(filepath.Join(dir, "org", "model"))
+	require.NoError(t, err)
+	assert.Equal(t, &Metadata{Revision: "v1.0", Commit: "0123abcd"}, m)
+}
+
+func TestResolveRevision(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "HEAD", r.Method)
+		assert.Equal(t, "/org/model/resolve/main/config.json", r.URL.Path)
+		w.Header().Set("X-Repo-Commit", "4567cdef")
+	}))
+	defer s.Close()
+
+	commit, err := ResolveRevision("org/model", Options{Endpoint: s.URL})
+	require.NoError(t, err)
+	assert.Equal(t, "4567cdef", commit)
+
+	_, err = ResolveRevision("org/model", Options{Endpoint: s.URL, Offline: true})
+	assert.Error(t, err)
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrAccessDenied is returned when the Hub denies the access to a file,
+// typically because the model is private or gated and no valid access
+// token has been provided.
+var ErrAccessDenied = errors.New("access denied by the Hugging Face Hub")
+
+// tokenEnvVars are the environment variables used by the Hugging Face tools
+// to provide the access token, in order of priority.
+var tokenEnvVars = []string{"HF_TOKEN", "HUGGING_FACE_HUB_TOKEN"}
+
+// ResolveAccessToken returns the access token to use for the Hugging Face Hub.
+//
+// If the given token is empty, it falls back to the HF_TOKEN and
+// HUGGING_FACE_HUB_TOKEN environment variables, and then to the token
+// stored by `huggingface-cli login` ("$HF_HOME/token", by default
+// "~/.cache/huggingface/token"). It returns an empty string if no token
+// is found, which is fine for public models.
+func ResolveAccessToken(token string) string {
+	if token != "" {
+		return token
+	}
+	for _, key := range tokenEnvVars {
+		if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+			return v
+		}
+	}
+	if filename := tokenFilename(); filename != "" {
+		if data, err := os.ReadFile(filename); err == nil {
+			return strings.TrimSpace(string(data))
+		}
+	}
+	return ""
+}
+
+func tokenFilename() string {
+	if home := os.Getenv("HF_HOME"); home != "" {
+		return filepath.Join(home, "token")
+	}
+	cache, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cache, ".cache", "huggingface", "token")
+}
+
+// accessDeniedReason returns a hint about why the Hub denied the access,
+// based on the response.
+func (d downloader) accessDeniedReason(resp *http.Response) string {
+	switch {
+	case resp.Header.Get("X-Error-Code") == "GatedRepo":
+		return "the model is gated: accept its conditions on huggingface.co with the account of the access token"
+	case d.accessToken == "":
+		return "the model may be private or gated: provide an access token"
+	default:
+		return "the access token is invalid or has no access to the model"
+	}
+}
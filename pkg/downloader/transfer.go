@@ -0,0 +1,378 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultParallelism is the default maximum number of chunks downloaded in parallel.
+	defaultParallelism = 4
+	// defaultChunkSize is the default minimum size of a chunk downloaded in parallel.
+	defaultChunkSize int64 = 64 << 20
+	// maxRetries is the maximum number of retries of a failed chunk download.
+	maxRetries = 5
+)
+
+// retryBaseDelay is the delay before the first retry, doubled at each attempt.
+var retryBaseDelay = time.Second
+
+// defaultNoRedirectClient is used to read the metadata of the files, which
+// the Hub sends along with the redirection to the storage of large files.
+var defaultNoRedirectClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// fileMetadata is the metadata of a remote file.
+type fileMetadata struct {
+	// size is the size of the file, or -1 if unknown.
+	size int64
+	// etag is the ETag of the file. For the files stored with Git LFS, the
+	// Hub reports the SHA-256 of the content, for the other ones the Git
+	// blob SHA-1.
+	etag string
+	// acceptRanges reports whether the file can be downloaded with range requests.
+	acceptRanges bool
+}
+
+// chunk is a byte range of a file, downloaded to its own part file.
+type chunk struct {
+	path  string
+	start int64
+	// end is the end of the range (exclusive), or -1 if unknown.
+	end int64
+	// whole reports whether the chunk is the whole file.
+	whole bool
+}
+
+// permanentError is an error which is not worth retrying.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// fetch downloads the file at the given URL to dest.
+func (d downloader) fetch(url, dest string) error {
+	meta, err := d.head(url)
+	if errors.Is(err, ErrAccessDenied) {
+		return err
+	}
+	if err != nil {
+		log.Debug().Err(err).Str("url", url).Msg("failed to read file metadata")
+		meta = fileMetadata{size: -1}
+	}
+
+	chunks := d.chunks(dest, meta)
+	prog := newDownloadProgress(int(meta.size))
+	prog.report = d.reportFunc(dest)
+	for _, c := range chunks {
+		if info, err := os.Stat(c.path); err == nil {
+			prog.Add(int(info.Size()))
+		}
+	}
+	prog.Start()
+	err = d.fetchChunks(url, chunks, prog)
+	prog.Stop()
+	if err != nil {
+		return err
+	}
+
+	tmp := chunks[0].path
+	if err = joinChunks(chunks); err != nil {
+		return err
+	}
+	if err = verifyChecksum(tmp, meta.etag); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dest)
+}
+
+// head reads the metadata of the file at the given URL.
+func (d downloader) head(url string) (fileMetadata, error) {
+	meta := fileMetadata{size: -1}
+	req, err := d.newRequest("HEAD", url)
+	if err != nil {
+		return meta, err
+	}
+	resp, err := d.headClient().Do(req)
+	if err != nil {
+		return meta, err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return meta, fmt.Errorf("%w: %s", ErrAccessDenied, d.accessDeniedReason(resp))
+	}
+	if resp.StatusCode >= 400 {
+		return meta, fmt.Errorf("%#v responded with %s", url, resp.Status)
+	}
+
+	h := resp.Header
+	if v := h.Get("X-Linked-Etag"); v != "" {
+		// Large file stored with Git LFS, served through redirection.
+		meta.etag = v
+		meta.acceptRanges = true
+		if size, err := strconv.ParseInt(h.Get("X-Linked-Size"), 10, 64); err == nil {
+			meta.size = size
+		}
+	} else {
+		meta.etag = h.Get("ETag")
+		meta.acceptRanges = h.Get("Accept-Ranges") == "bytes"
+		if resp.StatusCode == http.StatusOK && resp.ContentLength >= 0 {
+			meta.size = resp.ContentLength
+		}
+	}
+	meta.etag = strings.Trim(strings.TrimPrefix(meta.etag, "W/"), `"`)
+	return meta, nil
+}
+
+// chunks splits the file in the chunks to download.
+func (d downloader) chunks(dest string, meta fileMetadata) []chunk {
+	n := 1
+	if meta.acceptRanges && meta.size > 0 && d.chunkSize > 0 {
+		n = int(meta.size / d.chunkSize)
+		if n > d.parallelism {
+			n = d.parallelism
+		}
+		if n < 1 {
+			n = 1
+		}
+	}
+	if n == 1 {
+		return []chunk{{path: dest + ".incomplete", start: 0, end: meta.size, whole: true}}
+	}
+
+	chunks := make([]chunk, n)
+	size := meta.size / int64(n)
+	for i := range chunks {
+		chunks[i] = chunk{
+			path:  fmt.Sprintf("%s.incomplete.%d-of-%d", dest, i+1, n),
+			start: int64(i) * size,
+			end:   int64(i+1) * size,
+		}
+	}
+	chunks[n-1].end = meta.size
+	return chunks
+}
+
+// fetchChunks downloads the chunks in parallel, returning the first error.
+func (d downloader) fetchChunks(url string, chunks []chunk, prog *downloadProgress) error {
+	errs := make([]error, len(chunks))
+	var wg sync.WaitGroup
+	for i, c := range chunks {
+		wg.Add(1)
+		go func(i int, c chunk) {
+			defer wg.Done()
+			errs[i] = d.fetchChunkWithRetry(url, c, prog)
+		}(i, c)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d downloader) fetchChunkWithRetry(url string, c chunk, prog *downloadProgress) (err error) {
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			delay := retryBaseDelay << (attempt - 1)
+			log.Debug().Err(err).Str("file", c.path).Dur("delay", delay).Msg("download failed, retrying")
+			t := time.NewTimer(delay)
+			select {
+			case <-d.context().Done():
+				t.Stop()
+				return d.context().Err()
+			case <-t.C:
+			}
+		}
+		err = d.fetchChunk(url, c, prog)
+		var perr *permanentError
+		if err == nil || errors.As(err, &perr) || d.context().Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// fetchChunk downloads the chunk, resuming from the content of its part file.
+func (d downloader) fetchChunk(url string, c chunk, prog *downloadProgress) (err error) {
+	f, err := os.OpenFile(c.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return &permanentError{fmt.Errorf("error creating file %#v: %w", c.path, err)}
+	}
+	defer func() {
+		if e := f.Close(); e != nil && err == nil {
+			err = fmt.Errorf("error closing file %#v: %w", c.path, e)
+		}
+	}()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	have := info.Size()
+	if c.end >= 0 && c.start+have > c.end {
+		// Left over by a previous download of a different version of the file.
+		prog.Add(-int(have))
+		if err = f.Truncate(0); err != nil {
+			return err
+		}
+		have = 0
+	}
+	if c.end >= 0 && c.start+have == c.end {
+		return nil
+	}
+
+	req, err := d.newRequest("GET", url)
+	if err != nil {
+		return &permanentError{err}
+	}
+	switch {
+	case c.end >= 0 && (!c.whole || have > 0):
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", c.start+have, c.end-1))
+	case have > 0:
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", have))
+	}
+	resp, err := d.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := resp.Body.Close(); e != nil && err == nil {
+			err = fmt.Errorf("error closing %#v response body: %w", url, e)
+		}
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+	case resp.StatusCode == http.StatusOK && c.whole:
+		// Range not supported: start over.
+		prog.Add(-int(have))
+		if err = f.Truncate(0); err != nil {
+			return err
+		}
+		have = 0
+	case resp.StatusCode == http.StatusOK:
+		return &permanentError{fmt.Errorf("%#v doesn't support range requests", url)}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		prog.Add(-int(have))
+		if err = f.Truncate(0); err != nil {
+			return err
+		}
+		return fmt.Errorf("%#v responded with %s", url, resp.Status)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return &permanentError{fmt.Errorf("%w: %s", ErrAccessDenied, d.accessDeniedReason(resp))}
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("%#v responded with %s", url, resp.Status)
+	default:
+		return &permanentError{fmt.Errorf("%#v responded with %s", url, resp.Status)}
+	}
+
+	n, err := io.Copy(f, io.TeeReader(resp.Body, prog))
+	if err != nil {
+		return err
+	}
+	if c.end >= 0 && c.start+have+n != c.end {
+		return fmt.Errorf("incomplete download of %#v: %d of %d bytes", c.path, have+n, c.end-c.start)
+	}
+	return nil
+}
+
+// joinChunks appends the content of the chunks to the first one.
+func joinChunks(chunks []chunk) (err error) {
+	if len(chunks) == 1 {
+		return nil
+	}
+	f, err := os.OpenFile(chunks[0].path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := f.Close(); e != nil && err == nil {
+			err = e
+		}
+	}()
+	for _, c := range chunks[1:] {
+		if err = appendFile(f, c.path); err != nil {
+			return err
+		}
+		if err = os.Remove(c.path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendFile(w io.Writer, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// verifyChecksum verifies the content of the file against the ETag, if it
+// is a SHA-256 (Git LFS files) or a Git blob SHA-1 (regular files).
+func verifyChecksum(filename, etag string) error {
+	var h hash.Hash
+	switch {
+	case isHex(etag, sha256.Size):
+		h = sha256.New()
+	case isHex(etag, sha1.Size):
+		h = sha1.New()
+		info, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "blob %d\x00", info.Size())
+	default:
+		log.Debug().Str("file", filename).Msg("no checksum available, skipping verification")
+		return nil
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err = io.Copy(h, f); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != strings.ToLower(etag) {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", etag, sum)
+	}
+	return nil
+}
+
+func isHex(s string, size int) bool {
+	if len(s) != size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, content []byte, failures int32) (*httptest.Server, *int32) {
+	sum := sha256.Sum256(content)
+	var requests int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && atomic.AddInt32(&requests, 1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(s.Close)
+	return s, &requests
+}
+
+func testContent(n int) []byte {
+	content := make([]byte, n)
+	for i := range content {
+		content[i] = byte(i * 7)
+	}
+	return content
+}
+
+// setRetryBaseDelay sets the base delay of the retries for the test.
+func setRetryBaseDelay(t *testing.T, d time.Duration) {
+	old := retryBaseDelay
+	t.Cleanup(func() { retryBaseDelay = old })
+	retryBaseDelay = d
+}
+
+func TestFetch_ParallelWithRetry(t *testing.T) {
+	setRetryBaseDelay(t, time.Millisecond)
+	content := testContent(10_000)
+	s, requests := newTestServer(t, content, 2)
+
+	dest := filepath.Join(t.TempDir(), "file")
+	d := downloader{parallelism: 4, chunkSize: 1000}
+	require.NoError(t, d.fetch(s.URL, dest))
+
+	actual, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, content, actual)
+	assert.Equal(t, int32(6), atomic.LoadInt32(requests))
+
+	matches, err := filepath.Glob(dest + ".incomplete*")
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestFetch_Resume(t *testing.T) {
+	content := testContent(5000)
+	s, _ := newTestServer(t, content, 0)
+
+	dest := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(dest+".incomplete", content[:1234], 0644))
+
+	d := downloader{parallelism: 1, chunkSize: defaultChunkSize}
+	require.NoError(t, d.fetch(s.URL, dest))
+
+	actual, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, content, actual)
+}
+
+func TestFetch_Progress(t *testing.T) {
+	content := testContent(5000)
+	s, _ := newTestServer(t, content, 0)
+
+	modelPath := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(modelPath, "dir"), 0o755))
+	var mu sync.Mutex
+	var reports []Progress
+	d := downloader{parallelism: 2, chunkSize: 1000, modelPath: modelPath, modelName: "org/model",
+		progress: func(p Progress) {
+			mu.Lock()
+			reports = append(reports, p)
+			mu.Unlock()
+		}}
+	require.NoError(t, d.fetch(s.URL, filepath.Join(modelPath, "dir", "file")))
+
+	require.NotEmpty(t, reports)
+	last := reports[len(reports)-1]
+	assert.Equal(t, Progress{Model: "org/model", File: "dir/file", Downloaded: 5000, Total: 5000, Done: true}, last)
+	assert.Equal(t, float64(100), last.Percent())
+	data, err := json.Marshal(last)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"model":"org/model","file":"dir/file","downloaded":5000,"total":5000,"percent":100,"done":true}`, string(data))
+}
+
+func TestFetch_ChecksumMismatch(t *testing.T) {
+	content := testContent(5000)
+	s, _ := newTestServer(t, content, 0)
+
+	dest := filepath.Join(t.TempDir(), "file")
+	corrupted := bytes.Repeat([]byte{0}, 1234)
+	require.NoError(t, os.WriteFile(dest+".incomplete", corrupted, 0644))
+
+	d := downloader{parallelism: 1, chunkSize: defaultChunkSize}
+	assert.Error(t, d.fetch(s.URL, dest))
+	assert.NoFileExists(t, dest)
+	assert.NoFileExists(t, dest+".incomplete")
+}
+
+func TestFetch_Canceled(t *testing.T) {
+	setRetryBaseDelay(t, time.Hour)
+	s, _ := newTestServer(t, testContent(1000), 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	d := downloader{ctx: ctx, parallelism: 1, chunkSize: defaultChunkSize}
+	assert.ErrorIs(t, d.fetch(s.URL, filepath.Join(t.TempDir(), "file")), context.Canceled)
+}
+
+func TestFetch_AccessDenied(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("X-Error-Code", "GatedRepo")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader([]byte("content")))
+	}))
+	defer s.Close()
+
+	dest := filepath.Join(t.TempDir(), "file")
+	err := downloader{}.fetch(s.URL, dest)
+	assert.ErrorIs(t, err, ErrAccessDenied)
+
+	require.NoError(t, downloader{accessToken: "secret"}.fetch(s.URL, dest))
+	assert.FileExists(t, dest)
+}
+
+func TestResolveAccessToken(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HF_HOME", home)
+	t.Setenv("HF_TOKEN", "")
+	t.Setenv("HUGGING_FACE_HUB_TOKEN", "")
+	assert.Equal(t, "", ResolveAccessToken(""))
+
+	require.NoError(t, os.WriteFile(filepath.Join(home, "token"), []byte("from-file\n"), 0600))
+	assert.Equal(t, "from-file", ResolveAccessToken(""))
+
+	t.Setenv("HUGGING_FACE_HUB_TOKEN", "from-env")
+	assert.Equal(t, "from-env", ResolveAccessToken(""))
+	assert.Equal(t, "explicit", ResolveAccessToken("explicit"))
+}
@@ -0,0 +1,208 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package embeddingcache implements a persistent, content-addressed cache
+// of text embeddings, so that the unchanged documents of repeated ingestion
+// runs are not embedded again, even across restarts.
+//
+// Each vector is stored in its own file, named after the hash of the model
+// revision, the pooling strategy and the text, as little-endian float32
+// values. The least recently used vectors are evicted once the cache
+// exceeds its maximum size.
+package embeddingcache
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultMaxSize is the default maximum size of the cache, in bytes.
+const DefaultMaxSize = 1 << 30
+
+// Cache is an on-disk cache of embeddings.
+type Cache struct {
+	dir     string
+	maxSize int64
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	size    int64
+}
+
+// entry is a vector of the cache.
+type entry struct {
+	size int64
+	used time.Time
+}
+
+// Open opens the cache in the directory, creating it if needed, with the
+// maximum size in bytes (default DefaultMaxSize).
+func Open(dir string, maxSize int64) (*Cache, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	c := &Cache{dir: dir, maxSize: maxSize, entries: make(map[string]*entry)}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() || len(d.Name()) != sha256.Size*2 {
+			return err // skipping the temporary files too
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		c.entries[d.Name()] = &entry{size: info.Size(), used: info.ModTime()}
+		c.size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embeddingcache: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.evict(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Key returns the key of the embedding of the text, computed by the model
+// revision with the pooling strategy.
+func Key(revision string, poolingStrategy int, text string) string {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%d:%s%d:", len(revision), revision, poolingStrategy)
+	_, _ = h.Write([]byte(text))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// path returns the filename of the key, in a sub-directory named after its
+// first two characters, to keep the directories small.
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// Get returns the vector of the key, and whether it's found.
+func (c *Cache) Get(key string) ([]float32, bool, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok {
+		e.used = time.Now()
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false, nil
+	}
+	data, err := os.ReadFile(c.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		c.forget(key)
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("embeddingcache: %w", err)
+	}
+	if len(data)%4 != 0 {
+		c.forget(key)
+		return nil, false, fmt.Errorf("embeddingcache: invalid vector file %#v", c.path(key))
+	}
+	// The modification time records the last use across restarts.
+	now := time.Now()
+	_ = os.Chtimes(c.path(key), now, now)
+	v := make([]float32, len(data)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return v, true, nil
+}
+
+// Put stores the vector of the key, evicting the least recently used
+// vectors if the cache exceeds its maximum size.
+func (c *Cache) Put(key string, v []float32) error {
+	data := make([]byte, len(v)*4)
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(x))
+	}
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("embeddingcache: %w", err)
+	}
+	// Written atomically, since the cache can be shared by several processes.
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("embeddingcache: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("embeddingcache: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.entries[key]; ok {
+		c.size -= old.size
+	}
+	c.entries[key] = &entry{size: int64(len(data)), used: time.Now()}
+	c.size += int64(len(data))
+	return c.evict()
+}
+
+// Size returns the total size of the vectors, in bytes.
+func (c *Cache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Len returns the number of vectors.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func (c *Cache) forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.size -= e.size
+		delete(c.entries, key)
+	}
+}
+
+// evict removes the least recently used vectors until the cache doesn't
+// exceed its maximum size. It must be called with the lock held.
+func (c *Cache) evict() error {
+	if c.size <= c.maxSize {
+		return nil
+	}
+	keys := make([]string, 0, len(c.entries))
+	for k := range c.entries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.entries[keys[i]].used.Before(c.entries[keys[j]].used)
+	})
+	for _, k := range keys {
+		if c.size <= c.maxSize {
+			break
+		}
+		if err := os.Remove(c.path(k)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("embeddingcache: %w", err)
+		}
+		c.size -= c.entries[k].size
+		delete(c.entries, k)
+	}
+	return nil
+}
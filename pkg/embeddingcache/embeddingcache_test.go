@@ -0,0 +1,87 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package embeddingcache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir, 20)
+	require.NoError(t, err)
+
+	k1, k2, k3 := Key("m@1", 0, "a"), Key("m@1", 0, "b"), Key("m@2", 0, "a")
+	assert.NotEqual(t, k1, k3)
+	assert.NotEqual(t, k1, Key("m@1", 1, "a"))
+
+	_, ok, err := c.Get(k1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.NoError(t, c.Put(k1, []float32{1, 2}))
+	require.NoError(t, c.Put(k2, []float32{3, 4}))
+	v, ok, err := c.Get(k1)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []float32{1, 2}, v)
+
+	// k2 is the least recently used.
+	time.Sleep(10 * time.Millisecond)
+	_, _, _ = c.Get(k1)
+	require.NoError(t, c.Put(k3, []float32{5, 6}))
+	assert.Equal(t, int64(16), c.Size())
+	_, ok, _ = c.Get(k2)
+	assert.False(t, ok)
+	_, err = os.Stat(filepath.Join(dir, k2[:2], k2))
+	assert.True(t, os.IsNotExist(err))
+
+	// The vectors survive a restart.
+	c, err = Open(dir, 20)
+	require.NoError(t, err)
+	assert.Equal(t, 2, c.Len())
+	v, ok, err = c.Get(k3)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []float32{5, 6}, v)
+
+	// A smaller cache is evicted on open.
+	c, err = Open(dir, 8)
+	require.NoError(t, err)
+	assert.Equal(t, 1, c.Len())
+}
+
+type countingEncoder struct {
+	calls int
+}
+
+func (e *countingEncoder) Encode(_ context.Context, text string, _ int) (textencoding.Response, error) {
+	e.calls++
+	return textencoding.Response{Vector: mat.NewVecDense([]float32{float32(len(text)), 1})}, nil
+}
+
+func TestEncoder(t *testing.T) {
+	c, err := Open(t.TempDir(), 0)
+	require.NoError(t, err)
+	enc := &countingEncoder{}
+	e := Wrap(enc, c, "m@1")
+	for i := 0; i < 2; i++ {
+		resp, err := e.Encode(context.Background(), "abc", 0)
+		require.NoError(t, err)
+		assert.Equal(t, []float32{3, 1}, resp.Vector.Data().F32())
+	}
+	assert.Equal(t, 1, enc.calls)
+	_, err = Wrap(enc, c, "m@2").Encode(context.Background(), "abc", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, enc.calls)
+}
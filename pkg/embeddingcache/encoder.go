@@ -0,0 +1,65 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package embeddingcache
+
+import (
+	"context"
+	"io"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/rs/zerolog/log"
+)
+
+// Encoder is a text encoder whose embeddings are cached.
+type Encoder struct {
+	encoder  textencoding.Interface
+	cache    *Cache
+	revision string
+}
+
+var _ textencoding.Interface = &Encoder{}
+
+// Wrap returns the encoder caching the embeddings of the model, identified
+// by the revision, e.g. its name and commit SHA, so that the embeddings of
+// different models, or of an updated one, are not mixed.
+func Wrap(encoder textencoding.Interface, cache *Cache, revision string) *Encoder {
+	return &Encoder{encoder: encoder, cache: cache, revision: revision}
+}
+
+// Encode returns the cached embedding of the text, or computes and caches
+// it. The cached vectors are float32. The failures of the cache are logged,
+// and the embedding computed as if missed.
+func (e *Encoder) Encode(ctx context.Context, text string, poolingStrategy int) (textencoding.Response, error) {
+	key := Key(e.revision, poolingStrategy, text)
+	v, ok, err := e.cache.Get(key)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to read cached embedding")
+	}
+	if ok {
+		return textencoding.Response{Vector: mat.NewVecDense(v)}, nil
+	}
+	resp, err := e.encoder.Encode(ctx, text, poolingStrategy)
+	if err != nil {
+		return resp, err
+	}
+	if err := e.cache.Put(key, resp.Vector.Data().F32()); err != nil {
+		log.Warn().Err(err).Msg("failed to cache embedding")
+	}
+	return resp, nil
+}
+
+// Unwrap returns the wrapped encoder.
+func (e *Encoder) Unwrap() textencoding.Interface {
+	return e.encoder
+}
+
+// Close closes the wrapped encoder, if it's an io.Closer.
+func (e *Encoder) Close() error {
+	if c, ok := e.encoder.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package errdefs defines the errors of the tasks and of the server, each
+// identified by a stable code, which the server sends along with the gRPC
+// status, and in the JSON error bodies, so that the clients can tell the
+// failures apart with errors.Is, whatever their messages.
+package errdefs
+
+import "errors"
+
+// Domain is the domain of the codes in the error details of the gRPC
+// statuses (google.rpc.ErrorInfo).
+const Domain = "cybertron.nlpodyssey.com"
+
+// Code identifies a kind of error. The codes are stable across versions.
+type Code string
+
+const (
+	// CodeModelNotLoaded means that no model serving the method is loaded.
+	CodeModelNotLoaded Code = "MODEL_NOT_LOADED"
+	// CodeModelLoadFailed means that the model, loaded on its first
+	// request, failed to load; the next request loads it again.
+	CodeModelLoadFailed Code = "MODEL_LOAD_FAILED"
+	// CodeInvalidRequest means that the request is malformed.
+	CodeInvalidRequest Code = "INVALID_REQUEST"
+	// CodeInputTooLong means that the input exceeds the maximum length of
+	// the model.
+	CodeInputTooLong Code = "INPUT_TOO_LONG"
+	// CodeUnsupportedLanguage means that the model doesn't support the
+	// language of the request.
+	CodeUnsupportedLanguage Code = "UNSUPPORTED_LANGUAGE"
+	// CodeUnsupportedOption means that the model doesn't support an option
+	// of the request.
+	CodeUnsupportedOption Code = "UNSUPPORTED_OPTION"
+	// CodeDecodingTimeout means that the deadline of the request expired
+	// before the generation was complete.
+	CodeDecodingTimeout Code = "DECODING_TIMEOUT"
+	// CodeMemoryLimitExceeded means that the request would exceed the
+	// memory limit of the model.
+	CodeMemoryLimitExceeded Code = "MEMORY_LIMIT_EXCEEDED"
+	// CodePreempted means that the request was preempted by a request with
+	// a higher priority.
+	CodePreempted Code = "PREEMPTED"
+	// CodeUnknownTenant means that the credentials of the request don't
+	// match any tenant.
+	CodeUnknownTenant Code = "UNKNOWN_TENANT"
+	// CodeRateLimited means that the tenant exceeded its rate limit.
+	CodeRateLimited Code = "RATE_LIMITED"
+	// CodeQuotaExceeded means that the tenant exhausted its token quota.
+	CodeQuotaExceeded Code = "QUOTA_EXCEEDED"
+	// CodeJobNotFound means that the async job doesn't exist, or is no
+	// longer kept.
+	CodeJobNotFound Code = "JOB_NOT_FOUND"
+	// CodeInternal means that the server failed unexpectedly, e.g. because
+	// of a bug.
+	CodeInternal Code = "INTERNAL"
+)
+
+// Error is an error with a code. Two errors with the same code match each
+// other with errors.Is, so that the errors received by the clients match
+// the ones below.
+type Error struct {
+	Code    Code
+	Message string
+}
+
+// New returns an error with the code and the message.
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Error returns the message of the error.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports whether the target is an error with the same code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// The errors of the taxonomy, which the errors returned are matched against
+// with errors.Is.
+var (
+	ErrModelNotLoaded      = New(CodeModelNotLoaded, "model not loaded")
+	ErrModelLoadFailed     = New(CodeModelLoadFailed, "model failed to load")
+	ErrInvalidRequest      = New(CodeInvalidRequest, "invalid request")
+	ErrInputTooLong        = New(CodeInputTooLong, "input sequence too long")
+	ErrUnsupportedLanguage = New(CodeUnsupportedLanguage, "language not supported by the model")
+	ErrUnsupportedOption   = New(CodeUnsupportedOption, "option not supported by the model")
+	ErrDecodingTimeout     = New(CodeDecodingTimeout, "deadline expired while decoding")
+	ErrMemoryLimitExceeded = New(CodeMemoryLimitExceeded, "memory limit exceeded")
+	ErrPreempted           = New(CodePreempted, "request preempted by a higher-priority request")
+	ErrUnknownTenant       = New(CodeUnknownTenant, "unknown tenant")
+	ErrRateLimited         = New(CodeRateLimited, "tenant rate limit exceeded")
+	ErrQuotaExceeded       = New(CodeQuotaExceeded, "tenant token quota exceeded")
+	ErrJobNotFound         = New(CodeJobNotFound, "job not found")
+	ErrInternal            = New(CodeInternal, "internal error")
+)
+
+// CodeOf returns the code of the error, or an empty code if it's not an
+// error of the taxonomy.
+func CodeOf(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
@@ -0,0 +1,31 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errdefs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorIs(t *testing.T) {
+	err := fmt.Errorf("%w: 600 > 512", ErrInputTooLong)
+	assert.ErrorIs(t, err, ErrInputTooLong)
+	assert.NotErrorIs(t, err, ErrDecodingTimeout)
+	assert.Equal(t, "input sequence too long: 600 > 512", err.Error())
+
+	// e.g. received by a client, with the message of the server
+	received := New(CodeInputTooLong, "input sequence too long: 600 > 512")
+	assert.ErrorIs(t, received, ErrInputTooLong)
+	assert.True(t, errors.Is(received, ErrInputTooLong))
+}
+
+func TestCodeOf(t *testing.T) {
+	assert.Equal(t, CodePreempted, CodeOf(fmt.Errorf("batch: %w", ErrPreempted)))
+	assert.Equal(t, Code(""), CodeOf(errors.New("boom")))
+	assert.Equal(t, Code(""), CodeOf(nil))
+}
@@ -0,0 +1,267 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package filelock implements a cross-process lock based on a lease file,
+// which works on shared volumes (e.g. NFS) too, where the advisory locks of
+// the operating system are not reliable.
+//
+// The lock is held as long as the lease file exists and is fresh: the
+// holder refreshes its modification time periodically, so that the lock of
+// a crashed process expires after the lease duration.
+package filelock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultLease is the default duration after which a lock which is not
+	// refreshed is considered stale.
+	DefaultLease = 2 * time.Minute
+	// DefaultPollInterval is the default interval between attempts to
+	// acquire a lock held by another process.
+	DefaultPollInterval = time.Second
+)
+
+// Options are the options of a lock.
+type Options struct {
+	// Lease is the duration after which a lock which is not refreshed is
+	// considered stale (default DefaultLease). The holder refreshes it every
+	// third of the lease.
+	Lease time.Duration
+	// PollInterval is the interval between attempts to acquire a lock held
+	// by another process (default DefaultPollInterval).
+	PollInterval time.Duration
+}
+
+// ErrHeld is the error of TryAcquire if the lock is held by another
+// process.
+var ErrHeld = errors.New("filelock: lock held by another process")
+
+// ErrLost is the error releasing a lock whose lease file was broken, as
+// stale, by another process.
+var ErrLost = errors.New("filelock: lock lost")
+
+// Lock is a lock held by the current process.
+type Lock struct {
+	path string
+	// token identifies the lease file of the lock.
+	token string
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// owner identifies the holder of a lock, for diagnostics, and its lease
+// file by a random token.
+type owner struct {
+	Hostname string    `json:"hostname"`
+	PID      int       `json:"pid"`
+	Acquired time.Time `json:"acquired"`
+	Token    string    `json:"token"`
+}
+
+// Acquire acquires the lock with the given lease file, waiting until it's
+// released by its current holder, it expires, or the context is done.
+func Acquire(ctx context.Context, path string, opts Options) (*Lock, error) {
+	opts, err := prepare(path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	waiting := false
+	for {
+		l, err := tryAcquire(path, opts.Lease)
+		if l != nil || err != nil {
+			return l, err
+		}
+
+		if err := breakIfStale(path, opts.Lease); err != nil {
+			return nil, err
+		}
+		if !waiting {
+			waiting = true
+			log.Info().Str("lock", path).Interface("owner", readOwner(path)).Msg("waiting for the lock held by another process")
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to acquire lock %#v: %w", path, ctx.Err())
+		case <-time.After(opts.PollInterval):
+		}
+	}
+}
+
+// TryAcquire acquires the lock with the given lease file without waiting:
+// it fails with ErrHeld if the lock is held by another process, and it's
+// not stale.
+func TryAcquire(path string, opts Options) (*Lock, error) {
+	opts, err := prepare(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	l, err := tryAcquire(path, opts.Lease)
+	if l != nil || err != nil {
+		return l, err
+	}
+	if err := breakIfStale(path, opts.Lease); err != nil {
+		return nil, err
+	}
+	l, err = tryAcquire(path, opts.Lease)
+	if l == nil && err == nil {
+		err = fmt.Errorf("%w: %#v", ErrHeld, path)
+	}
+	return l, err
+}
+
+// prepare returns the options with the defaults set, creating the parent
+// directory of the lease file.
+func prepare(path string, opts Options) (Options, error) {
+	if opts.Lease <= 0 {
+		opts.Lease = DefaultLease
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	return opts, os.MkdirAll(filepath.Dir(path), 0755)
+}
+
+// tryAcquire acquires the lock if its lease file doesn't exist, returning
+// a nil lock otherwise.
+func tryAcquire(path string, lease time.Duration) (*Lock, error) {
+	token, err := tryCreate(path)
+	if err != nil {
+		return nil, err
+	}
+	// The lease file is read back, since another process may have broken
+	// it in the meantime, as stale.
+	if token == "" || !readOwner(path).owns(token) {
+		return nil, nil
+	}
+	l := &Lock{path: path, token: token, stop: make(chan struct{})}
+	l.wg.Add(1)
+	go l.refresh(lease / 3)
+	return l, nil
+}
+
+// Release releases the lock. It fails with ErrLost, leaving the lease file
+// in place, if the lease file isn't the one of the lock anymore.
+func (l *Lock) Release() error {
+	close(l.stop)
+	l.wg.Wait()
+	if !readOwner(l.path).owns(l.token) {
+		return fmt.Errorf("%w: %#v", ErrLost, l.path)
+	}
+	return os.Remove(l.path)
+}
+
+// refresh keeps the lock fresh until it's released.
+func (l *Lock) refresh(interval time.Duration) {
+	defer l.wg.Done()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-t.C:
+			if !readOwner(l.path).owns(l.token) {
+				log.Warn().Str("lock", l.path).Msg("lost the lock, broken by another process")
+				return
+			}
+			now := time.Now()
+			if err := os.Chtimes(l.path, now, now); err != nil {
+				log.Warn().Err(err).Str("lock", l.path).Msg("failed to refresh the lock")
+			}
+		}
+	}
+}
+
+// tryCreate creates the lease file, returning its token, or the empty
+// token if it already exists.
+func tryCreate(path string) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b[:])
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	hostname, _ := os.Hostname()
+	err = json.NewEncoder(f).Encode(owner{Hostname: hostname, PID: os.Getpid(), Acquired: time.Now(), Token: token})
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return "", err
+	}
+	return token, nil
+}
+
+// breakIfStale removes the lease file if it's stale. The file is renamed
+// first, so that only one of the processes competing for the lock breaks
+// it; if the renamed file turns out to be fresh, i.e. another process has
+// just acquired the lock, it's put back, unless a third process has created
+// a new lease file in the meantime: then the holder of the renamed one
+// loses the lock, and finds out reading its lease file back.
+func breakIfStale(path string, lease time.Duration) error {
+	if !isStale(path, lease) {
+		return nil
+	}
+	stale := fmt.Sprintf("%s.stale.%d", path, os.Getpid())
+	if err := os.Rename(path, stale); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if !isStale(stale, lease) {
+		if err := os.Link(stale, path); err != nil {
+			if !errors.Is(err, os.ErrExist) {
+				return err
+			}
+			log.Warn().Str("lock", path).Interface("owner", readOwner(stale)).Msg("failed to put back a fresh lock, replaced in the meantime")
+		}
+	} else {
+		log.Warn().Str("lock", path).Interface("owner", readOwner(stale)).Msg("broke stale lock")
+	}
+	return os.Remove(stale)
+}
+
+func isStale(path string, lease time.Duration) bool {
+	info, err := os.Stat(path)
+	return err == nil && time.Since(info.ModTime()) > lease
+}
+
+// owns reports whether the lease file of the owner has the token.
+func (o *owner) owns(token string) bool {
+	return o != nil && o.Token == token
+}
+
+func readOwner(path string) *owner {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	o := &owner{}
+	if json.Unmarshal(data, o) != nil {
+		return nil
+	}
+	return o
+}
@@ -0,0 +1,132 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package filelock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMutualExclusion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "org", "model.lock")
+	opts := Options{PollInterval: time.Millisecond}
+
+	var holders, maxHolders int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l, err := Acquire(context.Background(), path, opts)
+			require.NoError(t, err)
+			n := atomic.AddInt32(&holders, 1)
+			for {
+				m := atomic.LoadInt32(&maxHolders)
+				if n <= m || atomic.CompareAndSwapInt32(&maxHolders, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&holders, -1)
+			require.NoError(t, l.Release())
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), maxHolders)
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.lock")
+	require.NoError(t, os.WriteFile(path, []byte(`{"pid": 1}`), 0644))
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(path, old, old))
+
+	l, err := Acquire(context.Background(), path, Options{Lease: time.Minute, PollInterval: time.Millisecond})
+	require.NoError(t, err)
+	require.NoError(t, l.Release())
+}
+
+func TestAcquireTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.lock")
+	l, err := Acquire(context.Background(), path, Options{})
+	require.NoError(t, err)
+	defer l.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = Acquire(ctx, path, Options{PollInterval: time.Millisecond})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestLostLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.lock")
+	l, err := Acquire(context.Background(), path, Options{})
+	require.NoError(t, err)
+	o := readOwner(path)
+	require.NotNil(t, o)
+	assert.True(t, o.owns(l.token))
+	assert.Equal(t, os.Getpid(), o.PID)
+
+	// Another process breaks the lock, and acquires it.
+	require.NoError(t, os.Remove(path))
+	other, err := Acquire(context.Background(), path, Options{})
+	require.NoError(t, err)
+	assert.NotEqual(t, l.token, other.token)
+
+	assert.ErrorIs(t, l.Release(), ErrLost)
+	assert.FileExists(t, path, "the lease file of the new holder is left in place")
+	require.NoError(t, other.Release())
+	assert.NoFileExists(t, path)
+}
+
+func TestOwner(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		token string
+		want  bool
+	}{
+		{"same token", `{"pid": 1, "token": "a"}`, "a", true},
+		{"other token", `{"pid": 1, "token": "b"}`, "a", false},
+		{"no token", `{"pid": 1}`, "a", false},
+		{"invalid", `{`, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "model.lock")
+			require.NoError(t, os.WriteFile(path, []byte(tt.data), 0644))
+			assert.Equal(t, tt.want, readOwner(path).owns(tt.token))
+		})
+	}
+	assert.False(t, readOwner(filepath.Join(t.TempDir(), "missing")).owns(""))
+}
+
+func TestTryAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "org", "model.lock")
+	l, err := TryAcquire(path, Options{})
+	require.NoError(t, err)
+
+	_, err = TryAcquire(path, Options{})
+	assert.ErrorIs(t, err, ErrHeld)
+	require.NoError(t, l.Release())
+
+	// A stale lock is broken.
+	require.NoError(t, os.WriteFile(path, []byte(`{"pid": 1}`), 0644))
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(path, old, old))
+	l, err = TryAcquire(path, Options{Lease: time.Minute})
+	require.NoError(t, err)
+	require.NoError(t, l.Release())
+}
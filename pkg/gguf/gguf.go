@@ -0,0 +1,322 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package gguf implements reading and writing of GGUF files, the model
+// format of the GGML library, used by llama.cpp and related tools.
+//
+// Specification: https://github.com/ggerganov/ggml/blob/master/docs/gguf.md
+package gguf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
+)
+
+const (
+	// Magic is the magic number at the beginning of every GGUF file ("GGUF").
+	Magic uint32 = 0x46554747
+	// Version is the version of the format written by this package.
+	Version uint32 = 3
+	// DefaultAlignment is the default alignment of the tensor data.
+	DefaultAlignment = 32
+)
+
+// TensorType is the GGML type of the elements of a tensor.
+type TensorType uint32
+
+const (
+	// TypeF32 stores each value as float32.
+	TypeF32 TensorType = 0
+	// TypeF16 stores each value as IEEE 754 half-precision float.
+	TypeF16 TensorType = 1
+	// TypeQ8_0 stores blocks of 32 int8 values sharing a float16 scale.
+	TypeQ8_0 TensorType = 8
+	// TypeBF16 stores each value as bfloat16.
+	TypeBF16 TensorType = 30
+)
+
+// q8BlockSize is the number of values of a Q8_0 block.
+const q8BlockSize = 32
+
+// String returns the GGML name of the type.
+func (t TensorType) String() string {
+	switch t {
+	case TypeF32:
+		return "F32"
+	case TypeF16:
+		return "F16"
+	case TypeQ8_0:
+		return "Q8_0"
+	case TypeBF16:
+		return "BF16"
+	default:
+		return fmt.Sprintf("TensorType(%d)", uint32(t))
+	}
+}
+
+// encodedSize returns the number of bytes used to store n values.
+func (t TensorType) encodedSize(n int) (int, error) {
+	switch t {
+	case TypeF32:
+		return n * 4, nil
+	case TypeF16, TypeBF16:
+		return n * 2, nil
+	case TypeQ8_0:
+		if n%q8BlockSize != 0 {
+			return 0, fmt.Errorf("gguf: %d values are not a multiple of the Q8_0 block size", n)
+		}
+		return n / q8BlockSize * (2 + q8BlockSize), nil
+	default:
+		return 0, fmt.Errorf("gguf: unsupported tensor type %s", t)
+	}
+}
+
+// File type values of the "general.file_type" metadata, describing the
+// type of the majority of the tensors.
+const (
+	FileTypeAllF32     uint32 = 0
+	FileTypeMostlyF16  uint32 = 1
+	FileTypeMostlyQ80  uint32 = 7
+	FileTypeMostlyBF16 uint32 = 32
+)
+
+// FileType returns the "general.file_type" value corresponding to a tensor type.
+func FileType(t TensorType) uint32 {
+	switch t {
+	case TypeF16:
+		return FileTypeMostlyF16
+	case TypeQ8_0:
+		return FileTypeMostlyQ80
+	case TypeBF16:
+		return FileTypeMostlyBF16
+	default:
+		return FileTypeAllF32
+	}
+}
+
+// KV is a metadata key-value pair.
+//
+// The supported value types are uint8, int8, uint16, int16, uint32, int32,
+// uint64, int64, float32, float64, bool, string, and the arrays []string,
+// []int32, []uint32, []int64 and []float32.
+type KV struct {
+	Key   string
+	Value any
+}
+
+// Tensor is a named tensor of a GGUF file.
+type Tensor struct {
+	// Name is the name of the tensor.
+	Name string
+	// Shape is the size of each dimension, from the outermost to the
+	// innermost one, as in PyTorch (GGUF stores them in reverse order).
+	Shape []int
+	// Type is the storage type of the elements.
+	Type TensorType
+	// Data contains the encoded elements.
+	Data []byte
+	// load, if set, returns the values of a tensor whose data is encoded
+	// only when the file is written.
+	load func() ([]float32, error)
+}
+
+// NewTensor returns a new tensor encoding the given row-major values.
+func NewTensor(name string, shape []int, typ TensorType, values []float32) (*Tensor, error) {
+	n := numElements(shape)
+	if n != len(values) {
+		return nil, fmt.Errorf("gguf: tensor %q: shape %v doesn't match %d values", name, shape, len(values))
+	}
+	size, err := typ.encodedSize(n)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, size)
+	switch typ {
+	case TypeF32:
+		for i, v := range values {
+			binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(v))
+		}
+	case TypeF16:
+		for i, v := range values {
+			binary.LittleEndian.PutUint16(data[i*2:], quantization.Float32ToFloat16(v))
+		}
+	case TypeBF16:
+		for i, v := range values {
+			binary.LittleEndian.PutUint16(data[i*2:], quantization.Float32ToBFloat16(v))
+		}
+	case TypeQ8_0:
+		encodeQ8(data, values)
+	}
+	return &Tensor{Name: name, Shape: shape, Type: typ, Data: data}, nil
+}
+
+// NewLazyTensor returns a tensor whose values are obtained by calling load,
+// and encoded, only while the file is written. This allows writing large
+// models keeping a single tensor in memory at a time.
+func NewLazyTensor(name string, shape []int, typ TensorType, load func() ([]float32, error)) (*Tensor, error) {
+	if _, err := typ.encodedSize(numElements(shape)); err != nil {
+		return nil, err
+	}
+	return &Tensor{Name: name, Shape: shape, Type: typ, load: load}, nil
+}
+
+// encoded returns the encoded data of the tensor, loading it if needed.
+func (t *Tensor) encoded() ([]byte, error) {
+	if t.load == nil {
+		return t.Data, nil
+	}
+	values, err := t.load()
+	if err != nil {
+		return nil, err
+	}
+	e, err := NewTensor(t.Name, t.Shape, t.Type, values)
+	if err != nil {
+		return nil, err
+	}
+	return e.Data, nil
+}
+
+// size returns the size in bytes of the encoded data.
+func (t *Tensor) size() int {
+	if t.load == nil {
+		return len(t.Data)
+	}
+	n, _ := t.Type.encodedSize(numElements(t.Shape))
+	return n
+}
+
+func numElements(shape []int) int {
+	n := 1
+	for _, d := range shape {
+		n *= d
+	}
+	return n
+}
+
+// Float32s decodes the elements of the tensor.
+// The values of a lazy tensor are loaded, without any encoding.
+func (t *Tensor) Float32s() ([]float32, error) {
+	if t.load != nil {
+		return t.load()
+	}
+	n := numElements(t.Shape)
+	size, err := t.Type.encodedSize(n)
+	if err != nil {
+		return nil, err
+	}
+	if len(t.Data) != size {
+		return nil, fmt.Errorf("gguf: tensor %q: expected %d bytes, got %d", t.Name, size, len(t.Data))
+	}
+	out := make([]float32, n)
+	switch t.Type {
+	case TypeF32:
+		for i := range out {
+			out[i] = math.Float32frombits(binary.LittleEndian.Uint32(t.Data[i*4:]))
+		}
+	case TypeF16:
+		for i := range out {
+			out[i] = quantization.Float16ToFloat32(binary.LittleEndian.Uint16(t.Data[i*2:]))
+		}
+	case TypeBF16:
+		for i := range out {
+			out[i] = quantization.BFloat16ToFloat32(binary.LittleEndian.Uint16(t.Data[i*2:]))
+		}
+	case TypeQ8_0:
+		decodeQ8(out, t.Data)
+	}
+	return out, nil
+}
+
+// encodeQ8 quantizes the values into Q8_0 blocks: a float16 scale followed
+// by 32 int8 values, such that value = scale * q.
+func encodeQ8(dst []byte, values []float32) {
+	for b := 0; b < len(values)/q8BlockSize; b++ {
+		block := values[b*q8BlockSize : (b+1)*q8BlockSize]
+		out := dst[b*(2+q8BlockSize):]
+		var absMax float32
+		for _, v := range block {
+			if v < 0 {
+				v = -v
+			}
+			if v > absMax {
+				absMax = v
+			}
+		}
+		scale := absMax / 127
+		binary.LittleEndian.PutUint16(out, quantization.Float32ToFloat16(scale))
+		for i, v := range block {
+			var q int8
+			if scale != 0 {
+				q = int8(math.Round(float64(v / scale)))
+			}
+			out[2+i] = byte(q)
+		}
+	}
+}
+
+func decodeQ8(dst []float32, data []byte) {
+	for b := 0; b < len(dst)/q8BlockSize; b++ {
+		in := data[b*(2+q8BlockSize):]
+		scale := quantization.Float16ToFloat32(binary.LittleEndian.Uint16(in))
+		for i := 0; i < q8BlockSize; i++ {
+			dst[b*q8BlockSize+i] = scale * float32(int8(in[2+i]))
+		}
+	}
+}
+
+// File is the content of a GGUF file.
+type File struct {
+	// Metadata is the list of key-value pairs, in order.
+	Metadata []KV
+	// Tensors is the list of tensors, in order.
+	Tensors []*Tensor
+}
+
+// Set adds a metadata key-value pair, replacing the value of an existing key.
+func (f *File) Set(key string, value any) {
+	for i, kv := range f.Metadata {
+		if kv.Key == key {
+			f.Metadata[i].Value = value
+			return
+		}
+	}
+	f.Metadata = append(f.Metadata, KV{Key: key, Value: value})
+}
+
+// Get returns the value of a metadata key, and whether it was found.
+func (f *File) Get(key string) (any, bool) {
+	for _, kv := range f.Metadata {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Tensor returns the tensor with the given name, or nil if not found.
+func (f *File) Tensor(name string) *Tensor {
+	for _, t := range f.Tensors {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// alignment returns the alignment of the tensor data, from the
+// "general.alignment" metadata if present.
+func (f *File) alignment() int {
+	if v, ok := f.Get("general.alignment"); ok {
+		if a, ok := v.(uint32); ok && a > 0 {
+			return int(a)
+		}
+	}
+	return DefaultAlignment
+}
+
+var errInvalidFile = errors.New("gguf: invalid file")
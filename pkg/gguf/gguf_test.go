@@ -0,0 +1,72 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gguf
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_WriteAndDecode(t *testing.T) {
+	values := make([]float32, 64)
+	for i := range values {
+		values[i] = float32(i-32) / 10
+	}
+
+	f := &File{}
+	f.Set("general.architecture", "bert")
+	f.Set("bert.block_count", uint32(12))
+	f.Set("bert.attention.layer_norm_epsilon", float32(1e-12))
+	f.Set("bert.attention.causal", false)
+	f.Set("tokenizer.ggml.tokens", []string{"[PAD]", "▁hello", "world"})
+	f.Set("tokenizer.ggml.token_type", []int32{3, 1, 1})
+	for _, typ := range []TensorType{TypeF32, TypeF16, TypeBF16, TypeQ8_0} {
+		tensor, err := NewTensor("t."+typ.String(), []int{2, 32}, typ, values)
+		require.NoError(t, err)
+		f.Tensors = append(f.Tensors, tensor)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, f.Write(&buf))
+	assert.Equal(t, []byte("GGUF"), buf.Bytes()[:4])
+
+	decoded, err := Decode(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, f.Metadata, decoded.Metadata)
+	require.Len(t, decoded.Tensors, 4)
+
+	for _, tensor := range decoded.Tensors {
+		assert.Equal(t, []int{2, 32}, tensor.Shape)
+		actual, err := tensor.Float32s()
+		require.NoError(t, err)
+		assert.InDeltaSlice(t, values, actual, 0.02, tensor.Name)
+	}
+	exact, err := decoded.Tensor("t.F32").Float32s()
+	require.NoError(t, err)
+	assert.Equal(t, values, exact)
+}
+
+func TestNewTensor_Errors(t *testing.T) {
+	_, err := NewTensor("x", []int{2, 2}, TypeF32, []float32{1, 2, 3})
+	assert.Error(t, err)
+
+	_, err = NewTensor("x", []int{2, 2}, TypeQ8_0, []float32{1, 2, 3, 4})
+	assert.Error(t, err)
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	_, err := Decode([]byte("GGML"))
+	assert.Error(t, err)
+
+	f := &File{}
+	f.Set("general.name", "test")
+	var buf bytes.Buffer
+	require.NoError(t, f.Write(&buf))
+	_, err = Decode(buf.Bytes()[:buf.Len()-2])
+	assert.Error(t, err)
+}
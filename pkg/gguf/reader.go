@@ -0,0 +1,181 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gguf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+)
+
+// ReadFile reads a GGUF file.
+func ReadFile(filename string) (*File, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
+
+// Decode decodes the content of a GGUF file (versions 2 and 3).
+func Decode(data []byte) (*File, error) {
+	d := &decoder{buf: data}
+	if d.uint32() != Magic {
+		return nil, errInvalidFile
+	}
+	if v := d.uint32(); v != 2 && v != 3 {
+		return nil, fmt.Errorf("gguf: unsupported version %d", v)
+	}
+	nTensors, nKV := d.uint64(), d.uint64()
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	f := &File{}
+	for i := uint64(0); i < nKV && d.err == nil; i++ {
+		key := d.string()
+		value := d.value(d.uint32())
+		f.Metadata = append(f.Metadata, KV{Key: key, Value: value})
+	}
+
+	offsets := make([]uint64, 0, nTensors)
+	for i := uint64(0); i < nTensors && d.err == nil; i++ {
+		t := &Tensor{Name: d.string()}
+		nDims := d.uint32()
+		if nDims > 8 {
+			return nil, errInvalidFile
+		}
+		t.Shape = make([]int, nDims)
+		for j := int(nDims) - 1; j >= 0; j-- {
+			t.Shape[j] = int(d.uint64())
+		}
+		t.Type = TensorType(d.uint32())
+		offsets = append(offsets, d.uint64())
+		f.Tensors = append(f.Tensors, t)
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	start := padded(d.pos, f.alignment())
+	for i, t := range f.Tensors {
+		n := 1
+		for _, dim := range t.Shape {
+			n *= dim
+		}
+		size, err := t.Type.encodedSize(n)
+		if err != nil {
+			return nil, err
+		}
+		from := uint64(start) + offsets[i]
+		if from+uint64(size) > uint64(len(data)) {
+			return nil, errInvalidFile
+		}
+		t.Data = data[from : from+uint64(size)]
+	}
+	return f, nil
+}
+
+// decoder reads little-endian values, keeping the first error.
+type decoder struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil || n < 0 || d.pos+n > len(d.buf) {
+		if d.err == nil {
+			d.err = errInvalidFile
+		}
+		return make([]byte, 8)
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+func (d *decoder) uint32() uint32 { return binary.LittleEndian.Uint32(d.next(4)) }
+func (d *decoder) uint64() uint64 { return binary.LittleEndian.Uint64(d.next(8)) }
+
+func (d *decoder) string() string {
+	n := d.uint64()
+	if n > uint64(len(d.buf)) {
+		d.err = errInvalidFile
+		return ""
+	}
+	return string(d.next(int(n)))
+}
+
+func (d *decoder) value(typ uint32) any {
+	switch typ {
+	case typeUint8:
+		return d.next(1)[0]
+	case typeInt8:
+		return int8(d.next(1)[0])
+	case typeUint16:
+		return binary.LittleEndian.Uint16(d.next(2))
+	case typeInt16:
+		return int16(binary.LittleEndian.Uint16(d.next(2)))
+	case typeUint32:
+		return d.uint32()
+	case typeInt32:
+		return int32(d.uint32())
+	case typeFloat32:
+		return math.Float32frombits(d.uint32())
+	case typeBool:
+		return d.next(1)[0] != 0
+	case typeString:
+		return d.string()
+	case typeUint64:
+		return d.uint64()
+	case typeInt64:
+		return int64(d.uint64())
+	case typeFloat64:
+		return math.Float64frombits(d.uint64())
+	case typeArray:
+		return d.array()
+	default:
+		d.err = fmt.Errorf("gguf: unsupported metadata value type %d", typ)
+		return nil
+	}
+}
+
+// array decodes an array value. Arrays of the types supported by the
+// writer are returned as typed slices, the others as []any.
+func (d *decoder) array() any {
+	elemType, n := d.uint32(), d.uint64()
+	if n > uint64(len(d.buf)) {
+		d.err = errInvalidFile
+		return nil
+	}
+	values := make([]any, 0, n)
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		values = append(values, d.value(elemType))
+	}
+	switch elemType {
+	case typeString:
+		return typedSlice[string](values)
+	case typeInt32:
+		return typedSlice[int32](values)
+	case typeUint32:
+		return typedSlice[uint32](values)
+	case typeInt64:
+		return typedSlice[int64](values)
+	case typeFloat32:
+		return typedSlice[float32](values)
+	default:
+		return values
+	}
+}
+
+func typedSlice[T any](values []any) []T {
+	out := make([]T, len(values))
+	for i, v := range values {
+		out[i], _ = v.(T)
+	}
+	return out
+}
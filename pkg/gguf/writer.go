@@ -0,0 +1,203 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gguf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Metadata value types.
+const (
+	typeUint8   uint32 = 0
+	typeInt8    uint32 = 1
+	typeUint16  uint32 = 2
+	typeInt16   uint32 = 3
+	typeUint32  uint32 = 4
+	typeInt32   uint32 = 5
+	typeFloat32 uint32 = 6
+	typeBool    uint32 = 7
+	typeString  uint32 = 8
+	typeArray   uint32 = 9
+	typeUint64  uint32 = 10
+	typeInt64   uint32 = 11
+	typeFloat64 uint32 = 12
+)
+
+// WriteFile writes the GGUF file to the given filename.
+func (f *File) WriteFile(filename string) (err error) {
+	out, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := out.Close(); e != nil && err == nil {
+			err = e
+		}
+	}()
+	w := bufio.NewWriter(out)
+	if err = f.Write(w); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// Write writes the GGUF file to w.
+func (f *File) Write(w io.Writer) error {
+	cw := &countingWriter{w: w}
+	e := &encoder{w: cw}
+
+	e.put(Magic)
+	e.put(Version)
+	e.put(uint64(len(f.Tensors)))
+	e.put(uint64(len(f.Metadata)))
+	for _, kv := range f.Metadata {
+		e.string(kv.Key)
+		e.value(kv.Value)
+	}
+
+	align := f.alignment()
+	var offset uint64
+	for _, t := range f.Tensors {
+		e.string(t.Name)
+		e.put(uint32(len(t.Shape)))
+		for i := len(t.Shape) - 1; i >= 0; i-- {
+			e.put(uint64(t.Shape[i]))
+		}
+		e.put(uint32(t.Type))
+		e.put(offset)
+		offset += uint64(padded(t.size(), align))
+	}
+
+	for _, t := range f.Tensors {
+		e.pad(cw, align)
+		if e.err != nil {
+			break
+		}
+		data, err := t.encoded()
+		if err != nil {
+			return fmt.Errorf("gguf: tensor %q: %w", t.Name, err)
+		}
+		e.bytes(data)
+	}
+	return e.err
+}
+
+func padded(n, align int) int {
+	return (n + align - 1) / align * align
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// encoder writes little-endian values, keeping the first error.
+type encoder struct {
+	w   io.Writer
+	err error
+}
+
+func (e *encoder) put(v any) {
+	if e.err == nil {
+		e.err = binary.Write(e.w, binary.LittleEndian, v)
+	}
+}
+
+func (e *encoder) bytes(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+func (e *encoder) string(s string) {
+	e.put(uint64(len(s)))
+	e.bytes([]byte(s))
+}
+
+// pad writes zeros until the position is a multiple of align.
+func (e *encoder) pad(cw *countingWriter, align int) {
+	if n := padded(int(cw.n), align) - int(cw.n); n > 0 {
+		e.bytes(make([]byte, n))
+	}
+}
+
+// value writes a metadata value, preceded by its type.
+func (e *encoder) value(v any) {
+	typ, ok := valueType(v)
+	if !ok {
+		if e.err == nil {
+			e.err = fmt.Errorf("gguf: unsupported metadata value type %T", v)
+		}
+		return
+	}
+	e.put(typ)
+	switch x := v.(type) {
+	case string:
+		e.string(x)
+	case []string:
+		e.array(typeString, len(x), func(i int) { e.string(x[i]) })
+	case []int32:
+		e.array(typeInt32, len(x), func(i int) { e.put(x[i]) })
+	case []uint32:
+		e.array(typeUint32, len(x), func(i int) { e.put(x[i]) })
+	case []float32:
+		e.array(typeFloat32, len(x), func(i int) { e.put(x[i]) })
+	case []int64:
+		e.array(typeInt64, len(x), func(i int) { e.put(x[i]) })
+	default:
+		e.put(x)
+	}
+}
+
+func (e *encoder) array(elemType uint32, n int, elem func(i int)) {
+	e.put(elemType)
+	e.put(uint64(n))
+	for i := 0; i < n; i++ {
+		elem(i)
+	}
+}
+
+func valueType(v any) (uint32, bool) {
+	switch v.(type) {
+	case uint8:
+		return typeUint8, true
+	case int8:
+		return typeInt8, true
+	case uint16:
+		return typeUint16, true
+	case int16:
+		return typeInt16, true
+	case uint32:
+		return typeUint32, true
+	case int32:
+		return typeInt32, true
+	case float32:
+		return typeFloat32, true
+	case bool:
+		return typeBool, true
+	case string:
+		return typeString, true
+	case uint64:
+		return typeUint64, true
+	case int64:
+		return typeInt64, true
+	case float64:
+		return typeFloat64, true
+	case []string, []int32, []uint32, []float32, []int64:
+		return typeArray, true
+	default:
+		return 0, false
+	}
+}
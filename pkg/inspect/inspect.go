@@ -0,0 +1,401 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package inspect describes a model, either already in the models directory
+// or on the Hugging Face Hub, reading only its configuration and the headers
+// of its weights, so that its compatibility can be checked before
+// downloading it.
+package inspect
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/converter/safetensors"
+	"github.com/nlpodyssey/cybertron/pkg/downloader"
+	"github.com/nlpodyssey/cybertron/pkg/models"
+)
+
+const (
+	// convertedFilename is the name of the weights of the converted model.
+	convertedFilename = "spago_model.bin"
+	// ggufFilename is the name of the GGUF conversion of the model.
+	ggufFilename = "model.gguf"
+	// pytorchFilename is the name of the PyTorch weights.
+	pytorchFilename = "pytorch_model.bin"
+	// safetensorsIndexFilename is the name of the index of sharded safetensors weights.
+	safetensorsIndexFilename = "model.safetensors.index.json"
+)
+
+// Report describes a model.
+type Report struct {
+	// Model is the name of the model.
+	Model string `json:"model"`
+	// Local is true if the model was inspected in the models directory,
+	// false if on the Hub.
+	Local bool `json:"local"`
+	// ModelType is the model type of the configuration (e.g. "bert").
+	ModelType string `json:"model_type"`
+	// Architectures are the architectures of the configuration
+	// (e.g. "BertForSequenceClassification").
+	Architectures []string `json:"architectures"`
+	// Supported is true if the model type can be converted and loaded.
+	Supported bool `json:"supported"`
+	// Tasks are the tasks the model can fulfill, inferred from its
+	// architectures and labels.
+	Tasks []string `json:"tasks"`
+	// Tokenizer is the type of the tokenizer (e.g. "WordPiece").
+	Tokenizer string `json:"tokenizer"`
+	// Parameters is the number of parameters.
+	Parameters int64 `json:"parameters"`
+	// ParametersEstimated is true if the number of parameters is estimated
+	// from the size of the weights, since their shapes are not available
+	// without downloading them.
+	ParametersEstimated bool `json:"parameters_estimated"`
+	// WeightsSize is the size of the original weights in bytes.
+	WeightsSize int64 `json:"weights_size"`
+	// Memory is the estimated memory required by the weights, in bytes, by
+	// precision of the converted model ("float32", "float64", "float16",
+	// "int8").
+	Memory map[string]int64 `json:"memory"`
+	// Dimensions are the dimensions of the layers, used to estimate the
+	// memory of the activations (see EstimateMemory).
+	Dimensions Dimensions `json:"dimensions"`
+	// Tensors are the tensors of the weights, if available.
+	Tensors []Tensor `json:"tensors,omitempty"`
+	// Downloaded is true if the model is in the models directory.
+	Downloaded bool `json:"downloaded"`
+	// Commit is the commit SHA of the downloaded files, if known.
+	Commit string `json:"commit,omitempty"`
+	// Converted is true if the model is converted in the models directory.
+	Converted bool `json:"converted"`
+	// ConvertedSize is the size of the converted weights in bytes.
+	ConvertedSize int64 `json:"converted_size,omitempty"`
+	// GGUF is true if the model is also converted to GGUF.
+	GGUF bool `json:"gguf"`
+}
+
+// Tensor describes a tensor of the weights.
+type Tensor struct {
+	Name  string `json:"name"`
+	DType string `json:"dtype"`
+	Shape []int  `json:"shape"`
+}
+
+// bytesPerParameter is the size of a parameter, by precision.
+var bytesPerParameter = map[string]int64{
+	"float32": 4,
+	"float64": 8,
+	"float16": 2,
+	"int8":    1,
+}
+
+// Inspect describes the model, reading it from the models directory, if
+// there, or from the Hub otherwise, where only the configuration and the
+// headers of the weights are downloaded.
+func Inspect(modelsDir, modelName string, opts downloader.Options) (*Report, error) {
+	modelPath := filepath.Join(modelsDir, modelName)
+	var src source = localSource(modelPath)
+	local := fileExists(filepath.Join(modelPath, models.DefaultModelConfigFilename))
+	if !local {
+		remote, err := newRemoteSource(modelName, opts)
+		if err != nil {
+			return nil, err
+		}
+		src = remote
+	}
+
+	r := &Report{Model: modelName, Local: local}
+	c, err := r.readConfig(src)
+	if err != nil {
+		return nil, err
+	}
+	files, err := src.files()
+	if err != nil {
+		return nil, err
+	}
+	r.Tokenizer = tokenizerType(src, files)
+	if err := r.readWeights(src, files, c.TorchDType); err != nil {
+		return nil, err
+	}
+	r.Memory = make(map[string]int64, len(bytesPerParameter))
+	for precision, size := range bytesPerParameter {
+		r.Memory[precision] = r.Parameters * size
+	}
+	r.readLocalStatus(modelPath)
+	return r, nil
+}
+
+// modelConfig is the part of the model configuration which is inspected.
+type modelConfig struct {
+	ModelType     string         `json:"model_type"`
+	Architectures []string       `json:"architectures"`
+	Label2ID      map[string]int `json:"label2id"`
+	TorchDType    string         `json:"torch_dtype"`
+
+	// The dimensions, named after the BERT ones, the BART ones or the
+	// DistilBERT ones.
+	HiddenSize            int `json:"hidden_size"`
+	DModel                int `json:"d_model"`
+	Dim                   int `json:"dim"`
+	NumHiddenLayers       int `json:"num_hidden_layers"`
+	EncoderLayers         int `json:"encoder_layers"`
+	DecoderLayers         int `json:"decoder_layers"`
+	NLayers               int `json:"n_layers"`
+	NumAttentionHeads     int `json:"num_attention_heads"`
+	EncoderAttentionHeads int `json:"encoder_attention_heads"`
+	NHeads                int `json:"n_heads"`
+	IntermediateSize      int `json:"intermediate_size"`
+	EncoderFFNDim         int `json:"encoder_ffn_dim"`
+	HiddenDim             int `json:"hidden_dim"`
+	MaxPositionEmbeddings int `json:"max_position_embeddings"`
+}
+
+// readConfig reads the model type, the architectures and the tasks from the
+// configuration of the model, which is returned.
+func (r *Report) readConfig(src source) (modelConfig, error) {
+	var c modelConfig
+	if err := readJSON(src, models.DefaultModelConfigFilename, &c); err != nil {
+		return c, err
+	}
+	r.ModelType = c.ModelType
+	r.Architectures = c.Architectures
+	for _, t := range downloader.SupportedModelTypes() {
+		if t == c.ModelType {
+			r.Supported = true
+		}
+	}
+	r.Tasks = inferTasks(c)
+	r.Dimensions = c.dimensions()
+	return c, nil
+}
+
+// inferTasks returns the tasks which the architectures of the model can
+// fulfill, named as the tasks of the server.
+func inferTasks(c modelConfig) []string {
+	set := make(map[string]bool)
+	for _, arch := range c.Architectures {
+		switch {
+		case strings.HasSuffix(arch, "ForSequenceClassification"):
+			set["text-classification"] = true
+			if _, ok := c.Label2ID["entailment"]; ok {
+				set["zero-shot-classification"] = true
+			}
+		case strings.HasSuffix(arch, "ForTokenClassification"):
+			set["token-classification"] = true
+		case strings.HasSuffix(arch, "ForQuestionAnswering"):
+			set["question-answering"] = true
+		case strings.HasSuffix(arch, "ForCoreferenceResolution"):
+			set["coreference-resolution"] = true
+		case strings.HasSuffix(arch, "ForMaskedLM"), strings.HasSuffix(arch, "ForPreTraining"):
+			set["language-modeling"] = true
+			set["text-encoding"] = true
+		case strings.HasSuffix(arch, "ForConditionalGeneration"), strings.HasSuffix(arch, "MTModel"):
+			set["text2text"] = true
+		case strings.HasSuffix(arch, "Model"):
+			set["text-encoding"] = true
+		}
+	}
+	tasks := make([]string, 0, len(set))
+	for t := range set {
+		tasks = append(tasks, t)
+	}
+	sort.Strings(tasks)
+	return tasks
+}
+
+// tokenizerType returns the type of the tokenizer, from the tokenizer
+// configuration or else from the vocabulary files.
+func tokenizerType(src source, files map[string]int64) string {
+	var c struct {
+		TokenizerClass string `json:"tokenizer_class"`
+	}
+	if hasFile(files, "tokenizer_config.json") {
+		if err := readJSON(src, "tokenizer_config.json", &c); err == nil && c.TokenizerClass != "" {
+			return c.TokenizerClass
+		}
+	}
+	switch {
+	case hasFile(files, "vocab.txt"):
+		return "WordPiece"
+	case hasFile(files, "vocab.json") && hasFile(files, "merges.txt"):
+		return "BPE"
+	case hasFile(files, "spiece.model"), hasFile(files, "source.spm"), hasFile(files, "sentencepiece.bpe.model"):
+		return "SentencePiece"
+	case hasFile(files, "tokenizer.json"):
+		return "Tokenizers"
+	default:
+		return ""
+	}
+}
+
+// readWeights reads the tensors from the headers of the safetensors
+// weights, if any, or else estimates the number of parameters from the size
+// of the PyTorch weights, given their data type.
+func (r *Report) readWeights(src source, files map[string]int64, torchDType string) error {
+	var shards []string
+	switch {
+	case hasFile(files, safetensors.DefaultFilename):
+		shards = []string{safetensors.DefaultFilename}
+	case hasFile(files, safetensorsIndexFilename):
+		var index struct {
+			WeightMap map[string]string `json:"weight_map"`
+		}
+		if err := readJSON(src, safetensorsIndexFilename, &index); err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		for _, shard := range index.WeightMap {
+			if !seen[shard] {
+				seen[shard] = true
+				shards = append(shards, shard)
+			}
+		}
+		sort.Strings(shards)
+	}
+
+	if len(shards) == 0 {
+		size := int64(4)
+		if torchDType == "float16" || torchDType == "bfloat16" {
+			size = 2
+		}
+		r.WeightsSize = files[pytorchFilename]
+		r.Parameters = r.WeightsSize / size
+		r.ParametersEstimated = true
+		return nil
+	}
+	for _, shard := range shards {
+		r.WeightsSize += files[shard]
+		tensors, err := readSafetensorsHeader(src, shard)
+		if err != nil {
+			return err
+		}
+		for name, info := range tensors {
+			n := int64(1)
+			for _, d := range info.Shape {
+				n *= int64(d)
+			}
+			r.Parameters += n
+			r.Tensors = append(r.Tensors, Tensor{Name: name, DType: info.DType, Shape: info.Shape})
+		}
+	}
+	sort.Slice(r.Tensors, func(i, j int) bool { return r.Tensors[i].Name < r.Tensors[j].Name })
+	return nil
+}
+
+// readLocalStatus reads the download and conversion status of the model in
+// the models directory.
+func (r *Report) readLocalStatus(modelPath string) {
+	r.Downloaded = fileExists(filepath.Join(modelPath, models.DefaultModelConfigFilename))
+	if m, err := downloader.ReadMetadata(modelPath); err == nil {
+		r.Commit = m.Commit
+	}
+	if info, err := os.Stat(filepath.Join(modelPath, convertedFilename)); err == nil {
+		r.Converted = true
+		r.ConvertedSize = info.Size()
+	}
+	r.GGUF = fileExists(filepath.Join(modelPath, ggufFilename))
+}
+
+func readSafetensorsHeader(src source, name string) (map[string]safetensors.TensorInfo, error) {
+	f, err := src.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return safetensors.ReadHeader(f)
+}
+
+func readJSON(src source, name string, v any) error {
+	f, err := src.open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("error parsing %#v: %w", name, err)
+	}
+	return nil
+}
+
+func hasFile(files map[string]int64, name string) bool {
+	_, ok := files[name]
+	return ok
+}
+
+func fileExists(filename string) bool {
+	info, err := os.Stat(filename)
+	return err == nil && !info.IsDir()
+}
+
+// source is where the files of the model are read from.
+type source interface {
+	// files returns the size of the files, by name.
+	files() (map[string]int64, error)
+	// open opens a file.
+	open(name string) (io.ReadCloser, error)
+}
+
+// localSource reads the files from the model's directory.
+type localSource string
+
+func (s localSource) files() (map[string]int64, error) {
+	files := make(map[string]int64)
+	err := filepath.WalkDir(string(s), func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(string(s), path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = info.Size()
+		return nil
+	})
+	return files, err
+}
+
+func (s localSource) open(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(s), filepath.FromSlash(name)))
+}
+
+// remoteSource reads the files from the Hub.
+type remoteSource struct {
+	modelName string
+	opts      downloader.Options
+	list      map[string]int64
+}
+
+func newRemoteSource(modelName string, opts downloader.Options) (*remoteSource, error) {
+	if opts.Offline || downloader.IsOfflineEnv() {
+		return nil, errors.New("the model is not in the models directory, and cannot be inspected on the Hub in offline mode")
+	}
+	files, err := downloader.ListFiles(modelName, opts)
+	if err != nil {
+		return nil, err
+	}
+	list := make(map[string]int64, len(files))
+	for _, f := range files {
+		list[f.Name] = f.Size
+	}
+	return &remoteSource{modelName: modelName, opts: opts, list: list}, nil
+}
+
+func (s *remoteSource) files() (map[string]int64, error) {
+	return s.list, nil
+}
+
+func (s *remoteSource) open(name string) (io.ReadCloser, error) {
+	return downloader.OpenFile(s.modelName, name, s.opts)
+}
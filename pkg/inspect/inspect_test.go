@@ -0,0 +1,128 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inspect
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/downloader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func safetensorsContent(header string) string {
+	content := binary.LittleEndian.AppendUint64(nil, uint64(len(header)))
+	return string(append(content, header...)) + strings.Repeat("\x00", 64)
+}
+
+var testFiles = map[string]string{
+	"config.json": `{"model_type": "bert", "architectures": ["BertForSequenceClassification"], "label2id": {"entailment": 0, "contradiction": 1}}`,
+	"vocab.txt":   "[PAD]\n[UNK]\n",
+	"model.safetensors": safetensorsContent(`{"__metadata__":{"format":"pt"},` +
+		`"bert.embeddings.word_embeddings.weight":{"dtype":"F32","shape":[2,4],"data_offsets":[0,32]},` +
+		`"classifier.weight":{"dtype":"F32","shape":[2,4],"data_offsets":[32,64]}}`),
+}
+
+func TestInspect_Local(t *testing.T) {
+	dir := t.TempDir()
+	modelPath := filepath.Join(dir, "org", "model")
+	require.NoError(t, os.MkdirAll(modelPath, 0755))
+	for name, content := range testFiles {
+		require.NoError(t, os.WriteFile(filepath.Join(modelPath, name), []byte(content), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(modelPath, convertedFilename), make([]byte, 100), 0644))
+
+	r, err := Inspect(dir, "org/model", downloader.Options{Offline: true})
+	require.NoError(t, err)
+	assert.True(t, r.Local)
+	assert.Equal(t, "bert", r.ModelType)
+	assert.True(t, r.Supported)
+	assert.Equal(t, []string{"text-classification", "zero-shot-classification"}, r.Tasks)
+	assert.Equal(t, "WordPiece", r.Tokenizer)
+	assert.Equal(t, int64(16), r.Parameters)
+	assert.False(t, r.ParametersEstimated)
+	assert.Equal(t, int64(64), r.Memory["float32"])
+	assert.Equal(t, []Tensor{
+		{Name: "bert.embeddings.word_embeddings.weight", DType: "F32", Shape: []int{2, 4}},
+		{Name: "classifier.weight", DType: "F32", Shape: []int{2, 4}},
+	}, r.Tensors)
+	assert.True(t, r.Downloaded)
+	assert.True(t, r.Converted)
+	assert.Equal(t, int64(100), r.ConvertedSize)
+	assert.False(t, r.GGUF)
+}
+
+func TestInspect_Remote(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/models/org/model/tree/main" {
+			var files []downloader.RemoteFile
+			for name, content := range testFiles {
+				files = append(files, downloader.RemoteFile{Name: name, Size: int64(len(content)), Type: "file"})
+			}
+			_ = json.NewEncoder(w).Encode(files)
+			return
+		}
+		name := strings.TrimPrefix(r.URL.Path, "/org/model/resolve/main/")
+		content, ok := testFiles[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, name, time.Time{}, strings.NewReader(content))
+	}))
+	defer s.Close()
+
+	r, err := Inspect(t.TempDir(), "org/model", downloader.Options{Endpoint: s.URL})
+	require.NoError(t, err)
+	assert.False(t, r.Local)
+	assert.Equal(t, "WordPiece", r.Tokenizer)
+	assert.Equal(t, int64(16), r.Parameters)
+	assert.Equal(t, int64(len(testFiles["model.safetensors"])), r.WeightsSize)
+	assert.False(t, r.Downloaded)
+	assert.False(t, r.Converted)
+
+	_, err = Inspect(t.TempDir(), "org/model", downloader.Options{Endpoint: s.URL, Offline: true})
+	assert.Error(t, err)
+}
+
+func TestInferTasks(t *testing.T) {
+	assert.Equal(t, []string{"text2text"}, inferTasks(modelConfig{Architectures: []string{"MarianMTModel"}}))
+	assert.Equal(t, []string{"language-modeling", "text-encoding"}, inferTasks(modelConfig{Architectures: []string{"BertForMaskedLM"}}))
+	assert.Equal(t, []string{"text-encoding"}, inferTasks(modelConfig{Architectures: []string{"BertModel"}}))
+	assert.Equal(t, []string{"text-classification"}, inferTasks(modelConfig{Architectures: []string{"BertForSequenceClassification"}}))
+	assert.Equal(t, []string{"coreference-resolution"}, inferTasks(modelConfig{Architectures: []string{"BertForCoreferenceResolution"}}))
+}
+
+func TestReport_EstimateMemory(t *testing.T) {
+	r := &Report{
+		Memory:     map[string]int64{"float32": 1000, "float64": 2000},
+		Dimensions: modelConfig{Dim: 4, NLayers: 2, NHeads: 2, HiddenDim: 8, MaxPositionEmbeddings: 16}.dimensions(),
+	}
+	assert.Equal(t, Dimensions{HiddenSize: 4, Layers: 2, AttentionHeads: 2, IntermediateSize: 8, MaxPositions: 16}, r.Dimensions)
+
+	e, err := r.EstimateMemory("float32", 3, 10)
+	require.NoError(t, err)
+	// Per layer: 10*(8*4+2*8) + 2*2*10*10 = 880; embeddings: 3*10*4 = 120.
+	activations := int64(3 * (120 + 2*880) * 4)
+	assert.Equal(t, Estimate{Precision: "float32", BatchSize: 3, SequenceLength: 10, Weights: 1000, Activations: activations, Total: 1000 + activations}, e)
+
+	e, err = r.EstimateMemory("float64", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, e.BatchSize)
+	assert.Equal(t, 16, e.SequenceLength)
+
+	_, err = r.EstimateMemory("int4", 1, 10)
+	assert.Error(t, err)
+	_, err = (&Report{Memory: r.Memory}).EstimateMemory("float32", 1, 10)
+	assert.Error(t, err)
+}
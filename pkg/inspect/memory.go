@@ -0,0 +1,121 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inspect
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Dimensions are the dimensions of the layers of a model, from its
+// configuration, zero if unknown.
+type Dimensions struct {
+	// HiddenSize is the size of the hidden states.
+	HiddenSize int `json:"hidden_size"`
+	// Layers is the number of layers, of the encoder and of the decoder.
+	Layers int `json:"layers"`
+	// AttentionHeads is the number of attention heads of each layer.
+	AttentionHeads int `json:"attention_heads"`
+	// IntermediateSize is the size of the feed-forward layers.
+	IntermediateSize int `json:"intermediate_size"`
+	// MaxPositions is the maximum length of a sequence, in tokens.
+	MaxPositions int `json:"max_positions"`
+}
+
+// Estimate is the estimated resident memory of a model serving a batch of
+// sequences.
+type Estimate struct {
+	// Precision is the precision of the weights (see Report.Memory).
+	Precision string `json:"precision"`
+	// BatchSize is the number of sequences processed at once.
+	BatchSize int `json:"batch_size"`
+	// SequenceLength is the length of each sequence, in tokens.
+	SequenceLength int `json:"sequence_length"`
+	// Weights is the memory of the weights, in bytes.
+	Weights int64 `json:"weights"`
+	// Activations is the memory of the intermediate tensors of the forward
+	// pass of the batch, in bytes.
+	Activations int64 `json:"activations"`
+	// Total is the sum of the weights and of the activations, in bytes.
+	Total int64 `json:"total"`
+}
+
+// EstimateMemory estimates the resident memory of the model serving
+// batchSize sequences of sequenceLength tokens at once (default 1 sequence of
+// the maximum length of the model), with the weights in the given precision
+// (see Report.Memory).
+//
+// The intermediate tensors of a forward pass are kept until its end, so the
+// activations are the ones of all the layers: per token, the projections of
+// the attention and their output, the residuals and the normalizations, and
+// the feed-forward layers; per sequence, the attention scores and their
+// softmax, quadratic in its length. They're computed in 64 bits with the
+// "float64" precision, in 32 bits otherwise. The estimate is an upper bound
+// of the working set, since the memory of a tensor can be reused before the
+// end of the pass; the runtime and the tokenizer take some more.
+func (r *Report) EstimateMemory(precision string, batchSize, sequenceLength int) (Estimate, error) {
+	weights, ok := r.Memory[precision]
+	if !ok {
+		return Estimate{}, fmt.Errorf("invalid precision %#v", precision)
+	}
+	d := r.Dimensions
+	if d.HiddenSize <= 0 || d.Layers <= 0 {
+		return Estimate{}, errors.New("the dimensions of the model are not in its configuration")
+	}
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	if sequenceLength <= 0 {
+		sequenceLength = d.MaxPositions
+	}
+	if sequenceLength <= 0 {
+		return Estimate{}, errors.New("the sequence length is not set, and the maximum one of the model is unknown")
+	}
+	intermediate := d.IntermediateSize
+	if intermediate <= 0 {
+		intermediate = 4 * d.HiddenSize
+	}
+	heads := d.AttentionHeads
+	if heads <= 0 {
+		heads = 1
+	}
+	n := int64(sequenceLength)
+	perLayer := n*(8*int64(d.HiddenSize)+2*int64(intermediate)) + 2*int64(heads)*n*n
+	// The embeddings of the tokens and of their positions, and their sum.
+	embeddings := 3 * n * int64(d.HiddenSize)
+	elementSize := int64(4)
+	if precision == "float64" {
+		elementSize = 8
+	}
+	activations := int64(batchSize) * (embeddings + int64(d.Layers)*perLayer) * elementSize
+	return Estimate{
+		Precision:      precision,
+		BatchSize:      batchSize,
+		SequenceLength: sequenceLength,
+		Weights:        weights,
+		Activations:    activations,
+		Total:          weights + activations,
+	}, nil
+}
+
+// dimensions returns the dimensions of the layers from the configuration.
+func (c modelConfig) dimensions() Dimensions {
+	return Dimensions{
+		HiddenSize:       firstPositive(c.HiddenSize, c.DModel, c.Dim),
+		Layers:           firstPositive(c.NumHiddenLayers, c.EncoderLayers+c.DecoderLayers, c.NLayers),
+		AttentionHeads:   firstPositive(c.NumAttentionHeads, c.EncoderAttentionHeads, c.NHeads),
+		IntermediateSize: firstPositive(c.IntermediateSize, c.EncoderFFNDim, c.HiddenDim),
+		MaxPositions:     c.MaxPositionEmbeddings,
+	}
+}
+
+func firstPositive(values ...int) int {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
+}
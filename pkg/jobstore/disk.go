@@ -0,0 +1,117 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jobstore
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// sweepInterval is the minimum interval between the removals of the
+// expired files of a Disk store.
+const sweepInterval = time.Minute
+
+// Disk is a store of a directory, a file per key. The expired files are
+// removed by the writes, at most once per sweepInterval.
+type Disk struct {
+	dir string
+	now func() time.Time
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+var _ Store = &Disk{}
+
+// NewDisk returns the store of the directory, created on the first write.
+func NewDisk(dir string) *Disk {
+	return &Disk{dir: dir, now: time.Now}
+}
+
+// filename returns the file of the key, escaped so that it stays within
+// the directory.
+func (d *Disk) filename(key string) string {
+	return filepath.Join(d.dir, url.PathEscape(key))
+}
+
+// Get returns the value of the key, and whether it's found.
+func (d *Disk) Get(_ context.Context, key string) ([]byte, bool, error) {
+	data, err := os.ReadFile(d.filename(key))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return parseValue(data, d.now())
+}
+
+// Set sets the value of the key, expiring after the TTL, if positive. The
+// file is written atomically, so that the readers never see it partial.
+func (d *Disk) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return err
+	}
+	d.sweep()
+	f, err := os.CreateTemp(d.dir, ".incomplete-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(withExpiry(value, ttl, d.now()))
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), d.filename(key))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
+
+// sweep removes the expired files, unless it did less than sweepInterval
+// ago.
+func (d *Disk) sweep() {
+	d.mu.Lock()
+	now := d.now()
+	if now.Sub(d.lastSweep) < sweepInterval {
+		d.mu.Unlock()
+		return
+	}
+	d.lastSweep = now
+	d.mu.Unlock()
+
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		log.Warn().Err(err).Str("dir", d.dir).Msg("failed to sweep the job store")
+		return
+	}
+	var header [expiryLen]byte
+	for _, e := range entries {
+		if !e.Type().IsRegular() || strings.HasPrefix(e.Name(), ".incomplete-") {
+			continue
+		}
+		filename := filepath.Join(d.dir, e.Name())
+		f, err := os.Open(filename)
+		if err != nil {
+			continue
+		}
+		n, _ := f.Read(header[:])
+		f.Close()
+		if n == expiryLen && expired(header[:], now) {
+			if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+				log.Warn().Err(err).Str("file", filename).Msg("failed to remove the expired job")
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package jobstore implements the stores where the async jobs of the
+// server, and their results, are persisted, so that they survive the
+// restarts and can be fetched from any server of a fleet behind a load
+// balancer.
+//
+// The supported stores are a directory of the local filesystem, e.g. a
+// network share ("file:///path/to/dir"), Redis ("redis://" or "rediss://",
+// as for the response cache), and the object stores of the models ("s3://",
+// "gs://" and "az://", as for the model store).
+package jobstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/responsecache"
+	"github.com/nlpodyssey/cybertron/pkg/storage"
+)
+
+// Store is a store of jobs. It's the same as the one of the response cache.
+type Store = responsecache.Store
+
+// Open returns the store for the given URL.
+func Open(rawURL string) (Store, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("jobstore: invalid URL %#v: %w", rawURL, err)
+	}
+	switch u.Scheme {
+	case "file":
+		return NewDisk(filepath.FromSlash(u.Path)), nil
+	case "redis", "rediss":
+		return responsecache.NewRedis(u)
+	case "s3", "gs", "az":
+		s, err := storage.Open(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		return NewObjects(s), nil
+	default:
+		return nil, fmt.Errorf("jobstore: unsupported URL scheme %#v", u.Scheme)
+	}
+}
+
+// The values of the disk and the object stores are prefixed by their
+// expiration, in Unix milliseconds, big-endian, or zero if they don't
+// expire.
+const expiryLen = 8
+
+// withExpiry returns the value prefixed by its expiration after the TTL, if
+// positive.
+func withExpiry(value []byte, ttl time.Duration, now time.Time) []byte {
+	b := make([]byte, expiryLen, expiryLen+len(value))
+	if ttl > 0 {
+		binary.BigEndian.PutUint64(b, uint64(now.Add(ttl).UnixMilli()))
+	}
+	return append(b, value...)
+}
+
+// expired returns whether the value prefixed by its expiration is expired.
+func expired(data []byte, now time.Time) bool {
+	expiry := int64(binary.BigEndian.Uint64(data))
+	return expiry != 0 && now.UnixMilli() >= expiry
+}
+
+// parseValue returns the value prefixed by its expiration, and whether it's
+// still valid.
+func parseValue(data []byte, now time.Time) ([]byte, bool, error) {
+	if len(data) < expiryLen {
+		return nil, false, errors.New("jobstore: truncated value")
+	}
+	if expired(data, now) {
+		return nil, false, nil
+	}
+	return data[expiryLen:], true, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jobstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/responsecache"
+	"github.com/nlpodyssey/cybertron/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen(t *testing.T) {
+	s, err := Open("file:///tmp/jobs")
+	require.NoError(t, err)
+	assert.IsType(t, &Disk{}, s)
+
+	s, err = Open("redis://localhost")
+	require.NoError(t, err)
+	assert.IsType(t, &responsecache.Redis{}, s)
+
+	_, err = Open("memory")
+	assert.Error(t, err)
+}
+
+// testStore tests the store, whose clock is set by the function.
+func testStore(t *testing.T, s Store, setNow func(time.Time)) {
+	ctx := context.Background()
+	now := time.Now()
+	setNow(now)
+
+	require.NoError(t, s.Set(ctx, "cybertron:job:a", []byte("1"), time.Minute))
+	require.NoError(t, s.Set(ctx, "cybertron:job:b", []byte("2"), 0))
+	v, ok, err := s.Get(ctx, "cybertron:job:a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), v)
+
+	_, ok, err = s.Get(ctx, "cybertron:job:c")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	setNow(now.Add(time.Minute))
+	_, ok, err = s.Get(ctx, "cybertron:job:a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	v, ok, err = s.Get(ctx, "cybertron:job:b")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("2"), v)
+}
+
+func TestDisk(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "jobs")
+	d := NewDisk(dir)
+	testStore(t, d, func(now time.Time) { d.now = func() time.Time { return now } })
+
+	// The expired file is removed by the next write.
+	require.NoError(t, d.Set(context.Background(), "cybertron:job:c", []byte("3"), 0))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestObjects(t *testing.T) {
+	o := NewObjects(storage.NewFileStore(t.TempDir()))
+	testStore(t, o, func(now time.Time) { o.now = func() time.Time { return now } })
+}
@@ -0,0 +1,50 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jobstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/storage"
+)
+
+// Objects is a store of the objects of a model store, an object per key.
+// The expired objects are ignored, but not removed, since the model stores
+// can't delete them: a lifecycle rule of the bucket, expiring its objects
+// after the TTL, keeps it from growing.
+type Objects struct {
+	s   storage.Store
+	now func() time.Time
+}
+
+var _ Store = &Objects{}
+
+// NewObjects returns the store of the objects of the model store.
+func NewObjects(s storage.Store) *Objects {
+	return &Objects{s: s, now: time.Now}
+}
+
+// Get returns the value of the key, and whether it's found.
+func (o *Objects) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	var buf bytes.Buffer
+	err := o.s.Get(ctx, url.PathEscape(key), &buf)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return parseValue(buf.Bytes(), o.now())
+}
+
+// Set sets the value of the key, expiring after the TTL, if positive.
+func (o *Objects) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	data := withExpiry(value, ttl, o.now())
+	return o.s.Put(ctx, url.PathEscape(key), bytes.NewReader(data), int64(len(data)))
+}
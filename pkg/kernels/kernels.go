@@ -0,0 +1,265 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package kernels provides the vectorized kernels of the hot loops of the
+// inference: the ones of the matrix products, softmax and layer
+// normalization of the onnx runtime, and the ones of the activation
+// functions of both backends, e.g. GELU.
+//
+// They're implemented in assembly for the instruction sets detected at
+// runtime, AVX-512 or AVX2 on amd64 (see kernels_amd64.go) and NEON on
+// arm64 (see kernels_arm64.go), falling back to pure Go elsewhere.
+package kernels
+
+import "math"
+
+var (
+	axpyKernel     = axpyGeneric
+	dotKernel      = dotGeneric
+	sumKernel      = sumGeneric
+	maxKernel      = maxGeneric
+	scaleKernel    = scaleGeneric
+	addConstKernel = addConstGeneric
+	// rationalKernel evaluates the rational approximation on the first
+	// elements of x, a multiple of rationalWidth, the others being left to
+	// rationalGeneric.
+	rationalKernel = rationalGeneric
+	rationalWidth  = 1
+)
+
+// isa is the instruction set used by the kernels.
+var isa = "generic"
+
+// sets set the kernels of the instruction sets supported by the CPU, by
+// name.
+var sets = map[string]func(){
+	"generic": func() {
+		axpyKernel, dotKernel, sumKernel = axpyGeneric, dotGeneric, sumGeneric
+		maxKernel, scaleKernel, addConstKernel = maxGeneric, scaleGeneric, addConstGeneric
+		rationalKernel, rationalWidth = rationalGeneric, 1
+		isa = "generic"
+	},
+}
+
+// ISA returns the instruction set used by the kernels: "avx512" or "avx2"
+// on the amd64 CPUs supporting them, preferring the former, "neon" on
+// arm64, or "generic" elsewhere.
+func ISA() string {
+	return isa
+}
+
+// Axpy adds alpha*x to y, which must be at least as long as x.
+func Axpy(alpha float32, x, y []float32) {
+	axpyKernel(alpha, x, y[:len(x)])
+}
+
+// AxpyGeneric is Axpy in pure Go, whatever the instruction set.
+func AxpyGeneric(alpha float32, x, y []float32) {
+	axpyGeneric(alpha, x, y[:len(x)])
+}
+
+// Dot returns the dot product of x and y, which must be at least as long
+// as x.
+func Dot(x, y []float32) float32 {
+	return dotKernel(x, y[:len(x)])
+}
+
+// Sum returns the sum of the elements of x.
+func Sum(x []float32) float32 {
+	return sumKernel(x)
+}
+
+// Max returns the maximum element of x, or -Inf if it's empty.
+func Max(x []float32) float32 {
+	if len(x) == 0 {
+		return float32(math.Inf(-1))
+	}
+	return maxKernel(x)
+}
+
+// Scale multiplies the elements of x by alpha.
+func Scale(alpha float32, x []float32) {
+	scaleKernel(alpha, x)
+}
+
+// AddConst adds c to the elements of x.
+func AddConst(c float32, x []float32) {
+	addConstKernel(c, x)
+}
+
+// Erf replaces the elements of x with their error function, within 5e-7
+// of math.Erf.
+func Erf(x []float32) {
+	rational(x, &erfRational)
+}
+
+// Tanh replaces the elements of x with their hyperbolic tangent, within
+// 5e-7 of math.Tanh.
+func Tanh(x []float32) {
+	rational(x, &tanhRational)
+}
+
+// chunk is the number of elements whose activations are computed at a
+// time, in a buffer on the stack.
+const chunk = 256
+
+// GELU replaces the elements of x with their Gaussian error linear unit,
+// x * (1 + erf(x / sqrt(2))) / 2.
+func GELU(x []float32) {
+	var buf [chunk]float32
+	for len(x) > 0 {
+		n := len(x)
+		if n > chunk {
+			n = chunk
+		}
+		e := buf[:n]
+		for i, v := range x[:n] {
+			e[i] = v * (1 / math.Sqrt2)
+		}
+		Erf(e)
+		for i, v := range e {
+			x[i] *= 0.5 * (1 + v)
+		}
+		x = x[n:]
+	}
+}
+
+// GELUTanh replaces the elements of x with the approximation of their
+// Gaussian error linear unit with the hyperbolic tangent,
+// x * (1 + tanh(sqrt(2/pi) * (x + 0.044715 * x^3))) / 2.
+func GELUTanh(x []float32) {
+	const c = 0.7978845608028654 // sqrt(2/pi)
+	var buf [chunk]float32
+	for len(x) > 0 {
+		n := len(x)
+		if n > chunk {
+			n = chunk
+		}
+		t := buf[:n]
+		for i, v := range x[:n] {
+			t[i] = c * (v + 0.044715*v*v*v)
+		}
+		Tanh(t)
+		for i, v := range t {
+			x[i] *= 0.5 * (1 + v)
+		}
+		x = x[n:]
+	}
+}
+
+// rationalCoefficients are the coefficients of an odd rational function
+// approximating an odd function f on [-bound, bound], as in Eigen:
+// x * p(x^2) / q(x^2), with the coefficients of the polynomials p and q
+// from the highest degree. The values are clamped to [-1, 1], the limits
+// of f, which the function is close enough to beyond the bound. The
+// assembly kernels depend on the layout.
+type rationalCoefficients struct {
+	bound float32
+	p     [7]float32
+	q     [5]float32
+}
+
+var erfRational = rationalCoefficients{
+	bound: 4.5,
+	p: [7]float32{
+		-2.72614225801306e-10, 2.77068142495902e-08, -2.10102402082508e-06, -5.69250639462346e-05,
+		-7.34990630326855e-04, -2.95459980854025e-03, -1.60960333262415e-02,
+	},
+	q: [5]float32{
+		-1.45660718464996e-05, -2.13374055278905e-04, -1.68282697438203e-03, -7.37332916720468e-03,
+		-1.42647390514189e-02,
+	},
+}
+
+var tanhRational = rationalCoefficients{
+	bound: 10,
+	p: [7]float32{
+		-2.76076847742355e-16, 2.00018790482477e-13, -8.60467152213735e-11, 5.12229709037114e-08,
+		1.48572235717979e-05, 6.37261928875436e-04, 4.89352455891786e-03,
+	},
+	q: [5]float32{
+		0, 1.19825839466702e-06, 1.18534705686654e-04, 2.26843463243900e-03,
+		4.89352518554385e-03,
+	},
+}
+
+// rational replaces the elements of x with the rational function.
+func rational(x []float32, c *rationalCoefficients) {
+	n := len(x) - len(x)%rationalWidth
+	if n > 0 {
+		rationalKernel(x[:n], c)
+	}
+	rationalGeneric(x[n:], c)
+}
+
+func rationalGeneric(x []float32, c *rationalCoefficients) {
+	for i, v := range x {
+		v = clamp(v, c.bound)
+		v2 := v * v
+		p := c.p[0]
+		for _, a := range c.p[1:] {
+			p = p*v2 + a
+		}
+		q := c.q[0]
+		for _, b := range c.q[1:] {
+			q = q*v2 + b
+		}
+		x[i] = clamp(v*p/q, 1)
+	}
+}
+
+// clamp returns v clamped to [-bound, bound].
+func clamp(v, bound float32) float32 {
+	if v > bound {
+		return bound
+	}
+	if v < -bound {
+		return -bound
+	}
+	return v
+}
+
+func axpyGeneric(alpha float32, x, y []float32) {
+	for i, v := range x {
+		y[i] += alpha * v
+	}
+}
+
+func dotGeneric(x, y []float32) float32 {
+	var s float32
+	for i, v := range x {
+		s += v * y[i]
+	}
+	return s
+}
+
+func sumGeneric(x []float32) float32 {
+	var s float32
+	for _, v := range x {
+		s += v
+	}
+	return s
+}
+
+func maxGeneric(x []float32) float32 {
+	m := x[0]
+	for _, v := range x[1:] {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
+
+func scaleGeneric(alpha float32, x []float32) {
+	for i := range x {
+		x[i] *= alpha
+	}
+}
+
+func addConstGeneric(c float32, x []float32) {
+	for i := range x {
+		x[i] += c
+	}
+}
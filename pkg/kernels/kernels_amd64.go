@@ -0,0 +1,76 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kernels
+
+import "golang.org/x/sys/cpu"
+
+func init() {
+	if cpu.X86.HasAVX2 && cpu.X86.HasFMA {
+		sets["avx2"] = func() {
+			axpyKernel, dotKernel, sumKernel = axpyAVX2, dotAVX2, sumAVX2
+			maxKernel, scaleKernel, addConstKernel = maxAVX2, scaleAVX2, addConstAVX2
+			rationalKernel, rationalWidth = rationalAVX2, 8
+			isa = "avx2"
+		}
+		sets["avx2"]()
+	}
+	// The support of the 512-bit registers by the OS is checked as well.
+	if cpu.X86.HasAVX512F {
+		sets["avx512"] = func() {
+			axpyKernel, dotKernel, sumKernel = axpyAVX512, dotAVX512, sumAVX512
+			maxKernel, scaleKernel, addConstKernel = maxAVX512, scaleAVX512, addConstAVX512
+			rationalKernel, rationalWidth = rationalAVX512, 16
+			isa = "avx512"
+		}
+		sets["avx512"]()
+	}
+}
+
+// The AVX2 kernels are implemented in kernels_amd64.s, the AVX-512 ones in
+// kernels_avx512_amd64.s. The slices are expected to have the same length,
+// x not to be empty for the max kernels, and its length to be a multiple of
+// the width of the registers for the rational kernels.
+
+//go:noescape
+func axpyAVX2(alpha float32, x, y []float32)
+
+//go:noescape
+func dotAVX2(x, y []float32) float32
+
+//go:noescape
+func sumAVX2(x []float32) float32
+
+//go:noescape
+func maxAVX2(x []float32) float32
+
+//go:noescape
+func scaleAVX2(alpha float32, x []float32)
+
+//go:noescape
+func addConstAVX2(c float32, x []float32)
+
+//go:noescape
+func rationalAVX2(x []float32, c *rationalCoefficients)
+
+//go:noescape
+func axpyAVX512(alpha float32, x, y []float32)
+
+//go:noescape
+func dotAVX512(x, y []float32) float32
+
+//go:noescape
+func sumAVX512(x []float32) float32
+
+//go:noescape
+func maxAVX512(x []float32) float32
+
+//go:noescape
+func scaleAVX512(alpha float32, x []float32)
+
+//go:noescape
+func addConstAVX512(c float32, x []float32)
+
+//go:noescape
+func rationalAVX512(x []float32, c *rationalCoefficients)
@@ -0,0 +1,394 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include "textflag.h"
+
+// The loops process 32 elements per iteration, with four independent
+// 8-lane registers to hide the latency of the instructions, then 8
+// elements per iteration, then the remaining ones one by one.
+
+// func axpyAVX2(alpha float32, x, y []float32)
+TEXT ·axpyAVX2(SB), NOSPLIT, $0-56
+	VBROADCASTSS alpha+0(FP), Y0
+	MOVQ         x_base+8(FP), SI
+	MOVQ         y_base+32(FP), DI
+	MOVQ         x_len+16(FP), CX
+	XORQ         AX, AX
+	MOVQ         CX, DX
+	ANDQ         $-32, DX
+	JZ           axpy8
+
+axpy32:
+	VMOVUPS     (SI)(AX*4), Y1
+	VMOVUPS     32(SI)(AX*4), Y2
+	VMOVUPS     64(SI)(AX*4), Y3
+	VMOVUPS     96(SI)(AX*4), Y4
+	VFMADD213PS (DI)(AX*4), Y0, Y1
+	VFMADD213PS 32(DI)(AX*4), Y0, Y2
+	VFMADD213PS 64(DI)(AX*4), Y0, Y3
+	VFMADD213PS 96(DI)(AX*4), Y0, Y4
+	VMOVUPS     Y1, (DI)(AX*4)
+	VMOVUPS     Y2, 32(DI)(AX*4)
+	VMOVUPS     Y3, 64(DI)(AX*4)
+	VMOVUPS     Y4, 96(DI)(AX*4)
+	ADDQ        $32, AX
+	CMPQ        AX, DX
+	JL          axpy32
+
+axpy8:
+	MOVQ CX, DX
+	ANDQ $-8, DX
+	CMPQ AX, DX
+	JGE  axpy1
+
+axpy8loop:
+	VMOVUPS     (SI)(AX*4), Y1
+	VFMADD213PS (DI)(AX*4), Y0, Y1
+	VMOVUPS     Y1, (DI)(AX*4)
+	ADDQ        $8, AX
+	CMPQ        AX, DX
+	JL          axpy8loop
+
+axpy1:
+	CMPQ AX, CX
+	JGE  axpydone
+	VMOVSS      (SI)(AX*4), X1
+	VFMADD213SS (DI)(AX*4), X0, X1
+	VMOVSS      X1, (DI)(AX*4)
+	INCQ        AX
+	JMP         axpy1
+
+axpydone:
+	VZEROUPPER
+	RET
+
+// func dotAVX2(x, y []float32) float32
+TEXT ·dotAVX2(SB), NOSPLIT, $0-52
+	MOVQ   x_base+0(FP), SI
+	MOVQ   y_base+24(FP), DI
+	MOVQ   x_len+8(FP), CX
+	VXORPS Y0, Y0, Y0
+	VXORPS Y1, Y1, Y1
+	VXORPS Y2, Y2, Y2
+	VXORPS Y3, Y3, Y3
+	XORQ   AX, AX
+	MOVQ   CX, DX
+	ANDQ   $-32, DX
+	JZ     dot8
+
+dot32:
+	VMOVUPS     (SI)(AX*4), Y4
+	VMOVUPS     32(SI)(AX*4), Y5
+	VMOVUPS     64(SI)(AX*4), Y6
+	VMOVUPS     96(SI)(AX*4), Y7
+	VFMADD231PS (DI)(AX*4), Y4, Y0
+	VFMADD231PS 32(DI)(AX*4), Y5, Y1
+	VFMADD231PS 64(DI)(AX*4), Y6, Y2
+	VFMADD231PS 96(DI)(AX*4), Y7, Y3
+	ADDQ        $32, AX
+	CMPQ        AX, DX
+	JL          dot32
+
+dot8:
+	MOVQ CX, DX
+	ANDQ $-8, DX
+	CMPQ AX, DX
+	JGE  dotreduce
+
+dot8loop:
+	VMOVUPS     (SI)(AX*4), Y4
+	VFMADD231PS (DI)(AX*4), Y4, Y0
+	ADDQ        $8, AX
+	CMPQ        AX, DX
+	JL          dot8loop
+
+dotreduce:
+	VADDPS       Y1, Y0, Y0
+	VADDPS       Y3, Y2, Y2
+	VADDPS       Y2, Y0, Y0
+	VEXTRACTF128 $1, Y0, X1
+	VADDPS       X1, X0, X0
+	VHADDPS      X0, X0, X0
+	VHADDPS      X0, X0, X0
+
+dot1:
+	CMPQ AX, CX
+	JGE  dotdone
+	VMOVSS      (SI)(AX*4), X1
+	VFMADD231SS (DI)(AX*4), X1, X0
+	INCQ        AX
+	JMP         dot1
+
+dotdone:
+	VMOVSS X0, ret+48(FP)
+	VZEROUPPER
+	RET
+
+// func sumAVX2(x []float32) float32
+TEXT ·sumAVX2(SB), NOSPLIT, $0-28
+	MOVQ   x_base+0(FP), SI
+	MOVQ   x_len+8(FP), CX
+	VXORPS Y0, Y0, Y0
+	VXORPS Y1, Y1, Y1
+	VXORPS Y2, Y2, Y2
+	VXORPS Y3, Y3, Y3
+	XORQ   AX, AX
+	MOVQ   CX, DX
+	ANDQ   $-32, DX
+	JZ     sum8
+
+sum32:
+	VADDPS (SI)(AX*4), Y0, Y0
+	VADDPS 32(SI)(AX*4), Y1, Y1
+	VADDPS 64(SI)(AX*4), Y2, Y2
+	VADDPS 96(SI)(AX*4), Y3, Y3
+	ADDQ   $32, AX
+	CMPQ   AX, DX
+	JL     sum32
+
+sum8:
+	MOVQ CX, DX
+	ANDQ $-8, DX
+	CMPQ AX, DX
+	JGE  sumreduce
+
+sum8loop:
+	VADDPS (SI)(AX*4), Y0, Y0
+	ADDQ   $8, AX
+	CMPQ   AX, DX
+	JL     sum8loop
+
+sumreduce:
+	VADDPS       Y1, Y0, Y0
+	VADDPS       Y3, Y2, Y2
+	VADDPS       Y2, Y0, Y0
+	VEXTRACTF128 $1, Y0, X1
+	VADDPS       X1, X0, X0
+	VHADDPS      X0, X0, X0
+	VHADDPS      X0, X0, X0
+
+sum1:
+	CMPQ AX, CX
+	JGE  sumdone
+	VADDSS (SI)(AX*4), X0, X0
+	INCQ   AX
+	JMP    sum1
+
+sumdone:
+	VMOVSS X0, ret+24(FP)
+	VZEROUPPER
+	RET
+
+// func maxAVX2(x []float32) float32
+TEXT ·maxAVX2(SB), NOSPLIT, $0-28
+	MOVQ         x_base+0(FP), SI
+	MOVQ         x_len+8(FP), CX
+	VBROADCASTSS (SI), Y0
+	VMOVUPS      Y0, Y1
+	VMOVUPS      Y0, Y2
+	VMOVUPS      Y0, Y3
+	XORQ         AX, AX
+	MOVQ         CX, DX
+	ANDQ         $-32, DX
+	JZ           max8
+
+max32:
+	VMAXPS (SI)(AX*4), Y0, Y0
+	VMAXPS 32(SI)(AX*4), Y1, Y1
+	VMAXPS 64(SI)(AX*4), Y2, Y2
+	VMAXPS 96(SI)(AX*4), Y3, Y3
+	ADDQ   $32, AX
+	CMPQ   AX, DX
+	JL     max32
+
+max8:
+	MOVQ CX, DX
+	ANDQ $-8, DX
+	CMPQ AX, DX
+	JGE  maxreduce
+
+max8loop:
+	VMAXPS (SI)(AX*4), Y0, Y0
+	ADDQ   $8, AX
+	CMPQ   AX, DX
+	JL     max8loop
+
+maxreduce:
+	VMAXPS       Y1, Y0, Y0
+	VMAXPS       Y3, Y2, Y2
+	VMAXPS       Y2, Y0, Y0
+	VEXTRACTF128 $1, Y0, X1
+	VMAXPS       X1, X0, X0
+	VPERMILPS    $0x4e, X0, X1
+	VMAXPS       X1, X0, X0
+	VPERMILPS    $0xb1, X0, X1
+	VMAXPS       X1, X0, X0
+
+max1:
+	CMPQ AX, CX
+	JGE  maxdone
+	VMAXSS (SI)(AX*4), X0, X0
+	INCQ   AX
+	JMP    max1
+
+maxdone:
+	VMOVSS X0, ret+24(FP)
+	VZEROUPPER
+	RET
+
+// func scaleAVX2(alpha float32, x []float32)
+TEXT ·scaleAVX2(SB), NOSPLIT, $0-32
+	VBROADCASTSS alpha+0(FP), Y0
+	MOVQ         x_base+8(FP), SI
+	MOVQ         x_len+16(FP), CX
+	XORQ         AX, AX
+	MOVQ         CX, DX
+	ANDQ         $-32, DX
+	JZ           scale8
+
+scale32:
+	VMULPS  (SI)(AX*4), Y0, Y1
+	VMULPS  32(SI)(AX*4), Y0, Y2
+	VMULPS  64(SI)(AX*4), Y0, Y3
+	VMULPS  96(SI)(AX*4), Y0, Y4
+	VMOVUPS Y1, (SI)(AX*4)
+	VMOVUPS Y2, 32(SI)(AX*4)
+	VMOVUPS Y3, 64(SI)(AX*4)
+	VMOVUPS Y4, 96(SI)(AX*4)
+	ADDQ    $32, AX
+	CMPQ    AX, DX
+	JL      scale32
+
+scale8:
+	MOVQ CX, DX
+	ANDQ $-8, DX
+	CMPQ AX, DX
+	JGE  scale1
+
+scale8loop:
+	VMULPS  (SI)(AX*4), Y0, Y1
+	VMOVUPS Y1, (SI)(AX*4)
+	ADDQ    $8, AX
+	CMPQ    AX, DX
+	JL      scale8loop
+
+scale1:
+	CMPQ AX, CX
+	JGE  scaledone
+	VMULSS (SI)(AX*4), X0, X1
+	VMOVSS X1, (SI)(AX*4)
+	INCQ   AX
+	JMP    scale1
+
+scaledone:
+	VZEROUPPER
+	RET
+
+// func addConstAVX2(c float32, x []float32)
+TEXT ·addConstAVX2(SB), NOSPLIT, $0-32
+	VBROADCASTSS c+0(FP), Y0
+	MOVQ         x_base+8(FP), SI
+	MOVQ         x_len+16(FP), CX
+	XORQ         AX, AX
+	MOVQ         CX, DX
+	ANDQ         $-32, DX
+	JZ           add8
+
+add32:
+	VADDPS  (SI)(AX*4), Y0, Y1
+	VADDPS  32(SI)(AX*4), Y0, Y2
+	VADDPS  64(SI)(AX*4), Y0, Y3
+	VADDPS  96(SI)(AX*4), Y0, Y4
+	VMOVUPS Y1, (SI)(AX*4)
+	VMOVUPS Y2, 32(SI)(AX*4)
+	VMOVUPS Y3, 64(SI)(AX*4)
+	VMOVUPS Y4, 96(SI)(AX*4)
+	ADDQ    $32, AX
+	CMPQ    AX, DX
+	JL      add32
+
+add8:
+	MOVQ CX, DX
+	ANDQ $-8, DX
+	CMPQ AX, DX
+	JGE  add1
+
+add8loop:
+	VADDPS  (SI)(AX*4), Y0, Y1
+	VMOVUPS Y1, (SI)(AX*4)
+	ADDQ    $8, AX
+	CMPQ    AX, DX
+	JL      add8loop
+
+add1:
+	CMPQ AX, CX
+	JGE  adddone
+	VADDSS (SI)(AX*4), X0, X1
+	VMOVSS X1, (SI)(AX*4)
+	INCQ   AX
+	JMP    add1
+
+adddone:
+	VZEROUPPER
+	RET
+
+// The rational kernel evaluates x * p(x^2) / q(x^2) on 8 elements per
+// iteration, clamping x to [-bound, bound] and the result to [-1, 1], with
+// the NaNs as the second operands of the comparisons to propagate them.
+DATA one<>+0(SB)/4, $0x3f800000
+GLOBL one<>(SB), RODATA|NOPTR, $4
+
+// func rationalAVX2(x []float32, c *rationalCoefficients)
+TEXT ·rationalAVX2(SB), NOSPLIT, $0-32
+	MOVQ         x_base+0(FP), SI
+	MOVQ         x_len+8(FP), CX
+	MOVQ         c+24(FP), DX
+	VBROADCASTSS (DX), Y0
+	VXORPS       Y1, Y1, Y1
+	VSUBPS       Y0, Y1, Y1
+	VBROADCASTSS one<>(SB), Y2
+	VXORPS       Y3, Y3, Y3
+	VSUBPS       Y2, Y3, Y3
+	XORQ         AX, AX
+
+rational:
+	CMPQ         AX, CX
+	JGE          rationaldone
+	VMOVUPS      (SI)(AX*4), Y4
+	VMINPS       Y4, Y0, Y4
+	VMAXPS       Y4, Y1, Y4
+	VMULPS       Y4, Y4, Y5
+	VBROADCASTSS 4(DX), Y6
+	VBROADCASTSS 8(DX), Y7
+	VFMADD213PS  Y7, Y5, Y6
+	VBROADCASTSS 12(DX), Y7
+	VFMADD213PS  Y7, Y5, Y6
+	VBROADCASTSS 16(DX), Y7
+	VFMADD213PS  Y7, Y5, Y6
+	VBROADCASTSS 20(DX), Y7
+	VFMADD213PS  Y7, Y5, Y6
+	VBROADCASTSS 24(DX), Y7
+	VFMADD213PS  Y7, Y5, Y6
+	VBROADCASTSS 28(DX), Y7
+	VFMADD213PS  Y7, Y5, Y6
+	VMULPS       Y6, Y4, Y6
+	VBROADCASTSS 32(DX), Y8
+	VBROADCASTSS 36(DX), Y7
+	VFMADD213PS  Y7, Y5, Y8
+	VBROADCASTSS 40(DX), Y7
+	VFMADD213PS  Y7, Y5, Y8
+	VBROADCASTSS 44(DX), Y7
+	VFMADD213PS  Y7, Y5, Y8
+	VBROADCASTSS 48(DX), Y7
+	VFMADD213PS  Y7, Y5, Y8
+	VDIVPS       Y8, Y6, Y6
+	VMINPS       Y6, Y2, Y6
+	VMAXPS       Y6, Y3, Y6
+	VMOVUPS      Y6, (SI)(AX*4)
+	ADDQ         $8, AX
+	JMP          rational
+
+rationaldone:
+	VZEROUPPER
+	RET
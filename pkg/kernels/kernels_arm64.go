@@ -0,0 +1,41 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kernels
+
+func init() {
+	// NEON is part of the baseline of arm64.
+	sets["neon"] = func() {
+		axpyKernel, dotKernel, sumKernel = axpyNEON, dotNEON, sumNEON
+		maxKernel, scaleKernel, addConstKernel = maxNEON, scaleNEON, addConstNEON
+		rationalKernel, rationalWidth = rationalNEON, 4
+		isa = "neon"
+	}
+	sets["neon"]()
+}
+
+// The NEON kernels are implemented in kernels_arm64.s. The slices are
+// expected to have the same length, x not to be empty for the max kernel,
+// and its length to be a multiple of 4 for the rational kernel.
+
+//go:noescape
+func axpyNEON(alpha float32, x, y []float32)
+
+//go:noescape
+func dotNEON(x, y []float32) float32
+
+//go:noescape
+func sumNEON(x []float32) float32
+
+//go:noescape
+func maxNEON(x []float32) float32
+
+//go:noescape
+func scaleNEON(alpha float32, x []float32)
+
+//go:noescape
+func addConstNEON(c float32, x []float32)
+
+//go:noescape
+func rationalNEON(x []float32, c *rationalCoefficients)
@@ -0,0 +1,354 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include "textflag.h"
+
+// The loops process 16 elements per iteration, with four independent
+// 4-lane registers to hide the latency of the instructions, then 4
+// elements per iteration, then the remaining ones one by one.
+//
+// The Go assembler doesn't support the arithmetic instructions on vectors
+// of floats but FMLA, which are encoded with WORD.
+
+// func axpyNEON(alpha float32, x, y []float32)
+TEXT ·axpyNEON(SB), NOSPLIT, $0-56
+	FMOVS alpha+0(FP), F0
+	MOVD  x_base+8(FP), R0
+	MOVD  x_len+16(FP), R2
+	MOVD  y_base+32(FP), R1
+	VDUP  V0.S[0], V0.S4
+
+axpy16:
+	CMP    $16, R2
+	BLT    axpy4
+	VLD1.P 64(R0), [V1.S4, V2.S4, V3.S4, V4.S4]
+	VLD1   (R1), [V5.S4, V6.S4, V7.S4, V8.S4]
+	VFMLA  V0.S4, V1.S4, V5.S4
+	VFMLA  V0.S4, V2.S4, V6.S4
+	VFMLA  V0.S4, V3.S4, V7.S4
+	VFMLA  V0.S4, V4.S4, V8.S4
+	VST1.P [V5.S4, V6.S4, V7.S4, V8.S4], 64(R1)
+	SUB    $16, R2
+	B      axpy16
+
+axpy4:
+	CMP    $4, R2
+	BLT    axpy1
+	VLD1.P 16(R0), [V1.S4]
+	VLD1   (R1), [V5.S4]
+	VFMLA  V0.S4, V1.S4, V5.S4
+	VST1.P [V5.S4], 16(R1)
+	SUB    $4, R2
+	B      axpy4
+
+axpy1:
+	CBZ     R2, axpydone
+	FMOVS.P 4(R0), F1
+	FMOVS   (R1), F2
+	FMADDS  F0, F2, F1, F2
+	FMOVS.P F2, 4(R1)
+	SUB     $1, R2
+	B       axpy1
+
+axpydone:
+	RET
+
+// func dotNEON(x, y []float32) float32
+TEXT ·dotNEON(SB), NOSPLIT, $0-52
+	MOVD x_base+0(FP), R0
+	MOVD x_len+8(FP), R2
+	MOVD y_base+24(FP), R1
+	VEOR V16.B16, V16.B16, V16.B16
+	VEOR V17.B16, V17.B16, V17.B16
+	VEOR V18.B16, V18.B16, V18.B16
+	VEOR V19.B16, V19.B16, V19.B16
+
+dot16:
+	CMP    $16, R2
+	BLT    dot4
+	VLD1.P 64(R0), [V1.S4, V2.S4, V3.S4, V4.S4]
+	VLD1.P 64(R1), [V5.S4, V6.S4, V7.S4, V8.S4]
+	VFMLA  V1.S4, V5.S4, V16.S4
+	VFMLA  V2.S4, V6.S4, V17.S4
+	VFMLA  V3.S4, V7.S4, V18.S4
+	VFMLA  V4.S4, V8.S4, V19.S4
+	SUB    $16, R2
+	B      dot16
+
+dot4:
+	CMP    $4, R2
+	BLT    dotreduce
+	VLD1.P 16(R0), [V1.S4]
+	VLD1.P 16(R1), [V5.S4]
+	VFMLA  V1.S4, V5.S4, V16.S4
+	SUB    $4, R2
+	B      dot4
+
+dotreduce:
+	WORD $0x4e31d610 // FADD V16.4S, V16.4S, V17.4S
+	WORD $0x4e33d652 // FADD V18.4S, V18.4S, V19.4S
+	WORD $0x4e32d610 // FADD V16.4S, V16.4S, V18.4S
+	WORD $0x6e30d610 // FADDP V16.4S, V16.4S, V16.4S
+	WORD $0x7e30da10 // FADDP S16, V16.2S
+
+dot1:
+	CBZ     R2, dotdone
+	FMOVS.P 4(R0), F1
+	FMOVS.P 4(R1), F5
+	FMADDS  F1, F16, F5, F16
+	SUB     $1, R2
+	B       dot1
+
+dotdone:
+	FMOVS F16, ret+48(FP)
+	RET
+
+// func sumNEON(x []float32) float32
+TEXT ·sumNEON(SB), NOSPLIT, $0-28
+	MOVD x_base+0(FP), R0
+	MOVD x_len+8(FP), R2
+	VEOR V16.B16, V16.B16, V16.B16
+	VEOR V17.B16, V17.B16, V17.B16
+	VEOR V18.B16, V18.B16, V18.B16
+	VEOR V19.B16, V19.B16, V19.B16
+
+sum16:
+	CMP    $16, R2
+	BLT    sum4
+	VLD1.P 64(R0), [V1.S4, V2.S4, V3.S4, V4.S4]
+	WORD $0x4e21d610 // FADD V16.4S, V16.4S, V1.4S
+	WORD $0x4e22d631 // FADD V17.4S, V17.4S, V2.4S
+	WORD $0x4e23d652 // FADD V18.4S, V18.4S, V3.4S
+	WORD $0x4e24d673 // FADD V19.4S, V19.4S, V4.4S
+	SUB    $16, R2
+	B      sum16
+
+sum4:
+	CMP    $4, R2
+	BLT    sumreduce
+	VLD1.P 16(R0), [V1.S4]
+	WORD $0x4e21d610 // FADD V16.4S, V16.4S, V1.4S
+	SUB    $4, R2
+	B      sum4
+
+sumreduce:
+	WORD $0x4e31d610 // FADD V16.4S, V16.4S, V17.4S
+	WORD $0x4e33d652 // FADD V18.4S, V18.4S, V19.4S
+	WORD $0x4e32d610 // FADD V16.4S, V16.4S, V18.4S
+	WORD $0x6e30d610 // FADDP V16.4S, V16.4S, V16.4S
+	WORD $0x7e30da10 // FADDP S16, V16.2S
+
+sum1:
+	CBZ     R2, sumdone
+	FMOVS.P 4(R0), F1
+	FADDS   F1, F16
+	SUB     $1, R2
+	B       sum1
+
+sumdone:
+	FMOVS F16, ret+24(FP)
+	RET
+
+// func maxNEON(x []float32) float32
+TEXT ·maxNEON(SB), NOSPLIT, $0-28
+	MOVD x_base+0(FP), R0
+	MOVD x_len+8(FP), R2
+	FMOVS (R0), F16
+	VDUP  V16.S[0], V16.S4
+	VMOV  V16.B16, V17.B16
+	VMOV  V16.B16, V18.B16
+	VMOV  V16.B16, V19.B16
+
+max16:
+	CMP    $16, R2
+	BLT    max4
+	VLD1.P 64(R0), [V1.S4, V2.S4, V3.S4, V4.S4]
+	WORD $0x4e21f610 // FMAX V16.4S, V16.4S, V1.4S
+	WORD $0x4e22f631 // FMAX V17.4S, V17.4S, V2.4S
+	WORD $0x4e23f652 // FMAX V18.4S, V18.4S, V3.4S
+	WORD $0x4e24f673 // FMAX V19.4S, V19.4S, V4.4S
+	SUB    $16, R2
+	B      max16
+
+max4:
+	CMP    $4, R2
+	BLT    maxreduce
+	VLD1.P 16(R0), [V1.S4]
+	WORD $0x4e21f610 // FMAX V16.4S, V16.4S, V1.4S
+	SUB    $4, R2
+	B      max4
+
+maxreduce:
+	WORD $0x4e31f610 // FMAX V16.4S, V16.4S, V17.4S
+	WORD $0x4e33f652 // FMAX V18.4S, V18.4S, V19.4S
+	WORD $0x4e32f610 // FMAX V16.4S, V16.4S, V18.4S
+	WORD $0x6e30fa10 // FMAXV S16, V16.4S
+
+max1:
+	CBZ     R2, maxdone
+	FMOVS.P 4(R0), F1
+	FMAXS   F1, F16
+	SUB     $1, R2
+	B       max1
+
+maxdone:
+	FMOVS F16, ret+24(FP)
+	RET
+
+// func scaleNEON(alpha float32, x []float32)
+TEXT ·scaleNEON(SB), NOSPLIT, $0-32
+	FMOVS alpha+0(FP), F0
+	MOVD  x_base+8(FP), R0
+	MOVD  x_len+16(FP), R2
+	VDUP  V0.S[0], V0.S4
+
+scale16:
+	CMP    $16, R2
+	BLT    scale4
+	VLD1   (R0), [V1.S4, V2.S4, V3.S4, V4.S4]
+	WORD $0x6e20dc21 // FMUL V1.4S, V1.4S, V0.4S
+	WORD $0x6e20dc42 // FMUL V2.4S, V2.4S, V0.4S
+	WORD $0x6e20dc63 // FMUL V3.4S, V3.4S, V0.4S
+	WORD $0x6e20dc84 // FMUL V4.4S, V4.4S, V0.4S
+	VST1.P [V1.S4, V2.S4, V3.S4, V4.S4], 64(R0)
+	SUB    $16, R2
+	B      scale16
+
+scale4:
+	CMP    $4, R2
+	BLT    scale1
+	VLD1   (R0), [V1.S4]
+	WORD $0x6e20dc21 // FMUL V1.4S, V1.4S, V0.4S
+	VST1.P [V1.S4], 16(R0)
+	SUB    $4, R2
+	B      scale4
+
+scale1:
+	CBZ     R2, scaledone
+	FMOVS   (R0), F1
+	FMULS   F0, F1
+	FMOVS.P F1, 4(R0)
+	SUB     $1, R2
+	B       scale1
+
+scaledone:
+	RET
+
+// func addConstNEON(c float32, x []float32)
+TEXT ·addConstNEON(SB), NOSPLIT, $0-32
+	FMOVS c+0(FP), F0
+	MOVD  x_base+8(FP), R0
+	MOVD  x_len+16(FP), R2
+	VDUP  V0.S[0], V0.S4
+
+addConst16:
+	CMP    $16, R2
+	BLT    addConst4
+	VLD1   (R0), [V1.S4, V2.S4, V3.S4, V4.S4]
+	WORD $0x4e20d421 // FADD V1.4S, V1.4S, V0.4S
+	WORD $0x4e20d442 // FADD V2.4S, V2.4S, V0.4S
+	WORD $0x4e20d463 // FADD V3.4S, V3.4S, V0.4S
+	WORD $0x4e20d484 // FADD V4.4S, V4.4S, V0.4S
+	VST1.P [V1.S4, V2.S4, V3.S4, V4.S4], 64(R0)
+	SUB    $16, R2
+	B      addConst16
+
+addConst4:
+	CMP    $4, R2
+	BLT    addConst1
+	VLD1   (R0), [V1.S4]
+	WORD $0x4e20d421 // FADD V1.4S, V1.4S, V0.4S
+	VST1.P [V1.S4], 16(R0)
+	SUB    $4, R2
+	B      addConst4
+
+addConst1:
+	CBZ     R2, addConstdone
+	FMOVS   (R0), F1
+	FADDS   F0, F1
+	FMOVS.P F1, 4(R0)
+	SUB     $1, R2
+	B       addConst1
+
+addConstdone:
+	RET
+
+// The rational kernel evaluates x * p(x^2) / q(x^2) on 4 elements per
+// iteration, clamping x to [-bound, bound] and the result to [-1, 1]. FMIN
+// and FMAX propagate the NaNs. The coefficients are kept in V16-V27.
+
+// func rationalNEON(x []float32, c *rationalCoefficients)
+TEXT ·rationalNEON(SB), NOSPLIT, $0-32
+	MOVD  x_base+0(FP), R0
+	MOVD  x_len+8(FP), R2
+	MOVD  c+24(FP), R1
+	FMOVS (R1), F0
+	VDUP  V0.S[0], V0.S4
+	WORD $0x6ea0f801 // FNEG V1.4S, V0.4S
+	FMOVS $1.0, F2
+	VDUP  V2.S[0], V2.S4
+	WORD $0x6ea0f843 // FNEG V3.4S, V2.4S
+	FMOVS 4(R1), F16
+	VDUP  V16.S[0], V16.S4
+	FMOVS 8(R1), F17
+	VDUP  V17.S[0], V17.S4
+	FMOVS 12(R1), F18
+	VDUP  V18.S[0], V18.S4
+	FMOVS 16(R1), F19
+	VDUP  V19.S[0], V19.S4
+	FMOVS 20(R1), F20
+	VDUP  V20.S[0], V20.S4
+	FMOVS 24(R1), F21
+	VDUP  V21.S[0], V21.S4
+	FMOVS 28(R1), F22
+	VDUP  V22.S[0], V22.S4
+	FMOVS 32(R1), F23
+	VDUP  V23.S[0], V23.S4
+	FMOVS 36(R1), F24
+	VDUP  V24.S[0], V24.S4
+	FMOVS 40(R1), F25
+	VDUP  V25.S[0], V25.S4
+	FMOVS 44(R1), F26
+	VDUP  V26.S[0], V26.S4
+	FMOVS 48(R1), F27
+	VDUP  V27.S[0], V27.S4
+
+rational:
+	CBZ    R2, rationaldone
+	VLD1   (R0), [V4.S4]
+	WORD $0x4ea0f484 // FMIN V4.4S, V4.4S, V0.4S
+	WORD $0x4e21f484 // FMAX V4.4S, V4.4S, V1.4S
+	WORD $0x6e24dc85 // FMUL V5.4S, V4.4S, V4.4S
+	VMOV   V16.B16, V6.B16
+	VMOV   V17.B16, V7.B16
+	VFMLA  V5.S4, V6.S4, V7.S4
+	VMOV   V18.B16, V6.B16
+	VFMLA  V5.S4, V7.S4, V6.S4
+	VMOV   V19.B16, V7.B16
+	VFMLA  V5.S4, V6.S4, V7.S4
+	VMOV   V20.B16, V6.B16
+	VFMLA  V5.S4, V7.S4, V6.S4
+	VMOV   V21.B16, V7.B16
+	VFMLA  V5.S4, V6.S4, V7.S4
+	VMOV   V22.B16, V6.B16
+	VFMLA  V5.S4, V7.S4, V6.S4
+	WORD $0x6e24dcc6 // FMUL V6.4S, V6.4S, V4.4S
+	VMOV   V23.B16, V8.B16
+	VMOV   V24.B16, V9.B16
+	VFMLA  V5.S4, V8.S4, V9.S4
+	VMOV   V25.B16, V8.B16
+	VFMLA  V5.S4, V9.S4, V8.S4
+	VMOV   V26.B16, V9.B16
+	VFMLA  V5.S4, V8.S4, V9.S4
+	VMOV   V27.B16, V8.B16
+	VFMLA  V5.S4, V9.S4, V8.S4
+	WORD $0x6e28fcc6 // FDIV V6.4S, V6.4S, V8.4S
+	WORD $0x4ea2f4c6 // FMIN V6.4S, V6.4S, V2.4S
+	WORD $0x4e23f4c6 // FMAX V6.4S, V6.4S, V3.4S
+	VST1.P [V6.S4], 16(R0)
+	SUB    $4, R2
+	B      rational
+
+rationaldone:
+	RET
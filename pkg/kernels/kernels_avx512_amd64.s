@@ -0,0 +1,400 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include "textflag.h"
+
+// The loops process 64 elements per iteration, with four independent
+// 16-lane registers to hide the latency of the instructions, then 16
+// elements per iteration, then the remaining ones one by one.
+
+// func axpyAVX512(alpha float32, x, y []float32)
+TEXT ·axpyAVX512(SB), NOSPLIT, $0-56
+	VBROADCASTSS alpha+0(FP), Z0
+	MOVQ         x_base+8(FP), SI
+	MOVQ         y_base+32(FP), DI
+	MOVQ         x_len+16(FP), CX
+	XORQ         AX, AX
+	MOVQ         CX, DX
+	ANDQ         $-64, DX
+	JZ           axpy16
+
+axpy64:
+	VMOVUPS     (SI)(AX*4), Z1
+	VMOVUPS     64(SI)(AX*4), Z2
+	VMOVUPS     128(SI)(AX*4), Z3
+	VMOVUPS     192(SI)(AX*4), Z4
+	VFMADD213PS (DI)(AX*4), Z0, Z1
+	VFMADD213PS 64(DI)(AX*4), Z0, Z2
+	VFMADD213PS 128(DI)(AX*4), Z0, Z3
+	VFMADD213PS 192(DI)(AX*4), Z0, Z4
+	VMOVUPS     Z1, (DI)(AX*4)
+	VMOVUPS     Z2, 64(DI)(AX*4)
+	VMOVUPS     Z3, 128(DI)(AX*4)
+	VMOVUPS     Z4, 192(DI)(AX*4)
+	ADDQ        $64, AX
+	CMPQ        AX, DX
+	JL          axpy64
+
+axpy16:
+	MOVQ CX, DX
+	ANDQ $-16, DX
+	CMPQ AX, DX
+	JGE  axpy1
+
+axpy16loop:
+	VMOVUPS     (SI)(AX*4), Z1
+	VFMADD213PS (DI)(AX*4), Z0, Z1
+	VMOVUPS     Z1, (DI)(AX*4)
+	ADDQ        $16, AX
+	CMPQ        AX, DX
+	JL          axpy16loop
+
+axpy1:
+	CMPQ AX, CX
+	JGE  axpydone
+	VMOVSS      (SI)(AX*4), X1
+	VFMADD213SS (DI)(AX*4), X0, X1
+	VMOVSS      X1, (DI)(AX*4)
+	INCQ        AX
+	JMP         axpy1
+
+axpydone:
+	VZEROUPPER
+	RET
+
+// func dotAVX512(x, y []float32) float32
+TEXT ·dotAVX512(SB), NOSPLIT, $0-52
+	MOVQ   x_base+0(FP), SI
+	MOVQ   y_base+24(FP), DI
+	MOVQ   x_len+8(FP), CX
+	VPXORD Z0, Z0, Z0
+	VPXORD Z1, Z1, Z1
+	VPXORD Z2, Z2, Z2
+	VPXORD Z3, Z3, Z3
+	XORQ   AX, AX
+	MOVQ   CX, DX
+	ANDQ   $-64, DX
+	JZ     dot16
+
+dot64:
+	VMOVUPS     (SI)(AX*4), Z4
+	VMOVUPS     64(SI)(AX*4), Z5
+	VMOVUPS     128(SI)(AX*4), Z6
+	VMOVUPS     192(SI)(AX*4), Z7
+	VFMADD231PS (DI)(AX*4), Z4, Z0
+	VFMADD231PS 64(DI)(AX*4), Z5, Z1
+	VFMADD231PS 128(DI)(AX*4), Z6, Z2
+	VFMADD231PS 192(DI)(AX*4), Z7, Z3
+	ADDQ        $64, AX
+	CMPQ        AX, DX
+	JL          dot64
+
+dot16:
+	MOVQ CX, DX
+	ANDQ $-16, DX
+	CMPQ AX, DX
+	JGE  dotreduce
+
+dot16loop:
+	VMOVUPS     (SI)(AX*4), Z4
+	VFMADD231PS (DI)(AX*4), Z4, Z0
+	ADDQ        $16, AX
+	CMPQ        AX, DX
+	JL          dot16loop
+
+dotreduce:
+	VADDPS        Z1, Z0, Z0
+	VADDPS        Z3, Z2, Z2
+	VADDPS        Z2, Z0, Z0
+	VEXTRACTF64X4 $1, Z0, Y1
+	VADDPS        Y1, Y0, Y0
+	VEXTRACTF128  $1, Y0, X1
+	VADDPS        X1, X0, X0
+	VHADDPS       X0, X0, X0
+	VHADDPS       X0, X0, X0
+
+dot1:
+	CMPQ AX, CX
+	JGE  dotdone
+	VMOVSS      (SI)(AX*4), X1
+	VFMADD231SS (DI)(AX*4), X1, X0
+	INCQ        AX
+	JMP         dot1
+
+dotdone:
+	VMOVSS X0, ret+48(FP)
+	VZEROUPPER
+	RET
+
+// func sumAVX512(x []float32) float32
+TEXT ·sumAVX512(SB), NOSPLIT, $0-28
+	MOVQ   x_base+0(FP), SI
+	MOVQ   x_len+8(FP), CX
+	VPXORD Z0, Z0, Z0
+	VPXORD Z1, Z1, Z1
+	VPXORD Z2, Z2, Z2
+	VPXORD Z3, Z3, Z3
+	XORQ   AX, AX
+	MOVQ   CX, DX
+	ANDQ   $-64, DX
+	JZ     sum16
+
+sum64:
+	VADDPS (SI)(AX*4), Z0, Z0
+	VADDPS 64(SI)(AX*4), Z1, Z1
+	VADDPS 128(SI)(AX*4), Z2, Z2
+	VADDPS 192(SI)(AX*4), Z3, Z3
+	ADDQ   $64, AX
+	CMPQ   AX, DX
+	JL     sum64
+
+sum16:
+	MOVQ CX, DX
+	ANDQ $-16, DX
+	CMPQ AX, DX
+	JGE  sumreduce
+
+sum16loop:
+	VADDPS (SI)(AX*4), Z0, Z0
+	ADDQ   $16, AX
+	CMPQ   AX, DX
+	JL     sum16loop
+
+sumreduce:
+	VADDPS        Z1, Z0, Z0
+	VADDPS        Z3, Z2, Z2
+	VADDPS        Z2, Z0, Z0
+	VEXTRACTF64X4 $1, Z0, Y1
+	VADDPS        Y1, Y0, Y0
+	VEXTRACTF128  $1, Y0, X1
+	VADDPS        X1, X0, X0
+	VHADDPS       X0, X0, X0
+	VHADDPS       X0, X0, X0
+
+sum1:
+	CMPQ AX, CX
+	JGE  sumdone
+	VADDSS (SI)(AX*4), X0, X0
+	INCQ   AX
+	JMP    sum1
+
+sumdone:
+	VMOVSS X0, ret+24(FP)
+	VZEROUPPER
+	RET
+
+// func maxAVX512(x []float32) float32
+TEXT ·maxAVX512(SB), NOSPLIT, $0-28
+	MOVQ         x_base+0(FP), SI
+	MOVQ         x_len+8(FP), CX
+	VBROADCASTSS (SI), Z0
+	VMOVUPS      Z0, Z1
+	VMOVUPS      Z0, Z2
+	VMOVUPS      Z0, Z3
+	XORQ         AX, AX
+	MOVQ         CX, DX
+	ANDQ         $-64, DX
+	JZ           max16
+
+max64:
+	VMAXPS (SI)(AX*4), Z0, Z0
+	VMAXPS 64(SI)(AX*4), Z1, Z1
+	VMAXPS 128(SI)(AX*4), Z2, Z2
+	VMAXPS 192(SI)(AX*4), Z3, Z3
+	ADDQ   $64, AX
+	CMPQ   AX, DX
+	JL     max64
+
+max16:
+	MOVQ CX, DX
+	ANDQ $-16, DX
+	CMPQ AX, DX
+	JGE  maxreduce
+
+max16loop:
+	VMAXPS (SI)(AX*4), Z0, Z0
+	ADDQ   $16, AX
+	CMPQ   AX, DX
+	JL     max16loop
+
+maxreduce:
+	VMAXPS        Z1, Z0, Z0
+	VMAXPS        Z3, Z2, Z2
+	VMAXPS        Z2, Z0, Z0
+	VEXTRACTF64X4 $1, Z0, Y1
+	VMAXPS        Y1, Y0, Y0
+	VEXTRACTF128  $1, Y0, X1
+	VMAXPS        X1, X0, X0
+	VPERMILPS     $0x4e, X0, X1
+	VMAXPS        X1, X0, X0
+	VPERMILPS     $0xb1, X0, X1
+	VMAXPS        X1, X0, X0
+
+max1:
+	CMPQ AX, CX
+	JGE  maxdone
+	VMAXSS (SI)(AX*4), X0, X0
+	INCQ   AX
+	JMP    max1
+
+maxdone:
+	VMOVSS X0, ret+24(FP)
+	VZEROUPPER
+	RET
+
+// func scaleAVX512(alpha float32, x []float32)
+TEXT ·scaleAVX512(SB), NOSPLIT, $0-32
+	VBROADCASTSS alpha+0(FP), Z0
+	MOVQ         x_base+8(FP), SI
+	MOVQ         x_len+16(FP), CX
+	XORQ         AX, AX
+	MOVQ         CX, DX
+	ANDQ         $-64, DX
+	JZ           scale16
+
+scale64:
+	VMULPS  (SI)(AX*4), Z0, Z1
+	VMULPS  64(SI)(AX*4), Z0, Z2
+	VMULPS  128(SI)(AX*4), Z0, Z3
+	VMULPS  192(SI)(AX*4), Z0, Z4
+	VMOVUPS Z1, (SI)(AX*4)
+	VMOVUPS Z2, 64(SI)(AX*4)
+	VMOVUPS Z3, 128(SI)(AX*4)
+	VMOVUPS Z4, 192(SI)(AX*4)
+	ADDQ    $64, AX
+	CMPQ    AX, DX
+	JL      scale64
+
+scale16:
+	MOVQ CX, DX
+	ANDQ $-16, DX
+	CMPQ AX, DX
+	JGE  scale1
+
+scale16loop:
+	VMULPS  (SI)(AX*4), Z0, Z1
+	VMOVUPS Z1, (SI)(AX*4)
+	ADDQ    $16, AX
+	CMPQ    AX, DX
+	JL      scale16loop
+
+scale1:
+	CMPQ AX, CX
+	JGE  scaledone
+	VMULSS (SI)(AX*4), X0, X1
+	VMOVSS X1, (SI)(AX*4)
+	INCQ   AX
+	JMP    scale1
+
+scaledone:
+	VZEROUPPER
+	RET
+
+// func addConstAVX512(c float32, x []float32)
+TEXT ·addConstAVX512(SB), NOSPLIT, $0-32
+	VBROADCASTSS c+0(FP), Z0
+	MOVQ         x_base+8(FP), SI
+	MOVQ         x_len+16(FP), CX
+	XORQ         AX, AX
+	MOVQ         CX, DX
+	ANDQ         $-64, DX
+	JZ           add16
+
+add64:
+	VADDPS  (SI)(AX*4), Z0, Z1
+	VADDPS  64(SI)(AX*4), Z0, Z2
+	VADDPS  128(SI)(AX*4), Z0, Z3
+	VADDPS  192(SI)(AX*4), Z0, Z4
+	VMOVUPS Z1, (SI)(AX*4)
+	VMOVUPS Z2, 64(SI)(AX*4)
+	VMOVUPS Z3, 128(SI)(AX*4)
+	VMOVUPS Z4, 192(SI)(AX*4)
+	ADDQ    $64, AX
+	CMPQ    AX, DX
+	JL      add64
+
+add16:
+	MOVQ CX, DX
+	ANDQ $-16, DX
+	CMPQ AX, DX
+	JGE  add1
+
+add16loop:
+	VADDPS  (SI)(AX*4), Z0, Z1
+	VMOVUPS Z1, (SI)(AX*4)
+	ADDQ    $16, AX
+	CMPQ    AX, DX
+	JL      add16loop
+
+add1:
+	CMPQ AX, CX
+	JGE  adddone
+	VADDSS (SI)(AX*4), X0, X1
+	VMOVSS X1, (SI)(AX*4)
+	INCQ   AX
+	JMP    add1
+
+adddone:
+	VZEROUPPER
+	RET
+
+// The rational kernel evaluates x * p(x^2) / q(x^2) on 16 elements per
+// iteration, clamping x to [-bound, bound] and the result to [-1, 1], with
+// the NaNs as the second operands of the comparisons to propagate them.
+DATA one<>+0(SB)/4, $0x3f800000
+GLOBL one<>(SB), RODATA|NOPTR, $4
+
+// func rationalAVX512(x []float32, c *rationalCoefficients)
+TEXT ·rationalAVX512(SB), NOSPLIT, $0-32
+	MOVQ         x_base+0(FP), SI
+	MOVQ         x_len+8(FP), CX
+	MOVQ         c+24(FP), DX
+	VBROADCASTSS (DX), Z0
+	VPXORD       Z1, Z1, Z1
+	VSUBPS       Z0, Z1, Z1
+	VBROADCASTSS one<>(SB), Z2
+	VPXORD       Z3, Z3, Z3
+	VSUBPS       Z2, Z3, Z3
+	XORQ         AX, AX
+
+rational:
+	CMPQ         AX, CX
+	JGE          rationaldone
+	VMOVUPS      (SI)(AX*4), Z4
+	VMINPS       Z4, Z0, Z4
+	VMAXPS       Z4, Z1, Z4
+	VMULPS       Z4, Z4, Z5
+	VBROADCASTSS 4(DX), Z6
+	VBROADCASTSS 8(DX), Z7
+	VFMADD213PS  Z7, Z5, Z6
+	VBROADCASTSS 12(DX), Z7
+	VFMADD213PS  Z7, Z5, Z6
+	VBROADCASTSS 16(DX), Z7
+	VFMADD213PS  Z7, Z5, Z6
+	VBROADCASTSS 20(DX), Z7
+	VFMADD213PS  Z7, Z5, Z6
+	VBROADCASTSS 24(DX), Z7
+	VFMADD213PS  Z7, Z5, Z6
+	VBROADCASTSS 28(DX), Z7
+	VFMADD213PS  Z7, Z5, Z6
+	VMULPS       Z6, Z4, Z6
+	VBROADCASTSS 32(DX), Z8
+	VBROADCASTSS 36(DX), Z7
+	VFMADD213PS  Z7, Z5, Z8
+	VBROADCASTSS 40(DX), Z7
+	VFMADD213PS  Z7, Z5, Z8
+	VBROADCASTSS 44(DX), Z7
+	VFMADD213PS  Z7, Z5, Z8
+	VBROADCASTSS 48(DX), Z7
+	VFMADD213PS  Z7, Z5, Z8
+	VDIVPS       Z8, Z6, Z6
+	VMINPS       Z6, Z2, Z6
+	VMAXPS       Z6, Z3, Z6
+	VMOVUPS      Z6, (SI)(AX*4)
+	ADDQ         $16, AX
+	JMP          rational
+
+rationaldone:
+	VZEROUPPER
+	RET
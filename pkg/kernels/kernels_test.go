@@ -0,0 +1,112 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kernels
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func randomFloats(r *rand.Rand, n int) []float32 {
+	x := make([]float32, n)
+	for i := range x {
+		x[i] = r.Float32()*2 - 1
+	}
+	return x
+}
+
+// TestKernels compares the kernels of each instruction set supported by
+// the CPU with the generic ones, and the activation functions with the
+// ones of the math package, with lengths covering the unrolled loops and
+// the remainders.
+func TestKernels(t *testing.T) {
+	t.Logf("kernels: %s", ISA())
+	defer sets[ISA()]()
+	for isa, use := range sets {
+		use()
+		t.Run(isa, testKernels)
+		t.Run(isa+"/activations", testActivations)
+	}
+	assert.Equal(t, float32(math.Inf(-1)), Max(nil))
+}
+
+func testKernels(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n <= 150; n++ {
+		x, y := randomFloats(r, n), randomFloats(r, n)
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			want := append([]float32{}, y...)
+			axpyGeneric(0.5, x, want)
+			got := append([]float32{}, y...)
+			Axpy(0.5, x, got)
+			assert.InDeltaSlice(t, want, got, 1e-6)
+
+			assert.InDelta(t, dotGeneric(x, y), Dot(x, y), 1e-4)
+			assert.InDelta(t, sumGeneric(x), Sum(x), 1e-4)
+
+			want = append([]float32{}, x...)
+			scaleGeneric(3, want)
+			got = append([]float32{}, x...)
+			Scale(3, got)
+			assert.Equal(t, want, got)
+
+			want = append([]float32{}, x...)
+			addConstGeneric(-2, want)
+			got = append([]float32{}, x...)
+			AddConst(-2, got)
+			assert.Equal(t, want, got)
+
+			if n > 0 {
+				x[r.Intn(n)] = 5
+				assert.Equal(t, maxGeneric(x), Max(x))
+			}
+		})
+	}
+}
+
+func testActivations(t *testing.T) {
+	const sqrt2pi = 0.7978845608028654
+	tests := []struct {
+		name string
+		f    func([]float32)
+		want func(float64) float64
+	}{
+		{"erf", Erf, math.Erf},
+		{"tanh", Tanh, math.Tanh},
+		{"gelu", GELU, func(x float64) float64 {
+			return x * (1 + math.Erf(x/math.Sqrt2)) / 2
+		}},
+		{"gelu tanh", GELUTanh, func(x float64) float64 {
+			return x * (1 + math.Tanh(sqrt2pi*(x+0.044715*x*x*x))) / 2
+		}},
+	}
+	r := rand.New(rand.NewSource(1))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Over several chunks, with a tail, in [-6, 6], and beyond the
+			// bounds of the approximations.
+			x := randomFloats(r, 3*chunk+13)
+			for i := range x {
+				x[i] *= 6
+			}
+			x = append(x, 0, 1e-20, -1e-20, 4.5, -4.5, 10, -10, 20, -20, 1e4, -1e4)
+			got := append([]float32{}, x...)
+			tt.f(got)
+			for i, v := range x {
+				want := tt.want(float64(v))
+				assert.InDelta(t, want, got[i], 1e-6*math.Max(1, math.Abs(want)), "x = %g", v)
+			}
+
+			got = []float32{float32(math.NaN()), 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+			tt.f(got)
+			assert.True(t, math.IsNaN(float64(got[0])), "NaN propagates")
+			assert.False(t, math.IsNaN(float64(got[1])))
+		})
+	}
+}
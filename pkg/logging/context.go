@@ -0,0 +1,29 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Ctx returns the logger of the context, adding the fields of the request
+// to its logs, e.g. its ID and the model serving it, or the global logger
+// if the context has none.
+func Ctx(ctx context.Context) *zerolog.Logger {
+	if l := zerolog.Ctx(ctx); l.GetLevel() != zerolog.Disabled {
+		return l
+	}
+	return &log.Logger
+}
+
+// With returns the context with the logger of the context (see Ctx) adding
+// the fields, as pairs of key and value, to its logs.
+func With(ctx context.Context, fields ...any) context.Context {
+	l := Ctx(ctx).With().Fields(fields).Logger()
+	return l.WithContext(ctx)
+}
@@ -0,0 +1,147 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package logging configures the global logger of the commands: the level
+// and the format of the logs, the sampling of the frequent ones, and their
+// sinks besides the standard error, a file rotated by size and syslog. The
+// loggers of the contexts add the fields of the requests to their logs.
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Format is the format of the logs written to the standard error.
+type Format string
+
+const (
+	// FormatConsole writes the logs colorized and human-readable.
+	FormatConsole Format = "console"
+	// FormatJSON writes the logs as JSON lines, e.g. for a log collector.
+	FormatJSON Format = "json"
+)
+
+const (
+	// DefaultFileMaxSize is the default size in MiB of the log file beyond
+	// which it's rotated.
+	DefaultFileMaxSize = 100
+	// DefaultFileMaxBackups is the default number of rotated log files kept.
+	DefaultFileMaxBackups = 5
+)
+
+// Config is the configuration of the logger.
+type Config struct {
+	// Level is the minimum level of the logs (default debug).
+	Level zerolog.Level
+	// Format is the format of the logs of the standard error (default console).
+	Format Format
+	// Sampling is the sampling of the debug and info logs: one of every
+	// Sampling is written; the warnings and the errors are all written
+	// (default 0, all the logs).
+	Sampling int
+	// File is the file the logs are appended to, as JSON lines, in addition
+	// to the standard error (optional).
+	File string
+	// FileMaxSize is the size in MiB of the file beyond which it's rotated
+	// (default DefaultFileMaxSize).
+	FileMaxSize int
+	// FileMaxBackups is the number of rotated files kept, the oldest being
+	// removed (default DefaultFileMaxBackups).
+	FileMaxBackups int
+	// Syslog is the syslog daemon the logs are sent to, as JSON, in
+	// addition to the standard error: "local" for the one of the system, or
+	// its address, e.g. "udp://logs.example.com:514" (optional).
+	Syslog string
+}
+
+// ParseFormat parses a string into a Format.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatConsole, FormatJSON:
+		return f, nil
+	default:
+		return "", fmt.Errorf("invalid log format %#v", s)
+	}
+}
+
+// New returns the logger writing to the standard error, and to the sinks of
+// the configuration, with the closer of the sinks.
+func New(c Config, stderr io.Writer) (zerolog.Logger, io.Closer, error) {
+	var closers multiCloser
+	writers := []io.Writer{stderr}
+	switch c.Format {
+	case "", FormatConsole:
+		writers[0] = zerolog.ConsoleWriter{Out: stderr, TimeFormat: time.RFC3339}
+	case FormatJSON:
+	default:
+		return zerolog.Logger{}, nil, fmt.Errorf("invalid log format %#v", c.Format)
+	}
+	if c.File != "" {
+		maxSize, maxBackups := c.FileMaxSize, c.FileMaxBackups
+		if maxSize <= 0 {
+			maxSize = DefaultFileMaxSize
+		}
+		if maxBackups <= 0 {
+			maxBackups = DefaultFileMaxBackups
+		}
+		f, err := openRotatingFile(c.File, int64(maxSize)<<20, maxBackups)
+		if err != nil {
+			return zerolog.Logger{}, nil, fmt.Errorf("failed to open the log file: %w", err)
+		}
+		writers = append(writers, f)
+		closers = append(closers, f)
+	}
+	if c.Syslog != "" {
+		w, closer, err := dialSyslog(c.Syslog)
+		if err != nil {
+			_ = closers.Close()
+			return zerolog.Logger{}, nil, fmt.Errorf("failed to connect to syslog: %w", err)
+		}
+		writers = append(writers, w)
+		closers = append(closers, closer)
+	}
+
+	var out io.Writer = writers[0]
+	if len(writers) > 1 {
+		out = zerolog.MultiLevelWriter(writers...)
+	}
+	logger := zerolog.New(out).With().Timestamp().Logger()
+	if c.Sampling > 1 {
+		s := &zerolog.BasicSampler{N: uint32(c.Sampling)}
+		logger = logger.Sample(&zerolog.LevelSampler{DebugSampler: s, InfoSampler: s})
+	}
+	return logger, closers, nil
+}
+
+// Setup sets the global logger and level from the configuration, writing
+// to the standard error, and returns the closer of its sinks.
+func Setup(c Config) (io.Closer, error) {
+	logger, closer, err := New(c, os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	log.Logger = logger
+	zerolog.SetGlobalLevel(c.Level)
+	return closer, nil
+}
+
+// multiCloser closes all its closers, returning their errors joined.
+type multiCloser []io.Closer
+
+func (mc multiCloser) Close() error {
+	var errs []error
+	for _, c := range mc {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger, closer, err := New(Config{Format: FormatJSON}, &buf)
+		require.NoError(t, err)
+		defer closer.Close()
+		logger.Info().Str("model", "bert").Msg("loaded")
+
+		var line map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+		assert.Equal(t, "info", line["level"])
+		assert.Equal(t, "bert", line["model"])
+		assert.Equal(t, "loaded", line["message"])
+		assert.Contains(t, line, "time")
+	})
+	t.Run("sampling", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger, _, err := New(Config{Format: FormatJSON, Sampling: 3}, &buf)
+		require.NoError(t, err)
+		for i := 0; i < 9; i++ {
+			logger.Info().Msg("request")
+			logger.Warn().Msg("slow")
+		}
+		assert.Equal(t, 3, strings.Count(buf.String(), `"request"`))
+		assert.Equal(t, 9, strings.Count(buf.String(), `"slow"`))
+	})
+	t.Run("file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "cybertron.log")
+		var buf bytes.Buffer
+		logger, closer, err := New(Config{File: file}, &buf)
+		require.NoError(t, err)
+		logger.Info().Msg("loaded")
+		require.NoError(t, closer.Close())
+
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"message":"loaded"`)
+		assert.Contains(t, buf.String(), "loaded")
+	})
+	t.Run("invalid format", func(t *testing.T) {
+		_, _, err := New(Config{Format: "xml"}, &bytes.Buffer{})
+		assert.Error(t, err)
+	})
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cybertron.log")
+	r, err := openRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+	for _, line := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		_, err := r.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, r.Close())
+
+	for name, expected := range map[string]string{
+		path:        "dddddd\n",
+		path + ".1": "cccccc\n",
+		path + ".2": "bbbbbb\n",
+	} {
+		data, err := os.ReadFile(name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(data), name)
+	}
+	assert.NoFileExists(t, path+".3")
+}
+
+func TestWith(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+	ctx := logger.WithContext(context.Background())
+	ctx = With(ctx, "request_id", "42")
+	ctx = With(ctx, "model", "bert", "task", "text-classification")
+	Ctx(ctx).Info().Msg("classified")
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, map[string]any{
+		"level":      "info",
+		"request_id": "42",
+		"model":      "bert",
+		"task":       "text-classification",
+		"message":    "classified",
+	}, line)
+
+	assert.Equal(t, &log.Logger, Ctx(context.Background()))
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// rotatingFile is a log file rotated once it exceeds its maximum size: it's
+// renamed with the ".1" suffix, the previous backups shifted by one, e.g.
+// ".1" to ".2", the oldest beyond the maximum number being removed.
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	f          *os.File
+	size       int64
+}
+
+func openRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	r.f, r.size = f, info.Size()
+	return nil
+}
+
+// Write appends the log line to the file, rotating it first if the line
+// would make it exceed its maximum size.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	_ = os.Remove(r.backup(r.maxBackups))
+	for i := r.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(r.backup(i), r.backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(r.path, r.backup(1)); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+// backup returns the path of the i-th backup, the most recent being 1.
+func (r *rotatingFile) backup(i int) string {
+	return fmt.Sprintf("%s.%d", r.path, i)
+}
+
+// Close closes the file.
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
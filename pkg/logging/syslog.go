@@ -0,0 +1,35 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !windows && !plan9
+
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/syslog"
+	"net/url"
+
+	"github.com/rs/zerolog"
+)
+
+// dialSyslog connects to the syslog daemon, "local" or at the address, e.g.
+// "udp://logs.example.com:514", returning the writer of the logs, with the
+// priority of their level.
+func dialSyslog(addr string) (zerolog.LevelWriter, io.Closer, error) {
+	var network, raddr string
+	if addr != "local" {
+		u, err := url.Parse(addr)
+		if err != nil || u.Host == "" {
+			return nil, nil, fmt.Errorf("invalid syslog address %#v", addr)
+		}
+		network, raddr = u.Scheme, u.Host
+	}
+	w, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_DAEMON, "cybertron")
+	if err != nil {
+		return nil, nil, err
+	}
+	return zerolog.SyslogLevelWriter(w), w, nil
+}
@@ -0,0 +1,20 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build windows || plan9
+
+package logging
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+
+	"github.com/rs/zerolog"
+)
+
+// dialSyslog fails: syslog is not available on this system.
+func dialSyslog(string) (zerolog.LevelWriter, io.Closer, error) {
+	return nil, nil, fmt.Errorf("syslog not supported on %s", runtime.GOOS)
+}
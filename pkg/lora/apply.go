@@ -0,0 +1,282 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lora
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/models/bert"
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/nlpodyssey/spago/mat/float"
+	"github.com/nlpodyssey/spago/nn"
+	"github.com/nlpodyssey/spago/nn/linear"
+)
+
+// headNames are the names of the task heads of the BERT models, which the
+// adapters can replace as a whole (PEFT "modules_to_save").
+var headNames = map[string]bool{
+	"classifier": true,
+	"qa_outputs": true,
+}
+
+// Apply returns a copy of the model of a task (e.g. a
+// textclassification.Interface) with the adapter applied, sharing the
+// modules and the weights not updated by the adapter with the model, which
+// is left untouched. Only the BERT models are supported.
+func Apply[T any](m T, a *Adapter) (T, error) {
+	v := reflect.ValueOf(&m).Elem()
+	base := find[*bert.Model](v)
+	if base == nil {
+		return m, errors.New("lora: adapters are only supported by the BERT models")
+	}
+	replace, err := a.replacements(base, findHead(v))
+	if err != nil {
+		return m, fmt.Errorf("lora: %w", err)
+	}
+	c, _ := newCloner(replace).clone(v)
+	return c.Interface().(T), nil
+}
+
+// findHead returns the linear layer of the task head of the BERT model, if
+// any.
+func findHead(v reflect.Value) *linear.Model {
+	if m := find[*bert.ModelForSequenceClassification](v); m != nil {
+		return m.Classifier
+	}
+	if m := find[*bert.ModelForTokenClassification](v); m != nil {
+		return m.Classifier
+	}
+	if m := find[*bert.ModelForQuestionAnswering](v); m != nil {
+		return m.Classifier
+	}
+	return nil
+}
+
+// update is the pair of matrices of the low-rank update of a layer.
+type update struct {
+	a, b *tensor
+}
+
+// replacements returns the layers of the model updated by the adapter,
+// mapped to the ones replacing them.
+func (a *Adapter) replacements(m *bert.Model, head *linear.Model) (map[any]any, error) {
+	updates := make(map[string]*update)
+	headWeights := make(map[string]tensor)
+	for name, t := range a.weights {
+		t := t
+		module, kind := parseName(name)
+		switch kind {
+		case "lora_A", "lora_B":
+			u, ok := updates[module]
+			if !ok {
+				u = &update{}
+				updates[module] = u
+			}
+			if kind == "lora_A" {
+				u.a = &t
+			} else {
+				u.b = &t
+			}
+		case "weight", "bias":
+			if !headNames[module] || head == nil {
+				return nil, fmt.Errorf("unsupported adapter weight %#v", name)
+			}
+			headWeights[kind] = t
+		default:
+			return nil, fmt.Errorf("unsupported adapter weight %#v", name)
+		}
+	}
+
+	replace := make(map[any]any)
+	modules := make([]string, 0, len(updates))
+	for module := range updates {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	for _, module := range modules {
+		if err := a.replaceLayer(m, module, updates[module], replace); err != nil {
+			return nil, fmt.Errorf("module %#v: %w", module, err)
+		}
+	}
+	if len(headWeights) > 0 {
+		h, err := replaceHead(head, headWeights)
+		if err != nil {
+			return nil, err
+		}
+		replace[head] = h
+	}
+	return replace, nil
+}
+
+// parseName returns the path of the module of the weight, relative to the
+// BERT model, and the kind of the weight: "lora_A" or "lora_B" for the
+// low-rank updates, "weight" or "bias" for the modules replaced as a whole.
+func parseName(name string) (module, kind string) {
+	name = strings.TrimPrefix(name, "base_model.model.")
+	name = strings.Replace(name, ".modules_to_save", "", 1)
+	name = strings.Replace(name, ".default", "", 1)
+	name = strings.TrimPrefix(name, "bert.")
+	for _, kind := range []string{"lora_A", "lora_B"} {
+		if module, ok := strings.CutSuffix(name, "."+kind+".weight"); ok {
+			return module, kind
+		}
+	}
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
+
+// replaceLayer adds to the replacements the layers of the module of the
+// model with the update applied.
+func (a *Adapter) replaceLayer(m *bert.Model, module string, u *update, replace map[any]any) error {
+	if u.a == nil || u.b == nil {
+		return errors.New("missing lora_A or lora_B weight")
+	}
+	rest, ok := strings.CutPrefix(module, "encoder.layer.")
+	if !ok {
+		return errors.New("unsupported module")
+	}
+	index, path, _ := strings.Cut(rest, ".")
+	i, err := strconv.Atoi(index)
+	if err != nil || i < 0 || i >= len(m.Encoder.Layers) {
+		return fmt.Errorf("invalid layer %#v", index)
+	}
+	layer := m.Encoder.Layers[i]
+	attention := layer.SelfAttention.Attention
+
+	switch path {
+	case "attention.self.query", "attention.self.key", "attention.self.value":
+		// The projections are split among the heads, each one with its
+		// rows of the weights, and thus of the up-projection.
+		heads := attention.Heads
+		out, _ := u.b.dims()
+		if out%len(heads) != 0 {
+			return fmt.Errorf("lora_B has %d rows, not a multiple of the %d heads", out, len(heads))
+		}
+		rows := out / len(heads)
+		for j, head := range heads {
+			l := head.Query
+			switch path {
+			case "attention.self.key":
+				l = head.Key
+			case "attention.self.value":
+				l = head.Value
+			}
+			w, err := a.merge(l, u.a, u.b.rows(j*rows, (j+1)*rows))
+			if err != nil {
+				return err
+			}
+			replace[l] = w
+		}
+	case "attention.output.dense":
+		w, err := a.merge(attention.OutputMerge, u.a, u.b)
+		if err != nil {
+			return err
+		}
+		replace[attention.OutputMerge] = w
+	case "intermediate.dense", "output.dense":
+		k := 0
+		if path == "output.dense" {
+			k = 2
+		}
+		l, err := a.wrap(layer.FF.MLP[k], u)
+		if err != nil {
+			return err
+		}
+		replace[layer.FF.MLP[k]] = l
+	default:
+		return errors.New("unsupported module")
+	}
+	return nil
+}
+
+// merge returns a copy of the linear layer with the update merged into its
+// weights, and its bias shared.
+func (a *Adapter) merge(l *linear.Model, down, up *tensor) (*linear.Model, error) {
+	w := l.W.Value()
+	out, in := w.Dims()
+	am, bm, err := updateMatrices(w, down, up, out, in)
+	if err != nil {
+		return nil, err
+	}
+	return &linear.Model{
+		W: &mergedWeight{Param: l.W, a: am, b: bm, scale: a.Config.Scale()},
+		B: l.B,
+	}, nil
+}
+
+// wrap returns the layer of a feed-forward block adding the update to its
+// outputs.
+func (a *Adapter) wrap(l nn.StandardModel, u *update) (*Linear, error) {
+	var (
+		proto   mat.Matrix
+		out, in int
+	)
+	switch l := l.(type) {
+	case *linear.Model:
+		proto = l.W.Value()
+		out, in = proto.Dims()
+	case *quantization.Linear:
+		proto = l.B.Value()
+		out, in = l.W.Dims()
+	default:
+		return nil, fmt.Errorf("unsupported layer %T", l)
+	}
+	am, bm, err := updateMatrices(proto, u.a, u.b, out, in)
+	if err != nil {
+		return nil, err
+	}
+	return &Linear{Base: l, A: am, B: bm, Scale: a.Config.Scale()}, nil
+}
+
+// updateMatrices returns the matrices of the update of a layer with the
+// given size, of the same type of the prototype.
+func updateMatrices(proto mat.Matrix, down, up *tensor, out, in int) (a, b mat.Matrix, _ error) {
+	r, c := down.dims()
+	if c != in {
+		return nil, nil, fmt.Errorf("lora_A has %d columns, the layer %d inputs", c, in)
+	}
+	if br, bc := up.dims(); br != out || bc != r {
+		return nil, nil, fmt.Errorf("lora_B is %d×%d, expected %d×%d", br, bc, out, r)
+	}
+	a = proto.NewMatrix(r, c, float.SliceInterface(down.data))
+	b = proto.NewMatrix(out, r, float.SliceInterface(up.data))
+	return a, b, nil
+}
+
+// rows returns the rows from i to j (excluded) of the two-dimensional
+// tensor.
+func (t *tensor) rows(i, j int) *tensor {
+	_, c := t.dims()
+	return &tensor{data: t.data[i*c : j*c], shape: []int{j - i, c}}
+}
+
+// replaceHead returns the task head with the weight, and the bias, if any,
+// of the adapter.
+func replaceHead(head *linear.Model, weights map[string]tensor) (*linear.Model, error) {
+	w := head.W.Value()
+	out, in := w.Dims()
+	h := &linear.Model{W: head.W, B: head.B}
+	if t, ok := weights["weight"]; ok {
+		if r, c := t.dims(); r != out || c != in {
+			return nil, fmt.Errorf("the task head of the adapter is %d×%d, the one of the model %d×%d (the labels must be the same)", r, c, out, in)
+		}
+		h.W = nn.NewParam(w.NewMatrix(out, in, float.SliceInterface(t.data)))
+	}
+	if t, ok := weights["bias"]; ok {
+		if r, _ := t.dims(); r != out {
+			return nil, fmt.Errorf("the bias of the task head of the adapter has %d values, the one of the model %d", r, out)
+		}
+		h.B = nn.NewParam(head.B.Value().NewVec(float.SliceInterface(t.data)))
+	}
+	return h, nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lora
+
+import (
+	"reflect"
+)
+
+// find returns the first value of type P reachable from v through exported
+// fields, slices and interfaces, in depth-first order, or the zero value.
+func find[P comparable](v reflect.Value) P {
+	var zero P
+	t := reflect.TypeOf(zero)
+	var visit func(v reflect.Value, seen map[pointer]bool) (P, bool)
+	visit = func(v reflect.Value, seen map[pointer]bool) (P, bool) {
+		if v.Type() == t && v.CanInterface() {
+			if p := v.Interface().(P); p != zero {
+				return p, true
+			}
+		}
+		switch v.Kind() {
+		case reflect.Pointer:
+			if v.IsNil() || seen[pointerOf(v)] {
+				return zero, false
+			}
+			seen[pointerOf(v)] = true
+			return visit(v.Elem(), seen)
+		case reflect.Interface:
+			if !v.IsNil() {
+				return visit(v.Elem(), seen)
+			}
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				if !v.Type().Field(i).IsExported() {
+					continue
+				}
+				if p, ok := visit(v.Field(i), seen); ok {
+					return p, true
+				}
+			}
+		case reflect.Slice:
+			if !composite(v.Type().Elem()) {
+				return zero, false
+			}
+			for i := 0; i < v.Len(); i++ {
+				if p, ok := visit(v.Index(i), seen); ok {
+					return p, true
+				}
+			}
+		}
+		return zero, false
+	}
+	p, _ := visit(v, make(map[pointer]bool))
+	return p
+}
+
+// pointer identifies a pointer by its type too, since a struct and its
+// first field have the same address.
+type pointer struct {
+	t reflect.Type
+	p uintptr
+}
+
+func pointerOf(v reflect.Value) pointer {
+	return pointer{t: v.Type(), p: v.Pointer()}
+}
+
+// composite reports whether the values of the type can hold modules.
+func composite(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Struct, reflect.Slice:
+		return true
+	default:
+		return false
+	}
+}
+
+// cloner copies a value replacing some of the pointers it reaches, through
+// exported fields, slices and interfaces. Only the structs and the slices on
+// the paths to the replaced pointers are copied, the rest being shared.
+type cloner struct {
+	replace map[any]any
+	// copies maps the pointers visited to their copies, or to themselves,
+	// if nothing is replaced under them.
+	copies map[pointer]reflect.Value
+}
+
+func newCloner(replace map[any]any) *cloner {
+	return &cloner{replace: replace, copies: make(map[pointer]reflect.Value)}
+}
+
+// clone returns the copy of v, and whether it differs from v.
+func (c *cloner) clone(v reflect.Value) (reflect.Value, bool) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v, false
+		}
+		if r, ok := c.replacement(v, v.Type()); ok {
+			return r, true
+		}
+		if cp, ok := c.copies[pointerOf(v)]; ok {
+			return cp, cp.Pointer() != v.Pointer()
+		}
+		// The pointer is recorded before visiting its value, in case the
+		// value refers back to it.
+		c.copies[pointerOf(v)] = v
+		e, changed := c.clone(v.Elem())
+		if !changed {
+			return v, false
+		}
+		cp := reflect.New(v.Type().Elem())
+		cp.Elem().Set(e)
+		c.copies[pointerOf(v)] = cp
+		return cp, true
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+		// The replacement of a pointer held by an interface can be of
+		// any type implementing it.
+		e, changed := c.replacement(v.Elem(), v.Type())
+		if !changed {
+			e, changed = c.clone(v.Elem())
+		}
+		if !changed {
+			return v, false
+		}
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(e)
+		return cp, true
+	case reflect.Struct:
+		var cp reflect.Value
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			f, changed := c.clone(v.Field(i))
+			if !changed {
+				continue
+			}
+			if !cp.IsValid() {
+				cp = reflect.New(v.Type()).Elem()
+				cp.Set(v)
+			}
+			cp.Field(i).Set(f)
+		}
+		if !cp.IsValid() {
+			return v, false
+		}
+		return cp, true
+	case reflect.Slice:
+		if v.IsNil() || !composite(v.Type().Elem()) {
+			return v, false
+		}
+		var cp reflect.Value
+		for i := 0; i < v.Len(); i++ {
+			e, changed := c.clone(v.Index(i))
+			if !changed {
+				continue
+			}
+			if !cp.IsValid() {
+				cp = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+				reflect.Copy(cp, v)
+			}
+			cp.Index(i).Set(e)
+		}
+		if !cp.IsValid() {
+			return v, false
+		}
+		return cp, true
+	default:
+		return v, false
+	}
+}
+
+// replacement returns the replacement of the pointer, if any, provided that
+// it can be assigned to a value of type t.
+func (c *cloner) replacement(v reflect.Value, t reflect.Type) (reflect.Value, bool) {
+	if v.Kind() != reflect.Pointer || !v.CanInterface() {
+		return v, false
+	}
+	r, ok := c.replace[v.Interface()]
+	if !ok {
+		return v, false
+	}
+	rv := reflect.ValueOf(r)
+	if !rv.Type().AssignableTo(t) {
+		return v, false
+	}
+	return rv, true
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lora
+
+import (
+	"sync"
+
+	"github.com/nlpodyssey/spago/ag"
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/nlpodyssey/spago/nn"
+)
+
+var _ nn.StandardModel = &Linear{}
+
+// Linear is an inference-only linear layer adding the low-rank update of
+// an adapter to the outputs of the base layer, whose weights are shared.
+// It replaces the layers of an nn.ModuleList[nn.StandardModel].
+type Linear struct {
+	nn.Module
+	// Base is the layer of the base model, e.g. a linear.Model or a
+	// quantization.Linear.
+	Base nn.StandardModel
+	// A is the down-projection of the update (rank × input size).
+	A mat.Matrix
+	// B is the up-projection of the update (output size × rank).
+	B mat.Matrix
+	// Scale is the factor the update is scaled by.
+	Scale float64
+}
+
+// Forward performs the forward step for each input node and returns the result.
+// The outputs are constant nodes, since gradients are not propagated
+// through the adapters.
+func (m *Linear) Forward(xs ...ag.Node) []ag.Node {
+	ys := m.Base.Forward(xs...)
+	for i, x := range xs {
+		delta := m.B.Mul(m.A.Mul(x.Value())).ProdScalarInPlace(m.Scale)
+		ys[i] = ys[i].Value().Add(delta)
+	}
+	return ys
+}
+
+// mergedWeight is the weight of a layer of the base model with the
+// low-rank update of an adapter merged into it, for the layers that can't
+// be replaced by a Linear. The merged matrix is computed the first time
+// it's used, so that the adapters not requested take no memory for it.
+type mergedWeight struct {
+	// Param is the weight of the base layer.
+	nn.Param
+	a, b  mat.Matrix
+	scale float64
+
+	once   sync.Once
+	merged mat.Matrix
+}
+
+// Value returns the weight of the base layer plus the update.
+func (w *mergedWeight) Value() mat.Matrix {
+	w.once.Do(func() {
+		w.merged = w.Param.Value().Add(w.b.Mul(w.a).ProdScalarInPlace(w.scale))
+	})
+	return w.merged
+}
+
+// RequiresGrad returns false, since the merged weight is not trainable.
+func (w *mergedWeight) RequiresGrad() bool {
+	return false
+}
@@ -0,0 +1,163 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package lora loads the LoRA adapters fine-tuned with PEFT, and applies
+// them on top of a loaded base model, so that many lightweight fine-tunes
+// share the memory of a single copy of its weights.
+//
+// An adapter is applied to a shallow copy of the model, sharing all its
+// modules but the ones on the path to the layers the adapter updates. The
+// layers of the feed-forward blocks add the low-rank update to their
+// outputs on the fly, while the ones of the self-attention, which can't be
+// replaced by a different module, merge it into a copy of their weights,
+// computed the first time they're used.
+package lora
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/nlpodyssey/cybertron/pkg/converter/pytorch"
+)
+
+const (
+	// ConfigFilename is the name of the configuration file of an adapter.
+	ConfigFilename = "adapter_config.json"
+	// WeightsFilename is the name of the PyTorch file of the weights of an
+	// adapter, used if the safetensors one doesn't exist.
+	WeightsFilename = "adapter_model.bin"
+	// SafetensorsFilename is the name of the safetensors file of the
+	// weights of an adapter.
+	SafetensorsFilename = "adapter_model.safetensors"
+)
+
+// Config is the configuration of a LoRA adapter (adapter_config.json).
+type Config struct {
+	// PeftType is the type of the adapter, "LORA".
+	PeftType string `json:"peft_type"`
+	// BaseModel is the name or path of the model the adapter was trained on.
+	BaseModel string `json:"base_model_name_or_path"`
+	// R is the rank of the update matrices.
+	R int `json:"r"`
+	// Alpha is the scaling factor of the updates, divided by the rank.
+	Alpha float64 `json:"lora_alpha"`
+	// UseRSLoRA is whether the updates are scaled by Alpha divided by the
+	// square root of the rank instead (rank-stabilized LoRA).
+	UseRSLoRA bool `json:"use_rslora"`
+	// FanInFanOut is whether the weights of the layers are stored
+	// transposed, as in the GPT-2 models. It's not supported.
+	FanInFanOut bool `json:"fan_in_fan_out"`
+	// RankPattern and AlphaPattern override the rank and the alpha of some
+	// layers. They're not supported.
+	RankPattern  map[string]int     `json:"rank_pattern"`
+	AlphaPattern map[string]float64 `json:"alpha_pattern"`
+}
+
+// Scale returns the factor the low-rank updates are scaled by.
+func (c Config) Scale() float64 {
+	if c.UseRSLoRA {
+		return c.Alpha / math.Sqrt(float64(c.R))
+	}
+	return c.Alpha / float64(c.R)
+}
+
+// validate checks that the adapter is a LoRA one, with the supported
+// options.
+func (c Config) validate() error {
+	switch {
+	case c.PeftType != "" && c.PeftType != "LORA":
+		return fmt.Errorf("unsupported adapter type %#v", c.PeftType)
+	case c.R <= 0:
+		return fmt.Errorf("invalid rank %d", c.R)
+	case c.FanInFanOut:
+		return errors.New("fan_in_fan_out not supported")
+	case len(c.RankPattern) > 0 || len(c.AlphaPattern) > 0:
+		return errors.New("rank_pattern and alpha_pattern not supported")
+	}
+	return nil
+}
+
+// Adapter is a LoRA adapter.
+type Adapter struct {
+	// Config is the configuration of the adapter.
+	Config Config
+	// weights are the weights of the adapter, by name.
+	weights map[string]tensor
+}
+
+// tensor is a weight of an adapter, in row-major order.
+type tensor struct {
+	data  []float32
+	shape []int
+}
+
+// dims returns the number of rows and columns of the tensor, a vector
+// being a single column.
+func (t tensor) dims() (r, c int) {
+	if len(t.shape) == 1 {
+		return t.shape[0], 1
+	}
+	return t.shape[0], t.shape[1]
+}
+
+// Load loads the adapter from the directory, with its configuration and
+// its weights, in safetensors or PyTorch format.
+func Load(dir string) (*Adapter, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ConfigFilename))
+	if err != nil {
+		return nil, fmt.Errorf("lora: failed to read the adapter configuration: %w", err)
+	}
+	a := &Adapter{weights: make(map[string]tensor)}
+	if err := json.Unmarshal(data, &a.Config); err != nil {
+		return nil, fmt.Errorf("lora: invalid adapter configuration: %w", err)
+	}
+	if err := a.Config.validate(); err != nil {
+		return nil, fmt.Errorf("lora: %w", err)
+	}
+
+	filename := filepath.Join(dir, SafetensorsFilename)
+	if _, err := os.Stat(filename); err != nil {
+		filename = filepath.Join(dir, WeightsFilename)
+	}
+	params := pytorch.NewParamsProvider[float32]()
+	defer params.Close()
+	if err := params.Load(filename); err != nil {
+		return nil, fmt.Errorf("lora: failed to load the adapter weights: %w", err)
+	}
+	err = params.Iterate(func(name string, data []float32) error {
+		shape := params.Shape(name)
+		if len(shape) == 0 || len(shape) > 2 {
+			return fmt.Errorf("unsupported shape %v of %#v", shape, name)
+		}
+		a.weights[name] = tensor{data: data, shape: shape}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("lora: failed to read the adapter weights: %w", err)
+	}
+	if len(a.weights) == 0 {
+		return nil, errors.New("lora: the adapter has no weights")
+	}
+	return a, nil
+}
+
+type adapterKey struct{}
+
+// NewContext returns a copy of the context carrying the name of the adapter
+// applied to the model serving the request.
+func NewContext(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, adapterKey{}, name)
+}
+
+// FromContext returns the name of the adapter carried by the context, or
+// an empty string for the base model.
+func FromContext(ctx context.Context) string {
+	name, _ := ctx.Value(adapterKey{}).(string)
+	return name
+}
@@ -0,0 +1,253 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lora
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/models/bert"
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/nlpodyssey/spago/mat/float"
+	"github.com/nlpodyssey/spago/nn"
+	"github.com/nlpodyssey/spago/nn/linear"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// classifier stands for the model of a task.
+type classifier struct {
+	Model  *bert.ModelForSequenceClassification
+	Labels []string
+}
+
+func newClassifier() *classifier {
+	c := bert.Config{
+		HiddenSize:        4,
+		IntermediateSize:  6,
+		NumAttentionHeads: 2,
+		NumHiddenLayers:   2,
+		HiddenAct:         "gelu",
+	}
+	m := &bert.ModelForSequenceClassification{
+		Bert:       &bert.Model{Encoder: bert.NewEncoder[float32](c), Config: c},
+		Classifier: linear.New[float32](4, 3),
+	}
+	r := rand.New(rand.NewSource(42))
+	nn.ForEachParam(m, func(p nn.Param) {
+		v := p.Value()
+		rows, cols := v.Dims()
+		for i := 0; i < rows; i++ {
+			for j := 0; j < cols; j++ {
+				v.SetScalar(i, j, float.Interface(float32(r.Float64()-0.5)))
+			}
+		}
+	})
+	return &classifier{Model: m, Labels: []string{"a", "b", "c"}}
+}
+
+func randomTensor(seed int64, shape ...int) tensor {
+	r := rand.New(rand.NewSource(seed))
+	size := 1
+	for _, s := range shape {
+		size *= s
+	}
+	data := make([]float32, size)
+	for i := range data {
+		data[i] = float32(r.Float64() - 0.5)
+	}
+	return tensor{data: data, shape: shape}
+}
+
+func newAdapter(weights map[string]tensor) *Adapter {
+	return &Adapter{Config: Config{PeftType: "LORA", R: 2, Alpha: 4}, weights: weights}
+}
+
+// expected returns the weight plus the scaled update.
+func expected(w mat.Matrix, a, b tensor, scale float64) mat.Matrix {
+	am := mat.NewDense[float32](a.shape[0], a.shape[1], a.data)
+	bm := mat.NewDense[float32](b.shape[0], b.shape[1], b.data)
+	return w.Add(bm.Mul(am).ProdScalar(scale))
+}
+
+func TestApply(t *testing.T) {
+	base := newClassifier()
+	const prefix = "base_model.model.bert.encoder.layer.1."
+	queryA, queryB := randomTensor(1, 2, 4), randomTensor(2, 4, 2)
+	ffA, ffB := randomTensor(3, 2, 4), randomTensor(4, 6, 2)
+	head := randomTensor(5, 3, 4)
+	a := newAdapter(map[string]tensor{
+		prefix + "attention.self.query.lora_A.weight":              queryA,
+		prefix + "attention.self.query.lora_B.weight":              queryB,
+		prefix + "intermediate.dense.lora_A.default.weight":        ffA,
+		prefix + "intermediate.dense.lora_B.default.weight":        ffB,
+		"base_model.model.classifier.modules_to_save.weight":       head,
+		"base_model.model.classifier.modules_to_save.default.bias": randomTensor(6, 3),
+	})
+
+	view, err := Apply(base, a)
+	require.NoError(t, err)
+	require.NotSame(t, base, view)
+	assert.Equal(t, base.Labels, view.Labels)
+
+	// The modules not updated are shared.
+	assert.Same(t, base.Model.Bert.Encoder.Layers[0], view.Model.Bert.Encoder.Layers[0])
+	baseLayer, viewLayer := base.Model.Bert.Encoder.Layers[1], view.Model.Bert.Encoder.Layers[1]
+	require.NotSame(t, baseLayer, viewLayer)
+	assert.Same(t, baseLayer.SelfAttention.Norm, viewLayer.SelfAttention.Norm)
+	assert.Same(t, baseLayer.SelfAttention.Attention.OutputMerge, viewLayer.SelfAttention.Attention.OutputMerge)
+	assert.Same(t, baseLayer.FF.MLP[2], viewLayer.FF.MLP[2])
+
+	// The query projection of each head is updated with its rows of lora_B.
+	for j, h := range viewLayer.SelfAttention.Attention.Heads {
+		baseHead := baseLayer.SelfAttention.Attention.Heads[j]
+		assert.Same(t, baseHead.Key, h.Key)
+		assert.Same(t, baseHead.Query.B, h.Query.B)
+		want := expected(baseHead.Query.W.Value(), queryA, *queryB.rows(j*2, (j+1)*2), 2)
+		assert.InDeltaSlice(t, want.Data().F32(), h.Query.W.Value().Data().F32(), 1e-6)
+	}
+
+	// The feed-forward layer adds the update to its outputs.
+	ff, ok := viewLayer.FF.MLP[0].(*Linear)
+	require.True(t, ok)
+	baseFF := baseLayer.FF.MLP[0].(*linear.Model)
+	assert.Same(t, baseFF, ff.Base)
+	x := mat.NewVecDense([]float32{0.1, -0.2, 0.3, 0.4})
+	want := expected(baseFF.W.Value(), ffA, ffB, 2).Mul(x).Add(baseFF.B.Value())
+	assert.InDeltaSlice(t, want.Data().F32(), ff.Forward(x)[0].Value().Data().F32(), 1e-6)
+
+	// The task head is replaced.
+	assert.Equal(t, head.data, view.Model.Classifier.W.Value().Data().F32())
+	assert.NotEqual(t, head.data, base.Model.Classifier.W.Value().Data().F32())
+}
+
+func TestApply_Errors(t *testing.T) {
+	const prefix = "base_model.model.bert.encoder.layer.0."
+	tests := []struct {
+		name    string
+		weights map[string]tensor
+		err     string
+	}{
+		{
+			name: "unsupported module",
+			weights: map[string]tensor{
+				prefix + "attention.output.LayerNorm.lora_A.weight": randomTensor(1, 2, 4),
+				prefix + "attention.output.LayerNorm.lora_B.weight": randomTensor(1, 4, 2),
+			},
+			err: "unsupported module",
+		},
+		{
+			name: "invalid layer",
+			weights: map[string]tensor{
+				"base_model.model.bert.encoder.layer.5.output.dense.lora_A.weight": randomTensor(1, 2, 6),
+				"base_model.model.bert.encoder.layer.5.output.dense.lora_B.weight": randomTensor(1, 4, 2),
+			},
+			err: "invalid layer",
+		},
+		{
+			name: "missing lora_B",
+			weights: map[string]tensor{
+				prefix + "attention.self.value.lora_A.weight": randomTensor(1, 2, 4),
+			},
+			err: "missing lora_A or lora_B",
+		},
+		{
+			name: "shape mismatch",
+			weights: map[string]tensor{
+				prefix + "output.dense.lora_A.weight": randomTensor(1, 2, 4),
+				prefix + "output.dense.lora_B.weight": randomTensor(1, 4, 2),
+			},
+			err: "lora_A has 4 columns, the layer 6 inputs",
+		},
+		{
+			name: "different labels",
+			weights: map[string]tensor{
+				"base_model.model.classifier.modules_to_save.weight": randomTensor(1, 2, 4),
+			},
+			err: "the labels must be the same",
+		},
+		{
+			name: "unsupported weight",
+			weights: map[string]tensor{
+				"base_model.model.bert.pooler.dense.weight": randomTensor(1, 4, 4),
+			},
+			err: "unsupported adapter weight",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Apply(newClassifier(), newAdapter(tt.weights))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+
+	_, err := Apply(struct{ Labels []string }{}, newAdapter(nil))
+	assert.ErrorContains(t, err, "only supported by the BERT models")
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	config := `{"peft_type": "LORA", "base_model_name_or_path": "org/model", "r": 2, "lora_alpha": 8, "target_modules": ["query", "value"]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ConfigFilename), []byte(config), 0o644))
+	writeSafetensors(t, filepath.Join(dir, SafetensorsFilename), map[string]tensor{
+		"base_model.model.bert.encoder.layer.0.attention.self.value.lora_A.weight": randomTensor(1, 2, 4),
+		"base_model.model.bert.encoder.layer.0.attention.self.value.lora_B.weight": randomTensor(2, 4, 2),
+	})
+
+	a, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "org/model", a.Config.BaseModel)
+	assert.Equal(t, 4.0, a.Config.Scale())
+	assert.Len(t, a.weights, 2)
+	assert.Equal(t, []int{4, 2}, a.weights["base_model.model.bert.encoder.layer.0.attention.self.value.lora_B.weight"].shape)
+
+	_, err = Apply(newClassifier(), a)
+	assert.NoError(t, err)
+
+	config = `{"peft_type": "LORA", "r": 2, "lora_alpha": 8, "fan_in_fan_out": true}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ConfigFilename), []byte(config), 0o644))
+	_, err = Load(dir)
+	assert.ErrorContains(t, err, "fan_in_fan_out not supported")
+
+	_, err = Load(t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestConfig_Scale(t *testing.T) {
+	assert.Equal(t, 2.0, Config{R: 8, Alpha: 16}.Scale())
+	assert.Equal(t, 4.0, Config{R: 4, Alpha: 8, UseRSLoRA: true}.Scale())
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "", FromContext(ctx))
+	assert.Equal(t, "legal", FromContext(NewContext(ctx, "legal")))
+}
+
+// writeSafetensors writes the tensors to a safetensors file.
+func writeSafetensors(t *testing.T, filename string, tensors map[string]tensor) {
+	var header []string
+	var data []byte
+	for name, tt := range tensors {
+		shape := strings.Trim(strings.Join(strings.Fields(fmt.Sprint(tt.shape)), ","), "[]")
+		header = append(header, fmt.Sprintf(`%q:{"dtype":"F32","shape":[%s],"data_offsets":[%d,%d]}`,
+			name, shape, len(data), len(data)+4*len(tt.data)))
+		for _, v := range tt.data {
+			data = binary.LittleEndian.AppendUint32(data, math.Float32bits(v))
+		}
+	}
+	h := "{" + strings.Join(header, ",") + "}"
+	content := binary.LittleEndian.AppendUint64(nil, uint64(len(h)))
+	content = append(append(content, h...), data...)
+	require.NoError(t, os.WriteFile(filename, content, 0o644))
+}
@@ -84,8 +84,12 @@ type DecodingOutput struct {
 // DecodingFunc returns a decoding function that works using the encoder states derived from the input.
 // During inference, it adjusts the logits to avoid impossible tokens.
 func (m *ModelForConditionalGeneration) DecodingFunc(encoderInputIDs []int, scoreProc generationutils.ScoreProcessor, inference bool) func(batch []*DecodingInput) []*DecodingOutput {
-	encoderStates := m.Bart.Encoder.Encode(encoderInputIDs)
+	return m.DecodingFuncFromStates(m.Bart.Encoder.Encode(encoderInputIDs), scoreProc, inference)
+}
 
+// DecodingFuncFromStates returns a decoding function that works using the given encoder states, e.g. the
+// ones of an input encoded beforehand.
+func (m *ModelForConditionalGeneration) DecodingFuncFromStates(encoderStates []ag.Node, scoreProc generationutils.ScoreProcessor, inference bool) func(batch []*DecodingInput) []*DecodingOutput {
 	return func(batch []*DecodingInput) []*DecodingOutput {
 		result := make([]*DecodingOutput, len(batch))
 
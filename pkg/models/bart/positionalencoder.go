@@ -7,6 +7,7 @@ package bart
 import (
 	"encoding/gob"
 
+	"github.com/nlpodyssey/cybertron/pkg/models/embeddingtable"
 	"github.com/nlpodyssey/spago/ag"
 	"github.com/nlpodyssey/spago/embeddings"
 	"github.com/nlpodyssey/spago/embeddings/store"
@@ -35,6 +36,9 @@ type PositionalEncoder struct {
 	Embeddings *embeddings.Model[int]
 	// Config contains the configuration settings.
 	Config PositionalEncoderConfig
+	// table caches the embeddings of the positions, the same for all the
+	// inputs.
+	table embeddingtable.Table
 }
 
 func init() {
@@ -73,7 +77,13 @@ func NewPositionalEncoder[T float.DType](config PositionalEncoderConfig, repo st
 
 // Encode performs the forward step for each input and returns the result.
 func (m *PositionalEncoder) Encode(positions []int) []ag.Node {
-	return m.Embeddings.Encode(m.shift(positions))
+	return m.table.Encode(m.Embeddings, m.shift(positions))
+}
+
+// PrecomputePositions caches the embeddings of all the positions, up to the
+// maximum length of the model, which are otherwise cached on their first use.
+func (m *PositionalEncoder) PrecomputePositions() {
+	m.table.Precompute(m.Embeddings, m.Config.NumEmbeddings+m.Config.Offset)
 }
 
 // shift returns the shifted positions by the offset.
@@ -0,0 +1,134 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bert
+
+import (
+	"encoding/gob"
+
+	"github.com/nlpodyssey/spago/ag"
+	"github.com/nlpodyssey/spago/mat/float"
+	"github.com/nlpodyssey/spago/nn"
+	"github.com/nlpodyssey/spago/nn/activation"
+	"github.com/nlpodyssey/spago/nn/linear"
+	"github.com/nlpodyssey/spago/nn/normalization/layernorm"
+)
+
+var _ nn.Model = &ModelForCoreferenceResolution{}
+
+// ModelForCoreferenceResolution implements a Bert model for span-based
+// coreference resolution, scoring the spans as mentions, and the mentions as
+// antecedents of each other, by the representations of their start and end
+// tokens only, as in "Coreference Resolution without Span Representations"
+// (Kirstain et al., 2021).
+type ModelForCoreferenceResolution struct {
+	nn.Module
+	// Bart is the fine-tuned BERT model.
+	Bert *Model
+	// StartMentionMLP and EndMentionMLP are the feedforward layers of the
+	// representations of the tokens as start and end of the mentions.
+	StartMentionMLP nn.ModuleList[nn.StandardModel]
+	EndMentionMLP   nn.ModuleList[nn.StandardModel]
+	// StartCorefMLP and EndCorefMLP are the feedforward layers of the
+	// representations of the tokens as start and end of the antecedents.
+	StartCorefMLP nn.ModuleList[nn.StandardModel]
+	EndCorefMLP   nn.ModuleList[nn.StandardModel]
+	// MentionStartClassifier and MentionEndClassifier score the tokens as
+	// start and end of a mention.
+	MentionStartClassifier *linear.Model
+	MentionEndClassifier   *linear.Model
+	// MentionS2EClassifier scores the pairs of start and end tokens as mentions.
+	MentionS2EClassifier *linear.Model
+	// AntecedentS2SClassifier, AntecedentE2EClassifier, AntecedentS2EClassifier
+	// and AntecedentE2SClassifier score the pairs of mentions by their start
+	// and end tokens.
+	AntecedentS2SClassifier *linear.Model
+	AntecedentE2EClassifier *linear.Model
+	AntecedentS2EClassifier *linear.Model
+	AntecedentE2SClassifier *linear.Model
+}
+
+func init() {
+	gob.Register(&ModelForCoreferenceResolution{})
+}
+
+// MentionScores are the scores of the spans of the tokens as mentions: the
+// score of the span from the i-th to the j-th token is the sum of Starts[i],
+// Ends[j] and the element (i, j) of Joint.
+type MentionScores struct {
+	Starts []ag.Node
+	Ends   []ag.Node
+	Joint  ag.Node
+}
+
+// NewModelForCoreferenceResolution returns a new model for coreference
+// resolution, with start and end representations of the size of
+// Config.FFNNSize, or of the hidden size if not set.
+func NewModelForCoreferenceResolution[T float.DType](bert *Model) *ModelForCoreferenceResolution {
+	c := bert.Config
+	size := c.FFNNSize
+	if size == 0 {
+		size = c.HiddenSize
+	}
+	mlp := func() nn.ModuleList[nn.StandardModel] {
+		return []nn.StandardModel{
+			linear.New[T](c.HiddenSize, size),
+			activation.New(activation.MustActivation(c.HiddenAct)),
+			layernorm.New[T](size, c.LayerNormEps),
+		}
+	}
+	return &ModelForCoreferenceResolution{
+		Bert:                    bert,
+		StartMentionMLP:         mlp(),
+		EndMentionMLP:           mlp(),
+		StartCorefMLP:           mlp(),
+		EndCorefMLP:             mlp(),
+		MentionStartClassifier:  linear.New[T](size, 1),
+		MentionEndClassifier:    linear.New[T](size, 1),
+		MentionS2EClassifier:    linear.New[T](size, size),
+		AntecedentS2SClassifier: linear.New[T](size, size),
+		AntecedentE2EClassifier: linear.New[T](size, size),
+		AntecedentS2EClassifier: linear.New[T](size, size),
+		AntecedentE2SClassifier: linear.New[T](size, size),
+	}
+}
+
+// Mentions returns the scores of the spans of the tokens as mentions, with
+// the representations of the tokens as start and end of the antecedents,
+// to score the mentions kept (see Antecedents).
+func (m *ModelForCoreferenceResolution) Mentions(tokens []string) (scores MentionScores, starts, ends []ag.Node) {
+	encoded := m.Bert.Encode(tokens)
+	startMention := m.StartMentionMLP.Forward(encoded...)
+	endMention := m.EndMentionMLP.Forward(encoded...)
+
+	scores = MentionScores{
+		Starts: m.MentionStartClassifier.Forward(startMention...),
+		Ends:   m.MentionEndClassifier.Forward(endMention...),
+		Joint:  bilinear(m.MentionS2EClassifier, startMention, endMention),
+	}
+	return scores, m.StartCorefMLP.Forward(encoded...), m.EndCorefMLP.Forward(encoded...)
+}
+
+// Antecedents returns the scores of the mentions as antecedents of each
+// other, given the representations of their start and end tokens: the
+// element (i, j) is the score of the j-th mention as antecedent of the i-th
+// one, apart from their scores as mentions.
+func (m *ModelForCoreferenceResolution) Antecedents(starts, ends []ag.Node) ag.Node {
+	return ag.Add(
+		ag.Add(
+			bilinear(m.AntecedentS2SClassifier, starts, starts),
+			bilinear(m.AntecedentE2EClassifier, ends, ends),
+		),
+		ag.Add(
+			bilinear(m.AntecedentS2EClassifier, starts, ends),
+			bilinear(m.AntecedentE2SClassifier, ends, starts),
+		),
+	)
+}
+
+// bilinear returns the matrix of the scores of each x with each y, whose
+// element (i, j) is the dot product of the projection of xs[i] with ys[j].
+func bilinear(projection *linear.Model, xs, ys []ag.Node) ag.Node {
+	return ag.Mul(ag.Stack(projection.Forward(xs...)...), ag.T(ag.Stack(ys...)))
+}
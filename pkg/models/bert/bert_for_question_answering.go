@@ -39,9 +39,15 @@ func NewModelForQuestionAnswering[T float.DType](bert *Model) *ModelForQuestionA
 
 // Answer returns the "span start logits" and "span end logits".
 func (m *ModelForQuestionAnswering) Answer(tokens []string) (starts, ends []ag.Node) {
-	for _, y := range m.Classifier.Forward(m.Bert.Encode(tokens)...) {
+	for _, y := range m.Logits(tokens) {
 		starts = append(starts, ag.AtVec(y, 0))
 		ends = append(ends, ag.AtVec(y, 1))
 	}
 	return
 }
+
+// Logits returns the logits of each token, as a vector of its "span start
+// logit" and its "span end logit", without the nodes splitting them of Answer.
+func (m *ModelForQuestionAnswering) Logits(tokens []string) []ag.Node {
+	return m.Classifier.Forward(m.Bert.Encode(tokens)...)
+}
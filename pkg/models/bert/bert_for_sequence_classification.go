@@ -40,3 +40,10 @@ func NewModelForSequenceClassification[T float.DType](bert *Model) *ModelForSequ
 func (m *ModelForSequenceClassification) Classify(tokens []string) ag.Node {
 	return m.Classifier.Forward(m.Bert.Pooler.Forward(m.Bert.Encode(tokens)[0]))[0]
 }
+
+// ClassifyLayers returns the logits for the sequence classification, computed
+// from the output of the first n encoder layers only: faster but less accurate,
+// as the pooler and the classifier were trained on the output of the last one.
+func (m *ModelForSequenceClassification) ClassifyLayers(tokens []string, n int) ag.Node {
+	return m.Classifier.Forward(m.Bert.Pooler.Forward(m.Bert.EncodeLayers(tokens, n)[0]))[0]
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/gob"
 	"fmt"
 
+	"github.com/nlpodyssey/cybertron/pkg/models/bert/bertconfig"
 	"github.com/nlpodyssey/spago/ag"
 	"github.com/nlpodyssey/spago/nn"
 )
@@ -22,17 +23,17 @@ type ModelForSequenceEncoding struct {
 }
 
 // PoolingStrategyType defines the strategy to obtain the dense sequence representation
-type PoolingStrategyType int
+type PoolingStrategyType = bertconfig.PoolingStrategyType
 
 const (
 	// ClsTokenPooling gets the last encoding state corresponding to [CLS], i.e. the first token (default)
-	ClsTokenPooling PoolingStrategyType = iota
+	ClsTokenPooling = bertconfig.ClsTokenPooling
 	// MeanPooling takes the average of the last encoding states
-	MeanPooling
+	MeanPooling = bertconfig.MeanPooling
 	// MaxPooling takes the maximum of the last encoding states
-	MaxPooling
+	MaxPooling = bertconfig.MaxPooling
 	// MeanMaxPooling does MeanPooling and MaxPooling separately and then concat them together
-	MeanMaxPooling
+	MeanMaxPooling = bertconfig.MeanMaxPooling
 )
 
 func init() {
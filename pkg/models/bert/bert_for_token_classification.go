@@ -10,6 +10,7 @@ import (
 	"github.com/nlpodyssey/spago/ag"
 	"github.com/nlpodyssey/spago/mat/float"
 	"github.com/nlpodyssey/spago/nn"
+	"github.com/nlpodyssey/spago/nn/crf"
 	"github.com/nlpodyssey/spago/nn/linear"
 )
 
@@ -22,6 +23,10 @@ type ModelForTokenClassification struct {
 	Bert *Model
 	// Classifier is the linear layer for sequence classification.
 	Classifier *linear.Model
+	// CRF holds the transition scores between the labels of the models
+	// fine-tuned with a Conditional Random Fields layer on top, nil for
+	// the other ones.
+	CRF *crf.Model
 }
 
 func init() {
@@ -40,3 +45,18 @@ func NewModelForTokenClassification[T float.DType](bert *Model) *ModelForTokenCl
 func (m *ModelForTokenClassification) Classify(tokens []string) []ag.Node {
 	return m.Classifier.Forward(m.Bert.Encode(tokens)...)
 }
+
+// Decode returns the index of the label of each token, given the logits of
+// the tokens: the most likely sequence of labels according to the
+// transition scores, with the Viterbi algorithm, if the model has a CRF
+// layer, or the best label of each token on its own otherwise.
+func (m *ModelForTokenClassification) Decode(logits []ag.Node) []int {
+	if m.CRF != nil && len(logits) > 0 {
+		return m.CRF.Decode(logits)
+	}
+	labels := make([]int, len(logits))
+	for i, l := range logits {
+		labels[i] = l.Value().ArgMax()
+	}
+	return labels
+}
@@ -0,0 +1,153 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package bertconfig defines the configuration of the Bert models apart
+// from their implementation, so that it can be used without its embeddings
+// store, e.g. by the ONNX tasks compiled to WebAssembly.
+package bertconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+)
+
+// Config contains the global configuration of the Bert model and the heads of fine-tuning tasks.
+// The configuration coincides with that of Hugging Face to facilitate compatibility between the two architectures.
+type Config struct {
+	Architectures             []string          `json:"architectures"`
+	AttentionProbsDropoutProb float64           `json:"attention_probs_dropout_prob"`
+	GradientCheckpointing     bool              `json:"gradient_checkpointing"`
+	HiddenAct                 string            `json:"hidden_act"`
+	HiddenDropoutProb         float64           `json:"hidden_dropout_prob"`
+	HiddenSize                int               `json:"hidden_size"`
+	EmbeddingsSize            int               `json:"embeddings_size"`
+	InitializerRange          float64           `json:"initializer_range"`
+	IntermediateSize          int               `json:"intermediate_size"`
+	LayerNormEps              float64           `json:"layer_norm_eps"`
+	MaxPositionEmbeddings     int               `json:"max_position_embeddings"`
+	ModelType                 string            `json:"model_type"`
+	NumAttentionHeads         int               `json:"num_attention_heads"`
+	NumHiddenLayers           int               `json:"num_hidden_layers"`
+	PadTokenId                int               `json:"pad_token_id"`
+	PositionEmbeddingType     string            `json:"position_embedding_type"`
+	ProblemType               string            `json:"problem_type"`
+	TransformersVersion       string            `json:"transformers_version"`
+	TypeVocabSize             int               `json:"type_vocab_size"`
+	UseCache                  bool              `json:"use_cache"`
+	VocabSize                 int               `json:"vocab_size"`
+	ID2Label                  map[string]string `json:"id2label"`
+	RopeScaling               *RopeScaling      `json:"rope_scaling"`
+	// FFNNSize, MaxSpanLength and TopLambda configure the heads of the
+	// span-based coreference resolution models: the size of the start and
+	// end representations of the tokens, the maximum length in tokens of
+	// the mentions, and the ratio of candidate mentions kept per word.
+	FFNNSize      int     `json:"ffnn_size"`
+	MaxSpanLength int     `json:"max_span_length"`
+	TopLambda     float64 `json:"top_lambda"`
+	Cybertron     struct {
+		Training            bool   `json:"training"`
+		TokensStoreName     string `json:"tokens_store_name"`
+		PositionsStoreName  string `json:"positions_store_name"`
+		TokenTypesStoreName string `json:"token_types_store_name"`
+	}
+}
+
+// RopeScaling is the scaling of the rotary position embeddings of the models extending their context.
+type RopeScaling struct {
+	// Type is the scaling type, e.g. "linear" or "yarn" ("rope_type" in the recent configurations).
+	Type                          string  `json:"type"`
+	RopeType                      string  `json:"rope_type"`
+	Factor                        float64 `json:"factor"`
+	OriginalMaxPositionEmbeddings int     `json:"original_max_position_embeddings"`
+	BetaFast                      float64 `json:"beta_fast"`
+	BetaSlow                      float64 `json:"beta_slow"`
+}
+
+// Kind returns the scaling type, whichever the field it's set in.
+func (r RopeScaling) Kind() string {
+	if r.Type != "" {
+		return r.Type
+	}
+	return r.RopeType
+}
+
+// TokenizerConfig contains the configuration of the tokenizer.
+// The configuration coincides with that of Hugging Face to facilitate compatibility between the two architectures.
+type TokenizerConfig struct {
+	DoLowerCase          bool        `json:"do_lower_case"`
+	UnkToken             string      `json:"unk_token"`
+	SepToken             string      `json:"sep_token"`
+	PadToken             string      `json:"pad_token"`
+	ClsToken             string      `json:"cls_token"`
+	MaskToken            string      `json:"mask_token"`
+	TokenizeChineseChars bool        `json:"tokenize_chinese_chars"`
+	StripAccents         interface{} `json:"strip_accents"`
+	ModelMaxLength       int         `json:"model_max_length"`
+}
+
+// ConfigFile is the union of the configuration structures.
+type ConfigFile interface {
+	Config | TokenizerConfig
+}
+
+// ConfigFromFile loads a Bert model Config from file.
+func ConfigFromFile[T ConfigFile](file string) (config T, _ error) {
+	configFile, err := os.Open(file)
+	if err != nil {
+		return config, err
+	}
+	defer configFile.Close()
+	err = json.NewDecoder(configFile).Decode(&config)
+	if err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// ConfigFromFS loads a Bert model Config from a file of the file system.
+func ConfigFromFS[T ConfigFile](fsys fs.FS, name string) (config T, _ error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// ID2Label returns the labels sorted by their IDs, from the id2label map
+// of the configuration.
+func ID2Label(value map[string]string) ([]string, error) {
+	if len(value) == 0 {
+		return []string{"LABEL_0", "LABEL_1"}, nil // assume binary classification by default
+	}
+	y := make([]string, len(value))
+	for k, v := range value {
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 || i >= len(y) {
+			return nil, fmt.Errorf("invalid label ID %#v", k)
+		}
+		y[i] = v
+	}
+	return y, nil
+}
+
+// PoolingStrategyType defines the strategy to obtain the dense sequence representation
+type PoolingStrategyType int
+
+const (
+	// ClsTokenPooling gets the last encoding state corresponding to [CLS], i.e. the first token (default)
+	ClsTokenPooling PoolingStrategyType = iota
+	// MeanPooling takes the average of the last encoding states
+	MeanPooling
+	// MaxPooling takes the maximum of the last encoding states
+	MaxPooling
+	// MeanMaxPooling does MeanPooling and MaxPooling separately and then concat them together
+	MeanMaxPooling
+)
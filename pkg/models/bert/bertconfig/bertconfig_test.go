@@ -0,0 +1,62 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bertconfig
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config.json":           {Data: []byte(`{"hidden_size": 8, "id2label": {"0": "NEG", "1": "POS"}}`)},
+		"tokenizer_config.json": {Data: []byte(`{"do_lower_case": true}`)},
+		"invalid.json":          {Data: []byte(`{"hidden_size": "8"}`)},
+	}
+
+	config, err := ConfigFromFS[Config](fsys, "config.json")
+	require.NoError(t, err)
+	assert.Equal(t, 8, config.HiddenSize)
+	assert.Equal(t, map[string]string{"0": "NEG", "1": "POS"}, config.ID2Label)
+
+	tokenizerConfig, err := ConfigFromFS[TokenizerConfig](fsys, "tokenizer_config.json")
+	require.NoError(t, err)
+	assert.True(t, tokenizerConfig.DoLowerCase)
+
+	_, err = ConfigFromFS[Config](fsys, "invalid.json")
+	assert.Error(t, err)
+	_, err = ConfigFromFS[Config](fsys, "missing.json")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestID2Label(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   map[string]string
+		want    []string
+		wantErr bool
+	}{
+		{"default", nil, []string{"LABEL_0", "LABEL_1"}, false},
+		{"sorted by ID", map[string]string{"1": "POS", "0": "NEG", "2": "NEU"}, []string{"NEG", "POS", "NEU"}, false},
+		{"not a number", map[string]string{"0": "NEG", "one": "POS"}, nil, true},
+		{"negative", map[string]string{"0": "NEG", "-1": "POS"}, nil, true},
+		{"out of range", map[string]string{"0": "NEG", "2": "POS"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ID2Label(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
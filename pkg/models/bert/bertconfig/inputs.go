@@ -0,0 +1,132 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bertconfig
+
+// The special tokens of the BERT models, the default ones.
+const (
+	defaultClsToken = "[CLS]"
+	defaultSepToken = "[SEP]"
+)
+
+// doubleSeparatorModelTypes are the model types separating the texts of a
+// pair with two separators, e.g. "<s> A </s></s> B </s>" for RoBERTa.
+var doubleSeparatorModelTypes = map[string]bool{
+	"roberta":     true,
+	"xlm-roberta": true,
+	"camembert":   true,
+	"longformer":  true,
+	"mpnet":       true,
+}
+
+// Inputs builds the inputs of a model from the tokens of a text, or of a
+// pair of texts, as its architecture expects them: the special tokens
+// around the texts, and the token types of their segments.
+type Inputs struct {
+	// Cls is the token starting the inputs, e.g. "[CLS]", or "<s>".
+	Cls string
+	// Sep is the token ending each text, e.g. "[SEP]", or "</s>".
+	Sep string
+	// PairSep are the tokens between the texts of a pair, e.g. "[SEP]", or
+	// "</s>" twice for RoBERTa.
+	PairSep []string
+	// TokenTypes is the number of token types of the model: the models
+	// without segment embeddings, with at most one type, have the type 0
+	// for all the tokens.
+	TokenTypes int
+}
+
+// Encoded is the input of a model built by Inputs.
+type Encoded struct {
+	// Tokens are the tokens of the texts, with the special ones.
+	Tokens []string
+	// TokenTypeIDs are the token type of each token, e.g. 1 for the tokens
+	// of the second text of a pair.
+	TokenTypeIDs []int
+	// AttentionMask is 1 for each token, as the inputs aren't padded.
+	AttentionMask []int
+}
+
+// NewInputs returns the Inputs of the model of the configuration, with
+// the special tokens of its tokenizer, "[CLS]" and "[SEP]" by default, and
+// the separators of the pairs of its model type. The models with no
+// type_vocab_size, e.g. converted with the first releases, have the two
+// token types of BERT.
+func NewInputs(c Config, tc TokenizerConfig) Inputs {
+	in := Inputs{
+		Cls:        tc.ClsToken,
+		Sep:        tc.SepToken,
+		TokenTypes: c.TypeVocabSize,
+	}
+	if in.Cls == "" {
+		in.Cls = defaultClsToken
+	}
+	if in.Sep == "" {
+		in.Sep = defaultSepToken
+	}
+	in.PairSep = []string{in.Sep}
+	if doubleSeparatorModelTypes[c.ModelType] {
+		in.PairSep = []string{in.Sep, in.Sep}
+	}
+	if in.TokenTypes == 0 {
+		in.TokenTypes = 2
+	}
+	return in
+}
+
+// Single returns the input of the tokens of a text.
+func (in Inputs) Single(tokens []string) Encoded {
+	return in.encode(append(append([]string{in.Cls}, tokens...), in.Sep), 0)
+}
+
+// Pair returns the input of the tokens of a pair of texts, whose second
+// text has the token type 1, if the model has token types.
+func (in Inputs) Pair(first, second []string) Encoded {
+	tokens := make([]string, 0, len(first)+len(second)+len(in.PairSep)+2)
+	tokens = append(append(append(tokens, in.Cls), first...), in.PairSep...)
+	tokens = append(append(tokens, second...), in.Sep)
+	return in.encode(tokens, len(first)+1+len(in.PairSep))
+}
+
+// encode returns the input of the tokens, whose second text, if any,
+// starts at the given position (0 for none).
+func (in Inputs) encode(tokens []string, second int) Encoded {
+	e := Encoded{
+		Tokens:        tokens,
+		TokenTypeIDs:  make([]int, len(tokens)),
+		AttentionMask: make([]int, len(tokens)),
+	}
+	for i := range tokens {
+		e.AttentionMask[i] = 1
+		if second > 0 && i >= second && in.TokenTypes > 1 {
+			e.TokenTypeIDs[i] = 1
+		}
+	}
+	return e
+}
+
+// TokenTypeIDs returns the token types of the tokens of an input, built by
+// Single or Pair, e.g. after masking some of them: 0 up to the first
+// separator, and 1 after the separators of the pair, if the model has
+// token types.
+func (in Inputs) TokenTypeIDs(tokens []string) []int {
+	types := make([]int, len(tokens))
+	if in.TokenTypes <= 1 {
+		return types
+	}
+	second := 0
+	for i, t := range tokens {
+		if t == in.Sep {
+			second = i + len(in.PairSep)
+			break
+		}
+	}
+	if second == 0 {
+		return types
+	}
+	for i := second; i < len(types); i++ {
+		types[i] = 1
+	}
+	return types
+}
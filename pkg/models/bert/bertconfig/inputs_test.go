@@ -0,0 +1,42 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bertconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInputs(t *testing.T) {
+	t.Run("bert", func(t *testing.T) {
+		in := NewInputs(Config{ModelType: "bert", TypeVocabSize: 2}, TokenizerConfig{})
+		e := in.Pair([]string{"a", "b"}, []string{"c"})
+		assert.Equal(t, []string{"[CLS]", "a", "b", "[SEP]", "c", "[SEP]"}, e.Tokens)
+		assert.Equal(t, []int{0, 0, 0, 0, 1, 1}, e.TokenTypeIDs)
+		assert.Equal(t, []int{1, 1, 1, 1, 1, 1}, e.AttentionMask)
+		assert.Equal(t, e.TokenTypeIDs, in.TokenTypeIDs(e.Tokens))
+
+		e = in.Single([]string{"a"})
+		assert.Equal(t, []string{"[CLS]", "a", "[SEP]"}, e.Tokens)
+		assert.Equal(t, []int{0, 0, 0}, e.TokenTypeIDs)
+	})
+
+	t.Run("roberta", func(t *testing.T) {
+		in := NewInputs(Config{ModelType: "roberta", TypeVocabSize: 1}, TokenizerConfig{ClsToken: "<s>", SepToken: "</s>"})
+		e := in.Pair([]string{"a"}, []string{"b", "c"})
+		assert.Equal(t, []string{"<s>", "a", "</s>", "</s>", "b", "c", "</s>"}, e.Tokens)
+		assert.Equal(t, []int{0, 0, 0, 0, 0, 0, 0}, e.TokenTypeIDs)
+		assert.Equal(t, e.TokenTypeIDs, in.TokenTypeIDs(e.Tokens))
+	})
+
+	t.Run("double separator with token types", func(t *testing.T) {
+		in := NewInputs(Config{ModelType: "mpnet"}, TokenizerConfig{})
+		e := in.Pair([]string{"a"}, []string{"b"})
+		assert.Equal(t, []string{"[CLS]", "a", "[SEP]", "[SEP]", "b", "[SEP]"}, e.Tokens)
+		assert.Equal(t, []int{0, 0, 0, 0, 1, 1}, e.TokenTypeIDs)
+		assert.Equal(t, e.TokenTypeIDs, in.TokenTypeIDs(e.Tokens))
+	})
+}
@@ -5,7 +5,8 @@
 package bert
 
 import (
-	"github.com/nlpodyssey/cybertron/pkg/tokenizers/wordpiecetokenizer"
+	"github.com/nlpodyssey/cybertron/pkg/models/bert/bertconfig"
+	"github.com/nlpodyssey/cybertron/pkg/models/embeddingtable"
 	"github.com/nlpodyssey/spago/ag"
 	emb "github.com/nlpodyssey/spago/embeddings"
 	"github.com/nlpodyssey/spago/embeddings/store"
@@ -24,6 +25,10 @@ type Embeddings struct {
 	Norm       *layernorm.Model
 	Projector  *linear.Model
 	Config     Config
+	// positions and tokenTypes cache the embeddings of the positions and of
+	// the token types, the same for all the inputs.
+	positions  embeddingtable.Table
+	tokenTypes embeddingtable.Table
 }
 
 // NewEmbeddings returns a new Bert input embedding module.
@@ -55,25 +60,39 @@ func NewEmbeddings[T float.DType](c Config, repo store.Repository) *Embeddings {
 	}
 }
 
-// Encode performs the Bert input encoding.
+// Encode performs the Bert input encoding. The token types follow the
+// separators of the texts (see bertconfig.Inputs), all 0 for the models
+// without segment embeddings.
 func (m *Embeddings) Encode(tokens []string) []ag.Node {
+	types := bertconfig.NewInputs(m.Config, bertconfig.TokenizerConfig{}).TokenTypeIDs(tokens)
 	var (
-		encoded      = m.Tokens.Encode(tokens)
-		positions    = m.Positions.Encode(indices(len(tokens)))
-		tokenType, _ = m.TokenTypes.Embedding(0)
+		encoded   = m.Tokens.Encode(tokens)
+		positions = m.positions.Encode(m.Positions, indices(len(tokens)))
+		tokenType = [2]ag.Node{m.tokenType(0)}
 	)
-
-	sequenceIndex := 0
 	for i := 0; i < len(tokens); i++ {
-		encoded[i] = ag.Sum(encoded[i], positions[i], tokenType)
-		if tokens[i] == wordpiecetokenizer.DefaultSequenceSeparator {
-			sequenceIndex++
-			tokenType, _ = m.TokenTypes.Embedding(sequenceIndex)
+		t := types[i]
+		if tokenType[t] == nil {
+			tokenType[t] = m.tokenType(t)
 		}
+		encoded[i] = ag.Sum(encoded[i], positions[i], tokenType[t])
 	}
 	return m.useProjection(m.Norm.Forward(encoded...))
 }
 
+// tokenType returns the embedding of the token type.
+func (m *Embeddings) tokenType(i int) ag.Node {
+	return m.tokenTypes.Encode(m.TokenTypes, []int{i})[0]
+}
+
+// PrecomputePositions caches the embeddings of all the positions, up to the
+// maximum length of the model, and of all the token types, which are
+// otherwise cached on their first use.
+func (m *Embeddings) PrecomputePositions() {
+	m.positions.Precompute(m.Positions, m.Config.MaxPositionEmbeddings)
+	m.tokenTypes.Precompute(m.TokenTypes, m.Config.TypeVocabSize)
+}
+
 // useProjection returns the output of the projector if it is not nil, otherwise the input.
 func (m *Embeddings) useProjection(xs []ag.Node) []ag.Node {
 	if m.Projector == nil {
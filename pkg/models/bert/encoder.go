@@ -41,3 +41,12 @@ func NewEncoder[T float.DType](c Config) *Encoder {
 func (e *Encoder) Encode(xs []ag.Node) []ag.Node {
 	return e.Layers.Forward(xs...)
 }
+
+// EncodeLayers performs the Bert encoding with the first n layers only,
+// or all of them if n <= 0 or exceeds their number.
+func (e *Encoder) EncodeLayers(xs []ag.Node, n int) []ag.Node {
+	if n <= 0 || n > len(e.Layers) {
+		n = len(e.Layers)
+	}
+	return e.Layers[:n].Forward(xs...)
+}
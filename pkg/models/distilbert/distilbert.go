@@ -8,6 +8,9 @@ import (
 	"github.com/nlpodyssey/spago/embeddings/store/diskstore"
 	"github.com/nlpodyssey/spago/mat/float"
 	"github.com/nlpodyssey/spago/nn"
+
+	// register the quantized layers, which may replace the linear ones in converted models
+	_ "github.com/nlpodyssey/cybertron/pkg/quantization"
 )
 
 var _ nn.Model = &Model{}
@@ -52,3 +55,16 @@ func (m *Model) SetEmbeddings(repo *diskstore.Repository) (err error) {
 func (m *Model) Encode(tokens []string) []ag.Node {
 	return m.Transformer.Encode(m.Embeddings.Encode(tokens))
 }
+
+// EncodeEach produces the encoded representation for the input tokens, as
+// Encode, calling f with the hidden states of the embeddings, as layer 0,
+// and with the ones of each layer of the transformer.
+func (m *Model) EncodeEach(tokens []string, f func(layer int, states []ag.Node)) []ag.Node {
+	xs := m.Embeddings.Encode(tokens)
+	f(0, xs)
+	for i, layer := range m.Transformer.Layers {
+		xs = layer.Forward(xs...)
+		f(i+1, xs)
+	}
+	return xs
+}
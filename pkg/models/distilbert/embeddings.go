@@ -1,6 +1,7 @@
 package distilbert
 
 import (
+	"github.com/nlpodyssey/cybertron/pkg/models/embeddingtable"
 	"github.com/nlpodyssey/spago/ag"
 	emb "github.com/nlpodyssey/spago/embeddings"
 	"github.com/nlpodyssey/spago/embeddings/store"
@@ -18,6 +19,9 @@ type Embeddings struct {
 	Norm      *layernorm.Model
 	Projector *linear.Model
 	Config    Config
+	// positions caches the embeddings of the positions, the same for all the
+	// inputs.
+	positions embeddingtable.Table
 }
 
 // NewEmbeddings returns a new DistilBert input embedding module.
@@ -48,7 +52,7 @@ func NewEmbeddings[T float.DType](c Config, repo store.Repository) *Embeddings {
 func (m *Embeddings) Encode(tokens []string) []ag.Node {
 	var (
 		encoded   = m.Tokens.Encode(tokens)
-		positions = m.Positions.Encode(indices(len(tokens)))
+		positions = m.positions.Encode(m.Positions, indices(len(tokens)))
 	)
 
 	for i := 0; i < len(tokens); i++ {
@@ -57,6 +61,12 @@ func (m *Embeddings) Encode(tokens []string) []ag.Node {
 	return m.useDropout(m.Norm.Forward(encoded...))
 }
 
+// PrecomputePositions caches the embeddings of all the positions, up to the
+// maximum length of the model, which are otherwise cached on their first use.
+func (m *Embeddings) PrecomputePositions() {
+	m.positions.Precompute(m.Positions, m.Config.MaxPositionEmbeddings)
+}
+
 // useDropout returns the output of the dropout if it is not nil, otherwise the input.
 func (m *Embeddings) useDropout(xs []ag.Node) []ag.Node {
 	if m.Projector == nil {
@@ -0,0 +1,104 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package embeddingtable caches in memory the embeddings of a model which
+// are the same for all the inputs, e.g. the ones of the positions and of
+// the token types, instead of reading and decoding them from the store of
+// the embeddings on each forward pass.
+package embeddingtable
+
+import (
+	"sync"
+
+	"github.com/nlpodyssey/spago/ag"
+	"github.com/nlpodyssey/spago/embeddings"
+	"github.com/nlpodyssey/spago/mat"
+)
+
+// Table caches the embeddings of integer keys, e.g. positions, read from
+// the store on their first use. The zero value is an empty table, ready to
+// use; it's safe for concurrent use.
+type Table struct {
+	mu sync.RWMutex
+	// values are the embeddings by key, nil if not read yet.
+	values []mat.Matrix
+}
+
+// Encode returns the embeddings of the keys, reading the ones not cached
+// yet from the model. As the Encode of the model, the missing ones are the
+// ZeroEmbedding of the model. The cached embeddings are constants, so the
+// ones of a trainable model are encoded by the model instead, accumulating
+// their gradients.
+func (t *Table) Encode(m *embeddings.Model[int], keys []int) []ag.Node {
+	if m.Trainable {
+		return m.Encode(keys)
+	}
+	nodes := make([]ag.Node, len(keys))
+	missing := false
+	t.mu.RLock()
+	for i, k := range keys {
+		if k >= 0 && k < len(t.values) && t.values[k] != nil {
+			nodes[i] = t.values[k]
+		} else {
+			missing = true
+		}
+	}
+	t.mu.RUnlock()
+	if !missing {
+		return nodes
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, k := range keys {
+		if nodes[i] == nil {
+			nodes[i] = t.read(m, k)
+		}
+	}
+	return nodes
+}
+
+// read returns the embedding of the key, reading it from the model if not
+// cached yet. It must be called holding the lock.
+func (t *Table) read(m *embeddings.Model[int], k int) ag.Node {
+	if k >= 0 && k < len(t.values) && t.values[k] != nil {
+		return t.values[k]
+	}
+	e, ok := m.Embedding(k)
+	if !ok || k < 0 {
+		return m.ZeroEmbedding
+	}
+	if k >= len(t.values) {
+		t.values = append(t.values, make([]mat.Matrix, k+1-len(t.values))...)
+	}
+	// The value is decoded from the store, so it's not shared.
+	t.values[k] = e.Value()
+	return t.values[k]
+}
+
+// Precompute reads the embeddings of the keys from 0 to n-1 not cached yet,
+// e.g. the positions up to the maximum length of the model, at load time.
+func (t *Table) Precompute(m *embeddings.Model[int], n int) {
+	if m.Trainable {
+		return
+	}
+	keys := make([]int, n)
+	for i := range keys {
+		keys[i] = i
+	}
+	t.Encode(m, keys)
+}
+
+// Len returns the number of embeddings cached.
+func (t *Table) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	n := 0
+	for _, v := range t.values {
+		if v != nil {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,57 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package embeddingtable
+
+import (
+	"testing"
+
+	"github.com/nlpodyssey/spago/embeddings"
+	"github.com/nlpodyssey/spago/embeddings/store/memstore"
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/stretchr/testify/assert"
+)
+
+func newEmbeddings(n int) *embeddings.Model[int] {
+	m := embeddings.New[float32, int](embeddings.Config{Size: 2, StoreName: "positions"}, memstore.NewRepository())
+	for i := 0; i < n; i++ {
+		e, _ := m.Embedding(i)
+		e.ReplaceValue(mat.NewVecDense([]float32{float32(i), float32(-i)}))
+	}
+	return m
+}
+
+func TestTable_Encode(t *testing.T) {
+	m := newEmbeddings(3)
+	var table Table
+	nodes := table.Encode(m, []int{2, 0, 5})
+	assert.Equal(t, []float32{2, -2}, nodes[0].Value().Data().F32())
+	assert.Equal(t, []float32{0, 0}, nodes[1].Value().Data().F32())
+	// The missing embeddings are the zero embedding, nil by default.
+	assert.Nil(t, nodes[2])
+	assert.Equal(t, 2, table.Len())
+
+	// The embeddings are read once.
+	again := table.Encode(m, []int{0, 2})
+	assert.Same(t, nodes[1], again[0])
+	assert.Same(t, nodes[0], again[1])
+}
+
+func TestTable_Precompute(t *testing.T) {
+	m := newEmbeddings(4)
+	var table Table
+	table.Precompute(m, 3)
+	assert.Equal(t, 3, table.Len())
+	assert.Equal(t, []float32{1, -1}, table.Encode(m, []int{1})[0].Value().Data().F32())
+}
+
+func TestTable_Trainable(t *testing.T) {
+	m := newEmbeddings(2)
+	m.Trainable = true
+	var table Table
+	table.Precompute(m, 2)
+	nodes := table.Encode(m, []int{1})
+	assert.Equal(t, 0, table.Len())
+	assert.Equal(t, []float32{1, -1}, nodes[0].Value().Data().F32())
+}
@@ -33,12 +33,24 @@ func NewDecoder(scorer *linear.Model, crf *crf.Model) *Decoder {
 	}
 }
 
-// Decode performs the viterbi decoding.
+// Decode performs the viterbi decoding, or returns the best scoring tags of
+// the models without a CRF.
 func (m *Decoder) Decode(xs []ag.Node) ([]int, []float64) {
 	scores := m.Scorer.Forward(xs...)
+	if m.CRF == nil {
+		return bestTags(scores), bestScores(scores)
+	}
 	return m.CRF.Decode(scores), bestScores(scores)
 }
 
+func bestTags(scores []ag.Node) []int {
+	tags := make([]int, len(scores))
+	for i, item := range scores {
+		tags[i] = item.Value().ArgMax()
+	}
+	return tags
+}
+
 func bestScores(scores []ag.Node) []float64 {
 	bests := make([]float64, len(scores))
 	for i, item := range scores {
@@ -0,0 +1,99 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// pullExpires is how long each pull request waits for a message.
+const pullExpires = 5 * time.Second
+
+// PullConsumer pulls the messages of a JetStream pull consumer, e.g. the
+// consumer of a work queue stream, one at a time.
+type PullConsumer struct {
+	c        *Conn
+	subject  string
+	inbox    string
+	sub      *Subscription
+	expireNs int64
+}
+
+// PullConsumer returns the puller of the durable pull consumer of the
+// stream, which must already exist.
+func (c *Conn) PullConsumer(stream, consumer string) (*PullConsumer, error) {
+	inbox := NewInbox()
+	sub, err := c.Subscribe(inbox, "")
+	if err != nil {
+		return nil, err
+	}
+	return &PullConsumer{
+		c:        c,
+		subject:  fmt.Sprintf("$JS.API.CONSUMER.MSG.NEXT.%s.%s", stream, consumer),
+		inbox:    inbox,
+		sub:      sub,
+		expireNs: pullExpires.Nanoseconds(),
+	}, nil
+}
+
+// Next pulls the next message, waiting for it until the context is done.
+// The message must be acknowledged with Ack once processed, or it's
+// delivered again after the acknowledgement wait of the consumer.
+func (p *PullConsumer) Next(ctx context.Context) (*Msg, error) {
+	req, err := json.Marshal(map[string]int64{"batch": 1, "expires": p.expireNs})
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if err := p.c.Publish(p.subject, p.inbox, req); err != nil {
+			return nil, err
+		}
+		msg, err := p.waitMessage(ctx)
+		if err != nil || msg != nil {
+			return msg, err
+		}
+	}
+}
+
+// waitMessage waits for the response to a pull request, returning nil if
+// the request expired without messages.
+func (p *PullConsumer) waitMessage(ctx context.Context) (*Msg, error) {
+	for {
+		msg, err := p.sub.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		switch msg.Status {
+		case "":
+			return msg, nil
+		case "100": // heartbeat
+			continue
+		case "404", "408":
+			return nil, nil
+		case "503":
+			return nil, fmt.Errorf("nats: JetStream is not available")
+		default:
+			return nil, fmt.Errorf("nats: pull request failed with status %s: %s", msg.Status, msg.Header.Get("Description"))
+		}
+	}
+}
+
+// Ack acknowledges the message, so that it's not delivered again.
+func (p *PullConsumer) Ack(msg *Msg) error {
+	return p.c.Publish(msg.Reply, "", []byte("+ACK"))
+}
+
+// Nak negatively acknowledges the message, so that it's delivered again.
+func (p *PullConsumer) Nak(msg *Msg) error {
+	return p.c.Publish(msg.Reply, "", []byte("-NAK"))
+}
+
+// Close removes the subscription of the replies.
+func (p *PullConsumer) Close() error {
+	return p.sub.Unsubscribe()
+}
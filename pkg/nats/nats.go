@@ -0,0 +1,464 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package nats is a minimal client of the NATS protocol, supporting the
+// publish-subscribe, request-reply and queue group features, plus the
+// headers and status messages needed to pull from JetStream consumers.
+//
+// The connection is not re-established if lost: the subscriptions fail,
+// and it's up to the caller to connect again.
+package nats
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultURL is the default URL of the NATS server.
+const DefaultURL = "nats://127.0.0.1:4222"
+
+// ErrClosed is returned by the operations on a closed connection.
+var ErrClosed = errors.New("nats: connection closed")
+
+// Msg is a message received from a subscription.
+type Msg struct {
+	Subject string
+	// Reply is the subject to reply to, if any.
+	Reply string
+	// Header holds the headers of the message, if any.
+	Header textproto.MIMEHeader
+	// Status is the status code of a status message, e.g. "404" when a
+	// JetStream pull request finds no messages, or empty.
+	Status string
+	Data   []byte
+}
+
+// Options are the options of the connection.
+type Options struct {
+	// Name is the name of the client, shown by the server monitoring.
+	Name string
+	// Token is the authentication token (optional).
+	Token string
+	// User and Password are the authentication credentials (optional);
+	// they can also be given in the URL.
+	User     string
+	Password string
+	// TLSConfig is the TLS configuration, used when the server requires
+	// TLS or the URL scheme is "tls" (optional).
+	TLSConfig *tls.Config
+	// Timeout is the timeout of the connection handshake (default 5s).
+	Timeout time.Duration
+}
+
+// serverInfo is the part of the INFO message of the server which is used.
+type serverInfo struct {
+	TLSRequired bool `json:"tls_required"`
+	Headers     bool `json:"headers"`
+}
+
+// Conn is a connection to a NATS server.
+type Conn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	// wmu serializes the writes.
+	wmu sync.Mutex
+	w   *bufio.Writer
+
+	mu     sync.Mutex
+	subs   map[int64]*Subscription
+	nextID int64
+	err    error
+	closed chan struct{}
+}
+
+// Connect connects to the NATS server at the URL (default DefaultURL).
+func Connect(rawURL string, opts Options) (*Conn, error) {
+	if rawURL == "" {
+		rawURL = DefaultURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("nats: invalid URL %#v: %w", rawURL, err)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	if u.User != nil && opts.User == "" {
+		opts.User = u.User.Username()
+		opts.Password, _ = u.User.Password()
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	conn, err := net.DialTimeout("tcp", host, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+	c := &Conn{
+		conn:   conn,
+		r:      bufio.NewReader(conn),
+		subs:   make(map[int64]*Subscription),
+		closed: make(chan struct{}),
+	}
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	if err := c.handshake(u, opts); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+	_ = c.conn.SetDeadline(time.Time{})
+	go c.readLoop()
+	return c, nil
+}
+
+// handshake reads the INFO message, upgrades the connection to TLS if
+// needed, and sends the CONNECT message, waiting for the server to accept it.
+func (c *Conn) handshake(u *url.URL, opts Options) error {
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	op, args, _ := strings.Cut(line, " ")
+	if op != "INFO" {
+		return fmt.Errorf("unexpected %#v instead of INFO", op)
+	}
+	var info serverInfo
+	if err := json.Unmarshal([]byte(args), &info); err != nil {
+		return fmt.Errorf("invalid INFO: %w", err)
+	}
+	if info.TLSRequired || u.Scheme == "tls" || opts.TLSConfig != nil {
+		config := opts.TLSConfig
+		if config == nil {
+			config = &tls.Config{ServerName: u.Hostname()}
+		}
+		tlsConn := tls.Client(c.conn, config)
+		if err := tlsConn.Handshake(); err != nil {
+			return err
+		}
+		c.conn = tlsConn
+		c.r = bufio.NewReader(tlsConn)
+	}
+	c.w = bufio.NewWriter(c.conn)
+
+	connect, err := json.Marshal(map[string]any{
+		"verbose":       false,
+		"pedantic":      false,
+		"lang":          "go",
+		"version":       "cybertron",
+		"protocol":      1,
+		"name":          opts.Name,
+		"auth_token":    opts.Token,
+		"user":          opts.User,
+		"pass":          opts.Password,
+		"headers":       info.Headers,
+		"no_responders": info.Headers,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.w, "CONNECT %s\r\nPING\r\n", connect); err != nil {
+		return err
+	}
+	if err := c.w.Flush(); err != nil {
+		return err
+	}
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New(strings.Trim(strings.TrimPrefix(line, "-ERR "), "'"))
+		}
+	}
+}
+
+// readLine reads a protocol line, without the trailing CRLF.
+func (c *Conn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readLoop reads the messages from the server and dispatches them to the
+// subscriptions, until the connection is closed or fails.
+func (c *Conn) readLoop() {
+	var err error
+	for err == nil {
+		err = c.readOp()
+	}
+	c.fail(err)
+}
+
+// readOp reads and handles a protocol operation.
+func (c *Conn) readOp() error {
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	op, args, _ := strings.Cut(line, " ")
+	switch op {
+	case "MSG":
+		return c.readMsg(strings.Fields(args), false)
+	case "HMSG":
+		return c.readMsg(strings.Fields(args), true)
+	case "PING":
+		return c.write("PONG\r\n")
+	case "-ERR":
+		err := strings.Trim(args, "'")
+		if strings.Contains(strings.ToLower(err), "permissions violation") {
+			return nil // the connection stays open
+		}
+		return fmt.Errorf("nats: server error: %s", err)
+	default: // +OK, PONG, INFO
+		return nil
+	}
+}
+
+// readMsg reads a message, whose arguments are:
+// <subject> <sid> [reply-to] [#header bytes] <#total bytes>.
+func (c *Conn) readMsg(args []string, withHeaders bool) error {
+	n := 3
+	if withHeaders {
+		n++
+	}
+	if len(args) != n && len(args) != n+1 {
+		return fmt.Errorf("nats: invalid message arguments %q", args)
+	}
+	msg := &Msg{Subject: args[0]}
+	sid, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("nats: invalid message arguments %q", args)
+	}
+	if len(args) == n+1 {
+		msg.Reply = args[2]
+	}
+	total, err := strconv.Atoi(args[len(args)-1])
+	if err != nil {
+		return fmt.Errorf("nats: invalid message arguments %q", args)
+	}
+	headerSize := 0
+	if withHeaders {
+		if headerSize, err = strconv.Atoi(args[len(args)-2]); err != nil || headerSize > total {
+			return fmt.Errorf("nats: invalid message arguments %q", args)
+		}
+	}
+
+	payload := make([]byte, total+2)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return err
+	}
+	msg.Data = payload[headerSize:total]
+	if withHeaders {
+		if err := msg.parseHeader(payload[:headerSize]); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	sub := c.subs[sid]
+	c.mu.Unlock()
+	if sub != nil {
+		select {
+		case sub.ch <- msg:
+		case <-sub.done:
+		}
+	}
+	return nil
+}
+
+// parseHeader parses the header block: "NATS/1.0[ <status>[ <description>]]"
+// followed by MIME headers.
+func (m *Msg) parseHeader(block []byte) error {
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(block)))
+	line, err := r.ReadLine()
+	if err != nil {
+		return fmt.Errorf("nats: invalid message header: %w", err)
+	}
+	if !strings.HasPrefix(line, "NATS/1.0") {
+		return fmt.Errorf("nats: invalid message header %q", line)
+	}
+	if status := strings.Fields(strings.TrimPrefix(line, "NATS/1.0")); len(status) > 0 {
+		m.Status = status[0]
+	}
+	h, err := r.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("nats: invalid message header: %w", err)
+	}
+	m.Header = h
+	return nil
+}
+
+// write writes the protocol data and flushes it.
+func (c *Conn) write(data ...string) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if err := c.Err(); err != nil {
+		return err
+	}
+	for _, d := range data {
+		if _, err := c.w.WriteString(d); err != nil {
+			return err
+		}
+	}
+	return c.w.Flush()
+}
+
+// Publish publishes the data to the subject, with the subject to reply to,
+// if not empty.
+func (c *Conn) Publish(subject, reply string, data []byte) error {
+	if reply != "" {
+		reply += " "
+	}
+	return c.write(fmt.Sprintf("PUB %s %s%d\r\n", subject, reply, len(data)), string(data), "\r\n")
+}
+
+// PublishHeader publishes the data to the subject, with the headers, and
+// the subject to reply to, if not empty. The server must support the
+// headers.
+func (c *Conn) PublishHeader(subject, reply string, header textproto.MIMEHeader, data []byte) error {
+	var b strings.Builder
+	b.WriteString("NATS/1.0\r\n")
+	for k, vs := range header {
+		for _, v := range vs {
+			fmt.Fprintf(&b, "%s: %s\r\n", k, v)
+		}
+	}
+	b.WriteString("\r\n")
+	if reply != "" {
+		reply += " "
+	}
+	return c.write(fmt.Sprintf("HPUB %s %s%d %d\r\n", subject, reply, b.Len(), b.Len()+len(data)), b.String(), string(data), "\r\n")
+}
+
+// Subscription is a subscription to a subject.
+type Subscription struct {
+	c    *Conn
+	id   int64
+	ch   chan *Msg
+	done chan struct{}
+	once sync.Once
+}
+
+// Subscribe subscribes to the subject. If the queue group is not empty, each
+// message is delivered to only one of the subscribers of the group.
+func (c *Conn) Subscribe(subject, queue string) (*Subscription, error) {
+	c.mu.Lock()
+	c.nextID++
+	sub := &Subscription{c: c, id: c.nextID, ch: make(chan *Msg, 64), done: make(chan struct{})}
+	c.subs[sub.id] = sub
+	c.mu.Unlock()
+
+	if queue != "" {
+		queue += " "
+	}
+	if err := c.write(fmt.Sprintf("SUB %s %s%d\r\n", subject, queue, sub.id)); err != nil {
+		sub.close()
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Next returns the next message, waiting for it until the context is done.
+func (s *Subscription) Next(ctx context.Context) (*Msg, error) {
+	select {
+	case msg := <-s.ch:
+		return msg, nil
+	case <-s.done:
+		if err := s.c.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Unsubscribe removes the subscription.
+func (s *Subscription) Unsubscribe() error {
+	s.close()
+	return s.c.write(fmt.Sprintf("UNSUB %d\r\n", s.id))
+}
+
+func (s *Subscription) close() {
+	s.once.Do(func() {
+		s.c.mu.Lock()
+		delete(s.c.subs, s.id)
+		s.c.mu.Unlock()
+		close(s.done)
+	})
+}
+
+// NewInbox returns a unique subject to receive replies.
+func NewInbox() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "_INBOX." + hex.EncodeToString(b)
+}
+
+// Err returns the error the connection failed with, if any.
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Closed returns a channel closed when the connection is closed or fails.
+func (c *Conn) Closed() <-chan struct{} {
+	return c.closed
+}
+
+// fail closes the connection and its subscriptions with the error.
+func (c *Conn) fail(err error) {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return
+	}
+	c.err = err
+	subs := make([]*Subscription, 0, len(c.subs))
+	for _, s := range c.subs {
+		subs = append(subs, s)
+	}
+	c.mu.Unlock()
+
+	_ = c.conn.Close()
+	for _, s := range subs {
+		s.close()
+	}
+	close(c.closed)
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	c.wmu.Lock()
+	if c.Err() == nil {
+		_ = c.w.Flush()
+	}
+	c.wmu.Unlock()
+	c.fail(ErrClosed)
+	return nil
+}
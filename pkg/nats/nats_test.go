@@ -0,0 +1,212 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nats
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer is a NATS server routing the published messages to the
+// subscriptions with the same subject, and answering the JetStream pull
+// requests with the queued messages.
+type fakeServer struct {
+	t   *testing.T
+	lis net.Listener
+
+	mu      sync.Mutex
+	connect string
+	subs    map[string]string // subject -> sid
+	queue   []string          // JetStream messages
+	acks    []string
+	hpubs   []string // the published messages with headers
+	w       *bufio.Writer
+}
+
+func newFakeServer(t *testing.T, queue ...string) *fakeServer {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeServer{t: t, lis: lis, subs: map[string]string{}, queue: queue}
+	go s.serve()
+	t.Cleanup(func() { _ = lis.Close() })
+	return s
+}
+
+func (s *fakeServer) url() string {
+	return "nats://" + s.lis.Addr().String()
+}
+
+func (s *fakeServer) serve() {
+	conn, err := s.lis.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	s.w = bufio.NewWriter(conn)
+	s.send(`INFO {"server_id":"fake","headers":true}` + "\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		args := strings.Fields(line)
+		switch args[0] {
+		case "CONNECT":
+			s.mu.Lock()
+			s.connect = strings.TrimSpace(strings.TrimPrefix(line, "CONNECT"))
+			s.mu.Unlock()
+		case "PING":
+			s.send("PONG\r\n")
+		case "SUB":
+			s.mu.Lock()
+			s.subs[args[1]] = args[len(args)-1]
+			s.mu.Unlock()
+		case "PUB":
+			n, _ := strconv.Atoi(args[len(args)-1])
+			payload := make([]byte, n+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			reply := ""
+			if len(args) == 4 {
+				reply = args[2]
+			}
+			s.route(args[1], reply, string(payload[:n]))
+		case "HPUB":
+			n, _ := strconv.Atoi(args[len(args)-1])
+			payload := make([]byte, n+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.hpubs = append(s.hpubs, strings.Join(args[1:], " ")+"\n"+string(payload[:n]))
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *fakeServer) route(subject, reply, data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case strings.HasPrefix(subject, "$JS.API.CONSUMER.MSG.NEXT."):
+		sid := s.subs[reply]
+		if len(s.queue) == 0 {
+			s.sendLocked(fmt.Sprintf("HMSG %s %s 28 28\r\nNATS/1.0 408 Timeout\r\n\r\n\r\n", reply, sid))
+			return
+		}
+		msg := s.queue[0]
+		s.queue = s.queue[1:]
+		s.sendLocked(fmt.Sprintf("MSG jobs.in %s $JS.ACK.jobs.1 %d\r\n%s\r\n", sid, len(msg), msg))
+	case strings.HasPrefix(subject, "$JS.ACK."):
+		s.acks = append(s.acks, data)
+	default:
+		if sid, ok := s.subs[subject]; ok {
+			if reply != "" {
+				reply += " "
+			}
+			s.sendLocked(fmt.Sprintf("MSG %s %s %s%d\r\n%s\r\n", subject, sid, reply, len(data), data))
+		}
+	}
+}
+
+func (s *fakeServer) send(data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sendLocked(data)
+}
+
+func (s *fakeServer) sendLocked(data string) {
+	_, _ = s.w.WriteString(data)
+	_ = s.w.Flush()
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	s := newFakeServer(t)
+	c, err := Connect(s.url(), Options{Name: "test", Token: "secret"})
+	require.NoError(t, err)
+	defer c.Close()
+	s.mu.Lock()
+	assert.Contains(t, s.connect, `"auth_token":"secret"`)
+	s.mu.Unlock()
+
+	sub, err := c.Subscribe("greetings", "workers")
+	require.NoError(t, err)
+	require.NoError(t, c.Publish("greetings", "_INBOX.1", []byte("hello")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msg, err := sub.Next(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &Msg{Subject: "greetings", Reply: "_INBOX.1", Data: []byte("hello")}, msg)
+
+	require.NoError(t, c.Close())
+	_, err = sub.Next(ctx)
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestPullConsumer(t *testing.T) {
+	s := newFakeServer(t, "job 1")
+	c, err := Connect(s.url(), Options{})
+	require.NoError(t, err)
+	defer c.Close()
+
+	p, err := c.PullConsumer("jobs", "workers")
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msg, err := p.Next(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "job 1", string(msg.Data))
+	assert.Equal(t, "$JS.ACK.jobs.1", msg.Reply)
+	require.NoError(t, p.Ack(msg))
+
+	// The next pull requests time out, until the context is done.
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel2()
+	_, err = p.Next(ctx2)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.acks) == 1 && s.acks[0] == "+ACK"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestPublishHeader(t *testing.T) {
+	s := newFakeServer(t)
+	c, err := Connect(s.url(), Options{})
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, c.PublishHeader("greetings", "", textproto.MIMEHeader{"Foo": {"bar"}}, []byte("hello")))
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.hpubs) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "greetings 22 27\nNATS/1.0\r\nFoo: bar\r\n\r\nhello", s.hpubs[0])
+}
+
+func TestParseHeader(t *testing.T) {
+	m := &Msg{}
+	require.NoError(t, m.parseHeader([]byte("NATS/1.0 404 No Messages\r\nFoo: bar\r\n\r\n")))
+	assert.Equal(t, "404", m.Status)
+	assert.Equal(t, "bar", m.Header.Get("Foo"))
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package numa places the goroutines, and the memory they allocate, on the
+// NUMA nodes of the machine, e.g. the sockets of a dual-socket server, on
+// Linux, so that a model is served by the CPUs of the node holding its
+// weights, instead of reading them across the interconnect.
+//
+// The memory is placed by the kernel on the node of the CPU first writing
+// it, so the weights loaded by a goroutine bound to a node (see Bind) are
+// on that node, as long as the Go runtime allocates them fresh pages, as
+// for the large tensors of a model.
+package numa
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupported means that the NUMA nodes are not supported on the
+// operating system.
+var ErrUnsupported = errors.New("numa: not supported on this operating system")
+
+// Node is a NUMA node.
+type Node struct {
+	// ID is the number of the node.
+	ID int
+	// CPUs are the numbers of the CPUs of the node.
+	CPUs []int
+}
+
+// Select returns the nodes with the given list of IDs, in the list format of
+// Linux (see ParseList), or all of them if "all".
+func Select(nodes []Node, list string) ([]Node, error) {
+	if list == "all" {
+		return nodes, nil
+	}
+	ids, err := ParseList(list)
+	if err != nil {
+		return nil, err
+	}
+	selected := make([]Node, 0, len(ids))
+	for _, id := range ids {
+		i := sort.Search(len(nodes), func(i int) bool { return nodes[i].ID >= id })
+		if i == len(nodes) || nodes[i].ID != id {
+			return nil, fmt.Errorf("numa: node %d not found", id)
+		}
+		selected = append(selected, nodes[i])
+	}
+	return selected, nil
+}
+
+// ParseList parses a list of numbers in the list format of Linux, e.g. the
+// one of the CPUs of a node: comma-separated numbers and inclusive ranges,
+// e.g. "0-3,8,10-11". The numbers are sorted, without duplicates.
+func ParseList(s string) ([]int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	seen := map[int]bool{}
+	for _, part := range strings.Split(s, ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		lo, err := strconv.Atoi(first)
+		if err != nil || lo < 0 {
+			return nil, fmt.Errorf("numa: invalid list %#v", s)
+		}
+		hi := lo
+		if isRange {
+			if hi, err = strconv.Atoi(last); err != nil || hi < lo {
+				return nil, fmt.Errorf("numa: invalid list %#v", s)
+			}
+		}
+		for i := lo; i <= hi; i++ {
+			seen[i] = true
+		}
+	}
+	list := make([]int, 0, len(seen))
+	for i := range seen {
+		list = append(list, i)
+	}
+	sort.Ints(list)
+	return list, nil
+}
+
+var (
+	mu sync.Mutex
+	// bound are the nodes of the bound threads, by thread ID.
+	bound = map[int]Node{}
+)
+
+// Bound returns the node the calling goroutine is bound to, if any (see
+// Bind), e.g. so that the values shared by the goroutines are shared by the
+// ones bound to the same node only.
+func Bound() (Node, bool) {
+	tid, ok := threadID()
+	if !ok {
+		return Node{}, false
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	n, ok := bound[tid]
+	return n, ok
+}
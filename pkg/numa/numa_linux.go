@@ -0,0 +1,116 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package numa
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// nodesDir is the directory of the NUMA nodes in sysfs.
+const nodesDir = "/sys/devices/system/node"
+
+// Nodes returns the NUMA nodes of the machine having CPUs, sorted by ID;
+// a machine without NUMA has a single node.
+func Nodes() ([]Node, error) {
+	dirs, err := filepath.Glob(filepath.Join(nodesDir, "node[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	if len(dirs) == 0 {
+		return []Node{{ID: 0, CPUs: allCPUs()}}, nil
+	}
+	nodes := make([]Node, 0, len(dirs))
+	for _, dir := range dirs {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "cpulist"))
+		if err != nil {
+			return nil, err
+		}
+		cpus, err := ParseList(string(data))
+		if err != nil {
+			return nil, err
+		}
+		// The nodes without CPUs have memory only, e.g. persistent memory.
+		if len(cpus) > 0 {
+			nodes = append(nodes, Node{ID: id, CPUs: cpus})
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes, nil
+}
+
+// allCPUs returns the CPUs of the process.
+func allCPUs() []int {
+	var set unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &set); err != nil {
+		return nil
+	}
+	var cpus []int
+	for i := 0; i < len(set)*64; i++ {
+		if set.IsSet(i) {
+			cpus = append(cpus, i)
+		}
+	}
+	return cpus
+}
+
+// Bind binds the calling goroutine to the CPUs of the node, locking it to
+// its thread, until the returned function is called, by the same goroutine,
+// restoring the previous binding. The memory it writes first, e.g. the
+// weights of the models it loads, is placed on the node.
+func Bind(n Node) (unbind func(), _ error) {
+	runtime.LockOSThread()
+	var previous unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &previous); err != nil {
+		runtime.UnlockOSThread()
+		return nil, err
+	}
+	var set unix.CPUSet
+	for _, cpu := range n.CPUs {
+		set.Set(cpu)
+	}
+	if err := unix.SchedSetaffinity(0, &set); err != nil {
+		runtime.UnlockOSThread()
+		return nil, err
+	}
+	tid := unix.Gettid()
+	mu.Lock()
+	previousNode, wasBound := bound[tid]
+	bound[tid] = n
+	mu.Unlock()
+
+	return func() {
+		mu.Lock()
+		if wasBound {
+			bound[tid] = previousNode
+		} else {
+			delete(bound, tid)
+		}
+		mu.Unlock()
+		// If the binding can't be restored, the thread stays locked, and
+		// it's terminated once the goroutine exits, rather than running
+		// the other goroutines on the node.
+		if err := unix.SchedSetaffinity(0, &previous); err == nil {
+			runtime.UnlockOSThread()
+		}
+	}, nil
+}
+
+// threadID returns the ID of the calling thread.
+func threadID() (int, bool) {
+	return unix.Gettid(), true
+}
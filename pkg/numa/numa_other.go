@@ -0,0 +1,22 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package numa
+
+// Nodes returns ErrUnsupported, the NUMA nodes being unknown.
+func Nodes() ([]Node, error) {
+	return nil, ErrUnsupported
+}
+
+// Bind returns ErrUnsupported.
+func Bind(Node) (unbind func(), _ error) {
+	return nil, ErrUnsupported
+}
+
+// threadID returns false, no thread being bound.
+func threadID() (int, bool) {
+	return 0, false
+}
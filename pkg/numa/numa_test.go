@@ -0,0 +1,73 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package numa
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseList(t *testing.T) {
+	list, err := ParseList("8-9,0-2, 4,1\n")
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 4, 8, 9}, list)
+
+	list, err = ParseList("")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	for _, s := range []string{"a", "1-", "3-1", "-1", "1,,2"} {
+		_, err := ParseList(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	nodes := []Node{{ID: 0, CPUs: []int{0, 1}}, {ID: 1, CPUs: []int{2, 3}}, {ID: 3, CPUs: []int{4}}}
+	selected, err := Select(nodes, "all")
+	require.NoError(t, err)
+	assert.Equal(t, nodes, selected)
+
+	selected, err = Select(nodes, "1,3")
+	require.NoError(t, err)
+	assert.Equal(t, []Node{nodes[1], nodes[2]}, selected)
+
+	_, err = Select(nodes, "2")
+	assert.Error(t, err)
+}
+
+func TestBind(t *testing.T) {
+	nodes, err := Nodes()
+	if err == ErrUnsupported {
+		t.Skip(err)
+	}
+	require.NoError(t, err)
+	require.NotEmpty(t, nodes)
+
+	_, ok := Bound()
+	assert.False(t, ok)
+	unbind, err := Bind(nodes[0])
+	require.NoError(t, err)
+	n, ok := Bound()
+	assert.True(t, ok)
+	assert.Equal(t, nodes[0].ID, n.ID)
+
+	// The other goroutines aren't bound.
+	done := make(chan bool)
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		_, ok := Bound()
+		done <- ok
+	}()
+	assert.False(t, <-done)
+
+	unbind()
+	_, ok = Bound()
+	assert.False(t, ok)
+}
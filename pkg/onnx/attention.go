@@ -0,0 +1,358 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/nlpodyssey/cybertron/pkg/kernels"
+	"github.com/nlpodyssey/cybertron/pkg/numa"
+)
+
+// The attention of the transformers is exported as a MatMul of the queries
+// and the transposed keys, scaled, masked, normalized by a Softmax, and
+// multiplied by the values, materializing two L×L matrices per head: with
+// long inputs, e.g. 8k tokens, they take gigabytes. FuseAttention replaces
+// these subgraphs with the FusedAttention operator, which computes the same
+// result by tiles of queries and keys, keeping only the running maximum and
+// sum of each row of the softmax ("online softmax", as in FlashAttention).
+
+func init() {
+	register("FusedAttention", opFusedAttention)
+}
+
+// The tiles of the fused attention: the keys and values of a block are
+// reused by all the queries of a block while they're in the cache.
+const (
+	attentionBlockQueries = 32
+	attentionBlockKeys    = 256
+)
+
+// FuseAttention replaces the subgraphs computing the scaled dot-product
+// attention, Softmax(MatMul(q, kᵀ) / c + mask) · v, where the scaling and
+// the mask are optional, with the FusedAttention operator, which doesn't
+// materialize the matrices of the scores. The results may differ from the
+// original graph by the rounding errors. It returns the number of subgraphs
+// replaced.
+func (m *Model) FuseAttention() int {
+	g := m.Graph
+	producers := make(map[string]int)
+	consumers := make(map[string]int)
+	for i, n := range g.Nodes {
+		for _, name := range n.Outputs {
+			producers[name] = i
+		}
+		for _, name := range n.Inputs {
+			consumers[name]++
+		}
+	}
+	for _, name := range g.Outputs {
+		consumers[name]++
+	}
+	// producer returns the node computing the value, if it has the op type
+	// and the value is used by a single node.
+	producer := func(name, opType string) (*Node, bool) {
+		i, ok := producers[name]
+		if !ok || consumers[name] != 1 || g.Nodes[i].OpType != opType || g.Nodes[i].Domain != "" {
+			return nil, false
+		}
+		return g.Nodes[i], true
+	}
+
+	removed := make(map[*Node]bool)
+	fused := 0
+	for i, sm := range g.Nodes {
+		if sm.OpType != "Softmax" || sm.Domain != "" || len(sm.Inputs) != 1 || len(sm.Outputs) != 1 {
+			continue
+		}
+		// only the last axis can be checked without the shapes
+		defaultAxis := int64(-1)
+		if m.Opset < 13 {
+			defaultAxis = 1
+		}
+		if sm.intAttr("axis", defaultAxis) != -1 {
+			continue
+		}
+		var final *Node
+		for _, n := range g.Nodes[i+1:] {
+			if n.OpType == "MatMul" && n.Domain == "" && n.Inputs[0] == sm.Outputs[0] {
+				final = n
+				break
+			}
+		}
+		if final == nil || consumers[sm.Outputs[0]] != 1 {
+			continue
+		}
+
+		scores, mask := sm.Inputs[0], ""
+		add, hasMask := producer(scores, "Add")
+		if hasMask {
+			scores, mask = add.Inputs[0], add.Inputs[1]
+			if qk, _, _ := attentionScores(producer, scores); qk == nil {
+				scores, mask = mask, scores
+			}
+		}
+		qk, s, scale := attentionScores(producer, scores)
+		if qk == nil {
+			continue
+		}
+
+		divide := int64(0)
+		if s != nil {
+			if s.OpType == "Div" {
+				divide = 1
+			}
+			removed[s] = true
+		}
+		if hasMask {
+			removed[add] = true
+		}
+		removed[qk] = true
+		removed[sm] = true
+		*final = Node{
+			Name:    final.Name,
+			OpType:  "FusedAttention",
+			Inputs:  []string{qk.Inputs[0], qk.Inputs[1], final.Inputs[1], scale, mask},
+			Outputs: final.Outputs,
+			Attributes: map[string]*Attribute{
+				"divide": {Name: "divide", Int: divide},
+			},
+		}
+		fused++
+	}
+
+	nodes := g.Nodes[:0]
+	for _, n := range g.Nodes {
+		if !removed[n] {
+			nodes = append(nodes, n)
+		}
+	}
+	g.Nodes = nodes
+	return fused
+}
+
+// attentionScores returns the MatMul of the queries and the keys computing
+// the value, and its scaling, a Div or Mul by the scale, if any, or a nil
+// MatMul if the value isn't computed this way.
+func attentionScores(producer func(name, opType string) (*Node, bool), name string) (qk, s *Node, scale string) {
+	if qk, ok := producer(name, "MatMul"); ok {
+		return qk, nil, ""
+	}
+	if s, ok := producer(name, "Div"); ok {
+		if qk, ok := producer(s.Inputs[0], "MatMul"); ok {
+			return qk, s, s.Inputs[1]
+		}
+	}
+	if s, ok := producer(name, "Mul"); ok {
+		for k, in := range s.Inputs {
+			if qk, ok := producer(in, "MatMul"); ok {
+				return qk, s, s.Inputs[1-k]
+			}
+		}
+	}
+	return nil, nil, ""
+}
+
+// opFusedAttention computes Softmax(q · kt · scale + mask) · v, where q is
+// (…×Lq×d), kt (…×d×Lk), v (…×Lk×dv), and the mask is broadcastable to
+// (…×Lq×Lk). The scale is divided instead of multiplied if the "divide"
+// attribute is set. With the attention window of the model set, each query
+// attends to the keys at most that many positions away.
+func opFusedAttention(ctx *opContext, in []*Tensor) ([]*Tensor, error) {
+	q, kt, v, scale, mask := in[0], in[1], in[2], in[3], in[4]
+	if q.Rank() < 2 || kt.Rank() < 2 || v.Rank() < 2 {
+		return nil, fmt.Errorf("expected matrices, got %v, %v and %v", q.Shape, kt.Shape, v.Shape)
+	}
+	rq, rk, rv := q.Rank(), kt.Rank(), v.Rank()
+	lq, d := q.Shape[rq-2], q.Shape[rq-1]
+	lk, dv := kt.Shape[rk-1], v.Shape[rv-1]
+	if kt.Shape[rk-2] != d || v.Shape[rv-2] != lk {
+		return nil, fmt.Errorf("incompatible shapes %v, %v and %v", q.Shape, kt.Shape, v.Shape)
+	}
+
+	factor := float32(1)
+	if scale != nil {
+		factor = scale.float(0)
+		if ctx.node.intAttr("divide", 0) != 0 {
+			factor = 1 / factor
+		}
+	}
+	if ctx.attentionFactor != 0 {
+		factor *= ctx.attentionFactor
+	}
+
+	a := &attention{lq: lq, lk: lk, d: d, dv: dv, factor: factor, window: ctx.attentionWindow}
+	shapes := [][]int{q.Shape[:rq-2], kt.Shape[:rk-2], v.Shape[:rv-2], nil}
+	if mask != nil {
+		ms := mask.Shape
+		for len(ms) < 2 {
+			ms = append([]int{1}, ms...)
+		}
+		mi, mj := ms[len(ms)-2], ms[len(ms)-1]
+		if (mi != 1 && mi != lq) || (mj != 1 && mj != lk) {
+			return nil, fmt.Errorf("mask shape %v is not broadcastable to %dx%d", mask.Shape, lq, lk)
+		}
+		a.mask, a.maskSize = mask.AsFloats(), mi*mj
+		if mi != 1 {
+			a.maskRowStride = mj
+		}
+		if mj != 1 {
+			a.maskColStride = 1
+		}
+		shapes[3] = ms[:len(ms)-2]
+	}
+	batch, err := broadcastShape(shapes...)
+	if err != nil {
+		return nil, err
+	}
+
+	out := newTensor(Float, append(append([]int{}, batch...), lq, dv))
+	qf, ktf, vf := q.AsFloats(), kt.AsFloats(), v.AsFloats()
+	k := allocFloats(lk * d)
+	defer freeFloats(k)
+	forEachBroadcast(batch, shapes, func(o int, idx []int) {
+		// the keys are transposed back, so that the scores are dot products
+		// of contiguous rows
+		kto := ktf[idx[1]*d*lk:]
+		for j := 0; j < lk; j++ {
+			for p := 0; p < d; p++ {
+				k[j*d+p] = kto[p*lk+j]
+			}
+		}
+		a.q, a.k, a.v = qf[idx[0]*lq*d:], k, vf[idx[2]*lk*dv:]
+		a.maskOffset = idx[3] * a.maskSize
+		a.parallel(out.Floats[o*lq*dv:(o+1)*lq*dv], ctx.parallelism, ctx.numaNode)
+	})
+	return []*Tensor{out}, nil
+}
+
+// attention is the computation of a single attention matrix.
+type attention struct {
+	lq, lk, d, dv int
+	factor        float32
+	window        int
+	q, k, v       []float32
+	// mask is the additive mask, if any, indexed by maskOffset, then by the
+	// strides of the rows and of the columns, zero if broadcast.
+	mask                         []float32
+	maskSize, maskOffset         int
+	maskRowStride, maskColStride int
+}
+
+// parallel computes the attention of all the queries into dst (Lq×dv),
+// which must be zeroed, with the queries split among up to parallelism
+// goroutines, bound to the NUMA node if any.
+func (a *attention) parallel(dst []float32, parallelism int, node *numa.Node) {
+	if parallelism > a.lq {
+		parallelism = a.lq
+	}
+	if parallelism <= 1 {
+		a.compute(dst, 0, a.lq)
+		return
+	}
+	var wg sync.WaitGroup
+	rows := (a.lq + parallelism - 1) / parallelism
+	for i := 0; i < a.lq; i += rows {
+		end := i + rows
+		if end > a.lq {
+			end = a.lq
+		}
+		wg.Add(1)
+		i, end := i, end
+		goOn(node, func() {
+			defer wg.Done()
+			a.compute(dst, i, end)
+		})
+	}
+	wg.Wait()
+}
+
+// compute computes the attention of the queries from start to end, by
+// tiles of queries and keys.
+func (a *attention) compute(dst []float32, start, end int) {
+	negInf := float32(math.Inf(-1))
+	scores := make([]float32, attentionBlockKeys)
+	max := make([]float32, attentionBlockQueries)
+	sum := make([]float32, attentionBlockQueries)
+
+	for i0 := start; i0 < end; i0 += attentionBlockQueries {
+		i1 := i0 + attentionBlockQueries
+		if i1 > end {
+			i1 = end
+		}
+		for i := range max {
+			max[i], sum[i] = negInf, 0
+		}
+		lo, hi := a.keys(i0, i1-1)
+		for j0 := lo; j0 < hi; j0 += attentionBlockKeys {
+			j1 := j0 + attentionBlockKeys
+			if j1 > hi {
+				j1 = hi
+			}
+			for i := i0; i < i1; i++ {
+				from, to := a.keys(i, i)
+				if from < j0 {
+					from = j0
+				}
+				if to > j1 {
+					to = j1
+				}
+				if from >= to {
+					continue
+				}
+				row := scores[:to-from]
+				qi := a.q[i*a.d : (i+1)*a.d]
+				for j := from; j < to; j++ {
+					s := kernels.Dot(qi, a.k[j*a.d:(j+1)*a.d]) * a.factor
+					if a.mask != nil {
+						s += a.mask[a.maskOffset+i*a.maskRowStride+j*a.maskColStride]
+					}
+					row[j-from] = s
+				}
+				blockMax := kernels.Max(row)
+				if blockMax == negInf {
+					continue // fully masked
+				}
+				acc := dst[i*a.dv : (i+1)*a.dv]
+				b := i - i0
+				if blockMax > max[b] {
+					// rescale what was accumulated with the previous maximum
+					c := float32(math.Exp(float64(max[b] - blockMax)))
+					kernels.Scale(c, acc)
+					sum[b] *= c
+					max[b] = blockMax
+				}
+				for j := from; j < to; j++ {
+					p := float32(math.Exp(float64(row[j-from] - max[b])))
+					sum[b] += p
+					kernels.Axpy(p, a.v[j*a.dv:(j+1)*a.dv], acc)
+				}
+			}
+		}
+		for i := i0; i < i1; i++ {
+			if s := sum[i-i0]; s > 0 {
+				kernels.Scale(1/s, dst[i*a.dv:(i+1)*a.dv])
+			}
+		}
+	}
+}
+
+// keys returns the range of the keys attended by the queries from first
+// to last, included.
+func (a *attention) keys(first, last int) (int, int) {
+	if a.window <= 0 {
+		return 0, a.lk
+	}
+	lo, hi := first-a.window, last+a.window+1
+	if lo < 0 {
+		lo = 0
+	}
+	if hi > a.lk {
+		hi = a.lk
+	}
+	return lo, hi
+}
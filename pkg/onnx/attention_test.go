@@ -0,0 +1,123 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// attentionModel returns a model computing the attention of the inputs
+// "q" (1×2×L×d), "kt" (1×2×d×L), "v" (1×2×L×d) and "mask", scaled with
+// the given op type, the scale being the first operand of Mul.
+func attentionModel(scaleOp string) *Model {
+	scale := &Node{OpType: scaleOp, Inputs: []string{"s", "c"}, Outputs: []string{"scaled"}}
+	c := float32(math.Sqrt(8))
+	if scaleOp == "Mul" {
+		scale.Inputs = []string{"c", "s"}
+		c = 1 / c
+	}
+	return &Model{Opset: 13, Graph: &Graph{
+		Nodes: []*Node{
+			{OpType: "MatMul", Inputs: []string{"q", "kt"}, Outputs: []string{"s"}},
+			scale,
+			{OpType: "Add", Inputs: []string{"scaled", "mask"}, Outputs: []string{"z"}},
+			{OpType: "Softmax", Inputs: []string{"z"}, Outputs: []string{"p"},
+				Attributes: map[string]*Attribute{"axis": {Name: "axis", Int: -1}}},
+			{OpType: "MatMul", Inputs: []string{"p", "v"}, Outputs: []string{"y"}},
+		},
+		Initializers: map[string]*Tensor{"c": NewFloatTensor([]int{}, []float32{c})},
+		Inputs:       []string{"q", "kt", "v", "mask"},
+		Outputs:      []string{"y"},
+	}}
+}
+
+func randomTensor(r *rand.Rand, shape ...int) *Tensor {
+	data := make([]float32, shapeSize(shape))
+	for i := range data {
+		data[i] = float32(r.NormFloat64())
+	}
+	return NewFloatTensor(shape, data)
+}
+
+func attentionInputs(l int, mask *Tensor) map[string]*Tensor {
+	r := rand.New(rand.NewSource(42))
+	return map[string]*Tensor{
+		"q":    randomTensor(r, 1, 2, l, 8),
+		"kt":   randomTensor(r, 1, 2, 8, l),
+		"v":    randomTensor(r, 1, 2, l, 8),
+		"mask": mask,
+	}
+}
+
+func maxAbsDiff(a, b []float32) float64 {
+	var d float64
+	for i := range a {
+		d = math.Max(d, math.Abs(float64(a[i]-b[i])))
+	}
+	return d
+}
+
+func TestFuseAttention(t *testing.T) {
+	const l = 300 // more than a tile of keys
+	mask := make([]float32, l)
+	for j := l - 20; j < l; j++ {
+		mask[j] = -math.MaxFloat32 // padding
+	}
+	inputs := attentionInputs(l, NewFloatTensor([]int{1, 1, 1, l}, mask))
+
+	for _, scaleOp := range []string{"Div", "Mul"} {
+		t.Run(scaleOp, func(t *testing.T) {
+			m := attentionModel(scaleOp)
+			expected, err := m.Run(inputs)
+			require.NoError(t, err)
+
+			assert.Equal(t, 1, m.FuseAttention())
+			require.Len(t, m.Graph.Nodes, 1)
+			assert.Equal(t, "FusedAttention", m.Graph.Nodes[0].OpType)
+			require.NoError(t, m.Validate())
+
+			for _, p := range []int{1, 4} {
+				m.Parallelism = p
+				actual, err := m.Run(inputs)
+				require.NoError(t, err)
+				assert.Equal(t, expected["y"].Shape, actual["y"].Shape)
+				assert.Less(t, maxAbsDiff(expected["y"].Floats, actual["y"].Floats), 1e-5)
+			}
+		})
+	}
+}
+
+func TestFuseAttention_SharedIntermediate(t *testing.T) {
+	m := attentionModel("Div")
+	m.Graph.Outputs = append(m.Graph.Outputs, "p")
+	assert.Equal(t, 0, m.FuseAttention())
+	assert.Len(t, m.Graph.Nodes, 5)
+}
+
+func TestFusedAttention_Window(t *testing.T) {
+	const l, window = 100, 10
+	band := make([]float32, l*l)
+	for i := 0; i < l; i++ {
+		for j := 0; j < l; j++ {
+			if i-j > window || j-i > window {
+				band[i*l+j] = float32(math.Inf(-1))
+			}
+		}
+	}
+	m := attentionModel("Div")
+	expected, err := m.Run(attentionInputs(l, NewFloatTensor([]int{l, l}, band)))
+	require.NoError(t, err)
+
+	require.Equal(t, 1, m.FuseAttention())
+	m.AttentionWindow = window
+	actual, err := m.Run(attentionInputs(l, NewFloatTensor([]int{1}, []float32{0})))
+	require.NoError(t, err)
+	assert.Less(t, maxAbsDiff(expected["y"].Floats, actual["y"].Floats), 1e-5)
+}
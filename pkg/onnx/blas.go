@@ -0,0 +1,20 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+// blasGemm computes gemm with a BLAS library, when the runtime is built
+// with cgo and the "blas" tag (see blas_cgo.go); it's nil otherwise.
+var blasGemm func(dst, a, b []float32, m, k, n int)
+
+// blasMinOps is the minimum number of multiply-adds of the products
+// computed with BLAS, below which the overhead of the cgo call outweighs
+// the speedup.
+const blasMinOps = 1 << 16
+
+// UsesBLAS reports whether the large matrix products are computed with a
+// BLAS library.
+func UsesBLAS() bool {
+	return blasGemm != nil
+}
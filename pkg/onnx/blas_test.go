@@ -0,0 +1,26 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBLASGemm(t *testing.T) {
+	if !UsesBLAS() {
+		t.Skip("built without BLAS")
+	}
+	r := rand.New(rand.NewSource(1))
+	m, k, n := 33, 64, 65
+	a, b := randomFloats(r, m*k), randomFloats(r, k*n)
+	want := make([]float32, m*n)
+	gemm(want, a, b, m, k, n)
+	got := make([]float32, m*n)
+	blasGemm(got, a, b, m, k, n)
+	assert.InDeltaSlice(t, want, got, 1e-4)
+}
@@ -0,0 +1,98 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import "fmt"
+
+// broadcastShape returns the shape resulting from the multidirectional
+// (NumPy-style) broadcasting of the given shapes.
+func broadcastShape(shapes ...[]int) ([]int, error) {
+	rank := 0
+	for _, s := range shapes {
+		if len(s) > rank {
+			rank = len(s)
+		}
+	}
+	out := make([]int, rank)
+	for i := range out {
+		out[i] = 1
+	}
+	for _, s := range shapes {
+		offset := rank - len(s)
+		for i, d := range s {
+			switch {
+			case d == out[offset+i] || d == 1:
+			case out[offset+i] == 1:
+				out[offset+i] = d
+			default:
+				return nil, fmt.Errorf("shapes %v are not broadcastable", shapes)
+			}
+		}
+	}
+	return out, nil
+}
+
+// broadcastStrides returns the strides for indexing a tensor of the given
+// shape while iterating over the (broadcast) output shape. Broadcast
+// dimensions have a zero stride.
+func broadcastStrides(shape, out []int) []int {
+	st := strides(shape)
+	res := make([]int, len(out))
+	offset := len(out) - len(shape)
+	for i, d := range shape {
+		if d != 1 {
+			res[offset+i] = st[i]
+		}
+	}
+	return res
+}
+
+// forEachBroadcast iterates over all the elements of the output shape in
+// row-major order, calling f with the flat output index and the flat
+// indices of the corresponding elements of the inputs.
+func forEachBroadcast(out []int, shapes [][]int, f func(o int, idx []int)) {
+	st := make([][]int, len(shapes))
+	for k, s := range shapes {
+		st[k] = broadcastStrides(s, out)
+	}
+	forEachStrided(out, st, f)
+}
+
+// forEachStrided iterates over all the elements of the output shape in
+// row-major order, calling f with the flat output index and the offsets
+// obtained by applying each set of strides to the current position.
+func forEachStrided(out []int, st [][]int, f func(o int, idx []int)) {
+	n := shapeSize(out)
+	counter := make([]int, len(out))
+	idx := make([]int, len(st))
+	for o := 0; o < n; o++ {
+		f(o, idx)
+		for d := len(out) - 1; d >= 0; d-- {
+			counter[d]++
+			for k := range idx {
+				idx[k] += st[k][d]
+			}
+			if counter[d] < out[d] {
+				break
+			}
+			for k := range idx {
+				idx[k] -= st[k][d] * out[d]
+			}
+			counter[d] = 0
+		}
+	}
+}
+
+// normalizeAxis converts a possibly negative axis into the range [0, rank).
+func normalizeAxis(axis int64, rank int) (int, error) {
+	a := int(axis)
+	if a < 0 {
+		a += rank
+	}
+	if a < 0 || a >= rank {
+		return 0, fmt.Errorf("axis %d is out of range for rank %d", axis, rank)
+	}
+	return a, nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"fmt"
+	"sort"
+)
+
+// device is an accelerator computing the large matrix products of a model.
+type device interface {
+	// Sgemm accumulates in dst (m×n) the product of a (m×k) and b (k×n).
+	// If the key isn't nil, b is a weight of the model, copied to the
+	// device once and kept resident under the key.
+	Sgemm(dst, a, b []float32, m, k, n int, key any) error
+	// Close frees the weights resident on the device.
+	Close() error
+}
+
+// devices are the constructors of the accelerators the matrix products can
+// be offloaded to, by name. They're registered by the files built with their
+// tags (e.g. the "cuda" tag, see device_cuda.go).
+var devices = map[string]func() device{}
+
+// deviceMinOps is the minimum number of multiply-adds of the products
+// offloaded to an accelerator, below which the transfers of the operands
+// outweigh the speedup.
+const deviceMinOps = 1 << 20
+
+// weightKey identifies a weight on the device: the index-th matrix of an
+// initializer of the graph.
+type weightKey struct {
+	t     *Tensor
+	index int
+}
+
+// Devices returns the sorted names of the devices the models can run on:
+// "cpu", and the accelerators the runtime is built with.
+func Devices() []string {
+	names := []string{"cpu"}
+	for name := range devices {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// SetDevice sets the device computing the large matrix products of the
+// model: "cpu" (default), or an accelerator among Devices. The other
+// operators run on the CPU. The weights are kept resident on the
+// accelerator until the device is set again or the model is closed. It must
+// not be called while the model is running.
+func (m *Model) SetDevice(name string) error {
+	newDevice, ok := devices[name]
+	if !ok && name != "" && name != "cpu" {
+		return fmt.Errorf("onnx: device %q not available: the runtime must be built with the %q tag", name, name)
+	}
+	if err := m.closeDevice(); err != nil {
+		return err
+	}
+	if ok {
+		m.device = newDevice()
+	}
+	return nil
+}
+
+// closeDevice closes the device of the model, if any, and unsets it.
+func (m *Model) closeDevice() error {
+	if m.device == nil {
+		return nil
+	}
+	d := m.device
+	m.device = nil
+	if err := d.Close(); err != nil {
+		return fmt.Errorf("onnx: failed to close the device: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build cgo && cuda
+
+package onnx
+
+import "github.com/nlpodyssey/cybertron/pkg/onnx/internal/cuda"
+
+func init() {
+	devices["cuda"] = func() device { return cuda.NewDevice() }
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingDevice computes the products on the CPU, recording their keys.
+type recordingDevice struct {
+	mu     sync.Mutex
+	keys   []any
+	closed bool
+}
+
+func (d *recordingDevice) Sgemm(dst, a, b []float32, m, k, n int, key any) error {
+	d.mu.Lock()
+	d.keys = append(d.keys, key)
+	d.mu.Unlock()
+	gemm(dst, a, b, m, k, n)
+	return nil
+}
+
+func (d *recordingDevice) Close() error {
+	d.closed = true
+	return nil
+}
+
+func TestSetDevice(t *testing.T) {
+	m := &Model{}
+	require.NoError(t, m.SetDevice("cpu"))
+	assert.Nil(t, m.device)
+	assert.ErrorContains(t, m.SetDevice("metal"), `device "metal" not available`)
+	assert.Equal(t, "cpu", Devices()[0])
+
+	devices["recording"] = func() device { return &recordingDevice{} }
+	defer delete(devices, "recording")
+	require.NoError(t, m.SetDevice("recording"))
+	d := m.device.(*recordingDevice)
+	require.NoError(t, m.SetDevice("recording"))
+	assert.True(t, d.closed, "the previous device is closed")
+	d = m.device.(*recordingDevice)
+	require.NoError(t, m.Close())
+	assert.True(t, d.closed, "the device is closed with the model")
+	assert.Nil(t, m.device)
+}
+
+func TestDevice_Weights(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	const m, k, n = 64, 128, 128
+	w := NewFloatTensor([]int{2, k, n}, randomFloats(r, 2*k*n))
+	node := &Node{OpType: "MatMul", Inputs: []string{"x", "w"}, Outputs: []string{"y"}}
+	model := &Model{Opset: 13, Graph: &Graph{
+		Nodes:        []*Node{node, {OpType: "MatMul", Inputs: []string{"x", "y2"}, Outputs: []string{"z"}}},
+		Initializers: map[string]*Tensor{"w": w},
+		Inputs:       []string{"x", "y2"},
+		Outputs:      []string{"y", "z"},
+	}}
+	d := &recordingDevice{}
+	model.device = d
+
+	x := NewFloatTensor([]int{2, m, k}, randomFloats(r, 2*m*k))
+	activations := NewFloatTensor([]int{2, k, n}, randomFloats(r, 2*k*n))
+	for run := 0; run < 2; run++ {
+		_, err := model.Run(map[string]*Tensor{"x": x, "y2": activations})
+		require.NoError(t, err)
+	}
+	// The matrices of the weight have the same keys in each run, the
+	// activations none.
+	want := []any{weightKey{w, 0}, weightKey{w, 1}, nil, nil}
+	assert.Equal(t, append(want, want...), d.keys)
+}
+
+// TestDevices compares the products of the accelerators the runtime is
+// built with, if any, with the ones computed on the CPU, with their weights
+// resident or not, and concurrently.
+func TestDevices(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	m, k, n := 33, 64, 65
+	a, b := randomFloats(r, m*k), randomFloats(r, k*n)
+	want := make([]float32, m*n)
+	gemm(want, a, b, m, k, n)
+	for name, newDevice := range devices {
+		d := newDevice()
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			var key any
+			if i%2 == 0 {
+				key = "weight"
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				got := make([]float32, m*n)
+				if assert.NoError(t, d.Sgemm(got, a, b, m, k, n, key), name) {
+					assert.InDeltaSlice(t, want, got, 1e-4, name)
+				}
+			}()
+		}
+		wg.Wait()
+		assert.NoError(t, d.Close(), name)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+// EncoderInputs returns the inputs for a single sequence of token IDs, as
+// expected by the transformer encoders exported from Hugging Face models
+// (input_ids, attention_mask and token_type_ids, all with shape [1, n]).
+// Only the inputs declared by the graph are returned.
+func EncoderInputs(g *Graph, ids []int) map[string]*Tensor {
+	return EncoderInputsWithTypes(g, ids, nil)
+}
+
+// EncoderInputsWithTypes returns the inputs for a single sequence of token
+// IDs, as EncoderInputs, with the token type ID of each token, e.g. 1 for
+// the tokens of the second text of a pair, or 0 for all of them if nil.
+func EncoderInputsWithTypes(g *Graph, ids, typeIDs []int) map[string]*Tensor {
+	n := len(ids)
+	inputIDs := make([]int64, n)
+	mask := make([]int64, n)
+	types := make([]int64, n)
+	for i, id := range ids {
+		inputIDs[i] = int64(id)
+		mask[i] = 1
+	}
+	for i, t := range typeIDs {
+		types[i] = int64(t)
+	}
+	candidates := map[string]*Tensor{
+		"input_ids":      NewIntTensor([]int{1, n}, inputIDs),
+		"attention_mask": NewIntTensor([]int{1, n}, mask),
+		"token_type_ids": NewIntTensor([]int{1, n}, types),
+	}
+	inputs := make(map[string]*Tensor, len(candidates))
+	for name, t := range candidates {
+		if g.HasInput(name) {
+			inputs[name] = t
+		}
+	}
+	return inputs
+}
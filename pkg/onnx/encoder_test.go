@@ -0,0 +1,22 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncoderInputsWithTypes(t *testing.T) {
+	g := &Graph{Inputs: []string{"input_ids", "token_type_ids"}}
+	inputs := EncoderInputsWithTypes(g, []int{101, 7, 102, 8, 102}, []int{0, 0, 0, 1, 1})
+	assert.Len(t, inputs, 2)
+	assert.Equal(t, []int64{101, 7, 102, 8, 102}, inputs["input_ids"].Ints)
+	assert.Equal(t, []int64{0, 0, 0, 1, 1}, inputs["token_type_ids"].Ints)
+
+	inputs = EncoderInputs(g, []int{101, 102})
+	assert.Equal(t, []int64{0, 0}, inputs["token_type_ids"].Ints)
+}
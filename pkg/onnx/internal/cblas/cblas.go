@@ -0,0 +1,38 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build cgo && blas
+
+// Package cblas binds the matrix product of a CBLAS library: Apple
+// Accelerate on macOS, OpenBLAS elsewhere. Another implementation can be
+// linked by overriding CGO_CFLAGS and CGO_LDFLAGS.
+//
+// It's a separate package since a package can't have both cgo and Go
+// assembly files.
+package cblas
+
+/*
+#cgo darwin CFLAGS: -DACCELERATE_NEW_LAPACK
+#cgo darwin LDFLAGS: -framework Accelerate
+#cgo !darwin LDFLAGS: -lopenblas
+#ifdef __APPLE__
+#include <Accelerate/Accelerate.h>
+#else
+#include <cblas.h>
+#endif
+*/
+import "C"
+
+import "unsafe"
+
+// Sgemm accumulates in dst (m×n) the product of a (m×k) and b (k×n), in
+// row-major order. The matrices must not be empty.
+func Sgemm(dst, a, b []float32, m, k, n int) {
+	_, _, _ = dst[m*n-1], a[m*k-1], b[k*n-1]
+	C.cblas_sgemm(C.CblasRowMajor, C.CblasNoTrans, C.CblasNoTrans,
+		C.int(m), C.int(n), C.int(k),
+		1, (*C.float)(unsafe.Pointer(&a[0])), C.int(k),
+		(*C.float)(unsafe.Pointer(&b[0])), C.int(n),
+		1, (*C.float)(unsafe.Pointer(&dst[0])), C.int(n))
+}
@@ -0,0 +1,250 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build cgo && cuda
+
+// Package cuda binds the matrix product of cuBLAS, to offload the large
+// matrix products of the ONNX runtime to an NVIDIA GPU.
+//
+// It's experimental: the weights are copied to the GPU once, and kept
+// resident, but the activations are copied to the GPU and the result back
+// for each product, so it pays off only for large batches. Only the matrix
+// products are offloaded: the other operators, and the models of the spago
+// backend, run on the CPU.
+package cuda
+
+/*
+#cgo LDFLAGS: -lcublas -lcudart
+#include <cuda_runtime.h>
+#include <cublas_v2.h>
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+// ErrClosed is returned by the products of a closed device.
+var ErrClosed = errors.New("cuda: device closed")
+
+// Device computes matrix products on the GPU, keeping the weights resident
+// in its memory until it's closed. It's safe for concurrent use: each
+// product runs on its own stream.
+type Device struct {
+	mu      sync.Mutex
+	weights map[any]*weight
+	closed  bool
+}
+
+// weight is a matrix copied to the device memory, once ready.
+type weight struct {
+	ready chan struct{}
+	ptr   unsafe.Pointer
+	err   error
+}
+
+// NewDevice returns a device with no weights.
+func NewDevice() *Device {
+	return &Device{weights: make(map[any]*weight)}
+}
+
+// Sgemm accumulates in dst (m×n) the product of a (m×k) and b (k×n), in
+// row-major order, on the GPU. The matrices must not be empty.
+//
+// If the key isn't nil, b is a weight, which must not change: it's copied to
+// the device by the first product with the key, and reused by the next ones.
+func (d *Device) Sgemm(dst, a, b []float32, m, k, n int, key any) error {
+	_, _, _ = dst[m*n-1], a[m*k-1], b[k*n-1]
+	s, err := getStream()
+	if err != nil {
+		return err
+	}
+	defer putStream(s)
+
+	var db unsafe.Pointer
+	if key != nil {
+		db, err = d.resident(key, b[:k*n])
+	} else {
+		db, err = s.upload(&s.buffers[1], b[:k*n])
+	}
+	if err != nil {
+		return err
+	}
+	da, err := s.upload(&s.buffers[0], a[:m*k])
+	if err != nil {
+		return err
+	}
+	dc, err := s.upload(&s.buffers[2], dst[:m*n])
+	if err != nil {
+		return err
+	}
+	// cuBLAS is column-major: the row-major C = A·B is computed as the
+	// column-major Cᵀ = Bᵀ·Aᵀ, with the same memory layouts.
+	alpha, beta := C.float(1), C.float(1)
+	st := C.cublasSgemm(s.handle, C.CUBLAS_OP_N, C.CUBLAS_OP_N,
+		C.int(n), C.int(m), C.int(k),
+		&alpha, (*C.float)(db), C.int(n),
+		(*C.float)(da), C.int(k),
+		&beta, (*C.float)(dc), C.int(n))
+	if st != C.CUBLAS_STATUS_SUCCESS {
+		return fmt.Errorf("cuda: sgemm failed (status %d)", int(st))
+	}
+	size := C.size_t(m * n * 4)
+	if e := C.cudaMemcpyAsync(unsafe.Pointer(&dst[0]), dc, size, C.cudaMemcpyDeviceToHost, s.stream); e != C.cudaSuccess {
+		return cudaError(e)
+	}
+	if e := C.cudaStreamSynchronize(s.stream); e != C.cudaSuccess {
+		return cudaError(e)
+	}
+	return nil
+}
+
+// resident returns the device pointer of the weight with the key, copying
+// it to the device first if needed. The weights are copied once, without
+// blocking the products of the other weights.
+func (d *Device) resident(key any, values []float32) (unsafe.Pointer, error) {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil, ErrClosed
+	}
+	w, ok := d.weights[key]
+	if !ok {
+		w = &weight{ready: make(chan struct{})}
+		d.weights[key] = w
+	}
+	d.mu.Unlock()
+
+	if !ok {
+		w.ptr, w.err = alloc(values)
+		close(w.ready)
+		if w.err != nil {
+			// The next product tries again.
+			d.mu.Lock()
+			delete(d.weights, key)
+			d.mu.Unlock()
+		}
+	}
+	<-w.ready
+	return w.ptr, w.err
+}
+
+// Close frees the weights of the device, whose products then fail with
+// ErrClosed. It must not be called while a product is running.
+func (d *Device) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	for _, w := range d.weights {
+		<-w.ready
+		if w.ptr != nil {
+			C.cudaFree(w.ptr)
+		}
+	}
+	d.weights = nil
+	return nil
+}
+
+// alloc copies the values to new device memory, and returns its pointer.
+func alloc(values []float32) (unsafe.Pointer, error) {
+	size := C.size_t(len(values) * 4)
+	var p unsafe.Pointer
+	if e := C.cudaMalloc(&p, size); e != C.cudaSuccess {
+		return nil, cudaError(e)
+	}
+	if e := C.cudaMemcpy(p, unsafe.Pointer(&values[0]), size, C.cudaMemcpyHostToDevice); e != C.cudaSuccess {
+		C.cudaFree(p)
+		return nil, cudaError(e)
+	}
+	return p, nil
+}
+
+// stream is a cuBLAS handle bound to a CUDA stream, with the device buffers
+// of the operands of its products, grown as needed. A stream runs a product
+// at a time, the concurrent products taking different ones.
+type stream struct {
+	handle  C.cublasHandle_t
+	stream  C.cudaStream_t
+	buffers [3]buffer
+}
+
+// buffer is an allocation of device memory.
+type buffer struct {
+	ptr  unsafe.Pointer
+	size int
+}
+
+// The idle streams, reused by the next products, shared by the devices.
+var (
+	streamsMu sync.Mutex
+	streams   []*stream
+)
+
+// getStream returns an idle stream, or a new one if none is idle.
+func getStream() (*stream, error) {
+	streamsMu.Lock()
+	if n := len(streams); n > 0 {
+		s := streams[n-1]
+		streams = streams[:n-1]
+		streamsMu.Unlock()
+		return s, nil
+	}
+	streamsMu.Unlock()
+
+	s := &stream{}
+	if e := C.cudaStreamCreateWithFlags(&s.stream, C.cudaStreamNonBlocking); e != C.cudaSuccess {
+		return nil, cudaError(e)
+	}
+	if st := C.cublasCreate(&s.handle); st != C.CUBLAS_STATUS_SUCCESS {
+		C.cudaStreamDestroy(s.stream)
+		return nil, fmt.Errorf("cuda: failed to create the cuBLAS handle (status %d)", int(st))
+	}
+	if st := C.cublasSetStream(s.handle, s.stream); st != C.CUBLAS_STATUS_SUCCESS {
+		C.cublasDestroy(s.handle)
+		C.cudaStreamDestroy(s.stream)
+		return nil, fmt.Errorf("cuda: failed to set the stream of the cuBLAS handle (status %d)", int(st))
+	}
+	return s, nil
+}
+
+// putStream makes the stream idle.
+func putStream(s *stream) {
+	streamsMu.Lock()
+	defer streamsMu.Unlock()
+	streams = append(streams, s)
+}
+
+// upload copies the values to the buffer, growing it if needed, and
+// returns its device pointer. The copy is ordered before the next
+// operations of the stream.
+func (s *stream) upload(buf *buffer, values []float32) (unsafe.Pointer, error) {
+	size := len(values) * 4
+	if buf.size < size {
+		if buf.ptr != nil {
+			C.cudaFree(buf.ptr)
+			*buf = buffer{}
+		}
+		var p unsafe.Pointer
+		if e := C.cudaMalloc(&p, C.size_t(size)); e != C.cudaSuccess {
+			return nil, cudaError(e)
+		}
+		*buf = buffer{ptr: p, size: size}
+	}
+	// The copies from pageable memory return once the values are staged,
+	// so they don't outlive the call.
+	if e := C.cudaMemcpyAsync(buf.ptr, unsafe.Pointer(&values[0]), C.size_t(size), C.cudaMemcpyHostToDevice, s.stream); e != C.cudaSuccess {
+		return nil, cudaError(e)
+	}
+	return buf.ptr, nil
+}
+
+func cudaError(e C.cudaError_t) error {
+	return fmt.Errorf("cuda: %s", C.GoString(C.cudaGetErrorString(e)))
+}
@@ -0,0 +1,13 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import "github.com/nlpodyssey/cybertron/pkg/kernels"
+
+// KernelsISA returns the instruction set used by the vectorized kernels
+// of the runtime, shared with the spago models (see kernels.ISA).
+func KernelsISA() string {
+	return kernels.ISA()
+}
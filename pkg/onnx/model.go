@@ -0,0 +1,400 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+
+	"github.com/nlpodyssey/cybertron/pkg/numa"
+)
+
+// DefaultModelFilename is the default filename of an ONNX model inside
+// the model's directory.
+const DefaultModelFilename = "model.onnx"
+
+// Model is an ONNX model.
+type Model struct {
+	// IRVersion is the version of the ONNX intermediate representation.
+	IRVersion int64
+	// Opset is the version of the default ("ai.onnx") operator set.
+	Opset int64
+	// Graph is the computation graph.
+	Graph *Graph
+	// Parallelism is the maximum number of goroutines computing each
+	// matrix product (default 1).
+	Parallelism int
+	// MemoryLimit is the maximum number of bytes of the intermediate
+	// values held at once by a run, beyond which it fails with
+	// ErrMemoryLimitExceeded (default 0, unlimited).
+	MemoryLimit int64
+	// AttentionWindow is the maximum distance between the positions of the
+	// queries and of the keys they attend to, in the fused attention (see
+	// FuseAttention): a sliding window approximating the full attention of
+	// long inputs in linear time (default 0, the full attention).
+	AttentionWindow int
+	// NUMANode is the NUMA node the goroutines computing the matrix
+	// products and the attention are bound to, e.g. the one holding the
+	// weights, if set (see numa.Bind).
+	NUMANode *numa.Node
+	// kernels are the fastest kernels of the matrix products by shape, if
+	// tuned (see TuneKernels).
+	kernels kernelSelection
+	// device computes the large matrix products, if set (see SetDevice).
+	device device
+	// ropeFrequencies are the RoPE frequencies before the scaling, by the
+	// name of their initializer (see ScaleRoPE).
+	ropeFrequencies map[string][]float32
+	// attentionFactor multiplies the scores of the fused attention, if set.
+	attentionFactor float32
+}
+
+// Graph is the computation graph of an ONNX model.
+type Graph struct {
+	// Name is the name of the graph.
+	Name string
+	// Nodes is the list of nodes, in topological order.
+	Nodes []*Node
+	// Initializers contains the constant tensors (typically, the weights).
+	Initializers map[string]*Tensor
+	// Inputs is the list of the graph inputs, excluding the initializers.
+	Inputs []string
+	// Outputs is the list of the graph outputs.
+	Outputs []string
+}
+
+// Node is a single computation in the graph.
+type Node struct {
+	// Name is the optional name of the node.
+	Name string
+	// OpType is the name of the operator.
+	OpType string
+	// Domain is the domain of the operator ("" is the default ONNX domain).
+	Domain string
+	// Inputs is the list of input values; an empty name is an omitted optional input.
+	Inputs []string
+	// Outputs is the list of output values.
+	Outputs []string
+	// Attributes contains the node attributes by name.
+	Attributes map[string]*Attribute
+}
+
+// Attribute is a named attribute of a Node.
+type Attribute struct {
+	Name    string
+	Float   float32
+	Int     int64
+	String  string
+	Tensor  *Tensor
+	Floats  []float32
+	Ints    []int64
+	Strings []string
+}
+
+// LoadModel reads an ONNX model from file.
+func LoadModel(filename string) (*Model, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ONNX model %q: %w", filename, err)
+	}
+	m, err := DecodeModel(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ONNX model %q: %w", filename, err)
+	}
+	return m, nil
+}
+
+// LoadModelFS reads an ONNX model from a file of the file system.
+func LoadModelFS(fsys fs.FS, name string) (*Model, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ONNX model %q: %w", name, err)
+	}
+	m, err := DecodeModel(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ONNX model %q: %w", name, err)
+	}
+	return m, nil
+}
+
+// DecodeModel decodes a serialized ModelProto message.
+func DecodeModel(data []byte) (*Model, error) {
+	m := &Model{Opset: 1}
+	r := &wireReader{buf: data}
+	for !r.done() {
+		field, wireType, err := r.tag()
+		if err != nil {
+			return nil, err
+		}
+		switch field {
+		case 1:
+			var v uint64
+			v, err = r.varint()
+			m.IRVersion = int64(v)
+		case 7:
+			var b []byte
+			if b, err = r.bytes(); err == nil {
+				m.Graph, err = decodeGraph(b)
+			}
+		case 8:
+			var b []byte
+			if b, err = r.bytes(); err == nil {
+				var domain string
+				var version int64
+				domain, version, err = decodeOperatorSetID(b)
+				if err == nil && (domain == "" || domain == "ai.onnx") {
+					m.Opset = version
+				}
+			}
+		default:
+			err = r.skip(wireType)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if m.Graph == nil {
+		return nil, fmt.Errorf("onnx: model has no graph")
+	}
+	return m, nil
+}
+
+func decodeOperatorSetID(b []byte) (domain string, version int64, err error) {
+	r := &wireReader{buf: b}
+	for !r.done() {
+		field, wireType, err := r.tag()
+		if err != nil {
+			return "", 0, err
+		}
+		switch field {
+		case 1:
+			domain, err = r.string()
+		case 2:
+			var v uint64
+			v, err = r.varint()
+			version = int64(v)
+		default:
+			err = r.skip(wireType)
+		}
+		if err != nil {
+			return "", 0, err
+		}
+	}
+	return domain, version, nil
+}
+
+func decodeGraph(b []byte) (*Graph, error) {
+	g := &Graph{Initializers: make(map[string]*Tensor)}
+	var inputs []string
+	r := &wireReader{buf: b}
+	for !r.done() {
+		field, wireType, err := r.tag()
+		if err != nil {
+			return nil, err
+		}
+		var msg []byte
+		if field == 1 || field == 5 || field == 11 || field == 12 {
+			if msg, err = r.bytes(); err != nil {
+				return nil, err
+			}
+		}
+		switch field {
+		case 1:
+			var n *Node
+			if n, err = decodeNode(msg); err == nil {
+				g.Nodes = append(g.Nodes, n)
+			}
+		case 2:
+			g.Name, err = r.string()
+		case 5:
+			var name string
+			var t *Tensor
+			if name, t, err = decodeTensor(msg); err == nil {
+				g.Initializers[name] = t
+			}
+		case 11:
+			var name string
+			if name, err = decodeValueInfoName(msg); err == nil {
+				inputs = append(inputs, name)
+			}
+		case 12:
+			var name string
+			if name, err = decodeValueInfoName(msg); err == nil {
+				g.Outputs = append(g.Outputs, name)
+			}
+		default:
+			err = r.skip(wireType)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Before IR version 4, initializers were also listed among the inputs.
+	for _, name := range inputs {
+		if _, ok := g.Initializers[name]; !ok {
+			g.Inputs = append(g.Inputs, name)
+		}
+	}
+	return g, nil
+}
+
+func decodeValueInfoName(b []byte) (name string, err error) {
+	r := &wireReader{buf: b}
+	for !r.done() {
+		field, wireType, err := r.tag()
+		if err != nil {
+			return "", err
+		}
+		if field == 1 {
+			if name, err = r.string(); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if err = r.skip(wireType); err != nil {
+			return "", err
+		}
+	}
+	return name, nil
+}
+
+func decodeNode(b []byte) (*Node, error) {
+	n := &Node{Attributes: make(map[string]*Attribute)}
+	r := &wireReader{buf: b}
+	for !r.done() {
+		field, wireType, err := r.tag()
+		if err != nil {
+			return nil, err
+		}
+		switch field {
+		case 1:
+			var s string
+			s, err = r.string()
+			n.Inputs = append(n.Inputs, s)
+		case 2:
+			var s string
+			s, err = r.string()
+			n.Outputs = append(n.Outputs, s)
+		case 3:
+			n.Name, err = r.string()
+		case 4:
+			n.OpType, err = r.string()
+		case 5:
+			var msg []byte
+			if msg, err = r.bytes(); err == nil {
+				var a *Attribute
+				if a, err = decodeAttribute(msg); err == nil {
+					n.Attributes[a.Name] = a
+				}
+			}
+		case 7:
+			n.Domain, err = r.string()
+		default:
+			err = r.skip(wireType)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+func decodeAttribute(b []byte) (*Attribute, error) {
+	a := &Attribute{}
+	r := &wireReader{buf: b}
+	for !r.done() {
+		field, wireType, err := r.tag()
+		if err != nil {
+			return nil, err
+		}
+		switch field {
+		case 1:
+			a.Name, err = r.string()
+		case 2:
+			var v uint32
+			v, err = r.fixed32()
+			a.Float = math.Float32frombits(v)
+		case 3:
+			var v uint64
+			v, err = r.varint()
+			a.Int = int64(v)
+		case 4:
+			a.String, err = r.string()
+		case 5:
+			var msg []byte
+			if msg, err = r.bytes(); err == nil {
+				_, a.Tensor, err = decodeTensor(msg)
+			}
+		case 7:
+			a.Floats, err = r.float32s(wireType, a.Floats)
+		case 8:
+			a.Ints, err = r.int64s(wireType, a.Ints)
+		case 9:
+			var s string
+			s, err = r.string()
+			a.Strings = append(a.Strings, s)
+		default:
+			err = r.skip(wireType)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// intAttr returns the value of an integer attribute, or def if missing.
+func (n *Node) intAttr(name string, def int64) int64 {
+	if a, ok := n.Attributes[name]; ok {
+		return a.Int
+	}
+	return def
+}
+
+// floatAttr returns the value of a float attribute, or def if missing.
+func (n *Node) floatAttr(name string, def float32) float32 {
+	if a, ok := n.Attributes[name]; ok {
+		return a.Float
+	}
+	return def
+}
+
+// stringAttr returns the value of a string attribute, or def if missing.
+func (n *Node) stringAttr(name string, def string) string {
+	if a, ok := n.Attributes[name]; ok {
+		return a.String
+	}
+	return def
+}
+
+// intsAttr returns the value of an integers attribute, or nil if missing.
+func (n *Node) intsAttr(name string) []int64 {
+	if a, ok := n.Attributes[name]; ok {
+		return a.Ints
+	}
+	return nil
+}
+
+// HasInput reports whether the graph declares an input with the given name.
+func (g *Graph) HasInput(name string) bool {
+	for _, in := range g.Inputs {
+		if in == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HasOutput reports whether the graph declares an output with the given name.
+func (g *Graph) HasOutput(name string) bool {
+	for _, out := range g.Outputs {
+		if out == name {
+			return true
+		}
+	}
+	return false
+}
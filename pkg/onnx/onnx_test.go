@@ -0,0 +1,322 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"encoding/binary"
+	"io/fs"
+	"math"
+	"math/rand"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// protoWriter is a minimal Protocol Buffers encoder used to build test models.
+type protoWriter []byte
+
+func (w protoWriter) varint(field int, v uint64) protoWriter {
+	w = binary.AppendUvarint(w, uint64(field<<3|wireVarint))
+	return binary.AppendUvarint(w, v)
+}
+
+func (w protoWriter) bytes(field int, b []byte) protoWriter {
+	w = binary.AppendUvarint(w, uint64(field<<3|wireBytes))
+	w = binary.AppendUvarint(w, uint64(len(b)))
+	return append(w, b...)
+}
+
+func (w protoWriter) string(field int, s string) protoWriter {
+	return w.bytes(field, []byte(s))
+}
+
+func encodeFloatTensor(name string, shape []int64, data []float32) []byte {
+	var w protoWriter
+	for _, d := range shape {
+		w = w.varint(1, uint64(d))
+	}
+	w = w.varint(2, protoFloat)
+	w = w.string(8, name)
+	raw := make([]byte, 0, len(data)*4)
+	for _, v := range data {
+		raw = binary.LittleEndian.AppendUint32(raw, math.Float32bits(v))
+	}
+	return w.bytes(9, raw)
+}
+
+func encodeNode(opType string, inputs, outputs []string, attrs ...[]byte) []byte {
+	var w protoWriter
+	for _, in := range inputs {
+		w = w.string(1, in)
+	}
+	for _, out := range outputs {
+		w = w.string(2, out)
+	}
+	w = w.string(4, opType)
+	for _, a := range attrs {
+		w = w.bytes(5, a)
+	}
+	return w
+}
+
+func TestDecodeModel(t *testing.T) {
+	var graph protoWriter
+	graph = graph.bytes(1, encodeNode("MatMul", []string{"x", "w"}, []string{"h"}))
+	graph = graph.bytes(1, encodeNode("Add", []string{"h", "b"}, []string{"z"}))
+	graph = graph.bytes(1, encodeNode("Softmax", []string{"z"}, []string{"y"},
+		protoWriter{}.string(1, "axis").varint(3, 1)))
+	graph = graph.string(2, "test")
+	graph = graph.bytes(5, encodeFloatTensor("w", []int64{2, 2}, []float32{1, 0, 0, 1}))
+	graph = graph.bytes(5, encodeFloatTensor("b", []int64{2}, []float32{0, 1}))
+	graph = graph.bytes(11, protoWriter{}.string(1, "x"))
+	graph = graph.bytes(11, protoWriter{}.string(1, "w")) // initializers may also be listed as inputs
+	graph = graph.bytes(12, protoWriter{}.string(1, "y"))
+
+	var model protoWriter
+	model = model.varint(1, 8)
+	model = model.bytes(8, protoWriter{}.string(1, "").varint(2, 13))
+	model = model.bytes(7, graph)
+
+	m, err := DecodeModel(model)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), m.IRVersion)
+	assert.Equal(t, int64(13), m.Opset)
+	assert.Equal(t, "test", m.Graph.Name)
+	assert.Equal(t, []string{"x"}, m.Graph.Inputs)
+	assert.Equal(t, []string{"y"}, m.Graph.Outputs)
+	assert.Len(t, m.Graph.Nodes, 3)
+	assert.Equal(t, int64(1), m.Graph.Nodes[2].intAttr("axis", -1))
+	require.NoError(t, m.Validate())
+
+	out, err := m.Run(map[string]*Tensor{"x": NewFloatTensor([]int{1, 2}, []float32{1, 1})})
+	require.NoError(t, err)
+	y := out["y"]
+	assert.Equal(t, []int{1, 2}, y.Shape)
+	assert.InDelta(t, 1/(1+math.E), y.Floats[0], 1e-6)
+	assert.InDelta(t, math.E/(1+math.E), y.Floats[1], 1e-6)
+
+	_, err = m.Run(nil)
+	assert.Error(t, err)
+
+	require.NoError(t, m.Close())
+	_, err = m.Run(map[string]*Tensor{"x": NewFloatTensor([]int{1, 2}, []float32{1, 1})})
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestDecodeModel_Truncated(t *testing.T) {
+	model := protoWriter{}.bytes(7, protoWriter{}.string(2, "test"))
+	_, err := DecodeModel(model[:len(model)-1])
+	assert.Error(t, err)
+}
+
+// runNode executes a single node with the given inputs.
+func TestLoadModelFS(t *testing.T) {
+	graph := protoWriter{}.bytes(1, encodeNode("Softmax", []string{"x"}, []string{"y"}))
+	graph = graph.bytes(11, protoWriter{}.string(1, "x"))
+	graph = graph.bytes(12, protoWriter{}.string(1, "y"))
+	fsys := fstest.MapFS{
+		"model.onnx":     {Data: protoWriter{}.varint(1, 8).bytes(7, graph)},
+		"truncated.onnx": {Data: protoWriter{}.bytes(7, graph)[:4]},
+	}
+
+	m, err := LoadModelFS(fsys, "model.onnx")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x"}, m.Graph.Inputs)
+
+	_, err = LoadModelFS(fsys, "truncated.onnx")
+	assert.ErrorContains(t, err, "failed to decode")
+	_, err = LoadModelFS(fsys, "missing.onnx")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func runNode(t *testing.T, node *Node, inputs ...*Tensor) *Tensor {
+	t.Helper()
+	g := &Graph{Nodes: []*Node{node}, Initializers: map[string]*Tensor{}, Outputs: node.Outputs}
+	values := map[string]*Tensor{}
+	for i, in := range inputs {
+		if in != nil {
+			g.Inputs = append(g.Inputs, node.Inputs[i])
+			values[node.Inputs[i]] = in
+		}
+	}
+	out, err := (&Model{Opset: 13, Graph: g}).Run(values)
+	require.NoError(t, err)
+	return out[node.Outputs[0]]
+}
+
+func newNode(opType string, nInputs int, attrs ...*Attribute) *Node {
+	n := &Node{OpType: opType, Outputs: []string{"out"}, Attributes: map[string]*Attribute{}}
+	for i := 0; i < nInputs; i++ {
+		n.Inputs = append(n.Inputs, string(rune('a'+i)))
+	}
+	for _, a := range attrs {
+		n.Attributes[a.Name] = a
+	}
+	return n
+}
+
+func TestBroadcasting(t *testing.T) {
+	a := NewFloatTensor([]int{2, 1}, []float32{1, 2})
+	b := NewFloatTensor([]int{3}, []float32{10, 20, 30})
+	out := runNode(t, newNode("Add", 2), a, b)
+	assert.Equal(t, []int{2, 3}, out.Shape)
+	assert.Equal(t, []float32{11, 21, 31, 12, 22, 32}, out.Floats)
+
+	i := NewIntTensor([]int{2}, []int64{6, 8})
+	j := NewIntTensor([]int{}, []int64{2})
+	out = runNode(t, newNode("Div", 2), i, j)
+	assert.Equal(t, Int, out.Type)
+	assert.Equal(t, []int64{3, 4}, out.Ints)
+}
+
+func TestMatMul(t *testing.T) {
+	a := NewFloatTensor([]int{2, 2, 3}, []float32{1, 2, 3, 4, 5, 6, 1, 0, 0, 0, 1, 0})
+	b := NewFloatTensor([]int{3, 1}, []float32{1, 1, 1})
+	out := runNode(t, newNode("MatMul", 2), a, b)
+	assert.Equal(t, []int{2, 2, 1}, out.Shape)
+	assert.Equal(t, []float32{6, 15, 1, 1}, out.Floats)
+
+	v := NewFloatTensor([]int{3}, []float32{1, 1, 1})
+	out = runNode(t, newNode("MatMul", 2), a, v)
+	assert.Equal(t, []int{2, 2}, out.Shape)
+}
+
+func TestMatMul_Parallel(t *testing.T) {
+	a := NewFloatTensor([]int{5, 2}, []float32{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+	b := NewFloatTensor([]int{2, 2}, []float32{1, 0, 1, 1})
+	want, err := matMul(&opContext{parallelism: 1}, a, b)
+	require.NoError(t, err)
+	for _, p := range []int{2, 3, 8} {
+		out, err := matMul(&opContext{parallelism: p}, a, b)
+		require.NoError(t, err)
+		assert.Equalf(t, want.Floats, out.Floats, "parallelism %d", p)
+	}
+	assert.Equal(t, []float32{3, 2, 7, 4, 11, 6, 15, 8, 19, 10}, want.Floats)
+}
+
+func randomFloats(r *rand.Rand, n int) []float32 {
+	x := make([]float32, n)
+	for i := range x {
+		x[i] = r.Float32()*2 - 1
+	}
+	return x
+}
+
+func BenchmarkMatMul(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	x := NewFloatTensor([]int{128, 768}, randomFloats(r, 128*768))
+	w := NewFloatTensor([]int{768, 768}, randomFloats(r, 768*768))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := matMul(&opContext{}, x, w); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestGemm(t *testing.T) {
+	a := NewFloatTensor([]int{1, 2}, []float32{1, 2})
+	b := NewFloatTensor([]int{3, 2}, []float32{1, 0, 0, 1, 1, 1})
+	c := NewFloatTensor([]int{3}, []float32{1, 1, 1})
+	out := runNode(t, newNode("Gemm", 3, &Attribute{Name: "transB", Int: 1}), a, b, c)
+	assert.Equal(t, []int{1, 3}, out.Shape)
+	assert.Equal(t, []float32{2, 3, 4}, out.Floats)
+}
+
+func TestTranspose(t *testing.T) {
+	x := NewFloatTensor([]int{2, 3}, []float32{1, 2, 3, 4, 5, 6})
+	out := runNode(t, newNode("Transpose", 1), x)
+	assert.Equal(t, []int{3, 2}, out.Shape)
+	assert.Equal(t, []float32{1, 4, 2, 5, 3, 6}, out.Floats)
+}
+
+func TestGather(t *testing.T) {
+	data := NewFloatTensor([]int{3, 2}, []float32{1, 2, 3, 4, 5, 6})
+	indices := NewIntTensor([]int{1, 2}, []int64{2, -3})
+	out := runNode(t, newNode("Gather", 2), data, indices)
+	assert.Equal(t, []int{1, 2, 2}, out.Shape)
+	assert.Equal(t, []float32{5, 6, 1, 2}, out.Floats)
+}
+
+func TestReshapeAndUnsqueeze(t *testing.T) {
+	x := NewFloatTensor([]int{2, 3, 4}, make([]float32, 24))
+	out := runNode(t, newNode("Reshape", 2), x, NewIntTensor([]int{3}, []int64{0, -1, 2}))
+	assert.Equal(t, []int{2, 6, 2}, out.Shape)
+
+	out = runNode(t, newNode("Unsqueeze", 2), x, NewIntTensor([]int{2}, []int64{0, -1}))
+	assert.Equal(t, []int{1, 2, 3, 4, 1}, out.Shape)
+
+	out = runNode(t, newNode("Squeeze", 1), out)
+	assert.Equal(t, []int{2, 3, 4}, out.Shape)
+}
+
+func TestSlice(t *testing.T) {
+	x := NewFloatTensor([]int{2, 4}, []float32{1, 2, 3, 4, 5, 6, 7, 8})
+	starts := NewIntTensor([]int{1}, []int64{1})
+	ends := NewIntTensor([]int{1}, []int64{math.MaxInt64})
+	axes := NewIntTensor([]int{1}, []int64{1})
+	steps := NewIntTensor([]int{1}, []int64{2})
+	out := runNode(t, newNode("Slice", 5), x, starts, ends, axes, steps)
+	assert.Equal(t, []int{2, 2}, out.Shape)
+	assert.Equal(t, []float32{2, 4, 6, 8}, out.Floats)
+
+	starts = NewIntTensor([]int{1}, []int64{-1})
+	ends = NewIntTensor([]int{1}, []int64{math.MinInt64})
+	steps = NewIntTensor([]int{1}, []int64{-1})
+	out = runNode(t, newNode("Slice", 5), x, starts, ends, axes, steps)
+	assert.Equal(t, []float32{4, 3, 2, 1, 8, 7, 6, 5}, out.Floats)
+}
+
+func TestConcat(t *testing.T) {
+	a := NewIntTensor([]int{1}, []int64{1})
+	b := NewIntTensor([]int{2}, []int64{2, 3})
+	out := runNode(t, newNode("Concat", 2, &Attribute{Name: "axis", Int: 0}), a, b)
+	assert.Equal(t, []int64{1, 2, 3}, out.Ints)
+}
+
+func TestSoftmax(t *testing.T) {
+	x := NewFloatTensor([]int{2, 2}, []float32{0, 0, 1, 1})
+	out := runNode(t, newNode("Softmax", 1, &Attribute{Name: "axis", Int: 0}), x)
+	assert.InDelta(t, 1/(1+math.E), out.Floats[0], 1e-6)
+	assert.InDelta(t, math.E/(1+math.E), out.Floats[2], 1e-6)
+}
+
+func TestReduceMean(t *testing.T) {
+	x := NewFloatTensor([]int{2, 3}, []float32{1, 2, 3, 4, 5, 6})
+	out := runNode(t, newNode("ReduceMean", 1, &Attribute{Name: "axes", Ints: []int64{-1}}), x)
+	assert.Equal(t, []int{2, 1}, out.Shape)
+	assert.Equal(t, []float32{2, 5}, out.Floats)
+}
+
+func TestLayerNormalization(t *testing.T) {
+	x := NewFloatTensor([]int{1, 2}, []float32{1, 3})
+	scale := NewFloatTensor([]int{2}, []float32{1, 2})
+	bias := NewFloatTensor([]int{2}, []float32{0, 1})
+	out := runNode(t, newNode("LayerNormalization", 3, &Attribute{Name: "epsilon", Float: 0}), x, scale, bias)
+	assert.InDeltaSlice(t, []float32{-1, 3}, out.Floats, 1e-6)
+}
+
+func TestGelu(t *testing.T) {
+	x := NewFloatTensor([]int{3}, []float32{-1, 0, 2})
+	out := runNode(t, newNode("Gelu", 1), x)
+	assert.InDeltaSlice(t, []float32{-0.158655, 0, 1.954500}, out.Floats, 1e-5)
+	out = runNode(t, newNode("Gelu", 1, &Attribute{Name: "approximate", String: "tanh"}), x)
+	assert.InDeltaSlice(t, []float32{-0.158808, 0, 1.954598}, out.Floats, 1e-5)
+}
+
+func TestWhereAndEqual(t *testing.T) {
+	a := NewIntTensor([]int{3}, []int64{1, 0, 1})
+	zero := NewIntTensor([]int{}, []int64{0})
+	mask := runNode(t, newNode("Equal", 2), a, zero)
+	assert.Equal(t, Bool, mask.Type)
+	assert.Equal(t, []int64{0, 1, 0}, mask.Ints)
+
+	x := NewFloatTensor([]int{}, []float32{-100})
+	y := NewFloatTensor([]int{3}, []float32{1, 2, 3})
+	out := runNode(t, newNode("Where", 3), mask, x, y)
+	assert.Equal(t, []float32{1, -100, 3}, out.Floats)
+}
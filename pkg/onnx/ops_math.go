@@ -0,0 +1,508 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/nlpodyssey/cybertron/pkg/kernels"
+	"github.com/nlpodyssey/cybertron/pkg/numa"
+)
+
+func init() {
+	register("Add", arithmetic(
+		func(a, b float32) float32 { return a + b },
+		func(a, b int64) int64 { return a + b }))
+	register("Sub", arithmetic(
+		func(a, b float32) float32 { return a - b },
+		func(a, b int64) int64 { return a - b }))
+	register("Mul", arithmetic(
+		func(a, b float32) float32 { return a * b },
+		func(a, b int64) int64 { return a * b }))
+	register("Div", arithmetic(
+		func(a, b float32) float32 { return a / b },
+		func(a, b int64) int64 {
+			if b == 0 {
+				return 0
+			}
+			return a / b
+		}))
+	register("Pow", arithmetic(
+		func(a, b float32) float32 {
+			if b == 2 {
+				return a * a
+			}
+			return float32(math.Pow(float64(a), float64(b)))
+		},
+		func(a, b int64) int64 { return int64(math.Pow(float64(a), float64(b))) }))
+
+	register("Equal", comparison(func(a, b float32) bool { return a == b }))
+	register("Less", comparison(func(a, b float32) bool { return a < b }))
+	register("Greater", comparison(func(a, b float32) bool { return a > b }))
+
+	register("Sqrt", unary(func(x float32) float32 { return float32(math.Sqrt(float64(x))) }))
+	register("Exp", unary(func(x float32) float32 { return float32(math.Exp(float64(x))) }))
+	register("Log", unary(func(x float32) float32 { return float32(math.Log(float64(x))) }))
+	register("Erf", inPlace(kernels.Erf))
+	register("Tanh", inPlace(kernels.Tanh))
+	register("Gelu", opGelu)
+	register("Sigmoid", unary(func(x float32) float32 { return float32(1 / (1 + math.Exp(-float64(x)))) }))
+	register("Reciprocal", unary(func(x float32) float32 { return 1 / x }))
+	register("Relu", unary(func(x float32) float32 {
+		if x < 0 {
+			return 0
+		}
+		return x
+	}))
+	register("Neg", opNeg)
+	register("Not", opNot)
+	register("Where", opWhere)
+	register("MatMul", opMatMul)
+	register("Gemm", opGemm)
+	register("Softmax", opSoftmax)
+	register("ReduceMean", reduction(true))
+	register("ReduceSum", reduction(false))
+	register("LayerNormalization", opLayerNormalization)
+}
+
+// arithmetic returns a binary element-wise operator with broadcasting.
+// The integer function is used only when both operands are integers.
+func arithmetic(ff func(a, b float32) float32, fi func(a, b int64) int64) operator {
+	return func(_ *opContext, in []*Tensor) ([]*Tensor, error) {
+		a, b := in[0], in[1]
+		shape, err := broadcastShape(a.Shape, b.Shape)
+		if err != nil {
+			return nil, err
+		}
+		shapes := [][]int{a.Shape, b.Shape}
+		if a.Type != Float && b.Type != Float {
+			out := newTensor(Int, shape)
+			forEachBroadcast(shape, shapes, func(o int, idx []int) {
+				out.Ints[o] = fi(a.Ints[idx[0]], b.Ints[idx[1]])
+			})
+			return []*Tensor{out}, nil
+		}
+		out := newTensor(Float, shape)
+		forEachBroadcast(shape, shapes, func(o int, idx []int) {
+			out.Floats[o] = ff(a.float(idx[0]), b.float(idx[1]))
+		})
+		return []*Tensor{out}, nil
+	}
+}
+
+// comparison returns a binary element-wise operator producing a Bool tensor.
+func comparison(f func(a, b float32) bool) operator {
+	return func(_ *opContext, in []*Tensor) ([]*Tensor, error) {
+		a, b := in[0], in[1]
+		shape, err := broadcastShape(a.Shape, b.Shape)
+		if err != nil {
+			return nil, err
+		}
+		out := newTensor(Bool, shape)
+		forEachBroadcast(shape, [][]int{a.Shape, b.Shape}, func(o int, idx []int) {
+			if f(a.float(idx[0]), b.float(idx[1])) {
+				out.Ints[o] = 1
+			}
+		})
+		return []*Tensor{out}, nil
+	}
+}
+
+// unary returns an element-wise operator on floating-point values.
+func unary(f func(x float32) float32) operator {
+	return func(_ *opContext, in []*Tensor) ([]*Tensor, error) {
+		x := in[0].AsFloats()
+		out := newTensor(Float, in[0].Shape)
+		for i, v := range x {
+			out.Floats[i] = f(v)
+		}
+		return []*Tensor{out}, nil
+	}
+}
+
+// inPlace returns an element-wise operator on floating-point values, with
+// a kernel replacing the values of a copy of the input.
+func inPlace(kernel func(x []float32)) operator {
+	return func(_ *opContext, in []*Tensor) ([]*Tensor, error) {
+		out := newTensor(Float, in[0].Shape)
+		copy(out.Floats, in[0].AsFloats())
+		kernel(out.Floats)
+		return []*Tensor{out}, nil
+	}
+}
+
+// opGelu computes the GELU activation in a single pass, instead of the
+// five element-wise operators it's decomposed into before opset 20.
+func opGelu(ctx *opContext, in []*Tensor) ([]*Tensor, error) {
+	switch approximate := ctx.node.stringAttr("approximate", "none"); approximate {
+	case "none":
+		return inPlace(kernels.GELU)(ctx, in)
+	case "tanh":
+		return inPlace(kernels.GELUTanh)(ctx, in)
+	default:
+		return nil, fmt.Errorf("unsupported approximation %q", approximate)
+	}
+}
+
+func opNeg(_ *opContext, in []*Tensor) ([]*Tensor, error) {
+	x := in[0]
+	out := newTensor(x.Type, x.Shape)
+	if x.Type == Float {
+		for i, v := range x.Floats {
+			out.Floats[i] = -v
+		}
+	} else {
+		for i, v := range x.Ints {
+			out.Ints[i] = -v
+		}
+	}
+	return []*Tensor{out}, nil
+}
+
+func opNot(_ *opContext, in []*Tensor) ([]*Tensor, error) {
+	x := in[0].cast(Bool)
+	out := newTensor(Bool, x.Shape)
+	for i, v := range x.Ints {
+		if v == 0 {
+			out.Ints[i] = 1
+		}
+	}
+	return []*Tensor{out}, nil
+}
+
+func opWhere(_ *opContext, in []*Tensor) ([]*Tensor, error) {
+	cond, x, y := in[0], in[1], in[2]
+	shape, err := broadcastShape(cond.Shape, x.Shape, y.Shape)
+	if err != nil {
+		return nil, err
+	}
+	typ := x.Type
+	if y.Type == Float {
+		typ = Float
+	}
+	out := newTensor(typ, shape)
+	forEachBroadcast(shape, [][]int{cond.Shape, x.Shape, y.Shape}, func(o int, idx []int) {
+		src, i := y, idx[2]
+		if cond.int(idx[0]) != 0 {
+			src, i = x, idx[1]
+		}
+		if typ == Float {
+			out.Floats[o] = src.float(i)
+		} else {
+			out.Ints[o] = src.int(i)
+		}
+	})
+	return []*Tensor{out}, nil
+}
+
+func opMatMul(ctx *opContext, in []*Tensor) ([]*Tensor, error) {
+	out, err := matMul(ctx, in[0], in[1])
+	if err != nil {
+		return nil, err
+	}
+	return []*Tensor{out}, nil
+}
+
+// matMul computes the matrix product with NumPy semantics: the last two
+// dimensions are multiplied, and the leading ones are broadcast.
+// The products are computed by the context (see opContext.gemm).
+func matMul(ctx *opContext, a, b *Tensor) (*Tensor, error) {
+	aShape, bShape := a.Shape, b.Shape
+	if len(aShape) == 1 {
+		aShape = []int{1, aShape[0]}
+	}
+	if len(bShape) == 1 {
+		bShape = []int{bShape[0], 1}
+	}
+	ra, rb := len(aShape), len(bShape)
+	m, k := aShape[ra-2], aShape[ra-1]
+	k2, n := bShape[rb-2], bShape[rb-1]
+	if k != k2 {
+		return nil, fmt.Errorf("cannot multiply shapes %v and %v", a.Shape, b.Shape)
+	}
+	batch, err := broadcastShape(aShape[:ra-2], bShape[:rb-2])
+	if err != nil {
+		return nil, err
+	}
+
+	shape := append(append([]int{}, batch...), m, n)
+	out := newTensor(Float, shape)
+	af, bf := a.AsFloats(), b.AsFloats()
+	forEachBroadcast(batch, [][]int{aShape[:ra-2], bShape[:rb-2]}, func(o int, idx []int) {
+		if err == nil {
+			err = ctx.gemm(out.Floats[o*m*n:(o+1)*m*n], af[idx[0]*m*k:], bf[idx[1]*k*n:], m, k, n, ctx.weight(b, idx[1]))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// remove the dimensions added to the vector operands
+	switch {
+	case len(a.Shape) == 1 && len(b.Shape) == 1:
+		out.Shape = []int{}
+	case len(a.Shape) == 1:
+		out.Shape = append(shape[:len(shape)-2], n)
+	case len(b.Shape) == 1:
+		out.Shape = shape[:len(shape)-1]
+	}
+	return out, nil
+}
+
+// parallelGemm is gemm, computed with the kernel (see Kernel), with the
+// rows split among up to parallelism goroutines, bound to the NUMA node if
+// any. The empty kernel is BLAS if available and the product large, the
+// vectorized kernels otherwise.
+func parallelGemm(dst, a, b []float32, m, k, n, parallelism int, node *numa.Node, kernel Kernel) {
+	switch {
+	case kernel == KernelBLAS && blasGemm != nil,
+		kernel == "" && blasGemm != nil && m*k*n >= blasMinOps:
+		blasGemm(dst, a, b, m, k, n)
+		return
+	}
+	axpy := kernels.Axpy
+	if kernel == KernelGo {
+		axpy = kernels.AxpyGeneric
+	}
+	if parallelism > m {
+		parallelism = m
+	}
+	if parallelism <= 1 {
+		gemmWith(axpy, dst, a, b, m, k, n)
+		return
+	}
+	var wg sync.WaitGroup
+	rows := (m + parallelism - 1) / parallelism
+	for i := 0; i < m; i += rows {
+		end := i + rows
+		if end > m {
+			end = m
+		}
+		wg.Add(1)
+		i, end := i, end
+		goOn(node, func() {
+			defer wg.Done()
+			gemmWith(axpy, dst[i*n:end*n], a[i*k:end*k], b, end-i, k, n)
+		})
+	}
+	wg.Wait()
+}
+
+// gemm accumulates in dst (m×n) the product of a (m×k) and b (k×n).
+func gemm(dst, a, b []float32, m, k, n int) {
+	gemmWith(kernels.Axpy, dst, a, b, m, k, n)
+}
+
+// gemmWith is gemm, with the axpy kernel.
+func gemmWith(axpy func(alpha float32, x, y []float32), dst, a, b []float32, m, k, n int) {
+	for i := 0; i < m; i++ {
+		row := dst[i*n : (i+1)*n]
+		for p, av := range a[i*k : (i+1)*k] {
+			if av != 0 {
+				axpy(av, b[p*n:(p+1)*n], row)
+			}
+		}
+	}
+}
+
+func opGemm(ctx *opContext, in []*Tensor) ([]*Tensor, error) {
+	a, b, c := in[0], in[1], (*Tensor)(nil)
+	if len(in) > 2 {
+		c = in[2]
+	}
+	if a.Rank() != 2 || b.Rank() != 2 {
+		return nil, fmt.Errorf("expected 2D operands, got %v and %v", a.Shape, b.Shape)
+	}
+	if ctx.node.intAttr("transA", 0) != 0 {
+		a = transpose2D(a)
+	}
+	if ctx.node.intAttr("transB", 0) != 0 {
+		b = transpose2D(b)
+	}
+	out, err := matMul(ctx, a, b)
+	if err != nil {
+		return nil, err
+	}
+	if alpha := ctx.node.floatAttr("alpha", 1); alpha != 1 {
+		for i := range out.Floats {
+			out.Floats[i] *= alpha
+		}
+	}
+	if c != nil {
+		beta := ctx.node.floatAttr("beta", 1)
+		if _, err := broadcastShape(out.Shape, c.Shape); err != nil {
+			return nil, err
+		}
+		forEachBroadcast(out.Shape, [][]int{c.Shape}, func(o int, idx []int) {
+			out.Floats[o] += beta * c.float(idx[0])
+		})
+	}
+	return []*Tensor{out}, nil
+}
+
+func transpose2D(t *Tensor) *Tensor {
+	rows, cols := t.Shape[0], t.Shape[1]
+	x := t.AsFloats()
+	out := newTensor(Float, []int{cols, rows})
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			out.Floats[j*rows+i] = x[i*cols+j]
+		}
+	}
+	return out
+}
+
+func opSoftmax(ctx *opContext, in []*Tensor) ([]*Tensor, error) {
+	x := in[0]
+	defaultAxis := int64(-1)
+	if ctx.opset < 13 {
+		defaultAxis = 1
+	}
+	axis, err := normalizeAxis(ctx.node.intAttr("axis", defaultAxis), x.Rank())
+	if err != nil {
+		return nil, err
+	}
+
+	outer, dim, inner := shapeSize(x.Shape[:axis]), x.Shape[axis], shapeSize(x.Shape[axis+1:])
+	if ctx.opset < 13 {
+		// the input is coerced into a 2D matrix, and the softmax is computed on its rows
+		dim, inner = dim*inner, 1
+	}
+
+	values := x.AsFloats()
+	out := newTensor(Float, x.Shape)
+	if inner == 1 {
+		// contiguous rows, computed by the vectorized kernels
+		for start := 0; start < len(values); start += dim {
+			row := out.Floats[start : start+dim]
+			max := kernels.Max(values[start : start+dim])
+			for d, v := range values[start : start+dim] {
+				row[d] = float32(math.Exp(float64(v - max)))
+			}
+			kernels.Scale(1/kernels.Sum(row), row)
+		}
+		return []*Tensor{out}, nil
+	}
+	for o := 0; o < outer; o++ {
+		for i := 0; i < inner; i++ {
+			base := o*dim*inner + i
+			max := float32(math.Inf(-1))
+			for d := 0; d < dim; d++ {
+				if v := values[base+d*inner]; v > max {
+					max = v
+				}
+			}
+			var sum float32
+			for d := 0; d < dim; d++ {
+				e := float32(math.Exp(float64(values[base+d*inner] - max)))
+				out.Floats[base+d*inner] = e
+				sum += e
+			}
+			for d := 0; d < dim; d++ {
+				out.Floats[base+d*inner] /= sum
+			}
+		}
+	}
+	return []*Tensor{out}, nil
+}
+
+// reduction returns the ReduceSum operator, or ReduceMean if mean is true.
+func reduction(mean bool) operator {
+	return func(ctx *opContext, in []*Tensor) ([]*Tensor, error) {
+		x := in[0]
+		// the axes moved from an attribute to an input in opset 13 for
+		// ReduceSum, and in opset 18 for the other reductions
+		axes := ctx.node.intsAttr("axes")
+		if len(in) > 1 && in[1] != nil {
+			axes = in[1].AsInts()
+		}
+
+		reduced := make([]bool, x.Rank())
+		if len(axes) == 0 && ctx.node.intAttr("noop_with_empty_axes", 0) == 0 {
+			for i := range reduced {
+				reduced[i] = true
+			}
+		}
+		for _, a := range axes {
+			axis, err := normalizeAxis(a, x.Rank())
+			if err != nil {
+				return nil, err
+			}
+			reduced[axis] = true
+		}
+
+		keptShape := make([]int, x.Rank())
+		var outShape []int
+		count := 1
+		for i, d := range x.Shape {
+			keptShape[i] = d
+			if reduced[i] {
+				keptShape[i] = 1
+				count *= d
+			}
+			if !reduced[i] || ctx.node.intAttr("keepdims", 1) != 0 {
+				outShape = append(outShape, keptShape[i])
+			}
+		}
+
+		out := newTensor(Float, keptShape)
+		values := x.AsFloats()
+		forEachBroadcast(x.Shape, [][]int{keptShape}, func(i int, idx []int) {
+			out.Floats[idx[0]] += values[i]
+		})
+		if mean && count > 0 {
+			for i := range out.Floats {
+				out.Floats[i] /= float32(count)
+			}
+		}
+		if outShape == nil {
+			outShape = []int{}
+		}
+		if x.Type != Float {
+			out = out.cast(x.Type)
+		}
+		return []*Tensor{out.reshaped(outShape)}, nil
+	}
+}
+
+func opLayerNormalization(ctx *opContext, in []*Tensor) ([]*Tensor, error) {
+	x, scale, bias := in[0], in[1], (*Tensor)(nil)
+	if len(in) > 2 {
+		bias = in[2]
+	}
+	axis, err := normalizeAxis(ctx.node.intAttr("axis", -1), x.Rank())
+	if err != nil {
+		return nil, err
+	}
+	eps := ctx.node.floatAttr("epsilon", 1e-5)
+
+	size := shapeSize(x.Shape[axis:])
+	if scale.Size() != size || (bias != nil && bias.Size() != size) {
+		return nil, fmt.Errorf("scale and bias must have the shape %v", x.Shape[axis:])
+	}
+	values := x.AsFloats()
+	gamma := scale.AsFloats()
+	out := newTensor(Float, x.Shape)
+	var beta []float32
+	if bias != nil {
+		beta = bias.AsFloats()
+	}
+	for start := 0; start < len(values); start += size {
+		row := out.Floats[start : start+size]
+		copy(row, values[start:start+size])
+		kernels.AddConst(-kernels.Sum(row)/float32(size), row)
+		variance := kernels.Dot(row, row) / float32(size)
+		kernels.Scale(float32(1/math.Sqrt(float64(variance+eps))), row)
+		for i := range row {
+			row[i] *= gamma[i]
+		}
+		if beta != nil {
+			kernels.Axpy(1, beta, row)
+		}
+	}
+	return []*Tensor{out}, nil
+}
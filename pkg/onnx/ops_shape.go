@@ -0,0 +1,446 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"fmt"
+	"math"
+)
+
+func init() {
+	register("Identity", opIdentity)
+	register("Constant", opConstant)
+	register("ConstantOfShape", opConstantOfShape)
+	register("Cast", opCast)
+	register("Shape", opShape)
+	register("Reshape", opReshape)
+	register("Unsqueeze", opUnsqueeze)
+	register("Squeeze", opSqueeze)
+	register("Transpose", opTranspose)
+	register("Gather", opGather)
+	register("Concat", opConcat)
+	register("Slice", opSlice)
+	register("Expand", opExpand)
+	register("Range", opRange)
+}
+
+// copyElems copies n elements from src, starting at srcOff, into dst,
+// starting at dstOff. The two tensors must have the same type.
+func copyElems(dst *Tensor, dstOff int, src *Tensor, srcOff, n int) {
+	if dst.Type == Float {
+		copy(dst.Floats[dstOff:dstOff+n], src.Floats[srcOff:srcOff+n])
+		return
+	}
+	copy(dst.Ints[dstOff:dstOff+n], src.Ints[srcOff:srcOff+n])
+}
+
+// gatherStrided returns a new tensor with the given shape, whose elements are
+// read from t at the offsets obtained by applying the strides, plus base.
+func gatherStrided(t *Tensor, shape, st []int, base int) *Tensor {
+	out := newTensor(t.Type, shape)
+	forEachStrided(shape, [][]int{st}, func(o int, idx []int) {
+		if t.Type == Float {
+			out.Floats[o] = t.Floats[base+idx[0]]
+		} else {
+			out.Ints[o] = t.Ints[base+idx[0]]
+		}
+	})
+	return out
+}
+
+// toInts converts a slice of int64 to a slice of int.
+func toInts(v []int64) []int {
+	out := make([]int, len(v))
+	for i, x := range v {
+		out[i] = int(x)
+	}
+	return out
+}
+
+func opIdentity(_ *opContext, in []*Tensor) ([]*Tensor, error) {
+	return []*Tensor{in[0]}, nil
+}
+
+func opConstant(ctx *opContext, _ []*Tensor) ([]*Tensor, error) {
+	attrs := ctx.node.Attributes
+	switch {
+	case attrs["value"] != nil:
+		return []*Tensor{attrs["value"].Tensor}, nil
+	case attrs["value_float"] != nil:
+		return []*Tensor{NewFloatTensor([]int{}, []float32{attrs["value_float"].Float})}, nil
+	case attrs["value_floats"] != nil:
+		v := attrs["value_floats"].Floats
+		return []*Tensor{NewFloatTensor([]int{len(v)}, v)}, nil
+	case attrs["value_int"] != nil:
+		return []*Tensor{NewIntTensor([]int{}, []int64{attrs["value_int"].Int})}, nil
+	case attrs["value_ints"] != nil:
+		v := attrs["value_ints"].Ints
+		return []*Tensor{NewIntTensor([]int{len(v)}, v)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported constant value")
+	}
+}
+
+func opConstantOfShape(ctx *opContext, in []*Tensor) ([]*Tensor, error) {
+	shape := toInts(in[0].AsInts())
+	value := NewFloatTensor([]int{1}, []float32{0})
+	if a, ok := ctx.node.Attributes["value"]; ok && a.Tensor != nil {
+		value = a.Tensor
+	}
+	out := newTensor(value.Type, shape)
+	if value.Type == Float {
+		for i := range out.Floats {
+			out.Floats[i] = value.Floats[0]
+		}
+	} else {
+		for i := range out.Ints {
+			out.Ints[i] = value.Ints[0]
+		}
+	}
+	return []*Tensor{out}, nil
+}
+
+func opCast(ctx *opContext, in []*Tensor) ([]*Tensor, error) {
+	to, err := dataTypeFromProto(ctx.node.intAttr("to", protoFloat))
+	if err != nil {
+		return nil, err
+	}
+	return []*Tensor{in[0].cast(to)}, nil
+}
+
+func opShape(ctx *opContext, in []*Tensor) ([]*Tensor, error) {
+	rank := in[0].Rank()
+	// the optional start and end attributes were introduced in opset 15
+	start, end := int(ctx.node.intAttr("start", 0)), int(ctx.node.intAttr("end", int64(rank)))
+	start, end = clampIndex(start, rank), clampIndex(end, rank)
+	if end < start {
+		end = start
+	}
+	dims := make([]int64, 0, end-start)
+	for _, d := range in[0].Shape[start:end] {
+		dims = append(dims, int64(d))
+	}
+	return []*Tensor{NewIntTensor([]int{len(dims)}, dims)}, nil
+}
+
+func clampIndex(i, rank int) int {
+	if i < 0 {
+		i += rank
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > rank {
+		return rank
+	}
+	return i
+}
+
+func opReshape(ctx *opContext, in []*Tensor) ([]*Tensor, error) {
+	x := in[0]
+	var target []int64
+	if ctx.opset < 5 {
+		target = ctx.node.intsAttr("shape")
+	} else {
+		target = in[1].AsInts()
+	}
+	allowZero := ctx.node.intAttr("allowzero", 0) != 0
+
+	shape := make([]int, len(target))
+	inferred, known := -1, 1
+	for i, d := range target {
+		switch {
+		case d == -1:
+			if inferred >= 0 {
+				return nil, fmt.Errorf("at most one dimension can be -1")
+			}
+			inferred = i
+			continue
+		case d == 0 && !allowZero:
+			if i >= x.Rank() {
+				return nil, fmt.Errorf("cannot copy dimension %d of shape %v", i, x.Shape)
+			}
+			shape[i] = x.Shape[i]
+		default:
+			shape[i] = int(d)
+		}
+		known *= shape[i]
+	}
+	if inferred >= 0 {
+		if known == 0 {
+			return nil, fmt.Errorf("cannot infer dimension with a zero-sized shape")
+		}
+		shape[inferred] = x.Size() / known
+	}
+	if shapeSize(shape) != x.Size() {
+		return nil, fmt.Errorf("cannot reshape %v into %v", x.Shape, target)
+	}
+	return []*Tensor{x.reshaped(shape)}, nil
+}
+
+// axesArg returns the axes given as attribute (up to opset 12) or as
+// optional second input (from opset 13).
+func axesArg(ctx *opContext, in []*Tensor) []int64 {
+	if len(in) > 1 && in[1] != nil {
+		return in[1].AsInts()
+	}
+	return ctx.node.intsAttr("axes")
+}
+
+func opUnsqueeze(ctx *opContext, in []*Tensor) ([]*Tensor, error) {
+	x := in[0]
+	axes := axesArg(ctx, in)
+	rank := x.Rank() + len(axes)
+	inserted := make([]bool, rank)
+	for _, a := range axes {
+		axis, err := normalizeAxis(a, rank)
+		if err != nil {
+			return nil, err
+		}
+		inserted[axis] = true
+	}
+	shape := make([]int, 0, rank)
+	j := 0
+	for i := 0; i < rank; i++ {
+		if inserted[i] {
+			shape = append(shape, 1)
+			continue
+		}
+		shape = append(shape, x.Shape[j])
+		j++
+	}
+	return []*Tensor{x.reshaped(shape)}, nil
+}
+
+func opSqueeze(ctx *opContext, in []*Tensor) ([]*Tensor, error) {
+	x := in[0]
+	axes := axesArg(ctx, in)
+	removed := make([]bool, x.Rank())
+	for _, a := range axes {
+		axis, err := normalizeAxis(a, x.Rank())
+		if err != nil {
+			return nil, err
+		}
+		if x.Shape[axis] != 1 {
+			return nil, fmt.Errorf("cannot squeeze dimension %d of shape %v", axis, x.Shape)
+		}
+		removed[axis] = true
+	}
+	shape := []int{}
+	for i, d := range x.Shape {
+		if removed[i] || (len(axes) == 0 && d == 1) {
+			continue
+		}
+		shape = append(shape, d)
+	}
+	return []*Tensor{x.reshaped(shape)}, nil
+}
+
+func opTranspose(ctx *opContext, in []*Tensor) ([]*Tensor, error) {
+	x := in[0]
+	perm := ctx.node.intsAttr("perm")
+	if perm == nil {
+		for i := x.Rank() - 1; i >= 0; i-- {
+			perm = append(perm, int64(i))
+		}
+	}
+	if len(perm) != x.Rank() {
+		return nil, fmt.Errorf("invalid permutation %v for shape %v", perm, x.Shape)
+	}
+	inStrides := strides(x.Shape)
+	shape := make([]int, len(perm))
+	st := make([]int, len(perm))
+	for i, p := range perm {
+		shape[i] = x.Shape[p]
+		st[i] = inStrides[p]
+	}
+	return []*Tensor{gatherStrided(x, shape, st, 0)}, nil
+}
+
+func opGather(ctx *opContext, in []*Tensor) ([]*Tensor, error) {
+	data, indices := in[0], in[1]
+	axis, err := normalizeAxis(ctx.node.intAttr("axis", 0), data.Rank())
+	if err != nil {
+		return nil, err
+	}
+	shape := append(append(append([]int{}, data.Shape[:axis]...), indices.Shape...), data.Shape[axis+1:]...)
+	outer, dim, inner := shapeSize(data.Shape[:axis]), data.Shape[axis], shapeSize(data.Shape[axis+1:])
+	idx := indices.AsInts()
+
+	out := newTensor(data.Type, shape)
+	for o := 0; o < outer; o++ {
+		for j, i := range idx {
+			if i < 0 {
+				i += int64(dim)
+			}
+			if i < 0 || i >= int64(dim) {
+				return nil, fmt.Errorf("index %d is out of range [0, %d)", idx[j], dim)
+			}
+			copyElems(out, (o*len(idx)+j)*inner, data, (o*dim+int(i))*inner, inner)
+		}
+	}
+	return []*Tensor{out}, nil
+}
+
+func opConcat(ctx *opContext, in []*Tensor) ([]*Tensor, error) {
+	first := in[0]
+	axis, err := normalizeAxis(ctx.node.intAttr("axis", 0), first.Rank())
+	if err != nil {
+		return nil, err
+	}
+	shape := append([]int{}, first.Shape...)
+	shape[axis] = 0
+	typ := first.Type
+	for _, t := range in {
+		if t.Rank() != first.Rank() {
+			return nil, fmt.Errorf("cannot concatenate shapes %v and %v", first.Shape, t.Shape)
+		}
+		shape[axis] += t.Shape[axis]
+		if t.Type == Float {
+			typ = Float
+		}
+	}
+	outer, inner := shapeSize(shape[:axis]), shapeSize(shape[axis+1:])
+	out := newTensor(typ, shape)
+	offset := 0
+	for o := 0; o < outer; o++ {
+		for _, t := range in {
+			n := t.Shape[axis] * inner
+			copyElems(out, offset, t.cast(typ), o*n, n)
+			offset += n
+		}
+	}
+	return []*Tensor{out}, nil
+}
+
+func opSlice(ctx *opContext, in []*Tensor) ([]*Tensor, error) {
+	x := in[0]
+	var starts, ends, axes, steps []int64
+	if ctx.opset < 10 {
+		starts, ends, axes = ctx.node.intsAttr("starts"), ctx.node.intsAttr("ends"), ctx.node.intsAttr("axes")
+	} else {
+		starts, ends = in[1].AsInts(), in[2].AsInts()
+		if len(in) > 3 && in[3] != nil {
+			axes = in[3].AsInts()
+		}
+		if len(in) > 4 && in[4] != nil {
+			steps = in[4].AsInts()
+		}
+	}
+	if len(starts) != len(ends) {
+		return nil, fmt.Errorf("starts and ends must have the same length")
+	}
+
+	rank := x.Rank()
+	begin := make([]int, rank)
+	step := make([]int, rank)
+	shape := append([]int{}, x.Shape...)
+	for i := range step {
+		step[i] = 1
+	}
+	for i := range starts {
+		axis := i
+		if axes != nil {
+			a, err := normalizeAxis(axes[i], rank)
+			if err != nil {
+				return nil, err
+			}
+			axis = a
+		}
+		s := int64(1)
+		if steps != nil {
+			s = steps[i]
+		}
+		if s == 0 {
+			return nil, fmt.Errorf("slice step cannot be zero")
+		}
+		start, n := sliceRange(starts[i], ends[i], s, int64(x.Shape[axis]))
+		begin[axis], step[axis], shape[axis] = int(start), int(s), int(n)
+	}
+
+	inStrides := strides(x.Shape)
+	st := make([]int, rank)
+	base := 0
+	for i := range st {
+		st[i] = inStrides[i] * step[i]
+		base += begin[i] * inStrides[i]
+	}
+	return []*Tensor{gatherStrided(x, shape, st, base)}, nil
+}
+
+// sliceRange clamps the start and end indices of a slice along a dimension
+// of the given size, and returns the first index and the number of elements.
+func sliceRange(start, end, step, dim int64) (int64, int64) {
+	if start < 0 {
+		start += dim
+	}
+	if end < 0 {
+		end += dim
+	}
+	if step > 0 {
+		start = clamp64(start, 0, dim)
+		end = clamp64(end, 0, dim)
+		if end <= start {
+			return start, 0
+		}
+		return start, (end - start + step - 1) / step
+	}
+	start = clamp64(start, 0, dim-1)
+	end = clamp64(end, -1, dim-1)
+	if end >= start {
+		return start, 0
+	}
+	return start, (start - end - step - 1) / -step
+}
+
+func clamp64(v, lo, hi int64) int64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+func opExpand(_ *opContext, in []*Tensor) ([]*Tensor, error) {
+	x := in[0]
+	shape, err := broadcastShape(x.Shape, toInts(in[1].AsInts()))
+	if err != nil {
+		return nil, err
+	}
+	out := newTensor(x.Type, shape)
+	forEachBroadcast(shape, [][]int{x.Shape}, func(o int, idx []int) {
+		if x.Type == Float {
+			out.Floats[o] = x.Floats[idx[0]]
+		} else {
+			out.Ints[o] = x.Ints[idx[0]]
+		}
+	})
+	return []*Tensor{out}, nil
+}
+
+func opRange(_ *opContext, in []*Tensor) ([]*Tensor, error) {
+	start, limit, delta := in[0], in[1], in[2]
+	if delta.float(0) == 0 {
+		return nil, fmt.Errorf("range delta cannot be zero")
+	}
+	if start.Type == Float || limit.Type == Float || delta.Type == Float {
+		s, l, d := start.float(0), limit.float(0), delta.float(0)
+		n := int(math.Max(math.Ceil(float64((l-s)/d)), 0))
+		out := newTensor(Float, []int{n})
+		for i := range out.Floats {
+			out.Floats[i] = s + float32(i)*d
+		}
+		return []*Tensor{out}, nil
+	}
+	s, l, d := start.int(0), limit.int(0), delta.int(0)
+	n := int(math.Max(math.Ceil(float64(l-s)/float64(d)), 0))
+	out := newTensor(start.Type, []int{n})
+	for i := range out.Ints {
+		out.Ints[i] = s + int64(i)*d
+	}
+	return []*Tensor{out}, nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"math/bits"
+	"sync"
+	"unsafe"
+)
+
+// The buffers of the intermediate values are recycled once released by
+// Model.Run, so that the forward passes reuse the memory of the previous
+// ones, instead of leaving the garbage collector millions of short-lived
+// slices under load. They're pooled by size class, the powers of two.
+
+// minPooledSize is the minimum number of elements of the pooled buffers:
+// the smaller ones are cheap enough to allocate.
+const minPooledSize = 1 << 10
+
+var floatPools [64]sync.Pool
+
+// allocFloats returns a zero-filled buffer of n elements, from the pool if
+// possible.
+func allocFloats(n int) []float32 {
+	if n < minPooledSize {
+		return make([]float32, n)
+	}
+	class := bits.Len(uint(n - 1))
+	if p, ok := floatPools[class].Get().(*[]float32); ok {
+		s := (*p)[:n]
+		for i := range s {
+			s[i] = 0
+		}
+		return s
+	}
+	return make([]float32, n, 1<<class)
+}
+
+// freeFloats puts the buffer in the pool, if it has the capacity of a size
+// class. The buffer must not be used anymore.
+func freeFloats(s []float32) {
+	c := cap(s)
+	if c < minPooledSize || c&(c-1) != 0 {
+		return
+	}
+	s = s[:0]
+	floatPools[bits.Len(uint(c-1))].Put(&s)
+}
+
+// bufferRefs counts the values referencing each buffer during a run, since
+// the values returned by some operators share the buffers of their inputs,
+// e.g. Reshape and Identity, and accounts for the memory they hold.
+type bufferRefs struct {
+	counts map[unsafe.Pointer]int
+	// persistent are the buffers of the model and of the inputs, which
+	// outlive the run, and are not accounted for.
+	persistent map[unsafe.Pointer]bool
+	// live and peak are the bytes of the buffers referenced by the values,
+	// currently and at most.
+	live, peak int64
+}
+
+func newBufferRefs(m *Model, inputs map[string]*Tensor) *bufferRefs {
+	r := &bufferRefs{counts: map[unsafe.Pointer]int{}, persistent: map[unsafe.Pointer]bool{}}
+	keep := func(t *Tensor) {
+		if p, _ := buffer(t); p != nil {
+			r.persistent[p] = true
+		}
+	}
+	for _, t := range m.Graph.Initializers {
+		keep(t)
+	}
+	for _, t := range inputs {
+		keep(t)
+	}
+	// the Constant operators return the values of their attributes
+	for _, n := range m.Graph.Nodes {
+		for _, a := range n.Attributes {
+			keep(&Tensor{Floats: a.Floats, Ints: a.Ints})
+			if a.Tensor != nil {
+				keep(a.Tensor)
+			}
+		}
+	}
+	return r
+}
+
+// buffer returns the buffer of the tensor, nil if empty, and its size in
+// bytes.
+func buffer(t *Tensor) (unsafe.Pointer, int64) {
+	switch {
+	case t == nil:
+		return nil, 0
+	case len(t.Floats) > 0:
+		return unsafe.Pointer(unsafe.SliceData(t.Floats)), int64(cap(t.Floats)) * 4
+	case len(t.Ints) > 0:
+		return unsafe.Pointer(unsafe.SliceData(t.Ints)), int64(cap(t.Ints)) * 8
+	default:
+		return nil, 0
+	}
+}
+
+// retain records a value referencing the buffer of the tensor.
+func (r *bufferRefs) retain(t *Tensor) {
+	p, size := buffer(t)
+	if p == nil || r.persistent[p] {
+		return
+	}
+	if r.counts[p]++; r.counts[p] == 1 {
+		r.live += size
+		if r.live > r.peak {
+			r.peak = r.live
+		}
+	}
+}
+
+// release records a value referencing the buffer of the tensor released,
+// and recycles the buffer if it was the last one.
+func (r *bufferRefs) release(t *Tensor) {
+	p, size := buffer(t)
+	if p == nil || r.persistent[p] {
+		return
+	}
+	if r.counts[p]--; r.counts[p] > 0 {
+		return
+	}
+	delete(r.counts, p)
+	r.live -= size
+	if t.Type == Float {
+		freeFloats(t.Floats)
+	}
+}
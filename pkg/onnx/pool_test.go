@@ -0,0 +1,105 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllocFloats(t *testing.T) {
+	small := allocFloats(10)
+	assert.Len(t, small, 10)
+
+	s := allocFloats(3000)
+	assert.Len(t, s, 3000)
+	assert.Equal(t, 4096, cap(s))
+	for i := range s {
+		s[i] = 1
+	}
+	freeFloats(s)
+
+	// the pool may or may not return the same buffer, but always zeroed
+	s = allocFloats(2500)
+	assert.Len(t, s, 2500)
+	assert.Equal(t, 4096, cap(s))
+	for _, v := range s {
+		require.Zero(t, v)
+	}
+}
+
+func TestRun_ReleasesBuffers(t *testing.T) {
+	const n = 2 * minPooledSize
+	w := make([]float32, n)
+	for i := range w {
+		w[i] = float32(i % 7)
+	}
+	g := &Graph{
+		Nodes: []*Node{
+			{OpType: "Add", Inputs: []string{"x", "w"}, Outputs: []string{"h"}},
+			{OpType: "Reshape", Inputs: []string{"h", "shape"}, Outputs: []string{"r"}},
+			{OpType: "Identity", Inputs: []string{"x"}, Outputs: []string{"i"}},
+			{OpType: "Mul", Inputs: []string{"r", "i"}, Outputs: []string{"m"}},
+			{OpType: "Add", Inputs: []string{"m", "h"}, Outputs: []string{"y"}},
+		},
+		Initializers: map[string]*Tensor{
+			"w":     NewFloatTensor([]int{n}, w),
+			"shape": NewIntTensor([]int{1}, []int64{n}),
+		},
+		Inputs:  []string{"x"},
+		Outputs: []string{"y"},
+	}
+	m := &Model{Opset: 13, Graph: g}
+
+	x := make([]float32, n)
+	for i := range x {
+		x[i] = 2
+	}
+	var results [][]float32
+	for i := 0; i < 3; i++ {
+		out, err := m.Run(map[string]*Tensor{"x": NewFloatTensor([]int{n}, x)})
+		require.NoError(t, err)
+		results = append(results, out["y"].Floats)
+	}
+	for i := range w {
+		require.Equal(t, float32(i%7), w[i])
+		require.Equal(t, float32(2), x[i])
+		h := 2 + w[i]
+		require.Equal(t, 2*h+h, results[0][i])
+	}
+	assert.Equal(t, results[0], results[1])
+	assert.Equal(t, results[0], results[2])
+}
+
+func TestRunWithStats_MemoryLimit(t *testing.T) {
+	g := &Graph{
+		Nodes: []*Node{
+			{OpType: "Add", Inputs: []string{"x", "x"}, Outputs: []string{"a"}},
+			{OpType: "Mul", Inputs: []string{"a", "x"}, Outputs: []string{"b"}},
+			{OpType: "Add", Inputs: []string{"b", "x"}, Outputs: []string{"y"}},
+		},
+		Initializers: map[string]*Tensor{},
+		Inputs:       []string{"x"},
+		Outputs:      []string{"y"},
+	}
+	m := &Model{Opset: 13, Graph: g}
+	inputs := map[string]*Tensor{"x": NewFloatTensor([]int{4}, []float32{1, 2, 3, 4})}
+
+	// a and b, then b and y, are held at once
+	_, stats, err := m.RunWithStats(inputs)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2*4*4), stats.PeakMemory)
+
+	m.MemoryLimit = 2 * 4 * 4
+	_, err = m.Run(inputs)
+	assert.NoError(t, err)
+
+	m.MemoryLimit = 2*4*4 - 1
+	_, stats, err = m.RunWithStats(inputs)
+	assert.ErrorIs(t, err, ErrMemoryLimitExceeded)
+	assert.Equal(t, int64(2*4*4), stats.PeakMemory)
+}
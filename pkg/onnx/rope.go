@@ -0,0 +1,169 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The rotary position embeddings (RoPE) rotate the queries and the keys by
+// angles proportional to their positions, at the frequencies of the
+// "inv_freq" tensor: the exported models keep it as an initializer, from
+// which the graph computes the cosines and sines of the positions. Scaling
+// these frequencies lets the model attend to inputs longer than the ones it
+// was trained on.
+
+// RoPE scaling types.
+const (
+	// RopeScalingLinear divides the frequencies by the factor, i.e. the
+	// positions are interpolated ("position interpolation").
+	RopeScalingLinear = "linear"
+	// RopeScalingNTK increases the base of the frequencies (NTK-aware
+	// scaling), interpolating the low frequencies more than the high ones.
+	RopeScalingNTK = "ntk"
+	// RopeScalingYaRN interpolates the frequencies whose wavelength exceeds
+	// the original context only, and sharpens the attention (YaRN).
+	RopeScalingYaRN = "yarn"
+)
+
+// RopeScaling is the scaling of the rotary position embeddings of a model.
+type RopeScaling struct {
+	// Type is the scaling type (RopeScalingLinear, RopeScalingNTK or
+	// RopeScalingYaRN), or empty for none.
+	Type string
+	// Factor is the ratio between the extended context and the original one.
+	Factor float64
+	// OriginalMaxPositionEmbeddings is the context length the model was
+	// trained with, needed by YaRN.
+	OriginalMaxPositionEmbeddings int
+	// BetaFast and BetaSlow are the numbers of rotations over the original
+	// context delimiting the frequencies interpolated by YaRN (default 32 and 1).
+	BetaFast, BetaSlow float64
+}
+
+// ParseRopeScaling parses a RoPE scaling in the format "<type>:<factor>",
+// e.g. "yarn:4", or "none".
+func ParseRopeScaling(s string) (RopeScaling, error) {
+	if s == "" || s == "none" {
+		return RopeScaling{}, nil
+	}
+	typ, factor, ok := strings.Cut(s, ":")
+	f, err := strconv.ParseFloat(factor, 64)
+	if !ok || err != nil || f < 1 {
+		return RopeScaling{}, fmt.Errorf("invalid RoPE scaling %#v, expected <type>:<factor>, with a factor >= 1", s)
+	}
+	switch typ {
+	case RopeScalingLinear, RopeScalingNTK, RopeScalingYaRN:
+		return RopeScaling{Type: typ, Factor: f}, nil
+	default:
+		return RopeScaling{}, fmt.Errorf("invalid RoPE scaling type %#v", typ)
+	}
+}
+
+// String returns the scaling in the format parsed by ParseRopeScaling.
+func (s RopeScaling) String() string {
+	if s.Type == "" {
+		return "none"
+	}
+	return s.Type + ":" + strconv.FormatFloat(s.Factor, 'g', -1, 64)
+}
+
+// ScaleRoPE scales the rotary position embeddings of the model, i.e. the
+// initializers named "inv_freq" (or ending with it), replacing any previous
+// scaling. The YaRN attention factor is applied by the fused attention, so
+// FuseAttention must have been called. It fails if the model has no rotary
+// position embeddings.
+func (m *Model) ScaleRoPE(s RopeScaling) error {
+	g := m.Graph
+	if m.ropeFrequencies == nil {
+		m.ropeFrequencies = make(map[string][]float32)
+		for name, t := range g.Initializers {
+			if strings.HasSuffix(name, "inv_freq") && t.Type == Float {
+				m.ropeFrequencies[name] = append([]float32{}, t.Floats...)
+			}
+		}
+	}
+	if len(m.ropeFrequencies) == 0 {
+		return fmt.Errorf("onnx: the model has no rotary position embeddings (inv_freq) to scale")
+	}
+
+	m.attentionFactor = 0
+	if s.Type == RopeScalingYaRN {
+		fused := false
+		for _, n := range g.Nodes {
+			fused = fused || n.OpType == "FusedAttention"
+		}
+		if !fused {
+			return fmt.Errorf("onnx: the YaRN scaling requires the fused attention")
+		}
+		if s.OriginalMaxPositionEmbeddings <= 0 {
+			return fmt.Errorf("onnx: the YaRN scaling requires the original max position embeddings")
+		}
+		// the cosines and sines are scaled by the factor, hence the scores
+		// by its square
+		mscale := 0.1*math.Log(s.Factor) + 1
+		m.attentionFactor = float32(mscale * mscale)
+	}
+	for name, freq := range m.ropeFrequencies {
+		scaled, err := scaleFrequencies(freq, s)
+		if err != nil {
+			return err
+		}
+		g.Initializers[name] = NewFloatTensor(g.Initializers[name].Shape, scaled)
+	}
+	return nil
+}
+
+// scaleFrequencies returns the frequencies scaled, base^(-2i/dim) being the
+// i-th of dim/2.
+func scaleFrequencies(freq []float32, s RopeScaling) ([]float32, error) {
+	out := make([]float32, len(freq))
+	dim := 2 * float64(len(freq))
+	switch s.Type {
+	case "":
+		copy(out, freq)
+	case RopeScalingLinear:
+		for i, f := range freq {
+			out[i] = f / float32(s.Factor)
+		}
+	case RopeScalingNTK:
+		// base' = base * factor^(dim/(dim-2))
+		for i, f := range freq {
+			out[i] = f * float32(math.Pow(s.Factor, -2*float64(i)/(dim-2)))
+		}
+	case RopeScalingYaRN:
+		if len(freq) < 2 {
+			return nil, fmt.Errorf("onnx: too few RoPE frequencies for YaRN")
+		}
+		betaFast, betaSlow := s.BetaFast, s.BetaSlow
+		if betaFast == 0 {
+			betaFast = 32
+		}
+		if betaSlow == 0 {
+			betaSlow = 1
+		}
+		base := math.Pow(float64(freq[1]), -dim/2)
+		// the dimension rotating n times over the original context
+		correction := func(n float64) float64 {
+			return dim * math.Log(float64(s.OriginalMaxPositionEmbeddings)/(n*2*math.Pi)) / (2 * math.Log(base))
+		}
+		low := math.Max(math.Floor(correction(betaFast)), 0)
+		high := math.Min(math.Ceil(correction(betaSlow)), dim-1)
+		if low == high {
+			high += 0.001
+		}
+		for i, f := range freq {
+			ramp := math.Min(math.Max((float64(i)-low)/(high-low), 0), 1)
+			extrapolation := 1 - ramp
+			out[i] = float32(float64(f)/s.Factor*(1-extrapolation) + float64(f)*extrapolation)
+		}
+	default:
+		return nil, fmt.Errorf("onnx: invalid RoPE scaling type %#v", s.Type)
+	}
+	return out, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRopeScaling(t *testing.T) {
+	s, err := ParseRopeScaling("yarn:4")
+	require.NoError(t, err)
+	assert.Equal(t, RopeScaling{Type: RopeScalingYaRN, Factor: 4}, s)
+	assert.Equal(t, "yarn:4", s.String())
+
+	s, err = ParseRopeScaling("none")
+	require.NoError(t, err)
+	assert.Equal(t, RopeScaling{}, s)
+	assert.Equal(t, "none", s.String())
+
+	for _, invalid := range []string{"linear", "linear:0.5", "foo:2", "ntk:x"} {
+		_, err := ParseRopeScaling(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+// ropeModel returns a model with the RoPE frequencies of the given base and
+// dimension.
+func ropeModel(base float64, dim int) *Model {
+	freq := make([]float32, dim/2)
+	for i := range freq {
+		freq[i] = float32(math.Pow(base, -2*float64(i)/float64(dim)))
+	}
+	return &Model{Opset: 13, Graph: &Graph{
+		Initializers: map[string]*Tensor{"model.rotary_emb.inv_freq": NewFloatTensor([]int{len(freq)}, freq)},
+	}}
+}
+
+func TestScaleRoPE(t *testing.T) {
+	m := ropeModel(10000, 128)
+	original := m.Graph.Initializers["model.rotary_emb.inv_freq"].Floats
+	freq := func() []float32 {
+		return m.Graph.Initializers["model.rotary_emb.inv_freq"].Floats
+	}
+
+	t.Run("linear", func(t *testing.T) {
+		require.NoError(t, m.ScaleRoPE(RopeScaling{Type: RopeScalingLinear, Factor: 2}))
+		for i, f := range freq() {
+			assert.InDelta(t, original[i]/2, f, 1e-9)
+		}
+	})
+
+	t.Run("ntk", func(t *testing.T) {
+		// applied to the original frequencies, not to the linear ones
+		require.NoError(t, m.ScaleRoPE(RopeScaling{Type: RopeScalingNTK, Factor: 4}))
+		base := 10000 * math.Pow(4, 128.0/126)
+		for i, f := range freq() {
+			assert.InDelta(t, math.Pow(base, -2*float64(i)/128), f, 1e-6)
+		}
+	})
+
+	t.Run("yarn", func(t *testing.T) {
+		s := RopeScaling{Type: RopeScalingYaRN, Factor: 4, OriginalMaxPositionEmbeddings: 2048}
+		assert.Error(t, m.ScaleRoPE(s), "the fused attention is required")
+
+		m.Graph.Nodes = []*Node{{OpType: "FusedAttention"}}
+		require.NoError(t, m.ScaleRoPE(s))
+		f := freq()
+		// the high frequencies are kept, the low ones interpolated
+		assert.Equal(t, original[0], f[0])
+		assert.InDelta(t, original[63]/4, f[63], 1e-9)
+		assert.Greater(t, f[30], original[30]/4)
+		assert.Less(t, f[30], original[30])
+		assert.InDelta(t, math.Pow(0.1*math.Log(4)+1, 2), m.attentionFactor, 1e-6)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		require.NoError(t, m.ScaleRoPE(RopeScaling{}))
+		assert.Equal(t, original, freq())
+		assert.Zero(t, m.attentionFactor)
+	})
+
+	assert.Error(t, (&Model{Graph: &Graph{Initializers: map[string]*Tensor{}}}).ScaleRoPE(RopeScaling{Type: RopeScalingLinear, Factor: 2}))
+}
@@ -0,0 +1,238 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"github.com/nlpodyssey/cybertron/pkg/numa"
+)
+
+// ErrMemoryLimitExceeded means that a run would have held more memory than
+// the MemoryLimit of the model. It's checked once each operator has
+// computed its outputs, so the limit is exceeded by one operator at most.
+var ErrMemoryLimitExceeded = errdefs.New(errdefs.CodeMemoryLimitExceeded, "onnx: memory limit exceeded")
+
+// ErrClosed means that the model was closed (see Model.Close).
+var ErrClosed = errors.New("onnx: model closed")
+
+// RunStats are the statistics of a run.
+type RunStats struct {
+	// PeakMemory is the maximum number of bytes of the intermediate values
+	// held at once, graph outputs included.
+	PeakMemory int64
+}
+
+// operator computes the outputs of a node, given its inputs.
+// Omitted optional inputs are nil.
+type operator func(ctx *opContext, inputs []*Tensor) ([]*Tensor, error)
+
+// opContext provides an operator with the node being executed and the
+// opset version of the model, since some operators changed over time.
+type opContext struct {
+	node        *Node
+	opset       int64
+	parallelism int
+	// numaNode is the NUMANode of the model.
+	numaNode *numa.Node
+	// kernels are the kernels selected by TuneKernels, if any.
+	kernels kernelSelection
+	device  device
+	// initializers are the initializers of the graph, whose matrices are
+	// kept resident on the device, if any.
+	initializers map[string]*Tensor
+	// attentionWindow is the AttentionWindow of the model.
+	attentionWindow int
+	// attentionFactor multiplies the scores of the fused attention, if set.
+	attentionFactor float32
+}
+
+// gemm accumulates in dst (m×n) the product of a (m×k) and b (k×n), on the
+// device of the model if it's large enough to be worth the transfers, or
+// on the CPU otherwise, with the kernel selected for its shape, if any.
+// The key of b is the one of the weight it is, if any (see weight).
+func (ctx *opContext) gemm(dst, a, b []float32, m, k, n int, key any) error {
+	if ctx.device != nil && m*k*n >= deviceMinOps {
+		return ctx.device.Sgemm(dst, a, b, m, k, n, key)
+	}
+	parallelGemm(dst, a, b, m, k, n, ctx.parallelism, ctx.numaNode, ctx.kernels.choose(m, k, n))
+	return nil
+}
+
+// weight returns the key on the device of the index-th matrix of the input
+// of the node, if it's an initializer of the graph, i.e. a weight, or nil.
+func (ctx *opContext) weight(t *Tensor, index int) any {
+	if ctx.device == nil {
+		return nil
+	}
+	for _, name := range ctx.node.Inputs {
+		if name != "" && ctx.initializers[name] == t {
+			return weightKey{t: t, index: index}
+		}
+	}
+	return nil
+}
+
+// goOn runs the function in a new goroutine, bound to the NUMA node, if
+// any, as the goroutine running the model.
+func goOn(node *numa.Node, f func()) {
+	go func() {
+		if node != nil {
+			if unbind, err := numa.Bind(*node); err == nil {
+				defer unbind()
+			}
+		}
+		f()
+	}()
+}
+
+// operators is the registry of the supported operators, by op type.
+var operators = map[string]operator{}
+
+func register(opType string, op operator) {
+	operators[opType] = op
+}
+
+// SupportedOperators returns the sorted list of the supported op types.
+func SupportedOperators() []string {
+	names := make([]string, 0, len(operators))
+	for name := range operators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks that all the operators used by the model are supported.
+func (m *Model) Validate() error {
+	for _, n := range m.Graph.Nodes {
+		if n.Domain != "" && n.Domain != "ai.onnx" {
+			return fmt.Errorf("onnx: unsupported operator domain %q (node %q)", n.Domain, n.Name)
+		}
+		if _, ok := operators[n.OpType]; !ok {
+			return fmt.Errorf("onnx: unsupported operator %q (node %q)", n.OpType, n.Name)
+		}
+	}
+	return nil
+}
+
+// Run executes the graph with the given inputs, and returns the graph outputs.
+//
+// The nodes are executed sequentially in the order they appear in the
+// graph, which the ONNX specification requires to be topologically sorted.
+// Intermediate values are released as soon as they are no longer needed,
+// and their buffers reused by the next values, or runs (see pool.go).
+func (m *Model) Run(inputs map[string]*Tensor) (map[string]*Tensor, error) {
+	outputs, _, err := m.RunWithStats(inputs)
+	return outputs, err
+}
+
+// RunWithStats is like Run, and also returns the statistics of the run,
+// even if it failed.
+func (m *Model) RunWithStats(inputs map[string]*Tensor) (_ map[string]*Tensor, stats RunStats, _ error) {
+	g := m.Graph
+	if g == nil {
+		return nil, stats, ErrClosed
+	}
+	values := make(map[string]*Tensor, len(g.Initializers)+len(inputs))
+	for name, t := range g.Initializers {
+		values[name] = t
+	}
+	for _, name := range g.Inputs {
+		t, ok := inputs[name]
+		if !ok {
+			return nil, stats, fmt.Errorf("onnx: missing input %q", name)
+		}
+		values[name] = t
+	}
+
+	refs := newBufferRefs(m, inputs)
+	lastUse := m.lastUses()
+	for i, n := range g.Nodes {
+		op, ok := operators[n.OpType]
+		if !ok {
+			return nil, stats, fmt.Errorf("onnx: unsupported operator %q (node %q)", n.OpType, n.Name)
+		}
+		args := make([]*Tensor, len(n.Inputs))
+		for j, name := range n.Inputs {
+			if name == "" {
+				continue
+			}
+			t, ok := values[name]
+			if !ok {
+				return nil, stats, fmt.Errorf("onnx: node %q: undefined value %q", n.Name, name)
+			}
+			args[j] = t
+		}
+		ctx := &opContext{node: n, opset: m.Opset, parallelism: m.Parallelism, numaNode: m.NUMANode, kernels: m.kernels, device: m.device,
+			initializers: g.Initializers, attentionWindow: m.AttentionWindow, attentionFactor: m.attentionFactor}
+		results, err := op(ctx, args)
+		if err != nil {
+			return nil, stats, fmt.Errorf("onnx: node %q (%s): %w", n.Name, n.OpType, err)
+		}
+		for j, name := range n.Outputs {
+			if name != "" && j < len(results) {
+				values[name] = results[j]
+				refs.retain(results[j])
+			}
+		}
+		stats.PeakMemory = refs.peak
+		if m.MemoryLimit > 0 && refs.live > m.MemoryLimit {
+			return nil, stats, fmt.Errorf("%w: %d > %d bytes at node %q (%s)", ErrMemoryLimitExceeded, refs.live, m.MemoryLimit, n.Name, n.OpType)
+		}
+		for _, name := range n.Inputs {
+			if last, ok := lastUse[name]; ok && last == i {
+				if t, ok := values[name]; ok {
+					delete(values, name)
+					refs.release(t)
+				}
+			}
+		}
+	}
+
+	outputs := make(map[string]*Tensor, len(g.Outputs))
+	for _, name := range g.Outputs {
+		t, ok := values[name]
+		if !ok {
+			return nil, stats, fmt.Errorf("onnx: output %q was not computed", name)
+		}
+		outputs[name] = t
+	}
+	return outputs, stats, nil
+}
+
+// Close releases the graph of the model, with its weights, and the
+// kernels selected for it, so that their memory is freed even if the model
+// is still referenced; the runs following it fail with ErrClosed. It must
+// not be called while the model is running.
+func (m *Model) Close() error {
+	m.Graph = nil
+	m.kernels = nil
+	m.ropeFrequencies = nil
+	return m.closeDevice()
+}
+
+// lastUses returns, for each intermediate value, the index of the last node
+// using it. Initializers and graph outputs are never released.
+func (m *Model) lastUses() map[string]int {
+	g := m.Graph
+	keep := make(map[string]bool, len(g.Outputs))
+	for _, name := range g.Outputs {
+		keep[name] = true
+	}
+	last := make(map[string]int)
+	for i, n := range g.Nodes {
+		for _, name := range n.Inputs {
+			if _, isInit := g.Initializers[name]; isInit || keep[name] || name == "" {
+				continue
+			}
+			last[name] = i
+		}
+	}
+	return last
+}
@@ -0,0 +1,347 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
+)
+
+// DataType is the element type of a Tensor.
+//
+// The runtime keeps floating-point values as float32, and integer and
+// boolean values as int64, so that only three types have to be handled by
+// the operators, regardless of the type declared in the ONNX file.
+type DataType int
+
+const (
+	// Float is the type of float32 tensors.
+	Float DataType = iota
+	// Int is the type of int64 tensors.
+	Int
+	// Bool is the type of boolean tensors.
+	Bool
+)
+
+// ONNX TensorProto.DataType values.
+const (
+	protoFloat   = 1
+	protoUint8   = 2
+	protoInt8    = 3
+	protoUint16  = 4
+	protoInt16   = 5
+	protoInt32   = 6
+	protoInt64   = 7
+	protoBool    = 9
+	protoFloat16 = 10
+	protoDouble  = 11
+	protoUint32  = 12
+	protoUint64  = 13
+)
+
+// Tensor is a multidimensional array stored in row-major order.
+type Tensor struct {
+	// Type is the element type.
+	Type DataType
+	// Shape is the size of each dimension.
+	Shape []int
+	// Floats holds the data of Float tensors.
+	Floats []float32
+	// Ints holds the data of Int and Bool tensors.
+	Ints []int64
+}
+
+// NewFloatTensor returns a new Float tensor with the given shape and data.
+func NewFloatTensor(shape []int, data []float32) *Tensor {
+	return &Tensor{Type: Float, Shape: shape, Floats: data}
+}
+
+// NewIntTensor returns a new Int tensor with the given shape and data.
+func NewIntTensor(shape []int, data []int64) *Tensor {
+	return &Tensor{Type: Int, Shape: shape, Ints: data}
+}
+
+// newTensor returns a new zero-filled tensor of the given type and shape.
+func newTensor(t DataType, shape []int) *Tensor {
+	n := shapeSize(shape)
+	if t == Float {
+		return &Tensor{Type: Float, Shape: shape, Floats: allocFloats(n)}
+	}
+	return &Tensor{Type: t, Shape: shape, Ints: make([]int64, n)}
+}
+
+// Size returns the number of elements of the tensor.
+func (t *Tensor) Size() int {
+	return shapeSize(t.Shape)
+}
+
+// Rank returns the number of dimensions of the tensor.
+func (t *Tensor) Rank() int {
+	return len(t.Shape)
+}
+
+// float returns the i-th element as float32.
+func (t *Tensor) float(i int) float32 {
+	if t.Type == Float {
+		return t.Floats[i]
+	}
+	return float32(t.Ints[i])
+}
+
+// int returns the i-th element as int64.
+func (t *Tensor) int(i int) int64 {
+	if t.Type == Float {
+		return int64(t.Floats[i])
+	}
+	return t.Ints[i]
+}
+
+// AsFloats returns the elements of the tensor as float32, converting them if needed.
+func (t *Tensor) AsFloats() []float32 {
+	if t.Type == Float {
+		return t.Floats
+	}
+	out := make([]float32, len(t.Ints))
+	for i, v := range t.Ints {
+		out[i] = float32(v)
+	}
+	return out
+}
+
+// AsInts returns the elements of the tensor as int64, converting them if needed.
+func (t *Tensor) AsInts() []int64 {
+	if t.Type != Float {
+		return t.Ints
+	}
+	out := make([]int64, len(t.Floats))
+	for i, v := range t.Floats {
+		out[i] = int64(v)
+	}
+	return out
+}
+
+// cast returns the tensor converted to the given type.
+func (t *Tensor) cast(to DataType) *Tensor {
+	switch {
+	case t.Type == to:
+		return t
+	case to == Float:
+		return &Tensor{Type: Float, Shape: t.Shape, Floats: t.AsFloats()}
+	case to == Bool:
+		out := &Tensor{Type: Bool, Shape: t.Shape, Ints: make([]int64, t.Size())}
+		for i := range out.Ints {
+			if t.float(i) != 0 {
+				out.Ints[i] = 1
+			}
+		}
+		return out
+	default:
+		return &Tensor{Type: to, Shape: t.Shape, Ints: t.AsInts()}
+	}
+}
+
+// reshaped returns a tensor sharing the data of t, with a different shape.
+func (t *Tensor) reshaped(shape []int) *Tensor {
+	return &Tensor{Type: t.Type, Shape: shape, Floats: t.Floats, Ints: t.Ints}
+}
+
+func (t *Tensor) String() string {
+	if t.Type == Float {
+		return fmt.Sprintf("Tensor(float, %v)", t.Shape)
+	}
+	return fmt.Sprintf("Tensor(int, %v)", t.Shape)
+}
+
+func shapeSize(shape []int) int {
+	n := 1
+	for _, d := range shape {
+		n *= d
+	}
+	return n
+}
+
+// strides returns the row-major strides of the given shape.
+func strides(shape []int) []int {
+	s := make([]int, len(shape))
+	acc := 1
+	for i := len(shape) - 1; i >= 0; i-- {
+		s[i] = acc
+		acc *= shape[i]
+	}
+	return s
+}
+
+// decodeTensor decodes a TensorProto message.
+func decodeTensor(b []byte) (name string, _ *Tensor, err error) {
+	var (
+		dims     []int64
+		dataType int64
+		floats   []float32
+		doubles  []float64
+		ints     []int64
+		raw      []byte
+		external bool
+	)
+	r := &wireReader{buf: b}
+	for !r.done() {
+		field, wireType, err := r.tag()
+		if err != nil {
+			return "", nil, err
+		}
+		switch field {
+		case 1:
+			dims, err = r.int64s(wireType, dims)
+		case 2:
+			var v uint64
+			v, err = r.varint()
+			dataType = int64(v)
+		case 4:
+			floats, err = r.float32s(wireType, floats)
+		case 5, 7, 11:
+			ints, err = r.int64s(wireType, ints)
+		case 8:
+			name, err = r.string()
+		case 9:
+			raw, err = r.bytes()
+		case 10:
+			doubles, err = r.float64s(wireType, doubles)
+		case 14:
+			var v uint64
+			v, err = r.varint()
+			external = v == 1
+		default:
+			err = r.skip(wireType)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	if external {
+		return "", nil, fmt.Errorf("onnx: tensor %q: external data is not supported", name)
+	}
+
+	shape := make([]int, len(dims))
+	for i, d := range dims {
+		shape[i] = int(d)
+	}
+	n := shapeSize(shape)
+
+	switch dataType {
+	case protoFloat, protoDouble, protoFloat16:
+		t := &Tensor{Type: Float, Shape: shape}
+		switch {
+		case raw != nil:
+			t.Floats, err = rawFloats(raw, dataType, n)
+		case dataType == protoDouble:
+			t.Floats = make([]float32, len(doubles))
+			for i, v := range doubles {
+				t.Floats[i] = float32(v)
+			}
+		case dataType == protoFloat16:
+			t.Floats = make([]float32, len(ints))
+			for i, v := range ints {
+				t.Floats[i] = quantization.Float16ToFloat32(uint16(v))
+			}
+		default:
+			t.Floats = floats
+		}
+		if err == nil && len(t.Floats) != n {
+			err = fmt.Errorf("onnx: tensor %q: expected %d values, got %d", name, n, len(t.Floats))
+		}
+		return name, t, err
+	case protoUint8, protoInt8, protoUint16, protoInt16, protoInt32, protoInt64, protoUint32, protoUint64, protoBool:
+		t := &Tensor{Type: Int, Shape: shape, Ints: ints}
+		if dataType == protoBool {
+			t.Type = Bool
+		}
+		if raw != nil {
+			t.Ints, err = rawInts(raw, dataType, n)
+		}
+		if err == nil && len(t.Ints) != n {
+			err = fmt.Errorf("onnx: tensor %q: expected %d values, got %d", name, n, len(t.Ints))
+		}
+		return name, t, err
+	default:
+		return "", nil, fmt.Errorf("onnx: tensor %q: unsupported data type %d", name, dataType)
+	}
+}
+
+func rawFloats(raw []byte, dataType int64, n int) ([]float32, error) {
+	out := make([]float32, n)
+	switch dataType {
+	case protoFloat:
+		if len(raw) != n*4 {
+			return nil, errTruncated
+		}
+		for i := range out {
+			out[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[i*4:]))
+		}
+	case protoDouble:
+		if len(raw) != n*8 {
+			return nil, errTruncated
+		}
+		for i := range out {
+			out[i] = float32(math.Float64frombits(binary.LittleEndian.Uint64(raw[i*8:])))
+		}
+	case protoFloat16:
+		if len(raw) != n*2 {
+			return nil, errTruncated
+		}
+		for i := range out {
+			out[i] = quantization.Float16ToFloat32(binary.LittleEndian.Uint16(raw[i*2:]))
+		}
+	}
+	return out, nil
+}
+
+func rawInts(raw []byte, dataType int64, n int) ([]int64, error) {
+	size := map[int64]int{
+		protoUint8: 1, protoInt8: 1, protoBool: 1,
+		protoUint16: 2, protoInt16: 2,
+		protoInt32: 4, protoUint32: 4,
+		protoInt64: 8, protoUint64: 8,
+	}[dataType]
+	if len(raw) != n*size {
+		return nil, errTruncated
+	}
+	out := make([]int64, n)
+	for i := range out {
+		b := raw[i*size:]
+		switch dataType {
+		case protoUint8, protoBool:
+			out[i] = int64(b[0])
+		case protoInt8:
+			out[i] = int64(int8(b[0]))
+		case protoUint16:
+			out[i] = int64(binary.LittleEndian.Uint16(b))
+		case protoInt16:
+			out[i] = int64(int16(binary.LittleEndian.Uint16(b)))
+		case protoInt32:
+			out[i] = int64(int32(binary.LittleEndian.Uint32(b)))
+		case protoUint32:
+			out[i] = int64(binary.LittleEndian.Uint32(b))
+		default:
+			out[i] = int64(binary.LittleEndian.Uint64(b))
+		}
+	}
+	return out, nil
+}
+
+// dataTypeFromProto maps an ONNX TensorProto.DataType to the runtime type.
+func dataTypeFromProto(v int64) (DataType, error) {
+	switch v {
+	case protoFloat, protoDouble, protoFloat16:
+		return Float, nil
+	case protoBool:
+		return Bool, nil
+	case protoUint8, protoInt8, protoUint16, protoInt16, protoInt32, protoInt64, protoUint32, protoUint64:
+		return Int, nil
+	default:
+		return 0, fmt.Errorf("onnx: unsupported data type %d", v)
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/numa"
+)
+
+// DefaultKernelTuningFilename is the default filename of the kernels
+// selected by TuneKernels, inside the model's directory.
+const DefaultKernelTuningFilename = "kernels.json"
+
+// Kernel is a variant of the kernels computing the matrix products.
+type Kernel string
+
+const (
+	// KernelGo is the pure Go kernel.
+	KernelGo Kernel = "go"
+	// KernelSIMD is the kernel vectorized with the instruction set of the
+	// CPU (see KernelsISA).
+	KernelSIMD Kernel = "simd"
+	// KernelBLAS is the BLAS library (see UsesBLAS).
+	KernelBLAS Kernel = "blas"
+)
+
+// Kernels returns the kernels of the matrix products available on the
+// machine, with the runtime built.
+func Kernels() []Kernel {
+	kernels := []Kernel{KernelGo}
+	if KernelsISA() != "generic" {
+		kernels = append(kernels, KernelSIMD)
+	}
+	if blasGemm != nil {
+		kernels = append(kernels, KernelBLAS)
+	}
+	return kernels
+}
+
+// tuneRows are the numbers of rows of the products benchmarked for each
+// shape of the weights: a single token, and the tokens of a short and of a
+// long input. The products use the kernel of the largest number of rows not
+// above theirs.
+var tuneRows = []int{1, 16, 128}
+
+// tuneOps is the minimum number of multiply-adds timed by the benchmark of
+// a kernel, repeating the small products up to tuneMaxReps times.
+const (
+	tuneOps     = 1 << 24
+	tuneMaxReps = 100
+)
+
+// gemmShape is the shape of a matrix product: (rows×inner)·(inner×columns).
+type gemmShape struct {
+	rows, inner, columns int
+}
+
+// kernelSelection maps the shapes of the matrix products to their fastest
+// kernel, by the rows in tuneRows.
+type kernelSelection map[gemmShape]Kernel
+
+// choose returns the kernel of the product of the shape, or the empty
+// kernel (see parallelGemm) if its shape wasn't tuned.
+func (s kernelSelection) choose(m, k, n int) Kernel {
+	if len(s) == 0 {
+		return ""
+	}
+	rows := tuneRows[0]
+	for _, r := range tuneRows {
+		if r <= m {
+			rows = r
+		}
+	}
+	return s[gemmShape{rows: rows, inner: k, columns: n}]
+}
+
+// KernelTuning is the fastest kernel of each shape of the matrix products
+// of a model on a machine, as written in the cache file of TuneKernels.
+type KernelTuning struct {
+	// Machine identifies the machine, the build of the runtime and the
+	// parallelism the kernels were benchmarked with.
+	Machine string `json:"machine"`
+	// Choices are the kernels by shape.
+	Choices []KernelChoice `json:"choices"`
+}
+
+// KernelChoice is the fastest kernel of the matrix products of a shape.
+type KernelChoice struct {
+	// Rows is the number of rows of the benchmarked product; the kernel is
+	// used by the products with as many rows, up to the next tuned number.
+	Rows int `json:"rows"`
+	// Inner is the number of rows of the weights.
+	Inner int `json:"inner"`
+	// Columns is the number of columns of the weights.
+	Columns int `json:"columns"`
+	// Kernel is the fastest kernel.
+	Kernel Kernel `json:"kernel"`
+	// Duration is the time of a product with the kernel.
+	Duration time.Duration `json:"duration"`
+}
+
+// selection returns the kernels of the choices available on the machine.
+func (t KernelTuning) selection() kernelSelection {
+	available := map[Kernel]bool{}
+	for _, k := range Kernels() {
+		available[k] = true
+	}
+	s := kernelSelection{}
+	for _, c := range t.Choices {
+		if available[c.Kernel] {
+			s[gemmShape{rows: c.Rows, inner: c.Inner, columns: c.Columns}] = c.Kernel
+		}
+	}
+	return s
+}
+
+// TuneKernels selects the fastest kernel (see Kernels) of each shape of the
+// matrix products of the weights of the model on this machine, instead of
+// the global choice of BLAS for the large products: each is benchmarked
+// with the Parallelism of the model, on the numbers of rows of a single
+// token, and of a short and a long input. The selection is read from the
+// cache file, if any, if made on the same machine, and written to it
+// otherwise. If the file can't be written, the selection is made anyway,
+// and the error returned.
+func (m *Model) TuneKernels(cacheFile string) (cached bool, _ error) {
+	machine := machineID(m.Parallelism)
+	if t, err := readKernelTuning(cacheFile); err == nil && t.Machine == machine {
+		m.kernels = t.selection()
+		return true, nil
+	}
+	t := KernelTuning{Machine: machine, Choices: m.benchmarkKernels()}
+	m.kernels = t.selection()
+	if cacheFile == "" {
+		return false, nil
+	}
+	return false, writeKernelTuning(cacheFile, t)
+}
+
+// SelectedKernels returns the kernels selected by TuneKernels, by shape.
+func (m *Model) SelectedKernels() []KernelChoice {
+	choices := make([]KernelChoice, 0, len(m.kernels))
+	for s, k := range m.kernels {
+		choices = append(choices, KernelChoice{Rows: s.rows, Inner: s.inner, Columns: s.columns, Kernel: k})
+	}
+	sortChoices(choices)
+	return choices
+}
+
+// benchmarkKernels returns the fastest kernel of each shape of the weights
+// of the matrix products.
+func (m *Model) benchmarkKernels() []KernelChoice {
+	kernels := Kernels()
+	var choices []KernelChoice
+	for _, w := range m.weightShapes() {
+		for _, rows := range tuneRows {
+			c := KernelChoice{Rows: rows, Inner: w[0], Columns: w[1]}
+			for _, k := range kernels {
+				d := benchmarkGemm(k, rows, w[0], w[1], m.Parallelism, m.NUMANode)
+				if c.Kernel == "" || d < c.Duration {
+					c.Kernel, c.Duration = k, d
+				}
+			}
+			choices = append(choices, c)
+		}
+	}
+	sortChoices(choices)
+	return choices
+}
+
+// weightShapes returns the distinct shapes (inner×columns) of the weights
+// of the matrix products, i.e. their second operand, if an initializer.
+func (m *Model) weightShapes() [][2]int {
+	seen := map[[2]int]bool{}
+	var shapes [][2]int
+	for _, n := range m.Graph.Nodes {
+		if (n.OpType != "MatMul" && n.OpType != "Gemm") || len(n.Inputs) < 2 {
+			continue
+		}
+		w, ok := m.Graph.Initializers[n.Inputs[1]]
+		if !ok || w.Rank() < 2 || w.Size() == 0 {
+			continue
+		}
+		s := [2]int{w.Shape[w.Rank()-2], w.Shape[w.Rank()-1]}
+		if n.OpType == "Gemm" && n.intAttr("transB", 0) != 0 {
+			s[0], s[1] = s[1], s[0]
+		}
+		if !seen[s] {
+			seen[s] = true
+			shapes = append(shapes, s)
+		}
+	}
+	return shapes
+}
+
+// benchmarkGemm returns the time of a product of the shape with the
+// kernel, on the NUMA node if any: the best of its repetitions (see
+// tuneOps).
+func benchmarkGemm(kernel Kernel, m, k, n, parallelism int, node *numa.Node) time.Duration {
+	a, b, dst := make([]float32, m*k), make([]float32, k*n), make([]float32, m*n)
+	// The products skip the zeros of a, so none is.
+	for i := range a {
+		a[i] = float32(i%7+1) / 8
+	}
+	for i := range b {
+		b[i] = float32(i%5+1) / 8
+	}
+	reps := tuneOps / (m * k * n)
+	if reps < 1 {
+		reps = 1
+	} else if reps > tuneMaxReps {
+		reps = tuneMaxReps
+	}
+	parallelGemm(dst, a, b, m, k, n, parallelism, node, kernel)
+	best := time.Duration(1<<63 - 1)
+	for i := 0; i < reps; i++ {
+		start := time.Now()
+		parallelGemm(dst, a, b, m, k, n, parallelism, node, kernel)
+		if d := time.Since(start); d < best {
+			best = d
+		}
+	}
+	return best
+}
+
+// machineID identifies the machine, the build of the runtime and the
+// parallelism of the products, the kernels being tuned for all of them.
+func machineID(parallelism int) string {
+	host, _ := os.Hostname()
+	kernels := make([]string, 0, 3)
+	for _, k := range Kernels() {
+		kernels = append(kernels, string(k))
+	}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	return fmt.Sprintf("%s %s/%s cpus=%d isa=%s kernels=%s parallelism=%d",
+		host, runtime.GOOS, runtime.GOARCH, runtime.NumCPU(), KernelsISA(), strings.Join(kernels, ","), parallelism)
+}
+
+func sortChoices(choices []KernelChoice) {
+	sort.Slice(choices, func(i, j int) bool {
+		a, b := choices[i], choices[j]
+		if a.Inner != b.Inner {
+			return a.Inner < b.Inner
+		}
+		if a.Columns != b.Columns {
+			return a.Columns < b.Columns
+		}
+		return a.Rows < b.Rows
+	})
+}
+
+func readKernelTuning(filename string) (KernelTuning, error) {
+	var t KernelTuning
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return t, err
+	}
+	return t, json.Unmarshal(data, &t)
+}
+
+// writeKernelTuning writes the file atomically, since the model directory
+// can be shared by several processes.
+func writeKernelTuning(filename string, t KernelTuning) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", filename, os.Getpid())
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("onnx: failed to write the kernel tuning: %w", err)
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("onnx: failed to write the kernel tuning: %w", err)
+	}
+	return nil
+}
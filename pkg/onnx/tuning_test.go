@@ -0,0 +1,94 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallelGemm_Kernels(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	m, k, n := 7, 5, 3
+	a, b := randomFloats(r, m*k), randomFloats(r, k*n)
+	want := make([]float32, m*n)
+	gemm(want, a, b, m, k, n)
+	for _, kernel := range append(Kernels(), "") {
+		got := make([]float32, m*n)
+		parallelGemm(got, a, b, m, k, n, 2, nil, kernel)
+		assert.InDeltaSlice(t, want, got, 1e-4, string(kernel))
+	}
+}
+
+func TestKernelSelection_Choose(t *testing.T) {
+	s := kernelSelection{
+		{rows: 1, inner: 4, columns: 2}:   KernelGo,
+		{rows: 16, inner: 4, columns: 2}:  KernelSIMD,
+		{rows: 128, inner: 4, columns: 2}: KernelBLAS,
+	}
+	assert.Equal(t, KernelGo, s.choose(1, 4, 2))
+	assert.Equal(t, KernelGo, s.choose(15, 4, 2))
+	assert.Equal(t, KernelSIMD, s.choose(16, 4, 2))
+	assert.Equal(t, KernelBLAS, s.choose(1000, 4, 2))
+	assert.Equal(t, Kernel(""), s.choose(16, 4, 3))
+	assert.Equal(t, Kernel(""), kernelSelection(nil).choose(16, 4, 2))
+}
+
+func TestModel_TuneKernels(t *testing.T) {
+	newModel := func() *Model {
+		r := rand.New(rand.NewSource(1))
+		g := &Graph{
+			Nodes: []*Node{
+				{OpType: "MatMul", Inputs: []string{"x", "w"}, Outputs: []string{"h"}},
+				{OpType: "Gemm", Inputs: []string{"h", "v"}, Outputs: []string{"y"},
+					Attributes: map[string]*Attribute{"transB": {Name: "transB", Int: 1}}},
+			},
+			Initializers: map[string]*Tensor{
+				"w": NewFloatTensor([]int{8, 4}, randomFloats(r, 32)),
+				"v": NewFloatTensor([]int{2, 4}, randomFloats(r, 8)),
+			},
+			Inputs:  []string{"x"},
+			Outputs: []string{"y"},
+		}
+		return &Model{Opset: 13, Graph: g}
+	}
+	filename := filepath.Join(t.TempDir(), DefaultKernelTuningFilename)
+
+	m := newModel()
+	assert.Equal(t, [][2]int{{8, 4}, {4, 2}}, m.weightShapes())
+	cached, err := m.TuneKernels(filename)
+	require.NoError(t, err)
+	assert.False(t, cached)
+	choices := m.SelectedKernels()
+	assert.Len(t, choices, 2*len(tuneRows))
+	assert.Equal(t, KernelChoice{Rows: 1, Inner: 4, Columns: 2, Kernel: choices[0].Kernel}, choices[0])
+	assert.Contains(t, Kernels(), choices[0].Kernel)
+
+	// The selection is read from the cache file.
+	m2 := newModel()
+	cached, err = m2.TuneKernels(filename)
+	require.NoError(t, err)
+	assert.True(t, cached)
+	assert.Equal(t, choices, m2.SelectedKernels())
+
+	// The tuned model computes the same outputs.
+	x := NewFloatTensor([]int{3, 8}, randomFloats(rand.New(rand.NewSource(2)), 24))
+	want, err := newModel().Run(map[string]*Tensor{"x": x})
+	require.NoError(t, err)
+	got, err := m2.Run(map[string]*Tensor{"x": x})
+	require.NoError(t, err)
+	assert.InDeltaSlice(t, want["y"].Floats, got["y"].Floats, 1e-4)
+
+	// The selection made with another parallelism isn't reused.
+	m3 := newModel()
+	m3.Parallelism = 4
+	cached, err = m3.TuneKernels(filename)
+	require.NoError(t, err)
+	assert.False(t, cached)
+}
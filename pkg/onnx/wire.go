@@ -0,0 +1,163 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Protocol Buffers wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("onnx: truncated protobuf message")
+
+// wireReader is a minimal Protocol Buffers decoder, sufficient to read
+// the messages of the ONNX format without depending on generated code.
+type wireReader struct {
+	buf []byte
+}
+
+func (r *wireReader) done() bool {
+	return len(r.buf) == 0
+}
+
+func (r *wireReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	r.buf = r.buf[n:]
+	return v, nil
+}
+
+// tag returns the field number and the wire type of the next field.
+func (r *wireReader) tag() (int, int, error) {
+	v, err := r.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(v >> 3), int(v & 7), nil
+}
+
+func (r *wireReader) bytes() ([]byte, error) {
+	l, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(r.buf)) < l {
+		return nil, errTruncated
+	}
+	b := r.buf[:l]
+	r.buf = r.buf[l:]
+	return b, nil
+}
+
+func (r *wireReader) fixed32() (uint32, error) {
+	if len(r.buf) < 4 {
+		return 0, errTruncated
+	}
+	v := binary.LittleEndian.Uint32(r.buf)
+	r.buf = r.buf[4:]
+	return v, nil
+}
+
+func (r *wireReader) fixed64() (uint64, error) {
+	if len(r.buf) < 8 {
+		return 0, errTruncated
+	}
+	v := binary.LittleEndian.Uint64(r.buf)
+	r.buf = r.buf[8:]
+	return v, nil
+}
+
+func (r *wireReader) string() (string, error) {
+	b, err := r.bytes()
+	return string(b), err
+}
+
+// skip discards the value of a field of the given wire type.
+func (r *wireReader) skip(wireType int) error {
+	var err error
+	switch wireType {
+	case wireVarint:
+		_, err = r.varint()
+	case wireFixed64:
+		_, err = r.fixed64()
+	case wireBytes:
+		_, err = r.bytes()
+	case wireFixed32:
+		_, err = r.fixed32()
+	default:
+		err = fmt.Errorf("onnx: unsupported protobuf wire type %d", wireType)
+	}
+	return err
+}
+
+// int64s reads a repeated int64 field, either packed or not.
+func (r *wireReader) int64s(wireType int, dst []int64) ([]int64, error) {
+	if wireType == wireVarint {
+		v, err := r.varint()
+		return append(dst, int64(v)), err
+	}
+	b, err := r.bytes()
+	if err != nil {
+		return nil, err
+	}
+	packed := &wireReader{buf: b}
+	for !packed.done() {
+		v, err := packed.varint()
+		if err != nil {
+			return nil, err
+		}
+		dst = append(dst, int64(v))
+	}
+	return dst, nil
+}
+
+// float32s reads a repeated float field, either packed or not.
+func (r *wireReader) float32s(wireType int, dst []float32) ([]float32, error) {
+	if wireType == wireFixed32 {
+		v, err := r.fixed32()
+		return append(dst, math.Float32frombits(v)), err
+	}
+	b, err := r.bytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(b)%4 != 0 {
+		return nil, errTruncated
+	}
+	for i := 0; i < len(b); i += 4 {
+		dst = append(dst, math.Float32frombits(binary.LittleEndian.Uint32(b[i:])))
+	}
+	return dst, nil
+}
+
+// float64s reads a repeated double field, either packed or not.
+func (r *wireReader) float64s(wireType int, dst []float64) ([]float64, error) {
+	if wireType == wireFixed64 {
+		v, err := r.fixed64()
+		return append(dst, math.Float64frombits(v)), err
+	}
+	b, err := r.bytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(b)%8 != 0 {
+		return nil, errTruncated
+	}
+	for i := 0; i < len(b); i += 8 {
+		dst = append(dst, math.Float64frombits(binary.LittleEndian.Uint64(b[i:])))
+	}
+	return dst, nil
+}
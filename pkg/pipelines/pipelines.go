@@ -0,0 +1,186 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pipelines embeds the models of Cybertron in Go applications in a
+// few lines, without the server: Load returns a pipeline ready to use,
+// downloading and converting its model on the first use, with simple
+// methods for its task.
+//
+//	p, err := pipelines.Load("sentiment", nil)
+//	if err != nil {
+//		return err
+//	}
+//	defer p.Close()
+//	result, err := p.(*pipelines.TextClassification).Classify(ctx, "I love this movie")
+//
+// The pipelines use the default model of their name, or the one of the
+// options; the models of the tasks package can be used directly for
+// anything else.
+package pipelines
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/languagemodeling"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/postagging"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/relationextraction"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier"
+)
+
+const (
+	// DefaultModelForSentimentAnalysis is a multilingual model rating the
+	// sentiment of product reviews from "1 star" to "5 stars".
+	// Model card: https://huggingface.co/nlptown/bert-base-multilingual-uncased-sentiment
+	DefaultModelForSentimentAnalysis = "nlptown/bert-base-multilingual-uncased-sentiment"
+
+	// DefaultModelForQuestionAnswering is an English model for extractive
+	// question answering, trained on SQuAD 2.0.
+	// Model card: https://huggingface.co/deepset/bert-base-cased-squad2
+	DefaultModelForQuestionAnswering = "deepset/bert-base-cased-squad2"
+)
+
+// Pipeline is a model ready to use for a task. Its concrete type, e.g.
+// *TextClassification, has the methods of the task.
+type Pipeline interface {
+	// Task returns the task of the pipeline, e.g. "text-classification".
+	Task() string
+	// Close releases the resources of the model.
+	io.Closer
+}
+
+// Options are the options of a pipeline.
+type Options struct {
+	// Model is the name of the model (format: <org>/<model>), downloaded
+	// from the Hugging Face Hub (default the model of the pipeline).
+	Model string
+	// ModelsDir is the directory where the models are downloaded and
+	// converted (default the "cybertron/models" directory of the user
+	// cache, e.g. ~/.cache/cybertron/models on Linux).
+	ModelsDir string
+	// Config is the configuration of the loading of the model, for the
+	// advanced settings, e.g. the revision or the backend; its ModelsDir
+	// and ModelName are set from the options (optional).
+	Config *tasks.Config
+}
+
+// pipeline describes a pipeline by its name.
+type pipeline struct {
+	// task is the name of the task.
+	task string
+	// model is the default model, none if empty.
+	model string
+	// load loads the pipeline of the task.
+	load func(conf *tasks.Config, p pipeline) (Pipeline, error)
+	// generation are the options of the text generation of the text2text
+	// pipelines (default text2text.DefaultOptions).
+	generation *text2text.Options
+}
+
+// pipelines are the pipelines by name, the tasks included.
+var pipelines = map[string]pipeline{
+	"text-classification":      {task: "text-classification", load: loadTextClassification},
+	"sentiment":                {task: "text-classification", model: DefaultModelForSentimentAnalysis, load: loadTextClassification},
+	"zero-shot-classification": {task: "zero-shot-classification", model: zeroshotclassifier.DefaultModel, load: loadZeroShotClassification},
+	"question-answering":       {task: "question-answering", model: DefaultModelForQuestionAnswering, load: loadQuestionAnswering},
+	"token-classification":     {task: "token-classification", model: tokenclassification.DefaultEnglishModel, load: loadTokenClassification},
+	"ner":                      {task: "token-classification", model: tokenclassification.DefaultEnglishModel, load: loadTokenClassification},
+	"text-encoding":            {task: "text-encoding", model: textencoding.DefaultModel, load: loadTextEncoding},
+	"feature-extraction":       {task: "text-encoding", model: textencoding.DefaultModel, load: loadTextEncoding},
+	"language-modeling":        {task: "language-modeling", model: languagemodeling.DefaultModel, load: loadLanguageModeling},
+	"coreference-resolution":   {task: "coreference-resolution", load: loadCoreferenceResolution},
+	"relation-extraction":      {task: "relation-extraction", model: relationextraction.DefaultModel, load: loadRelationExtraction},
+	"sentence-segmentation":    {task: "sentence-segmentation", model: tasks.RuleBasedModel, load: loadSentenceSegmentation},
+	"pos-tagging":              {task: "pos-tagging", model: postagging.DefaultModel, load: loadPOSTagging},
+	"fill-mask":                {task: "language-modeling", model: languagemodeling.DefaultModel, load: loadLanguageModeling},
+	"text2text":                {task: "text2text", load: loadText2Text},
+	"summarization":            {task: "text2text", model: text2text.DefaultModelForTextSummarization, load: loadText2Text},
+	"paraphrase":               {task: "text2text", model: text2text.DefaultModelForTextParaphrasing, load: loadText2Text, generation: text2text.DefaultOptionsForTextParaphrasing()},
+}
+
+// translationName matches the names of the translation pipelines, e.g.
+// "translation_en_to_it", with the source and target languages.
+var translationName = regexp.MustCompile(`^translation_([a-z]{2,3})_to_([a-z]{2,3})$`)
+
+// Names returns the names of the pipelines, sorted, the translation ones
+// excluded, since they're named after any pair of languages, e.g.
+// "translation_en_to_it".
+func Names() []string {
+	names := make([]string, 0, len(pipelines))
+	for name := range pipelines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookup returns the pipeline with the name.
+func lookup(name string) (pipeline, error) {
+	if p, ok := pipelines[name]; ok {
+		return p, nil
+	}
+	if m := translationName.FindStringSubmatch(name); m != nil {
+		return pipeline{
+			task:  "text2text",
+			model: fmt.Sprintf(text2text.DefaultModelTemplateForMachineTranslation, m[1], m[2]),
+			load:  loadText2Text,
+		}, nil
+	}
+	return pipeline{}, fmt.Errorf("unknown pipeline %#v", name)
+}
+
+// Load returns the pipeline with the name, e.g. "sentiment" or
+// "translation_en_to_it" (see Names), with its model loaded, downloading
+// and converting it first if missing. The options can be nil.
+func Load(name string, opts *Options) (Pipeline, error) {
+	p, err := lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	conf, err := p.config(opts)
+	if err != nil {
+		return nil, fmt.Errorf("pipeline %#v: %w", name, err)
+	}
+	pl, err := p.load(conf, p)
+	if err != nil {
+		return nil, fmt.Errorf("pipeline %#v: %w", name, err)
+	}
+	return pl, nil
+}
+
+// config returns the configuration of the loading of the model of the
+// pipeline.
+func (p pipeline) config(opts *Options) (*tasks.Config, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	var conf tasks.Config
+	if opts.Config != nil {
+		conf = *opts.Config
+	}
+	conf.ModelName = opts.Model
+	if conf.ModelName == "" {
+		conf.ModelName = p.model
+	}
+	if conf.ModelName == "" {
+		return nil, fmt.Errorf("no default model for the %s task: the model must be set", p.task)
+	}
+	conf.ModelsDir = opts.ModelsDir
+	if conf.ModelsDir == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the models directory: %w", err)
+		}
+		conf.ModelsDir = filepath.Join(dir, "cybertron", "models")
+	}
+	return &conf, nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pipelines
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	p, err := lookup("sentiment")
+	require.NoError(t, err)
+	assert.Equal(t, "text-classification", p.task)
+	assert.Equal(t, DefaultModelForSentimentAnalysis, p.model)
+
+	p, err = lookup("translation_en_to_it")
+	require.NoError(t, err)
+	assert.Equal(t, "text2text", p.task)
+	assert.Equal(t, "Helsinki-NLP/opus-mt-en-it", p.model)
+
+	_, err = lookup("translation_en")
+	assert.Error(t, err)
+	_, err = lookup("unknown")
+	assert.Error(t, err)
+
+	for _, name := range Names() {
+		_, err := lookup(name)
+		assert.NoError(t, err, name)
+	}
+}
+
+func TestPipeline_config(t *testing.T) {
+	p, err := lookup("ner")
+	require.NoError(t, err)
+
+	conf, err := p.config(nil)
+	require.NoError(t, err)
+	assert.Equal(t, p.model, conf.ModelName)
+	assert.NotEmpty(t, conf.ModelsDir)
+
+	conf, err = p.config(&Options{
+		Model:     "org/model",
+		ModelsDir: "models",
+		Config:    &tasks.Config{ModelName: "ignored", Revision: "v1.0"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "org/model", conf.ModelName)
+	assert.Equal(t, "models", conf.ModelsDir)
+	assert.Equal(t, "v1.0", conf.Revision)
+
+	_, err = Load("text-classification", &Options{ModelsDir: "models"})
+	assert.ErrorContains(t, err, "the model must be set")
+}
+
+func TestLoad_ruleBased(t *testing.T) {
+	p, err := Load("sentence-segmentation", &Options{ModelsDir: t.TempDir()})
+	require.NoError(t, err)
+	defer p.Close()
+
+	sentences, err := p.(*SentenceSegmentation).Segment(context.Background(), "Dr. Smith arrived. He sat down.")
+	require.NoError(t, err)
+	require.Len(t, sentences, 2)
+	assert.Equal(t, "Dr. Smith arrived.", sentences[0].Text)
+}
@@ -0,0 +1,370 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pipelines
+
+import (
+	"context"
+
+	"github.com/nlpodyssey/cybertron/pkg/models/bert/bertconfig"
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/coreference"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/languagemodeling"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/postagging"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/relationextraction"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier"
+	"github.com/nlpodyssey/cybertron/pkg/utils/matview"
+)
+
+// TextClassification is the pipeline of the text classification, e.g. of
+// the sentiment analysis.
+type TextClassification struct {
+	// Model is the model of the pipeline, for the advanced usages.
+	Model textclassification.Interface
+}
+
+func loadTextClassification(conf *tasks.Config, _ pipeline) (Pipeline, error) {
+	m, err := tasks.Load[textclassification.Interface](conf)
+	if err != nil {
+		return nil, err
+	}
+	return &TextClassification{Model: m}, nil
+}
+
+// Task returns "text-classification".
+func (p *TextClassification) Task() string { return "text-classification" }
+
+// Close releases the resources of the model.
+func (p *TextClassification) Close() error { return tasks.Close(p.Model) }
+
+// Classify returns the labels of the text, sorted by descending score.
+func (p *TextClassification) Classify(ctx context.Context, text string) (textclassification.Response, error) {
+	return p.Model.Classify(ctx, text, textclassification.Parameters{})
+}
+
+// ClassifyPair returns the labels of the pair of texts, e.g. a premise and
+// its hypothesis, or a query and a passage, sorted by descending score.
+func (p *TextClassification) ClassifyPair(ctx context.Context, text, pair string) (textclassification.Response, error) {
+	return p.Model.Classify(ctx, text, textclassification.Parameters{TextPair: pair})
+}
+
+// ZeroShotClassification is the pipeline of the zero-shot classification.
+type ZeroShotClassification struct {
+	// Model is the model of the pipeline, for the advanced usages.
+	Model zeroshotclassifier.Interface
+}
+
+func loadZeroShotClassification(conf *tasks.Config, _ pipeline) (Pipeline, error) {
+	m, err := tasks.Load[zeroshotclassifier.Interface](conf)
+	if err != nil {
+		return nil, err
+	}
+	return &ZeroShotClassification{Model: m}, nil
+}
+
+// Task returns "zero-shot-classification".
+func (p *ZeroShotClassification) Task() string { return "zero-shot-classification" }
+
+// Close releases the resources of the model.
+func (p *ZeroShotClassification) Close() error { return tasks.Close(p.Model) }
+
+// Classify returns the candidate labels of the text, sorted by descending
+// score, with the default hypothesis template; the labels are exclusive.
+func (p *ZeroShotClassification) Classify(ctx context.Context, text string, labels ...string) (zeroshotclassifier.Response, error) {
+	return p.Model.Classify(ctx, text, zeroshotclassifier.Parameters{
+		CandidateLabels:    labels,
+		HypothesisTemplate: zeroshotclassifier.DefaultHypothesisTemplate,
+	})
+}
+
+// QuestionAnswering is the pipeline of the extractive question answering.
+type QuestionAnswering struct {
+	// Model is the model of the pipeline, for the advanced usages.
+	Model questionanswering.Interface
+}
+
+func loadQuestionAnswering(conf *tasks.Config, _ pipeline) (Pipeline, error) {
+	m, err := tasks.Load[questionanswering.Interface](conf)
+	if err != nil {
+		return nil, err
+	}
+	return &QuestionAnswering{Model: m}, nil
+}
+
+// Task returns "question-answering".
+func (p *QuestionAnswering) Task() string { return "question-answering" }
+
+// Close releases the resources of the model.
+func (p *QuestionAnswering) Close() error { return tasks.Close(p.Model) }
+
+// Answer returns the answers to the question found in the passage, sorted
+// by descending score, with the default options.
+func (p *QuestionAnswering) Answer(ctx context.Context, question, passage string) (questionanswering.Response, error) {
+	return p.Model.Answer(ctx, question, passage, &questionanswering.Options{})
+}
+
+// TokenClassification is the pipeline of the token classification, e.g.
+// of the named entity recognition.
+type TokenClassification struct {
+	// Model is the model of the pipeline, for the advanced usages.
+	Model tokenclassification.Interface
+}
+
+func loadTokenClassification(conf *tasks.Config, _ pipeline) (Pipeline, error) {
+	m, err := tasks.Load[tokenclassification.Interface](conf)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenClassification{Model: m}, nil
+}
+
+// Task returns "token-classification".
+func (p *TokenClassification) Task() string { return "token-classification" }
+
+// Close releases the resources of the model.
+func (p *TokenClassification) Close() error { return tasks.Close(p.Model) }
+
+// Classify returns the entities of the text, its tokens grouped according
+// to the IOB annotation schema.
+func (p *TokenClassification) Classify(ctx context.Context, text string) (tokenclassification.Response, error) {
+	return p.Model.Classify(ctx, text, tokenclassification.Parameters{
+		AggregationStrategy: tokenclassification.AggregationStrategySimple,
+	})
+}
+
+// ClassifyNested returns the entities of the text, possibly nested in or
+// overlapping with each other, with their depth (see
+// tokenclassification.AggregateNested).
+func (p *TokenClassification) ClassifyNested(ctx context.Context, text string) (tokenclassification.Response, error) {
+	return p.Model.Classify(ctx, text, tokenclassification.Parameters{
+		AggregationStrategy: tokenclassification.AggregationStrategyNested,
+	})
+}
+
+// TextEncoding is the pipeline of the text encoding, e.g. for the
+// semantic search.
+type TextEncoding struct {
+	// Model is the model of the pipeline, for the advanced usages.
+	Model textencoding.Interface
+}
+
+func loadTextEncoding(conf *tasks.Config, _ pipeline) (Pipeline, error) {
+	m, err := tasks.Load[textencoding.Interface](conf)
+	if err != nil {
+		return nil, err
+	}
+	return &TextEncoding{Model: m}, nil
+}
+
+// Task returns "text-encoding".
+func (p *TextEncoding) Task() string { return "text-encoding" }
+
+// Close releases the resources of the model.
+func (p *TextEncoding) Close() error { return tasks.Close(p.Model) }
+
+// Encode returns the vector of the text, the mean of the encodings of its
+// tokens, as the sentence-transformers models do.
+func (p *TextEncoding) Encode(ctx context.Context, text string) ([]float32, error) {
+	result, err := p.Model.Encode(ctx, text, int(bertconfig.MeanPooling))
+	if err != nil {
+		return nil, err
+	}
+	return matview.Float32s(result.Vector), nil
+}
+
+// LanguageModeling is the pipeline of the masked language modeling.
+type LanguageModeling struct {
+	// Model is the model of the pipeline, for the advanced usages.
+	Model languagemodeling.Interface
+}
+
+func loadLanguageModeling(conf *tasks.Config, _ pipeline) (Pipeline, error) {
+	m, err := tasks.Load[languagemodeling.Interface](conf)
+	if err != nil {
+		return nil, err
+	}
+	return &LanguageModeling{Model: m}, nil
+}
+
+// Task returns "language-modeling".
+func (p *LanguageModeling) Task() string { return "language-modeling" }
+
+// Close releases the resources of the model.
+func (p *LanguageModeling) Close() error { return tasks.Close(p.Model) }
+
+// Predict returns the most likely words of the masked tokens of the text,
+// e.g. "[MASK]" for the BERT models.
+func (p *LanguageModeling) Predict(ctx context.Context, text string) (languagemodeling.Response, error) {
+	return p.Model.Predict(ctx, text, languagemodeling.Parameters{})
+}
+
+// CoreferenceResolution is the pipeline of the coreference resolution.
+type CoreferenceResolution struct {
+	// Model is the model of the pipeline, for the advanced usages.
+	Model coreference.Interface
+}
+
+func loadCoreferenceResolution(conf *tasks.Config, _ pipeline) (Pipeline, error) {
+	m, err := tasks.Load[coreference.Interface](conf)
+	if err != nil {
+		return nil, err
+	}
+	return &CoreferenceResolution{Model: m}, nil
+}
+
+// Task returns "coreference-resolution".
+func (p *CoreferenceResolution) Task() string { return "coreference-resolution" }
+
+// Close releases the resources of the model.
+func (p *CoreferenceResolution) Close() error { return tasks.Close(p.Model) }
+
+// Resolve returns the clusters of the mentions of the same entities in the
+// text, e.g. "Alice" and "she".
+func (p *CoreferenceResolution) Resolve(ctx context.Context, text string) ([]coreference.Cluster, error) {
+	result, err := p.Model.Resolve(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	return result.Clusters, nil
+}
+
+// RelationExtraction is the pipeline of the relation extraction.
+type RelationExtraction struct {
+	// Model is the model of the pipeline, for the advanced usages.
+	Model relationextraction.Interface
+}
+
+func loadRelationExtraction(conf *tasks.Config, _ pipeline) (Pipeline, error) {
+	m, err := tasks.Load[relationextraction.Interface](conf)
+	if err != nil {
+		return nil, err
+	}
+	return &RelationExtraction{Model: m}, nil
+}
+
+// Task returns "relation-extraction".
+func (p *RelationExtraction) Task() string { return "relation-extraction" }
+
+// Close releases the resources of the model.
+func (p *RelationExtraction) Close() error { return tasks.Close(p.Model) }
+
+// Extract returns the typed relations between the entities of the text,
+// e.g. "country" from "Rome" to "Italy"; if any entities are given, by
+// their text, only the relations between them.
+func (p *RelationExtraction) Extract(ctx context.Context, text string, entities ...string) ([]relationextraction.Relation, error) {
+	params := relationextraction.Parameters{Entities: make([]relationextraction.Entity, len(entities))}
+	for i, e := range entities {
+		params.Entities[i] = relationextraction.Entity{Text: e}
+	}
+	result, err := p.Model.Extract(ctx, text, params)
+	if err != nil {
+		return nil, err
+	}
+	return result.Relations, nil
+}
+
+// SentenceSegmentation is the pipeline of the sentence segmentation.
+type SentenceSegmentation struct {
+	// Model is the model of the pipeline, for the advanced usages.
+	Model sentencesegmentation.Interface
+}
+
+func loadSentenceSegmentation(conf *tasks.Config, _ pipeline) (Pipeline, error) {
+	m, err := tasks.Load[sentencesegmentation.Interface](conf)
+	if err != nil {
+		return nil, err
+	}
+	return &SentenceSegmentation{Model: m}, nil
+}
+
+// Task returns "sentence-segmentation".
+func (p *SentenceSegmentation) Task() string { return "sentence-segmentation" }
+
+// Close releases the resources of the model.
+func (p *SentenceSegmentation) Close() error { return tasks.Close(p.Model) }
+
+// Segment returns the sentences of the text.
+func (p *SentenceSegmentation) Segment(ctx context.Context, text string) ([]sentencesegmentation.Sentence, error) {
+	result, err := p.Model.Segment(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	return result.Sentences, nil
+}
+
+// POSTagging is the pipeline of the part-of-speech tagging.
+type POSTagging struct {
+	// Model is the model of the pipeline, for the advanced usages.
+	Model postagging.Interface
+}
+
+func loadPOSTagging(conf *tasks.Config, _ pipeline) (Pipeline, error) {
+	m, err := tasks.Load[postagging.Interface](conf)
+	if err != nil {
+		return nil, err
+	}
+	return &POSTagging{Model: m}, nil
+}
+
+// Task returns "pos-tagging".
+func (p *POSTagging) Task() string { return "pos-tagging" }
+
+// Close releases the resources of the model.
+func (p *POSTagging) Close() error { return tasks.Close(p.Model) }
+
+// Tag returns the words of the text with their part-of-speech tags, e.g.
+// "NOUN" or "VERB".
+func (p *POSTagging) Tag(ctx context.Context, text string) ([]postagging.Token, error) {
+	result, err := p.Model.Tag(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	return result.Tokens, nil
+}
+
+// Text2Text is the pipeline of the text generation, e.g. of the
+// translation, the summarization or the paraphrasing.
+type Text2Text struct {
+	// Model is the model of the pipeline, for the advanced usages.
+	Model text2text.Interface
+	// Options are the options of the generation of the pipeline.
+	Options *text2text.Options
+}
+
+func loadText2Text(conf *tasks.Config, p pipeline) (Pipeline, error) {
+	m, err := tasks.Load[text2text.Interface](conf)
+	if err != nil {
+		return nil, err
+	}
+	opts := text2text.DefaultOptions()
+	if p.generation != nil {
+		*opts = *p.generation
+	}
+	return &Text2Text{Model: m, Options: opts}, nil
+}
+
+// Task returns "text2text".
+func (p *Text2Text) Task() string { return "text2text" }
+
+// Close releases the resources of the model.
+func (p *Text2Text) Close() error { return tasks.Close(p.Model) }
+
+// Generate returns the text generated from the input, e.g. its
+// translation.
+func (p *Text2Text) Generate(ctx context.Context, text string) (string, error) {
+	result, err := p.Model.Generate(ctx, text, p.Options)
+	if err != nil {
+		return "", err
+	}
+	if len(result.Texts) == 0 {
+		return "", nil
+	}
+	return result.Texts[0], nil
+}
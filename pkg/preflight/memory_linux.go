@@ -0,0 +1,58 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package preflight
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// availableMemory returns the memory available to the process, in bytes:
+// the one available on the system, within the limit of the cgroup of the
+// process, if any, e.g. of its container.
+func availableMemory() (int64, bool) {
+	available, ok := memInfoAvailable()
+	if !ok {
+		return 0, false
+	}
+	limit, err1 := readInt("/sys/fs/cgroup/memory.max")
+	current, err2 := readInt("/sys/fs/cgroup/memory.current")
+	if err1 == nil && err2 == nil && limit-current < available {
+		available = limit - current
+	}
+	return available, true
+}
+
+// memInfoAvailable returns the MemAvailable of /proc/meminfo, in bytes.
+func memInfoAvailable() (int64, bool) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// e.g. "MemAvailable:   12345678 kB"
+		fields := strings.Fields(s.Text())
+		if len(fields) == 3 && fields[0] == "MemAvailable:" && fields[2] == "kB" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			return kb << 10, err == nil
+		}
+	}
+	return 0, false
+}
+
+// readInt reads the integer of the file, failing for "max", i.e. no limit.
+func readInt(filename string) (int64, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
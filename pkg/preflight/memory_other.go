@@ -0,0 +1,12 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package preflight
+
+// availableMemory returns false, the available memory being unknown.
+func availableMemory() (int64, bool) {
+	return 0, false
+}
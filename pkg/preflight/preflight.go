@@ -0,0 +1,272 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package preflight checks that a server can serve its models before
+// loading them: the integrity of their files, the consistency of their
+// tokenizers with their models, the availability of the address to listen
+// on, and the memory needed by their weights against the available one. All
+// the problems are reported at once, each with the action fixing it, instead
+// of failing on the first one, possibly minutes into the loading.
+package preflight
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/cache"
+	"github.com/nlpodyssey/cybertron/pkg/models"
+)
+
+// Problem is a problem found by the checks.
+type Problem struct {
+	// Subject is what the problem affects, e.g. `model "org/name"`.
+	Subject string
+	// Message describes the problem.
+	Message string
+	// Fix is the action fixing the problem, if known.
+	Fix string
+}
+
+// String returns a human-readable description of the problem.
+func (p Problem) String() string {
+	s := p.Subject + ": " + p.Message
+	if p.Fix != "" {
+		s += " (" + p.Fix + ")"
+	}
+	return s
+}
+
+// Error is the error of the problems found.
+type Error struct {
+	Problems []Problem
+}
+
+// Error lists the problems, one per line.
+func (e *Error) Error() string {
+	var b strings.Builder
+	if len(e.Problems) == 1 {
+		b.WriteString("preflight: 1 problem found:")
+	} else {
+		fmt.Fprintf(&b, "preflight: %d problems found:", len(e.Problems))
+	}
+	for _, p := range e.Problems {
+		b.WriteString("\n  - ")
+		b.WriteString(p.String())
+	}
+	return b.String()
+}
+
+// Model is a model to check.
+type Model struct {
+	// Name is the name of the model.
+	Name string
+	// ModelsDir is the directory of the models, the model being in the
+	// subdirectory of its name.
+	ModelsDir string
+	// Local is whether the model must already be in the models directory,
+	// since it's not downloaded, e.g. offline; a missing model is a problem.
+	Local bool
+	// External is whether the files of the model aren't downloaded and
+	// converted by the loader, e.g. with the onnx backend, so that their
+	// integrity isn't checked.
+	External bool
+}
+
+// Options are the options of the checks.
+type Options struct {
+	// Models are the models to check.
+	Models []Model
+	// Network and Address are the address the server listens on, checked
+	// to be available (optional).
+	Network string
+	Address string
+	// AvailableMemory is the memory available to the process, in bytes,
+	// against which the memory of the weights of the models is checked
+	// (default the one reported by the system, on Linux; not checked if
+	// unknown).
+	AvailableMemory int64
+	// Problems are the problems found beforehand, e.g. by the validation of
+	// the configuration, reported with the others.
+	Problems []Problem
+}
+
+// Run runs the checks, returning an *Error with all the problems found, if
+// any.
+func Run(opts Options) error {
+	problems := append([]Problem(nil), opts.Problems...)
+	checked := make(map[string]bool)
+	for _, m := range opts.Models {
+		// The same model can be served for several tasks.
+		if dir := filepath.Join(m.ModelsDir, m.Name); !checked[dir] {
+			checked[dir] = true
+			problems = append(problems, checkModel(m)...)
+		}
+	}
+	if opts.Address != "" {
+		problems = append(problems, checkAddress(opts.Network, opts.Address)...)
+	}
+	problems = append(problems, checkMemory(opts.Models, opts.AvailableMemory)...)
+	if len(problems) == 0 {
+		return nil
+	}
+	return &Error{Problems: problems}
+}
+
+// checkModel checks the integrity of the files of the model, and the size
+// of the vocabulary of its tokenizer against the one of the model.
+func checkModel(m Model) []Problem {
+	subject := fmt.Sprintf("model %#v", m.Name)
+	dir := filepath.Join(m.ModelsDir, m.Name)
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		if !m.Local {
+			// It's downloaded at startup.
+			return nil
+		}
+		return []Problem{{
+			Subject: subject,
+			Message: fmt.Sprintf("not found in %s", m.ModelsDir),
+			Fix:     "download it with the download subcommand, or enable the downloads",
+		}}
+	}
+	var problems []Problem
+	if !m.External {
+		found, err := cache.Verify(m.ModelsDir, m.Name)
+		if err != nil {
+			problems = append(problems, Problem{Subject: subject, Message: err.Error()})
+		}
+		for _, p := range found {
+			problems = append(problems, Problem{
+				Subject: subject,
+				Message: p.String(),
+				Fix:     "remove the model directory to download and convert the model again",
+			})
+		}
+	}
+	if p, ok := checkVocabulary(dir); ok {
+		p.Subject = subject
+		problems = append(problems, p)
+	}
+	return problems
+}
+
+// checkVocabulary reports whether the tokenizer of the model in the
+// directory has more tokens than the embeddings of the model, whose
+// tokens beyond them would fail the requests. The vocabulary of the
+// tokenizer is either a vocab.txt file, with a token per line, or a
+// vocab.json file, mapping the tokens to their IDs.
+func checkVocabulary(dir string) (Problem, bool) {
+	var config struct {
+		VocabSize int `json:"vocab_size"`
+	}
+	data, err := os.ReadFile(filepath.Join(dir, models.DefaultModelConfigFilename))
+	if err != nil || json.Unmarshal(data, &config) != nil || config.VocabSize <= 0 {
+		return Problem{}, false
+	}
+	n, filename, err := tokenizerVocabSize(dir)
+	if err != nil {
+		return Problem{Message: err.Error(), Fix: "remove the model directory to download the model again"}, true
+	}
+	if n <= config.VocabSize {
+		return Problem{}, false
+	}
+	return Problem{
+		Message: fmt.Sprintf("the vocabulary of the tokenizer (%s, %d tokens) is larger than the one of the model (vocab_size %d)", filename, n, config.VocabSize),
+		Fix:     "check that the tokenizer files belong to the model, or download it again",
+	}, true
+}
+
+// tokenizerVocabSize returns the number of tokens of the vocabulary of the
+// tokenizer in the directory, with its filename, or zero if not found.
+func tokenizerVocabSize(dir string) (int, string, error) {
+	if f, err := os.Open(filepath.Join(dir, "vocab.txt")); err == nil {
+		defer f.Close()
+		n := 0
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			if s.Text() != "" {
+				n++
+			}
+		}
+		if err := s.Err(); err != nil {
+			return 0, "", fmt.Errorf("failed to read vocab.txt: %w", err)
+		}
+		return n, "vocab.txt", nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "vocab.json"))
+	if err != nil {
+		return 0, "", nil
+	}
+	var vocab map[string]int
+	if err := json.Unmarshal(data, &vocab); err != nil {
+		return 0, "", fmt.Errorf("invalid vocab.json: %w", err)
+	}
+	return len(vocab), "vocab.json", nil
+}
+
+// checkAddress checks that the address can be listened on.
+func checkAddress(network, address string) []Problem {
+	if network == "" {
+		network = "tcp"
+	}
+	if strings.HasSuffix(address, ":0") {
+		// A random port.
+		return nil
+	}
+	lis, err := net.Listen(network, address)
+	if err != nil {
+		return []Problem{{
+			Subject: fmt.Sprintf("address %#v", address),
+			Message: err.Error(),
+			Fix:     "stop the process listening on it, or listen on another address",
+		}}
+	}
+	_ = lis.Close()
+	return nil
+}
+
+// weightsFilenames are the files of the weights of a model, converted or
+// to be converted, by preference.
+var weightsFilenames = []string{"spago_model.bin", "model.onnx", "model.safetensors", "pytorch_model.bin"}
+
+// checkMemory checks the memory of the weights of the models against the
+// available one. The weights of each model directory are counted once,
+// since the models loaded from it, and their replicas, share them; the
+// models not downloaded yet aren't counted.
+func checkMemory(ms []Model, available int64) []Problem {
+	if available <= 0 {
+		var ok bool
+		if available, ok = availableMemory(); !ok {
+			return nil
+		}
+	}
+	var total int64
+	seen := make(map[string]bool)
+	for _, m := range ms {
+		dir := filepath.Join(m.ModelsDir, m.Name)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		for _, name := range weightsFilenames {
+			if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				total += info.Size()
+				break
+			}
+		}
+	}
+	if total <= available {
+		return nil
+	}
+	return []Problem{{
+		Subject: "memory",
+		Message: fmt.Sprintf("the weights of the models need %d MiB, but only %d MiB are available", total>>20, available>>20),
+		Fix:     "serve fewer models, or quantized ones, or raise the memory limit",
+	}}
+}
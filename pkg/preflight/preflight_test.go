@@ -0,0 +1,81 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package preflight
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeModel writes a BERT model with the vocabulary of the tokenizer and
+// the weights of the given sizes.
+func writeModel(t *testing.T, dir, name string, vocabSize, tokens, weights int) {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(p, 0o755))
+	files := map[string]string{
+		"config.json":           `{"model_type": "bert", "vocab_size": ` + strconv.Itoa(vocabSize) + `}`,
+		"tokenizer_config.json": "{}",
+		"vocab.txt":             strings.Repeat("token\n", tokens),
+		"spago_model.bin":       strings.Repeat("w", weights),
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(p, name), []byte(content), 0o644))
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	writeModel(t, dir, "org/ok", 10, 10, 100)
+	assert.NoError(t, Run(Options{
+		Models:          []Model{{Name: "org/ok", ModelsDir: dir, Local: true}, {Name: "org/missing", ModelsDir: dir}},
+		AvailableMemory: 1 << 20,
+	}))
+}
+
+func TestRunReportsAllProblems(t *testing.T) {
+	dir := t.TempDir()
+	writeModel(t, dir, "org/vocab", 10, 12, 600)
+	writeModel(t, dir, "org/broken", 10, 10, 600)
+	require.NoError(t, os.Remove(filepath.Join(dir, "org/broken", "tokenizer_config.json")))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	err = Run(Options{
+		Models: []Model{
+			{Name: "org/vocab", ModelsDir: dir},
+			{Name: "org/broken", ModelsDir: dir},
+			// The same model, served for another task, isn't checked twice.
+			{Name: "org/vocab", ModelsDir: dir},
+			{Name: "org/missing", ModelsDir: dir, Local: true},
+		},
+		Network:         "tcp",
+		Address:         lis.Addr().String(),
+		AvailableMemory: 1000,
+		Problems:        []Problem{{Subject: "TLS", Message: "invalid certificate"}},
+	})
+	var e *Error
+	require.True(t, errors.As(err, &e))
+	subjects := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		subjects[i] = p.Subject
+	}
+	assert.Equal(t, []string{"TLS", `model "org/vocab"`, `model "org/broken"`, `model "org/missing"`, `address "` + lis.Addr().String() + `"`, "memory"}, subjects)
+	assert.Contains(t, e.Problems[1].Message, "12 tokens")
+	assert.Contains(t, e.Problems[2].Message, "tokenizer_config.json: missing file")
+	assert.Contains(t, e.Problems[3].Message, "not found")
+	assert.NotEmpty(t, e.Problems[3].Fix)
+	assert.Contains(t, err.Error(), "preflight: 6 problems found:\n  - TLS: invalid certificate\n")
+}
@@ -0,0 +1,177 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quantization
+
+import (
+	"math"
+
+	"github.com/nlpodyssey/spago/mat"
+)
+
+// HalfFormat is a 16-bit floating-point storage format.
+type HalfFormat int
+
+const (
+	// FormatFloat16 is the IEEE 754 half-precision format (1 sign bit, 5 exponent bits, 10 mantissa bits).
+	FormatFloat16 HalfFormat = iota
+	// FormatBFloat16 is the "brain" floating-point format (1 sign bit, 8 exponent bits, 7 mantissa bits),
+	// which trades precision for the same dynamic range of float32.
+	FormatBFloat16
+)
+
+// HalfMatrix is a matrix whose values are stored in a 16-bit floating-point
+// format, and promoted to float32 at compute time.
+type HalfMatrix struct {
+	// Rows is the number of rows of the matrix.
+	Rows int
+	// Cols is the number of columns of the matrix.
+	Cols int
+	// Format is the storage format of Data.
+	Format HalfFormat
+	// Data contains the encoded values in row-major order.
+	Data []uint16
+}
+
+// NewHalfMatrix returns the 16-bit representation of the given matrix.
+func NewHalfMatrix(m mat.Matrix, format HalfFormat) *HalfMatrix {
+	rows, cols := m.Dims()
+	data := m.Data().F32()
+	h := &HalfMatrix{
+		Rows:   rows,
+		Cols:   cols,
+		Format: format,
+		Data:   make([]uint16, len(data)),
+	}
+	encode := h.encoder()
+	for i, v := range data {
+		h.Data[i] = encode(v)
+	}
+	return h
+}
+
+// Dims returns the number of rows and columns of the matrix.
+func (h *HalfMatrix) Dims() (r, c int) {
+	return h.Rows, h.Cols
+}
+
+// Dequantize returns the float32 values of the matrix, in row-major order.
+func (h *HalfMatrix) Dequantize() []float32 {
+	out := make([]float32, len(h.Data))
+	decode := h.decoder()
+	for i, v := range h.Data {
+		out[i] = decode(v)
+	}
+	return out
+}
+
+// MulVec computes the product between the matrix and the vector x, storing
+// the result in dst. The values of each row are promoted to float32 while
+// computing its dot product with x, so that the full-precision matrix is
+// never materialized.
+func (h *HalfMatrix) MulVec(dst, x []float32) {
+	if len(x) != h.Cols || len(dst) != h.Rows {
+		panic("quantization: matrix-vector dimensions mismatch")
+	}
+	dot := dotFloat16Kernel
+	if h.Format == FormatBFloat16 {
+		dot = dotBFloat16Kernel
+	}
+	for r := range dst {
+		dst[r] = dot(h.Data[r*h.Cols:(r+1)*h.Cols], x)
+	}
+}
+
+func (h *HalfMatrix) encoder() func(float32) uint16 {
+	if h.Format == FormatBFloat16 {
+		return Float32ToBFloat16
+	}
+	return Float32ToFloat16
+}
+
+func (h *HalfMatrix) decoder() func(uint16) float32 {
+	if h.Format == FormatBFloat16 {
+		return BFloat16ToFloat32
+	}
+	return Float16ToFloat32
+}
+
+// Float32ToBFloat16 converts a float32 to bfloat16, rounding to nearest even.
+func Float32ToBFloat16(f float32) uint16 {
+	bits := math.Float32bits(f)
+	if f != f { // NaN: keep it quiet, avoiding to round it to infinity
+		return uint16(bits>>16) | 0x0040
+	}
+	bits += 0x7fff + (bits>>16)&1
+	return uint16(bits >> 16)
+}
+
+// BFloat16ToFloat32 converts a bfloat16 to float32.
+func BFloat16ToFloat32(b uint16) float32 {
+	return math.Float32frombits(uint32(b) << 16)
+}
+
+// Float32ToFloat16 converts a float32 to IEEE 754 half-precision, rounding
+// to nearest even. Values out of range become infinities, and values too
+// small to be represented become (signed) zeros or subnormals.
+func Float32ToFloat16(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int32(bits>>23) & 0xff
+	mant := bits & 0x7fffff
+
+	switch {
+	case exp == 0xff: // Inf or NaN
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	case exp-127 > 15: // overflow
+		return sign | 0x7c00
+	case exp-127 >= -14: // normal
+		half := uint32(exp-127+15)<<10 | mant>>13
+		// round to nearest even; a carry into the exponent is still correct
+		round := mant & 0x1fff
+		if round > 0x1000 || (round == 0x1000 && half&1 == 1) {
+			half++
+		}
+		return sign | uint16(half)
+	case exp-127 >= -25: // subnormal
+		mant |= 0x800000
+		shift := uint32(-(exp - 127) - 14 + 13)
+		half := mant >> shift
+		rem := mant & (1<<shift - 1)
+		mid := uint32(1) << (shift - 1)
+		if rem > mid || (rem == mid && half&1 == 1) {
+			half++
+		}
+		return sign | uint16(half)
+	default: // underflow
+		return sign
+	}
+}
+
+// Float16ToFloat32 converts an IEEE 754 half-precision value to float32.
+func Float16ToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch {
+	case exp == 0x1f: // Inf or NaN
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case exp == 0 && mant == 0: // zero
+		return math.Float32frombits(sign)
+	case exp == 0: // subnormal: normalize it
+		e := uint32(127 - 15 + 1)
+		for mant&0x400 == 0 {
+			mant <<= 1
+			e--
+		}
+		mant &= 0x3ff
+		return math.Float32frombits(sign | e<<23 | mant<<13)
+	default:
+		return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quantization
+
+import (
+	"math"
+
+	"github.com/nlpodyssey/spago/mat"
+)
+
+// int8Max is the largest magnitude used by the symmetric int8 quantization.
+const int8Max = 127
+
+// Int8Matrix is a matrix of 8-bit signed integers, symmetrically quantized
+// with a separate scale factor for each row (i.e. for each output channel).
+type Int8Matrix struct {
+	// Rows is the number of rows of the matrix.
+	Rows int
+	// Cols is the number of columns of the matrix.
+	Cols int
+	// Data contains the quantized values in row-major order.
+	Data []int8
+	// Scales contains the scale factor of each row.
+	Scales []float32
+}
+
+// QuantizeInt8 returns the per-row int8 quantization of the given matrix.
+func QuantizeInt8(m mat.Matrix) *Int8Matrix {
+	rows, cols := m.Dims()
+	data := m.Data().F32()
+	q := &Int8Matrix{
+		Rows:   rows,
+		Cols:   cols,
+		Data:   make([]int8, len(data)),
+		Scales: make([]float32, rows),
+	}
+	for r := 0; r < rows; r++ {
+		q.Scales[r] = quantizeInt8(q.Data[r*cols:(r+1)*cols], data[r*cols:(r+1)*cols])
+	}
+	return q
+}
+
+// Dims returns the number of rows and columns of the matrix.
+func (q *Int8Matrix) Dims() (r, c int) {
+	return q.Rows, q.Cols
+}
+
+// Dequantize returns the approximated float values of the matrix, in row-major order.
+func (q *Int8Matrix) Dequantize() []float32 {
+	out := make([]float32, len(q.Data))
+	for r := 0; r < q.Rows; r++ {
+		scale := q.Scales[r]
+		for c := 0; c < q.Cols; c++ {
+			i := r*q.Cols + c
+			out[i] = float32(q.Data[i]) * scale
+		}
+	}
+	return out
+}
+
+// MulVec computes the product between the matrix and the vector x, storing
+// the result in dst.
+//
+// The vector is dynamically quantized to int8 as well, so that the dot
+// products are computed with integer arithmetic and rescaled only once
+// per row.
+func (q *Int8Matrix) MulVec(dst, x []float32) {
+	if len(x) != q.Cols || len(dst) != q.Rows {
+		panic("quantization: matrix-vector dimensions mismatch")
+	}
+	xq := make([]int8, len(x))
+	xScale := quantizeInt8(xq, x)
+
+	for r := 0; r < q.Rows; r++ {
+		row := q.Data[r*q.Cols : (r+1)*q.Cols]
+		dst[r] = float32(dotInt8Kernel(row, xq)) * q.Scales[r] * xScale
+	}
+}
+
+// quantizeInt8 quantizes src into dst, returning the scale factor.
+func quantizeInt8(dst []int8, src []float32) float32 {
+	var absMax float32
+	for _, v := range src {
+		if a := float32(math.Abs(float64(v))); a > absMax {
+			absMax = a
+		}
+	}
+	if absMax == 0 {
+		for i := range dst {
+			dst[i] = 0
+		}
+		return 0
+	}
+	scale := absMax / int8Max
+	for i, v := range src {
+		dst[i] = int8(math.Round(float64(v / scale)))
+	}
+	return scale
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quantization
+
+import "sync"
+
+// The kernels of the dot products of the rows of the reduced-precision
+// matrices with the vectors, computed on the stored values, without
+// converting the rows first. They're implemented in assembly for the
+// instruction sets detected at runtime (see kernels_amd64.go), falling back
+// to pure Go.
+var (
+	dotInt8Kernel     = dotInt8Generic
+	dotFloat16Kernel  = dotFloat16Generic
+	dotBFloat16Kernel = dotBFloat16Generic
+)
+
+// kernelsISA is the instruction set used by the kernels.
+var kernelsISA = "generic"
+
+// dotInt8Generic returns the dot product of two int8 vectors of the same
+// size. The accumulator can't overflow as long as the vectors are shorter
+// than math.MaxInt32 / (127 * 127) elements.
+func dotInt8Generic(a, b []int8) int32 {
+	var s0, s1, s2, s3 int32
+	b = b[:len(a)]
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		s0 += int32(a[i]) * int32(b[i])
+		s1 += int32(a[i+1]) * int32(b[i+1])
+		s2 += int32(a[i+2]) * int32(b[i+2])
+		s3 += int32(a[i+3]) * int32(b[i+3])
+	}
+	for ; i < len(a); i++ {
+		s0 += int32(a[i]) * int32(b[i])
+	}
+	return s0 + s1 + s2 + s3
+}
+
+// dotBFloat16Generic returns the dot product of the bfloat16 values of w
+// with x, of the same size.
+func dotBFloat16Generic(w []uint16, x []float32) float32 {
+	var sum float32
+	x = x[:len(w)]
+	for i, v := range w {
+		sum += BFloat16ToFloat32(v) * x[i]
+	}
+	return sum
+}
+
+// dotFloat16Generic returns the dot product of the float16 values of w with
+// x, of the same size, converted with a lookup table.
+func dotFloat16Generic(w []uint16, x []float32) float32 {
+	table := float16Table()
+	var sum float32
+	x = x[:len(w)]
+	for i, v := range w {
+		sum += table[v] * x[i]
+	}
+	return sum
+}
+
+var (
+	float16TableOnce sync.Once
+	float16Values    *[1 << 16]float32
+)
+
+// float16Table returns the float32 values of all the float16 ones, built
+// on the first use.
+func float16Table() *[1 << 16]float32 {
+	float16TableOnce.Do(func() {
+		float16Values = new([1 << 16]float32)
+		for i := range float16Values {
+			float16Values[i] = Float16ToFloat32(uint16(i))
+		}
+	})
+	return float16Values
+}
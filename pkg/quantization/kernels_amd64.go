@@ -0,0 +1,39 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quantization
+
+import "golang.org/x/sys/cpu"
+
+func init() {
+	if cpu.X86.HasAVX2 && cpu.X86.HasFMA {
+		dotInt8Kernel, dotBFloat16Kernel = dotInt8AVX2, dotBFloat16AVX2
+		if hasF16C() {
+			dotFloat16Kernel = dotFloat16AVX2
+		}
+		kernelsISA = "avx2"
+	}
+}
+
+// hasF16C returns whether the CPU supports the conversions of the float16
+// values, which x/sys/cpu doesn't detect.
+func hasF16C() bool {
+	const f16c = 1 << 29
+	return cpuid1ECX()&f16c != 0
+}
+
+// The kernels are implemented in kernels_amd64.s. The slices are expected
+// to have the same length.
+
+//go:noescape
+func dotInt8AVX2(a, b []int8) int32
+
+//go:noescape
+func dotBFloat16AVX2(w []uint16, x []float32) float32
+
+//go:noescape
+func dotFloat16AVX2(w []uint16, x []float32) float32
+
+// cpuid1ECX returns the ECX register of the CPUID leaf 1.
+func cpuid1ECX() uint32
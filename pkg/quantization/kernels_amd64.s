@@ -0,0 +1,188 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include "textflag.h"
+
+// The loops process 32 (int8) or 16 (16-bit) elements per iteration, with
+// two independent accumulators, then 16 or 8 elements per iteration, then
+// the remaining ones one by one.
+
+// func dotInt8AVX2(a, b []int8) int32
+TEXT ·dotInt8AVX2(SB), NOSPLIT, $0-52
+	MOVQ   a_base+0(FP), SI
+	MOVQ   b_base+24(FP), DI
+	MOVQ   a_len+8(FP), CX
+	VPXOR  Y0, Y0, Y0
+	VPXOR  Y5, Y5, Y5
+	XORQ   AX, AX
+	MOVQ   CX, DX
+	ANDQ   $-32, DX
+	JZ     int8dot16
+
+int8dot32:
+	// The values are sign-extended to 16 bits, and the products of each
+	// pair summed to 32 bits.
+	VPMOVSXBW (SI)(AX*1), Y1
+	VPMOVSXBW (DI)(AX*1), Y2
+	VPMADDWD  Y2, Y1, Y1
+	VPADDD    Y1, Y0, Y0
+	VPMOVSXBW 16(SI)(AX*1), Y3
+	VPMOVSXBW 16(DI)(AX*1), Y4
+	VPMADDWD  Y4, Y3, Y3
+	VPADDD    Y3, Y5, Y5
+	ADDQ      $32, AX
+	CMPQ      AX, DX
+	JL        int8dot32
+
+int8dot16:
+	MOVQ CX, DX
+	ANDQ $-16, DX
+	CMPQ AX, DX
+	JGE  int8dotreduce
+	VPMOVSXBW (SI)(AX*1), Y1
+	VPMOVSXBW (DI)(AX*1), Y2
+	VPMADDWD  Y2, Y1, Y1
+	VPADDD    Y1, Y0, Y0
+	ADDQ      $16, AX
+
+int8dotreduce:
+	VPADDD       Y5, Y0, Y0
+	VEXTRACTI128 $1, Y0, X1
+	VPADDD       X1, X0, X0
+	VPSHUFD      $0x4e, X0, X1
+	VPADDD       X1, X0, X0
+	VPSHUFD      $0xb1, X0, X1
+	VPADDD       X1, X0, X0
+	VMOVD        X0, BX
+
+int8dot1:
+	CMPQ    AX, CX
+	JGE     int8dotdone
+	MOVBLSX (SI)(AX*1), R8
+	MOVBLSX (DI)(AX*1), R9
+	IMULL   R9, R8
+	ADDL    R8, BX
+	INCQ    AX
+	JMP     int8dot1
+
+int8dotdone:
+	MOVL BX, ret+48(FP)
+	VZEROUPPER
+	RET
+
+// func dotBFloat16AVX2(w []uint16, x []float32) float32
+TEXT ·dotBFloat16AVX2(SB), NOSPLIT, $0-52
+	MOVQ   w_base+0(FP), SI
+	MOVQ   x_base+24(FP), DI
+	MOVQ   w_len+8(FP), CX
+	VXORPS Y0, Y0, Y0
+	VXORPS Y3, Y3, Y3
+	XORQ   AX, AX
+	MOVQ   CX, DX
+	ANDQ   $-16, DX
+	JZ     bf16dot8
+
+bf16dot16:
+	// The bfloat16 values are the upper halves of the float32 ones.
+	VPMOVZXWD   (SI)(AX*2), Y1
+	VPSLLD      $16, Y1, Y1
+	VFMADD231PS (DI)(AX*4), Y1, Y0
+	VPMOVZXWD   16(SI)(AX*2), Y2
+	VPSLLD      $16, Y2, Y2
+	VFMADD231PS 32(DI)(AX*4), Y2, Y3
+	ADDQ        $16, AX
+	CMPQ        AX, DX
+	JL          bf16dot16
+
+bf16dot8:
+	MOVQ CX, DX
+	ANDQ $-8, DX
+	CMPQ AX, DX
+	JGE  bf16dotreduce
+	VPMOVZXWD   (SI)(AX*2), Y1
+	VPSLLD      $16, Y1, Y1
+	VFMADD231PS (DI)(AX*4), Y1, Y0
+	ADDQ        $8, AX
+
+bf16dotreduce:
+	VADDPS       Y3, Y0, Y0
+	VEXTRACTF128 $1, Y0, X1
+	VADDPS       X1, X0, X0
+	VHADDPS      X0, X0, X0
+	VHADDPS      X0, X0, X0
+
+bf16dot1:
+	CMPQ        AX, CX
+	JGE         bf16dotdone
+	MOVWLZX     (SI)(AX*2), R8
+	SHLL        $16, R8
+	VMOVD       R8, X1
+	VFMADD231SS (DI)(AX*4), X1, X0
+	INCQ        AX
+	JMP         bf16dot1
+
+bf16dotdone:
+	VMOVSS X0, ret+48(FP)
+	VZEROUPPER
+	RET
+
+// func dotFloat16AVX2(w []uint16, x []float32) float32
+TEXT ·dotFloat16AVX2(SB), NOSPLIT, $0-52
+	MOVQ   w_base+0(FP), SI
+	MOVQ   x_base+24(FP), DI
+	MOVQ   w_len+8(FP), CX
+	VXORPS Y0, Y0, Y0
+	VXORPS Y3, Y3, Y3
+	XORQ   AX, AX
+	MOVQ   CX, DX
+	ANDQ   $-16, DX
+	JZ     f16dot8
+
+f16dot16:
+	VCVTPH2PS   (SI)(AX*2), Y1
+	VFMADD231PS (DI)(AX*4), Y1, Y0
+	VCVTPH2PS   16(SI)(AX*2), Y2
+	VFMADD231PS 32(DI)(AX*4), Y2, Y3
+	ADDQ        $16, AX
+	CMPQ        AX, DX
+	JL          f16dot16
+
+f16dot8:
+	MOVQ CX, DX
+	ANDQ $-8, DX
+	CMPQ AX, DX
+	JGE  f16dotreduce
+	VCVTPH2PS   (SI)(AX*2), Y1
+	VFMADD231PS (DI)(AX*4), Y1, Y0
+	ADDQ        $8, AX
+
+f16dotreduce:
+	VADDPS       Y3, Y0, Y0
+	VEXTRACTF128 $1, Y0, X1
+	VADDPS       X1, X0, X0
+	VHADDPS      X0, X0, X0
+	VHADDPS      X0, X0, X0
+
+f16dot1:
+	CMPQ        AX, CX
+	JGE         f16dotdone
+	MOVWLZX     (SI)(AX*2), R8
+	VMOVD       R8, X1
+	VCVTPH2PS   X1, X1
+	VFMADD231SS (DI)(AX*4), X1, X0
+	INCQ        AX
+	JMP         f16dot1
+
+f16dotdone:
+	VMOVSS X0, ret+48(FP)
+	VZEROUPPER
+	RET
+
+// func cpuid1ECX() uint32
+TEXT ·cpuid1ECX(SB), NOSPLIT, $0-4
+	MOVL  $1, AX
+	XORL  CX, CX
+	CPUID
+	MOVL  CX, ret+0(FP)
+	RET
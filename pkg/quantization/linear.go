@@ -0,0 +1,73 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quantization
+
+import (
+	"encoding/gob"
+
+	"github.com/nlpodyssey/cybertron/pkg/utils/matview"
+	"github.com/nlpodyssey/spago/ag"
+	"github.com/nlpodyssey/spago/mat/float"
+	"github.com/nlpodyssey/spago/nn"
+	"github.com/nlpodyssey/spago/nn/linear"
+)
+
+var _ nn.StandardModel = &Linear{}
+
+// Weights is implemented by the reduced-precision weight matrices.
+type Weights interface {
+	// Dims returns the number of rows and columns of the matrix.
+	Dims() (r, c int)
+	// MulVec computes the product between the matrix and the vector x,
+	// storing the result in dst.
+	MulVec(dst, x []float32)
+	// Dequantize returns the float values of the matrix, in row-major order.
+	Dequantize() []float32
+}
+
+var (
+	_ Weights = &Int8Matrix{}
+	_ Weights = &HalfMatrix{}
+)
+
+// Linear is an inference-only linear layer whose weights are stored with
+// reduced precision. It is a drop-in replacement for linear.Model.
+type Linear struct {
+	nn.Module
+	// W is the reduced-precision weight matrix.
+	W Weights
+	// B is the bias, kept at full precision.
+	B nn.Param
+}
+
+func init() {
+	gob.Register(&Linear{})
+	gob.Register(&Int8Matrix{})
+	gob.Register(&HalfMatrix{})
+}
+
+// NewLinear returns a new Linear, replacing the weights of the given model
+// with the given ones.
+func NewLinear(m *linear.Model, w Weights) *Linear {
+	return &Linear{
+		W: w,
+		B: m.B,
+	}
+}
+
+// Forward performs the forward step for each input node and returns the result.
+// The outputs are constant nodes, since gradients are not propagated
+// through reduced-precision weights.
+func (m *Linear) Forward(xs ...ag.Node) []ag.Node {
+	b := m.B.Value()
+	rows, _ := m.W.Dims()
+	ys := make([]ag.Node, len(xs))
+	for i, x := range xs {
+		y := make([]float32, rows)
+		m.W.MulVec(y, matview.Float32s(x.Value()))
+		ys[i] = b.NewVec(float.SliceInterface(y)).AddInPlace(b)
+	}
+	return ys
+}
@@ -0,0 +1,476 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quantization
+
+import (
+	"errors"
+
+	"github.com/nlpodyssey/cybertron/pkg/utils/matview"
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/nlpodyssey/spago/mat/float"
+)
+
+var _ mat.Matrix = &weightsMatrix{}
+
+// errReadOnly is the panic of the methods modifying a weightsMatrix.
+var errReadOnly = errors.New("quantization: the value of a quantized parameter is read-only")
+
+// weightsMatrix is the value of a Param: a read-only matrix over its
+// reduced-precision weights. Its products with the vectors, e.g. the ones
+// of the linear layers computing ag.Affine for each token, run directly on
+// the weights with Weights.MulVec, without materializing the float32
+// matrix. The other methods, seldom used with the weights, e.g. to export
+// them, compute on the dequantized matrix.
+type weightsMatrix struct {
+	W Weights
+}
+
+// prototype creates the new matrices of a weightsMatrix, of its float32
+// type, without dequantizing it.
+var prototype mat.Matrix = mat.NewScalar[float32](0)
+
+// dense returns the dequantized matrix.
+func (m *weightsMatrix) dense() mat.Matrix {
+	r, c := m.W.Dims()
+	return mat.NewDense[float32](r, c, m.W.Dequantize())
+}
+
+func (m *weightsMatrix) Rows() int {
+	r, _ := m.W.Dims()
+	return r
+}
+
+func (m *weightsMatrix) Columns() int {
+	_, c := m.W.Dims()
+	return c
+}
+
+func (m *weightsMatrix) Dims() (r, c int) {
+	return m.W.Dims()
+}
+
+// Mul returns the product of the matrix with the other one, computed on the
+// weights one column of the other at a time, e.g. for the vector of a token.
+func (m *weightsMatrix) Mul(other mat.Matrix) mat.Matrix {
+	rows, cols := m.W.Dims()
+	if other.Rows() != cols {
+		panic("quantization: matrices have incompatible dimensions")
+	}
+	n := other.Columns()
+	if n == 1 {
+		y := other.NewEmptyVec(rows)
+		if d, ok := y.(*mat.Dense[float32]); ok {
+			m.W.MulVec(mat.Data[float32](d), matview.Float32s(other))
+			return y
+		}
+		data := make([]float32, rows)
+		m.W.MulVec(data, matview.Float32s(other))
+		return other.NewVec(float.SliceInterface(data))
+	}
+	x := matview.Float32s(other.T())
+	y := make([]float32, rows*n)
+	col := make([]float32, rows)
+	for j := 0; j < n; j++ {
+		m.W.MulVec(col, x[j*cols:(j+1)*cols])
+		for i, v := range col {
+			y[i*n+j] = v
+		}
+	}
+	return other.NewMatrix(rows, n, float.SliceInterface(y))
+}
+
+// Value returns the matrix itself.
+func (m *weightsMatrix) Value() mat.Matrix {
+	return m
+}
+
+// Grad returns nil, since the weights are not trainable.
+func (m *weightsMatrix) Grad() mat.Matrix {
+	return nil
+}
+
+func (m *weightsMatrix) HasGrad() bool {
+	return false
+}
+
+func (m *weightsMatrix) RequiresGrad() bool {
+	return false
+}
+
+func (m *weightsMatrix) SetRequiresGrad(bool) {}
+
+func (m *weightsMatrix) AccGrad(mat.Matrix) {}
+
+func (m *weightsMatrix) ZeroGrad() {}
+
+// The other methods compute on the dequantized matrix, except the ones
+// creating new matrices.
+
+func (m *weightsMatrix) Size() int {
+	r, c := m.W.Dims()
+	return r * c
+}
+
+func (m *weightsMatrix) Data() float.Slice {
+	return m.dense().Data()
+}
+
+func (m *weightsMatrix) ZerosLike() mat.Matrix {
+	return m.dense().ZerosLike()
+}
+
+func (m *weightsMatrix) OnesLike() mat.Matrix {
+	return m.dense().OnesLike()
+}
+
+func (m *weightsMatrix) Scalar() float.Float {
+	return m.dense().Scalar()
+}
+
+func (m *weightsMatrix) At(r int, c int) mat.Matrix {
+	return m.dense().At(r, c)
+}
+
+func (m *weightsMatrix) ScalarAt(r int, c int) float.Float {
+	return m.dense().ScalarAt(r, c)
+}
+
+func (m *weightsMatrix) AtVec(i int) mat.Matrix {
+	return m.dense().AtVec(i)
+}
+
+func (m *weightsMatrix) ScalarAtVec(i int) float.Float {
+	return m.dense().ScalarAtVec(i)
+}
+
+func (m *weightsMatrix) ExtractRow(i int) mat.Matrix {
+	return m.dense().ExtractRow(i)
+}
+
+func (m *weightsMatrix) ExtractColumn(i int) mat.Matrix {
+	return m.dense().ExtractColumn(i)
+}
+
+func (m *weightsMatrix) Slice(fromRow, fromCol, toRow, toCol int) mat.Matrix {
+	return m.dense().Slice(fromRow, fromCol, toRow, toCol)
+}
+
+func (m *weightsMatrix) Reshape(r, c int) mat.Matrix {
+	return m.dense().Reshape(r, c)
+}
+
+func (m *weightsMatrix) Flatten() mat.Matrix {
+	return m.dense().Flatten()
+}
+
+func (m *weightsMatrix) ResizeVector(newSize int) mat.Matrix {
+	return m.dense().ResizeVector(newSize)
+}
+
+func (m *weightsMatrix) T() mat.Matrix {
+	return m.dense().T()
+}
+
+func (m *weightsMatrix) Add(other mat.Matrix) mat.Matrix {
+	return m.dense().Add(other)
+}
+
+func (m *weightsMatrix) AddScalar(n float64) mat.Matrix {
+	return m.dense().AddScalar(n)
+}
+
+func (m *weightsMatrix) Sub(other mat.Matrix) mat.Matrix {
+	return m.dense().Sub(other)
+}
+
+func (m *weightsMatrix) SubScalar(n float64) mat.Matrix {
+	return m.dense().SubScalar(n)
+}
+
+func (m *weightsMatrix) Prod(other mat.Matrix) mat.Matrix {
+	return m.dense().Prod(other)
+}
+
+func (m *weightsMatrix) ProdScalar(n float64) mat.Matrix {
+	return m.dense().ProdScalar(n)
+}
+
+func (m *weightsMatrix) Div(other mat.Matrix) mat.Matrix {
+	return m.dense().Div(other)
+}
+
+func (m *weightsMatrix) MulT(other mat.Matrix) mat.Matrix {
+	return m.dense().MulT(other)
+}
+
+func (m *weightsMatrix) DotUnitary(other mat.Matrix) mat.Matrix {
+	return m.dense().DotUnitary(other)
+}
+
+func (m *weightsMatrix) Maximum(other mat.Matrix) mat.Matrix {
+	return m.dense().Maximum(other)
+}
+
+func (m *weightsMatrix) Minimum(other mat.Matrix) mat.Matrix {
+	return m.dense().Minimum(other)
+}
+
+func (m *weightsMatrix) Abs() mat.Matrix {
+	return m.dense().Abs()
+}
+
+func (m *weightsMatrix) Pow(power float64) mat.Matrix {
+	return m.dense().Pow(power)
+}
+
+func (m *weightsMatrix) Sqrt() mat.Matrix {
+	return m.dense().Sqrt()
+}
+
+func (m *weightsMatrix) Log() mat.Matrix {
+	return m.dense().Log()
+}
+
+func (m *weightsMatrix) Exp() mat.Matrix {
+	return m.dense().Exp()
+}
+
+func (m *weightsMatrix) Sigmoid() mat.Matrix {
+	return m.dense().Sigmoid()
+}
+
+func (m *weightsMatrix) Sum() mat.Matrix {
+	return m.dense().Sum()
+}
+
+func (m *weightsMatrix) Max() mat.Matrix {
+	return m.dense().Max()
+}
+
+func (m *weightsMatrix) Min() mat.Matrix {
+	return m.dense().Min()
+}
+
+func (m *weightsMatrix) ArgMax() int {
+	return m.dense().ArgMax()
+}
+
+func (m *weightsMatrix) Softmax() mat.Matrix {
+	return m.dense().Softmax()
+}
+
+func (m *weightsMatrix) CumSum() mat.Matrix {
+	return m.dense().CumSum()
+}
+
+func (m *weightsMatrix) Range(start, end int) mat.Matrix {
+	return m.dense().Range(start, end)
+}
+
+func (m *weightsMatrix) SplitV(sizes ...int) []mat.Matrix {
+	return m.dense().SplitV(sizes...)
+}
+
+func (m *weightsMatrix) Augment() mat.Matrix {
+	return m.dense().Augment()
+}
+
+func (m *weightsMatrix) PadRows(n int) mat.Matrix {
+	return m.dense().PadRows(n)
+}
+
+func (m *weightsMatrix) PadColumns(n int) mat.Matrix {
+	return m.dense().PadColumns(n)
+}
+
+func (m *weightsMatrix) AppendRows(vs ...mat.Matrix) mat.Matrix {
+	return m.dense().AppendRows(vs...)
+}
+
+func (m *weightsMatrix) Norm(pow float64) mat.Matrix {
+	return m.dense().Norm(pow)
+}
+
+func (m *weightsMatrix) Pivoting(row int) (mat.Matrix, bool, [2]int) {
+	return m.dense().Pivoting(row)
+}
+
+func (m *weightsMatrix) Normalize2() mat.Matrix {
+	return m.dense().Normalize2()
+}
+
+func (m *weightsMatrix) LU() (l, u, p mat.Matrix) {
+	return m.dense().LU()
+}
+
+func (m *weightsMatrix) Inverse() mat.Matrix {
+	return m.dense().Inverse()
+}
+
+func (m *weightsMatrix) VecForEach(fn func(i int, v float64)) {
+	m.dense().VecForEach(fn)
+}
+
+func (m *weightsMatrix) Apply(fn func(r, c int, v float64) float64) mat.Matrix {
+	return m.dense().Apply(fn)
+}
+
+func (m *weightsMatrix) ApplyWithAlpha(fn func(r, c int, v float64, alpha ...float64) float64, alpha ...float64) mat.Matrix {
+	return m.dense().ApplyWithAlpha(fn, alpha...)
+}
+
+func (m *weightsMatrix) DoNonZero(fn func(r, c int, v float64)) {
+	m.dense().DoNonZero(fn)
+}
+
+func (m *weightsMatrix) DoVecNonZero(fn func(i int, v float64)) {
+	m.dense().DoVecNonZero(fn)
+}
+
+func (m *weightsMatrix) Clone() mat.Matrix {
+	return m.dense().Clone()
+}
+
+func (m *weightsMatrix) String() string {
+	return m.dense().String()
+}
+
+func (m *weightsMatrix) NewMatrix(rows, cols int, data float.Slice, opts ...mat.MatrixOption) mat.Matrix {
+	return prototype.NewMatrix(rows, cols, data, opts...)
+}
+
+func (m *weightsMatrix) NewVec(data float.Slice, opts ...mat.MatrixOption) mat.Matrix {
+	return prototype.NewVec(data, opts...)
+}
+
+func (m *weightsMatrix) NewScalar(v float64, opts ...mat.MatrixOption) mat.Matrix {
+	return prototype.NewScalar(v, opts...)
+}
+
+func (m *weightsMatrix) NewEmptyVec(size int, opts ...mat.MatrixOption) mat.Matrix {
+	return prototype.NewEmptyVec(size, opts...)
+}
+
+func (m *weightsMatrix) NewEmptyMatrix(rows, cols int, opts ...mat.MatrixOption) mat.Matrix {
+	return prototype.NewEmptyMatrix(rows, cols, opts...)
+}
+
+func (m *weightsMatrix) NewInitMatrix(rows, cols int, v float64, opts ...mat.MatrixOption) mat.Matrix {
+	return prototype.NewInitMatrix(rows, cols, v, opts...)
+}
+
+func (m *weightsMatrix) NewInitFuncMatrix(rows, cols int, fn func(r, c int) float64, opts ...mat.MatrixOption) mat.Matrix {
+	return prototype.NewInitFuncMatrix(rows, cols, fn, opts...)
+}
+
+func (m *weightsMatrix) NewInitVec(size int, v float64, opts ...mat.MatrixOption) mat.Matrix {
+	return prototype.NewInitVec(size, v, opts...)
+}
+
+func (m *weightsMatrix) NewIdentityMatrix(size int, opts ...mat.MatrixOption) mat.Matrix {
+	return prototype.NewIdentityMatrix(size, opts...)
+}
+
+func (m *weightsMatrix) NewOneHotVec(size int, oneAt int, opts ...mat.MatrixOption) mat.Matrix {
+	return prototype.NewOneHotVec(size, oneAt, opts...)
+}
+
+func (m *weightsMatrix) NewConcatV(vs ...mat.Matrix) mat.Matrix {
+	return prototype.NewConcatV(vs...)
+}
+
+func (m *weightsMatrix) NewStack(vs ...mat.Matrix) mat.Matrix {
+	return prototype.NewStack(vs...)
+}
+
+// The methods modifying the matrix panic, since it's read-only.
+
+func (*weightsMatrix) SetData(data float.Slice) {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) Zeros() {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) Set(r int, c int, m mat.Matrix) {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) SetScalar(r int, c int, v float.Float) {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) SetVec(i int, m mat.Matrix) {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) SetVecScalar(i int, v float.Float) {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) ReshapeInPlace(r, c int) mat.Matrix {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) FlattenInPlace() mat.Matrix {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) TransposeInPlace() mat.Matrix {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) AddInPlace(other mat.Matrix) mat.Matrix {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) AddScalarInPlace(n float64) mat.Matrix {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) SubInPlace(other mat.Matrix) mat.Matrix {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) SubScalarInPlace(n float64) mat.Matrix {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) ProdInPlace(other mat.Matrix) mat.Matrix {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) ProdScalarInPlace(n float64) mat.Matrix {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) ProdMatrixScalarInPlace(m mat.Matrix, n float64) mat.Matrix {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) DivInPlace(other mat.Matrix) mat.Matrix {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) ClipInPlace(min, max float64) mat.Matrix {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) SwapInPlace(r1, r2 int) mat.Matrix {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) ApplyInPlace(fn func(r, c int, v float64) float64, a mat.Matrix) mat.Matrix {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) ApplyWithAlphaInPlace(fn func(r, c int, v float64, alpha ...float64) float64, a mat.Matrix, alpha ...float64) mat.Matrix {
+	panic(errReadOnly)
+}
+
+func (*weightsMatrix) Copy(other mat.Matrix) {
+	panic(errReadOnly)
+}
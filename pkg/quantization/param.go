@@ -0,0 +1,123 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quantization
+
+import (
+	"encoding/gob"
+	"fmt"
+	"sync"
+
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/nlpodyssey/spago/nn"
+)
+
+var _ nn.Param = &Param{}
+
+// Param is an inference-only parameter whose matrix is stored with reduced
+// precision. It replaces the weights of the linear layers that can't be
+// replaced by a Linear, e.g. the projections of the self-attention: its
+// value is a read-only view over the weights, whose products with the
+// vectors of the tokens, computed by ag.Affine, run on the weights as in a
+// Linear, without dequantizing them.
+type Param struct {
+	// W is the reduced-precision matrix.
+	W Weights
+	// Scheme is the scheme of the matrix, which the values replacing it are
+	// quantized with.
+	Scheme Scheme
+
+	mu      sync.Mutex
+	payload *nn.Payload
+}
+
+func init() {
+	gob.Register(&Param{})
+}
+
+// NewParam returns the parameter of the value quantized with the scheme.
+func NewParam(value mat.Matrix, scheme Scheme) (*Param, error) {
+	w, err := quantizeMatrix(value, scheme)
+	if err != nil {
+		return nil, err
+	}
+	return &Param{W: w, Scheme: scheme}, nil
+}
+
+// quantizeMatrix returns the matrix quantized with the scheme.
+func quantizeMatrix(m mat.Matrix, scheme Scheme) (Weights, error) {
+	switch scheme {
+	case Int8:
+		return QuantizeInt8(m), nil
+	case Float16:
+		return NewHalfMatrix(m, FormatFloat16), nil
+	case BFloat16:
+		return NewHalfMatrix(m, FormatBFloat16), nil
+	default:
+		return nil, fmt.Errorf("unsupported quantization scheme: %s", scheme)
+	}
+}
+
+// Value returns the read-only matrix of the weights (see weightsMatrix).
+func (p *Param) Value() mat.Matrix {
+	return &weightsMatrix{W: p.W}
+}
+
+// Grad returns nil, since the parameter is not trainable.
+func (p *Param) Grad() mat.Matrix {
+	return nil
+}
+
+// HasGrad returns false, since the parameter is not trainable.
+func (p *Param) HasGrad() bool {
+	return false
+}
+
+// RequiresGrad returns false, since the parameter is not trainable.
+func (p *Param) RequiresGrad() bool {
+	return false
+}
+
+// AccGrad does nothing, since the parameter is not trainable.
+func (p *Param) AccGrad(mat.Matrix) {}
+
+// ZeroGrad does nothing, since the parameter is not trainable.
+func (p *Param) ZeroGrad() {}
+
+// SetRequiresGrad does nothing, since the parameter is not trainable.
+func (p *Param) SetRequiresGrad(bool) {}
+
+// ReplaceValue replaces the matrix with the value quantized with the
+// scheme of the parameter.
+func (p *Param) ReplaceValue(value mat.Matrix) {
+	w, err := quantizeMatrix(value, p.Scheme)
+	if err != nil {
+		panic(fmt.Sprintf("quantization: %v", err))
+	}
+	p.W = w
+}
+
+// ApplyDelta replaces the matrix with its sum with the delta, quantized.
+func (p *Param) ApplyDelta(delta mat.Matrix) {
+	p.ReplaceValue(p.Value().Add(delta))
+}
+
+// Payload returns the optimizer support structure (can be nil).
+func (p *Param) Payload() *nn.Payload {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.payload
+}
+
+// SetPayload sets the optimizer support structure.
+func (p *Param) SetPayload(payload *nn.Payload) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.payload = payload
+}
+
+// ClearPayload clears the optimizer support structure.
+func (p *Param) ClearPayload() {
+	p.SetPayload(nil)
+}
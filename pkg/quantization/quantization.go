@@ -0,0 +1,135 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package quantization provides reduced-precision representations of the
+// weights of a converted model, together with the modules able to run
+// inference directly on them.
+package quantization
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/nlpodyssey/spago/nn"
+	"github.com/nlpodyssey/spago/nn/linear"
+)
+
+// Scheme is a quantization scheme for the weights of a model.
+type Scheme int
+
+const (
+	// None means that the weights are left untouched.
+	None Scheme = iota
+	// Int8 means that the weights of the linear layers are quantized to
+	// 8-bit signed integers, with one scale factor per output channel.
+	Int8
+	// Float16 means that the weights of the linear layers are stored as
+	// IEEE 754 half-precision floats.
+	Float16
+	// BFloat16 means that the weights of the linear layers are stored as
+	// bfloat16 floats.
+	BFloat16
+)
+
+// schemeValues is a list of supported quantization schemes.
+var schemeValues = map[string]Scheme{
+	"none":     None,
+	"int8":     Int8,
+	"float16":  Float16,
+	"bfloat16": BFloat16,
+}
+
+// ParseScheme parses a string into a quantization scheme.
+func ParseScheme(s string) (Scheme, error) {
+	result, ok := schemeValues[s]
+	if !ok {
+		return 0, fmt.Errorf("invalid quantization scheme value %#v", s)
+	}
+	return result, nil
+}
+
+// String returns the name of the scheme.
+func (s Scheme) String() string {
+	for k, v := range schemeValues {
+		if v == s {
+			return k
+		}
+	}
+	return fmt.Sprintf("Scheme(%d)", int(s))
+}
+
+// moduleListType is the type of the lists of standard models, whose linear
+// layers are replaced by a Linear.
+var moduleListType = reflect.TypeOf(nn.ModuleList[nn.StandardModel]{})
+
+// Quantize quantizes, in place, the weights of all the linear layers of the
+// given model, walking its modules recursively, according to the scheme.
+//
+// The layers held by an nn.ModuleList[nn.StandardModel], e.g. the
+// feed-forward blocks of the encoders, are replaced by a Linear, computing
+// their products on the quantized weights. The other ones, e.g. the
+// projections of the self-attention or the task heads, held by fields of
+// type *linear.Model that can't hold a different implementation, keep
+// their type, but their weight is replaced by a Param, whose products
+// run on the quantized weights too. The biases, the embeddings and the normalizations are
+// left at full precision.
+//
+// It returns the number of quantized layers.
+func Quantize(m nn.Model, scheme Scheme) (int, error) {
+	switch scheme {
+	case None:
+		return 0, nil
+	case Int8, Float16, BFloat16:
+	default:
+		return 0, fmt.Errorf("unsupported quantization scheme: %s", scheme)
+	}
+
+	count := 0
+	var err error
+	// The models are visited before their submodels, so the layers of the
+	// lists are replaced before they could be visited.
+	nn.Apply(m, func(model nn.Model) {
+		if err != nil {
+			return
+		}
+		if l, ok := model.(*linear.Model); ok {
+			if _, ok := l.W.(*Param); ok {
+				return // shared with a layer already quantized
+			}
+			var p *Param
+			if p, err = NewParam(l.W.Value(), scheme); err == nil {
+				l.W = p
+				count++
+			}
+			return
+		}
+		v := reflect.Indirect(reflect.ValueOf(model))
+		if v.Kind() != reflect.Struct {
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Field(i)
+			if f.Type() != moduleListType || !f.CanInterface() {
+				continue
+			}
+			// The list shares its backing array with the field, so the
+			// replacement is visible from the model itself.
+			list := f.Interface().(nn.ModuleList[nn.StandardModel])
+			for j, layer := range list {
+				if l, ok := layer.(*linear.Model); ok {
+					var w Weights
+					if w, err = quantizeMatrix(l.W.Value(), scheme); err != nil {
+						return
+					}
+					list[j] = NewLinear(l, w)
+					count++
+				}
+			}
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
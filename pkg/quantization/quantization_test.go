@@ -0,0 +1,291 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quantization
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math/rand"
+	"testing"
+
+	"github.com/nlpodyssey/spago/ag"
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/nlpodyssey/spago/nn"
+	"github.com/nlpodyssey/spago/nn/activation"
+	"github.com/nlpodyssey/spago/nn/linear"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScheme(t *testing.T) {
+	s, err := ParseScheme("int8")
+	require.NoError(t, err)
+	assert.Equal(t, Int8, s)
+	assert.Equal(t, "int8", s.String())
+
+	_, err = ParseScheme("int4")
+	assert.Error(t, err)
+}
+
+func TestQuantizeInt8(t *testing.T) {
+	m := mat.NewDense[float32](2, 3, []float32{
+		0.5, -1.0, 0.25,
+		0, 0, 0,
+	})
+	q := QuantizeInt8(m)
+
+	assert.Equal(t, []int8{64, -127, 32, 0, 0, 0}, q.Data)
+	assert.Equal(t, []float32{1.0 / 127, 0}, q.Scales)
+
+	deq := q.Dequantize()
+	for i, v := range m.Data().F32() {
+		assert.InDelta(t, v, deq[i], 1.0/127)
+	}
+}
+
+func TestInt8Matrix_MulVec(t *testing.T) {
+	m := mat.NewDense[float32](2, 3, []float32{
+		0.1, 0.2, 0.3,
+		-0.4, 0.5, -0.6,
+	})
+	x := mat.NewVecDense[float32]([]float32{1, -2, 3})
+
+	expected := m.Mul(x).Data().F32()
+	actual := make([]float32, 2)
+	QuantizeInt8(m).MulVec(actual, x.Data().F32())
+
+	for i := range expected {
+		assert.InDelta(t, expected[i], actual[i], 0.02)
+	}
+}
+
+type testModel struct {
+	nn.Module
+	Layers nn.ModuleList[nn.StandardModel]
+	Blocks []*testBlock
+	Head   *linear.Model
+}
+
+type testBlock struct {
+	nn.Module
+	Projection *linear.Model
+}
+
+func TestQuantize(t *testing.T) {
+	newLinear := func() *linear.Model {
+		l := linear.New[float32](3, 2)
+		l.W.ReplaceValue(mat.NewDense[float32](2, 3, []float32{0.1, 0.2, 0.3, -0.4, 0.5, -0.6}))
+		l.B.ReplaceValue(mat.NewVecDense[float32]([]float32{1, 2}))
+		return l
+	}
+	shared := newLinear()
+	m := &testModel{
+		Layers: []nn.StandardModel{newLinear(), activation.New(activation.Tanh)},
+		Blocks: []*testBlock{{Projection: newLinear()}, {Projection: shared}, {Projection: shared}},
+		Head:   newLinear(),
+	}
+	x := mat.NewVecDense[float32]([]float32{1, -2, 3})
+	expected := m.Layers[0].Forward(x)[0].Value().Data().F32()
+
+	n, err := Quantize(m, Int8)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.IsType(t, &Linear{}, m.Layers[0])
+	assert.IsType(t, &activation.Model{}, m.Layers[1])
+	for _, l := range []*linear.Model{m.Blocks[0].Projection, m.Blocks[1].Projection, m.Head} {
+		assert.IsType(t, &Param{}, l.W)
+		assert.IsType(t, &Int8Matrix{}, l.W.(*Param).W)
+		assert.False(t, l.W.RequiresGrad())
+		// The bias is kept at full precision.
+		_, quantized := l.B.(*Param)
+		assert.False(t, quantized)
+	}
+
+	for _, l := range []nn.StandardModel{m.Layers[0], m.Blocks[0].Projection, m.Head} {
+		actual := l.Forward(x)[0].Value().Data().F32()
+		for i := range expected {
+			assert.InDelta(t, expected[i], actual[i], 0.02)
+		}
+	}
+
+	n, err = Quantize(m, None)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestFloat16Conversion(t *testing.T) {
+	tests := []struct {
+		f float32
+		h uint16
+	}{
+		{0, 0x0000},
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{0.5, 0x3800},
+		{65504, 0x7bff},
+		{1e6, 0x7c00},
+		{5.960464477539063e-08, 0x0001},
+		{6.103515625e-05, 0x0400},
+	}
+	for _, tt := range tests {
+		assert.Equalf(t, tt.h, Float32ToFloat16(tt.f), "float16 of %v", tt.f)
+		if tt.h != 0x7c00 {
+			assert.Equalf(t, tt.f, Float16ToFloat32(tt.h), "float32 of %#x", tt.h)
+		}
+	}
+}
+
+func TestBFloat16Conversion(t *testing.T) {
+	assert.Equal(t, uint16(0x3f80), Float32ToBFloat16(1))
+	assert.Equal(t, uint16(0xc000), Float32ToBFloat16(-2))
+	assert.Equal(t, float32(1), BFloat16ToFloat32(0x3f80))
+	assert.InDelta(t, 3.14159, BFloat16ToFloat32(Float32ToBFloat16(3.14159)), 0.01)
+}
+
+func TestHalfMatrix_MulVec(t *testing.T) {
+	m := mat.NewDense[float32](2, 3, []float32{
+		0.1, 0.2, 0.3,
+		-0.4, 0.5, -0.6,
+	})
+	x := mat.NewVecDense[float32]([]float32{1, -2, 3})
+	expected := m.Mul(x).Data().F32()
+
+	for _, format := range []HalfFormat{FormatFloat16, FormatBFloat16} {
+		actual := make([]float32, 2)
+		NewHalfMatrix(m, format).MulVec(actual, x.Data().F32())
+		for i := range expected {
+			assert.InDelta(t, expected[i], actual[i], 0.01)
+		}
+	}
+}
+
+func TestParam(t *testing.T) {
+	value := mat.NewDense[float32](2, 3, []float32{0.1, 0.2, 0.3, -0.4, 0.5, -0.6})
+	for _, scheme := range []Scheme{Int8, Float16, BFloat16} {
+		t.Run(scheme.String(), func(t *testing.T) {
+			p, err := NewParam(value, scheme)
+			require.NoError(t, err)
+			assert.InDeltaSlice(t, value.Data().F32(), p.Value().Data().F32(), 0.01)
+
+			p.ReplaceValue(value.ProdScalar(2))
+			assert.InDeltaSlice(t, value.ProdScalar(2).Data().F32(), p.Value().Data().F32(), 0.02)
+			assert.Equal(t, scheme, p.Scheme)
+
+			var buf bytes.Buffer
+			require.NoError(t, gob.NewEncoder(&buf).Encode(&struct{ W nn.Param }{p}))
+			var decoded struct{ W nn.Param }
+			require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+			assert.Equal(t, p.Value().Data().F32(), decoded.W.Value().Data().F32())
+		})
+	}
+
+	_, err := NewParam(value, None)
+	assert.Error(t, err)
+}
+
+func TestWeightsMatrix(t *testing.T) {
+	value := mat.NewDense[float32](2, 3, []float32{0.1, 0.2, 0.3, -0.4, 0.5, -0.6})
+	x := mat.NewDense[float32](3, 2, []float32{1, 0.5, -2, 0, 3, -1})
+	for _, scheme := range []Scheme{Int8, Float16, BFloat16} {
+		t.Run(scheme.String(), func(t *testing.T) {
+			p, err := NewParam(value, scheme)
+			require.NoError(t, err)
+			w := p.Value()
+			assert.Equal(t, 2, w.Rows())
+			assert.Equal(t, 3, w.Columns())
+			assert.Equal(t, 6, w.Size())
+			assert.Same(t, w, w.Value())
+			assert.False(t, w.RequiresGrad())
+
+			// The products with the vectors and with the matrices.
+			for j := 0; j < 2; j++ {
+				col := x.ExtractColumn(j)
+				assert.InDeltaSlice(t, value.Mul(col).Data().F32(), w.Mul(col).Data().F32(), 0.02)
+			}
+			got := w.Mul(x)
+			assert.Equal(t, 2, got.Rows())
+			assert.Equal(t, 2, got.Columns())
+			assert.InDeltaSlice(t, value.Mul(x).Data().F32(), got.Data().F32(), 0.02)
+
+			// The linear layers compute their products on the weights.
+			l := linear.New[float32](3, 2)
+			l.W = p
+			l.B.ReplaceValue(mat.NewVecDense[float32]([]float32{1, 2}))
+			want := value.Mul(x.ExtractColumn(0)).Add(l.B.Value()).Data().F32()
+			assert.InDeltaSlice(t, want, l.Forward(x.ExtractColumn(0))[0].Value().Data().F32(), 0.02)
+
+			// The other methods compute on the dequantized matrix.
+			assert.InDeltaSlice(t, value.T().Data().F32(), w.T().Data().F32(), 0.01)
+			assert.InDelta(t, value.Sum().Scalar().F64(), w.Sum().Scalar().F64(), 0.02)
+			assert.Equal(t, []float32{0, 0}, w.NewEmptyVec(2).Data().F32())
+
+			assert.PanicsWithError(t, errReadOnly.Error(), func() { w.AddInPlace(value) })
+			assert.PanicsWithError(t, errReadOnly.Error(), func() { w.SetData(value.Data()) })
+		})
+	}
+}
+
+// BenchmarkLinear compares the forward pass of a linear layer, over the
+// tokens of a sequence, with full-precision weights and with the quantized
+// ones, reporting the memory of the weights.
+func BenchmarkLinear(b *testing.B) {
+	const size, tokens = 768, 128
+	rng := rand.New(rand.NewSource(1))
+	value := mat.NewInitFuncDense[float32](size, size, func(int, int) float32 {
+		return float32(rng.NormFloat64())
+	})
+	xs := make([]ag.Node, tokens)
+	for i := range xs {
+		xs[i] = mat.NewInitFuncDense[float32](size, 1, func(int, int) float32 {
+			return float32(rng.NormFloat64())
+		})
+	}
+
+	for _, scheme := range []Scheme{None, Int8, Float16, BFloat16} {
+		b.Run(scheme.String(), func(b *testing.B) {
+			l := linear.New[float32](size, size)
+			l.W.ReplaceValue(value)
+			weightBytes := size * size * 4
+			if scheme != None {
+				p, err := NewParam(value, scheme)
+				require.NoError(b, err)
+				l.W = p
+				switch w := p.W.(type) {
+				case *Int8Matrix:
+					weightBytes = len(w.Data) + len(w.Scales)*4
+				case *HalfMatrix:
+					weightBytes = len(w.Data) * 2
+				}
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				l.Forward(xs...)
+			}
+			b.ReportMetric(float64(weightBytes), "weight-bytes")
+		})
+	}
+}
+
+func TestKernels(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{0, 1, 7, 8, 15, 16, 17, 31, 32, 33, 64, 100} {
+		a, b := make([]int8, n), make([]int8, n)
+		w, x := make([]uint16, n), make([]float32, n)
+		for i := 0; i < n; i++ {
+			a[i], b[i] = int8(rng.Intn(255)-127), int8(rng.Intn(255)-127)
+			w[i], x[i] = Float32ToFloat16(float32(rng.NormFloat64())), float32(rng.NormFloat64())
+		}
+		assert.Equal(t, dotInt8Generic(a, b), dotInt8Kernel(a, b), "int8 of size %d on %s", n, kernelsISA)
+		assert.InDelta(t, dotFloat16Generic(w, x), dotFloat16Kernel(w, x), 1e-3, "float16 of size %d on %s", n, kernelsISA)
+		assert.InDelta(t, dotBFloat16Generic(w, x), dotBFloat16Kernel(w, x), 1e-3, "bfloat16 of size %d on %s", n, kernelsISA)
+
+		var want float32
+		for i, v := range w {
+			want += Float16ToFloat32(v) * x[i]
+		}
+		assert.InDelta(t, want, dotFloat16Generic(w, x), 1e-3)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package responsecache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU is an in-memory cache evicting the least recently used entries.
+type LRU struct {
+	size int
+	// now returns the current time, overridden by the tests.
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order lists the entries from the most to the least recently used.
+	order *list.List
+}
+
+// lruEntry is an entry of the LRU cache.
+type lruEntry struct {
+	key   string
+	value []byte
+	// expires is the expiration time, or zero if the entry doesn't expire.
+	expires time.Time
+}
+
+// NewLRU returns an LRU cache holding up to size entries.
+func NewLRU(size int) *LRU {
+	return &LRU{
+		size:    size,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Get returns the value of the key, and whether it's found and not expired.
+func (c *LRU) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*lruEntry)
+	if !e.expires.IsZero() && !c.now().Before(e.expires) {
+		c.remove(el)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(el)
+	return e.value, true, nil
+}
+
+// Set sets the value of the key, evicting the least recently used entry if
+// the cache is full.
+func (c *LRU) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	e := &lruEntry{key: key, value: value}
+	if ttl > 0 {
+		e.expires = c.now().Add(ttl)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return nil
+	}
+	c.entries[key] = c.order.PushFront(e)
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+// Len returns the number of entries, the expired ones included.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*lruEntry).key)
+}
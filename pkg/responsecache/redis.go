@@ -0,0 +1,249 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package responsecache
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// redisMaxIdle is the maximum number of idle connections kept open.
+	redisMaxIdle = 8
+	// redisDialTimeout is the timeout of the connection to Redis.
+	redisDialTimeout = 5 * time.Second
+)
+
+// Redis is a cache stored in Redis, through a minimal client of the RESP
+// protocol.
+type Redis struct {
+	addr     string
+	tls      bool
+	user     string
+	password string
+	db       int
+
+	mu   sync.Mutex
+	idle []*redisConn
+}
+
+// redisConn is a connection to Redis.
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// NewRedis returns the cache stored in Redis at the URL
+// "redis://[[user]:password@]host[:port][/db]", or "rediss://" for TLS.
+// The connections are opened on demand.
+func NewRedis(u *url.URL) (*Redis, error) {
+	r := &Redis{addr: u.Host, tls: u.Scheme == "rediss"}
+	if u.Port() == "" {
+		r.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		r.user = u.User.Username()
+		r.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		n, err := strconv.Atoi(db)
+		if err != nil {
+			return nil, fmt.Errorf("responsecache: invalid Redis database %#v", db)
+		}
+		r.db = n
+	}
+	return r, nil
+}
+
+// Get returns the value of the key, and whether it's found.
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := r.do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("responsecache: unexpected Redis reply %v", reply)
+	}
+	return value, true, nil
+}
+
+// Set sets the value of the key, expiring after the TTL, if positive.
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := r.do(ctx, args...)
+	return err
+}
+
+// Do sends the command, returning the reply: nil, a string for simple
+// strings, an int64, a []byte for bulk strings or a []any for arrays. It
+// lets the other packages sharing the Redis of the cache, e.g. for their
+// queues, send their own commands.
+func (r *Redis) Do(ctx context.Context, args ...string) (any, error) {
+	return r.do(ctx, args...)
+}
+
+// do sends the command on an idle connection, or on a new one, returning the
+// reply: nil, a string for simple strings, an int64, a []byte for bulk
+// strings or a []any for arrays.
+func (r *Redis) do(ctx context.Context, args ...string) (any, error) {
+	c, err := r.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = c.conn.SetDeadline(deadline)
+	} else {
+		_ = c.conn.SetDeadline(time.Time{})
+	}
+	reply, err := c.do(args...)
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		// The state of the connection is unknown.
+		_ = c.conn.Close()
+		return nil, fmt.Errorf("responsecache: redis: %w", err)
+	}
+	r.put(c)
+	if err != nil {
+		return nil, fmt.Errorf("responsecache: redis: %w", err)
+	}
+	return reply, nil
+}
+
+// get returns an idle connection, or a new one.
+func (r *Redis) get(ctx context.Context) (*redisConn, error) {
+	r.mu.Lock()
+	if n := len(r.idle); n > 0 {
+		c := r.idle[n-1]
+		r.idle = r.idle[:n-1]
+		r.mu.Unlock()
+		return c, nil
+	}
+	r.mu.Unlock()
+	return r.dial(ctx)
+}
+
+// put returns the connection to the idle ones, or closes it.
+func (r *Redis) put(c *redisConn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.idle) >= redisMaxIdle {
+		_ = c.conn.Close()
+		return
+	}
+	r.idle = append(r.idle, c)
+}
+
+// dial opens a connection, authenticating and selecting the database.
+func (r *Redis) dial(ctx context.Context) (*redisConn, error) {
+	d := &net.Dialer{Timeout: redisDialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", r.addr)
+	if err != nil {
+		return nil, fmt.Errorf("responsecache: redis: %w", err)
+	}
+	if r.tls {
+		host, _, _ := net.SplitHostPort(r.addr)
+		conn = tls.Client(conn, &tls.Config{ServerName: host})
+	}
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	_ = conn.SetDeadline(time.Now().Add(redisDialTimeout))
+	if r.password != "" {
+		args := []string{"AUTH", r.password}
+		if r.user != "" {
+			args = []string{"AUTH", r.user, r.password}
+		}
+		if _, err := c.do(args...); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("responsecache: redis: %w", err)
+		}
+	}
+	if r.db != 0 {
+		if _, err := c.do("SELECT", strconv.Itoa(r.db)); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("responsecache: redis: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// redisError is an error reply.
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+// do sends the command and reads the reply.
+func (c *redisConn) do(args ...string) (any, error) {
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// readReply reads a reply of the RESP protocol.
+func (c *redisConn) readReply() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err // nil bulk string
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err // nil array
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("invalid reply %q", line)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package responsecache implements the stores where the responses to the
+// requests can be cached, so that repeated identical requests, e.g. for the
+// embeddings of popular queries, skip the inference entirely.
+//
+// The supported stores are an in-memory LRU cache ("memory") and Redis
+// ("redis://[:password@]host[:port][/db]", or "rediss://" for TLS), which
+// can be shared by a fleet of servers.
+package responsecache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultSize is the default maximum number of entries of the in-memory cache.
+const DefaultSize = 10000
+
+// Store is a store of cached responses.
+type Store interface {
+	// Get returns the value of the key, and whether it's found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set sets the value of the key, expiring after the TTL, if positive.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// Open returns the store for the given URL: "memory" for an in-memory LRU
+// cache holding up to size entries (default DefaultSize), or a Redis URL.
+func Open(rawURL string, size int) (Store, error) {
+	if rawURL == "memory" {
+		if size <= 0 {
+			size = DefaultSize
+		}
+		return NewLRU(size), nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("responsecache: invalid URL %#v: %w", rawURL, err)
+	}
+	switch u.Scheme {
+	case "redis", "rediss":
+		return NewRedis(u)
+	default:
+		return nil, fmt.Errorf("responsecache: unsupported cache %#v", rawURL)
+	}
+}
+
+// Key returns the key of a response, from the parts identifying the
+// request, e.g. the task, the model and the encoded request.
+func Key(parts ...[]byte) string {
+	h := sha256.New()
+	for _, p := range parts {
+		// The length prefix avoids collisions between different splits.
+		_, _ = fmt.Fprintf(h, "%d:", len(p))
+		_, _ = h.Write(p)
+	}
+	return "cybertron:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// Normalize normalizes the text of an input, so that inputs differing only
+// by Unicode composition or by leading, trailing and repeated whitespace
+// share the same key. It's meant for the inputs whose responses carry no
+// offsets nor texts of the input, e.g. the embeddings.
+func Normalize(text string) string {
+	return strings.Join(strings.Fields(norm.NFC.String(text)), " ")
+}
@@ -0,0 +1,184 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package responsecache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKey(t *testing.T) {
+	k := Key([]byte("textencoding"), []byte("model"), []byte(Normalize("  Hello \t  world\n")))
+	assert.Equal(t, k, Key([]byte("textencoding"), []byte("model"), []byte(Normalize("Hello world"))))
+	assert.True(t, strings.HasPrefix(k, "cybertron:"))
+	assert.NotEqual(t, Key([]byte("ab"), []byte("c")), Key([]byte("a"), []byte("bc")))
+	assert.Equal(t, "café", Normalize("café"))
+}
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := NewLRU(2)
+	c.now = func() time.Time { return now }
+
+	require.NoError(t, c.Set(ctx, "a", []byte("1"), 0))
+	require.NoError(t, c.Set(ctx, "b", []byte("2"), time.Minute))
+	v, ok, err := c.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), v)
+
+	// "b" is the least recently used.
+	require.NoError(t, c.Set(ctx, "c", []byte("3"), 0))
+	_, ok, _ = c.Get(ctx, "b")
+	assert.False(t, ok)
+	assert.Equal(t, 2, c.Len())
+
+	require.NoError(t, c.Set(ctx, "d", []byte("4"), time.Minute))
+	now = now.Add(time.Minute)
+	_, ok, _ = c.Get(ctx, "d")
+	assert.False(t, ok)
+	_, ok, _ = c.Get(ctx, "c")
+	assert.True(t, ok)
+}
+
+// fakeRedis is a Redis server supporting AUTH, SELECT, GET and SET.
+type fakeRedis struct {
+	lis net.Listener
+
+	mu       sync.Mutex
+	values   map[string]string
+	commands []string
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeRedis{lis: lis, values: map[string]string{}}
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { _ = lis.Close() })
+	return s
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, strings.Join(args, " "))
+		var reply string
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			if args[len(args)-1] == "secret" {
+				reply = "+OK\r\n"
+			} else {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case "SELECT":
+			reply = "+OK\r\n"
+		case "GET":
+			if v, ok := s.values[args[1]]; ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+			} else {
+				reply = "$-1\r\n"
+			}
+		case "SET":
+			s.values[args[1]] = args[2]
+			reply = "+OK\r\n"
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		s.mu.Unlock()
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func TestRedis(t *testing.T) {
+	s := newFakeRedis(t)
+	ctx := context.Background()
+	store, err := Open(fmt.Sprintf("redis://:secret@%s/2", s.lis.Addr()), 0)
+	require.NoError(t, err)
+
+	_, ok, err := store.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.NoError(t, store.Set(ctx, "k", []byte("v\r\n1"), 1500*time.Millisecond))
+	v, ok, err := store.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("v\r\n1"), v)
+
+	s.mu.Lock()
+	assert.Equal(t, []string{"AUTH secret", "SELECT 2", "GET k", "SET k v\r\n1 PX 1500", "GET k"}, s.commands)
+	s.mu.Unlock()
+
+	u, _ := url.Parse(fmt.Sprintf("redis://:wrong@%s", s.lis.Addr()))
+	bad, err := NewRedis(u)
+	require.NoError(t, err)
+	_, _, err = bad.Get(ctx, "k")
+	assert.ErrorContains(t, err, "WRONGPASS")
+}
+
+func TestOpen(t *testing.T) {
+	store, err := Open("memory", 0)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultSize, store.(*LRU).size)
+	_, err = Open("memcached://localhost", 0)
+	assert.Error(t, err)
+}
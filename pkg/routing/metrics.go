@@ -0,0 +1,104 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics accounts the requests served by the versions of the models. It's
+// safe for concurrent use.
+type Metrics struct {
+	mu    sync.Mutex
+	stats []*Stats
+}
+
+// Stats are the statistics of the requests of a version of a model.
+type Stats struct {
+	model, version string
+	shadow         bool
+	requests       atomic.Int64
+	errors         atomic.Int64
+	dropped        atomic.Int64
+	// nanos is the total time spent serving the requests.
+	nanos atomic.Int64
+}
+
+// Register returns the statistics of the version of the model, exported by
+// WriteMetrics. With a nil Metrics, they're just not exported.
+func (m *Metrics) Register(model, version string, shadow bool) *Stats {
+	s := &Stats{model: model, version: version, shadow: shadow}
+	if m != nil {
+		m.mu.Lock()
+		m.stats = append(m.stats, s)
+		m.mu.Unlock()
+	}
+	return s
+}
+
+// Observe records a request served in the duration, failed if err is not nil.
+func (s *Stats) Observe(d time.Duration, err error) {
+	s.requests.Add(1)
+	if err != nil {
+		s.errors.Add(1)
+	}
+	s.nanos.Add(int64(d))
+}
+
+// Drop records a request not sent to the shadow version, because too many
+// were already in flight.
+func (s *Stats) Drop() {
+	s.dropped.Add(1)
+}
+
+// metric is a metric of the versions of the models.
+type metric struct {
+	name, kind, help string
+	value            func(*Stats) float64
+}
+
+var metrics = []metric{
+	{"cybertron_model_version_requests_total", "counter", "Requests served per model version.",
+		func(s *Stats) float64 { return float64(s.requests.Load()) }},
+	{"cybertron_model_version_errors_total", "counter", "Requests failed per model version.",
+		func(s *Stats) float64 { return float64(s.errors.Load()) }},
+	{"cybertron_model_version_request_seconds_total", "counter", "Time spent serving the requests per model version.",
+		func(s *Stats) float64 { return time.Duration(s.nanos.Load()).Seconds() }},
+	{"cybertron_model_version_dropped_requests_total", "counter", "Requests not mirrored to the shadow versions, because too many were in flight.",
+		func(s *Stats) float64 { return float64(s.dropped.Load()) }},
+}
+
+// labelEscaper escapes the values of the labels.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteMetrics writes the statistics of the versions of the models in the
+// Prometheus text exposition format, labeled by model, version and role,
+// "serving" or "shadow".
+func (m *Metrics) WriteMetrics(w io.Writer) error {
+	m.mu.Lock()
+	stats := append([]*Stats(nil), m.stats...)
+	m.mu.Unlock()
+	bw := bufio.NewWriter(w)
+	for _, mt := range metrics {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", mt.name, mt.help, mt.name, mt.kind)
+		for _, s := range stats {
+			role := "serving"
+			if s.shadow {
+				role = "shadow"
+			}
+			fmt.Fprintf(bw, "%s{model=\"%s\",version=\"%s\",role=\"%s\"} %s\n", mt.name,
+				labelEscaper.Replace(s.model), labelEscaper.Replace(s.version), role,
+				strconv.FormatFloat(mt.value(s), 'g', -1, 64))
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// DefaultVirtualNodes is the default number of virtual nodes of each
+// replica of a Ring.
+const DefaultVirtualNodes = 100
+
+// Ring routes the keys, e.g. the IDs of the conversations, to the replicas
+// of a server by consistent hashing, so that the requests with the same key
+// are always served by the same replica, e.g. the one holding the state of
+// the conversation. Each replica owns the arcs of the ring ending at its
+// virtual nodes, so that adding or removing a replica only moves the keys of
+// its own arcs, about 1/n of them, to or from the other replicas.
+type Ring struct {
+	// hashes are the sorted positions of the virtual nodes on the ring.
+	hashes []uint64
+	// owners are the replicas of the virtual nodes, by position.
+	owners []string
+}
+
+// NewRing returns the ring of the replicas, e.g. their addresses, each with
+// the given number of virtual nodes (default DefaultVirtualNodes).
+func NewRing(replicas []string, virtualNodes int) (*Ring, error) {
+	if len(replicas) == 0 {
+		return nil, fmt.Errorf("routing: no replicas")
+	}
+	if virtualNodes <= 0 {
+		virtualNodes = DefaultVirtualNodes
+	}
+	type node struct {
+		hash  uint64
+		owner string
+	}
+	nodes := make([]node, 0, len(replicas)*virtualNodes)
+	seen := make(map[string]bool, len(replicas))
+	for _, r := range replicas {
+		if seen[r] {
+			return nil, fmt.Errorf("routing: duplicate replica %#v", r)
+		}
+		seen[r] = true
+		for i := 0; i < virtualNodes; i++ {
+			nodes = append(nodes, node{hash: ringHash(r + "#" + strconv.Itoa(i)), owner: r})
+		}
+	}
+	// The ties are broken by the replica, so that the ring doesn't depend
+	// on the order of the replicas.
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].hash != nodes[j].hash {
+			return nodes[i].hash < nodes[j].hash
+		}
+		return nodes[i].owner < nodes[j].owner
+	})
+	r := &Ring{hashes: make([]uint64, len(nodes)), owners: make([]string, len(nodes))}
+	for i, n := range nodes {
+		r.hashes[i], r.owners[i] = n.hash, n.owner
+	}
+	return r, nil
+}
+
+// Pick returns the replica of the key: the one of the first virtual node
+// following the key on the ring.
+func (r *Ring) Pick(key string) string {
+	h := ringHash(key)
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.owners[i]
+}
+
+// ringHash returns the position of the string on the ring.
+func ringHash(s string) uint64 {
+	sum := sha256.Sum256([]byte(s))
+	return binary.BigEndian.Uint64(sum[:8])
+}
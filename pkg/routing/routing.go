@@ -0,0 +1,57 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package routing splits the traffic of a model between its versions, e.g.
+// to roll out a new version to a share of the requests, and accounts the
+// requests served by each version, and by the shadow versions, which are
+// sent a copy of the requests and whose results are discarded. It also routes
+// the requests of the same conversation to the same replica of a server,
+// by consistent hashing.
+package routing
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Split picks the versions serving the requests by their shares of the
+// traffic.
+type Split struct {
+	// cumulative are the cumulative shares of the versions, the last one
+	// being one.
+	cumulative []float64
+}
+
+// NewSplit returns the split of the traffic by the shares of the versions,
+// scaled to sum to one.
+func NewSplit(shares []float64) (Split, error) {
+	if len(shares) == 0 {
+		return Split{}, fmt.Errorf("routing: no versions")
+	}
+	sum := 0.0
+	for _, s := range shares {
+		if !(s > 0) {
+			return Split{}, fmt.Errorf("routing: invalid share %v", s)
+		}
+		sum += s
+	}
+	cumulative := make([]float64, len(shares))
+	acc := 0.0
+	for i, s := range shares {
+		acc += s
+		cumulative[i] = acc / sum
+	}
+	cumulative[len(cumulative)-1] = 1
+	return Split{cumulative: cumulative}, nil
+}
+
+// Pick returns the index of the version serving a request, given a random
+// number uniformly distributed in [0, 1).
+func (s Split) Pick(x float64) int {
+	i := sort.Search(len(s.cumulative), func(i int) bool { return s.cumulative[i] > x })
+	if i == len(s.cumulative) {
+		i--
+	}
+	return i
+}
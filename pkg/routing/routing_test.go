@@ -0,0 +1,94 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplit(t *testing.T) {
+	s, err := NewSplit([]float64{90, 10})
+	require.NoError(t, err)
+	assert.Equal(t, 0, s.Pick(0))
+	assert.Equal(t, 0, s.Pick(0.89))
+	assert.Equal(t, 1, s.Pick(0.9))
+	assert.Equal(t, 1, s.Pick(0.999))
+
+	counts := make([]int, 2)
+	for i := 0; i < 1000; i++ {
+		counts[s.Pick(float64(i)/1000)]++
+	}
+	assert.Equal(t, []int{900, 100}, counts)
+}
+
+func TestSplitErrors(t *testing.T) {
+	_, err := NewSplit(nil)
+	assert.Error(t, err)
+	_, err = NewSplit([]float64{1, 0})
+	assert.Error(t, err)
+}
+
+func TestWriteMetrics(t *testing.T) {
+	var m Metrics
+	v1 := m.Register("clf", "org/clf@v1", false)
+	v2 := m.Register("clf", "org/clf@v2", true)
+	v1.Observe(500*time.Millisecond, nil)
+	v1.Observe(time.Second, errors.New("failure"))
+	v2.Drop()
+
+	var b strings.Builder
+	require.NoError(t, m.WriteMetrics(&b))
+	out := b.String()
+	assert.Contains(t, out, "# TYPE cybertron_model_version_requests_total counter\n")
+	assert.Contains(t, out, `cybertron_model_version_requests_total{model="clf",version="org/clf@v1",role="serving"} 2`)
+	assert.Contains(t, out, `cybertron_model_version_errors_total{model="clf",version="org/clf@v1",role="serving"} 1`)
+	assert.Contains(t, out, `cybertron_model_version_request_seconds_total{model="clf",version="org/clf@v1",role="serving"} 1.5`)
+	assert.Contains(t, out, `cybertron_model_version_dropped_requests_total{model="clf",version="org/clf@v2",role="shadow"} 1`)
+}
+
+func TestRegisterNil(t *testing.T) {
+	var m *Metrics
+	s := m.Register("clf", "v1", false)
+	s.Observe(time.Second, nil)
+	assert.Equal(t, int64(1), s.requests.Load())
+}
+
+func TestRing(t *testing.T) {
+	_, err := NewRing(nil, 0)
+	assert.Error(t, err)
+	_, err = NewRing([]string{"a", "a"}, 0)
+	assert.Error(t, err)
+
+	r, err := NewRing([]string{"a:8080", "b:8080", "c:8080"}, 0)
+	require.NoError(t, err)
+	r2, err := NewRing([]string{"c:8080", "a:8080", "b:8080"}, 0)
+	require.NoError(t, err)
+	counts := make(map[string]int)
+	for i := 0; i < 3000; i++ {
+		key := fmt.Sprintf("conversation-%d", i)
+		counts[r.Pick(key)]++
+		assert.Equal(t, r.Pick(key), r2.Pick(key))
+	}
+	for _, n := range counts {
+		assert.InDelta(t, 1000, n, 250)
+	}
+
+	// Only the keys of the removed replica move.
+	r3, err := NewRing([]string{"a:8080", "b:8080"}, 0)
+	require.NoError(t, err)
+	for i := 0; i < 3000; i++ {
+		key := fmt.Sprintf("conversation-%d", i)
+		if owner := r.Pick(key); owner != "c:8080" {
+			assert.Equal(t, owner, r3.Pick(key))
+		}
+	}
+}
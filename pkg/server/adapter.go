@@ -0,0 +1,39 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/nlpodyssey/cybertron/pkg/lora"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// adapterHeader is the HTTP header, or gRPC metadata, naming the LoRA
+// adapter applied to the model serving a request (see tasks.Config), none
+// by default. It's ignored by the models without adapters.
+const adapterHeader = "cybertron-adapter"
+
+// adapterInterceptor sets the adapter of the gRPC requests from their
+// metadata.
+func adapterInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(adapterHeader); len(v) > 0 && v[0] != "" {
+		ctx = lora.NewContext(ctx, v[0])
+	}
+	return handler(ctx, req)
+}
+
+// withAdapter sets the adapter of the HTTP requests from their header.
+func withAdapter(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Header.Get(adapterHeader); v != "" {
+			r = r.WithContext(lora.NewContext(r.Context(), v))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
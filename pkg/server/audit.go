@@ -0,0 +1,107 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/nlpodyssey/cybertron/pkg/audit"
+	"github.com/nlpodyssey/cybertron/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// AuditLog records the requests of a task service, with their responses, in
+// an audit log. The audit log is best effort: its failures are logged, and
+// the requests served anyway.
+type AuditLog struct {
+	// Logger writes the records.
+	Logger *audit.Logger
+	// Model identifies the model, e.g. its name and revision.
+	Model string
+}
+
+// WithAuditLog sets the audit log of the request handler returned by
+// ResolveRequestHandler, and returns it.
+func WithAuditLog(rh RequestHandler, al *AuditLog) RequestHandler {
+	if h, ok := rh.(interface{ shared() *sharedResponses }); ok {
+		h.shared().audit = al
+	}
+	return rh
+}
+
+// callerKey is the context key of the caller of a request.
+type callerKey struct{}
+
+// withCaller returns a copy of the context with the caller of the request,
+// e.g. its tenant.
+func withCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// caller returns the caller of the request: its tenant, if identified, or
+// its address.
+func caller(ctx context.Context) string {
+	if c, ok := ctx.Value(callerKey{}).(string); ok {
+		return c
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	// Set by the HTTP gateway.
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get("x-forwarded-for"); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// method returns the full name of the method of the request.
+func method(ctx context.Context, req proto.Message) string {
+	if m, ok := grpc.Method(ctx); ok && m != "" {
+		return m
+	}
+	if m, ok := runtime.RPCMethod(ctx); ok {
+		return m
+	}
+	// Served over NATS.
+	return string(req.ProtoReflect().Descriptor().FullName())
+}
+
+// log records the request received at the start time, with its response or
+// error.
+func (al *AuditLog) log(ctx context.Context, start time.Time, req, resp proto.Message, err error) {
+	r := audit.Record{
+		Time:    start,
+		Method:  method(ctx, req),
+		Model:   al.Model,
+		Caller:  caller(ctx),
+		Request: marshalAudit(req),
+		Latency: time.Since(start),
+	}
+	if err != nil {
+		r.Error = status.Convert(err).Message()
+	} else {
+		r.Response = marshalAudit(resp)
+	}
+	if err := al.Logger.Log(r); err != nil {
+		logging.Ctx(ctx).Warn().Err(err).Msg("failed to write audit record")
+	}
+}
+
+// marshalAudit returns the JSON encoding of the message.
+func marshalAudit(m proto.Message) string {
+	data, err := protojson.Marshal(m)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,325 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// bulkPath is the HTTP path of the bulk uploads, by the name of the task,
+// e.g. "text-encoding", or of its method, e.g.
+// "textencoding.v1.TextEncodingService.Encode".
+const bulkPath = "/v1/bulk/{task}"
+
+// bulkBatchSize is the number of rows of a bulk upload served concurrently.
+const bulkBatchSize = 32
+
+// bulkMethod returns the name and method of the task, by its name or by the
+// name of its method.
+func (r methodRegistry) bulkMethod(task string) (string, natsMethod, bool) {
+	for name, t := range taskNames {
+		if m, ok := r[name]; ok && t == task {
+			return name, m, true
+		}
+	}
+	m, ok := r[task]
+	return task, m, ok
+}
+
+// bulkCall returns the name of the method of the task of a bulk upload, or
+// of an async job, and the function serving its rows: the method of the
+// generation, or, on a frontend of the work queue, its workers.
+func (s *Server) bulkCall(methods methodRegistry, task string) (string, func(context.Context, []byte) []byte, error) {
+	if s.queue != nil {
+		name, ok := queueMethod(task)
+		if !ok {
+			return "", nil, fmt.Errorf("%w: unknown task %#v", errdefs.ErrInvalidRequest, task)
+		}
+		return name, s.queueCall(name), nil
+	}
+	name, m, ok := methods.bulkMethod(task)
+	if !ok {
+		return "", nil, fmt.Errorf("%w: no model serving task %#v", errdefs.ErrModelNotLoaded, task)
+	}
+	return name, func(ctx context.Context, data []byte) []byte {
+		return m.call(ctx, data, chainUnaryInterceptors(s.rowInterceptors()...))
+	}, nil
+}
+
+// requestDescriptor returns the descriptor of the request of the method.
+func requestDescriptor(name string) (protoreflect.MessageDescriptor, error) {
+	service := parentName(name)
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, err
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+	md := sd.Methods().ByName(protoreflect.Name(name[len(service)+1:]))
+	if md == nil {
+		return nil, fmt.Errorf("unknown method %s", name)
+	}
+	return md.Input(), nil
+}
+
+// bulkRows reads the rows of a bulk upload as the JSON requests of the
+// method.
+type bulkRows interface {
+	// next returns the next request, or the error of the row, or io.EOF
+	// at the end of the upload; any other error stops the upload.
+	next() (data []byte, rowErr error, err error)
+}
+
+// jsonlRows are the rows of a JSON lines upload, each the JSON request.
+type jsonlRows struct {
+	scanner *bufio.Scanner
+}
+
+func (r *jsonlRows) next() ([]byte, error, error) {
+	for r.scanner.Scan() {
+		if line := bytes.TrimSpace(r.scanner.Bytes()); len(line) > 0 {
+			return append([]byte(nil), line...), nil, nil
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return nil, nil, io.EOF
+}
+
+// csvRows are the rows of a CSV or TSV upload, with a header naming the
+// fields of the request, by their proto or JSON names: the cells of the
+// scalar fields are their text, "true" or "false" for a bool, and the ones
+// of the repeated and message fields their JSON. The empty cells are unset.
+type csvRows struct {
+	r      *csv.Reader
+	fields []protoreflect.FieldDescriptor
+}
+
+func newCSVRows(r io.Reader, comma rune, md protoreflect.MessageDescriptor) (*csvRows, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read the header: %v", errdefs.ErrInvalidRequest, err)
+	}
+	fields := make([]protoreflect.FieldDescriptor, len(header))
+	for i, name := range header {
+		if fields[i] = lookupField(md, name); fields[i] == nil {
+			return nil, fmt.Errorf("%w: unknown field %#v of %s", errdefs.ErrInvalidRequest, name, md.Name())
+		}
+	}
+	return &csvRows{r: cr, fields: fields}, nil
+}
+
+func (r *csvRows) next() ([]byte, error, error) {
+	record, err := r.r.Read()
+	var pe *csv.ParseError
+	if errors.As(err, &pe) {
+		return nil, fmt.Errorf("%w: %v", errdefs.ErrInvalidRequest, err), nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(record) > len(r.fields) {
+		return nil, fmt.Errorf("%w: row with %d cells for %d fields", errdefs.ErrInvalidRequest, len(record), len(r.fields)), nil
+	}
+	req := make(map[string]any, len(record))
+	for i, cell := range record {
+		if cell == "" {
+			continue
+		}
+		fd := r.fields[i]
+		switch {
+		case fd.IsList() || fd.IsMap() || fd.Message() != nil:
+			if !json.Valid([]byte(cell)) {
+				return nil, fmt.Errorf("%w: invalid JSON of field %s", errdefs.ErrInvalidRequest, fd.Name()), nil
+			}
+			req[string(fd.Name())] = json.RawMessage(cell)
+		case fd.Kind() == protoreflect.BoolKind:
+			b, err := strconv.ParseBool(cell)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid bool of field %s", errdefs.ErrInvalidRequest, fd.Name()), nil
+			}
+			req[string(fd.Name())] = b
+		default:
+			// The numbers and the enums are parsed from the strings too.
+			req[string(fd.Name())] = cell
+		}
+	}
+	data, err := json.Marshal(req)
+	return data, err, nil
+}
+
+// openBulkRows returns the rows of the upload, in the format of its content
+// type: CSV (text/csv), TSV (text/tab-separated-values), or JSON lines
+// otherwise.
+func openBulkRows(body io.Reader, contentType string, md protoreflect.MessageDescriptor) (bulkRows, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return newCSVRows(body, ',', md)
+	case "text/tab-separated-values":
+		return newCSVRows(body, '\t', md)
+	default:
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		return &jsonlRows{scanner: scanner}, nil
+	}
+}
+
+// spoolBody copies the body of the request to a temporary file, returning
+// it rewound, to be removed once closed.
+func spoolBody(body io.Reader) (*os.File, error) {
+	f, err := os.CreateTemp("", "cybertron-bulk-*")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Remove(f.Name()); err != nil {
+		log.Warn().Err(err).Msg("failed to remove the bulk upload file")
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// serveBulk serves the bulk uploads of the methods: each row of the body,
+// in JSON lines, CSV or TSV, is a request of the task, and the responses
+// are streamed back as JSON lines, in the order of the rows, each the JSON
+// response or the error of its row, as over NATS. The rows are served in
+// batches of bulkBatchSize, concurrently, each batch written and flushed
+// once complete.
+//
+// The HTTP/2 uploads are streamed in both directions, while the HTTP/1
+// ones are read whole before the first response, since HTTP/1 handlers
+// can't read the body after writing the response.
+func (s *Server) serveBulk(methods methodRegistry) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		name, call, err := s.bulkCall(methods, params["task"])
+		if err != nil {
+			writeHTTPError(w, r, err)
+			return
+		}
+		if err := s.checkSunset(parentName(parentName(name))); err != nil {
+			writeHTTPError(w, r, err)
+			return
+		}
+		md, err := requestDescriptor(name)
+		if err != nil {
+			writeHTTPError(w, r, err)
+			return
+		}
+		var body io.Reader = r.Body
+		if r.ProtoMajor < 2 {
+			f, err := spoolBody(r.Body)
+			if err != nil {
+				writeHTTPError(w, r, err)
+				return
+			}
+			defer f.Close()
+			body = f
+		}
+		rows, err := openBulkRows(body, r.Header.Get("Content-Type"), md)
+		if err != nil {
+			writeHTTPError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		flush := func() {}
+		if f, ok := w.(http.Flusher); ok {
+			flush = f.Flush
+		}
+		_, _, err = serveRows(r.Context(), rows, call, w, flush)
+		if errors.Is(err, errdefs.ErrInvalidRequest) {
+			log.Warn().Err(err).Str("method", name).Msg("failed to read the bulk upload")
+			_, _ = w.Write(append(natsError(statusError(err)), '\n'))
+		}
+	}
+}
+
+// isErrorResponse returns whether the JSON response is an error, as
+// returned by natsError.
+func isErrorResponse(resp []byte) bool {
+	return bytes.HasPrefix(resp, []byte(`{"error":`))
+}
+
+// serveRows serves the rows with the function, in batches of bulkBatchSize
+// rows served concurrently, writing their responses as JSON lines, in the
+// order of the rows, and calling flush after each batch. It returns the
+// number of rows served and of the failed ones, once all the rows are
+// served, or the error stopping them: ErrInvalidRequest if the rows can't
+// be read, or the error of the context or of the writer.
+func serveRows(ctx context.Context, rows bulkRows, call func(context.Context, []byte) []byte, w io.Writer, flush func()) (served, failed int, err error) {
+	results := make([][]byte, bulkBatchSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return served, failed, err
+		}
+		var wg sync.WaitGroup
+		var readErr error
+		n := 0
+		for ; n < bulkBatchSize; n++ {
+			data, rowErr, err := rows.next()
+			if err != nil {
+				readErr = err
+				break
+			}
+			if rowErr != nil {
+				results[n] = natsError(statusError(rowErr))
+				continue
+			}
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i] = call(ctx, data)
+			}(n)
+		}
+		wg.Wait()
+		for _, res := range results[:n] {
+			if isErrorResponse(res) {
+				failed++
+			}
+			if _, err := w.Write(append(res, '\n')); err != nil {
+				return served, failed, err
+			}
+			served++
+		}
+		flush()
+		switch {
+		case readErr == io.EOF:
+			return served, failed, nil
+		case readErr != nil:
+			return served, failed, fmt.Errorf("%w: %v", errdefs.ErrInvalidRequest, readErr)
+		}
+	}
+}
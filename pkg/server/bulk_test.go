@@ -0,0 +1,303 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readBulkRows returns the requests and the errors of the rows, as strings,
+// until the end of the upload or the error stopping it.
+func readBulkRows(rows bulkRows) ([]string, error) {
+	var got []string
+	for {
+		data, rowErr, err := rows.next()
+		switch {
+		case err == io.EOF:
+			return got, nil
+		case err != nil:
+			return got, err
+		case rowErr != nil:
+			got = append(got, "error: "+rowErr.Error())
+		default:
+			got = append(got, string(data))
+		}
+	}
+}
+
+func TestOpenBulkRows(t *testing.T) {
+	md, err := requestDescriptor("textclassification.v1.TextClassificationService.Classify")
+	require.NoError(t, err)
+	listMD, err := requestDescriptor("textencoding.v2.TextEncodingService.Encode")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		listFields  bool
+		// want are the prefixes of the requests or of the errors.
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "json lines",
+			body: "{\"input\":\"a\"}\n\n  {\"input\":\"b\"}  \n",
+			want: []string{`{"input":"a"}`, `{"input":"b"}`},
+		},
+		{
+			name:        "csv",
+			contentType: "text/csv; charset=utf-8",
+			body:        "input,layers,explain,textPair\na,2,true,b\n\"c, d\",,false,\ne\n",
+			want: []string{
+				`{"explain":true,"input":"a","layers":"2","text_pair":"b"}`,
+				`{"explain":false,"input":"c, d"}`,
+				`{"input":"e"}`,
+			},
+		},
+		{
+			name:        "tsv",
+			contentType: "text/tab-separated-values",
+			body:        "text_pair\tinput\nb\ta, c\n",
+			want:        []string{`{"input":"a, c","text_pair":"b"}`},
+		},
+		{
+			name:        "csv list fields",
+			contentType: "text/csv",
+			body:        "inputs,vector_encoding\n\"[\"\"a\"\",\"\"b\"\"]\",PACKED\n[,FLOATS\n",
+			listFields:  true,
+			want: []string{
+				`{"inputs":["a","b"],"vector_encoding":"PACKED"}`,
+				"error: invalid request: invalid JSON of field inputs",
+			},
+		},
+		{
+			name:        "csv row errors",
+			contentType: "text/csv",
+			body:        "input,explain\na,yes\nb,true,c\nd,\"e\n",
+			want: []string{
+				"error: invalid request: invalid bool of field explain",
+				"error: invalid request: row with 3 cells for 2 fields",
+				"error: invalid request: parse error on line 4",
+			},
+		},
+		{name: "csv unknown field", contentType: "text/csv", body: "input,missing\n", wantErr: true},
+		{name: "csv without header", contentType: "text/csv", body: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := md
+			if tt.listFields {
+				d = listMD
+			}
+			rows, err := openBulkRows(strings.NewReader(tt.body), tt.contentType, d)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errdefs.ErrInvalidRequest)
+				return
+			}
+			require.NoError(t, err)
+			got, err := readBulkRows(rows)
+			require.NoError(t, err)
+			require.Len(t, got, len(tt.want))
+			for i, want := range tt.want {
+				assert.True(t, strings.HasPrefix(got[i], want), got[i])
+			}
+		})
+	}
+}
+
+func TestRequestDescriptor(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "textencoding.v1.TextEncodingService.Encode", want: "textencoding.v1.EncodingRequest"},
+		{name: "tokenclassification.v1.TokenClassificationService.Classify", want: "tokenclassification.v1.ClassifyRequest"},
+		{name: "textencoding.v1.TextEncodingService.Missing", wantErr: true},
+		{name: "textencoding.v1.MissingService.Encode", wantErr: true},
+		{name: "textencoding.v1.EncodingRequest.Input", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, err := requestDescriptor(tt.name)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(md.FullName()))
+		})
+	}
+}
+
+// sliceRows are the rows of the requests, the "fail" one failing the upload,
+// and the "invalid" ones their row.
+type sliceRows []string
+
+func (r *sliceRows) next() ([]byte, error, error) {
+	if len(*r) == 0 {
+		return nil, nil, io.EOF
+	}
+	row := (*r)[0]
+	*r = (*r)[1:]
+	switch {
+	case row == "fail":
+		return nil, nil, errors.New("broken upload")
+	case strings.HasPrefix(row, "invalid"):
+		return nil, fmt.Errorf("%w: %s", errdefs.ErrInvalidRequest, row), nil
+	}
+	return []byte(row), nil, nil
+}
+
+func TestServeRows(t *testing.T) {
+	echo := func(_ context.Context, data []byte) []byte {
+		if bytes.Equal(data, []byte("error")) {
+			return natsError(errdefs.ErrInvalidRequest)
+		}
+		return []byte(`"` + string(data) + `"`)
+	}
+	numbers := func(n int) []string {
+		rows := make([]string, n)
+		for i := range rows {
+			rows[i] = fmt.Sprint(i)
+		}
+		return rows
+	}
+
+	tests := []struct {
+		name        string
+		rows        []string
+		wantLines   int
+		wantFailed  int
+		wantFlushes int
+		wantErr     error
+	}{
+		{name: "empty", wantFlushes: 1},
+		{name: "one batch", rows: numbers(3), wantLines: 3, wantFlushes: 1},
+		{name: "full batches", rows: numbers(2 * bulkBatchSize), wantLines: 2 * bulkBatchSize, wantFlushes: 3},
+		{name: "last batch", rows: numbers(bulkBatchSize + 1), wantLines: bulkBatchSize + 1, wantFlushes: 2},
+		{name: "failed rows", rows: []string{"a", "invalid row", "error", "b"}, wantLines: 4, wantFailed: 2, wantFlushes: 1},
+		{name: "broken upload", rows: []string{"a", "b", "fail", "c"}, wantLines: 2, wantFlushes: 1, wantErr: errdefs.ErrInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := sliceRows(tt.rows)
+			var buf bytes.Buffer
+			flushes := 0
+			served, failed, err := serveRows(context.Background(), &rows, echo, &buf, func() { flushes++ })
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantLines, served)
+			assert.Equal(t, tt.wantFailed, failed)
+			assert.Equal(t, tt.wantFlushes, flushes)
+
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			if tt.wantLines == 0 {
+				lines = nil
+			}
+			require.Len(t, lines, tt.wantLines)
+			for i, line := range lines {
+				switch row := tt.rows[i]; {
+				case strings.HasPrefix(row, "invalid"), row == "error":
+					assert.True(t, isErrorResponse([]byte(line)), line)
+				default:
+					assert.Equal(t, `"`+row+`"`, line, "the responses are in the order of the rows")
+				}
+			}
+		})
+	}
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		rows := sliceRows(numbers(3))
+		served, _, err := serveRows(ctx, &rows, echo, io.Discard, func() {})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Zero(t, served)
+	})
+}
+
+func TestServeBulk(t *testing.T) {
+	s := New(&Config{}, RequestHandlers{
+		NewServerForTextEncoding(lengthEncoder{}),
+		NewServerForTokenClassification(&lastWordClassifier{}),
+	})
+	g, err := s.newGeneration(context.Background(), s.handler)
+	require.NoError(t, err)
+	s.current.Store(g)
+
+	tests := []struct {
+		name        string
+		task        string
+		contentType string
+		body        string
+		wantStatus  int
+		want        []string
+	}{
+		{
+			name:        "csv by task name",
+			task:        "text-encoding",
+			contentType: "text/csv",
+			body:        "input\nab\nabcd\n",
+			wantStatus:  http.StatusOK,
+			want:        []string{`{"vector":[2,-2,0.5]}`, `{"vector":[4,-4,0.5]}`},
+		},
+		{
+			name:       "json lines by method name",
+			task:       "tokenclassification.v1.TokenClassificationService.Classify",
+			body:       "{\"input\":\"a b\"}\n{\"input\":1}\n",
+			wantStatus: http.StatusOK,
+			want: []string{
+				`{"tokens":[{"text":"b","start":2,"end":3,"label":"B-PER","byteStart":2,"byteEnd":3}]}`,
+				`{"error":`,
+			},
+		},
+		{
+			name:       "read error",
+			task:       "text-encoding",
+			body:       "{\"input\":\"a\"}\n" + strings.Repeat("a", 16*1024*1024+1),
+			wantStatus: http.StatusOK,
+			want:       []string{`{"vector":[1,-1,0.5]}`, `{"error":{"code":3,`},
+		},
+		{name: "unknown field", task: "text-encoding", contentType: "text/csv", body: "missing\n", wantStatus: http.StatusBadRequest},
+		{name: "unknown task", task: "text-classification", body: "{}\n", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/bulk/"+tt.task, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			g.handler.ServeHTTP(w, r)
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.want == nil {
+				return
+			}
+			assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+			lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+			require.Len(t, lines, len(tt.want))
+			for i, want := range tt.want {
+				assert.True(t, strings.HasPrefix(strings.ReplaceAll(lines[i], " ", ""), want), lines[i])
+			}
+		})
+	}
+}
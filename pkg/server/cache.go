@@ -0,0 +1,259 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/logging"
+	"github.com/nlpodyssey/cybertron/pkg/lora"
+	"github.com/nlpodyssey/cybertron/pkg/responsecache"
+	textencodingv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textencoding/v1"
+	textencodingv2 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textencoding/v2"
+	"github.com/nlpodyssey/cybertron/pkg/trace"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ResponseCache caches the responses of a task service, keyed by the
+// request type, the model, and the exact request, so that repeated
+// identical requests skip the inference. Only the texts of the requests
+// whose responses carry no offsets nor texts of the input, e.g. the
+// embeddings, are normalized (see normalizedRequests). The cache is best
+// effort: its failures are logged, and the requests served as if missed.
+type ResponseCache struct {
+	// Store is where the responses are cached.
+	Store responsecache.Store
+	// Model identifies the model, e.g. its name and revision, so that the
+	// responses of different models, or of an updated one, are not mixed.
+	Model string
+	// TTL is the time to live of the responses (optional, default no expiration).
+	TTL time.Duration
+}
+
+// WithResponseCache sets the response cache of the request handler returned
+// by ResolveRequestHandler, and returns it.
+func WithResponseCache(rh RequestHandler, rc *ResponseCache) RequestHandler {
+	if h, ok := rh.(interface{ shared() *sharedResponses }); ok {
+		h.shared().cache = rc
+	}
+	return rh
+}
+
+// sharedResponses is embedded by the task services whose responses can be
+// shared among identical requests: cached, and coalesced while in flight.
+type sharedResponses struct {
+	cache   *ResponseCache
+	flights *flightGroup
+	// audit is the audit log of the requests, if enabled.
+	audit *AuditLog
+	// timeout is the maximum time to serve a request, if set.
+	timeout time.Duration
+	// normalization is the normalization of the input texts reported in
+	// the responses, if any.
+	normalization string
+	// latency records the latency of the requests, if set.
+	latency *modelLatency
+}
+
+func (sr *sharedResponses) shared() *sharedResponses {
+	return sr
+}
+
+// uncached returns the options of sr for the requests whose responses
+// must not be shared, without the cache and the coalescing.
+func (sr *sharedResponses) uncached() *sharedResponses {
+	return &sharedResponses{audit: sr.audit, timeout: sr.timeout, normalization: sr.normalization, latency: sr.latency}
+}
+
+// respond serves the request with the function, unless its response is
+// cached or already being computed for an identical request. The errors
+// are converted to gRPC statuses by statusError. The request is recorded
+// in the audit log, if enabled, with its latency, if recorded, and fails
+// once the timeout, if set, expires.
+// The normalization of the input texts, if any, is reported in the header.
+// Only the fields of the response selected in the context, if any, are
+// returned, without modifying the response shared. The traced requests are
+// neither cached nor coalesced, and their trace is reported once served.
+func respond[Req, Resp proto.Message](ctx context.Context, sr *sharedResponses, req Req, f func(context.Context, Req) (Resp, error)) (Resp, error) {
+	reportNormalization(ctx, sr.normalization)
+	if t := trace.FromContext(ctx); t != nil {
+		sr = sr.uncached()
+		defer reportTrace(ctx, t)
+	}
+	mask, err := responseFields[Resp](ctx)
+	if err != nil {
+		var zero Resp
+		return zero, statusError(err)
+	}
+	start := time.Now()
+	resp, err := measure(ctx, sr.latency, req, func(ctx context.Context, req Req) (Resp, error) {
+		return respondShared(ctx, sr, req, f)
+	})
+	if sr.audit != nil {
+		sr.audit.log(ctx, start, req, resp, err)
+	}
+	if err != nil {
+		return resp, err
+	}
+	return applyFieldMask(mask, resp), nil
+}
+
+// respondShared serves the request as described by respond, without
+// recording it. The panics of the function are recovered as errors, and
+// the faults injected in the task, if any, are applied to it.
+func respondShared[Req, Resp proto.Message](ctx context.Context, sr *sharedResponses, req Req, f func(context.Context, Req) (Resp, error)) (Resp, error) {
+	f = withStatusError(withRecovery(withCallTimeout(withFaults(f), sr.timeout)))
+	if sr.cache == nil && sr.flights == nil {
+		return f(ctx, req)
+	}
+	key, err := requestKey(sr.cache.model(), lora.FromContext(ctx), req)
+	if err != nil {
+		logging.Ctx(ctx).Warn().Err(err).Msg("failed to compute request key")
+		return f(ctx, req)
+	}
+	if resp, ok := lookupResponse[Resp](ctx, sr.cache, key); ok {
+		return resp, nil
+	}
+	compute := func(ctx context.Context) (proto.Message, error) {
+		resp, err := f(ctx, req)
+		if err == nil {
+			sr.cache.store(ctx, key, resp)
+		}
+		return resp, err
+	}
+	if sr.flights == nil {
+		resp, err := compute(ctx)
+		return resp.(Resp), err
+	}
+	resp, err := sr.flights.do(ctx, key, compute)
+	if err != nil {
+		var zero Resp
+		return zero, err
+	}
+	return resp.(Resp), nil
+}
+
+// withCallTimeout returns the function failing once the timeout, if positive,
+// expires. It's applied to the call of the function, shared by the
+// coalesced requests, rather than to each of them.
+func withCallTimeout[Req, Resp any](f func(context.Context, Req) (Resp, error), timeout time.Duration) func(context.Context, Req) (Resp, error) {
+	if timeout <= 0 {
+		return f
+	}
+	return func(ctx context.Context, req Req) (Resp, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return f(ctx, req)
+	}
+}
+
+// withStatusError returns the function with its errors converted by
+// statusError.
+func withStatusError[Req, Resp any](f func(context.Context, Req) (Resp, error)) func(context.Context, Req) (Resp, error) {
+	return func(ctx context.Context, req Req) (Resp, error) {
+		resp, err := f(ctx, req)
+		return resp, statusError(err)
+	}
+}
+
+// lookupResponse returns the cached response of the key, if found.
+func lookupResponse[T proto.Message](ctx context.Context, rc *ResponseCache, key string) (T, bool) {
+	var resp T
+	if rc == nil {
+		return resp, false
+	}
+	data, ok, err := rc.Store.Get(ctx, key)
+	if err != nil {
+		logging.Ctx(ctx).Warn().Err(err).Msg("failed to get cached response")
+		return resp, false
+	}
+	if !ok {
+		return resp, false
+	}
+	resp = resp.ProtoReflect().New().Interface().(T)
+	if err := proto.Unmarshal(data, resp); err != nil {
+		logging.Ctx(ctx).Warn().Err(err).Msg("failed to decode cached response")
+		return resp, false
+	}
+	return resp, true
+}
+
+// store caches the response of the key.
+func (rc *ResponseCache) store(ctx context.Context, key string, resp proto.Message) {
+	if rc == nil {
+		return
+	}
+	data, err := proto.Marshal(resp)
+	if err == nil {
+		err = rc.Store.Set(ctx, key, data, rc.TTL)
+	}
+	if err != nil {
+		logging.Ctx(ctx).Warn().Err(err).Msg("failed to cache response")
+	}
+}
+
+// model returns the identifier of the model, or an empty string if the
+// cache is disabled.
+func (rc *ResponseCache) model() string {
+	if rc == nil {
+		return ""
+	}
+	return rc.Model
+}
+
+// normalizedRequests are the requests whose texts are normalized in their
+// keys, sharing the responses of the inputs differing only by Unicode
+// composition or whitespace: their responses carry no offsets nor texts
+// of the input, which would be the ones of another input.
+var normalizedRequests = map[protoreflect.FullName]bool{
+	(&textencodingv1.EncodingRequest{}).ProtoReflect().Descriptor().FullName(): true,
+	(&textencodingv2.EncodingRequest{}).ProtoReflect().Descriptor().FullName(): true,
+}
+
+// requestKey returns the key of the request of the model, with the adapter,
+// if any.
+func requestKey(model, adapter string, req proto.Message) (string, error) {
+	if normalizedRequests[req.ProtoReflect().Descriptor().FullName()] {
+		req = proto.Clone(req)
+		normalizeTexts(req.ProtoReflect())
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	name := req.ProtoReflect().Descriptor().FullName()
+	parts := [][]byte{[]byte(name), []byte(model), data}
+	if adapter != "" {
+		parts = append(parts, []byte(adapter))
+	}
+	return responsecache.Key(parts...), nil
+}
+
+// normalizeTexts normalizes the strings of the message, recursively.
+func normalizeTexts(m protoreflect.Message) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList() && fd.Kind() == protoreflect.StringKind:
+			l := v.List()
+			for i := 0; i < l.Len(); i++ {
+				l.Set(i, protoreflect.ValueOfString(responsecache.Normalize(l.Get(i).String())))
+			}
+		case fd.IsList() && fd.Message() != nil:
+			l := v.List()
+			for i := 0; i < l.Len(); i++ {
+				normalizeTexts(l.Get(i).Message())
+			}
+		case fd.IsMap():
+			// Left as is.
+		case fd.Kind() == protoreflect.StringKind:
+			m.Set(fd, protoreflect.ValueOfString(responsecache.Normalize(v.String())))
+		case fd.Message() != nil:
+			normalizeTexts(v.Message())
+		}
+		return true
+	})
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/responsecache"
+	textencodingv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textencoding/v1"
+	tokenclassificationv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/tokenclassification/v1"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lastWordClassifier labels the last word of the text, with its offsets.
+type lastWordClassifier struct {
+	calls int
+}
+
+func (c *lastWordClassifier) Classify(_ context.Context, text string, _ tokenclassification.Parameters) (tokenclassification.Response, error) {
+	c.calls++
+	start := strings.LastIndex(text, " ") + 1
+	return tokenclassification.Response{Tokens: []tokenclassification.Token{{
+		Text:      text[start:],
+		Label:     "B-PER",
+		Start:     start,
+		End:       len(text),
+		ByteStart: start,
+		ByteEnd:   len(text),
+	}}}, nil
+}
+
+func TestResponseCache_exactInputs(t *testing.T) {
+	classifier := &lastWordClassifier{}
+	rh := WithResponseCache(NewServerForTokenClassification(classifier), &ResponseCache{
+		Store: responsecache.NewLRU(10),
+		Model: "model",
+	})
+	s := rh.(*serverForTokenClassification)
+	ctx := context.Background()
+
+	spaced, err := s.Classify(ctx, &tokenclassificationv1.ClassifyRequest{Input: "a  b"})
+	require.NoError(t, err)
+	single, err := s.Classify(ctx, &tokenclassificationv1.ClassifyRequest{Input: "a b"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), spaced.Tokens[0].Start)
+	assert.Equal(t, int32(2), single.Tokens[0].Start)
+
+	cached, err := s.Classify(ctx, &tokenclassificationv1.ClassifyRequest{Input: "a  b"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), cached.Tokens[0].Start)
+	assert.Equal(t, 2, classifier.calls)
+}
+
+func TestRequestKey(t *testing.T) {
+	k1, err := requestKey("model", "", &textencodingv1.EncodingRequest{Input: "  Hello \t world"})
+	require.NoError(t, err)
+	k2, err := requestKey("model", "", &textencodingv1.EncodingRequest{Input: "Hello world"})
+	require.NoError(t, err)
+	assert.Equal(t, k1, k2, "the embeddings are shared by the normalized inputs")
+
+	k1, err = requestKey("model", "", &tokenclassificationv1.ClassifyRequest{Input: "a  b"})
+	require.NoError(t, err)
+	k2, err = requestKey("model", "", &tokenclassificationv1.ClassifyRequest{Input: "a b"})
+	require.NoError(t, err)
+	assert.NotEqual(t, k1, k2)
+
+	k3, err := requestKey("model", "adapter", &tokenclassificationv1.ClassifyRequest{Input: "a b"})
+	require.NoError(t, err)
+	assert.NotEqual(t, k2, k3)
+}
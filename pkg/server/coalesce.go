@@ -0,0 +1,109 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/usage"
+	"google.golang.org/protobuf/proto"
+)
+
+// WithRequestCoalescing makes the request handler returned by
+// ResolveRequestHandler serve the concurrent identical requests with a
+// single inference, sharing its response, and returns it.
+func WithRequestCoalescing(rh RequestHandler) RequestHandler {
+	if h, ok := rh.(interface{ shared() *sharedResponses }); ok {
+		h.shared().flights = &flightGroup{calls: make(map[string]*flight)}
+	}
+	return rh
+}
+
+// flightGroup coalesces the calls with the same key while in flight.
+//
+// Unlike singleflight, a call is not bound to the context of the first
+// caller: it's canceled only once all the callers waiting for it are gone.
+// The tokens processed by a call are counted once for each caller receiving
+// its result, so that the coalesced requests of different tenants are all
+// accounted.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+// flight is a call in flight.
+type flight struct {
+	done chan struct{}
+	resp proto.Message
+	err  error
+	// usage counts the tokens processed by the call.
+	usage usage.Counter
+	// waiters is the number of callers waiting for the call.
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// do calls the function, unless a call with the same key is in flight, in
+// which case it waits for its result.
+func (g *flightGroup) do(ctx context.Context, key string, f func(context.Context) (proto.Message, error)) (proto.Message, error) {
+	g.mu.Lock()
+	c, ok := g.calls[key]
+	if !ok {
+		c = &flight{done: make(chan struct{})}
+		callCtx, cancel := context.WithCancel(usage.NewContext(detachedContext{ctx}, &c.usage))
+		c.cancel = cancel
+		g.calls[key] = c
+		go func() {
+			c.resp, c.err = f(callCtx)
+			g.forget(key, c)
+			cancel()
+			close(c.done)
+		}()
+	}
+	c.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		input, output := c.usage.Tokens()
+		usage.AddTokens(ctx, int(input), int(output))
+		return c.resp, c.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			// Nobody is waiting anymore: a new identical request
+			// starts a new call.
+			c.cancel()
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// forget removes the call, if still registered.
+func (g *flightGroup) forget(key string, c *flight) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+}
+
+// detachedContext carries the values of the parent context, but not its
+// deadline and cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+func (c detachedContext) Value(key any) any         { return c.parent.Value(key) }
@@ -0,0 +1,173 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	textencodingv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textencoding/v1"
+	"github.com/nlpodyssey/cybertron/pkg/tenancy"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: make(map[string]*flight)}
+}
+
+// waitForWaiters waits until the call with the key has n waiters.
+func waitForWaiters(t *testing.T, g *flightGroup, key string, n int) {
+	require.Eventually(t, func() bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		c, ok := g.calls[key]
+		return ok && c.waiters == n
+	}, time.Second, time.Millisecond)
+}
+
+func TestFlightGroup(t *testing.T) {
+	errCall := errors.New("call failed")
+	tests := []struct {
+		name    string
+		err     error
+		callers int
+	}{
+		{"response", nil, 5},
+		{"error", errCall, 5},
+		{"single caller", nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newFlightGroup()
+			var calls atomic.Int32
+			release := make(chan struct{})
+			f := func(context.Context) (proto.Message, error) {
+				calls.Add(1)
+				<-release
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return &textencodingv1.EncodingResponse{Vector: []float32{1}}, nil
+			}
+
+			var wg sync.WaitGroup
+			for i := 0; i < tt.callers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					resp, err := g.do(context.Background(), "key", f)
+					if tt.err != nil {
+						assert.ErrorIs(t, err, tt.err)
+						return
+					}
+					if assert.NoError(t, err) {
+						assert.Equal(t, []float32{1}, resp.(*textencodingv1.EncodingResponse).Vector)
+					}
+				}()
+			}
+			waitForWaiters(t, g, "key", tt.callers)
+			close(release)
+			wg.Wait()
+
+			assert.Equal(t, int32(1), calls.Load(), "the identical requests are coalesced")
+			assert.Empty(t, g.calls, "the calls done are forgotten")
+		})
+	}
+}
+
+func TestFlightGroup_Cancel(t *testing.T) {
+	g := newFlightGroup()
+	started := make(chan context.Context, 2)
+	f := func(ctx context.Context) (proto.Message, error) {
+		started <- ctx
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	second, cancelSecond := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() { _, err := g.do(first, "key", f); errs <- err }()
+	callCtx := <-started
+	go func() { _, err := g.do(second, "key", f); errs <- err }()
+	waitForWaiters(t, g, "key", 2)
+
+	cancelFirst()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	assert.NoError(t, callCtx.Err(), "the call goes on while a caller is waiting")
+
+	cancelSecond()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	assert.ErrorIs(t, callCtx.Err(), context.Canceled, "the call is canceled once all the callers are gone")
+
+	g.mu.Lock()
+	_, ok := g.calls["key"]
+	g.mu.Unlock()
+	assert.False(t, ok, "a new identical request starts a new call")
+}
+
+func TestFlightGroup_Tenants(t *testing.T) {
+	tenants, err := tenancy.New([]tenancy.Tenant{
+		{Name: "acme", APIKeys: []string{"acme-key"}},
+		{Name: "initech", APIKeys: []string{"initech-key"}},
+	})
+	require.NoError(t, err)
+	acme, err := tenants.ByName("acme")
+	require.NoError(t, err)
+	initech, err := tenants.ByName("initech")
+	require.NoError(t, err)
+
+	g := newFlightGroup()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	f := func(ctx context.Context) (proto.Message, error) {
+		calls.Add(1)
+		<-release
+		usage.AddTokens(ctx, 3, 1)
+		return &textencodingv1.EncodingResponse{}, nil
+	}
+
+	var wg sync.WaitGroup
+	request := func(a *tenancy.Account) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := account(context.Background(), a, func(ctx context.Context) (proto.Message, error) {
+				return g.do(ctx, "key", f)
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	request(acme)
+	waitForWaiters(t, g, "key", 1)
+	request(initech)
+	waitForWaiters(t, g, "key", 2)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load(), "the identical requests are coalesced")
+	for _, a := range []*tenancy.Account{acme, initech} {
+		u := a.Usage()
+		assert.Equal(t, int64(3), u.InputTokens, a.Name())
+		assert.Equal(t, int64(1), u.OutputTokens, a.Name())
+	}
+}
+
+func TestWithRequestCoalescing(t *testing.T) {
+	rh := WithRequestCoalescing(NewServerForTextClassification(pairClassifier{}))
+	s := rh.(*serverForTextClassification)
+	assert.NotNil(t, s.flights)
+
+	// The handlers not sharing their responses are returned as they are.
+	hs := RequestHandlers{rh}
+	assert.Equal(t, hs, WithRequestCoalescing(hs))
+}
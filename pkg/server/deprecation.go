@@ -0,0 +1,157 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// deprecationHeader is the gRPC metadata, sent in the header of the
+	// responses of a deprecated version of an API, naming the package of
+	// the version replacing it, e.g. "text2text.v2". The HTTP responses
+	// have the Deprecation, Sunset and Link headers instead.
+	deprecationHeader = "cybertron-deprecation"
+	// sunsetHeader is the gRPC metadata, sent with the deprecationHeader,
+	// with the time the version stops being served, in RFC 3339 format, if
+	// any, see Config.APISunset.
+	sunsetHeader = "cybertron-sunset"
+)
+
+// deprecation is a version of an API replaced by a newer one.
+type deprecation struct {
+	// successor is the package of the version replacing it.
+	successor string
+	// since is when it was deprecated, i.e. when the successor was added.
+	since time.Time
+	// paths are the HTTP paths of its methods, with the ones of the
+	// successor.
+	paths map[string]string
+}
+
+// deprecations are the deprecated versions of the APIs, by package. A
+// version is served along with its successor until the Config.APISunset,
+// set by the operators once their clients are notified, and fails with
+// UNIMPLEMENTED after that. The changes breaking the clients, e.g. to the
+// request options, go in a new version, the old one being kept as a shim
+// translating its requests.
+var deprecations = map[string]deprecation{
+	"text2text.v1": {
+		successor: "text2text.v2",
+		since:     time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC),
+		paths:     map[string]string{"/v1/generate": "/v2/generate"},
+	},
+}
+
+// deprecatedPaths are the packages of the deprecated versions of the APIs,
+// by the HTTP paths of their methods.
+var deprecatedPaths = func() map[string]string {
+	m := make(map[string]string)
+	for pkg, d := range deprecations {
+		for path := range d.paths {
+			m[path] = pkg
+		}
+	}
+	return m
+}()
+
+// parentName returns the name up to its last dot, e.g. the service of a
+// method, or the package of a service.
+func parentName(name string) string {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// checkSunset returns the error of the requests to the version of the API,
+// if deprecated and past the sunset.
+func (s *Server) checkSunset(pkg string) error {
+	d, ok := deprecations[pkg]
+	sunset := s.conf.APISunset
+	if !ok || sunset.IsZero() || time.Now().Before(sunset) {
+		return nil
+	}
+	return status.Error(codes.Unimplemented, fmt.Sprintf("the %s API was removed on %s: use %s",
+		pkg, sunset.Format(time.DateOnly), d.successor))
+}
+
+// logDeprecations warns about the deprecated versions of the APIs among the
+// methods served.
+func (s *Server) logDeprecations(methods methodRegistry) {
+	logged := make(map[string]bool)
+	for name := range methods {
+		pkg := parentName(parentName(name))
+		d, ok := deprecations[pkg]
+		if !ok || logged[pkg] {
+			continue
+		}
+		logged[pkg] = true
+		e := log.Warn().
+			Str("api", pkg).
+			Str("successor", d.successor)
+		if !s.conf.APISunset.IsZero() {
+			e = e.Time("sunset", s.conf.APISunset)
+		}
+		e.Msg("serving a deprecated API")
+	}
+}
+
+// deprecationInterceptor reports the deprecation of the gRPC requests to a
+// deprecated version of an API in the header of their responses, failing
+// them past its sunset.
+func (s *Server) deprecationInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	service, _, _ := strings.Cut(strings.TrimPrefix(info.FullMethod, "/"), "/")
+	pkg := parentName(service)
+	d, ok := deprecations[pkg]
+	if !ok {
+		return handler(ctx, req)
+	}
+	if err := s.checkSunset(pkg); err != nil {
+		return nil, err
+	}
+	md := metadata.Pairs(deprecationHeader, d.successor)
+	if sunset := s.conf.APISunset; !sunset.IsZero() {
+		md.Set(sunsetHeader, sunset.UTC().Format(time.RFC3339))
+	}
+	_ = grpc.SetHeader(ctx, md)
+	return handler(ctx, req)
+}
+
+// withDeprecation reports the deprecation of the HTTP requests to a
+// deprecated version of an API in the Deprecation (RFC 9745) and Sunset
+// (RFC 8594) headers of their responses, the latter if the sunset is set,
+// with a Link header to the path of the successor, failing them past the
+// sunset.
+func (s *Server) withDeprecation(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pkg, ok := deprecatedPaths[r.URL.Path]
+		if !ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if err := s.checkSunset(pkg); err != nil {
+			writeHTTPError(w, r, err)
+			return
+		}
+		d := deprecations[pkg]
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", d.since.Unix()))
+		if sunset := s.conf.APISunset; !sunset.IsZero() {
+			w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, d.paths[r.URL.Path]))
+		h.ServeHTTP(w, r)
+	})
+}
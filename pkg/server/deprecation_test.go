@@ -0,0 +1,133 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestCheckSunset(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	for _, tc := range []struct {
+		name    string
+		pkg     string
+		sunset  time.Time
+		removed bool
+	}{
+		{"no sunset", "text2text.v1", time.Time{}, false},
+		{"before the sunset", "text2text.v1", future, false},
+		{"past the sunset", "text2text.v1", past, true},
+		{"successor", "text2text.v2", past, false},
+		{"not deprecated", "textclassification.v1", past, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &Server{conf: &Config{APISunset: tc.sunset}}
+			err := s.checkSunset(tc.pkg)
+			if !tc.removed {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, codes.Unimplemented, status.Code(err))
+			assert.Contains(t, err.Error(), "use text2text.v2")
+		})
+	}
+}
+
+func TestWithDeprecation(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	serve := func(sunset time.Time, path string) *httptest.ResponseRecorder {
+		s := &Server{conf: &Config{APISunset: sunset}}
+		w := httptest.NewRecorder()
+		s.withDeprecation(ok).ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		return w
+	}
+	since := deprecations["text2text.v1"].since
+
+	t.Run("no sunset", func(t *testing.T) {
+		w := serve(time.Time{}, "/v1/generate")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, fmt.Sprintf("@%d", since.Unix()), w.Header().Get("Deprecation"))
+		assert.Empty(t, w.Header().Get("Sunset"))
+		assert.Equal(t, `</v2/generate>; rel="successor-version"`, w.Header().Get("Link"))
+	})
+
+	t.Run("before the sunset", func(t *testing.T) {
+		sunset := time.Now().Add(24 * time.Hour)
+		w := serve(sunset, "/v1/generate")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, sunset.UTC().Format(http.TimeFormat), w.Header().Get("Sunset"))
+	})
+
+	t.Run("past the sunset", func(t *testing.T) {
+		w := serve(time.Now().Add(-time.Hour), "/v1/generate")
+		assert.Equal(t, http.StatusNotImplemented, w.Code)
+	})
+
+	t.Run("not deprecated", func(t *testing.T) {
+		w := serve(time.Now().Add(-time.Hour), "/v2/generate")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Deprecation"))
+		assert.Empty(t, w.Header().Get("Link"))
+	})
+}
+
+// headerStream is a grpc.ServerTransportStream recording the header.
+type headerStream struct {
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return "" }
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *headerStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *headerStream) SetTrailer(metadata.MD) error { return nil }
+
+func TestDeprecationInterceptor(t *testing.T) {
+	call := func(sunset time.Time, method string) (metadata.MD, error) {
+		s := &Server{conf: &Config{APISunset: sunset}}
+		stream := &headerStream{}
+		ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+		_, err := s.deprecationInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method},
+			func(ctx context.Context, req any) (any, error) { return "ok", nil })
+		return stream.header, err
+	}
+
+	header, err := call(time.Time{}, "/text2text.v1.Text2TextService/Generate")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"text2text.v2"}, header.Get(deprecationHeader))
+	assert.Empty(t, header.Get(sunsetHeader))
+
+	sunset := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	header, err = call(sunset, "/text2text.v1.Text2TextService/Generate")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2030-01-01T00:00:00Z"}, header.Get(sunsetHeader))
+
+	header, err = call(sunset, "/text2text.v2.Text2TextService/Generate")
+	require.NoError(t, err)
+	assert.Empty(t, header)
+
+	_, err = call(time.Now().Add(-time.Hour), "/text2text.v1.Text2TextService/Generate")
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
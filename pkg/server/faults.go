@@ -0,0 +1,247 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/proto"
+)
+
+// faults injects the faults set with the admin API in the requests, in the
+// builds with the "chaos" tag only (see faults_chaos.go); it's nil in the
+// other builds, which can't inject any.
+var faults *faultInjector
+
+// Fault is a failure injected in the requests of a task, to validate the
+// retries and the timeouts of the clients against realistic failures
+// without breaking the models.
+type Fault struct {
+	// Task is the name of the task of the requests, e.g.
+	// "text-classification", or empty for the requests of all the tasks.
+	Task string `json:"task,omitempty"`
+	// Latency delays the requests before the inference, within their
+	// timeout.
+	Latency time.Duration `json:"latency,omitempty"`
+	// Error fails the requests, after the latency, with the code of the
+	// errdefs taxonomy, e.g. "INTERNAL" or "MODEL_LOAD_FAILED".
+	Error errdefs.Code `json:"error,omitempty"`
+	// OOM fails the requests as if they exceeded the memory limit of the
+	// model, i.e. with MEMORY_LIMIT_EXCEEDED.
+	OOM bool `json:"oom,omitempty"`
+	// Rate is the fraction of the requests failing with the error, all of
+	// them if zero.
+	Rate float64 `json:"rate,omitempty"`
+}
+
+// MarshalJSON encodes the fault with its latency as a string, e.g. "2s".
+func (f Fault) MarshalJSON() ([]byte, error) {
+	type fault Fault
+	v := struct {
+		fault
+		Latency string `json:"latency,omitempty"`
+	}{fault: fault(f)}
+	if f.Latency > 0 {
+		v.Latency = f.Latency.String()
+	}
+	return json.Marshal(v)
+}
+
+// err returns the error of the fault, if any.
+func (f Fault) err() error {
+	switch {
+	case f.OOM:
+		return errdefs.New(errdefs.CodeMemoryLimitExceeded, "injected fault: memory limit exceeded")
+	case f.Error != "":
+		return errdefs.New(f.Error, fmt.Sprintf("injected fault: %s", f.Error))
+	default:
+		return nil
+	}
+}
+
+// parseFault parses the fault of the task from the query of a request of
+// the admin API.
+func parseFault(task string, get func(string) string) (Fault, error) {
+	if task != "" && !knownTask(task) {
+		return Fault{}, fmt.Errorf("unknown task %#v", task)
+	}
+	f := Fault{Task: task, Error: errdefs.Code(strings.ToUpper(get("error")))}
+	if s := get("latency"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			return Fault{}, fmt.Errorf("invalid latency %#v", s)
+		}
+		f.Latency = d
+	}
+	if _, ok := statusCodes[f.Error]; f.Error != "" && !ok {
+		return Fault{}, fmt.Errorf("unknown error code %#v", f.Error)
+	}
+	if s := get("oom"); s != "" {
+		oom, err := strconv.ParseBool(s)
+		if err != nil {
+			return Fault{}, fmt.Errorf("invalid oom %#v", s)
+		}
+		f.OOM = oom
+	}
+	if s := get("rate"); s != "" {
+		rate, err := strconv.ParseFloat(s, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return Fault{}, fmt.Errorf("invalid rate %#v: must be between 0 and 1", s)
+		}
+		f.Rate = rate
+	}
+	if f.Latency == 0 && f.err() == nil {
+		return Fault{}, fmt.Errorf("no latency, error or oom set")
+	}
+	return f, nil
+}
+
+// faultInjector holds the faults of the tasks, injecting them in the
+// requests.
+type faultInjector struct {
+	mu sync.RWMutex
+	// faults are the faults by task, the one of all the tasks keyed by an
+	// empty string.
+	faults map[string]Fault
+	// rand decides the requests failing, at the rate of the faults.
+	rand *rand.Rand
+}
+
+func newFaultInjector() *faultInjector {
+	return &faultInjector{
+		faults: make(map[string]Fault),
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// inject injects the fault of the task, or of all the tasks, if any: it
+// waits for its latency, unless the context is done first, and returns its
+// error, if the request is among the ones failing.
+func (fi *faultInjector) inject(ctx context.Context, task string) error {
+	fi.mu.Lock()
+	f, ok := fi.faults[task]
+	if !ok {
+		f, ok = fi.faults[""]
+	}
+	fails := ok && (f.Rate == 0 || fi.rand.Float64() < f.Rate)
+	fi.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	if f.Latency > 0 {
+		t := time.NewTimer(f.Latency)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	if !fails {
+		return nil
+	}
+	return f.err()
+}
+
+// list returns the faults, sorted by task.
+func (fi *faultInjector) list() []Fault {
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
+	list := make([]Fault, 0, len(fi.faults))
+	for _, f := range fi.faults {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Task < list[j].Task })
+	return list
+}
+
+// ServeHTTP lists the faults on GET requests, sets the fault of the "task"
+// of the query, or of all the tasks, from its "latency", "error", "oom"
+// and "rate" on PUT requests, replacing the one of the same task, and
+// removes it on DELETE requests, all of them without a task.
+func (fi *faultInjector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	task := q.Get("task")
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{"faults": fi.list()}); err != nil {
+			log.Warn().Err(err).Msg("failed to write the faults")
+		}
+	case http.MethodPut:
+		f, err := parseFault(task, q.Get)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fi.mu.Lock()
+		fi.faults[task] = f
+		fi.mu.Unlock()
+		log.Warn().Interface("fault", f).Msg("fault injected")
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		fi.mu.Lock()
+		if task == "" {
+			fi.faults = make(map[string]Fault)
+		} else {
+			delete(fi.faults, task)
+		}
+		fi.mu.Unlock()
+		log.Info().Str("task", task).Msg("faults removed")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// withFaults returns the function failing with the faults injected in the
+// requests of its task, if any. It's applied to the inference, so that the
+// cached responses are not affected.
+func withFaults[Req proto.Message, Resp any](f func(context.Context, Req) (Resp, error)) func(context.Context, Req) (Resp, error) {
+	if faults == nil {
+		return f
+	}
+	return func(ctx context.Context, req Req) (Resp, error) {
+		if err := faults.inject(ctx, requestTask(req)); err != nil {
+			var zero Resp
+			return zero, err
+		}
+		return f(ctx, req)
+	}
+}
+
+// knownTask reports whether the task is one of the tasks served.
+func knownTask(task string) bool {
+	for _, t := range taskNames {
+		if t == task {
+			return true
+		}
+	}
+	return false
+}
+
+// requestTask returns the name of the task of the request, from the
+// package of its service, of any version, or an empty string if unknown.
+func requestTask(req proto.Message) string {
+	api := parentName(string(req.ProtoReflect().Descriptor().ParentFile().Package()))
+	for name, task := range taskNames {
+		if strings.HasPrefix(name, api+".") {
+			return task
+		}
+	}
+	return ""
+}
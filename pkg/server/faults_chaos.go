@@ -0,0 +1,13 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build chaos
+
+package server
+
+// The builds with the "chaos" tag, meant for the resilience tests only,
+// inject the faults set at /admin/faults.
+func init() {
+	faults = newFaultInjector()
+}
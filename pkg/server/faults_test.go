@@ -0,0 +1,74 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	text2textv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/text2text/v1"
+	textclassificationv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textclassification/v1"
+	textencodingv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textencoding/v1"
+	textencodingv2 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textencoding/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaultInjector(t *testing.T) {
+	fi := newFaultInjector()
+	put := func(query string) int {
+		w := httptest.NewRecorder()
+		fi.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/admin/faults?"+query, nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, put("task=text-classification"))
+	assert.Equal(t, http.StatusBadRequest, put("task=unknown&error=internal"))
+	assert.Equal(t, http.StatusBadRequest, put("error=UNKNOWN_CODE"))
+	assert.Equal(t, http.StatusBadRequest, put("latency=1s&rate=2"))
+	require.Equal(t, http.StatusNoContent, put("task=text-classification&error=model_load_failed"))
+	require.Equal(t, http.StatusNoContent, put("latency=20ms&oom=true&rate=0.000001"))
+
+	ctx := context.Background()
+	err := fi.inject(ctx, "text-classification")
+	assert.True(t, errors.Is(err, errdefs.ErrModelLoadFailed), err)
+
+	start := time.Now()
+	assert.NoError(t, fi.inject(ctx, "text-encoding"))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, fi.inject(ctx, "text-encoding"), context.DeadlineExceeded)
+
+	w := httptest.NewRecorder()
+	fi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/faults", nil))
+	var list struct {
+		Faults []map[string]any `json:"faults"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Faults, 2)
+	assert.Equal(t, "20ms", list.Faults[0]["latency"])
+	assert.Equal(t, "MODEL_LOAD_FAILED", list.Faults[1]["error"])
+
+	w = httptest.NewRecorder()
+	fi.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/faults", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, fi.list())
+	assert.NoError(t, fi.inject(context.Background(), "text-classification"))
+}
+
+func TestRequestTask(t *testing.T) {
+	assert.Equal(t, "text-classification", requestTask(&textclassificationv1.ClassifyRequest{}))
+	assert.Equal(t, "text-encoding", requestTask(&textencodingv1.EncodingRequest{}))
+	assert.Equal(t, "text-encoding", requestTask(&textencodingv2.EncodingRequest{}))
+	assert.Equal(t, "text2text", requestTask(&text2textv1.GenerateRequest{}))
+}
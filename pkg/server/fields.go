@@ -0,0 +1,157 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// fieldsHeader is the HTTP header, or gRPC metadata, selecting the fields
+// of the response of a request, as comma-separated paths of their names,
+// in the proto or JSON form, e.g. "labels" or "attributions.text", all by
+// default. The other fields are left unset, so that they aren't serialized.
+const fieldsHeader = "cybertron-fields"
+
+type fieldsKey struct{}
+
+// contextWithFields returns the context selecting the fields of the
+// response.
+func contextWithFields(ctx context.Context, fields string) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// fieldsFromContext returns the fields of the response selected in the
+// context, empty for all of them.
+func fieldsFromContext(ctx context.Context) string {
+	fields, _ := ctx.Value(fieldsKey{}).(string)
+	return fields
+}
+
+// fieldsInterceptor selects the fields of the responses of the gRPC
+// requests from their metadata.
+func fieldsInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(fieldsHeader); len(v) > 0 && v[0] != "" {
+		ctx = contextWithFields(ctx, v[0])
+	}
+	return handler(ctx, req)
+}
+
+// withFields selects the fields of the responses of the HTTP requests from
+// their header.
+func withFields(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Header.Get(fieldsHeader); v != "" {
+			r = r.WithContext(contextWithFields(r.Context(), v))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// fieldMask is the tree of the fields of a message selected, by number; a
+// field without subfields is selected whole.
+type fieldMask map[protoreflect.FieldNumber]fieldMask
+
+// parseFieldMask parses the comma-separated paths of the fields of the
+// message, failing on the unknown ones.
+func parseFieldMask(md protoreflect.MessageDescriptor, fields string) (fieldMask, error) {
+	mask := make(fieldMask)
+	for _, path := range strings.Split(fields, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if err := mask.add(md, path, strings.Split(path, ".")); err != nil {
+			return nil, err
+		}
+	}
+	return mask, nil
+}
+
+// add adds the fields of the path to the mask of the message.
+func (m fieldMask) add(md protoreflect.MessageDescriptor, path string, names []string) error {
+	fd := lookupField(md, names[0])
+	if fd == nil {
+		return fmt.Errorf("%w: unknown field %#v of %s", errdefs.ErrInvalidRequest, path, md.Name())
+	}
+	sub, selected := m[fd.Number()]
+	if len(names) == 1 || (selected && sub == nil) {
+		m[fd.Number()] = nil // the whole field
+		return nil
+	}
+	if fd.Message() == nil || fd.IsMap() {
+		return fmt.Errorf("%w: field %#v of %s has no subfields", errdefs.ErrInvalidRequest, path, md.Name())
+	}
+	if !selected {
+		sub = make(fieldMask)
+		m[fd.Number()] = sub
+	}
+	return sub.add(fd.Message(), path, names[1:])
+}
+
+// lookupField returns the field of the message by its proto or JSON name.
+func lookupField(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	if fd := md.Fields().ByName(protoreflect.Name(name)); fd != nil {
+		return fd
+	}
+	return md.Fields().ByJSONName(name)
+}
+
+// project returns a new message with the fields of the mask only, sharing
+// their values with the message. The message isn't modified, since it may
+// be shared with other requests, e.g. cached.
+func (m fieldMask) project(msg protoreflect.Message) protoreflect.Message {
+	out := msg.New()
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		sub, ok := m[fd.Number()]
+		switch {
+		case !ok:
+		case sub == nil:
+			out.Set(fd, v)
+		case fd.IsList():
+			list := out.Mutable(fd).List()
+			for i := 0; i < v.List().Len(); i++ {
+				list.Append(protoreflect.ValueOfMessage(sub.project(v.List().Get(i).Message())))
+			}
+		default:
+			out.Set(fd, protoreflect.ValueOfMessage(sub.project(v.Message())))
+		}
+		return true
+	})
+	return out
+}
+
+// responseFields returns the mask of the fields of the response selected
+// in the context, nil for all of them, failing if they're unknown.
+func responseFields[Resp proto.Message](ctx context.Context) (fieldMask, error) {
+	fields := fieldsFromContext(ctx)
+	if fields == "" {
+		return nil, nil
+	}
+	var zero Resp
+	mask, err := parseFieldMask(zero.ProtoReflect().Descriptor(), fields)
+	if err != nil || len(mask) == 0 {
+		return nil, err
+	}
+	return mask, nil
+}
+
+// applyFieldMask returns the response with the fields of the mask only, or
+// the response itself if the mask is nil.
+func applyFieldMask[Resp proto.Message](m fieldMask, resp Resp) Resp {
+	if m == nil {
+		return resp
+	}
+	return m.project(resp.ProtoReflect()).Interface().(Resp)
+}
@@ -0,0 +1,149 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	text2textv2 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/text2text/v2"
+	tokenclassificationv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/tokenclassification/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestParseFieldMask(t *testing.T) {
+	md := (&tokenclassificationv1.ClassifyResponse{}).ProtoReflect().Descriptor()
+	tests := []struct {
+		fields  string
+		want    fieldMask
+		wantErr bool
+	}{
+		{fields: "", want: fieldMask{}},
+		{fields: " , ", want: fieldMask{}},
+		{fields: "tokens", want: fieldMask{1: nil}},
+		{fields: "tokens.text, tokens.byte_start", want: fieldMask{1: {1: nil, 6: nil}}},
+		{fields: "tokens.text,tokens.byteStart", want: fieldMask{1: {1: nil, 6: nil}}},
+		{fields: "tokens.text,tokens", want: fieldMask{1: nil}},
+		{fields: "tokens,tokens.text", want: fieldMask{1: nil}},
+		{fields: "labels", wantErr: true},
+		{fields: "tokens.unknown", wantErr: true},
+		{fields: "tokens.text.length", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseFieldMask(md, tt.fields)
+		if tt.wantErr {
+			assert.ErrorIs(t, err, errdefs.ErrInvalidRequest, tt.fields)
+			continue
+		}
+		require.NoError(t, err, tt.fields)
+		assert.Equal(t, tt.want, got, tt.fields)
+	}
+}
+
+func TestApplyFieldMask(t *testing.T) {
+	resp := &tokenclassificationv1.ClassifyResponse{Tokens: []*tokenclassificationv1.Token{
+		{Text: "Rome", Start: 0, End: 4, Label: "LOC", Score: 0.9},
+		{Text: "Paris", Start: 9, End: 14, Label: "LOC", Score: 0.8},
+	}}
+	original := proto.Clone(resp)
+
+	tests := []struct {
+		fields string
+		want   *tokenclassificationv1.ClassifyResponse
+	}{
+		{fields: "", want: resp},
+		{fields: "tokens", want: resp},
+		{fields: "tokens.text,tokens.label", want: &tokenclassificationv1.ClassifyResponse{Tokens: []*tokenclassificationv1.Token{
+			{Text: "Rome", Label: "LOC"},
+			{Text: "Paris", Label: "LOC"},
+		}}},
+	}
+	for _, tt := range tests {
+		mask, err := responseFields[*tokenclassificationv1.ClassifyResponse](contextWithFields(context.Background(), tt.fields))
+		require.NoError(t, err, tt.fields)
+		got := applyFieldMask(mask, resp)
+		assert.True(t, proto.Equal(tt.want, got), "%s: %v", tt.fields, got)
+	}
+	assert.True(t, proto.Equal(original, resp), "the response is not modified")
+
+	// The singular messages are projected too.
+	req := &text2textv2.GenerateRequest{
+		Inputs:   []string{"input"},
+		Sampling: &text2textv2.SamplingParameters{Enabled: proto.Bool(true), Temperature: proto.Float64(0.7)},
+	}
+	mask, err := parseFieldMask(req.ProtoReflect().Descriptor(), "sampling.temperature")
+	require.NoError(t, err)
+	got := applyFieldMask(mask, req)
+	assert.True(t, proto.Equal(&text2textv2.GenerateRequest{
+		Sampling: &text2textv2.SamplingParameters{Temperature: proto.Float64(0.7)},
+	}, got), got)
+}
+
+func TestResponseFields(t *testing.T) {
+	mask, err := responseFields[*tokenclassificationv1.ClassifyResponse](context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, mask, "all the fields by default")
+
+	mask, err = responseFields[*tokenclassificationv1.ClassifyResponse](contextWithFields(context.Background(), ","))
+	require.NoError(t, err)
+	assert.Nil(t, mask)
+
+	_, err = responseFields[*tokenclassificationv1.ClassifyResponse](contextWithFields(context.Background(), "vector"))
+	assert.ErrorIs(t, err, errdefs.ErrInvalidRequest)
+}
+
+func TestFieldsInterceptor(t *testing.T) {
+	tests := []struct {
+		md   metadata.MD
+		want string
+	}{
+		{md: nil, want: ""},
+		{md: metadata.Pairs(fieldsHeader, ""), want: ""},
+		{md: metadata.Pairs(fieldsHeader, "tokens.text"), want: "tokens.text"},
+	}
+	for _, tt := range tests {
+		var got string
+		ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+		_, err := fieldsInterceptor(ctx, nil, nil, func(ctx context.Context, _ any) (any, error) {
+			got = fieldsFromContext(ctx)
+			return nil, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+}
+
+func TestWithFields(t *testing.T) {
+	for _, header := range []string{"", "labels,scores"} {
+		var got string
+		h := withFields(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			got = fieldsFromContext(r.Context())
+		}))
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		if header != "" {
+			r.Header.Set(fieldsHeader, header)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		assert.Equal(t, header, got)
+	}
+}
+
+func TestServerForTokenClassification_Fields(t *testing.T) {
+	s := NewServerForTokenClassification(&lastWordClassifier{}).(*serverForTokenClassification)
+	ctx := contextWithFields(context.Background(), "tokens.text")
+	resp, err := s.Classify(ctx, &tokenclassificationv1.ClassifyRequest{Input: "hello Rome"})
+	require.NoError(t, err)
+	require.Len(t, resp.Tokens, 1)
+	assert.True(t, proto.Equal(&tokenclassificationv1.Token{Text: "Rome"}, resp.Tokens[0]), resp.Tokens[0])
+
+	_, err = s.Classify(contextWithFields(context.Background(), "unknown"), &tokenclassificationv1.ClassifyRequest{Input: "hello"})
+	assert.Error(t, err)
+}
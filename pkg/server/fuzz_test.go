@@ -0,0 +1,92 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// requestDescriptors returns the descriptors of the requests of the tasks,
+// in a stable order.
+func requestDescriptors(f *testing.F) []protoreflect.MessageDescriptor {
+	names := make([]string, 0, len(taskNames))
+	for name := range taskNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	mds := make([]protoreflect.MessageDescriptor, len(names))
+	for i, name := range names {
+		md, err := requestDescriptor(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		mds[i] = md
+	}
+	return mds
+}
+
+func FuzzRequestJSON(f *testing.F) {
+	for _, s := range []string{
+		`{"input": "I love this movie"}`,
+		`{"text": "Paris", "parameters": {"candidate_labels": ["city", "country"], "multi_label": true}}`,
+		`{"input": "\udc00\ud800", "pooling_strategy": "MEAN"}`,
+		`{"input": "` + strings.Repeat("a", 100000) + `"}`,
+		`{"input": [[[[[[[[[[]]]]]]]]]]}`,
+		"{\"input\": \"\xff\xfe\"}",
+		`null`,
+	} {
+		f.Add([]byte(s))
+	}
+	mds := requestDescriptors(f)
+	gateway := &runtime.JSONPb{}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, md := range mds {
+			// The requests of the HTTP gateway, and of NATS and the bulk uploads.
+			_ = gateway.NewDecoder(bytes.NewReader(data)).Decode(dynamicpb.NewMessage(md))
+			_ = protojson.Unmarshal(data, dynamicpb.NewMessage(md))
+		}
+	})
+}
+
+func FuzzBulkRows(f *testing.F) {
+	for _, s := range []string{
+		"input\nI love this movie\n\"quoted, with a comma\"\n",
+		"text,parameters\nParis,\"{\"\"candidate_labels\"\": [\"\"city\"\"]}\"\n",
+		"input,input\na,b,c\n",
+		"\"unterminated\n",
+		"\xff\xfe\n\n",
+		"{\"input\": \"a\"}\n\n{\"input\": \"" + strings.Repeat("b", 100000) + "\"}\n",
+	} {
+		f.Add(s)
+	}
+	mds := requestDescriptors(f)
+	f.Fuzz(func(t *testing.T, body string) {
+		for _, md := range mds {
+			for _, contentType := range []string{"text/csv", "text/tab-separated-values", "application/jsonl"} {
+				rows, err := openBulkRows(strings.NewReader(body), contentType, md)
+				if err != nil {
+					continue
+				}
+				for {
+					data, rowErr, err := rows.next()
+					if err != nil {
+						break
+					}
+					if rowErr == nil {
+						_ = protojson.Unmarshal(data, dynamicpb.NewMessage(md))
+					}
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,106 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: coreference/v2/coreference.proto
+
+package coreferencev2
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// CoreferenceServiceClient is the client API for CoreferenceService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CoreferenceServiceClient interface {
+	Resolve(ctx context.Context, in *ResolveRequest, opts ...grpc.CallOption) (*ResolveResponse, error)
+}
+
+type coreferenceServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCoreferenceServiceClient(cc grpc.ClientConnInterface) CoreferenceServiceClient {
+	return &coreferenceServiceClient{cc}
+}
+
+func (c *coreferenceServiceClient) Resolve(ctx context.Context, in *ResolveRequest, opts ...grpc.CallOption) (*ResolveResponse, error) {
+	out := new(ResolveResponse)
+	err := c.cc.Invoke(ctx, "/coreference.v2.CoreferenceService/Resolve", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CoreferenceServiceServer is the server API for CoreferenceService service.
+// All implementations must embed UnimplementedCoreferenceServiceServer
+// for forward compatibility
+type CoreferenceServiceServer interface {
+	Resolve(context.Context, *ResolveRequest) (*ResolveResponse, error)
+	mustEmbedUnimplementedCoreferenceServiceServer()
+}
+
+// UnimplementedCoreferenceServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedCoreferenceServiceServer struct {
+}
+
+func (UnimplementedCoreferenceServiceServer) Resolve(context.Context, *ResolveRequest) (*ResolveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Resolve not implemented")
+}
+func (UnimplementedCoreferenceServiceServer) mustEmbedUnimplementedCoreferenceServiceServer() {
+}
+
+// UnsafeCoreferenceServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CoreferenceServiceServer will
+// result in compilation errors.
+type UnsafeCoreferenceServiceServer interface {
+	mustEmbedUnimplementedCoreferenceServiceServer()
+}
+
+func RegisterCoreferenceServiceServer(s grpc.ServiceRegistrar, srv CoreferenceServiceServer) {
+	s.RegisterService(&CoreferenceService_ServiceDesc, srv)
+}
+
+func _CoreferenceService_Resolve_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoreferenceServiceServer).Resolve(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/coreference.v2.CoreferenceService/Resolve",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoreferenceServiceServer).Resolve(ctx, req.(*ResolveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CoreferenceService_ServiceDesc is the grpc.ServiceDesc for CoreferenceService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CoreferenceService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "coreference.v2.CoreferenceService",
+	HandlerType: (*CoreferenceServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Resolve",
+			Handler:    _CoreferenceService_Resolve_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "coreference/v2/coreference.proto",
+}
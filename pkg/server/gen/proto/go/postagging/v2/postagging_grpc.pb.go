@@ -0,0 +1,106 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: postagging/v2/postagging.proto
+
+package postaggingv2
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// PosTaggingServiceClient is the client API for PosTaggingService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PosTaggingServiceClient interface {
+	Tag(ctx context.Context, in *TagRequest, opts ...grpc.CallOption) (*TagResponse, error)
+}
+
+type posTaggingServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPosTaggingServiceClient(cc grpc.ClientConnInterface) PosTaggingServiceClient {
+	return &posTaggingServiceClient{cc}
+}
+
+func (c *posTaggingServiceClient) Tag(ctx context.Context, in *TagRequest, opts ...grpc.CallOption) (*TagResponse, error) {
+	out := new(TagResponse)
+	err := c.cc.Invoke(ctx, "/postagging.v2.PosTaggingService/Tag", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PosTaggingServiceServer is the server API for PosTaggingService service.
+// All implementations must embed UnimplementedPosTaggingServiceServer
+// for forward compatibility
+type PosTaggingServiceServer interface {
+	Tag(context.Context, *TagRequest) (*TagResponse, error)
+	mustEmbedUnimplementedPosTaggingServiceServer()
+}
+
+// UnimplementedPosTaggingServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedPosTaggingServiceServer struct {
+}
+
+func (UnimplementedPosTaggingServiceServer) Tag(context.Context, *TagRequest) (*TagResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Tag not implemented")
+}
+func (UnimplementedPosTaggingServiceServer) mustEmbedUnimplementedPosTaggingServiceServer() {
+}
+
+// UnsafePosTaggingServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PosTaggingServiceServer will
+// result in compilation errors.
+type UnsafePosTaggingServiceServer interface {
+	mustEmbedUnimplementedPosTaggingServiceServer()
+}
+
+func RegisterPosTaggingServiceServer(s grpc.ServiceRegistrar, srv PosTaggingServiceServer) {
+	s.RegisterService(&PosTaggingService_ServiceDesc, srv)
+}
+
+func _PosTaggingService_Tag_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TagRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PosTaggingServiceServer).Tag(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/postagging.v2.PosTaggingService/Tag",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PosTaggingServiceServer).Tag(ctx, req.(*TagRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PosTaggingService_ServiceDesc is the grpc.ServiceDesc for PosTaggingService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PosTaggingService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "postagging.v2.PosTaggingService",
+	HandlerType: (*PosTaggingServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Tag",
+			Handler:    _PosTaggingService_Tag_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "postagging/v2/postagging.proto",
+}
@@ -0,0 +1,106 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: relationextraction/v2/relationextraction.proto
+
+package relationextractionv2
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// RelationExtractionServiceClient is the client API for RelationExtractionService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RelationExtractionServiceClient interface {
+	Extract(ctx context.Context, in *ExtractRequest, opts ...grpc.CallOption) (*ExtractResponse, error)
+}
+
+type relationExtractionServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRelationExtractionServiceClient(cc grpc.ClientConnInterface) RelationExtractionServiceClient {
+	return &relationExtractionServiceClient{cc}
+}
+
+func (c *relationExtractionServiceClient) Extract(ctx context.Context, in *ExtractRequest, opts ...grpc.CallOption) (*ExtractResponse, error) {
+	out := new(ExtractResponse)
+	err := c.cc.Invoke(ctx, "/relationextraction.v2.RelationExtractionService/Extract", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RelationExtractionServiceServer is the server API for RelationExtractionService service.
+// All implementations must embed UnimplementedRelationExtractionServiceServer
+// for forward compatibility
+type RelationExtractionServiceServer interface {
+	Extract(context.Context, *ExtractRequest) (*ExtractResponse, error)
+	mustEmbedUnimplementedRelationExtractionServiceServer()
+}
+
+// UnimplementedRelationExtractionServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedRelationExtractionServiceServer struct {
+}
+
+func (UnimplementedRelationExtractionServiceServer) Extract(context.Context, *ExtractRequest) (*ExtractResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Extract not implemented")
+}
+func (UnimplementedRelationExtractionServiceServer) mustEmbedUnimplementedRelationExtractionServiceServer() {
+}
+
+// UnsafeRelationExtractionServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RelationExtractionServiceServer will
+// result in compilation errors.
+type UnsafeRelationExtractionServiceServer interface {
+	mustEmbedUnimplementedRelationExtractionServiceServer()
+}
+
+func RegisterRelationExtractionServiceServer(s grpc.ServiceRegistrar, srv RelationExtractionServiceServer) {
+	s.RegisterService(&RelationExtractionService_ServiceDesc, srv)
+}
+
+func _RelationExtractionService_Extract_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExtractRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RelationExtractionServiceServer).Extract(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/relationextraction.v2.RelationExtractionService/Extract",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RelationExtractionServiceServer).Extract(ctx, req.(*ExtractRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RelationExtractionService_ServiceDesc is the grpc.ServiceDesc for RelationExtractionService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RelationExtractionService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "relationextraction.v2.RelationExtractionService",
+	HandlerType: (*RelationExtractionServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Extract",
+			Handler:    _RelationExtractionService_Extract_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "relationextraction/v2/relationextraction.proto",
+}
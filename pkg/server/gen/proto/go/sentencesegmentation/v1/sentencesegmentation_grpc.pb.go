@@ -0,0 +1,106 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: sentencesegmentation/v1/sentencesegmentation.proto
+
+package sentencesegmentationv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// SentenceSegmentationServiceClient is the client API for SentenceSegmentationService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SentenceSegmentationServiceClient interface {
+	Segment(ctx context.Context, in *SegmentRequest, opts ...grpc.CallOption) (*SegmentResponse, error)
+}
+
+type sentenceSegmentationServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSentenceSegmentationServiceClient(cc grpc.ClientConnInterface) SentenceSegmentationServiceClient {
+	return &sentenceSegmentationServiceClient{cc}
+}
+
+func (c *sentenceSegmentationServiceClient) Segment(ctx context.Context, in *SegmentRequest, opts ...grpc.CallOption) (*SegmentResponse, error) {
+	out := new(SegmentResponse)
+	err := c.cc.Invoke(ctx, "/sentencesegmentation.v1.SentenceSegmentationService/Segment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SentenceSegmentationServiceServer is the server API for SentenceSegmentationService service.
+// All implementations must embed UnimplementedSentenceSegmentationServiceServer
+// for forward compatibility
+type SentenceSegmentationServiceServer interface {
+	Segment(context.Context, *SegmentRequest) (*SegmentResponse, error)
+	mustEmbedUnimplementedSentenceSegmentationServiceServer()
+}
+
+// UnimplementedSentenceSegmentationServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedSentenceSegmentationServiceServer struct {
+}
+
+func (UnimplementedSentenceSegmentationServiceServer) Segment(context.Context, *SegmentRequest) (*SegmentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Segment not implemented")
+}
+func (UnimplementedSentenceSegmentationServiceServer) mustEmbedUnimplementedSentenceSegmentationServiceServer() {
+}
+
+// UnsafeSentenceSegmentationServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SentenceSegmentationServiceServer will
+// result in compilation errors.
+type UnsafeSentenceSegmentationServiceServer interface {
+	mustEmbedUnimplementedSentenceSegmentationServiceServer()
+}
+
+func RegisterSentenceSegmentationServiceServer(s grpc.ServiceRegistrar, srv SentenceSegmentationServiceServer) {
+	s.RegisterService(&SentenceSegmentationService_ServiceDesc, srv)
+}
+
+func _SentenceSegmentationService_Segment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SegmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SentenceSegmentationServiceServer).Segment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sentencesegmentation.v1.SentenceSegmentationService/Segment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SentenceSegmentationServiceServer).Segment(ctx, req.(*SegmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SentenceSegmentationService_ServiceDesc is the grpc.ServiceDesc for SentenceSegmentationService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SentenceSegmentationService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "sentencesegmentation.v1.SentenceSegmentationService",
+	HandlerType: (*SentenceSegmentationServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Segment",
+			Handler:    _SentenceSegmentationService_Segment_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "sentencesegmentation/v1/sentencesegmentation.proto",
+}
@@ -21,13 +21,63 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type EncodingRequest_VectorEncoding int32
+
+const (
+	// The vector is returned as a list of floats (default)
+	EncodingRequest_FLOATS EncodingRequest_VectorEncoding = 0
+	// The vector is returned packed as little-endian float32 values, in
+	// vector_bytes, base64-encoded in JSON
+	EncodingRequest_PACKED EncodingRequest_VectorEncoding = 1
+)
+
+// Enum value maps for EncodingRequest_VectorEncoding.
+var (
+	EncodingRequest_VectorEncoding_name = map[int32]string{
+		0: "FLOATS",
+		1: "PACKED",
+	}
+	EncodingRequest_VectorEncoding_value = map[string]int32{
+		"FLOATS": 0,
+		"PACKED": 1,
+	}
+)
+
+func (x EncodingRequest_VectorEncoding) Enum() *EncodingRequest_VectorEncoding {
+	p := new(EncodingRequest_VectorEncoding)
+	*p = x
+	return p
+}
+
+func (x EncodingRequest_VectorEncoding) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EncodingRequest_VectorEncoding) Descriptor() protoreflect.EnumDescriptor {
+	return file_textencoding_v1_textencoding_proto_enumTypes[0].Descriptor()
+}
+
+func (EncodingRequest_VectorEncoding) Type() protoreflect.EnumType {
+	return &file_textencoding_v1_textencoding_proto_enumTypes[0]
+}
+
+func (x EncodingRequest_VectorEncoding) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EncodingRequest_VectorEncoding.Descriptor instead.
+func (EncodingRequest_VectorEncoding) EnumDescriptor() ([]byte, []int) {
+	return file_textencoding_v1_textencoding_proto_rawDescGZIP(), []int{0, 0}
+}
+
 type EncodingRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Input           string `protobuf:"bytes,1,opt,name=input,proto3" json:"input,omitempty"`
-	PoolingStrategy int32  `protobuf:"varint,2,opt,name=pooling_strategy,json=poolingStrategy,proto3" json:"pooling_strategy,omitempty"`
+	Input           string                         `protobuf:"bytes,1,opt,name=input,proto3" json:"input,omitempty"`
+	PoolingStrategy int32                          `protobuf:"varint,2,opt,name=pooling_strategy,json=poolingStrategy,proto3" json:"pooling_strategy,omitempty"`
+	VectorEncoding  EncodingRequest_VectorEncoding `protobuf:"varint,3,opt,name=vector_encoding,json=vectorEncoding,proto3,enum=textencoding.v1.EncodingRequest_VectorEncoding" json:"vector_encoding,omitempty"`
 }
 
 func (x *EncodingRequest) Reset() {
@@ -76,12 +126,20 @@ func (x *EncodingRequest) GetPoolingStrategy() int32 {
 	return 0
 }
 
+func (x *EncodingRequest) GetVectorEncoding() EncodingRequest_VectorEncoding {
+	if x != nil {
+		return x.VectorEncoding
+	}
+	return EncodingRequest_FLOATS
+}
+
 type EncodingResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Vector []float32 `protobuf:"fixed32,1,rep,packed,name=vector,proto3" json:"vector,omitempty"`
+	Vector      []float32 `protobuf:"fixed32,1,rep,packed,name=vector,proto3" json:"vector,omitempty"`
+	VectorBytes []byte    `protobuf:"bytes,2,opt,name=vector_bytes,json=vectorBytes,proto3" json:"vector_bytes,omitempty"`
 }
 
 func (x *EncodingResponse) Reset() {
@@ -123,6 +181,13 @@ func (x *EncodingResponse) GetVector() []float32 {
 	return nil
 }
 
+func (x *EncodingResponse) GetVectorBytes() []byte {
+	if x != nil {
+		return x.VectorBytes
+	}
+	return nil
+}
+
 var File_textencoding_v1_textencoding_proto protoreflect.FileDescriptor
 
 var file_textencoding_v1_textencoding_proto_rawDesc = []byte{
@@ -131,28 +196,39 @@ var file_textencoding_v1_textencoding_proto_rawDesc = []byte{
 	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0f, 0x74, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69,
 	0x6e, 0x67, 0x2e, 0x76, 0x31, 0x1a, 0x1c, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x61, 0x70,
 	0x69, 0x2f, 0x61, 0x6e, 0x6e, 0x6f, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x22, 0x52, 0x0a, 0x0f, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x29, 0x0a, 0x10,
-	0x70, 0x6f, 0x6f, 0x6c, 0x69, 0x6e, 0x67, 0x5f, 0x73, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0f, 0x70, 0x6f, 0x6f, 0x6c, 0x69, 0x6e, 0x67, 0x53,
-	0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x22, 0x2a, 0x0a, 0x10, 0x45, 0x6e, 0x63, 0x6f, 0x64,
-	0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x76,
-	0x65, 0x63, 0x74, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x03, 0x28, 0x02, 0x52, 0x06, 0x76, 0x65, 0x63,
-	0x74, 0x6f, 0x72, 0x32, 0x7b, 0x0a, 0x13, 0x54, 0x65, 0x78, 0x74, 0x45, 0x6e, 0x63, 0x6f, 0x64,
-	0x69, 0x6e, 0x67, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x64, 0x0a, 0x06, 0x45, 0x6e,
-	0x63, 0x6f, 0x64, 0x65, 0x12, 0x20, 0x2e, 0x74, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x63, 0x6f, 0x64,
-	0x69, 0x6e, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x21, 0x2e, 0x74, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x63,
-	0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e,
-	0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x15, 0x82, 0xd3, 0xe4, 0x93, 0x02,
-	0x0f, 0x22, 0x0a, 0x2f, 0x76, 0x31, 0x2f, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x65, 0x3a, 0x01, 0x2a,
-	0x42, 0x50, 0x5a, 0x4e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6e,
-	0x6c, 0x70, 0x6f, 0x64, 0x79, 0x73, 0x73, 0x65, 0x79, 0x2f, 0x63, 0x79, 0x62, 0x65, 0x72, 0x74,
-	0x72, 0x6f, 0x6e, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x61,
-	0x70, 0x69, 0x73, 0x2f, 0x74, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67,
-	0x2f, 0x76, 0x31, 0x3b, 0x74, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67,
-	0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x6f, 0x74, 0x6f, 0x22, 0xd6, 0x01, 0x0a, 0x0f, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e, 0x70, 0x75, 0x74,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x29, 0x0a,
+	0x10, 0x70, 0x6f, 0x6f, 0x6c, 0x69, 0x6e, 0x67, 0x5f, 0x73, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67,
+	0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0f, 0x70, 0x6f, 0x6f, 0x6c, 0x69, 0x6e, 0x67,
+	0x53, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x12, 0x58, 0x0a, 0x0f, 0x76, 0x65, 0x63, 0x74,
+	0x6f, 0x72, 0x5f, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x0e, 0x32, 0x2f, 0x2e, 0x74, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67,
+	0x2e, 0x76, 0x31, 0x2e, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x2e, 0x56, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69,
+	0x6e, 0x67, 0x52, 0x0e, 0x76, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69,
+	0x6e, 0x67, 0x22, 0x28, 0x0a, 0x0e, 0x56, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x45, 0x6e, 0x63, 0x6f,
+	0x64, 0x69, 0x6e, 0x67, 0x12, 0x0a, 0x0a, 0x06, 0x46, 0x4c, 0x4f, 0x41, 0x54, 0x53, 0x10, 0x00,
+	0x12, 0x0a, 0x0a, 0x06, 0x50, 0x41, 0x43, 0x4b, 0x45, 0x44, 0x10, 0x01, 0x22, 0x4d, 0x0a, 0x10,
+	0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x16, 0x0a, 0x06, 0x76, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x03, 0x28, 0x02,
+	0x52, 0x06, 0x76, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x12, 0x21, 0x0a, 0x0c, 0x76, 0x65, 0x63, 0x74,
+	0x6f, 0x72, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b,
+	0x76, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x42, 0x79, 0x74, 0x65, 0x73, 0x32, 0x7b, 0x0a, 0x13, 0x54,
+	0x65, 0x78, 0x74, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x53, 0x65, 0x72, 0x76, 0x69,
+	0x63, 0x65, 0x12, 0x64, 0x0a, 0x06, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x20, 0x2e, 0x74,
+	0x65, 0x78, 0x74, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x45,
+	0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x21,
+	0x2e, 0x74, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x76, 0x31,
+	0x2e, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x22, 0x15, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x0f, 0x22, 0x0a, 0x2f, 0x76, 0x31, 0x2f, 0x65,
+	0x6e, 0x63, 0x6f, 0x64, 0x65, 0x3a, 0x01, 0x2a, 0x42, 0x50, 0x5a, 0x4e, 0x67, 0x69, 0x74, 0x68,
+	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6e, 0x6c, 0x70, 0x6f, 0x64, 0x79, 0x73, 0x73, 0x65,
+	0x79, 0x2f, 0x63, 0x79, 0x62, 0x65, 0x72, 0x74, 0x72, 0x6f, 0x6e, 0x2f, 0x70, 0x6b, 0x67, 0x2f,
+	0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x61, 0x70, 0x69, 0x73, 0x2f, 0x74, 0x65, 0x78, 0x74,
+	0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2f, 0x76, 0x31, 0x3b, 0x74, 0x65, 0x78, 0x74,
+	0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
 }
 
 var (
@@ -167,19 +243,22 @@ func file_textencoding_v1_textencoding_proto_rawDescGZIP() []byte {
 	return file_textencoding_v1_textencoding_proto_rawDescData
 }
 
+var file_textencoding_v1_textencoding_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
 var file_textencoding_v1_textencoding_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
 var file_textencoding_v1_textencoding_proto_goTypes = []interface{}{
-	(*EncodingRequest)(nil),  // 0: textencoding.v1.EncodingRequest
-	(*EncodingResponse)(nil), // 1: textencoding.v1.EncodingResponse
+	(EncodingRequest_VectorEncoding)(0), // 0: textencoding.v1.EncodingRequest.VectorEncoding
+	(*EncodingRequest)(nil),             // 1: textencoding.v1.EncodingRequest
+	(*EncodingResponse)(nil),            // 2: textencoding.v1.EncodingResponse
 }
 var file_textencoding_v1_textencoding_proto_depIdxs = []int32{
-	0, // 0: textencoding.v1.TextEncodingService.Encode:input_type -> textencoding.v1.EncodingRequest
-	1, // 1: textencoding.v1.TextEncodingService.Encode:output_type -> textencoding.v1.EncodingResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0, // 0: textencoding.v1.EncodingRequest.vector_encoding:type_name -> textencoding.v1.EncodingRequest.VectorEncoding
+	1, // 1: textencoding.v1.TextEncodingService.Encode:input_type -> textencoding.v1.EncodingRequest
+	2, // 2: textencoding.v1.TextEncodingService.Encode:output_type -> textencoding.v1.EncodingResponse
+	2, // [2:3] is the sub-list for method output_type
+	1, // [1:2] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_textencoding_v1_textencoding_proto_init() }
@@ -218,13 +297,14 @@ func file_textencoding_v1_textencoding_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_textencoding_v1_textencoding_proto_rawDesc,
-			NumEnums:      0,
+			NumEnums:      1,
 			NumMessages:   2,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_textencoding_v1_textencoding_proto_goTypes,
 		DependencyIndexes: file_textencoding_v1_textencoding_proto_depIdxs,
+		EnumInfos:         file_textencoding_v1_textencoding_proto_enumTypes,
 		MessageInfos:      file_textencoding_v1_textencoding_proto_msgTypes,
 	}.Build()
 	File_textencoding_v1_textencoding_proto = out.File
@@ -0,0 +1,383 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        (unknown)
+// source: textencoding/v2/textencoding.proto
+
+package textencodingv2
+
+import (
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type EncodingRequest_VectorEncoding int32
+
+const (
+	// The vectors are returned as lists of floats (default)
+	EncodingRequest_FLOATS EncodingRequest_VectorEncoding = 0
+	// The vectors are returned packed as little-endian float32 values, in
+	// vector_bytes, base64-encoded in JSON
+	EncodingRequest_PACKED EncodingRequest_VectorEncoding = 1
+)
+
+// Enum value maps for EncodingRequest_VectorEncoding.
+var (
+	EncodingRequest_VectorEncoding_name = map[int32]string{
+		0: "FLOATS",
+		1: "PACKED",
+	}
+	EncodingRequest_VectorEncoding_value = map[string]int32{
+		"FLOATS": 0,
+		"PACKED": 1,
+	}
+)
+
+func (x EncodingRequest_VectorEncoding) Enum() *EncodingRequest_VectorEncoding {
+	p := new(EncodingRequest_VectorEncoding)
+	*p = x
+	return p
+}
+
+func (x EncodingRequest_VectorEncoding) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EncodingRequest_VectorEncoding) Descriptor() protoreflect.EnumDescriptor {
+	return file_textencoding_v2_textencoding_proto_enumTypes[0].Descriptor()
+}
+
+func (EncodingRequest_VectorEncoding) Type() protoreflect.EnumType {
+	return &file_textencoding_v2_textencoding_proto_enumTypes[0]
+}
+
+func (x EncodingRequest_VectorEncoding) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EncodingRequest_VectorEncoding.Descriptor instead.
+func (EncodingRequest_VectorEncoding) EnumDescriptor() ([]byte, []int) {
+	return file_textencoding_v2_textencoding_proto_rawDescGZIP(), []int{0, 0}
+}
+
+type EncodingRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The inputs are encoded in a batch, with the same options.
+	Inputs          []string                       `protobuf:"bytes,1,rep,name=inputs,proto3" json:"inputs,omitempty"`
+	PoolingStrategy int32                          `protobuf:"varint,2,opt,name=pooling_strategy,json=poolingStrategy,proto3" json:"pooling_strategy,omitempty"`
+	VectorEncoding  EncodingRequest_VectorEncoding `protobuf:"varint,3,opt,name=vector_encoding,json=vectorEncoding,proto3,enum=textencoding.v2.EncodingRequest_VectorEncoding" json:"vector_encoding,omitempty"`
+}
+
+func (x *EncodingRequest) Reset() {
+	*x = EncodingRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_textencoding_v2_textencoding_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EncodingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncodingRequest) ProtoMessage() {}
+
+func (x *EncodingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_textencoding_v2_textencoding_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncodingRequest.ProtoReflect.Descriptor instead.
+func (*EncodingRequest) Descriptor() ([]byte, []int) {
+	return file_textencoding_v2_textencoding_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *EncodingRequest) GetInputs() []string {
+	if x != nil {
+		return x.Inputs
+	}
+	return nil
+}
+
+func (x *EncodingRequest) GetPoolingStrategy() int32 {
+	if x != nil {
+		return x.PoolingStrategy
+	}
+	return 0
+}
+
+func (x *EncodingRequest) GetVectorEncoding() EncodingRequest_VectorEncoding {
+	if x != nil {
+		return x.VectorEncoding
+	}
+	return EncodingRequest_FLOATS
+}
+
+type EncodingResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The embeddings of the inputs, in the same order.
+	Embeddings []*Embedding `protobuf:"bytes,1,rep,name=embeddings,proto3" json:"embeddings,omitempty"`
+}
+
+func (x *EncodingResponse) Reset() {
+	*x = EncodingResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_textencoding_v2_textencoding_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EncodingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncodingResponse) ProtoMessage() {}
+
+func (x *EncodingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_textencoding_v2_textencoding_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncodingResponse.ProtoReflect.Descriptor instead.
+func (*EncodingResponse) Descriptor() ([]byte, []int) {
+	return file_textencoding_v2_textencoding_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *EncodingResponse) GetEmbeddings() []*Embedding {
+	if x != nil {
+		return x.Embeddings
+	}
+	return nil
+}
+
+type Embedding struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Vector []float32 `protobuf:"fixed32,1,rep,packed,name=vector,proto3" json:"vector,omitempty"`
+	// The vector packed as little-endian float32 values, if requested in
+	// place of the list of floats.
+	VectorBytes []byte `protobuf:"bytes,2,opt,name=vector_bytes,json=vectorBytes,proto3" json:"vector_bytes,omitempty"`
+}
+
+func (x *Embedding) Reset() {
+	*x = Embedding{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_textencoding_v2_textencoding_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Embedding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Embedding) ProtoMessage() {}
+
+func (x *Embedding) ProtoReflect() protoreflect.Message {
+	mi := &file_textencoding_v2_textencoding_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Embedding.ProtoReflect.Descriptor instead.
+func (*Embedding) Descriptor() ([]byte, []int) {
+	return file_textencoding_v2_textencoding_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Embedding) GetVector() []float32 {
+	if x != nil {
+		return x.Vector
+	}
+	return nil
+}
+
+func (x *Embedding) GetVectorBytes() []byte {
+	if x != nil {
+		return x.VectorBytes
+	}
+	return nil
+}
+
+var File_textencoding_v2_textencoding_proto protoreflect.FileDescriptor
+
+var file_textencoding_v2_textencoding_proto_rawDesc = []byte{
+	0x0a, 0x22, 0x74, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2f, 0x76,
+	0x32, 0x2f, 0x74, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0f, 0x74, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69,
+	0x6e, 0x67, 0x2e, 0x76, 0x32, 0x1a, 0x1c, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x61, 0x70,
+	0x69, 0x2f, 0x61, 0x6e, 0x6e, 0x6f, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x22, 0xd8, 0x01, 0x0a, 0x0f, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x69, 0x6e, 0x70, 0x75, 0x74,
+	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x12,
+	0x29, 0x0a, 0x10, 0x70, 0x6f, 0x6f, 0x6c, 0x69, 0x6e, 0x67, 0x5f, 0x73, 0x74, 0x72, 0x61, 0x74,
+	0x65, 0x67, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0f, 0x70, 0x6f, 0x6f, 0x6c, 0x69,
+	0x6e, 0x67, 0x53, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x12, 0x58, 0x0a, 0x0f, 0x76, 0x65,
+	0x63, 0x74, 0x6f, 0x72, 0x5f, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x0e, 0x32, 0x2f, 0x2e, 0x74, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69,
+	0x6e, 0x67, 0x2e, 0x76, 0x32, 0x2e, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x56, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x45, 0x6e, 0x63, 0x6f,
+	0x64, 0x69, 0x6e, 0x67, 0x52, 0x0e, 0x76, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x45, 0x6e, 0x63, 0x6f,
+	0x64, 0x69, 0x6e, 0x67, 0x22, 0x28, 0x0a, 0x0e, 0x56, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x45, 0x6e,
+	0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x12, 0x0a, 0x0a, 0x06, 0x46, 0x4c, 0x4f, 0x41, 0x54, 0x53,
+	0x10, 0x00, 0x12, 0x0a, 0x0a, 0x06, 0x50, 0x41, 0x43, 0x4b, 0x45, 0x44, 0x10, 0x01, 0x22, 0x4e,
+	0x0a, 0x10, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x3a, 0x0a, 0x0a, 0x65, 0x6d, 0x62, 0x65, 0x64, 0x64, 0x69, 0x6e, 0x67, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x74, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x63,
+	0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x76, 0x32, 0x2e, 0x45, 0x6d, 0x62, 0x65, 0x64, 0x64, 0x69,
+	0x6e, 0x67, 0x52, 0x0a, 0x65, 0x6d, 0x62, 0x65, 0x64, 0x64, 0x69, 0x6e, 0x67, 0x73, 0x22, 0x46,
+	0x0a, 0x09, 0x45, 0x6d, 0x62, 0x65, 0x64, 0x64, 0x69, 0x6e, 0x67, 0x12, 0x16, 0x0a, 0x06, 0x76,
+	0x65, 0x63, 0x74, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x03, 0x28, 0x02, 0x52, 0x06, 0x76, 0x65, 0x63,
+	0x74, 0x6f, 0x72, 0x12, 0x21, 0x0a, 0x0c, 0x76, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x5f, 0x62, 0x79,
+	0x74, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x76, 0x65, 0x63, 0x74, 0x6f,
+	0x72, 0x42, 0x79, 0x74, 0x65, 0x73, 0x32, 0x7b, 0x0a, 0x13, 0x54, 0x65, 0x78, 0x74, 0x45, 0x6e,
+	0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x64, 0x0a,
+	0x06, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x20, 0x2e, 0x74, 0x65, 0x78, 0x74, 0x65, 0x6e,
+	0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x76, 0x32, 0x2e, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69,
+	0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x21, 0x2e, 0x74, 0x65, 0x78, 0x74,
+	0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x76, 0x32, 0x2e, 0x45, 0x6e, 0x63, 0x6f,
+	0x64, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x15, 0x82, 0xd3,
+	0xe4, 0x93, 0x02, 0x0f, 0x3a, 0x01, 0x2a, 0x22, 0x0a, 0x2f, 0x76, 0x32, 0x2f, 0x65, 0x6e, 0x63,
+	0x6f, 0x64, 0x65, 0x42, 0x50, 0x5a, 0x4e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f,
+	0x6d, 0x2f, 0x6e, 0x6c, 0x70, 0x6f, 0x64, 0x79, 0x73, 0x73, 0x65, 0x79, 0x2f, 0x63, 0x79, 0x62,
+	0x65, 0x72, 0x74, 0x72, 0x6f, 0x6e, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x2f, 0x61, 0x70, 0x69, 0x73, 0x2f, 0x74, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x63, 0x6f, 0x64,
+	0x69, 0x6e, 0x67, 0x2f, 0x76, 0x32, 0x3b, 0x74, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x63, 0x6f, 0x64,
+	0x69, 0x6e, 0x67, 0x76, 0x32, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_textencoding_v2_textencoding_proto_rawDescOnce sync.Once
+	file_textencoding_v2_textencoding_proto_rawDescData = file_textencoding_v2_textencoding_proto_rawDesc
+)
+
+func file_textencoding_v2_textencoding_proto_rawDescGZIP() []byte {
+	file_textencoding_v2_textencoding_proto_rawDescOnce.Do(func() {
+		file_textencoding_v2_textencoding_proto_rawDescData = protoimpl.X.CompressGZIP(file_textencoding_v2_textencoding_proto_rawDescData)
+	})
+	return file_textencoding_v2_textencoding_proto_rawDescData
+}
+
+var file_textencoding_v2_textencoding_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_textencoding_v2_textencoding_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_textencoding_v2_textencoding_proto_goTypes = []interface{}{
+	(EncodingRequest_VectorEncoding)(0), // 0: textencoding.v2.EncodingRequest.VectorEncoding
+	(*EncodingRequest)(nil),             // 1: textencoding.v2.EncodingRequest
+	(*EncodingResponse)(nil),            // 2: textencoding.v2.EncodingResponse
+	(*Embedding)(nil),                   // 3: textencoding.v2.Embedding
+}
+var file_textencoding_v2_textencoding_proto_depIdxs = []int32{
+	0, // 0: textencoding.v2.EncodingRequest.vector_encoding:type_name -> textencoding.v2.EncodingRequest.VectorEncoding
+	3, // 1: textencoding.v2.EncodingResponse.embeddings:type_name -> textencoding.v2.Embedding
+	1, // 2: textencoding.v2.TextEncodingService.Encode:input_type -> textencoding.v2.EncodingRequest
+	2, // 3: textencoding.v2.TextEncodingService.Encode:output_type -> textencoding.v2.EncodingResponse
+	3, // [3:4] is the sub-list for method output_type
+	2, // [2:3] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_textencoding_v2_textencoding_proto_init() }
+func file_textencoding_v2_textencoding_proto_init() {
+	if File_textencoding_v2_textencoding_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_textencoding_v2_textencoding_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EncodingRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_textencoding_v2_textencoding_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EncodingResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_textencoding_v2_textencoding_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Embedding); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_textencoding_v2_textencoding_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_textencoding_v2_textencoding_proto_goTypes,
+		DependencyIndexes: file_textencoding_v2_textencoding_proto_depIdxs,
+		EnumInfos:         file_textencoding_v2_textencoding_proto_enumTypes,
+		MessageInfos:      file_textencoding_v2_textencoding_proto_msgTypes,
+	}.Build()
+	File_textencoding_v2_textencoding_proto = out.File
+	file_textencoding_v2_textencoding_proto_rawDesc = nil
+	file_textencoding_v2_textencoding_proto_goTypes = nil
+	file_textencoding_v2_textencoding_proto_depIdxs = nil
+}
@@ -0,0 +1,105 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: textencoding/v2/textencoding.proto
+
+package textencodingv2
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// TextEncodingServiceClient is the client API for TextEncodingService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TextEncodingServiceClient interface {
+	Encode(ctx context.Context, in *EncodingRequest, opts ...grpc.CallOption) (*EncodingResponse, error)
+}
+
+type textEncodingServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTextEncodingServiceClient(cc grpc.ClientConnInterface) TextEncodingServiceClient {
+	return &textEncodingServiceClient{cc}
+}
+
+func (c *textEncodingServiceClient) Encode(ctx context.Context, in *EncodingRequest, opts ...grpc.CallOption) (*EncodingResponse, error) {
+	out := new(EncodingResponse)
+	err := c.cc.Invoke(ctx, "/textencoding.v2.TextEncodingService/Encode", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TextEncodingServiceServer is the server API for TextEncodingService service.
+// All implementations must embed UnimplementedTextEncodingServiceServer
+// for forward compatibility
+type TextEncodingServiceServer interface {
+	Encode(context.Context, *EncodingRequest) (*EncodingResponse, error)
+	mustEmbedUnimplementedTextEncodingServiceServer()
+}
+
+// UnimplementedTextEncodingServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedTextEncodingServiceServer struct {
+}
+
+func (UnimplementedTextEncodingServiceServer) Encode(context.Context, *EncodingRequest) (*EncodingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Encode not implemented")
+}
+func (UnimplementedTextEncodingServiceServer) mustEmbedUnimplementedTextEncodingServiceServer() {}
+
+// UnsafeTextEncodingServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TextEncodingServiceServer will
+// result in compilation errors.
+type UnsafeTextEncodingServiceServer interface {
+	mustEmbedUnimplementedTextEncodingServiceServer()
+}
+
+func RegisterTextEncodingServiceServer(s grpc.ServiceRegistrar, srv TextEncodingServiceServer) {
+	s.RegisterService(&TextEncodingService_ServiceDesc, srv)
+}
+
+func _TextEncodingService_Encode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EncodingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TextEncodingServiceServer).Encode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/textencoding.v2.TextEncodingService/Encode",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TextEncodingServiceServer).Encode(ctx, req.(*EncodingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TextEncodingService_ServiceDesc is the grpc.ServiceDesc for TextEncodingService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TextEncodingService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "textencoding.v2.TextEncodingService",
+	HandlerType: (*TextEncodingServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Encode",
+			Handler:    _TextEncodingService_Encode_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "textencoding/v2/textencoding.proto",
+}
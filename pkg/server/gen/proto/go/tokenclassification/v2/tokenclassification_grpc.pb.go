@@ -0,0 +1,106 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: tokenclassification/v2/tokenclassification.proto
+
+package tokenclassificationv2
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// TokenClassificationServiceClient is the client API for TokenClassificationService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TokenClassificationServiceClient interface {
+	Classify(ctx context.Context, in *ClassifyRequest, opts ...grpc.CallOption) (*ClassifyResponse, error)
+}
+
+type tokenClassificationServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTokenClassificationServiceClient(cc grpc.ClientConnInterface) TokenClassificationServiceClient {
+	return &tokenClassificationServiceClient{cc}
+}
+
+func (c *tokenClassificationServiceClient) Classify(ctx context.Context, in *ClassifyRequest, opts ...grpc.CallOption) (*ClassifyResponse, error) {
+	out := new(ClassifyResponse)
+	err := c.cc.Invoke(ctx, "/tokenclassification.v2.TokenClassificationService/Classify", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TokenClassificationServiceServer is the server API for TokenClassificationService service.
+// All implementations must embed UnimplementedTokenClassificationServiceServer
+// for forward compatibility
+type TokenClassificationServiceServer interface {
+	Classify(context.Context, *ClassifyRequest) (*ClassifyResponse, error)
+	mustEmbedUnimplementedTokenClassificationServiceServer()
+}
+
+// UnimplementedTokenClassificationServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedTokenClassificationServiceServer struct {
+}
+
+func (UnimplementedTokenClassificationServiceServer) Classify(context.Context, *ClassifyRequest) (*ClassifyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Classify not implemented")
+}
+func (UnimplementedTokenClassificationServiceServer) mustEmbedUnimplementedTokenClassificationServiceServer() {
+}
+
+// UnsafeTokenClassificationServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TokenClassificationServiceServer will
+// result in compilation errors.
+type UnsafeTokenClassificationServiceServer interface {
+	mustEmbedUnimplementedTokenClassificationServiceServer()
+}
+
+func RegisterTokenClassificationServiceServer(s grpc.ServiceRegistrar, srv TokenClassificationServiceServer) {
+	s.RegisterService(&TokenClassificationService_ServiceDesc, srv)
+}
+
+func _TokenClassificationService_Classify_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClassifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenClassificationServiceServer).Classify(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/tokenclassification.v2.TokenClassificationService/Classify",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenClassificationServiceServer).Classify(ctx, req.(*ClassifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TokenClassificationService_ServiceDesc is the grpc.ServiceDesc for TokenClassificationService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TokenClassificationService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tokenclassification.v2.TokenClassificationService",
+	HandlerType: (*TokenClassificationServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Classify",
+			Handler:    _TokenClassificationService_Classify_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "tokenclassification/v2/tokenclassification.proto",
+}
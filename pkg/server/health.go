@@ -0,0 +1,56 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+// healthPath is the path of the health of the server and of its models.
+const healthPath = "/health"
+
+// ModelHealth is the health of a served model, reported at /health.
+type ModelHealth struct {
+	// Model identifies the model, e.g. by name and revision.
+	Model string `json:"model"`
+	// Status is the load status of the model (see tasks.LoadStatus), e.g.
+	// "loading".
+	Status string `json:"status"`
+	// Error is the error of the last load of the model, if it failed.
+	Error string `json:"error,omitempty"`
+}
+
+// withHealth serves the health of the models at /health, if reported.
+func (s *Server) withHealth(h http.Handler) http.Handler {
+	if s.conf.ModelHealth == nil {
+		return h
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", h)
+	mux.HandleFunc(healthPath, s.serveHealth)
+	return mux
+}
+
+// serveHealth writes the health of the models, and the status of the
+// server: "loading" while any of them is loading, "serving" otherwise. The
+// server is serving while its models are loaded lazily, so that their first
+// requests reach it, and while their loads fail, since other models may be
+// ready; the status of each model tells them apart.
+func (s *Server) serveHealth(w http.ResponseWriter, _ *http.Request) {
+	models := s.conf.ModelHealth()
+	status := "serving"
+	for _, m := range models {
+		if m.Status == "loading" {
+			status = "loading"
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"status": status, "models": models}); err != nil {
+		log.Warn().Err(err).Msg("failed to write the health of the models")
+	}
+}
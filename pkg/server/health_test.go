@@ -0,0 +1,78 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithHealth(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	tests := []struct {
+		name       string
+		models     []ModelHealth
+		noHealth   bool
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "not reported", noHealth: true, path: "/health", wantStatus: http.StatusTeapot},
+		{name: "other paths", path: "/v1/classify", wantStatus: http.StatusTeapot},
+		{
+			name:       "no models",
+			path:       "/health",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"models":[],"status":"serving"}`,
+		},
+		{
+			name:       "ready",
+			models:     []ModelHealth{{Model: "a@main", Status: "ready"}, {Model: "b@main", Status: "pending"}},
+			path:       "/health",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"models":[{"model":"a@main","status":"ready"},{"model":"b@main","status":"pending"}],"status":"serving"}`,
+		},
+		{
+			name:       "loading",
+			models:     []ModelHealth{{Model: "a@main", Status: "ready"}, {Model: "b@main", Status: "loading"}},
+			path:       "/health",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"models":[{"model":"a@main","status":"ready"},{"model":"b@main","status":"loading"}],"status":"loading"}`,
+		},
+		{
+			name:       "failed",
+			models:     []ModelHealth{{Model: "a@main", Status: "failed", Error: "download failed"}},
+			path:       "/health",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"models":[{"model":"a@main","status":"failed","error":"download failed"}],"status":"serving"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &Config{}
+			if !tt.noHealth {
+				models := tt.models
+				if models == nil {
+					models = []ModelHealth{}
+				}
+				conf.ModelHealth = func() []ModelHealth { return models }
+			}
+			s := New(conf, RequestHandlers{})
+			w := httptest.NewRecorder()
+			s.withHealth(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantBody != "" {
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+				assert.JSONEq(t, tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,607 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"github.com/nlpodyssey/cybertron/pkg/tenancy"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// jobsPath is the HTTP path submitting an async job, by the name of
+	// the task or of its method, as for the bulk uploads.
+	jobsPath = "/v1/jobs/{task}"
+	// jobPath is the HTTP path of the status of a job.
+	jobPath = "/v1/jobs/{id}"
+	// jobResultsPath is the HTTP path of the results of a job.
+	jobResultsPath = "/v1/jobs/{id}/results"
+)
+
+const (
+	// webhookHeader is the HTTP header of the request of a job with the URL
+	// the job is POSTed to once finished.
+	webhookHeader = "cybertron-webhook"
+	// signatureHeader is the HTTP header of the webhook requests with
+	// their timestamp and HMAC-SHA256 signature, keyed by the webhook
+	// secret: "t=<unix seconds>,sha256=<hex>", the signed message being the
+	// timestamp, a dot, and the body, so that the receivers can reject the
+	// forged and the replayed requests.
+	signatureHeader = "Cybertron-Signature"
+)
+
+const (
+	// jobRetention is how long a finished job is kept, by default.
+	jobRetention = 24 * time.Hour
+	// jobStoreTimeout is the timeout of the writes to the job store.
+	jobStoreTimeout = 30 * time.Second
+	// webhookAttempts is the number of attempts to deliver a webhook,
+	// with an exponential backoff from webhookBackoff.
+	webhookAttempts = 5
+	webhookBackoff  = time.Second
+	// webhookMaxResults is the maximum size of the results sent in the
+	// body of a webhook; larger ones are fetched from the resultsPath.
+	webhookMaxResults = 1 << 20
+	// jobMaxResults is the maximum size of the results of a job, kept in
+	// memory while it's running: the job fails once exceeded.
+	jobMaxResults = 256 << 20
+)
+
+// jobStatus is the status of an async job.
+type jobStatus string
+
+const (
+	jobRunning   jobStatus = "RUNNING"
+	jobSucceeded jobStatus = "SUCCEEDED"
+	jobFailed    jobStatus = "FAILED"
+)
+
+// asyncJob is a bulk upload served in the background: its results are kept
+// until they expire, and POSTed to its webhook once finished, if any. The
+// running jobs are kept in memory, and, with a job store, also written to
+// it after each batch of rows, and moved to it once finished.
+type asyncJob struct {
+	ID     string    `json:"id"`
+	Method string    `json:"method"`
+	Status jobStatus `json:"status"`
+	// Rows and FailedRows are the numbers of rows served so far, and of
+	// the ones that failed among them.
+	Rows       int        `json:"rows"`
+	FailedRows int        `json:"failedRows"`
+	Error      string     `json:"error,omitempty"`
+	Created    time.Time  `json:"created"`
+	Finished   *time.Time `json:"finished,omitempty"`
+
+	tenant  string
+	webhook string
+	results []byte
+}
+
+// jobRegistry holds the async jobs, by ID.
+type jobRegistry struct {
+	mu   sync.Mutex
+	jobs map[string]*asyncJob
+}
+
+func newJobRegistry() *jobRegistry {
+	return &jobRegistry{jobs: make(map[string]*asyncJob)}
+}
+
+// add adds the job, removing the ones finished for longer than the
+// retention.
+func (r *jobRegistry) add(j *asyncJob, retention time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, old := range r.jobs {
+		if old.Finished != nil && time.Since(*old.Finished) > retention {
+			delete(r.jobs, id)
+		}
+	}
+	r.jobs[j.ID] = j
+}
+
+// get returns a copy of the job, and whether it's found.
+func (r *jobRegistry) get(id string) (asyncJob, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j, ok := r.jobs[id]
+	if !ok {
+		return asyncJob{}, false
+	}
+	return *j, true
+}
+
+// remove removes the job.
+func (r *jobRegistry) remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.jobs, id)
+}
+
+// update updates the job with the function.
+func (r *jobRegistry) update(j *asyncJob, f func(j *asyncJob)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f(j)
+}
+
+// jobWriter appends the JSON lines of the results to the job, counting
+// them, up to the maximum size of the results, if any, failing with
+// ErrMemoryLimitExceeded beyond.
+type jobWriter struct {
+	r   *jobRegistry
+	j   *asyncJob
+	max int
+}
+
+func (w jobWriter) Write(p []byte) (int, error) {
+	var err error
+	w.r.update(w.j, func(j *asyncJob) {
+		if w.max > 0 && len(j.results)+len(p) > w.max {
+			err = fmt.Errorf("%w: the results of the job exceed %d bytes", errdefs.ErrMemoryLimitExceeded, w.max)
+			return
+		}
+		j.results = append(j.results, p...)
+		j.Rows++
+		if isErrorResponse(p) {
+			j.FailedRows++
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// newJobID returns a random ID, unguessable, so that the jobs aren't
+// visible to the other callers.
+func newJobID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// parseWebhook parses the webhook URL of a job, if any. The URLs of the
+// loopback, private and link-local addresses are rejected, unless allowed
+// by the configuration; the host names are checked again once resolved,
+// when the webhook is delivered, see webhookDialControl.
+func (s *Server) parseWebhook(raw string) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+	if s.conf.WebhookSecret == "" {
+		return "", fmt.Errorf("%w: webhooks are disabled", errdefs.ErrInvalidRequest)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return "", fmt.Errorf("%w: invalid webhook URL %#v", errdefs.ErrInvalidRequest, raw)
+	}
+	if !s.conf.WebhookAllowPrivate {
+		host := u.Hostname()
+		if ip := net.ParseIP(host); (ip != nil && !isPublicIP(ip)) || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+			return "", fmt.Errorf("%w: webhook URL %#v of a non-public address", errdefs.ErrInvalidRequest, raw)
+		}
+	}
+	return u.String(), nil
+}
+
+// cgnatRange is the shared address space of the carrier-grade NATs
+// (RFC 6598), not routed on the internet.
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP returns whether the address is routed on the internet, i.e.
+// it isn't a loopback, private, link-local (e.g. the metadata services of
+// the clouds), multicast, or unspecified address.
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() && !ip.IsUnspecified() && !cgnatRange.Contains(ip)
+}
+
+// webhookDialControl rejects the connections of the webhooks to the
+// non-public addresses, checked once the host is resolved, so that a host
+// name can't point them to the internal services of the server.
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("webhook connection to the non-public address %s refused", host)
+	}
+	return nil
+}
+
+// webhookClient returns the HTTP client of the webhooks, connecting only to
+// the public addresses, unless allowed by the configuration. It doesn't go
+// through the proxies of the environment, whose addresses would be the ones
+// checked instead.
+func (s *Server) webhookClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !s.conf.WebhookAllowPrivate {
+		dialer.Control = webhookDialControl
+	}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+		// The redirects aren't followed, the webhooks being signed for
+		// their URL.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// submitJob starts an async job serving the rows of the body as the bulk
+// uploads do, and replies with the job, ACCEPTED. The body is read whole
+// before, so that the job outlives the request.
+func (s *Server) submitJob(methods methodRegistry) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		name, _, err := s.bulkCall(methods, params["task"])
+		if err != nil {
+			writeHTTPError(w, r, err)
+			return
+		}
+		if err := s.checkSunset(parentName(parentName(name))); err != nil {
+			writeHTTPError(w, r, err)
+			return
+		}
+		webhook, err := s.parseWebhook(r.Header.Get(webhookHeader))
+		if err != nil {
+			writeHTTPError(w, r, err)
+			return
+		}
+		md, err := requestDescriptor(name)
+		if err != nil {
+			writeHTTPError(w, r, err)
+			return
+		}
+		f, err := spoolBody(r.Body)
+		if err != nil {
+			writeHTTPError(w, r, err)
+			return
+		}
+		rows, err := openBulkRows(f, r.Header.Get("Content-Type"), md)
+		if err != nil {
+			f.Close()
+			writeHTTPError(w, r, err)
+			return
+		}
+
+		tenant, _ := r.Context().Value(callerKey{}).(string)
+		j := &asyncJob{
+			ID:      newJobID(),
+			Method:  name,
+			Status:  jobRunning,
+			Created: time.Now().UTC(),
+			tenant:  tenant,
+			webhook: webhook,
+		}
+		snapshot := *j
+		s.jobs.add(j, s.jobRetention())
+		s.storeJob(snapshot, false)
+		// The job keeps the values of the request, e.g. its tenant, priority
+		// and adapter, but it's only canceled with the server.
+		ctx, cancel := context.WithCancel(detachedContext{r.Context()})
+		go func() {
+			select {
+			case <-s.ctx.Done():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		go func() {
+			defer cancel()
+			defer f.Close()
+			s.runJob(ctx, j, rows)
+		}()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/v1/jobs/"+j.ID)
+		w.WriteHeader(http.StatusAccepted)
+		if err := json.NewEncoder(w).Encode(snapshot); err != nil {
+			log.Warn().Err(err).Msg("failed to write the job")
+		}
+	}
+}
+
+// runJob serves the rows of the job with the current request handler, or
+// with the workers of the work queue on a frontend, and delivers its
+// webhook once finished. Its tenant, if any, is accounted for
+// the tokens processed.
+func (s *Server) runJob(ctx context.Context, j *asyncJob, rows bulkRows) {
+	call := func(ctx context.Context, data []byte) []byte {
+		_, resp := s.callCurrent(ctx, j.Method, data, chainUnaryInterceptors(s.rowInterceptors()...))
+		return resp
+	}
+	if s.queue != nil {
+		call = s.queueCall(j.Method)
+	}
+	run := func(ctx context.Context) (any, error) {
+		// The progress is stored after each batch, for the other servers.
+		flush := func() {
+			if s.conf.JobStore == nil {
+				return
+			}
+			var snapshot asyncJob
+			s.jobs.update(j, func(j *asyncJob) { snapshot = *j })
+			s.storeJob(snapshot, false)
+		}
+		_, _, err := serveRows(ctx, rows, call, jobWriter{r: s.jobs, j: j, max: jobMaxResults}, flush)
+		return nil, err
+	}
+	var err error
+	if a := s.jobAccount(j); a != nil {
+		_, err = account(ctx, a, run)
+	} else {
+		_, err = run(ctx)
+	}
+
+	var snapshot asyncJob
+	s.jobs.update(j, func(j *asyncJob) {
+		now := time.Now().UTC()
+		j.Finished = &now
+		j.Status = jobSucceeded
+		if err != nil {
+			j.Status = jobFailed
+			j.Error = err.Error()
+		}
+		snapshot = *j
+	})
+	if s.storeJob(snapshot, true) {
+		// The job is served by the store from now on.
+		s.jobs.remove(j.ID)
+	}
+	log.Info().
+		Str("job", j.ID).
+		Str("method", j.Method).
+		Str("status", string(snapshot.Status)).
+		Int("rows", snapshot.Rows).
+		Int("failed_rows", snapshot.FailedRows).
+		Msg("job finished")
+	if snapshot.webhook != "" {
+		s.deliverWebhook(ctx, snapshot)
+	}
+}
+
+// jobRetention returns how long the finished jobs are kept.
+func (s *Server) jobRetention() time.Duration {
+	if s.conf.JobTTL > 0 {
+		return s.conf.JobTTL
+	}
+	return jobRetention
+}
+
+// jobKey returns the key of the job in the job store.
+func jobKey(id string) string {
+	return "cybertron:job:" + id
+}
+
+// jobResultsKey returns the key of the results of the job in the job store.
+func jobResultsKey(id string) string {
+	return "cybertron:job:" + id + ":results"
+}
+
+// storedJob is a job in the job store, with its tenant.
+type storedJob struct {
+	asyncJob
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// storeJob writes the job to the job store, if any, with its results if
+// finished, before the job itself, so that the finished jobs always have
+// them. It returns whether the job is stored; the failures are logged, the
+// job being still served from memory by this server.
+func (s *Server) storeJob(j asyncJob, finished bool) bool {
+	if s.conf.JobStore == nil {
+		return false
+	}
+	// The job is stored even if the server is stopping.
+	ctx, cancel := context.WithTimeout(detachedContext{s.ctx}, jobStoreTimeout)
+	defer cancel()
+	ttl := s.jobRetention()
+	if finished {
+		if err := s.conf.JobStore.Set(ctx, jobResultsKey(j.ID), j.results, ttl); err != nil {
+			log.Warn().Err(err).Str("job", j.ID).Msg("failed to store the job results")
+			return false
+		}
+	}
+	data, err := json.Marshal(storedJob{asyncJob: j, Tenant: j.tenant})
+	if err == nil {
+		err = s.conf.JobStore.Set(ctx, jobKey(j.ID), data, ttl)
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("job", j.ID).Msg("failed to store the job")
+		return false
+	}
+	return true
+}
+
+// getJob returns the job of the tenant, from memory if served by this
+// server, or from the job store, with its results if requested.
+func (s *Server) getJob(ctx context.Context, id, tenant string, withResults bool) (asyncJob, error) {
+	notFound := fmt.Errorf("%w: %#v", errdefs.ErrJobNotFound, id)
+	if j, ok := s.jobs.get(id); ok {
+		if j.tenant != tenant {
+			return asyncJob{}, notFound
+		}
+		return j, nil
+	}
+	if s.conf.JobStore == nil {
+		return asyncJob{}, notFound
+	}
+	data, ok, err := s.conf.JobStore.Get(ctx, jobKey(id))
+	if err != nil {
+		return asyncJob{}, err
+	}
+	if !ok {
+		return asyncJob{}, notFound
+	}
+	var sj storedJob
+	if err := json.Unmarshal(data, &sj); err != nil {
+		return asyncJob{}, err
+	}
+	if sj.Tenant != tenant {
+		return asyncJob{}, notFound
+	}
+	j := sj.asyncJob
+	j.tenant = sj.Tenant
+	if withResults && j.Finished != nil {
+		if j.results, _, err = s.conf.JobStore.Get(ctx, jobResultsKey(id)); err != nil {
+			return asyncJob{}, err
+		}
+	}
+	return j, nil
+}
+
+// jobAccount returns the account of the tenant of the job, if any.
+func (s *Server) jobAccount(j *asyncJob) *tenancy.Account {
+	if s.conf.Tenants == nil || j.tenant == "" {
+		return nil
+	}
+	a, err := s.conf.Tenants.ByName(j.tenant)
+	if err != nil {
+		log.Warn().Err(err).Str("job", j.ID).Msg("failed to account the job")
+		return nil
+	}
+	return a
+}
+
+// webhookPayload is the body of the webhook of a job: the job, with its
+// results, or the path to fetch them from if they're too large.
+type webhookPayload struct {
+	asyncJob
+	Results     []json.RawMessage `json:"results,omitempty"`
+	ResultsPath string            `json:"resultsPath,omitempty"`
+}
+
+// signWebhook returns the signature of the body of a webhook at the time.
+func signWebhook(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return "t=" + ts + ",sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliverWebhook POSTs the finished job to its webhook, retrying on the
+// network errors and on the 5xx, 408 and 429 responses.
+func (s *Server) deliverWebhook(ctx context.Context, j asyncJob) {
+	p := webhookPayload{asyncJob: j}
+	if len(j.results) <= webhookMaxResults {
+		for _, line := range bytes.Split(bytes.TrimSpace(j.results), []byte("\n")) {
+			if len(line) > 0 {
+				p.Results = append(p.Results, line)
+			}
+		}
+	} else {
+		p.ResultsPath = "/v1/jobs/" + j.ID + "/results"
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		log.Err(err).Str("job", j.ID).Msg("failed to encode the webhook")
+		return
+	}
+
+	client := s.webhookClient()
+	backoff := webhookBackoff
+	for attempt := 1; ; attempt++ {
+		err := postWebhook(ctx, client, j.webhook, body, signWebhook(s.conf.WebhookSecret, time.Now(), body))
+		if err == nil {
+			return
+		}
+		if !err.(retryableError).retry || attempt == webhookAttempts {
+			log.Warn().Err(err).Str("job", j.ID).Int("attempts", attempt).Msg("failed to deliver the webhook")
+			return
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// retryableError is an error delivering a webhook, and whether it's worth
+// retrying.
+type retryableError struct {
+	error
+	retry bool
+}
+
+// postWebhook POSTs the signed body to the webhook.
+func postWebhook(ctx context.Context, client *http.Client, webhook string, body []byte, signature string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return retryableError{err, false}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(signatureHeader, signature)
+	resp, err := client.Do(req)
+	if err != nil {
+		return retryableError{err, ctx.Err() == nil}
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests:
+		return retryableError{fmt.Errorf("webhook responded %s", resp.Status), true}
+	default:
+		return retryableError{fmt.Errorf("webhook responded %s", resp.Status), false}
+	}
+}
+
+// serveJob writes the status of a job.
+func (s *Server) serveJob(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	tenant, _ := r.Context().Value(callerKey{}).(string)
+	j, err := s.getJob(r.Context(), params["id"], tenant, false)
+	if err != nil {
+		writeHTTPError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(j); err != nil {
+		log.Warn().Err(err).Msg("failed to write the job")
+	}
+}
+
+// serveJobResults writes the results of a job, as the JSON lines of the
+// bulk uploads: the ones served so far, if it's running on this server, or
+// none until it's finished, if it's running on another one.
+func (s *Server) serveJobResults(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	tenant, _ := r.Context().Value(callerKey{}).(string)
+	j, err := s.getJob(r.Context(), params["id"], tenant, true)
+	if err != nil {
+		writeHTTPError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	_, _ = w.Write(j.results)
+}
@@ -0,0 +1,218 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/logging"
+	"github.com/nlpodyssey/cybertron/pkg/timings"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
+	"github.com/rs/zerolog"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// latencyBuckets are the upper bounds in seconds of the buckets of the
+// latency histograms.
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// inputLengthBounds are the upper bounds in tokens of the input lengths the
+// latency histograms are bucketed by, the last bucket having no bound.
+var inputLengthBounds = []int64{16, 32, 64, 128, 256, 512, 1024}
+
+// labelEscaper escapes the values of the labels.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// LatencyMetrics records the latency of the requests of the models,
+// exported as histograms by model, method and input length in tokens, and
+// logs the requests slower than a threshold, so that the pathological
+// inputs can be pinpointed. It's safe for concurrent use.
+type LatencyMetrics struct {
+	// SlowThreshold is the latency beyond which the requests are logged as
+	// slow, with their input length, their parameters and the time spent in
+	// their stages (optional: not logged if zero).
+	SlowThreshold time.Duration
+
+	mu         sync.Mutex
+	histograms map[latencyKey]*histogram
+}
+
+// latencyKey identifies a latency histogram.
+type latencyKey struct {
+	model, method string
+	// length is the index of the bucket of the input length.
+	length int
+}
+
+// histogram is a latency histogram.
+type histogram struct {
+	// counts are the number of requests in each bucket, not cumulative,
+	// the last one counting the ones beyond the last bound.
+	counts []int64
+	sum    float64
+}
+
+// modelLatency is the LatencyMetrics of the requests of a model.
+type modelLatency struct {
+	metrics *LatencyMetrics
+	model   string
+}
+
+// WithLatency sets the metrics recording the latency of the requests of
+// the request handler returned by ResolveRequestHandler, as the ones of the
+// model, e.g. its name and revision, and returns it.
+func WithLatency(rh RequestHandler, metrics *LatencyMetrics, model string) RequestHandler {
+	if h, ok := rh.(interface{ shared() *sharedResponses }); ok {
+		h.shared().latency = &modelLatency{metrics: metrics, model: model}
+	}
+	return rh
+}
+
+// measure serves the request with the function, recording its latency,
+// and logging it if slow, with the tokens and the stages the function
+// accounted in the context.
+func measure[Req, Resp proto.Message](ctx context.Context, ml *modelLatency, req Req, f func(context.Context, Req) (Resp, error)) (Resp, error) {
+	if ml == nil {
+		return f(ctx, req)
+	}
+	var c usage.Counter
+	var t timings.Timings
+	start := time.Now()
+	resp, err := f(timings.NewContext(usage.NewContext(ctx, &c), &t), req)
+	latency := time.Since(start)
+
+	input, output := c.Tokens()
+	// The tokens are still accounted by the counter of the caller, if any.
+	usage.AddTokens(ctx, int(input), int(output))
+	m := method(ctx, req)
+	ml.metrics.observe(ml.model, m, input, latency)
+	if th := ml.metrics.SlowThreshold; th > 0 && latency >= th {
+		stages := zerolog.Dict()
+		for _, s := range t.Stages() {
+			stages.Dur(s.Name, s.Duration)
+		}
+		logging.Ctx(ctx).Warn().
+			Str("model", ml.model).
+			Str("method", m).
+			Dur("latency", latency).
+			Int64("input_tokens", input).
+			Int64("output_tokens", output).
+			RawJSON("parameters", requestParameters(req)).
+			Dict("stages", stages).
+			Err(err).
+			Msg("slow request")
+	}
+	return resp, err
+}
+
+// requestParameters returns the JSON encoding of the request without its
+// input texts, i.e. its string fields, so that the slow requests can be
+// logged without personal information.
+func requestParameters(req proto.Message) []byte {
+	params := proto.Clone(req)
+	m := params.ProtoReflect()
+	m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if fd.Kind() == protoreflect.StringKind && !fd.IsMap() {
+			m.Clear(fd)
+		}
+		return true
+	})
+	data, err := protojson.Marshal(params)
+	if err != nil || len(data) == 0 {
+		return []byte("{}")
+	}
+	return data
+}
+
+// observe records the latency of a request with the input length.
+func (lm *LatencyMetrics) observe(model, method string, input int64, latency time.Duration) {
+	key := latencyKey{model: model, method: method, length: len(inputLengthBounds)}
+	for i, b := range inputLengthBounds {
+		if input <= b {
+			key.length = i
+			break
+		}
+	}
+	s := latency.Seconds()
+	bucket := sort.SearchFloat64s(latencyBuckets, s)
+
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	if lm.histograms == nil {
+		lm.histograms = make(map[latencyKey]*histogram)
+	}
+	h, ok := lm.histograms[key]
+	if !ok {
+		h = &histogram{counts: make([]int64, len(latencyBuckets)+1)}
+		lm.histograms[key] = h
+	}
+	h.counts[bucket]++
+	h.sum += s
+}
+
+// inputLengthLabel returns the label of the bucket of the input length,
+// e.g. "17-32", or "1025+" for the last one.
+func inputLengthLabel(i int) string {
+	var lower int64
+	if i > 0 {
+		lower = inputLengthBounds[i-1] + 1
+	}
+	if i == len(inputLengthBounds) {
+		return fmt.Sprintf("%d+", lower)
+	}
+	return fmt.Sprintf("%d-%d", lower, inputLengthBounds[i])
+}
+
+// WriteMetrics writes the latency histograms in the Prometheus text
+// exposition format, labeled by model, method and input length in tokens.
+func (lm *LatencyMetrics) WriteMetrics(w io.Writer) error {
+	const name = "cybertron_request_duration_seconds"
+	lm.mu.Lock()
+	keys := make([]latencyKey, 0, len(lm.histograms))
+	histograms := make(map[latencyKey]histogram, len(lm.histograms))
+	for k, h := range lm.histograms {
+		keys = append(keys, k)
+		histograms[k] = histogram{counts: append([]int64(nil), h.counts...), sum: h.sum}
+	}
+	lm.mu.Unlock()
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.model != b.model {
+			return a.model < b.model
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.length < b.length
+	})
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# HELP %s Latency of the requests per model, method and input length in tokens.\n# TYPE %s histogram\n", name, name)
+	for _, k := range keys {
+		h := histograms[k]
+		labels := fmt.Sprintf(`model="%s",method="%s",input_tokens="%s"`,
+			labelEscaper.Replace(k.model), labelEscaper.Replace(k.method), inputLengthLabel(k.length))
+		var count int64
+		for i, b := range latencyBuckets {
+			count += h.counts[i]
+			fmt.Fprintf(bw, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, strconv.FormatFloat(b, 'g', -1, 64), count)
+		}
+		count += h.counts[len(latencyBuckets)]
+		fmt.Fprintf(bw, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, count)
+		fmt.Fprintf(bw, "%s_sum{%s} %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(bw, "%s_count{%s} %d\n", name, labels, count)
+	}
+	return bw.Flush()
+}
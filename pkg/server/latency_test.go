@@ -0,0 +1,55 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	textencodingv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textencoding/v1"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyMetrics(t *testing.T) {
+	lm := &LatencyMetrics{}
+	ml := &modelLatency{metrics: lm, model: "bert"}
+	encode := func(ctx context.Context, req *textencodingv1.EncodingRequest) (*textencodingv1.EncodingResponse, error) {
+		usage.AddTokens(ctx, len(strings.Fields(req.Input)), 0)
+		return &textencodingv1.EncodingResponse{}, nil
+	}
+
+	var c usage.Counter
+	ctx := usage.NewContext(context.Background(), &c)
+	for _, input := range []string{"a b c", "d e", strings.Repeat("x ", 100)} {
+		_, err := measure(ctx, ml, &textencodingv1.EncodingRequest{Input: input}, encode)
+		require.NoError(t, err)
+	}
+	input, _ := c.Tokens()
+	assert.Equal(t, int64(105), input, "tokens accounted by the caller")
+
+	var buf bytes.Buffer
+	require.NoError(t, lm.WriteMetrics(&buf))
+	metrics := buf.String()
+	assert.Contains(t, metrics, "# TYPE cybertron_request_duration_seconds histogram\n")
+	assert.Contains(t, metrics, `cybertron_request_duration_seconds_count{model="bert",method="textencoding.v1.EncodingRequest",input_tokens="0-16"} 2`)
+	assert.Contains(t, metrics, `cybertron_request_duration_seconds_bucket{model="bert",method="textencoding.v1.EncodingRequest",input_tokens="65-128",le="+Inf"} 1`)
+}
+
+func TestRequestParameters(t *testing.T) {
+	req := &textencodingv1.EncodingRequest{Input: "personal text", PoolingStrategy: 2}
+	assert.JSONEq(t, `{"poolingStrategy":2}`, string(requestParameters(req)))
+	assert.Equal(t, "personal text", req.Input, "request left untouched")
+	assert.Equal(t, "{}", string(requestParameters(&textencodingv1.EncodingRequest{Input: "text"})))
+}
+
+func TestInputLengthLabel(t *testing.T) {
+	assert.Equal(t, "0-16", inputLengthLabel(0))
+	assert.Equal(t, "17-32", inputLengthLabel(1))
+	assert.Equal(t, "1025+", inputLengthLabel(len(inputLengthBounds)))
+}
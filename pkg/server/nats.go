@@ -0,0 +1,246 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"github.com/nlpodyssey/cybertron/pkg/nats"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/scheduling"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// DefaultNATSSubjectPrefix is the default prefix of the NATS subjects.
+	DefaultNATSSubjectPrefix = "cybertron"
+	// DefaultNATSQueue is the default NATS queue group.
+	DefaultNATSQueue = "cybertron"
+)
+
+// NATSConfig is the configuration of the NATS transport, where each method
+// of the services is exposed on the subject "<prefix>.<service>.<method>",
+// e.g. "cybertron.textencoding.v1.TextEncodingService.Encode". Requests and
+// responses are the JSON encoding of the gRPC messages, as for the HTTP API;
+// a failure is replied as {"error": {"code": ..., "message": ...}}.
+type NATSConfig struct {
+	// URL is the URL of the NATS server; the transport is disabled if empty.
+	URL string
+	// SubjectPrefix is the prefix of the request-reply subjects (default "cybertron").
+	SubjectPrefix string
+	// Queue is the queue group of the subscriptions, so that the requests
+	// are shared among the instances of the server (default "cybertron").
+	Queue string
+	// Workers is the number of messages processed concurrently (default 1).
+	Workers int
+	// Stream and Consumer are the JetStream stream and durable pull consumer
+	// of a work queue, whose messages are processed and acknowledged
+	// (optional). The method is resolved from the end of the subject of
+	// each message, e.g. "jobs.textencoding.v1.TextEncodingService.Encode".
+	Stream   string
+	Consumer string
+	// ResultsPrefix is the prefix of the subjects the results of the
+	// JetStream messages are published to, followed by the service and
+	// method (optional: the results are discarded if empty).
+	ResultsPrefix string
+}
+
+func setBaselineNATSConfig(c *NATSConfig) {
+	if c.SubjectPrefix == "" {
+		c.SubjectPrefix = DefaultNATSSubjectPrefix
+	}
+	if c.Queue == "" {
+		c.Queue = DefaultNATSQueue
+	}
+	if c.Workers < 1 {
+		c.Workers = 1
+	}
+}
+
+// natsMethod is a unary method of a gRPC service.
+type natsMethod struct {
+	impl    any
+	handler func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error)
+}
+
+// methodRegistry is a grpc.ServiceRegistrar collecting the unary methods of
+// the services, by "<service>.<method>".
+type methodRegistry map[string]natsMethod
+
+// RegisterService registers the unary methods of the service.
+func (r methodRegistry) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	for _, m := range desc.Methods {
+		r[desc.ServiceName+"."+m.MethodName] = natsMethod{impl: impl, handler: m.Handler}
+	}
+}
+
+// lookup returns the name and method matching the end of the subject.
+func (r methodRegistry) lookup(subject string) (string, natsMethod, bool) {
+	for name, m := range r {
+		if subject == name || strings.HasSuffix(subject, "."+name) {
+			return name, m, true
+		}
+	}
+	return "", natsMethod{}, false
+}
+
+// call calls the method with the JSON request, through the interceptor,
+// returning the JSON response.
+func (m natsMethod) call(ctx context.Context, data []byte, interceptor grpc.UnaryServerInterceptor) []byte {
+	dec := func(v any) error {
+		if len(data) == 0 {
+			return nil
+		}
+		if err := protojson.Unmarshal(data, v.(proto.Message)); err != nil {
+			return statusError(fmt.Errorf("%w: %v", errdefs.ErrInvalidRequest, err))
+		}
+		return nil
+	}
+	resp, err := m.handler(m.impl, ctx, dec, interceptor)
+	if err != nil {
+		return natsError(err)
+	}
+	out, err := protojson.Marshal(resp.(proto.Message))
+	if err != nil {
+		return natsError(err)
+	}
+	return out
+}
+
+// natsError returns the JSON encoding of the error, with its code in the
+// errdefs taxonomy as the reason, if any.
+func natsError(err error) []byte {
+	s := status.Convert(err)
+	e := map[string]any{"code": s.Code(), "message": s.Message()}
+	if reason := errorReason(s); reason != "" {
+		e["reason"] = reason
+	}
+	data, _ := json.Marshal(map[string]any{"error": e})
+	return data
+}
+
+// serveNATS serves the requests received over NATS with the current
+// generation, until the context is done or the connection fails.
+func (s *Server) serveNATS(ctx context.Context) error {
+	conf := s.conf.NATS
+	conn, err := nats.Connect(conf.URL, nats.Options{Name: "cybertron"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	sub, err := conn.Subscribe(conf.SubjectPrefix+".>", conf.Queue)
+	if err != nil {
+		return err
+	}
+	log.Info().Str("url", conf.URL).Str("subjects", conf.SubjectPrefix+".>").Str("queue", conf.Queue).Msg("serving over NATS")
+
+	g, gctx := errgroup.WithContext(ctx)
+	for i := 0; i < conf.Workers; i++ {
+		g.Go(func() error {
+			return s.serveNATSRequests(gctx, conn, sub)
+		})
+	}
+	if conf.Stream != "" {
+		log.Info().Str("stream", conf.Stream).Str("consumer", conf.Consumer).Msg("consuming JetStream work queue")
+		for i := 0; i < conf.Workers; i++ {
+			g.Go(func() error {
+				return s.serveNATSWorkQueue(gctx, conn)
+			})
+		}
+	}
+	err = g.Wait()
+	if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// serveNATSRequests replies to the requests of the subscription.
+func (s *Server) serveNATSRequests(ctx context.Context, conn *nats.Conn, sub *nats.Subscription) error {
+	for {
+		msg, err := sub.Next(ctx)
+		if err != nil {
+			return err
+		}
+		if msg.Reply == "" {
+			continue // not a request
+		}
+		_, resp := s.callNATS(ctx, msg)
+		if err := conn.Publish(msg.Reply, "", resp); err != nil {
+			return err
+		}
+	}
+}
+
+// serveNATSWorkQueue processes the messages of the JetStream consumer,
+// publishing their results, and acknowledges them. A message whose result
+// can't be published is delivered again. The messages are served with the
+// batch priority.
+func (s *Server) serveNATSWorkQueue(ctx context.Context, conn *nats.Conn) error {
+	ctx = scheduling.NewContext(ctx, scheduling.Batch)
+	conf := s.conf.NATS
+	pc, err := conn.PullConsumer(conf.Stream, conf.Consumer)
+	if err != nil {
+		return err
+	}
+	defer pc.Close()
+	for {
+		msg, err := pc.Next(ctx)
+		if err != nil {
+			return err
+		}
+		name, resp := s.callNATS(ctx, msg)
+		if conf.ResultsPrefix != "" && name != "" {
+			if err := conn.Publish(conf.ResultsPrefix+"."+name, "", resp); err != nil {
+				log.Err(err).Str("subject", msg.Subject).Msg("failed to publish result")
+				if err := pc.Nak(msg); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if err := pc.Ack(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// callNATS calls the method of the current generation matching the subject
+// of the message, through the interceptors of the gRPC requests, the
+// headers of the message being their metadata, e.g. the API key, the
+// request ID, the adapter or the timeout.
+func (s *Server) callNATS(ctx context.Context, msg *nats.Msg) (string, []byte) {
+	md := make(metadata.MD, len(msg.Header))
+	for key, values := range msg.Header {
+		md.Append(key, values...)
+	}
+	ctx = metadata.NewIncomingContext(ctx, md)
+	return s.callCurrent(ctx, msg.Subject, msg.Data, chainUnaryInterceptors(s.unaryInterceptors()...))
+}
+
+// callCurrent calls the method of the current generation matching the
+// subject with the JSON request, through the interceptor, returning the
+// name of the method, empty if not found, and the JSON response.
+func (s *Server) callCurrent(ctx context.Context, subject string, data []byte, interceptor grpc.UnaryServerInterceptor) (string, []byte) {
+	g := s.acquireCurrent()
+	defer g.mu.RUnlock()
+	name, m, ok := g.methods.lookup(subject)
+	if !ok {
+		log.Warn().Str("subject", subject).Msg("no method for NATS subject")
+		return "", natsError(statusError(fmt.Errorf("%w: no model serving subject %#v", errdefs.ErrModelNotLoaded, subject)))
+	}
+	return name, m.call(ctx, data, interceptor)
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/lora"
+	"github.com/nlpodyssey/cybertron/pkg/nats"
+	textencodingv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textencoding/v1"
+	"github.com/nlpodyssey/cybertron/pkg/tenancy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestChainUnaryInterceptors(t *testing.T) {
+	var calls []string
+	interceptor := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			calls = append(calls, name)
+			return handler(ctx, req)
+		}
+	}
+	chain := chainUnaryInterceptors(interceptor("a"), interceptor("b"), interceptor("c"))
+	resp, err := chain(context.Background(), "req", &grpc.UnaryServerInfo{}, func(_ context.Context, req any) (any, error) {
+		calls = append(calls, "handler")
+		return req, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "req", resp)
+	assert.Equal(t, []string{"a", "b", "c", "handler"}, calls)
+}
+
+// newNATSTestServer returns a server whose encoding method, invoked through
+// the interceptor as the generated handlers do, replies with the adapter and
+// the time left to its deadline, if any, as the vector.
+func newNATSTestServer(conf *Config) *Server {
+	s := &Server{conf: conf, ctx: context.Background()}
+	encode := func(_ any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		req := &textencodingv1.EncodingRequest{}
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		info := &grpc.UnaryServerInfo{FullMethod: "/textencoding.v1.TextEncodingService/Encode"}
+		return interceptor(ctx, req, info, func(ctx context.Context, _ any) (any, error) {
+			var left float32
+			if deadline, ok := ctx.Deadline(); ok {
+				left = float32(time.Until(deadline).Seconds())
+			}
+			return &textencodingv1.EncodingResponse{Vector: []float32{float32(len(lora.FromContext(ctx))), left}}, nil
+		})
+	}
+	s.current.Store(&generation{methods: methodRegistry{
+		"textencoding.v1.TextEncodingService.Encode": {handler: encode},
+	}})
+	return s
+}
+
+func TestCallNATS(t *testing.T) {
+	tenants, err := tenancy.New([]tenancy.Tenant{{Name: "acme", APIKeys: []string{"key"}}})
+	require.NoError(t, err)
+	s := newNATSTestServer(&Config{Tenants: tenants})
+
+	tests := []struct {
+		name   string
+		header textproto.MIMEHeader
+		code   codes.Code
+		vector []float32
+	}{
+		{"no API key", nil, codes.Unauthenticated, nil},
+		{"unknown API key", textproto.MIMEHeader{"Cybertron-Api-Key": {"other"}}, codes.Unauthenticated, nil},
+		{"API key", textproto.MIMEHeader{"Cybertron-Api-Key": {"key"}}, codes.OK, []float32{0, 0}},
+		{"bearer token", textproto.MIMEHeader{"Authorization": {"Bearer key"}}, codes.OK, []float32{0, 0}},
+		{"adapter", textproto.MIMEHeader{"Cybertron-Api-Key": {"key"}, "Cybertron-Adapter": {"legal"}}, codes.OK, []float32{5, 0}},
+		{"invalid timeout", textproto.MIMEHeader{"Cybertron-Api-Key": {"key"}, "Cybertron-Timeout": {"soon"}}, codes.InvalidArgument, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &nats.Msg{Subject: "cybertron.textencoding.v1.TextEncodingService.Encode", Header: tt.header, Data: []byte(`{"input": "a"}`)}
+			name, data := s.callNATS(context.Background(), msg)
+			assert.Equal(t, "textencoding.v1.TextEncodingService.Encode", name)
+			var resp struct {
+				Vector []float32
+				Error  *struct{ Code codes.Code }
+			}
+			require.NoError(t, json.Unmarshal(data, &resp))
+			if tt.code != codes.OK {
+				require.NotNil(t, resp.Error, string(data))
+				assert.Equal(t, tt.code, resp.Error.Code)
+				return
+			}
+			require.Nil(t, resp.Error, string(data))
+			assert.Equal(t, tt.vector, resp.Vector)
+		})
+	}
+
+	t.Run("timeout", func(t *testing.T) {
+		msg := &nats.Msg{
+			Subject: "cybertron.textencoding.v1.TextEncodingService.Encode",
+			Header:  textproto.MIMEHeader{"Cybertron-Api-Key": {"key"}, "Cybertron-Timeout": {"30s"}},
+		}
+		_, data := s.callNATS(context.Background(), msg)
+		var resp textencodingv1.EncodingResponse
+		require.NoError(t, json.Unmarshal(data, &resp))
+		require.Len(t, resp.Vector, 2)
+		assert.InDelta(t, 30, resp.Vector[1], 1)
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		a, err := tenants.ByName("acme")
+		require.NoError(t, err)
+		assert.EqualValues(t, 5, a.Usage().Requests)
+	})
+}
@@ -0,0 +1,39 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+
+	"github.com/nlpodyssey/cybertron/pkg/textnorm"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// normalizationHeader is the gRPC metadata, sent in the header of the
+// responses, reporting the normalization of the input texts applied by the
+// model, e.g. "nfc,collapse-whitespace". The HTTP gateway forwards it as
+// the Grpc-Metadata-Cybertron-Normalization header.
+const normalizationHeader = "cybertron-normalization"
+
+// WithNormalization reports the normalization of the input texts applied by
+// the model (see tasks.Config) in the responses of the request handler
+// returned by ResolveRequestHandler, and returns it.
+func WithNormalization(rh RequestHandler, opts textnorm.Options) RequestHandler {
+	if h, ok := rh.(interface{ shared() *sharedResponses }); ok && !opts.IsZero() {
+		h.shared().normalization = opts.String()
+	}
+	return rh
+}
+
+// reportNormalization sets the normalization in the header of the response
+// of the gRPC call, if any. The responses of the other transports, e.g.
+// NATS, don't report it.
+func reportNormalization(ctx context.Context, normalization string) {
+	if normalization == "" {
+		return
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(normalizationHeader, normalization))
+}
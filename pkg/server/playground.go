@@ -0,0 +1,74 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+)
+
+// playgroundFiles are the files of the playground page.
+//
+//go:embed playground
+var playgroundFiles embed.FS
+
+// taskNames are the names of the tasks, by the unary methods of their
+// services, e.g. the forms of the playground, or the paths of the bulk
+// uploads.
+var taskNames = map[string]string{
+	"text2text.v2.Text2TextService.Generate":                      "text2text",
+	"zeroshot.v1.ZeroShotService.Classify":                        "zero-shot-classification",
+	"questionanswering.v1.QuestionAnsweringService.Answer":        "question-answering",
+	"textclassification.v1.TextClassificationService.Classify":    "text-classification",
+	"tokenclassification.v1.TokenClassificationService.Classify":  "token-classification",
+	"textencoding.v1.TextEncodingService.Encode":                  "text-encoding",
+	"languagemodeling.v1.LanguageModelingService.Predict":         "language-modeling",
+	"coreference.v1.CoreferenceService.Resolve":                   "coreference-resolution",
+	"relationextraction.v1.RelationExtractionService.Extract":     "relation-extraction",
+	"sentencesegmentation.v1.SentenceSegmentationService.Segment": "sentence-segmentation",
+	"postagging.v1.PosTaggingService.Tag":                         "pos-tagging",
+}
+
+// withPlayground serves the playground at /playground/, if enabled: a page
+// with a form for each task served, calling the HTTP APIs, and the tasks
+// served at /playground/tasks.
+func (s *Server) withPlayground(h http.Handler) http.Handler {
+	if !s.conf.Playground {
+		return h
+	}
+	files, err := fs.Sub(playgroundFiles, "playground")
+	if err != nil {
+		panic(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", h)
+	mux.Handle("/playground/", http.StripPrefix("/playground/", http.FileServer(http.FS(files))))
+	mux.HandleFunc("/playground/tasks", s.servePlaygroundTasks)
+	return mux
+}
+
+// servePlaygroundTasks writes the tasks served by the current request
+// handler.
+func (s *Server) servePlaygroundTasks(w http.ResponseWriter, _ *http.Request) {
+	g := s.acquireCurrent()
+	tasks := []string{}
+	for name := range g.methods {
+		if task, ok := taskNames[name]; ok {
+			tasks = append(tasks, task)
+		}
+	}
+	g.mu.RUnlock()
+	sort.Strings(tasks)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"tasks": tasks}); err != nil {
+		log.Warn().Err(err).Msg("failed to write the tasks of the playground")
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// The playground shows the form of each task served, and calls the HTTP
+// APIs of the server with its inputs.
+'use strict';
+
+const headerInputs = {
+  'Cybertron-Api-Key': document.getElementById('api-key'),
+  'Cybertron-Adapter': document.getElementById('adapter'),
+};
+
+// The request headers are kept for the session.
+for (const [name, input] of Object.entries(headerInputs)) {
+  input.value = sessionStorage.getItem(name) || '';
+  input.addEventListener('change', () => sessionStorage.setItem(name, input.value));
+}
+
+// call posts the body to the path of the API, returning the JSON response,
+// or throwing the message of the error.
+async function call(path, body) {
+  const headers = {'Content-Type': 'application/json'};
+  for (const [name, input] of Object.entries(headerInputs)) {
+    if (input.value) {
+      headers[name] = input.value;
+    }
+  }
+  const resp = await fetch(path, {method: 'POST', headers, body: JSON.stringify(body)});
+  const text = await resp.text();
+  let data;
+  try {
+    data = JSON.parse(text);
+  } catch {
+    data = {message: text};
+  }
+  if (!resp.ok) {
+    throw new Error(data.message || resp.statusText);
+  }
+  return data;
+}
+
+// element returns a new element with the text and the children.
+function element(tag, text, ...children) {
+  const e = document.createElement(tag);
+  if (text !== undefined) {
+    e.textContent = text;
+  }
+  e.append(...children);
+  return e;
+}
+
+// scoreList returns the list of the labels with their scores as bars.
+function scoreList(labels, scores) {
+  const list = element('ol');
+  labels.forEach((label, i) => {
+    const bar = element('meter');
+    bar.value = scores[i];
+    list.append(element('li', '', element('span', label), bar, element('span', scores[i].toFixed(3))));
+  });
+  return list;
+}
+
+// highlight returns the text with the spans, from start to end in code
+// points, marked with their titles.
+function highlight(text, spans) {
+  const chars = Array.from(text);
+  const p = element('p');
+  let last = 0;
+  for (const s of spans) {
+    p.append(chars.slice(last, s.start).join(''));
+    const mark = element('mark', chars.slice(s.start, s.end).join(''));
+    mark.title = s.title;
+    mark.style.setProperty('--weight', s.weight);
+    p.append(mark);
+    last = s.end;
+  }
+  p.append(chars.slice(last).join(''));
+  return p;
+}
+
+// unpack returns the floats packed as little-endian float32 values, encoded
+// in base64.
+function unpack(packed) {
+  const view = new DataView(Uint8Array.from(atob(packed), (c) => c.charCodeAt(0)).buffer);
+  return Array.from({length: view.byteLength / 4}, (_, i) => view.getFloat32(i * 4, true));
+}
+
+function cosine(a, b) {
+  let dot = 0, na = 0, nb = 0;
+  for (let i = 0; i < a.length; i++) {
+    dot += a[i] * b[i];
+    na += a[i] * a[i];
+    nb += b[i] * b[i];
+  }
+  return dot / Math.sqrt(na * nb);
+}
+
+// tasks run the form of each task, returning the elements of the result.
+const tasks = {
+  'text-classification': async (f) => {
+    const r = await call('/v1/classify', {input: f.input.value, explain: f.explain.checked});
+    const out = [scoreList(r.labels, r.scores)];
+    if (r.attributions && r.attributions.length > 0) {
+      const max = Math.max(...r.attributions.map((a) => Math.abs(a.score))) || 1;
+      out.push(highlight(f.input.value, r.attributions.map((a) => ({
+        start: a.start, end: a.end, title: a.score.toFixed(3), weight: Math.max(a.score, 0) / max,
+      }))));
+    }
+    return out;
+  },
+  'zero-shot-classification': async (f) => {
+    const r = await call('/v1/classify', {
+      input: f.input.value,
+      parameters: {
+        candidateLabels: f.labels.value.split(',').map((s) => s.trim()).filter((s) => s),
+        hypothesisTemplate: f.template.value,
+        multiLabel: f.multi.checked,
+      },
+    });
+    return [scoreList(r.labels, r.scores)];
+  },
+  'token-classification': async (f) => {
+    const r = await call('/v1/classify', {input: f.input.value, aggregationStrategy: 'SIMPLE'});
+    return [highlight(f.input.value, r.tokens.map((t) => ({
+      start: t.start, end: t.end, title: `${t.label} ${t.score.toFixed(3)}`, weight: 1,
+    })))];
+  },
+  'question-answering': async (f) => {
+    const r = await call('/v1/answer', {question: f.question.value, passage: f.passage.value});
+    if (r.answers.length === 0) {
+      return [element('p', 'No answer found.')];
+    }
+    return [scoreList(r.answers.map((a) => a.text), r.answers.map((a) => a.score))];
+  },
+  'text2text': async (f) => {
+    const sampling = {enabled: f.sample.checked};
+    if (f.temperature.value !== '') {
+      sampling.temperature = Number(f.temperature.value);
+    }
+    const r = await call('/v2/generate', {inputs: [f.input.value], sampling});
+    return r.generations[0].texts.map((t) => element('blockquote', t));
+  },
+  'text-encoding': async (f) => {
+    const [a, b] = await Promise.all([
+      call('/v1/encode', {input: f.first.value, vectorEncoding: 'PACKED'}),
+      call('/v1/encode', {input: f.second.value, vectorEncoding: 'PACKED'}),
+    ]);
+    const [va, vb] = [unpack(a.vectorBytes), unpack(b.vectorBytes)];
+    return [
+      element('p', `Cosine similarity: ${cosine(va, vb).toFixed(4)}`),
+      element('p', `${va.length} dimensions`),
+    ];
+  },
+  'language-modeling': async (f) => {
+    const r = await call('/v1/predict', {input: f.input.value, parameters: {k: 5}});
+    return r.tokens.map((t) => scoreList(t.words, t.scores));
+  },
+  'coreference-resolution': async (f) => {
+    const r = await call('/v1/resolve', {input: f.input.value});
+    if (!r.clusters || r.clusters.length === 0) {
+      return [element('p', 'No coreferences found.')];
+    }
+    return [element('ol', undefined, ...r.clusters.map((c) => element('li', c.mentions.map((m) => m.text).join(' · '))))];
+  },
+  'relation-extraction': async (f) => {
+    const entities = f.entities.value.split(',').map((s) => s.trim()).filter((s) => s).map((text) => ({text}));
+    const r = await call('/v1/extract', {input: f.input.value, entities});
+    if (!r.relations || r.relations.length === 0) {
+      return [element('p', 'No relations found.')];
+    }
+    return [scoreList(r.relations.map((x) => `${x.head.text} → ${x.type} → ${x.tail.text}`), r.relations.map((x) => x.score))];
+  },
+  'sentence-segmentation': async (f) => {
+    const r = await call('/v1/segment', {input: f.input.value});
+    return [element('ol', undefined, ...(r.sentences || []).map((s) => element('li', s.text)))];
+  },
+  'pos-tagging': async (f) => {
+    const r = await call('/v1/tag', {input: f.input.value});
+    return [highlight(f.input.value, (r.tokens || []).map((t) => ({
+      start: t.start, end: t.end, title: `${t.tag} ${t.score.toFixed(3)}`, weight: 1,
+    })))];
+  },
+};
+
+for (const form of document.querySelectorAll('form[data-task]')) {
+  form.addEventListener('submit', async (event) => {
+    event.preventDefault();
+    const output = form.querySelector('output');
+    const button = form.querySelector('button');
+    button.disabled = true;
+    output.className = '';
+    output.replaceChildren(element('p', 'Running…'));
+    const start = performance.now();
+    try {
+      const result = await tasks[form.dataset.task](form);
+      const took = element('small', `${Math.round(performance.now() - start)} ms`);
+      output.replaceChildren(...result, took);
+    } catch (err) {
+      output.className = 'error';
+      output.replaceChildren(element('p', err.message));
+    } finally {
+      button.disabled = false;
+    }
+  });
+}
+
+// Only the forms of the tasks served are shown.
+fetch('tasks')
+  .then((resp) => resp.json())
+  .then(({tasks: served}) => {
+    for (const task of served) {
+      const form = document.querySelector(`form[data-task="${task}"]`);
+      if (form) {
+        form.hidden = false;
+      }
+    }
+    document.getElementById('no-tasks').hidden = served.length > 0;
+  });
@@ -0,0 +1,61 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPlayground(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	newServer := func(playground bool) *Server {
+		s := New(&Config{Playground: playground}, RequestHandlers{
+			NewServerForTextClassification(pairClassifier{}),
+			NewServerForTokenClassification(&lastWordClassifier{}),
+		})
+		g, err := s.newGeneration(context.Background(), s.handler)
+		require.NoError(t, err)
+		s.current.Store(g)
+		return s
+	}
+
+	tests := []struct {
+		name         string
+		playground   bool
+		path         string
+		wantStatus   int
+		wantContains string
+	}{
+		{name: "disabled", path: "/playground/", wantStatus: http.StatusTeapot},
+		{name: "page", playground: true, path: "/playground/", wantStatus: http.StatusOK, wantContains: "<html"},
+		{name: "script", playground: true, path: "/playground/playground.js", wantStatus: http.StatusOK},
+		{name: "missing file", playground: true, path: "/playground/missing.js", wantStatus: http.StatusNotFound},
+		{
+			name:         "tasks",
+			playground:   true,
+			path:         "/playground/tasks",
+			wantStatus:   http.StatusOK,
+			wantContains: `{"tasks":["text-classification","token-classification"]}`,
+		},
+		{name: "other paths", playground: true, path: "/v1/classify", wantStatus: http.StatusTeapot},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newServer(tt.playground).withPlayground(next)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.wantContains)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/scheduling"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// priorityHeader is the HTTP header, or gRPC metadata, setting the priority
+// of a request ("interactive"|"batch"), interactive by default.
+const priorityHeader = "cybertron-priority"
+
+// priorityInterceptor sets the priority of the gRPC requests from their
+// metadata.
+func priorityInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(priorityHeader); len(v) > 0 {
+		p, err := scheduling.ParsePriority(v[0])
+		if err != nil {
+			return nil, statusError(fmt.Errorf("%w: %v", errdefs.ErrInvalidRequest, err))
+		}
+		ctx = scheduling.NewContext(ctx, p)
+	}
+	return handler(ctx, req)
+}
+
+// withPriority sets the priority of the HTTP requests from their header.
+func withPriority(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Header.Get(priorityHeader); v != "" {
+			p, err := scheduling.ParsePriority(v)
+			if err != nil {
+				writeHTTPError(w, r, fmt.Errorf("%w: %v", errdefs.ErrInvalidRequest, err))
+				return
+			}
+			r = r.WithContext(scheduling.NewContext(r.Context(), p))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
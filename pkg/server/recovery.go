@@ -0,0 +1,102 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"github.com/nlpodyssey/cybertron/pkg/logging"
+	"google.golang.org/grpc"
+)
+
+// PanicHook is called with the panics recovered while serving the requests,
+// and their stacks, e.g. to report them to an error tracker such as Sentry.
+type PanicHook func(ctx context.Context, recovered any, stack []byte)
+
+// panicError is the panic of a request, recovered.
+type panicError struct {
+	value any
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// recoverPanic recovers the panic of the calling function, if any, setting
+// its error.
+func recoverPanic(err *error) {
+	if v := recover(); v != nil {
+		*err = &panicError{value: v, stack: debug.Stack()}
+	}
+}
+
+// withRecovery returns the function failing with a panicError instead of
+// panicking, e.g. because of a malformed input reaching the math layer, so
+// that a request can't crash the server, even when served by another
+// goroutine.
+func withRecovery[Req, Resp any](f func(context.Context, Req) (Resp, error)) func(context.Context, Req) (Resp, error) {
+	return func(ctx context.Context, req Req) (_ Resp, err error) {
+		defer recoverPanic(&err)
+		return f(ctx, req)
+	}
+}
+
+// reportPanic logs the panic of the request, if the error is one, calling
+// the panic hook, and returns the internal error sent to the client in its
+// place, without the details of the panic.
+func (s *Server) reportPanic(ctx context.Context, err error) error {
+	var pe *panicError
+	if !errors.As(err, &pe) {
+		return err
+	}
+	logging.Ctx(ctx).Error().Interface("panic", pe.value).Bytes("stack", pe.stack).Msg("recovered from panic while serving request")
+	if s.conf.PanicHook != nil {
+		s.conf.PanicHook(ctx, pe.value, pe.stack)
+	}
+	return statusError(errdefs.ErrInternal)
+}
+
+// recoveryInterceptor recovers the panics of the gRPC requests.
+func (s *Server) recoveryInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		err = s.reportPanic(ctx, err)
+	}()
+	defer recoverPanic(&err)
+	return handler(ctx, req)
+}
+
+// httpErrorHandler writes the errors of the HTTP gateway, the panics
+// recovered included.
+func (s *Server) httpErrorHandler(ctx context.Context, mux *runtime.ServeMux, m runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	runtime.DefaultHTTPErrorHandler(ctx, mux, m, w, r, s.reportPanic(ctx, err))
+}
+
+// withHTTPRecovery recovers the panics of the HTTP requests.
+func (s *Server) withHTTPRecovery(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		defer func() {
+			if err != nil {
+				writeHTTPError(w, r, s.reportPanic(r.Context(), err))
+			}
+		}()
+		defer func() {
+			if v := recover(); v != nil {
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				err = &panicError{value: v, stack: debug.Stack()}
+			}
+		}()
+		h.ServeHTTP(w, r)
+	})
+}
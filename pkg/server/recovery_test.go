@@ -0,0 +1,147 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	textclassificationv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textclassification/v1"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// panickingClassifier panics on every request.
+type panickingClassifier struct{}
+
+func (panickingClassifier) Classify(context.Context, string, textclassification.Parameters) (textclassification.Response, error) {
+	panic("index out of range")
+}
+
+// newRecoveryServer returns a server recording the values of the panics
+// reported to its hook.
+func newRecoveryServer() (*Server, *[]any) {
+	var panics []any
+	s := New(&Config{PanicHook: func(_ context.Context, v any, stack []byte) {
+		panics = append(panics, v)
+	}}, NewServerForTextClassification(panickingClassifier{}))
+	return s, &panics
+}
+
+func TestWithRecovery(t *testing.T) {
+	errRequest := errors.New("request failed")
+	tests := []struct {
+		name      string
+		f         func(context.Context, string) (string, error)
+		want      string
+		wantErr   error
+		wantPanic any
+	}{
+		{
+			name: "response",
+			f:    func(_ context.Context, s string) (string, error) { return s, nil },
+			want: "req",
+		},
+		{
+			name:    "error",
+			f:       func(context.Context, string) (string, error) { return "", errRequest },
+			wantErr: errRequest,
+		},
+		{
+			name:      "panic",
+			f:         func(context.Context, string) (string, error) { panic("boom") },
+			wantPanic: "boom",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := withRecovery(tt.f)(context.Background(), "req")
+			switch {
+			case tt.wantPanic != nil:
+				var pe *panicError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, tt.wantPanic, pe.value)
+				assert.NotEmpty(t, pe.stack)
+			case tt.wantErr != nil:
+				assert.ErrorIs(t, err, tt.wantErr)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRecoveryInterceptor(t *testing.T) {
+	s, panics := newRecoveryServer()
+	errRequest := errors.New("request failed")
+	tests := []struct {
+		name     string
+		handler  grpc.UnaryHandler
+		wantErr  error
+		wantCode codes.Code
+	}{
+		{
+			name:    "error",
+			handler: func(context.Context, any) (any, error) { return nil, errRequest },
+			wantErr: errRequest,
+		},
+		{
+			name:     "panic",
+			handler:  func(context.Context, any) (any, error) { panic("boom") },
+			wantCode: codes.Internal,
+		},
+		{
+			name: "panic of a task",
+			handler: func(ctx context.Context, _ any) (any, error) {
+				// The panics of the tasks are recovered by the task services.
+				return s.handler.(*serverForTextClassification).Classify(ctx, &textclassificationv1.ClassifyRequest{Input: "text"})
+			},
+			wantCode: codes.Internal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*panics = nil
+			_, err := s.recoveryInterceptor(context.Background(), nil, nil, tt.handler)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, *panics)
+				return
+			}
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.NotContains(t, err.Error(), "boom", "the details of the panic are not sent")
+			assert.Len(t, *panics, 1)
+		})
+	}
+}
+
+func TestWithHTTPRecovery(t *testing.T) {
+	s, panics := newRecoveryServer()
+
+	h := s.withHTTPRecovery(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic("boom") }))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "boom")
+	assert.Equal(t, []any{"boom"}, *panics)
+
+	ok := s.withHTTPRecovery(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) }))
+	w = httptest.NewRecorder()
+	ok.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	abort := s.withHTTPRecovery(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic(http.ErrAbortHandler) }))
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		abort.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}, "the aborted requests are not recovered")
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/nlpodyssey/cybertron/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// requestIDHeader is the HTTP header, or gRPC metadata, with the ID of a
+// request, e.g. set by a proxy to trace it, generated if missing, and sent
+// back in the header of its response. The ID is added to the logs of the
+// request.
+const requestIDHeader = "x-request-id"
+
+// maxRequestIDLength is the maximum length of the IDs of the requests,
+// beyond which they're replaced by a generated one, so that a client can't
+// bloat the logs.
+const maxRequestIDLength = 128
+
+// newRequestID returns a random ID for a request without one.
+func newRequestID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// contextWithRequestID returns the context with the ID of the request
+// added to its logs, or a generated one if empty, and the ID.
+func contextWithRequestID(ctx context.Context, id string) (context.Context, string) {
+	if id == "" || len(id) > maxRequestIDLength {
+		id = newRequestID()
+	}
+	return logging.With(ctx, "request_id", id), id
+}
+
+// requestIDInterceptor adds the ID of the gRPC requests to their logs,
+// sending it back in the header of their responses.
+func requestIDInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	var id string
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(requestIDHeader); len(v) > 0 {
+		id = v[0]
+	}
+	ctx, id = contextWithRequestID(ctx, id)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, id))
+	return handler(ctx, req)
+}
+
+// withRequestID adds the ID of the HTTP requests to their logs, sending it
+// back in the header of their responses.
+func withRequestID(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, id := contextWithRequestID(r.Context(), r.Header.Get(requestIDHeader))
+		w.Header().Set(requestIDHeader, id)
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -9,26 +9,36 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/nlpodyssey/cybertron/pkg/jobstore"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/coreference"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/languagemodeling"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/postagging"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/relationextraction"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier"
+	"github.com/nlpodyssey/cybertron/pkg/tenancy"
+	"github.com/nlpodyssey/cybertron/pkg/workqueue"
 	"github.com/rs/cors"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
-	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
 const (
@@ -40,9 +50,21 @@ const (
 
 // Server is a server that provides gRPC and HTTP/2 APIs.
 type Server struct {
-	conf    *Config
+	conf *Config
+	// handler is the request handler the server is started with: the ones
+	// swapped in are only held by their generations.
 	handler RequestHandler
 	health  *health.Server
+	// ctx is the context the server was started with.
+	ctx context.Context
+	// current is the generation of handlers serving new requests.
+	current atomic.Pointer[generation]
+	// swapMu serializes the swaps of the request handler.
+	swapMu sync.Mutex
+	// jobs are the async jobs, outliving the generations.
+	jobs *jobRegistry
+	// queue is the client of the work queue, on a frontend.
+	queue *workqueue.Client
 }
 
 // Config is the configuration for the server.
@@ -53,6 +75,65 @@ type Config struct {
 	TLSEnabled     bool
 	TLSCert        string
 	TLSKey         string
+	// NATS is the configuration of the NATS transport (optional).
+	NATS NATSConfig
+	// WorkQueue is the configuration of the work queue (optional).
+	WorkQueue WorkQueueConfig
+	// Tenants are the tenants sharing the server: the requests must carry the
+	// API key of one of them, and are subject to its rate limit and token
+	// quota (optional: the server is open if nil). The usage of the tenants is
+	// exported as metrics at /metrics.
+	Tenants *tenancy.Registry
+	// TenantHeader is the header, set by a trusted proxy, identifying the
+	// tenant of a request by its name instead of its API key (optional).
+	TenantHeader string
+	// AdminKey is the key authorizing the requests of the admin API, i.e.
+	// the usage of the tenants at /admin/tenants, and the AdminHandlers,
+	// and the traces of the inference of the requests carrying it in their
+	// Cybertron-Trace header (optional: disabled if empty).
+	AdminKey string
+	// AdminHandlers are the handlers of the admin API by path, relative to
+	// /admin/, e.g. "evaluation" for /admin/evaluation (optional).
+	AdminHandlers map[string]http.Handler
+	// PanicHook is called with the panics recovered while serving the
+	// requests, which fail with an internal error, e.g. to report them to an
+	// error tracker (optional).
+	PanicHook PanicHook
+	// Metrics are exported at /metrics, after the ones of the tenants, e.g.
+	// the ones of the versions of the models (optional).
+	Metrics []MetricsWriter
+	// ModelHealth reports the health of the models at /health, e.g. of the
+	// ones loaded lazily, while they're loading (optional).
+	ModelHealth func() []ModelHealth
+	// Playground serves the web playground at /playground/, a page with a
+	// form for each task served, to try the models from the browser
+	// without writing a client (optional).
+	Playground bool
+	// APISunset is when the deprecated versions of the APIs stop being
+	// served, their requests failing with UNIMPLEMENTED after it, reported
+	// in the responses until then (optional: they're served indefinitely if
+	// zero).
+	APISunset time.Time
+	// WebhookSecret is the key signing the webhooks of the async jobs with
+	// HMAC-SHA256 (optional: the webhooks are disabled if empty).
+	WebhookSecret string
+	// WebhookAllowPrivate allows the webhooks to the loopback, private and
+	// link-local addresses, e.g. to the services of a private network
+	// (optional: only the public addresses are allowed by default, so that
+	// the tenants can't reach the internal services through the server).
+	WebhookAllowPrivate bool
+	// JobStore is the store of the async jobs and of their results, so that
+	// they survive the restarts and can be fetched from any server
+	// sharing it (optional: they're kept in memory if nil).
+	JobStore jobstore.Store
+	// JobTTL is how long the finished async jobs are kept (optional: 24
+	// hours if zero).
+	JobTTL time.Duration
+}
+
+// MetricsWriter writes metrics in the Prometheus text exposition format.
+type MetricsWriter interface {
+	WriteMetrics(w io.Writer) error
 }
 
 // RequestHandler is implemented by any task-specific service that can be
@@ -62,6 +143,31 @@ type RequestHandler interface {
 	RegisterHandlerServer(context.Context, *runtime.ServeMux) error
 }
 
+// RequestHandlers is a RequestHandler registering several task-specific
+// services in the same Server, e.g. to serve multiple models at once.
+// Each service can be registered only once.
+type RequestHandlers []RequestHandler
+
+// RegisterServer registers all the services with the gRPC server.
+func (hs RequestHandlers) RegisterServer(r grpc.ServiceRegistrar) error {
+	for _, h := range hs {
+		if err := h.RegisterServer(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RegisterHandlerServer registers all the HTTP handlers with the mux.
+func (hs RequestHandlers) RegisterHandlerServer(ctx context.Context, mux *runtime.ServeMux) error {
+	for _, h := range hs {
+		if err := h.RegisterHandlerServer(ctx, mux); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ResolveRequestHandler instantiates a new task-server based on the model.
 func ResolveRequestHandler(model any) (RequestHandler, error) {
 	switch m := model.(type) {
@@ -79,6 +185,14 @@ func ResolveRequestHandler(model any) (RequestHandler, error) {
 		return NewServerForTokenClassification(m), nil
 	case languagemodeling.Interface:
 		return NewServerForLanguageModeling(m), nil
+	case coreference.Interface:
+		return NewServerForCoreference(m), nil
+	case relationextraction.Interface:
+		return NewServerForRelationExtraction(m), nil
+	case sentencesegmentation.Interface:
+		return NewServerForSentenceSegmentation(m), nil
+	case postagging.Interface:
+		return NewServerForPOSTagging(m), nil
 	default:
 		return nil, fmt.Errorf("failed to resolve register funcs for model/task type %T", m)
 	}
@@ -87,11 +201,16 @@ func ResolveRequestHandler(model any) (RequestHandler, error) {
 // New creates a new server.
 func New(conf *Config, handler RequestHandler) *Server {
 	setBaselineConfig(conf)
-	return &Server{
+	s := &Server{
 		conf:    conf,
 		handler: handler,
 		health:  health.NewServer(),
+		jobs:    newJobRegistry(),
+	}
+	if conf.WorkQueue.Queue != nil && conf.WorkQueue.Frontend {
+		s.queue = workqueue.NewClient(conf.WorkQueue.Queue)
 	}
+	return s
 }
 
 func setBaselineConfig(c *Config) {
@@ -101,23 +220,25 @@ func setBaselineConfig(c *Config) {
 	if c.Address == "" {
 		c.Address = DefaultAddress
 	}
+	setBaselineNATSConfig(&c.NATS)
+	if c.WorkQueue.Workers < 1 {
+		c.WorkQueue.Workers = 1
+	}
 }
 
 // Start up the server and block until the context is done.
 func (s *Server) Start(ctx context.Context) error {
 	conf := s.conf
 
-	grpcServer := grpc.NewServer()
-
-	grpc_health_v1.RegisterHealthServer(grpcServer, s.health)
-
-	if err := s.handler.RegisterServer(grpcServer); err != nil {
-		return fmt.Errorf("failed to register gRPC server: %w", err)
+	s.swapMu.Lock()
+	g, err := s.newGeneration(ctx, s.handler)
+	if err == nil {
+		s.ctx = ctx
+		s.current.Store(g)
 	}
-
-	mux := runtime.NewServeMux()
-	if err := s.handler.RegisterHandlerServer(ctx, mux); err != nil {
-		return fmt.Errorf("failed to register gRPC handler server: %w", err)
+	s.swapMu.Unlock()
+	if err != nil {
+		return err
 	}
 
 	lis, err := net.Listen(conf.Network, conf.Address)
@@ -129,10 +250,11 @@ func (s *Server) Start(ctx context.Context) error {
 		conf.Address = lis.Addr().String()
 	}
 
-	handler := cors.New(s.corsOptions()).Handler(mux)
-	handler = s.handlerFunc(grpcServer, handler)
-
-	err = s.serve(ctx, lis, handler)
+	if conf.NATS.URL == "" && conf.WorkQueue.Queue == nil {
+		err = s.serve(ctx, lis, s.withAdmin(s.withHealth(s.withPlayground(http.HandlerFunc(s.serveCurrent)))))
+	} else {
+		err = s.serveWithQueues(ctx, lis)
+	}
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return fmt.Errorf("failed to serve: %w", err)
 	}
@@ -140,6 +262,32 @@ func (s *Server) Start(ctx context.Context) error {
 	return nil
 }
 
+// serveWithQueues serves the HTTP/2 APIs, the NATS transport and the work
+// queue, if enabled, stopping all of them as soon as one of them fails.
+func (s *Server) serveWithQueues(ctx context.Context, lis net.Listener) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		return s.serve(ctx, lis, s.withAdmin(s.withHealth(s.withPlayground(http.HandlerFunc(s.serveCurrent)))))
+	})
+	if s.conf.NATS.URL != "" {
+		g.Go(func() error {
+			if err := s.serveNATS(ctx); err != nil {
+				return fmt.Errorf("NATS: %w", err)
+			}
+			return nil
+		})
+	}
+	if s.conf.WorkQueue.Queue != nil {
+		g.Go(func() error {
+			if err := s.serveWorkQueue(ctx); err != nil {
+				return fmt.Errorf("work queue: %w", err)
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
 // corsOptions returns the CORS options for the server.
 func (s *Server) corsOptions() cors.Options {
 	return cors.Options{
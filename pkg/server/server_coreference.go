@@ -0,0 +1,114 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	coreferencev1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/coreference/v1"
+	coreferencev2 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/coreference/v2"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/coreference"
+	"google.golang.org/grpc"
+)
+
+// serverForCoreference is a server that provides gRPC and HTTP/2 APIs for the coreference resolution task.
+type serverForCoreference struct {
+	coreferencev1.UnimplementedCoreferenceServiceServer
+	sharedResponses
+	resolver coreference.Interface
+}
+
+func NewServerForCoreference(resolver coreference.Interface) RequestHandler {
+	return &serverForCoreference{resolver: resolver}
+}
+
+func (s *serverForCoreference) RegisterServer(r grpc.ServiceRegistrar) error {
+	coreferencev1.RegisterCoreferenceServiceServer(r, s)
+	coreferencev2.RegisterCoreferenceServiceServer(r, coreferenceV2{s: s})
+	return nil
+}
+
+func (s *serverForCoreference) RegisterHandlerServer(ctx context.Context, mux *runtime.ServeMux) error {
+	if err := coreferencev1.RegisterCoreferenceServiceHandlerServer(ctx, mux, s); err != nil {
+		return err
+	}
+	return coreferencev2.RegisterCoreferenceServiceHandlerServer(ctx, mux, coreferenceV2{s: s})
+}
+
+// Resolve handles the Resolve request.
+func (s *serverForCoreference) Resolve(ctx context.Context, req *coreferencev1.ResolveRequest) (*coreferencev1.ResolveResponse, error) {
+	return respond(ctx, &s.sharedResponses, req, s.resolve)
+}
+
+func (s *serverForCoreference) resolve(ctx context.Context, req *coreferencev1.ResolveRequest) (*coreferencev1.ResolveResponse, error) {
+	result, err := s.resolver.Resolve(ctx, req.GetInput())
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := make([]*coreferencev1.Cluster, len(result.Clusters))
+	for i, c := range result.Clusters {
+		mentions := make([]*coreferencev1.Mention, len(c.Mentions))
+		for j, m := range c.Mentions {
+			mentions[j] = &coreferencev1.Mention{
+				Text:  m.Text,
+				Start: int32(m.Start),
+				End:   int32(m.End),
+				Score: m.Score,
+
+				ByteStart: int32(m.ByteStart),
+				ByteEnd:   int32(m.ByteEnd),
+			}
+		}
+		clusters[i] = &coreferencev1.Cluster{Mentions: mentions}
+	}
+	resp := &coreferencev1.ResolveResponse{
+		Clusters: clusters,
+	}
+	return resp, nil
+}
+
+// coreferenceV2 serves the v2 API of the coreference resolution, resolving a
+// batch of inputs.
+type coreferenceV2 struct {
+	coreferencev2.UnimplementedCoreferenceServiceServer
+	s *serverForCoreference
+}
+
+// Resolve handles the Resolve request of the v2 API.
+func (v coreferenceV2) Resolve(ctx context.Context, req *coreferencev2.ResolveRequest) (*coreferencev2.ResolveResponse, error) {
+	return respond(ctx, &v.s.sharedResponses, req, v.resolve)
+}
+
+func (v coreferenceV2) resolve(ctx context.Context, req *coreferencev2.ResolveRequest) (*coreferencev2.ResolveResponse, error) {
+	resp := &coreferencev2.ResolveResponse{
+		Resolutions: make([]*coreferencev2.Resolution, 0, len(req.GetInputs())),
+	}
+	for _, input := range req.GetInputs() {
+		result, err := v.s.resolver.Resolve(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		clusters := make([]*coreferencev2.Cluster, len(result.Clusters))
+		for i, c := range result.Clusters {
+			mentions := make([]*coreferencev2.Mention, len(c.Mentions))
+			for j, m := range c.Mentions {
+				mentions[j] = &coreferencev2.Mention{
+					Text:  m.Text,
+					Start: int32(m.Start),
+					End:   int32(m.End),
+					Score: m.Score,
+
+					ByteStart: int32(m.ByteStart),
+					ByteEnd:   int32(m.ByteEnd),
+				}
+			}
+			clusters[i] = &coreferencev2.Cluster{Mentions: mentions}
+		}
+		resp.Resolutions = append(resp.Resolutions, &coreferencev2.Resolution{Clusters: clusters})
+	}
+	return resp, nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	langaugemodelingnv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/languagemodeling/v1"
+	languagemodelingv2 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/languagemodeling/v2"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/languagemodeling"
 	"google.golang.org/grpc"
 )
@@ -16,6 +17,7 @@ import (
 // serverForLanguageModeling is a server that provides gRPC and HTTP/2 APIs for Language Modeling task.
 type serverForLanguageModeling struct {
 	langaugemodelingnv1.UnimplementedLanguageModelingServiceServer
+	sharedResponses
 	predictor languagemodeling.Interface
 }
 
@@ -25,15 +27,23 @@ func NewServerForLanguageModeling(predictor languagemodeling.Interface) RequestH
 
 func (s *serverForLanguageModeling) RegisterServer(r grpc.ServiceRegistrar) error {
 	langaugemodelingnv1.RegisterLanguageModelingServiceServer(r, s)
+	languagemodelingv2.RegisterLanguageModelingServiceServer(r, languageModelingV2{s: s})
 	return nil
 }
 
 func (s *serverForLanguageModeling) RegisterHandlerServer(ctx context.Context, mux *runtime.ServeMux) error {
-	return langaugemodelingnv1.RegisterLanguageModelingServiceHandlerServer(ctx, mux, s)
+	if err := langaugemodelingnv1.RegisterLanguageModelingServiceHandlerServer(ctx, mux, s); err != nil {
+		return err
+	}
+	return languagemodelingv2.RegisterLanguageModelingServiceHandlerServer(ctx, mux, languageModelingV2{s: s})
 }
 
 // Predict handles the Predict request.
 func (s *serverForLanguageModeling) Predict(ctx context.Context, req *langaugemodelingnv1.LanguageModelingRequest) (*langaugemodelingnv1.LanguageModelingResponse, error) {
+	return respond(ctx, &s.sharedResponses, req, s.predict)
+}
+
+func (s *serverForLanguageModeling) predict(ctx context.Context, req *langaugemodelingnv1.LanguageModelingRequest) (*langaugemodelingnv1.LanguageModelingResponse, error) {
 	result, err := s.predictor.Predict(ctx, req.GetInput(), languagemodeling.Parameters{
 		K: int(req.GetParameters().GetK()),
 	})
@@ -48,6 +58,9 @@ func (s *serverForLanguageModeling) Predict(ctx context.Context, req *langaugemo
 			Scores: token.Scores,
 			Start:  int32(token.Start),
 			End:    int32(token.End),
+
+			ByteStart: int32(token.ByteStart),
+			ByteEnd:   int32(token.ByteEnd),
 		}
 	}
 	resp := &langaugemodelingnv1.LanguageModelingResponse{
@@ -55,3 +68,44 @@ func (s *serverForLanguageModeling) Predict(ctx context.Context, req *langaugemo
 	}
 	return resp, nil
 }
+
+// languageModelingV2 serves the v2 API of the language modeling, predicting
+// a batch of inputs with the same parameters.
+type languageModelingV2 struct {
+	languagemodelingv2.UnimplementedLanguageModelingServiceServer
+	s *serverForLanguageModeling
+}
+
+// Predict handles the Predict request of the v2 API.
+func (v languageModelingV2) Predict(ctx context.Context, req *languagemodelingv2.PredictRequest) (*languagemodelingv2.PredictResponse, error) {
+	return respond(ctx, &v.s.sharedResponses, req, v.predict)
+}
+
+func (v languageModelingV2) predict(ctx context.Context, req *languagemodelingv2.PredictRequest) (*languagemodelingv2.PredictResponse, error) {
+	params := languagemodeling.Parameters{
+		K: int(req.GetParameters().GetK()),
+	}
+	resp := &languagemodelingv2.PredictResponse{
+		Predictions: make([]*languagemodelingv2.Prediction, 0, len(req.GetInputs())),
+	}
+	for _, input := range req.GetInputs() {
+		result, err := v.s.predictor.Predict(ctx, input, params)
+		if err != nil {
+			return nil, err
+		}
+		tokens := make([]*languagemodelingv2.Token, len(result.Tokens))
+		for i, token := range result.Tokens {
+			tokens[i] = &languagemodelingv2.Token{
+				Words:  token.Words,
+				Scores: token.Scores,
+				Start:  int32(token.Start),
+				End:    int32(token.End),
+
+				ByteStart: int32(token.ByteStart),
+				ByteEnd:   int32(token.ByteEnd),
+			}
+		}
+		resp.Predictions = append(resp.Predictions, &languagemodelingv2.Prediction{Tokens: tokens})
+	}
+	return resp, nil
+}
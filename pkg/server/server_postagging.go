@@ -0,0 +1,108 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	postaggingv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/postagging/v1"
+	postaggingv2 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/postagging/v2"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/postagging"
+	"google.golang.org/grpc"
+)
+
+// serverForPOSTagging is a server that provides gRPC and HTTP/2 APIs for the part-of-speech tagging task.
+type serverForPOSTagging struct {
+	postaggingv1.UnimplementedPosTaggingServiceServer
+	sharedResponses
+	tagger postagging.Interface
+}
+
+func NewServerForPOSTagging(tagger postagging.Interface) RequestHandler {
+	return &serverForPOSTagging{tagger: tagger}
+}
+
+func (s *serverForPOSTagging) RegisterServer(r grpc.ServiceRegistrar) error {
+	postaggingv1.RegisterPosTaggingServiceServer(r, s)
+	postaggingv2.RegisterPosTaggingServiceServer(r, posTaggingV2{s: s})
+	return nil
+}
+
+func (s *serverForPOSTagging) RegisterHandlerServer(ctx context.Context, mux *runtime.ServeMux) error {
+	if err := postaggingv1.RegisterPosTaggingServiceHandlerServer(ctx, mux, s); err != nil {
+		return err
+	}
+	return postaggingv2.RegisterPosTaggingServiceHandlerServer(ctx, mux, posTaggingV2{s: s})
+}
+
+// Tag handles the Tag request.
+func (s *serverForPOSTagging) Tag(ctx context.Context, req *postaggingv1.TagRequest) (*postaggingv1.TagResponse, error) {
+	return respond(ctx, &s.sharedResponses, req, s.tag)
+}
+
+func (s *serverForPOSTagging) tag(ctx context.Context, req *postaggingv1.TagRequest) (*postaggingv1.TagResponse, error) {
+	result, err := s.tagger.Tag(ctx, req.GetInput())
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]*postaggingv1.Token, len(result.Tokens))
+	for i, t := range result.Tokens {
+		tokens[i] = &postaggingv1.Token{
+			Text:  t.Text,
+			Start: int32(t.Start),
+			End:   int32(t.End),
+			Tag:   t.Tag,
+			Score: t.Score,
+
+			ByteStart: int32(t.ByteStart),
+			ByteEnd:   int32(t.ByteEnd),
+		}
+	}
+	resp := &postaggingv1.TagResponse{
+		Tokens: tokens,
+	}
+	return resp, nil
+}
+
+// posTaggingV2 serves the v2 API of the part-of-speech tagging, tagging a
+// batch of inputs.
+type posTaggingV2 struct {
+	postaggingv2.UnimplementedPosTaggingServiceServer
+	s *serverForPOSTagging
+}
+
+// Tag handles the Tag request of the v2 API.
+func (v posTaggingV2) Tag(ctx context.Context, req *postaggingv2.TagRequest) (*postaggingv2.TagResponse, error) {
+	return respond(ctx, &v.s.sharedResponses, req, v.tag)
+}
+
+func (v posTaggingV2) tag(ctx context.Context, req *postaggingv2.TagRequest) (*postaggingv2.TagResponse, error) {
+	resp := &postaggingv2.TagResponse{
+		Taggings: make([]*postaggingv2.Tagging, 0, len(req.GetInputs())),
+	}
+	for _, input := range req.GetInputs() {
+		result, err := v.s.tagger.Tag(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		tokens := make([]*postaggingv2.Token, len(result.Tokens))
+		for i, t := range result.Tokens {
+			tokens[i] = &postaggingv2.Token{
+				Text:  t.Text,
+				Start: int32(t.Start),
+				End:   int32(t.End),
+				Tag:   t.Tag,
+				Score: t.Score,
+
+				ByteStart: int32(t.ByteStart),
+				ByteEnd:   int32(t.ByteEnd),
+			}
+		}
+		resp.Taggings = append(resp.Taggings, &postaggingv2.Tagging{Tokens: tokens})
+	}
+	return resp, nil
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	questionansweringv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/questionanswering/v1"
+	questionansweringv2 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/questionanswering/v2"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/attribution"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering"
 	"google.golang.org/grpc"
 )
@@ -16,6 +18,7 @@ import (
 // serverForQuestionAnswering is a server that provides gRPC and HTTP/2 APIs for Interface task.
 type serverForQuestionAnswering struct {
 	questionansweringv1.UnimplementedQuestionAnsweringServiceServer
+	sharedResponses
 	engine questionanswering.Interface
 }
 
@@ -25,21 +28,30 @@ func NewServerForQuestionAnswering(engine questionanswering.Interface) RequestHa
 
 func (s *serverForQuestionAnswering) RegisterServer(r grpc.ServiceRegistrar) error {
 	questionansweringv1.RegisterQuestionAnsweringServiceServer(r, s)
+	questionansweringv2.RegisterQuestionAnsweringServiceServer(r, questionAnsweringV2{s: s})
 	return nil
 }
 
 func (s *serverForQuestionAnswering) RegisterHandlerServer(ctx context.Context, mux *runtime.ServeMux) error {
-	return questionansweringv1.RegisterQuestionAnsweringServiceHandlerServer(ctx, mux, s)
+	if err := questionansweringv1.RegisterQuestionAnsweringServiceHandlerServer(ctx, mux, s); err != nil {
+		return err
+	}
+	return questionansweringv2.RegisterQuestionAnsweringServiceHandlerServer(ctx, mux, questionAnsweringV2{s: s})
 }
 
 // Answer handles the Answer request.
 func (s *serverForQuestionAnswering) Answer(ctx context.Context, req *questionansweringv1.AnswerRequest) (*questionansweringv1.AnswerResponse, error) {
+	return respond(ctx, &s.sharedResponses, req, s.answer)
+}
+
+func (s *serverForQuestionAnswering) answer(ctx context.Context, req *questionansweringv1.AnswerRequest) (*questionansweringv1.AnswerResponse, error) {
 	params := req.GetOptions()
 	opts := &questionanswering.Options{
 		MaxAnswers:      int(params.GetMaxAnswers()),
 		MaxAnswerLength: int(params.GetMaxAnswersLen()),
 		MinScore:        params.GetMinScore(),
 		MaxCandidates:   int(params.GetMaxCandidates()),
+		Explain:         params.GetExplain(),
 	}
 
 	result, err := s.engine.Answer(ctx, req.GetQuestion(), req.GetPassage(), opts)
@@ -53,6 +65,11 @@ func (s *serverForQuestionAnswering) Answer(ctx context.Context, req *questionan
 			Score: answer.Score,
 			Start: int64(answer.Start),
 			End:   int64(answer.End),
+
+			ByteStart: int64(answer.ByteStart),
+			ByteEnd:   int64(answer.ByteEnd),
+
+			Attributions: convAttributions(answer.Attributions),
 		}
 	}
 	resp := &questionansweringv1.AnswerResponse{
@@ -60,3 +77,77 @@ func (s *serverForQuestionAnswering) Answer(ctx context.Context, req *questionan
 	}
 	return resp, nil
 }
+
+func convAttributions(attributions []attribution.Token) []*questionansweringv1.Attribution {
+	out := make([]*questionansweringv1.Attribution, len(attributions))
+	for i, a := range attributions {
+		out[i] = &questionansweringv1.Attribution{
+			Text:      a.Text,
+			Start:     int64(a.Start),
+			End:       int64(a.End),
+			Score:     a.Score,
+			ByteStart: int64(a.ByteStart),
+			ByteEnd:   int64(a.ByteEnd),
+		}
+	}
+	return out
+}
+
+// questionAnsweringV2 serves the v2 API of the question answering, answering
+// a batch of questions over the same passage.
+type questionAnsweringV2 struct {
+	questionansweringv2.UnimplementedQuestionAnsweringServiceServer
+	s *serverForQuestionAnswering
+}
+
+// Answer handles the Answer request of the v2 API.
+func (v questionAnsweringV2) Answer(ctx context.Context, req *questionansweringv2.AnswerRequest) (*questionansweringv2.AnswerResponse, error) {
+	return respond(ctx, &v.s.sharedResponses, req, v.answer)
+}
+
+func (v questionAnsweringV2) answer(ctx context.Context, req *questionansweringv2.AnswerRequest) (*questionansweringv2.AnswerResponse, error) {
+	params := req.GetOptions()
+	opts := &questionanswering.Options{
+		MaxAnswers:      int(params.GetMaxAnswers()),
+		MaxAnswerLength: int(params.GetMaxAnswersLen()),
+		MinScore:        params.GetMinScore(),
+		MaxCandidates:   int(params.GetMaxCandidates()),
+		Explain:         params.GetExplain(),
+	}
+	resp := &questionansweringv2.AnswerResponse{
+		Results: make([]*questionansweringv2.Answers, 0, len(req.GetQuestions())),
+	}
+	for _, question := range req.GetQuestions() {
+		result, err := v.s.engine.Answer(ctx, question, req.GetPassage(), opts)
+		if err != nil {
+			return nil, err
+		}
+		answers := make([]*questionansweringv2.Answer, len(result.Answers))
+		for i, answer := range result.Answers {
+			attributions := make([]*questionansweringv2.Attribution, len(answer.Attributions))
+			for j, a := range answer.Attributions {
+				attributions[j] = &questionansweringv2.Attribution{
+					Text:      a.Text,
+					Start:     int64(a.Start),
+					End:       int64(a.End),
+					Score:     a.Score,
+					ByteStart: int64(a.ByteStart),
+					ByteEnd:   int64(a.ByteEnd),
+				}
+			}
+			answers[i] = &questionansweringv2.Answer{
+				Text:  answer.Text,
+				Score: answer.Score,
+				Start: int64(answer.Start),
+				End:   int64(answer.End),
+
+				ByteStart: int64(answer.ByteStart),
+				ByteEnd:   int64(answer.ByteEnd),
+
+				Attributions: attributions,
+			}
+		}
+		resp.Results = append(resp.Results, &questionansweringv2.Answers{Answers: answers})
+	}
+	return resp, nil
+}
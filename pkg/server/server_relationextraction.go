@@ -0,0 +1,144 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	relationextractionv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/relationextraction/v1"
+	relationextractionv2 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/relationextraction/v2"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/relationextraction"
+	"google.golang.org/grpc"
+)
+
+// serverForRelationExtraction is a server that provides gRPC and HTTP/2 APIs for the relation extraction task.
+type serverForRelationExtraction struct {
+	relationextractionv1.UnimplementedRelationExtractionServiceServer
+	sharedResponses
+	extractor relationextraction.Interface
+}
+
+func NewServerForRelationExtraction(extractor relationextraction.Interface) RequestHandler {
+	return &serverForRelationExtraction{extractor: extractor}
+}
+
+func (s *serverForRelationExtraction) RegisterServer(r grpc.ServiceRegistrar) error {
+	relationextractionv1.RegisterRelationExtractionServiceServer(r, s)
+	relationextractionv2.RegisterRelationExtractionServiceServer(r, relationExtractionV2{s: s})
+	return nil
+}
+
+func (s *serverForRelationExtraction) RegisterHandlerServer(ctx context.Context, mux *runtime.ServeMux) error {
+	if err := relationextractionv1.RegisterRelationExtractionServiceHandlerServer(ctx, mux, s); err != nil {
+		return err
+	}
+	return relationextractionv2.RegisterRelationExtractionServiceHandlerServer(ctx, mux, relationExtractionV2{s: s})
+}
+
+// Extract handles the Extract request.
+func (s *serverForRelationExtraction) Extract(ctx context.Context, req *relationextractionv1.ExtractRequest) (*relationextractionv1.ExtractResponse, error) {
+	return respond(ctx, &s.sharedResponses, req, s.extract)
+}
+
+func (s *serverForRelationExtraction) extract(ctx context.Context, req *relationextractionv1.ExtractRequest) (*relationextractionv1.ExtractResponse, error) {
+	entities := make([]relationextraction.Entity, len(req.GetEntities()))
+	for i, e := range req.GetEntities() {
+		entities[i] = relationextraction.Entity{
+			Text:  e.GetText(),
+			Label: e.GetLabel(),
+			Start: int(e.GetStart()),
+			End:   int(e.GetEnd()),
+		}
+	}
+	result, err := s.extractor.Extract(ctx, req.GetInput(), relationextraction.Parameters{
+		Entities: entities,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	relations := make([]*relationextractionv1.Relation, len(result.Relations))
+	for i, r := range result.Relations {
+		relations[i] = &relationextractionv1.Relation{
+			Head:  entityToProto(r.Head),
+			Tail:  entityToProto(r.Tail),
+			Type:  r.Type,
+			Score: r.Score,
+		}
+	}
+	resp := &relationextractionv1.ExtractResponse{
+		Relations: relations,
+	}
+	return resp, nil
+}
+
+func entityToProto(e relationextraction.Entity) *relationextractionv1.Entity {
+	return &relationextractionv1.Entity{
+		Text:      e.Text,
+		Label:     e.Label,
+		Start:     int32(e.Start),
+		End:       int32(e.End),
+		ByteStart: int32(e.ByteStart),
+		ByteEnd:   int32(e.ByteEnd),
+	}
+}
+
+// relationExtractionV2 serves the v2 API of the relation extraction,
+// extracting the relations of a batch of inputs.
+type relationExtractionV2 struct {
+	relationextractionv2.UnimplementedRelationExtractionServiceServer
+	s *serverForRelationExtraction
+}
+
+// Extract handles the Extract request of the v2 API.
+func (v relationExtractionV2) Extract(ctx context.Context, req *relationextractionv2.ExtractRequest) (*relationextractionv2.ExtractResponse, error) {
+	return respond(ctx, &v.s.sharedResponses, req, v.extract)
+}
+
+func (v relationExtractionV2) extract(ctx context.Context, req *relationextractionv2.ExtractRequest) (*relationextractionv2.ExtractResponse, error) {
+	resp := &relationextractionv2.ExtractResponse{
+		Extractions: make([]*relationextractionv2.Extraction, 0, len(req.GetInputs())),
+	}
+	for _, input := range req.GetInputs() {
+		entities := make([]relationextraction.Entity, len(input.GetEntities()))
+		for i, e := range input.GetEntities() {
+			entities[i] = relationextraction.Entity{
+				Text:  e.GetText(),
+				Label: e.GetLabel(),
+				Start: int(e.GetStart()),
+				End:   int(e.GetEnd()),
+			}
+		}
+		result, err := v.s.extractor.Extract(ctx, input.GetText(), relationextraction.Parameters{
+			Entities: entities,
+		})
+		if err != nil {
+			return nil, err
+		}
+		relations := make([]*relationextractionv2.Relation, len(result.Relations))
+		for i, r := range result.Relations {
+			relations[i] = &relationextractionv2.Relation{
+				Head:  entityToProtoV2(r.Head),
+				Tail:  entityToProtoV2(r.Tail),
+				Type:  r.Type,
+				Score: r.Score,
+			}
+		}
+		resp.Extractions = append(resp.Extractions, &relationextractionv2.Extraction{Relations: relations})
+	}
+	return resp, nil
+}
+
+func entityToProtoV2(e relationextraction.Entity) *relationextractionv2.Entity {
+	return &relationextractionv2.Entity{
+		Text:      e.Text,
+		Label:     e.Label,
+		Start:     int32(e.Start),
+		End:       int32(e.End),
+		ByteStart: int32(e.ByteStart),
+		ByteEnd:   int32(e.ByteEnd),
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	sentencesegmentationv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/sentencesegmentation/v1"
+	sentencesegmentationv2 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/sentencesegmentation/v2"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation"
+	"google.golang.org/grpc"
+)
+
+// serverForSentenceSegmentation is a server that provides gRPC and HTTP/2 APIs for the sentence segmentation task.
+type serverForSentenceSegmentation struct {
+	sentencesegmentationv1.UnimplementedSentenceSegmentationServiceServer
+	sharedResponses
+	segmenter sentencesegmentation.Interface
+}
+
+func NewServerForSentenceSegmentation(segmenter sentencesegmentation.Interface) RequestHandler {
+	return &serverForSentenceSegmentation{segmenter: segmenter}
+}
+
+func (s *serverForSentenceSegmentation) RegisterServer(r grpc.ServiceRegistrar) error {
+	sentencesegmentationv1.RegisterSentenceSegmentationServiceServer(r, s)
+	sentencesegmentationv2.RegisterSentenceSegmentationServiceServer(r, sentenceSegmentationV2{s: s})
+	return nil
+}
+
+func (s *serverForSentenceSegmentation) RegisterHandlerServer(ctx context.Context, mux *runtime.ServeMux) error {
+	if err := sentencesegmentationv1.RegisterSentenceSegmentationServiceHandlerServer(ctx, mux, s); err != nil {
+		return err
+	}
+	return sentencesegmentationv2.RegisterSentenceSegmentationServiceHandlerServer(ctx, mux, sentenceSegmentationV2{s: s})
+}
+
+// Segment handles the Segment request.
+func (s *serverForSentenceSegmentation) Segment(ctx context.Context, req *sentencesegmentationv1.SegmentRequest) (*sentencesegmentationv1.SegmentResponse, error) {
+	return respond(ctx, &s.sharedResponses, req, s.segment)
+}
+
+func (s *serverForSentenceSegmentation) segment(ctx context.Context, req *sentencesegmentationv1.SegmentRequest) (*sentencesegmentationv1.SegmentResponse, error) {
+	result, err := s.segmenter.Segment(ctx, req.GetInput())
+	if err != nil {
+		return nil, err
+	}
+
+	sentences := make([]*sentencesegmentationv1.Sentence, len(result.Sentences))
+	for i, s := range result.Sentences {
+		sentences[i] = &sentencesegmentationv1.Sentence{
+			Text:  s.Text,
+			Start: int32(s.Start),
+			End:   int32(s.End),
+
+			ByteStart: int32(s.ByteStart),
+			ByteEnd:   int32(s.ByteEnd),
+		}
+	}
+	resp := &sentencesegmentationv1.SegmentResponse{
+		Sentences: sentences,
+	}
+	return resp, nil
+}
+
+// sentenceSegmentationV2 serves the v2 API of the sentence segmentation,
+// segmenting a batch of inputs.
+type sentenceSegmentationV2 struct {
+	sentencesegmentationv2.UnimplementedSentenceSegmentationServiceServer
+	s *serverForSentenceSegmentation
+}
+
+// Segment handles the Segment request of the v2 API.
+func (v sentenceSegmentationV2) Segment(ctx context.Context, req *sentencesegmentationv2.SegmentRequest) (*sentencesegmentationv2.SegmentResponse, error) {
+	return respond(ctx, &v.s.sharedResponses, req, v.segment)
+}
+
+func (v sentenceSegmentationV2) segment(ctx context.Context, req *sentencesegmentationv2.SegmentRequest) (*sentencesegmentationv2.SegmentResponse, error) {
+	resp := &sentencesegmentationv2.SegmentResponse{
+		Segmentations: make([]*sentencesegmentationv2.Segmentation, 0, len(req.GetInputs())),
+	}
+	for _, input := range req.GetInputs() {
+		result, err := v.s.segmenter.Segment(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		sentences := make([]*sentencesegmentationv2.Sentence, len(result.Sentences))
+		for i, s := range result.Sentences {
+			sentences[i] = &sentencesegmentationv2.Sentence{
+				Text:  s.Text,
+				Start: int32(s.Start),
+				End:   int32(s.End),
+
+				ByteStart: int32(s.ByteStart),
+				ByteEnd:   int32(s.ByteEnd),
+			}
+		}
+		resp.Segmentations = append(resp.Segmentations, &sentencesegmentationv2.Segmentation{Sentences: sentences})
+	}
+	return resp, nil
+}
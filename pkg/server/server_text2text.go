@@ -6,17 +6,24 @@ package server
 
 import (
 	"context"
+	"errors"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
 	text2textv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/text2text/v1"
+	text2textv2 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/text2text/v2"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
 	"github.com/nlpodyssey/cybertron/pkg/utils/nullable"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 // serverForTextGeneration is a server that provides gRPC and HTTP/2 APIs for Interface task.
+// It serves the v2 API, and the deprecated v1 one through text2textV1.
 type serverForTextGeneration struct {
-	text2textv1.UnimplementedText2TextServiceServer
+	text2textv2.UnimplementedText2TextServiceServer
+	sharedResponses
 	generator text2text.Interface
 }
 
@@ -25,32 +32,123 @@ func NewServerForTextGeneration(generator text2text.Interface) RequestHandler {
 }
 
 func (s *serverForTextGeneration) RegisterServer(r grpc.ServiceRegistrar) error {
-	text2textv1.RegisterText2TextServiceServer(r, s)
+	text2textv2.RegisterText2TextServiceServer(r, s)
+	text2textv1.RegisterText2TextServiceServer(r, text2textV1{s: s})
 	return nil
 }
 
 func (s *serverForTextGeneration) RegisterHandlerServer(ctx context.Context, mux *runtime.ServeMux) error {
-	return text2textv1.RegisterText2TextServiceHandlerServer(ctx, mux, s)
+	if err := text2textv2.RegisterText2TextServiceHandlerServer(ctx, mux, s); err != nil {
+		return err
+	}
+	return text2textv1.RegisterText2TextServiceHandlerServer(ctx, mux, text2textV1{s: s})
 }
 
 // Generate handles the Generate request.
-func (s *serverForTextGeneration) Generate(ctx context.Context, req *text2textv1.GenerateRequest) (*text2textv1.GenerateResponse, error) {
-	opts := req.GetParameters()
-	if opts == nil {
-		opts = &text2textv1.Text2TextParameters{}
-	}
-	result, err := s.generator.Generate(ctx, req.GetInput(), &text2text.Options{
-		Temperature: nullable.Any(opts.Temperature),
-		Sample:      nullable.Any(opts.DoSample),
-		TopK:        nullable.Int(opts.TopK),
-		TopP:        nullable.Any(opts.TopP),
+func (s *serverForTextGeneration) Generate(ctx context.Context, req *text2textv2.GenerateRequest) (*text2textv2.GenerateResponse, error) {
+	if req.GetSampling().GetEnabled() {
+		return respond(ctx, s.uncached(), req, s.generate) // the sampled texts are random
+	}
+	return respond(ctx, &s.sharedResponses, req, s.generate)
+}
+
+// generate generates the texts of the inputs in turn. If a generation times
+// out, the partial output has the generations completed before it.
+func (s *serverForTextGeneration) generate(ctx context.Context, req *text2textv2.GenerateRequest) (*text2textv2.GenerateResponse, error) {
+	params := req.GetSampling()
+	if params == nil {
+		params = &text2textv2.SamplingParameters{}
+	}
+	opts := &text2text.Options{
+		Temperature: nullable.Any(params.Temperature),
+		Sample:      nullable.Any(params.Enabled),
+		TopK:        nullable.Int(params.TopK),
+		TopP:        nullable.Any(params.TopP),
+	}
+	resp := &text2textv2.GenerateResponse{
+		Generations: make([]*text2textv2.Generation, 0, len(req.GetInputs())),
+	}
+	for _, input := range req.GetInputs() {
+		result, err := s.generator.Generate(ctx, input, opts)
+		resp.Generations = append(resp.Generations, &text2textv2.Generation{
+			Texts:  result.Texts,
+			Scores: result.Scores,
+		})
+		if req.GetPartialOutput() && errors.Is(err, errdefs.ErrDecodingTimeout) {
+			return nil, statusError(err, resp)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// text2textV1 serves the deprecated v1 API of the text generation,
+// translating its requests to the ones of the v2 API, and the responses
+// back, so that both are served by the same generator.
+type text2textV1 struct {
+	text2textv1.UnimplementedText2TextServiceServer
+	s *serverForTextGeneration
+}
+
+// Generate handles the Generate request of the v1 API.
+func (v text2textV1) Generate(ctx context.Context, req *text2textv1.GenerateRequest) (*text2textv1.GenerateResponse, error) {
+	mask, err := responseFields[*text2textv1.GenerateResponse](ctx)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	params := req.GetParameters()
+	var sampling *text2textv2.SamplingParameters
+	if params != nil {
+		sampling = &text2textv2.SamplingParameters{
+			Enabled:     params.DoSample,
+			Temperature: params.Temperature,
+			TopK:        params.TopK,
+			TopP:        params.TopP,
+		}
+	}
+	// The fields are selected in the v1 response.
+	resp, err := v.s.Generate(contextWithFields(ctx, ""), &text2textv2.GenerateRequest{
+		Inputs:        []string{req.GetInput()},
+		Sampling:      sampling,
+		PartialOutput: req.GetPartialOutput(),
 	})
 	if err != nil {
-		return nil, err
+		return nil, downgradeText2TextError(err)
 	}
-	resp := &text2textv1.GenerateResponse{
-		Texts:  result.Texts,
-		Scores: result.Scores,
+	return applyFieldMask(mask, downgradeText2TextResponse(resp)), nil
+}
+
+// downgradeText2TextResponse returns the v1 response of the generation of
+// the single input of a v2 one.
+func downgradeText2TextResponse(resp *text2textv2.GenerateResponse) *text2textv1.GenerateResponse {
+	g := &text2textv2.Generation{}
+	if len(resp.GetGenerations()) > 0 {
+		g = resp.GetGenerations()[0]
 	}
-	return resp, nil
+	return &text2textv1.GenerateResponse{
+		Texts:  g.GetTexts(),
+		Scores: g.GetScores(),
+	}
+}
+
+// downgradeText2TextError replaces the partial v2 response in the details of
+// the error, if any, with the v1 one.
+func downgradeText2TextError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	p := st.Proto()
+	for i, d := range p.GetDetails() {
+		resp := &text2textv2.GenerateResponse{}
+		if !d.MessageIs(resp) || d.UnmarshalTo(resp) != nil {
+			continue
+		}
+		if a, err := anypb.New(downgradeText2TextResponse(resp)); err == nil {
+			p.Details[i] = a
+		}
+	}
+	return status.FromProto(p).Err()
 }
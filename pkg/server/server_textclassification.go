@@ -6,9 +6,12 @@ package server
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
 	textclassificationv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textclassification/v1"
+	textclassificationv2 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textclassification/v2"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
 	"google.golang.org/grpc"
 )
@@ -16,6 +19,7 @@ import (
 // serverForTextClassification is a server that provides gRPC and HTTP/2 APIs for Text Classification task.
 type serverForTextClassification struct {
 	textclassificationv1.UnimplementedTextClassificationServiceServer
+	sharedResponses
 	classifier textclassification.Interface
 }
 
@@ -25,22 +29,108 @@ func NewServerForTextClassification(classifier textclassification.Interface) Req
 
 func (s *serverForTextClassification) RegisterServer(r grpc.ServiceRegistrar) error {
 	textclassificationv1.RegisterTextClassificationServiceServer(r, s)
+	textclassificationv2.RegisterTextClassificationServiceServer(r, textClassificationV2{s: s})
 	return nil
 }
 
 func (s *serverForTextClassification) RegisterHandlerServer(ctx context.Context, mux *runtime.ServeMux) error {
-	return textclassificationv1.RegisterTextClassificationServiceHandlerServer(ctx, mux, s)
+	if err := textclassificationv1.RegisterTextClassificationServiceHandlerServer(ctx, mux, s); err != nil {
+		return err
+	}
+	return textclassificationv2.RegisterTextClassificationServiceHandlerServer(ctx, mux, textClassificationV2{s: s})
 }
 
 // Classify handles the Classify request.
 func (s *serverForTextClassification) Classify(ctx context.Context, req *textclassificationv1.ClassifyRequest) (*textclassificationv1.ClassifyResponse, error) {
-	result, err := s.classifier.Classify(ctx, req.GetInput())
+	return respond(ctx, &s.sharedResponses, req, s.classify)
+}
+
+func (s *serverForTextClassification) classify(ctx context.Context, req *textclassificationv1.ClassifyRequest) (*textclassificationv1.ClassifyResponse, error) {
+	params := textclassification.Parameters{
+		TextPair: req.GetTextPair(),
+		Layers:   encoderLayers(req.GetLayers()),
+		Explain:  req.GetExplain(),
+	}
+	result, err := s.classifier.Classify(ctx, req.GetInput(), params)
 	if err != nil {
 		return nil, err
 	}
+	attributions := make([]*textclassificationv1.Attribution, len(result.Attributions))
+	for i, a := range result.Attributions {
+		attributions[i] = &textclassificationv1.Attribution{
+			Text:      a.Text,
+			Start:     int32(a.Start),
+			End:       int32(a.End),
+			Score:     a.Score,
+			ByteStart: int32(a.ByteStart),
+			ByteEnd:   int32(a.ByteEnd),
+		}
+	}
 	resp := &textclassificationv1.ClassifyResponse{
-		Labels: result.Labels,
-		Scores: result.Scores,
+		Labels:       result.Labels,
+		Scores:       result.Scores,
+		Attributions: attributions,
+	}
+	return resp, nil
+}
+
+// encoderLayers returns the number of the first encoder layers requested,
+// 0 (all of them) if not positive.
+func encoderLayers(n int32) int {
+	if n < 0 {
+		return 0
+	}
+	return int(n)
+}
+
+// textClassificationV2 serves the v2 API of the text classification,
+// classifying a batch of inputs with the same options.
+type textClassificationV2 struct {
+	textclassificationv2.UnimplementedTextClassificationServiceServer
+	s *serverForTextClassification
+}
+
+// Classify handles the Classify request of the v2 API.
+func (v textClassificationV2) Classify(ctx context.Context, req *textclassificationv2.ClassifyRequest) (*textclassificationv2.ClassifyResponse, error) {
+	return respond(ctx, &v.s.sharedResponses, req, v.classify)
+}
+
+func (v textClassificationV2) classify(ctx context.Context, req *textclassificationv2.ClassifyRequest) (*textclassificationv2.ClassifyResponse, error) {
+	inputs, pairs := req.GetInputs(), req.GetTextPairs()
+	if len(pairs) > 0 && len(pairs) != len(inputs) {
+		return nil, fmt.Errorf("%w: %d text pairs for %d inputs", errdefs.ErrInvalidRequest, len(pairs), len(inputs))
+	}
+	params := textclassification.Parameters{
+		Layers:  encoderLayers(req.GetLayers()),
+		Explain: req.GetExplain(),
+	}
+	resp := &textclassificationv2.ClassifyResponse{
+		Classifications: make([]*textclassificationv2.Classification, 0, len(inputs)),
+	}
+	for i, input := range inputs {
+		if len(pairs) > 0 {
+			params.TextPair = pairs[i]
+		}
+		result, err := v.s.classifier.Classify(ctx, input, params)
+		if err != nil {
+			return nil, err
+		}
+		attributions := make([]*textclassificationv2.Attribution, len(result.Attributions))
+		for j, a := range result.Attributions {
+			attributions[j] = &textclassificationv2.Attribution{
+				Text:      a.Text,
+				Start:     int32(a.Start),
+				End:       int32(a.End),
+				Score:     a.Score,
+				ByteStart: int32(a.ByteStart),
+				ByteEnd:   int32(a.ByteEnd),
+			}
+		}
+		resp.Classifications = append(resp.Classifications, &textclassificationv2.Classification{
+			Labels:       result.Labels,
+			Scores:       result.Scores,
+			Attributions: attributions,
+		})
 	}
 	return resp, nil
 }
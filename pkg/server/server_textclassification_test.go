@@ -0,0 +1,52 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	textclassificationv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textclassification/v1"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// layersClassifier has two encoder layers, and returns the number of them
+// requested as its score.
+type layersClassifier struct{}
+
+func (layersClassifier) Classify(_ context.Context, _ string, parameters textclassification.Parameters) (textclassification.Response, error) {
+	n := parameters.Layers
+	if n > 2 {
+		return textclassification.Response{}, textclassification.ErrLayersNotSupported
+	}
+	return textclassification.Response{Labels: []string{"label"}, Scores: []float64{float64(n)}}, nil
+}
+
+func TestServerForTextClassification_Layers(t *testing.T) {
+	s := NewServerForTextClassification(layersClassifier{}).(*serverForTextClassification)
+	tests := []struct {
+		layers   int32
+		want     float64
+		wantCode codes.Code
+	}{
+		{layers: 0, want: 0},
+		{layers: 2, want: 2},
+		{layers: -1, want: 0},
+		{layers: 3, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		resp, err := s.Classify(context.Background(), &textclassificationv1.ClassifyRequest{Input: "text", Layers: tt.layers})
+		if tt.wantCode != codes.OK {
+			assert.Equal(t, tt.wantCode, status.Code(err), tt.layers)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, []float64{tt.want}, resp.Scores, tt.layers)
+	}
+}
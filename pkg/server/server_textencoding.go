@@ -6,16 +6,21 @@ package server
 
 import (
 	"context"
+	"encoding/binary"
+	"math"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	textencodingv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textencoding/v1"
+	textencodingv2 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textencoding/v2"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/utils/matview"
 	"google.golang.org/grpc"
 )
 
 // serverForTextClassification is a server that provides gRPC and HTTP/2 APIs for Text Classification task.
 type serverForTextEncoding struct {
 	textencodingv1.UnimplementedTextEncodingServiceServer
+	sharedResponses
 	encoder textencoding.Interface
 }
 
@@ -25,21 +30,72 @@ func NewServerForTextEncoding(encoder textencoding.Interface) RequestHandler {
 
 func (s *serverForTextEncoding) RegisterServer(r grpc.ServiceRegistrar) error {
 	textencodingv1.RegisterTextEncodingServiceServer(r, s)
+	textencodingv2.RegisterTextEncodingServiceServer(r, textEncodingV2{s: s})
 	return nil
 }
 
 func (s *serverForTextEncoding) RegisterHandlerServer(ctx context.Context, mux *runtime.ServeMux) error {
-	return textencodingv1.RegisterTextEncodingServiceHandlerServer(ctx, mux, s)
+	if err := textencodingv1.RegisterTextEncodingServiceHandlerServer(ctx, mux, s); err != nil {
+		return err
+	}
+	return textencodingv2.RegisterTextEncodingServiceHandlerServer(ctx, mux, textEncodingV2{s: s})
 }
 
 // Encode handles the Encode request.
 func (s *serverForTextEncoding) Encode(ctx context.Context, req *textencodingv1.EncodingRequest) (*textencodingv1.EncodingResponse, error) {
+	return respond(ctx, &s.sharedResponses, req, s.encode)
+}
+
+func (s *serverForTextEncoding) encode(ctx context.Context, req *textencodingv1.EncodingRequest) (*textencodingv1.EncodingResponse, error) {
 	result, err := s.encoder.Encode(ctx, req.GetInput(), int(req.GetPoolingStrategy()))
 	if err != nil {
 		return nil, err
 	}
-	resp := &textencodingv1.EncodingResponse{
-		Vector: result.Vector.Data().F32(),
+	vector := matview.Float32s(result.Vector)
+	if req.GetVectorEncoding() == textencodingv1.EncodingRequest_PACKED {
+		return &textencodingv1.EncodingResponse{VectorBytes: packFloat32(vector)}, nil
+	}
+	return &textencodingv1.EncodingResponse{Vector: vector}, nil
+}
+
+// packFloat32 returns the values packed as little-endian float32, 4 bytes
+// each, smaller and faster to parse than their JSON numbers.
+func packFloat32(v []float32) []byte {
+	data := make([]byte, len(v)*4)
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(x))
+	}
+	return data
+}
+
+// textEncodingV2 serves the v2 API of the text encoding, encoding a batch of
+// inputs with the same options.
+type textEncodingV2 struct {
+	textencodingv2.UnimplementedTextEncodingServiceServer
+	s *serverForTextEncoding
+}
+
+// Encode handles the Encode request of the v2 API.
+func (v textEncodingV2) Encode(ctx context.Context, req *textencodingv2.EncodingRequest) (*textencodingv2.EncodingResponse, error) {
+	return respond(ctx, &v.s.sharedResponses, req, v.encode)
+}
+
+func (v textEncodingV2) encode(ctx context.Context, req *textencodingv2.EncodingRequest) (*textencodingv2.EncodingResponse, error) {
+	packed := req.GetVectorEncoding() == textencodingv2.EncodingRequest_PACKED
+	resp := &textencodingv2.EncodingResponse{
+		Embeddings: make([]*textencodingv2.Embedding, 0, len(req.GetInputs())),
+	}
+	for _, input := range req.GetInputs() {
+		result, err := v.s.encoder.Encode(ctx, input, int(req.GetPoolingStrategy()))
+		if err != nil {
+			return nil, err
+		}
+		vector := matview.Float32s(result.Vector)
+		if packed {
+			resp.Embeddings = append(resp.Embeddings, &textencodingv2.Embedding{VectorBytes: packFloat32(vector)})
+			continue
+		}
+		resp.Embeddings = append(resp.Embeddings, &textencodingv2.Embedding{Vector: vector})
 	}
 	return resp, nil
 }
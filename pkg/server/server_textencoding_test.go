@@ -0,0 +1,82 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	textencodingv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textencoding/v1"
+	textencodingv2 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textencoding/v2"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lengthEncoder encodes a text as the vector of its length and its negation.
+type lengthEncoder struct{}
+
+func (lengthEncoder) Encode(_ context.Context, text string, _ int) (textencoding.Response, error) {
+	n := float32(len(text))
+	return textencoding.Response{Vector: mat.NewVecDense[float32]([]float32{n, -n, 0.5})}, nil
+}
+
+// unpackFloat32 returns the values packed as little-endian float32.
+func unpackFloat32(data []byte) []float32 {
+	v := make([]float32, len(data)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return v
+}
+
+func TestPackFloat32(t *testing.T) {
+	tests := []struct {
+		v    []float32
+		want []byte
+	}{
+		{v: nil, want: []byte{}},
+		{v: []float32{1}, want: []byte{0x00, 0x00, 0x80, 0x3f}},
+		{v: []float32{-2, 0.5}, want: []byte{0x00, 0x00, 0x00, 0xc0, 0x00, 0x00, 0x00, 0x3f}},
+	}
+	for _, tt := range tests {
+		got := packFloat32(tt.v)
+		assert.Equal(t, tt.want, got, tt.v)
+		assert.Equal(t, len(tt.v), len(unpackFloat32(got)))
+	}
+	v := []float32{float32(math.Inf(1)), math.SmallestNonzeroFloat32, -0}
+	assert.Equal(t, v, unpackFloat32(packFloat32(v)))
+}
+
+func TestServerForTextEncoding_VectorEncoding(t *testing.T) {
+	s := NewServerForTextEncoding(lengthEncoder{}).(*serverForTextEncoding)
+	ctx := context.Background()
+
+	resp, err := s.Encode(ctx, &textencodingv1.EncodingRequest{Input: "abc"})
+	require.NoError(t, err)
+	assert.Equal(t, []float32{3, -3, 0.5}, resp.Vector)
+	assert.Empty(t, resp.VectorBytes)
+
+	resp, err = s.Encode(ctx, &textencodingv1.EncodingRequest{Input: "abc", VectorEncoding: textencodingv1.EncodingRequest_PACKED})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Vector)
+	assert.Equal(t, []float32{3, -3, 0.5}, unpackFloat32(resp.VectorBytes))
+
+	v2 := textEncodingV2{s: s}
+	respV2, err := v2.Encode(ctx, &textencodingv2.EncodingRequest{Inputs: []string{"a", "ab"}, VectorEncoding: textencodingv2.EncodingRequest_PACKED})
+	require.NoError(t, err)
+	require.Len(t, respV2.Embeddings, 2)
+	assert.Equal(t, []float32{1, -1, 0.5}, unpackFloat32(respV2.Embeddings[0].VectorBytes))
+	assert.Equal(t, []float32{2, -2, 0.5}, unpackFloat32(respV2.Embeddings[1].VectorBytes))
+	assert.Empty(t, respV2.Embeddings[0].Vector)
+
+	respV2, err = v2.Encode(ctx, &textencodingv2.EncodingRequest{Inputs: []string{"a"}})
+	require.NoError(t, err)
+	assert.Equal(t, []float32{1, -1, 0.5}, respV2.Embeddings[0].Vector)
+	assert.Empty(t, respV2.Embeddings[0].VectorBytes)
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	tokenclassificationv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/tokenclassification/v1"
+	tokenclassificationv2 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/tokenclassification/v2"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
 	"google.golang.org/grpc"
 )
@@ -17,6 +18,7 @@ import (
 // serverForTextClassification is a server that provides gRPC and HTTP/2 APIs for Token Classification task.
 type serverForTokenClassification struct {
 	tokenclassificationv1.UnimplementedTokenClassificationServiceServer
+	sharedResponses
 	classifier tokenclassification.Interface
 }
 
@@ -26,15 +28,23 @@ func NewServerForTokenClassification(classifier tokenclassification.Interface) R
 
 func (s *serverForTokenClassification) RegisterServer(r grpc.ServiceRegistrar) error {
 	tokenclassificationv1.RegisterTokenClassificationServiceServer(r, s)
+	tokenclassificationv2.RegisterTokenClassificationServiceServer(r, tokenClassificationV2{s: s})
 	return nil
 }
 
 func (s *serverForTokenClassification) RegisterHandlerServer(ctx context.Context, mux *runtime.ServeMux) error {
-	return tokenclassificationv1.RegisterTokenClassificationServiceHandlerServer(ctx, mux, s)
+	if err := tokenclassificationv1.RegisterTokenClassificationServiceHandlerServer(ctx, mux, s); err != nil {
+		return err
+	}
+	return tokenclassificationv2.RegisterTokenClassificationServiceHandlerServer(ctx, mux, tokenClassificationV2{s: s})
 }
 
 // Classify handles the Classify request.
 func (s *serverForTokenClassification) Classify(ctx context.Context, req *tokenclassificationv1.ClassifyRequest) (*tokenclassificationv1.ClassifyResponse, error) {
+	return respond(ctx, &s.sharedResponses, req, s.classify)
+}
+
+func (s *serverForTokenClassification) classify(ctx context.Context, req *tokenclassificationv1.ClassifyRequest) (*tokenclassificationv1.ClassifyResponse, error) {
 	result, err := s.classifier.Classify(ctx, req.GetInput(), tokenclassification.Parameters{
 		AggregationStrategy: convAggregationStrategy(req.AggregationStrategy),
 	})
@@ -50,6 +60,10 @@ func (s *serverForTokenClassification) Classify(ctx context.Context, req *tokenc
 			Score: token.Score,
 			Start: int32(token.Start),
 			End:   int32(token.End),
+
+			ByteStart: int32(token.ByteStart),
+			ByteEnd:   int32(token.ByteEnd),
+			Depth:     int32(token.Depth),
 		}
 	}
 	resp := &tokenclassificationv1.ClassifyResponse{
@@ -64,7 +78,53 @@ func convAggregationStrategy(strategy tokenclassificationv1.ClassifyRequest_Aggr
 		return tokenclassification.AggregationStrategyNone
 	case tokenclassificationv1.ClassifyRequest_SIMPLE:
 		return tokenclassification.AggregationStrategySimple
+	case tokenclassificationv1.ClassifyRequest_NESTED:
+		return tokenclassification.AggregationStrategyNested
 	default:
 		panic(fmt.Sprintf("server: invalid aggregation strategy [%s] for token classification", strategy))
 	}
 }
+
+// tokenClassificationV2 serves the v2 API of the token classification,
+// classifying a batch of inputs with the same aggregation strategy.
+type tokenClassificationV2 struct {
+	tokenclassificationv2.UnimplementedTokenClassificationServiceServer
+	s *serverForTokenClassification
+}
+
+// Classify handles the Classify request of the v2 API.
+func (v tokenClassificationV2) Classify(ctx context.Context, req *tokenclassificationv2.ClassifyRequest) (*tokenclassificationv2.ClassifyResponse, error) {
+	return respond(ctx, &v.s.sharedResponses, req, v.classify)
+}
+
+func (v tokenClassificationV2) classify(ctx context.Context, req *tokenclassificationv2.ClassifyRequest) (*tokenclassificationv2.ClassifyResponse, error) {
+	// The strategies have the same values in both versions.
+	params := tokenclassification.Parameters{
+		AggregationStrategy: convAggregationStrategy(tokenclassificationv1.ClassifyRequest_AggregationStrategy(req.GetAggregationStrategy())),
+	}
+	resp := &tokenclassificationv2.ClassifyResponse{
+		Classifications: make([]*tokenclassificationv2.Classification, 0, len(req.GetInputs())),
+	}
+	for _, input := range req.GetInputs() {
+		result, err := v.s.classifier.Classify(ctx, input, params)
+		if err != nil {
+			return nil, err
+		}
+		tokens := make([]*tokenclassificationv2.Token, len(result.Tokens))
+		for i, token := range result.Tokens {
+			tokens[i] = &tokenclassificationv2.Token{
+				Text:  token.Text,
+				Label: token.Label,
+				Score: token.Score,
+				Start: int32(token.Start),
+				End:   int32(token.End),
+
+				ByteStart: int32(token.ByteStart),
+				ByteEnd:   int32(token.ByteEnd),
+				Depth:     int32(token.Depth),
+			}
+		}
+		resp.Classifications = append(resp.Classifications, &tokenclassificationv2.Classification{Tokens: tokens})
+	}
+	return resp, nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	zeroshotv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/zeroshot/v1"
+	zeroshotv2 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/zeroshot/v2"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier"
 	"google.golang.org/grpc"
 )
@@ -16,6 +17,7 @@ import (
 // serverForZeroShotClassification is a server that provides gRPC and HTTP/2 APIs for Zero-Shot Classification task.
 type serverForZeroShotClassification struct {
 	zeroshotv1.UnimplementedZeroShotServiceServer
+	sharedResponses
 	classifier zeroshotclassifier.Interface
 }
 
@@ -25,15 +27,23 @@ func NewServerForZeroShotClassification(classifier zeroshotclassifier.Interface)
 
 func (s *serverForZeroShotClassification) RegisterServer(r grpc.ServiceRegistrar) error {
 	zeroshotv1.RegisterZeroShotServiceServer(r, s)
+	zeroshotv2.RegisterZeroShotServiceServer(r, zeroShotV2{s: s})
 	return nil
 }
 
 func (s *serverForZeroShotClassification) RegisterHandlerServer(ctx context.Context, mux *runtime.ServeMux) error {
-	return zeroshotv1.RegisterZeroShotServiceHandlerServer(ctx, mux, s)
+	if err := zeroshotv1.RegisterZeroShotServiceHandlerServer(ctx, mux, s); err != nil {
+		return err
+	}
+	return zeroshotv2.RegisterZeroShotServiceHandlerServer(ctx, mux, zeroShotV2{s: s})
 }
 
 // Classify handles the Classify request.
 func (s *serverForZeroShotClassification) Classify(ctx context.Context, req *zeroshotv1.ClassifyRequest) (*zeroshotv1.ClassifyResponse, error) {
+	return respond(ctx, &s.sharedResponses, req, s.classify)
+}
+
+func (s *serverForZeroShotClassification) classify(ctx context.Context, req *zeroshotv1.ClassifyRequest) (*zeroshotv1.ClassifyResponse, error) {
 	params := req.GetParameters()
 	candidateLabels := params.GetCandidateLabels()
 	result, err := s.classifier.Classify(ctx, req.GetInput(), zeroshotclassifier.Parameters{
@@ -51,3 +61,37 @@ func (s *serverForZeroShotClassification) Classify(ctx context.Context, req *zer
 	}
 	return resp, nil
 }
+
+// zeroShotV2 serves the v2 API of the zero-shot classification, classifying
+// a batch of inputs with the same parameters.
+type zeroShotV2 struct {
+	zeroshotv2.UnimplementedZeroShotServiceServer
+	s *serverForZeroShotClassification
+}
+
+// Classify handles the Classify request of the v2 API.
+func (v zeroShotV2) Classify(ctx context.Context, req *zeroshotv2.ClassifyRequest) (*zeroshotv2.ClassifyResponse, error) {
+	return respond(ctx, &v.s.sharedResponses, req, v.classify)
+}
+
+func (v zeroShotV2) classify(ctx context.Context, req *zeroshotv2.ClassifyRequest) (*zeroshotv2.ClassifyResponse, error) {
+	params := req.GetParameters()
+	resp := &zeroshotv2.ClassifyResponse{
+		Classifications: make([]*zeroshotv2.Classification, 0, len(req.GetInputs())),
+	}
+	for _, input := range req.GetInputs() {
+		result, err := v.s.classifier.Classify(ctx, input, zeroshotclassifier.Parameters{
+			CandidateLabels:    params.GetCandidateLabels(),
+			HypothesisTemplate: params.GetHypothesisTemplate(),
+			MultiLabel:         params.GetMultiLabel(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.Classifications = append(resp.Classifications, &zeroshotv2.Classification{
+			Labels: result.Labels,
+			Scores: result.Scores,
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+)
+
+// statusCodes are the gRPC status codes of the errors of the taxonomy.
+var statusCodes = map[errdefs.Code]codes.Code{
+	errdefs.CodeModelNotLoaded:      codes.NotFound,
+	errdefs.CodeModelLoadFailed:     codes.Unavailable,
+	errdefs.CodeInvalidRequest:      codes.InvalidArgument,
+	errdefs.CodeInputTooLong:        codes.InvalidArgument,
+	errdefs.CodeUnsupportedLanguage: codes.InvalidArgument,
+	errdefs.CodeUnsupportedOption:   codes.InvalidArgument,
+	errdefs.CodeDecodingTimeout:     codes.DeadlineExceeded,
+	errdefs.CodeMemoryLimitExceeded: codes.ResourceExhausted,
+	errdefs.CodePreempted:           codes.Aborted,
+	errdefs.CodeUnknownTenant:       codes.Unauthenticated,
+	errdefs.CodeRateLimited:         codes.ResourceExhausted,
+	errdefs.CodeQuotaExceeded:       codes.ResourceExhausted,
+	errdefs.CodeJobNotFound:         codes.NotFound,
+	errdefs.CodeInternal:            codes.Internal,
+}
+
+// statusError returns the error of a task with the gRPC status code
+// matching it, if any, so that the clients (and the HTTP gateway, mapping
+// the codes to the HTTP statuses) can tell the failures apart. The code of
+// the error in the errdefs taxonomy is set in the details of the status,
+// as the reason of a google.rpc.ErrorInfo, followed by the details given.
+func statusError(err error, details ...protoiface.MessageV1) error {
+	var e *errdefs.Error
+	switch {
+	case err == nil:
+		return nil
+	case !errors.As(err, &e):
+		if s := status.FromContextError(err); s.Code() != codes.Unknown {
+			return s.Err()
+		}
+		return err
+	}
+	code, ok := statusCodes[e.Code]
+	if !ok {
+		code = codes.Unknown
+	}
+	s := status.New(code, err.Error())
+	details = append([]protoiface.MessageV1{&errdetails.ErrorInfo{Reason: string(e.Code), Domain: errdefs.Domain}}, details...)
+	if d, err := s.WithDetails(details...); err == nil {
+		s = d
+	}
+	return s.Err()
+}
+
+// errorReason returns the code of the error in the errdefs taxonomy, from
+// the details of its gRPC status, if any.
+func errorReason(s *status.Status) string {
+	for _, d := range s.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.GetDomain() == errdefs.Domain {
+			return info.GetReason()
+		}
+	}
+	return ""
+}
+
+// writeHTTPError writes the error as the HTTP gateway does, with the status
+// matching its gRPC code and its JSON body.
+func writeHTTPError(w http.ResponseWriter, r *http.Request, err error) {
+	runtime.DefaultHTTPErrorHandler(r.Context(), runtime.NewServeMux(), &runtime.JSONPb{}, w, r, statusError(err))
+}
+
+// unknownServiceHandler fails the gRPC requests of the services not served,
+// since no model serving them is loaded.
+func unknownServiceHandler(_ any, stream grpc.ServerStream) error {
+	method, _ := grpc.MethodFromServerStream(stream)
+	return statusError(fmt.Errorf("%w: no model serving %s", errdefs.ErrModelNotLoaded, method))
+}
@@ -0,0 +1,149 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/rs/cors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// generation is the gRPC server and HTTP handler of a request handler.
+// Requests are served by the current generation; a generation replaced by
+// a new one is retired as soon as its in-flight requests are completed.
+type generation struct {
+	handler http.Handler
+	// methods are the unary methods served over NATS, and by the bulk
+	// uploads.
+	methods methodRegistry
+	// mu is read-locked by each in-flight request.
+	mu      sync.RWMutex
+	retired bool
+}
+
+// newGeneration registers the request handler in a new gRPC server and
+// HTTP mux.
+func (s *Server) newGeneration(ctx context.Context, rh RequestHandler) (*generation, error) {
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.unaryInterceptors()...),
+		grpc.UnknownServiceHandler(unknownServiceHandler),
+	)
+
+	grpc_health_v1.RegisterHealthServer(grpcServer, s.health)
+
+	if err := rh.RegisterServer(grpcServer); err != nil {
+		return nil, fmt.Errorf("failed to register gRPC server: %w", err)
+	}
+
+	methods := make(methodRegistry)
+	if err := rh.RegisterServer(methods); err != nil {
+		return nil, fmt.Errorf("failed to register NATS methods: %w", err)
+	}
+	s.logDeprecations(methods)
+
+	mux := runtime.NewServeMux(runtime.WithErrorHandler(s.httpErrorHandler))
+	if err := rh.RegisterHandlerServer(ctx, mux); err != nil {
+		return nil, fmt.Errorf("failed to register gRPC handler server: %w", err)
+	}
+	for _, h := range []struct {
+		method, path string
+		handler      runtime.HandlerFunc
+	}{
+		{http.MethodPost, bulkPath, s.serveBulk(methods)},
+		{http.MethodPost, jobsPath, s.submitJob(methods)},
+		{http.MethodGet, jobPath, s.serveJob},
+		{http.MethodGet, jobResultsPath, s.serveJobResults},
+	} {
+		if err := mux.HandlePath(h.method, h.path, h.handler); err != nil {
+			return nil, fmt.Errorf("failed to register handler of %s: %w", h.path, err)
+		}
+	}
+
+	handler := withRequestID(s.withHTTPRecovery(cors.New(s.corsOptions()).Handler(s.withDeprecation(s.withTenancy(withPriority(withAdapter(withFields(s.withTrace(withTimeout(mux))))))))))
+	return &generation{handler: s.handlerFunc(grpcServer, handler), methods: methods}, nil
+}
+
+// unaryInterceptors are the interceptors of the unary gRPC requests, and of
+// the ones over NATS, in order.
+func (s *Server) unaryInterceptors() []grpc.UnaryServerInterceptor {
+	return []grpc.UnaryServerInterceptor{requestIDInterceptor, s.recoveryInterceptor, s.deprecationInterceptor, s.tenancyInterceptor, priorityInterceptor, adapterInterceptor, fieldsInterceptor, s.traceInterceptor, timeoutInterceptor}
+}
+
+// rowInterceptors are the interceptors of the rows of the bulk uploads, of
+// the async jobs and of the work queue: the ones of the unary requests but
+// the request ID and the tenancy, which are the ones of the upload, and
+// its account.
+func (s *Server) rowInterceptors() []grpc.UnaryServerInterceptor {
+	return []grpc.UnaryServerInterceptor{s.recoveryInterceptor, s.deprecationInterceptor, priorityInterceptor, adapterInterceptor, fieldsInterceptor, s.traceInterceptor, timeoutInterceptor}
+}
+
+// chainUnaryInterceptors returns the interceptor calling the interceptors
+// in order, as grpc.ChainUnaryInterceptor does.
+func chainUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], handler
+			handler = func(ctx context.Context, req any) (any, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// serveCurrent serves the request with the current generation.
+func (s *Server) serveCurrent(w http.ResponseWriter, r *http.Request) {
+	g := s.acquireCurrent()
+	defer g.mu.RUnlock()
+	g.handler.ServeHTTP(w, r)
+}
+
+// acquireCurrent returns the current generation, read-locked.
+func (s *Server) acquireCurrent() *generation {
+	for {
+		g := s.current.Load()
+		g.mu.RLock()
+		if !g.retired {
+			return g
+		}
+		// Swapped in the meantime.
+		g.mu.RUnlock()
+	}
+}
+
+// SwapRequestHandler replaces the request handler of the running server,
+// e.g. to serve a new version of the model, without dropping any request:
+// new requests are served by the new handler as soon as it's registered,
+// and SwapRequestHandler returns once the requests in flight on the old
+// handler are completed, so that its model can be finalized.
+//
+// The new handler must serve the same services as the old one, since the
+// clients can't be notified of any change.
+func (s *Server) SwapRequestHandler(rh RequestHandler) error {
+	s.swapMu.Lock()
+	defer s.swapMu.Unlock()
+
+	old := s.current.Load()
+	if old == nil {
+		return errors.New("failed to swap request handler: server not started")
+	}
+	g, err := s.newGeneration(s.ctx, rh)
+	if err != nil {
+		return err
+	}
+	s.current.Store(g)
+
+	old.mu.Lock()
+	old.retired = true
+	old.mu.Unlock()
+	return nil
+}
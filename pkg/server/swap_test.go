@@ -0,0 +1,49 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwapRequestHandler(t *testing.T) {
+	handler := NewServerForTextClassification(pairClassifier{})
+	s := New(&Config{}, handler)
+	require.Error(t, s.SwapRequestHandler(handler), "server not started")
+
+	ctx := context.Background()
+	g, err := s.newGeneration(ctx, s.handler)
+	require.NoError(t, err)
+	s.ctx = ctx
+	s.current.Store(g)
+
+	// The requests acquire the current generation while it's swapped.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				g := s.acquireCurrent()
+				assert.Contains(t, g.methods, "textclassification.v1.TextClassificationService.Classify")
+				g.mu.RUnlock()
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		require.NoError(t, s.SwapRequestHandler(NewServerForTextClassification(pairClassifier{})))
+	}
+	wg.Wait()
+
+	assert.True(t, g.retired)
+	assert.NotSame(t, g, s.current.Load())
+	assert.False(t, s.current.Load().retired)
+	assert.Same(t, handler, s.handler, "the handler the server is started with")
+}
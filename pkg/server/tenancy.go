@@ -0,0 +1,172 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/tenancy"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// apiKeyHeader is the HTTP header, or gRPC metadata, carrying the API key of
+// the tenant, alternatively to the bearer token of the authorization.
+const apiKeyHeader = "cybertron-api-key"
+
+// tenantCredentials returns the API key and the tenant name of a request
+// from the function looking up its headers.
+func (s *Server) tenantCredentials(get func(string) string) (apiKey, name string) {
+	apiKey = get(apiKeyHeader)
+	if apiKey == "" {
+		apiKey = bearerToken(get("authorization"))
+	}
+	if h := s.conf.TenantHeader; h != "" {
+		name = get(h)
+	}
+	return apiKey, name
+}
+
+// bearerToken returns the token of the authorization, if it's a bearer one.
+func bearerToken(authorization string) string {
+	const prefix = "bearer "
+	if len(authorization) > len(prefix) && strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return authorization[len(prefix):]
+	}
+	return ""
+}
+
+// admit returns the account of the tenant of a request, identified by its
+// name, if a tenant header is configured and set, or by its API key, and
+// admits the request.
+func (s *Server) admit(apiKey, name string) (*tenancy.Account, error) {
+	var a *tenancy.Account
+	var err error
+	if name != "" {
+		a, err = s.conf.Tenants.ByName(name)
+	} else {
+		a, err = s.conf.Tenants.ByAPIKey(apiKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a, a.Admit()
+}
+
+// account serves the request of the tenant, recording the tokens it
+// processed, and identifying the tenant as its caller.
+func account[R any](ctx context.Context, a *tenancy.Account, f func(context.Context) (R, error)) (R, error) {
+	var c usage.Counter
+	defer func() {
+		a.Record(c.Tokens())
+	}()
+	return f(withCaller(usage.NewContext(ctx, &c), a.Name()))
+}
+
+// tenancyInterceptor admits the gRPC requests of the tenants, accounting
+// their usage. The health checks are open.
+func (s *Server) tenancyInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if s.conf.Tenants == nil || strings.HasPrefix(info.FullMethod, "/grpc.health.v1.Health/") {
+		return handler(ctx, req)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	a, err := s.admit(s.tenantCredentials(func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return account(ctx, a, func(ctx context.Context) (any, error) {
+		return handler(ctx, req)
+	})
+}
+
+// withTenancy admits the HTTP requests of the tenants, accounting their
+// usage.
+func (s *Server) withTenancy(h http.Handler) http.Handler {
+	if s.conf.Tenants == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a, err := s.admit(s.tenantCredentials(r.Header.Get))
+		if err != nil {
+			writeHTTPError(w, r, err)
+			return
+		}
+		_, _ = account(r.Context(), a, func(ctx context.Context) (any, error) {
+			h.ServeHTTP(w, r.WithContext(ctx))
+			return nil, nil
+		})
+	})
+}
+
+// withAdmin serves the usage of the tenants at /admin/tenants, the faults
+// injected at /admin/faults in the builds with the "chaos" tag, and the
+// admin handlers of the configuration, to the requests authorized with the
+// admin key, and the metrics of the tenants, with the other metrics of the
+// configuration, at /metrics.
+func (s *Server) withAdmin(h http.Handler) http.Handler {
+	metrics := s.conf.Metrics
+	if s.conf.Tenants != nil {
+		metrics = append([]MetricsWriter{s.conf.Tenants}, metrics...)
+	}
+	admin := s.conf.AdminKey != "" && (s.conf.Tenants != nil || faults != nil || len(s.conf.AdminHandlers) > 0)
+	if len(metrics) == 0 && !admin {
+		return h
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", h)
+	if len(metrics) > 0 {
+		mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+			for _, m := range metrics {
+				if err := m.WriteMetrics(w); err != nil {
+					log.Warn().Err(err).Msg("failed to write the metrics")
+					return
+				}
+			}
+		})
+	}
+	if !admin {
+		return mux
+	}
+	if s.conf.Tenants != nil {
+		mux.Handle("/admin/tenants", s.withAdminKey(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(map[string]any{"tenants": s.conf.Tenants.Usage()}); err != nil {
+				log.Warn().Err(err).Msg("failed to write the usage of the tenants")
+			}
+		})))
+	}
+	if faults != nil {
+		log.Warn().Msg("fault injection enabled at /admin/faults")
+		mux.Handle("/admin/faults", s.withAdminKey(faults))
+	}
+	for path, ah := range s.conf.AdminHandlers {
+		mux.Handle("/admin/"+strings.TrimPrefix(path, "/"), s.withAdminKey(ah))
+	}
+	return mux
+}
+
+// withAdminKey serves the requests authorized with the admin key only.
+func (s *Server) withAdminKey(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := bearerToken(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare([]byte(key), []byte(s.conf.AdminKey)) != 1 {
+			http.Error(w, "invalid admin key", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
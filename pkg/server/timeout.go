@@ -0,0 +1,72 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// timeoutHeader is the HTTP header, or gRPC metadata, setting the timeout of
+// a request, e.g. "2s", in addition to the deadline of the gRPC call.
+const timeoutHeader = "cybertron-timeout"
+
+// WithTimeout sets the maximum time to serve a request of the request
+// handler returned by ResolveRequestHandler, beyond which the requests fail
+// with DEADLINE_EXCEEDED, and returns it.
+func WithTimeout(rh RequestHandler, timeout time.Duration) RequestHandler {
+	if h, ok := rh.(interface{ shared() *sharedResponses }); ok {
+		h.shared().timeout = timeout
+	}
+	return rh
+}
+
+// parseTimeout parses the timeout of a request.
+func parseTimeout(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%w: invalid timeout %#v", errdefs.ErrInvalidRequest, s)
+	}
+	return d, nil
+}
+
+// timeoutInterceptor sets the timeout of the gRPC requests from their
+// metadata.
+func timeoutInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(timeoutHeader); len(v) > 0 {
+		d, err := parseTimeout(v[0])
+		if err != nil {
+			return nil, statusError(err)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+	return handler(ctx, req)
+}
+
+// withTimeout sets the timeout of the HTTP requests from their header.
+func withTimeout(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Header.Get(timeoutHeader); v != "" {
+			d, err := parseTimeout(v)
+			if err != nil {
+				writeHTTPError(w, r, err)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,189 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	text2textv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/text2text/v1"
+	text2textv2 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/text2text/v2"
+	textclassificationv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textclassification/v1"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseTimeout(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    time.Duration
+		wantErr bool
+	}{
+		{s: "2s", want: 2 * time.Second},
+		{s: "150ms", want: 150 * time.Millisecond},
+		{s: "0s", wantErr: true},
+		{s: "-1s", wantErr: true},
+		{s: "2", wantErr: true},
+		{s: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseTimeout(tt.s)
+		if tt.wantErr {
+			assert.ErrorIs(t, err, errdefs.ErrInvalidRequest, tt.s)
+			continue
+		}
+		require.NoError(t, err, tt.s)
+		assert.Equal(t, tt.want, got, tt.s)
+	}
+}
+
+// deadlineOf returns the time left before the deadline of the context, or
+// zero if it has none.
+func deadlineOf(ctx context.Context) time.Duration {
+	d, ok := ctx.Deadline()
+	if !ok {
+		return 0
+	}
+	return time.Until(d)
+}
+
+func TestTimeoutInterceptor(t *testing.T) {
+	tests := []struct {
+		name     string
+		md       metadata.MD
+		wantMin  time.Duration
+		wantCode codes.Code
+	}{
+		{name: "no timeout"},
+		{name: "timeout", md: metadata.Pairs(timeoutHeader, "1h"), wantMin: 59 * time.Minute},
+		{name: "invalid timeout", md: metadata.Pairs(timeoutHeader, "soon"), wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			var left time.Duration
+			_, err := timeoutInterceptor(ctx, nil, nil, func(ctx context.Context, _ any) (any, error) {
+				left = deadlineOf(ctx)
+				return nil, nil
+			})
+			if tt.wantCode != codes.OK {
+				assert.Equal(t, tt.wantCode, status.Code(err))
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantMin == 0 {
+				assert.Zero(t, left)
+				return
+			}
+			assert.Greater(t, left, tt.wantMin)
+		})
+	}
+}
+
+func TestWithTimeout_HTTP(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		wantMin    time.Duration
+		wantStatus int
+	}{
+		{name: "no timeout", wantStatus: http.StatusOK},
+		{name: "timeout", header: "1h", wantMin: 59 * time.Minute, wantStatus: http.StatusOK},
+		{name: "invalid timeout", header: "0s", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var left time.Duration
+			h := withTimeout(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				left = deadlineOf(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.header != "" {
+				r.Header.Set(timeoutHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantMin == 0 {
+				assert.Zero(t, left)
+				return
+			}
+			assert.Greater(t, left, tt.wantMin)
+		})
+	}
+}
+
+// waitingClassifier waits for the end of the requests.
+type waitingClassifier struct{}
+
+func (waitingClassifier) Classify(ctx context.Context, _ string, _ textclassification.Parameters) (textclassification.Response, error) {
+	<-ctx.Done()
+	return textclassification.Response{}, ctx.Err()
+}
+
+func TestWithTimeout(t *testing.T) {
+	rh := WithTimeout(NewServerForTextClassification(waitingClassifier{}), 10*time.Millisecond)
+	_, err := rh.(*serverForTextClassification).Classify(context.Background(), &textclassificationv1.ClassifyRequest{Input: "text"})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
+
+// timingOutGenerator times out on the "slow" inputs, with a partial text.
+type timingOutGenerator struct{}
+
+func (timingOutGenerator) Generate(_ context.Context, text string, _ *text2text.Options) (text2text.Response, error) {
+	if text == "slow" {
+		return text2text.Response{Texts: []string{"partial"}, Scores: []float64{0.5}},
+			fmt.Errorf("%w: %w", errdefs.ErrDecodingTimeout, context.DeadlineExceeded)
+	}
+	return text2text.Response{Texts: []string{text}, Scores: []float64{1}}, nil
+}
+
+func TestServerForTextGeneration_PartialOutput(t *testing.T) {
+	s := NewServerForTextGeneration(timingOutGenerator{}).(*serverForTextGeneration)
+	ctx := context.Background()
+
+	_, err := s.Generate(ctx, &text2textv2.GenerateRequest{Inputs: []string{"fast", "slow"}})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	for _, d := range status.Convert(err).Details() {
+		assert.IsType(t, &errdetails.ErrorInfo{}, d, "no partial output unless requested")
+	}
+
+	_, err = s.Generate(ctx, &text2textv2.GenerateRequest{Inputs: []string{"fast", "slow", "never"}, PartialOutput: true})
+	st := status.Convert(err)
+	assert.Equal(t, codes.DeadlineExceeded, st.Code())
+	var partial *text2textv2.GenerateResponse
+	for _, d := range st.Details() {
+		if resp, ok := d.(*text2textv2.GenerateResponse); ok {
+			partial = resp
+		}
+	}
+	require.NotNil(t, partial)
+	require.Len(t, partial.Generations, 2, "the inputs after the one timing out are not generated")
+	assert.Equal(t, []string{"fast"}, partial.Generations[0].Texts)
+	assert.Equal(t, []string{"partial"}, partial.Generations[1].Texts)
+
+	_, err = text2textV1{s: s}.Generate(ctx, &text2textv1.GenerateRequest{Input: "slow", PartialOutput: true})
+	st = status.Convert(err)
+	assert.Equal(t, codes.DeadlineExceeded, st.Code())
+	var partialV1 *text2textv1.GenerateResponse
+	for _, d := range st.Details() {
+		if resp, ok := d.(*text2textv1.GenerateResponse); ok {
+			partialV1 = resp
+		}
+	}
+	require.NotNil(t, partialV1, "the partial output is downgraded to the v1 response")
+	assert.Equal(t, []string{"partial"}, partialV1.Texts)
+}
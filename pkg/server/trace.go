@@ -0,0 +1,76 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	"github.com/nlpodyssey/cybertron/pkg/logging"
+	"github.com/nlpodyssey/cybertron/pkg/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// traceHeader is the HTTP header, or gRPC metadata, carrying the admin key
+// to trace the inference of a request: its tokens, attention masks and the
+// statistics of the hidden states of each layer of the model are logged,
+// and sent back as JSON in the header of the response, which the HTTP
+// gateway forwards as the Grpc-Metadata-Cybertron-Trace header.
+const traceHeader = "cybertron-trace"
+
+// traceContext returns the context tracing the request, if the key is the
+// admin key, or false otherwise.
+func (s *Server) traceContext(ctx context.Context, key string) (context.Context, bool) {
+	if s.conf.AdminKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(s.conf.AdminKey)) != 1 {
+		return ctx, false
+	}
+	return trace.NewContext(ctx, new(trace.Trace)), true
+}
+
+// traceInterceptor traces the gRPC requests carrying the admin key in
+// their metadata.
+func (s *Server) traceInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(traceHeader); len(v) > 0 && v[0] != "" {
+		var ok bool
+		if ctx, ok = s.traceContext(ctx, v[0]); !ok {
+			return nil, status.Error(codes.Unauthenticated, "invalid admin key")
+		}
+	}
+	return handler(ctx, req)
+}
+
+// withTrace traces the HTTP requests carrying the admin key in their
+// header.
+func (s *Server) withTrace(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Header.Get(traceHeader); v != "" {
+			ctx, ok := s.traceContext(r.Context(), v)
+			if !ok {
+				http.Error(w, "invalid admin key", http.StatusUnauthorized)
+				return
+			}
+			r = r.WithContext(ctx)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// reportTrace logs the trace of the request, and sets it in the header of
+// the response of the gRPC call, if any.
+func reportTrace(ctx context.Context, t *trace.Trace) {
+	data, err := json.Marshal(map[string]any{"inputs": t.Inputs()})
+	if err != nil {
+		logging.Ctx(ctx).Warn().Err(err).Msg("failed to encode the inference trace")
+		return
+	}
+	logging.Ctx(ctx).Info().RawJSON("trace", data).Msg("inference trace")
+	_ = grpc.SetHeader(ctx, metadata.Pairs(traceHeader, string(data)))
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	textclassificationv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textclassification/v1"
+	textclassificationv2 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textclassification/v2"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// pairClassifier labels the texts by their text pair, if any, or by their
+// length otherwise.
+type pairClassifier struct{}
+
+func (pairClassifier) Classify(_ context.Context, text string, parameters textclassification.Parameters) (textclassification.Response, error) {
+	if text == "" {
+		return textclassification.Response{}, errdefs.ErrInvalidRequest
+	}
+	label := parameters.TextPair
+	if label == "" {
+		label = strings.Repeat("x", len(text))
+	}
+	return textclassification.Response{Labels: []string{label}, Scores: []float64{1}}, nil
+}
+
+func TestTextClassificationV2(t *testing.T) {
+	s := NewServerForTextClassification(pairClassifier{}).(*serverForTextClassification)
+	v2 := textClassificationV2{s: s}
+	ctx := context.Background()
+
+	resp, err := v2.Classify(ctx, &textclassificationv2.ClassifyRequest{Inputs: []string{"a", "bb", "ccc"}})
+	require.NoError(t, err)
+	require.Len(t, resp.Classifications, 3)
+	for i, want := range []string{"x", "xx", "xxx"} {
+		assert.Equal(t, []string{want}, resp.Classifications[i].Labels)
+	}
+
+	resp, err = v2.Classify(ctx, &textclassificationv2.ClassifyRequest{
+		Inputs:    []string{"a", "b"},
+		TextPairs: []string{"first", "second"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first"}, resp.Classifications[0].Labels)
+	assert.Equal(t, []string{"second"}, resp.Classifications[1].Labels)
+
+	_, err = v2.Classify(ctx, &textclassificationv2.ClassifyRequest{
+		Inputs:    []string{"a", "b"},
+		TextPairs: []string{"first"},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = v2.Classify(ctx, &textclassificationv2.ClassifyRequest{Inputs: []string{"a", ""}})
+	assert.Error(t, err)
+
+	// Both versions are served by the same classifier.
+	v1, err := s.Classify(ctx, &textclassificationv1.ClassifyRequest{Input: "bb"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"xx"}, v1.Labels)
+}
+
+func TestRegisterServer_versions(t *testing.T) {
+	methods := make(methodRegistry)
+	require.NoError(t, NewServerForTextClassification(pairClassifier{}).RegisterServer(methods))
+	assert.Contains(t, methods, "textclassification.v1.TextClassificationService.Classify")
+	assert.Contains(t, methods, "textclassification.v2.TextClassificationService.Classify")
+}
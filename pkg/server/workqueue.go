@@ -0,0 +1,147 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/lora"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/scheduling"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
+	"github.com/nlpodyssey/cybertron/pkg/workqueue"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// The metadata of the rows of the work queue, besides the gRPC metadata of
+// their adapter, fields and timeout, and of their results.
+const (
+	// tenantMetadata is the tenant of the upload of a row, accounted by the
+	// frontend.
+	tenantMetadata = "cybertron-tenant"
+	// inputTokensMetadata and outputTokensMetadata are the tokens processed
+	// by a row, sent back to the frontend to account them to its tenant.
+	inputTokensMetadata  = "cybertron-input-tokens"
+	outputTokensMetadata = "cybertron-output-tokens"
+)
+
+// WorkQueueConfig is the configuration of the work queue shared by a fleet
+// of servers, to scale the batch workloads horizontally: the frontends push
+// the rows of their bulk uploads and async jobs to the queue, and aggregate
+// their results, while the workers serve them with their models.
+type WorkQueueConfig struct {
+	// Queue is the work queue; disabled if nil.
+	Queue workqueue.Queue
+	// Frontend makes the server a frontend of the queue, pushing the rows of
+	// all its bulk uploads and async jobs, whether its models serve their
+	// tasks or not, instead of a worker serving the rows of the queue.
+	Frontend bool
+	// Workers is the number of rows served concurrently by a worker
+	// (default 1).
+	Workers int
+}
+
+// queueMethod returns the name of the method of the task, by its name or
+// by the one of its method, without a model serving it, as the frontends
+// do.
+func queueMethod(task string) (string, bool) {
+	for name, t := range taskNames {
+		if t == task {
+			return name, true
+		}
+	}
+	if _, err := requestDescriptor(task); err != nil {
+		return "", false
+	}
+	return task, true
+}
+
+// queueCall returns the function serving the rows of the method through
+// the work queue, with the tenant, adapter, fields and timeout of the
+// upload, accounting the tokens of the rows to its tenant.
+func (s *Server) queueCall(name string) func(context.Context, []byte) []byte {
+	return func(ctx context.Context, data []byte) []byte {
+		res, err := s.queue.Call(ctx, name, data, queueMetadata(ctx))
+		if err != nil {
+			if ctx.Err() != nil {
+				return natsError(statusError(ctx.Err()))
+			}
+			return natsError(status.Errorf(codes.Unavailable, "work queue: %v", err))
+		}
+		input, _ := strconv.Atoi(res.Metadata[inputTokensMetadata])
+		output, _ := strconv.Atoi(res.Metadata[outputTokensMetadata])
+		usage.AddTokens(ctx, input, output)
+		return res.Data
+	}
+}
+
+// queueMetadata returns the metadata of the rows of the request of the
+// context.
+func queueMetadata(ctx context.Context) map[string]string {
+	md := make(map[string]string)
+	if tenant, ok := ctx.Value(callerKey{}).(string); ok {
+		md[tenantMetadata] = tenant
+	}
+	if adapter := lora.FromContext(ctx); adapter != "" {
+		md[adapterHeader] = adapter
+	}
+	if fields := fieldsFromContext(ctx); fields != "" {
+		md[fieldsHeader] = fields
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if d := time.Until(deadline); d > 0 {
+			md[timeoutHeader] = d.String()
+		}
+	}
+	return md
+}
+
+// serveTask serves the row of the work queue with the current generation,
+// its metadata being the one of the gRPC request, returning the tokens it
+// processed in the metadata of its result.
+func (s *Server) serveTask(ctx context.Context, t *workqueue.Task) ([]byte, map[string]string) {
+	md := make(metadata.MD, len(t.Metadata))
+	for key, value := range t.Metadata {
+		md.Set(key, value)
+	}
+	ctx = metadata.NewIncomingContext(ctx, md)
+	if tenant := t.Metadata[tenantMetadata]; tenant != "" {
+		ctx = withCaller(ctx, tenant)
+	}
+	var c usage.Counter
+	_, resp := s.callCurrent(usage.NewContext(ctx, &c), t.Method, t.Data, chainUnaryInterceptors(s.rowInterceptors()...))
+	input, output := c.Tokens()
+	return resp, map[string]string{
+		inputTokensMetadata:  strconv.FormatInt(input, 10),
+		outputTokensMetadata: strconv.FormatInt(output, 10),
+	}
+}
+
+// serveWorkQueue dispatches the results of the queue to the rows waiting
+// for them, on a frontend, or serves the rows of the queue with the current
+// generation, with the batch priority, on a worker, until the context is
+// done or the queue fails.
+func (s *Server) serveWorkQueue(ctx context.Context) error {
+	conf := s.conf.WorkQueue
+	defer conf.Queue.Close()
+	var err error
+	if s.queue != nil {
+		log.Info().Msg("serving as a frontend of the work queue")
+		err = s.queue.Run(ctx)
+	} else {
+		log.Info().Int("workers", conf.Workers).Msg("serving the work queue")
+		ctx = scheduling.NewContext(ctx, scheduling.Batch)
+		err = workqueue.Serve(ctx, conf.Queue, conf.Workers, s.serveTask)
+	}
+	if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
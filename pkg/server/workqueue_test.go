@@ -0,0 +1,127 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/lora"
+	textencodingv1 "github.com/nlpodyssey/cybertron/pkg/server/gen/proto/go/textencoding/v1"
+	"github.com/nlpodyssey/cybertron/pkg/tenancy"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
+	"github.com/nlpodyssey/cybertron/pkg/workqueue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// chanQueue is a work queue of channels, with a single inbox.
+type chanQueue struct {
+	tasks   chan workqueue.Task
+	results chan workqueue.Result
+}
+
+func (q *chanQueue) Push(_ context.Context, t workqueue.Task) error {
+	q.tasks <- t
+	return nil
+}
+
+func (q *chanQueue) Pull(ctx context.Context) (*workqueue.Task, error) {
+	select {
+	case t := <-q.tasks:
+		return &t, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (q *chanQueue) Complete(_ context.Context, t *workqueue.Task, result []byte, metadata map[string]string) error {
+	q.results <- workqueue.Result{ID: t.ID, Data: result, Metadata: metadata}
+	return nil
+}
+
+func (q *chanQueue) Inbox() string {
+	return "inbox"
+}
+
+func (q *chanQueue) Result(ctx context.Context) (workqueue.Result, error) {
+	select {
+	case r := <-q.results:
+		return r, nil
+	case <-ctx.Done():
+		return workqueue.Result{}, ctx.Err()
+	}
+}
+
+func (q *chanQueue) Close() error {
+	return nil
+}
+
+func TestQueueMetadata(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, queueMetadata(ctx))
+
+	ctx = withCaller(lora.NewContext(contextWithFields(ctx, "vector"), "legal"), "acme")
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	md := queueMetadata(ctx)
+	timeout, err := parseTimeout(md[timeoutHeader])
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute, timeout, float64(time.Second))
+	delete(md, timeoutHeader)
+	assert.Equal(t, map[string]string{tenantMetadata: "acme", adapterHeader: "legal", fieldsHeader: "vector"}, md)
+}
+
+func TestQueueCall(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	q := &chanQueue{tasks: make(chan workqueue.Task, 1), results: make(chan workqueue.Result, 1)}
+
+	// The worker replies with the lengths of the adapter and of the caller
+	// of the row, and with whether it has a deadline.
+	worker := &Server{conf: &Config{}, ctx: ctx}
+	encode := func(_ any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		req := &textencodingv1.EncodingRequest{}
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		info := &grpc.UnaryServerInfo{FullMethod: "/textencoding.v1.TextEncodingService/Encode"}
+		return interceptor(ctx, req, info, func(ctx context.Context, _ any) (any, error) {
+			usage.AddTokens(ctx, len(req.Input), 1)
+			var deadline float32
+			if _, ok := ctx.Deadline(); ok {
+				deadline = 1
+			}
+			return &textencodingv1.EncodingResponse{Vector: []float32{float32(len(lora.FromContext(ctx))), float32(len(caller(ctx))), deadline}}, nil
+		})
+	}
+	worker.current.Store(&generation{methods: methodRegistry{
+		"textencoding.v1.TextEncodingService.Encode": {handler: encode},
+	}})
+	go func() { _ = workqueue.Serve(ctx, q, 1, worker.serveTask) }()
+
+	tenants, err := tenancy.New([]tenancy.Tenant{{Name: "acme", APIKeys: []string{"key"}}})
+	require.NoError(t, err)
+	a, err := tenants.ByName("acme")
+	require.NoError(t, err)
+	frontend := &Server{conf: &Config{Tenants: tenants}, queue: workqueue.NewClient(q)}
+	go func() { _ = frontend.queue.Run(ctx) }()
+
+	call := frontend.queueCall("textencoding.v1.TextEncodingService.Encode")
+	data, err := account(lora.NewContext(ctx, "legal"), a, func(ctx context.Context) ([]byte, error) {
+		return call(ctx, []byte(`{"input": "abc"}`)), nil
+	})
+	require.NoError(t, err)
+	var resp textencodingv1.EncodingResponse
+	require.NoError(t, json.Unmarshal(data, &resp), string(data))
+	assert.Equal(t, []float32{5, 4, 1}, resp.Vector)
+
+	u := a.Usage()
+	assert.EqualValues(t, 3, u.InputTokens)
+	assert.EqualValues(t, 1, u.OutputTokens)
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// azureAPIVersion is the version of the Blob service REST API.
+const azureAPIVersion = "2021-08-06"
+
+// AzureStore is an Azure Blob Storage store, authorized with a shared
+// access signature (SAS).
+type AzureStore struct {
+	endpoint  string
+	container string
+	prefix    string
+	sasToken  url.Values
+	client    *http.Client
+}
+
+var _ Store = &AzureStore{}
+
+// OpenAzure returns a new AzureStore for the container of the storage
+// account, with keys under the prefix.
+//
+// The SAS token is read from AZURE_STORAGE_SAS_TOKEN. It must grant the
+// list and read permissions to pull models, and the write one to push them.
+func OpenAzure(account, container, prefix string) (*AzureStore, error) {
+	token := strings.TrimPrefix(os.Getenv("AZURE_STORAGE_SAS_TOKEN"), "?")
+	if token == "" {
+		return nil, errors.New("storage: missing AZURE_STORAGE_SAS_TOKEN")
+	}
+	sas, err := url.ParseQuery(token)
+	if err != nil {
+		return nil, fmt.Errorf("storage: invalid AZURE_STORAGE_SAS_TOKEN: %w", err)
+	}
+	if container == "" {
+		return nil, errors.New("storage: missing Azure container name")
+	}
+	return &AzureStore{
+		endpoint:  fmt.Sprintf("https://%s.blob.core.windows.net", account),
+		container: container,
+		prefix:    prefix,
+		sasToken:  sas,
+		client:    http.DefaultClient,
+	}, nil
+}
+
+// List returns the objects whose key starts with the given prefix.
+func (s *AzureStore) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	marker := ""
+	for {
+		query := url.Values{"restype": {"container"}, "comp": {"list"}, "prefix": {joinKey(s.prefix, prefix)}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		resp, err := s.do(ctx, "GET", "", query, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Blobs struct {
+				Blob []struct {
+					Name       string
+					Properties struct {
+						ContentLength int64 `xml:"Content-Length"`
+					}
+				}
+			}
+			NextMarker string
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("storage: invalid Azure list response: %w", err)
+		}
+		for _, b := range result.Blobs.Blob {
+			objects = append(objects, Object{Key: trimKey(s.prefix, b.Name), Size: b.Properties.ContentLength})
+		}
+		if result.NextMarker == "" {
+			return objects, nil
+		}
+		marker = result.NextMarker
+	}
+}
+
+// Get writes the content of an object to w.
+func (s *AzureStore) Get(ctx context.Context, key string, w io.Writer) error {
+	resp, err := s.do(ctx, "GET", joinKey(s.prefix, key), nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Put creates or replaces an object with size bytes read from r.
+// Objects are uploaded as single block blobs, up to 5000 MiB.
+func (s *AzureStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	resp, err := s.do(ctx, "PUT", joinKey(s.prefix, key), nil, r, size)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (s *AzureStore) do(ctx context.Context, method, key string, query url.Values, body io.Reader, size int64) (*http.Response, error) {
+	u := fmt.Sprintf("%s/%s", s.endpoint, url.PathEscape(s.container))
+	if key != "" {
+		u += "/" + escapePath(key)
+	}
+	q := url.Values{}
+	for k, v := range s.sasToken {
+		q[k] = v
+	}
+	for k, v := range query {
+		q[k] = v
+	}
+	u += "?" + q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	if body != nil {
+		req.ContentLength = size
+		req.Header.Set("x-ms-blob-type", "BlockBlob")
+	}
+	return checkResponse(s.client.Do(req))
+}
@@ -0,0 +1,93 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileStore is a store on the local filesystem, e.g. a network share.
+type FileStore struct {
+	root string
+}
+
+var _ Store = &FileStore{}
+
+// NewFileStore returns a new FileStore rooted at the given directory.
+func NewFileStore(root string) *FileStore {
+	return &FileStore{root: root}
+}
+
+// List returns the objects whose key starts with the given prefix.
+func (s *FileStore) List(_ context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	err := filepath.WalkDir(s.root, func(p string, d fs.DirEntry, err error) error {
+		if os.IsNotExist(err) {
+			return fs.SkipDir
+		}
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{Key: key, Size: info.Size()})
+		return nil
+	})
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, err
+}
+
+// Get writes the content of an object to w.
+func (s *FileStore) Get(_ context.Context, key string, w io.Writer) error {
+	f, err := os.Open(s.filename(key))
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Put creates or replaces an object with size bytes read from r.
+func (s *FileStore) Put(_ context.Context, key string, r io.Reader, size int64) (err error) {
+	filename := s.filename(key)
+	if err = os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := f.Close(); e != nil && err == nil {
+			err = e
+		}
+	}()
+	_, err = io.CopyN(f, r, size)
+	return err
+}
+
+func (s *FileStore) filename(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+// gcsEndpoint is the endpoint of the Google Cloud Storage JSON API.
+const gcsEndpoint = "https://storage.googleapis.com"
+
+// GCSStore is a Google Cloud Storage store, accessed with the JSON API.
+type GCSStore struct {
+	endpoint    string
+	bucket      string
+	prefix      string
+	accessToken string
+	client      *http.Client
+}
+
+var _ Store = &GCSStore{}
+
+// OpenGCS returns a new GCSStore for the bucket, with keys under the prefix.
+//
+// The OAuth 2.0 access token is read from GOOGLE_OAUTH_ACCESS_TOKEN (e.g.
+// the output of `gcloud auth print-access-token`, or a token obtained from
+// the metadata server of the instance).
+func OpenGCS(bucket, prefix string) (*GCSStore, error) {
+	s := &GCSStore{
+		endpoint:    gcsEndpoint,
+		bucket:      bucket,
+		prefix:      prefix,
+		accessToken: os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"),
+		client:      http.DefaultClient,
+	}
+	if s.accessToken == "" {
+		return nil, errors.New("storage: missing GOOGLE_OAUTH_ACCESS_TOKEN")
+	}
+	return s, nil
+}
+
+// List returns the objects whose key starts with the given prefix.
+func (s *GCSStore) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	token := ""
+	for {
+		query := url.Values{"prefix": {joinKey(s.prefix, prefix)}, "fields": {"items(name,size),nextPageToken"}}
+		if token != "" {
+			query.Set("pageToken", token)
+		}
+		u := fmt.Sprintf("%s/storage/v1/b/%s/o?%s", s.endpoint, url.PathEscape(s.bucket), query.Encode())
+		resp, err := s.do(ctx, "GET", u, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Items []struct {
+				Name string `json:"name"`
+				Size string `json:"size"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("storage: invalid GCS list response: %w", err)
+		}
+		for _, item := range result.Items {
+			size, err := strconv.ParseInt(item.Size, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("storage: invalid size of GCS object %#v", item.Name)
+			}
+			objects = append(objects, Object{Key: trimKey(s.prefix, item.Name), Size: size})
+		}
+		if result.NextPageToken == "" {
+			return objects, nil
+		}
+		token = result.NextPageToken
+	}
+}
+
+// Get writes the content of an object to w.
+func (s *GCSStore) Get(ctx context.Context, key string, w io.Writer) error {
+	u := fmt.Sprintf("%s/storage/v1/b/%s/o/%s?alt=media", s.endpoint, url.PathEscape(s.bucket), url.PathEscape(joinKey(s.prefix, key)))
+	resp, err := s.do(ctx, "GET", u, nil, 0)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Put creates or replaces an object with size bytes read from r.
+func (s *GCSStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	query := url.Values{"uploadType": {"media"}, "name": {joinKey(s.prefix, key)}}
+	u := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?%s", s.endpoint, url.PathEscape(s.bucket), query.Encode())
+	resp, err := s.do(ctx, "POST", u, r, size)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (s *GCSStore) do(ctx context.Context, method, u string, body io.Reader, size int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	req.Header.Set("Authorization", "Bearer "+s.accessToken)
+	return checkResponse(s.client.Do(req))
+}
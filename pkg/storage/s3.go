@@ -0,0 +1,266 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// unsignedPayload is used as payload hash of the uploads, so that they can
+// be streamed without reading the content twice.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Store is an Amazon S3, or S3-compatible, store. Requests are signed
+// with AWS Signature Version 4 and use path-style URLs.
+type S3Store struct {
+	endpoint        string
+	region          string
+	bucket          string
+	prefix          string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	client          *http.Client
+}
+
+var _ Store = &S3Store{}
+
+// OpenS3 returns a new S3Store for the bucket, with keys under the prefix.
+//
+// The credentials are read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY
+// and AWS_SESSION_TOKEN (optional), the region from AWS_REGION (default
+// "us-east-1"). AWS_ENDPOINT_URL sets the endpoint of S3-compatible
+// services, e.g. MinIO.
+func OpenS3(bucket, prefix string) (*S3Store, error) {
+	s := &S3Store{
+		endpoint:        os.Getenv("AWS_ENDPOINT_URL"),
+		region:          os.Getenv("AWS_REGION"),
+		bucket:          bucket,
+		prefix:          prefix,
+		accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		client:          http.DefaultClient,
+	}
+	if s.accessKeyID == "" || s.secretAccessKey == "" {
+		return nil, errors.New("storage: missing AWS_ACCESS_KEY_ID or AWS_SECRET_ACCESS_KEY")
+	}
+	if s.region == "" {
+		s.region = "us-east-1"
+	}
+	if s.endpoint == "" {
+		s.endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", s.region)
+	}
+	s.endpoint = strings.TrimSuffix(s.endpoint, "/")
+	return s, nil
+}
+
+// List returns the objects whose key starts with the given prefix.
+func (s *S3Store) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {joinKey(s.prefix, prefix)}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := s.do(ctx, "GET", "", query, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Contents []struct {
+				Key  string
+				Size int64
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("storage: invalid S3 list response: %w", err)
+		}
+		for _, c := range result.Contents {
+			objects = append(objects, Object{Key: trimKey(s.prefix, c.Key), Size: c.Size})
+		}
+		if !result.IsTruncated {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Get writes the content of an object to w.
+func (s *S3Store) Get(ctx context.Context, key string, w io.Writer) error {
+	resp, err := s.do(ctx, "GET", joinKey(s.prefix, key), nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Put creates or replaces an object with size bytes read from r.
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	resp, err := s.do(ctx, "PUT", joinKey(s.prefix, key), nil, r, size)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (s *S3Store) do(ctx context.Context, method, key string, query url.Values, body io.Reader, size int64) (*http.Response, error) {
+	u := fmt.Sprintf("%s/%s", s.endpoint, s.bucket)
+	if key != "" {
+		u += "/" + escapePath(key)
+	}
+	if len(query) > 0 {
+		u += "?" + canonicalQuery(query)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.sign(req, time.Now().UTC())
+	return checkResponse(s.client.Do(req))
+}
+
+// sign signs the request with AWS Signature Version 4.
+func (s *S3Store) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := unsignedPayload
+	if req.Body == nil {
+		payloadHash = hex.EncodeToString(sha256Sum(nil))
+	}
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-") {
+			headers[lk] = strings.TrimSpace(v[0])
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, s.region)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(sha256Sum([]byte(canonicalRequest))),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretAccessKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Sum(data []byte) []byte {
+	h := sha256.Sum256(data)
+	return h[:]
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery encodes the query as required by the signature: sorted by
+// key, with spaces encoded as "%20".
+func canonicalQuery(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+// escapePath escapes each segment of a slash-separated key, encoding all
+// the characters but the unreserved ones, as required by the signature.
+func escapePath(key string) string {
+	var sb strings.Builder
+	for _, b := range []byte(key) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~', b == '/':
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "/" + key
+}
+
+func trimKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, prefix+"/")
+}
+
+// checkResponse turns the error responses into errors.
+func checkResponse(resp *http.Response, err error) (*http.Response, error) {
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, resp.Request.URL.Path)
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("storage: %s %s responded with %s: %s", resp.Request.Method, resp.Request.URL.Path, resp.Status, msg)
+}
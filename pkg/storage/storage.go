@@ -0,0 +1,164 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package storage implements the stores where converted models can be
+// pulled from and pushed to, so that a fleet of servers can share the same
+// models without converting them on each node.
+//
+// The supported stores are the local filesystem ("file:///path/to/dir"),
+// Amazon S3 and S3-compatible services ("s3://bucket/prefix"), Google Cloud
+// Storage ("gs://bucket/prefix") and Azure Blob Storage
+// ("az://account/container/prefix"). The credentials of the cloud stores
+// are read from the environment, as described by each Open function.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Object is an object of a store.
+type Object struct {
+	// Key is the slash-separated key of the object, relative to the root of the store.
+	Key string
+	// Size is the size in bytes.
+	Size int64
+}
+
+// Store is a store of objects.
+type Store interface {
+	// List returns the objects whose key starts with the given prefix.
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// Get writes the content of an object to w.
+	Get(ctx context.Context, key string, w io.Writer) error
+	// Put creates or replaces an object with size bytes read from r.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+}
+
+// Open returns the store for the given URL.
+func Open(rawURL string) (Store, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("storage: invalid URL %#v: %w", rawURL, err)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	switch u.Scheme {
+	case "file":
+		return NewFileStore(filepath.FromSlash(u.Path)), nil
+	case "s3":
+		return OpenS3(u.Host, prefix)
+	case "gs":
+		return OpenGCS(u.Host, prefix)
+	case "az":
+		container, prefix, _ := strings.Cut(prefix, "/")
+		return OpenAzure(u.Host, container, prefix)
+	default:
+		return nil, fmt.Errorf("storage: unsupported URL scheme %#v", u.Scheme)
+	}
+}
+
+// excludedFiles are the files of the model directory which are not pushed:
+// the original checkpoints, which are only needed for the conversion, and
+// the local usage record.
+var excludedFiles = map[string]bool{
+	"pytorch_model.bin": true,
+	"model.safetensors": true,
+	".last_used":        true,
+}
+
+// Pull downloads the objects of a model, stored under the model name, to
+// the model directory. Files with the same size as the object are skipped.
+// It returns the number of objects of the model in the store, which is
+// zero if the model is not there.
+func Pull(ctx context.Context, s Store, modelName, modelDir string) (int, error) {
+	prefix := strings.Trim(modelName, "/") + "/"
+	objects, err := s.List(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+	for _, obj := range objects {
+		rel := strings.TrimPrefix(obj.Key, prefix)
+		if rel == "" || rel != path.Clean(rel) || strings.HasPrefix(rel, "../") {
+			return 0, fmt.Errorf("storage: invalid object key %#v", obj.Key)
+		}
+		filename := filepath.Join(modelDir, filepath.FromSlash(rel))
+		if info, err := os.Stat(filename); err == nil && info.Size() == obj.Size {
+			continue
+		}
+		log.Debug().Str("key", obj.Key).Str("destination", filename).Msg("pulling")
+		if err := getFile(ctx, s, obj.Key, filename); err != nil {
+			return 0, err
+		}
+	}
+	return len(objects), nil
+}
+
+func getFile(ctx context.Context, s Store, key, filename string) (err error) {
+	if err = os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	tmp := filename + ".incomplete"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := f.Close(); e != nil && err == nil {
+			err = e
+		}
+		if err != nil {
+			_ = os.Remove(tmp)
+			return
+		}
+		err = os.Rename(tmp, filename)
+	}()
+	return s.Get(ctx, key, f)
+}
+
+// Push uploads the files of the model directory to the store, under the
+// model name. The original checkpoints are not uploaded.
+func Push(ctx context.Context, s Store, modelName, modelDir string) error {
+	prefix := strings.Trim(modelName, "/") + "/"
+	return filepath.WalkDir(modelDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(modelDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if excludedFiles[rel] || strings.HasSuffix(rel, ".incomplete") {
+			return nil
+		}
+		log.Debug().Str("file", p).Str("key", prefix+rel).Msg("pushing")
+		return putFile(ctx, s, prefix+rel, p)
+	})
+}
+
+func putFile(ctx context.Context, s Store, key, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, key, f, info.Size())
+}
+
+// ErrNotFound is returned when an object doesn't exist.
+var ErrNotFound = errors.New("storage: object not found")
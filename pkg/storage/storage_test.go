@@ -0,0 +1,104 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushAndPull(t *testing.T) {
+	ctx := context.Background()
+	modelDir := t.TempDir()
+	files := map[string]string{
+		"config.json":       `{"model_type": "bert"}`,
+		"spago_model.bin":   "weights",
+		"repo/tokens/000":   "embeddings",
+		"pytorch_model.bin": "original weights",
+	}
+	for name, content := range files {
+		p := filepath.Join(modelDir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	}
+
+	store, err := Open("file://" + filepath.ToSlash(t.TempDir()))
+	require.NoError(t, err)
+
+	n, err := Pull(ctx, store, "org/model", t.TempDir())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	require.NoError(t, Push(ctx, store, "org/model", modelDir))
+	objects, err := store.List(ctx, "org/")
+	require.NoError(t, err)
+	assert.Equal(t, []Object{
+		{Key: "org/model/config.json", Size: 22},
+		{Key: "org/model/repo/tokens/000", Size: 10},
+		{Key: "org/model/spago_model.bin", Size: 7},
+	}, objects)
+
+	dest := t.TempDir()
+	n, err = Pull(ctx, store, "org/model", dest)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	data, err := os.ReadFile(filepath.Join(dest, "repo", "tokens", "000"))
+	require.NoError(t, err)
+	assert.Equal(t, "embeddings", string(data))
+	assert.NoFileExists(t, filepath.Join(dest, "pytorch_model.bin"))
+}
+
+func TestS3Store(t *testing.T) {
+	var uploaded string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=key/") || r.Header.Get("X-Amz-Date") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/bucket":
+			assert.Equal(t, "models/org/", r.URL.Query().Get("prefix"))
+			_, _ = io.WriteString(w, `<ListBucketResult><Contents><Key>models/org/model/config.json</Key><Size>2</Size></Contents><IsTruncated>false</IsTruncated></ListBucketResult>`)
+		case r.Method == "GET" && r.URL.Path == "/bucket/models/org/model/config.json":
+			_, _ = io.WriteString(w, "{}")
+		case r.Method == "PUT" && r.URL.Path == "/bucket/models/org/model/vocab.txt":
+			data, _ := io.ReadAll(r.Body)
+			uploaded = string(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+
+	t.Setenv("AWS_ENDPOINT_URL", s.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	store, err := Open("s3://bucket/models")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	objects, err := store.List(ctx, "org/")
+	require.NoError(t, err)
+	assert.Equal(t, []Object{{Key: "org/model/config.json", Size: 2}}, objects)
+
+	var sb strings.Builder
+	require.NoError(t, store.Get(ctx, "org/model/config.json", &sb))
+	assert.Equal(t, "{}", sb.String())
+
+	require.NoError(t, store.Put(ctx, "org/model/vocab.txt", strings.NewReader("[PAD]"), 5))
+	assert.Equal(t, "[PAD]", uploaded)
+
+	assert.ErrorIs(t, store.Get(ctx, "org/model/missing", &sb), ErrNotFound)
+}
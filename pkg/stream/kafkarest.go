@@ -0,0 +1,214 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// kafkaJSONType is the content type of the requests of the Kafka REST
+	// Proxy API v2, without records.
+	kafkaJSONType = "application/vnd.kafka.v2+json"
+	// kafkaBinaryType is the content type of the records, whose keys and
+	// values are base64 encoded.
+	kafkaBinaryType = "application/vnd.kafka.binary.v2+json"
+)
+
+// KafkaOptions are the options of the Kafka consumers and producers.
+type KafkaOptions struct {
+	// Client is the HTTP client (default http.DefaultClient).
+	Client *http.Client
+	// FetchTimeout is the maximum time the proxy waits for records on each
+	// fetch (default 1s).
+	FetchTimeout time.Duration
+	// MaxBytes is the maximum size of the records of each fetch (optional).
+	MaxBytes int
+}
+
+func (o KafkaOptions) client() *http.Client {
+	if o.Client != nil {
+		return o.Client
+	}
+	return http.DefaultClient
+}
+
+// KafkaConsumer is a consumer of a Kafka consumer group, through a Kafka
+// REST Proxy (API v2). The offsets are committed explicitly.
+type KafkaConsumer struct {
+	opts    KafkaOptions
+	baseURI string
+}
+
+// NewKafkaConsumer creates the consumer instance in the consumer group, on
+// the Kafka REST Proxy at the given URL, and subscribes it to the topics.
+// The instances of the same group share the partitions of the topics, so
+// that the messages are consumed in parallel.
+func NewKafkaConsumer(ctx context.Context, proxyURL, group string, topics []string, opts KafkaOptions) (*KafkaConsumer, error) {
+	var created struct {
+		BaseURI string `json:"base_uri"`
+	}
+	u := fmt.Sprintf("%s/consumers/%s", strings.TrimSuffix(proxyURL, "/"), url.PathEscape(group))
+	err := kafkaDo(ctx, opts.client(), "POST", u, kafkaJSONType, map[string]string{
+		"format":             "binary",
+		"auto.offset.reset":  "earliest",
+		"auto.commit.enable": "false",
+	}, &created)
+	if err != nil {
+		return nil, fmt.Errorf("kafka: failed to create the consumer: %w", err)
+	}
+	c := &KafkaConsumer{opts: opts, baseURI: created.BaseURI}
+	err = kafkaDo(ctx, opts.client(), "POST", c.baseURI+"/subscription", kafkaJSONType, map[string][]string{"topics": topics}, nil)
+	if err != nil {
+		_ = c.Close()
+		return nil, fmt.Errorf("kafka: failed to subscribe to %v: %w", topics, err)
+	}
+	return c, nil
+}
+
+// kafkaRecord is a record of the Kafka REST Proxy API, with binary key and value.
+type kafkaRecord struct {
+	Topic     string `json:"topic,omitempty"`
+	Partition int32  `json:"partition,omitempty"`
+	Offset    int64  `json:"offset,omitempty"`
+	Key       []byte `json:"key"`
+	Value     []byte `json:"value"`
+}
+
+// Fetch returns the next records.
+func (c *KafkaConsumer) Fetch(ctx context.Context) ([]Message, error) {
+	timeout := c.opts.FetchTimeout
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	q := url.Values{"timeout": {fmt.Sprint(timeout.Milliseconds())}}
+	if c.opts.MaxBytes > 0 {
+		q.Set("max_bytes", fmt.Sprint(c.opts.MaxBytes))
+	}
+	var records []kafkaRecord
+	if err := kafkaDo(ctx, c.opts.client(), "GET", c.baseURI+"/records?"+q.Encode(), kafkaBinaryType, nil, &records); err != nil {
+		return nil, err
+	}
+	messages := make([]Message, len(records))
+	for i, r := range records {
+		messages[i] = Message{Topic: r.Topic, Partition: r.Partition, Offset: r.Offset, Key: r.Key, Value: r.Value}
+	}
+	return messages, nil
+}
+
+// Commit commits, for each partition, the highest offset of the messages.
+func (c *KafkaConsumer) Commit(ctx context.Context, messages []Message) error {
+	type partition struct {
+		topic     string
+		partition int32
+	}
+	offsets := make(map[partition]int64)
+	for _, m := range messages {
+		p := partition{m.Topic, m.Partition}
+		if o, ok := offsets[p]; !ok || m.Offset > o {
+			offsets[p] = m.Offset
+		}
+	}
+	type offset struct {
+		Topic     string `json:"topic"`
+		Partition int32  `json:"partition"`
+		Offset    int64  `json:"offset"`
+	}
+	body := struct {
+		Offsets []offset `json:"offsets"`
+	}{}
+	for p, o := range offsets {
+		body.Offsets = append(body.Offsets, offset{Topic: p.topic, Partition: p.partition, Offset: o})
+	}
+	return kafkaDo(ctx, c.opts.client(), "POST", c.baseURI+"/offsets", kafkaJSONType, body, nil)
+}
+
+// Close deletes the consumer instance, leaving the consumer group.
+func (c *KafkaConsumer) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return kafkaDo(ctx, c.opts.client(), "DELETE", c.baseURI, kafkaJSONType, nil, nil)
+}
+
+// KafkaProducer produces messages through a Kafka REST Proxy (API v2).
+type KafkaProducer struct {
+	opts     KafkaOptions
+	proxyURL string
+}
+
+// NewKafkaProducer returns a producer to the Kafka REST Proxy at the given URL.
+func NewKafkaProducer(proxyURL string, opts KafkaOptions) *KafkaProducer {
+	return &KafkaProducer{opts: opts, proxyURL: strings.TrimSuffix(proxyURL, "/")}
+}
+
+// Produce appends the messages to the topic, failing if any of them is not.
+func (p *KafkaProducer) Produce(ctx context.Context, topic string, messages []Message) error {
+	records := make([]kafkaRecord, len(messages))
+	for i, m := range messages {
+		records[i] = kafkaRecord{Key: m.Key, Value: m.Value}
+	}
+	var resp struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	u := fmt.Sprintf("%s/topics/%s", p.proxyURL, url.PathEscape(topic))
+	err := kafkaDo(ctx, p.opts.client(), "POST", u, kafkaBinaryType, map[string][]kafkaRecord{"records": records}, &resp)
+	if err != nil {
+		return err
+	}
+	for _, o := range resp.Offsets {
+		if o.ErrorCode != nil {
+			return fmt.Errorf("kafka: failed to produce to %#v: %s (error code %d)", topic, o.Error, *o.ErrorCode)
+		}
+	}
+	return nil
+}
+
+// kafkaDo sends a request to the Kafka REST Proxy, encoding the body, if
+// any, and decoding the response into out, if not nil.
+func kafkaDo(ctx context.Context, client *http.Client, method, u, contentType string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", contentType)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&e)
+		return fmt.Errorf("%s %s responded with %s: %s", method, u, resp.Status, e.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
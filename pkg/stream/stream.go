@@ -0,0 +1,149 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package stream runs a task over the messages of a stream, e.g. a Kafka
+// topic, producing the results to another one, with at-least-once
+// semantics: the messages consumed are committed only once their results
+// are produced.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Message is a message of a stream.
+type Message struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+}
+
+// Consumer consumes the messages of a stream.
+type Consumer interface {
+	// Fetch returns the next messages, possibly none.
+	Fetch(ctx context.Context) ([]Message, error)
+	// Commit marks the messages as processed, so that they're not fetched
+	// again by the consumer group.
+	Commit(ctx context.Context, messages []Message) error
+	// Close leaves the consumer group.
+	Close() error
+}
+
+// Producer produces messages to a stream.
+type Producer interface {
+	// Produce appends the messages to the topic.
+	Produce(ctx context.Context, topic string, messages []Message) error
+}
+
+// Func processes an input.
+type Func func(ctx context.Context, input string) (any, error)
+
+// Options are the options of Run.
+type Options struct {
+	// OutputTopic is the topic the results are produced to.
+	OutputTopic string
+	// Parallelism is the number of messages processed concurrently, of
+	// each fetched batch (default 1).
+	Parallelism int
+}
+
+// Run fetches the messages from the consumer, processes them with the
+// function, and produces the results to the output topic, until the
+// context is done or an error occurs.
+//
+// The value of a message is either a JSON object with the input in its
+// "input" field, or the input itself. Each result is produced with the key
+// of the input message, as the JSON object of the input, or {"input": ...}
+// for a plain text input, with the result in the "output" field or, if the
+// processing failed, the error in the "error" field, so that a message
+// which can't be processed doesn't block the stream.
+//
+// The messages of each batch are committed only once all their results are
+// produced: in case of failure, they're processed again, possibly producing
+// the same results more than once.
+func Run(ctx context.Context, c Consumer, p Producer, f Func, opts Options) error {
+	if opts.Parallelism < 1 {
+		opts.Parallelism = 1
+	}
+	for ctx.Err() == nil {
+		messages, err := c.Fetch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return fmt.Errorf("stream: fetch: %w", err)
+		}
+		if len(messages) == 0 {
+			continue
+		}
+		results, err := processAll(ctx, f, messages, opts.Parallelism)
+		if err != nil {
+			break // interrupted: the batch is processed again on restart
+		}
+		if err := p.Produce(ctx, opts.OutputTopic, results); err != nil {
+			return fmt.Errorf("stream: produce: %w", err)
+		}
+		if err := c.Commit(ctx, messages); err != nil {
+			return fmt.Errorf("stream: commit: %w", err)
+		}
+	}
+	return ctx.Err()
+}
+
+// processAll processes the messages concurrently, returning the results in
+// the same order. It fails only if the context is done.
+func processAll(ctx context.Context, f Func, messages []Message, parallelism int) ([]Message, error) {
+	results := make([]Message, len(messages))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, m := range messages {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, m Message) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = Message{Key: m.Key, Value: process(ctx, f, m.Value)}
+		}(i, m)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// process processes the value of a message, returning the value of the
+// result message.
+func process(ctx context.Context, f Func, value []byte) []byte {
+	var fields map[string]any
+	input, ok := "", false
+	if json.Unmarshal(value, &fields) == nil {
+		input, ok = fields["input"].(string)
+	}
+	if !ok {
+		input = string(value)
+		fields = map[string]any{"input": input}
+	}
+
+	out, err := f(ctx, input)
+	if err == nil {
+		fields["output"] = out
+	} else {
+		fields["error"] = err.Error()
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		delete(fields, "output")
+		fields["error"] = err.Error()
+		data, _ = json.Marshal(fields)
+	}
+	return data
+}
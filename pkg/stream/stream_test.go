@@ -0,0 +1,147 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConsumer returns the batches, then cancels the context.
+type fakeConsumer struct {
+	batches   [][]Message
+	committed []Message
+	cancel    context.CancelFunc
+}
+
+func (c *fakeConsumer) Fetch(context.Context) ([]Message, error) {
+	if len(c.batches) == 0 {
+		c.cancel()
+		return nil, context.Canceled
+	}
+	b := c.batches[0]
+	c.batches = c.batches[1:]
+	return b, nil
+}
+
+func (c *fakeConsumer) Commit(_ context.Context, messages []Message) error {
+	c.committed = append(c.committed, messages...)
+	return nil
+}
+
+func (c *fakeConsumer) Close() error { return nil }
+
+type fakeProducer struct {
+	mu       sync.Mutex
+	produced []Message
+	err      error
+}
+
+func (p *fakeProducer) Produce(_ context.Context, topic string, messages []Message) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, m := range messages {
+		m.Topic = topic
+		p.produced = append(p.produced, m)
+	}
+	return nil
+}
+
+func upper(_ context.Context, input string) (any, error) {
+	if input == "" {
+		return nil, errors.New("empty input")
+	}
+	return strings.ToUpper(input), nil
+}
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &fakeConsumer{cancel: cancel, batches: [][]Message{
+		{{Offset: 0, Key: []byte("a"), Value: []byte("hello")}, {Offset: 1, Value: []byte(`{"id": 7, "input": "world"}`)}},
+		{{Offset: 2, Value: []byte("")}},
+	}}
+	p := &fakeProducer{}
+	err := Run(ctx, c, p, upper, Options{OutputTopic: "out", Parallelism: 2})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	require.Len(t, p.produced, 3)
+	assert.Equal(t, Message{Topic: "out", Key: []byte("a"), Value: []byte(`{"input":"hello","output":"HELLO"}`)}, p.produced[0])
+	assert.JSONEq(t, `{"id": 7, "input": "world", "output": "WORLD"}`, string(p.produced[1].Value))
+	assert.JSONEq(t, `{"input": "", "error": "empty input"}`, string(p.produced[2].Value))
+	assert.Len(t, c.committed, 3)
+}
+
+func TestRun_ProduceFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &fakeConsumer{cancel: cancel, batches: [][]Message{{{Value: []byte("hello")}}}}
+	err := Run(ctx, c, &fakeProducer{err: errors.New("broker down")}, upper, Options{OutputTopic: "out"})
+	assert.Error(t, err)
+	assert.Empty(t, c.committed)
+}
+
+func TestKafkaREST(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]string{}
+	var s *httptest.Server
+	s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests[r.Method+" "+r.URL.Path] = string(body)
+		mu.Unlock()
+		switch r.Method + " " + r.URL.Path {
+		case "POST /consumers/group":
+			_ = json.NewEncoder(w).Encode(map[string]string{"instance_id": "c1", "base_uri": s.URL + "/consumers/group/instances/c1"})
+		case "POST /consumers/group/instances/c1/subscription", "POST /consumers/group/instances/c1/offsets", "DELETE /consumers/group/instances/c1":
+			w.WriteHeader(http.StatusNoContent)
+		case "GET /consumers/group/instances/c1/records":
+			assert.Equal(t, kafkaBinaryType, r.Header.Get("Accept"))
+			_, _ = w.Write([]byte(`[{"topic":"in","partition":1,"offset":4,"key":null,"value":"aGVsbG8="},` +
+				`{"topic":"in","partition":1,"offset":5,"key":"aw==","value":"d29ybGQ="}]`))
+		case "POST /topics/out":
+			_, _ = w.Write([]byte(`{"offsets":[{"partition":0,"offset":10}]}`))
+		case "POST /topics/bad":
+			_, _ = w.Write([]byte(`{"offsets":[{"error_code":40301,"error":"not authorized"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+
+	ctx := context.Background()
+	c, err := NewKafkaConsumer(ctx, s.URL, "group", []string{"in"}, KafkaOptions{})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"topics":["in"]}`, requests["POST /consumers/group/instances/c1/subscription"])
+	assert.JSONEq(t, `{"format":"binary","auto.offset.reset":"earliest","auto.commit.enable":"false"}`, requests["POST /consumers/group"])
+
+	messages, err := c.Fetch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []Message{
+		{Topic: "in", Partition: 1, Offset: 4, Value: []byte("hello")},
+		{Topic: "in", Partition: 1, Offset: 5, Key: []byte("k"), Value: []byte("world")},
+	}, messages)
+
+	require.NoError(t, c.Commit(ctx, messages))
+	assert.JSONEq(t, `{"offsets":[{"topic":"in","partition":1,"offset":5}]}`, requests["POST /consumers/group/instances/c1/offsets"])
+	require.NoError(t, c.Close())
+
+	p := NewKafkaProducer(s.URL+"/", KafkaOptions{})
+	require.NoError(t, p.Produce(ctx, "out", []Message{{Key: []byte("k"), Value: []byte("v")}}))
+	assert.JSONEq(t, `{"records":[{"key":"aw==","value":"dg=="}]}`, requests["POST /topics/out"])
+	assert.Error(t, p.Produce(ctx, "bad", []Message{{Value: []byte("v")}}))
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"encoding/gob"
+	"reflect"
+
+	"github.com/nlpodyssey/cybertron/pkg/kernels"
+	"github.com/nlpodyssey/spago/ag"
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/nlpodyssey/spago/nn"
+	"github.com/nlpodyssey/spago/nn/activation"
+)
+
+// geluModel computes the GELU activation of the spago models with the
+// vectorized kernels (see kernels.GELUTanh), instead of spago's per-element
+// float64 one, for the inputs of type float32 not requiring gradients, the
+// ones of the inference. It falls back to spago's otherwise.
+type geluModel struct {
+	nn.Module
+}
+
+var _ nn.StandardModel = &geluModel{}
+
+func init() {
+	gob.Register(&geluModel{})
+}
+
+// Forward returns the activations of the inputs.
+func (m *geluModel) Forward(xs ...ag.Node) []ag.Node {
+	ys := make([]ag.Node, len(xs))
+	for i, x := range xs {
+		v, ok := x.Value().(*mat.Dense[float32])
+		if !ok || x.RequiresGrad() {
+			ys[i] = ag.GELU(x)
+			continue
+		}
+		y := mat.NewDense[float32](v.Rows(), v.Columns(), mat.Data[float32](v))
+		kernels.GELUTanh(mat.Data[float32](y))
+		ys[i] = y
+	}
+	return ys
+}
+
+// withKernelActivations returns the loading function replacing the GELU
+// activations of the spago models it loads with the ones computed by the
+// vectorized kernels (see geluModel).
+func (l loader[T]) withKernelActivations(load func() (T, error)) func() (T, error) {
+	if l.conf.Backend == BackendONNX {
+		return load
+	}
+	return func() (T, error) {
+		obj, err := load()
+		if err != nil {
+			return obj, err
+		}
+		replaceActivations(obj)
+		return obj, nil
+	}
+}
+
+// modelList is the type of the lists of modules of the spago models
+// holding their activations, e.g. in the feed-forward blocks.
+var modelList = reflect.TypeOf(nn.ModuleList[nn.StandardModel]{})
+
+// replaceActivations replaces the GELU activations in the lists of modules
+// of the spago models held by the model of the task, returning their
+// number.
+func replaceActivations(obj any) int {
+	v := reflect.Indirect(reflect.ValueOf(obj))
+	if v.Kind() != reflect.Struct {
+		return 0
+	}
+	n := 0
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if !v.Type().Field(i).IsExported() || (f.Kind() == reflect.Pointer && f.IsNil()) {
+			continue
+		}
+		m, ok := f.Interface().(nn.Model)
+		if !ok {
+			continue
+		}
+		nn.Apply(m, func(m nn.Model) {
+			s := reflect.Indirect(reflect.ValueOf(m))
+			if s.Kind() != reflect.Struct {
+				return
+			}
+			for j := 0; j < s.NumField(); j++ {
+				list := s.Field(j)
+				if list.Type() != modelList || !s.Type().Field(j).IsExported() {
+					continue
+				}
+				for k := 0; k < list.Len(); k++ {
+					a, ok := list.Index(k).Interface().(*activation.Model)
+					if ok && a.Activation == activation.GELU && len(a.Params) == 0 {
+						list.Index(k).Set(reflect.ValueOf(&geluModel{}))
+						n++
+					}
+				}
+			}
+		})
+	}
+	return n
+}
@@ -0,0 +1,54 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/models/bert"
+	"github.com/nlpodyssey/spago/ag"
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/nlpodyssey/spago/nn"
+	"github.com/nlpodyssey/spago/nn/activation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplaceActivations(t *testing.T) {
+	newBlock := func(a activation.Name) *bert.FeedForwardBlock {
+		b := bert.NewFeedForwardBlock[float32](bert.FeedForwardBlockConfig{Dim: 4, HiddenDim: 8, Activation: a})
+		nn.ForEachParam(b, func(p nn.Param) {
+			data := mat.Data[float32](p.Value())
+			for i := range data {
+				data[i] = float32(i%7-3) / 5
+			}
+		})
+		return b
+	}
+	obj := &struct {
+		GELU, ReLU *bert.FeedForwardBlock
+		Nil        *bert.FeedForwardBlock
+	}{GELU: newBlock(activation.GELU), ReLU: newBlock(activation.ReLU)}
+
+	xs := []ag.Node{mat.NewVecDense([]float32{-2, -0.5, 0.5, 3}), mat.NewVecDense([]float32{1, 0, -1, 8})}
+	want := obj.GELU.Forward(xs)
+
+	assert.Equal(t, 1, replaceActivations(obj))
+	assert.IsType(t, &geluModel{}, obj.GELU.MLP[1])
+	assert.IsType(t, &activation.Model{}, obj.ReLU.MLP[1])
+
+	got := obj.GELU.Forward(xs)
+	require.Len(t, got, len(want))
+	for i := range want {
+		assert.InDeltaSlice(t, mat.Data[float32](want[i].Value()), mat.Data[float32](got[i].Value()), 1e-5)
+	}
+
+	// The inputs requiring gradients, e.g. in the training, are computed by
+	// spago, with the gradients.
+	x := mat.NewVecDense([]float32{-1, 2}, mat.WithGrad(true))
+	y := (&geluModel{}).Forward(x)[0]
+	assert.True(t, y.RequiresGrad())
+	assert.InDeltaSlice(t, mat.Data[float32](ag.GELU(x).Value()), mat.Data[float32](y.Value()), 1e-6)
+}
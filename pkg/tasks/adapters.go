@@ -0,0 +1,412 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"github.com/nlpodyssey/cybertron/pkg/lora"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/coreference"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/languagemodeling"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/postagging"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/relationextraction"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier"
+	"github.com/rs/zerolog/log"
+)
+
+// AdapterSet is the set of the LoRA adapters of a model, shared by its
+// replicas, which can be changed while the model serves requests, e.g. by
+// the admin API of the server. The requests in flight are served with the
+// adapters they started with.
+type AdapterSet struct {
+	mu       sync.Mutex
+	adapters map[string]*lora.Adapter
+	// models are the replicas the adapters are applied to.
+	models []adapterHolder
+}
+
+// adapterHolder is implemented by the adapted models.
+type adapterHolder interface {
+	apply(name string, a *lora.Adapter) (undo func(), err error)
+	remove(name string)
+}
+
+// Add applies the adapter to the model with the given name, replacing the
+// adapter with the same name, if any. On error, the model is left unchanged.
+func (s *AdapterSet) Add(name string, a *lora.Adapter) error {
+	if name == "" {
+		return errors.New("adapter name not specified")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	undos := make([]func(), 0, len(s.models))
+	for _, m := range s.models {
+		undo, err := m.apply(name, a)
+		if err != nil {
+			for _, undo := range undos {
+				undo()
+			}
+			return fmt.Errorf("failed to apply adapter %#v: %w", name, err)
+		}
+		undos = append(undos, undo)
+	}
+	s.adapters[name] = a
+	return nil
+}
+
+// Remove removes the adapter with the given name, reporting whether it was
+// present. The requests naming it fail from then on.
+func (s *AdapterSet) Remove(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.adapters[name]; !ok {
+		return false
+	}
+	for _, m := range s.models {
+		m.remove(name)
+	}
+	delete(s.adapters, name)
+	return true
+}
+
+// Adapter returns the adapter with the given name, if any.
+func (s *AdapterSet) Adapter(name string) (*lora.Adapter, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.adapters[name]
+	return a, ok
+}
+
+// Names returns the sorted names of the adapters.
+func (s *AdapterSet) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.adapters))
+	for name := range s.adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// register applies the adapters of the set to the replica, which is
+// updated by the set from then on.
+func (s *AdapterSet) register(m adapterHolder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, a := range s.adapters {
+		if _, err := m.apply(name, a); err != nil {
+			return fmt.Errorf("failed to apply adapter %#v: %w", name, err)
+		}
+	}
+	s.models = append(s.models, m)
+	return nil
+}
+
+// Adapters returns the set of the adapters of the model, if it supports
+// them. For a model loaded with replicas, the set is shared by all of them;
+// for an ensemble, or a model with variants, the first model is considered.
+// A model loaded lazily is loaded first (see Config.Lazy).
+func Adapters(m any) (*AdapterSet, bool) {
+	for {
+		if a, ok := m.(interface{ adapterSet() *AdapterSet }); ok {
+			return a.adapterSet(), true
+		}
+		var ok bool
+		if m, ok = unwrapResolved(m); !ok {
+			return nil, false
+		}
+	}
+}
+
+// adapted serves each request with the base model, or with the copy of it
+// with the LoRA adapter named by the context of the request applied (see
+// lora.NewContext). The copies share the weights of the base model, so the
+// requests with different adapters are served side by side by the same
+// replicas, each input of a request in its own forward pass, as without
+// adapters.
+type adapted[T any] struct {
+	base T
+	set  *AdapterSet
+	mu   sync.RWMutex
+	// views are the copies of the base model by adapter name.
+	views map[string]T
+}
+
+// Unwrap returns the base model.
+func (a *adapted[T]) Unwrap() any {
+	return a.base
+}
+
+// Close closes the base model, whose resources the copies share.
+func (a *adapted[T]) Close() error {
+	if c, ok := any(a.base).(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (a *adapted[T]) adapterSet() *AdapterSet {
+	return a.set
+}
+
+// apply adds the copy of the base model with the adapter applied, returning
+// the function restoring the previous one.
+func (a *adapted[T]) apply(name string, adapter *lora.Adapter) (func(), error) {
+	v, err := lora.Apply(a.base, adapter)
+	if err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	prev, ok := a.views[name]
+	a.views[name] = v
+	return func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if ok {
+			a.views[name] = prev
+		} else {
+			delete(a.views, name)
+		}
+	}, nil
+}
+
+func (a *adapted[T]) remove(name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.views, name)
+}
+
+// pick returns the model serving the request: the copy with the adapter
+// named by the context, if any, or the base model.
+func (a *adapted[T]) pick(ctx context.Context) (T, error) {
+	name := lora.FromContext(ctx)
+	if name == "" {
+		return a.base, nil
+	}
+	a.mu.RLock()
+	m, ok := a.views[name]
+	a.mu.RUnlock()
+	if !ok {
+		return m, fmt.Errorf("%w: unknown adapter %#v", errdefs.ErrInvalidRequest, name)
+	}
+	return m, nil
+}
+
+// adapt calls the function with the model picked for the request.
+func adapt[T, R any](ctx context.Context, a *adapted[T], f func(T) (R, error)) (R, error) {
+	m, err := a.pick(ctx)
+	if err != nil {
+		var zero R
+		return zero, err
+	}
+	return f(m)
+}
+
+// withAdapters returns the loading function wrapping each model loaded so
+// that the adapters of a set shared by the replicas are applied to it: the
+// ones configured, if any, loaded once, and the ones added later (see
+// Adapters). The models of the ONNX backend are not wrapped.
+func (l loader[T]) withAdapters(load func() (T, error)) (func() (T, error), error) {
+	if l.conf.Backend == BackendONNX {
+		if len(l.conf.Adapters) > 0 {
+			return nil, fmt.Errorf("the %s backend doesn't support the adapters", l.conf.Backend)
+		}
+		return load, nil
+	}
+	set := &AdapterSet{adapters: make(map[string]*lora.Adapter, len(l.conf.Adapters))}
+	for name, dir := range l.conf.Adapters {
+		if name == "" {
+			return nil, errors.New("adapter name not specified")
+		}
+		a, err := lora.Load(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load adapter %#v: %w", name, err)
+		}
+		set.adapters[name] = a
+	}
+	return func() (T, error) {
+		obj, err := load()
+		if err != nil {
+			return obj, err
+		}
+		w, err := wrapAdapters(obj, set)
+		if err != nil {
+			Finalize(obj)
+			var empty T
+			return empty, err
+		}
+		if len(set.adapters) > 0 {
+			log.Info().Str("model", l.conf.ModelName).Int("adapters", len(set.adapters)).Msg("model adapters applied")
+		}
+		return w, nil
+	}, nil
+}
+
+// wrapAdapters returns the model of the task T serving the requests with
+// the adapters of the set applied, as selected by their contexts.
+func wrapAdapters[T any](m T, set *AdapterSet) (T, error) {
+	a := &adapted[T]{base: m, set: set, views: make(map[string]T)}
+	var w any
+	switch p := any(a).(type) {
+	case *adapted[text2text.Interface]:
+		w = text2textAdapted{p}
+	case *adapted[zeroshotclassifier.Interface]:
+		w = zeroShotAdapted{p}
+	case *adapted[questionanswering.Interface]:
+		w = questionAnsweringAdapted{p}
+	case *adapted[textclassification.Interface]:
+		w = textClassificationAdapted{p}
+	case *adapted[tokenclassification.Interface]:
+		w = tokenClassificationAdapted{p}
+	case *adapted[textencoding.Interface]:
+		w = textEncodingAdapted{p}
+	case *adapted[languagemodeling.Interface]:
+		w = languageModelingAdapted{p}
+	case *adapted[coreference.Interface]:
+		w = coreferenceAdapted{p}
+	case *adapted[relationextraction.Interface]:
+		w = relationExtractionAdapted{p}
+	case *adapted[postagging.Interface]:
+		w = posTaggingAdapted{p}
+	case *adapted[sentencesegmentation.Interface]:
+		w = sentenceSegmentationAdapted{p}
+	}
+	obj, ok := w.(T)
+	if !ok {
+		if len(set.adapters) == 0 {
+			// The models of other types are served as they are.
+			return m, nil
+		}
+		return obj, fmt.Errorf("loader: adapters not supported for type %T", m)
+	}
+	if err := set.register(a); err != nil {
+		return obj, err
+	}
+	return obj, nil
+}
+
+type text2textAdapted struct {
+	*adapted[text2text.Interface]
+}
+
+func (a text2textAdapted) Generate(ctx context.Context, text string, opts *text2text.Options) (text2text.Response, error) {
+	return adapt(ctx, a.adapted, func(m text2text.Interface) (text2text.Response, error) {
+		return m.Generate(ctx, text, opts)
+	})
+}
+
+type zeroShotAdapted struct {
+	*adapted[zeroshotclassifier.Interface]
+}
+
+func (a zeroShotAdapted) Classify(ctx context.Context, text string, parameters zeroshotclassifier.Parameters) (zeroshotclassifier.Response, error) {
+	return adapt(ctx, a.adapted, func(m zeroshotclassifier.Interface) (zeroshotclassifier.Response, error) {
+		return m.Classify(ctx, text, parameters)
+	})
+}
+
+type questionAnsweringAdapted struct {
+	*adapted[questionanswering.Interface]
+}
+
+func (a questionAnsweringAdapted) Answer(ctx context.Context, question string, passage string, opts *questionanswering.Options) (questionanswering.Response, error) {
+	return adapt(ctx, a.adapted, func(m questionanswering.Interface) (questionanswering.Response, error) {
+		return m.Answer(ctx, question, passage, opts)
+	})
+}
+
+type textClassificationAdapted struct {
+	*adapted[textclassification.Interface]
+}
+
+func (a textClassificationAdapted) Classify(ctx context.Context, text string, parameters textclassification.Parameters) (textclassification.Response, error) {
+	return adapt(ctx, a.adapted, func(m textclassification.Interface) (textclassification.Response, error) {
+		return m.Classify(ctx, text, parameters)
+	})
+}
+
+type tokenClassificationAdapted struct {
+	*adapted[tokenclassification.Interface]
+}
+
+func (a tokenClassificationAdapted) Classify(ctx context.Context, text string, parameters tokenclassification.Parameters) (tokenclassification.Response, error) {
+	return adapt(ctx, a.adapted, func(m tokenclassification.Interface) (tokenclassification.Response, error) {
+		return m.Classify(ctx, text, parameters)
+	})
+}
+
+type textEncodingAdapted struct {
+	*adapted[textencoding.Interface]
+}
+
+func (a textEncodingAdapted) Encode(ctx context.Context, text string, poolingStrategy int) (textencoding.Response, error) {
+	return adapt(ctx, a.adapted, func(m textencoding.Interface) (textencoding.Response, error) {
+		return m.Encode(ctx, text, poolingStrategy)
+	})
+}
+
+type languageModelingAdapted struct {
+	*adapted[languagemodeling.Interface]
+}
+
+func (a languageModelingAdapted) Predict(ctx context.Context, text string, parameters languagemodeling.Parameters) (languagemodeling.Response, error) {
+	return adapt(ctx, a.adapted, func(m languagemodeling.Interface) (languagemodeling.Response, error) {
+		return m.Predict(ctx, text, parameters)
+	})
+}
+
+type coreferenceAdapted struct {
+	*adapted[coreference.Interface]
+}
+
+func (a coreferenceAdapted) Resolve(ctx context.Context, text string) (coreference.Response, error) {
+	return adapt(ctx, a.adapted, func(m coreference.Interface) (coreference.Response, error) {
+		return m.Resolve(ctx, text)
+	})
+}
+
+type relationExtractionAdapted struct {
+	*adapted[relationextraction.Interface]
+}
+
+func (a relationExtractionAdapted) Extract(ctx context.Context, text string, parameters relationextraction.Parameters) (relationextraction.Response, error) {
+	return adapt(ctx, a.adapted, func(m relationextraction.Interface) (relationextraction.Response, error) {
+		return m.Extract(ctx, text, parameters)
+	})
+}
+
+type sentenceSegmentationAdapted struct {
+	*adapted[sentencesegmentation.Interface]
+}
+
+func (a sentenceSegmentationAdapted) Segment(ctx context.Context, text string) (sentencesegmentation.Response, error) {
+	return adapt(ctx, a.adapted, func(m sentencesegmentation.Interface) (sentencesegmentation.Response, error) {
+		return m.Segment(ctx, text)
+	})
+}
+
+type posTaggingAdapted struct {
+	*adapted[postagging.Interface]
+}
+
+func (a posTaggingAdapted) Tag(ctx context.Context, text string) (postagging.Response, error) {
+	return adapt(ctx, a.adapted, func(m postagging.Interface) (postagging.Response, error) {
+		return m.Tag(ctx, text)
+	})
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/converter/convertertest"
+	"github.com/nlpodyssey/cybertron/pkg/converter/safetensors"
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"github.com/nlpodyssey/cybertron/pkg/lora"
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadAdapter writes the LoRA adapter of rank 2 of the checkpoint, whose
+// names are relative to "base_model.model.bert.encoder.layer.", and loads
+// it.
+func loadAdapter(t *testing.T, weights convertertest.Checkpoint) *lora.Adapter {
+	dir := t.TempDir()
+	c := make(convertertest.Checkpoint, len(weights))
+	for name, shape := range weights {
+		c["base_model.model.bert.encoder.layer."+name] = shape
+	}
+	require.NoError(t, convertertest.WriteSafetensors(dir, c))
+	require.NoError(t, os.Rename(filepath.Join(dir, safetensors.DefaultFilename), filepath.Join(dir, lora.SafetensorsFilename)))
+	require.NoError(t, convertertest.WriteJSON(dir, lora.ConfigFilename, lora.Config{PeftType: "LORA", R: 2, Alpha: 4}))
+	a, err := lora.Load(dir)
+	require.NoError(t, err)
+	return a
+}
+
+func TestAdapters(t *testing.T) {
+	m := loadSynthetic[textclassification.Interface](t, "BertForSequenceClassification", convertertest.Checkpoint{
+		"classifier.weight": {3, testHiddenSize},
+		"classifier.bias":   {3},
+	})
+	set, ok := tasks.Adapters(m)
+	require.True(t, ok)
+	assert.Empty(t, set.Names())
+
+	h := testHiddenSize
+	require.NoError(t, set.Add("query", loadAdapter(t, convertertest.Checkpoint{
+		"0.attention.self.query.lora_A.weight": {2, h},
+		"0.attention.self.query.lora_B.weight": {h, 2},
+	})))
+	require.NoError(t, set.Add("ff", loadAdapter(t, convertertest.Checkpoint{
+		"1.intermediate.dense.lora_A.weight": {2, h},
+		"1.intermediate.dense.lora_B.weight": {2 * h, 2},
+	})))
+	assert.Equal(t, []string{"ff", "query"}, set.Names())
+
+	classify := func(adapter string) []float64 {
+		ctx := context.Background()
+		if adapter != "" {
+			ctx = lora.NewContext(ctx, adapter)
+		}
+		r, err := m.Classify(ctx, "the cat sat.", textclassification.Parameters{})
+		require.NoError(t, err)
+		return r.Scores
+	}
+	adapters := []string{"", "query", "ff"}
+	want := make(map[string][]float64, len(adapters))
+	for _, a := range adapters {
+		want[a] = classify(a)
+	}
+	assert.NotEqual(t, want[""], want["query"])
+	assert.NotEqual(t, want[""], want["ff"])
+	assert.NotEqual(t, want["query"], want["ff"])
+
+	t.Run("side by side", func(t *testing.T) {
+		// The requests with different adapters are served concurrently by
+		// the same model, each with its own.
+		var wg sync.WaitGroup
+		for i := 0; i < 3*testGoroutines; i++ {
+			a := adapters[i%len(adapters)]
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.Equal(t, want[a], classify(a))
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("invalid adapter", func(t *testing.T) {
+		err := set.Add("query", loadAdapter(t, convertertest.Checkpoint{
+			"0.output.dense.lora_A.weight": {2, h},
+			"0.output.dense.lora_B.weight": {h, 2},
+		}))
+		assert.ErrorContains(t, err, `failed to apply adapter "query"`)
+		// The adapter in place is kept.
+		assert.Equal(t, want["query"], classify("query"))
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		assert.True(t, set.Remove("query"))
+		assert.False(t, set.Remove("query"))
+		assert.Equal(t, []string{"ff"}, set.Names())
+		_, err := m.Classify(lora.NewContext(context.Background(), "query"), "the cat sat.", textclassification.Parameters{})
+		assert.ErrorIs(t, err, errdefs.ErrInvalidRequest)
+		assert.Equal(t, want["ff"], classify("ff"))
+		assert.Equal(t, want[""], classify(""))
+	})
+
+	assert.Error(t, set.Add("", loadAdapter(t, convertertest.Checkpoint{
+		"0.attention.self.query.lora_A.weight": {2, h},
+		"0.attention.self.query.lora_B.weight": {h, 2},
+	})))
+
+	_, ok = tasks.Adapters(struct{}{})
+	assert.False(t, ok)
+}
@@ -0,0 +1,83 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package attribution explains the predictions of the models, attributing
+// them to the tokens of their inputs by occlusion: each token is masked in
+// turn, and its attribution is how much the probability of the prediction
+// drops without it. It needs no gradients, and works with any model, at the
+// cost of a forward pass per token.
+package attribution
+
+import (
+	"context"
+
+	"github.com/nlpodyssey/cybertron/pkg/tokenizers"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
+)
+
+// Token is the attribution of a prediction to a token of the input.
+type Token struct {
+	// Text is the text of the token.
+	Text string
+	// Start is the start index of the token in the text, in runes.
+	Start int
+	// End is the end index of the token in the text, in runes.
+	End int
+	// ByteStart is the start index of the token in the text, in bytes.
+	ByteStart int
+	// ByteEnd is the end index of the token in the text, in bytes.
+	ByteEnd int
+	// Score is the drop of the probability of the prediction when the token
+	// is masked: positive if the token supports the prediction, negative if
+	// it goes against it.
+	Score float64
+}
+
+// Occlusion returns the attributions of the predictions to the tokens at
+// the positions, given the probabilities of the predictions with all the
+// tokens, base, and the function returning them with the tokens given.
+// The i-th attribution holds the drops of the probabilities when the token
+// at the i-th position is replaced with the mask. The forward passes are
+// added to the input tokens of the usage of the request.
+func Occlusion(ctx context.Context, tokens []string, positions []int, mask string, base []float64, probs func(tokens []string) ([]float64, error)) ([][]float64, error) {
+	occluded := make([]string, len(tokens))
+	copy(occluded, tokens)
+	out := make([][]float64, len(positions))
+	for i, pos := range positions {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		occluded[pos] = mask
+		p, err := probs(occluded)
+		occluded[pos] = tokens[pos]
+		if err != nil {
+			return nil, err
+		}
+		usage.AddTokens(ctx, len(tokens), 0)
+		out[i] = make([]float64, len(base))
+		for j := range base {
+			out[i][j] = base[j] - p[j]
+		}
+	}
+	return out, nil
+}
+
+// Tokens returns the attributions to the tokens of the text, with their
+// offsets in runes, and the scores, aligned with them.
+func Tokens(text string, tokens []tokenizers.StringOffsetsPair, scores []float64) []Token {
+	r := tokenizers.NewRuneOffsets(text)
+	out := make([]Token, len(tokens))
+	for i, t := range tokens {
+		b := r.Bytes(t.Offsets)
+		out[i] = Token{
+			Text:      text[b.Start:b.End],
+			Start:     t.Offsets.Start,
+			End:       t.Offsets.End,
+			ByteStart: b.Start,
+			ByteEnd:   b.End,
+			Score:     scores[i],
+		}
+	}
+	return out
+}
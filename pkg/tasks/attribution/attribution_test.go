@@ -0,0 +1,76 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package attribution
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/tokenizers"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keywordProbs returns the probability of a prediction depending on the
+// occurrences of the "good" and of the "bad" tokens.
+func keywordProbs(tokens []string) ([]float64, error) {
+	p := 0.5
+	for _, t := range tokens {
+		switch t {
+		case "good":
+			p += 0.3
+		case "bad":
+			p -= 0.2
+		}
+	}
+	return []float64{p, 1 - p}, nil
+}
+
+func TestOcclusion(t *testing.T) {
+	tokens := []string{"[CLS]", "good", "not", "bad", "[SEP]"}
+	base, _ := keywordProbs(tokens)
+	var c usage.Counter
+	ctx := usage.NewContext(context.Background(), &c)
+
+	drops, err := Occlusion(ctx, tokens, []int{1, 2, 3}, "[MASK]", base, keywordProbs)
+	require.NoError(t, err)
+	require.Len(t, drops, 3)
+	assert.InDeltaSlice(t, []float64{0.3, -0.3}, drops[0], 1e-9)
+	assert.InDeltaSlice(t, []float64{0, 0}, drops[1], 1e-9)
+	assert.InDeltaSlice(t, []float64{-0.2, 0.2}, drops[2], 1e-9)
+
+	input, _ := c.Tokens()
+	assert.Equal(t, int64(3*len(tokens)), input)
+	assert.Equal(t, []string{"[CLS]", "good", "not", "bad", "[SEP]"}, tokens, "the tokens are left unchanged")
+}
+
+func TestOcclusionErrors(t *testing.T) {
+	tokens := []string{"a", "b"}
+	failure := errors.New("failure")
+	_, err := Occlusion(context.Background(), tokens, []int{0, 1}, "[MASK]", []float64{1},
+		func([]string) ([]float64, error) { return nil, failure })
+	assert.ErrorIs(t, err, failure)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Occlusion(ctx, tokens, []int{0, 1}, "[MASK]", []float64{1}, keywordProbs)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestTokens(t *testing.T) {
+	text := "Très bien 👍"
+	tokens := []tokenizers.StringOffsetsPair{
+		{String: "tres", Offsets: tokenizers.OffsetsType{Start: 0, End: 4}},
+		{String: "bien", Offsets: tokenizers.OffsetsType{Start: 5, End: 9}},
+		{String: "👍", Offsets: tokenizers.OffsetsType{Start: 10, End: 11}},
+	}
+	assert.Equal(t, []Token{
+		{Text: "Très", Start: 0, End: 4, ByteStart: 0, ByteEnd: 5, Score: 0.1},
+		{Text: "bien", Start: 5, End: 9, ByteStart: 6, ByteEnd: 10, Score: 0.2},
+		{Text: "👍", Start: 10, End: 11, ByteStart: 11, ByteEnd: 15, Score: -0.3},
+	}, Tokens(text, tokens, []float64{0.1, 0.2, -0.3}))
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+
+	"github.com/nlpodyssey/cybertron/pkg/calibration"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier"
+)
+
+// CalibrationNone disables the calibration of the model (see Config).
+const CalibrationNone = "none"
+
+// calibrated calibrates the probabilities of the responses of a classifier.
+type calibrated[T any] struct {
+	m T
+	c calibration.Calibration
+}
+
+// Unwrap returns the model.
+func (c calibrated[T]) Unwrap() any {
+	return c.m
+}
+
+// Close closes the model.
+func (c calibrated[T]) Close() error {
+	if cl, ok := any(c.m).(io.Closer); ok {
+		return cl.Close()
+	}
+	return nil
+}
+
+// Calibration returns the calibration of the probabilities.
+func (c calibrated[T]) Calibration() calibration.Calibration {
+	return c.c
+}
+
+// loadCalibration returns the calibration of the model: the one of the file
+// configured, or of the sidecar file in the model directory, if any.
+func (l loader[T]) loadCalibration() (calibration.Calibration, error) {
+	switch l.conf.Calibration {
+	case CalibrationNone:
+		return calibration.Calibration{}, nil
+	case "":
+		c, err := calibration.Load(filepath.Join(l.modelDir(), calibration.Filename))
+		if errors.Is(err, fs.ErrNotExist) {
+			return c, nil
+		}
+		return c, err
+	default:
+		return calibration.Load(l.conf.Calibration)
+	}
+}
+
+// wrapCalibration returns the model of the task T calibrating the
+// probabilities of its responses, if it's a classifier.
+func wrapCalibration[T any](m T, c calibration.Calibration) (T, bool) {
+	var w any
+	switch p := any(&m).(type) {
+	case *textclassification.Interface:
+		w = textClassificationCalibrated{calibrated[textclassification.Interface]{*p, c}}
+	case *zeroshotclassifier.Interface:
+		w = zeroShotCalibrated{calibrated[zeroshotclassifier.Interface]{*p, c}}
+	}
+	obj, ok := w.(T)
+	return obj, ok
+}
+
+// sortByScore sorts the labels and the scores by descending score, since
+// the Platt scaling can change their order.
+func sortByScore(labels []string, scores []float64) {
+	sort.Sort(byScore{labels, scores})
+}
+
+type byScore struct {
+	labels []string
+	scores []float64
+}
+
+func (s byScore) Len() int           { return len(s.scores) }
+func (s byScore) Less(i, j int) bool { return s.scores[i] > s.scores[j] }
+func (s byScore) Swap(i, j int) {
+	s.labels[i], s.labels[j] = s.labels[j], s.labels[i]
+	s.scores[i], s.scores[j] = s.scores[j], s.scores[i]
+}
+
+type textClassificationCalibrated struct {
+	calibrated[textclassification.Interface]
+}
+
+func (c textClassificationCalibrated) Classify(ctx context.Context, text string, parameters textclassification.Parameters) (textclassification.Response, error) {
+	resp, err := c.m.Classify(ctx, text, parameters)
+	if err != nil || len(resp.Scores) == 0 {
+		return resp, err
+	}
+	resp.Scores = c.c.Distribution(resp.Scores)
+	sortByScore(resp.Labels, resp.Scores)
+	return resp, nil
+}
+
+type zeroShotCalibrated struct {
+	calibrated[zeroshotclassifier.Interface]
+}
+
+func (c zeroShotCalibrated) Classify(ctx context.Context, text string, parameters zeroshotclassifier.Parameters) (zeroshotclassifier.Response, error) {
+	resp, err := c.m.Classify(ctx, text, parameters)
+	if err != nil || len(resp.Scores) == 0 {
+		return resp, err
+	}
+	// The scores of the labels are independent with more labels allowed,
+	// or a single one, else they're a distribution over the labels.
+	if parameters.MultiLabel || len(resp.Scores) == 1 {
+		for i, s := range resp.Scores {
+			resp.Scores[i] = c.c.Score(s)
+		}
+	} else {
+		resp.Scores = c.c.Distribution(resp.Scores)
+	}
+	sortByScore(resp.Labels, resp.Scores)
+	return resp, nil
+}
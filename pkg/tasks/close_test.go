@@ -0,0 +1,32 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/converter/convertertest"
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClose(t *testing.T) {
+	models := []any{
+		loadSynthetic[textclassification.Interface](t, "BertForSequenceClassification", convertertest.Checkpoint{
+			"classifier.weight": {3, testHiddenSize},
+			"classifier.bias":   {3},
+		}),
+		loadSynthetic[textencoding.Interface](t, "BertModel", nil),
+	}
+	for _, m := range models {
+		assert.Implements(t, (*io.Closer)(nil), m)
+		assert.NoError(t, tasks.Close(m))
+		assert.NoError(t, tasks.Close(m), "closing twice")
+	}
+	assert.NoError(t, tasks.Close(struct{}{}))
+}
@@ -0,0 +1,191 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/converter/convertertest"
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/languagemodeling"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testHiddenSize   = 8
+	testMaxPositions = 16
+	testGoroutines   = 8
+)
+
+var (
+	testVocabulary = []string{"[PAD]", "[UNK]", "[CLS]", "[SEP]", "[MASK]", "the", "cat", "sat", "##s", "."}
+	testInputs     = []string{"the cat sat.", "the cats sat", "cat", "the [MASK] sat."}
+)
+
+// TestConcurrentInference checks that the models of the tasks serve
+// concurrent requests with the same responses as sequential ones, sharing
+// their weights: run it with the race detector (see "make race") to catch
+// any state shared by the requests.
+func TestConcurrentInference(t *testing.T) {
+	t.Run("textclassification", func(t *testing.T) {
+		m := loadSynthetic[textclassification.Interface](t, "BertForSequenceClassification", convertertest.Checkpoint{
+			"classifier.weight": {3, testHiddenSize},
+			"classifier.bias":   {3},
+		})
+		testConcurrently(t, func(ctx context.Context, text string) (any, error) {
+			return m.Classify(ctx, text, textclassification.Parameters{})
+		})
+	})
+	t.Run("tokenclassification", func(t *testing.T) {
+		m := loadSynthetic[tokenclassification.Interface](t, "BertForTokenClassification", convertertest.Checkpoint{
+			"classifier.weight": {3, testHiddenSize},
+			"classifier.bias":   {3},
+		})
+		testConcurrently(t, func(ctx context.Context, text string) (any, error) {
+			return m.Classify(ctx, text, tokenclassification.Parameters{
+				AggregationStrategy: tokenclassification.AggregationStrategyNone,
+			})
+		})
+	})
+	t.Run("textencoding", func(t *testing.T) {
+		m := loadSynthetic[textencoding.Interface](t, "BertModel", nil)
+		testConcurrently(t, func(ctx context.Context, text string) (any, error) {
+			r, err := m.Encode(ctx, text, 1)
+			if err != nil {
+				return nil, err
+			}
+			return r.Vector.Data().F32(), nil
+		})
+	})
+	t.Run("languagemodeling", func(t *testing.T) {
+		m := loadSynthetic[languagemodeling.Interface](t, "BertForMaskedLM", convertertest.Checkpoint{
+			"cls.predictions.transform.dense.weight":     {testHiddenSize, testHiddenSize},
+			"cls.predictions.transform.dense.bias":       {testHiddenSize},
+			"cls.predictions.transform.LayerNorm.weight": {testHiddenSize},
+			"cls.predictions.transform.LayerNorm.bias":   {testHiddenSize},
+			"cls.predictions.decoder.weight":             {len(testVocabulary), testHiddenSize},
+			"cls.predictions.decoder.bias":               {len(testVocabulary)},
+		})
+		testConcurrently(t, func(ctx context.Context, text string) (any, error) {
+			if !strings.Contains(text, "[MASK]") {
+				return nil, nil
+			}
+			return m.Predict(ctx, text, languagemodeling.Parameters{K: 3})
+		})
+	})
+}
+
+// testConcurrently calls the function with each input from several
+// goroutines at once, comparing the responses with the sequential ones.
+func testConcurrently(t *testing.T, f func(context.Context, string) (any, error)) {
+	ctx := context.Background()
+	expected := make([]any, len(testInputs))
+	for i, input := range testInputs {
+		r, err := f(ctx, input)
+		require.NoError(t, err)
+		expected[i] = r
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < testGoroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for j := range testInputs {
+				i := (g + j) % len(testInputs)
+				r, err := f(ctx, testInputs[i])
+				if assert.NoError(t, err) {
+					assert.Equal(t, expected[i], r, testInputs[i])
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+// loadSynthetic loads the model of the task T converted from a synthetic
+// BERT checkpoint of the architecture, with the weights of its head.
+func loadSynthetic[T any](t *testing.T, architecture string, head convertertest.Checkpoint) T {
+	modelsDir := t.TempDir()
+	modelName := "synthetic/" + strings.ToLower(architecture)
+	writeSynthetic(t, filepath.Join(modelsDir, modelName), architecture, head)
+
+	m, err := tasks.Load[T](&tasks.Config{
+		ModelsDir:      modelsDir,
+		ModelName:      modelName,
+		DownloadPolicy: tasks.DownloadNever,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { tasks.Finalize(m) })
+	return m
+}
+
+// writeSynthetic writes a synthetic BERT checkpoint of the architecture,
+// with the weights of its head, in the directory.
+func writeSynthetic(t *testing.T, dir, architecture string, head convertertest.Checkpoint) {
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, convertertest.WriteJSON(dir, "config.json", map[string]any{
+		"architectures":           []string{architecture},
+		"model_type":              "bert",
+		"hidden_act":              "gelu",
+		"hidden_size":             testHiddenSize,
+		"intermediate_size":       2 * testHiddenSize,
+		"layer_norm_eps":          1e-12,
+		"max_position_embeddings": testMaxPositions,
+		"num_attention_heads":     2,
+		"num_hidden_layers":       2,
+		"type_vocab_size":         2,
+		"vocab_size":              len(testVocabulary),
+		"id2label":                map[string]string{"0": "O", "1": "B-PER", "2": "I-PER"},
+	}))
+	require.NoError(t, convertertest.WriteJSON(dir, "tokenizer_config.json", map[string]any{"do_lower_case": true}))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vocab.txt"), []byte(strings.Join(testVocabulary, "\n")+"\n"), 0o644))
+	checkpoint := syntheticCheckpoint()
+	for name, shape := range head {
+		checkpoint[name] = shape
+	}
+	require.NoError(t, convertertest.WriteSafetensors(dir, checkpoint))
+}
+
+// syntheticCheckpoint returns the weights of a small BERT model, named as
+// in the checkpoints of the transformers library.
+func syntheticCheckpoint() convertertest.Checkpoint {
+	h := testHiddenSize
+	c := convertertest.Checkpoint{
+		"bert.embeddings.word_embeddings.weight":       {len(testVocabulary), h},
+		"bert.embeddings.position_embeddings.weight":   {testMaxPositions, h},
+		"bert.embeddings.token_type_embeddings.weight": {2, h},
+		"bert.embeddings.LayerNorm.weight":             {h},
+		"bert.embeddings.LayerNorm.bias":               {h},
+		"bert.pooler.dense.weight":                     {h, h},
+		"bert.pooler.dense.bias":                       {h},
+	}
+	for l := 0; l < 2; l++ {
+		prefix := fmt.Sprintf("bert.encoder.layer.%d", l)
+		for _, name := range []string{"attention.self.query", "attention.self.key", "attention.self.value", "attention.output.dense"} {
+			c[prefix+"."+name+".weight"] = []int{h, h}
+			c[prefix+"."+name+".bias"] = []int{h}
+		}
+		c[prefix+".attention.output.LayerNorm.weight"] = []int{h}
+		c[prefix+".attention.output.LayerNorm.bias"] = []int{h}
+		c[prefix+".intermediate.dense.weight"] = []int{2 * h, h}
+		c[prefix+".intermediate.dense.bias"] = []int{2 * h}
+		c[prefix+".output.dense.weight"] = []int{h, 2 * h}
+		c[prefix+".output.dense.bias"] = []int{h}
+		c[prefix+".output.LayerNorm.weight"] = []int{h}
+		c[prefix+".output.LayerNorm.bias"] = []int{h}
+	}
+	return c
+}
@@ -5,8 +5,15 @@
 package tasks
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/models/bert/bertconfig"
+	"github.com/nlpodyssey/cybertron/pkg/onnx"
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
+	"github.com/nlpodyssey/cybertron/pkg/textnorm"
 )
 
 // DownloadPolicy is a policy for downloading a model.
@@ -18,6 +25,12 @@ type ConversionPolicy int
 // FloatPrecision is the floating-point precision of the converted model.
 type FloatPrecision int
 
+// Backend is the engine used to run the model.
+type Backend int
+
+// Pooling is the pooling strategy of the text encodings (see Config.Pooling).
+type Pooling string
+
 const (
 	// DownloadMissing means that the model will be downloaded only if it doesn't exist.
 	DownloadMissing DownloadPolicy = iota
@@ -43,20 +56,159 @@ const (
 	F64
 )
 
+const (
+	// BackendSpago runs the model converted to spago (default).
+	BackendSpago Backend = iota
+	// BackendONNX runs an ONNX model ("model.onnx") found in the model directory,
+	// with no download or conversion. Only the text encoding and text
+	// classification tasks are supported.
+	BackendONNX
+)
+
+const (
+	// PoolingCLS encodes the texts with the encoding of their first token, e.g. [CLS].
+	PoolingCLS Pooling = "cls"
+	// PoolingMean encodes the texts with the mean of the encodings of their tokens.
+	PoolingMean Pooling = "mean"
+	// PoolingMax encodes the texts with the maximum of the encodings of their tokens.
+	PoolingMax Pooling = "max"
+	// PoolingMeanMax encodes the texts with the mean and the maximum of the encodings of their
+	// tokens, concatenated.
+	PoolingMeanMax Pooling = "mean-max"
+)
+
 // Config is the configuration for the loader.
 type Config struct {
 	// ModelsDir is the directory where the models are stored.
 	ModelsDir string
 	// ModelName is the name of the model (format: <org>/<model>).
 	ModelName string
-	// HubAccessToken is the access token for the Hugging Face Hub.
+	// HubAccessToken is the access token for the Hugging Face Hub, needed for private and gated models.
+	// If empty, the HF_TOKEN environment variable or the token saved by huggingface-cli is used, if any.
 	HubAccessToken string
+	// HubEndpoint is the URL of the Hugging Face Hub or of a mirror (default HF_ENDPOINT, or https://huggingface.co)
+	HubEndpoint string
+	// HTTPProxy is the URL of the HTTP(S) or SOCKS5 proxy for downloads (default HTTPS_PROXY, HTTP_PROXY)
+	HTTPProxy string
+	// CABundle is a PEM file of additional CA certificates to trust for downloads
+	CABundle string
+	// Revision is the branch, tag or commit SHA of the model to download (default main)
+	Revision string
 	// DownloadPolicy is the policy for downloading the model (default missing)
 	DownloadPolicy DownloadPolicy
 	// ConversionPolicy is the policy for converting the model (default missing)
 	ConversionPolicy ConversionPolicy
 	// ConversionPrecision is the floating-point precision of the converted model (default 32)
 	ConversionPrecision FloatPrecision
+	// ConversionQuantization is the quantization scheme applied to the weights of the converted model (default none)
+	ConversionQuantization quantization.Scheme
+	// ConversionGGUF enables the conversion of the model to GGUF too, in addition to the native format (default false)
+	ConversionGGUF bool
+	// ConversionVerification enables the comparison of the model outputs with the reference outputs
+	// found in the model directory, or computed with the Hugging Face Inference API (default false)
+	ConversionVerification bool
+	// Bundle is the path of a model bundle to load instead of downloading and converting the model.
+	// The bundle is extracted into the model directory, verifying the integrity of each file.
+	Bundle string
+	// ModelStore is the URL of an object store (e.g. "s3://bucket/models") to pull the converted
+	// model from, instead of downloading and converting it, when it's there
+	ModelStore string
+	// ModelStorePush enables pushing the model to the ModelStore after the conversion (default false)
+	ModelStorePush bool
+	// Offline disables any network access: the model is loaded from the local cache only,
+	// failing if any file is missing (default false). It's also enabled by HF_HUB_OFFLINE.
+	Offline bool
+	// Backend is the engine used to run the model (default spago)
+	Backend Backend
+	// Replicas is the number of replicas of the model loaded, each running one forward pass at a time,
+	// among which the requests are scheduled, so that they're served in parallel; the replicas of the
+	// spago backend share the weights of their encoder (default 1)
+	Replicas int
+	// NUMANodes are the NUMA nodes the replicas are placed on, round-robin, on Linux: "all", or a list of
+	// their IDs, e.g. "0-1"; each replica is loaded, with its own copy of the weights, and serves the requests
+	// bound to the CPUs of its node, so that it doesn't read its weights across the sockets (default none)
+	NUMANodes string
+	// IntraOpParallelism is the maximum number of goroutines used within a single request, e.g. by the
+	// matrix products of the onnx backend, or the candidate labels scored concurrently by the zero-shot
+	// classification (default 1 for the onnx backend, the number of CPUs for the zero-shot classification)
+	IntraOpParallelism int
+	// Device is the device running the large matrix products of the model: "cpu", or an accelerator the onnx
+	// backend is built with, e.g. "cuda" with the cuda build tag (default cpu, the only one of the spago backend)
+	Device string
+	// MemoryLimit is the maximum memory in MiB of the intermediate tensors held at once by a request of the
+	// onnx backend, beyond which the request fails with onnx.ErrMemoryLimitExceeded (default 0, unlimited)
+	MemoryLimit int
+	// AttentionWindow is the maximum distance between the tokens attending to each other in the onnx backend:
+	// a sliding window approximating the full attention of long inputs in linear time (default 0, the full attention)
+	AttentionWindow int
+	// RopeScaling is the scaling of the rotary position embeddings of the onnx models having them, extending
+	// the maximum length of the inputs by its factor; it replaces the scaling of the model configuration (optional)
+	RopeScaling onnx.RopeScaling
+	// KernelTuning selects the fastest kernel of the matrix products of the onnx backend (pure Go, SIMD or BLAS)
+	// for each shape of the weights, benchmarking them at load time, instead of using BLAS for all the large
+	// products; the selection is cached in the model directory, for the same machine (default false)
+	KernelTuning bool
+	// InterOpParallelism is the maximum number of requests served concurrently by each replica of the
+	// model; the others wait for their turn (default unlimited, or 1 with more than one replica)
+	InterOpParallelism int
+	// PriorityWeight is the number of interactive requests served for each batch request while both are
+	// waiting for a replica (see scheduling.Priority); it requires replicas or an inter-op parallelism (default 4)
+	PriorityWeight int
+	// Preemption cancels the batch text generations, failing with scheduling.ErrPreempted, when an interactive
+	// request waits for a replica (default false)
+	Preemption bool
+	// Timeout is the maximum time the server spends serving a request of the model, beyond which the request
+	// fails with DEADLINE_EXCEEDED, with the texts generated so far for the text generations (default 0, unlimited)
+	Timeout time.Duration
+	// Normalization is the normalization of the input texts before their tokenization, e.g. the Unicode
+	// NFC composition; the offsets of the responses refer to the original texts (default none)
+	Normalization textnorm.Options
+	// Calibration is the JSON file with the calibration of the probabilities of the classifiers (see the
+	// calibration package), or "none" (default the calibration.json file in the model directory, if any)
+	Calibration string
+	// Adapters maps the names of the LoRA adapters to the directories of their PEFT files, applied on top
+	// of the BERT models, sharing their weights; each request is served with the adapter named by its
+	// context (see lora.NewContext), or by the model alone; the adapters can be changed once the model is
+	// loaded (see Adapters) (optional)
+	Adapters map[string]string
+	// Lazy defers the loading of the model, with its download and conversion, to its first request, which
+	// waits for it, as do the requests following it; if the load fails, they fail with
+	// errdefs.ErrModelLoadFailed, and the next request loads it again. The lookups of its capabilities, e.g.
+	// AsTokenizer, load it as well (default false)
+	Lazy bool
+	// PrecomputePositions reads the embeddings of all the positions, up to the maximum length of the model,
+	// and of the token types of the spago backend at load time, instead of on their first use; they're kept in
+	// memory, rather than read from the embeddings store on each request, either way (default false)
+	PrecomputePositions bool
+	// MaxLength is the maximum length in tokens of the inputs, below the one of the model, beyond which the
+	// requests fail with errdefs.ErrInputTooLong, for the models exposing their tokens (see Tokenizer), e.g. to
+	// bound the latency of the requests (default 0, the one of the model)
+	MaxLength int
+	// Pooling is the pooling strategy of the text encodings, replacing the one of the requests, e.g. the one
+	// the model was trained with (default none, the one of the requests)
+	Pooling Pooling
+	// Resolver resolves the model name to the model directory, replacing the download of the model
+	// from the Hugging Face Hub, and the model store (optional)
+	Resolver ModelResolver
+	// Progress is called with the progress of the loading of the model: its stage, and the bytes downloaded
+	// and the estimated time left of each file, every half second while it's downloaded (optional)
+	Progress func(Progress)
+}
+
+// ModelResolver resolves a model name to a local directory with the model files, e.g. fetching
+// them from an internal registry, an artifact store or a signed-URL service.
+// The model is then converted in that directory, according to the conversion policy.
+type ModelResolver interface {
+	// Resolve makes the files of the model available locally, and returns the model directory.
+	Resolve(ctx context.Context, modelName string) (string, error)
+}
+
+// ModelResolverFunc is an adapter to use a function as a ModelResolver.
+type ModelResolverFunc func(ctx context.Context, modelName string) (string, error)
+
+// Resolve calls f(ctx, modelName).
+func (f ModelResolverFunc) Resolve(ctx context.Context, modelName string) (string, error) {
+	return f(ctx, modelName)
 }
 
 // FullModelPath returns the full model path.
@@ -84,6 +236,20 @@ var floatPrecisionValues = map[string]FloatPrecision{
 	"64": F64,
 }
 
+// backendValues is a list of supported backends.
+var backendValues = map[string]Backend{
+	"spago": BackendSpago,
+	"onnx":  BackendONNX,
+}
+
+// poolingStrategies are the strategies of the text encoding of the poolings.
+var poolingStrategies = map[Pooling]bertconfig.PoolingStrategyType{
+	PoolingCLS:     bertconfig.ClsTokenPooling,
+	PoolingMean:    bertconfig.MeanPooling,
+	PoolingMax:     bertconfig.MaxPooling,
+	PoolingMeanMax: bertconfig.MeanMaxPooling,
+}
+
 // ParseDownloadPolicy parses a string into a download policy.
 func ParseDownloadPolicy(s string) (DownloadPolicy, error) {
 	result, ok := downloadPolicyValues[s]
@@ -110,3 +276,60 @@ func ParseFloatPrecision(s string) (FloatPrecision, error) {
 	}
 	return result, nil
 }
+
+// ParseBackend parses a string into a Backend.
+func ParseBackend(s string) (Backend, error) {
+	result, ok := backendValues[s]
+	if !ok {
+		return 0, fmt.Errorf("invalid model backend value %#v", s)
+	}
+	return result, nil
+}
+
+// ParsePooling parses a string into a Pooling.
+func ParsePooling(s string) (Pooling, error) {
+	if _, ok := poolingStrategies[Pooling(s)]; !ok {
+		return "", fmt.Errorf("invalid model pooling value %#v", s)
+	}
+	return Pooling(s), nil
+}
+
+// String returns the name of the download policy.
+func (p DownloadPolicy) String() string {
+	for k, v := range downloadPolicyValues {
+		if v == p {
+			return k
+		}
+	}
+	return fmt.Sprintf("DownloadPolicy(%d)", int(p))
+}
+
+// String returns the name of the conversion policy.
+func (p ConversionPolicy) String() string {
+	for k, v := range conversionPolicyValues {
+		if v == p {
+			return k
+		}
+	}
+	return fmt.Sprintf("ConversionPolicy(%d)", int(p))
+}
+
+// String returns the bits of the floating-point precision.
+func (p FloatPrecision) String() string {
+	for k, v := range floatPrecisionValues {
+		if v == p {
+			return k
+		}
+	}
+	return fmt.Sprintf("FloatPrecision(%d)", int(p))
+}
+
+// String returns the name of the backend.
+func (b Backend) String() string {
+	for k, v := range backendValues {
+		if v == b {
+			return k
+		}
+	}
+	return fmt.Sprintf("Backend(%d)", int(b))
+}
@@ -0,0 +1,254 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bert
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/models/bert"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/coreference"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/diskstoremode"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sharedweights"
+	"github.com/nlpodyssey/cybertron/pkg/timings"
+	"github.com/nlpodyssey/cybertron/pkg/tokenizers"
+	"github.com/nlpodyssey/cybertron/pkg/tokenizers/wordpiecetokenizer"
+	"github.com/nlpodyssey/cybertron/pkg/trace"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
+	"github.com/nlpodyssey/cybertron/pkg/utils/matview"
+	"github.com/nlpodyssey/cybertron/pkg/vocabulary"
+	"github.com/nlpodyssey/spago/ag"
+	"github.com/nlpodyssey/spago/embeddings/store/diskstore"
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/nlpodyssey/spago/nn"
+)
+
+// CoreferenceResolution is a span-based coreference resolution model.
+type CoreferenceResolution struct {
+	// Model is the model used to resolve the coreferences.
+	Model *bert.ModelForCoreferenceResolution
+	// Tokenizer is the tokenizer used to tokenize the texts.
+	Tokenizer *wordpiecetokenizer.WordPieceTokenizer
+	// MaxSpanLength is the maximum length in tokens of the mentions.
+	MaxSpanLength int
+	// TopLambda is the ratio of the candidate mentions kept per word of the
+	// text, scored as antecedents of each other.
+	TopLambda float64
+	// doLowerCase is a flag indicating if the model should lowercase the input before tokenization.
+	doLowerCase bool
+	// release releases the encoder, with its embeddings, shared with the
+	// other models loaded from the same file.
+	release func() error
+}
+
+// LoadCoreferenceResolution returns a CoreferenceResolution loading the model, the embeddings and the tokenizer from a directory.
+func LoadCoreferenceResolution(modelPath string) (*CoreferenceResolution, error) {
+	vocab, err := vocabulary.NewFromFile(filepath.Join(modelPath, "vocab.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vocabulary for coreference resolution: %w", err)
+	}
+	tokenizer := wordpiecetokenizer.New(vocab)
+
+	tokenizerConfig, err := bert.ConfigFromFile[bert.TokenizerConfig](path.Join(modelPath, "tokenizer_config.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tokenizer config for coreference resolution: %w", err)
+	}
+
+	config, err := bert.ConfigFromFile[bert.Config](path.Join(modelPath, "config.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config for coreference resolution: %w", err)
+	}
+
+	m, err := nn.LoadFromFile[*bert.ModelForCoreferenceResolution](path.Join(modelPath, "spago_model.bin"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load bert model: %w", err)
+	}
+
+	key, err := sharedweights.Key(path.Join(modelPath, "spago_model.bin"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load bert model: %w", err)
+	}
+	encoder, release, err := sharedweights.Acquire(key, func() (*bert.Model, io.Closer, error) {
+		embeddingsRepo, err := diskstore.NewRepository(filepath.Join(modelPath, "repo"), diskstoremode.DefaultDiskStoreMode)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load embeddings repository for coreference resolution: %w", err)
+		}
+		if err := m.Bert.SetEmbeddings(embeddingsRepo); err != nil {
+			_ = embeddingsRepo.Close()
+			return nil, nil, fmt.Errorf("failed to set embeddings: %w", err)
+		}
+		return m.Bert, embeddingsRepo, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.Bert = encoder
+
+	maxSpanLength, topLambda := config.MaxSpanLength, config.TopLambda
+	if maxSpanLength == 0 {
+		maxSpanLength = coreference.DefaultMaxSpanLength
+	}
+	if topLambda == 0 {
+		topLambda = coreference.DefaultTopLambda
+	}
+
+	return &CoreferenceResolution{
+		Model:         m,
+		Tokenizer:     tokenizer,
+		MaxSpanLength: maxSpanLength,
+		TopLambda:     topLambda,
+		doLowerCase:   tokenizerConfig.DoLowerCase,
+		release:       release,
+	}, nil
+}
+
+// Close finalizes the CoreferenceResolution resources.
+// It satisfies the interface io.Closer.
+func (m *CoreferenceResolution) Close() error {
+	return m.release()
+}
+
+// Resolve returns the clusters of the mentions of the same entities in the
+// given text. The spans of words up to MaxSpanLength tokens are scored as
+// mentions, the best TopLambda of them per word kept (see
+// coreference.TopSpans); each mention is then linked to its best scoring
+// antecedent, if any scores better than none, and the chains of linked
+// mentions are the clusters. The text must fit the maximum length of the
+// model.
+func (m *CoreferenceResolution) Resolve(ctx context.Context, text string) (coreference.Response, error) {
+	end := timings.Start(ctx, timings.Tokenization)
+	tokenized := m.tokenize(text)
+	end()
+	if n, max := len(tokenized), m.Model.Bert.Config.MaxPositionEmbeddings-2; n > max {
+		return coreference.Response{}, fmt.Errorf("%w: %d > %d", coreference.ErrInputSequenceTooLong, n, max)
+	}
+	if len(tokenized) == 0 {
+		return coreference.Response{}, nil
+	}
+
+	end = timings.Start(ctx, timings.Forward)
+	padded := pad(tokenizers.GetStrings(tokenized))
+	usage.AddTokens(ctx, len(tokenized), 0)
+	scores, starts, ends := m.Model.Mentions(padded)
+	trace.Encode(ctx, padded, m.Model.Bert.EncodeEach)
+
+	words := wordpiecetokenizer.GroupSubWords(tokenized)
+	bounds := wordBounds(tokenized)
+	k := int(m.TopLambda * float64(len(words)))
+	if k < 1 {
+		k = 1
+	}
+	mentions := coreference.TopSpans(m.candidates(scores, bounds), k)
+
+	// The representations of the first and last token of each mention,
+	// shifted by the [CLS] token.
+	mentionStarts := make([]ag.Node, len(mentions))
+	mentionEnds := make([]ag.Node, len(mentions))
+	for i, s := range mentions {
+		mentionStarts[i] = starts[bounds[s.Start]+1]
+		mentionEnds[i] = ends[bounds[s.End+1]]
+	}
+	antecedents := bestAntecedents(mentions, m.Model.Antecedents(mentionStarts, mentionEnds).Value())
+	end()
+
+	offsets := tokenizers.NewRuneOffsets(text)
+	clusters := coreference.Clusters(antecedents)
+	response := coreference.Response{
+		Clusters: make([]coreference.Cluster, len(clusters)),
+	}
+	for i, c := range clusters {
+		cluster := coreference.Cluster{Mentions: make([]coreference.Mention, len(c))}
+		for j, index := range c {
+			s := mentions[index]
+			o := tokenizers.OffsetsType{Start: words[s.Start].Offsets.Start, End: words[s.End].Offsets.End}
+			b := offsets.Bytes(o)
+			cluster.Mentions[j] = coreference.Mention{
+				Text:      text[b.Start:b.End],
+				Start:     o.Start,
+				End:       o.End,
+				ByteStart: b.Start,
+				ByteEnd:   b.End,
+				Score:     1 / (1 + math.Exp(-s.Score)),
+			}
+		}
+		response.Clusters[i] = cluster
+	}
+	return response, nil
+}
+
+// candidates returns the spans of words up to MaxSpanLength tokens, with
+// their scores as mentions, given the index of the first token of each word
+// (see wordBounds).
+func (m *CoreferenceResolution) candidates(scores bert.MentionScores, bounds []int) []coreference.Span {
+	joint := scores.Joint.Value()
+	size := joint.Columns()
+	var spans []coreference.Span
+	for i := 0; i < len(bounds)-1; i++ {
+		start := bounds[i] + 1
+		for j := i; j < len(bounds)-1 && bounds[j+1]-bounds[i] <= m.MaxSpanLength; j++ {
+			end := bounds[j+1]
+			score := matview.At(scores.Starts[start].Value(), 0) +
+				matview.At(scores.Ends[end].Value(), 0) +
+				matview.At(joint, start*size+end)
+			spans = append(spans, coreference.Span{Start: i, End: j, Score: score})
+		}
+	}
+	return spans
+}
+
+// bestAntecedents returns the index of the best antecedent of each mention,
+// or -1 if none scores better than no antecedent at all, scored 0. The
+// score of an antecedent is the one of the pair, from the matrix of the
+// model, plus the ones of the two mentions.
+func bestAntecedents(mentions []coreference.Span, scores mat.Matrix) []int {
+	n := len(mentions)
+	antecedents := make([]int, n)
+	for i := range mentions {
+		best, bestScore := -1, 0.0
+		for j := 0; j < i; j++ {
+			score := matview.At(scores, i*n+j) + mentions[i].Score + mentions[j].Score
+			if score > bestScore {
+				best, bestScore = j, score
+			}
+		}
+		antecedents[i] = best
+	}
+	return antecedents
+}
+
+// wordBounds returns the index of the first token of each word, split in
+// sub-words prefixed by "##", followed by the number of tokens.
+func wordBounds(tokens []tokenizers.StringOffsetsPair) []int {
+	bounds := make([]int, 0, len(tokens)+1)
+	for i, t := range tokens {
+		if i > 0 && strings.HasPrefix(t.String, wordpiecetokenizer.DefaultSplitPrefix) {
+			continue
+		}
+		bounds = append(bounds, i)
+	}
+	return append(bounds, len(tokens))
+}
+
+// tokenize returns the tokens of the given text (without padding tokens).
+func (m *CoreferenceResolution) tokenize(text string) []tokenizers.StringOffsetsPair {
+	if m.doLowerCase {
+		text = strings.ToLower(text)
+	}
+	return m.Tokenizer.Tokenize(text)
+}
+
+// Tokenize returns the tokens of the given text, as seen by the model.
+func (m *CoreferenceResolution) Tokenize(text string) []string {
+	return tokenizers.GetStrings(m.tokenize(text))
+}
+
+func pad(tokens []string) []string {
+	return append([]string{wordpiecetokenizer.DefaultClassToken}, append(tokens, wordpiecetokenizer.DefaultSequenceSeparator)...)
+}
@@ -0,0 +1,57 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package coreference
+
+import (
+	"context"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+)
+
+const (
+	// DefaultMaxSpanLength is the default maximum length in tokens of the
+	// mentions, for the models not configuring it.
+	DefaultMaxSpanLength = 30
+
+	// DefaultTopLambda is the default ratio of the candidate mentions kept
+	// per word of the text, for the models not configuring it.
+	DefaultTopLambda = 0.4
+)
+
+// ErrInputSequenceTooLong means that pre-processing the input text
+// produced a sequence that exceeds the maximum allowed length.
+var ErrInputSequenceTooLong = errdefs.ErrInputTooLong
+
+// Interface defines the main functions for the coreference resolution task.
+// Its implementations are safe for concurrent use.
+type Interface interface {
+	// Resolve returns the clusters of the mentions of the same entities in
+	// the given text.
+	Resolve(ctx context.Context, text string) (Response, error)
+}
+
+// Mention is a span of the text referring to an entity. Start and End are
+// its offsets in the text in runes, ByteStart and ByteEnd in bytes. Score
+// is the probability of the span being a mention.
+type Mention struct {
+	Text      string
+	Start     int
+	End       int
+	ByteStart int
+	ByteEnd   int
+	Score     float64
+}
+
+// Cluster is a group of the mentions of the same entity, sorted by their
+// position in the text.
+type Cluster struct {
+	Mentions []Mention
+}
+
+// Response contains the response from coreference resolution: the clusters
+// of two mentions at least, sorted by the position of their first mention.
+type Response struct {
+	Clusters []Cluster
+}
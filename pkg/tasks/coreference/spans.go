@@ -0,0 +1,76 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package coreference
+
+import "sort"
+
+// Span is a candidate mention, from the Start-th to the End-th word of the
+// text (both included), with its score as mention.
+type Span struct {
+	Start int
+	End   int
+	Score float64
+}
+
+// TopSpans returns the k best scoring spans, skipping the ones crossing a
+// better one, i.e. overlapping with it without being nested in, or
+// enclosing, it. They're sorted by their start, the enclosing ones first.
+func TopSpans(spans []Span, k int) []Span {
+	sorted := append([]Span(nil), spans...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Score > sorted[j].Score
+	})
+	top := make([]Span, 0, k)
+	for _, s := range sorted {
+		if len(top) == k {
+			break
+		}
+		crossing := false
+		for _, t := range top {
+			if (s.Start < t.Start && t.Start <= s.End && s.End < t.End) ||
+				(t.Start < s.Start && s.Start <= t.End && t.End < s.End) {
+				crossing = true
+				break
+			}
+		}
+		if !crossing {
+			top = append(top, s)
+		}
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Start != top[j].Start {
+			return top[i].Start < top[j].Start
+		}
+		return top[i].End > top[j].End
+	})
+	return top
+}
+
+// Clusters groups the mentions linked to their antecedents, given the index
+// of the antecedent of each mention, preceding it, or -1 for the mentions
+// with no antecedent. It returns the indices of the mentions of the
+// clusters of two mentions at least, sorted by their first mention.
+func Clusters(antecedents []int) [][]int {
+	cluster := make([]int, len(antecedents))
+	var clusters [][]int
+	for i, a := range antecedents {
+		if a < 0 {
+			cluster[i] = -1
+			continue
+		}
+		c := cluster[a]
+		if c < 0 {
+			c = len(clusters)
+			cluster[a] = c
+			clusters = append(clusters, []int{a})
+		}
+		cluster[i] = c
+		clusters[c] = append(clusters[c], i)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i][0] < clusters[j][0]
+	})
+	return clusters
+}
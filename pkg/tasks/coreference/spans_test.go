@@ -0,0 +1,42 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package coreference
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTopSpans(t *testing.T) {
+	spans := []Span{
+		{Start: 0, End: 1, Score: 0.9},
+		{Start: 1, End: 2, Score: 0.8}, // crossing the first one
+		{Start: 0, End: 0, Score: 0.7},
+		{Start: 3, End: 3, Score: 0.6},
+		{Start: 4, End: 5, Score: 0.1},
+	}
+	want := []Span{
+		{Start: 0, End: 1, Score: 0.9},
+		{Start: 0, End: 0, Score: 0.7},
+		{Start: 3, End: 3, Score: 0.6},
+	}
+	if got := TopSpans(spans, 3); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestClusters(t *testing.T) {
+	// "John said he would come, and Mary said she would not."
+	antecedents := []int{-1, 0, -1, -1, 3, 1}
+	want := [][]int{{0, 1, 5}, {3, 4}}
+	if got := Clusters(antecedents); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// The clusters are sorted by their first mention, not by the last.
+	if got, want := Clusters([]int{-1, -1, 1, 0}), [][]int{{0, 3}, {1, 2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier"
+	"golang.org/x/sync/errgroup"
+)
+
+// rrfK is the constant of the reciprocal rank fusion, damping the weight of
+// the first ranks, as in its original formulation.
+const rrfK = 60
+
+// ensemble runs each input through all its models, concurrently, and
+// combines their outputs, weighted by model.
+type ensemble[T any] struct {
+	models []T
+	// weights are the weights of the models, summing to one.
+	weights []float64
+}
+
+// Close closes all the models, returning the first error, if any.
+func (e *ensemble[T]) Close() error {
+	var err error
+	for _, m := range e.models {
+		if c, ok := any(m).(io.Closer); ok {
+			if cerr := c.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
+
+// run calls the function with each model, concurrently, and returns their
+// results, in the order of the models. It fails as soon as one of them does.
+func run[T, R any](ctx context.Context, e *ensemble[T], f func(context.Context, T) (R, error)) ([]R, error) {
+	results := make([]R, len(e.models))
+	g, ctx := errgroup.WithContext(ctx)
+	for i, m := range e.models {
+		i, m := i, m
+		g.Go(func() (err error) {
+			results[i], err = f(ctx, m)
+			return err
+		})
+	}
+	return results, g.Wait()
+}
+
+// Ensemble returns the model of the task T combining the outputs of the
+// models, weighted by the weights, or equally if nil: the probabilities of
+// the labels of the classifications are averaged, and the answers are
+// ranked by reciprocal rank fusion. The ensemble closes the models.
+func Ensemble[T any](models []T, weights []float64) (T, error) {
+	var obj T
+	if len(models) == 0 {
+		return obj, errors.New("ensemble: no models")
+	}
+	w, err := normalizeWeights(len(models), weights)
+	if err != nil {
+		return obj, err
+	}
+	var e any
+	switch ms := any(models).(type) {
+	case []textclassification.Interface:
+		e = textClassificationEnsemble{&ensemble[textclassification.Interface]{ms, w}}
+	case []zeroshotclassifier.Interface:
+		e = zeroShotEnsemble{&ensemble[zeroshotclassifier.Interface]{ms, w}}
+	case []questionanswering.Interface:
+		e = questionAnsweringEnsemble{&ensemble[questionanswering.Interface]{ms, w}}
+	}
+	obj, ok := e.(T)
+	if !ok {
+		return obj, fmt.Errorf("ensemble: type %T not supported", obj)
+	}
+	return obj, nil
+}
+
+// normalizeWeights returns the weights of n models, scaled to sum to one.
+func normalizeWeights(n int, weights []float64) ([]float64, error) {
+	if weights == nil {
+		weights = make([]float64, n)
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	if len(weights) != n {
+		return nil, fmt.Errorf("ensemble: %d weights for %d models", len(weights), n)
+	}
+	sum := 0.0
+	for _, w := range weights {
+		if !(w > 0) {
+			return nil, fmt.Errorf("ensemble: invalid weight %v", w)
+		}
+		sum += w
+	}
+	out := make([]float64, n)
+	for i, w := range weights {
+		out[i] = w / sum
+	}
+	return out, nil
+}
+
+// averageScores returns the weighted average of the scores of the labels,
+// the labels missing from a response scoring zero, sorted by descending
+// score, the ties in order of appearance.
+func averageScores(weights []float64, labels [][]string, scores [][]float64) ([]string, []float64) {
+	index := make(map[string]int)
+	var outLabels []string
+	var outScores []float64
+	for i, ls := range labels {
+		for j, l := range ls {
+			k, ok := index[l]
+			if !ok {
+				k = len(outLabels)
+				index[l] = k
+				outLabels = append(outLabels, l)
+				outScores = append(outScores, 0)
+			}
+			outScores[k] += weights[i] * scores[i][j]
+		}
+	}
+	sort.Stable(byScore{outLabels, outScores})
+	return outLabels, outScores
+}
+
+type textClassificationEnsemble struct {
+	*ensemble[textclassification.Interface]
+}
+
+func (e textClassificationEnsemble) Classify(ctx context.Context, text string, parameters textclassification.Parameters) (textclassification.Response, error) {
+	if parameters.Explain {
+		return textclassification.Response{}, textclassification.ErrExplainNotSupported
+	}
+	resps, err := run(ctx, e.ensemble, func(ctx context.Context, m textclassification.Interface) (textclassification.Response, error) {
+		return m.Classify(ctx, text, parameters)
+	})
+	if err != nil {
+		return textclassification.Response{}, err
+	}
+	labels, scores := make([][]string, len(resps)), make([][]float64, len(resps))
+	for i, r := range resps {
+		labels[i], scores[i] = r.Labels, r.Scores
+	}
+	resp := textclassification.Response{}
+	resp.Labels, resp.Scores = averageScores(e.weights, labels, scores)
+	return resp, nil
+}
+
+type zeroShotEnsemble struct {
+	*ensemble[zeroshotclassifier.Interface]
+}
+
+func (e zeroShotEnsemble) Classify(ctx context.Context, text string, parameters zeroshotclassifier.Parameters) (zeroshotclassifier.Response, error) {
+	resps, err := run(ctx, e.ensemble, func(ctx context.Context, m zeroshotclassifier.Interface) (zeroshotclassifier.Response, error) {
+		return m.Classify(ctx, text, parameters)
+	})
+	if err != nil {
+		return zeroshotclassifier.Response{}, err
+	}
+	labels, scores := make([][]string, len(resps)), make([][]float64, len(resps))
+	for i, r := range resps {
+		labels[i], scores[i] = r.Labels, r.Scores
+	}
+	resp := zeroshotclassifier.Response{}
+	resp.Labels, resp.Scores = averageScores(e.weights, labels, scores)
+	return resp, nil
+}
+
+type questionAnsweringEnsemble struct {
+	*ensemble[questionanswering.Interface]
+}
+
+// Answer returns the answers of all the models, ranked by the weighted sum
+// of their reciprocal ranks. The score of an answer is the weighted average
+// of its scores, the models not returning it scoring zero.
+func (e questionAnsweringEnsemble) Answer(ctx context.Context, question string, passage string, opts *questionanswering.Options) (questionanswering.Response, error) {
+	if opts == nil {
+		opts = &questionanswering.Options{}
+	}
+	if opts.Explain {
+		return questionanswering.Response{}, questionanswering.ErrExplainNotSupported
+	}
+	resps, err := run(ctx, e.ensemble, func(ctx context.Context, m questionanswering.Interface) (questionanswering.Response, error) {
+		o := *opts // the models may set the defaults of the options
+		return m.Answer(ctx, question, passage, &o)
+	})
+	if err != nil {
+		return questionanswering.Response{}, err
+	}
+
+	type span struct{ start, end int }
+	index := make(map[span]int)
+	var answers []questionanswering.Answer
+	var fused []float64
+	maxAnswers := opts.MaxAnswers
+	for i, r := range resps {
+		if opts.MaxAnswers == 0 && len(r.Answers) > maxAnswers {
+			maxAnswers = len(r.Answers) // as many as the default of the models
+		}
+		for rank, a := range r.Answers {
+			s := span{a.ByteStart, a.ByteEnd}
+			k, ok := index[s]
+			if !ok {
+				k = len(answers)
+				index[s] = k
+				answers = append(answers, a)
+				answers[k].Score = 0
+				fused = append(fused, 0)
+			}
+			answers[k].Score += e.weights[i] * a.Score
+			fused[k] += e.weights[i] / float64(rrfK+rank+1)
+		}
+	}
+	sort.Stable(byFusedRank{answers, fused})
+	if len(answers) > maxAnswers {
+		answers = answers[:maxAnswers]
+	}
+	return questionanswering.Response{Answers: answers}, nil
+}
+
+type byFusedRank struct {
+	answers []questionanswering.Answer
+	fused   []float64
+}
+
+func (s byFusedRank) Len() int           { return len(s.fused) }
+func (s byFusedRank) Less(i, j int) bool { return s.fused[i] > s.fused[j] }
+func (s byFusedRank) Swap(i, j int) {
+	s.answers[i], s.answers[j] = s.answers[j], s.answers[i]
+	s.fused[i], s.fused[j] = s.fused[j], s.fused[i]
+}
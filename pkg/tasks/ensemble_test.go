@@ -0,0 +1,165 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedClassifier returns the same classification for any text.
+type fixedClassifier struct {
+	labels []string
+	scores []float64
+	err    error
+}
+
+func (c fixedClassifier) Classify(context.Context, string, textclassification.Parameters) (textclassification.Response, error) {
+	return textclassification.Response{Labels: c.labels, Scores: c.scores}, c.err
+}
+
+// fixedAnswerer returns the same answers for any question.
+type fixedAnswerer struct {
+	answers []questionanswering.Answer
+}
+
+func (a fixedAnswerer) Answer(context.Context, string, string, *questionanswering.Options) (questionanswering.Response, error) {
+	return questionanswering.Response{Answers: a.answers}, nil
+}
+
+func TestNormalizeWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		n       int
+		weights []float64
+		want    []float64
+		wantErr bool
+	}{
+		{name: "equal", n: 4, want: []float64{0.25, 0.25, 0.25, 0.25}},
+		{name: "weighted", n: 2, weights: []float64{3, 1}, want: []float64{0.75, 0.25}},
+		{name: "mismatch", n: 2, weights: []float64{1}, wantErr: true},
+		{name: "zero", n: 2, weights: []float64{1, 0}, wantErr: true},
+		{name: "negative", n: 2, weights: []float64{1, -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeWeights(tt.n, tt.weights)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDeltaSlice(t, tt.want, got, 1e-9)
+		})
+	}
+}
+
+func TestAverageScores(t *testing.T) {
+	labels, scores := averageScores(
+		[]float64{0.5, 0.5},
+		[][]string{{"pos", "neg"}, {"neg", "pos", "neu"}},
+		[][]float64{{0.8, 0.2}, {0.6, 0.3, 0.1}},
+	)
+	assert.Equal(t, []string{"pos", "neg", "neu"}, labels)
+	assert.InDeltaSlice(t, []float64{0.55, 0.4, 0.05}, scores, 1e-9)
+}
+
+func TestEnsemble_Errors(t *testing.T) {
+	_, err := Ensemble[textclassification.Interface](nil, nil)
+	assert.Error(t, err)
+
+	models := []textclassification.Interface{fixedClassifier{}, fixedClassifier{}}
+	_, err = Ensemble(models, []float64{1})
+	assert.Error(t, err)
+
+	type unsupported interface{ Foo() }
+	_, err = Ensemble([]unsupported{nil, nil}, nil)
+	assert.Error(t, err)
+}
+
+func TestTextClassificationEnsemble(t *testing.T) {
+	m, err := Ensemble([]textclassification.Interface{
+		fixedClassifier{labels: []string{"pos", "neg"}, scores: []float64{0.9, 0.1}},
+		fixedClassifier{labels: []string{"neg", "pos"}, scores: []float64{0.7, 0.3}},
+	}, []float64{1, 3})
+	require.NoError(t, err)
+
+	resp, err := m.Classify(context.Background(), "text", textclassification.Parameters{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"neg", "pos"}, resp.Labels)
+	assert.InDeltaSlice(t, []float64{0.55, 0.45}, resp.Scores, 1e-9)
+
+	_, err = m.Classify(context.Background(), "text", textclassification.Parameters{Explain: true})
+	assert.ErrorIs(t, err, textclassification.ErrExplainNotSupported)
+
+	errModel := errors.New("model failed")
+	failing, err := Ensemble([]textclassification.Interface{
+		fixedClassifier{labels: []string{"pos"}, scores: []float64{1}},
+		fixedClassifier{err: errModel},
+	}, nil)
+	require.NoError(t, err)
+	_, err = failing.Classify(context.Background(), "text", textclassification.Parameters{})
+	assert.ErrorIs(t, err, errModel, "the ensemble fails if any of its models does")
+}
+
+func TestQuestionAnsweringEnsemble(t *testing.T) {
+	answer := func(text string, start int, score float64) questionanswering.Answer {
+		return questionanswering.Answer{Text: text, Start: start, End: start + len(text), ByteStart: start, ByteEnd: start + len(text), Score: score}
+	}
+	m, err := Ensemble([]questionanswering.Interface{
+		fixedAnswerer{[]questionanswering.Answer{answer("a", 0, 0.6), answer("b", 2, 0.3), answer("c", 4, 0.1)}},
+		fixedAnswerer{[]questionanswering.Answer{answer("b", 2, 0.8), answer("a", 0, 0.2)}},
+		fixedAnswerer{[]questionanswering.Answer{answer("b", 2, 0.5), answer("d", 6, 0.5)}},
+	}, nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		opts       *questionanswering.Options
+		wantTexts  []string
+		wantScores []float64
+	}{
+		{
+			name:       "default",
+			wantTexts:  []string{"b", "a", "d"}, // as many as the most answers of a model
+			wantScores: []float64{1.6 / 3, 0.8 / 3, 0.5 / 3},
+		},
+		{
+			name:       "max answers",
+			opts:       &questionanswering.Options{MaxAnswers: 2},
+			wantTexts:  []string{"b", "a"},
+			wantScores: []float64{1.6 / 3, 0.8 / 3},
+		},
+		{
+			name:       "more max answers",
+			opts:       &questionanswering.Options{MaxAnswers: 10},
+			wantTexts:  []string{"b", "a", "d", "c"},
+			wantScores: []float64{1.6 / 3, 0.8 / 3, 0.5 / 3, 0.1 / 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := m.Answer(context.Background(), "question", "a b c d", tt.opts)
+			require.NoError(t, err)
+			var texts []string
+			var scores []float64
+			for _, a := range resp.Answers {
+				texts = append(texts, a.Text)
+				scores = append(scores, a.Score)
+			}
+			assert.Equal(t, tt.wantTexts, texts)
+			assert.InDeltaSlice(t, tt.wantScores, scores, 1e-9)
+		})
+	}
+
+	_, err = m.Answer(context.Background(), "question", "a b c d", &questionanswering.Options{Explain: true})
+	assert.ErrorIs(t, err, questionanswering.ErrExplainNotSupported)
+}
@@ -0,0 +1,456 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package evaluation runs a golden dataset, i.e. labeled examples, through
+// a model, and reports the metrics appropriate to its task, to be compared
+// with the ones of a baseline report, e.g. of the model being replaced, so
+// that the models regressing on the dataset are caught before serving.
+package evaluation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/datasets"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier"
+)
+
+// DefaultTolerance is the maximum drop of a metric from the baseline
+// allowed by Compare when no tolerance is given.
+const DefaultTolerance = 0.01
+
+// Task names.
+const (
+	TaskTextClassification     = "text-classification"
+	TaskZeroShotClassification = "zero-shot-classification"
+	TaskTokenClassification    = "token-classification"
+	TaskQuestionAnswering      = "question-answering"
+	TaskText2Text              = "text2text"
+	TaskTextEncoding           = "text-encoding"
+)
+
+// Dataset is a golden dataset of a task.
+type Dataset struct {
+	// Task is the task of the examples.
+	Task string `json:"task"`
+	// CandidateLabels are the labels of the zero-shot classification.
+	CandidateLabels []string `json:"candidate_labels,omitempty"`
+	// PoolingStrategy is the pooling strategy of the text encoding.
+	PoolingStrategy int `json:"pooling_strategy,omitempty"`
+	// Examples are the labeled examples.
+	Examples []Example `json:"examples"`
+}
+
+// Example is an input with its expected output.
+type Example = datasets.Example
+
+// Entity is an entity of the token classification.
+type Entity = datasets.Entity
+
+// ReadDataset reads and validates the dataset of the JSON file.
+func ReadDataset(filename string) (*Dataset, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	ds := &Dataset{}
+	if err := json.Unmarshal(data, ds); err != nil {
+		return nil, fmt.Errorf("evaluation: invalid dataset %#v: %w", filename, err)
+	}
+	if err := ds.validate(); err != nil {
+		return nil, fmt.Errorf("evaluation: invalid dataset %#v: %w", filename, err)
+	}
+	return ds, nil
+}
+
+// LoadDataset reads and validates the dataset of the file, either a golden
+// dataset in the JSON format of ReadDataset, or a dataset in a format of the
+// datasets package, by extension, e.g. CoNLL or SQuAD, of the task implied
+// by the format, or else of the given one. The candidate labels of a
+// zero-shot classification are the distinct labels of its examples.
+func LoadDataset(filename, task string) (*Dataset, error) {
+	format, ok := datasets.FormatOf(filename)
+	if !ok || format == datasets.FormatSQuAD && !isSQuAD(filename) {
+		return ReadDataset(filename)
+	}
+	if t := format.Task(); t != "" {
+		if task != "" && task != t {
+			return nil, fmt.Errorf("evaluation: %s dataset %#v for task %#v", format, filename, task)
+		}
+		task = t
+	}
+	if task == "" {
+		return nil, fmt.Errorf("evaluation: unknown task of the %s dataset %#v", format, filename)
+	}
+	examples, err := datasets.ReadFile(filename, format)
+	if err != nil {
+		return nil, fmt.Errorf("evaluation: invalid dataset %#v: %w", filename, err)
+	}
+	ds := &Dataset{Task: task, Examples: examples}
+	if task == TaskZeroShotClassification {
+		seen := make(map[string]bool)
+		for _, ex := range examples {
+			if ex.Label != "" && !seen[ex.Label] {
+				seen[ex.Label] = true
+				ds.CandidateLabels = append(ds.CandidateLabels, ex.Label)
+			}
+		}
+	}
+	if err := ds.validate(); err != nil {
+		return nil, fmt.Errorf("evaluation: invalid dataset %#v: %w", filename, err)
+	}
+	return ds, nil
+}
+
+// isSQuAD reports whether the JSON file is a SQuAD dataset rather than a
+// golden one, i.e. it has the "data" of the former.
+func isSQuAD(filename string) bool {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return false
+	}
+	var f struct {
+		Data json.RawMessage `json:"data"`
+	}
+	return json.Unmarshal(data, &f) == nil && f.Data != nil
+}
+
+func (ds *Dataset) validate() error {
+	if len(ds.Examples) == 0 {
+		return fmt.Errorf("no examples")
+	}
+	for i, ex := range ds.Examples {
+		var missing string
+		switch ds.Task {
+		case TaskTextClassification, TaskZeroShotClassification:
+			if ex.Label == "" {
+				missing = "label"
+			}
+		case TaskQuestionAnswering:
+			if ex.Question == "" || ex.Reference == "" {
+				missing = "question or reference"
+			}
+		case TaskText2Text, TaskTextEncoding:
+			if ex.Reference == "" {
+				missing = "reference"
+			}
+		case TaskTokenClassification:
+		default:
+			return fmt.Errorf("unsupported task %#v", ds.Task)
+		}
+		if missing != "" {
+			return fmt.Errorf("example %d: missing %s", i+1, missing)
+		}
+	}
+	if ds.Task == TaskZeroShotClassification && len(ds.CandidateLabels) < 2 {
+		return fmt.Errorf("less than two candidate labels")
+	}
+	return nil
+}
+
+// Report is the result of an evaluation.
+type Report struct {
+	// Task is the evaluated task.
+	Task string `json:"task"`
+	// Examples is the number of examples.
+	Examples int `json:"examples"`
+	// Metrics are the values of the metrics, higher being better.
+	Metrics map[string]float64 `json:"metrics"`
+}
+
+// ReadReport reads the report of the JSON file, e.g. a baseline.
+func ReadReport(filename string) (*Report, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("evaluation: invalid report %#v: %w", filename, err)
+	}
+	return r, nil
+}
+
+// Save writes the report to the JSON file.
+func (r *Report) Save(filename string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+// String returns a human-readable representation of the report.
+func (r *Report) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s evaluation of %d examples:\n", r.Task, r.Examples)
+	for _, name := range r.metricNames() {
+		fmt.Fprintf(&sb, "  %s\t%.4f\n", name, r.Metrics[name])
+	}
+	return sb.String()
+}
+
+func (r *Report) metricNames() []string {
+	names := make([]string, 0, len(r.Metrics))
+	for name := range r.Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Regression is a metric dropping from the baseline beyond the tolerance.
+type Regression struct {
+	Metric   string  `json:"metric"`
+	Baseline float64 `json:"baseline"`
+	Actual   float64 `json:"actual"`
+}
+
+func (r Regression) String() string {
+	return fmt.Sprintf("%s dropped from %.4f to %.4f", r.Metric, r.Baseline, r.Actual)
+}
+
+// Compare returns the metrics of the report dropping from the ones of the
+// baseline by more than the tolerance (DefaultTolerance if zero), sorted by
+// name. The metrics missing from the report are regressions too.
+func Compare(report, baseline *Report, tolerance float64) ([]Regression, error) {
+	if report.Task != baseline.Task {
+		return nil, fmt.Errorf("evaluation: report of task %#v compared with a baseline of task %#v", report.Task, baseline.Task)
+	}
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+	var regressions []Regression
+	for _, name := range baseline.metricNames() {
+		b := baseline.Metrics[name]
+		a, ok := report.Metrics[name]
+		if !ok {
+			a = math.NaN()
+		}
+		if !(a >= b-tolerance) {
+			regressions = append(regressions, Regression{Metric: name, Baseline: b, Actual: a})
+		}
+	}
+	return regressions, nil
+}
+
+// Evaluate runs the examples of the dataset through the model, which must
+// implement the interface of the task of the dataset, and reports the
+// metrics of the task:
+//
+//   - "accuracy" and "macro_f1" of the labels, for the text and zero-shot
+//     classifications;
+//   - "precision", "recall" and "f1" of the entities, matched by text and
+//     label, for the token classification;
+//   - "exact_match" and "f1" of the words of the best answers, for the
+//     question answering;
+//   - "bleu", "rouge1" and "rougeL" of the first generated texts, for the
+//     text generation;
+//   - "cosine_similarity" of the encodings of the inputs and of their
+//     references, on average, for the text encoding.
+func Evaluate(ctx context.Context, model any, ds *Dataset) (*Report, error) {
+	if err := ds.validate(); err != nil {
+		return nil, fmt.Errorf("evaluation: invalid dataset: %w", err)
+	}
+	var metrics map[string]float64
+	var err error
+	switch ds.Task {
+	case TaskTextClassification:
+		m, ok := model.(textclassification.Interface)
+		if !ok {
+			return nil, unsupported(model, ds.Task)
+		}
+		metrics, err = evaluateLabels(ds, func(ex Example) (string, error) {
+			resp, err := m.Classify(ctx, ex.Input, textclassification.Parameters{})
+			return topLabel(resp.Labels), err
+		})
+	case TaskZeroShotClassification:
+		m, ok := model.(zeroshotclassifier.Interface)
+		if !ok {
+			return nil, unsupported(model, ds.Task)
+		}
+		params := zeroshotclassifier.Parameters{CandidateLabels: ds.CandidateLabels}
+		metrics, err = evaluateLabels(ds, func(ex Example) (string, error) {
+			resp, err := m.Classify(ctx, ex.Input, params)
+			return topLabel(resp.Labels), err
+		})
+	case TaskTokenClassification:
+		m, ok := model.(tokenclassification.Interface)
+		if !ok {
+			return nil, unsupported(model, ds.Task)
+		}
+		metrics, err = evaluateTokenClassification(ctx, m, ds)
+	case TaskQuestionAnswering:
+		m, ok := model.(questionanswering.Interface)
+		if !ok {
+			return nil, unsupported(model, ds.Task)
+		}
+		metrics, err = evaluateQuestionAnswering(ctx, m, ds)
+	case TaskText2Text:
+		m, ok := model.(text2text.Interface)
+		if !ok {
+			return nil, unsupported(model, ds.Task)
+		}
+		metrics, err = evaluateText2Text(ctx, m, ds)
+	case TaskTextEncoding:
+		m, ok := model.(textencoding.Interface)
+		if !ok {
+			return nil, unsupported(model, ds.Task)
+		}
+		metrics, err = evaluateTextEncoding(ctx, m, ds)
+	default:
+		return nil, fmt.Errorf("evaluation: unsupported task %#v", ds.Task)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Report{Task: ds.Task, Examples: len(ds.Examples), Metrics: metrics}, nil
+}
+
+func unsupported(model any, task string) error {
+	return fmt.Errorf("evaluation: %T doesn't support the %s task", model, task)
+}
+
+func topLabel(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	return labels[0]
+}
+
+// exampleError returns the error of the i-th example.
+func exampleError(i int, err error) error {
+	return fmt.Errorf("evaluation: example %d: %w", i+1, err)
+}
+
+func evaluateLabels(ds *Dataset, predict func(Example) (string, error)) (map[string]float64, error) {
+	predictions := make([]string, len(ds.Examples))
+	targets := make([]string, len(ds.Examples))
+	for i, ex := range ds.Examples {
+		p, err := predict(ex)
+		if err != nil {
+			return nil, exampleError(i, err)
+		}
+		predictions[i], targets[i] = p, ex.Label
+	}
+	return map[string]float64{
+		"accuracy": Accuracy(predictions, targets),
+		"macro_f1": MacroF1(predictions, targets),
+	}, nil
+}
+
+func evaluateTokenClassification(ctx context.Context, m tokenclassification.Interface, ds *Dataset) (map[string]float64, error) {
+	params := tokenclassification.Parameters{AggregationStrategy: tokenclassification.AggregationStrategySimple}
+	tp, fp, fn := 0, 0, 0
+	for i, ex := range ds.Examples {
+		resp, err := m.Classify(ctx, ex.Input, params)
+		if err != nil {
+			return nil, exampleError(i, err)
+		}
+		expected := make(map[Entity]int, len(ex.Entities))
+		for _, e := range ex.Entities {
+			expected[e]++
+		}
+		for _, t := range resp.Tokens {
+			e := Entity{Text: t.Text, Label: t.Label}
+			if expected[e] > 0 {
+				expected[e]--
+				tp++
+			} else {
+				fp++
+			}
+		}
+		for _, n := range expected {
+			fn += n
+		}
+	}
+	metrics := map[string]float64{"precision": 0, "recall": 0, "f1": f1(tp, fp, fn)}
+	if tp+fp > 0 {
+		metrics["precision"] = float64(tp) / float64(tp+fp)
+	}
+	if tp+fn > 0 {
+		metrics["recall"] = float64(tp) / float64(tp+fn)
+	}
+	return metrics, nil
+}
+
+func evaluateQuestionAnswering(ctx context.Context, m questionanswering.Interface, ds *Dataset) (map[string]float64, error) {
+	exact, f1Sum := 0, 0.0
+	for i, ex := range ds.Examples {
+		resp, err := m.Answer(ctx, ex.Question, ex.Input, &questionanswering.Options{MaxAnswers: 1})
+		if err != nil {
+			return nil, exampleError(i, err)
+		}
+		answer := ""
+		if len(resp.Answers) > 0 {
+			answer = resp.Answers[0].Text
+		}
+		if strings.Join(Words(answer), " ") == strings.Join(Words(ex.Reference), " ") {
+			exact++
+		}
+		f1Sum += TokenF1(answer, ex.Reference)
+	}
+	n := float64(len(ds.Examples))
+	return map[string]float64{"exact_match": float64(exact) / n, "f1": f1Sum / n}, nil
+}
+
+func evaluateText2Text(ctx context.Context, m text2text.Interface, ds *Dataset) (map[string]float64, error) {
+	predictions := make([]string, len(ds.Examples))
+	references := make([]string, len(ds.Examples))
+	rouge1, rougeL := 0.0, 0.0
+	for i, ex := range ds.Examples {
+		resp, err := m.Generate(ctx, ex.Input, nil)
+		if err != nil {
+			return nil, exampleError(i, err)
+		}
+		if len(resp.Texts) > 0 {
+			predictions[i] = resp.Texts[0]
+		}
+		references[i] = ex.Reference
+		rouge1 += Rouge1(predictions[i], ex.Reference)
+		rougeL += RougeL(predictions[i], ex.Reference)
+	}
+	n := float64(len(ds.Examples))
+	return map[string]float64{
+		"bleu":   BLEU(predictions, references),
+		"rouge1": rouge1 / n,
+		"rougeL": rougeL / n,
+	}, nil
+}
+
+func evaluateTextEncoding(ctx context.Context, m textencoding.Interface, ds *Dataset) (map[string]float64, error) {
+	encode := func(text string) ([]float64, error) {
+		resp, err := m.Encode(ctx, text, ds.PoolingStrategy)
+		if err != nil {
+			return nil, err
+		}
+		return resp.Vector.Data().F64(), nil
+	}
+	sum := 0.0
+	for i, ex := range ds.Examples {
+		a, err := encode(ex.Input)
+		if err != nil {
+			return nil, exampleError(i, err)
+		}
+		b, err := encode(ex.Reference)
+		if err != nil {
+			return nil, exampleError(i, err)
+		}
+		if len(a) != len(b) {
+			return nil, exampleError(i, fmt.Errorf("vectors of different sizes %d and %d", len(a), len(b)))
+		}
+		sum += CosineSimilarity(a, b)
+	}
+	return map[string]float64{"cosine_similarity": sum / float64(len(ds.Examples))}, nil
+}
@@ -0,0 +1,212 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package evaluation
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClassifier map[string]string
+
+func (f fakeClassifier) Classify(_ context.Context, text string, _ textclassification.Parameters) (textclassification.Response, error) {
+	return textclassification.Response{Labels: []string{f[text]}, Scores: []float64{1}}, nil
+}
+
+type fakeTokenClassifier map[string][]tokenclassification.Token
+
+func (f fakeTokenClassifier) Classify(_ context.Context, text string, _ tokenclassification.Parameters) (tokenclassification.Response, error) {
+	return tokenclassification.Response{Tokens: f[text]}, nil
+}
+
+type fakeAnswerer map[string]string
+
+func (f fakeAnswerer) Answer(_ context.Context, question, _ string, _ *questionanswering.Options) (questionanswering.Response, error) {
+	return questionanswering.Response{Answers: []questionanswering.Answer{{Text: f[question]}}}, nil
+}
+
+type fakeGenerator map[string]string
+
+func (f fakeGenerator) Generate(_ context.Context, text string, _ *text2text.Options) (text2text.Response, error) {
+	return text2text.Response{Texts: []string{f[text]}, Scores: []float64{0}}, nil
+}
+
+type fakeEncoder map[string][]float64
+
+func (f fakeEncoder) Encode(_ context.Context, text string, _ int) (textencoding.Response, error) {
+	return textencoding.Response{Vector: mat.NewVecDense(f[text])}, nil
+}
+
+func TestMetrics(t *testing.T) {
+	predictions := []string{"pos", "pos", "neg", "neg"}
+	targets := []string{"pos", "neg", "neg", "neg"}
+	assert.Equal(t, 0.75, Accuracy(predictions, targets))
+	// pos: P=1/2 R=1 F1=2/3; neg: P=1 R=2/3 F1=4/5
+	assert.InDelta(t, (2.0/3+4.0/5)/2, MacroF1(predictions, targets), 1e-9)
+
+	assert.Equal(t, []string{"the", "cat", "s", "hat", "42"}, Words("The cat's hat, 42!"))
+	assert.InDelta(t, 1, TokenF1("The Cat", "the cat."), 1e-9)
+	assert.InDelta(t, 2.0/3, Rouge1("the cat", "the black cat sat"), 1e-9)
+	// LCS of "a b c d" and "a c b d" is 3.
+	assert.InDelta(t, 0.75, RougeL("a b c d", "a c b d"), 1e-9)
+	assert.Zero(t, RougeL("", "a"))
+
+	assert.InDelta(t, 1, BLEU([]string{"the cat sat on the mat"}, []string{"the cat sat on the mat"}), 1e-9)
+	assert.Zero(t, BLEU([]string{"dog"}, []string{"the cat"}))
+	short := BLEU([]string{"the cat"}, []string{"the cat sat on the mat"})
+	assert.Greater(t, short, 0.0)
+	assert.Less(t, short, 0.5)
+
+	assert.InDelta(t, 1, CosineSimilarity([]float64{1, 2}, []float64{2, 4}), 1e-9)
+	assert.InDelta(t, 0, CosineSimilarity([]float64{1, 0}, []float64{0, 3}), 1e-9)
+	assert.Zero(t, CosineSimilarity([]float64{0, 0}, []float64{1, 1}))
+}
+
+func TestEvaluate(t *testing.T) {
+	ctx := context.Background()
+
+	report, err := Evaluate(ctx, fakeClassifier{"good": "pos", "bad": "pos"}, &Dataset{
+		Task:     TaskTextClassification,
+		Examples: []Example{{Input: "good", Label: "pos"}, {Input: "bad", Label: "neg"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Examples)
+	assert.Equal(t, 0.5, report.Metrics["accuracy"])
+
+	report, err = Evaluate(ctx, fakeTokenClassifier{"Rome and Paris": {
+		{Text: "Rome", Label: "LOC"}, {Text: "and", Label: "ORG"},
+	}}, &Dataset{
+		Task: TaskTokenClassification,
+		Examples: []Example{{Input: "Rome and Paris", Entities: []Entity{
+			{Text: "Rome", Label: "LOC"}, {Text: "Paris", Label: "LOC"},
+		}}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"precision": 0.5, "recall": 0.5, "f1": 0.5}, report.Metrics)
+
+	report, err = Evaluate(ctx, fakeAnswerer{"who?": "the Cat", "where?": "on a mat"}, &Dataset{
+		Task: TaskQuestionAnswering,
+		Examples: []Example{
+			{Input: "p", Question: "who?", Reference: "the cat"},
+			{Input: "p", Question: "where?", Reference: "mat"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, report.Metrics["exact_match"])
+	assert.InDelta(t, (1+0.5)/2, report.Metrics["f1"], 1e-9)
+
+	report, err = Evaluate(ctx, fakeGenerator{"x": "the cat sat on the mat"}, &Dataset{
+		Task:     TaskText2Text,
+		Examples: []Example{{Input: "x", Reference: "the cat sat on the mat"}},
+	})
+	require.NoError(t, err)
+	assert.InDelta(t, 1, report.Metrics["bleu"], 1e-9)
+	assert.InDelta(t, 1, report.Metrics["rougeL"], 1e-9)
+
+	report, err = Evaluate(ctx, fakeEncoder{"a": {1, 0}, "b": {1, 1}}, &Dataset{
+		Task:     TaskTextEncoding,
+		Examples: []Example{{Input: "a", Reference: "b"}},
+	})
+	require.NoError(t, err)
+	assert.InDelta(t, 1/math.Sqrt2, report.Metrics["cosine_similarity"], 1e-9)
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	ctx := context.Background()
+	_, err := Evaluate(ctx, fakeEncoder{}, &Dataset{
+		Task:     TaskTextClassification,
+		Examples: []Example{{Input: "a", Label: "pos"}},
+	})
+	assert.Error(t, err, "the model doesn't support the task")
+
+	_, err = Evaluate(ctx, fakeClassifier{}, &Dataset{Task: TaskTextClassification})
+	assert.Error(t, err, "no examples")
+
+	_, err = Evaluate(ctx, fakeClassifier{}, &Dataset{
+		Task:     TaskTextClassification,
+		Examples: []Example{{Input: "a"}},
+	})
+	assert.Error(t, err, "missing label")
+}
+
+func TestCompare(t *testing.T) {
+	baseline := &Report{Task: TaskTextClassification, Metrics: map[string]float64{"accuracy": 0.9, "macro_f1": 0.8}}
+	report := &Report{Task: TaskTextClassification, Metrics: map[string]float64{"accuracy": 0.895, "macro_f1": 0.7}}
+
+	regressions, err := Compare(report, baseline, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []Regression{{Metric: "macro_f1", Baseline: 0.8, Actual: 0.7}}, regressions)
+
+	regressions, err = Compare(report, baseline, 0.2)
+	require.NoError(t, err)
+	assert.Empty(t, regressions)
+
+	delete(report.Metrics, "accuracy")
+	regressions, err = Compare(report, baseline, 0.2)
+	require.NoError(t, err)
+	require.Len(t, regressions, 1)
+	assert.Equal(t, "accuracy", regressions[0].Metric)
+
+	_, err = Compare(&Report{Task: TaskText2Text}, baseline, 0)
+	assert.Error(t, err)
+}
+
+func TestReportSave(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "report.json")
+	report := &Report{Task: TaskTextClassification, Examples: 2, Metrics: map[string]float64{"accuracy": 0.5}}
+	require.NoError(t, report.Save(filename))
+	read, err := ReadReport(filename)
+	require.NoError(t, err)
+	assert.Equal(t, report, read)
+	assert.Equal(t, "text-classification evaluation of 2 examples:\n  accuracy\t0.5000\n", report.String())
+}
+
+func TestLoadDataset(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		filename := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(filename, []byte(data), 0644))
+		return filename
+	}
+
+	golden := write("golden.json", `{"task": "text-classification", "examples": [{"input": "Hi", "label": "greeting"}]}`)
+	ds, err := LoadDataset(golden, "")
+	require.NoError(t, err)
+	assert.Equal(t, TaskTextClassification, ds.Task)
+
+	squad := write("dev.json", `{"data": [{"paragraphs": [{"context": "Rome is in Italy.", "qas": [{"id": "1", "question": "Where is Rome?", "answers": [{"text": "Italy"}]}]}]}]}`)
+	ds, err = LoadDataset(squad, "")
+	require.NoError(t, err)
+	assert.Equal(t, TaskQuestionAnswering, ds.Task)
+	assert.Equal(t, "Italy", ds.Examples[0].Reference)
+	_, err = LoadDataset(squad, TaskText2Text)
+	assert.Error(t, err)
+
+	conll := write("test.conll", "Rome B-LOC\nis O\n")
+	ds, err = LoadDataset(conll, "")
+	require.NoError(t, err)
+	assert.Equal(t, TaskTokenClassification, ds.Task)
+	assert.Equal(t, []Entity{{Text: "Rome", Label: "LOC"}}, ds.Examples[0].Entities)
+
+	csv := write("labels.csv", "text,label\nI love it,positive\nI hate it,negative\nGreat,positive\n")
+	_, err = LoadDataset(csv, "")
+	assert.ErrorContains(t, err, "unknown task")
+	ds, err = LoadDataset(csv, TaskZeroShotClassification)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"positive", "negative"}, ds.CandidateLabels)
+	assert.Len(t, ds.Examples, 3)
+}
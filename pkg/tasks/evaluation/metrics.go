@@ -0,0 +1,208 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package evaluation
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Accuracy returns the fraction of the predictions equal to the targets.
+func Accuracy(predictions, targets []string) float64 {
+	if len(targets) == 0 {
+		return 0
+	}
+	correct := 0
+	for i, t := range targets {
+		if predictions[i] == t {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(targets))
+}
+
+// MacroF1 returns the unweighted mean of the F1 scores of the labels of the
+// targets and of the predictions.
+func MacroF1(predictions, targets []string) float64 {
+	tp := make(map[string]int)
+	fp := make(map[string]int)
+	fn := make(map[string]int)
+	var labels []string
+	seen := make(map[string]bool)
+	for i, t := range targets {
+		p := predictions[i]
+		for _, l := range []string{t, p} {
+			if !seen[l] {
+				seen[l] = true
+				labels = append(labels, l)
+			}
+		}
+		if p == t {
+			tp[t]++
+		} else {
+			fp[p]++
+			fn[t]++
+		}
+	}
+	if len(labels) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, l := range labels {
+		sum += f1(tp[l], fp[l], fn[l])
+	}
+	return sum / float64(len(labels))
+}
+
+// f1 returns the F1 score of the true positives, false positives and false
+// negatives.
+func f1(tp, fp, fn int) float64 {
+	if tp == 0 {
+		return 0
+	}
+	return 2 * float64(tp) / float64(2*tp+fp+fn)
+}
+
+// Words returns the lowercase words of the text, split on the characters
+// other than letters and digits.
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// TokenF1 returns the F1 score of the words of the prediction, as a bag of
+// words, against the ones of the reference.
+func TokenF1(prediction, reference string) float64 {
+	p, r := Words(prediction), Words(reference)
+	if len(p) == 0 || len(r) == 0 {
+		if len(p) == len(r) {
+			return 1
+		}
+		return 0
+	}
+	counts := make(map[string]int, len(r))
+	for _, w := range r {
+		counts[w]++
+	}
+	common := 0
+	for _, w := range p {
+		if counts[w] > 0 {
+			counts[w]--
+			common++
+		}
+	}
+	return f1(common, len(p)-common, len(r)-common)
+}
+
+// Rouge1 returns the ROUGE-1 F1 score of the prediction against the
+// reference, i.e. the F1 score of their overlapping words.
+func Rouge1(prediction, reference string) float64 {
+	return TokenF1(prediction, reference)
+}
+
+// RougeL returns the ROUGE-L F1 score of the prediction against the
+// reference, based on the longest common subsequence of their words.
+func RougeL(prediction, reference string) float64 {
+	p, r := Words(prediction), Words(reference)
+	if len(p) == 0 || len(r) == 0 {
+		return 0
+	}
+	lcs := lcsLength(p, r)
+	return f1(lcs, len(p)-lcs, len(r)-lcs)
+}
+
+// lcsLength returns the length of the longest common subsequence of a and b.
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] >= cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// bleuOrder is the maximum order of the n-grams of BLEU.
+const bleuOrder = 4
+
+// BLEU returns the corpus BLEU score of the predictions against the
+// references, with the n-grams up to 4 words and the brevity penalty.
+// The precisions of the n-grams are smoothed by adding one to their counts,
+// so that a corpus of short texts doesn't score zero.
+func BLEU(predictions, references []string) float64 {
+	var matches, totals [bleuOrder]int
+	predLen, refLen := 0, 0
+	for i, pred := range predictions {
+		p, r := Words(pred), Words(references[i])
+		predLen += len(p)
+		refLen += len(r)
+		for n := 1; n <= bleuOrder; n++ {
+			refCounts := ngrams(r, n)
+			for g, c := range ngrams(p, n) {
+				if rc := refCounts[g]; rc < c {
+					c = rc
+				}
+				matches[n-1] += c
+			}
+			if len(p) >= n {
+				totals[n-1] += len(p) - n + 1
+			}
+		}
+	}
+	if predLen == 0 {
+		return 0
+	}
+	logPrecision := 0.0
+	for n := 0; n < bleuOrder; n++ {
+		m, t := float64(matches[n]), float64(totals[n])
+		if n > 0 {
+			m, t = m+1, t+1
+		}
+		if m == 0 {
+			return 0
+		}
+		logPrecision += math.Log(m/t) / bleuOrder
+	}
+	brevity := 1.0
+	if predLen < refLen {
+		brevity = math.Exp(1 - float64(refLen)/float64(predLen))
+	}
+	return brevity * math.Exp(logPrecision)
+}
+
+// ngrams returns the counts of the n-grams of the words.
+func ngrams(words []string, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i+n <= len(words); i++ {
+		counts[strings.Join(words[i:i+n], " ")]++
+	}
+	return counts
+}
+
+// CosineSimilarity returns the cosine similarity of the vectors, zero if
+// either is null.
+func CosineSimilarity(a, b []float64) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
@@ -13,11 +13,20 @@ import (
 // Finalize finalizes the structures i.e. closes the underlying models.
 // If there is an error, it logs it and then calls os.Exit(1).
 func Finalize(i any) {
-	ii, ok := i.(io.Closer)
-	if !ok {
-		return
-	}
-	if err := ii.Close(); err != nil {
+	if err := Close(i); err != nil {
 		log.Fatal().Err(err).Send()
 	}
 }
+
+// Close closes the model returned by Load, if it's an io.Closer, as all
+// the models of the tasks are: it releases the memory of its weights, once
+// not shared with other models anymore, closes its embeddings store and
+// waits for its background requests, e.g. the ones mirrored to the shadow
+// versions, so that the models can be loaded and unloaded without leaking.
+// The model must not be used afterwards.
+func Close(m any) error {
+	if c, ok := m.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/coreference"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/languagemodeling"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+)
+
+// limited fails the requests whose inputs are longer than the maximum
+// length, in the tokens of the model, before their inference.
+type limited[T any] struct {
+	m         T
+	tokenizer Tokenizer
+	maxLength int
+}
+
+// Unwrap returns the model.
+func (l limited[T]) Unwrap() any {
+	return l.m
+}
+
+// Close closes the model.
+func (l limited[T]) Close() error {
+	if c, ok := any(l.m).(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// check fails if the text is longer than the maximum length.
+func (l limited[T]) check(text string) error {
+	if n := len(l.tokenizer.Tokenize(text)); n > l.maxLength {
+		return fmt.Errorf("%w: %d > %d", errdefs.ErrInputTooLong, n, l.maxLength)
+	}
+	return nil
+}
+
+// checkPair fails if the text, paired with the second text, if any, is
+// longer than the maximum length. The tokens of the second text are counted
+// without the special tokens preceding it, e.g. [CLS], which aren't repeated.
+func (l limited[T]) checkPair(text, pair string) error {
+	if pair == "" {
+		return l.check(text)
+	}
+	n := len(l.tokenizer.Tokenize(text)) + len(l.tokenizer.Tokenize(pair)) - 1
+	if n > l.maxLength {
+		return fmt.Errorf("%w: %d > %d", errdefs.ErrInputTooLong, n, l.maxLength)
+	}
+	return nil
+}
+
+// wrapMaxLength returns the model of the task T failing the requests whose
+// inputs are longer than the maximum length. The model must expose its
+// tokens (see Tokenizer).
+func wrapMaxLength[T any](m T, maxLength int) (T, error) {
+	var w any
+	if tok, ok := AsTokenizer(m); ok {
+		switch p := any(&m).(type) {
+		case *textclassification.Interface:
+			w = textClassificationLimited{limited[textclassification.Interface]{*p, tok, maxLength}}
+		case *tokenclassification.Interface:
+			w = tokenClassificationLimited{limited[tokenclassification.Interface]{*p, tok, maxLength}}
+		case *textencoding.Interface:
+			w = textEncodingLimited{limited[textencoding.Interface]{*p, tok, maxLength}}
+		case *languagemodeling.Interface:
+			w = languageModelingLimited{limited[languagemodeling.Interface]{*p, tok, maxLength}}
+		case *coreference.Interface:
+			w = coreferenceLimited{limited[coreference.Interface]{*p, tok, maxLength}}
+		}
+	}
+	obj, ok := w.(T)
+	if !ok {
+		return obj, fmt.Errorf("loader: max length not supported for type %T", m)
+	}
+	return obj, nil
+}
+
+type textClassificationLimited struct {
+	limited[textclassification.Interface]
+}
+
+func (l textClassificationLimited) Classify(ctx context.Context, text string, parameters textclassification.Parameters) (textclassification.Response, error) {
+	if err := l.checkPair(text, parameters.TextPair); err != nil {
+		return textclassification.Response{}, err
+	}
+	return l.m.Classify(ctx, text, parameters)
+}
+
+type tokenClassificationLimited struct {
+	limited[tokenclassification.Interface]
+}
+
+func (l tokenClassificationLimited) Classify(ctx context.Context, text string, parameters tokenclassification.Parameters) (tokenclassification.Response, error) {
+	if err := l.check(text); err != nil {
+		return tokenclassification.Response{}, err
+	}
+	return l.m.Classify(ctx, text, parameters)
+}
+
+type textEncodingLimited struct {
+	limited[textencoding.Interface]
+}
+
+func (l textEncodingLimited) Encode(ctx context.Context, text string, poolingStrategy int) (textencoding.Response, error) {
+	if err := l.check(text); err != nil {
+		return textencoding.Response{}, err
+	}
+	return l.m.Encode(ctx, text, poolingStrategy)
+}
+
+type languageModelingLimited struct {
+	limited[languagemodeling.Interface]
+}
+
+func (l languageModelingLimited) Predict(ctx context.Context, text string, parameters languagemodeling.Parameters) (languagemodeling.Response, error) {
+	if err := l.check(text); err != nil {
+		return languagemodeling.Response{}, err
+	}
+	return l.m.Predict(ctx, text, parameters)
+}
+
+type coreferenceLimited struct {
+	limited[coreference.Interface]
+}
+
+func (l coreferenceLimited) Resolve(ctx context.Context, text string) (coreference.Response, error) {
+	if err := l.check(text); err != nil {
+		return coreference.Response{}, err
+	}
+	return l.m.Resolve(ctx, text)
+}
+
+// pooled encodes the texts with the pooling strategy of the configuration,
+// instead of the one of the requests.
+type pooled struct {
+	m        textencoding.Interface
+	strategy int
+}
+
+// wrapPooling returns the model of the task T encoding the texts with the
+// pooling strategy. The task must be the text encoding.
+func wrapPooling[T any](m T, pooling Pooling) (T, error) {
+	var w any
+	if p, ok := any(&m).(*textencoding.Interface); ok {
+		w = pooled{m: *p, strategy: int(poolingStrategies[pooling])}
+	}
+	obj, ok := w.(T)
+	if !ok {
+		return obj, fmt.Errorf("loader: pooling not supported for type %T", m)
+	}
+	return obj, nil
+}
+
+// Unwrap returns the model.
+func (p pooled) Unwrap() any {
+	return p.m
+}
+
+// Close closes the model.
+func (p pooled) Close() error {
+	if c, ok := p.m.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (p pooled) Encode(ctx context.Context, text string, _ int) (textencoding.Response, error) {
+	return p.m.Encode(ctx, text, p.strategy)
+}
@@ -7,6 +7,7 @@ package bert
 import (
 	"context"
 	"fmt"
+	"io"
 	"path"
 	"path/filepath"
 	"sort"
@@ -17,8 +18,12 @@ import (
 	"github.com/nlpodyssey/cybertron/pkg/models/bert"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/diskstoremode"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/languagemodeling"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sharedweights"
+	"github.com/nlpodyssey/cybertron/pkg/timings"
 	"github.com/nlpodyssey/cybertron/pkg/tokenizers"
 	"github.com/nlpodyssey/cybertron/pkg/tokenizers/wordpiecetokenizer"
+	"github.com/nlpodyssey/cybertron/pkg/trace"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
 	"github.com/nlpodyssey/cybertron/pkg/vocabulary"
 	"github.com/nlpodyssey/spago/embeddings/store/diskstore"
 	"github.com/nlpodyssey/spago/nn"
@@ -36,8 +41,9 @@ type LanguageModel struct {
 	Tokenizer *wordpiecetokenizer.WordPieceTokenizer
 	// doLowerCase is a flag indicating if the model should lowercase the input before tokenization.
 	doLowerCase bool
-	// embeddingsRepo is the repository used for loading embeddings.
-	embeddingsRepo *diskstore.Repository
+	// release releases the encoder, with its embeddings, shared with the
+	// other models loaded from the same file.
+	release func() error
 }
 
 // LoadMaskedLanguageModel returns a LanguageModel loading the model, the embeddings and the tokenizer from a directory.
@@ -53,49 +59,66 @@ func LoadMaskedLanguageModel(modelPath string) (*LanguageModel, error) {
 		return nil, fmt.Errorf("failed to load tokenizer config for text classification: %w", err)
 	}
 
-	embeddingsRepo, err := diskstore.NewRepository(filepath.Join(modelPath, "repo"), diskstoremode.DefaultDiskStoreMode)
+	m, err := nn.LoadFromFile[*bert.ModelForMaskedLM](path.Join(modelPath, "spago_model.bin"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to load embeddings repository for text classification: %w", err)
+		return nil, fmt.Errorf("failed to load bart model: %w", err)
 	}
 
-	m, err := nn.LoadFromFile[*bert.ModelForMaskedLM](path.Join(modelPath, "spago_model.bin"))
+	key, err := sharedweights.Key(path.Join(modelPath, "spago_model.bin"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load bart model: %w", err)
 	}
-
-	err = m.Bert.SetEmbeddings(embeddingsRepo)
+	encoder, release, err := sharedweights.Acquire(key, func() (*bert.Model, io.Closer, error) {
+		embeddingsRepo, err := diskstore.NewRepository(filepath.Join(modelPath, "repo"), diskstoremode.DefaultDiskStoreMode)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load embeddings repository for text classification: %w", err)
+		}
+		if err := m.Bert.SetEmbeddings(embeddingsRepo); err != nil {
+			_ = embeddingsRepo.Close()
+			return nil, nil, fmt.Errorf("failed to set embeddings: %w", err)
+		}
+		return m.Bert, embeddingsRepo, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to set embeddings: %w", err)
+		return nil, err
 	}
+	m.Bert = encoder
 
 	return &LanguageModel{
-		Model:          m,
-		vocab:          vocab,
-		Tokenizer:      tokenizer,
-		doLowerCase:    tokenizerConfig.DoLowerCase,
-		embeddingsRepo: embeddingsRepo,
+		Model:       m,
+		vocab:       vocab,
+		Tokenizer:   tokenizer,
+		doLowerCase: tokenizerConfig.DoLowerCase,
+		release:     release,
 	}, nil
 }
 
 // Close finalizes the LanguageModel resources.
 // It satisfies the interface io.Closer.
 func (m *LanguageModel) Close() error {
-	return m.embeddingsRepo.Close()
+	return m.release()
 }
 
 // Predict returns the predicted tokens
-func (m *LanguageModel) Predict(_ context.Context, text string, parameters languagemodeling.Parameters) (languagemodeling.Response, error) {
+func (m *LanguageModel) Predict(ctx context.Context, text string, parameters languagemodeling.Parameters) (languagemodeling.Response, error) {
 	if parameters.K == 0 {
 		parameters.K = defaultTopK
 	}
 
+	end := timings.Start(ctx, timings.Tokenization)
 	tokenized := pad(m.tokenize(text))
+	end()
 	if l, max := len(tokenized), m.Model.Bert.Config.MaxPositionEmbeddings; l > max {
 		return languagemodeling.Response{}, fmt.Errorf("%w: %d > %d", languagemodeling.ErrInputSequenceTooLong, l, max)
 	}
+	usage.AddTokens(ctx, len(tokenized), 0)
 
+	end = timings.Start(ctx, timings.Forward)
 	prediction := m.Model.Predict(tokenizers.GetStrings(tokenized))
+	end()
+	trace.Encode(ctx, tokenizers.GetStrings(tokenized), m.Model.Bert.EncodeEach)
 
+	offsets := tokenizers.NewRuneOffsets(text)
 	result := make([]languagemodeling.Token, 0, len(prediction))
 	for i, logits := range prediction {
 		probs := logits.Value().Softmax()
@@ -111,12 +134,14 @@ func (m *LanguageModel) Predict(_ context.Context, text string, parameters langu
 			scores = append(scores, item.Score)
 		}
 
-		start, end := tokenized[i].Offsets.Start, tokenized[i].Offsets.End
+		b := offsets.Bytes(tokenized[i].Offsets)
 		result = append(result, languagemodeling.Token{
-			Start:  start,
-			End:    end,
-			Words:  words,
-			Scores: scores,
+			Start:     tokenized[i].Offsets.Start,
+			End:       tokenized[i].Offsets.End,
+			Words:     words,
+			Scores:    scores,
+			ByteStart: b.Start,
+			ByteEnd:   b.End,
 		})
 	}
 
@@ -137,6 +162,11 @@ func (m *LanguageModel) tokenize(text string) []tokenizers.StringOffsetsPair {
 	return m.Tokenizer.Tokenize(text)
 }
 
+// Tokenize returns the tokens of the given text, as seen by the model.
+func (m *LanguageModel) Tokenize(text string) []string {
+	return tokenizers.GetStrings(m.tokenize(text))
+}
+
 func pad(tokens []tokenizers.StringOffsetsPair) []tokenizers.StringOffsetsPair {
 	return append(prepend(tokens, tokenizers.StringOffsetsPair{String: wordpiecetokenizer.DefaultClassToken}),
 		tokenizers.StringOffsetsPair{String: wordpiecetokenizer.DefaultSequenceSeparator})
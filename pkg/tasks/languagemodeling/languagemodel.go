@@ -6,7 +6,8 @@ package languagemodeling
 
 import (
 	"context"
-	"errors"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
 )
 
 const (
@@ -21,9 +22,10 @@ const (
 
 // ErrInputSequenceTooLong means that pre-processing the input text
 // produced a sequence that exceeds the maximum allowed length.
-var ErrInputSequenceTooLong = errors.New("input sequence too long")
+var ErrInputSequenceTooLong = errdefs.ErrInputTooLong
 
 // Interface defines the main functions for language modelling.
+// Its implementations are safe for concurrent use.
 type Interface interface {
 	// Predict returns the prediction of the given example.
 	Predict(ctx context.Context, text string, parameters Parameters) (Response, error)
@@ -35,12 +37,15 @@ type Parameters struct {
 	K int
 }
 
-// Token is a labeled text token.
+// Token is a labeled text token. Start and End are its offsets in the text
+// in runes, ByteStart and ByteEnd in bytes.
 type Token struct {
-	Start  int
-	End    int
-	Words  []string
-	Scores []float64
+	Start     int
+	End       int
+	Words     []string
+	Scores    []float64
+	ByteStart int
+	ByteEnd   int
 }
 
 // Response contains the response from language modelling..
@@ -0,0 +1,33 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/converter/convertertest"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextClassification_Layers(t *testing.T) {
+	m := loadSynthetic[textclassification.Interface](t, "BertForSequenceClassification", convertertest.Checkpoint{
+		"classifier.weight": {3, testHiddenSize},
+		"classifier.bias":   {3},
+	})
+	classify := func(layers int) textclassification.Response {
+		r, err := m.Classify(context.Background(), "the cat sat.", textclassification.Parameters{Layers: layers})
+		require.NoError(t, err)
+		return r
+	}
+
+	all := classify(0)
+	// The synthetic model has two layers.
+	assert.Equal(t, all, classify(2))
+	assert.Equal(t, all, classify(5), "more layers than the model has run all of them")
+	assert.NotEqual(t, all, classify(1), "the first layer only")
+}
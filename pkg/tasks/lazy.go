@@ -0,0 +1,356 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/coreference"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/languagemodeling"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/postagging"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/relationextraction"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier"
+	"github.com/rs/zerolog/log"
+)
+
+// The load statuses of the models (see LoadStatus).
+const (
+	// LoadPending is the status of a model loaded lazily which wasn't
+	// requested yet.
+	LoadPending = "pending"
+	// LoadLoading is the status of a model being loaded, whose requests
+	// wait for it.
+	LoadLoading = "loading"
+	// LoadReady is the status of a loaded model.
+	LoadReady = "ready"
+	// LoadFailed is the status of a model loaded lazily whose last load
+	// failed; the next request loads it again.
+	LoadFailed = "failed"
+)
+
+// LoadStatus returns the load status of the model, and the error of its
+// last load, if it failed. The models not loaded lazily are ready.
+func LoadStatus(m any) (string, error) {
+	for {
+		if l, ok := m.(interface{ loadStatus() (string, error) }); ok {
+			return l.loadStatus()
+		}
+		r, ok := m.(interface{ Unwrap() any })
+		if !ok {
+			return LoadReady, nil
+		}
+		m = r.Unwrap()
+	}
+}
+
+// lazy loads a model on its first request (see Config.Lazy). The requests
+// wait for the model while it's loaded; if the load fails, they fail with
+// errdefs.ErrModelLoadFailed, and the next request loads it again.
+type lazy[T any] struct {
+	name string
+	load func() (T, error)
+
+	mu     sync.Mutex
+	m      T
+	loaded bool
+	closed bool
+	// loading is closed once the load in progress, if any, is done.
+	loading chan struct{}
+	// err is the error of the last load, if it failed.
+	err error
+}
+
+// get returns the model, loading it if needed, or waiting for the load in
+// progress until the context is done.
+func (l *lazy[T]) get(ctx context.Context) (T, error) {
+	var empty T
+	l.mu.Lock()
+	if l.loaded {
+		m := l.m
+		l.mu.Unlock()
+		return m, nil
+	}
+	if l.closed {
+		l.mu.Unlock()
+		return empty, fmt.Errorf("%w: model %#v closed", errdefs.ErrModelLoadFailed, l.name)
+	}
+	if l.loading == nil {
+		l.loading = make(chan struct{})
+		// The load outlives the request starting it, so that the requests
+		// waiting for it aren't failed when the first is canceled.
+		go l.run(l.loading)
+	}
+	loading := l.loading
+	l.mu.Unlock()
+
+	select {
+	case <-loading:
+	case <-ctx.Done():
+		return empty, ctx.Err()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.loaded {
+		return empty, fmt.Errorf("%w: model %#v: %v", errdefs.ErrModelLoadFailed, l.name, l.err)
+	}
+	return l.m, nil
+}
+
+// run loads the model, closing loading once done.
+func (l *lazy[T]) run(loading chan struct{}) {
+	log.Info().Str("model", l.name).Msg("loading model on its first request")
+	start := time.Now()
+	m, err := l.load()
+	if err != nil {
+		log.Err(err).Str("model", l.name).Msg("failed to load model")
+	} else {
+		log.Info().Str("model", l.name).Dur("duration", time.Since(start)).Msg("model loaded")
+	}
+
+	l.mu.Lock()
+	closed := l.closed
+	switch {
+	case err != nil:
+		l.err = err
+	case !closed:
+		l.m, l.loaded, l.err = m, true, nil
+	}
+	l.loading = nil
+	l.mu.Unlock()
+	close(loading)
+	if err == nil && closed {
+		Finalize(m)
+	}
+}
+
+func (l *lazy[T]) loadStatus() (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	switch {
+	case l.loaded:
+		return LoadReady, nil
+	case l.loading != nil:
+		return LoadLoading, nil
+	case l.err != nil:
+		return LoadFailed, l.err
+	default:
+		return LoadPending, nil
+	}
+}
+
+// Unwrap returns the model, or nil if it's not loaded yet.
+func (l *lazy[T]) Unwrap() any {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.loaded {
+		return nil
+	}
+	return l.m
+}
+
+// resolve returns the model, loading it if needed, or nil if its load
+// fails. It's used to find the optional capabilities of the model (see
+// AsTokenizer and Adapters), which the lazy wrappers don't expose.
+func (l *lazy[T]) resolve() any {
+	m, err := l.get(context.Background())
+	if err != nil {
+		return nil
+	}
+	return m
+}
+
+// unwrapResolved returns the model wrapped by m, loading it first if it's
+// loaded lazily, or false if m doesn't wrap a model.
+func unwrapResolved(m any) (any, bool) {
+	if r, ok := m.(interface{ resolve() any }); ok {
+		return r.resolve(), true
+	}
+	r, ok := m.(interface{ Unwrap() any })
+	if !ok {
+		return nil, false
+	}
+	return r.Unwrap(), true
+}
+
+// Close closes the model, if loaded, or once its load in progress is done.
+func (l *lazy[T]) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	if !l.loaded {
+		return nil
+	}
+	l.loaded = false
+	if c, ok := any(l.m).(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// withLazy calls the function with the model, loading it if needed.
+func withLazy[T, R any](ctx context.Context, l *lazy[T], f func(T) (R, error)) (R, error) {
+	m, err := l.get(ctx)
+	if err != nil {
+		var empty R
+		return empty, err
+	}
+	return f(m)
+}
+
+// wrapLazy returns the model of the task T loaded with the function on its
+// first request.
+func wrapLazy[T any](name string, load func() (T, error)) (T, error) {
+	var w any
+	switch l := any(&lazy[T]{name: name, load: load}).(type) {
+	case *lazy[text2text.Interface]:
+		w = text2textLazy{l}
+	case *lazy[zeroshotclassifier.Interface]:
+		w = zeroShotLazy{l}
+	case *lazy[questionanswering.Interface]:
+		w = questionAnsweringLazy{l}
+	case *lazy[textclassification.Interface]:
+		w = textClassificationLazy{l}
+	case *lazy[tokenclassification.Interface]:
+		w = tokenClassificationLazy{l}
+	case *lazy[textencoding.Interface]:
+		w = textEncodingLazy{l}
+	case *lazy[languagemodeling.Interface]:
+		w = languageModelingLazy{l}
+	case *lazy[coreference.Interface]:
+		w = coreferenceLazy{l}
+	case *lazy[relationextraction.Interface]:
+		w = relationExtractionLazy{l}
+	case *lazy[postagging.Interface]:
+		w = posTaggingLazy{l}
+	case *lazy[sentencesegmentation.Interface]:
+		w = sentenceSegmentationLazy{l}
+	}
+	obj, ok := w.(T)
+	if !ok {
+		return obj, fmt.Errorf("loader: lazy loading not supported for type %T", obj)
+	}
+	return obj, nil
+}
+
+type text2textLazy struct {
+	*lazy[text2text.Interface]
+}
+
+func (l text2textLazy) Generate(ctx context.Context, text string, opts *text2text.Options) (text2text.Response, error) {
+	return withLazy(ctx, l.lazy, func(m text2text.Interface) (text2text.Response, error) {
+		return m.Generate(ctx, text, opts)
+	})
+}
+
+type zeroShotLazy struct {
+	*lazy[zeroshotclassifier.Interface]
+}
+
+func (l zeroShotLazy) Classify(ctx context.Context, text string, parameters zeroshotclassifier.Parameters) (zeroshotclassifier.Response, error) {
+	return withLazy(ctx, l.lazy, func(m zeroshotclassifier.Interface) (zeroshotclassifier.Response, error) {
+		return m.Classify(ctx, text, parameters)
+	})
+}
+
+type questionAnsweringLazy struct {
+	*lazy[questionanswering.Interface]
+}
+
+func (l questionAnsweringLazy) Answer(ctx context.Context, question string, passage string, opts *questionanswering.Options) (questionanswering.Response, error) {
+	return withLazy(ctx, l.lazy, func(m questionanswering.Interface) (questionanswering.Response, error) {
+		return m.Answer(ctx, question, passage, opts)
+	})
+}
+
+type textClassificationLazy struct {
+	*lazy[textclassification.Interface]
+}
+
+func (l textClassificationLazy) Classify(ctx context.Context, text string, parameters textclassification.Parameters) (textclassification.Response, error) {
+	return withLazy(ctx, l.lazy, func(m textclassification.Interface) (textclassification.Response, error) {
+		return m.Classify(ctx, text, parameters)
+	})
+}
+
+type tokenClassificationLazy struct {
+	*lazy[tokenclassification.Interface]
+}
+
+func (l tokenClassificationLazy) Classify(ctx context.Context, text string, parameters tokenclassification.Parameters) (tokenclassification.Response, error) {
+	return withLazy(ctx, l.lazy, func(m tokenclassification.Interface) (tokenclassification.Response, error) {
+		return m.Classify(ctx, text, parameters)
+	})
+}
+
+type textEncodingLazy struct {
+	*lazy[textencoding.Interface]
+}
+
+func (l textEncodingLazy) Encode(ctx context.Context, text string, poolingStrategy int) (textencoding.Response, error) {
+	return withLazy(ctx, l.lazy, func(m textencoding.Interface) (textencoding.Response, error) {
+		return m.Encode(ctx, text, poolingStrategy)
+	})
+}
+
+type languageModelingLazy struct {
+	*lazy[languagemodeling.Interface]
+}
+
+func (l languageModelingLazy) Predict(ctx context.Context, text string, parameters languagemodeling.Parameters) (languagemodeling.Response, error) {
+	return withLazy(ctx, l.lazy, func(m languagemodeling.Interface) (languagemodeling.Response, error) {
+		return m.Predict(ctx, text, parameters)
+	})
+}
+
+type coreferenceLazy struct {
+	*lazy[coreference.Interface]
+}
+
+func (l coreferenceLazy) Resolve(ctx context.Context, text string) (coreference.Response, error) {
+	return withLazy(ctx, l.lazy, func(m coreference.Interface) (coreference.Response, error) {
+		return m.Resolve(ctx, text)
+	})
+}
+
+type relationExtractionLazy struct {
+	*lazy[relationextraction.Interface]
+}
+
+func (l relationExtractionLazy) Extract(ctx context.Context, text string, parameters relationextraction.Parameters) (relationextraction.Response, error) {
+	return withLazy(ctx, l.lazy, func(m relationextraction.Interface) (relationextraction.Response, error) {
+		return m.Extract(ctx, text, parameters)
+	})
+}
+
+type sentenceSegmentationLazy struct {
+	*lazy[sentencesegmentation.Interface]
+}
+
+func (l sentenceSegmentationLazy) Segment(ctx context.Context, text string) (sentencesegmentation.Response, error) {
+	return withLazy(ctx, l.lazy, func(m sentencesegmentation.Interface) (sentencesegmentation.Response, error) {
+		return m.Segment(ctx, text)
+	})
+}
+
+type posTaggingLazy struct {
+	*lazy[postagging.Interface]
+}
+
+func (l posTaggingLazy) Tag(ctx context.Context, text string) (postagging.Response, error) {
+	return withLazy(ctx, l.lazy, func(m postagging.Interface) (postagging.Response, error) {
+		return m.Tag(ctx, text)
+	})
+}
@@ -0,0 +1,280 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedLoader loads a closingClassifier once each load is released, failing
+// the loads while failing is set.
+type gatedLoader struct {
+	release chan struct{}
+	loads   atomic.Int32
+	failing atomic.Bool
+	loaded  []*closingClassifier
+	mu      sync.Mutex
+}
+
+func newGatedLoader() *gatedLoader {
+	return &gatedLoader{release: make(chan struct{})}
+}
+
+func (g *gatedLoader) load() (textclassification.Interface, error) {
+	g.loads.Add(1)
+	<-g.release
+	if g.failing.Load() {
+		return nil, errors.New("download failed")
+	}
+	m := &closingClassifier{}
+	g.mu.Lock()
+	g.loaded = append(g.loaded, m)
+	g.mu.Unlock()
+	return m, nil
+}
+
+// closingClassifier records whether it's closed.
+type closingClassifier struct {
+	closed atomic.Bool
+}
+
+func (c *closingClassifier) Classify(context.Context, string, textclassification.Parameters) (textclassification.Response, error) {
+	return textclassification.Response{Labels: []string{"label"}, Scores: []float64{1}}, nil
+}
+
+func (c *closingClassifier) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+// classifyAsync classifies a text with the model, returning the channel of
+// the error.
+func classifyAsync(ctx context.Context, m textclassification.Interface) <-chan error {
+	errs := make(chan error, 1)
+	go func() {
+		_, err := m.Classify(ctx, "text", textclassification.Parameters{})
+		errs <- err
+	}()
+	return errs
+}
+
+// waitLoads waits for n loads to start.
+func waitLoads(t *testing.T, g *gatedLoader, n int32) {
+	assert.Eventually(t, func() bool { return g.loads.Load() == n }, time.Second, time.Millisecond)
+}
+
+// waitingContext signals when a request waits for the load of the model,
+// i.e. once its Done method is called.
+type waitingContext struct {
+	context.Context
+	once    sync.Once
+	waiting chan struct{}
+}
+
+func newWaitingContext(ctx context.Context) *waitingContext {
+	return &waitingContext{Context: ctx, waiting: make(chan struct{})}
+}
+
+func (c *waitingContext) Done() <-chan struct{} {
+	c.once.Do(func() { close(c.waiting) })
+	return c.Context.Done()
+}
+
+func TestWrapLazy(t *testing.T) {
+	g := newGatedLoader()
+	m, err := wrapLazy("model", g.load)
+	require.NoError(t, err)
+	assertStatus := func(want string, wantErr bool) {
+		t.Helper()
+		status, err := LoadStatus(m)
+		assert.Equal(t, want, status)
+		assert.Equal(t, wantErr, err != nil, err)
+	}
+	assertStatus(LoadPending, false)
+	assert.Nil(t, m.(interface{ Unwrap() any }).Unwrap(), "not loaded yet")
+	assert.Zero(t, g.loads.Load(), "not loaded before its first request")
+
+	// A failed load fails the requests waiting for it.
+	g.failing.Store(true)
+	first, second := newWaitingContext(context.Background()), newWaitingContext(context.Background())
+	firstErrs, secondErrs := classifyAsync(first, m), classifyAsync(second, m)
+	<-first.waiting
+	<-second.waiting
+	assertStatus(LoadLoading, false)
+	g.release <- struct{}{}
+	for _, errs := range []<-chan error{firstErrs, secondErrs} {
+		err := <-errs
+		assert.ErrorIs(t, err, errdefs.ErrModelLoadFailed)
+		assert.ErrorContains(t, err, "download failed")
+	}
+	assert.Equal(t, int32(1), g.loads.Load(), "the requests share the load")
+	assertStatus(LoadFailed, true)
+
+	// The next request loads it again.
+	g.failing.Store(false)
+	errs := classifyAsync(context.Background(), m)
+	waitLoads(t, g, 2)
+	g.release <- struct{}{}
+	require.NoError(t, <-errs)
+	assertStatus(LoadReady, false)
+	assert.Same(t, g.loaded[0], m.(interface{ Unwrap() any }).Unwrap())
+
+	_, err = m.Classify(context.Background(), "text", textclassification.Parameters{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), g.loads.Load(), "the loaded model is reused")
+
+	require.NoError(t, Close(m))
+	assert.True(t, g.loaded[0].closed.Load())
+	_, err = m.Classify(context.Background(), "text", textclassification.Parameters{})
+	assert.ErrorIs(t, err, errdefs.ErrModelLoadFailed, "closed")
+}
+
+func TestWrapLazy_Canceled(t *testing.T) {
+	g := newGatedLoader()
+	m, err := wrapLazy("model", g.load)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := classifyAsync(ctx, m)
+	waitLoads(t, g, 1)
+	waitingCtx := newWaitingContext(context.Background())
+	waiting := classifyAsync(waitingCtx, m)
+	<-waitingCtx.waiting
+	cancel()
+	assert.ErrorIs(t, <-canceled, context.Canceled)
+
+	// The load outlives the request starting it.
+	g.release <- struct{}{}
+	assert.NoError(t, <-waiting)
+	assert.Equal(t, int32(1), g.loads.Load())
+	require.NoError(t, Close(m))
+}
+
+func TestWrapLazy_CloseWhileLoading(t *testing.T) {
+	g := newGatedLoader()
+	m, err := wrapLazy("model", g.load)
+	require.NoError(t, err)
+
+	errs := classifyAsync(context.Background(), m)
+	waitLoads(t, g, 1)
+	require.NoError(t, Close(m))
+	g.release <- struct{}{}
+	assert.ErrorIs(t, <-errs, errdefs.ErrModelLoadFailed)
+	assert.Eventually(t, g.loaded[0].closed.Load, time.Second, time.Millisecond, "the model loaded once closed is finalized")
+	status, _ := LoadStatus(m)
+	assert.Equal(t, LoadPending, status)
+}
+
+func TestWrapLazy_Tasks(t *testing.T) {
+	tests := []struct {
+		name string
+		wrap func() (any, error)
+	}{
+		{"text classification", func() (any, error) {
+			return wrapLazy("model", func() (textclassification.Interface, error) { return nil, nil })
+		}},
+		{"text encoding", func() (any, error) {
+			return wrapLazy("model", func() (textencoding.Interface, error) { return nil, nil })
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := tt.wrap()
+			require.NoError(t, err)
+			status, err := LoadStatus(m)
+			assert.NoError(t, err)
+			assert.Equal(t, LoadPending, status)
+		})
+	}
+
+	_, err := wrapLazy("model", func() (any, error) { return nil, nil })
+	assert.ErrorContains(t, err, "lazy loading not supported")
+}
+
+// tokenizingClassifier is a classifier exposing its tokens.
+type tokenizingClassifier struct {
+	closingClassifier
+}
+
+func (*tokenizingClassifier) Tokenize(text string) []string {
+	return strings.Fields(text)
+}
+
+func TestWrapLazy_Capabilities(t *testing.T) {
+	var loads atomic.Int32
+	m, err := wrapLazy("model", func() (textclassification.Interface, error) {
+		loads.Add(1)
+		return &tokenizingClassifier{}, nil
+	})
+	require.NoError(t, err)
+	_, ok := m.(Tokenizer)
+	assert.False(t, ok, "the wrapper exposes the task only")
+
+	// The model is loaded to find its capabilities, once.
+	tok, ok := AsTokenizer(unwrapper{m})
+	require.True(t, ok)
+	assert.Equal(t, []string{"a", "text"}, tok.Tokenize("a text"))
+	_, ok = AsTokenizer(m)
+	assert.True(t, ok)
+	assert.Equal(t, int32(1), loads.Load())
+	_, ok = Adapters(m)
+	assert.False(t, ok)
+
+	// The models whose load fails have none.
+	failing, err := wrapLazy("model", func() (textclassification.Interface, error) {
+		return nil, errors.New("download failed")
+	})
+	require.NoError(t, err)
+	_, ok = AsTokenizer(failing)
+	assert.False(t, ok)
+	status, err := LoadStatus(failing)
+	assert.Equal(t, LoadFailed, status)
+	assert.ErrorContains(t, err, "download failed")
+	require.NoError(t, Close(m))
+}
+
+func TestLoadStatus(t *testing.T) {
+	lazy, err := wrapLazy("model", newGatedLoader().load)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		m    any
+		want string
+	}{
+		{name: "not lazy", m: &closingClassifier{}, want: LoadReady},
+		{name: "lazy", m: lazy, want: LoadPending},
+		{name: "wrapped lazy", m: unwrapper{unwrapper{lazy}}, want: LoadPending},
+		{name: "wrapped", m: unwrapper{&closingClassifier{}}, want: LoadReady},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := LoadStatus(tt.m)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, status)
+		})
+	}
+}
+
+// unwrapper wraps a model.
+type unwrapper struct {
+	m any
+}
+
+func (u unwrapper) Unwrap() any {
+	return u.m
+}
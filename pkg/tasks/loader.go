@@ -5,45 +5,110 @@
 package tasks
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
+	"time"
 
+	"github.com/nlpodyssey/cybertron/pkg/bundle"
+	"github.com/nlpodyssey/cybertron/pkg/cache"
 	"github.com/nlpodyssey/cybertron/pkg/converter"
 	"github.com/nlpodyssey/cybertron/pkg/downloader"
+	"github.com/nlpodyssey/cybertron/pkg/filelock"
 	"github.com/nlpodyssey/cybertron/pkg/models"
+	"github.com/nlpodyssey/cybertron/pkg/numa"
+	"github.com/nlpodyssey/cybertron/pkg/onnx"
+	"github.com/nlpodyssey/cybertron/pkg/storage"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/coreference"
+	bert_for_coreference_resolution "github.com/nlpodyssey/cybertron/pkg/tasks/coreference/bert"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/languagemodeling"
 	bert_for_language_modeling "github.com/nlpodyssey/cybertron/pkg/tasks/languagemodeling/bert"
 	distilbert_for_language_modeling "github.com/nlpodyssey/cybertron/pkg/tasks/languagemodeling/distilbert"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/postagging"
+	tagger_for_pos_tagging "github.com/nlpodyssey/cybertron/pkg/tasks/postagging/tagger"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering"
 	bert_for_question_answering "github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering/bert"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/relationextraction"
+	rebel_for_relation_extraction "github.com/nlpodyssey/cybertron/pkg/tasks/relationextraction/rebel"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/scheduling"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation"
+	tagger_for_sentence_segmentation "github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation/tagger"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
 	bart_for_text_to_text "github.com/nlpodyssey/cybertron/pkg/tasks/text2text/bart"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
 	bert_for_text_classification "github.com/nlpodyssey/cybertron/pkg/tasks/textclassification/bert"
+	onnx_for_text_classification "github.com/nlpodyssey/cybertron/pkg/tasks/textclassification/onnx"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
 	bert_for_text_encoding "github.com/nlpodyssey/cybertron/pkg/tasks/textencoding/bert"
 	distilbert_for_text_encoding "github.com/nlpodyssey/cybertron/pkg/tasks/textencoding/distilbert"
+	onnx_for_text_encoding "github.com/nlpodyssey/cybertron/pkg/tasks/textencoding/onnx"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
 	bert_for_token_classification "github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification/bert"
 	flair_for_token_classification "github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification/flair"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/verification"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier"
 	bart_for_zero_shot_classification "github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier/bart"
+	"github.com/rs/zerolog/log"
 )
 
 var (
-	text2textInterface           = reflect.TypeOf((*text2text.Interface)(nil)).Elem()
-	zeroshotclassifierInterface  = reflect.TypeOf((*zeroshotclassifier.Interface)(nil)).Elem()
-	questionansweringInterface   = reflect.TypeOf((*questionanswering.Interface)(nil)).Elem()
-	textclassificationInterface  = reflect.TypeOf((*textclassification.Interface)(nil)).Elem()
-	tokenclassificationInterface = reflect.TypeOf((*tokenclassification.Interface)(nil)).Elem()
-	textencodingInterface        = reflect.TypeOf((*textencoding.Interface)(nil)).Elem()
-	languagemodelingInterface    = reflect.TypeOf((*languagemodeling.Interface)(nil)).Elem()
+	text2textInterface            = reflect.TypeOf((*text2text.Interface)(nil)).Elem()
+	zeroshotclassifierInterface   = reflect.TypeOf((*zeroshotclassifier.Interface)(nil)).Elem()
+	questionansweringInterface    = reflect.TypeOf((*questionanswering.Interface)(nil)).Elem()
+	textclassificationInterface   = reflect.TypeOf((*textclassification.Interface)(nil)).Elem()
+	tokenclassificationInterface  = reflect.TypeOf((*tokenclassification.Interface)(nil)).Elem()
+	textencodingInterface         = reflect.TypeOf((*textencoding.Interface)(nil)).Elem()
+	languagemodelingInterface     = reflect.TypeOf((*languagemodeling.Interface)(nil)).Elem()
+	coreferenceInterface          = reflect.TypeOf((*coreference.Interface)(nil)).Elem()
+	relationextractionInterface   = reflect.TypeOf((*relationextraction.Interface)(nil)).Elem()
+	sentencesegmentationInterface = reflect.TypeOf((*sentencesegmentation.Interface)(nil)).Elem()
+	postaggingInterface           = reflect.TypeOf((*postagging.Interface)(nil)).Elem()
 )
 
-// Load loads a model from file.
+// Load loads a model from file, or returns the model loading it on its
+// first request, if lazy (see Config.Lazy).
 func Load[T any](conf *Config) (T, error) {
-	return loader[T]{conf: *conf}.load()
+	return LoadContext[T](context.Background(), conf)
+}
+
+// LoadContext is like Load, the context bounding the download, the
+// conversion and the loading of the model, which fail with its error once
+// it's done. The lazy models are loaded regardless of it, on their first
+// request.
+func LoadContext[T any](ctx context.Context, conf *Config) (T, error) {
+	l := loader[T]{ctx: ctx, conf: *conf}
+	if conf.Lazy {
+		l.ctx = context.Background()
+		return wrapLazy(conf.ModelName, l.load)
+	}
+	return l.load()
+}
+
+// Prepare downloads and converts the model, as Load does, without loading
+// it, e.g. to populate the models directory ahead of time. It returns the
+// model directory.
+func Prepare(conf *Config) (string, error) {
+	l := loader[any]{ctx: context.Background(), conf: *conf}
+	if l.conf.ModelName == "" {
+		return "", errors.New("model name not specified")
+	}
+	if l.isRuleBased() {
+		// The rule-based implementations have no model.
+		return "", nil
+	}
+	dir, err := l.resolveModelDir()
+	if err != nil {
+		return "", err
+	}
+	l.resolvedDir = dir
+	if l.conf.Backend == BackendONNX {
+		// ONNX models are neither downloaded nor converted.
+		return l.modelDir(), nil
+	}
+	return l.modelDir(), l.prepareWithLock()
 }
 
 func LoadModelForTextGeneration(conf *Config) (text2text.Interface, error) {
@@ -74,27 +139,229 @@ func LoadModelForTokenClassification(conf *Config) (tokenclassification.Interfac
 	return Load[tokenclassification.Interface](conf)
 }
 
+func LoadModelForCoreferenceResolution(conf *Config) (coreference.Interface, error) {
+	return Load[coreference.Interface](conf)
+}
+
+func LoadModelForRelationExtraction(conf *Config) (relationextraction.Interface, error) {
+	return Load[relationextraction.Interface](conf)
+}
+
+func LoadModelForSentenceSegmentation(conf *Config) (sentencesegmentation.Interface, error) {
+	return Load[sentencesegmentation.Interface](conf)
+}
+
+func LoadModelForPOSTagging(conf *Config) (postagging.Interface, error) {
+	return Load[postagging.Interface](conf)
+}
+
 type loader[T any] struct {
+	// ctx bounds the download, the conversion and the loading of the model.
+	ctx  context.Context
 	conf Config
+	// resolvedDir is the model directory returned by the model resolver, if any.
+	resolvedDir string
 }
 
-func (l loader[T]) load() (obj T, _ error) {
-	loadingFunc, err := l.resolveLoadingFunc()
+// resolveModelDir returns the model directory given by the model resolver,
+// if any, or an empty string otherwise.
+func (l loader[T]) resolveModelDir() (string, error) {
+	if l.conf.Resolver == nil {
+		return "", nil
+	}
+	dir, err := l.conf.Resolver.Resolve(l.ctx, l.conf.ModelName)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve model %#v: %w", l.conf.ModelName, err)
+	}
+	return dir, nil
+}
+
+// modelDir returns the model directory.
+func (l loader[T]) modelDir() string {
+	if l.resolvedDir != "" {
+		return l.resolvedDir
+	}
+	return l.conf.FullModelPath()
+}
+
+func (l *loader[T]) load() (T, error) {
+	obj, err := l.loadReplicas()
+	if err != nil {
+		return obj, err
+	}
+	w, err := l.wrap(obj)
+	if err != nil {
+		Finalize(obj)
+		return w, err
+	}
+	l.report(StageReady, nil)
+	return w, nil
+}
+
+// wrap returns the model calibrating the probabilities of its responses,
+// limiting the length of its inputs, encoding them with the pooling strategy
+// and normalizing them, as configured, and adding its fields to the logs of
+// its requests.
+func (l *loader[T]) wrap(obj T) (T, error) {
+	c, err := l.loadCalibration()
 	if err != nil {
 		return obj, err
 	}
+	if !c.IsZero() {
+		w, ok := wrapCalibration(obj, c)
+		switch {
+		case ok:
+			obj = w
+			log.Info().Str("model", l.conf.ModelName).Msg("model probabilities calibrated")
+		case l.conf.Calibration != "":
+			return w, fmt.Errorf("loader: calibration not supported for type %T", obj)
+		}
+	}
+	if l.conf.MaxLength > 0 {
+		if obj, err = wrapMaxLength(obj, l.conf.MaxLength); err != nil {
+			return obj, err
+		}
+	}
+	if l.conf.Pooling != "" {
+		if obj, err = wrapPooling(obj, l.conf.Pooling); err != nil {
+			return obj, err
+		}
+	}
+	if !l.conf.Normalization.IsZero() {
+		if obj, err = wrapNormalization(obj, l.conf.Normalization); err != nil {
+			return obj, err
+		}
+	}
+	return wrapLogContext(obj, l.logFields()), nil
+}
 
+// loadReplicas loads the model, with its replicas, if any. It sets the
+// resolved model directory.
+func (l *loader[T]) loadReplicas() (obj T, _ error) {
 	if l.conf.ModelName == "" {
 		return obj, errors.New("model name not specified")
 	}
-	if err := l.download(); err != nil {
+	if l.isRuleBased() {
+		return l.loadRuleBased()
+	}
+	if d := l.conf.Device; d != "" && d != "cpu" && l.conf.Backend != BackendONNX {
+		return obj, fmt.Errorf("the %s backend doesn't support the device %#v", l.conf.Backend, d)
+	}
+	if l.conf.MemoryLimit > 0 && l.conf.Backend != BackendONNX {
+		return obj, fmt.Errorf("the %s backend doesn't support the memory limit", l.conf.Backend)
+	}
+	if l.conf.AttentionWindow > 0 && l.conf.Backend != BackendONNX {
+		return obj, fmt.Errorf("the %s backend doesn't support the attention window", l.conf.Backend)
+	}
+	if l.conf.RopeScaling.Type != "" && l.conf.Backend != BackendONNX {
+		return obj, fmt.Errorf("the %s backend doesn't support the RoPE scaling", l.conf.Backend)
+	}
+	if l.conf.KernelTuning && l.conf.Backend != BackendONNX {
+		return obj, fmt.Errorf("the %s backend doesn't support the kernel tuning", l.conf.Backend)
+	}
+	if l.conf.MaxLength < 0 {
+		return obj, fmt.Errorf("invalid max length %d", l.conf.MaxLength)
+	}
+	if _, ok := poolingStrategies[l.conf.Pooling]; l.conf.Pooling != "" && !ok {
+		return obj, fmt.Errorf("invalid pooling %#v", l.conf.Pooling)
+	}
+	dir, err := l.resolveModelDir()
+	if err != nil {
 		return obj, err
 	}
-	if err := l.convert(); err != nil {
+	l.resolvedDir = dir
+	// The loading function is bound to the loader with the resolved directory.
+	loadingFunc, err := l.resolveLoadingFunc()
+	if err != nil {
+		return obj, err
+	}
+	loadingFunc = l.withPrecomputedPositions(loadingFunc)
+	loadingFunc = l.withKernelActivations(loadingFunc)
+	if loadingFunc, err = l.withAdapters(loadingFunc); err != nil {
+		return obj, err
+	}
+	p, err := l.numaPlacement()
+	if err != nil {
+		return obj, err
+	}
+	loadingFunc = l.withPlacement(p, loadingFunc)
+	if l.conf.Backend == BackendONNX {
+		loadONNX := l.withPlacement(p, l.resolveONNXModel)
+		l.report(StageLoading, nil)
+		if obj, err = loadONNX(); err != nil {
+			return obj, err
+		}
+		return l.withReplicas(obj, loadONNX, p)
+	}
+	if err := l.prepareWithLock(); err != nil {
 		return obj, err
 	}
+	if err := l.ctx.Err(); err != nil {
+		return obj, err
+	}
+
+	l.report(StageLoading, nil)
+	obj, err = loadingFunc()
+	if err != nil {
+		return obj, err
+	}
+	if l.conf.ConversionVerification {
+		if err := l.verify(obj); err != nil {
+			Finalize(obj)
+			var empty T
+			return empty, err
+		}
+	}
+	if err := cache.Touch(l.modelDir()); err != nil {
+		log.Warn().Err(err).Msg("failed to record the model usage")
+	}
+	return l.withReplicas(obj, loadingFunc, p)
+}
 
-	return loadingFunc()
+// withReplicas loads the other replicas of the model, if more than one is
+// configured, and returns the model scheduling the requests on all of them,
+// with at most InterOpParallelism requests served by each at a time, each
+// bound to the NUMA node of its replica, if placed.
+func (l loader[T]) withReplicas(first T, load func() (T, error), p *placement) (T, error) {
+	if l.conf.Replicas <= 1 && l.conf.InterOpParallelism <= 0 && len(p.placed) == 0 {
+		return first, nil
+	}
+	models := []T{first}
+	finalize := func() {
+		for _, m := range models {
+			Finalize(m)
+		}
+	}
+	for len(models) < l.conf.Replicas {
+		if err := l.ctx.Err(); err != nil {
+			finalize()
+			var empty T
+			return empty, err
+		}
+		m, err := load()
+		if err != nil {
+			finalize()
+			var empty T
+			return empty, fmt.Errorf("failed to load replica %d: %w", len(models)+1, err)
+		}
+		models = append(models, m)
+	}
+	concurrency := l.conf.InterOpParallelism
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	obj, err := wrapReplicas(models, p.placed, concurrency, scheduling.Options{
+		Weight:     l.conf.PriorityWeight,
+		Preemption: l.conf.Preemption,
+	})
+	if err != nil {
+		finalize()
+		return obj, err
+	}
+	if len(models) > 1 {
+		log.Info().Str("model", l.conf.ModelName).Int("replicas", len(models)).Msg("model replicas loaded")
+	}
+	return obj, nil
 }
 
 func (l loader[T]) resolveLoadingFunc() (func() (T, error), error) {
@@ -114,11 +381,50 @@ func (l loader[T]) resolveLoadingFunc() (func() (T, error), error) {
 		return l.resolveModelForTextEncoding, nil
 	case t.Implements(languagemodelingInterface):
 		return l.resolveModelForLanguageModeling, nil
+	case t.Implements(coreferenceInterface):
+		return l.resolveModelForCoreferenceResolution, nil
+	case t.Implements(relationextractionInterface):
+		return l.resolveModelForRelationExtraction, nil
+	case t.Implements(sentencesegmentationInterface):
+		return l.resolveModelForSentenceSegmentation, nil
+	case t.Implements(postaggingInterface):
+		return l.resolveModelForPOSTagging, nil
 	default:
 		return nil, fmt.Errorf("loader: invalid type %T", obj)
 	}
 }
 
+// taskName returns the name of the task, as in the bundle manifest.
+func (l loader[T]) taskName() string {
+	_, t := l.reflectType()
+	switch {
+	case t.Implements(text2textInterface):
+		return "text2text"
+	case t.Implements(zeroshotclassifierInterface):
+		return "zero-shot-classification"
+	case t.Implements(questionansweringInterface):
+		return "question-answering"
+	case t.Implements(textclassificationInterface):
+		return "text-classification"
+	case t.Implements(tokenclassificationInterface):
+		return "token-classification"
+	case t.Implements(textencodingInterface):
+		return "text-encoding"
+	case t.Implements(languagemodelingInterface):
+		return "language-modeling"
+	case t.Implements(coreferenceInterface):
+		return "coreference-resolution"
+	case t.Implements(relationextractionInterface):
+		return "relation-extraction"
+	case t.Implements(sentencesegmentationInterface):
+		return "sentence-segmentation"
+	case t.Implements(postaggingInterface):
+		return "pos-tagging"
+	default:
+		return ""
+	}
+}
+
 func (l loader[T]) reflectType() (obj T, t reflect.Type) {
 	if any(obj) == nil {
 		return obj, reflect.ValueOf(&obj).Type().Elem()
@@ -127,7 +433,7 @@ func (l loader[T]) reflectType() (obj T, t reflect.Type) {
 }
 
 func (l loader[T]) resolveModelForText2Text() (obj T, _ error) {
-	modelDir := l.conf.FullModelPath()
+	modelDir := l.modelDir()
 	modelConfig, err := models.ReadCommonModelConfig(modelDir, "")
 	if err != nil {
 		return obj, err
@@ -142,7 +448,7 @@ func (l loader[T]) resolveModelForText2Text() (obj T, _ error) {
 }
 
 func (l loader[T]) resolveModelForZeroShotClassification() (obj T, _ error) {
-	modelDir := l.conf.FullModelPath()
+	modelDir := l.modelDir()
 	modelConfig, err := models.ReadCommonModelConfig(modelDir, "")
 	if err != nil {
 		return obj, err
@@ -150,14 +456,19 @@ func (l loader[T]) resolveModelForZeroShotClassification() (obj T, _ error) {
 
 	switch modelConfig.ModelType {
 	case "bart":
-		return typeCheck[T](bart_for_zero_shot_classification.LoadZeroShotClassifier(modelDir))
+		m, err := bart_for_zero_shot_classification.LoadZeroShotClassifier(modelDir)
+		if err != nil {
+			return obj, err
+		}
+		m.Parallelism = l.conf.IntraOpParallelism
+		return typeCheck[T](m, nil)
 	default:
 		return obj, fmt.Errorf("model type %#v doesn't support the zero-shot classification task", modelConfig.ModelType)
 	}
 }
 
 func (l loader[T]) resolveModelForQuestionAnswering() (obj T, _ error) {
-	modelDir := l.conf.FullModelPath()
+	modelDir := l.modelDir()
 	modelConfig, err := models.ReadCommonModelConfig(modelDir, "")
 	if err != nil {
 		return obj, err
@@ -172,7 +483,7 @@ func (l loader[T]) resolveModelForQuestionAnswering() (obj T, _ error) {
 }
 
 func (l loader[T]) resolveModelForTextClassification() (obj T, _ error) {
-	modelDir := l.conf.FullModelPath()
+	modelDir := l.modelDir()
 	modelConfig, err := models.ReadCommonModelConfig(modelDir, "")
 	if err != nil {
 		return obj, err
@@ -187,7 +498,7 @@ func (l loader[T]) resolveModelForTextClassification() (obj T, _ error) {
 }
 
 func (l loader[T]) resolveModelForTokenClassification() (obj T, _ error) {
-	modelDir := l.conf.FullModelPath()
+	modelDir := l.modelDir()
 	modelConfig, err := models.ReadCommonModelConfig(modelDir, "")
 	if err != nil {
 		return obj, err
@@ -203,8 +514,65 @@ func (l loader[T]) resolveModelForTokenClassification() (obj T, _ error) {
 	}
 }
 
+func (l loader[T]) resolveModelForCoreferenceResolution() (obj T, _ error) {
+	modelDir := l.modelDir()
+	modelConfig, err := models.ReadCommonModelConfig(modelDir, "")
+	if err != nil {
+		return obj, err
+	}
+
+	switch modelConfig.ModelType {
+	case "bert":
+		return typeCheck[T](bert_for_coreference_resolution.LoadCoreferenceResolution(modelDir))
+	default:
+		return obj, fmt.Errorf("model type %#v doesn't support the coreference resolution task", modelConfig.ModelType)
+	}
+}
+
+func (l loader[T]) resolveModelForRelationExtraction() (obj T, _ error) {
+	modelDir := l.modelDir()
+	modelConfig, err := models.ReadCommonModelConfig(modelDir, "")
+	if err != nil {
+		return obj, err
+	}
+
+	switch modelConfig.ModelType {
+	case "bart":
+		return typeCheck[T](rebel_for_relation_extraction.LoadRelationExtraction(modelDir))
+	default:
+		return obj, fmt.Errorf("model type %#v doesn't support the relation extraction task", modelConfig.ModelType)
+	}
+}
+
+func (l loader[T]) resolveModelForSentenceSegmentation() (obj T, _ error) {
+	m, err := l.loadTokenClassification("sentence segmentation")
+	if err != nil {
+		return obj, err
+	}
+	return typeCheck[T](tagger_for_sentence_segmentation.New(m), nil)
+}
+
+func (l loader[T]) resolveModelForPOSTagging() (obj T, _ error) {
+	m, err := l.loadTokenClassification("part-of-speech tagging")
+	if err != nil {
+		return obj, err
+	}
+	return typeCheck[T](tagger_for_pos_tagging.New(m), nil)
+}
+
+// loadTokenClassification loads the token classification model of the
+// tasks implemented by labeling the words of the texts.
+func (l loader[T]) loadTokenClassification(task string) (tokenclassification.Interface, error) {
+	tl := loader[tokenclassification.Interface]{ctx: l.ctx, conf: l.conf, resolvedDir: l.resolvedDir}
+	m, err := tl.resolveModelForTokenClassification()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the model of the %s task: %w", task, err)
+	}
+	return m, nil
+}
+
 func (l loader[T]) resolveModelForTextEncoding() (obj T, _ error) {
-	modelDir := l.conf.FullModelPath()
+	modelDir := l.modelDir()
 	modelConfig, err := models.ReadCommonModelConfig(modelDir, "")
 	if err != nil {
 		return obj, err
@@ -221,7 +589,7 @@ func (l loader[T]) resolveModelForTextEncoding() (obj T, _ error) {
 }
 
 func (l loader[T]) resolveModelForLanguageModeling() (obj T, _ error) {
-	modelDir := l.conf.FullModelPath()
+	modelDir := l.modelDir()
 	modelConfig, err := models.ReadCommonModelConfig(modelDir, "")
 	if err != nil {
 		return obj, err
@@ -237,6 +605,75 @@ func (l loader[T]) resolveModelForLanguageModeling() (obj T, _ error) {
 	}
 }
 
+// tuneKernels selects the fastest kernels of the matrix products of the
+// ONNX model on this machine, cached in the model directory.
+func (l loader[T]) tuneKernels(m *onnx.Model) {
+	start := time.Now()
+	cached, err := m.TuneKernels(filepath.Join(l.modelDir(), onnx.DefaultKernelTuningFilename))
+	if err != nil {
+		log.Warn().Err(err).Str("model", l.conf.ModelName).Msg("failed to cache the model kernels")
+	}
+	log.Info().Str("model", l.conf.ModelName).Bool("cached", cached).Dur("duration", time.Since(start)).Msg("model kernels tuned")
+}
+
+// resolveONNXModel loads an ONNX model from the model directory. The files
+// are expected to be already there, so neither download nor conversion is
+// performed.
+func (l loader[T]) resolveONNXModel() (obj T, _ error) {
+	modelDir := l.modelDir()
+	_, t := l.reflectType()
+	switch {
+	case t.Implements(textclassificationInterface):
+		m, err := onnx_for_text_classification.LoadTextClassification(modelDir)
+		if err != nil {
+			return obj, err
+		}
+		m.Model.Parallelism = l.conf.IntraOpParallelism
+		m.Model.MemoryLimit = int64(l.conf.MemoryLimit) << 20
+		m.Model.AttentionWindow = l.conf.AttentionWindow
+		if n, ok := numa.Bound(); ok {
+			m.Model.NUMANode = &n
+		}
+		if l.conf.RopeScaling.Type != "" {
+			if err := m.SetRopeScaling(l.conf.RopeScaling); err != nil {
+				return obj, err
+			}
+		}
+		if err := m.Model.SetDevice(l.conf.Device); err != nil {
+			return obj, err
+		}
+		if l.conf.KernelTuning {
+			l.tuneKernels(m.Model)
+		}
+		return typeCheck[T](m, nil)
+	case t.Implements(textencodingInterface):
+		m, err := onnx_for_text_encoding.LoadTextEncoding(modelDir)
+		if err != nil {
+			return obj, err
+		}
+		m.Model.Parallelism = l.conf.IntraOpParallelism
+		m.Model.MemoryLimit = int64(l.conf.MemoryLimit) << 20
+		m.Model.AttentionWindow = l.conf.AttentionWindow
+		if n, ok := numa.Bound(); ok {
+			m.Model.NUMANode = &n
+		}
+		if l.conf.RopeScaling.Type != "" {
+			if err := m.SetRopeScaling(l.conf.RopeScaling); err != nil {
+				return obj, err
+			}
+		}
+		if err := m.Model.SetDevice(l.conf.Device); err != nil {
+			return obj, err
+		}
+		if l.conf.KernelTuning {
+			l.tuneKernels(m.Model)
+		}
+		return typeCheck[T](m, nil)
+	default:
+		return obj, fmt.Errorf("the onnx backend doesn't support the task %T", obj)
+	}
+}
+
 func typeCheck[T any](i any, err error) (T, error) {
 	var empty T
 	if err != nil {
@@ -249,6 +686,11 @@ func typeCheck[T any](i any, err error) (T, error) {
 }
 
 func (l loader[T]) download() error {
+	if l.offline() {
+		// Whatever the download policy, fail fast if anything is missing.
+		return downloader.DownloadContext(l.ctx, l.conf.ModelsDir, l.conf.ModelName, downloader.Options{Offline: true})
+	}
+
 	var overwriteIfExists bool
 	switch l.conf.DownloadPolicy {
 	case DownloadNever:
@@ -260,7 +702,32 @@ func (l loader[T]) download() error {
 	default:
 		return fmt.Errorf("invalid model download policy: %#v", l.conf.DownloadPolicy)
 	}
-	return downloader.Download(l.conf.ModelsDir, l.conf.ModelName, overwriteIfExists, l.conf.HubAccessToken)
+	return downloader.DownloadContext(l.ctx, l.conf.ModelsDir, l.conf.ModelName, downloader.Options{
+		OverwriteIfExist: overwriteIfExists,
+		AccessToken:      l.conf.HubAccessToken,
+		Revision:         l.conf.Revision,
+		Endpoint:         l.conf.HubEndpoint,
+		Proxy:            l.conf.HTTPProxy,
+		CABundle:         l.conf.CABundle,
+		Progress:         l.downloadProgress(),
+	})
+}
+
+// extractBundle extracts the model bundle into the model directory,
+// verifying the integrity of its files and that it's meant for the task.
+func (l loader[T]) extractBundle() error {
+	manifest, err := bundle.ReadManifest(l.conf.Bundle)
+	if err != nil {
+		return err
+	}
+	if task := l.taskName(); manifest.Task != "" && task != "" && manifest.Task != task {
+		return fmt.Errorf("model bundle is meant for task %#v, not %#v", manifest.Task, task)
+	}
+	if _, err = bundle.Extract(l.conf.Bundle, l.modelDir()); err != nil {
+		return fmt.Errorf("failed to extract model bundle: %w", err)
+	}
+	log.Info().Str("bundle", l.conf.Bundle).Str("model", manifest.ModelName).Int("files", len(manifest.Files)).Msg("model bundle verified")
+	return nil
 }
 
 func (l loader[T]) convert() error {
@@ -276,19 +743,152 @@ func (l loader[T]) convert() error {
 		return fmt.Errorf("invalid model conversion policy: %#v", l.conf.ConversionPrecision)
 	}
 
-	modelPath := l.conf.FullModelPath()
+	modelPath := l.modelDir()
+	l.report(StageConverting, nil)
 
 	var err error
 	switch l.conf.ConversionPrecision {
 	case F32:
-		err = converter.Convert[float32](modelPath, overwriteIfExists)
+		err = converter.Convert[float32](modelPath, overwriteIfExists, l.conf.ConversionQuantization)
 	case F64:
-		err = converter.Convert[float64](modelPath, overwriteIfExists)
+		err = converter.Convert[float64](modelPath, overwriteIfExists, l.conf.ConversionQuantization)
 	default:
 		return fmt.Errorf("invalid model conversion precision: %#v", l.conf.ConversionPrecision)
 	}
 	if err != nil {
 		return fmt.Errorf("failed to convert model: %w", err)
 	}
+
+	if l.conf.ConversionGGUF {
+		err = converter.ConvertToGGUF(modelPath, overwriteIfExists, l.conf.ConversionQuantization)
+		if err != nil {
+			return fmt.Errorf("failed to convert model to GGUF: %w", err)
+		}
+	}
+	return nil
+}
+
+// verify compares the outputs of the loaded model with the reference
+// outputs, failing if they diverge more than the tolerance.
+func (l loader[T]) verify(obj T) error {
+	modelDir := l.modelDir()
+	ref, err := verification.ReadReference(modelDir)
+	if os.IsNotExist(err) && l.offline() {
+		return fmt.Errorf("reference outputs %#v not found (offline mode)", verification.ReferenceFilename)
+	}
+	if os.IsNotExist(err) {
+		log.Info().Str("model", l.conf.ModelName).Msg("reference outputs not found, computing them with the Hugging Face Inference API")
+		ref, err = verification.FetchReference(l.ctx, l.conf.ModelName, downloader.ResolveAccessToken(l.conf.HubAccessToken))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get the reference outputs: %w", err)
+	}
+
+	report, err := verification.Verify(l.ctx, obj, ref)
+	if err != nil {
+		return fmt.Errorf("failed to verify model: %w", err)
+	}
+	for _, e := range report.Examples {
+		log.Debug().Str("input", e.Input).Float64("divergence", e.MaxDivergence).Msg("verification example")
+	}
+	if !report.Passed() {
+		return fmt.Errorf("model verification failed: max divergence %g exceeds tolerance %g", report.MaxDivergence, report.Tolerance)
+	}
+	log.Info().Float64("divergence", report.MaxDivergence).Float64("tolerance", report.Tolerance).Msg("model verification passed")
+	return nil
+}
+
+// lockTimeout is the maximum time waiting for the lock of the model
+// directory held by another process, if the context of the loading has no
+// deadline.
+const lockTimeout = time.Hour
+
+// prepareWithLock prepares the model directory, holding a lock on it, so
+// that multiple processes sharing the models directory don't download or
+// convert the same model at the same time. The lock is waited for until the
+// context of the loading is done, or up to lockTimeout.
+func (l loader[T]) prepareWithLock() (err error) {
+	ctx := l.ctx
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, lockTimeout)
+		defer cancel()
+	}
+	lock, err := filelock.Acquire(ctx, l.modelDir()+".lock", filelock.Options{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := lock.Release(); e != nil && err == nil {
+			err = e
+		}
+	}()
+	return l.prepare()
+}
+
+// prepare prepares the model directory: extracting the bundle, or pulling
+// the model from the store, or downloading and converting it.
+func (l loader[T]) prepare() error {
+	if l.conf.Bundle != "" {
+		return l.extractBundle()
+	}
+	if l.conf.Resolver != nil {
+		// The resolver replaces the store and the download.
+		return l.convert()
+	}
+	pulled, err := l.pull()
+	if err != nil || pulled {
+		return err
+	}
+	if err := l.download(); err != nil {
+		return err
+	}
+	if err := l.ctx.Err(); err != nil {
+		return err
+	}
+	if err := l.convert(); err != nil {
+		return err
+	}
+	return l.push()
+}
+
+// pull pulls the converted model from the model store, if any. It reports
+// whether the model was found there.
+func (l loader[T]) pull() (bool, error) {
+	if l.conf.ModelStore == "" || l.offline() {
+		return false, nil
+	}
+	store, err := storage.Open(l.conf.ModelStore)
+	if err != nil {
+		return false, err
+	}
+	n, err := storage.Pull(l.ctx, store, l.conf.ModelName, l.modelDir())
+	if err != nil {
+		return false, fmt.Errorf("failed to pull model from store: %w", err)
+	}
+	if n > 0 {
+		log.Info().Str("store", l.conf.ModelStore).Int("files", n).Msg("model pulled from store")
+	}
+	return n > 0, nil
+}
+
+// push pushes the converted model to the model store, if enabled.
+func (l loader[T]) push() error {
+	if l.conf.ModelStore == "" || !l.conf.ModelStorePush || l.offline() {
+		return nil
+	}
+	store, err := storage.Open(l.conf.ModelStore)
+	if err != nil {
+		return err
+	}
+	if err = storage.Push(l.ctx, store, l.conf.ModelName, l.modelDir()); err != nil {
+		return fmt.Errorf("failed to push model to store: %w", err)
+	}
+	log.Info().Str("store", l.conf.ModelStore).Msg("model pushed to store")
 	return nil
 }
+
+// offline reports whether the network must not be used.
+func (l loader[T]) offline() bool {
+	return l.conf.Offline || downloader.IsOfflineEnv()
+}
@@ -0,0 +1,183 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"context"
+	"io"
+
+	"github.com/nlpodyssey/cybertron/pkg/logging"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/coreference"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/languagemodeling"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/postagging"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/relationextraction"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier"
+)
+
+// logged adds the fields of a model, i.e. its name, revision and task, to
+// the logger of the context of its requests (see logging.Ctx), so that the
+// logs of a server of several models can be filtered by model.
+type logged[T any] struct {
+	m      T
+	fields []any
+}
+
+// Unwrap returns the model.
+func (l logged[T]) Unwrap() any {
+	return l.m
+}
+
+// Close closes the model.
+func (l logged[T]) Close() error {
+	if c, ok := any(l.m).(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (l logged[T]) context(ctx context.Context) context.Context {
+	return logging.With(ctx, l.fields...)
+}
+
+// logFields returns the fields of the model added to the logs of its
+// requests.
+func (l *loader[T]) logFields() []any {
+	fields := []any{"model", l.conf.ModelName}
+	if l.conf.Revision != "" {
+		fields = append(fields, "revision", l.conf.Revision)
+	}
+	if task := l.taskName(); task != "" {
+		fields = append(fields, "task", task)
+	}
+	return fields
+}
+
+// wrapLogContext returns the model of the task T adding the fields to the
+// logs of its requests, or the model itself if T isn't a task.
+func wrapLogContext[T any](m T, fields []any) T {
+	var w any
+	switch p := any(&m).(type) {
+	case *text2text.Interface:
+		w = text2textLogged{logged[text2text.Interface]{*p, fields}}
+	case *zeroshotclassifier.Interface:
+		w = zeroShotLogged{logged[zeroshotclassifier.Interface]{*p, fields}}
+	case *questionanswering.Interface:
+		w = questionAnsweringLogged{logged[questionanswering.Interface]{*p, fields}}
+	case *textclassification.Interface:
+		w = textClassificationLogged{logged[textclassification.Interface]{*p, fields}}
+	case *tokenclassification.Interface:
+		w = tokenClassificationLogged{logged[tokenclassification.Interface]{*p, fields}}
+	case *textencoding.Interface:
+		w = textEncodingLogged{logged[textencoding.Interface]{*p, fields}}
+	case *languagemodeling.Interface:
+		w = languageModelingLogged{logged[languagemodeling.Interface]{*p, fields}}
+	case *coreference.Interface:
+		w = coreferenceLogged{logged[coreference.Interface]{*p, fields}}
+	case *relationextraction.Interface:
+		w = relationExtractionLogged{logged[relationextraction.Interface]{*p, fields}}
+	case *postagging.Interface:
+		w = posTaggingLogged{logged[postagging.Interface]{*p, fields}}
+	case *sentencesegmentation.Interface:
+		w = sentenceSegmentationLogged{logged[sentencesegmentation.Interface]{*p, fields}}
+	}
+	if obj, ok := w.(T); ok {
+		return obj
+	}
+	return m
+}
+
+type text2textLogged struct {
+	logged[text2text.Interface]
+}
+
+func (l text2textLogged) Generate(ctx context.Context, text string, opts *text2text.Options) (text2text.Response, error) {
+	return l.m.Generate(l.context(ctx), text, opts)
+}
+
+type zeroShotLogged struct {
+	logged[zeroshotclassifier.Interface]
+}
+
+func (l zeroShotLogged) Classify(ctx context.Context, text string, parameters zeroshotclassifier.Parameters) (zeroshotclassifier.Response, error) {
+	return l.m.Classify(l.context(ctx), text, parameters)
+}
+
+type questionAnsweringLogged struct {
+	logged[questionanswering.Interface]
+}
+
+func (l questionAnsweringLogged) Answer(ctx context.Context, question string, passage string, opts *questionanswering.Options) (questionanswering.Response, error) {
+	return l.m.Answer(l.context(ctx), question, passage, opts)
+}
+
+type textClassificationLogged struct {
+	logged[textclassification.Interface]
+}
+
+func (l textClassificationLogged) Classify(ctx context.Context, text string, parameters textclassification.Parameters) (textclassification.Response, error) {
+	return l.m.Classify(l.context(ctx), text, parameters)
+}
+
+type tokenClassificationLogged struct {
+	logged[tokenclassification.Interface]
+}
+
+func (l tokenClassificationLogged) Classify(ctx context.Context, text string, parameters tokenclassification.Parameters) (tokenclassification.Response, error) {
+	return l.m.Classify(l.context(ctx), text, parameters)
+}
+
+type textEncodingLogged struct {
+	logged[textencoding.Interface]
+}
+
+func (l textEncodingLogged) Encode(ctx context.Context, text string, poolingStrategy int) (textencoding.Response, error) {
+	return l.m.Encode(l.context(ctx), text, poolingStrategy)
+}
+
+type languageModelingLogged struct {
+	logged[languagemodeling.Interface]
+}
+
+func (l languageModelingLogged) Predict(ctx context.Context, text string, parameters languagemodeling.Parameters) (languagemodeling.Response, error) {
+	return l.m.Predict(l.context(ctx), text, parameters)
+}
+
+type coreferenceLogged struct {
+	logged[coreference.Interface]
+}
+
+func (l coreferenceLogged) Resolve(ctx context.Context, text string) (coreference.Response, error) {
+	return l.m.Resolve(l.context(ctx), text)
+}
+
+type relationExtractionLogged struct {
+	logged[relationextraction.Interface]
+}
+
+func (l relationExtractionLogged) Extract(ctx context.Context, text string, parameters relationextraction.Parameters) (relationextraction.Response, error) {
+	return l.m.Extract(l.context(ctx), text, parameters)
+}
+
+type sentenceSegmentationLogged struct {
+	logged[sentencesegmentation.Interface]
+}
+
+func (l sentenceSegmentationLogged) Segment(ctx context.Context, text string) (sentencesegmentation.Response, error) {
+	return l.m.Segment(l.context(ctx), text)
+}
+
+type posTaggingLogged struct {
+	logged[postagging.Interface]
+}
+
+func (l posTaggingLogged) Tag(ctx context.Context, text string) (postagging.Response, error) {
+	return l.m.Tag(l.context(ctx), text)
+}
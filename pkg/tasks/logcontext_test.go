@@ -0,0 +1,53 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/logging"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loggingClassifier logs each of its requests with the logger of their
+// context.
+type loggingClassifier struct{}
+
+func (loggingClassifier) Classify(ctx context.Context, text string, _ textclassification.Parameters) (textclassification.Response, error) {
+	logging.Ctx(ctx).Info().Str("text", text).Msg("classified")
+	return textclassification.Response{}, nil
+}
+
+func TestWrapLogContext(t *testing.T) {
+	l := &loader[textclassification.Interface]{conf: Config{ModelName: "org/model", Revision: "v1"}}
+	m := wrapLogContext[textclassification.Interface](loggingClassifier{}, l.logFields())
+
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+	ctx := logging.With(logger.WithContext(context.Background()), "request_id", "42")
+	_, err := m.Classify(ctx, "hello", textclassification.Parameters{})
+	require.NoError(t, err)
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, map[string]any{
+		"level":      "info",
+		"request_id": "42",
+		"model":      "org/model",
+		"revision":   "v1",
+		"task":       "text-classification",
+		"text":       "hello",
+		"message":    "classified",
+	}, line)
+
+	assert.Equal(t, loggingClassifier{}, m.(interface{ Unwrap() any }).Unwrap())
+	assert.Equal(t, "not a task", wrapLogContext("not a task", nil))
+}
@@ -0,0 +1,287 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/attribution"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/coreference"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/languagemodeling"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/postagging"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/relationextraction"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier"
+	"github.com/nlpodyssey/cybertron/pkg/textnorm"
+	"github.com/nlpodyssey/cybertron/pkg/tokenizers"
+)
+
+// normalized normalizes the input texts of a model before their
+// tokenization. The offsets of the responses are mapped back to the
+// original texts, in bytes and in runes, so that they're unaffected by the
+// normalization.
+type normalized[T any] struct {
+	m    T
+	opts textnorm.Options
+}
+
+// Unwrap returns the model.
+func (n normalized[T]) Unwrap() any {
+	return n.m
+}
+
+// Close closes the model.
+func (n normalized[T]) Close() error {
+	if c, ok := any(n.m).(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Normalization returns the normalization applied to the input texts.
+func (n normalized[T]) Normalization() textnorm.Options {
+	return n.opts
+}
+
+func (n normalized[T]) normalize(text string) *textnorm.Text {
+	return textnorm.Normalize(text, n.opts)
+}
+
+// originalOffsets returns the offsets in bytes and in runes in the original
+// text of the offsets in bytes in the normalized text.
+func originalOffsets(t *textnorm.Text, r tokenizers.RuneOffsets, byteStart, byteEnd int) (b, o tokenizers.OffsetsType) {
+	b.Start, b.End = t.Original(byteStart, byteEnd)
+	return b, r.Runes(b)
+}
+
+// originalAttributions maps the attributions to the tokens of the normalized
+// text back to the original one, in place.
+func originalAttributions(t *textnorm.Text, r tokenizers.RuneOffsets, text string, attributions []attribution.Token) {
+	for i, a := range attributions {
+		b, o := originalOffsets(t, r, a.ByteStart, a.ByteEnd)
+		a.Text = text[b.Start:b.End]
+		a.Start, a.End, a.ByteStart, a.ByteEnd = o.Start, o.End, b.Start, b.End
+		attributions[i] = a
+	}
+}
+
+// wrapNormalization returns the model of the task T normalizing the input
+// texts with the options.
+func wrapNormalization[T any](m T, opts textnorm.Options) (T, error) {
+	var w any
+	switch p := any(&m).(type) {
+	case *text2text.Interface:
+		w = text2textNormalized{normalized[text2text.Interface]{*p, opts}}
+	case *zeroshotclassifier.Interface:
+		w = zeroShotNormalized{normalized[zeroshotclassifier.Interface]{*p, opts}}
+	case *questionanswering.Interface:
+		w = questionAnsweringNormalized{normalized[questionanswering.Interface]{*p, opts}}
+	case *textclassification.Interface:
+		w = textClassificationNormalized{normalized[textclassification.Interface]{*p, opts}}
+	case *tokenclassification.Interface:
+		w = tokenClassificationNormalized{normalized[tokenclassification.Interface]{*p, opts}}
+	case *textencoding.Interface:
+		w = textEncodingNormalized{normalized[textencoding.Interface]{*p, opts}}
+	case *languagemodeling.Interface:
+		w = languageModelingNormalized{normalized[languagemodeling.Interface]{*p, opts}}
+	case *coreference.Interface:
+		w = coreferenceNormalized{normalized[coreference.Interface]{*p, opts}}
+	case *relationextraction.Interface:
+		w = relationExtractionNormalized{normalized[relationextraction.Interface]{*p, opts}}
+	case *sentencesegmentation.Interface:
+		w = sentenceSegmentationNormalized{normalized[sentencesegmentation.Interface]{*p, opts}}
+	case *postagging.Interface:
+		w = posTaggingNormalized{normalized[postagging.Interface]{*p, opts}}
+	}
+	obj, ok := w.(T)
+	if !ok {
+		return obj, fmt.Errorf("loader: normalization not supported for type %T", m)
+	}
+	return obj, nil
+}
+
+type text2textNormalized struct {
+	normalized[text2text.Interface]
+}
+
+func (n text2textNormalized) Generate(ctx context.Context, text string, opts *text2text.Options) (text2text.Response, error) {
+	return n.m.Generate(ctx, n.normalize(text).Text, opts)
+}
+
+type zeroShotNormalized struct {
+	normalized[zeroshotclassifier.Interface]
+}
+
+func (n zeroShotNormalized) Classify(ctx context.Context, text string, parameters zeroshotclassifier.Parameters) (zeroshotclassifier.Response, error) {
+	return n.m.Classify(ctx, n.normalize(text).Text, parameters)
+}
+
+type questionAnsweringNormalized struct {
+	normalized[questionanswering.Interface]
+}
+
+func (n questionAnsweringNormalized) Answer(ctx context.Context, question string, passage string, opts *questionanswering.Options) (questionanswering.Response, error) {
+	p := n.normalize(passage)
+	resp, err := n.m.Answer(ctx, n.normalize(question).Text, p.Text, opts)
+	r := tokenizers.NewRuneOffsets(passage)
+	for i, a := range resp.Answers {
+		b, o := originalOffsets(p, r, a.ByteStart, a.ByteEnd)
+		a.Text = strings.Trim(passage[b.Start:b.End], " ")
+		a.Start, a.End, a.ByteStart, a.ByteEnd = o.Start, o.End, b.Start, b.End
+		originalAttributions(p, r, passage, a.Attributions)
+		resp.Answers[i] = a
+	}
+	return resp, err
+}
+
+type textClassificationNormalized struct {
+	normalized[textclassification.Interface]
+}
+
+func (n textClassificationNormalized) Classify(ctx context.Context, text string, parameters textclassification.Parameters) (textclassification.Response, error) {
+	t := n.normalize(text)
+	if parameters.TextPair != "" {
+		parameters.TextPair = n.normalize(parameters.TextPair).Text
+	}
+	resp, err := n.m.Classify(ctx, t.Text, parameters)
+	if len(resp.Attributions) > 0 {
+		originalAttributions(t, tokenizers.NewRuneOffsets(text), text, resp.Attributions)
+	}
+	return resp, err
+}
+
+type tokenClassificationNormalized struct {
+	normalized[tokenclassification.Interface]
+}
+
+func (n tokenClassificationNormalized) Classify(ctx context.Context, text string, parameters tokenclassification.Parameters) (tokenclassification.Response, error) {
+	t := n.normalize(text)
+	resp, err := n.m.Classify(ctx, t.Text, parameters)
+	r := tokenizers.NewRuneOffsets(text)
+	for i, tok := range resp.Tokens {
+		b, o := originalOffsets(t, r, tok.ByteStart, tok.ByteEnd)
+		tok.Text = text[b.Start:b.End]
+		tok.Start, tok.End, tok.ByteStart, tok.ByteEnd = o.Start, o.End, b.Start, b.End
+		resp.Tokens[i] = tok
+	}
+	return resp, err
+}
+
+type textEncodingNormalized struct {
+	normalized[textencoding.Interface]
+}
+
+func (n textEncodingNormalized) Encode(ctx context.Context, text string, poolingStrategy int) (textencoding.Response, error) {
+	return n.m.Encode(ctx, n.normalize(text).Text, poolingStrategy)
+}
+
+type languageModelingNormalized struct {
+	normalized[languagemodeling.Interface]
+}
+
+func (n languageModelingNormalized) Predict(ctx context.Context, text string, parameters languagemodeling.Parameters) (languagemodeling.Response, error) {
+	t := n.normalize(text)
+	resp, err := n.m.Predict(ctx, t.Text, parameters)
+	r := tokenizers.NewRuneOffsets(text)
+	for i, tok := range resp.Tokens {
+		b, o := originalOffsets(t, r, tok.ByteStart, tok.ByteEnd)
+		resp.Tokens[i].Start, resp.Tokens[i].End = o.Start, o.End
+		resp.Tokens[i].ByteStart, resp.Tokens[i].ByteEnd = b.Start, b.End
+	}
+	return resp, err
+}
+
+type coreferenceNormalized struct {
+	normalized[coreference.Interface]
+}
+
+func (n coreferenceNormalized) Resolve(ctx context.Context, text string) (coreference.Response, error) {
+	t := n.normalize(text)
+	resp, err := n.m.Resolve(ctx, t.Text)
+	r := tokenizers.NewRuneOffsets(text)
+	for _, c := range resp.Clusters {
+		for i, m := range c.Mentions {
+			b, o := originalOffsets(t, r, m.ByteStart, m.ByteEnd)
+			m.Text = text[b.Start:b.End]
+			m.Start, m.End, m.ByteStart, m.ByteEnd = o.Start, o.End, b.Start, b.End
+			c.Mentions[i] = m
+		}
+	}
+	return resp, err
+}
+
+type relationExtractionNormalized struct {
+	normalized[relationextraction.Interface]
+}
+
+// Extract passes the entities of the parameters by their normalized text,
+// since their offsets are the ones of the original text.
+func (n relationExtractionNormalized) Extract(ctx context.Context, text string, parameters relationextraction.Parameters) (relationextraction.Response, error) {
+	entities, err := relationextraction.ResolveEntities(text, parameters.Entities)
+	if err != nil {
+		return relationextraction.Response{}, err
+	}
+	for i, e := range entities {
+		entities[i] = relationextraction.Entity{Text: n.normalize(e.Text).Text, Label: e.Label}
+	}
+	t := n.normalize(text)
+	resp, err := n.m.Extract(ctx, t.Text, relationextraction.Parameters{Entities: entities})
+	r := tokenizers.NewRuneOffsets(text)
+	original := func(e relationextraction.Entity) relationextraction.Entity {
+		if e.Start < 0 {
+			return e
+		}
+		b, o := originalOffsets(t, r, e.ByteStart, e.ByteEnd)
+		e.Text = text[b.Start:b.End]
+		e.Start, e.End, e.ByteStart, e.ByteEnd = o.Start, o.End, b.Start, b.End
+		return e
+	}
+	for i, rel := range resp.Relations {
+		resp.Relations[i].Head, resp.Relations[i].Tail = original(rel.Head), original(rel.Tail)
+	}
+	return resp, err
+}
+
+type sentenceSegmentationNormalized struct {
+	normalized[sentencesegmentation.Interface]
+}
+
+func (n sentenceSegmentationNormalized) Segment(ctx context.Context, text string) (sentencesegmentation.Response, error) {
+	t := n.normalize(text)
+	resp, err := n.m.Segment(ctx, t.Text)
+	r := tokenizers.NewRuneOffsets(text)
+	for i, s := range resp.Sentences {
+		b, o := originalOffsets(t, r, s.ByteStart, s.ByteEnd)
+		s.Text = text[b.Start:b.End]
+		s.Start, s.End, s.ByteStart, s.ByteEnd = o.Start, o.End, b.Start, b.End
+		resp.Sentences[i] = s
+	}
+	return resp, err
+}
+
+type posTaggingNormalized struct {
+	normalized[postagging.Interface]
+}
+
+func (n posTaggingNormalized) Tag(ctx context.Context, text string) (postagging.Response, error) {
+	t := n.normalize(text)
+	resp, err := n.m.Tag(ctx, t.Text)
+	r := tokenizers.NewRuneOffsets(text)
+	for i, token := range resp.Tokens {
+		b, o := originalOffsets(t, r, token.ByteStart, token.ByteEnd)
+		token.Text = text[b.Start:b.End]
+		token.Start, token.End, token.ByteStart, token.ByteEnd = o.Start, o.End, b.Start, b.End
+		resp.Tokens[i] = token
+	}
+	return resp, err
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"fmt"
+
+	"github.com/nlpodyssey/cybertron/pkg/numa"
+	"github.com/rs/zerolog/log"
+)
+
+// placement places the replicas of a model on NUMA nodes, round-robin.
+type placement struct {
+	nodes []numa.Node
+	// placed are the nodes of the replicas loaded so far, in order.
+	placed []*numa.Node
+}
+
+// numaPlacement returns the placement of the replicas on the configured
+// NUMA nodes, empty if not configured (see Config.NUMANodes).
+func (l loader[T]) numaPlacement() (*placement, error) {
+	p := &placement{}
+	if l.conf.NUMANodes == "" {
+		return p, nil
+	}
+	nodes, err := numa.Nodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the NUMA nodes: %w", err)
+	}
+	if p.nodes, err = numa.Select(nodes, l.conf.NUMANodes); err != nil {
+		return nil, err
+	}
+	if len(p.nodes) == 0 {
+		return nil, fmt.Errorf("no NUMA nodes in %#v", l.conf.NUMANodes)
+	}
+	return p, nil
+}
+
+// withPlacement returns the loading function loading each model bound to
+// the next node of the placement, if any, so that its weights are on that
+// node (see numa.Bind).
+func (l loader[T]) withPlacement(p *placement, load func() (T, error)) func() (T, error) {
+	if len(p.nodes) == 0 {
+		return load
+	}
+	return func() (T, error) {
+		node := p.nodes[len(p.placed)%len(p.nodes)]
+		unbind, err := numa.Bind(node)
+		if err != nil {
+			var empty T
+			return empty, fmt.Errorf("failed to bind to the NUMA node %d: %w", node.ID, err)
+		}
+		defer unbind()
+		obj, err := load()
+		if err != nil {
+			return obj, err
+		}
+		p.placed = append(p.placed, &node)
+		log.Info().Str("model", l.conf.ModelName).Int("node", node.ID).Msg("model placed on NUMA node")
+		return obj, nil
+	}
+}
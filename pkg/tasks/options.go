@@ -0,0 +1,87 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"context"
+
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
+)
+
+// Option sets an option of the configuration of the loading of a model (see
+// New). Any function setting the Config can be used as an Option, for the
+// settings without one.
+type Option func(*Config)
+
+// New returns the model of the task T with the name, e.g.
+// textclassification.Interface, loaded with the options, as Load does: the
+// context bounds its download, conversion and loading.
+//
+//	m, err := tasks.New[textencoding.Interface](ctx, textencoding.DefaultModel,
+//		tasks.WithModelsDir("models"),
+//		tasks.WithMaxLength(256),
+//		tasks.WithPooling(tasks.PoolingMean))
+func New[T any](ctx context.Context, modelName string, opts ...Option) (T, error) {
+	conf := Config{ModelName: modelName}
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	return LoadContext[T](ctx, &conf)
+}
+
+// WithModelsDir sets the directory where the models are stored.
+func WithModelsDir(dir string) Option {
+	return func(c *Config) { c.ModelsDir = dir }
+}
+
+// WithRevision sets the branch, tag or commit SHA of the model to download.
+func WithRevision(revision string) Option {
+	return func(c *Config) { c.Revision = revision }
+}
+
+// WithBackend sets the engine used to run the model.
+func WithBackend(backend Backend) Option {
+	return func(c *Config) { c.Backend = backend }
+}
+
+// WithDevice sets the device running the large matrix products of the model,
+// e.g. "cuda", for the onnx backend.
+func WithDevice(device string) Option {
+	return func(c *Config) { c.Device = device }
+}
+
+// WithQuantization sets the quantization scheme applied to the weights of the
+// converted model.
+func WithQuantization(scheme quantization.Scheme) Option {
+	return func(c *Config) { c.ConversionQuantization = scheme }
+}
+
+// WithPrecision sets the floating-point precision of the converted model.
+func WithPrecision(precision FloatPrecision) Option {
+	return func(c *Config) { c.ConversionPrecision = precision }
+}
+
+// WithReplicas sets the number of replicas of the model loaded.
+func WithReplicas(n int) Option {
+	return func(c *Config) { c.Replicas = n }
+}
+
+// WithMaxLength sets the maximum length in tokens of the inputs, beyond which
+// the requests fail with errdefs.ErrInputTooLong.
+func WithMaxLength(n int) Option {
+	return func(c *Config) { c.MaxLength = n }
+}
+
+// WithPooling sets the pooling strategy of the text encodings, replacing the
+// one of the requests.
+func WithPooling(pooling Pooling) Option {
+	return func(c *Config) { c.Pooling = pooling }
+}
+
+// WithProgress sets the function called with the progress of the loading of
+// the model, e.g. of its download.
+func WithProgress(f func(Progress)) Option {
+	return func(c *Config) { c.Progress = f }
+}
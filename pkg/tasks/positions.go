@@ -0,0 +1,61 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/nlpodyssey/spago/nn"
+	"github.com/rs/zerolog/log"
+)
+
+// withPrecomputedPositions returns the loading function precomputing the
+// embeddings of the positions of the models it loads, if configured (see
+// Config.PrecomputePositions).
+func (l loader[T]) withPrecomputedPositions(load func() (T, error)) func() (T, error) {
+	if !l.conf.PrecomputePositions || l.conf.Backend == BackendONNX {
+		return load
+	}
+	return func() (T, error) {
+		obj, err := load()
+		if err != nil {
+			return obj, err
+		}
+		start := time.Now()
+		if n := precomputePositions(obj); n > 0 {
+			log.Info().Str("model", l.conf.ModelName).Dur("duration", time.Since(start)).Msg("model positions precomputed")
+		}
+		return obj, nil
+	}
+}
+
+// precomputePositions precomputes the embeddings of the positions of the
+// modules of the spago models held by the model of the task, returning the
+// number of the modules.
+func precomputePositions(obj any) int {
+	v := reflect.Indirect(reflect.ValueOf(obj))
+	if v.Kind() != reflect.Struct {
+		return 0
+	}
+	n := 0
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if !v.Type().Field(i).IsExported() || (f.Kind() == reflect.Pointer && f.IsNil()) {
+			continue
+		}
+		m, ok := f.Interface().(nn.Model)
+		if !ok {
+			continue
+		}
+		nn.Apply(m, func(m nn.Model) {
+			if p, ok := m.(interface{ PrecomputePositions() }); ok {
+				p.PrecomputePositions()
+				n++
+			}
+		})
+	}
+	return n
+}
@@ -0,0 +1,38 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postagging
+
+import "context"
+
+// DefaultModel is a Flair model for the part-of-speech tagging of the English
+// language with the universal part-of-speech tags (UPOS).
+// Model card: https://huggingface.co/flair/upos-english
+const DefaultModel = "flair/upos-english"
+
+// Interface defines the main functions for the part-of-speech tagging task.
+// Its implementations are safe for concurrent use.
+type Interface interface {
+	// Tag returns the words of the given text, with their part-of-speech tags.
+	Tag(ctx context.Context, text string) (Response, error)
+}
+
+// Token is a word of the text with its part-of-speech tag, e.g. "NOUN" or
+// "VERB". Start and End are its offsets in the text in runes, ByteStart and
+// ByteEnd in bytes. Score is the probability of the tag, 1 for the
+// rule-based taggers.
+type Token struct {
+	Text      string
+	Start     int
+	End       int
+	Tag       string
+	Score     float64
+	ByteStart int
+	ByteEnd   int
+}
+
+// Response contains the response from part-of-speech tagging.
+type Response struct {
+	Tokens []Token
+}
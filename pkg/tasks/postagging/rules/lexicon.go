@@ -0,0 +1,81 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rules
+
+import "strings"
+
+// closedClasses are the English words of the closed classes, by their
+// universal part-of-speech tag, with some of the most common open class
+// words, tagged by their most frequent tag.
+var closedClasses = map[string]string{
+	"DET": "the a an this these those every each some any no all both either neither another such " +
+		"what which whatever whichever",
+	"PRON": "i you he she it we they me him us them myself yourself himself herself itself ourselves " +
+		"yourselves themselves mine yours hers ours theirs my your his her its our their who whom whose " +
+		"someone somebody something anyone anybody anything everyone everybody everything nobody nothing none",
+	"ADP": "of in on at by for with about against between into through during before after above below " +
+		"from up down over under to than via without within along across behind beyond among around near " +
+		"since until upon toward towards onto off out as per despite except inside outside throughout",
+	"CCONJ": "and or but nor &",
+	"SCONJ": "if because although though while whereas unless whether that once",
+	"AUX": "be am is are was were been being 'm 're will would shall should can could may might must " +
+		"'ll 'd 've ca wo",
+	"PART": "not n't 's",
+	"ADV": "very too also just only never always often sometimes usually here there now then still " +
+		"already even again soon quite rather really almost perhaps however ever yet so how when where why " +
+		"else instead together away back much more most less least well",
+	"INTJ": "oh ah hey hello hi yes wow oops please thanks ok okay",
+	"NUM": "zero one two three four five six seven eight nine ten eleven twelve thirteen fourteen fifteen " +
+		"sixteen seventeen eighteen nineteen twenty thirty forty fifty sixty seventy eighty ninety hundred " +
+		"thousand million billion",
+	"VERB": "have has had having do does did done doing go goes went gone going say says said make makes " +
+		"made get gets got take takes took taken come comes came see sees saw seen know knows knew known " +
+		"give gives gave given find finds found tell tells told think thinks thought become became leave left " +
+		"feel felt bring brought begin began begun keep kept hold held write wrote written stand stood hear " +
+		"heard mean meant meet met run ran pay paid sit sat speak spoke spoken grow grew grown lose lost fall " +
+		"fell sent build built understand understood break broke broken spend spent buy bought choose chose " +
+		"want wants wanted seem seems seemed try tries tried ask asks asked let put",
+	"ADJ": "good better best bad worse worst new old first last long great little own other big high " +
+		"different small large next early young important few public same able sure free full whole real " +
+		"true possible late hard major easy clear happy",
+}
+
+// DefaultLexicon returns the tags of the English words of the closed
+// classes, and of some of the most common open class words, by their
+// lowercase form.
+func DefaultLexicon() map[string]string {
+	lexicon := make(map[string]string)
+	for tag, words := range closedClasses {
+		for _, w := range strings.Fields(words) {
+			lexicon[w] = tag
+		}
+	}
+	return lexicon
+}
+
+// suffixes are the tags of the words by their suffixes, the longest first.
+var suffixes = []struct {
+	suffix string
+	tag    string
+}{
+	{"ization", "NOUN"}, {"ation", "NOUN"}, {"ition", "NOUN"}, {"tion", "NOUN"}, {"sion", "NOUN"},
+	{"ment", "NOUN"}, {"ness", "NOUN"}, {"ship", "NOUN"}, {"hood", "NOUN"}, {"ance", "NOUN"}, {"ence", "NOUN"},
+	{"ity", "NOUN"}, {"ism", "NOUN"}, {"ist", "NOUN"},
+	{"ously", "ADV"}, {"ly", "ADV"},
+	{"able", "ADJ"}, {"ible", "ADJ"}, {"ous", "ADJ"}, {"ful", "ADJ"}, {"less", "ADJ"}, {"ive", "ADJ"},
+	{"ical", "ADJ"}, {"ish", "ADJ"}, {"ary", "ADJ"}, {"ic", "ADJ"}, {"al", "ADJ"},
+	{"ize", "VERB"}, {"ise", "VERB"}, {"ify", "VERB"}, {"ing", "VERB"}, {"ed", "VERB"},
+}
+
+// bySuffix returns the tag of the word by its suffix, if any, for the words
+// longer than the suffix by three letters at least.
+func bySuffix(word string) (string, bool) {
+	for _, s := range suffixes {
+		if len(word) >= len(s.suffix)+3 && strings.HasSuffix(word, s.suffix) {
+			return s.tag, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,192 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package rules implements a rule-based English part-of-speech tagging,
+// needing no model, with the universal part-of-speech tags (UPOS).
+package rules
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/postagging"
+	segmentation "github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation/rules"
+	"github.com/nlpodyssey/cybertron/pkg/tokenizers"
+)
+
+var _ postagging.Interface = &Tagger{}
+
+// Tagger is a rule-based English part-of-speech tagger. The words are tagged
+// by the Lexicon, if found, else as proper nouns if capitalized, but at the
+// start of the sentences, else by their suffix, e.g. "-ly" adverbs, or else
+// as nouns. A few rules of the context fix the tags of the ambiguous words,
+// e.g. the verbs after the subject pronouns and the modals, the auxiliary
+// "have" and "do" followed by a verb, or the infinitive "to".
+type Tagger struct {
+	// Lexicon are the tags of the words, by their lowercase form.
+	Lexicon map[string]string
+	// segmenter finds the starts of the sentences.
+	segmenter *segmentation.Segmenter
+}
+
+// New returns a new Tagger with the DefaultLexicon.
+func New() *Tagger {
+	return &Tagger{
+		Lexicon:   DefaultLexicon(),
+		segmenter: segmentation.New(),
+	}
+}
+
+// Tag returns the words of the given text (see segmentation.Tokenize), with
+// their part-of-speech tags.
+func (t *Tagger) Tag(_ context.Context, text string) (postagging.Response, error) {
+	starts := make(map[int]bool)
+	for _, s := range t.segmenter.Split(text) {
+		starts[s.Start] = true
+	}
+
+	words := segmentation.Tokenize(text)
+	tags := make([]string, len(words))
+	// guessed are the words tagged as nouns for want of a better guess.
+	guessed := make([]bool, len(words))
+	initial := true
+	for i, w := range words {
+		if starts[w.Offsets.Start] {
+			initial = true
+		}
+		tags[i], guessed[i] = t.tagWord(w.String, initial)
+		if tags[i] != "PUNCT" {
+			initial = false
+		}
+	}
+	t.applyContext(words, tags, guessed)
+
+	offsets := tokenizers.NewRuneOffsets(text)
+	tokens := make([]postagging.Token, len(words))
+	for i, w := range words {
+		b := offsets.Bytes(w.Offsets)
+		tokens[i] = postagging.Token{
+			Text:      w.String,
+			Start:     w.Offsets.Start,
+			End:       w.Offsets.End,
+			Tag:       tags[i],
+			Score:     1,
+			ByteStart: b.Start,
+			ByteEnd:   b.End,
+		}
+	}
+	return postagging.Response{Tokens: tokens}, nil
+}
+
+// tagWord returns the tag of the word on its own, and whether it's a noun
+// for want of a better guess.
+func (t *Tagger) tagWord(word string, initial bool) (string, bool) {
+	r, _ := utf8.DecodeRuneInString(word)
+	switch {
+	case isSymbol(word):
+		return "SYM", false
+	case unicode.IsPunct(r) && utf8.RuneCountInString(word) == 1:
+		return "PUNCT", false
+	case unicode.IsNumber(r):
+		return "NUM", false
+	}
+	lower := strings.ToLower(strings.ReplaceAll(word, "’", "'"))
+	if tag, ok := t.Lexicon[lower]; ok {
+		return tag, false
+	}
+	if unicode.IsUpper(r) && !initial {
+		return "PROPN", false
+	}
+	if tag, ok := bySuffix(lower); ok {
+		return tag, false
+	}
+	return "NOUN", true
+}
+
+// applyContext fixes the tags of the words by their context.
+func (t *Tagger) applyContext(words []tokenizers.StringOffsetsPair, tags []string, guessed []bool) {
+	lower := func(i int) string {
+		if i < 0 || i >= len(words) {
+			return ""
+		}
+		return strings.ToLower(strings.ReplaceAll(words[i].String, "’", "'"))
+	}
+	// next returns the index of the next word, skipping the negations and
+	// the adverbs.
+	next := func(i int) int {
+		for i++; i < len(words) && (tags[i] == "ADV" || tags[i] == "PART" && lower(i) != "'s"); i++ {
+		}
+		return i
+	}
+
+	// The verbs after the subject pronouns and the modals.
+	for i := range words {
+		switch tags[i] {
+		case "PRON", "AUX":
+			if !isSubjectOrModal(lower(i)) {
+				continue
+			}
+			if j := next(i); j < len(words) && guessed[j] {
+				tags[j] = "VERB"
+			}
+		}
+	}
+	for i := range words {
+		switch w := lower(i); {
+		case guessed[i] && unicode.IsUpper([]rune(words[i].String)[0]) && i+1 < len(words) &&
+			(tags[i+1] == "PROPN" || lower(i+1) == "'s"):
+			// e.g. "John's" or "New York" at the start of the sentence
+			tags[i] = "PROPN"
+		case w == "'s" && tags[i] == "PART" && i > 0 && tags[i-1] == "PRON":
+			// e.g. "it's"
+			tags[i] = "AUX"
+		case isAuxiliaryVerb(w):
+			if j := next(i); j < len(words) && (tags[j] == "VERB" || tags[j] == "AUX") {
+				tags[i] = "AUX"
+			}
+		case w == "to":
+			if i+1 < len(words) && (tags[i+1] == "VERB" || tags[i+1] == "AUX") {
+				tags[i] = "PART"
+			}
+		case w == "that":
+			switch {
+			case i+1 < len(words) && tags[i+1] == "NOUN":
+				tags[i] = "DET"
+			case i > 0 && (tags[i-1] == "NOUN" || tags[i-1] == "PROPN") && i+1 < len(words) &&
+				(tags[i+1] == "VERB" || tags[i+1] == "AUX"):
+				tags[i] = "PRON"
+			}
+		}
+	}
+}
+
+// isSubjectOrModal reports whether the word is a subject pronoun or a modal
+// auxiliary, followed by a verb.
+func isSubjectOrModal(w string) bool {
+	switch w {
+	case "i", "you", "he", "she", "we", "they",
+		"will", "would", "shall", "should", "can", "could", "may", "might", "must", "'ll", "'d", "ca", "wo":
+		return true
+	default:
+		return false
+	}
+}
+
+// isAuxiliaryVerb reports whether the verb is an auxiliary when followed by
+// another verb, e.g. "have" of "have seen".
+func isAuxiliaryVerb(w string) bool {
+	switch w {
+	case "have", "has", "had", "having", "do", "does", "did":
+		return true
+	default:
+		return false
+	}
+}
+
+func isSymbol(w string) bool {
+	r, size := utf8.DecodeRuneInString(w)
+	return size == len(w) && (unicode.IsSymbol(r) || strings.ContainsRune("%#@*/\\§", r))
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rules
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/postagging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagger_Tag(t *testing.T) {
+	tagger := New()
+	tags := func(t *testing.T, text string) string {
+		resp, err := tagger.Tag(context.Background(), text)
+		require.NoError(t, err)
+		out := make([]string, len(resp.Tokens))
+		for i, token := range resp.Tokens {
+			out[i] = token.Text + "/" + token.Tag
+		}
+		return strings.Join(out, " ")
+	}
+
+	t.Run("auxiliaries and verbs", func(t *testing.T) {
+		got := tags(t, "I don't think that Alice has finished her report, but she will submit it.")
+		assert.Equal(t, "I/PRON do/AUX n't/PART think/VERB that/SCONJ Alice/PROPN has/AUX finished/VERB her/PRON "+
+			"report/NOUN ,/PUNCT but/CCONJ she/PRON will/AUX submit/VERB it/PRON ./PUNCT", got)
+	})
+
+	t.Run("proper nouns, numbers and symbols", func(t *testing.T) {
+		got := tags(t, "John's car was quickly repaired in Paris for $300.")
+		assert.Equal(t, "John/PROPN 's/PART car/NOUN was/AUX quickly/ADV repaired/VERB in/ADP Paris/PROPN "+
+			"for/ADP $/SYM 300/NUM ./PUNCT", got)
+	})
+
+	t.Run("infinitive and contractions", func(t *testing.T) {
+		got := tags(t, "They want to go to school. It's beautiful!")
+		assert.Equal(t, "They/PRON want/VERB to/PART go/VERB to/ADP school/NOUN ./PUNCT "+
+			"It/PRON 's/AUX beautiful/ADJ !/PUNCT", got)
+	})
+
+	t.Run("offsets", func(t *testing.T) {
+		resp, err := tagger.Tag(context.Background(), "Zoë's café")
+		require.NoError(t, err)
+		assert.Equal(t, []postagging.Token{
+			{Text: "Zoë", Start: 0, End: 3, Tag: "PROPN", Score: 1, ByteStart: 0, ByteEnd: 4},
+			{Text: "'s", Start: 3, End: 5, Tag: "PART", Score: 1, ByteStart: 4, ByteEnd: 6},
+			{Text: "café", Start: 6, End: 10, Tag: "NOUN", Score: 1, ByteStart: 7, ByteEnd: 12},
+		}, resp.Tokens)
+	})
+}
+
+func TestDefaultLexicon(t *testing.T) {
+	seen := make(map[string]string)
+	for tag, words := range closedClasses {
+		for _, w := range strings.Fields(words) {
+			if other, ok := seen[w]; ok {
+				t.Errorf("word %q tagged both %s and %s", w, other, tag)
+			}
+			seen[w] = tag
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tagger implements the part-of-speech tagging with the token
+// classification models labeling the words with their tags, e.g. the Flair
+// and the BERT part-of-speech taggers.
+package tagger
+
+import (
+	"context"
+	"io"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/postagging"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+)
+
+var _ postagging.Interface = &Tagger{}
+
+// Tagger is a part-of-speech tagger tagging the words with the labels of a
+// token classification model.
+type Tagger struct {
+	// Model is the token classification model labeling the words.
+	Model tokenclassification.Interface
+}
+
+// New returns a new Tagger tagging the words with the model.
+func New(m tokenclassification.Interface) *Tagger {
+	return &Tagger{Model: m}
+}
+
+// Close finalizes the model of the Tagger, if it's an io.Closer.
+// It satisfies the interface io.Closer.
+func (t *Tagger) Close() error {
+	if c, ok := t.Model.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Tag returns the words of the given text, as tokenized by the model, with
+// their labels as part-of-speech tags, without their IOB prefix, if any.
+func (t *Tagger) Tag(ctx context.Context, text string) (postagging.Response, error) {
+	resp, err := t.Model.Classify(ctx, text, tokenclassification.Parameters{
+		AggregationStrategy: tokenclassification.AggregationStrategyNone,
+	})
+	if err != nil {
+		return postagging.Response{}, err
+	}
+
+	tokens := make([]postagging.Token, len(resp.Tokens))
+	for i, token := range resp.Tokens {
+		tokens[i] = postagging.Token{
+			Text:      token.Text,
+			Start:     token.Start,
+			End:       token.End,
+			Tag:       tokenclassification.StripPrefix(token.Label),
+			Score:     token.Score,
+			ByteStart: token.ByteStart,
+			ByteEnd:   token.ByteEnd,
+		}
+	}
+	return postagging.Response{Tokens: tokens}, nil
+}
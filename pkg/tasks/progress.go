@@ -0,0 +1,55 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"github.com/nlpodyssey/cybertron/pkg/downloader"
+)
+
+// Stage is a stage of the loading of a model (see Progress).
+type Stage string
+
+const (
+	// StageDownloading is the download of the files of the model.
+	StageDownloading Stage = "downloading"
+	// StageConverting is the conversion of the model, or the check that it
+	// is already converted.
+	StageConverting Stage = "converting"
+	// StageLoading is the loading of the converted model in memory.
+	StageLoading Stage = "loading"
+	// StageReady means that the model is loaded, ready to serve.
+	StageReady Stage = "ready"
+)
+
+// Progress is the progress of the loading of a model, reported to
+// Config.Progress.
+type Progress struct {
+	// Model is the name of the model.
+	Model string `json:"model"`
+	// Stage is the stage of the loading.
+	Stage Stage `json:"stage"`
+	// Download is the progress of the download of a file, in the
+	// downloading stage.
+	Download *downloader.Progress `json:"download,omitempty"`
+}
+
+// report reports the stage of the loading to the Progress of the
+// configuration, if set.
+func (l loader[T]) report(stage Stage, d *downloader.Progress) {
+	if l.conf.Progress != nil {
+		l.conf.Progress(Progress{Model: l.conf.ModelName, Stage: stage, Download: d})
+	}
+}
+
+// downloadProgress returns the function reporting the progress of the
+// download of the files, if the Progress of the configuration is set.
+func (l loader[T]) downloadProgress() func(downloader.Progress) {
+	if l.conf.Progress == nil {
+		return nil
+	}
+	return func(p downloader.Progress) {
+		l.report(StageDownloading, &p)
+	}
+}
@@ -7,16 +7,24 @@ package bert
 import (
 	"context"
 	"fmt"
+	"io"
 	"path"
 	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/nlpodyssey/cybertron/pkg/models/bert"
+	"github.com/nlpodyssey/cybertron/pkg/models/bert/bertconfig"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/attribution"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/diskstoremode"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sharedweights"
+	"github.com/nlpodyssey/cybertron/pkg/timings"
 	"github.com/nlpodyssey/cybertron/pkg/tokenizers"
 	"github.com/nlpodyssey/cybertron/pkg/tokenizers/wordpiecetokenizer"
+	"github.com/nlpodyssey/cybertron/pkg/trace"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
+	"github.com/nlpodyssey/cybertron/pkg/utils/matview"
 	"github.com/nlpodyssey/cybertron/pkg/utils/sliceutils"
 	"github.com/nlpodyssey/cybertron/pkg/vocabulary"
 	"github.com/nlpodyssey/spago/ag"
@@ -38,8 +46,11 @@ type QuestionAnswering struct {
 	Model *bert.ModelForQuestionAnswering
 	// Tokenizer is the tokenizer used to tokenize questions and passages.
 	Tokenizer *wordpiecetokenizer.WordPieceTokenizer
-	// embeddingsRepo is the repository used for loading embeddings.
-	embeddingsRepo *diskstore.Repository
+	// inputs builds the inputs of the model from the question and the passage.
+	inputs bertconfig.Inputs
+	// release releases the encoder, with its embeddings, shared with the
+	// other models loaded from the same file.
+	release func() error
 }
 
 // LoadQuestionAnswering returns a QuestionAnswering loading the model, the embeddings and the tokenizer from a directory.
@@ -50,48 +61,65 @@ func LoadQuestionAnswering(modelPath string) (*QuestionAnswering, error) {
 	}
 	tokenizer := wordpiecetokenizer.New(vocab)
 
-	embeddingsRepo, err := diskstore.NewRepository(filepath.Join(modelPath, "repo"), diskstoremode.DefaultDiskStoreMode)
+	m, err := nn.LoadFromFile[*bert.ModelForQuestionAnswering](path.Join(modelPath, "spago_model.bin"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to load embeddings repository for question-answering: %w", err)
+		return nil, fmt.Errorf("failed to load bart model: %w", err)
 	}
 
-	m, err := nn.LoadFromFile[*bert.ModelForQuestionAnswering](path.Join(modelPath, "spago_model.bin"))
+	key, err := sharedweights.Key(path.Join(modelPath, "spago_model.bin"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load bart model: %w", err)
 	}
-
-	err = m.Bert.SetEmbeddings(embeddingsRepo)
+	encoder, release, err := sharedweights.Acquire(key, func() (*bert.Model, io.Closer, error) {
+		embeddingsRepo, err := diskstore.NewRepository(filepath.Join(modelPath, "repo"), diskstoremode.DefaultDiskStoreMode)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load embeddings repository for question-answering: %w", err)
+		}
+		if err := m.Bert.SetEmbeddings(embeddingsRepo); err != nil {
+			_ = embeddingsRepo.Close()
+			return nil, nil, fmt.Errorf("failed to set embeddings: %w", err)
+		}
+		return m.Bert, embeddingsRepo, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to set embeddings: %w", err)
+		return nil, err
 	}
+	m.Bert = encoder
 
 	return &QuestionAnswering{
-		Model:          m,
-		Tokenizer:      tokenizer,
-		embeddingsRepo: embeddingsRepo,
+		Model:     m,
+		Tokenizer: tokenizer,
+		inputs:    bertconfig.NewInputs(m.Bert.Config, bertconfig.TokenizerConfig{}),
+		release:   release,
 	}, nil
 }
 
 // Close finalizes the QuestionAnswering resources.
 // It satisfies the interface io.Closer.
 func (qa *QuestionAnswering) Close() error {
-	return qa.embeddingsRepo.Close()
+	return qa.release()
 }
 
 // Answer returns the answers for the given question and passage.
 // The options may assume default values if those are not set.
-func (qa *QuestionAnswering) Answer(_ context.Context, question string, passage string, opts *questionanswering.Options) (questionanswering.Response, error) {
+func (qa *QuestionAnswering) Answer(ctx context.Context, question string, passage string, opts *questionanswering.Options) (questionanswering.Response, error) {
 	checkOptions(opts)
 
+	end := timings.Start(ctx, timings.Tokenization)
 	qt, pt := qa.tokenize(question, passage)
+	end()
 	if l, max := len(qt)+len(pt), qa.Model.Bert.Config.MaxPositionEmbeddings; l > max {
 		return questionanswering.Response{}, fmt.Errorf("%w: %d > %d", questionanswering.ErrInputSequenceTooLong, l, max)
 	}
+	usage.AddTokens(ctx, len(qt)+len(pt), 0)
 
-	starts, ends := qa.Model.Answer(concat(qt, pt))
-	starts, ends = adjustLogitsForInference(starts, ends, qt, pt)
-	startsIdx := getBestIndices(extractScores(starts), opts.MaxCandidates)
-	endsIdx := getBestIndices(extractScores(ends), opts.MaxCandidates)
+	tokenized := qa.inputs.Pair(tokenizers.GetStrings(qt), tokenizers.GetStrings(pt)).Tokens
+	end = timings.Start(ctx, timings.Forward)
+	starts, ends := passageLogits(qa.Model.Logits(tokenized), len(tokenized)-len(pt)-1, pt)
+	end()
+	trace.Encode(ctx, tokenized, qa.Model.Bert.EncodeEach)
+	startsIdx := getBestIndices(starts, opts.MaxCandidates)
+	endsIdx := getBestIndices(ends, opts.MaxCandidates)
 	candidates := searchCandidates(startsIdx, endsIdx, starts, ends, pt, passage, opts.MaxAnswerLength)
 	answers := filterUnlikelyCandidates(candidates, opts.MinScore)
 
@@ -107,11 +135,77 @@ func (qa *QuestionAnswering) Answer(_ context.Context, question string, passage
 		answers = answers[:opts.MaxAnswers]
 	}
 
+	if opts.Explain {
+		if err := qa.explain(ctx, passage, tokenized, pt, starts, ends, answers); err != nil {
+			return questionanswering.Response{}, err
+		}
+	}
+
 	return questionanswering.Response{
 		Answers: answers,
 	}, nil
 }
 
+// explain sets the attributions of the answers to the tokens of the
+// passage, masking them in turn.
+func (qa *QuestionAnswering) explain(ctx context.Context, passage string, tokenized []string, pt []tokenizers.StringOffsetsPair, starts, ends []float64, answers []questionanswering.Answer) error {
+	spans := make([][2]int, len(answers))
+	for i, a := range answers {
+		spans[i] = tokenSpan(pt, a)
+	}
+	start := len(tokenized) - len(pt) - 1 // after the question and the separators
+	positions := make([]int, len(pt))
+	for i := range positions {
+		positions[i] = start + i
+	}
+	drops, err := attribution.Occlusion(ctx, tokenized, positions, wordpiecetokenizer.DefaultMaskToken, spanProbs(starts, ends, spans),
+		func(tokens []string) ([]float64, error) {
+			starts, ends := passageLogits(qa.Model.Logits(tokens), start, pt)
+			return spanProbs(starts, ends, spans), nil
+		})
+	if err != nil {
+		return err
+	}
+	scores := make([]float64, len(pt))
+	for i := range answers {
+		for j, d := range drops {
+			scores[j] = d[i]
+		}
+		answers[i].Attributions = attribution.Tokens(passage, pt, scores)
+	}
+	return nil
+}
+
+// tokenSpan returns the indices of the first and last tokens of the passage
+// of the answer.
+func tokenSpan(pt []tokenizers.StringOffsetsPair, a questionanswering.Answer) (span [2]int) {
+	for i, t := range pt {
+		if t.Offsets.Start == a.Start {
+			span[0] = i
+			break
+		}
+	}
+	for i := len(pt) - 1; i >= 0; i-- {
+		if pt[i].Offsets.End == a.End {
+			span[1] = i
+			break
+		}
+	}
+	return span
+}
+
+// spanProbs returns the probability of each span of tokens, as the product
+// of the probabilities of its start and of its end tokens.
+func spanProbs(starts, ends []float64, spans [][2]int) []float64 {
+	startProbs := mat.NewVecDense(starts).Softmax().Data().F64()
+	endProbs := mat.NewVecDense(ends).Softmax().Data().F64()
+	probs := make([]float64, len(spans))
+	for i, s := range spans {
+		probs[i] = startProbs[s[0]] * endProbs[s[1]]
+	}
+	return probs
+}
+
 func checkOptions(opts *questionanswering.Options) {
 	if opts.MaxAnswers == 0 {
 		opts.MaxAnswers = defaultMaxAnswers
@@ -134,34 +228,24 @@ func (qa *QuestionAnswering) tokenize(question string, passage string) (qt []tok
 	return
 }
 
-// concat concatenates the question and passage tokens.
-func concat(question, passage []tokenizers.StringOffsetsPair) []string {
-	cls := wordpiecetokenizer.DefaultClassToken
-	sep := wordpiecetokenizer.DefaultSequenceSeparator
-	tokenized := append([]string{cls}, append(tokenizers.GetStrings(question), sep)...)
-	tokenized = append(tokenized, append(tokenizers.GetStrings(passage), sep)...)
-	return tokenized
-}
-
-// adjustLogitsForInference adjusts the logits for inference.
-func adjustLogitsForInference(starts, ends []ag.Node, question, passage []tokenizers.StringOffsetsPair) ([]ag.Node, []ag.Node) {
-	passageStartIndex := len(question) + 2 // the offset is for [CLS] and [SEP] tokens
-	passageEndIndex := passageStartIndex + len(passage)
-	return starts[passageStartIndex:passageEndIndex], ends[passageStartIndex:passageEndIndex]
-}
-
-// extractScores extracts the scores from the logits.
-func extractScores(logits []ag.Node) []float64 {
-	scores := make([]float64, len(logits))
-	for i, node := range logits {
-		scores[i] = node.Value().Scalar().F64()
+// passageLogits returns the "span start logits" and the "span end logits" of
+// the tokens of the passage, starting at the given index of the input,
+// reading them from the logits of the model through views, instead of
+// splitting them into nodes.
+func passageLogits(logits []ag.Node, passageStartIndex int, passage []tokenizers.StringOffsetsPair) (starts, ends []float64) {
+	starts = make([]float64, len(passage))
+	ends = make([]float64, len(passage))
+	for i, y := range logits[passageStartIndex : passageStartIndex+len(passage)] {
+		v := y.Value()
+		starts[i], ends[i] = matview.At(v, 0), matview.At(v, 1)
 	}
-	return scores
+	return starts, ends
 }
 
 // getBestIndices returns the best indices from the given scores.
 func getBestIndices(logits []float64, size int) []int {
-	s := sliceutils.NewIndexedSlice(logits)
+	// The scores are sorted, so they're copied first.
+	s := sliceutils.NewIndexedSlice(append([]float64(nil), logits...))
 	sort.Sort(sort.Reverse(s))
 	if len(s.Indices) < size {
 		return s.Indices
@@ -170,7 +254,8 @@ func getBestIndices(logits []float64, size int) []int {
 }
 
 // searchCandidates searches the candidates from the given starts and ends logits.
-func searchCandidates(startsIdx, endsIdx []int, starts, ends []ag.Node, pt []tokenizers.StringOffsetsPair, passage string, maxLen int) []questionanswering.Answer {
+func searchCandidates(startsIdx, endsIdx []int, starts, ends []float64, pt []tokenizers.StringOffsetsPair, passage string, maxLen int) []questionanswering.Answer {
+	offsets := tokenizers.NewRuneOffsets(passage)
 	candidates := make([]questionanswering.Answer, 0)
 	scores := make([]float64, 0) // the scores are aligned with the candidate answers
 	for _, startIndex := range startsIdx {
@@ -181,13 +266,15 @@ func searchCandidates(startsIdx, endsIdx []int, starts, ends []ag.Node, pt []tok
 			case endIndex-startIndex+1 > maxLen:
 				continue
 			default:
-				startOffset := pt[startIndex].Offsets.Start
-				endOffset := pt[endIndex].Offsets.End
-				scores = append(scores, ag.Add(starts[startIndex], ends[endIndex]).Value().Scalar().F64())
+				o := tokenizers.OffsetsType{Start: pt[startIndex].Offsets.Start, End: pt[endIndex].Offsets.End}
+				b := offsets.Bytes(o)
+				scores = append(scores, starts[startIndex]+ends[endIndex])
 				candidates = append(candidates, questionanswering.Answer{
-					Text:  strings.Trim(string([]rune(passage)[startOffset:endOffset]), " "),
-					Start: startOffset,
-					End:   endOffset,
+					Text:      strings.Trim(passage[b.Start:b.End], " "),
+					Start:     o.Start,
+					End:       o.End,
+					ByteStart: b.Start,
+					ByteEnd:   b.End,
 				})
 			}
 		}
@@ -6,7 +6,9 @@ package questionanswering
 
 import (
 	"context"
-	"errors"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/attribution"
 )
 
 const (
@@ -21,9 +23,14 @@ const (
 
 // ErrInputSequenceTooLong means that pre-processing the input text
 // produced a sequence that exceeds the maximum allowed length.
-var ErrInputSequenceTooLong = errors.New("input sequence too long")
+var ErrInputSequenceTooLong = errdefs.ErrInputTooLong
+
+// ErrExplainNotSupported means that the model can't explain its answers,
+// as requested with Options.Explain.
+var ErrExplainNotSupported = errdefs.New(errdefs.CodeUnsupportedOption, "explanation not supported by the model")
 
 // Interface defines the main functions for question-answering task.
+// Its implementations are safe for concurrent use.
 type Interface interface {
 	Answer(ctx context.Context, question string, passage string, opts *Options) (Response, error)
 }
@@ -38,18 +45,30 @@ type Options struct {
 	MinScore float64
 	// MaxCandidates
 	MaxCandidates int
+	// Explain requests the attributions of each answer to the tokens of the
+	// passage (see the attribution package), at the cost of a forward pass
+	// per token. The models not supporting it fail with ErrExplainNotSupported.
+	Explain bool
 }
 
 // Answer represents the single answer of a question.
 type Answer struct {
 	// Text is the span of text containing the answer.
 	Text string
-	// Start is the start index of the answer in the passage.
+	// Start is the start index of the answer in the passage, in runes.
 	Start int
-	// End is the end index of the answer in the passage.
+	// End is the end index of the answer in the passage, in runes.
 	End int
 	// Score is the score of the answer.
 	Score float64
+	// ByteStart is the start index of the answer in the passage, in bytes.
+	ByteStart int
+	// ByteEnd is the end index of the answer in the passage, in bytes.
+	ByteEnd int
+	// Attributions are the attributions of the answer to the tokens of the
+	// passage, if requested with Explain: the drops of the probability of
+	// its start and end tokens when each token is masked.
+	Attributions []attribution.Token
 }
 
 // Response contains the response from question-answering task.
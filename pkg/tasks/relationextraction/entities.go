@@ -0,0 +1,97 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package relationextraction
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+)
+
+// Locate returns the entity with the given text at its first occurrence in
+// the text, or with the offsets -1 if not found.
+func Locate(text, entity string) Entity {
+	i := strings.Index(text, entity)
+	if entity == "" || i < 0 {
+		return Entity{Text: entity, Start: -1, End: -1, ByteStart: -1, ByteEnd: -1}
+	}
+	start := utf8.RuneCountInString(text[:i])
+	return Entity{
+		Text:      entity,
+		Start:     start,
+		End:       start + utf8.RuneCountInString(entity),
+		ByteStart: i,
+		ByteEnd:   i + len(entity),
+	}
+}
+
+// ResolveEntities returns the entities of the parameters completed from the
+// text: the text of the ones given by their offsets in runes, and the
+// offsets of the ones given by their text, at its first occurrence. It fails
+// with errdefs.ErrInvalidRequest if an entity is out of the text, or not
+// found in it.
+func ResolveEntities(text string, entities []Entity) ([]Entity, error) {
+	if len(entities) == 0 {
+		return nil, nil
+	}
+	runes := make([]int, 0, len(text)+1)
+	for i := range text {
+		runes = append(runes, i)
+	}
+	runes = append(runes, len(text))
+
+	resolved := make([]Entity, len(entities))
+	for i, e := range entities {
+		switch {
+		case e.End > e.Start:
+			if e.Start < 0 || e.End >= len(runes) {
+				return nil, fmt.Errorf("%w: entity [%d, %d) out of the text", errdefs.ErrInvalidRequest, e.Start, e.End)
+			}
+			e.ByteStart, e.ByteEnd = runes[e.Start], runes[e.End]
+			e.Text = text[e.ByteStart:e.ByteEnd]
+		case e.Text != "":
+			label := e.Label
+			if e = Locate(text, e.Text); e.Start < 0 {
+				return nil, fmt.Errorf("%w: entity %q not found in the text", errdefs.ErrInvalidRequest, e.Text)
+			}
+			e.Label = label
+		default:
+			return nil, fmt.Errorf("%w: entity without text nor offsets", errdefs.ErrInvalidRequest)
+		}
+		resolved[i] = e
+	}
+	return resolved, nil
+}
+
+// FilterByEntities returns the relations between the given entities only,
+// matching the head and the tail of each relation with the entities by
+// their text, regardless of the case; their head and tail are the matching
+// entities, with their labels and offsets.
+func FilterByEntities(relations []Relation, entities []Entity) []Relation {
+	find := func(e Entity) (Entity, bool) {
+		for _, x := range entities {
+			if strings.EqualFold(strings.TrimSpace(x.Text), e.Text) {
+				return x, true
+			}
+		}
+		return e, false
+	}
+	var filtered []Relation
+	for _, r := range relations {
+		head, ok := find(r.Head)
+		if !ok {
+			continue
+		}
+		tail, ok := find(r.Tail)
+		if !ok {
+			continue
+		}
+		r.Head, r.Tail = head, tail
+		filtered = append(filtered, r)
+	}
+	return filtered
+}
@@ -0,0 +1,56 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package relationextraction
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+)
+
+func TestResolveEntities(t *testing.T) {
+	text := "Città di Roma, in Italia"
+	entities, err := ResolveEntities(text, []Entity{
+		{Start: 9, End: 13, Label: "LOC"},
+		{Text: "Italia", Label: "LOC"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entity{
+		{Text: "Roma", Label: "LOC", Start: 9, End: 13, ByteStart: 10, ByteEnd: 14},
+		{Text: "Italia", Label: "LOC", Start: 18, End: 24, ByteStart: 19, ByteEnd: 25},
+	}
+	if !reflect.DeepEqual(entities, want) {
+		t.Errorf("got %+v, want %+v", entities, want)
+	}
+
+	for _, e := range []Entity{{Start: 20, End: 30}, {Text: "Milano"}, {}} {
+		if _, err := ResolveEntities(text, []Entity{e}); !errors.Is(err, errdefs.ErrInvalidRequest) {
+			t.Errorf("got error %v for entity %+v, want ErrInvalidRequest", err, e)
+		}
+	}
+}
+
+func TestFilterByEntities(t *testing.T) {
+	text := "Rome is the capital of Italy"
+	relations := []Relation{
+		{Head: Locate(text, "Rome"), Tail: Locate(text, "Italy"), Type: "country", Score: 0.9},
+		{Head: Locate(text, "Italy"), Tail: Locate(text, "Europe"), Type: "continent", Score: 0.8},
+	}
+	entities := []Entity{
+		{Text: "rome", Label: "LOC", Start: 0, End: 4, ByteStart: 0, ByteEnd: 4},
+		{Text: "Italy", Label: "LOC", Start: 23, End: 28, ByteStart: 23, ByteEnd: 28},
+	}
+	want := []Relation{{Head: entities[0], Tail: entities[1], Type: "country", Score: 0.9}}
+	if got := FilterByEntities(relations, entities); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := relations[1].Tail; got.Start != -1 || got.ByteEnd != -1 {
+		t.Errorf("got offsets of an entity not in the text %+v, want -1", got)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package rebel implements the relation extraction with the models
+// generating the relations of a text as linearized triplets, as REBEL
+// (Relation Extraction By End-to-end Language generation) does.
+package rebel
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/relationextraction"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
+	bart_for_text_to_text "github.com/nlpodyssey/cybertron/pkg/tasks/text2text/bart"
+)
+
+var _ relationextraction.Interface = &RelationExtraction{}
+
+// RelationExtraction is a relation extraction model generating the
+// relations of the texts.
+type RelationExtraction struct {
+	// Model is the model used to generate the linearized triplets.
+	Model *bart_for_text_to_text.Text2Text
+}
+
+// LoadRelationExtraction returns a RelationExtraction loading the model, the embeddings and the tokenizer from a directory.
+func LoadRelationExtraction(modelPath string) (*RelationExtraction, error) {
+	m, err := bart_for_text_to_text.LoadText2Text(modelPath)
+	if err != nil {
+		return nil, err
+	}
+	return &RelationExtraction{Model: m}, nil
+}
+
+// Close finalizes the RelationExtraction resources.
+// It satisfies the interface io.Closer.
+func (m *RelationExtraction) Close() error {
+	return m.Model.Close()
+}
+
+// Extract returns the relations generated by the model for the text, sorted
+// by their score, the most likely first: the exponential of the score of
+// the sequence generating them, e.g. the geometric mean of the
+// probabilities of its tokens with the default length penalty. The entities
+// are found by the model; the ones of the parameters, if any, keep the
+// relations between them only (see relationextraction.FilterByEntities).
+func (m *RelationExtraction) Extract(ctx context.Context, text string, parameters relationextraction.Parameters) (relationextraction.Response, error) {
+	entities, err := relationextraction.ResolveEntities(text, parameters.Entities)
+	if err != nil {
+		return relationextraction.Response{}, err
+	}
+	result, err := m.Model.Generate(ctx, text, text2text.DefaultOptions())
+	if err != nil {
+		return relationextraction.Response{}, err
+	}
+
+	var relations []relationextraction.Relation
+	seen := make(map[Triplet]bool)
+	for i, generated := range result.Texts {
+		score := math.Exp(result.Scores[i])
+		for _, t := range ParseTriplets(generated) {
+			if seen[t] {
+				continue
+			}
+			seen[t] = true
+			relations = append(relations, relationextraction.Relation{
+				Head:  relationextraction.Locate(text, t.Head),
+				Tail:  relationextraction.Locate(text, t.Tail),
+				Type:  t.Type,
+				Score: score,
+			})
+		}
+	}
+	sort.SliceStable(relations, func(i, j int) bool {
+		return relations[i].Score > relations[j].Score
+	})
+	if len(entities) > 0 {
+		relations = relationextraction.FilterByEntities(relations, entities)
+	}
+	return relationextraction.Response{Relations: relations}, nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rebel
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Triplet is a relation of the given type from the Head entity to the Tail
+// one, as generated by the model.
+type Triplet struct {
+	Head string
+	Type string
+	Tail string
+}
+
+// tripletTags matches the tags of the triplets linearized by REBEL.
+var tripletTags = regexp.MustCompile(`<triplet>|<subj>|<obj>`)
+
+// ParseTriplets returns the triplets of a text linearized by REBEL, e.g.
+// "<triplet> Rome <subj> Italy <obj> country <subj> Lazio <obj> located in
+// the administrative territorial entity": each "<triplet>" is followed by
+// the head, then by one "<subj>" for each of its relations, followed by the
+// tail and, after "<obj>", by the type. The incomplete triplets are dropped.
+func ParseTriplets(text string) []Triplet {
+	for _, s := range []string{"<s>", "</s>", "<pad>"} {
+		text = strings.ReplaceAll(text, s, "")
+	}
+
+	var triplets []Triplet
+	var cur Triplet
+	commit := func() {
+		if cur.Head != "" && cur.Tail != "" && cur.Type != "" {
+			triplets = append(triplets, cur)
+		}
+	}
+	tag, last := "", 0
+	for _, loc := range append(tripletTags.FindAllStringIndex(text, -1), []int{len(text), len(text)}) {
+		segment := strings.Join(strings.Fields(text[last:loc[0]]), " ")
+		switch tag {
+		case "<triplet>":
+			cur.Head = segment
+		case "<subj>":
+			cur.Tail = segment
+		case "<obj>":
+			cur.Type = segment
+		}
+		tag, last = text[loc[0]:loc[1]], loc[1]
+
+		switch tag {
+		case "<triplet>":
+			commit()
+			cur = Triplet{}
+		case "<subj>":
+			commit()
+			cur.Tail, cur.Type = "", ""
+		}
+	}
+	commit()
+	return triplets
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rebel
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTriplets(t *testing.T) {
+	text := "<s><triplet> Punta Cana <subj> La Altagracia Province <obj> located in the administrative territorial entity <subj> Dominican Republic <obj> country <triplet> Higuey <subj> La Altagracia Province <obj> located in the administrative territorial entity <subj> Dominican Republic <obj> country <triplet> La Altagracia Province <subj> province <obj> instance of <subj> Dominican Republic <obj> country <triplet> province <subj> Dominican Republic <obj> country <triplet> Dominican Republic <subj> La Altagracia Province <obj> contains administrative territorial entity</s>"
+	want := []Triplet{
+		{Head: "Punta Cana", Type: "located in the administrative territorial entity", Tail: "La Altagracia Province"},
+		{Head: "Punta Cana", Type: "country", Tail: "Dominican Republic"},
+		{Head: "Higuey", Type: "located in the administrative territorial entity", Tail: "La Altagracia Province"},
+		{Head: "Higuey", Type: "country", Tail: "Dominican Republic"},
+		{Head: "La Altagracia Province", Type: "instance of", Tail: "province"},
+		{Head: "La Altagracia Province", Type: "country", Tail: "Dominican Republic"},
+		{Head: "province", Type: "country", Tail: "Dominican Republic"},
+		{Head: "Dominican Republic", Type: "contains administrative territorial entity", Tail: "La Altagracia Province"},
+	}
+	triplets := ParseTriplets(text)
+	if !reflect.DeepEqual(triplets, want) {
+		t.Errorf("expected:\n%v\nactual:\n%v", want, triplets)
+	}
+}
+
+// TestParseTriplets_Incomplete checks that the triplets missing their head,
+// tail or type, e.g. when the generation is truncated, are dropped, and the
+// complete ones around them kept.
+func TestParseTriplets_Incomplete(t *testing.T) {
+	complete := Triplet{Head: "Punta Cana", Type: "country", Tail: "Dominican Republic"}
+	tests := []struct {
+		name string
+		text string
+		want []Triplet
+	}{
+		{"no triplets", "no triplets", nil},
+		{"empty", "<s></s><pad>", nil},
+		{"missing head", "<triplet> <subj> Dominican Republic <obj> country", nil},
+		{"missing tail", "<triplet> Punta Cana <subj> <obj> country", nil},
+		{"missing type", "<triplet> Punta Cana <subj> Dominican Republic <obj>", nil},
+		{"missing type tag", "<triplet> Punta Cana <subj> Dominican Republic", nil},
+		{"head only", "<triplet> Punta Cana", nil},
+		{
+			"truncated after a complete one",
+			"<triplet> Punta Cana <subj> Dominican Republic <obj> country <subj> La Altagracia",
+			[]Triplet{complete},
+		},
+		{
+			"incomplete before a complete one",
+			"<triplet> Higüey <subj> <obj> country <triplet> Punta Cana <subj> Dominican Republic <obj> country</s>",
+			[]Triplet{complete},
+		},
+		{
+			"incomplete relation of a head",
+			"<triplet> Punta Cana <subj> <obj> located in <subj> Dominican Republic <obj> country",
+			[]Triplet{complete},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseTriplets(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected:\n%v\nactual:\n%v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package relationextraction
+
+import "context"
+
+// DefaultModel is REBEL, a text generation model that performs end-to-end relation extraction
+// for more than 200 different relation types.
+// Model card: https://huggingface.co/Babelscape/rebel-large
+const DefaultModel = "Babelscape/rebel-large"
+
+// Interface defines the main functions for the relation extraction task.
+// Its implementations are safe for concurrent use.
+type Interface interface {
+	// Extract returns the typed relations between the entities of the given
+	// text.
+	Extract(ctx context.Context, text string, parameters Parameters) (Response, error)
+}
+
+// Parameters are the parameters of the relation extraction.
+type Parameters struct {
+	// Entities are the entities of the text already identified, e.g. by a
+	// token classification model, each one by its offsets, or else by its
+	// text (optional): only the relations between them are returned, with
+	// their labels.
+	Entities []Entity
+}
+
+// Entity is an entity of the text. Start and End are its offsets in the
+// text in runes, ByteStart and ByteEnd in bytes, all -1 if its text isn't
+// found verbatim in the text, e.g. an entity normalized by the model.
+type Entity struct {
+	Text      string
+	Label     string
+	Start     int
+	End       int
+	ByteStart int
+	ByteEnd   int
+}
+
+// Relation is a relation of the given type from the Head entity to the
+// Tail one, e.g. "country" from "Rome" to "Italy", with its probability.
+type Relation struct {
+	Head  Entity
+	Tail  Entity
+	Type  string
+	Score float64
+}
+
+// Response contains the response from relation extraction.
+type Response struct {
+	Relations []Relation
+}
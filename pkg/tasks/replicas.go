@@ -0,0 +1,227 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/nlpodyssey/cybertron/pkg/numa"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/coreference"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/languagemodeling"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/postagging"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/relationextraction"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/scheduling"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier"
+)
+
+// replicas schedules the requests on a pool of replicas of a model, each
+// serving up to a number of requests at a time, so that the requests are
+// served in parallel by as many forward passes as replicas. The requests
+// waiting for a replica are served by priority (see scheduling.Scheduler).
+type replicas[T any] struct {
+	all []T
+	// nodes are the NUMA nodes of the replicas, if placed on nodes.
+	nodes []*numa.Node
+	// sched has a slot for each request a replica can serve concurrently,
+	// the index of the replica.
+	sched *scheduling.Scheduler[int]
+}
+
+func newReplicas[T any](models []T, nodes []*numa.Node, concurrency int, opts scheduling.Options) *replicas[T] {
+	slots := make([]int, 0, len(models)*concurrency)
+	for i := 0; i < concurrency; i++ {
+		for j := range models {
+			slots = append(slots, j)
+		}
+	}
+	return &replicas[T]{all: models, nodes: nodes, sched: scheduling.New(slots, opts)}
+}
+
+// Unwrap returns the first replica.
+func (r *replicas[T]) Unwrap() any {
+	return r.all[0]
+}
+
+// Close closes all the replicas, returning the first error, if any.
+func (r *replicas[T]) Close() error {
+	var err error
+	for _, m := range r.all {
+		if c, ok := any(m).(io.Closer); ok {
+			if cerr := c.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
+
+// withReplica calls the function with a free replica, waiting for one
+// until the context is done, bound to the NUMA node of the replica, if
+// any. The preemptible calls with batch priority are canceled when an
+// interactive request waits, if the preemption is enabled.
+func withReplica[T, R any](ctx context.Context, r *replicas[T], preemptible bool, f func(context.Context, T) (R, error)) (R, error) {
+	return scheduling.Do(ctx, r.sched, preemptible, func(ctx context.Context, i int) (R, error) {
+		if i < len(r.nodes) {
+			if unbind, err := numa.Bind(*r.nodes[i]); err == nil {
+				defer unbind()
+			}
+		}
+		return f(ctx, r.all[i])
+	})
+}
+
+// wrapReplicas returns the model of the task T serving the requests with
+// the replicas, up to concurrency requests each, scheduled with the options,
+// bound to their NUMA nodes, if any.
+func wrapReplicas[T any](models []T, nodes []*numa.Node, concurrency int, opts scheduling.Options) (T, error) {
+	var w any
+	switch ms := any(models).(type) {
+	case []text2text.Interface:
+		w = text2textReplicas{newReplicas(ms, nodes, concurrency, opts)}
+	case []zeroshotclassifier.Interface:
+		w = zeroShotReplicas{newReplicas(ms, nodes, concurrency, opts)}
+	case []questionanswering.Interface:
+		w = questionAnsweringReplicas{newReplicas(ms, nodes, concurrency, opts)}
+	case []textclassification.Interface:
+		w = textClassificationReplicas{newReplicas(ms, nodes, concurrency, opts)}
+	case []tokenclassification.Interface:
+		w = tokenClassificationReplicas{newReplicas(ms, nodes, concurrency, opts)}
+	case []textencoding.Interface:
+		w = textEncodingReplicas{newReplicas(ms, nodes, concurrency, opts)}
+	case []languagemodeling.Interface:
+		w = languageModelingReplicas{newReplicas(ms, nodes, concurrency, opts)}
+	case []coreference.Interface:
+		w = coreferenceReplicas{newReplicas(ms, nodes, concurrency, opts)}
+	case []relationextraction.Interface:
+		w = relationExtractionReplicas{newReplicas(ms, nodes, concurrency, opts)}
+	case []postagging.Interface:
+		w = posTaggingReplicas{newReplicas(ms, nodes, concurrency, opts)}
+	case []sentencesegmentation.Interface:
+		w = sentenceSegmentationReplicas{newReplicas(ms, nodes, concurrency, opts)}
+	}
+	obj, ok := w.(T)
+	if !ok {
+		return obj, fmt.Errorf("loader: replicas not supported for type %T", models[0])
+	}
+	return obj, nil
+}
+
+type text2textReplicas struct {
+	*replicas[text2text.Interface]
+}
+
+func (r text2textReplicas) Generate(ctx context.Context, text string, opts *text2text.Options) (text2text.Response, error) {
+	return withReplica(ctx, r.replicas, true, func(ctx context.Context, m text2text.Interface) (text2text.Response, error) {
+		return m.Generate(ctx, text, opts)
+	})
+}
+
+type zeroShotReplicas struct {
+	*replicas[zeroshotclassifier.Interface]
+}
+
+func (r zeroShotReplicas) Classify(ctx context.Context, text string, parameters zeroshotclassifier.Parameters) (zeroshotclassifier.Response, error) {
+	return withReplica(ctx, r.replicas, false, func(ctx context.Context, m zeroshotclassifier.Interface) (zeroshotclassifier.Response, error) {
+		return m.Classify(ctx, text, parameters)
+	})
+}
+
+type questionAnsweringReplicas struct {
+	*replicas[questionanswering.Interface]
+}
+
+func (r questionAnsweringReplicas) Answer(ctx context.Context, question string, passage string, opts *questionanswering.Options) (questionanswering.Response, error) {
+	return withReplica(ctx, r.replicas, false, func(ctx context.Context, m questionanswering.Interface) (questionanswering.Response, error) {
+		return m.Answer(ctx, question, passage, opts)
+	})
+}
+
+type textClassificationReplicas struct {
+	*replicas[textclassification.Interface]
+}
+
+func (r textClassificationReplicas) Classify(ctx context.Context, text string, parameters textclassification.Parameters) (textclassification.Response, error) {
+	return withReplica(ctx, r.replicas, false, func(ctx context.Context, m textclassification.Interface) (textclassification.Response, error) {
+		return m.Classify(ctx, text, parameters)
+	})
+}
+
+type tokenClassificationReplicas struct {
+	*replicas[tokenclassification.Interface]
+}
+
+func (r tokenClassificationReplicas) Classify(ctx context.Context, text string, parameters tokenclassification.Parameters) (tokenclassification.Response, error) {
+	return withReplica(ctx, r.replicas, false, func(ctx context.Context, m tokenclassification.Interface) (tokenclassification.Response, error) {
+		return m.Classify(ctx, text, parameters)
+	})
+}
+
+type textEncodingReplicas struct {
+	*replicas[textencoding.Interface]
+}
+
+func (r textEncodingReplicas) Encode(ctx context.Context, text string, poolingStrategy int) (textencoding.Response, error) {
+	return withReplica(ctx, r.replicas, false, func(ctx context.Context, m textencoding.Interface) (textencoding.Response, error) {
+		return m.Encode(ctx, text, poolingStrategy)
+	})
+}
+
+type languageModelingReplicas struct {
+	*replicas[languagemodeling.Interface]
+}
+
+func (r languageModelingReplicas) Predict(ctx context.Context, text string, parameters languagemodeling.Parameters) (languagemodeling.Response, error) {
+	return withReplica(ctx, r.replicas, false, func(ctx context.Context, m languagemodeling.Interface) (languagemodeling.Response, error) {
+		return m.Predict(ctx, text, parameters)
+	})
+}
+
+type coreferenceReplicas struct {
+	*replicas[coreference.Interface]
+}
+
+func (r coreferenceReplicas) Resolve(ctx context.Context, text string) (coreference.Response, error) {
+	return withReplica(ctx, r.replicas, false, func(ctx context.Context, m coreference.Interface) (coreference.Response, error) {
+		return m.Resolve(ctx, text)
+	})
+}
+
+type relationExtractionReplicas struct {
+	*replicas[relationextraction.Interface]
+}
+
+func (r relationExtractionReplicas) Extract(ctx context.Context, text string, parameters relationextraction.Parameters) (relationextraction.Response, error) {
+	return withReplica(ctx, r.replicas, false, func(ctx context.Context, m relationextraction.Interface) (relationextraction.Response, error) {
+		return m.Extract(ctx, text, parameters)
+	})
+}
+
+type sentenceSegmentationReplicas struct {
+	*replicas[sentencesegmentation.Interface]
+}
+
+func (r sentenceSegmentationReplicas) Segment(ctx context.Context, text string) (sentencesegmentation.Response, error) {
+	return withReplica(ctx, r.replicas, false, func(ctx context.Context, m sentencesegmentation.Interface) (sentencesegmentation.Response, error) {
+		return m.Segment(ctx, text)
+	})
+}
+
+type posTaggingReplicas struct {
+	*replicas[postagging.Interface]
+}
+
+func (r posTaggingReplicas) Tag(ctx context.Context, text string) (postagging.Response, error) {
+	return withReplica(ctx, r.replicas, false, func(ctx context.Context, m postagging.Interface) (postagging.Response, error) {
+		return m.Tag(ctx, text)
+	})
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/scheduling"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingClassifier records the requests it serves at a time.
+type countingClassifier struct {
+	id       int
+	active   *atomic.Int32
+	maxAt    *atomic.Int32
+	inFlight atomic.Int32
+	closeErr error
+	closed   bool
+}
+
+func (c *countingClassifier) Classify(context.Context, string, textclassification.Parameters) (textclassification.Response, error) {
+	n := c.active.Add(1)
+	defer c.active.Add(-1)
+	if c.inFlight.Add(1) > 1 {
+		panic("more than one request at a time served by the replica")
+	}
+	defer c.inFlight.Add(-1)
+	for {
+		m := c.maxAt.Load()
+		if n <= m || c.maxAt.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	return textclassification.Response{Labels: []string{"label"}, Scores: []float64{float64(c.id)}}, nil
+}
+
+func (c *countingClassifier) Close() error {
+	c.closed = true
+	return c.closeErr
+}
+
+func TestWrapReplicas(t *testing.T) {
+	errClose := errors.New("close failed")
+	var active, maxAt atomic.Int32
+	models := []textclassification.Interface{
+		&countingClassifier{id: 0, active: &active, maxAt: &maxAt},
+		&countingClassifier{id: 1, active: &active, maxAt: &maxAt, closeErr: errClose},
+		&countingClassifier{id: 2, active: &active, maxAt: &maxAt},
+	}
+	m, err := wrapReplicas(models, nil, 1, scheduling.Options{})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_, err := m.Classify(context.Background(), "text", textclassification.Parameters{})
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, maxAt.Load(), int32(len(models)), "at most one request at a time per replica")
+
+	assert.Same(t, models[0], m.(interface{ Unwrap() any }).Unwrap(), "the first replica is unwrapped")
+	assert.ErrorIs(t, m.(interface{ Close() error }).Close(), errClose)
+	for _, model := range models {
+		assert.True(t, model.(*countingClassifier).closed, "all the replicas are closed")
+	}
+}
+
+// blockingClassifier serves the requests once released.
+type blockingClassifier struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c blockingClassifier) Classify(context.Context, string, textclassification.Parameters) (textclassification.Response, error) {
+	c.started <- struct{}{}
+	<-c.release
+	return textclassification.Response{}, nil
+}
+
+func TestWrapReplicas_Canceled(t *testing.T) {
+	c := blockingClassifier{started: make(chan struct{}), release: make(chan struct{})}
+	m, err := wrapReplicas([]textclassification.Interface{c}, nil, 1, scheduling.Options{})
+	require.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		_, err := m.Classify(context.Background(), "busy", textclassification.Parameters{})
+		done <- err
+	}()
+	<-c.started
+
+	// The request waiting for the busy replica gives up with its context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = m.Classify(ctx, "text", textclassification.Parameters{})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	close(c.release)
+	assert.NoError(t, <-done)
+}
+
+func TestWrapReplicas_Unsupported(t *testing.T) {
+	type unsupported interface{ Foo() }
+	_, err := wrapReplicas([]unsupported{nil}, nil, 1, scheduling.Options{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Resolver(t *testing.T) {
+	// The resolved directory is outside of the models directory.
+	resolvedDir := filepath.Join(t.TempDir(), "registry", "encoder")
+	writeSynthetic(t, resolvedDir, "BertModel", nil)
+	errResolver := errors.New("registry unavailable")
+
+	tests := []struct {
+		name    string
+		resolve func(ctx context.Context, modelName string) (string, error)
+		wantErr bool
+		errIs   error
+	}{
+		{
+			name: "resolved",
+			resolve: func(_ context.Context, modelName string) (string, error) {
+				if modelName != "org/encoder" {
+					return "", errors.New("unexpected model name")
+				}
+				return resolvedDir, nil
+			},
+		},
+		{
+			name: "resolver error",
+			resolve: func(context.Context, string) (string, error) {
+				return "", errResolver
+			},
+			wantErr: true,
+			errIs:   errResolver,
+		},
+		{
+			name: "missing directory",
+			resolve: func(context.Context, string) (string, error) {
+				return filepath.Join(resolvedDir, "missing"), nil
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := tasks.Load[textencoding.Interface](&tasks.Config{
+				ModelsDir:      t.TempDir(),
+				ModelName:      "org/encoder",
+				DownloadPolicy: tasks.DownloadNever,
+				Resolver:       tasks.ModelResolverFunc(tt.resolve),
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+				if tt.errIs != nil {
+					assert.ErrorIs(t, err, tt.errIs)
+				}
+				return
+			}
+			require.NoError(t, err)
+			defer tasks.Finalize(m)
+			_, err = m.Encode(context.Background(), "the cat sat.", 1)
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,315 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/routing"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/coreference"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/languagemodeling"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/postagging"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/questionanswering"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/relationextraction"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
+)
+
+// maxShadowRequests is the maximum number of requests in flight to each
+// shadow version, beyond which the requests are not mirrored, so that a
+// slow shadow version doesn't pile them up.
+const maxShadowRequests = 16
+
+// Variant is a version of a model served by Route.
+type Variant[T any] struct {
+	Model T
+	// Version names the version in the metrics.
+	Version string
+	// Share is the share of the traffic served by the version, relative to
+	// the ones of the other versions.
+	Share float64
+	// Shadow is whether the version is sent a copy of all the requests,
+	// instead of serving a share of them: its results are discarded, and
+	// its usage isn't accounted to the requests.
+	Shadow bool
+}
+
+// routed serves each request with one of its versions, picked at random by
+// their shares of the traffic, and mirrors it to the shadow versions.
+type routed[T any] struct {
+	serving []T
+	split   routing.Split
+	stats   []*routing.Stats
+	shadows []shadow[T]
+}
+
+// shadow is a shadow version of a routed model.
+type shadow[T any] struct {
+	m     T
+	stats *routing.Stats
+	// inflight has a slot for each request in flight.
+	inflight chan struct{}
+}
+
+// Unwrap returns the first serving version.
+func (r *routed[T]) Unwrap() any {
+	return r.serving[0]
+}
+
+// Close closes all the versions, returning the first error, if any. It
+// waits for the requests mirrored to the shadow versions first, taking all
+// their slots, so that no request is mirrored anymore.
+func (r *routed[T]) Close() error {
+	models := append([]T(nil), r.serving...)
+	for _, s := range r.shadows {
+		for i := 0; i < cap(s.inflight); i++ {
+			s.inflight <- struct{}{}
+		}
+		models = append(models, s.m)
+	}
+	var err error
+	for _, m := range models {
+		if c, ok := any(m).(io.Closer); ok {
+			if cerr := c.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
+
+// route mirrors the request to the shadow versions, and serves it with the
+// version picked by the split, calling the function with it.
+func route[T, R any](ctx context.Context, r *routed[T], f func(context.Context, T) (R, error)) (R, error) {
+	for _, s := range r.shadows {
+		mirror(ctx, s, f)
+	}
+	i := r.split.Pick(rand.Float64())
+	start := time.Now()
+	resp, err := f(ctx, r.serving[i])
+	r.stats[i].Observe(time.Since(start), err)
+	return resp, err
+}
+
+// mirror calls the function with the shadow version in the background,
+// unless too many requests are in flight. The mirrored request isn't
+// canceled with the original one, but has the same deadline, if any.
+func mirror[T, R any](ctx context.Context, s shadow[T], f func(context.Context, T) (R, error)) {
+	select {
+	case s.inflight <- struct{}{}:
+	default:
+		s.stats.Drop()
+		return
+	}
+	sctx, cancel := context.WithCancel(usage.NewContext(detached{ctx}, &usage.Counter{}))
+	if deadline, ok := ctx.Deadline(); ok {
+		sctx, cancel = context.WithDeadline(sctx, deadline)
+	}
+	go func() {
+		defer func() { <-s.inflight }()
+		defer cancel()
+		start := time.Now()
+		_, err := f(sctx, s.m)
+		s.stats.Observe(time.Since(start), err)
+	}()
+}
+
+// detached is a context with the values of its parent, but not its
+// cancellation.
+type detached struct {
+	parent context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detached) Done() <-chan struct{}       { return nil }
+func (detached) Err() error                  { return nil }
+func (d detached) Value(key any) any         { return d.parent.Value(key) }
+
+// Route returns the model of the task T serving the requests with the
+// versions, picked at random by their shares of the traffic, and mirroring
+// them to the shadow versions. The requests of each version are accounted
+// in the metrics, as the ones of the model with the name, if not nil. The
+// routed model closes the versions.
+func Route[T any](name string, variants []Variant[T], metrics *routing.Metrics) (T, error) {
+	var obj T
+	r := &routed[T]{}
+	var shares []float64
+	for _, v := range variants {
+		stats := metrics.Register(name, v.Version, v.Shadow)
+		if v.Shadow {
+			r.shadows = append(r.shadows, shadow[T]{m: v.Model, stats: stats, inflight: make(chan struct{}, maxShadowRequests)})
+			continue
+		}
+		r.serving = append(r.serving, v.Model)
+		r.stats = append(r.stats, stats)
+		shares = append(shares, v.Share)
+	}
+	if len(r.serving) == 0 {
+		return obj, errors.New("routing: no serving versions")
+	}
+	var err error
+	if r.split, err = routing.NewSplit(shares); err != nil {
+		return obj, err
+	}
+
+	var w any
+	switch p := any(r).(type) {
+	case *routed[text2text.Interface]:
+		w = text2textRouted{p}
+	case *routed[zeroshotclassifier.Interface]:
+		w = zeroShotRouted{p}
+	case *routed[questionanswering.Interface]:
+		w = questionAnsweringRouted{p}
+	case *routed[textclassification.Interface]:
+		w = textClassificationRouted{p}
+	case *routed[tokenclassification.Interface]:
+		w = tokenClassificationRouted{p}
+	case *routed[textencoding.Interface]:
+		w = textEncodingRouted{p}
+	case *routed[languagemodeling.Interface]:
+		w = languageModelingRouted{p}
+	case *routed[coreference.Interface]:
+		w = coreferenceRouted{p}
+	case *routed[relationextraction.Interface]:
+		w = relationExtractionRouted{p}
+	case *routed[postagging.Interface]:
+		w = posTaggingRouted{p}
+	case *routed[sentencesegmentation.Interface]:
+		w = sentenceSegmentationRouted{p}
+	}
+	obj, ok := w.(T)
+	if !ok {
+		return obj, fmt.Errorf("routing: type %T not supported", r.serving[0])
+	}
+	return obj, nil
+}
+
+type text2textRouted struct {
+	*routed[text2text.Interface]
+}
+
+func (r text2textRouted) Generate(ctx context.Context, text string, opts *text2text.Options) (text2text.Response, error) {
+	return route(ctx, r.routed, func(ctx context.Context, m text2text.Interface) (text2text.Response, error) {
+		if opts != nil {
+			o := *opts // the versions may set the defaults of the options concurrently
+			return m.Generate(ctx, text, &o)
+		}
+		return m.Generate(ctx, text, nil)
+	})
+}
+
+type zeroShotRouted struct {
+	*routed[zeroshotclassifier.Interface]
+}
+
+func (r zeroShotRouted) Classify(ctx context.Context, text string, parameters zeroshotclassifier.Parameters) (zeroshotclassifier.Response, error) {
+	return route(ctx, r.routed, func(ctx context.Context, m zeroshotclassifier.Interface) (zeroshotclassifier.Response, error) {
+		return m.Classify(ctx, text, parameters)
+	})
+}
+
+type questionAnsweringRouted struct {
+	*routed[questionanswering.Interface]
+}
+
+func (r questionAnsweringRouted) Answer(ctx context.Context, question string, passage string, opts *questionanswering.Options) (questionanswering.Response, error) {
+	return route(ctx, r.routed, func(ctx context.Context, m questionanswering.Interface) (questionanswering.Response, error) {
+		if opts != nil {
+			o := *opts // the versions may set the defaults of the options concurrently
+			return m.Answer(ctx, question, passage, &o)
+		}
+		return m.Answer(ctx, question, passage, nil)
+	})
+}
+
+type textClassificationRouted struct {
+	*routed[textclassification.Interface]
+}
+
+func (r textClassificationRouted) Classify(ctx context.Context, text string, parameters textclassification.Parameters) (textclassification.Response, error) {
+	return route(ctx, r.routed, func(ctx context.Context, m textclassification.Interface) (textclassification.Response, error) {
+		return m.Classify(ctx, text, parameters)
+	})
+}
+
+type tokenClassificationRouted struct {
+	*routed[tokenclassification.Interface]
+}
+
+func (r tokenClassificationRouted) Classify(ctx context.Context, text string, parameters tokenclassification.Parameters) (tokenclassification.Response, error) {
+	return route(ctx, r.routed, func(ctx context.Context, m tokenclassification.Interface) (tokenclassification.Response, error) {
+		return m.Classify(ctx, text, parameters)
+	})
+}
+
+type textEncodingRouted struct {
+	*routed[textencoding.Interface]
+}
+
+func (r textEncodingRouted) Encode(ctx context.Context, text string, poolingStrategy int) (textencoding.Response, error) {
+	return route(ctx, r.routed, func(ctx context.Context, m textencoding.Interface) (textencoding.Response, error) {
+		return m.Encode(ctx, text, poolingStrategy)
+	})
+}
+
+type languageModelingRouted struct {
+	*routed[languagemodeling.Interface]
+}
+
+func (r languageModelingRouted) Predict(ctx context.Context, text string, parameters languagemodeling.Parameters) (languagemodeling.Response, error) {
+	return route(ctx, r.routed, func(ctx context.Context, m languagemodeling.Interface) (languagemodeling.Response, error) {
+		return m.Predict(ctx, text, parameters)
+	})
+}
+
+type coreferenceRouted struct {
+	*routed[coreference.Interface]
+}
+
+func (r coreferenceRouted) Resolve(ctx context.Context, text string) (coreference.Response, error) {
+	return route(ctx, r.routed, func(ctx context.Context, m coreference.Interface) (coreference.Response, error) {
+		return m.Resolve(ctx, text)
+	})
+}
+
+type relationExtractionRouted struct {
+	*routed[relationextraction.Interface]
+}
+
+func (r relationExtractionRouted) Extract(ctx context.Context, text string, parameters relationextraction.Parameters) (relationextraction.Response, error) {
+	return route(ctx, r.routed, func(ctx context.Context, m relationextraction.Interface) (relationextraction.Response, error) {
+		return m.Extract(ctx, text, parameters)
+	})
+}
+
+type sentenceSegmentationRouted struct {
+	*routed[sentencesegmentation.Interface]
+}
+
+func (r sentenceSegmentationRouted) Segment(ctx context.Context, text string) (sentencesegmentation.Response, error) {
+	return route(ctx, r.routed, func(ctx context.Context, m sentencesegmentation.Interface) (sentencesegmentation.Response, error) {
+		return m.Segment(ctx, text)
+	})
+}
+
+type posTaggingRouted struct {
+	*routed[postagging.Interface]
+}
+
+func (r posTaggingRouted) Tag(ctx context.Context, text string) (postagging.Response, error) {
+	return route(ctx, r.routed, func(ctx context.Context, m postagging.Interface) (postagging.Response, error) {
+		return m.Tag(ctx, text)
+	})
+}
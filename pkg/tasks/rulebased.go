@@ -0,0 +1,36 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"fmt"
+
+	rules_for_pos_tagging "github.com/nlpodyssey/cybertron/pkg/tasks/postagging/rules"
+	rules_for_sentence_segmentation "github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation/rules"
+)
+
+// RuleBasedModel is the name of the model of the rule-based implementations
+// of the tasks having one, the sentence segmentation and the part-of-speech
+// tagging, which are neither downloaded nor converted.
+const RuleBasedModel = "rule-based"
+
+// isRuleBased reports whether the rule-based implementation of the task is
+// configured in place of a model.
+func (l loader[T]) isRuleBased() bool {
+	return l.conf.ModelName == RuleBasedModel
+}
+
+// loadRuleBased returns the rule-based implementation of the task.
+func (l loader[T]) loadRuleBased() (obj T, _ error) {
+	_, t := l.reflectType()
+	switch {
+	case t.Implements(sentencesegmentationInterface):
+		return typeCheck[T](rules_for_sentence_segmentation.New(), nil)
+	case t.Implements(postaggingInterface):
+		return typeCheck[T](rules_for_pos_tagging.New(), nil)
+	default:
+		return obj, fmt.Errorf("loader: no rule-based implementation for type %T", obj)
+	}
+}
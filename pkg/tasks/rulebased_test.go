@@ -0,0 +1,39 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/postagging"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadRuleBased(t *testing.T) {
+	conf := &Config{ModelsDir: t.TempDir(), ModelName: RuleBasedModel}
+
+	s, err := Load[sentencesegmentation.Interface](conf)
+	require.NoError(t, err)
+	segmented, err := s.Segment(context.Background(), "It rained. The match was postponed.")
+	require.NoError(t, err)
+	assert.Len(t, segmented.Sentences, 2)
+
+	p, err := Load[postagging.Interface](conf)
+	require.NoError(t, err)
+	tagged, err := p.Tag(context.Background(), "It rained.")
+	require.NoError(t, err)
+	assert.Len(t, tagged.Tokens, 3)
+
+	_, err = Load[textclassification.Interface](conf)
+	assert.Error(t, err)
+
+	dir, err := Prepare(conf)
+	require.NoError(t, err)
+	assert.Empty(t, dir)
+}
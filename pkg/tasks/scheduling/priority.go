@@ -0,0 +1,71 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scheduling
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+)
+
+// Priority is the priority class of a request.
+type Priority int
+
+const (
+	// Interactive is the priority of the latency-sensitive requests, e.g. the
+	// queries of the users (default).
+	Interactive Priority = iota
+	// Batch is the priority of the bulk requests, e.g. the embeddings of a
+	// corpus, served when no interactive request is waiting, except for a
+	// share of the replicas that prevents them from starving.
+	Batch
+)
+
+// numPriorities is the number of priority classes.
+const numPriorities = 2
+
+// ErrPreempted means that the request was preempted by a request with a
+// higher priority, and can be retried.
+var ErrPreempted = errdefs.ErrPreempted
+
+// ParsePriority parses a priority ("interactive"|"batch").
+func ParsePriority(s string) (Priority, error) {
+	switch s {
+	case "interactive":
+		return Interactive, nil
+	case "batch":
+		return Batch, nil
+	default:
+		return 0, fmt.Errorf("invalid priority %#v", s)
+	}
+}
+
+// String returns the name of the priority.
+func (p Priority) String() string {
+	switch p {
+	case Interactive:
+		return "interactive"
+	case Batch:
+		return "batch"
+	default:
+		return fmt.Sprintf("Priority(%d)", int(p))
+	}
+}
+
+type priorityKey struct{}
+
+// NewContext returns a copy of the context carrying the priority of the
+// request.
+func NewContext(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// FromContext returns the priority of the request carried by the context,
+// Interactive if unset.
+func FromContext(ctx context.Context) Priority {
+	p, _ := ctx.Value(priorityKey{}).(Priority)
+	return p
+}
@@ -0,0 +1,203 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scheduling
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/timings"
+)
+
+// DefaultWeight is the default number of interactive requests served for
+// each batch request while both are waiting.
+const DefaultWeight = 4
+
+// Options are the options of a Scheduler.
+type Options struct {
+	// Weight is the number of interactive requests served for each batch
+	// request while both are waiting (default DefaultWeight).
+	Weight int
+	// Preemption enables the preemption of the preemptible batch requests,
+	// e.g. long generations, when an interactive request waits for a slot.
+	Preemption bool
+}
+
+// Scheduler hands out a set of slots, e.g. the replicas of a model, each to
+// a request at a time. The requests waiting for a slot are queued by
+// priority, and served by weighted round-robin between the queues.
+type Scheduler[T any] struct {
+	weight     int
+	preemption bool
+
+	mu     sync.Mutex
+	free   []T
+	queues [numPriorities][]*waiter[T]
+	// served is the number of interactive requests served since the last
+	// batch one, while batch requests were waiting.
+	served int
+	// preemptible are the running preemptible batch requests, from the
+	// oldest.
+	preemptible []*lease
+}
+
+// waiter is a request waiting for a slot.
+type waiter[T any] struct {
+	// slot receives the slot handed over to the request.
+	slot chan T
+}
+
+// lease is a running preemptible request.
+type lease struct {
+	cancel context.CancelCauseFunc
+}
+
+// New returns a Scheduler handing out the slots.
+func New[T any](slots []T, opts Options) *Scheduler[T] {
+	if opts.Weight <= 0 {
+		opts.Weight = DefaultWeight
+	}
+	return &Scheduler[T]{
+		weight:     opts.Weight,
+		preemption: opts.Preemption,
+		free:       append([]T{}, slots...),
+	}
+}
+
+// Do calls the function with a free slot, waiting for one, with the
+// priority of the context, until the context is done. If the call is
+// preemptible, a batch request is canceled when an interactive request
+// waits for a slot, and fails with ErrPreempted.
+func Do[T, R any](ctx context.Context, s *Scheduler[T], preemptible bool, f func(context.Context, T) (R, error)) (R, error) {
+	start := time.Now()
+	slot, err := s.acquire(ctx)
+	timings.Add(ctx, timings.Queue, time.Since(start))
+	if err != nil {
+		var zero R
+		return zero, err
+	}
+	defer s.release(slot)
+	if !preemptible || !s.preemption || priorityOf(ctx) != Batch {
+		return f(ctx, slot)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	l := &lease{cancel: cancel}
+	s.mu.Lock()
+	s.preemptible = append(s.preemptible, l)
+	s.mu.Unlock()
+	defer s.unregister(l)
+
+	r, err := f(ctx, slot)
+	if err != nil && context.Cause(ctx) == ErrPreempted {
+		var zero R
+		return zero, ErrPreempted
+	}
+	return r, err
+}
+
+// priorityOf returns the priority of the context, the unknown ones being
+// served as batch requests.
+func priorityOf(ctx context.Context) Priority {
+	p := FromContext(ctx)
+	if p < Interactive || p >= numPriorities {
+		return Batch
+	}
+	return p
+}
+
+// acquire returns a free slot, waiting for one until the context is done.
+func (s *Scheduler[T]) acquire(ctx context.Context) (T, error) {
+	p := priorityOf(ctx)
+	s.mu.Lock()
+	if n := len(s.free); n > 0 {
+		slot := s.free[n-1]
+		s.free = s.free[:n-1]
+		s.mu.Unlock()
+		return slot, nil
+	}
+	w := &waiter[T]{slot: make(chan T, 1)}
+	s.queues[p] = append(s.queues[p], w)
+	if p == Interactive && s.preemption && len(s.preemptible) > 0 {
+		s.preemptible[0].cancel(ErrPreempted)
+		s.preemptible = s.preemptible[1:]
+	}
+	s.mu.Unlock()
+
+	select {
+	case slot := <-w.slot:
+		return slot, nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		waiting := s.dequeue(p, w)
+		s.mu.Unlock()
+		if !waiting {
+			// the slot was handed over in the meantime
+			s.release(<-w.slot)
+		}
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// release hands the slot over to the next waiting request, if any, or
+// returns it to the free ones.
+func (s *Scheduler[T]) release(slot T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if w := s.next(); w != nil {
+		w.slot <- slot
+		return
+	}
+	s.free = append(s.free, slot)
+}
+
+// next removes and returns the next request to serve, if any.
+func (s *Scheduler[T]) next() *waiter[T] {
+	interactive, batch := len(s.queues[Interactive]) > 0, len(s.queues[Batch]) > 0
+	var p Priority
+	switch {
+	case interactive && batch && s.served < s.weight:
+		p = Interactive
+		s.served++
+	case interactive && !batch:
+		p = Interactive
+	case batch:
+		p = Batch
+		s.served = 0
+	default:
+		return nil
+	}
+	w := s.queues[p][0]
+	s.queues[p][0] = nil
+	s.queues[p] = s.queues[p][1:]
+	return w
+}
+
+// dequeue removes the waiting request from the queue, reporting whether it
+// was still waiting.
+func (s *Scheduler[T]) dequeue(p Priority, w *waiter[T]) bool {
+	for i, x := range s.queues[p] {
+		if x == w {
+			s.queues[p] = append(s.queues[p][:i], s.queues[p][i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// unregister removes the preemptible request, if not preempted yet.
+func (s *Scheduler[T]) unregister(l *lease) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, x := range s.preemptible {
+		if x == l {
+			s.preemptible = append(s.preemptible[:i], s.preemptible[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scheduling
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitQueued waits until n requests of the priority are queued.
+func waitQueued[T any](t *testing.T, s *Scheduler[T], p Priority, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.queues[p]) == n
+	}, time.Second, time.Millisecond)
+}
+
+func TestSchedulerWeightedPriorities(t *testing.T) {
+	s := New([]int{0}, Options{Weight: 2})
+	hold := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		_, _ = Do(context.Background(), s, false, func(context.Context, int) (any, error) {
+			<-hold
+			return nil, nil
+		})
+		close(done)
+	}()
+	waitQueuedFree(t, s)
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	request := func(name string, p Priority, queued int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := Do(NewContext(context.Background(), p), s, false, func(context.Context, int) (any, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				order = append(order, name)
+				return nil, nil
+			})
+			assert.NoError(t, err)
+		}()
+		waitQueued(t, s, p, queued)
+	}
+	request("b1", Batch, 1)
+	request("b2", Batch, 2)
+	request("i1", Interactive, 1)
+	request("i2", Interactive, 2)
+	request("i3", Interactive, 3)
+
+	close(hold)
+	<-done
+	wg.Wait()
+	assert.Equal(t, []string{"i1", "i2", "b1", "i3", "b2"}, order)
+}
+
+// waitQueuedFree waits until all the slots are taken.
+func waitQueuedFree[T any](t *testing.T, s *Scheduler[T]) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.free) == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestSchedulerCanceledWhileWaiting(t *testing.T) {
+	s := New([]string{"replica"}, Options{})
+	hold := make(chan struct{})
+	go func() {
+		_, _ = Do(context.Background(), s, false, func(context.Context, string) (any, error) {
+			<-hold
+			return nil, nil
+		})
+	}()
+	waitQueuedFree(t, s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		_, err := Do(ctx, s, false, func(context.Context, string) (any, error) {
+			return nil, nil
+		})
+		errc <- err
+	}()
+	waitQueued(t, s, Interactive, 1)
+	cancel()
+	assert.ErrorIs(t, <-errc, context.Canceled)
+	waitQueued(t, s, Interactive, 0)
+
+	close(hold)
+	got, err := Do(context.Background(), s, false, func(_ context.Context, slot string) (string, error) {
+		return slot, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "replica", got)
+}
+
+func TestSchedulerPreemption(t *testing.T) {
+	s := New([]int{0}, Options{Preemption: true})
+	errc := make(chan error)
+	go func() {
+		_, err := Do(NewContext(context.Background(), Batch), s, true, func(ctx context.Context, _ int) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		errc <- err
+	}()
+	waitQueuedFree(t, s)
+
+	got, err := Do(context.Background(), s, false, func(context.Context, int) (string, error) {
+		return "interactive", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "interactive", got)
+	assert.ErrorIs(t, <-errc, ErrPreempted)
+}
+
+func TestParsePriority(t *testing.T) {
+	for _, p := range []Priority{Interactive, Batch} {
+		got, err := ParsePriority(p.String())
+		require.NoError(t, err)
+		assert.Equal(t, p, got)
+	}
+	_, err := ParsePriority("urgent")
+	assert.Error(t, err)
+	assert.Equal(t, Interactive, FromContext(context.Background()))
+	assert.Equal(t, Batch, FromContext(NewContext(context.Background(), Batch)))
+}
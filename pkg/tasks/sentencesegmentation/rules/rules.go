@@ -0,0 +1,146 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package rules implements a rule-based sentence segmentation, needing no
+// model, for the languages ending their sentences with punctuation marks.
+package rules
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation"
+	"github.com/nlpodyssey/cybertron/pkg/tokenizers"
+)
+
+var _ sentencesegmentation.Interface = &Segmenter{}
+
+// DefaultAbbreviations are the common English abbreviations followed by a
+// period which doesn't end the sentence.
+var DefaultAbbreviations = []string{
+	"mr", "mrs", "ms", "dr", "prof", "sr", "jr", "st", "mt", "rev", "gen", "gov", "lt", "col", "sgt", "capt",
+	"vs", "inc", "ltd", "co", "corp", "dept", "est", "approx", "no", "nos", "fig", "vol", "pp",
+	"jan", "feb", "mar", "apr", "jun", "jul", "aug", "sep", "sept", "oct", "nov", "dec",
+}
+
+// Segmenter is a rule-based sentence segmenter. A sentence ends at a run of
+// terminal punctuation marks, with the closing quotes and brackets
+// following them, followed by a space and by a word not starting in
+// lowercase, e.g. "It rained. The match", unless the period follows an
+// abbreviation or an initial, e.g. "Dr. Smith" or "J. Smith". The blank
+// lines end the sentences too, e.g. the ones of the titles.
+type Segmenter struct {
+	// Abbreviations are the abbreviations, lowercase and without the
+	// period, followed by a period which doesn't end the sentence.
+	Abbreviations map[string]bool
+}
+
+// New returns a new Segmenter with the DefaultAbbreviations.
+func New() *Segmenter {
+	abbreviations := make(map[string]bool, len(DefaultAbbreviations))
+	for _, a := range DefaultAbbreviations {
+		abbreviations[a] = true
+	}
+	return &Segmenter{Abbreviations: abbreviations}
+}
+
+// Segment returns the sentences of the given text.
+func (s *Segmenter) Segment(_ context.Context, text string) (sentencesegmentation.Response, error) {
+	return sentencesegmentation.Response{Sentences: s.Split(text)}, nil
+}
+
+// Split returns the sentences of the given text.
+func (s *Segmenter) Split(text string) []sentencesegmentation.Sentence {
+	tokens := Tokenize(text)
+	offsets := tokenizers.NewRuneOffsets(text)
+	var sentences []sentencesegmentation.Sentence
+	emit := func(first, last int) {
+		o := tokenizers.OffsetsType{Start: tokens[first].Offsets.Start, End: tokens[last].Offsets.End}
+		b := offsets.Bytes(o)
+		sentences = append(sentences, sentencesegmentation.Sentence{
+			Text:      text[b.Start:b.End],
+			Start:     o.Start,
+			End:       o.End,
+			ByteStart: b.Start,
+			ByteEnd:   b.End,
+		})
+	}
+
+	start := 0
+	for i := 0; i < len(tokens); i++ {
+		if i > start && strings.Count(gap(text, offsets, tokens[i-1], tokens[i]), "\n") >= 2 {
+			emit(start, i-1)
+			start = i
+		}
+		if !isTerminal(tokens[i].String) {
+			continue
+		}
+		j := i
+		for j+1 < len(tokens) && gap(text, offsets, tokens[j], tokens[j+1]) == "" &&
+			(isTerminal(tokens[j+1].String) || isClosing(tokens[j+1].String)) {
+			j++
+		}
+		if j+1 == len(tokens) || s.endsAt(text, offsets, tokens, i, j) {
+			emit(start, j)
+			start = j + 1
+		}
+		i = j
+	}
+	if start < len(tokens) {
+		emit(start, len(tokens)-1)
+	}
+	return sentences
+}
+
+// endsAt reports whether the sentence ends at the run of terminal marks,
+// and closing ones, from the i-th to the j-th token, followed by another.
+func (s *Segmenter) endsAt(text string, offsets tokenizers.RuneOffsets, tokens []tokenizers.StringOffsetsPair, i, j int) bool {
+	next := tokens[j+1]
+	if gap(text, offsets, tokens[j], next) == "" && !isWide(tokens[j].String) {
+		return false
+	}
+	if tokens[i].String == "." && (i == j || !isTerminal(tokens[i+1].String)) && i > 0 &&
+		gap(text, offsets, tokens[i-1], tokens[i]) == "" {
+		prev := tokens[i-1].String
+		if s.Abbreviations[strings.ToLower(prev)] {
+			return false
+		}
+		if r, size := utf8.DecodeRuneInString(prev); size == len(prev) && unicode.IsUpper(r) {
+			return false
+		}
+	}
+	r, _ := utf8.DecodeRuneInString(next.String)
+	return !unicode.IsLower(r)
+}
+
+// gap returns the text between two tokens.
+func gap(text string, offsets tokenizers.RuneOffsets, a, b tokenizers.StringOffsetsPair) string {
+	return text[offsets.Bytes(a.Offsets).End:offsets.Bytes(b.Offsets).Start]
+}
+
+func isTerminal(s string) bool {
+	switch s {
+	case ".", "!", "?", "…", "‼", "⁇", "⁈", "⁉", "。", "！", "？":
+		return true
+	default:
+		return false
+	}
+}
+
+// isWide reports whether the terminal mark is one of the full-width ones,
+// not followed by a space.
+func isWide(s string) bool {
+	return s == "。" || s == "！" || s == "？"
+}
+
+func isClosing(s string) bool {
+	switch s {
+	case ")", "]", "}", "\"", "'", "”", "’", "»", "」", "』":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rules
+
+import (
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation"
+	"github.com/nlpodyssey/cybertron/pkg/tokenizers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSegmenter_Split(t *testing.T) {
+	s := New()
+	texts := func(sentences []sentencesegmentation.Sentence) []string {
+		out := make([]string, len(sentences))
+		for i, s := range sentences {
+			out[i] = s.Text
+		}
+		return out
+	}
+
+	t.Run("terminal marks", func(t *testing.T) {
+		got := s.Split("It rained all day. Did the match start? No!  It was postponed...")
+		assert.Equal(t, []string{"It rained all day.", "Did the match start?", "No!", "It was postponed..."}, texts(got))
+	})
+
+	t.Run("abbreviations, initials and numbers", func(t *testing.T) {
+		got := s.Split("Dr. Smith met J. R. Tolkien at 3.30 p.m. on Monday, e.g. after lunch. It cost $2.50 in the U.S. Nobody came.")
+		assert.Equal(t, []string{
+			"Dr. Smith met J. R. Tolkien at 3.30 p.m. on Monday, e.g. after lunch.",
+			"It cost $2.50 in the U.S.",
+			"Nobody came.",
+		}, texts(got))
+	})
+
+	t.Run("closing quotes and brackets", func(t *testing.T) {
+		got := s.Split(`He said "I'm late." (She didn't answer.) Then he left`)
+		assert.Equal(t, []string{`He said "I'm late."`, "(She didn't answer.)", "Then he left"}, texts(got))
+	})
+
+	t.Run("blank lines", func(t *testing.T) {
+		got := s.Split("Introduction\n\nThe results are\nshown below.")
+		assert.Equal(t, []string{"Introduction", "The results are\nshown below."}, texts(got))
+	})
+
+	t.Run("offsets", func(t *testing.T) {
+		got := s.Split("Né à Paris. Où?")
+		assert.Equal(t, []sentencesegmentation.Sentence{
+			{Text: "Né à Paris.", Start: 0, End: 11, ByteStart: 0, ByteEnd: 13},
+			{Text: "Où?", Start: 12, End: 15, ByteStart: 14, ByteEnd: 18},
+		}, got)
+	})
+
+	t.Run("empty text", func(t *testing.T) {
+		assert.Empty(t, s.Split(" \n "))
+	})
+}
+
+func TestTokenize(t *testing.T) {
+	got := tokenizers.GetStrings(Tokenize("I don't know O'Brien's well-known 1,000.5 items, e.g. these."))
+	want := []string{"I", "do", "n't", "know", "O'Brien", "'s", "well-known", "1,000.5", "items", ",", "e.g", ".", "these", "."}
+	assert.Equal(t, want, got)
+}
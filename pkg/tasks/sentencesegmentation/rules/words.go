@@ -0,0 +1,56 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rules
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/tokenizers"
+)
+
+// wordPattern matches the words, with their inner hyphens, apostrophes and
+// periods, e.g. "well-known", "O'Brien" or "e.g", the numbers, with their
+// separators, e.g. "3.14" or "1,000", and any other character but the
+// spaces on its own, e.g. the punctuation marks.
+var wordPattern = regexp.MustCompile(`[\p{L}\p{M}]+(?:[-.'’][\p{L}\p{M}]+)*|\p{N}+(?:[.,:/]\p{N}+)*|\S`)
+
+// contractions are the suffixes split from their words.
+var contractions = []string{"n't", "n’t", "'s", "’s", "'re", "’re", "'ll", "’ll", "'ve", "’ve", "'d", "’d", "'m", "’m"}
+
+// Tokenize returns the words of the text, as the rule-based tasks see them:
+// the words, the numbers and the punctuation marks, with the contractions
+// split from their words, e.g. "do" and "n't" of "don't", or "John" and
+// "'s" of "John's". Their offsets are counted in runes.
+func Tokenize(text string) []tokenizers.StringOffsetsPair {
+	offsets := tokenizers.NewRuneOffsets(text)
+	var tokens []tokenizers.StringOffsetsPair
+	add := func(start, end int) {
+		tokens = append(tokens, tokenizers.StringOffsetsPair{
+			String:  text[start:end],
+			Offsets: offsets.Runes(tokenizers.OffsetsType{Start: start, End: end}),
+		})
+	}
+	for _, loc := range wordPattern.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		if split := contraction(text[start:end]); split > 0 {
+			add(start, start+split)
+			start += split
+		}
+		add(start, end)
+	}
+	return tokens
+}
+
+// contraction returns the index of the contraction ending the word, or 0 if
+// none does.
+func contraction(word string) int {
+	for _, c := range contractions {
+		if i := len(word) - len(c); i > 0 && strings.EqualFold(word[i:], c) {
+			return i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,30 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sentencesegmentation
+
+import "context"
+
+// Interface defines the main functions for the sentence segmentation task.
+// Its implementations are safe for concurrent use.
+type Interface interface {
+	// Segment returns the sentences of the given text.
+	Segment(ctx context.Context, text string) (Response, error)
+}
+
+// Sentence is a sentence of the text, without the spaces around it. Start
+// and End are its offsets in the text in runes, ByteStart and ByteEnd in
+// bytes.
+type Sentence struct {
+	Text      string
+	Start     int
+	End       int
+	ByteStart int
+	ByteEnd   int
+}
+
+// Response contains the response from sentence segmentation.
+type Response struct {
+	Sentences []Sentence
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tagger implements the sentence segmentation with the token
+// classification models labeling the words ending the sentences, e.g. the
+// punctuation restoration ones, which segment also the texts without
+// punctuation, such as the transcripts of speech.
+package tagger
+
+import (
+	"context"
+	"io"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sentencesegmentation"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+)
+
+var _ sentencesegmentation.Interface = &Segmenter{}
+
+// DefaultEndLabels are the labels of the words ending the sentences: the
+// terminal punctuation marks following them, for the punctuation
+// restoration models, or the end of sentence label.
+var DefaultEndLabels = []string{".", "!", "?", "EOS"}
+
+// Segmenter is a sentence segmenter ending the sentences at the words
+// labeled with one of the EndLabels by a token classification model.
+type Segmenter struct {
+	// Model is the token classification model labeling the words.
+	Model tokenclassification.Interface
+	// EndLabels are the labels of the words ending the sentences, without
+	// their IOB prefix, if any.
+	EndLabels map[string]bool
+}
+
+// New returns a new Segmenter labeling the words with the model, with the
+// DefaultEndLabels.
+func New(m tokenclassification.Interface) *Segmenter {
+	labels := make(map[string]bool, len(DefaultEndLabels))
+	for _, l := range DefaultEndLabels {
+		labels[l] = true
+	}
+	return &Segmenter{Model: m, EndLabels: labels}
+}
+
+// Close finalizes the model of the Segmenter, if it's an io.Closer.
+// It satisfies the interface io.Closer.
+func (s *Segmenter) Close() error {
+	if c, ok := s.Model.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Segment returns the sentences of the given text, ending at the words
+// labeled with one of the EndLabels, and at the last word.
+func (s *Segmenter) Segment(ctx context.Context, text string) (sentencesegmentation.Response, error) {
+	resp, err := s.Model.Classify(ctx, text, tokenclassification.Parameters{
+		AggregationStrategy: tokenclassification.AggregationStrategyNone,
+	})
+	if err != nil {
+		return sentencesegmentation.Response{}, err
+	}
+
+	var sentences []sentencesegmentation.Sentence
+	first := 0
+	for i, t := range resp.Tokens {
+		if i < len(resp.Tokens)-1 && !s.EndLabels[tokenclassification.StripPrefix(t.Label)] {
+			continue
+		}
+		start, end := resp.Tokens[first], t
+		sentences = append(sentences, sentencesegmentation.Sentence{
+			Text:      text[start.ByteStart:end.ByteEnd],
+			Start:     start.Start,
+			End:       end.End,
+			ByteStart: start.ByteStart,
+			ByteEnd:   end.ByteEnd,
+		})
+		first = i + 1
+	}
+	return sentencesegmentation.Response{Sentences: sentences}, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sharedweights shares the weights loaded more than once in a
+// process, e.g. by several tasks, or several models, configured with the
+// same checkpoint, so that they're held in memory once: the first loaded
+// are kept, with the resources they depend on, until the last holder
+// releases them.
+package sharedweights
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/nlpodyssey/cybertron/pkg/numa"
+)
+
+// entry is the value shared by the holders of a key, once ready.
+type entry struct {
+	ready  chan struct{}
+	value  any
+	closer io.Closer
+	err    error
+	refs   int
+}
+
+var (
+	mu      sync.Mutex
+	entries = map[string]*entry{}
+)
+
+// Key returns the key of the weights of the file: its absolute path, with
+// the symbolic links resolved, and its size and modification time, so that
+// the weights of a file which was replaced aren't shared with the ones of
+// its previous version.
+func Key(filename string) (string, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	fi, err := os.Stat(abs)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%d:%d", abs, fi.Size(), fi.ModTime().UnixNano()), nil
+}
+
+// Acquire returns the value of the key shared with its other holders, or
+// the one returned by load, with the resource it depends on, e.g. its
+// embeddings repository, if it's the first holder. The values are shared by
+// key and by type, e.g. the same file holding the encoder of different
+// models. The holders bound to a NUMA node share the values loaded on that
+// node only (see numa.Bind), so that each node holds its own copy. The
+// closer, if not nil, is closed once the last holder calls its release
+// function, which must be called once. If load fails, the holders waiting
+// for it get its error, and the next one loads the value again.
+func Acquire[T any](key string, load func() (T, io.Closer, error)) (T, func() error, error) {
+	var zero T
+	key = fmt.Sprintf("%T:%s", zero, key)
+	if n, ok := numa.Bound(); ok {
+		key = fmt.Sprintf("%s@node%d", key, n.ID)
+	}
+
+	// The value is loaded without holding the lock, so that the loads of
+	// the other keys, and the releases, aren't blocked by it: the holders
+	// of the same key wait for it instead.
+	mu.Lock()
+	e, ok := entries[key]
+	if !ok {
+		e = &entry{ready: make(chan struct{})}
+		entries[key] = e
+	}
+	e.refs++
+	mu.Unlock()
+
+	if !ok {
+		e.value, e.closer, e.err = load()
+		if e.err != nil {
+			// The next holder loads it again.
+			mu.Lock()
+			delete(entries, key)
+			mu.Unlock()
+		}
+		close(e.ready)
+	}
+	<-e.ready
+	if e.err != nil {
+		return zero, nil, e.err
+	}
+	var once sync.Once
+	release := func() (err error) {
+		once.Do(func() { err = releaseEntry(key, e) })
+		return err
+	}
+	return e.value.(T), release, nil
+}
+
+// releaseEntry releases a reference to the entry of the key, closing it
+// once it was the last.
+func releaseEntry(key string, e *entry) error {
+	mu.Lock()
+	e.refs--
+	last := e.refs == 0
+	if last {
+		delete(entries, key)
+	}
+	mu.Unlock()
+	if !last || e.closer == nil {
+		return nil
+	}
+	return e.closer.Close()
+}
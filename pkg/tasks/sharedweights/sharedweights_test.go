@@ -0,0 +1,154 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sharedweights
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/numa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type closer struct{ closed int }
+
+func (c *closer) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestAcquire(t *testing.T) {
+	c := &closer{}
+	loads := 0
+	load := func() (*int, io.Closer, error) {
+		loads++
+		v := loads
+		return &v, c, nil
+	}
+
+	v1, release1, err := Acquire("key", load)
+	require.NoError(t, err)
+	v2, release2, err := Acquire("key", load)
+	require.NoError(t, err)
+	assert.Same(t, v1, v2)
+	assert.Equal(t, 1, loads)
+
+	// The values of different types aren't shared.
+	s, releaseString, err := Acquire("key", func() (string, io.Closer, error) { return "other", nil, nil })
+	require.NoError(t, err)
+	assert.Equal(t, "other", s)
+	require.NoError(t, releaseString())
+
+	require.NoError(t, release1())
+	require.NoError(t, release1())
+	assert.Equal(t, 0, c.closed)
+	require.NoError(t, release2())
+	assert.Equal(t, 1, c.closed)
+
+	// Once released, the value is loaded again.
+	v3, release3, err := Acquire("key", load)
+	require.NoError(t, err)
+	assert.Equal(t, 2, *v3)
+	require.NoError(t, release3())
+	assert.Equal(t, 2, c.closed)
+}
+
+func TestAcquireError(t *testing.T) {
+	failure := errors.New("failure")
+	_, _, err := Acquire("failing", func() (int, io.Closer, error) { return 0, nil, failure })
+	assert.ErrorIs(t, err, failure)
+
+	v, release, err := Acquire("failing", func() (int, io.Closer, error) { return 42, nil, nil })
+	require.NoError(t, err)
+	assert.Equal(t, 42, v)
+	require.NoError(t, release())
+}
+
+func TestAcquireNUMA(t *testing.T) {
+	nodes, err := numa.Nodes()
+	if err == numa.ErrUnsupported {
+		t.Skip(err)
+	}
+	require.NoError(t, err)
+	load := func() (*int, io.Closer, error) { return new(int), nil, nil }
+	v1, release1, err := Acquire("numa", load)
+	require.NoError(t, err)
+	defer release1()
+
+	// The goroutines bound to a node don't share the values of the others.
+	unbind, err := numa.Bind(nodes[0])
+	require.NoError(t, err)
+	v2, release2, err := Acquire("numa", load)
+	unbind()
+	require.NoError(t, err)
+	defer release2()
+	assert.NotSame(t, v1, v2)
+}
+
+func TestKey(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "spago_model.bin")
+	require.NoError(t, os.WriteFile(filename, []byte("weights"), 0o644))
+
+	k1, err := Key(filename)
+	require.NoError(t, err)
+	link := filepath.Join(dir, "link.bin")
+	require.NoError(t, os.Symlink(filename, link))
+	k2, err := Key(link)
+	require.NoError(t, err)
+	assert.Equal(t, k1, k2)
+
+	// A replaced file has a different key.
+	require.NoError(t, os.WriteFile(filename, []byte("new weights"), 0o644))
+	require.NoError(t, os.Chtimes(filename, time.Now(), time.Now().Add(time.Hour)))
+	k3, err := Key(filename)
+	require.NoError(t, err)
+	assert.NotEqual(t, k1, k3)
+
+	_, err = Key(filepath.Join(dir, "missing.bin"))
+	assert.Error(t, err)
+}
+
+func TestAcquireConcurrent(t *testing.T) {
+	started, unblock := make(chan struct{}), make(chan struct{})
+	slow := func() (*int, io.Closer, error) {
+		close(started)
+		<-unblock
+		return new(int), nil, nil
+	}
+	type result struct {
+		v       *int
+		release func() error
+	}
+	done := make(chan result, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			v, release, err := Acquire("slow", slow)
+			assert.NoError(t, err)
+			done <- result{v, release}
+		}()
+		if i == 0 {
+			<-started
+		}
+	}
+
+	// The values of the other keys are acquired and released meanwhile.
+	v, release, err := Acquire("fast", func() (int, io.Closer, error) { return 1, nil, nil })
+	require.NoError(t, err)
+	assert.Equal(t, 1, v)
+	require.NoError(t, release())
+
+	// The holders of the same key wait for the first load.
+	close(unblock)
+	r1, r2 := <-done, <-done
+	assert.Same(t, r1.v, r2.v)
+	require.NoError(t, r1.release())
+	require.NoError(t, r2.release())
+}
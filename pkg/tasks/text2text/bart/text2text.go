@@ -6,19 +6,27 @@ package bart
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"path"
 	"path/filepath"
 
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
 	"github.com/nlpodyssey/cybertron/pkg/generationutils"
 	"github.com/nlpodyssey/cybertron/pkg/models/bart"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/diskstoremode"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sharedweights"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
+	"github.com/nlpodyssey/cybertron/pkg/timings"
 	"github.com/nlpodyssey/cybertron/pkg/tokenizers/bpetokenizer"
 	"github.com/nlpodyssey/cybertron/pkg/tokenizers/sentencepiece"
+	"github.com/nlpodyssey/cybertron/pkg/trace"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
 	"github.com/nlpodyssey/cybertron/pkg/utils/nullable"
+	"github.com/nlpodyssey/spago/ag"
 	"github.com/nlpodyssey/spago/embeddings/store/diskstore"
 	"github.com/nlpodyssey/spago/mat"
 	"github.com/nlpodyssey/spago/nn"
@@ -34,8 +42,9 @@ type Text2Text struct {
 	Model *bart.ModelForConditionalGeneration
 	// Tokenizer is the tokenizer used for conditional generation.
 	Tokenizer Tokenizer
-	// embeddingsRepo is the repository used for loading embeddings.
-	embeddingsRepo *diskstore.Repository
+	// release releases the encoder, with its embeddings, shared with the
+	// other models loaded from the same file.
+	release func() error
 }
 
 type Tokenizer interface {
@@ -45,30 +54,41 @@ type Tokenizer interface {
 
 // LoadText2Text returns a Text2Text loading the model, the embeddings and the tokenizer from a directory.
 func LoadText2Text(modelPath string) (*Text2Text, error) {
-	embeddingsRepo, err := diskstore.NewRepository(filepath.Join(modelPath, "repo"), diskstoremode.DefaultDiskStoreMode)
+	m, err := nn.LoadFromFile[*bart.ModelForConditionalGeneration](path.Join(modelPath, "spago_model.bin"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to load embeddings repository for text2text: %w", err)
+		return nil, fmt.Errorf("failed to load bart model: %w", err)
 	}
 
-	m, err := nn.LoadFromFile[*bart.ModelForConditionalGeneration](path.Join(modelPath, "spago_model.bin"))
+	key, err := sharedweights.Key(path.Join(modelPath, "spago_model.bin"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load bart model: %w", err)
 	}
-
-	err = m.Bart.SetEmbeddings(embeddingsRepo)
+	encoder, release, err := sharedweights.Acquire(key, func() (*bart.Model, io.Closer, error) {
+		embeddingsRepo, err := diskstore.NewRepository(filepath.Join(modelPath, "repo"), diskstoremode.DefaultDiskStoreMode)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load embeddings repository for text2text: %w", err)
+		}
+		if err := m.Bart.SetEmbeddings(embeddingsRepo); err != nil {
+			_ = embeddingsRepo.Close()
+			return nil, nil, fmt.Errorf("failed to load embeddings: %w", err)
+		}
+		return m.Bart, embeddingsRepo, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to load embeddings: %w", err)
+		return nil, err
 	}
+	m.Bart = encoder
 
 	tok, err := resolveTokenizer(modelPath, m.Bart.Config)
 	if err != nil {
+		_ = release()
 		return nil, err
 	}
 
 	return &Text2Text{
-		Model:          m,
-		Tokenizer:      tok,
-		embeddingsRepo: embeddingsRepo,
+		Model:     m,
+		Tokenizer: tok,
+		release:   release,
 	}, nil
 }
 
@@ -118,7 +138,7 @@ func doesFileExist(fileName string) bool {
 // Close finalizes the Text2Text resources.
 // It satisfies the interface io.Closer.
 func (m *Text2Text) Close() error {
-	return m.embeddingsRepo.Close()
+	return m.release()
 }
 
 // Generate generates a text from the input.
@@ -131,27 +151,49 @@ func (m *Text2Text) Generate(ctx context.Context, text string, opts *text2text.O
 			TopP:        nullable.Type[float64]{Valid: false},
 		}
 	}
+	end := timings.Start(ctx, timings.Tokenization)
 	tokenized, err := m.Tokenizer.Tokenize(text)
+	end()
 	if err != nil {
 		return text2text.Response{}, err
 	}
 	if l, max := len(tokenized), m.Model.Bart.Config.MaxLength; l > max {
 		return text2text.Response{}, fmt.Errorf("%w: %d > %d", text2text.ErrInputSequenceTooLong, l, max)
 	}
+	usage.AddTokens(ctx, len(tokenized), 0)
+	if trace.Enabled(ctx) {
+		trace.Record(ctx, trace.Input{TokenIDs: tokenized, AttentionMask: trace.Ones(len(tokenized))})
+	}
+	if err := ctx.Err(); err != nil {
+		return text2text.Response{}, err
+	}
+	end = timings.Start(ctx, timings.Forward)
+	encoderStates := m.Model.Bart.Encoder.Encode(tokenized)
+	end()
 
-	sequences, scores := m.process(ctx, tokenized, *opts)
+	end = timings.Start(ctx, timings.Decoding)
+	sequences, scores := m.process(ctx, encoderStates, *opts)
+	end()
 	result := text2text.Response{
 		Texts:  make([]string, len(sequences)),
 		Scores: make([]float64, len(scores)),
 	}
 	for i, sequence := range sequences {
 		result.Texts[i], result.Scores[i] = m.Tokenizer.Detokenize(sequence, true), scores[i]
+		usage.AddTokens(ctx, 0, len(sequence))
+	}
+	if err := ctx.Err(); err != nil {
+		// The decoding stopped early: the texts are incomplete.
+		if errors.Is(err, context.DeadlineExceeded) {
+			return result, fmt.Errorf("%w: %w", errdefs.ErrDecodingTimeout, err)
+		}
+		return text2text.Response{}, err
 	}
 	return result, nil
 }
 
-func (m *Text2Text) process(ctx context.Context, inputIDs []int, opts text2text.Options) ([][]int, []float64) {
-	next := m.Model.DecodingFunc(inputIDs, m.logProbProcessor(opts), true)
+func (m *Text2Text) process(ctx context.Context, encoderStates []ag.Node, opts text2text.Options) ([][]int, []float64) {
+	next := m.Model.DecodingFuncFromStates(encoderStates, m.logProbProcessor(opts), true)
 	cache := make([]bart.Cache, m.Model.Bart.Config.NumBeams)
 
 	predictNext := func(decodingInputIDs [][]int, lastBeamIndices []int) []mat.Matrix {
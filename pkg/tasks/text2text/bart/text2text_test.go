@@ -0,0 +1,193 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bart
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	bartconverter "github.com/nlpodyssey/cybertron/pkg/converter/bart"
+	"github.com/nlpodyssey/cybertron/pkg/converter/convertertest"
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testHiddenSize   = 8
+	testFFNDim       = 16
+	testNumLayers    = 1
+	testMaxPositions = 16
+	testMaxLength    = 8
+	testVocabSize    = 10
+)
+
+func TestGenerate_TooLong(t *testing.T) {
+	m := loadTestText2Text(t, writeTestModel(t))
+	_, err := m.Generate(context.Background(), strings.Repeat("a", testMaxLength+1), nil)
+	assert.ErrorIs(t, err, text2text.ErrInputSequenceTooLong)
+	resp, err := m.Generate(context.Background(), strings.Repeat("a", testMaxLength), nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, resp.Texts)
+}
+
+func TestGenerate_Timeout(t *testing.T) {
+	m := loadTestText2Text(t, writeTestModel(t))
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	resp, err := m.Generate(expired, "abc", nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, errdefs.ErrDecodingTimeout, "expired before the decoding")
+	assert.Empty(t, resp.Texts)
+
+	// The deadline expires after the first decoding step.
+	resp, err = m.Generate(newExpiringContext(1), "abc", nil)
+	assert.ErrorIs(t, err, errdefs.ErrDecodingTimeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotEmpty(t, resp.Texts, "the texts generated so far are returned")
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = m.Generate(canceled, "abc", nil)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// expiringContext is a context whose deadline expires once its Done method
+// is called more than n times, e.g. after n decoding steps.
+type expiringContext struct {
+	context.Context
+	mu   sync.Mutex
+	n    int
+	done chan struct{}
+}
+
+func newExpiringContext(n int) *expiringContext {
+	return &expiringContext{Context: context.Background(), n: n, done: make(chan struct{})}
+}
+
+func (c *expiringContext) Done() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.n--; c.n == -1 {
+		close(c.done)
+	}
+	return c.done
+}
+
+func (c *expiringContext) Err() error {
+	select {
+	case <-c.done:
+		return context.DeadlineExceeded
+	default:
+		return nil
+	}
+}
+
+// writeTestModel converts a tiny synthetic BART checkpoint in a temporary
+// directory, returning the directory.
+func writeTestModel(t *testing.T) string {
+	dir := t.TempDir()
+	config := map[string]any{
+		"architectures":           []string{"BartForConditionalGeneration"},
+		"model_type":              "bart",
+		"activation_function":     "gelu",
+		"d_model":                 testHiddenSize,
+		"encoder_attention_heads": 2,
+		"decoder_attention_heads": 2,
+		"encoder_ffn_dim":         testFFNDim,
+		"decoder_ffn_dim":         testFFNDim,
+		"encoder_layers":          testNumLayers,
+		"decoder_layers":          testNumLayers,
+		"max_position_embeddings": testMaxPositions,
+		"normalize_embedding":     true,
+		"vocab_size":              testVocabSize,
+		"is_encoder_decoder":      true,
+		"bos_token_id":            0,
+		"pad_token_id":            1,
+		"eos_token_id":            2,
+		"decoder_start_token_id":  2,
+		"num_beams":               2,
+		"max_length":              testMaxLength,
+	}
+	require.NoError(t, convertertest.WriteJSON(dir, "config.json", config))
+	require.NoError(t, convertertest.WriteSafetensors(dir, testCheckpoint()))
+	require.NoError(t, bartconverter.Convert[float32](dir, true))
+
+	// The BPE tokenizer is loaded, but replaced by testTokenizer.
+	require.NoError(t, convertertest.WriteJSON(dir, "vocab.json", map[string]int{"a": 0}))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "merges.txt"), nil, 0o644))
+	return dir
+}
+
+// loadTestText2Text loads the model in the directory.
+func loadTestText2Text(t *testing.T, dir string) *Text2Text {
+	m, err := LoadText2Text(dir)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = m.Close() })
+	m.Tokenizer = testTokenizer{}
+	return m
+}
+
+// testCheckpoint returns the weights of the model, named as in the
+// checkpoints of the transformers library.
+func testCheckpoint() convertertest.Checkpoint {
+	h, f := testHiddenSize, testFFNDim
+	c := convertertest.Checkpoint{
+		"model.shared.weight": {testVocabSize, h},
+		"final_logits_bias":   {1, testVocabSize},
+	}
+	for _, stack := range []string{"encoder", "decoder"} {
+		prefix := "model." + stack
+		c[prefix+".embed_positions.weight"] = []int{testMaxPositions + 2, h}
+		c[prefix+".layernorm_embedding.weight"] = []int{h}
+		c[prefix+".layernorm_embedding.bias"] = []int{h}
+		attentions := []string{"self_attn"}
+		if stack == "decoder" {
+			attentions = append(attentions, "encoder_attn")
+		}
+		for l := 0; l < testNumLayers; l++ {
+			layer := fmt.Sprintf("%s.layers.%d", prefix, l)
+			for _, attn := range attentions {
+				for _, name := range []string{"q_proj", "k_proj", "v_proj", "out_proj"} {
+					c[layer+"."+attn+"."+name+".weight"] = []int{h, h}
+					c[layer+"."+attn+"."+name+".bias"] = []int{h}
+				}
+				c[layer+"."+attn+"_layer_norm.weight"] = []int{h}
+				c[layer+"."+attn+"_layer_norm.bias"] = []int{h}
+			}
+			c[layer+".fc1.weight"] = []int{f, h}
+			c[layer+".fc1.bias"] = []int{f}
+			c[layer+".fc2.weight"] = []int{h, f}
+			c[layer+".fc2.bias"] = []int{h}
+			c[layer+".final_layer_norm.weight"] = []int{h}
+			c[layer+".final_layer_norm.bias"] = []int{h}
+		}
+	}
+	return c
+}
+
+// testTokenizer maps each letter from 'a' to one of the token IDs past the
+// special ones.
+type testTokenizer struct{}
+
+func (testTokenizer) Tokenize(text string) ([]int, error) {
+	ids := make([]int, len(text))
+	for i, r := range text {
+		ids[i] = 3 + int(r-'a')%(testVocabSize-3)
+	}
+	return ids, nil
+}
+
+func (testTokenizer) Detokenize(tokenIDs []int, _ bool) string {
+	return fmt.Sprint(tokenIDs)
+}
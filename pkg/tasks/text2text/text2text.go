@@ -6,10 +6,10 @@ package text2text
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
 	"github.com/nlpodyssey/cybertron/pkg/utils/nullable"
 )
 
@@ -52,8 +52,11 @@ func DefaultModelForMachineTranslation(source, target string) string {
 }
 
 // Interface defines the main functions for the Text2Text task.
+// Its implementations are safe for concurrent use.
 type Interface interface {
 	// Generate generates text (e.g. translation, summarization, paraphrase) from the given input.
+	// If the deadline of the context expires before the generation is complete, it fails with
+	// errdefs.ErrDecodingTimeout, returning the texts generated so far.
 	Generate(ctx context.Context, text string, opts *Options) (Response, error)
 }
 
@@ -79,7 +82,7 @@ type Response struct {
 
 // ErrInputSequenceTooLong means that pre-processing the input text
 // produced a sequence that exceeds the maximum allowed length.
-var ErrInputSequenceTooLong = errors.New("input sequence too long")
+var ErrInputSequenceTooLong = errdefs.ErrInputTooLong
 
 // DefaultOptions returns the default options for generating text.
 func DefaultOptions() *Options {
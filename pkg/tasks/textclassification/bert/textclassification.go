@@ -7,6 +7,7 @@ package bert
 import (
 	"context"
 	"fmt"
+	"io"
 	"path"
 	"path/filepath"
 	"sort"
@@ -14,10 +15,17 @@ import (
 	"strings"
 
 	"github.com/nlpodyssey/cybertron/pkg/models/bert"
+	"github.com/nlpodyssey/cybertron/pkg/models/bert/bertconfig"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/attribution"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/diskstoremode"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sharedweights"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/timings"
 	"github.com/nlpodyssey/cybertron/pkg/tokenizers"
 	"github.com/nlpodyssey/cybertron/pkg/tokenizers/wordpiecetokenizer"
+	"github.com/nlpodyssey/cybertron/pkg/trace"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
+	"github.com/nlpodyssey/cybertron/pkg/utils/matview"
 	"github.com/nlpodyssey/cybertron/pkg/utils/sliceutils"
 	"github.com/nlpodyssey/cybertron/pkg/vocabulary"
 	"github.com/nlpodyssey/spago/embeddings/store/diskstore"
@@ -35,8 +43,11 @@ type TextClassification struct {
 	Labels []string
 	// doLowerCase is a flag indicating if the model should lowercase the input before tokenization.
 	doLowerCase bool
-	// embeddingsRepo is the repository used for loading embeddings.
-	embeddingsRepo *diskstore.Repository
+	// inputs builds the inputs of the model, with its special tokens.
+	inputs bertconfig.Inputs
+	// release releases the encoder, with its embeddings, shared with the
+	// other models loaded from the same file.
+	release func() error
 }
 
 // LoadTextClassification returns a TextClassification loading the model, the embeddings and the tokenizer from a directory.
@@ -58,27 +69,38 @@ func LoadTextClassification(modelPath string) (*TextClassification, error) {
 	}
 	labels := ID2Label(config.ID2Label)
 
-	embeddingsRepo, err := diskstore.NewRepository(filepath.Join(modelPath, "repo"), diskstoremode.DefaultDiskStoreMode)
+	m, err := nn.LoadFromFile[*bert.ModelForSequenceClassification](path.Join(modelPath, "spago_model.bin"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to load embeddings repository for text classification: %w", err)
+		return nil, fmt.Errorf("failed to load bart model: %w", err)
 	}
 
-	m, err := nn.LoadFromFile[*bert.ModelForSequenceClassification](path.Join(modelPath, "spago_model.bin"))
+	key, err := sharedweights.Key(path.Join(modelPath, "spago_model.bin"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load bart model: %w", err)
 	}
-
-	err = m.Bert.SetEmbeddings(embeddingsRepo)
+	encoder, release, err := sharedweights.Acquire(key, func() (*bert.Model, io.Closer, error) {
+		embeddingsRepo, err := diskstore.NewRepository(filepath.Join(modelPath, "repo"), diskstoremode.DefaultDiskStoreMode)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load embeddings repository for text classification: %w", err)
+		}
+		if err := m.Bert.SetEmbeddings(embeddingsRepo); err != nil {
+			_ = embeddingsRepo.Close()
+			return nil, nil, fmt.Errorf("failed to set embeddings: %w", err)
+		}
+		return m.Bert, embeddingsRepo, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to set embeddings: %w", err)
+		return nil, err
 	}
+	m.Bert = encoder
 
 	return &TextClassification{
-		Model:          m,
-		Tokenizer:      tokenizer,
-		Labels:         labels,
-		doLowerCase:    tokenizerConfig.DoLowerCase,
-		embeddingsRepo: embeddingsRepo,
+		Model:       m,
+		Tokenizer:   tokenizer,
+		Labels:      labels,
+		doLowerCase: tokenizerConfig.DoLowerCase,
+		inputs:      bertconfig.NewInputs(config, tokenizerConfig),
+		release:     release,
 	}, nil
 }
 
@@ -100,19 +122,28 @@ func ID2Label(value map[string]string) []string {
 // Close finalizes the TextClassification resources.
 // It satisfies the interface io.Closer.
 func (m *TextClassification) Close() error {
-	return m.embeddingsRepo.Close()
+	return m.release()
 }
 
-// Classify returns the classification of the given text.
-func (m *TextClassification) Classify(_ context.Context, text string) (textclassification.Response, error) {
-	tokenized := m.tokenize(text)
+// Classify returns the classification of the given text, paired with the
+// second text of the parameters, if any, after the separators of the model,
+// running only the first encoder layers of the model if requested, and
+// explaining it if requested, with the attributions to the tokens of the
+// first text only.
+func (m *TextClassification) Classify(ctx context.Context, text string, parameters textclassification.Parameters) (textclassification.Response, error) {
+	end := timings.Start(ctx, timings.Tokenization)
+	tokenized, pieces := m.tokenize(text, parameters.TextPair)
+	end()
 	if l, max := len(tokenized), m.Model.Bert.Config.MaxPositionEmbeddings; l > max {
 		return textclassification.Response{}, fmt.Errorf("%w: %d > %d", textclassification.ErrInputSequenceTooLong, l, max)
 	}
-	logits := m.Model.Classify(tokenized)
-	probs := logits.Value().Softmax()
-
-	result := sliceutils.NewIndexedSlice[float64](probs.Data().F64())
+	usage.AddTokens(ctx, len(tokenized), 0)
+	layers := parameters.Layers
+	end = timings.Start(ctx, timings.Forward)
+	logits := m.Model.ClassifyLayers(tokenized, layers)
+	end()
+	trace.Encode(ctx, tokenized, m.Model.Bert.EncodeEach)
+	result := sliceutils.NewIndexedSlice[float64](matview.Softmax(logits.Value()))
 	sort.Stable(sort.Reverse(result))
 
 	labels := make([]string, len(m.Labels))
@@ -124,15 +155,59 @@ func (m *TextClassification) Classify(_ context.Context, text string) (textclass
 		Labels: labels,
 		Scores: result.Slice,
 	}
+	if parameters.Explain {
+		attributions, err := m.explain(ctx, text, tokenized, pieces, layers, result.Indices[0], result.Slice[0])
+		if err != nil {
+			return textclassification.Response{}, err
+		}
+		response.Attributions = attributions
+	}
 	return response, nil
 }
 
-// tokenize returns the tokens of the given text (including padding tokens).
-func (m *TextClassification) tokenize(text string) []string {
+// explain returns the attributions of the label, predicted with the
+// probability, to the word pieces of the text, masking them in turn.
+func (m *TextClassification) explain(ctx context.Context, text string, tokenized []string, pieces []tokenizers.StringOffsetsPair, layers, label int, prob float64) ([]attribution.Token, error) {
+	positions := make([]int, len(pieces))
+	for i := range positions {
+		positions[i] = i + 1 // after the [CLS] token
+	}
+	drops, err := attribution.Occlusion(ctx, tokenized, positions, wordpiecetokenizer.DefaultMaskToken, []float64{prob},
+		func(tokens []string) ([]float64, error) {
+			return []float64{matview.SoftmaxAt(m.Model.ClassifyLayers(tokens, layers).Value(), label)}, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	scores := make([]float64, len(drops))
+	for i, d := range drops {
+		scores[i] = d[0]
+	}
+	return attribution.Tokens(text, pieces, scores), nil
+}
+
+// tokenize returns the tokens of the given text, paired with the second
+// text if not empty, with the special tokens of the model, and the word
+// pieces of the first text alone, with their offsets.
+func (m *TextClassification) tokenize(text, pair string) ([]string, []tokenizers.StringOffsetsPair) {
+	pieces := m.Tokenizer.Tokenize(m.lowerCase(text))
+	if pair == "" {
+		return m.inputs.Single(tokenizers.GetStrings(pieces)).Tokens, pieces
+	}
+	pairPieces := m.Tokenizer.Tokenize(m.lowerCase(pair))
+	return m.inputs.Pair(tokenizers.GetStrings(pieces), tokenizers.GetStrings(pairPieces)).Tokens, pieces
+}
+
+// lowerCase returns the text lowercased, if the model is uncased.
+func (m *TextClassification) lowerCase(text string) string {
 	if m.doLowerCase {
-		text = strings.ToLower(text)
+		return strings.ToLower(text)
 	}
-	cls := wordpiecetokenizer.DefaultClassToken
-	sep := wordpiecetokenizer.DefaultSequenceSeparator
-	return append([]string{cls}, append(tokenizers.GetStrings(m.Tokenizer.Tokenize(text)), sep)...)
+	return text
+}
+
+// Tokenize returns the tokens of the given text, as seen by the model.
+func (m *TextClassification) Tokenize(text string) []string {
+	tokens, _ := m.tokenize(text, "")
+	return tokens
 }
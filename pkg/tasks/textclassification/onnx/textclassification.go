@@ -0,0 +1,291 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/logging"
+	"github.com/nlpodyssey/cybertron/pkg/models/bert/bertconfig"
+	onnxmodel "github.com/nlpodyssey/cybertron/pkg/onnx"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/attribution"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/timings"
+	"github.com/nlpodyssey/cybertron/pkg/tokenizers"
+	"github.com/nlpodyssey/cybertron/pkg/tokenizers/wordpiecetokenizer"
+	"github.com/nlpodyssey/cybertron/pkg/trace"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
+	"github.com/nlpodyssey/cybertron/pkg/utils/sliceutils"
+	"github.com/nlpodyssey/cybertron/pkg/vocabulary"
+	"github.com/rs/zerolog/log"
+)
+
+var _ textclassification.Interface = &TextClassification{}
+
+// TextClassification is a text classification model executed by the ONNX runtime.
+type TextClassification struct {
+	// Model is the ONNX model of the classifier.
+	Model *onnxmodel.Model
+	// Tokenizer is the tokenizer used to tokenize the input text.
+	Tokenizer *wordpiecetokenizer.WordPieceTokenizer
+	// Vocabulary is the vocabulary used to map the tokens to the input IDs.
+	Vocabulary *vocabulary.Vocabulary
+	// Config is the configuration of the classifier.
+	Config bertconfig.Config
+	// Labels is the list of labels used for classification.
+	Labels []string
+	// doLowerCase is a flag indicating if the model should lowercase the input before tokenization.
+	doLowerCase bool
+	// inputs builds the inputs of the model, with its special tokens and token types.
+	inputs bertconfig.Inputs
+	// maxLength is the maximum number of tokens of the input, extended by the RoPE scaling.
+	maxLength int
+}
+
+// LoadTextClassification returns a TextClassification loading the ONNX model and the tokenizer from a directory.
+func LoadTextClassification(modelPath string) (*TextClassification, error) {
+	return LoadTextClassificationFS(os.DirFS(modelPath))
+}
+
+// LoadTextClassificationFS returns a TextClassification loading the ONNX model and the tokenizer from the root of a file system,
+// e.g. the files provided by the host when compiled to WebAssembly.
+func LoadTextClassificationFS(fsys fs.FS) (*TextClassification, error) {
+	vocab, err := vocabulary.NewFromFS(fsys, "vocab.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vocabulary for text classification: %w", err)
+	}
+
+	tokenizerConfig, err := bertconfig.ConfigFromFS[bertconfig.TokenizerConfig](fsys, "tokenizer_config.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tokenizer config for text classification: %w", err)
+	}
+
+	config, err := bertconfig.ConfigFromFS[bertconfig.Config](fsys, "config.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config for text classification: %w", err)
+	}
+
+	m, err := onnxmodel.LoadModelFS(fsys, onnxmodel.DefaultModelFilename)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	m.FuseAttention()
+
+	labels, err := bertconfig.ID2Label(config.ID2Label)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load labels for text classification: %w", err)
+	}
+
+	t := &TextClassification{
+		Model:       m,
+		Tokenizer:   wordpiecetokenizer.New(vocab),
+		Vocabulary:  vocab,
+		Config:      config,
+		Labels:      labels,
+		doLowerCase: tokenizerConfig.DoLowerCase,
+		inputs:      bertconfig.NewInputs(config, tokenizerConfig),
+		maxLength:   config.MaxPositionEmbeddings,
+	}
+	if rs := config.RopeScaling; rs != nil {
+		switch rs.Kind() {
+		case onnxmodel.RopeScalingLinear, onnxmodel.RopeScalingYaRN:
+			err = t.SetRopeScaling(onnxmodel.RopeScaling{
+				Type:                          rs.Kind(),
+				Factor:                        rs.Factor,
+				OriginalMaxPositionEmbeddings: rs.OriginalMaxPositionEmbeddings,
+				BetaFast:                      rs.BetaFast,
+				BetaSlow:                      rs.BetaSlow,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to scale the rotary position embeddings for text classification: %w", err)
+			}
+		default:
+			log.Warn().Str("type", rs.Kind()).Msg("unsupported RoPE scaling of the model, ignored")
+		}
+	}
+	return t, nil
+}
+
+// Close finalizes the TextClassification resources, releasing the weights of the model.
+// It satisfies the interface io.Closer.
+func (m *TextClassification) Close() error {
+	return m.Model.Close()
+}
+
+// SetRopeScaling scales the rotary position embeddings of the model, replacing the scaling of its configuration,
+// if any, and extends the maximum length of the inputs by the factor. The original maximum length, if not set,
+// is the one of the configuration. It fails if the model has no rotary position embeddings.
+func (m *TextClassification) SetRopeScaling(s onnxmodel.RopeScaling) error {
+	if s.OriginalMaxPositionEmbeddings == 0 {
+		s.OriginalMaxPositionEmbeddings = m.Config.MaxPositionEmbeddings
+		if rs := m.Config.RopeScaling; rs != nil && rs.OriginalMaxPositionEmbeddings > 0 {
+			s.OriginalMaxPositionEmbeddings = rs.OriginalMaxPositionEmbeddings
+		}
+	}
+	if err := m.Model.ScaleRoPE(s); err != nil {
+		return err
+	}
+	m.maxLength = m.Config.MaxPositionEmbeddings
+	if s.Type != "" {
+		m.maxLength = int(float64(s.OriginalMaxPositionEmbeddings) * s.Factor)
+	}
+	return nil
+}
+
+// Classify returns the classification of the given text, paired with the
+// second text of the parameters, if any, whose tokens have the token type 1
+// if the model has token types, explaining it if requested, with the
+// attributions to the tokens of the first text only. Running a subset of
+// the encoder layers is not supported.
+func (m *TextClassification) Classify(ctx context.Context, text string, parameters textclassification.Parameters) (textclassification.Response, error) {
+	if parameters.Layers > 0 {
+		return textclassification.Response{}, textclassification.ErrLayersNotSupported
+	}
+	end := timings.Start(ctx, timings.Tokenization)
+	tokenized, pieces := m.tokenize(text, parameters.TextPair)
+	end()
+	if l, max := len(tokenized), m.maxLength; l > max {
+		return textclassification.Response{}, fmt.Errorf("%w: %d > %d", textclassification.ErrInputSequenceTooLong, l, max)
+	}
+	usage.AddTokens(ctx, len(tokenized), 0)
+	if trace.Enabled(ctx) {
+		trace.Record(ctx, trace.Input{Tokens: tokenized, TokenIDs: m.tokenIDs(tokenized), TokenTypeIDs: m.inputs.TokenTypeIDs(tokenized), AttentionMask: trace.Ones(len(tokenized))})
+	}
+
+	probs, err := m.forward(ctx, tokenized)
+	if err != nil {
+		return textclassification.Response{}, err
+	}
+	result := sliceutils.NewIndexedSlice[float64](probs)
+	sort.Stable(sort.Reverse(result))
+
+	labels := make([]string, len(m.Labels))
+	for i, ii := range result.Indices {
+		labels[i] = m.Labels[ii]
+	}
+
+	response := textclassification.Response{
+		Labels: labels,
+		Scores: result.Slice,
+	}
+	if parameters.Explain {
+		attributions, err := m.explain(ctx, text, tokenized, pieces, result.Indices[0], result.Slice[0])
+		if err != nil {
+			return textclassification.Response{}, err
+		}
+		response.Attributions = attributions
+	}
+	return response, nil
+}
+
+// forward returns the probabilities of the labels for the tokens.
+func (m *TextClassification) forward(ctx context.Context, tokenized []string) ([]float64, error) {
+	end := timings.Start(ctx, timings.Forward)
+	outputs, stats, err := m.Model.RunWithStats(onnxmodel.EncoderInputsWithTypes(m.Model.Graph, m.tokenIDs(tokenized), m.inputs.TokenTypeIDs(tokenized)))
+	end()
+	logging.Ctx(ctx).Debug().Int("tokens", len(tokenized)).Int64("peak_memory", stats.PeakMemory).Err(err).Msg("onnx forward pass")
+	if err != nil {
+		return nil, err
+	}
+	logits, ok := outputs["logits"]
+	if !ok {
+		logits = outputs[m.Model.Graph.Outputs[0]]
+	}
+	if logits.Size() != len(m.Labels) {
+		return nil, fmt.Errorf("onnx: expected %d logits, got %d", len(m.Labels), logits.Size())
+	}
+	return softmax(logits.AsFloats()), nil
+}
+
+// explain returns the attributions of the label, predicted with the
+// probability, to the word pieces of the text, masking them in turn.
+func (m *TextClassification) explain(ctx context.Context, text string, tokenized []string, pieces []tokenizers.StringOffsetsPair, label int, prob float64) ([]attribution.Token, error) {
+	positions := make([]int, len(pieces))
+	for i := range positions {
+		positions[i] = i + 1 // after the [CLS] token
+	}
+	drops, err := attribution.Occlusion(ctx, tokenized, positions, wordpiecetokenizer.DefaultMaskToken, []float64{prob},
+		func(tokens []string) ([]float64, error) {
+			probs, err := m.forward(ctx, tokens)
+			if err != nil {
+				return nil, err
+			}
+			return []float64{probs[label]}, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	scores := make([]float64, len(drops))
+	for i, d := range drops {
+		scores[i] = d[0]
+	}
+	return attribution.Tokens(text, pieces, scores), nil
+}
+
+func softmax(logits []float32) []float64 {
+	max := math.Inf(-1)
+	for _, v := range logits {
+		max = math.Max(max, float64(v))
+	}
+	out := make([]float64, len(logits))
+	var sum float64
+	for i, v := range logits {
+		out[i] = math.Exp(float64(v) - max)
+		sum += out[i]
+	}
+	for i := range out {
+		out[i] /= sum
+	}
+	return out
+}
+
+// tokenIDs maps the tokens to their IDs, using the unknown token for the missing ones.
+func (m *TextClassification) tokenIDs(tokens []string) []int {
+	unk, _ := m.Vocabulary.ID(wordpiecetokenizer.DefaultUnknownToken)
+	ids := make([]int, len(tokens))
+	for i, t := range tokens {
+		id, ok := m.Vocabulary.ID(t)
+		if !ok {
+			id = unk
+		}
+		ids[i] = id
+	}
+	return ids
+}
+
+// tokenize returns the tokens of the given text, paired with the second
+// text if not empty, with the special tokens of the model, and the word
+// pieces of the first text alone, with their offsets.
+func (m *TextClassification) tokenize(text, pair string) ([]string, []tokenizers.StringOffsetsPair) {
+	pieces := m.Tokenizer.Tokenize(m.lowerCase(text))
+	if pair == "" {
+		return m.inputs.Single(tokenizers.GetStrings(pieces)).Tokens, pieces
+	}
+	pairPieces := m.Tokenizer.Tokenize(m.lowerCase(pair))
+	return m.inputs.Pair(tokenizers.GetStrings(pieces), tokenizers.GetStrings(pairPieces)).Tokens, pieces
+}
+
+// lowerCase returns the text lowercased, if the model is uncased.
+func (m *TextClassification) lowerCase(text string) string {
+	if m.doLowerCase {
+		return strings.ToLower(text)
+	}
+	return text
+}
+
+// Tokenize returns the tokens of the given text, as seen by the model.
+func (m *TextClassification) Tokenize(text string) []string {
+	tokens, _ := m.tokenize(text, "")
+	return tokens
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// emptyModel is a serialized ONNX model with an empty graph.
+var emptyModel = []byte{0x08, 0x08, 0x3a, 0x00}
+
+// unsupportedModel is a serialized ONNX model with a node of an
+// unsupported operator.
+var unsupportedModel = []byte{0x08, 0x08, 0x3a, 0x06, 0x0a, 0x04, 0x22, 0x02, 'N', 'o'}
+
+func TestLoadTextClassificationFS(t *testing.T) {
+	files := func(overrides map[string]string) fstest.MapFS {
+		fsys := fstest.MapFS{
+			"vocab.txt":             {Data: []byte("[PAD]\n[UNK]\n[CLS]\n[SEP]\nthe\n")},
+			"tokenizer_config.json": {Data: []byte(`{"do_lower_case": true}`)},
+			"config.json":           {Data: []byte(`{"max_position_embeddings": 16, "id2label": {"0": "NEG", "1": "POS"}}`)},
+			"model.onnx":            {Data: emptyModel},
+		}
+		for name, content := range overrides {
+			if content == "" {
+				delete(fsys, name)
+				continue
+			}
+			fsys[name] = &fstest.MapFile{Data: []byte(content)}
+		}
+		return fsys
+	}
+
+	m, err := LoadTextClassificationFS(files(nil))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"NEG", "POS"}, m.Labels)
+	assert.True(t, m.doLowerCase)
+
+	_, err = m.Classify(context.Background(), "the", textclassification.Parameters{Layers: 1})
+	assert.ErrorIs(t, err, textclassification.ErrLayersNotSupported, "early exit not supported")
+
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		wantErr   string
+	}{
+		{"missing vocabulary", map[string]string{"vocab.txt": ""}, "failed to load vocabulary"},
+		{"missing tokenizer config", map[string]string{"tokenizer_config.json": ""}, "failed to load tokenizer config"},
+		{"invalid config", map[string]string{"config.json": "{"}, "failed to load config"},
+		{"missing model", map[string]string{"model.onnx": ""}, "failed to read ONNX model"},
+		{"invalid model", map[string]string{"model.onnx": "\x3a\x10"}, "failed to decode ONNX model"},
+		{"unsupported operator", map[string]string{"model.onnx": string(unsupportedModel)}, "unsupported operator"},
+		{"invalid labels", map[string]string{"config.json": `{"id2label": {"0": "NEG", "2": "POS"}}`}, "failed to load labels"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadTextClassificationFS(files(tt.overrides))
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -6,7 +6,9 @@ package textclassification
 
 import (
 	"context"
-	"errors"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/attribution"
 )
 
 const (
@@ -23,12 +25,41 @@ const (
 
 // ErrInputSequenceTooLong means that pre-processing the input text
 // produced a sequence that exceeds the maximum allowed length.
-var ErrInputSequenceTooLong = errors.New("input sequence too long")
+var ErrInputSequenceTooLong = errdefs.ErrInputTooLong
+
+// ErrLayersNotSupported means that the model can't run only a subset of
+// its encoder layers, as requested with Parameters.Layers.
+var ErrLayersNotSupported = errdefs.New(errdefs.CodeUnsupportedOption, "early exit not supported by the model")
+
+// ErrExplainNotSupported means that the model can't explain its
+// classification, as requested with Parameters.Explain.
+var ErrExplainNotSupported = errdefs.New(errdefs.CodeUnsupportedOption, "explanation not supported by the model")
+
+// Parameters are the options of a classification.
+type Parameters struct {
+	// TextPair is the second text of the pair to classify, if not empty,
+	// e.g. the hypothesis of a premise for natural language inference, or
+	// the passage of a query for relevance. The models encode the pair as a
+	// single input, with the separator and the token types of their
+	// architecture.
+	TextPair string
+	// Layers is the number of the first encoder layers of the model to run,
+	// if positive, followed by its classification head: a "fast" mode
+	// trading accuracy for latency. The models not supporting it fail with
+	// ErrLayersNotSupported.
+	Layers int
+	// Explain requests the explanation of the first label, with the
+	// attributions to the tokens of the text (see the attribution package),
+	// at the cost of a forward pass per token. The models not supporting it
+	// fail with ErrExplainNotSupported.
+	Explain bool
+}
 
 // Interface defines the main functions for text classification task.
+// Its implementations are safe for concurrent use.
 type Interface interface {
 	// Classify returns the classification of the given example.
-	Classify(ctx context.Context, text string) (Response, error)
+	Classify(ctx context.Context, text string, parameters Parameters) (Response, error)
 }
 
 // Response contains the response from text classification.
@@ -38,6 +69,9 @@ type Response struct {
 	Labels []string
 	// a list of floats that correspond the probability of label, in the same order as labels.
 	Scores []float64
+	// Attributions are the attributions of the first label to the tokens of
+	// the text, if requested with Parameters.Explain.
+	Attributions []attribution.Token
 }
 
 // Filter returns a function to filter the classification response with respect to two parameters, keepThreshold and
@@ -56,8 +90,9 @@ func Filter(keepThreshold, keepSumThreshold float64) func(r Response) Response {
 			return Response{}
 		}
 		return Response{
-			Labels: response.Labels[:n+1],
-			Scores: response.Scores[:n+1],
+			Labels:       response.Labels[:n+1],
+			Scores:       response.Scores[:n+1],
+			Attributions: response.Attributions,
 		}
 	}
 }
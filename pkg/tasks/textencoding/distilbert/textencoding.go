@@ -3,17 +3,20 @@ package distilbert
 import (
 	"context"
 	"fmt"
+	"io"
 	"path"
 	"path/filepath"
 	"strings"
 
-	"github.com/nlpodyssey/spago/mat"
-
 	"github.com/nlpodyssey/cybertron/pkg/models/distilbert"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/diskstoremode"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sharedweights"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/timings"
 	"github.com/nlpodyssey/cybertron/pkg/tokenizers"
 	"github.com/nlpodyssey/cybertron/pkg/tokenizers/wordpiecetokenizer"
+	"github.com/nlpodyssey/cybertron/pkg/trace"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
 	"github.com/nlpodyssey/cybertron/pkg/vocabulary"
 	"github.com/nlpodyssey/spago/embeddings/store/diskstore"
 	"github.com/nlpodyssey/spago/nn"
@@ -29,8 +32,9 @@ type TextEncoding struct {
 	Tokenizer *wordpiecetokenizer.WordPieceTokenizer
 	// doLowerCase is a flag indicating if the model should lowercase the input before tokenization.
 	doLowerCase bool
-	// embeddingsRepo is the repository used for loading embeddings.
-	embeddingsRepo *diskstore.Repository
+	// release releases the encoder, with its embeddings, shared with the
+	// other models loaded from the same file.
+	release func() error
 }
 
 // LoadTextEncoding returns a TextEncoding loading the model, the embeddings and the tokenizer from a directory.
@@ -46,48 +50,66 @@ func LoadTextEncoding(modelPath string) (*TextEncoding, error) {
 		return nil, fmt.Errorf("failed to load tokenizer config for text encoding: %w", err)
 	}
 
-	embeddingsRepo, err := diskstore.NewRepository(filepath.Join(modelPath, "repo"), diskstoremode.DefaultDiskStoreMode)
+	m, err := nn.LoadFromFile[*distilbert.ModelForSequenceEncoding](path.Join(modelPath, "spago_model.bin"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to load embeddings repository for text encoding: %w", err)
+		return nil, fmt.Errorf("failed to load distilbert model: %w", err)
 	}
 
-	m, err := nn.LoadFromFile[*distilbert.ModelForSequenceEncoding](path.Join(modelPath, "spago_model.bin"))
+	key, err := sharedweights.Key(path.Join(modelPath, "spago_model.bin"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load distilbert model: %w", err)
 	}
-
-	err = m.DistilBert.SetEmbeddings(embeddingsRepo)
+	encoder, release, err := sharedweights.Acquire(key, func() (*distilbert.Model, io.Closer, error) {
+		embeddingsRepo, err := diskstore.NewRepository(filepath.Join(modelPath, "repo"), diskstoremode.DefaultDiskStoreMode)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load embeddings repository for text encoding: %w", err)
+		}
+		if err := m.DistilBert.SetEmbeddings(embeddingsRepo); err != nil {
+			_ = embeddingsRepo.Close()
+			return nil, nil, fmt.Errorf("failed to set embeddings: %w", err)
+		}
+		return m.DistilBert, embeddingsRepo, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to set embeddings: %w", err)
+		return nil, err
 	}
+	m.DistilBert = encoder
 
 	return &TextEncoding{
-		Model:          m,
-		Tokenizer:      tokenizer,
-		doLowerCase:    tokenizerConfig.DoLowerCase,
-		embeddingsRepo: embeddingsRepo,
+		Model:       m,
+		Tokenizer:   tokenizer,
+		doLowerCase: tokenizerConfig.DoLowerCase,
+		release:     release,
 	}, nil
 }
 
 // Close finalizes the TextEncoding resources.
 // It satisfies the interface io.Closer.
 func (m *TextEncoding) Close() error {
-	return m.embeddingsRepo.Close()
+	return m.release()
 }
 
 // Encode returns the dense encoded representation of the given text.
-func (m *TextEncoding) Encode(_ context.Context, text string, poolingStrategy int) (textencoding.Response, error) {
+func (m *TextEncoding) Encode(ctx context.Context, text string, poolingStrategy int) (textencoding.Response, error) {
+	end := timings.Start(ctx, timings.Tokenization)
 	tokenized := m.tokenize(text)
+	end()
 	if l, max := len(tokenized), m.Model.DistilBert.Config.MaxPositionEmbeddings; l > max {
 		return textencoding.Response{}, fmt.Errorf("%w: %d > %d", textencoding.ErrInputSequenceTooLong, l, max)
 	}
+	usage.AddTokens(ctx, len(tokenized), 0)
+	end = timings.Start(ctx, timings.Forward)
 	encoded, err := m.Model.Encode(tokenized, distilbert.PoolingStrategyType(poolingStrategy))
+	end()
+	trace.Encode(ctx, tokenized, m.Model.DistilBert.EncodeEach)
 	if err != nil {
 		return textencoding.Response{}, err
 	}
 
 	response := textencoding.Response{
-		Vector: mat.CopyValue(encoded),
+		// The value isn't shared with other requests: it's returned as
+		// is, instead of a copy.
+		Vector: encoded.Value(),
 	}
 	return response, nil
 }
@@ -101,3 +123,8 @@ func (m *TextEncoding) tokenize(text string) []string {
 	sep := wordpiecetokenizer.DefaultSequenceSeparator
 	return append([]string{cls}, append(tokenizers.GetStrings(m.Tokenizer.Tokenize(text)), sep)...)
 }
+
+// Tokenize returns the tokens of the given text, as seen by the model.
+func (m *TextEncoding) Tokenize(text string) []string {
+	return m.tokenize(text)
+}
@@ -0,0 +1,243 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/rs/zerolog/log"
+
+	"github.com/nlpodyssey/cybertron/pkg/logging"
+	"github.com/nlpodyssey/cybertron/pkg/models/bert/bertconfig"
+	onnxmodel "github.com/nlpodyssey/cybertron/pkg/onnx"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/timings"
+	"github.com/nlpodyssey/cybertron/pkg/tokenizers"
+	"github.com/nlpodyssey/cybertron/pkg/tokenizers/wordpiecetokenizer"
+	"github.com/nlpodyssey/cybertron/pkg/trace"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
+	"github.com/nlpodyssey/cybertron/pkg/vocabulary"
+)
+
+var _ textencoding.Interface = &TextEncoding{}
+
+// TextEncoding is a text encoding model executed by the ONNX runtime.
+type TextEncoding struct {
+	// Model is the ONNX model of the encoder.
+	Model *onnxmodel.Model
+	// Tokenizer is the tokenizer used to tokenize the input text.
+	Tokenizer *wordpiecetokenizer.WordPieceTokenizer
+	// Vocabulary is the vocabulary used to map the tokens to the input IDs.
+	Vocabulary *vocabulary.Vocabulary
+	// Config is the configuration of the encoder.
+	Config bertconfig.Config
+	// doLowerCase is a flag indicating if the model should lowercase the input before tokenization.
+	doLowerCase bool
+	// inputs builds the inputs of the model, with its special tokens and token types.
+	inputs bertconfig.Inputs
+	// maxLength is the maximum number of tokens of the input, extended by the RoPE scaling.
+	maxLength int
+}
+
+// LoadTextEncoding returns a TextEncoding loading the ONNX model and the tokenizer from a directory.
+func LoadTextEncoding(modelPath string) (*TextEncoding, error) {
+	return LoadTextEncodingFS(os.DirFS(modelPath))
+}
+
+// LoadTextEncodingFS returns a TextEncoding loading the ONNX model and the tokenizer from the root of a file system,
+// e.g. the files provided by the host when compiled to WebAssembly.
+func LoadTextEncodingFS(fsys fs.FS) (*TextEncoding, error) {
+	vocab, err := vocabulary.NewFromFS(fsys, "vocab.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vocabulary for text encoding: %w", err)
+	}
+
+	tokenizerConfig, err := bertconfig.ConfigFromFS[bertconfig.TokenizerConfig](fsys, "tokenizer_config.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tokenizer config for text encoding: %w", err)
+	}
+
+	config, err := bertconfig.ConfigFromFS[bertconfig.Config](fsys, "config.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config for text encoding: %w", err)
+	}
+
+	m, err := onnxmodel.LoadModelFS(fsys, onnxmodel.DefaultModelFilename)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	m.FuseAttention()
+
+	t := &TextEncoding{
+		Model:       m,
+		Tokenizer:   wordpiecetokenizer.New(vocab),
+		Vocabulary:  vocab,
+		Config:      config,
+		doLowerCase: tokenizerConfig.DoLowerCase,
+		inputs:      bertconfig.NewInputs(config, tokenizerConfig),
+		maxLength:   config.MaxPositionEmbeddings,
+	}
+	if rs := config.RopeScaling; rs != nil {
+		switch rs.Kind() {
+		case onnxmodel.RopeScalingLinear, onnxmodel.RopeScalingYaRN:
+			err = t.SetRopeScaling(onnxmodel.RopeScaling{
+				Type:                          rs.Kind(),
+				Factor:                        rs.Factor,
+				OriginalMaxPositionEmbeddings: rs.OriginalMaxPositionEmbeddings,
+				BetaFast:                      rs.BetaFast,
+				BetaSlow:                      rs.BetaSlow,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to scale the rotary position embeddings for text encoding: %w", err)
+			}
+		default:
+			log.Warn().Str("type", rs.Kind()).Msg("unsupported RoPE scaling of the model, ignored")
+		}
+	}
+	return t, nil
+}
+
+// Close finalizes the TextEncoding resources, releasing the weights of the model.
+// It satisfies the interface io.Closer.
+func (m *TextEncoding) Close() error {
+	return m.Model.Close()
+}
+
+// SetRopeScaling scales the rotary position embeddings of the model, replacing the scaling of its configuration,
+// if any, and extends the maximum length of the inputs by the factor. The original maximum length, if not set,
+// is the one of the configuration. It fails if the model has no rotary position embeddings.
+func (m *TextEncoding) SetRopeScaling(s onnxmodel.RopeScaling) error {
+	if s.OriginalMaxPositionEmbeddings == 0 {
+		s.OriginalMaxPositionEmbeddings = m.Config.MaxPositionEmbeddings
+		if rs := m.Config.RopeScaling; rs != nil && rs.OriginalMaxPositionEmbeddings > 0 {
+			s.OriginalMaxPositionEmbeddings = rs.OriginalMaxPositionEmbeddings
+		}
+	}
+	if err := m.Model.ScaleRoPE(s); err != nil {
+		return err
+	}
+	m.maxLength = m.Config.MaxPositionEmbeddings
+	if s.Type != "" {
+		m.maxLength = int(float64(s.OriginalMaxPositionEmbeddings) * s.Factor)
+	}
+	return nil
+}
+
+// Encode returns the dense encoded representation of the given text.
+func (m *TextEncoding) Encode(ctx context.Context, text string, poolingStrategy int) (textencoding.Response, error) {
+	end := timings.Start(ctx, timings.Tokenization)
+	tokenized := m.tokenize(text)
+	end()
+	if l, max := len(tokenized), m.maxLength; l > max {
+		return textencoding.Response{}, fmt.Errorf("%w: %d > %d", textencoding.ErrInputSequenceTooLong, l, max)
+	}
+	usage.AddTokens(ctx, len(tokenized), 0)
+	if trace.Enabled(ctx) {
+		trace.Record(ctx, trace.Input{Tokens: tokenized, TokenIDs: m.tokenIDs(tokenized), AttentionMask: trace.Ones(len(tokenized))})
+	}
+
+	end = timings.Start(ctx, timings.Forward)
+	outputs, stats, err := m.Model.RunWithStats(onnxmodel.EncoderInputs(m.Model.Graph, m.tokenIDs(tokenized)))
+	end()
+	logging.Ctx(ctx).Debug().Int("tokens", len(tokenized)).Int64("peak_memory", stats.PeakMemory).Err(err).Msg("onnx forward pass")
+	if err != nil {
+		return textencoding.Response{}, err
+	}
+
+	encoded, err := m.pooling(outputs, bertconfig.PoolingStrategyType(poolingStrategy))
+	if err != nil {
+		return textencoding.Response{}, err
+	}
+	return textencoding.Response{
+		Vector: mat.NewVecDense(encoded),
+	}, nil
+}
+
+// pooling computes the sequence representation from the outputs of the
+// encoder, which are expected to be "last_hidden_state" (or the first
+// output of the graph) and, optionally, "pooler_output".
+func (m *TextEncoding) pooling(outputs map[string]*onnxmodel.Tensor, ps bertconfig.PoolingStrategyType) ([]float32, error) {
+	hidden, ok := outputs["last_hidden_state"]
+	if !ok {
+		hidden = outputs[m.Model.Graph.Outputs[0]]
+	}
+	if hidden.Rank() != 3 {
+		return nil, fmt.Errorf("onnx: unexpected hidden states shape %v", hidden.Shape)
+	}
+	n, size := hidden.Shape[1], hidden.Shape[2]
+	states := hidden.AsFloats()
+
+	mean := func() []float32 {
+		out := make([]float32, size)
+		for i := 0; i < n; i++ {
+			for j, v := range states[i*size : (i+1)*size] {
+				out[j] += v / float32(n)
+			}
+		}
+		return out
+	}
+	max := func() []float32 {
+		out := append([]float32{}, states[:size]...)
+		for i := 1; i < n; i++ {
+			for j, v := range states[i*size : (i+1)*size] {
+				if v > out[j] {
+					out[j] = v
+				}
+			}
+		}
+		return out
+	}
+
+	switch ps {
+	case bertconfig.MeanPooling:
+		return mean(), nil
+	case bertconfig.MaxPooling:
+		return max(), nil
+	case bertconfig.MeanMaxPooling:
+		return append(mean(), max()...), nil
+	case bertconfig.ClsTokenPooling:
+		if pooled, ok := outputs["pooler_output"]; ok {
+			return pooled.AsFloats(), nil
+		}
+		return append([]float32{}, states[:size]...), nil
+	default:
+		return nil, fmt.Errorf("onnx: invalid pooling strategy")
+	}
+}
+
+// tokenIDs maps the tokens to their IDs, using the unknown token for the missing ones.
+func (m *TextEncoding) tokenIDs(tokens []string) []int {
+	unk, _ := m.Vocabulary.ID(wordpiecetokenizer.DefaultUnknownToken)
+	ids := make([]int, len(tokens))
+	for i, t := range tokens {
+		id, ok := m.Vocabulary.ID(t)
+		if !ok {
+			id = unk
+		}
+		ids[i] = id
+	}
+	return ids
+}
+
+// tokenize returns the tokens of the given text, with the special tokens of the model.
+func (m *TextEncoding) tokenize(text string) []string {
+	if m.doLowerCase {
+		text = strings.ToLower(text)
+	}
+	return m.inputs.Single(tokenizers.GetStrings(m.Tokenizer.Tokenize(text))).Tokens
+}
+
+// Tokenize returns the tokens of the given text, as seen by the model.
+func (m *TextEncoding) Tokenize(text string) []string {
+	return m.tokenize(text)
+}
@@ -6,8 +6,8 @@ package textencoding
 
 import (
 	"context"
-	"errors"
 
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
 	"github.com/nlpodyssey/spago/mat"
 )
 
@@ -27,9 +27,10 @@ const (
 
 // ErrInputSequenceTooLong means that pre-processing the input text
 // produced a sequence that exceeds the maximum allowed length.
-var ErrInputSequenceTooLong = errors.New("input sequence too long")
+var ErrInputSequenceTooLong = errdefs.ErrInputTooLong
 
 // Interface defines the main functions for text encoding task.
+// Its implementations are safe for concurrent use.
 type Interface interface {
 	// Encode returns the encoded representation of the given example.
 	Encode(ctx context.Context, text string, poolingStrategy int) (Response, error)
@@ -7,6 +7,8 @@ package bert
 import (
 	"context"
 	"fmt"
+	"io"
+	"math"
 	"path"
 	"path/filepath"
 	"strconv"
@@ -14,9 +16,14 @@ import (
 
 	"github.com/nlpodyssey/cybertron/pkg/models/bert"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/diskstoremode"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sharedweights"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+	"github.com/nlpodyssey/cybertron/pkg/timings"
 	"github.com/nlpodyssey/cybertron/pkg/tokenizers"
 	"github.com/nlpodyssey/cybertron/pkg/tokenizers/wordpiecetokenizer"
+	"github.com/nlpodyssey/cybertron/pkg/trace"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
+	"github.com/nlpodyssey/cybertron/pkg/utils/matview"
 	"github.com/nlpodyssey/cybertron/pkg/vocabulary"
 	"github.com/nlpodyssey/spago/ag"
 	"github.com/nlpodyssey/spago/embeddings/store/diskstore"
@@ -24,6 +31,10 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// multiLabelProblemType is the problem type of the configuration of the
+// models classifying each token with more labels.
+const multiLabelProblemType = "multi_label_classification"
+
 // TokenClassification is a token classification model.
 type TokenClassification struct {
 	// Model is the model used to answer questions.
@@ -32,10 +43,17 @@ type TokenClassification struct {
 	Tokenizer *wordpiecetokenizer.WordPieceTokenizer
 	// Labels is the list of labels used for classification.
 	Labels []string
+	// WindowOverlap is the number of tokens shared by the consecutive
+	// windows of the texts longer than the maximum length of the model.
+	WindowOverlap int
 	// doLowerCase is a flag indicating if the model should lowercase the input before tokenization.
 	doLowerCase bool
-	// embeddingsRepo is the repository used for loading embeddings.
-	embeddingsRepo *diskstore.Repository
+	// multiLabel is a flag indicating if the model scores each label of a
+	// token on its own, with a sigmoid, e.g. to classify nested entities.
+	multiLabel bool
+	// release releases the encoder, with its embeddings, shared with the
+	// other models loaded from the same file.
+	release func() error
 }
 
 // LoadTokenClassification returns a TokenClassification loading the model, the embeddings and the tokenizer from a directory.
@@ -57,27 +75,39 @@ func LoadTokenClassification(modelPath string) (*TokenClassification, error) {
 	}
 	labels := ID2Label(config.ID2Label)
 
-	embeddingsRepo, err := diskstore.NewRepository(filepath.Join(modelPath, "repo"), diskstoremode.DefaultDiskStoreMode)
+	m, err := nn.LoadFromFile[*bert.ModelForTokenClassification](path.Join(modelPath, "spago_model.bin"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to load embeddings repository for text classification: %w", err)
+		return nil, fmt.Errorf("failed to load bart model: %w", err)
 	}
 
-	m, err := nn.LoadFromFile[*bert.ModelForTokenClassification](path.Join(modelPath, "spago_model.bin"))
+	key, err := sharedweights.Key(path.Join(modelPath, "spago_model.bin"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load bart model: %w", err)
 	}
-
-	err = m.Bert.SetEmbeddings(embeddingsRepo)
+	encoder, release, err := sharedweights.Acquire(key, func() (*bert.Model, io.Closer, error) {
+		embeddingsRepo, err := diskstore.NewRepository(filepath.Join(modelPath, "repo"), diskstoremode.DefaultDiskStoreMode)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load embeddings repository for text classification: %w", err)
+		}
+		if err := m.Bert.SetEmbeddings(embeddingsRepo); err != nil {
+			_ = embeddingsRepo.Close()
+			return nil, nil, fmt.Errorf("failed to set embeddings: %w", err)
+		}
+		return m.Bert, embeddingsRepo, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to set embeddings: %w", err)
+		return nil, err
 	}
+	m.Bert = encoder
 
 	return &TokenClassification{
-		Model:          &ModelForTokenClassification{ModelForTokenClassification: m},
-		Tokenizer:      tokenizer,
-		Labels:         labels,
-		doLowerCase:    tokenizerConfig.DoLowerCase,
-		embeddingsRepo: embeddingsRepo,
+		Model:         &ModelForTokenClassification{ModelForTokenClassification: m},
+		Tokenizer:     tokenizer,
+		Labels:        labels,
+		WindowOverlap: tokenclassification.DefaultWindowOverlap,
+		doLowerCase:   tokenizerConfig.DoLowerCase,
+		multiLabel:    config.ProblemType == multiLabelProblemType,
+		release:       release,
 	}, nil
 }
 
@@ -99,32 +129,66 @@ func ID2Label(value map[string]string) []string {
 // Close finalizes the TokenClassification resources.
 // It satisfies the interface io.Closer.
 func (m *TokenClassification) Close() error {
-	return m.embeddingsRepo.Close()
+	return m.release()
 }
 
-// Classify returns the classification of the given text.
-func (m *TokenClassification) Classify(_ context.Context, text string, parameters tokenclassification.Parameters) (tokenclassification.Response, error) {
+// Classify returns the classification of the given text. The texts longer
+// than the maximum length of the model are classified in overlapping
+// windows (see tokenclassification.Windows), the entities spanning their
+// boundaries merged by the aggregation. The labels of the models with a CRF
+// layer are the most likely sequence of labels of the text, decoded with
+// the Viterbi algorithm, instead of the best label of each token. The
+// nested entities are the ones of the models with the
+// "multi_label_classification" problem type, whose tokens have all the
+// labels scored above 0.5; the tokens of the other models have their best
+// label only.
+func (m *TokenClassification) Classify(ctx context.Context, text string, parameters tokenclassification.Parameters) (tokenclassification.Response, error) {
+	end := timings.Start(ctx, timings.Tokenization)
 	tokenized := m.tokenize(text)
-	if l, max := len(tokenized), m.Model.Bert.Config.MaxPositionEmbeddings; l > max {
-		return tokenclassification.Response{}, fmt.Errorf("%w: %d > %d", tokenclassification.ErrInputSequenceTooLong, l, max)
+	end()
+	words := wordLengths(tokenized)
+	windows, err := tokenclassification.Windows(words, m.Model.Bert.Config.MaxPositionEmbeddings-2, m.WindowOverlap)
+	if err != nil {
+		return tokenclassification.Response{}, err
 	}
 
-	logits := m.Model.Classify(pad(tokenizers.GetStrings(tokenized)))
+	end = timings.Start(ctx, timings.Forward)
+	logits := m.classifyWindows(ctx, tokenizers.GetStrings(tokenized), words, windows)
+	var classes []int
+	if m.Model.CRF != nil {
+		// The labels of the whole text, across the windows.
+		classes = m.Model.Decode(logits)
+	}
+	end()
+	offsets := tokenizers.NewRuneOffsets(text)
 	tokens := make([]tokenclassification.Token, 0, len(tokenized))
+	var candidates [][]tokenclassification.Candidate
 	for i, token := range wordpiecetokenizer.GroupSubWords(tokenized) {
 		label, score := m.getBestClass(logits[i])
+		if classes != nil {
+			label, score = m.Labels[classes[i]], matview.SoftmaxAt(logits[i].Value(), classes[i])
+		}
+		if parameters.AggregationStrategy == tokenclassification.AggregationStrategyNested {
+			candidates = append(candidates, m.getCandidates(logits[i], label, score))
+		}
 
+		b := offsets.Bytes(token.Offsets)
 		tokens = append(tokens, tokenclassification.Token{
-			Text:  text[token.Offsets.Start:token.Offsets.End],
-			Start: token.Offsets.Start,
-			End:   token.Offsets.End,
-			Label: label,
-			Score: score,
+			Text:      text[b.Start:b.End],
+			Start:     token.Offsets.Start,
+			End:       token.Offsets.End,
+			ByteStart: b.Start,
+			ByteEnd:   b.End,
+			Label:     label,
+			Score:     score,
 		})
 	}
 
-	if parameters.AggregationStrategy == tokenclassification.AggregationStrategySimple {
+	switch parameters.AggregationStrategy {
+	case tokenclassification.AggregationStrategySimple:
 		tokens = tokenclassification.FilterNotEntities(tokenclassification.Aggregate(tokens))
+	case tokenclassification.AggregationStrategyNested:
+		tokens = tokenclassification.AggregateNested(tokens, candidates)
 	}
 
 	response := tokenclassification.Response{
@@ -133,14 +197,72 @@ func (m *TokenClassification) Classify(_ context.Context, text string, parameter
 	return response, nil
 }
 
+// classifyWindows returns the logits of each word, classifying the windows
+// of its words on their own, with the logits of the window owning each word
+// (see tokenclassification.Owners).
+func (m *TokenClassification) classifyWindows(ctx context.Context, tokens []string, words []int, windows []tokenclassification.Window) []ag.Node {
+	// starts are the indices of the first token of each word.
+	starts := make([]int, len(words)+1)
+	for i, n := range words {
+		starts[i+1] = starts[i] + n
+	}
+	owners := tokenclassification.Owners(windows)
+	logits := make([]ag.Node, len(words))
+	for wi, w := range windows {
+		padded := pad(tokens[starts[w.Start]:starts[w.End]])
+		usage.AddTokens(ctx, starts[w.End]-starts[w.Start], 0)
+		wl := m.Model.Classify(padded)
+		trace.Encode(ctx, padded, m.Model.Bert.EncodeEach)
+		for i := w.Start; i < w.End && i-w.Start < len(wl); i++ {
+			if owners[i] == wi {
+				logits[i] = wl[i-w.Start]
+			}
+		}
+	}
+	return logits
+}
+
+// wordLengths returns the number of tokens of each word, split in
+// sub-words prefixed by "##".
+func wordLengths(tokens []tokenizers.StringOffsetsPair) []int {
+	words := make([]int, 0, len(tokens))
+	for _, t := range tokens {
+		if len(words) > 0 && strings.HasPrefix(t.String, wordpiecetokenizer.DefaultSplitPrefix) {
+			words[len(words)-1]++
+			continue
+		}
+		words = append(words, 1)
+	}
+	return words
+}
+
+// getBestClass returns the label of the largest logit, with its
+// probability, reading the logits through a view, without allocating.
 func (m *TokenClassification) getBestClass(logits ag.Node) (label string, score float64) {
-	probs := logits.Value().Softmax()
-	argmax := probs.ArgMax()
-	score = probs.AtVec(argmax).Scalar().F64()
+	v := logits.Value()
+	argmax := matview.ArgMax(v)
+	score = matview.SoftmaxAt(v, argmax)
 	label = m.Labels[argmax]
 	return
 }
 
+// getCandidates returns the labels of a token for the nested entities:
+// the ones scored above 0.5 by the sigmoid of their logits, for the
+// multi-label models, or the best one otherwise.
+func (m *TokenClassification) getCandidates(logits ag.Node, label string, score float64) []tokenclassification.Candidate {
+	if !m.multiLabel {
+		return []tokenclassification.Candidate{{Label: label, Score: score}}
+	}
+	v := logits.Value()
+	var candidates []tokenclassification.Candidate
+	for i, l := range m.Labels {
+		if p := 1 / (1 + math.Exp(-matview.At(v, i))); p > 0.5 {
+			candidates = append(candidates, tokenclassification.Candidate{Label: l, Score: p})
+		}
+	}
+	return candidates
+}
+
 // tokenize returns the tokens of the given text (without padding tokens).
 func (m *TokenClassification) tokenize(text string) []tokenizers.StringOffsetsPair {
 	if m.doLowerCase {
@@ -149,6 +271,11 @@ func (m *TokenClassification) tokenize(text string) []tokenizers.StringOffsetsPa
 	return m.Tokenizer.Tokenize(text)
 }
 
+// Tokenize returns the tokens of the given text, as seen by the model.
+func (m *TokenClassification) Tokenize(text string) []string {
+	return tokenizers.GetStrings(m.tokenize(text))
+}
+
 func pad(tokens []string) []string {
 	return append(prepend(tokens, wordpiecetokenizer.DefaultClassToken), wordpiecetokenizer.DefaultSequenceSeparator)
 }
@@ -14,8 +14,11 @@ import (
 	"github.com/nlpodyssey/cybertron/pkg/models/flair"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/diskstoremode"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+	"github.com/nlpodyssey/cybertron/pkg/timings"
 	"github.com/nlpodyssey/cybertron/pkg/tokenizers"
 	"github.com/nlpodyssey/cybertron/pkg/tokenizers/basetokenizer"
+	"github.com/nlpodyssey/cybertron/pkg/trace"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
 	"github.com/nlpodyssey/spago/embeddings/store/diskstore"
 	"github.com/nlpodyssey/spago/nn"
 	"github.com/rs/zerolog/log"
@@ -86,24 +89,44 @@ func (m *TokenClassification) Close() error {
 }
 
 // Classify returns the classification of the given text.
-func (m *TokenClassification) Classify(_ context.Context, text string, parameters tokenclassification.Parameters) (tokenclassification.Response, error) {
+func (m *TokenClassification) Classify(ctx context.Context, text string, parameters tokenclassification.Parameters) (tokenclassification.Response, error) {
+	end := timings.Start(ctx, timings.Tokenization)
 	tokenized := m.tokenize(text)
+	end()
+	usage.AddTokens(ctx, len(tokenized), 0)
+	if trace.Enabled(ctx) {
+		trace.Record(ctx, trace.Input{Tokens: tokenizers.GetStrings(tokenized)})
+	}
 
+	end = timings.Start(ctx, timings.Forward)
 	classes, scores := m.Model.Forward(tokenizers.GetStrings(tokenized))
+	end()
 
+	offsets := tokenizers.NewRuneOffsets(text)
 	tokens := make([]tokenclassification.Token, 0, len(tokenized))
 	for i, token := range tokenized {
+		b := offsets.Bytes(token.Offsets)
 		tokens = append(tokens, tokenclassification.Token{
-			Text:  text[token.Offsets.Start:token.Offsets.End],
-			Start: token.Offsets.Start,
-			End:   token.Offsets.End,
-			Label: m.Labels[classes[i]],
-			Score: scores[i],
+			Text:      text[b.Start:b.End],
+			Start:     token.Offsets.Start,
+			End:       token.Offsets.End,
+			ByteStart: b.Start,
+			ByteEnd:   b.End,
+			Label:     m.Labels[classes[i]],
+			Score:     scores[i],
 		})
 	}
 
-	if parameters.AggregationStrategy == tokenclassification.AggregationStrategySimple {
+	switch parameters.AggregationStrategy {
+	case tokenclassification.AggregationStrategySimple:
 		tokens = tokenclassification.FilterNotEntities(tokenclassification.Aggregate(tokens))
+	case tokenclassification.AggregationStrategyNested:
+		// The tokens have a label only: the entities can't overlap.
+		candidates := make([][]tokenclassification.Candidate, len(tokens))
+		for i, t := range tokens {
+			candidates[i] = []tokenclassification.Candidate{{Label: t.Label, Score: t.Score}}
+		}
+		tokens = tokenclassification.AggregateNested(tokens, candidates)
 	}
 
 	response := tokenclassification.Response{
@@ -116,3 +139,8 @@ func (m *TokenClassification) Classify(_ context.Context, text string, parameter
 func (m *TokenClassification) tokenize(text string) []tokenizers.StringOffsetsPair {
 	return m.Tokenizer.Tokenize(text)
 }
+
+// Tokenize returns the tokens of the given text, as seen by the model.
+func (m *TokenClassification) Tokenize(text string) []string {
+	return tokenizers.GetStrings(m.tokenize(text))
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tokenclassification
+
+import "sort"
+
+// Candidate is a label of a token, with its probability, among the ones of
+// the models classifying each token with more labels, e.g. a "B-LOC" token
+// in a "B-ORG" one for "University of Rome".
+type Candidate struct {
+	Label string
+	Score float64
+}
+
+// AggregateNested groups the tokens in entities, according to the IOB
+// annotation schema of each entity type on its own, given the candidate
+// labels of each token: the entities of different types can be nested in,
+// or overlap with, each other. They're sorted by their start, the enclosing
+// ones first, each one with the number of entities enclosing it as Depth.
+func AggregateNested(tokens []Token, candidates [][]Candidate) []Token {
+	var types []string
+	seen := make(map[string]bool)
+	for _, cs := range candidates {
+		for _, c := range cs {
+			if t := StripPrefix(c.Label); t != "" && !seen[t] {
+				seen[t] = true
+				types = append(types, t)
+			}
+		}
+	}
+
+	var entities []Token
+	for _, typ := range types {
+		seq := make([]Token, len(tokens))
+		for i, t := range tokens {
+			t.Label, t.Score = "O", 0
+			for _, c := range candidates[i] {
+				if StripPrefix(c.Label) == typ {
+					t.Label, t.Score = c.Label, c.Score
+					break
+				}
+			}
+			seq[i] = t
+		}
+		entities = append(entities, FilterNotEntities(Aggregate(seq))...)
+	}
+
+	sort.SliceStable(entities, func(i, j int) bool {
+		if entities[i].Start != entities[j].Start {
+			return entities[i].Start < entities[j].Start
+		}
+		return entities[i].End > entities[j].End
+	})
+	for i := range entities {
+		for _, e := range entities[:i] {
+			if e.Start <= entities[i].Start && e.End >= entities[i].End {
+				entities[i].Depth++
+			}
+		}
+	}
+	return entities
+}
@@ -0,0 +1,35 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tokenclassification
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAggregateNested(t *testing.T) {
+	tokens := []Token{
+		{Text: "University", Start: 0, End: 10},
+		{Text: "of", Start: 11, End: 13},
+		{Text: "Rome", Start: 14, End: 18},
+		{Text: "Tor", Start: 19, End: 22},
+		{Text: "Vergata", Start: 23, End: 30},
+	}
+	candidates := [][]Candidate{
+		{{Label: "B-ORG", Score: 0.9}},
+		{{Label: "I-ORG", Score: 0.8}},
+		{{Label: "I-ORG", Score: 0.8}, {Label: "B-LOC", Score: 0.7}},
+		{{Label: "I-ORG", Score: 0.6}, {Label: "B-LOC", Score: 0.6}},
+		{{Label: "I-ORG", Score: 0.6}, {Label: "I-LOC", Score: 0.5}},
+	}
+	want := []Token{
+		{Text: "University of Rome Tor Vergata", Start: 0, End: 30, Label: "ORG", Score: 0.9},
+		{Text: "Rome", Start: 14, End: 18, Label: "LOC", Score: 0.7, Depth: 1},
+		{Text: "Tor Vergata", Start: 19, End: 30, Label: "LOC", Score: 0.6, Depth: 1},
+	}
+	if got := AggregateNested(tokens, candidates); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
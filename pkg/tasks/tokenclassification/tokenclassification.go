@@ -6,7 +6,8 @@ package tokenclassification
 
 import (
 	"context"
-	"errors"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
 )
 
 const (
@@ -30,29 +31,40 @@ const (
 
 	// AggregationStrategySimple - Entities are grouped according to the IOB annotation schema.
 	AggregationStrategySimple AggregationStrategy = "simple"
+
+	// AggregationStrategyNested - Entities are grouped according to the IOB annotation schema of each entity
+	// type on its own, so that they can be nested in, or overlap with, the entities of the other types
+	// (see AggregateNested).
+	AggregationStrategyNested AggregationStrategy = "nested"
 )
 
 // ErrInputSequenceTooLong means that pre-processing the input text
 // produced a sequence that exceeds the maximum allowed length.
-var ErrInputSequenceTooLong = errors.New("input sequence too long")
+var ErrInputSequenceTooLong = errdefs.ErrInputTooLong
 
 type Parameters struct {
 	AggregationStrategy AggregationStrategy
 }
 
 // Interface defines the main functions for token classification task.
+// Its implementations are safe for concurrent use.
 type Interface interface {
 	// Classify returns the classification of the given example.
 	Classify(ctx context.Context, text string, parameters Parameters) (Response, error)
 }
 
-// Token is a labeled text token.
+// Token is a labeled text token. Start and End are its offsets in the text
+// in runes, ByteStart and ByteEnd in bytes. Depth is the number of entities
+// enclosing it, with AggregationStrategyNested.
 type Token struct {
-	Text  string
-	Start int
-	End   int
-	Label string
-	Score float64
+	Text      string
+	Start     int
+	End       int
+	Label     string
+	Score     float64
+	ByteStart int
+	ByteEnd   int
+	Depth     int
 }
 
 // Response contains the response from token classification.
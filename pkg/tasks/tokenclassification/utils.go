@@ -4,7 +4,10 @@
 
 package tokenclassification
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 func FilterNotEntities(tokens []Token) []Token {
 	ret := make([]Token, 0)
@@ -52,19 +55,23 @@ func (a *aggregator) canAggregate(prefix byte) bool {
 func (a *aggregator) aggregate(t Token) {
 	last := &a.tokens[len(a.tokens)-1]
 	last.End = t.End
+	last.ByteEnd = t.ByteEnd
 	last.Text = fmt.Sprintf("%s %s", last.Text, t.Text)
 }
 
 func (a *aggregator) append(t Token) {
-	t.Label = stripPrefix(t.Label)
+	t.Label = StripPrefix(t.Label)
 	a.tokens = append(a.tokens, t)
 }
 
-func stripPrefix(label string) string {
+// StripPrefix returns the label without its IOB (or BIOES, BILOU) prefix,
+// e.g. "PER" of "B-PER", or the empty string for the outside label "O".
+// The labels without a prefix are returned as they are.
+func StripPrefix(label string) string {
 	switch {
 	case label == "O": // outside
 		return ""
-	case len(label) > 2:
+	case len(label) > 2 && label[1] == '-' && strings.IndexByte("BIESLU", label[0]) >= 0:
 		return label[2:]
 	default:
 		return label
@@ -41,6 +41,17 @@ func TestAggregate(t *testing.T) {
 	}
 
 	tests := []testCase{
+		// Offsets in runes and in bytes
+		{
+			input: []Token{
+				{Text: "São", Start: 0, End: 3, ByteStart: 0, ByteEnd: 4, Label: "B-LOC"},
+				{Text: "Paulo", Start: 4, End: 9, ByteStart: 5, ByteEnd: 10, Label: "I-LOC"},
+			},
+			want: []Token{
+				{Text: "São Paulo", Start: 0, End: 9, ByteStart: 0, ByteEnd: 10, Label: "LOC"},
+			},
+		},
+
 		// IOB
 		{
 			input: []Token{
@@ -246,3 +257,26 @@ func TestAggregate(t *testing.T) {
 		}
 	}
 }
+
+func TestStripPrefix(t *testing.T) {
+	tests := []struct {
+		label, want string
+	}{
+		{"B-PER", "PER"},
+		{"I-PER", "PER"},
+		{"E-EOS", "EOS"},
+		{"S-NOUN", "NOUN"},
+		{"L-LOC", "LOC"},
+		{"U-ORG", "ORG"},
+		{"O", ""},
+		{"NOUN", "NOUN"},
+		{"X-NOUN", "X-NOUN"},
+		{"B-", "B-"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := StripPrefix(tt.label); got != tt.want {
+			t.Errorf("StripPrefix(%q) = %q, want %q", tt.label, got, tt.want)
+		}
+	}
+}
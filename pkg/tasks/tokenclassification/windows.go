@@ -0,0 +1,84 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tokenclassification
+
+import "fmt"
+
+// DefaultWindowOverlap is the default number of tokens shared by two
+// consecutive windows of a document longer than the maximum length of the
+// model (see Windows).
+const DefaultWindowOverlap = 128
+
+// Window is a range of the words of a document, from Start (included) to
+// End (excluded), classified on its own.
+type Window struct {
+	Start int
+	End   int
+}
+
+// Windows splits the words of a document, given their number of tokens,
+// into windows of at most size tokens, each one starting before the end
+// of the previous one, so that they share about overlap tokens: the words
+// close to the edge of a window are classified with the context of the
+// next one too (see Owners). The overlap is at most half the size. The
+// windows don't split the words; it fails with ErrInputSequenceTooLong if
+// a word alone is longer than the size.
+func Windows(words []int, size, overlap int) ([]Window, error) {
+	if overlap > size/2 {
+		overlap = size / 2
+	}
+	var windows []Window
+	for start := 0; start < len(words); {
+		end, n := start, 0
+		for end < len(words) && n+words[end] <= size {
+			n += words[end]
+			end++
+		}
+		if end == start {
+			return nil, fmt.Errorf("%w: word of %d tokens > %d", ErrInputSequenceTooLong, words[start], size)
+		}
+		windows = append(windows, Window{Start: start, End: end})
+		if end == len(words) {
+			break
+		}
+		// The next window starts after the words of the last window
+		// covering the overlap, after one word at least.
+		next, shared := end, 0
+		for next-1 > start && shared+words[next-1] <= overlap {
+			next--
+			shared += words[next]
+		}
+		start = next
+	}
+	return windows, nil
+}
+
+// Owners returns, for each word of the document, the index of the window
+// whose classification of it is used: the one where the word is farthest
+// from the edges, with the most context on both sides. The words shared by
+// two windows are thus split at the middle of their overlap, and the
+// entities spanning it are merged by the aggregation of the labels.
+func Owners(windows []Window) []int {
+	if len(windows) == 0 {
+		return nil
+	}
+	owners := make([]int, windows[len(windows)-1].End)
+	distances := make([]int, len(owners))
+	for i := range distances {
+		distances[i] = -1
+	}
+	for wi, w := range windows {
+		for i := w.Start; i < w.End; i++ {
+			d := i - w.Start
+			if r := w.End - 1 - i; r < d {
+				d = r
+			}
+			if d > distances[i] {
+				owners[i], distances[i] = wi, d
+			}
+		}
+	}
+	return owners
+}
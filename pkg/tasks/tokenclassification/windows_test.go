@@ -0,0 +1,45 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tokenclassification
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestWindows(t *testing.T) {
+	words := []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+	windows, err := Windows(words, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Window{{0, 4}, {2, 6}, {4, 8}, {6, 10}}
+	if !reflect.DeepEqual(windows, want) {
+		t.Errorf("got windows %v, want %v", windows, want)
+	}
+	owners := Owners(windows)
+	wantOwners := []int{0, 0, 0, 1, 1, 2, 2, 3, 3, 3}
+	if !reflect.DeepEqual(owners, wantOwners) {
+		t.Errorf("got owners %v, want %v", owners, wantOwners)
+	}
+
+	// The windows don't split the words.
+	windows, err = Windows([]int{2, 3, 1}, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Window{{0, 1}, {1, 3}}; !reflect.DeepEqual(windows, want) {
+		t.Errorf("got windows %v, want %v", windows, want)
+	}
+
+	if windows, _ := Windows([]int{3, 1}, 8, 2); len(windows) != 1 {
+		t.Errorf("got %d windows of a short text, want 1", len(windows))
+	}
+
+	if _, err := Windows([]int{1, 5}, 4, 2); !errors.Is(err, ErrInputSequenceTooLong) {
+		t.Errorf("got error %v, want ErrInputSequenceTooLong", err)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks
+
+import "github.com/nlpodyssey/cybertron/pkg/textnorm"
+
+// Tokenizer is implemented by the models which expose how the text is split
+// into tokens before being processed, e.g. to inspect it while experimenting
+// with a model.
+type Tokenizer interface {
+	// Tokenize returns the tokens of the given text, as seen by the model.
+	Tokenize(text string) []string
+}
+
+// AsTokenizer returns the model as a Tokenizer, if it exposes its tokens.
+// For a model loaded with replicas, the first replica is considered. For a
+// model normalizing its input texts, the texts are normalized likewise. A
+// model loaded lazily is loaded first (see Config.Lazy).
+func AsTokenizer(m any) (Tokenizer, bool) {
+	var opts textnorm.Options
+	for {
+		if n, ok := m.(interface{ Normalization() textnorm.Options }); ok {
+			opts = n.Normalization()
+		}
+		if t, ok := m.(Tokenizer); ok {
+			if !opts.IsZero() {
+				t = normalizedTokenizer{t, opts}
+			}
+			return t, true
+		}
+		var ok bool
+		if m, ok = unwrapResolved(m); !ok {
+			return nil, false
+		}
+	}
+}
+
+// normalizedTokenizer normalizes the texts before their tokenization.
+type normalizedTokenizer struct {
+	Tokenizer
+	opts textnorm.Options
+}
+
+func (t normalizedTokenizer) Tokenize(text string) []string {
+	return t.Tokenizer.Tokenize(textnorm.Normalize(text, t.opts).Text)
+}
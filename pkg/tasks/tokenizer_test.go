@@ -0,0 +1,58 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tasks_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/textnorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fieldsTokenizer splits the texts on whitespace.
+type fieldsTokenizer struct{}
+
+func (fieldsTokenizer) Tokenize(text string) []string { return strings.Fields(text) }
+
+// wrapper wraps a model, e.g. as the replicas do.
+type wrapper struct{ m any }
+
+func (w wrapper) Unwrap() any { return w.m }
+
+// normalizing wraps a model normalizing its input texts.
+type normalizing struct {
+	wrapper
+	opts textnorm.Options
+}
+
+func (n normalizing) Normalization() textnorm.Options { return n.opts }
+
+func TestAsTokenizer(t *testing.T) {
+	tests := []struct {
+		name  string
+		model any
+		want  []string // nil if the model doesn't expose its tokens
+	}{
+		{"tokenizer", fieldsTokenizer{}, []string{"The", "Cat"}},
+		{"wrapped", wrapper{wrapper{fieldsTokenizer{}}}, []string{"The", "Cat"}},
+		{"normalized", normalizing{wrapper{fieldsTokenizer{}}, textnorm.Options{Lowercase: true}}, []string{"the", "cat"}},
+		{"not a tokenizer", struct{}{}, nil},
+		{"wrapped not a tokenizer", wrapper{struct{}{}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok, ok := tasks.AsTokenizer(tt.model)
+			if tt.want == nil {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.want, tok.Tokenize("The Cat"))
+		})
+	}
+}
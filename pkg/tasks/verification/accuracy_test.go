@@ -0,0 +1,93 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package verification_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/downloader"
+	"github.com/nlpodyssey/cybertron/pkg/tasks"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/verification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const accuracyFixturesDir = "testdata/accuracy"
+
+// TestAccuracy compares the outputs of the models of the matrix with the
+// reference outputs of the transformers library recorded in the fixtures.
+//
+// It downloads and converts the models, so it only runs with the env var
+// TEST_ACCURACY set (see "make accuracy"); the models are kept in the
+// directory TEST_ACCURACY_MODELS_DIR, if set. With TEST_ACCURACY_UPDATE
+// set, the fixtures are recorded again with the Hugging Face Inference API.
+func TestAccuracy(t *testing.T) {
+	if os.Getenv("TEST_ACCURACY") == "" {
+		// We don't want to download and convert the models every time the tests run.
+		t.Skip("skipping test - set env var TEST_ACCURACY to run this test")
+	}
+	matrix, err := verification.ReadMatrix(filepath.Join(accuracyFixturesDir, "matrix.json"))
+	require.NoError(t, err)
+
+	modelsDir := os.Getenv("TEST_ACCURACY_MODELS_DIR")
+	if modelsDir == "" {
+		modelsDir = t.TempDir()
+	}
+	update := os.Getenv("TEST_ACCURACY_UPDATE") != ""
+
+	for _, c := range matrix.Cases {
+		c := c
+		t.Run(c.Name(), func(t *testing.T) {
+			if update {
+				recordFixture(t, c)
+			}
+			ref, err := verification.ReadFixture(accuracyFixturesDir, c)
+			if os.IsNotExist(err) {
+				t.Skipf("no fixture for %s - set env var TEST_ACCURACY_UPDATE to record it", c.Name())
+			}
+			require.NoError(t, err)
+
+			model := loadModel(t, modelsDir, c)
+			report, err := verification.Verify(context.Background(), model, ref)
+			require.NoError(t, err)
+			assert.True(t, report.Passed(), report.String())
+		})
+	}
+}
+
+func recordFixture(t *testing.T, c verification.Case) {
+	fetch := verification.FetchReference
+	if c.Task == verification.TaskTextClassification {
+		fetch = verification.FetchClassificationReference
+	}
+	ref, err := fetch(context.Background(), c.Model, downloader.ResolveAccessToken(""))
+	require.NoError(t, err)
+	require.NoError(t, verification.WriteFixture(accuracyFixturesDir, c, ref))
+}
+
+func loadModel(t *testing.T, modelsDir string, c verification.Case) any {
+	conf := &tasks.Config{ModelsDir: modelsDir, ModelName: c.Model}
+	if c.Backend != "" {
+		backend, err := tasks.ParseBackend(c.Backend)
+		require.NoError(t, err)
+		conf.Backend = backend
+	}
+
+	var model any
+	var err error
+	switch c.Task {
+	case verification.TaskTextEncoding:
+		model, err = tasks.Load[textencoding.Interface](conf)
+	case verification.TaskTextClassification:
+		model, err = tasks.Load[textclassification.Interface](conf)
+	}
+	require.NoError(t, err)
+	return model
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package verification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// featureExtractionURL is the URL of the feature-extraction pipeline of the
+// Hugging Face Inference API, in the format:
+// "https://api-inference.huggingface.co/pipeline/feature-extraction/{model_id}"
+const featureExtractionURL = "https://api-inference.huggingface.co/pipeline/feature-extraction/%s"
+
+// textClassificationURL is the URL of the text-classification pipeline of
+// the Hugging Face Inference API, in the format:
+// "https://api-inference.huggingface.co/pipeline/text-classification/{model_id}"
+const textClassificationURL = "https://api-inference.huggingface.co/pipeline/text-classification/%s"
+
+// meanPooling is the value of the bert.MeanPooling strategy.
+const meanPooling = 1
+
+// FetchReference computes the text encoding reference outputs of the
+// canonical inputs with the Hugging Face Inference API.
+//
+// The API returns either the sentence embeddings (sentence-transformers
+// models) or the last hidden states of each token, which are averaged:
+// in both cases the outputs are compared with the mean pooling strategy.
+func FetchReference(ctx context.Context, modelName, accessToken string) (*Reference, error) {
+	outputs, err := fetchOutputs(ctx, fmt.Sprintf(featureExtractionURL, modelName), accessToken, nil)
+	if err != nil {
+		return nil, err
+	}
+	ref := &Reference{Task: TaskTextEncoding, PoolingStrategy: meanPooling}
+	for i, output := range outputs {
+		vector, err := decodeFeatures(output)
+		if err != nil {
+			return nil, fmt.Errorf("verification: inference API: %w", err)
+		}
+		ref.Examples = append(ref.Examples, Example{Input: CanonicalInputs[i], Vector: vector})
+	}
+	return ref, nil
+}
+
+// FetchClassificationReference computes the text classification reference
+// outputs of the canonical inputs with the Hugging Face Inference API,
+// i.e. the probabilities of all the labels of the model.
+func FetchClassificationReference(ctx context.Context, modelName, accessToken string) (*Reference, error) {
+	params := map[string]any{"top_k": nil}
+	outputs, err := fetchOutputs(ctx, fmt.Sprintf(textClassificationURL, modelName), accessToken, params)
+	if err != nil {
+		return nil, err
+	}
+	ref := &Reference{Task: TaskTextClassification}
+	for i, output := range outputs {
+		var labels []struct {
+			Label string  `json:"label"`
+			Score float64 `json:"score"`
+		}
+		if err := json.Unmarshal(output, &labels); err != nil {
+			return nil, fmt.Errorf("verification: inference API: %w", err)
+		}
+		scores := make(map[string]float64, len(labels))
+		for _, l := range labels {
+			scores[l.Label] = l.Score
+		}
+		ref.Examples = append(ref.Examples, Example{Input: CanonicalInputs[i], Scores: scores})
+	}
+	return ref, nil
+}
+
+// fetchOutputs runs the canonical inputs through a pipeline of the Inference
+// API, returning the output of each input.
+func fetchOutputs(ctx context.Context, url, accessToken string, params map[string]any) ([]json.RawMessage, error) {
+	payload := map[string]any{
+		"inputs":  CanonicalInputs,
+		"options": map[string]any{"wait_for_model": true},
+	}
+	if params != nil {
+		payload["parameters"] = params
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("verification: inference API: %s: %s", resp.Status, data)
+	}
+
+	var outputs []json.RawMessage
+	if err := json.Unmarshal(data, &outputs); err != nil {
+		return nil, fmt.Errorf("verification: inference API: %w", err)
+	}
+	if len(outputs) != len(CanonicalInputs) {
+		return nil, fmt.Errorf("verification: inference API: expected %d outputs, got %d", len(CanonicalInputs), len(outputs))
+	}
+	return outputs, nil
+}
+
+// decodeFeatures decodes a sentence embedding, or the mean of the token
+// embeddings.
+func decodeFeatures(data json.RawMessage) ([]float64, error) {
+	var vector []float64
+	if err := json.Unmarshal(data, &vector); err == nil {
+		return vector, nil
+	}
+	var tokens [][]float64
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty features")
+	}
+	mean := make([]float64, len(tokens[0]))
+	for _, t := range tokens {
+		if len(t) != len(mean) {
+			return nil, fmt.Errorf("inconsistent features size")
+		}
+		for j, v := range t {
+			mean[j] += v
+		}
+	}
+	for j := range mean {
+		mean[j] /= float64(len(tokens))
+	}
+	return mean, nil
+}
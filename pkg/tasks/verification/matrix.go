@@ -0,0 +1,106 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package verification
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Matrix is a matrix of models and tasks whose outputs are checked against
+// the reference outputs of the transformers library, recorded as fixtures,
+// to catch the numerical drift of the runtimes, e.g. when a kernel changes
+// (see the accuracy test, run with "make accuracy").
+type Matrix struct {
+	// Cases are the models, each with a task and a backend.
+	Cases []Case `json:"cases"`
+}
+
+// Case is a model checked with a task and a backend.
+type Case struct {
+	// Model is the name of the model on the Hugging Face Hub.
+	Model string `json:"model"`
+	// Task is the task of the reference outputs (see Reference.Task).
+	Task string `json:"task"`
+	// Backend is the backend running the model (default "spago").
+	Backend string `json:"backend,omitempty"`
+	// Tolerance is the maximum absolute divergence allowed, replacing the
+	// one of the reference (optional).
+	Tolerance float64 `json:"tolerance,omitempty"`
+}
+
+// Name returns the name of the case, e.g. the one of its subtest.
+func (c Case) Name() string {
+	backend := c.Backend
+	if backend == "" {
+		backend = "spago"
+	}
+	return fmt.Sprintf("%s/%s/%s", c.Model, c.Task, backend)
+}
+
+// FixtureFilename returns the file of the reference outputs of the case in
+// the fixtures directory. The references are the ones of the transformers
+// library, so they're shared by the backends.
+func (c Case) FixtureFilename(dir string) string {
+	return filepath.Join(dir, fmt.Sprintf("%s.%s.json", strings.ReplaceAll(c.Model, "/", "__"), c.Task))
+}
+
+// ReadMatrix reads the matrix from the JSON file.
+func ReadMatrix(filename string) (*Matrix, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	m := &Matrix{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("verification: invalid matrix file: %w", err)
+	}
+	for _, c := range m.Cases {
+		if c.Model == "" {
+			return nil, fmt.Errorf("verification: invalid matrix file: case without model")
+		}
+		if c.Task != TaskTextEncoding && c.Task != TaskTextClassification {
+			return nil, fmt.Errorf("verification: invalid matrix file: %s: unsupported task %#v", c.Model, c.Task)
+		}
+	}
+	return m, nil
+}
+
+// ReadFixture reads the reference outputs of the case from the fixtures
+// directory, with the tolerance of the case, if any. If the file doesn't
+// exist, the returned error satisfies os.IsNotExist.
+func ReadFixture(dir string, c Case) (*Reference, error) {
+	data, err := os.ReadFile(c.FixtureFilename(dir))
+	if err != nil {
+		return nil, err
+	}
+	ref := &Reference{}
+	if err := json.Unmarshal(data, ref); err != nil {
+		return nil, fmt.Errorf("verification: invalid fixture of %s: %w", c.Name(), err)
+	}
+	if ref.Task != c.Task {
+		return nil, fmt.Errorf("verification: the fixture of %s is of the task %#v", c.Name(), ref.Task)
+	}
+	if c.Tolerance > 0 {
+		ref.Tolerance = c.Tolerance
+	}
+	return ref, nil
+}
+
+// WriteFixture records the reference outputs of the case in the fixtures
+// directory.
+func WriteFixture(dir string, c Case, ref *Reference) error {
+	data, err := json.MarshalIndent(ref, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.FixtureFilename(dir), append(data, '\n'), 0644)
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package verification
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+)
+
+// CheckParity checks that the candidate model is a drop-in replacement of
+// the current one, e.g. a newer revision of the same model, running the
+// canonical inputs through both: the candidate must respond without errors
+// and with outputs of the same shape, i.e. vectors of the same size for the
+// text encoding task and the same labels for the text classification task.
+// For the token classification and the text2text tasks, whose outputs
+// depend on the inputs, the candidate must respond without errors, and
+// with the same number of texts for the text2text task. This also warms up
+// the candidate before it serves any request.
+//
+// For the other tasks, the outputs aren't compared: the candidate is only
+// checked to be of the same type of the current model.
+func CheckParity(ctx context.Context, current, candidate any) error {
+	switch c := current.(type) {
+	case textencoding.Interface:
+		m, ok := candidate.(textencoding.Interface)
+		if !ok {
+			return fmt.Errorf("verification: %T doesn't support the %s task", candidate, TaskTextEncoding)
+		}
+		return checkTextEncodingParity(ctx, c, m)
+	case textclassification.Interface:
+		m, ok := candidate.(textclassification.Interface)
+		if !ok {
+			return fmt.Errorf("verification: %T doesn't support the %s task", candidate, TaskTextClassification)
+		}
+		return checkTextClassificationParity(ctx, c, m)
+	case tokenclassification.Interface:
+		m, ok := candidate.(tokenclassification.Interface)
+		if !ok {
+			return fmt.Errorf("verification: %T doesn't support the token classification task", candidate)
+		}
+		return checkTokenClassificationParity(ctx, m)
+	case text2text.Interface:
+		m, ok := candidate.(text2text.Interface)
+		if !ok {
+			return fmt.Errorf("verification: %T doesn't support the text2text task", candidate)
+		}
+		return checkText2TextParity(ctx, c, m)
+	default:
+		if reflect.TypeOf(current) != reflect.TypeOf(candidate) {
+			return fmt.Errorf("verification: %T is not a replacement of %T", candidate, current)
+		}
+		return nil
+	}
+}
+
+func checkTextEncodingParity(ctx context.Context, current, candidate textencoding.Interface) error {
+	for _, input := range CanonicalInputs {
+		expected, err := current.Encode(ctx, input, meanPooling)
+		if err != nil {
+			return err
+		}
+		actual, err := candidate.Encode(ctx, input, meanPooling)
+		if err != nil {
+			return fmt.Errorf("verification: %q: %w", input, err)
+		}
+		if a, e := actual.Vector.Size(), expected.Vector.Size(); a != e {
+			return fmt.Errorf("verification: %q: expected a vector of size %d, got %d", input, e, a)
+		}
+	}
+	return nil
+}
+
+func checkTextClassificationParity(ctx context.Context, current, candidate textclassification.Interface) error {
+	for _, input := range CanonicalInputs {
+		expected, err := current.Classify(ctx, input, textclassification.Parameters{})
+		if err != nil {
+			return err
+		}
+		actual, err := candidate.Classify(ctx, input, textclassification.Parameters{})
+		if err != nil {
+			return fmt.Errorf("verification: %q: %w", input, err)
+		}
+		if a, e := sortedLabels(actual.Labels), sortedLabels(expected.Labels); !reflect.DeepEqual(a, e) {
+			return fmt.Errorf("verification: %q: expected labels %q, got %q", input, e, a)
+		}
+	}
+	return nil
+}
+
+func checkTokenClassificationParity(ctx context.Context, candidate tokenclassification.Interface) error {
+	params := tokenclassification.Parameters{AggregationStrategy: tokenclassification.AggregationStrategySimple}
+	for _, input := range CanonicalInputs {
+		if _, err := candidate.Classify(ctx, input, params); err != nil {
+			return fmt.Errorf("verification: %q: %w", input, err)
+		}
+	}
+	return nil
+}
+
+func checkText2TextParity(ctx context.Context, current, candidate text2text.Interface) error {
+	for _, input := range CanonicalInputs {
+		expected, err := current.Generate(ctx, input, nil)
+		if err != nil {
+			return err
+		}
+		actual, err := candidate.Generate(ctx, input, nil)
+		if err != nil {
+			return fmt.Errorf("verification: %q: %w", input, err)
+		}
+		if a, e := len(actual.Texts), len(expected.Texts); a != e {
+			return fmt.Errorf("verification: %q: expected %d texts, got %d", input, e, a)
+		}
+	}
+	return nil
+}
+
+func sortedLabels(labels []string) []string {
+	s := append([]string(nil), labels...)
+	sort.Strings(s)
+	return s
+}
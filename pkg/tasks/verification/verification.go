@@ -0,0 +1,209 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package verification checks a converted model against reference outputs,
+// typically produced by the original PyTorch implementation, to catch
+// weight-mapping bugs that don't cause any error during the conversion.
+package verification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+)
+
+// ReferenceFilename is the name of the file, in the model directory,
+// containing the reference outputs.
+const ReferenceFilename = "reference_outputs.json"
+
+// DefaultTolerance is the maximum absolute divergence allowed when the
+// reference doesn't specify one.
+const DefaultTolerance = 1e-3
+
+// CanonicalInputs are the inputs used to compute the reference outputs
+// when they are not shipped with the model.
+var CanonicalInputs = []string{
+	"Hello, world!",
+	"The quick brown fox jumps over the lazy dog.",
+	"I love this movie, it is the best one I have ever seen.",
+	"Paris is the capital and most populous city of France.",
+	"Ceci n'est pas une pipe.",
+}
+
+// Reference contains the expected outputs of a model for a set of inputs.
+type Reference struct {
+	// Task is the task the outputs refer to ("text-encoding" or "text-classification").
+	Task string `json:"task"`
+	// PoolingStrategy is the pooling strategy of the text encoding outputs.
+	PoolingStrategy int `json:"pooling_strategy"`
+	// Tolerance is the maximum absolute divergence allowed (default DefaultTolerance).
+	Tolerance float64 `json:"tolerance,omitempty"`
+	// Examples are the inputs with the expected outputs.
+	Examples []Example `json:"examples"`
+}
+
+// Task names.
+const (
+	TaskTextEncoding       = "text-encoding"
+	TaskTextClassification = "text-classification"
+)
+
+// Example is an input with the expected output.
+type Example struct {
+	// Input is the input text.
+	Input string `json:"input"`
+	// Vector is the expected encoding, for the text encoding task.
+	Vector []float64 `json:"vector,omitempty"`
+	// Scores are the expected label probabilities, for the text classification task.
+	Scores map[string]float64 `json:"scores,omitempty"`
+}
+
+// ReadReference reads the reference outputs from the model directory.
+// If the file doesn't exist, the returned error satisfies os.IsNotExist.
+func ReadReference(modelDir string) (*Reference, error) {
+	data, err := os.ReadFile(filepath.Join(modelDir, ReferenceFilename))
+	if err != nil {
+		return nil, err
+	}
+	ref := &Reference{}
+	if err := json.Unmarshal(data, ref); err != nil {
+		return nil, fmt.Errorf("verification: invalid reference file: %w", err)
+	}
+	return ref, nil
+}
+
+// Report is the result of a verification.
+type Report struct {
+	// Task is the verified task.
+	Task string
+	// Tolerance is the maximum absolute divergence allowed.
+	Tolerance float64
+	// Examples contains the divergence of each example.
+	Examples []ExampleReport
+	// MaxDivergence is the maximum divergence over all the examples.
+	MaxDivergence float64
+}
+
+// ExampleReport is the divergence of a single example.
+type ExampleReport struct {
+	// Input is the input text.
+	Input string
+	// MaxDivergence is the maximum absolute difference between the actual and the expected values.
+	MaxDivergence float64
+}
+
+// Passed reports whether the divergence is within the tolerance.
+func (r *Report) Passed() bool {
+	return r.MaxDivergence <= r.Tolerance
+}
+
+// String returns a human-readable representation of the report.
+func (r *Report) String() string {
+	var sb strings.Builder
+	status := "PASSED"
+	if !r.Passed() {
+		status = "FAILED"
+	}
+	fmt.Fprintf(&sb, "%s verification %s: max divergence %.3g (tolerance %.3g)\n", r.Task, status, r.MaxDivergence, r.Tolerance)
+	for _, e := range r.Examples {
+		fmt.Fprintf(&sb, "  %.3g\t%q\n", e.MaxDivergence, e.Input)
+	}
+	return sb.String()
+}
+
+func (r *Report) add(input string, divergence float64) {
+	r.Examples = append(r.Examples, ExampleReport{Input: input, MaxDivergence: divergence})
+	if divergence > r.MaxDivergence || math.IsNaN(divergence) {
+		r.MaxDivergence = divergence
+	}
+}
+
+func newReport(ref *Reference) *Report {
+	tolerance := ref.Tolerance
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+	return &Report{Task: ref.Task, Tolerance: tolerance}
+}
+
+// Verify runs the reference inputs through the model, which must implement
+// the interface of the reference task, and reports the divergence from the
+// expected outputs.
+func Verify(ctx context.Context, model any, ref *Reference) (*Report, error) {
+	switch ref.Task {
+	case TaskTextEncoding:
+		m, ok := model.(textencoding.Interface)
+		if !ok {
+			return nil, fmt.Errorf("verification: %T doesn't support the %s task", model, ref.Task)
+		}
+		return verifyTextEncoding(ctx, m, ref)
+	case TaskTextClassification:
+		m, ok := model.(textclassification.Interface)
+		if !ok {
+			return nil, fmt.Errorf("verification: %T doesn't support the %s task", model, ref.Task)
+		}
+		return verifyTextClassification(ctx, m, ref)
+	default:
+		return nil, fmt.Errorf("verification: unsupported task %#v", ref.Task)
+	}
+}
+
+func verifyTextEncoding(ctx context.Context, m textencoding.Interface, ref *Reference) (*Report, error) {
+	report := newReport(ref)
+	for _, ex := range ref.Examples {
+		resp, err := m.Encode(ctx, ex.Input, ref.PoolingStrategy)
+		if err != nil {
+			return nil, err
+		}
+		actual := resp.Vector.Data().F64()
+		if len(actual) != len(ex.Vector) {
+			return nil, fmt.Errorf("verification: %q: expected a vector of size %d, got %d", ex.Input, len(ex.Vector), len(actual))
+		}
+		report.add(ex.Input, maxAbsDiff(actual, ex.Vector))
+	}
+	return report, nil
+}
+
+func verifyTextClassification(ctx context.Context, m textclassification.Interface, ref *Reference) (*Report, error) {
+	report := newReport(ref)
+	for _, ex := range ref.Examples {
+		resp, err := m.Classify(ctx, ex.Input, textclassification.Parameters{})
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Labels) != len(ex.Scores) {
+			return nil, fmt.Errorf("verification: %q: expected %d labels, got %d", ex.Input, len(ex.Scores), len(resp.Labels))
+		}
+		actual := make([]float64, 0, len(resp.Labels))
+		expected := make([]float64, 0, len(resp.Labels))
+		for i, label := range resp.Labels {
+			score, ok := ex.Scores[label]
+			if !ok {
+				return nil, fmt.Errorf("verification: %q: unexpected label %q", ex.Input, label)
+			}
+			actual = append(actual, resp.Scores[i])
+			expected = append(expected, score)
+		}
+		report.add(ex.Input, maxAbsDiff(actual, expected))
+	}
+	return report, nil
+}
+
+func maxAbsDiff(a, b []float64) float64 {
+	var m float64
+	for i := range a {
+		d := math.Abs(a[i] - b[i])
+		if d > m || math.IsNaN(d) {
+			m = d
+		}
+	}
+	return m
+}
@@ -0,0 +1,153 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package verification
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/tasks/text2text"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textencoding"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification"
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEncoder map[string][]float64
+
+func (f fakeEncoder) Encode(_ context.Context, text string, _ int) (textencoding.Response, error) {
+	return textencoding.Response{Vector: mat.NewVecDense(f[text])}, nil
+}
+
+type fakeClassifier struct{}
+
+func (fakeClassifier) Classify(context.Context, string, textclassification.Parameters) (textclassification.Response, error) {
+	return textclassification.Response{Labels: []string{"POSITIVE", "NEGATIVE"}, Scores: []float64{0.9, 0.1}}, nil
+}
+
+type fakeTokenClassifier struct{ err error }
+
+func (f fakeTokenClassifier) Classify(context.Context, string, tokenclassification.Parameters) (tokenclassification.Response, error) {
+	return tokenclassification.Response{}, f.err
+}
+
+type fakeGenerator int
+
+func (f fakeGenerator) Generate(context.Context, string, *text2text.Options) (text2text.Response, error) {
+	return text2text.Response{Texts: make([]string, f), Scores: make([]float64, f)}, nil
+}
+
+func TestVerify_TextEncoding(t *testing.T) {
+	model := fakeEncoder{"a": {1, 2, 3}, "b": {0.5, 0.5}}
+	ref := &Reference{
+		Task: TaskTextEncoding,
+		Examples: []Example{
+			{Input: "a", Vector: []float64{1, 2.0005, 3}},
+			{Input: "b", Vector: []float64{0.5, 0.5}},
+		},
+	}
+	report, err := Verify(context.Background(), model, ref)
+	require.NoError(t, err)
+	assert.True(t, report.Passed())
+	assert.InDelta(t, 0.0005, report.MaxDivergence, 1e-9)
+	assert.Len(t, report.Examples, 2)
+
+	ref.Examples[1].Vector = []float64{0.5, 0.6}
+	report, err = Verify(context.Background(), model, ref)
+	require.NoError(t, err)
+	assert.False(t, report.Passed())
+	assert.InDelta(t, 0.1, report.MaxDivergence, 1e-9)
+
+	ref.Examples[1].Vector = []float64{0.5}
+	_, err = Verify(context.Background(), model, ref)
+	assert.Error(t, err)
+}
+
+func TestVerify_TextClassification(t *testing.T) {
+	ref := &Reference{
+		Task:      TaskTextClassification,
+		Tolerance: 0.01,
+		Examples:  []Example{{Input: "x", Scores: map[string]float64{"NEGATIVE": 0.105, "POSITIVE": 0.895}}},
+	}
+	report, err := Verify(context.Background(), fakeClassifier{}, ref)
+	require.NoError(t, err)
+	assert.True(t, report.Passed())
+
+	_, err = Verify(context.Background(), fakeEncoder{}, ref)
+	assert.Error(t, err)
+}
+
+func TestDecodeFeatures(t *testing.T) {
+	v, err := decodeFeatures([]byte(`[1, 2]`))
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1, 2}, v)
+
+	v, err = decodeFeatures([]byte(`[[1, 2], [3, 4]]`))
+	require.NoError(t, err)
+	assert.Equal(t, []float64{2, 3}, v)
+}
+
+func TestCheckParity(t *testing.T) {
+	ctx := context.Background()
+	current := fakeEncoder{}
+	for _, input := range CanonicalInputs {
+		current[input] = []float64{1, 2, 3}
+	}
+	candidate := fakeEncoder{}
+	for _, input := range CanonicalInputs {
+		candidate[input] = []float64{3, 2, 1}
+	}
+	assert.NoError(t, CheckParity(ctx, current, candidate))
+
+	candidate[CanonicalInputs[0]] = []float64{1, 2}
+	assert.Error(t, CheckParity(ctx, current, candidate))
+
+	assert.NoError(t, CheckParity(ctx, fakeClassifier{}, fakeClassifier{}))
+	assert.Error(t, CheckParity(ctx, fakeClassifier{}, current))
+
+	assert.NoError(t, CheckParity(ctx, fakeTokenClassifier{}, fakeTokenClassifier{}))
+	assert.Error(t, CheckParity(ctx, fakeTokenClassifier{}, fakeTokenClassifier{err: errors.New("failed")}))
+	assert.Error(t, CheckParity(ctx, fakeTokenClassifier{}, current))
+
+	assert.NoError(t, CheckParity(ctx, fakeGenerator(1), fakeGenerator(1)))
+	assert.Error(t, CheckParity(ctx, fakeGenerator(1), fakeGenerator(2)))
+	assert.Error(t, CheckParity(ctx, fakeGenerator(1), current))
+}
+
+func TestFixture(t *testing.T) {
+	dir := t.TempDir()
+	c := Case{Model: "org/model", Task: TaskTextEncoding, Tolerance: 0.01}
+	assert.Equal(t, "org/model/text-encoding/spago", c.Name())
+
+	_, err := ReadFixture(dir, c)
+	assert.True(t, os.IsNotExist(err))
+
+	ref := &Reference{Task: TaskTextEncoding, Examples: []Example{{Input: "a", Vector: []float64{1, 2}}}}
+	require.NoError(t, WriteFixture(dir, c, ref))
+	assert.FileExists(t, filepath.Join(dir, "org__model.text-encoding.json"))
+	actual, err := ReadFixture(dir, c)
+	require.NoError(t, err)
+	assert.Equal(t, 0.01, actual.Tolerance)
+	assert.Equal(t, ref.Examples, actual.Examples)
+
+	_, err = ReadFixture(dir, Case{Model: "org/model", Task: TaskTextClassification})
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReadMatrix(t *testing.T) {
+	m, err := ReadMatrix("testdata/accuracy/matrix.json")
+	require.NoError(t, err)
+	assert.NotEmpty(t, m.Cases)
+
+	filename := filepath.Join(t.TempDir(), "matrix.json")
+	require.NoError(t, os.WriteFile(filename, []byte(`{"cases": [{"model": "m", "task": "translation"}]}`), 0644))
+	_, err = ReadMatrix(filename)
+	assert.Error(t, err)
+}
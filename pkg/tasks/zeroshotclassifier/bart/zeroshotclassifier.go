@@ -7,16 +7,22 @@ package bart
 import (
 	"context"
 	"fmt"
+	"io"
 	"path"
 	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/nlpodyssey/cybertron/pkg/models/bart"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/diskstoremode"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/sharedweights"
 	"github.com/nlpodyssey/cybertron/pkg/tasks/zeroshotclassifier"
+	"github.com/nlpodyssey/cybertron/pkg/timings"
 	"github.com/nlpodyssey/cybertron/pkg/tokenizers/bpetokenizer"
+	"github.com/nlpodyssey/cybertron/pkg/trace"
+	"github.com/nlpodyssey/cybertron/pkg/usage"
 	"github.com/nlpodyssey/cybertron/pkg/utils/sliceutils"
 	"github.com/nlpodyssey/spago/embeddings/store/diskstore"
 	"github.com/nlpodyssey/spago/mat"
@@ -38,8 +44,11 @@ type ZeroShotClassifier struct {
 	// Model is the model used for zero-shot classification.
 	Model *bart.ModelForSequenceClassification
 	// Tokenizer is the tokenizer.
-	Tokenizer                     *bpetokenizer.BPETokenizer
-	embeddingsRepo                *diskstore.Repository
+	Tokenizer *bpetokenizer.BPETokenizer
+	// Parallelism is the maximum number of candidate labels scored
+	// concurrently by each request (default runtime.NumCPU()).
+	Parallelism                   int
+	release                       func() error
 	entailmentID, contradictionID int
 }
 
@@ -50,34 +59,46 @@ func LoadZeroShotClassifier(modelPath string) (*ZeroShotClassifier, error) {
 		return nil, fmt.Errorf("failed to load sentencepiece tokenizer for zero-shot: %w", err)
 	}
 
-	embeddingsRepo, err := diskstore.NewRepository(filepath.Join(modelPath, "repo"), diskstoremode.DefaultDiskStoreMode)
+	m, err := nn.LoadFromFile[*bart.ModelForSequenceClassification](path.Join(modelPath, "spago_model.bin"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to load embeddings repository for zero-shot: %w", err)
+		return nil, fmt.Errorf("failed to load bart model: %w", err)
 	}
 
-	m, err := nn.LoadFromFile[*bart.ModelForSequenceClassification](path.Join(modelPath, "spago_model.bin"))
+	key, err := sharedweights.Key(path.Join(modelPath, "spago_model.bin"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load bart model: %w", err)
 	}
-
-	err = m.Bart.SetEmbeddings(embeddingsRepo)
+	encoder, release, err := sharedweights.Acquire(key, func() (*bart.Model, io.Closer, error) {
+		embeddingsRepo, err := diskstore.NewRepository(filepath.Join(modelPath, "repo"), diskstoremode.DefaultDiskStoreMode)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load embeddings repository for zero-shot: %w", err)
+		}
+		if err := m.Bart.SetEmbeddings(embeddingsRepo); err != nil {
+			_ = embeddingsRepo.Close()
+			return nil, nil, fmt.Errorf("failed to load embeddings: %w", err)
+		}
+		return m.Bart, embeddingsRepo, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to load embeddings: %w", err)
+		return nil, err
 	}
+	m.Bart = encoder
 
 	entailmentID, err := m.Bart.Config.EntailmentID()
 	if err != nil {
+		_ = release()
 		return nil, err
 	}
 	contradictionID, err := m.Bart.Config.ContradictionID()
 	if err != nil {
+		_ = release()
 		return nil, err
 	}
 
 	return &ZeroShotClassifier{
 		Model:           m,
 		Tokenizer:       tok,
-		embeddingsRepo:  embeddingsRepo,
+		release:         release,
 		entailmentID:    entailmentID,
 		contradictionID: contradictionID,
 	}, nil
@@ -86,12 +107,14 @@ func LoadZeroShotClassifier(modelPath string) (*ZeroShotClassifier, error) {
 // Close finalizes the ZeroShotClassifier resources.
 // It satisfies the interface io.Closer.
 func (m *ZeroShotClassifier) Close() error {
-	return m.embeddingsRepo.Close()
+	return m.release()
 }
 
 // Classify classifies the input.
-func (m *ZeroShotClassifier) Classify(_ context.Context, text string, parameters zeroshotclassifier.Parameters) (zeroshotclassifier.Response, error) {
+func (m *ZeroShotClassifier) Classify(ctx context.Context, text string, parameters zeroshotclassifier.Parameters) (zeroshotclassifier.Response, error) {
+	end := timings.Start(ctx, timings.Tokenization)
 	premise, err := m.tokenize(text, defaultStartTokenID, defaultEndTokenID)
+	end()
 	if err != nil {
 		return zeroshotclassifier.Response{}, err
 	}
@@ -102,7 +125,11 @@ func (m *ZeroShotClassifier) Classify(_ context.Context, text string, parameters
 	multiClass := parameters.MultiLabel || len(parameters.CandidateLabels) == 1
 	scoreFn := m.score(premise, multiClass)
 
-	ch := make(chan struct{}, runtime.NumCPU())
+	parallelism := m.Parallelism
+	if parallelism <= 0 {
+		parallelism = runtime.NumCPU()
+	}
+	ch := make(chan struct{}, parallelism)
 	eg, _ := errgroup.WithContext(context.Background())
 
 	var scores mat.Matrix = mat.NewEmptyVecDense[float64](len(parameters.CandidateLabels))
@@ -117,7 +144,14 @@ func (m *ZeroShotClassifier) Classify(_ context.Context, text string, parameters
 				defaultEndTokenID,
 			)
 			if err == nil {
+				usage.AddTokens(ctx, len(premise)+len(hypothesis), 0)
+				if trace.Enabled(ctx) {
+					ids := append(append([]int(nil), premise...), hypothesis...)
+					trace.Record(ctx, trace.Input{TokenIDs: ids, AttentionMask: trace.Ones(len(ids))})
+				}
+				start := time.Now()
 				score := scoreFn(hypothesis)
+				timings.Add(ctx, timings.Forward, time.Since(start))
 				scores.SetVecScalar(i, float.Interface(score))
 			}
 			<-ch
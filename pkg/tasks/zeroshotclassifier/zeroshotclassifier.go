@@ -6,7 +6,8 @@ package zeroshotclassifier
 
 import (
 	"context"
-	"errors"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
 )
 
 const (
@@ -22,9 +23,10 @@ const (
 
 // ErrInputSequenceTooLong means that pre-processing the input text
 // produced a sequence that exceeds the maximum allowed length.
-var ErrInputSequenceTooLong = errors.New("input sequence too long")
+var ErrInputSequenceTooLong = errdefs.ErrInputTooLong
 
 // Interface defines the main functions for zero-shot classification task.
+// Its implementations are safe for concurrent use.
 type Interface interface {
 	// Classify returns the classification of the given example.
 	Classify(ctx context.Context, text string, parameters Parameters) (Response, error)
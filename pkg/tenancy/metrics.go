@@ -0,0 +1,54 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tenancy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// metric is a metric of the usage of the tenants.
+type metric struct {
+	name, kind, help string
+	value            func(Usage) int64
+	// labels are the extra labels of the metric, if any.
+	labels string
+}
+
+var metrics = []metric{
+	{"cybertron_tenant_requests_total", "counter", "Requests admitted per tenant.",
+		func(u Usage) int64 { return u.Requests }, ""},
+	{"cybertron_tenant_rejected_requests_total", "counter", "Requests rejected per tenant and reason.",
+		func(u Usage) int64 { return u.RateLimited }, `,reason="rate_limit"`},
+	{"cybertron_tenant_rejected_requests_total", "", "",
+		func(u Usage) int64 { return u.QuotaExceeded }, `,reason="token_quota"`},
+	{"cybertron_tenant_input_tokens_total", "counter", "Input tokens processed per tenant.",
+		func(u Usage) int64 { return u.InputTokens }, ""},
+	{"cybertron_tenant_output_tokens_total", "counter", "Output tokens generated per tenant.",
+		func(u Usage) int64 { return u.OutputTokens }, ""},
+	{"cybertron_tenant_quota_used_tokens", "gauge", "Tokens counted against the quota of the current period per tenant.",
+		func(u Usage) int64 { return u.QuotaUsed }, ""},
+}
+
+// labelEscaper escapes the values of the labels.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteMetrics writes the usage of the tenants in the Prometheus text
+// exposition format.
+func (r *Registry) WriteMetrics(w io.Writer) error {
+	usage := r.Usage()
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		if m.kind != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		}
+		for _, u := range usage {
+			fmt.Fprintf(bw, "%s{tenant=\"%s\"%s} %d\n", m.name, labelEscaper.Replace(u.Tenant), m.labels, m.value(u))
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,253 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tenancy identifies the tenants sharing a server by their API keys,
+// enforcing their rate limits and token quotas, and accounting their usage.
+package tenancy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/errdefs"
+)
+
+// DefaultQuotaPeriod is the default period of the token quotas.
+const DefaultQuotaPeriod = 24 * time.Hour
+
+var (
+	// ErrUnknownTenant means that the API key, or the tenant name, of the
+	// request doesn't match any tenant.
+	ErrUnknownTenant = errdefs.ErrUnknownTenant
+	// ErrRateLimited means that the tenant exceeded its rate limit.
+	ErrRateLimited = errdefs.ErrRateLimited
+	// ErrQuotaExceeded means that the tenant exhausted its token quota for
+	// the current period.
+	ErrQuotaExceeded = errdefs.ErrQuotaExceeded
+)
+
+// Tenant is the configuration of a tenant.
+type Tenant struct {
+	// Name is the unique name of the tenant.
+	Name string `json:"name"`
+	// APIKeys are the API keys identifying the requests of the tenant.
+	APIKeys []string `json:"api_keys"`
+	// RateLimit is the maximum number of requests per second (default 0,
+	// unlimited).
+	RateLimit float64 `json:"rate_limit"`
+	// Burst is the number of requests that can exceed the rate limit at
+	// once (default the rate limit, at least 1).
+	Burst int `json:"burst"`
+	// TokenQuota is the maximum number of input and output tokens of the
+	// requests in each quota period (default 0, unlimited). A request is
+	// rejected once the quota is exhausted.
+	TokenQuota int64 `json:"token_quota"`
+	// QuotaPeriod is the period of the token quota, e.g. "1h" (default "24h").
+	QuotaPeriod string `json:"quota_period"`
+}
+
+// Usage is the usage of a tenant since the server started.
+type Usage struct {
+	// Tenant is the name of the tenant.
+	Tenant string `json:"tenant"`
+	// Requests is the number of requests served.
+	Requests int64 `json:"requests"`
+	// RateLimited and QuotaExceeded are the numbers of requests rejected by
+	// the rate limit and by the token quota.
+	RateLimited   int64 `json:"rate_limited"`
+	QuotaExceeded int64 `json:"quota_exceeded"`
+	// InputTokens and OutputTokens are the numbers of tokens processed and
+	// generated by the requests.
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+	// QuotaUsed is the number of tokens counted against the quota in the
+	// current period, which ends at QuotaReset.
+	QuotaUsed  int64     `json:"quota_used"`
+	QuotaReset time.Time `json:"quota_reset"`
+}
+
+// Registry holds the tenants and their usage. It's safe for concurrent use.
+type Registry struct {
+	tenants []*Account
+	byKey   map[string]*Account
+	byName  map[string]*Account
+	// now returns the current time, overridden by the tests.
+	now func() time.Time
+}
+
+// Account is a tenant with the state of its limits and its usage.
+type Account struct {
+	conf     Tenant
+	period   time.Duration
+	registry *Registry
+
+	mu sync.Mutex
+	// allowance is the number of requests of the token bucket of the rate
+	// limit, refilled at the time of update.
+	allowance float64
+	updated   time.Time
+	usage     Usage
+}
+
+// New returns a registry of the tenants, which must have unique names and
+// API keys.
+func New(tenants []Tenant) (*Registry, error) {
+	r := &Registry{
+		byKey:  make(map[string]*Account),
+		byName: make(map[string]*Account),
+		now:    time.Now,
+	}
+	for i, t := range tenants {
+		if t.Name == "" {
+			return nil, fmt.Errorf("tenant #%d: name not specified", i+1)
+		}
+		if _, ok := r.byName[t.Name]; ok {
+			return nil, fmt.Errorf("tenant %#v: duplicate name", t.Name)
+		}
+		if t.RateLimit < 0 || t.Burst < 0 || t.TokenQuota < 0 {
+			return nil, fmt.Errorf("tenant %#v: negative limit", t.Name)
+		}
+		a := &Account{conf: t, registry: r, period: DefaultQuotaPeriod, usage: Usage{Tenant: t.Name}}
+		if t.QuotaPeriod != "" {
+			d, err := time.ParseDuration(t.QuotaPeriod)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("tenant %#v: invalid quota period %#v", t.Name, t.QuotaPeriod)
+			}
+			a.period = d
+		}
+		if a.conf.Burst == 0 {
+			a.conf.Burst = int(math.Max(1, math.Ceil(t.RateLimit)))
+		}
+		a.allowance = float64(a.conf.Burst)
+		for _, key := range t.APIKeys {
+			if key == "" {
+				return nil, fmt.Errorf("tenant %#v: empty API key", t.Name)
+			}
+			if _, ok := r.byKey[key]; ok {
+				return nil, fmt.Errorf("tenant %#v: API key shared with another tenant", t.Name)
+			}
+			r.byKey[key] = a
+		}
+		r.byName[t.Name] = a
+		r.tenants = append(r.tenants, a)
+	}
+	return r, nil
+}
+
+// tenantsFile is the JSON file of the tenants.
+type tenantsFile struct {
+	Tenants []Tenant `json:"tenants"`
+}
+
+// Load returns a registry of the tenants of the JSON file, in the format
+// {"tenants": [{"name": ..., "api_keys": [...], ...}]}.
+func Load(filename string) (*Registry, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	f := &tenantsFile{}
+	if err := dec.Decode(f); err != nil {
+		return nil, fmt.Errorf("invalid tenants file %#v: %w", filename, err)
+	}
+	r, err := New(f.Tenants)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenants file %#v: %w", filename, err)
+	}
+	return r, nil
+}
+
+// ByAPIKey returns the account of the tenant with the API key.
+func (r *Registry) ByAPIKey(key string) (*Account, error) {
+	if a, ok := r.byKey[key]; ok {
+		return a, nil
+	}
+	return nil, ErrUnknownTenant
+}
+
+// ByName returns the account of the tenant with the name, e.g. set by a
+// trusted proxy authenticating the requests.
+func (r *Registry) ByName(name string) (*Account, error) {
+	if a, ok := r.byName[name]; ok {
+		return a, nil
+	}
+	return nil, ErrUnknownTenant
+}
+
+// Usage returns the usage of all the tenants, in the order of their
+// configuration.
+func (r *Registry) Usage() []Usage {
+	usage := make([]Usage, len(r.tenants))
+	for i, a := range r.tenants {
+		usage[i] = a.Usage()
+	}
+	return usage
+}
+
+// Name returns the name of the tenant.
+func (a *Account) Name() string {
+	return a.conf.Name
+}
+
+// Admit counts a new request of the tenant, or rejects it with
+// ErrRateLimited or ErrQuotaExceeded.
+func (a *Account) Admit() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.registry.now()
+	a.resetQuota(now)
+	if a.conf.TokenQuota > 0 && a.usage.QuotaUsed >= a.conf.TokenQuota {
+		a.usage.QuotaExceeded++
+		return ErrQuotaExceeded
+	}
+	if a.conf.RateLimit > 0 {
+		if !a.updated.IsZero() {
+			a.allowance += now.Sub(a.updated).Seconds() * a.conf.RateLimit
+			a.allowance = math.Min(a.allowance, float64(a.conf.Burst))
+		}
+		a.updated = now
+		if a.allowance < 1 {
+			a.usage.RateLimited++
+			return ErrRateLimited
+		}
+		a.allowance--
+	}
+	a.usage.Requests++
+	return nil
+}
+
+// Record adds the tokens of a request admitted to the usage of the tenant,
+// and counts them against its quota.
+func (a *Account) Record(inputTokens, outputTokens int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.resetQuota(a.registry.now())
+	a.usage.InputTokens += inputTokens
+	a.usage.OutputTokens += outputTokens
+	a.usage.QuotaUsed += inputTokens + outputTokens
+}
+
+// Usage returns the usage of the tenant.
+func (a *Account) Usage() Usage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.resetQuota(a.registry.now())
+	return a.usage
+}
+
+// resetQuota starts a new quota period if the current one is over.
+func (a *Account) resetQuota(now time.Time) {
+	if now.Before(a.usage.QuotaReset) {
+		return
+	}
+	a.usage.QuotaUsed = 0
+	a.usage.QuotaReset = now.Truncate(a.period).Add(a.period)
+}
@@ -0,0 +1,118 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tenancy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRegistry(t *testing.T, tenants ...Tenant) (*Registry, *time.Time) {
+	t.Helper()
+	r, err := New(tenants)
+	require.NoError(t, err)
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	return r, &now
+}
+
+func TestRateLimit(t *testing.T) {
+	r, now := newTestRegistry(t, Tenant{Name: "a", APIKeys: []string{"key-a"}, RateLimit: 2})
+	a, err := r.ByAPIKey("key-a")
+	require.NoError(t, err)
+
+	assert.NoError(t, a.Admit())
+	assert.NoError(t, a.Admit())
+	assert.ErrorIs(t, a.Admit(), ErrRateLimited)
+
+	*now = now.Add(500 * time.Millisecond)
+	assert.NoError(t, a.Admit())
+	assert.ErrorIs(t, a.Admit(), ErrRateLimited)
+
+	u := a.Usage()
+	assert.Equal(t, int64(3), u.Requests)
+	assert.Equal(t, int64(2), u.RateLimited)
+}
+
+func TestTokenQuota(t *testing.T) {
+	r, now := newTestRegistry(t, Tenant{Name: "a", APIKeys: []string{"key-a"}, TokenQuota: 100, QuotaPeriod: "1h"})
+	a, err := r.ByName("a")
+	require.NoError(t, err)
+
+	require.NoError(t, a.Admit())
+	a.Record(60, 0)
+	require.NoError(t, a.Admit())
+	a.Record(30, 20)
+	assert.ErrorIs(t, a.Admit(), ErrQuotaExceeded)
+
+	u := a.Usage()
+	assert.Equal(t, int64(90), u.InputTokens)
+	assert.Equal(t, int64(20), u.OutputTokens)
+	assert.Equal(t, int64(110), u.QuotaUsed)
+	assert.Equal(t, time.Date(2023, 5, 1, 11, 0, 0, 0, time.UTC), u.QuotaReset)
+
+	*now = now.Add(time.Hour)
+	assert.NoError(t, a.Admit())
+	u = a.Usage()
+	assert.Equal(t, int64(0), u.QuotaUsed)
+	assert.Equal(t, int64(90), u.InputTokens)
+	assert.Equal(t, int64(1), u.QuotaExceeded)
+}
+
+func TestUnknownTenant(t *testing.T) {
+	r, _ := newTestRegistry(t, Tenant{Name: "a", APIKeys: []string{"key-a"}})
+	_, err := r.ByAPIKey("key-b")
+	assert.ErrorIs(t, err, ErrUnknownTenant)
+	_, err = r.ByName("b")
+	assert.ErrorIs(t, err, ErrUnknownTenant)
+}
+
+func TestNewInvalid(t *testing.T) {
+	_, err := New([]Tenant{{Name: "a", APIKeys: []string{"k"}}, {Name: "b", APIKeys: []string{"k"}}})
+	assert.Error(t, err)
+	_, err = New([]Tenant{{Name: "a"}, {Name: "a"}})
+	assert.Error(t, err)
+	_, err = New([]Tenant{{Name: "a", QuotaPeriod: "daily"}})
+	assert.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "tenants.json")
+	require.NoError(t, os.WriteFile(filename, []byte(`{"tenants": [
+		{"name": "search", "api_keys": ["k1", "k2"], "rate_limit": 10},
+		{"name": "batch", "api_keys": ["k3"], "token_quota": 1000000, "quota_period": "24h"}
+	]}`), 0644))
+	r, err := Load(filename)
+	require.NoError(t, err)
+	a, err := r.ByAPIKey("k2")
+	require.NoError(t, err)
+	assert.Equal(t, "search", a.Name())
+
+	require.NoError(t, os.WriteFile(filename, []byte(`{"tenants": [{"name": "a", "keys": ["k"]}]}`), 0644))
+	_, err = Load(filename)
+	assert.Error(t, err)
+}
+
+func TestWriteMetrics(t *testing.T) {
+	r, _ := newTestRegistry(t, Tenant{Name: "a", APIKeys: []string{"key-a"}})
+	a, _ := r.ByName("a")
+	require.NoError(t, a.Admit())
+	a.Record(7, 3)
+
+	var b strings.Builder
+	require.NoError(t, r.WriteMetrics(&b))
+	out := b.String()
+	assert.Contains(t, out, "# TYPE cybertron_tenant_requests_total counter\n")
+	assert.Contains(t, out, `cybertron_tenant_requests_total{tenant="a"} 1`)
+	assert.Contains(t, out, `cybertron_tenant_rejected_requests_total{tenant="a",reason="rate_limit"} 0`)
+	assert.Contains(t, out, `cybertron_tenant_input_tokens_total{tenant="a"} 7`)
+	assert.Contains(t, out, `cybertron_tenant_output_tokens_total{tenant="a"} 3`)
+}
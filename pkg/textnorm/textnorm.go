@@ -0,0 +1,246 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package textnorm normalizes the input texts before their tokenization,
+// e.g. composing their Unicode characters or collapsing their whitespace,
+// keeping track of the original position of each byte, so that the offsets
+// of the tokens in the normalized text can be mapped back to the original.
+package textnorm
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Form is the Unicode normalization form of the texts.
+type Form int
+
+const (
+	// FormNone leaves the Unicode characters as they are.
+	FormNone Form = iota
+	// FormNFC is the canonical composition.
+	FormNFC
+	// FormNFKC is the compatibility composition, e.g. replacing the
+	// ligatures and the full-width characters.
+	FormNFKC
+)
+
+// Options are the normalization steps applied to the texts, in the order of
+// the fields. The zero value leaves the texts unchanged.
+type Options struct {
+	// Form is the Unicode normalization form.
+	Form Form
+	// StripControl removes the control characters, but the whitespace.
+	StripControl bool
+	// CollapseWhitespace replaces each run of whitespace with a single
+	// space, removing the leading and trailing whitespace.
+	CollapseWhitespace bool
+	// Lowercase maps the characters to lower case.
+	Lowercase bool
+}
+
+// IsZero reports whether the options leave the texts unchanged.
+func (o Options) IsZero() bool {
+	return o == Options{}
+}
+
+// ParseOptions parses a comma-separated list of the normalization steps:
+// "nfc" or "nfkc", "strip-control", "collapse-whitespace" and "lowercase".
+// The empty string and "none" are the zero options.
+func ParseOptions(s string) (Options, error) {
+	var o Options
+	if s == "" || s == "none" {
+		return o, nil
+	}
+	for _, step := range strings.Split(s, ",") {
+		switch strings.ToLower(strings.TrimSpace(step)) {
+		case "nfc":
+			o.Form = FormNFC
+		case "nfkc":
+			o.Form = FormNFKC
+		case "strip-control":
+			o.StripControl = true
+		case "collapse-whitespace":
+			o.CollapseWhitespace = true
+		case "lowercase":
+			o.Lowercase = true
+		default:
+			return Options{}, fmt.Errorf("textnorm: unknown normalization step %#v", step)
+		}
+	}
+	return o, nil
+}
+
+// String returns the options in the format parsed by ParseOptions.
+func (o Options) String() string {
+	var steps []string
+	switch o.Form {
+	case FormNFC:
+		steps = append(steps, "nfc")
+	case FormNFKC:
+		steps = append(steps, "nfkc")
+	}
+	if o.StripControl {
+		steps = append(steps, "strip-control")
+	}
+	if o.CollapseWhitespace {
+		steps = append(steps, "collapse-whitespace")
+	}
+	if o.Lowercase {
+		steps = append(steps, "lowercase")
+	}
+	if len(steps) == 0 {
+		return "none"
+	}
+	return strings.Join(steps, ",")
+}
+
+// Text is a normalized text.
+type Text struct {
+	// Text is the normalized text.
+	Text string
+	// original is the text before the normalization.
+	original string
+	// starts and ends are the span in the original text of each byte of the
+	// normalized one, or nil if the text is unchanged.
+	starts, ends []int
+}
+
+// Normalize normalizes the text with the options.
+func Normalize(text string, opts Options) *Text {
+	t := &Text{Text: text, original: text}
+	if opts.IsZero() {
+		return t
+	}
+	n := normalizer{
+		opts:   opts,
+		out:    make([]byte, 0, len(text)),
+		starts: make([]int, 0, len(text)),
+		ends:   make([]int, 0, len(text)),
+	}
+	if f, ok := unicodeForm(opts.Form); ok {
+		// Each segment of the normalized text comes from a segment of the
+		// original text, as a whole. A decomposition can span more segments
+		// before the position in the original text moves forward.
+		var it norm.Iter
+		it.InitString(f, text)
+		var seg []byte
+		for start := 0; !it.Done(); {
+			seg = append(seg, it.Next()...)
+			if pos := it.Pos(); pos > start {
+				n.write(seg, start, pos)
+				seg, start = seg[:0], pos
+			}
+		}
+	} else {
+		for i := 0; i < len(text); {
+			r, size := utf8.DecodeRuneInString(text[i:])
+			n.writeRune(r, i, i+size)
+			i += size
+		}
+	}
+	t.Text, t.starts, t.ends = string(n.out), n.starts, n.ends
+	return t
+}
+
+// Original returns the span in the original text of the span of the
+// normalized text from start to end, in bytes.
+func (t *Text) Original(start, end int) (int, int) {
+	if t.starts == nil {
+		return start, end
+	}
+	if start >= len(t.Text) {
+		return len(t.original), len(t.original)
+	}
+	if start < 0 {
+		start = 0
+	}
+	oStart := t.starts[start]
+	if end <= start {
+		return oStart, oStart
+	}
+	if end > len(t.Text) {
+		end = len(t.Text)
+	}
+	return oStart, t.ends[end-1]
+}
+
+// OriginalText returns the text of the original span of the span of the
+// normalized text from start to end, in bytes.
+func (t *Text) OriginalText(start, end int) string {
+	start, end = t.Original(start, end)
+	return t.original[start:end]
+}
+
+func unicodeForm(f Form) (norm.Form, bool) {
+	switch f {
+	case FormNFC:
+		return norm.NFC, true
+	case FormNFKC:
+		return norm.NFKC, true
+	default:
+		return 0, false
+	}
+}
+
+// normalizer writes the normalized text, with the original span of each
+// byte written.
+type normalizer struct {
+	opts         Options
+	out          []byte
+	starts, ends []int
+	// space is the original span of the whitespace not written yet, if
+	// the whitespace is collapsed.
+	space      bool
+	spaceStart int
+	spaceEnd   int
+}
+
+// write writes the runes of the segment coming from the original span.
+func (n *normalizer) write(seg []byte, start, end int) {
+	for len(seg) > 0 {
+		r, size := utf8.DecodeRune(seg)
+		n.writeRune(r, start, end)
+		seg = seg[size:]
+	}
+}
+
+// writeRune writes the rune coming from the original span.
+func (n *normalizer) writeRune(r rune, start, end int) {
+	if n.opts.StripControl && unicode.IsControl(r) && !unicode.IsSpace(r) {
+		return
+	}
+	if n.opts.CollapseWhitespace {
+		if unicode.IsSpace(r) {
+			if !n.space {
+				n.space, n.spaceStart = true, start
+			}
+			n.spaceEnd = end
+			return
+		}
+		if n.space {
+			n.space = false
+			if len(n.out) > 0 {
+				n.append(' ', n.spaceStart, n.spaceEnd)
+			}
+		}
+	}
+	if n.opts.Lowercase {
+		r = unicode.ToLower(r)
+	}
+	n.append(r, start, end)
+}
+
+func (n *normalizer) append(r rune, start, end int) {
+	l := len(n.out)
+	n.out = utf8.AppendRune(n.out, r)
+	for i := l; i < len(n.out); i++ {
+		n.starts = append(n.starts, start)
+		n.ends = append(n.ends, end)
+	}
+}
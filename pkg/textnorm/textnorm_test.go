@@ -0,0 +1,74 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package textnorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOptions(t *testing.T) {
+	o, err := ParseOptions("nfkc, strip-control,collapse-whitespace,lowercase")
+	require.NoError(t, err)
+	assert.Equal(t, Options{Form: FormNFKC, StripControl: true, CollapseWhitespace: true, Lowercase: true}, o)
+	assert.Equal(t, "nfkc,strip-control,collapse-whitespace,lowercase", o.String())
+
+	o, err = ParseOptions("none")
+	require.NoError(t, err)
+	assert.True(t, o.IsZero())
+	assert.Equal(t, "none", o.String())
+
+	_, err = ParseOptions("nfc,uppercase")
+	assert.Error(t, err)
+}
+
+func TestNormalize(t *testing.T) {
+	t.Run("unchanged", func(t *testing.T) {
+		n := Normalize("Hello  World", Options{})
+		assert.Equal(t, "Hello  World", n.Text)
+		start, end := n.Original(7, 12)
+		assert.Equal(t, []int{7, 12}, []int{start, end})
+	})
+
+	t.Run("nfc", func(t *testing.T) {
+		// "e" followed by the combining acute accent.
+		n := Normalize("café bar", Options{Form: FormNFC})
+		assert.Equal(t, "café bar", n.Text)
+		assert.Equal(t, "café", n.OriginalText(0, 5))
+		assert.Equal(t, "bar", n.OriginalText(6, 9))
+	})
+
+	t.Run("nfkc", func(t *testing.T) {
+		n := Normalize("ﬁne", Options{Form: FormNFKC})
+		assert.Equal(t, "fine", n.Text)
+		assert.Equal(t, "ﬁ", n.OriginalText(0, 1))
+		assert.Equal(t, "ne", n.OriginalText(2, 4))
+	})
+
+	t.Run("strip control and collapse whitespace", func(t *testing.T) {
+		n := Normalize(" \tHello\x00 \n World​ ", Options{StripControl: true, CollapseWhitespace: true})
+		assert.Equal(t, "Hello World​", n.Text)
+		assert.Equal(t, "Hello", n.OriginalText(0, 5))
+		assert.Equal(t, " \n ", n.OriginalText(5, 6))
+		assert.Equal(t, "World", n.OriginalText(6, 11))
+	})
+
+	t.Run("lowercase", func(t *testing.T) {
+		n := Normalize("ÀB", Options{Lowercase: true})
+		assert.Equal(t, "àb", n.Text)
+		assert.Equal(t, "B", n.OriginalText(2, 3))
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		n := Normalize("  a  ", Options{CollapseWhitespace: true})
+		assert.Equal(t, "a", n.Text)
+		start, end := n.Original(0, 10)
+		assert.Equal(t, []int{2, 3}, []int{start, end})
+		start, end = n.Original(1, 1)
+		assert.Equal(t, []int{5, 5}, []int{start, end})
+	})
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package timings records the time spent by the requests in their stages,
+// e.g. the tokenization of the input and the forward pass of the model: the
+// tasks add them to the Timings carried by the context of the request, if
+// any.
+package timings
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// The stages of the requests.
+const (
+	// Queue is the wait for a replica of the model.
+	Queue = "queue"
+	// Tokenization is the tokenization of the input texts.
+	Tokenization = "tokenization"
+	// Forward is the forward pass of the model, e.g. of its encoder.
+	Forward = "forward"
+	// Decoding is the generation of the output tokens.
+	Decoding = "decoding"
+)
+
+// Stage is the time spent by a request in a stage.
+type Stage struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Timings records the time spent by a request in its stages. It's safe for
+// concurrent use.
+type Timings struct {
+	mu     sync.Mutex
+	stages []Stage
+}
+
+// Stages returns the time spent in each stage, in the order they were first
+// entered, summing the times of the stages entered several times, e.g. the
+// forward passes of a zero-shot classification.
+func (t *Timings) Stages() []Stage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Stage(nil), t.stages...)
+}
+
+func (t *Timings) add(name string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.stages {
+		if t.stages[i].Name == name {
+			t.stages[i].Duration += d
+			return
+		}
+	}
+	t.stages = append(t.stages, Stage{Name: name, Duration: d})
+}
+
+type timingsKey struct{}
+
+// NewContext returns a copy of the context carrying the timings of the
+// request.
+func NewContext(ctx context.Context, t *Timings) context.Context {
+	return context.WithValue(ctx, timingsKey{}, t)
+}
+
+// Add adds the time spent in the stage to the timings carried by the
+// context, if any.
+func Add(ctx context.Context, name string, d time.Duration) {
+	if t, _ := ctx.Value(timingsKey{}).(*Timings); t != nil {
+		t.add(name, d)
+	}
+}
+
+// Start starts the stage, returning the function ending it, which adds the
+// time spent to the timings carried by the context, if any.
+//
+//	end := timings.Start(ctx, timings.Tokenization)
+//	tokenized := m.tokenize(text)
+//	end()
+func Start(ctx context.Context, name string) func() {
+	t, _ := ctx.Value(timingsKey{}).(*Timings)
+	if t == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() { t.add(name, time.Since(start)) }
+}
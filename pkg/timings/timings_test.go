@@ -0,0 +1,34 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timings
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimings(t *testing.T) {
+	var tm Timings
+	ctx := NewContext(context.Background(), &tm)
+	Add(ctx, Tokenization, time.Millisecond)
+	Add(ctx, Forward, 2*time.Millisecond)
+	Add(ctx, Forward, 3*time.Millisecond)
+	end := Start(ctx, Decoding)
+	end()
+
+	stages := tm.Stages()
+	assert.Equal(t, []Stage{
+		{Tokenization, time.Millisecond},
+		{Forward, 5 * time.Millisecond},
+	}, stages[:2])
+	assert.Equal(t, Decoding, stages[2].Name)
+
+	// Without timings in the context.
+	Add(context.Background(), Forward, time.Second)
+	Start(context.Background(), Forward)()
+}
@@ -5,7 +5,9 @@
 package basetokenizer
 
 import (
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/nlpodyssey/cybertron/pkg/tokenizers"
 )
@@ -94,3 +96,18 @@ func TestBaseTokenizer_isPunctuation(t *testing.T) {
 		}
 	}
 }
+
+func FuzzBaseTokenizer_Tokenize(f *testing.F) {
+	for _, s := range []string{"Hey friend! \n \t How are you?!?", "", "\xff\xfe", "naïve café 🤔", strings.Repeat("a.", 10000)} {
+		f.Add(s)
+	}
+	tokenizer := New(RegisterSpecialWords("[CLS]", "[SEP]"))
+	f.Fuzz(func(t *testing.T, s string) {
+		length := utf8.RuneCountInString(s)
+		for _, token := range tokenizer.Tokenize(s) {
+			if token.Offsets.Start < 0 || token.Offsets.Start > token.Offsets.End || token.Offsets.End > length {
+				t.Fatalf("invalid offsets %v of %q in a text of %d runes", token.Offsets, token.String, length)
+			}
+		}
+	})
+}
@@ -5,7 +5,10 @@
 package bpetokenizer
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/nlpodyssey/cybertron/pkg/tokenizers"
@@ -36,3 +39,47 @@ func TestNewFromModelFolder(t *testing.T) {
 		t.Errorf("expected:\n  %#v\nactual:\n  %#v\n", expected, actual)
 	}
 }
+
+func FuzzBPETokenizer_Tokenize(f *testing.F) {
+	for _, s := range []string{"related unrelated", "", "\xff\xfe", "naïve café 🤔", strings.Repeat("unrelated ", 1000)} {
+		f.Add(s)
+	}
+	tokenizer, err := NewFromModelFolder("testdata/dummy-roberta-model")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		// Errors are fine, e.g. for the characters out of the vocabulary: panics aren't.
+		_, _ = tokenizer.Tokenize(s)
+		_, _ = tokenizer.Encode(s)
+	})
+}
+
+func FuzzNewFromModelFolder_Merges(f *testing.F) {
+	merges, err := os.ReadFile("testdata/dummy-roberta-model/merges.txt")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(string(merges))
+	f.Add("#version: 0.2\nr e\n\n")
+	f.Add("r  e\nun related extra\n")
+	f.Add("\xff \xfe\n")
+	vocab, err := os.ReadFile("testdata/dummy-roberta-model/vocab.json")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Fuzz(func(t *testing.T, merges string) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "vocab.json"), vocab, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "merges.txt"), []byte(merges), 0644); err != nil {
+			t.Fatal(err)
+		}
+		tokenizer, err := NewFromModelFolder(dir)
+		if err != nil {
+			return
+		}
+		_, _ = tokenizer.Tokenize("related unrelated")
+	})
+}
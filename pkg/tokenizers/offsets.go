@@ -0,0 +1,55 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tokenizers
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// RuneOffsets maps the offsets of a text counted in runes, such as the ones
+// of the tokens of the BaseTokenizer and of the WordPieceTokenizer, to the
+// offsets counted in bytes, and back, e.g. to slice the text with them.
+// It holds the byte offset of each rune, followed by the length of the text.
+type RuneOffsets []int
+
+// NewRuneOffsets returns the RuneOffsets of the text.
+func NewRuneOffsets(text string) RuneOffsets {
+	r := make(RuneOffsets, 0, utf8.RuneCountInString(text)+1)
+	for i := range text {
+		r = append(r, i)
+	}
+	return append(r, len(text))
+}
+
+// Bytes returns the offsets in bytes of the offsets in runes.
+func (r RuneOffsets) Bytes(o OffsetsType) OffsetsType {
+	return OffsetsType{Start: r.byteAt(o.Start), End: r.byteAt(o.End)}
+}
+
+// Runes returns the offsets in runes of the offsets in bytes. The offsets
+// within a rune are extended to include the whole rune.
+func (r RuneOffsets) Runes(o OffsetsType) OffsetsType {
+	start := sort.SearchInts(r, o.Start+1) - 1
+	if start < 0 {
+		start = 0
+	}
+	end := sort.SearchInts(r, o.End)
+	if end >= len(r) {
+		end = len(r) - 1
+	}
+	return OffsetsType{Start: start, End: end}
+}
+
+func (r RuneOffsets) byteAt(i int) int {
+	switch {
+	case i < 0:
+		return 0
+	case i >= len(r):
+		return r[len(r)-1]
+	default:
+		return r[i]
+	}
+}
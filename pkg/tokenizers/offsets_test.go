@@ -0,0 +1,83 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tokenizers_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/tokenizers"
+	"github.com/nlpodyssey/cybertron/pkg/tokenizers/basetokenizer"
+	"github.com/nlpodyssey/cybertron/pkg/tokenizers/wordpiecetokenizer"
+	"github.com/nlpodyssey/cybertron/pkg/vocabulary"
+	"github.com/stretchr/testify/assert"
+)
+
+// conformanceTexts are the texts whose spans are checked to be aligned to
+// the original text, with the characters made of more runes, or more bytes.
+var conformanceTexts = []string{
+	"Hello world!",
+	"The family \U0001F468\u200d\U0001F469\u200d\U0001F467 visited Paris.", // zero-width joiners
+	"Thumbs \U0001F44D\U0001F3FD up in Zürich",                             // skin tone modifier
+	"Cafe\u0301 in Montre\u0301al",                                         // combining acute accents
+	"Flags \U0001F1EE\U0001F1F9\U0001F1EB\U0001F1F7 at São Paulo",          // regional indicators
+	"zero\u200bwidth space and a\u200djoiner",
+	"東京 is in 日本!",
+}
+
+func TestRuneOffsets(t *testing.T) {
+	r := tokenizers.NewRuneOffsets("aé👍b")
+	assert.Equal(t, tokenizers.RuneOffsets{0, 1, 3, 7, 8}, r)
+	assert.Equal(t, tokenizers.OffsetsType{Start: 1, End: 7}, r.Bytes(tokenizers.OffsetsType{Start: 1, End: 3}))
+	assert.Equal(t, tokenizers.OffsetsType{Start: 1, End: 3}, r.Runes(tokenizers.OffsetsType{Start: 1, End: 7}))
+	// The offsets within a rune include the whole rune.
+	assert.Equal(t, tokenizers.OffsetsType{Start: 2, End: 3}, r.Runes(tokenizers.OffsetsType{Start: 4, End: 5}))
+	// The offsets out of range are clamped.
+	assert.Equal(t, tokenizers.OffsetsType{Start: 0, End: 8}, r.Bytes(tokenizers.OffsetsType{Start: -1, End: 10}))
+	assert.Equal(t, tokenizers.OffsetsType{Start: 0, End: 4}, r.Runes(tokenizers.OffsetsType{Start: -1, End: 10}))
+
+	assert.Equal(t, tokenizers.RuneOffsets{0}, tokenizers.NewRuneOffsets(""))
+}
+
+func TestOffsetsConformance(t *testing.T) {
+	for _, text := range conformanceTexts {
+		t.Run(text, func(t *testing.T) {
+			r := tokenizers.NewRuneOffsets(text)
+			base := basetokenizer.New().Tokenize(text)
+			assert.NotEmpty(t, base)
+			for _, token := range base {
+				b := r.Bytes(token.Offsets)
+				assert.Equal(t, token.String, text[b.Start:b.End])
+				assert.Equal(t, token.Offsets, r.Runes(b))
+			}
+
+			wordPiece := wordpiecetokenizer.New(splittingVocabulary(base)).Tokenize(text)
+			assert.Greater(t, len(wordPiece), len(base)-1)
+			for _, token := range wordPiece {
+				b := r.Bytes(token.Offsets)
+				assert.Equal(t, strings.TrimPrefix(token.String, wordpiecetokenizer.DefaultSplitPrefix), text[b.Start:b.End])
+				assert.Equal(t, token.Offsets, r.Runes(b))
+			}
+			for _, word := range wordpiecetokenizer.GroupSubWords(wordPiece) {
+				b := r.Bytes(word.Offsets)
+				assert.Equal(t, word.String, text[b.Start:b.End])
+			}
+		})
+	}
+}
+
+// splittingVocabulary returns a vocabulary splitting each of the tokens
+// into its first rune and the rest.
+func splittingVocabulary(tokens []tokenizers.StringOffsetsPair) *vocabulary.Vocabulary {
+	terms := []string{wordpiecetokenizer.DefaultUnknownToken}
+	for _, token := range tokens {
+		runes := []rune(token.String)
+		terms = append(terms, string(runes[0]))
+		if len(runes) > 1 {
+			terms = append(terms, wordpiecetokenizer.DefaultSplitPrefix+string(runes[1:]))
+		}
+	}
+	return vocabulary.New(terms)
+}
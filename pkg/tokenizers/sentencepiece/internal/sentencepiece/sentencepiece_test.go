@@ -5,7 +5,10 @@
 package sentencepiece
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -371,3 +374,35 @@ func firstNChars(s string, n int) string {
 	}
 	return s[:n]
 }
+
+func FuzzSentencepiece_Tokenize(f *testing.F) {
+	for _, s := range []string{"This is a sample sentence to be tokénized", "", "\xff\xfe", "İs th!s 𩸽 Ϻ Šœ Ugljšić dấu nặng", strings.Repeat("tokenized ", 1000)} {
+		f.Add(s)
+	}
+	sp, err := NewSentencepieceFromFile("test_data/spm.model", true)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		_ = sp.Tokenize(s)
+		_ = sp.TokenizeToIDs(s)
+	})
+}
+
+func FuzzNewSentencepieceFromFile(f *testing.F) {
+	// The seeds are small models, since the real ones take seconds to load.
+	f.Add([]byte{})
+	f.Add([]byte{0x0a, 0x07, 0x0a, 0x01, 0x61, 0x15, 0x00, 0x00, 0x80, 0xbf})
+	f.Add([]byte{0x0a, 0x05, 0x0a, 0x01, 0x61, 0x18, 0x02, 0x0a, 0x05, 0x0a, 0x01, 0x62, 0x18, 0x03})
+	f.Fuzz(func(t *testing.T, model []byte) {
+		filename := filepath.Join(t.TempDir(), "spiece.model")
+		if err := os.WriteFile(filename, model, 0644); err != nil {
+			t.Fatal(err)
+		}
+		sp, err := NewSentencepieceFromFile(filename, false)
+		if err != nil {
+			return
+		}
+		_ = sp.Tokenize("This is a sample sentence")
+	})
+}
@@ -0,0 +1,30 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wordpiecetokenizer
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/nlpodyssey/cybertron/pkg/vocabulary"
+)
+
+func FuzzWordPieceTokenizer_Tokenize(f *testing.F) {
+	for _, s := range []string{"[CLS] unaffable [SEP]", "", "\xff\xfe", "naïve café 🤔", strings.Repeat("x", 1000), strings.Repeat("unaffable ", 10000)} {
+		f.Add(s)
+	}
+	tokenizer := New(vocabulary.New([]string{"[UNK]", "[CLS]", "[SEP]", "[MASK]", "un", "##aff", "##able", "a", "##ï", "🤔"}))
+	f.Fuzz(func(t *testing.T, s string) {
+		length := utf8.RuneCountInString(s)
+		tokens := tokenizer.Tokenize(s)
+		for _, token := range tokens {
+			if token.Offsets.Start < 0 || token.Offsets.Start > token.Offsets.End || token.Offsets.End > length {
+				t.Fatalf("invalid offsets %v of %q in a text of %d runes", token.Offsets, token.String, length)
+			}
+		}
+		_ = GroupSubWords(tokens)
+	})
+}
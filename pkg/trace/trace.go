@@ -0,0 +1,158 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package trace records the internals of the inference of the requests
+// traced, i.e. the tokens of their inputs, their attention masks, and the
+// statistics of the hidden states of each layer of the models, e.g. to
+// diagnose the divergence of a model from its reference implementation.
+// The tasks add them to the Trace carried by the context of the request,
+// if any.
+package trace
+
+import (
+	"context"
+	"math"
+	"sync"
+
+	"github.com/nlpodyssey/spago/ag"
+)
+
+// Input is the trace of an input of a model, e.g. of the text of a request,
+// or of each of its hypotheses for a zero-shot classification.
+type Input struct {
+	// Tokens are the tokens of the input, as seen by the model, with the
+	// special ones, if known.
+	Tokens []string `json:"tokens,omitempty"`
+	// TokenIDs are the IDs of the tokens, if known.
+	TokenIDs []int `json:"token_ids,omitempty"`
+	// TokenTypeIDs are the token types of the tokens, e.g. 1 for the second
+	// text of a pair, if known.
+	TokenTypeIDs []int `json:"token_type_ids,omitempty"`
+	// AttentionMask is 1 for the tokens attended by the model, 0 for the
+	// padding ones.
+	AttentionMask []int `json:"attention_mask,omitempty"`
+	// Layers are the statistics of the hidden states of each layer, from
+	// the embeddings, if recorded.
+	Layers []Layer `json:"layers,omitempty"`
+}
+
+// Layer is the statistics of the hidden states of a layer of a model, over
+// the tokens of the input.
+type Layer struct {
+	// Layer is the index of the layer, 0 for the embeddings.
+	Layer int `json:"layer"`
+	// Mean is the mean of the values of the hidden states.
+	Mean float64 `json:"mean"`
+	// Norm is the mean of the L2 norms of the hidden states of the tokens.
+	Norm float64 `json:"norm"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	// NonFinite is the number of NaN and infinite values, left out of the
+	// other statistics.
+	NonFinite int `json:"non_finite,omitempty"`
+}
+
+// Trace records the inputs of a request. It's safe for concurrent use.
+type Trace struct {
+	mu     sync.Mutex
+	inputs []Input
+}
+
+// Inputs returns the inputs recorded, in the order they were recorded.
+func (t *Trace) Inputs() []Input {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Input(nil), t.inputs...)
+}
+
+func (t *Trace) add(in Input) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inputs = append(t.inputs, in)
+}
+
+type traceKey struct{}
+
+// NewContext returns a copy of the context carrying the trace of the
+// request.
+func NewContext(ctx context.Context, t *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+// FromContext returns the trace carried by the context, or nil if the
+// request isn't traced.
+func FromContext(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+// Enabled reports whether the request of the context is traced, so that
+// the tasks compute what they record only if so.
+func Enabled(ctx context.Context) bool {
+	return FromContext(ctx) != nil
+}
+
+// Record adds the input to the trace carried by the context, if any.
+func Record(ctx context.Context, in Input) {
+	if t := FromContext(ctx); t != nil {
+		t.add(in)
+	}
+}
+
+// EncodeFunc encodes the tokens, calling the function with the hidden
+// states of the embeddings, as layer 0, and of each layer of the encoder,
+// e.g. bert.Model.EncodeEach.
+type EncodeFunc func(tokens []string, f func(layer int, states []ag.Node)) []ag.Node
+
+// Encode records the tokens, attended in full, with the statistics of the
+// hidden states of each layer of the encoder, if the context is traced.
+// The tokens are encoded once more for the trace, so that the forward pass
+// of the requests not traced is left as is.
+func Encode(ctx context.Context, tokens []string, encode EncodeFunc) {
+	if !Enabled(ctx) {
+		return
+	}
+	in := Input{Tokens: tokens, AttentionMask: Ones(len(tokens))}
+	encode(tokens, func(layer int, states []ag.Node) {
+		in.Layers = append(in.Layers, Stats(layer, states))
+	})
+	Record(ctx, in)
+}
+
+// Stats returns the statistics of the hidden states of the layer.
+func Stats(layer int, states []ag.Node) Layer {
+	l := Layer{Layer: layer, Min: math.Inf(1), Max: math.Inf(-1)}
+	var sum float64
+	var n int
+	for _, s := range states {
+		var sq float64
+		for _, v := range s.Value().Data().F64() {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				l.NonFinite++
+				continue
+			}
+			sum += v
+			sq += v * v
+			l.Min = math.Min(l.Min, v)
+			l.Max = math.Max(l.Max, v)
+			n++
+		}
+		l.Norm += math.Sqrt(sq)
+	}
+	if n == 0 {
+		return Layer{Layer: layer, NonFinite: l.NonFinite}
+	}
+	l.Mean = sum / float64(n)
+	l.Norm /= float64(len(states))
+	return l
+}
+
+// Ones returns the attention mask of n tokens without padding.
+func Ones(n int) []int {
+	mask := make([]int, n)
+	for i := range mask {
+		mask[i] = 1
+	}
+	return mask
+}
@@ -0,0 +1,49 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/nlpodyssey/spago/ag"
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode(t *testing.T) {
+	encode := func(tokens []string, f func(int, []ag.Node)) []ag.Node {
+		xs := []ag.Node{mat.NewVecDense([]float64{3, 4}), mat.NewVecDense([]float64{0, -1})}
+		f(0, xs)
+		ys := []ag.Node{mat.NewVecDense([]float64{math.NaN(), 2})}
+		f(1, ys)
+		return ys
+	}
+
+	// Without trace in the context.
+	Encode(context.Background(), []string{"a"}, func([]string, func(int, []ag.Node)) []ag.Node {
+		t.Fatal("encoded without trace")
+		return nil
+	})
+
+	var tr Trace
+	ctx := NewContext(context.Background(), &tr)
+	assert.True(t, Enabled(ctx))
+	Encode(ctx, []string{"[CLS]", "[SEP]"}, encode)
+	Record(ctx, Input{TokenIDs: []int{0, 2}})
+
+	assert.Equal(t, []Input{
+		{
+			Tokens:        []string{"[CLS]", "[SEP]"},
+			AttentionMask: []int{1, 1},
+			Layers: []Layer{
+				{Layer: 0, Mean: 1.5, Norm: 3, Min: -1, Max: 4},
+				{Layer: 1, Mean: 2, Norm: 2, Min: 2, Max: 2, NonFinite: 1},
+			},
+		},
+		{TokenIDs: []int{0, 2}},
+	}, tr.Inputs())
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package training
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/nlpodyssey/spago/ag"
+)
+
+// autoGradFunctionField is the field of an operator holding its function.
+var autoGradFunctionField = func() reflect.StructField {
+	f, ok := reflect.TypeOf(ag.Operator{}).FieldByName("fn")
+	if !ok || f.Type != reflect.TypeOf((*ag.AutoGradFunction[ag.DualValue])(nil)).Elem() {
+		panic("training: unexpected layout of ag.Operator")
+	}
+	return f
+}()
+
+// autoGradFunction returns the function of the operator.
+func autoGradFunction(op *ag.Operator) ag.AutoGradFunction[ag.DualValue] {
+	p := unsafe.Add(unsafe.Pointer(op), autoGradFunctionField.Offset)
+	return *(*ag.AutoGradFunction[ag.DualValue])(p)
+}
+
+// backward accumulates the gradients of the loss to the nodes of its graph,
+// as ag.Backward does, running the backward functions of the operators one
+// at a time, in reverse topological order.
+//
+// ag.Backward runs the function of each operator in a goroutine while it
+// traverses its operands, which updates their state without synchronization
+// with the goroutines accumulating their gradients.
+func backward(loss ag.Node) error {
+	op, ok := loss.(*ag.Operator)
+	if !ok || !op.RequiresGrad() {
+		return nil
+	}
+	var order []*ag.Operator
+	visited := make(map[*ag.Operator]bool)
+	var visit func(op *ag.Operator)
+	visit = func(op *ag.Operator) {
+		visited[op] = true
+		for _, x := range op.Operands() {
+			if xop, ok := x.(*ag.Operator); ok && !visited[xop] && xop.RequiresGrad() {
+				visit(xop)
+			}
+		}
+		order = append(order, op)
+	}
+	visit(op)
+
+	if !op.HasGrad() {
+		op.AccGrad(op.Value().OnesLike())
+	}
+	for i := len(order) - 1; i >= 0; i-- {
+		if !order[i].HasGrad() {
+			continue
+		}
+		if err := autoGradFunction(order[i]).Backward(order[i].Grad()); err != nil {
+			return fmt.Errorf("training: failed to compute the gradients: %w", err)
+		}
+	}
+	return nil
+}
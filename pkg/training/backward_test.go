@@ -0,0 +1,49 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package training
+
+import (
+	"testing"
+
+	"github.com/nlpodyssey/spago/ag"
+	"github.com/nlpodyssey/spago/losses"
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/nlpodyssey/spago/mat/float"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackward(t *testing.T) {
+	// loss returns the loss of a graph reusing its parameters and an
+	// intermediate node.
+	loss := func(w, b mat.Matrix) ag.Node {
+		x := mat.NewVecDense([]float64{0.5, -1, 2})
+		h := ag.Tanh(ag.Affine(b, w, x))
+		y := ag.Add(ag.Mul(w, ag.Sigmoid(h)), ag.Square(h))
+		return ag.Add(losses.CrossEntropy(y, 1), ag.ReduceSum(ag.Prod(h, h)))
+	}
+	w := mat.NewDense[float64](3, 3, []float64{0.1, -0.2, 0.3, 0.4, 0.5, -0.6, 0.7, -0.8, 0.9})
+	b := mat.NewVecDense([]float64{0.1, 0.2, -0.3})
+	w.SetRequiresGrad(true)
+	b.SetRequiresGrad(true)
+	require.NoError(t, backward(loss(w, b)))
+
+	// The gradients match the finite differences of the loss.
+	const eps = 1e-6
+	for _, p := range []mat.Matrix{w, b} {
+		grad := p.Grad().Data().F64()
+		for i, v := range p.Data().F64() {
+			p.SetScalar(i/p.Columns(), i%p.Columns(), float.Interface(v+eps))
+			up := loss(w.Clone(), b.Clone()).Value().Scalar().F64()
+			p.SetScalar(i/p.Columns(), i%p.Columns(), float.Interface(v-eps))
+			down := loss(w.Clone(), b.Clone()).Value().Scalar().F64()
+			p.SetScalar(i/p.Columns(), i%p.Columns(), float.Interface(v))
+			assert.InDelta(t, (up-down)/(2*eps), grad[i], 1e-6)
+		}
+	}
+
+	// A loss not requiring gradients has nothing to propagate.
+	assert.NoError(t, backward(ag.Add(mat.NewScalar[float64](1), mat.NewScalar[float64](2))))
+}
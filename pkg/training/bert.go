@@ -0,0 +1,167 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package training
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/datasets"
+	"github.com/nlpodyssey/cybertron/pkg/models"
+	bert_for_text_classification "github.com/nlpodyssey/cybertron/pkg/tasks/textclassification/bert"
+	bert_for_token_classification "github.com/nlpodyssey/cybertron/pkg/tasks/tokenclassification/bert"
+	"github.com/nlpodyssey/cybertron/pkg/tokenizers/wordpiecetokenizer"
+	"github.com/nlpodyssey/spago/ag"
+	"github.com/nlpodyssey/spago/nn"
+	"github.com/nlpodyssey/spago/nn/linear"
+)
+
+// load loads the model of the directory for the task.
+func load(modelDir, task string) (learner, error) {
+	modelConfig, err := models.ReadCommonModelConfig(modelDir, "")
+	if err != nil {
+		return nil, err
+	}
+	if modelConfig.ModelType != "bert" {
+		return nil, fmt.Errorf("training: fine-tuning not supported for model type %#v", modelConfig.ModelType)
+	}
+	switch task {
+	case TaskTextClassification:
+		m, err := bert_for_text_classification.LoadTextClassification(modelDir)
+		if err != nil {
+			return nil, err
+		}
+		return &textClassifier{m}, nil
+	case TaskTokenClassification:
+		m, err := bert_for_token_classification.LoadTokenClassification(modelDir)
+		if err != nil {
+			return nil, err
+		}
+		return &tokenClassifier{m}, nil
+	default:
+		return nil, fmt.Errorf("training: fine-tuning not supported for task %#v", task)
+	}
+}
+
+// textClassifier is a BERT text classification model being fine-tuned.
+type textClassifier struct {
+	*bert_for_text_classification.TextClassification
+}
+
+func (m *textClassifier) labels() []string    { return m.Labels }
+func (m *textClassifier) head() *linear.Model { return m.Model.Classifier }
+func (m *textClassifier) encoder() nn.Model   { return m.Model.Bert }
+func (m *textClassifier) model() nn.Model     { return m.Model }
+
+func (m *textClassifier) setHead(h *linear.Model, labels []string) {
+	m.Model.Classifier, m.Labels = h, labels
+}
+
+// prepare returns the tokens of the input, truncated to the maximum length
+// of the model, and its label.
+func (m *textClassifier) prepare(ex datasets.Example) ([]string, []string, error) {
+	if ex.Label == "" {
+		return nil, nil, errors.New(`missing "label"`)
+	}
+	tokens := m.Tokenize(ex.Input)
+	if max := m.Model.Bert.Config.MaxPositionEmbeddings; len(tokens) > max {
+		tokens = append(tokens[:max-1], wordpiecetokenizer.DefaultSequenceSeparator)
+	}
+	return tokens, []string{ex.Label}, nil
+}
+
+// encode returns the pooled encoding of the tokens.
+func (m *textClassifier) encode(tokens []string) []ag.Node {
+	return []ag.Node{m.Model.Bert.Pooler.Forward(m.Model.Bert.Encode(tokens)[0])}
+}
+
+func (m *textClassifier) save(filename string) error {
+	return nn.DumpToFile(m.Model, filename)
+}
+
+// tokenClassifier is a BERT token classification model being fine-tuned.
+type tokenClassifier struct {
+	*bert_for_token_classification.TokenClassification
+}
+
+func (m *tokenClassifier) labels() []string    { return m.Labels }
+func (m *tokenClassifier) head() *linear.Model { return m.Model.Classifier }
+func (m *tokenClassifier) encoder() nn.Model   { return m.Model.Bert }
+func (m *tokenClassifier) model() nn.Model     { return m.Model.ModelForTokenClassification }
+
+// setHead replaces the head, dropping the CRF layer, if any, whose
+// transition scores are the ones between the old labels.
+func (m *tokenClassifier) setHead(h *linear.Model, labels []string) {
+	m.Model.Classifier, m.Labels = h, labels
+	m.Model.CRF = nil
+}
+
+// prepare returns the word pieces of the "tokens" of the example, and the
+// "tags" of the words classified by the model: the first of the ones of a
+// token split by the tokenizer, e.g. at its punctuation, gets its tag, and
+// the next ones its inside tag.
+func (m *tokenClassifier) prepare(ex datasets.Example) ([]string, []string, error) {
+	words, err := stringsField(ex.Fields, "tokens")
+	if err != nil {
+		return nil, nil, err
+	}
+	tags, err := stringsField(ex.Fields, "tags")
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tags) != len(words) {
+		return nil, nil, fmt.Errorf("%d tags for %d tokens", len(tags), len(words))
+	}
+	tokens := []string{wordpiecetokenizer.DefaultClassToken}
+	var targets []string
+	for i, word := range words {
+		tag := tags[i]
+		for _, piece := range m.Tokenize(word) {
+			tokens = append(tokens, piece)
+			if strings.HasPrefix(piece, wordpiecetokenizer.DefaultSplitPrefix) {
+				continue
+			}
+			targets = append(targets, tag)
+			if label, ok := strings.CutPrefix(tag, "B-"); ok {
+				tag = "I-" + label
+			}
+		}
+	}
+	tokens = append(tokens, wordpiecetokenizer.DefaultSequenceSeparator)
+	if l, max := len(tokens), m.Model.Bert.Config.MaxPositionEmbeddings; l > max {
+		return nil, nil, fmt.Errorf("%d tokens exceed the maximum length %d", l, max)
+	}
+	return tokens, targets, nil
+}
+
+// encode returns the encodings of the words of the tokens.
+func (m *tokenClassifier) encode(tokens []string) []ag.Node {
+	return m.Model.EncodeAndReduce(tokens)
+}
+
+func (m *tokenClassifier) save(filename string) error {
+	return nn.DumpToFile(m.Model.ModelForTokenClassification, filename)
+}
+
+// stringsField returns the strings of the list field.
+func stringsField(fields map[string]any, name string) ([]string, error) {
+	switch v := fields[name].(type) {
+	case []string:
+		return v, nil
+	case []any:
+		s := make([]string, len(v))
+		for i, x := range v {
+			str, ok := x.(string)
+			if !ok {
+				return nil, fmt.Errorf("non-string %#v in %#v", x, name)
+			}
+			s[i] = str
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("missing list field %#v", name)
+	}
+}
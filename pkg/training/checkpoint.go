@@ -0,0 +1,120 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package training
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/nlpodyssey/spago/gd"
+	"github.com/nlpodyssey/spago/nn"
+)
+
+const (
+	// stateFilename is the file of the progress in the checkpoint directory.
+	stateFilename = "state.json"
+	// paramsFilename is the file of the trained parameters, with the state
+	// of the optimizer, in the checkpoint directory.
+	paramsFilename = "params.bin"
+)
+
+// checkpoint is the progress of a training.
+type checkpoint struct {
+	// Labels are the labels of the classification head.
+	Labels []string `json:"labels"`
+	// Epochs are the statistics of the epochs completed.
+	Epochs []Epoch `json:"epochs"`
+	// Steps is the number of optimization steps taken.
+	Steps int `json:"steps"`
+}
+
+// resume restores the parameters of the model and the state of the optimizer
+// from the checkpoint of the directory, if any, and returns it.
+func resume(dir string, m nn.Model, labels []string, optimizer *gd.Optimizer) (checkpoint, error) {
+	var cp checkpoint
+	data, err := os.ReadFile(filepath.Join(dir, stateFilename))
+	if errors.Is(err, fs.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, err
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, err
+	}
+	if !equal(cp.Labels, labels) {
+		return cp, fmt.Errorf("checkpoint of labels %q instead of %q", cp.Labels, labels)
+	}
+	saved, err := nn.LoadFromFile[[]nn.Param](filepath.Join(dir, paramsFilename))
+	if err != nil {
+		return cp, err
+	}
+	params := collectParams(m)
+	if len(saved) != len(params) {
+		return cp, fmt.Errorf("checkpoint of %d parameters instead of %d", len(saved), len(params))
+	}
+	for i, p := range params {
+		r, c := p.Value().Dims()
+		if sr, sc := saved[i].Value().Dims(); sr != r || sc != c {
+			return cp, fmt.Errorf("checkpoint of parameter %d of shape %dx%d instead of %dx%d", i, sr, sc, r, c)
+		}
+		p.ReplaceValue(saved[i].Value())
+		p.SetPayload(saved[i].Payload())
+	}
+	for i := 0; i < cp.Steps; i++ {
+		optimizer.IncExample()
+	}
+	return cp, nil
+}
+
+// writeCheckpoint writes the parameters of the model, with the state of the
+// optimizer, and the progress to the checkpoint directory.
+func writeCheckpoint(dir string, m nn.Model, cp checkpoint) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	filename := filepath.Join(dir, paramsFilename)
+	if err := nn.DumpToFile(collectParams(m), filename+".tmp"); err != nil {
+		return err
+	}
+	if err := os.Rename(filename+".tmp", filename); err != nil {
+		return err
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	filename = filepath.Join(dir, stateFilename)
+	if err := os.WriteFile(filename+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+// collectParams returns the parameters of the model, in the order of their
+// traversal.
+func collectParams(m nn.Model) []nn.Param {
+	var params []nn.Param
+	nn.ForEachParam(m, func(p nn.Param) {
+		params = append(params, p)
+	})
+	return params
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,205 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package training
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/nlpodyssey/cybertron/pkg/datasets"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/rs/zerolog/log"
+)
+
+// softLabelsFilename is the file of the soft labels of the teacher in the
+// checkpoint directory, so that the corpus is annotated once.
+const softLabelsFilename = "soft_labels.json"
+
+// SoftLabels are the probabilities of the labels predicted by a teacher
+// model for the texts of a corpus.
+type SoftLabels struct {
+	// Labels are the labels of the teacher, sorted.
+	Labels []string `json:"labels"`
+	// Texts are the texts of the corpus.
+	Texts []string `json:"texts"`
+	// Probabilities are the probabilities of the labels for each text.
+	Probabilities [][]float64 `json:"probabilities"`
+}
+
+// Annotate runs the teacher over the inputs of the examples, returning the
+// probabilities it predicts for each of the labels of its responses.
+func Annotate(ctx context.Context, teacher textclassification.Interface, examples []datasets.Example) (*SoftLabels, error) {
+	responses := make([]textclassification.Response, len(examples))
+	seen := make(map[string]bool)
+	var labels []string
+	for i, ex := range examples {
+		r, err := teacher.Classify(ctx, ex.Input, textclassification.Parameters{})
+		if err != nil {
+			return nil, fmt.Errorf("training: example %d: %w", i+1, err)
+		}
+		responses[i] = r
+		for _, label := range r.Labels {
+			if !seen[label] {
+				seen[label] = true
+				labels = append(labels, label)
+			}
+		}
+	}
+	sort.Strings(labels)
+	index := make(map[string]int, len(labels))
+	for i, label := range labels {
+		index[label] = i
+	}
+	sl := &SoftLabels{
+		Labels:        labels,
+		Texts:         make([]string, len(examples)),
+		Probabilities: make([][]float64, len(examples)),
+	}
+	for i, r := range responses {
+		p := make([]float64, len(labels))
+		for j, label := range r.Labels {
+			p[index[label]] = r.Scores[j]
+		}
+		sl.Texts[i], sl.Probabilities[i] = examples[i].Input, p
+	}
+	return sl, nil
+}
+
+// Distill trains the text classification model of the directory, the
+// student, e.g. a MiniLM-sized BERT model, to predict the soft labels of
+// the teacher for the inputs of the corpus, and exports it to the output
+// directory, as FineTune does. The student gets a new classification head
+// unless it has the labels of the teacher; with FullModel, its encoder is
+// trained too, as it's usually needed.
+//
+// The corpus is annotated by the teacher once, its soft labels being
+// stored in the checkpoint, along with the progress of the training.
+func Distill(ctx context.Context, teacher textclassification.Interface, modelDir, outputDir string, corpus []datasets.Example, conf Config) (*Report, error) {
+	if conf.Task != "" && conf.Task != TaskTextClassification {
+		return nil, fmt.Errorf("training: distillation not supported for task %#v", conf.Task)
+	}
+	checkpointDir := outputDir + CheckpointSuffix
+	sl, err := softLabels(ctx, teacher, corpus, checkpointDir)
+	if err != nil {
+		return nil, err
+	}
+	l, err := load(modelDir, TaskTextClassification)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+	report, err := distill(ctx, l, sl, conf, checkpointDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := export(l, modelDir, outputDir, report.Labels); err != nil {
+		return nil, fmt.Errorf("training: failed to export the model: %w", err)
+	}
+	return report, os.RemoveAll(checkpointDir)
+}
+
+// softLabels returns the soft labels of the teacher for the corpus stored
+// in the checkpoint directory, if any, or annotates the corpus and stores
+// them there.
+func softLabels(ctx context.Context, teacher textclassification.Interface, corpus []datasets.Example, dir string) (*SoftLabels, error) {
+	filename := filepath.Join(dir, softLabelsFilename)
+	data, err := os.ReadFile(filename)
+	if err == nil {
+		sl := new(SoftLabels)
+		if err := json.Unmarshal(data, sl); err != nil {
+			return nil, fmt.Errorf("training: failed to resume: %w", err)
+		}
+		if len(sl.Texts) != len(corpus) {
+			return nil, fmt.Errorf("training: failed to resume: checkpoint of %d texts instead of %d", len(sl.Texts), len(corpus))
+		}
+		return sl, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	sl, err := Annotate(ctx, teacher, corpus)
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Int("examples", len(corpus)).Strs("labels", sl.Labels).Msg("corpus annotated by the teacher")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if data, err = json.Marshal(sl); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filename+".tmp", data, 0644); err != nil {
+		return nil, err
+	}
+	return sl, os.Rename(filename+".tmp", filename)
+}
+
+// distill trains the model of the learner on the soft labels, with a new
+// classification head, unless it has their labels (see fit).
+func distill(ctx context.Context, l learner, sl *SoftLabels, conf Config, checkpointDir string) (*Report, error) {
+	conf = conf.withDefaults()
+	if err := conf.validate(); err != nil {
+		return nil, fmt.Errorf("training: %w", err)
+	}
+	if len(sl.Texts) == 0 {
+		return nil, errors.New("training: no examples")
+	}
+	if !equal(l.labels(), sl.Labels) {
+		w := l.head().W.Value()
+		_, in := w.Dims()
+		l.setHead(newHead(w, in, len(sl.Labels), conf.Seed), sl.Labels)
+		log.Info().Strs("labels", sl.Labels).Msg("training a new classification head")
+	}
+
+	samples := make([]*sample, len(sl.Texts))
+	for i, text := range sl.Texts {
+		p := sl.Probabilities[i]
+		if len(p) != len(sl.Labels) {
+			return nil, fmt.Errorf("training: example %d: %d probabilities for %d labels", i+1, len(p), len(sl.Labels))
+		}
+		best := 0
+		for j := range p {
+			if p[j] > p[best] {
+				best = j
+			}
+		}
+		tokens, _, err := l.prepare(datasets.Example{Input: text, Label: sl.Labels[best]})
+		if err != nil {
+			return nil, fmt.Errorf("training: example %d: %w", i+1, err)
+		}
+		samples[i] = &sample{
+			tokens:  tokens,
+			targets: []int{best},
+			soft:    [][]float64{soften(p, conf.Temperature)},
+		}
+	}
+	return fit(ctx, l, samples, sl.Labels, conf, checkpointDir)
+}
+
+// soften returns the probabilities softened by the temperature, as if their
+// logits were divided by it.
+func soften(p []float64, temperature float64) []float64 {
+	q := make([]float64, len(p))
+	var sum float64
+	for i, v := range p {
+		q[i] = math.Pow(v, 1/temperature)
+		sum += q[i]
+	}
+	if sum == 0 {
+		return q
+	}
+	for i := range q {
+		q[i] /= sum
+	}
+	return q
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package training
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/datasets"
+	"github.com/nlpodyssey/cybertron/pkg/tasks/textclassification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTeacher classifies the texts by their words, counting its calls.
+type fakeTeacher struct {
+	calls int
+}
+
+func (t *fakeTeacher) Classify(_ context.Context, text string, _ textclassification.Parameters) (textclassification.Response, error) {
+	t.calls++
+	if text == "" {
+		return textclassification.Response{}, errors.New("empty text")
+	}
+	for _, w := range strings.Fields("bad awful poor") {
+		if strings.Contains(text, w) {
+			return textclassification.Response{Labels: []string{"negative", "positive"}, Scores: []float64{0.8, 0.2}}, nil
+		}
+	}
+	return textclassification.Response{Labels: []string{"positive", "negative"}, Scores: []float64{0.9, 0.1}}, nil
+}
+
+func TestAnnotate(t *testing.T) {
+	sl, err := Annotate(context.Background(), &fakeTeacher{}, examples[3:5])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"negative", "positive"}, sl.Labels)
+	assert.Equal(t, []string{"good plot", "bad movie"}, sl.Texts)
+	assert.Equal(t, [][]float64{{0.1, 0.9}, {0.8, 0.2}}, sl.Probabilities)
+
+	_, err = Annotate(context.Background(), &fakeTeacher{}, []datasets.Example{{Input: ""}})
+	assert.ErrorContains(t, err, "example 1: empty text")
+}
+
+func TestDistill(t *testing.T) {
+	teacher := &fakeTeacher{}
+	corpus := make([]datasets.Example, len(examples))
+	for i, ex := range examples {
+		corpus[i] = datasets.Example{Input: ex.Input}
+	}
+	dir := filepath.Join(t.TempDir(), "checkpoint")
+	sl, err := softLabels(context.Background(), teacher, corpus, dir)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, softLabelsFilename))
+
+	// The soft labels are read from the checkpoint once stored.
+	stored, err := softLabels(context.Background(), teacher, corpus, dir)
+	require.NoError(t, err)
+	assert.Equal(t, sl, stored)
+	assert.Equal(t, len(corpus), teacher.calls)
+	_, err = softLabels(context.Background(), teacher, corpus[:2], dir)
+	assert.ErrorContains(t, err, "checkpoint of 8 texts instead of 2")
+
+	l := newFakeLearner()
+	conf := Config{Epochs: 30, BatchSize: 2, LearningRate: 0.1, FullModel: true}
+	report, err := distill(context.Background(), l, sl, conf, dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"negative", "positive"}, report.Labels)
+	require.Len(t, report.Epochs, 30)
+	assert.Less(t, report.Epochs[29].Loss, report.Epochs[0].Loss)
+
+	for _, ex := range examples {
+		out := l.head().Forward(l.encode(strings.Fields(ex.Input))...)[0]
+		assert.Equal(t, ex.Label, report.Labels[out.Value().ArgMax()], ex.Input)
+	}
+}
+
+func TestSoften(t *testing.T) {
+	assert.InDeltaSlice(t, []float64{0.75, 0.25}, soften([]float64{0.9, 0.1}, 2), 1e-9)
+	assert.InDeltaSlice(t, []float64{0.9, 0.1}, soften([]float64{0.9, 0.1}, 1), 1e-9)
+	assert.Equal(t, []float64{0, 0}, soften([]float64{0, 0}, 2))
+}
@@ -0,0 +1,133 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package training
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nlpodyssey/cybertron/pkg/calibration"
+	"github.com/nlpodyssey/cybertron/pkg/downloader"
+	"github.com/nlpodyssey/cybertron/pkg/models"
+	"github.com/nlpodyssey/spago/nn"
+)
+
+// goModelFilename is the file of the converted model.
+const goModelFilename = "spago_model.bin"
+
+// staleFiles are the patterns of the files of the model directory not
+// exported with the fine-tuned model: the original weights, in any format,
+// and the files describing them.
+var staleFiles = []string{
+	goModelFilename,
+	"pytorch_model*.bin",
+	"pytorch_model.bin.index.json",
+	"model*.safetensors",
+	"model.safetensors.index.json",
+	"tf_model.h5",
+	"flax_model.msgpack",
+	"model.gguf",
+	"model.onnx",
+	calibration.Filename,
+	downloader.MetadataFilename,
+	"reference_outputs.json",
+}
+
+// export writes the fine-tuned model to the output directory, with the
+// other files of the model directory, e.g. its vocabulary and embeddings,
+// and the labels of its classification head in its configuration.
+func export(l learner, modelDir, outputDir string, labels []string) error {
+	if err := copyDir(modelDir, outputDir); err != nil {
+		return err
+	}
+	if err := writeLabels(filepath.Join(outputDir, models.DefaultModelConfigFilename), labels); err != nil {
+		return err
+	}
+	nn.ClearSupport(l.model())
+	return l.save(filepath.Join(outputDir, goModelFilename))
+}
+
+// copyDir copies the files of the directory, but the hidden and stale ones,
+// to the output directory.
+func copyDir(dir, outputDir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") || isStale(name) {
+			continue
+		}
+		src, dst := filepath.Join(dir, name), filepath.Join(outputDir, name)
+		if e.IsDir() {
+			err = copyDir(src, dst)
+		} else {
+			err = copyFile(src, dst)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isStale(name string) bool {
+	for _, pattern := range staleFiles {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// writeLabels sets the labels of the classification head in the
+// configuration file.
+func writeLabels(filename string, labels []string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var config map[string]any
+	if err := json.Unmarshal(data, &config); err != nil {
+		return err
+	}
+	id2label := make(map[string]string, len(labels))
+	label2id := make(map[string]int, len(labels))
+	for i, label := range labels {
+		id2label[strconv.Itoa(i)] = label
+		label2id[label] = i
+	}
+	config["id2label"], config["label2id"] = id2label, label2id
+	data, err = json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0644)
+}
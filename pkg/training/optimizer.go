@@ -0,0 +1,37 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package training
+
+import (
+	"github.com/nlpodyssey/spago/gd"
+	"github.com/nlpodyssey/spago/gd/adam"
+	"github.com/nlpodyssey/spago/initializers"
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/nlpodyssey/spago/mat/rand"
+	"github.com/nlpodyssey/spago/nn/linear"
+)
+
+// newAdam returns the Adam optimization method of the configuration, AdamW
+// with a weight decay, for the parameters of the type of the matrix.
+func newAdam(like mat.Matrix, conf Config) gd.Method {
+	c := adam.NewAdamWConfig(conf.LearningRate, 0.9, 0.999, 1e-8, conf.WeightDecay)
+	if _, ok := like.(*mat.Dense[float64]); ok {
+		return adam.New[float64](c)
+	}
+	return adam.New[float32](c)
+}
+
+// newHead returns a new classification head, with the parameters of the
+// type of the matrix, initialized with the seed.
+func newHead(like mat.Matrix, in, out int, seed uint64) *linear.Model {
+	var h *linear.Model
+	if _, ok := like.(*mat.Dense[float64]); ok {
+		h = linear.New[float64](in, out)
+	} else {
+		h = linear.New[float32](in, out)
+	}
+	initializers.XavierUniform(h.W.Value(), 1, rand.NewLockedRand(seed))
+	return h
+}
@@ -0,0 +1,415 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package training fine-tunes the classification head of the text and token
+// classification models, and optionally their encoder, on a labeled dataset,
+// or on the soft labels of a larger teacher model for an unlabeled corpus
+// (knowledge distillation).
+// The progress is checkpointed after each epoch, so that an interrupted
+// training can be resumed, and the fine-tuned model is exported to a model
+// directory that can be served as any other.
+package training
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+
+	"github.com/nlpodyssey/cybertron/pkg/datasets"
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
+	"github.com/nlpodyssey/spago/ag"
+	"github.com/nlpodyssey/spago/gd"
+	"github.com/nlpodyssey/spago/losses"
+	"github.com/nlpodyssey/spago/mat/float"
+	"github.com/nlpodyssey/spago/nn"
+	"github.com/nlpodyssey/spago/nn/linear"
+	"github.com/rs/zerolog/log"
+)
+
+// CheckpointSuffix is appended to the output directory to get the directory
+// of the checkpoint.
+const CheckpointSuffix = ".checkpoint"
+
+// Task names.
+const (
+	TaskTextClassification  = "text-classification"
+	TaskTokenClassification = "token-classification"
+)
+
+// Config is the configuration of the fine-tuning.
+type Config struct {
+	// Task is the task of the model, TaskTextClassification or
+	// TaskTokenClassification.
+	Task string
+	// Epochs is the number of passes over the training examples (default 3).
+	Epochs int
+	// BatchSize is the number of examples per optimization step
+	// (default 16).
+	BatchSize int
+	// LearningRate is the step size of Adam (default 1e-3, or 3e-5 with
+	// FullModel).
+	LearningRate float64
+	// WeightDecay is the decoupled weight decay of AdamW (default 0).
+	WeightDecay float64
+	// MaxGradNorm is the maximum L2 norm the gradients are clipped to
+	// (default 1).
+	MaxGradNorm float64
+	// FullModel is whether the encoder is fine-tuned too, rather than the
+	// classification head alone.
+	FullModel bool
+	// ValidationSplit is the fraction of the examples held out to measure
+	// the accuracy after each epoch (default 0).
+	ValidationSplit float64
+	// Seed seeds the split and the shuffling of the examples, and the
+	// initialization of a new classification head.
+	Seed uint64
+	// Temperature softens the probabilities of the teacher and of the model
+	// in the distillation (see Distill) (default 2).
+	Temperature float64
+}
+
+func (c Config) withDefaults() Config {
+	if c.Epochs <= 0 {
+		c.Epochs = 3
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 16
+	}
+	if c.LearningRate <= 0 {
+		c.LearningRate = 1e-3
+		if c.FullModel {
+			c.LearningRate = 3e-5
+		}
+	}
+	if c.MaxGradNorm <= 0 {
+		c.MaxGradNorm = 1
+	}
+	if c.Temperature <= 0 {
+		c.Temperature = 2
+	}
+	return c
+}
+
+func (c Config) validate() error {
+	if c.ValidationSplit < 0 || c.ValidationSplit >= 1 {
+		return fmt.Errorf("validation split %g not in [0, 1)", c.ValidationSplit)
+	}
+	if c.WeightDecay < 0 {
+		return fmt.Errorf("negative weight decay %g", c.WeightDecay)
+	}
+	return nil
+}
+
+// Report is the result of a fine-tuning.
+type Report struct {
+	// Labels are the labels of the classification head.
+	Labels []string `json:"labels"`
+	// Examples is the number of training examples.
+	Examples int `json:"examples"`
+	// Validation is the number of examples held out.
+	Validation int `json:"validation,omitempty"`
+	// Epochs are the statistics of each epoch.
+	Epochs []Epoch `json:"epochs"`
+}
+
+// Epoch are the statistics of an epoch.
+type Epoch struct {
+	// Epoch is the number of the epoch, from 1.
+	Epoch int `json:"epoch"`
+	// Loss is the mean cross-entropy loss of the training examples.
+	Loss float64 `json:"loss"`
+	// Accuracy is the accuracy of the predictions of the labels of the
+	// examples held out, if any: in the distillation, the agreement with
+	// the teacher.
+	Accuracy float64 `json:"accuracy,omitempty"`
+}
+
+// FineTune fine-tunes the model of the directory on the labeled examples,
+// and exports it to the output directory. A new classification head is
+// trained, from scratch, when the labels of the examples aren't all labels
+// of the model. The text classification examples need their label, and the
+// token classification ones the "tokens" and their IOB "tags" fields, e.g.
+// of CoNLL.
+//
+// The progress is checkpointed in the output directory with the
+// CheckpointSuffix, which is resumed from if present, e.g. after the context
+// is canceled, and removed once the model is exported.
+func FineTune(ctx context.Context, modelDir, outputDir string, examples []datasets.Example, conf Config) (*Report, error) {
+	l, err := load(modelDir, conf.Task)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+	checkpointDir := outputDir + CheckpointSuffix
+	report, err := train(ctx, l, examples, conf, checkpointDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := export(l, modelDir, outputDir, report.Labels); err != nil {
+		return nil, fmt.Errorf("training: failed to export the model: %w", err)
+	}
+	return report, os.RemoveAll(checkpointDir)
+}
+
+// learner is a model being fine-tuned.
+type learner interface {
+	// labels returns the labels of the outputs of the head.
+	labels() []string
+	// head returns the classification head.
+	head() *linear.Model
+	// setHead replaces the classification head, of the labels.
+	setHead(h *linear.Model, labels []string)
+	// encoder returns the model computing the inputs of the head.
+	encoder() nn.Model
+	// model returns the whole model, encoder and head.
+	model() nn.Model
+	// prepare returns the tokens of the example, and the labels of the
+	// outputs of the head for them.
+	prepare(ex datasets.Example) ([]string, []string, error)
+	// encode returns the inputs of the head for the tokens.
+	encode(tokens []string) []ag.Node
+	// save writes the model to the file.
+	save(filename string) error
+	// Close closes the model.
+	Close() error
+}
+
+// sample is a training example.
+type sample struct {
+	tokens  []string
+	targets []int
+	// soft are the probabilities of the labels of the outputs, softened by
+	// the temperature, in the distillation.
+	soft [][]float64
+	// features are the inputs of the head, computed once when the encoder
+	// is frozen.
+	features []ag.Node
+}
+
+// train fine-tunes the model of the learner on the labeled examples, with a
+// new classification head, if they have other labels (see fit).
+func train(ctx context.Context, l learner, examples []datasets.Example, conf Config, checkpointDir string) (*Report, error) {
+	conf = conf.withDefaults()
+	if err := conf.validate(); err != nil {
+		return nil, fmt.Errorf("training: %w", err)
+	}
+	if len(examples) == 0 {
+		return nil, errors.New("training: no examples")
+	}
+
+	samples := make([]*sample, len(examples))
+	outputs := make([][]string, len(examples))
+	seen := make(map[string]bool)
+	var labels []string
+	for i, ex := range examples {
+		tokens, out, err := l.prepare(ex)
+		if err != nil {
+			return nil, fmt.Errorf("training: example %d: %w", i+1, err)
+		}
+		samples[i] = &sample{tokens: tokens}
+		outputs[i] = out
+		for _, label := range out {
+			if !seen[label] {
+				seen[label] = true
+				labels = append(labels, label)
+			}
+		}
+	}
+	if !containsAll(l.labels(), labels) {
+		sort.Strings(labels)
+		w := l.head().W.Value()
+		_, in := w.Dims()
+		l.setHead(newHead(w, in, len(labels), conf.Seed), labels)
+		log.Info().Strs("labels", labels).Msg("training a new classification head")
+	}
+	labels = l.labels()
+	index := make(map[string]int, len(labels))
+	for i, label := range labels {
+		index[label] = i
+	}
+	for i, out := range outputs {
+		samples[i].targets = make([]int, len(out))
+		for j, label := range out {
+			samples[i].targets[j] = index[label]
+		}
+	}
+	return fit(ctx, l, samples, labels, conf, checkpointDir)
+}
+
+// fit trains the model of the learner on the samples, of the labels of its
+// head, resuming from the checkpoint of the directory, if any, and updating
+// it after each epoch.
+func fit(ctx context.Context, l learner, samples []*sample, labels []string, conf Config, checkpointDir string) (*Report, error) {
+	rnd := rand.New(rand.NewSource(int64(conf.Seed)))
+	rnd.Shuffle(len(samples), func(i, j int) { samples[i], samples[j] = samples[j], samples[i] })
+	validation := samples[:int(float64(len(samples))*conf.ValidationSplit)]
+	training := samples[len(validation):]
+	if len(training) == 0 {
+		return nil, errors.New("training: no examples left for training")
+	}
+
+	// A head quantized at conversion time is trained at full precision.
+	if h := l.head(); h != nil {
+		if _, ok := h.W.(*quantization.Param); ok {
+			h.W = nn.NewParam(h.W.Value())
+		}
+	}
+	trained := nn.Model(l.head())
+	if conf.FullModel {
+		trained = l.model()
+	} else {
+		defer freeze(l.encoder())()
+	}
+	optimizer := gd.NewOptimizer(trained, newAdam(l.head().W.Value(), conf)).
+		WithClipGradByNorm(conf.MaxGradNorm, 2)
+
+	cp, err := resume(checkpointDir, trained, labels, optimizer)
+	if err != nil {
+		return nil, fmt.Errorf("training: failed to resume: %w", err)
+	}
+	report := &Report{Labels: labels, Examples: len(training), Validation: len(validation), Epochs: cp.Epochs}
+	t := trainer{l: l, frozen: !conf.FullModel, temperature: conf.Temperature}
+	for epoch := len(cp.Epochs); epoch < conf.Epochs; epoch++ {
+		order := rand.New(rand.NewSource(int64(conf.Seed) + int64(epoch) + 1)).Perm(len(training))
+		var total float64
+		for start := 0; start < len(order); start += conf.BatchSize {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			end := start + conf.BatchSize
+			if end > len(order) {
+				end = len(order)
+			}
+			batch := make([]ag.Node, 0, end-start)
+			for _, i := range order[start:end] {
+				batch = append(batch, t.loss(training[i]))
+			}
+			loss := ag.Mean(batch)
+			if err := backward(loss); err != nil {
+				return nil, err
+			}
+			total += loss.Value().Scalar().F64() * float64(len(batch))
+			optimizer.Optimize()
+			optimizer.IncExample()
+			cp.Steps++
+		}
+		stats := Epoch{Epoch: epoch + 1, Loss: total / float64(len(training))}
+		if len(validation) > 0 {
+			stats.Accuracy = t.accuracy(validation)
+		}
+		report.Epochs = append(report.Epochs, stats)
+		log.Info().Int("epoch", stats.Epoch).Float64("loss", stats.Loss).Float64("accuracy", stats.Accuracy).Msg("epoch trained")
+
+		cp.Labels, cp.Epochs = labels, report.Epochs
+		if err := writeCheckpoint(checkpointDir, trained, cp); err != nil {
+			return nil, fmt.Errorf("training: failed to write the checkpoint: %w", err)
+		}
+	}
+	return report, nil
+}
+
+// trainer computes the losses and the predictions of the samples.
+type trainer struct {
+	l      learner
+	frozen bool
+	// temperature softens the probabilities of the model for the soft
+	// labels.
+	temperature float64
+}
+
+// features returns the inputs of the head for the sample, computed once if
+// the encoder is frozen.
+func (t trainer) features(s *sample) []ag.Node {
+	if !t.frozen {
+		return t.l.encode(s.tokens)
+	}
+	if s.features == nil {
+		encoded := t.l.encode(s.tokens)
+		s.features = make([]ag.Node, len(encoded))
+		for i, n := range encoded {
+			v := n.Value().Clone()
+			v.SetRequiresGrad(false)
+			s.features[i] = v
+		}
+	}
+	return s.features
+}
+
+// loss returns the mean cross-entropy loss of the outputs of the sample,
+// with respect to their soft labels, if any, or to their targets.
+func (t trainer) loss(s *sample) ag.Node {
+	logits := t.l.head().Forward(t.features(s)...)
+	terms := make([]ag.Node, len(logits))
+	for i, x := range logits {
+		if s.soft != nil {
+			terms[i] = t.softCrossEntropy(x, s.soft[i])
+		} else {
+			terms[i] = losses.CrossEntropy(x, s.targets[i])
+		}
+	}
+	return ag.Mean(terms)
+}
+
+// softCrossEntropy returns the cross-entropy of the probabilities of the
+// logits, softened by the temperature, with respect to the soft labels,
+// scaled by the square of the temperature, so that the magnitude of the
+// gradients doesn't depend on it (Hinton et al., 2015).
+func (t trainer) softCrossEntropy(x ag.Node, soft []float64) ag.Node {
+	v := x.Value()
+	logp := ag.LogSoftmax(ag.DivScalar(x, v.NewScalar(t.temperature)))
+	target := v.NewVec(float.SliceInterface(soft))
+	return ag.ProdScalar(ag.Neg(ag.Dot(target, logp)), v.NewScalar(t.temperature*t.temperature))
+}
+
+// accuracy returns the fraction of the outputs of the samples whose label is
+// predicted.
+func (t trainer) accuracy(samples []*sample) float64 {
+	var correct, total int
+	for _, s := range samples {
+		for i, x := range t.l.head().Forward(t.features(s)...) {
+			if x.Value().ArgMax() == s.targets[i] {
+				correct++
+			}
+			total++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(correct) / float64(total)
+}
+
+// containsAll reports whether all the labels are in the set.
+func containsAll(set, labels []string) bool {
+	in := make(map[string]bool, len(set))
+	for _, label := range set {
+		in[label] = true
+	}
+	for _, label := range labels {
+		if !in[label] {
+			return false
+		}
+	}
+	return true
+}
+
+// freeze stops the training of the parameters of the model, and returns the
+// function resuming it.
+func freeze(m nn.Model) (unfreeze func()) {
+	var frozen []nn.Param
+	nn.ForEachParam(m, func(p nn.Param) {
+		if p.RequiresGrad() {
+			p.SetRequiresGrad(false)
+			frozen = append(frozen, p)
+		}
+	})
+	return func() {
+		for _, p := range frozen {
+			p.SetRequiresGrad(true)
+		}
+	}
+}
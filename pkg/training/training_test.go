@@ -0,0 +1,214 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package training
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nlpodyssey/cybertron/pkg/datasets"
+	"github.com/nlpodyssey/cybertron/pkg/quantization"
+	"github.com/nlpodyssey/spago/ag"
+	"github.com/nlpodyssey/spago/initializers"
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/nlpodyssey/spago/mat/rand"
+	"github.com/nlpodyssey/spago/nn"
+	"github.com/nlpodyssey/spago/nn/linear"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeModel encodes the bag of words of a text.
+type fakeModel struct {
+	nn.Module
+	Encoder    *linear.Model
+	Classifier *linear.Model
+}
+
+// fakeLearner is a text classifier of fakeModel.
+type fakeLearner struct {
+	m           *fakeModel
+	vocabulary  map[string]int
+	labelsOfOut []string
+}
+
+func newFakeLearner() *fakeLearner {
+	vocabulary := make(map[string]int)
+	for i, w := range strings.Fields("good great fine bad awful poor movie plot") {
+		vocabulary[w] = i
+	}
+	m := &fakeModel{
+		Encoder:    linear.New[float32](len(vocabulary), 8),
+		Classifier: linear.New[float32](8, 2),
+	}
+	initializers.XavierUniform(m.Encoder.W.Value(), 1, rand.NewLockedRand(1))
+	return &fakeLearner{m: m, vocabulary: vocabulary, labelsOfOut: []string{"LABEL_0", "LABEL_1"}}
+}
+
+func (l *fakeLearner) labels() []string    { return l.labelsOfOut }
+func (l *fakeLearner) head() *linear.Model { return l.m.Classifier }
+func (l *fakeLearner) encoder() nn.Model   { return l.m.Encoder }
+func (l *fakeLearner) model() nn.Model     { return l.m }
+func (l *fakeLearner) Close() error        { return nil }
+
+func (l *fakeLearner) setHead(h *linear.Model, labels []string) {
+	l.m.Classifier, l.labelsOfOut = h, labels
+}
+
+func (l *fakeLearner) prepare(ex datasets.Example) ([]string, []string, error) {
+	return strings.Fields(ex.Input), []string{ex.Label}, nil
+}
+
+func (l *fakeLearner) encode(tokens []string) []ag.Node {
+	x := make([]float32, len(l.vocabulary))
+	for _, t := range tokens {
+		x[l.vocabulary[t]] = 1
+	}
+	return []ag.Node{ag.Tanh(l.m.Encoder.Forward(mat.NewVecDense(x))[0])}
+}
+
+func (l *fakeLearner) save(filename string) error {
+	return nn.DumpToFile(l.m, filename)
+}
+
+var examples = []datasets.Example{
+	{Input: "good movie", Label: "positive"},
+	{Input: "great plot", Label: "positive"},
+	{Input: "fine movie", Label: "positive"},
+	{Input: "good plot", Label: "positive"},
+	{Input: "bad movie", Label: "negative"},
+	{Input: "awful plot", Label: "negative"},
+	{Input: "poor movie", Label: "negative"},
+	{Input: "bad plot", Label: "negative"},
+}
+
+func TestTrain(t *testing.T) {
+	l := newFakeLearner()
+	encoder := l.m.Encoder.W.Value().Clone()
+	conf := Config{Epochs: 30, BatchSize: 2, LearningRate: 0.1}
+	report, err := train(context.Background(), l, examples, conf, filepath.Join(t.TempDir(), "checkpoint"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"negative", "positive"}, report.Labels)
+	assert.Equal(t, 8, report.Examples)
+	require.Len(t, report.Epochs, 30)
+	assert.Less(t, report.Epochs[29].Loss, report.Epochs[0].Loss/2)
+	assert.Equal(t, encoder.Data(), l.m.Encoder.W.Value().Data(), "the encoder is frozen")
+	assert.True(t, l.m.Encoder.W.RequiresGrad())
+
+	for _, ex := range examples {
+		out := l.head().Forward(l.encode(strings.Fields(ex.Input))...)[0]
+		assert.Equal(t, ex.Label, report.Labels[out.Value().ArgMax()], ex.Input)
+	}
+}
+
+func TestTrain_FullModel(t *testing.T) {
+	l := newFakeLearner()
+	l.labelsOfOut = []string{"positive", "negative"}
+	encoder := l.m.Encoder.W.Value().Clone()
+	conf := Config{Epochs: 2, LearningRate: 0.01, FullModel: true, ValidationSplit: 0.25}
+	report, err := train(context.Background(), l, examples, conf, filepath.Join(t.TempDir(), "checkpoint"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"positive", "negative"}, report.Labels, "the labels of the model are kept")
+	assert.Equal(t, 6, report.Examples)
+	assert.Equal(t, 2, report.Validation)
+	assert.Contains(t, []float64{0, 0.5, 1}, report.Epochs[1].Accuracy)
+	assert.NotEqual(t, encoder.Data(), l.m.Encoder.W.Value().Data())
+}
+
+func TestTrain_Quantized(t *testing.T) {
+	l := newFakeLearner()
+	l.labelsOfOut = []string{"positive", "negative"}
+	n, err := quantization.Quantize(l.m, quantization.Int8)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	encoder := l.m.Encoder.W.Value()
+	head := l.m.Classifier.W.Value()
+
+	conf := Config{Epochs: 2, LearningRate: 0.01, FullModel: true}
+	_, err = train(context.Background(), l, examples, conf, filepath.Join(t.TempDir(), "checkpoint"))
+	require.NoError(t, err)
+	// The head is trained at full precision, the quantized encoder is
+	// frozen.
+	assert.IsType(t, &quantization.Param{}, l.m.Encoder.W)
+	assert.Equal(t, encoder.Data(), l.m.Encoder.W.Value().Data())
+	assert.NotEqual(t, head.Data(), l.m.Classifier.W.Value().Data())
+}
+
+func TestTrain_Resume(t *testing.T) {
+	conf := Config{Epochs: 4, BatchSize: 3, LearningRate: 0.05, FullModel: true, Seed: 7}
+	want, err := train(context.Background(), newFakeLearner(), examples, conf, filepath.Join(t.TempDir(), "checkpoint"))
+	require.NoError(t, err)
+
+	dir := filepath.Join(t.TempDir(), "checkpoint")
+	interrupted := conf
+	interrupted.Epochs = 2
+	_, err = train(context.Background(), newFakeLearner(), examples, interrupted, dir)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = train(ctx, newFakeLearner(), examples, conf, dir)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	got, err := train(context.Background(), newFakeLearner(), examples, conf, dir)
+	require.NoError(t, err)
+	require.Len(t, got.Epochs, 4)
+	for i, e := range want.Epochs {
+		assert.InDelta(t, e.Loss, got.Epochs[i].Loss, 1e-5)
+	}
+
+	_, err = train(context.Background(), newFakeLearner(), examples[:4], conf, dir)
+	assert.ErrorContains(t, err, "checkpoint of labels")
+}
+
+func TestTrain_Errors(t *testing.T) {
+	_, err := train(context.Background(), newFakeLearner(), nil, Config{}, t.TempDir())
+	assert.Error(t, err)
+	_, err = train(context.Background(), newFakeLearner(), examples, Config{ValidationSplit: 1}, t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestExport(t *testing.T) {
+	modelDir := t.TempDir()
+	files := map[string]string{
+		"config.json":            `{"model_type": "bert", "id2label": {"0": "LABEL_0", "1": "LABEL_1"}}`,
+		"vocab.txt":              "[PAD]\n",
+		"repo/data":              "embeddings",
+		"spago_model.bin":        "old",
+		"pytorch_model.bin":      "old",
+		"calibration.json":       "{}",
+		"download_metadata.json": "{}",
+		".last_used":             "",
+	}
+	for name, data := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(modelDir, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(modelDir, name), []byte(data), 0644))
+	}
+	outputDir := filepath.Join(t.TempDir(), "fine-tuned")
+	l := newFakeLearner()
+	require.NoError(t, export(l, modelDir, outputDir, []string{"negative", "positive"}))
+
+	for _, name := range []string{"vocab.txt", "repo/data", "spago_model.bin"} {
+		assert.FileExists(t, filepath.Join(outputDir, name))
+	}
+	for _, name := range []string{"pytorch_model.bin", "calibration.json", "download_metadata.json", ".last_used"} {
+		assert.NoFileExists(t, filepath.Join(outputDir, name))
+	}
+	data, err := os.ReadFile(filepath.Join(outputDir, "config.json"))
+	require.NoError(t, err)
+	var config map[string]any
+	require.NoError(t, json.Unmarshal(data, &config))
+	assert.Equal(t, "bert", config["model_type"])
+	assert.Equal(t, map[string]any{"0": "negative", "1": "positive"}, config["id2label"])
+	assert.Equal(t, map[string]any{"negative": float64(0), "positive": float64(1)}, config["label2id"])
+
+	m, err := nn.LoadFromFile[*fakeModel](filepath.Join(outputDir, "spago_model.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, l.m.Classifier.W.Value().Data(), m.Classifier.W.Value().Data())
+}
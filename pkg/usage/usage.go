@@ -0,0 +1,42 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package usage counts the tokens processed by the requests: the tasks add
+// them to the Counter carried by the context of the request, if any.
+package usage
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Counter counts the input and output tokens of a request. It's safe for
+// concurrent use.
+type Counter struct {
+	input, output atomic.Int64
+}
+
+// Tokens returns the number of input and output tokens counted.
+func (c *Counter) Tokens() (input, output int64) {
+	return c.input.Load(), c.output.Load()
+}
+
+type counterKey struct{}
+
+// NewContext returns a copy of the context carrying the counter of the
+// tokens of the request.
+func NewContext(ctx context.Context, c *Counter) context.Context {
+	return context.WithValue(ctx, counterKey{}, c)
+}
+
+// AddTokens adds the input and output tokens to the counter carried by the
+// context, if any.
+func AddTokens(ctx context.Context, input, output int) {
+	c, _ := ctx.Value(counterKey{}).(*Counter)
+	if c == nil {
+		return
+	}
+	c.input.Add(int64(input))
+	c.output.Add(int64(output))
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package matview reads the values of the matrices of spago through views
+// over their underlying data, instead of the copies returned by their Data,
+// Slice, ExtractRow and AtVec methods, so that the hot paths of the models,
+// e.g. reading the logits of each token, don't allocate per value.
+//
+// The views share the data of the matrices: they must not be modified, and
+// they reflect the later changes of the matrices. Only the dense matrices
+// of spago have views; the values of the others are copied.
+package matview
+
+import (
+	"math"
+
+	"github.com/nlpodyssey/spago/mat"
+)
+
+// Float32s returns the values of the matrix, in row-major order, as a view
+// over its data if it's a dense float32 matrix, converted otherwise.
+func Float32s(m mat.Matrix) []float32 {
+	if d, ok := m.(*mat.Dense[float32]); ok {
+		return mat.Data[float32](d)
+	}
+	return m.Data().F32()
+}
+
+// Float64s returns the values of the matrix, in row-major order, as a view
+// over its data if it's a dense float64 matrix, converted otherwise.
+func Float64s(m mat.Matrix) []float64 {
+	if d, ok := m.(*mat.Dense[float64]); ok {
+		return mat.Data[float64](d)
+	}
+	return m.Data().F64()
+}
+
+// Row returns the values of the i-th row of the matrix, as a view over its
+// data if it's a dense float32 matrix, converted otherwise.
+func Row(m mat.Matrix, i int) []float32 {
+	cols := m.Columns()
+	if i < 0 || i >= m.Rows() {
+		panic("matview: row index out of range")
+	}
+	return Float32s(m)[i*cols : (i+1)*cols]
+}
+
+// At returns the i-th value of the matrix, in row-major order, without
+// copying the others.
+func At(m mat.Matrix, i int) float64 {
+	switch d := m.(type) {
+	case *mat.Dense[float32]:
+		return float64(mat.Data[float32](d)[i])
+	case *mat.Dense[float64]:
+		return mat.Data[float64](d)[i]
+	default:
+		return m.ScalarAtVec(i).F64()
+	}
+}
+
+// Len returns the number of values of the matrix.
+func Len(m mat.Matrix) int {
+	return m.Rows() * m.Columns()
+}
+
+// ArgMax returns the index of the largest value of the matrix, in row-major
+// order, the first one if tied.
+func ArgMax(m mat.Matrix) int {
+	best := 0
+	for i, n := 1, Len(m); i < n; i++ {
+		if At(m, i) > At(m, best) {
+			best = i
+		}
+	}
+	return best
+}
+
+// Softmax returns the softmax of the values of the matrix, in row-major
+// order, the only allocation being the one of the result.
+func Softmax(m mat.Matrix) []float64 {
+	n := Len(m)
+	probs := make([]float64, n)
+	if n == 0 {
+		return probs
+	}
+	max := At(m, ArgMax(m))
+	var sum float64
+	for i := range probs {
+		probs[i] = math.Exp(At(m, i) - max)
+		sum += probs[i]
+	}
+	for i := range probs {
+		probs[i] /= sum
+	}
+	return probs
+}
+
+// SoftmaxAt returns the i-th value of the softmax of the values of the
+// matrix, without allocating the others.
+func SoftmaxAt(m mat.Matrix, i int) float64 {
+	max := At(m, ArgMax(m))
+	var sum float64
+	for j, n := 0, Len(m); j < n; j++ {
+		sum += math.Exp(At(m, j) - max)
+	}
+	return math.Exp(At(m, i)-max) / sum
+}
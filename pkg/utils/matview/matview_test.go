@@ -0,0 +1,48 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package matview
+
+import (
+	"testing"
+
+	"github.com/nlpodyssey/spago/mat"
+	"github.com/nlpodyssey/spago/mat/float"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFloat32s(t *testing.T) {
+	m := mat.NewDense[float32](2, 2, []float32{1, 2, 3, 4})
+	v := Float32s(m)
+	assert.Equal(t, []float32{1, 2, 3, 4}, v)
+	// The view shares the data of the matrix.
+	m.SetScalar(0, 0, float.Interface(float32(5)))
+	assert.Equal(t, float32(5), v[0])
+	assert.Equal(t, []float32{3, 4}, Row(m, 1))
+
+	// The values of a float64 matrix are converted.
+	assert.Equal(t, []float32{1, 2}, Float32s(mat.NewVecDense([]float64{1, 2})))
+}
+
+func TestFloat64s(t *testing.T) {
+	m := mat.NewVecDense([]float64{1, 2})
+	v := Float64s(m)
+	m.SetVecScalar(1, float.Interface(float64(3)))
+	assert.Equal(t, []float64{1, 3}, v)
+	assert.Equal(t, []float64{1, 2}, Float64s(mat.NewVecDense([]float32{1, 2})))
+}
+
+func TestSoftmax(t *testing.T) {
+	for _, m := range []mat.Matrix{
+		mat.NewVecDense([]float32{1, 3, 2}),
+		mat.NewVecDense([]float64{1, 3, 2}),
+	} {
+		assert.Equal(t, 1, ArgMax(m))
+		assert.InDelta(t, 3, At(m, 1), 1e-6)
+		expected := m.Softmax().Data().F64()
+		assert.InDeltaSlice(t, expected, Softmax(m), 1e-6)
+		assert.InDelta(t, expected[2], SoftmaxAt(m, 2), 1e-6)
+	}
+	assert.Empty(t, Softmax(mat.NewEmptyVecDense[float32](0)))
+}
@@ -9,6 +9,8 @@ import (
 	"bytes"
 	"encoding/gob"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"sync/atomic"
 )
@@ -45,12 +47,29 @@ func NewFromFile(path string) (*Vocabulary, error) {
 		return &Vocabulary{}, err
 	}
 	defer f.Close()
-	scanner := bufio.NewScanner(f)
+	return NewFromReader(f)
+}
+
+// NewFromFS returns a new vocabulary populated with the content of a file
+// of the file system.
+func NewFromFS(fsys fs.FS, name string) (*Vocabulary, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return &Vocabulary{}, err
+	}
+	defer f.Close()
+	return NewFromReader(f)
+}
+
+// NewFromReader returns a new vocabulary populated with the terms read, one
+// per line.
+func NewFromReader(r io.Reader) (*Vocabulary, error) {
+	scanner := bufio.NewScanner(r)
 	voc := New([]string{})
 	for scanner.Scan() {
 		voc.Add(scanner.Text())
 	}
-	return voc, nil
+	return voc, scanner.Err()
 }
 
 // Items returns all items.
@@ -80,9 +99,8 @@ func (c *Vocabulary) MustID(term string) int {
 
 // Term returns the term given the ID, and whether or not it was found in the vocabulary.
 func (c *Vocabulary) Term(id int) (string, bool) {
-	size := atomic.LoadInt64(&c.maxID)
-	maxID := int(size)
-	if id >= maxID {
+	maxID := int(atomic.LoadInt64(&c.maxID))
+	if id < 0 || id > maxID {
 		return "", false
 	}
 	return c.inverse[id], true
@@ -7,7 +7,10 @@ package vocabulary_test
 import (
 	"bytes"
 	"encoding/gob"
+	"io/fs"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/nlpodyssey/cybertron/pkg/vocabulary"
 	"github.com/stretchr/testify/assert"
@@ -24,6 +27,17 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestVocabulary_Term(t *testing.T) {
+	voc := vocabulary.New([]string{"word1", "word2", "word3"})
+	term, ok := voc.Term(2)
+	assert.True(t, ok)
+	assert.Equal(t, "word3", term)
+	_, ok = voc.Term(3)
+	assert.False(t, ok)
+	_, ok = voc.Term(-1)
+	assert.False(t, ok)
+}
+
 func TestVocabulary_LongestPrefix(t *testing.T) {
 	items := []string{"a", "aa", "aaa", "bbbb"}
 	voc := vocabulary.New(items)
@@ -60,3 +74,22 @@ func TestVocabulary_Gob(t *testing.T) {
 	require.Nil(t, err)
 	assert.Equal(t, v1, v2)
 }
+
+func TestNewFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"vocab.txt": {Data: []byte("[PAD]\nthe\ncat\n")},
+		"long.txt":  {Data: []byte(strings.Repeat("a", 70*1024) + "\n")},
+	}
+
+	voc, err := vocabulary.NewFromFS(fsys, "vocab.txt")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"[PAD]", "the", "cat"}, voc.Items())
+	id, ok := voc.ID("cat")
+	assert.True(t, ok)
+	assert.Equal(t, 2, id)
+
+	_, err = vocabulary.NewFromFS(fsys, "missing.txt")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = vocabulary.NewFromFS(fsys, "long.txt")
+	assert.Error(t, err, "the scanner errors are returned")
+}
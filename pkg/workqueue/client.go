@@ -0,0 +1,94 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrClientStopped is returned by the calls of a Client no longer receiving
+// the results.
+var ErrClientStopped = errors.New("workqueue: client stopped")
+
+// Client is the side of the frontend: it pushes the requests to the queue,
+// and waits for their results, dispatched by Run.
+type Client struct {
+	q Queue
+
+	mu      sync.Mutex
+	pending map[string]chan Result
+	stopped bool
+}
+
+// NewClient returns the client of the queue.
+func NewClient(q Queue) *Client {
+	return &Client{q: q, pending: make(map[string]chan Result)}
+}
+
+// Run dispatches the results of the inbox to the calls waiting for them,
+// until the context is done or the queue fails, failing the pending calls.
+func (c *Client) Run(ctx context.Context) error {
+	defer func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.stopped = true
+		for id, ch := range c.pending {
+			close(ch)
+			delete(c.pending, id)
+		}
+	}()
+	for {
+		res, err := c.q.Result(ctx)
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[res.ID]
+		delete(c.pending, res.ID)
+		c.mu.Unlock()
+		if !ok {
+			// The call was canceled, or the task was delivered twice.
+			log.Debug().Str("task", res.ID).Msg("discarding the result of an unknown task")
+			continue
+		}
+		ch <- res
+	}
+}
+
+// Call pushes the JSON request of the method, with its metadata, and waits
+// for its result, until the context is done.
+func (c *Client) Call(ctx context.Context, method string, data []byte, metadata map[string]string) (Result, error) {
+	t := Task{ID: newID(), Method: method, Data: data, ReplyTo: c.q.Inbox(), Metadata: metadata}
+	ch := make(chan Result, 1)
+	c.mu.Lock()
+	if c.stopped {
+		c.mu.Unlock()
+		return Result{}, ErrClientStopped
+	}
+	c.pending[t.ID] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.pending, t.ID)
+	}()
+
+	if err := c.q.Push(ctx, t); err != nil {
+		return Result{}, err
+	}
+	select {
+	case res, ok := <-ch:
+		if !ok {
+			return Result{}, ErrClientStopped
+		}
+		return res, nil
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/nlpodyssey/cybertron/pkg/nats"
+)
+
+// DefaultJetStreamSubject is the default prefix of the subjects of the
+// tasks, followed by their method.
+const DefaultJetStreamSubject = "cybertron-tasks"
+
+// The headers of the messages of the tasks, and of their results.
+const (
+	taskIDHeader   = "Cybertron-Task-Id"
+	replyToHeader  = "Cybertron-Reply-To"
+	metadataHeader = "Cybertron-Metadata"
+)
+
+// JetStream is a queue of a JetStream work queue stream, capturing the
+// subjects "<subject>.>", whose messages are pulled by the workers through
+// a durable pull consumer. The stream and the consumer must exist. The
+// inbox of each frontend is a NATS inbox.
+type JetStream struct {
+	conn    *nats.Conn
+	subject string
+	inbox   string
+	results *nats.Subscription
+
+	// mu serializes the pulls, the pull consumer serving one at a time.
+	mu       sync.Mutex
+	stream   string
+	consumer string
+	pc       *nats.PullConsumer
+}
+
+var _ Queue = &JetStream{}
+
+// OpenJetStream returns the queue of the NATS URL, whose query sets the
+// stream, the consumer and the prefix of the subjects, e.g.
+// "nats://host?stream=TASKS&consumer=workers&subject=tasks". The stream
+// and the consumer are only needed to pull the tasks.
+func OpenJetStream(u *url.URL) (*JetStream, error) {
+	q := u.Query()
+	u2 := *u
+	u2.RawQuery = ""
+	subject := q.Get("subject")
+	if subject == "" {
+		subject = DefaultJetStreamSubject
+	}
+	conn, err := nats.Connect(u2.String(), nats.Options{Name: "cybertron"})
+	if err != nil {
+		return nil, err
+	}
+	inbox := nats.NewInbox()
+	results, err := conn.Subscribe(inbox, "")
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return &JetStream{
+		conn:     conn,
+		subject:  subject,
+		inbox:    inbox,
+		results:  results,
+		stream:   q.Get("stream"),
+		consumer: q.Get("consumer"),
+	}, nil
+}
+
+// Push publishes the task to the subject of its method.
+func (q *JetStream) Push(_ context.Context, t Task) error {
+	header := textproto.MIMEHeader{taskIDHeader: {t.ID}}
+	if t.ReplyTo != "" {
+		header.Set(replyToHeader, t.ReplyTo)
+	}
+	if md := encodeMetadata(t.Metadata); md != "" {
+		header.Set(metadataHeader, md)
+	}
+	return q.conn.PublishHeader(q.subject+"."+t.Method, "", header, t.Data)
+}
+
+// Pull pulls the next message of the consumer.
+func (q *JetStream) Pull(ctx context.Context) (*Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pc == nil {
+		if q.stream == "" || q.consumer == "" {
+			return nil, errors.New("workqueue: the stream and the consumer of the JetStream queue are required")
+		}
+		pc, err := q.conn.PullConsumer(q.stream, q.consumer)
+		if err != nil {
+			return nil, err
+		}
+		q.pc = pc
+	}
+	msg, err := q.pc.Next(ctx)
+	if err != nil {
+		return nil, err
+	}
+	md, err := decodeMetadata(msg.Header.Get(metadataHeader))
+	if err != nil {
+		// Never served: acknowledged not to be delivered again.
+		_ = q.pc.Ack(msg)
+		return nil, err
+	}
+	return &Task{
+		ID:       msg.Header.Get(taskIDHeader),
+		Method:   strings.TrimPrefix(msg.Subject, q.subject+"."),
+		Data:     msg.Data,
+		ReplyTo:  msg.Header.Get(replyToHeader),
+		Metadata: md,
+		ref:      msg,
+	}, nil
+}
+
+// Complete publishes the result to the inbox of the task, if any, and
+// acknowledges its message.
+func (q *JetStream) Complete(_ context.Context, t *Task, result []byte, metadata map[string]string) error {
+	if t.ReplyTo != "" {
+		header := textproto.MIMEHeader{taskIDHeader: {t.ID}}
+		if md := encodeMetadata(metadata); md != "" {
+			header.Set(metadataHeader, md)
+		}
+		if err := q.conn.PublishHeader(t.ReplyTo, "", header, result); err != nil {
+			return err
+		}
+	}
+	return q.pc.Ack(t.ref.(*nats.Msg))
+}
+
+// Inbox returns the NATS inbox of the results.
+func (q *JetStream) Inbox() string {
+	return q.inbox
+}
+
+// Result returns the next message of the inbox.
+func (q *JetStream) Result(ctx context.Context) (Result, error) {
+	msg, err := q.results.Next(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	md, err := decodeMetadata(msg.Header.Get(metadataHeader))
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{ID: msg.Header.Get(taskIDHeader), Data: msg.Data, Metadata: md}, nil
+}
+
+// Close closes the connection.
+func (q *JetStream) Close() error {
+	return q.conn.Close()
+}
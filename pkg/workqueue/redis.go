@@ -0,0 +1,250 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package workqueue
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nlpodyssey/cybertron/pkg/responsecache"
+)
+
+const (
+	// DefaultRedisStream is the default key of the stream of the tasks.
+	DefaultRedisStream = "cybertron:tasks"
+	// DefaultRedisGroup is the default consumer group of the workers.
+	DefaultRedisGroup = "cybertron"
+)
+
+const (
+	// redisBlock is how long each read of the stream, or of the inbox, waits.
+	redisBlock = 5 * time.Second
+	// redisClaimIdle is how long a task is pending before being claimed by
+	// another worker, e.g. once its worker crashed.
+	redisClaimIdle = 10 * time.Minute
+	// redisInboxTTL is how long the results wait in an inbox, e.g. of a
+	// frontend which stopped.
+	redisInboxTTL = time.Hour
+)
+
+// Redis is a queue of a Redis stream: the workers share a consumer group,
+// and claim the tasks left pending by the others for longer than
+// redisClaimIdle. The inbox of each frontend is a list.
+type Redis struct {
+	r        *responsecache.Redis
+	stream   string
+	group    string
+	consumer string
+	inbox    string
+
+	mu          sync.Mutex
+	groupExists bool
+}
+
+var _ Queue = &Redis{}
+
+// OpenRedis returns the queue of the Redis URL, whose query can set the
+// stream and the group, e.g. "redis://host?stream=tasks&group=workers".
+// The connections are opened on demand.
+func OpenRedis(u *url.URL) (*Redis, error) {
+	q := u.Query()
+	u2 := *u
+	u2.RawQuery = ""
+	r, err := responsecache.NewRedis(&u2)
+	if err != nil {
+		return nil, err
+	}
+	stream := q.Get("stream")
+	if stream == "" {
+		stream = DefaultRedisStream
+	}
+	group := q.Get("group")
+	if group == "" {
+		group = DefaultRedisGroup
+	}
+	id := newID()
+	return &Redis{
+		r:        r,
+		stream:   stream,
+		group:    group,
+		consumer: id,
+		inbox:    stream + ":inbox:" + id,
+	}, nil
+}
+
+// Push pushes the task.
+func (q *Redis) Push(ctx context.Context, t Task) error {
+	_, err := q.r.Do(ctx, "XADD", q.stream, "*",
+		"id", t.ID, "method", t.Method, "data", string(t.Data), "reply", t.ReplyTo, "metadata", encodeMetadata(t.Metadata))
+	return err
+}
+
+// ensureGroup creates the consumer group of the workers, if it doesn't
+// exist, with the stream.
+func (q *Redis) ensureGroup(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.groupExists {
+		return nil
+	}
+	_, err := q.r.Do(ctx, "XGROUP", "CREATE", q.stream, q.group, "0", "MKSTREAM")
+	if err != nil && !strings.Contains(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	q.groupExists = true
+	return nil
+}
+
+// Pull returns the next task: one left pending by another worker for too
+// long, or a new one.
+func (q *Redis) Pull(ctx context.Context) (*Task, error) {
+	if err := q.ensureGroup(ctx); err != nil {
+		return nil, err
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// XAUTOCLAIM replies the next cursor, the claimed entries, and the
+		// deleted ones.
+		reply, err := q.r.Do(ctx, "XAUTOCLAIM", q.stream, q.group, q.consumer,
+			strconv.FormatInt(redisClaimIdle.Milliseconds(), 10), "0-0", "COUNT", "1")
+		if err != nil {
+			return nil, err
+		}
+		if claimed, ok := reply.([]any); ok && len(claimed) > 1 {
+			if t, err := redisTask(claimed[1]); t != nil || err != nil {
+				return t, err
+			}
+		}
+
+		// XREADGROUP replies the entries by stream, or nil once blocked
+		// for redisBlock.
+		reply, err = q.r.Do(ctx, "XREADGROUP", "GROUP", q.group, q.consumer, "COUNT", "1",
+			"BLOCK", strconv.FormatInt(redisBlock.Milliseconds(), 10), "STREAMS", q.stream, ">")
+		if err != nil {
+			return nil, err
+		}
+		if streams, ok := reply.([]any); ok && len(streams) > 0 {
+			stream, ok := streams[0].([]any)
+			if !ok || len(stream) != 2 {
+				return nil, fmt.Errorf("workqueue: unexpected Redis reply %v", reply)
+			}
+			if t, err := redisTask(stream[1]); t != nil || err != nil {
+				return t, err
+			}
+		}
+	}
+}
+
+// redisTask returns the task of the first entry of the list, if any.
+func redisTask(entries any) (*Task, error) {
+	list, ok := entries.([]any)
+	if !ok || len(list) == 0 {
+		return nil, nil
+	}
+	entry, ok := list[0].([]any)
+	if !ok || len(entry) != 2 {
+		return nil, fmt.Errorf("workqueue: unexpected Redis entry %v", list[0])
+	}
+	id, _ := entry[0].([]byte)
+	fields, _ := entry[1].([]any)
+	t := &Task{ref: string(id)}
+	for i := 0; i+1 < len(fields); i += 2 {
+		k, _ := fields[i].([]byte)
+		v, _ := fields[i+1].([]byte)
+		switch string(k) {
+		case "id":
+			t.ID = string(v)
+		case "method":
+			t.Method = string(v)
+		case "data":
+			t.Data = v
+		case "reply":
+			t.ReplyTo = string(v)
+		case "metadata":
+			md, err := decodeMetadata(string(v))
+			if err != nil {
+				return nil, err
+			}
+			t.Metadata = md
+		}
+	}
+	return t, nil
+}
+
+// Complete pushes the result to the inbox of the task, if any, as its ID,
+// metadata and data, separated by new lines, and acknowledges and removes
+// its entry.
+func (q *Redis) Complete(ctx context.Context, t *Task, result []byte, metadata map[string]string) error {
+	if t.ReplyTo != "" {
+		if _, err := q.r.Do(ctx, "RPUSH", t.ReplyTo, t.ID+"\n"+encodeMetadata(metadata)+"\n"+string(result)); err != nil {
+			return err
+		}
+		if _, err := q.r.Do(ctx, "PEXPIRE", t.ReplyTo, strconv.FormatInt(redisInboxTTL.Milliseconds(), 10)); err != nil {
+			return err
+		}
+	}
+	id, _ := t.ref.(string)
+	if _, err := q.r.Do(ctx, "XACK", q.stream, q.group, id); err != nil {
+		return err
+	}
+	_, err := q.r.Do(ctx, "XDEL", q.stream, id)
+	return err
+}
+
+// Inbox returns the key of the list of the results.
+func (q *Redis) Inbox() string {
+	return q.inbox
+}
+
+// Result returns the next result of the inbox.
+func (q *Redis) Result(ctx context.Context) (Result, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+		// BLPOP replies the key and the value, or nil once blocked for
+		// redisBlock.
+		reply, err := q.r.Do(ctx, "BLPOP", q.inbox, strconv.Itoa(int(redisBlock.Seconds())))
+		if err != nil {
+			return Result{}, err
+		}
+		item, ok := reply.([]any)
+		if !ok || len(item) != 2 {
+			continue
+		}
+		value, _ := item[1].([]byte)
+		return redisResult(value)
+	}
+}
+
+// redisResult returns the result of an item of the inbox.
+func redisResult(value []byte) (Result, error) {
+	id, rest, ok := bytes.Cut(value, []byte("\n"))
+	if !ok {
+		return Result{}, fmt.Errorf("workqueue: invalid result %q", value)
+	}
+	md, data, ok := bytes.Cut(rest, []byte("\n"))
+	if !ok {
+		return Result{}, fmt.Errorf("workqueue: invalid result %q", value)
+	}
+	metadata, err := decodeMetadata(string(md))
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{ID: string(id), Data: data, Metadata: metadata}, nil
+}
+
+// Close does nothing, the connections being opened on demand.
+func (q *Redis) Close() error {
+	return nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package workqueue
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+)
+
+// Serve is the side of the workers: it serves the tasks of the queue with
+// the function, returning the JSON response of the method, or its error,
+// and the metadata of the response, with the given number of workers, until
+// the context is done or the queue fails. A task whose result can't be sent
+// is left unacknowledged, so that it's delivered again.
+func Serve(ctx context.Context, q Queue, workers int, serve func(ctx context.Context, t *Task) ([]byte, map[string]string)) error {
+	if workers < 1 {
+		workers = 1
+	}
+	g, ctx := errgroup.WithContext(ctx)
+	for i := 0; i < workers; i++ {
+		g.Go(func() error {
+			for {
+				t, err := q.Pull(ctx)
+				if err != nil {
+					return err
+				}
+				result, metadata := serve(ctx, t)
+				if err := q.Complete(ctx, t, result, metadata); err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					log.Err(err).Str("task", t.ID).Str("method", t.Method).Msg("failed to complete the task")
+				}
+			}
+		})
+	}
+	return g.Wait()
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package workqueue implements the work queues shared by a fleet of
+// servers, so that the batch workloads scale horizontally with the number
+// of workers, without an orchestrator: a thin frontend pushes the requests,
+// e.g. the rows of a bulk upload, each worker pulls and serves them, and
+// sends their results back to the inbox of the frontend, which aggregates
+// them.
+//
+// The supported queues are Redis streams
+// ("redis://[[user]:password@]host[:port][/db]?stream=<key>&group=<name>",
+// or "rediss://" for TLS), needing Redis 6.2, and JetStream work queues
+// ("nats://host[:port]?stream=<name>&consumer=<name>&subject=<prefix>").
+package workqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// Task is a request of the queue.
+type Task struct {
+	// ID identifies the task to the frontend which pushed it.
+	ID string
+	// Method is the name of the method, "<service>.<method>", e.g.
+	// "textencoding.v1.TextEncodingService.Encode".
+	Method string
+	// Data is the JSON request of the method.
+	Data []byte
+	// ReplyTo is the inbox of the frontend the result is sent to (optional:
+	// the result is discarded if empty).
+	ReplyTo string
+	// Metadata is the metadata of the request, e.g. its tenant or adapter
+	// (optional).
+	Metadata map[string]string
+
+	// ref is the reference of the task in the queue, to acknowledge it.
+	ref any
+}
+
+// Result is the result of a task, sent back to its frontend.
+type Result struct {
+	// ID is the ID of the task.
+	ID string
+	// Data is the JSON response of the method, or its error.
+	Data []byte
+	// Metadata is the metadata of the response, e.g. the tokens it
+	// processed (optional).
+	Metadata map[string]string
+}
+
+// Queue is a work queue. Its methods can be called concurrently.
+type Queue interface {
+	// Push pushes the task.
+	Push(ctx context.Context, t Task) error
+	// Pull returns the next task, waiting for it until the context is done.
+	// It must be completed once served, or it's delivered again, to
+	// another worker, once its acknowledgment times out.
+	Pull(ctx context.Context) (*Task, error)
+	// Complete sends the result of the task, with its metadata, to its
+	// frontend, if any, and acknowledges it.
+	Complete(ctx context.Context, t *Task, result []byte, metadata map[string]string) error
+	// Inbox returns the inbox of the results of the tasks pushed with it.
+	Inbox() string
+	// Result returns the next result sent to the inbox, waiting for it until
+	// the context is done.
+	Result(ctx context.Context) (Result, error)
+	// Close closes the connections of the queue.
+	Close() error
+}
+
+// Open returns the queue of the URL.
+func Open(rawURL string) (Queue, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("workqueue: invalid URL %#v: %w", rawURL, err)
+	}
+	switch u.Scheme {
+	case "redis", "rediss":
+		return OpenRedis(u)
+	case "nats", "tls":
+		return OpenJetStream(u)
+	default:
+		return nil, fmt.Errorf("workqueue: unsupported URL scheme %#v", u.Scheme)
+	}
+}
+
+// encodeMetadata returns the JSON encoding of the metadata, empty if none.
+func encodeMetadata(metadata map[string]string) string {
+	if len(metadata) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(metadata)
+	return string(data)
+}
+
+// decodeMetadata returns the metadata of its JSON encoding, nil if empty.
+func decodeMetadata(data string) (map[string]string, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var metadata map[string]string
+	if err := json.Unmarshal([]byte(data), &metadata); err != nil {
+		return nil, fmt.Errorf("workqueue: invalid metadata %q: %w", data, err)
+	}
+	return metadata, nil
+}
+
+// newID returns a random ID, e.g. of a task or of an inbox.
+func newID() string {
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,204 @@
+// Copyright 2023 The NLP Odyssey Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package workqueue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memQueue is a queue of channels, with a single inbox.
+type memQueue struct {
+	tasks   chan Task
+	results chan Result
+
+	mu        sync.Mutex
+	completed int
+}
+
+func newMemQueue() *memQueue {
+	return &memQueue{tasks: make(chan Task, 16), results: make(chan Result, 16)}
+}
+
+func (q *memQueue) Push(_ context.Context, t Task) error {
+	q.tasks <- t
+	return nil
+}
+
+func (q *memQueue) Pull(ctx context.Context) (*Task, error) {
+	select {
+	case t := <-q.tasks:
+		return &t, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (q *memQueue) Complete(_ context.Context, t *Task, result []byte, metadata map[string]string) error {
+	q.mu.Lock()
+	q.completed++
+	q.mu.Unlock()
+	if t.ReplyTo != "" {
+		q.results <- Result{ID: t.ID, Data: result, Metadata: metadata}
+	}
+	return nil
+}
+
+func (q *memQueue) Inbox() string {
+	return "inbox"
+}
+
+func (q *memQueue) Result(ctx context.Context) (Result, error) {
+	select {
+	case r := <-q.results:
+		return r, nil
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	}
+}
+
+func (q *memQueue) Close() error {
+	return nil
+}
+
+func TestClientAndServe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	q := newMemQueue()
+	c := NewClient(q)
+	go func() { _ = c.Run(ctx) }()
+	go func() {
+		_ = Serve(ctx, q, 3, func(_ context.Context, t *Task) ([]byte, map[string]string) {
+			return []byte(fmt.Sprintf(`{"method":%q,"request":%s}`, t.Method, t.Data)), map[string]string{"tenant": t.Metadata["tenant"]}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tenant := fmt.Sprint("tenant", i)
+			res, err := c.Call(ctx, "svc.Method", []byte(fmt.Sprint(i)), map[string]string{"tenant": tenant})
+			require.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf(`{"method":"svc.Method","request":%d}`, i), string(res.Data))
+			assert.Equal(t, map[string]string{"tenant": tenant}, res.Metadata)
+		}(i)
+	}
+	wg.Wait()
+	q.mu.Lock()
+	assert.Equal(t, 10, q.completed)
+	q.mu.Unlock()
+}
+
+func TestClient_Stopped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := newMemQueue()
+	c := NewClient(q)
+	done := make(chan error)
+	go func() { done <- c.Run(ctx) }()
+
+	// The task is never served.
+	errs := make(chan error)
+	go func() {
+		_, err := c.Call(context.Background(), "svc.Method", []byte("{}"), nil)
+		errs <- err
+	}()
+	<-q.tasks
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.ErrorIs(t, <-errs, ErrClientStopped)
+	_, err := c.Call(context.Background(), "svc.Method", []byte("{}"), nil)
+	assert.ErrorIs(t, err, ErrClientStopped)
+}
+
+func TestRedisTask(t *testing.T) {
+	entries := []any{[]any{
+		[]byte("1-0"),
+		[]any{[]byte("id"), []byte("a"), []byte("method"), []byte("svc.Method"), []byte("data"), []byte("{}"), []byte("reply"), []byte("inbox")},
+	}}
+	task, err := redisTask(entries)
+	require.NoError(t, err)
+	assert.Equal(t, &Task{ID: "a", Method: "svc.Method", Data: []byte("{}"), ReplyTo: "inbox", ref: "1-0"}, task)
+
+	entries = []any{[]any{
+		[]byte("2-0"),
+		[]any{[]byte("id"), []byte("b"), []byte("method"), []byte("svc.Method"), []byte("metadata"), []byte(`{"cybertron-adapter":"legal"}`)},
+	}}
+	task, err = redisTask(entries)
+	require.NoError(t, err)
+	assert.Equal(t, &Task{ID: "b", Method: "svc.Method", Metadata: map[string]string{"cybertron-adapter": "legal"}, ref: "2-0"}, task)
+
+	entries = []any{[]any{
+		[]byte("3-0"),
+		[]any{[]byte("id"), []byte("c"), []byte("metadata"), []byte(`[]`)},
+	}}
+	_, err = redisTask(entries)
+	assert.Error(t, err)
+
+	task, err = redisTask([]any{})
+	require.NoError(t, err)
+	assert.Nil(t, task)
+}
+
+func TestRedisResult(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Result
+		wantErr bool
+	}{
+		{"a\n\n{}", Result{ID: "a", Data: []byte("{}")}, false},
+		{"a\n{\"tokens\":\"3\"}\n{\"x\":\"\\n\"}", Result{ID: "a", Data: []byte(`{"x":"\n"}`), Metadata: map[string]string{"tokens": "3"}}, false},
+		{"a", Result{}, true},
+		{"a\n{}", Result{}, true},
+		{"a\nnull?\n{}", Result{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := redisResult([]byte(tt.value))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	assert.Equal(t, "", encodeMetadata(nil))
+	assert.Equal(t, "", encodeMetadata(map[string]string{}))
+
+	md := map[string]string{"cybertron-adapter": "legal", "cybertron-tenant": "acme"}
+	got, err := decodeMetadata(encodeMetadata(md))
+	require.NoError(t, err)
+	assert.Equal(t, md, got)
+
+	got, err = decodeMetadata("")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	_, err = decodeMetadata("{")
+	assert.Error(t, err)
+}
+
+func TestOpen(t *testing.T) {
+	q, err := Open("redis://localhost/1?stream=tasks&group=workers")
+	require.NoError(t, err)
+	r := q.(*Redis)
+	assert.Equal(t, "tasks", r.stream)
+	assert.Equal(t, "workers", r.group)
+	assert.Contains(t, r.Inbox(), "tasks:inbox:")
+
+	_, err = Open("memory")
+	assert.Error(t, err)
+}